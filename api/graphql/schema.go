@@ -0,0 +1,9 @@
+// Package graphql holds the StreamHub GraphQL schema definition
+package graphql
+
+import _ "embed"
+
+// Schema is the GraphQL schema in SDL form
+//
+//go:embed schema.graphqls
+var Schema string
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tinle0301/streaming-platform-api/internal/websocket"
+)
+
+// adminClient calls a WebSocket node's hub operations API
+type adminClient struct {
+	baseURL string
+	token   string
+	http    *http.Client
+}
+
+// newAdminClient creates a client for the node at baseURL
+func newAdminClient(baseURL, token string) *adminClient {
+	return &adminClient{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		token:   token,
+		http:    &http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+// Status returns the node's metrics and feature switches
+func (c *adminClient) Status(ctx context.Context) (websocket.HubStatus, error) {
+	var status websocket.HubStatus
+	err := c.do(ctx, http.MethodGet, "", nil, nil, &status)
+	return status, err
+}
+
+// Rooms returns up to limit rooms, busiest first
+func (c *adminClient) Rooms(ctx context.Context, limit int) ([]websocket.RoomSummary, error) {
+	var rooms []websocket.RoomSummary
+	err := c.do(ctx, http.MethodGet, "/rooms", url.Values{"limit": {strconv.Itoa(limit)}}, nil, &rooms)
+	return rooms, err
+}
+
+// RoomClients returns the connections in a room
+func (c *adminClient) RoomClients(ctx context.Context, room string) ([]websocket.RoomClient, error) {
+	var clients []websocket.RoomClient
+	err := c.do(ctx, http.MethodGet, "/rooms/clients", url.Values{"room": {room}}, nil, &clients)
+	return clients, err
+}
+
+// Disconnect closes one of a user's connections
+func (c *adminClient) Disconnect(ctx context.Context, userID, sessionID string) error {
+	return c.do(ctx, http.MethodDelete, "/sessions", url.Values{"user_id": {userID}, "id": {sessionID}}, nil, nil)
+}
+
+// SetFeature switches a feature on or off
+func (c *adminClient) SetFeature(ctx context.Context, feature string, enabled bool) error {
+	body := map[string]interface{}{"feature": feature, "enabled": enabled}
+	return c.do(ctx, http.MethodPut, "/features", nil, body, nil)
+}
+
+// Snapshot returns a dump of the node's state with redact's personal data
+// hidden
+func (c *adminClient) Snapshot(ctx context.Context, redact string) (json.RawMessage, error) {
+	var snapshot json.RawMessage
+	err := c.do(ctx, http.MethodGet, "/snapshot", url.Values{"redact": {redact}}, nil, &snapshot)
+	return snapshot, err
+}
+
+// do sends a request to the admin API and decodes its JSON response into out
+func (c *adminClient) do(ctx context.Context, method, path string, query url.Values, body, out interface{}) error {
+	target := c.baseURL + websocket.AdminPath + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+
+	var reader io.Reader
+	if body != nil {
+		raw, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(raw)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, target, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s %s: %s: %s", method, websocket.AdminPath+path, resp.Status, strings.TrimSpace(string(message)))
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode %s response: %w", websocket.AdminPath+path, err)
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+// Command streamhub-admin is a terminal console for on-call operators: it
+// watches a WebSocket node's live metrics, inspects its rooms, disconnects
+// clients, switches features, and saves state snapshots, through the node's
+// hub operations API.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/tinle0301/streaming-platform-api/internal/websocket"
+)
+
+func main() {
+	target := flag.String("url", getEnv("WS_ADMIN_URL", "http://localhost:8081"), "base URL of the WebSocket node")
+	token := flag.String("token", os.Getenv("WS_ADMIN_TOKEN"), "admin API bearer token (WS_ADMIN_TOKEN)")
+	interval := flag.Duration("interval", 2*time.Second, "how often to refresh")
+	redact := flag.String("redact", "all", "personal data hidden in saved snapshots: all, none, or user_ids,ips,metadata")
+	flag.Parse()
+
+	if *token == "" {
+		fmt.Fprintln(os.Stderr, "streamhub-admin: -token or WS_ADMIN_TOKEN is required")
+		os.Exit(2)
+	}
+	if _, err := websocket.ParseSnapshotRedaction(*redact); err != nil {
+		fmt.Fprintf(os.Stderr, "streamhub-admin: -redact: %v\n", err)
+		os.Exit(2)
+	}
+	if *interval < 100*time.Millisecond {
+		*interval = 100 * time.Millisecond
+	}
+
+	program := tea.NewProgram(newModel(newAdminClient(*target, *token), *target, *interval, *redact), tea.WithAltScreen())
+	if _, err := program.Run(); err != nil {
+		log.Fatalf("streamhub-admin failed: %v", err)
+	}
+}
+
+func getEnv(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
@@ -0,0 +1,454 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/tinle0301/streaming-platform-api/internal/websocket"
+)
+
+// Screens of the console
+type screen int
+
+const (
+	screenRooms screen = iota
+	screenClients
+	screenFeatures
+)
+
+// roomLimit is how many of the busiest rooms are listed
+const roomLimit = 200
+
+// requestTimeout bounds each admin API call
+const requestTimeout = 5 * time.Second
+
+var (
+	titleStyle    = lipgloss.NewStyle().Bold(true)
+	selectedStyle = lipgloss.NewStyle().Reverse(true)
+	faintStyle    = lipgloss.NewStyle().Faint(true)
+	errorStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("9"))
+	offStyle      = lipgloss.NewStyle().Foreground(lipgloss.Color("11"))
+)
+
+// tickMsg triggers a refresh
+type tickMsg time.Time
+
+// snapshotMsg is a refreshed status and room list
+type snapshotMsg struct {
+	status websocket.HubStatus
+	rooms  []websocket.RoomSummary
+	at     time.Time
+}
+
+// clientsMsg is the refreshed client list of a room
+type clientsMsg struct {
+	room    string
+	clients []websocket.RoomClient
+}
+
+// actionMsg reports the outcome of a disconnect or feature switch
+type actionMsg struct {
+	text string
+	err  error
+}
+
+// errMsg reports a failed refresh
+type errMsg struct{ err error }
+
+// model is the console's state
+type model struct {
+	client   *adminClient
+	target   string
+	interval time.Duration
+	redact   string
+
+	screen screen
+	cursor int
+	width  int
+	height int
+
+	status   websocket.HubStatus
+	previous *snapshotMsg
+	rates    [2]float64 // sent and received messages per second
+	rooms    []websocket.RoomSummary
+	room     string
+	clients  []websocket.RoomClient
+	loaded   bool
+	updated  time.Time
+
+	// confirm is the client awaiting disconnect confirmation
+	confirm *websocket.RoomClient
+
+	notice string
+	err    error
+}
+
+// newModel creates a console for the node at target; saved snapshots hide
+// the personal data named by redact
+func newModel(client *adminClient, target string, interval time.Duration, redact string) model {
+	return model{client: client, target: target, interval: interval, redact: redact}
+}
+
+// Init starts the first refresh
+func (m model) Init() tea.Cmd {
+	return m.refresh()
+}
+
+// Update handles key presses, refreshes, and action results
+func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width, m.height = msg.Width, msg.Height
+		return m, nil
+
+	case tickMsg:
+		return m, m.refresh()
+
+	case snapshotMsg:
+		if m.previous != nil {
+			if elapsed := msg.at.Sub(m.previous.at).Seconds(); elapsed > 0 {
+				m.rates[0] = float64(msg.status.MessagesSent-m.previous.status.MessagesSent) / elapsed
+				m.rates[1] = float64(msg.status.MessagesReceived-m.previous.status.MessagesReceived) / elapsed
+			}
+		}
+		m.previous = &msg
+		m.status, m.rooms, m.updated, m.loaded, m.err = msg.status, msg.rooms, msg.at, true, nil
+		m.clampCursor()
+		return m, m.tick()
+
+	case clientsMsg:
+		if msg.room == m.room {
+			m.clients = msg.clients
+			m.clampCursor()
+		}
+		return m, nil
+
+	case actionMsg:
+		m.notice, m.err = msg.text, msg.err
+		return m, m.refresh()
+
+	case errMsg:
+		m.err = msg.err
+		return m, m.tick()
+
+	case tea.KeyMsg:
+		return m.handleKey(msg)
+	}
+	return m, nil
+}
+
+// handleKey handles a key press
+func (m model) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	key := msg.String()
+
+	if m.confirm != nil {
+		client := *m.confirm
+		m.confirm = nil
+		if key == "y" {
+			return m, m.disconnect(client)
+		}
+		m.notice = "Disconnect cancelled"
+		return m, nil
+	}
+
+	switch key {
+	case "ctrl+c", "q":
+		return m, tea.Quit
+	case "r":
+		return m, m.refresh()
+	case "s":
+		return m, m.saveSnapshot()
+	case "1":
+		m.screen, m.cursor = screenRooms, 0
+	case "2", "f":
+		m.screen, m.cursor = screenFeatures, 0
+	case "up", "k":
+		if m.cursor > 0 {
+			m.cursor--
+		}
+	case "down", "j":
+		m.cursor++
+		m.clampCursor()
+	case "esc", "backspace":
+		if m.screen == screenClients {
+			m.screen, m.cursor = screenRooms, 0
+		}
+	case "enter", " ":
+		switch {
+		case m.screen == screenRooms && m.cursor < len(m.rooms):
+			m.room, m.clients = m.rooms[m.cursor].Room, nil
+			m.screen, m.cursor = screenClients, 0
+			return m, m.refresh()
+		case m.screen == screenFeatures:
+			if feature := m.featureAt(m.cursor); feature != "" {
+				return m, m.setFeature(feature, !m.status.Features[feature])
+			}
+		}
+	case "d":
+		if m.screen == screenClients && m.cursor < len(m.clients) {
+			client := m.clients[m.cursor]
+			m.confirm = &client
+		}
+	}
+	return m, nil
+}
+
+// clampCursor keeps the cursor on the current list
+func (m *model) clampCursor() {
+	n := 0
+	switch m.screen {
+	case screenRooms:
+		n = len(m.rooms)
+	case screenClients:
+		n = len(m.clients)
+	case screenFeatures:
+		n = len(m.status.Features)
+	}
+	if m.cursor >= n {
+		m.cursor = n - 1
+	}
+	if m.cursor < 0 {
+		m.cursor = 0
+	}
+}
+
+// featureAt returns the i-th feature in name order
+func (m model) featureAt(i int) string {
+	names := sortedKeys(m.status.Features)
+	if i < 0 || i >= len(names) {
+		return ""
+	}
+	return names[i]
+}
+
+// tick schedules the next refresh
+func (m model) tick() tea.Cmd {
+	return tea.Tick(m.interval, func(t time.Time) tea.Msg { return tickMsg(t) })
+}
+
+// refresh fetches the status and rooms, and the clients of the room being
+// inspected
+func (m model) refresh() tea.Cmd {
+	client := m.client
+	cmds := []tea.Cmd{func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+		defer cancel()
+		status, err := client.Status(ctx)
+		if err != nil {
+			return errMsg{err}
+		}
+		rooms, err := client.Rooms(ctx, roomLimit)
+		if err != nil {
+			return errMsg{err}
+		}
+		return snapshotMsg{status: status, rooms: rooms, at: time.Now()}
+	}}
+
+	if m.screen == screenClients {
+		room := m.room
+		cmds = append(cmds, func() tea.Msg {
+			ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+			defer cancel()
+			clients, err := client.RoomClients(ctx, room)
+			if err != nil {
+				return errMsg{err}
+			}
+			return clientsMsg{room: room, clients: clients}
+		})
+	}
+	return tea.Batch(cmds...)
+}
+
+// disconnect closes a client's connection
+func (m model) disconnect(target websocket.RoomClient) tea.Cmd {
+	client := m.client
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+		defer cancel()
+		if err := client.Disconnect(ctx, target.UserID, target.ID); err != nil {
+			return actionMsg{err: err}
+		}
+		return actionMsg{text: fmt.Sprintf("Disconnected %s (%s)", target.UserID, target.ID)}
+	}
+}
+
+// setFeature switches a feature on or off
+func (m model) setFeature(feature string, enabled bool) tea.Cmd {
+	client := m.client
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+		defer cancel()
+		if err := client.SetFeature(ctx, feature, enabled); err != nil {
+			return actionMsg{err: err}
+		}
+		return actionMsg{text: fmt.Sprintf("Switched %s %s", feature, onOff(enabled))}
+	}
+}
+
+// saveSnapshot saves a dump of the node's state to the working directory
+func (m model) saveSnapshot() tea.Cmd {
+	client, redact := m.client, m.redact
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+		defer cancel()
+		snapshot, err := client.Snapshot(ctx, redact)
+		if err != nil {
+			return actionMsg{err: err}
+		}
+		name := "hub-snapshot-" + time.Now().UTC().Format("20060102T150405Z") + ".json"
+		if err := os.WriteFile(name, snapshot, 0o600); err != nil {
+			return actionMsg{err: fmt.Errorf("failed to save snapshot: %w", err)}
+		}
+		return actionMsg{text: fmt.Sprintf("Saved snapshot to %s (redacted: %s)", name, redact)}
+	}
+}
+
+// View renders the console
+func (m model) View() string {
+	var b strings.Builder
+	b.WriteString(titleStyle.Render("streamhub-admin") + "  " + m.target + "\n")
+
+	if !m.loaded {
+		if m.err != nil {
+			b.WriteString(errorStyle.Render("Error: "+m.err.Error()) + "\n")
+		} else {
+			b.WriteString("Connecting...\n")
+		}
+		b.WriteString(faintStyle.Render("q quit") + "\n")
+		return b.String()
+	}
+
+	status := m.status
+	state := "serving"
+	if status.Draining {
+		state = offStyle.Render("draining")
+	}
+	fmt.Fprintf(&b, "%s  connections %d (total %d)  rooms %d  msgs/s out %.1f in %.1f  rate limited %d\n",
+		state, status.ActiveConnections, status.TotalConnections, status.Rooms, m.rates[0], m.rates[1], status.RateLimitedMessages)
+	fmt.Fprintf(&b, "%s\n\n", faintStyle.Render("updated "+m.updated.Format("15:04:05")))
+
+	// Leave room for the header, footer, and column headings
+	visible := m.height - 9
+	if visible < 5 {
+		visible = 20
+	}
+
+	switch m.screen {
+	case screenRooms:
+		b.WriteString(titleStyle.Render("Rooms") + faintStyle.Render("  [1] rooms  [2] features") + "\n")
+		fmt.Fprintf(&b, "  %-48s %8s\n", "ROOM", "CLIENTS")
+		lines := make([]string, 0, len(m.rooms))
+		for _, room := range m.rooms {
+			lines = append(lines, fmt.Sprintf("%-48s %8d", truncate(room.Room, 48), room.Clients))
+		}
+		m.writeList(&b, lines, visible, "no rooms")
+
+	case screenClients:
+		b.WriteString(titleStyle.Render("Room "+m.room) + faintStyle.Render(fmt.Sprintf("  %d clients", len(m.clients))) + "\n")
+		fmt.Fprintf(&b, "  %-24s %-20s %-16s %-18s %9s\n", "USER", "SESSION", "IP", "DEVICE", "CONNECTED")
+		lines := make([]string, 0, len(m.clients))
+		for _, client := range m.clients {
+			user := client.UserID
+			if client.Spectator {
+				user += " (spectator)"
+			}
+			lines = append(lines, fmt.Sprintf("%-24s %-20s %-16s %-18s %9s",
+				truncate(user, 24), truncate(client.ID, 20), truncate(client.RemoteIP, 16), truncate(client.Device, 18),
+				time.Since(client.ConnectedAt).Truncate(time.Second)))
+		}
+		m.writeList(&b, lines, visible, "no clients")
+
+	case screenFeatures:
+		b.WriteString(titleStyle.Render("Features") + faintStyle.Render("  [1] rooms  [2] features  (this node only)") + "\n")
+		names := sortedKeys(status.Features)
+		lines := make([]string, 0, len(names))
+		for _, name := range names {
+			state := onOff(status.Features[name])
+			if !status.Features[name] {
+				state = offStyle.Render(state)
+			}
+			lines = append(lines, fmt.Sprintf("%-24s %s", name, state))
+		}
+		m.writeList(&b, lines, visible, "no features")
+	}
+
+	b.WriteString("\n")
+	switch {
+	case m.confirm != nil:
+		b.WriteString(offStyle.Render(fmt.Sprintf("Disconnect %s (%s)? y/n", m.confirm.UserID, m.confirm.ID)) + "\n")
+	case m.err != nil:
+		b.WriteString(errorStyle.Render("Error: "+m.err.Error()) + "\n")
+	case m.notice != "":
+		b.WriteString(m.notice + "\n")
+	default:
+		b.WriteString("\n")
+	}
+	b.WriteString(faintStyle.Render(m.help()) + "\n")
+	return b.String()
+}
+
+// writeList renders lines around the cursor, at most visible of them
+func (m model) writeList(b *strings.Builder, lines []string, visible int, empty string) {
+	if len(lines) == 0 {
+		b.WriteString(faintStyle.Render("  "+empty) + "\n")
+		return
+	}
+	start := 0
+	if m.cursor >= visible {
+		start = m.cursor - visible + 1
+	}
+	for i := start; i < len(lines) && i < start+visible; i++ {
+		if i == m.cursor {
+			b.WriteString("> " + selectedStyle.Render(lines[i]) + "\n")
+		} else {
+			b.WriteString("  " + lines[i] + "\n")
+		}
+	}
+	if len(lines) > start+visible {
+		b.WriteString(faintStyle.Render(fmt.Sprintf("  ... %d more", len(lines)-start-visible)) + "\n")
+	}
+}
+
+// help lists the keys of the current screen
+func (m model) help() string {
+	switch m.screen {
+	case screenClients:
+		return "↑/↓ move  d disconnect  esc back  s snapshot  r refresh  q quit"
+	case screenFeatures:
+		return "↑/↓ move  enter toggle  1 rooms  s snapshot  r refresh  q quit"
+	default:
+		return "↑/↓ move  enter inspect  2 features  s snapshot  r refresh  q quit"
+	}
+}
+
+// sortedKeys returns a map's keys in order
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// truncate shortens s to n characters
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n-1] + "…"
+}
+
+// onOff names a switch state
+func onOff(enabled bool) string {
+	if enabled {
+		return "on"
+	}
+	return "off"
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+	"github.com/redis/go-redis/v9"
+	schema "github.com/tinle0301/streaming-platform-api/api/graphql"
+	"github.com/tinle0301/streaming-platform-api/internal/permissions"
+	"github.com/tinle0301/streaming-platform-api/internal/retention"
+	"github.com/tinle0301/streaming-platform-api/internal/seed"
+	"github.com/tinle0301/streaming-platform-api/internal/store"
+	"github.com/tinle0301/streaming-platform-api/internal/websocket"
+)
+
+const usage = `streamhub - StreamHub operations tool
+
+Usage:
+  streamhub <command> [flags]
+
+Commands:
+  archive-restore     Rehydrate an archived time range into a queryable table
+  asyncapi-export     Write the WebSocket API's AsyncAPI document as JSON
+  permissions-export  Write the role × operation × resource permission matrix as JSON
+  seed                Fill a development database with realistic fake content
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "archive-restore":
+		err = runArchiveRestore(os.Args[2:])
+	case "asyncapi-export":
+		err = runAsyncAPIExport(os.Args[2:])
+	case "permissions-export":
+		err = runPermissionsExport(os.Args[2:])
+	case "seed":
+		err = runSeed(os.Args[2:])
+	case "help", "-h", "--help":
+		fmt.Print(usage)
+		return
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command: %s\n\n%s", os.Args[1], usage)
+		os.Exit(2)
+	}
+
+	if err != nil {
+		log.Fatalf("%s failed: %v", os.Args[1], err)
+	}
+}
+
+// runArchiveRestore implements the archive-restore command
+func runArchiveRestore(args []string) error {
+	flags := flag.NewFlagSet("archive-restore", flag.ExitOnError)
+	table := flags.String("table", "", "source table that was archived (e.g. chat_messages)")
+	target := flags.String("into", "", "target table to restore into (defaults to <table>_restored)")
+	column := flags.String("column", "created_at", "timestamp column used for archiving")
+	from := flags.String("from", "", "start of range (RFC3339)")
+	to := flags.String("to", "", "end of range (RFC3339)")
+	bucket := flags.String("bucket", getEnv("ARCHIVE_BUCKET", ""), "S3 bucket holding archives")
+	region := flags.String("region", getEnv("AWS_REGION", "us-east-1"), "S3 bucket region")
+	dir := flags.String("dir", "", "local archive directory (instead of S3)")
+	flags.Parse(args)
+
+	if *table == "" || *from == "" || *to == "" {
+		flags.Usage()
+		return fmt.Errorf("-table, -from and -to are required")
+	}
+	if *target == "" {
+		*target = *table + "_restored"
+	}
+
+	fromTime, err := time.Parse(time.RFC3339, *from)
+	if err != nil {
+		return fmt.Errorf("invalid -from: %w", err)
+	}
+	toTime, err := time.Parse(time.RFC3339, *to)
+	if err != nil {
+		return fmt.Errorf("invalid -to: %w", err)
+	}
+
+	var store retention.ObjectStore
+	switch {
+	case *dir != "":
+		store = retention.NewDirStore(*dir)
+	case *bucket != "":
+		store = retention.NewS3Store(*bucket, *region)
+	default:
+		return fmt.Errorf("either -bucket or -dir is required")
+	}
+
+	db, err := sql.Open("pgx", getEnv("DATABASE_URL", "postgresql://localhost:5432/streamhub"))
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+
+	// The target table mirrors the source table's columns
+	createTarget := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (LIKE %s INCLUDING ALL)", *target, *table)
+	if _, err := db.ExecContext(ctx, createTarget); err != nil {
+		return fmt.Errorf("failed to create %s: %w", *target, err)
+	}
+
+	restored, err := retention.NewRestorer(db, store).Restore(ctx, *table, *target, *column, fromTime, toTime)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Restored %d rows into %s", restored, *target)
+	return nil
+}
+
+// runAsyncAPIExport implements the asyncapi-export command
+func runAsyncAPIExport(args []string) error {
+	flags := flag.NewFlagSet("asyncapi-export", flag.ExitOnError)
+	output := flags.String("o", "", "file to write (defaults to stdout)")
+	serverURL := flags.String("server", "", "public WebSocket URL to list, e.g. wss://ws.example.com/ws")
+	flags.Parse(args)
+
+	info := websocket.DefaultAsyncAPIInfo()
+	info.ServerURL = *serverURL
+	return writeJSON(*output, websocket.AsyncAPI(info))
+}
+
+// runPermissionsExport implements the permissions-export command
+func runPermissionsExport(args []string) error {
+	flags := flag.NewFlagSet("permissions-export", flag.ExitOnError)
+	output := flags.String("o", "", "file to write (defaults to stdout)")
+	flags.Parse(args)
+
+	matrix, err := permissions.Parse(schema.Schema)
+	if err != nil {
+		return err
+	}
+
+	if err := writeJSON(*output, matrix); err != nil {
+		return err
+	}
+	if *output != "" {
+		log.Printf("Wrote %d permissions to %s", len(matrix.Permissions), *output)
+	}
+	return nil
+}
+
+// runSeed implements the seed command
+func runSeed(args []string) error {
+	defaults := seed.DefaultOptions()
+	flags := flag.NewFlagSet("seed", flag.ExitOnError)
+	var opts seed.Options
+	flags.IntVar(&opts.Channels, "channels", defaults.Channels, "channels that stream")
+	flags.IntVar(&opts.Live, "live", defaults.Live, "channels live right now")
+	flags.IntVar(&opts.Viewers, "viewers", defaults.Viewers, "users who only watch")
+	flags.IntVar(&opts.FollowsPerViewer, "follows", defaults.FollowsPerViewer, "channels each viewer follows on average")
+	flags.IntVar(&opts.Days, "days", defaults.Days, "days of past broadcasts and analytics")
+	flags.IntVar(&opts.ChatPerLiveStream, "chat", defaults.ChatPerLiveStream, "chat history messages per live stream")
+	flags.IntVar(&opts.Premieres, "premieres", defaults.Premieres, "upcoming premieres")
+	flags.Int64Var(&opts.Seed, "seed", defaults.Seed, "random seed; the same seed generates the same content")
+	flags.StringVar(&opts.Tenant, "tenant", defaults.Tenant, "tenant owning the seeded content")
+	reset := flags.Bool("reset", false, "delete earlier seeded content first")
+	flags.Parse(args)
+
+	data, err := seed.Generate(opts, time.Now())
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	pool, err := store.NewPool(ctx, getEnv("DATABASE_URL", "postgresql://localhost:5432/streamhub"), store.DefaultPoolConfig())
+	if err != nil {
+		return err
+	}
+	defer pool.Close()
+
+	redisOpts, err := redis.ParseURL(getEnv("REDIS_URL", "redis://localhost:6379"))
+	if err != nil {
+		return fmt.Errorf("invalid REDIS_URL: %w", err)
+	}
+	redisClient := redis.NewClient(redisOpts)
+	defer redisClient.Close()
+
+	if *reset {
+		deleted, err := seed.Reset(ctx, pool)
+		if err != nil {
+			return err
+		}
+		log.Printf("Deleted %d seeded users", deleted)
+	}
+
+	summary, err := seed.Write(ctx, pool, redisClient, data)
+	if err != nil {
+		return err
+	}
+	log.Printf("Seeded users can sign in as <username>@%s with password %q", seed.EmailDomain, seed.Password)
+	return writeJSON("", summary)
+}
+
+// writeJSON writes v as indented JSON to a file, or to stdout without one
+func writeJSON(path string, v interface{}) error {
+	out := os.Stdout
+	if path != "" {
+		file, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		out = file
+	}
+
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(v); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
+
+func getEnv(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
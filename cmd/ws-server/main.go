@@ -2,79 +2,437 @@ package main
 
 import (
 	"context"
-	"log"
+	"encoding/json"
+	"errors"
+	"log/slog"
 	"net/http"
+	"net/netip"
 	"os"
 	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
 	"syscall"
 	"time"
 
 	gorillaWS "github.com/gorilla/websocket"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/redis/go-redis/v9"
+	"github.com/tinle0301/streaming-platform-api/internal/audit"
+	"github.com/tinle0301/streaming-platform-api/internal/chatactivity"
+	"github.com/tinle0301/streaming-platform-api/internal/chathistory"
+	"github.com/tinle0301/streaming-platform-api/internal/cluster"
+	"github.com/tinle0301/streaming-platform-api/internal/communitychat"
+	"github.com/tinle0301/streaming-platform-api/internal/compliance"
+	"github.com/tinle0301/streaming-platform-api/internal/config"
+	"github.com/tinle0301/streaming-platform-api/internal/directmessages"
+	"github.com/tinle0301/streaming-platform-api/internal/events"
+	"github.com/tinle0301/streaming-platform-api/internal/health"
+	"github.com/tinle0301/streaming-platform-api/internal/httpmiddleware"
+	"github.com/tinle0301/streaming-platform-api/internal/logging"
+	"github.com/tinle0301/streaming-platform-api/internal/metering"
+	"github.com/tinle0301/streaming-platform-api/internal/moderation"
+	"github.com/tinle0301/streaming-platform-api/internal/parties"
+	"github.com/tinle0301/streaming-platform-api/internal/presence"
+	"github.com/tinle0301/streaming-platform-api/internal/ratelimit"
+	"github.com/tinle0301/streaming-platform-api/internal/rewards"
+	"github.com/tinle0301/streaming-platform-api/internal/startup"
+	"github.com/tinle0301/streaming-platform-api/internal/store"
+	"github.com/tinle0301/streaming-platform-api/internal/subscriptions"
+	"github.com/tinle0301/streaming-platform-api/internal/tenancy"
+	"github.com/tinle0301/streaming-platform-api/internal/tracing"
+	"github.com/tinle0301/streaming-platform-api/internal/users"
 	"github.com/tinle0301/streaming-platform-api/internal/websocket"
 )
 
 const (
-	defaultWSPort = "8081"
+	// How often this node saves per-room delivery stats to Redis
+	deliveryStatsInterval = 15 * time.Second
+
+	// How often this node meters tenants' connection-minutes and messages
+	usageMeterInterval = time.Minute
+
+	// How often this node reloads tenants' quotas and shares its
+	// connections per tenant
+	tenantQuotaInterval = 10 * time.Second
+
+	// How often this node reloads subscriber badges of rooms with chat
+	subscriberBadgeInterval = 10 * time.Second
+
+	// How often this node reloads shadow bans made on other nodes
+	shadowBanInterval = 5 * time.Second
+
+	// How often this node reloads channel roles and its rooms' streamers
+	channelRoleInterval = 5 * time.Second
+
+	// Upgrade admission: steady rate, burst, and how many may queue
+	upgradeRate      = 500
+	upgradeBurst     = 1000
+	upgradeQueueSize = 5000
+	upgradeMaxWait   = 5 * time.Second
+
+	// Room state sections are cached per room for roomStateTTL; presence
+	// lists at most maxPresenceUsers signed-in viewers
+	roomStateTTL     = 2 * time.Second
+	maxPresenceUsers = 100
 )
 
+// fanOutEventTypes are the domain events relayed to WebSocket clients
+var fanOutEventTypes = []string{"stream.*", "chat.*", "raid.*", "user.*", "subscription.*", "bits.*", "moderation.*", "automod.*", "notification.*", "caption.*", "party.*", "premiere.*", "community.*", "direct.*", "goal.*"}
+
+var upgradeLimiter = websocket.NewUpgradeLimiter(upgradeRate, upgradeBurst, upgradeQueueSize, upgradeMaxWait)
+
+// upgrader's CheckOrigin is set from ALLOWED_ORIGINS at startup
 var upgrader = gorillaWS.Upgrader{
 	ReadBufferSize:  1024,
 	WriteBufferSize: 1024,
-	CheckOrigin: func(r *http.Request) bool {
-		// TODO: Implement proper origin checking in production
-		return true
-	},
 }
 
 func main() {
-	log.Println("Starting StreamHub WebSocket Server...")
+	// Configuration is loaded first so logging follows it too; invalid
+	// settings stop startup once logging can report them
+	cfg, err := config.Load(context.Background(), config.ServiceWS)
+	logger := logging.Setup(cfg.Logging, os.Stderr)
+	if err != nil {
+		fatal("Invalid configuration", "err", err)
+	}
+	slog.Info("Starting StreamHub WebSocket Server", "environment", cfg.Environment, "config_file", cfg.File)
+	slog.Debug("Configuration loaded", "config", cfg.Redacted())
+
+	// Spans are exported when an OTLP endpoint is configured
+	tracer := tracing.Setup(cfg.Tracing)
+
+	// Browsers may only connect from allowed origins
+	origins := httpmiddleware.NewOriginPolicy(cfg.AllowedOrigins)
+	upgrader.CheckOrigin = origins.CheckOrigin
+	spectatorUpgrader.CheckOrigin = origins.CheckOrigin
+
+	// Wait for Redis/RabbitMQ so a slow broker doesn't disable handoff or fan-out
+	deps := []startup.Dependency{
+		{Name: "postgres", Required: cfg.Environment == "production", Check: startup.PostgresCheck(cfg.API.DatabaseURL)},
+		{Name: "redis", Check: startup.RedisCheck(cfg.RedisURL)},
+	}
+	if cfg.RabbitMQURL != "" {
+		deps = append(deps, startup.Dependency{Name: "rabbitmq", Check: startup.RabbitMQCheck(cfg.RabbitMQURL)})
+	}
+	if err := startup.WaitFor(context.Background(), cfg.DependencyWait, deps...); err != nil {
+		fatal("Startup aborted", "err", err)
+	}
 
 	// Create WebSocket hub
 	hub := websocket.NewHub()
 
+	// /ready probes the hub and each backend that was reachable at startup
+	readiness := health.NewChecks()
+	readiness.Register("hub", hub.Ready)
+
+	// /health reports a stuck hub event loop and a lost event subscription,
+	// and how long the hub and event dispatch take. Draining is healthy.
+	latencies := health.NewTracker("ws-server")
+	latencies.AddStatus("hub", func(ctx context.Context) error {
+		if err := hub.Ready(ctx); err != nil && !errors.Is(err, websocket.ErrHubDraining) {
+			return err
+		}
+		return nil
+	})
+
+	// Moderation changes are audited under the signed-in actor
+	auditLog := audit.NewStdLogger()
+
+	// Bots registered here must HMAC-sign their inbound messages
+	botKeys := websocket.NewStaticBotKeys()
+	for userID, key := range cfg.WS.BotKeys {
+		botKeys.Register(userID, key)
+	}
+	hub.SetBotKeyStore(botKeys)
+
+	// Viewer count updates slow down as rooms grow
+	hub.SetViewerCountPolicy(cfg.WS.ViewerCount)
+
+	// Per-room metric labels are bounded to the top rooms
+	hub.SetCardinality(cfg.WS.Cardinality)
+
+	// Mega rooms sample chat for viewers; streamers and moderators keep the firehose
+	hub.SetChatSamplingPolicy(cfg.WS.ChatSampling)
+
+	// Access tokens from the API server authenticate connections and are
+	// refreshed in place with auth_refresh before they expire
+	// Guest tokens let anonymous viewers keep their identity across reconnects
+	tokens := users.NewTokenIssuer(cfg.JWTSecret, cfg.WS.GuestTokenTTL)
+	hub.SetTokenVerifier(tokenVerifier(tokens))
+	hub.SetGuestTokenIssuer(websocket.GuestTokenIssuerFunc(tokens.IssueGuest))
+
+	// Anonymous viewers may only watch a few rooms and cannot chat
+	hub.SetGuestPolicy(cfg.WS.GuestPolicy)
+
+	// Token-bucket limits on inbound messages per connection and per IP
+	hub.SetMessageRateLimits(cfg.WS.RateLimits)
+
+	// Large frames are compressed for clients that negotiate permessage-deflate
+	hub.SetCompression(cfg.WS.Compression)
+
+	// Users may hold a few connections at once, e.g. one per device
+	hub.SetConnectionLimitPolicy(cfg.WS.ConnectionLimit)
+	hub.SetDrainOptions(cfg.WS.Drain)
+
 	// Start hub in background
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
+	// A JWT_SECRET rotated in the secrets provider is picked up without a
+	// restart; tokens signed with the old one stay valid until they expire
+	if jwtSecret, err := config.RotatingSecretFromEnv(ctx, "JWT_SECRET"); err == nil {
+		jwtSecret.OnRotate(tokens.SetSecret)
+		go jwtSecret.Watch(ctx, cfg.SecretRotationInterval)
+	} else if !errors.Is(err, config.ErrSecretNotFound) {
+		slog.Warn("JWT_SECRET rotation disabled", "err", err)
+	}
+
 	go hub.Run(ctx)
 
+	// Watch time and channel points accrue only for attentive viewers, to
+	// balances in Postgres shared by every node
+	pool, err := store.NewPool(ctx, cfg.API.DatabaseURL, cfg.API.DatabasePool)
+	if err != nil {
+		fatal("Invalid database URL", "err", err)
+	}
+	defer pool.Close()
+	readiness.Register("postgres", pool.Ping)
+	ledger := rewards.NewPostgresLedger(pool)
+
+	// Shadow bans hide a user's chat from everyone but themselves; they are
+	// kept in Postgres and reloaded by every node
+	shadowBans := moderation.NewShadowBanList(pool, auditLog)
+	hub.SetShadowBanChecker(shadowBans)
+	go shadowBans.Run(ctx, shadowBanInterval)
+
+	// Streams' owners are their rooms' streamers; moderators are granted
+	// per channel or room and kept in Postgres. They keep the full chat in
+	// sampled rooms, use stream manager mode, and manage shadow bans.
+	roles := moderation.NewChannelRoles(pool)
+	hub.SetRoomRoleChecker(roles)
+	hub.SetStreamerChecker(roles)
+	go roles.Run(ctx, channelRoleInterval)
+
+	// Restricted content's rooms are closed to other regions, and mature
+	// streams' rooms to viewers who fail the age gate
+	ageGate := compliance.NewAgeGate(compliance.NewPostgresProfiles(pool), cfg.MatureCategories...)
+	locator, err := compliance.NewCIDRGeoLocator(cfg.GeoIPRanges)
+	if err != nil {
+		fatal("Invalid geo IP ranges", "err", err)
+	}
+	restrictions := compliance.NewGeoRestrictions(locator, compliance.NewPostgresRestrictions(pool), nil)
+	streams := store.NewPostgresStreamRepository(pool)
+	hub.SetRoomAuthorizer(compliance.NewRoomGate(ageGate, restrictions, streams))
+
+	// Joiners of a stream's room get its title, category, and status in
+	// room_state
+	hub.AddRoomStateSection(websocket.RoomStateStream, streamRoomState(streams), roomStateTTL)
+
+	var watchSink websocket.WatchTimeSink = ledger
+	var eventPublisher events.Publisher
+	if publisher, err := newEventPublisher(cfg); err != nil {
+		slog.Warn("Watch progress events disabled", "err", err)
+	} else {
+		// Reward campaigns on the API server accrue from published watch time
+		defer publisher.Close()
+		watchSink = rewards.NewWatchPublisher(ledger, publisher)
+		eventPublisher = tenancy.NewPublisher(publisher)
+		if checker, ok := publisher.(health.Checker); ok {
+			readiness.Register("event-publisher", checker.Ready)
+		}
+	}
+	watchTime := websocket.NewWatchTimeTracker(hub, watchSink, cfg.WS.WatchChallengeInterval, websocket.DefaultWatchTimePolicy())
+	go watchTime.Run(ctx)
+
+	// Node registry and resume store enable connection handoff on deploys
+	var registry *cluster.Registry
+	var resumeStore websocket.ResumeStore
+	if redisClient, err := newRedisClient(cfg.RedisURL); err != nil {
+		slog.Warn("Connection handoff disabled", "err", err)
+	} else {
+		defer redisClient.Close()
+		readiness.Register("redis", health.RedisCheck(redisClient))
+
+		nodeID := cfg.WS.NodeID
+		registry = cluster.NewRegistry(redisClient, nodeID, cfg.WS.PublicURL, hub.GetTotalClients)
+		resumeStore = cluster.NewRedisResumeStore(redisClient)
+
+		// Dropped connections keep their rooms and missed messages for a
+		// while, so clients can resume on any node
+		if cfg.WS.Sessions.Window > 0 {
+			hub.SetSessions(cluster.NewRedisSessionStore(redisClient), cfg.WS.Sessions)
+		}
+
+		registryCtx, stopRegistry := context.WithCancel(context.Background())
+		defer stopRegistry()
+		go registry.Run(registryCtx)
+
+		// Creators see delivery quality summed across nodes in StreamAnalytics
+		go hub.ReportDeliveryStats(ctx, cluster.NewRedisDeliveryStats(redisClient), deliveryStatsInterval)
+
+		// Presence lists who is watching each stream across nodes and
+		// drives cluster-wide viewer_count broadcasts
+		tracker := presence.NewTracker(redisClient, cfg.WS.Presence)
+		hub.SetRoomObserver(tracker)
+		hub.SetViewerCountSource(tracker)
+		hub.AddRoomStateSection(websocket.RoomStatePresence, presenceRoomState(presence.NewStore(redisClient)), roomStateTTL)
+		go tracker.Run(ctx)
+
+		// Chat activity over time feeds highlight compilation
+		chatActivity := chatactivity.NewRecorder(redisClient)
+		hub.SetChatObserver(chatActivity)
+		go chatActivity.Run(ctx)
+
+		// Each room's latest chat is kept for viewers who join later
+		chatHistory := chathistory.NewRecorder(redisClient)
+		hub.SetChatHistory(chatHistory)
+		go chatHistory.Run(ctx)
+
+		// Chat messages carry their senders' subscriber tiers for badges
+		subscriberBadges := subscriptions.NewBadgeCache(redisClient)
+		hub.SetSubscriberBadges(subscriberBadges)
+		go subscriberBadges.Run(ctx, subscriberBadgeInterval)
+
+		// White-label tenants are billed for their viewers' connections
+		// and the messages delivered to them
+		if cfg.UsageMetering {
+			meter := metering.NewMeter(redisClient)
+			go hub.MeterUsage(ctx, meter, usageMeterInterval)
+			go meter.Run(ctx)
+		}
+
+		// Tenants' quotas are set on the API server; connections are
+		// counted across nodes and events published for a tenant's
+		// clients count against its events/sec
+		if cfg.MultiTenant {
+			quotas := ratelimit.NewQuotas(ratelimit.QuotaTable{})
+			go quotas.Follow(ctx, ratelimit.NewQuotaStore(redisClient), tenantQuotaInterval)
+			enforcer := ratelimit.NewEnforcer(redisClient, quotas, nodeID)
+			hub.SetTenantQuotas(enforcer)
+			go hub.ReportTenantConnections(ctx, tenantQuotaInterval)
+			if eventPublisher != nil {
+				eventPublisher = ratelimit.NewPublisher(eventPublisher, enforcer)
+			}
+		}
+
+		// Watch party hosts control their party's playback; party chat is
+		// relayed across nodes and stored through published events
+		hub.SetWatchParties(parties.NewRegistry(redisClient), eventPublisher)
+
+		// Channels' community rooms are opened and closed by the API server
+		hub.SetCommunityChat(communitychat.NewRegistry(redisClient))
+
+		// Direct messages are checked by the API server, which notifies
+		// recipients who aren't connected to any node
+		if eventPublisher != nil {
+			online := directmessages.NewOnlineTracker(redisClient, directmessages.DefaultOnlineOptions())
+			hub.SetDirectMessages(eventPublisher, online)
+			go online.Run(ctx)
+		}
+	}
+
+	// Fan domain events out to connected clients
+	if subscriber, err := newEventSubscriber(cfg); err != nil {
+		slog.Warn("Event fan-out disabled", "err", err)
+	} else {
+		defer subscriber.Close()
+		if checker, ok := subscriber.(health.Checker); ok {
+			readiness.Register("event-subscriber", checker.Ready)
+		}
+		subscriptionEnded := make(chan struct{})
+		var subscriptionErr error
+		go func() {
+			defer close(subscriptionEnded)
+			subscriptionErr = subscriber.Subscribe(ctx, fanOutEventTypes, func(ctx context.Context, event events.Event) error {
+				start := time.Now()
+				err := hub.DispatchEvent(ctx, event)
+				latencies.Observe("event-dispatch", time.Since(start))
+				return err
+			})
+			if subscriptionErr != nil {
+				slog.Error("Event subscription ended", "err", subscriptionErr)
+			}
+		}()
+		latencies.AddStatus("event-subscriber", func(ctx context.Context) error {
+			select {
+			case <-subscriptionEnded:
+				if subscriptionErr != nil {
+					return subscriptionErr
+				}
+				return errors.New("event subscription ended")
+			default:
+				return nil
+			}
+		})
+	}
+
 	// Setup HTTP server
 	mux := http.NewServeMux()
 
 	// WebSocket endpoint
 	mux.HandleFunc("/ws", func(w http.ResponseWriter, r *http.Request) {
-		serveWs(hub, w, r)
+		serveWs(hub, resumeStore, w, r)
 	})
 
-	// Health check endpoint
-	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
-		w.WriteHeader(http.StatusOK)
-		w.Write([]byte(`{"status":"healthy"}`))
+	// Message reference for client teams, generated from the message
+	// registry; ASYNCAPI_SERVER_URL is the load-balanced endpoint it names
+	asyncAPIInfo := websocket.DefaultAsyncAPIInfo()
+	asyncAPIInfo.ServerURL = cfg.WS.AsyncAPIServerURL
+	mux.HandleFunc("/asyncapi.json", websocket.AsyncAPIHandler(asyncAPIInfo))
+
+	// A user's own connections, labelled by device
+	mux.HandleFunc("/sessions", func(w http.ResponseWriter, r *http.Request) {
+		sessionsHandler(hub, w, r)
 	})
 
+	// Shadow ban administration for channel moderators and platform admins
+	admins := make(map[string]bool, len(cfg.AdminUserIDs))
+	for _, id := range cfg.AdminUserIDs {
+		admins[id] = true
+	}
+	mux.Handle("/admin/shadowbans", users.RequireUser(tokens, nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		shadowBanHandler(shadowBans, roles, admins, w, r)
+	})))
+
+	// Streamer and moderator roles, granted by platform admins
+	mux.Handle("/admin/roles", users.RequireAdmin(tokens, cfg.AdminUserIDs)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rolesHandler(roles, auditLog, w, r)
+	})))
+
+	// Hub operations API for streamhub-admin
+	if cfg.WS.AdminToken != "" {
+		admin := websocket.AdminHandler(hub, cfg.WS.AdminToken)
+		mux.Handle(websocket.AdminPath, admin)
+		mux.Handle(websocket.AdminPath+"/", admin)
+	}
+
+	// The signed-in viewer's channel point balances
+	mux.Handle("/points", users.RequireUser(tokens, nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		pointsHandler(ledger, w, r)
+	})))
+
+	// Liveness and readiness
+	mux.HandleFunc("/health", latencies.Handler())
+	mux.HandleFunc("/ready", readiness.Handler())
+
 	// Metrics endpoint
-	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
-		metrics := hub.GetMetrics()
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
-		// TODO: Format metrics properly
-		log.Printf("Current metrics: %+v", metrics)
-	})
+	prometheus.MustRegister(hub.Collector())
+	mux.Handle("/metrics", promhttp.Handler())
 
-	port := getEnv("WS_PORT", defaultWSPort)
 	server := &http.Server{
-		Addr:         ":" + port,
-		Handler:      mux,
+		Addr:         ":" + cfg.WS.Port,
+		Handler:      httpmiddleware.Stack(logger, stackOptions(cfg)).Then(mux),
 		ReadTimeout:  15 * time.Second,
 		WriteTimeout: 15 * time.Second,
 	}
 
 	// Start server
 	go func() {
-		log.Printf("🔌 WebSocket Server listening on port %s", port)
-		log.Printf("   Connect at: ws://localhost:%s/ws", port)
+		slog.Info("WebSocket Server listening", "port", cfg.WS.Port, "path", "/ws")
 		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("Failed to start WebSocket server: %v", err)
+			fatal("Failed to start WebSocket server", "err", err)
 		}
 	}()
 
@@ -83,54 +441,588 @@ func main() {
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 
-	log.Println("Shutting down WebSocket server...")
+	slog.Info("Shutting down WebSocket server")
+
+	// Hand clients off to the remaining nodes before closing connections
+	if registry != nil {
+		handoff(hub, registry, resumeStore, cfg.WS.HandoffWindow)
+	}
 
-	// Cancel hub context
+	// Cancel hub context and wait for it to drain its connections
 	cancel()
 
-	// Shutdown HTTP server
 	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer shutdownCancel()
 
+	select {
+	case <-hub.Done():
+	case <-shutdownCtx.Done():
+		slog.Warn("Hub drain did not finish before the shutdown timeout")
+	}
+
+	// Shutdown HTTP server
+
 	if err := server.Shutdown(shutdownCtx); err != nil {
-		log.Printf("WebSocket server forced to shutdown: %v", err)
+		slog.Error("WebSocket server forced to shutdown", "err", err)
+	}
+	if err := tracer.Shutdown(shutdownCtx); err != nil {
+		slog.Error("Error flushing spans", "err", err)
 	}
 
-	log.Println("WebSocket server exited")
+	slog.Info("WebSocket server exited")
+}
+
+// handoff advertises this node as draining and tells connected clients
+// which peer to reconnect to, staggered over window
+func handoff(hub *websocket.Hub, registry *cluster.Registry, resumeStore websocket.ResumeStore, window time.Duration) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	registry.MarkDraining(ctx)
+
+	peers, err := registry.Peers(ctx)
+	if err != nil {
+		slog.Error("Error listing peers for handoff", "err", err)
+	}
+	targets := make([]string, 0, len(peers))
+	for _, peer := range peers {
+		targets = append(targets, peer.PublicURL)
+	}
+
+	if hub.Handoff(ctx, resumeStore, targets, window) > 0 {
+		// Give clients their staggered window to reconnect elsewhere
+		time.Sleep(window)
+	}
+}
+
+// spectatorUpgrader uses small read buffers and pools write buffers, since
+// spectators never send data and receive comparatively little
+var spectatorUpgrader = gorillaWS.Upgrader{
+	ReadBufferSize:  256,
+	WriteBufferSize: 1024,
+	WriteBufferPool: &sync.Pool{},
 }
 
 // serveWs handles websocket requests from clients
-func serveWs(hub *websocket.Hub, w http.ResponseWriter, r *http.Request) {
-	// Extract user ID from query params or JWT token
-	userID := r.URL.Query().Get("user_id")
-	if userID == "" {
-		// TODO: Extract from JWT token in production
-		userID = "anonymous"
+func serveWs(hub *websocket.Hub, resumeStore websocket.ResumeStore, w http.ResponseWriter, r *http.Request) {
+	// Authenticated clients present an access token; browsers cannot set
+	// headers on WebSocket requests, so it may also come as a query param
+	var claims websocket.TokenClaims
+	var guestSession *websocket.GuestSession
+	if token := bearerToken(r); token != "" {
+		verified, err := hub.Authenticate(token)
+		if err != nil {
+			http.Error(w, "Invalid or expired token", http.StatusUnauthorized)
+			return
+		}
+		claims = verified
+	} else if userID := r.URL.Query().Get("user_id"); userID != "" && !hub.TokenAuthEnabled() {
+		// Development only: trust user_id when token authentication is off
+		claims = websocket.TokenClaims{UserID: userID}
+	} else {
+		// Everyone else is a guest until they sign in over the connection,
+		// in the tenant they declare at handshake
+		tenantID := guestTenant(r)
+		if !tenancy.ValidID(tenantID) {
+			http.Error(w, "Invalid tenant", http.StatusBadRequest)
+			return
+		}
+		session, err := hub.NewGuestSession(tenantID)
+		if err != nil {
+			slog.Error("Failed to create guest session", "err", err)
+			http.Error(w, "Failed to create guest session", http.StatusInternalServerError)
+			return
+		}
+		guestSession = session
+		claims = session.Claims
+	}
+	userID := claims.UserID
+
+	if err := hub.AdmitUser(userID); err != nil {
+		http.Error(w, "Too many connections", http.StatusTooManyRequests)
+		return
+	}
+	tenantID := claims.TenantID
+	if tenantID == "" {
+		tenantID = tenancy.Default
+	}
+	if err := hub.AdmitTenant(r.Context(), tenantID); err != nil {
+		http.Error(w, "Tenant connection quota exceeded", http.StatusTooManyRequests)
+		return
+	}
+
+	// A draining node sends new connections to the remaining nodes
+	if hub.Draining() {
+		retryAfter := websocket.ReconnectJitter(time.Second, 10*time.Second)
+		w.Header().Set("Retry-After", strconv.Itoa(int(retryAfter.Seconds())+1))
+		http.Error(w, "Server is shutting down", http.StatusServiceUnavailable)
+		return
+	}
+
+	// Smooth out reconnect storms; overflow is told to retry with jitter
+	if err := upgradeLimiter.Wait(r.Context()); err != nil {
+		retryAfter := websocket.ReconnectJitter(time.Second, 10*time.Second)
+		w.Header().Set("Retry-After", strconv.Itoa(int(retryAfter.Seconds())+1))
+		http.Error(w, "Too many connection attempts", http.StatusServiceUnavailable)
+		return
 	}
 
-	// Upgrade HTTP connection to WebSocket
-	conn, err := upgrader.Upgrade(w, r, nil)
+	// Spectators declare themselves at handshake along with their rooms
+	spectator := r.URL.Query().Get("mode") == "spectator"
+	connUpgrader := &upgrader
+	if spectator {
+		connUpgrader = &spectatorUpgrader
+	}
+
+	// Upgrade HTTP connection to WebSocket, compressed unless the client
+	// opts out with ?compression=off
+	conn, err := hub.Upgrade(connUpgrader, w, r, nil)
 	if err != nil {
-		log.Printf("Failed to upgrade connection: %v", err)
+		slog.Error("Failed to upgrade connection", "err", err)
 		return
 	}
 
 	// Create new client
-	client := websocket.NewClient(hub, conn, userID)
+	var client *websocket.Client
+	if spectator {
+		client = websocket.NewSpectatorClient(hub, conn, userID)
+	} else {
+		client = websocket.NewClient(hub, conn, userID)
+	}
+	client.SetClaims(claims)
+	client.SetRemoteIP(remoteIP(r))
+	client.SetDevice(deviceLabel(r))
 
-	// Register client with hub
-	hub.Register <- client
+	// Register client with hub; a draining hub closes the connection
+	if !hub.AddClient(client) {
+		return
+	}
 
 	// Start client goroutines
 	go client.WritePump()
 	go client.ReadPump()
 
-	log.Printf("New WebSocket connection: userID=%s", userID)
+	if guestSession != nil {
+		client.SendGuestSession(guestSession)
+	}
+
+	if spectator {
+		for _, room := range strings.Split(r.URL.Query().Get("rooms"), ",") {
+			if room = strings.TrimSpace(room); room != "" {
+				client.Subscribe(room)
+			}
+		}
+	}
+
+	// Restore rooms transferred from a draining node
+	if token := r.URL.Query().Get("handoff_token"); token != "" && resumeStore != nil {
+		if err := hub.Resume(r.Context(), resumeStore, client, token); err != nil {
+			logging.FromContext(r.Context()).Warn("Failed to resume handoff", "user_id", userID, "err", err)
+		}
+	}
+
+	// Restore a dropped session's rooms and replay what it missed
+	if token := r.URL.Query().Get("resume_token"); token != "" {
+		if err := hub.ResumeSession(r.Context(), client, token); err != nil {
+			logging.FromContext(r.Context()).Warn("Failed to resume session", "user_id", userID, "err", err)
+		}
+	}
+
+	logging.FromContext(r.Context()).Info("New WebSocket connection", "user_id", userID, "guest", claims.Guest,
+		"spectator", spectator)
+}
+
+// sessionsHandler lists the caller's connections and ends one by ID
+func sessionsHandler(hub *websocket.Hub, w http.ResponseWriter, r *http.Request) {
+	userID := r.URL.Query().Get("user_id")
+	if token := bearerToken(r); token != "" {
+		claims, err := hub.Authenticate(token)
+		if err != nil {
+			http.Error(w, "Invalid or expired token", http.StatusUnauthorized)
+			return
+		}
+		userID = claims.UserID
+	} else if userID == "" || hub.TokenAuthEnabled() {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(hub.Sessions(userID))
+
+	case http.MethodDelete:
+		if !hub.EndSession(userID, r.URL.Query().Get("id")) {
+			http.Error(w, "Not found", http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// deviceLabel returns the device a client declared at handshake, e.g.
+// "iPhone" or "Chrome on Windows"
+func deviceLabel(r *http.Request) string {
+	if label := r.Header.Get("X-Device-Label"); label != "" {
+		return label
+	}
+	return r.URL.Query().Get("device")
+}
+
+// guestTenant returns the tenant a guest declared at handshake with the
+// X-Tenant-ID header or tenant query param. Guest tokens carry it, so only
+// public rooms of that tenant become joinable.
+func guestTenant(r *http.Request) string {
+	if tenantID := r.Header.Get(tenancy.Header); tenantID != "" {
+		return tenantID
+	}
+	if tenantID := r.URL.Query().Get("tenant"); tenantID != "" {
+		return tenantID
+	}
+	return tenancy.Default
+}
+
+// bearerToken returns the access token from the Authorization header or
+// the token query param
+func bearerToken(r *http.Request) string {
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		return token
+	}
+	return r.URL.Query().Get("token")
+}
+
+// tokenVerifier adapts the API server's token issuer to the hub
+func tokenVerifier(tokens *users.TokenIssuer) websocket.TokenVerifier {
+	return websocket.TokenVerifierFunc(func(token string) (websocket.TokenClaims, error) {
+		claims, err := tokens.Verify(token)
+		if err != nil {
+			return websocket.TokenClaims{}, err
+		}
+		return websocket.TokenClaims{
+			UserID:    claims.UserID(),
+			ExpiresAt: claims.ExpiresAt.Time,
+			Guest:     claims.Guest,
+			TenantID:  claims.TenantID(),
+		}, nil
+	})
+}
+
+// shadowBanHandler lists, creates, and lifts shadow bans. An empty
+// channel applies the ban platform-wide. Channel streamers and moderators
+// manage their channel's bans, platform admins any; the signed-in user is
+// the actor recorded in the audit log.
+func shadowBanHandler(bans *moderation.ShadowBanList, roles *moderation.ChannelRoles, admins map[string]bool, w http.ResponseWriter, r *http.Request) {
+	claims, _ := users.ClaimsFromContext(r.Context())
+	actorID := claims.UserID()
+	mayModerate := func(channel string) bool {
+		return admins[actorID] || (channel != moderation.PlatformWide && roles.IsPrivileged(channel, actorID))
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		channel := r.URL.Query().Get("channel")
+		if !mayModerate(channel) {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+		list, err := bans.List(r.Context(), channel)
+		if err != nil {
+			slog.Error("Error listing shadow bans", "channel", channel, "err", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(list)
+
+	case http.MethodPost:
+		var request struct {
+			Channel string `json:"channel"`
+			UserID  string `json:"user_id"`
+			Reason  string `json:"reason"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil || request.UserID == "" {
+			http.Error(w, "Bad request", http.StatusBadRequest)
+			return
+		}
+		if !mayModerate(request.Channel) {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+		if err := bans.Ban(r.Context(), request.Channel, request.UserID, actorID, request.Reason); err != nil {
+			slog.Error("Error shadow banning user", "channel", request.Channel, "user_id", request.UserID, "err", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	case http.MethodDelete:
+		query := r.URL.Query()
+		if query.Get("user_id") == "" {
+			http.Error(w, "Bad request", http.StatusBadRequest)
+			return
+		}
+		if !mayModerate(query.Get("channel")) {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+		removed, err := bans.Unban(r.Context(), query.Get("channel"), query.Get("user_id"), actorID)
+		if err != nil {
+			slog.Error("Error lifting shadow ban", "channel", query.Get("channel"), "user_id", query.Get("user_id"), "err", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		if !removed {
+			http.Error(w, "Not found", http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// rolesHandler lists, grants, and revokes channel roles. A channel is a
+// channel's user ID, granting the role in all its streams' rooms, or a
+// room. Changes are audited under the signed-in admin.
+func rolesHandler(roles *moderation.ChannelRoles, auditLog audit.Logger, w http.ResponseWriter, r *http.Request) {
+	claims, _ := users.ClaimsFromContext(r.Context())
+
+	switch r.Method {
+	case http.MethodGet:
+		list, err := roles.List(r.Context(), r.URL.Query().Get("channel"))
+		if err != nil {
+			slog.Error("Error listing channel roles", "channel", r.URL.Query().Get("channel"), "err", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(list)
+
+	case http.MethodPost:
+		var request struct {
+			Channel string          `json:"channel"`
+			UserID  string          `json:"user_id"`
+			Role    moderation.Role `json:"role"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil || request.Channel == "" || request.UserID == "" {
+			http.Error(w, "Bad request", http.StatusBadRequest)
+			return
+		}
+		if request.Role != moderation.RoleStreamer && request.Role != moderation.RoleModerator {
+			http.Error(w, "Unknown role", http.StatusBadRequest)
+			return
+		}
+		if err := roles.Grant(r.Context(), request.Channel, request.UserID, request.Role); err != nil {
+			slog.Error("Error granting channel role", "channel", request.Channel, "user_id", request.UserID, "err", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		recordRoleChange(r.Context(), auditLog, audit.ActionRoleGrant, claims.UserID(), request.Channel, request.UserID, string(request.Role))
+		w.WriteHeader(http.StatusNoContent)
+
+	case http.MethodDelete:
+		query := r.URL.Query()
+		removed, err := roles.Revoke(r.Context(), query.Get("channel"), query.Get("user_id"))
+		if err != nil {
+			slog.Error("Error revoking channel role", "channel", query.Get("channel"), "user_id", query.Get("user_id"), "err", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		if !removed {
+			http.Error(w, "Not found", http.StatusNotFound)
+			return
+		}
+		recordRoleChange(r.Context(), auditLog, audit.ActionRoleRevoke, claims.UserID(), query.Get("channel"), query.Get("user_id"), "")
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
 }
 
-func getEnv(key, defaultValue string) string {
-	if value := os.Getenv(key); value != "" {
-		return value
+// recordRoleChange writes an audit entry for a granted or revoked role
+func recordRoleChange(ctx context.Context, auditLog audit.Logger, action, actorID, channel, userID, role string) {
+	entry := audit.Entry{
+		Action:   action,
+		ActorID:  actorID,
+		TargetID: userID,
+		Resource: channel,
+	}
+	if role != "" {
+		entry.Metadata = map[string]string{"role": role}
+	}
+	if err := auditLog.Record(ctx, entry); err != nil {
+		slog.Error("Error recording audit entry", "err", err)
+	}
+}
+
+// pointsHandler returns the signed-in viewer's balance in a channel of
+// their tenant:
+//
+//	GET /points?channel=<stream>
+func pointsHandler(ledger *rewards.PostgresLedger, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	claims, _ := users.ClaimsFromContext(r.Context())
+	channel := r.URL.Query().Get("channel")
+	if channel == "" {
+		http.Error(w, "channel is required", http.StatusBadRequest)
+		return
 	}
-	return defaultValue
+
+	balance, err := ledger.Balance(tenancy.WithTenant(r.Context(), claims.TenantID()), claims.UserID(), channel)
+	if err != nil {
+		slog.Error("Error loading channel point balance", "user_id", claims.UserID(), "channel", channel, "err", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(balance)
+}
+
+// newRedisClient connects to Redis and verifies the connection
+func newRedisClient(redisURL string) (*redis.Client, error) {
+	opts, err := redis.ParseURL(redisURL)
+	if err != nil {
+		return nil, err
+	}
+
+	client := redis.NewClient(opts)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, err
+	}
+	return client, nil
+}
+
+// streamRoomState loads the stream section of a stream room's room_state;
+// other rooms have none
+func streamRoomState(streams store.StreamRepository) websocket.StateLoader {
+	return func(ctx context.Context, room string) (map[string]interface{}, error) {
+		tenantID, streamID := tenancy.SplitRoom(room)
+		if !store.IsUUID(streamID) {
+			return nil, nil
+		}
+
+		stream, err := streams.Get(tenancy.WithTenant(ctx, tenantID), streamID)
+		if errors.Is(err, store.ErrNotFound) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{
+			"title":        stream.Title,
+			"category":     stream.Category,
+			"status":       stream.Status,
+			"is_mature":    stream.IsMature,
+			"chat_enabled": stream.ChatEnabled,
+		}, nil
+	}
+}
+
+// presenceRoomState loads the presence section of a room's room_state: how
+// many viewers are in it on every node and up to maxPresenceUsers of the
+// signed-in ones
+func presenceRoomState(viewers *presence.Store) websocket.StateLoader {
+	return func(ctx context.Context, room string) (map[string]interface{}, error) {
+		present, err := viewers.GetViewers(ctx, room)
+		if err != nil {
+			return nil, err
+		}
+		userIDs := present.UserIDs
+		if len(userIDs) > maxPresenceUsers {
+			userIDs = userIDs[:maxPresenceUsers]
+		}
+		return map[string]interface{}{
+			"total":    present.Total(),
+			"guests":   present.Guests,
+			"user_ids": userIDs,
+		}, nil
+	}
+}
+
+// remoteIP returns the client's IP as the middleware stack resolved it
+func remoteIP(r *http.Request) string {
+	return httpmiddleware.ClientIPFromContext(r.Context())
+}
+
+// stackOptions configures the middleware stack. WS_TRUST_FORWARDED_FOR
+// without TRUSTED_PROXIES keeps trusting X-Forwarded-For from any peer.
+func stackOptions(cfg config.Config) httpmiddleware.StackOptions {
+	trusted := cfg.TrustedProxies
+	if len(trusted) == 0 && cfg.WS.TrustForwardedFor {
+		trusted = []netip.Prefix{netip.MustParsePrefix("0.0.0.0/0"), netip.MustParsePrefix("::/0")}
+	}
+	return httpmiddleware.StackOptions{
+		TrustedProxies:    trusted,
+		MaxRequestTimeout: cfg.MaxRequestTimeout,
+	}
+}
+
+// newEventSubscriber consumes events from a Redis stream when EVENT_BACKEND
+// is redis-streams, from Kafka when it is kafka, from NATS JetStream when it
+// is nats, from RabbitMQ when RABBITMQ_URL is set, and otherwise from Redis
+// Pub/Sub. Each node gets its own consumer group, consumer, or queue since
+// every node must relay every event to its own clients.
+func newEventSubscriber(cfg config.Config) (events.Subscriber, error) {
+	nodeID := cfg.WS.NodeID
+	if cfg.EventBackend == events.BackendRedisStreams {
+		opts := events.DefaultRedisStreamsSubscriberOptions("ws-server."+nodeID, nodeID)
+		opts.Durable = false
+		return events.NewRedisStreamsSubscriber(cfg.RedisURL, opts)
+	}
+	if cfg.EventBackend == events.BackendKafka {
+		opts := events.DefaultKafkaSubscriberOptions(cfg.Kafka, "ws-server."+nodeID)
+		opts.Durable = false
+		opts.Rebalance = cfg.KafkaRebalance
+		return events.NewKafkaSubscriber(opts)
+	}
+	if cfg.EventBackend == events.BackendNATS {
+		opts := events.DefaultNATSSubscriberOptions(cfg.NATS, "ws-server."+nodeID)
+		opts.Durable = false
+		return events.NewNATSSubscriber(opts)
+	}
+	if cfg.RabbitMQURL != "" {
+		opts := events.DefaultRabbitMQSubscriberOptions("ws-server." + nodeID)
+		opts.Durable = false
+		return events.NewRabbitMQSubscriber(cfg.RabbitMQURL, opts)
+	}
+	return events.NewRedisSubscriber(cfg.RedisURL)
+}
+
+// newEventPublisher publishes to the same backend newEventSubscriber reads
+func newEventPublisher(cfg config.Config) (events.Publisher, error) {
+	if cfg.EventBackend == events.BackendRedisStreams {
+		opts := events.DefaultRedisStreamsPublisherOptions()
+		opts.MaxLen = cfg.EventStreamMaxLen
+		return events.NewRedisStreamsPublisher(cfg.RedisURL, opts)
+	}
+	if cfg.EventBackend == events.BackendKafka {
+		return events.NewKafkaPublisher(cfg.Kafka)
+	}
+	if cfg.EventBackend == events.BackendNATS {
+		return events.NewNATSPublisher(cfg.NATS)
+	}
+	if cfg.RabbitMQURL != "" {
+		return events.NewRabbitMQPublisher(cfg.RabbitMQURL)
+	}
+	return events.NewRedisPublisher(cfg.RedisURL)
+}
+
+// fatal logs an error and exits
+func fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
 }
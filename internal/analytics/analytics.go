@@ -0,0 +1,187 @@
+// Package analytics aggregates each stream's audience into per-minute
+// buckets: viewer counts sampled from presence, views from presence joins,
+// chat messages from chat activity, and new followers from follow events.
+package analytics
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/tinle0301/streaming-platform-api/internal/store"
+	"github.com/tinle0301/streaming-platform-api/internal/tenancy"
+)
+
+// BucketSize is the width of each stored aggregate
+const BucketSize = time.Minute
+
+// Summary totals a stream's aggregates over a time range
+type Summary struct {
+	Views          int
+	PeakViewers    int
+	AverageViewers float64
+	NewFollowers   int
+	ChatMessages   int
+
+	// WatchTime is the time all viewers spent watching, from the average
+	// viewer count of each minute
+	WatchTime time.Duration
+}
+
+// Point is one step of a stream's analytics time series
+type Point struct {
+	Start          time.Time
+	AverageViewers float64
+	ChatMessages   int
+}
+
+// Repository persists per-minute stream aggregates. Buckets belong to
+// their stream's tenant, and other tenants' are not read.
+type Repository interface {
+	// RecordViewers adds a viewer count sample to the minute's bucket
+	RecordViewers(ctx context.Context, streamID string, minute time.Time, viewers int) error
+
+	// SetActivity stores the minute's final view and chat message counts,
+	// replacing earlier values so collection can be repeated safely
+	SetActivity(ctx context.Context, streamID string, minute time.Time, views, chatMessages int) error
+
+	// AddFollower counts a new follower in the minute's bucket
+	AddFollower(ctx context.Context, streamID string, minute time.Time) error
+
+	// Summary totals the buckets starting in [from, to)
+	Summary(ctx context.Context, streamID string, from, to time.Time) (Summary, error)
+
+	// Series groups the buckets starting in [from, to) into steps
+	Series(ctx context.Context, streamID string, from, to time.Time, step time.Duration) ([]Point, error)
+}
+
+// PostgresRepository implements Repository on PostgreSQL
+type PostgresRepository struct {
+	pool *pgxpool.Pool
+}
+
+// NewPostgresRepository creates an analytics repository on pool
+func NewPostgresRepository(pool *pgxpool.Pool) *PostgresRepository {
+	return &PostgresRepository{pool: pool}
+}
+
+// streamTenant selects the tenant of the stream named by $1. Collection
+// runs for every tenant's streams, so buckets take their stream's tenant
+// rather than the context's.
+const streamTenant = `(SELECT tenant_id FROM streams WHERE id = $1)`
+
+// RecordViewers upserts a viewer sample
+func (r *PostgresRepository) RecordViewers(ctx context.Context, streamID string, minute time.Time, viewers int) error {
+	if !store.IsUUID(streamID) {
+		return nil
+	}
+
+	_, err := r.pool.Exec(ctx, `
+		INSERT INTO stream_analytics (stream_id, bucket_start, peak_viewers, viewer_total, viewer_samples, tenant_id)
+		VALUES ($1, $2, $3, $3, 1, `+streamTenant+`)
+		ON CONFLICT (stream_id, bucket_start) DO UPDATE SET
+			peak_viewers = GREATEST(stream_analytics.peak_viewers, EXCLUDED.peak_viewers),
+			viewer_total = stream_analytics.viewer_total + EXCLUDED.viewer_total,
+			viewer_samples = stream_analytics.viewer_samples + 1`,
+		streamID, minute, viewers)
+	if err != nil {
+		return fmt.Errorf("failed to record viewers: %w", err)
+	}
+	return nil
+}
+
+// SetActivity upserts a minute's views and chat messages
+func (r *PostgresRepository) SetActivity(ctx context.Context, streamID string, minute time.Time, views, chatMessages int) error {
+	if !store.IsUUID(streamID) {
+		return nil
+	}
+
+	_, err := r.pool.Exec(ctx, `
+		INSERT INTO stream_analytics (stream_id, bucket_start, views, chat_messages, tenant_id)
+		VALUES ($1, $2, $3, $4, `+streamTenant+`)
+		ON CONFLICT (stream_id, bucket_start) DO UPDATE SET
+			views = EXCLUDED.views, chat_messages = EXCLUDED.chat_messages`,
+		streamID, minute, views, chatMessages)
+	if err != nil {
+		return fmt.Errorf("failed to set stream activity: %w", err)
+	}
+	return nil
+}
+
+// AddFollower increments a minute's new followers
+func (r *PostgresRepository) AddFollower(ctx context.Context, streamID string, minute time.Time) error {
+	if !store.IsUUID(streamID) {
+		return nil
+	}
+
+	_, err := r.pool.Exec(ctx, `
+		INSERT INTO stream_analytics (stream_id, bucket_start, new_followers, tenant_id)
+		VALUES ($1, $2, 1, `+streamTenant+`)
+		ON CONFLICT (stream_id, bucket_start) DO UPDATE SET
+			new_followers = stream_analytics.new_followers + 1`,
+		streamID, minute)
+	if err != nil {
+		return fmt.Errorf("failed to add follower: %w", err)
+	}
+	return nil
+}
+
+// Summary aggregates a stream's buckets
+func (r *PostgresRepository) Summary(ctx context.Context, streamID string, from, to time.Time) (Summary, error) {
+	var summary Summary
+	if !store.IsUUID(streamID) {
+		return summary, nil
+	}
+
+	var watchMinutes float64
+	err := r.pool.QueryRow(ctx, `
+		SELECT COALESCE(SUM(views), 0), COALESCE(MAX(peak_viewers), 0),
+			COALESCE(AVG(viewer_total::float8 / NULLIF(viewer_samples, 0)), 0),
+			COALESCE(SUM(viewer_total::float8 / NULLIF(viewer_samples, 0)), 0),
+			COALESCE(SUM(new_followers), 0), COALESCE(SUM(chat_messages), 0)
+		FROM stream_analytics
+		WHERE stream_id = $1 AND bucket_start >= $2 AND bucket_start < $3`+tenancy.Condition("tenant_id", 4),
+		streamID, from, to, tenancy.Scope(ctx),
+	).Scan(&summary.Views, &summary.PeakViewers, &summary.AverageViewers, &watchMinutes,
+		&summary.NewFollowers, &summary.ChatMessages)
+	if err != nil {
+		return summary, fmt.Errorf("failed to summarize stream analytics: %w", err)
+	}
+	summary.WatchTime = time.Duration(watchMinutes * float64(BucketSize))
+	return summary, nil
+}
+
+// Series averages viewers and sums chat messages per step
+func (r *PostgresRepository) Series(ctx context.Context, streamID string, from, to time.Time, step time.Duration) ([]Point, error) {
+	if !store.IsUUID(streamID) {
+		return []Point{}, nil
+	}
+
+	rows, err := r.pool.Query(ctx, `
+		SELECT to_timestamp(floor(extract(epoch FROM bucket_start) / $4) * $4) AS point,
+			COALESCE(AVG(viewer_total::float8 / NULLIF(viewer_samples, 0)), 0),
+			SUM(chat_messages)
+		FROM stream_analytics
+		WHERE stream_id = $1 AND bucket_start >= $2 AND bucket_start < $3`+tenancy.Condition("tenant_id", 5)+`
+		GROUP BY point
+		ORDER BY point`,
+		streamID, from, to, int64(step.Seconds()), tenancy.Scope(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list stream analytics: %w", err)
+	}
+	defer rows.Close()
+
+	points := []Point{}
+	for rows.Next() {
+		var point Point
+		if err := rows.Scan(&point.Start, &point.AverageViewers, &point.ChatMessages); err != nil {
+			return nil, fmt.Errorf("failed to scan stream analytics: %w", err)
+		}
+		points = append(points, point)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list stream analytics: %w", err)
+	}
+	return points, nil
+}
@@ -0,0 +1,155 @@
+package analytics
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/tinle0301/streaming-platform-api/internal/chatactivity"
+	"github.com/tinle0301/streaming-platform-api/internal/events"
+	"github.com/tinle0301/streaming-platform-api/internal/store"
+)
+
+const (
+	// collectPageSize is how many live streams are read per page
+	collectPageSize = 100
+
+	// claimKeyPrefix namespaces the Redis keys replicas claim sample slots with
+	claimKeyPrefix = "analytics:sample:"
+)
+
+// ViewerSource supplies cluster-wide viewer counts and views
+type ViewerSource interface {
+	Counts(ctx context.Context, streamIDs []string) (map[string]int, error)
+	Views(ctx context.Context, streamID string, minute time.Time) (int, error)
+}
+
+// ChatSource supplies recent chat activity
+type ChatSource interface {
+	Recent(ctx context.Context, streamID string, n int, until time.Time) ([]chatactivity.Bucket, error)
+}
+
+// Collector samples live streams into per-minute aggregates and counts new
+// followers of live channels
+type Collector struct {
+	streams store.StreamRepository
+	repo    Repository
+	viewers ViewerSource
+	chat    ChatSource
+	claims  *redis.Client
+}
+
+// NewCollector creates a collector. Replicas sharing claims take turns, so
+// each sample slot is collected once.
+func NewCollector(streams store.StreamRepository, repo Repository, viewers ViewerSource, chat ChatSource, claims *redis.Client) *Collector {
+	return &Collector{streams: streams, repo: repo, viewers: viewers, chat: chat, claims: claims}
+}
+
+// RunOnce samples every live stream's viewers into the current minute and
+// stores the previous minute's views and chat messages. It returns how many
+// streams were sampled, or zero if another replica claimed the slot.
+func (c *Collector) RunOnce(ctx context.Context, interval time.Duration) (int, error) {
+	now := time.Now()
+	claimed, err := c.claims.SetNX(ctx, fmt.Sprintf("%s%d", claimKeyPrefix, now.Truncate(interval).Unix()), 1, interval).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to claim analytics sample: %w", err)
+	}
+	if !claimed {
+		return 0, nil
+	}
+
+	// The previous minute is collected again on every sample until the
+	// current one ends; later chat flushes just overwrite it
+	minute := now.Truncate(BucketSize)
+	previous := minute.Add(-BucketSize)
+
+	sampled := 0
+	for offset := 0; ; offset += collectPageSize {
+		streams, total, err := c.streams.List(ctx, store.StreamFilter{
+			Status: store.StreamStatusLive,
+			Limit:  collectPageSize,
+			Offset: offset,
+		})
+		if err != nil {
+			return sampled, err
+		}
+
+		ids := make([]string, 0, len(streams))
+		for _, stream := range streams {
+			ids = append(ids, stream.ID)
+		}
+		counts, err := c.viewers.Counts(ctx, ids)
+		if err != nil {
+			return sampled, err
+		}
+
+		for _, stream := range streams {
+			if err := c.repo.RecordViewers(ctx, stream.ID, minute, counts[stream.ID]); err != nil {
+				return sampled, err
+			}
+			if err := c.collectActivity(ctx, stream.ID, previous); err != nil {
+				log.Printf("Error collecting stream activity: streamID=%s, err=%v", stream.ID, err)
+			}
+			sampled++
+		}
+
+		if offset+len(streams) >= total || len(streams) == 0 {
+			break
+		}
+	}
+	return sampled, nil
+}
+
+// Run samples every interval until ctx is done
+func (c *Collector) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := c.RunOnce(ctx, interval); err != nil {
+				log.Printf("Stream analytics collection failed: err=%v", err)
+			}
+		}
+	}
+}
+
+// HandleEvent counts new followers of live channels against their stream
+func (c *Collector) HandleEvent(ctx context.Context, event events.Event) error {
+	if event.Type != events.EventTypeNewFollower || event.UserID == "" {
+		return nil
+	}
+
+	streams, _, err := c.streams.List(ctx, store.StreamFilter{
+		StreamerID: event.UserID,
+		Status:     store.StreamStatusLive,
+		Limit:      1,
+	})
+	if err != nil || len(streams) == 0 {
+		return err
+	}
+	return c.repo.AddFollower(ctx, streams[0].ID, event.Timestamp.Truncate(BucketSize))
+}
+
+// collectActivity stores a finished minute's views and chat messages
+func (c *Collector) collectActivity(ctx context.Context, streamID string, minute time.Time) error {
+	views, err := c.viewers.Views(ctx, streamID, minute)
+	if err != nil {
+		return err
+	}
+	buckets, err := c.chat.Recent(ctx, streamID, int(BucketSize/chatactivity.BucketSize), minute.Add(BucketSize))
+	if err != nil {
+		return err
+	}
+
+	messages := 0
+	for _, bucket := range buckets {
+		messages += bucket.Messages
+	}
+	return c.repo.SetActivity(ctx, streamID, minute, views, messages)
+}
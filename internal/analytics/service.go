@@ -0,0 +1,81 @@
+package analytics
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrUnknownRange is returned for a time range name without a definition
+var ErrUnknownRange = errors.New("unknown time range")
+
+// Range is a reporting window ending now, split into steps for the series
+type Range struct {
+	// Window is how far back the range reaches; zero means all time
+	Window time.Duration
+	Step   time.Duration
+}
+
+// ranges maps the GraphQL TimeRange values to their windows
+var ranges = map[string]Range{
+	"HOUR":     {Window: time.Hour, Step: time.Minute},
+	"DAY":      {Window: 24 * time.Hour, Step: 15 * time.Minute},
+	"WEEK":     {Window: 7 * 24 * time.Hour, Step: time.Hour},
+	"MONTH":    {Window: 30 * 24 * time.Hour, Step: 6 * time.Hour},
+	"YEAR":     {Window: 365 * 24 * time.Hour, Step: 7 * 24 * time.Hour},
+	"ALL_TIME": {Step: 7 * 24 * time.Hour},
+}
+
+// LookupRange returns the range a GraphQL TimeRange value names
+func LookupRange(name string) (Range, error) {
+	rng, ok := ranges[name]
+	if !ok {
+		return Range{}, ErrUnknownRange
+	}
+	return rng, nil
+}
+
+// From is where the range starts when it ends at to
+func (rng Range) From(to time.Time) time.Time {
+	if rng.Window == 0 {
+		return time.Unix(0, 0)
+	}
+	return to.Add(-rng.Window)
+}
+
+// Report is a stream's analytics over a range
+type Report struct {
+	Summary
+	Points []Point
+}
+
+// Service reads stream analytics
+type Service struct {
+	repo Repository
+}
+
+// NewService creates an analytics reader
+func NewService(repo Repository) *Service {
+	return &Service{repo: repo}
+}
+
+// StreamReport summarizes a stream over the named range, ending now
+func (s *Service) StreamReport(ctx context.Context, streamID, rangeName string) (*Report, error) {
+	rng, err := LookupRange(rangeName)
+	if err != nil {
+		return nil, err
+	}
+
+	to := time.Now()
+	from := rng.From(to)
+
+	summary, err := s.repo.Summary(ctx, streamID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	points, err := s.repo.Series(ctx, streamID, from, to, rng.Step)
+	if err != nil {
+		return nil, err
+	}
+	return &Report{Summary: summary, Points: points}, nil
+}
@@ -0,0 +1,186 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Component is a unit of the server with a managed lifecycle
+type Component interface {
+	Name() string
+	Start(ctx context.Context) error
+	Stop(ctx context.Context) error
+}
+
+// ReadinessChecker is implemented by components that contribute to /ready
+type ReadinessChecker interface {
+	Ready(ctx context.Context) error
+}
+
+// Hook adapts plain functions to Component; nil functions are no-ops
+type Hook struct {
+	ComponentName string
+	OnStart       func(ctx context.Context) error
+	OnStop        func(ctx context.Context) error
+	OnReady       func(ctx context.Context) error
+}
+
+// Name returns the component name
+func (h Hook) Name() string {
+	return h.ComponentName
+}
+
+// Start runs OnStart
+func (h Hook) Start(ctx context.Context) error {
+	if h.OnStart == nil {
+		return nil
+	}
+	return h.OnStart(ctx)
+}
+
+// Stop runs OnStop
+func (h Hook) Stop(ctx context.Context) error {
+	if h.OnStop == nil {
+		return nil
+	}
+	return h.OnStop(ctx)
+}
+
+// Ready runs OnReady
+func (h Hook) Ready(ctx context.Context) error {
+	if h.OnReady == nil {
+		return nil
+	}
+	return h.OnReady(ctx)
+}
+
+// readinessTimeout bounds each component's readiness check
+const readinessTimeout = 2 * time.Second
+
+// App starts components in registration order and stops them in reverse
+type App struct {
+	components []Component
+	started    []Component
+	mu         sync.Mutex
+}
+
+// New creates an empty application container
+func New() *App {
+	return &App{}
+}
+
+// Register adds a component; components start in the order registered, so
+// register dependencies before their dependents
+func (a *App) Register(components ...Component) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.components = append(a.components, components...)
+}
+
+// Start starts every component in order. If one fails, the components
+// already started are stopped in reverse order and the error is returned.
+func (a *App) Start(ctx context.Context) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for _, component := range a.components {
+		start := time.Now()
+		if err := component.Start(ctx); err != nil {
+			a.stopLocked(ctx)
+			return fmt.Errorf("failed to start %s: %w", component.Name(), err)
+		}
+		a.started = append(a.started, component)
+		log.Printf("Started %s in %v", component.Name(), time.Since(start).Round(time.Millisecond))
+	}
+	return nil
+}
+
+// Stop stops started components in reverse order, continuing past failures
+func (a *App) Stop(ctx context.Context) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return a.stopLocked(ctx)
+}
+
+// stopLocked stops started components; the caller must hold a.mu
+func (a *App) stopLocked(ctx context.Context) error {
+	var errs []error
+	for i := len(a.started) - 1; i >= 0; i-- {
+		component := a.started[i]
+		if err := component.Stop(ctx); err != nil {
+			log.Printf("Error stopping %s: %v", component.Name(), err)
+			errs = append(errs, fmt.Errorf("failed to stop %s: %w", component.Name(), err))
+			continue
+		}
+		log.Printf("Stopped %s", component.Name())
+	}
+	a.started = nil
+	return errors.Join(errs...)
+}
+
+// ComponentStatus is one component's entry in the readiness report
+type ComponentStatus struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Readiness checks every started component that implements ReadinessChecker
+// and reports whether all of them are ready
+func (a *App) Readiness(ctx context.Context) (map[string]ComponentStatus, bool) {
+	a.mu.Lock()
+	started := make([]Component, len(a.started))
+	copy(started, a.started)
+	a.mu.Unlock()
+
+	statuses := make(map[string]ComponentStatus, len(started))
+	ready := len(started) > 0
+	for _, component := range started {
+		checker, ok := component.(ReadinessChecker)
+		if !ok {
+			continue
+		}
+
+		checkCtx, cancel := context.WithTimeout(ctx, readinessTimeout)
+		err := checker.Ready(checkCtx)
+		cancel()
+
+		if err != nil {
+			statuses[component.Name()] = ComponentStatus{Status: "not_ready", Error: err.Error()}
+			ready = false
+			continue
+		}
+		statuses[component.Name()] = ComponentStatus{Status: "ready"}
+	}
+	return statuses, ready
+}
+
+// ReadinessHandler serves /ready: 200 when every component is ready, 503
+// otherwise, with per-component status in the body
+func (a *App) ReadinessHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		statuses, ready := a.Readiness(r.Context())
+
+		status := "ready"
+		code := http.StatusOK
+		if !ready {
+			status = "not_ready"
+			code = http.StatusServiceUnavailable
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(code)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"status":     status,
+			"components": statuses,
+			"timestamp":  time.Now().Format(time.RFC3339),
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package app
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/redis/go-redis/v9"
+	"github.com/tinle0301/streaming-platform-api/internal/health"
+	"github.com/tinle0301/streaming-platform-api/internal/store"
+)
+
+// Clients holds connections shared across modules. Build it once at startup
+// and pass it to module constructors instead of opening per-module clients.
+type Clients struct {
+	Postgres *pgxpool.Pool
+	Redis    *redis.Client
+}
+
+// ClientsConfig locates the shared backing services
+type ClientsConfig struct {
+	DatabaseURL  string
+	DatabasePool store.PoolConfig
+	RedisURL     string
+}
+
+// NewClients creates the shared clients. Both connect lazily; reachability
+// is verified by the components returned from Components.
+func NewClients(ctx context.Context, cfg ClientsConfig) (*Clients, error) {
+	pool, err := store.NewPool(ctx, cfg.DatabaseURL, cfg.DatabasePool)
+	if err != nil {
+		return nil, err
+	}
+
+	redisOpts, err := redis.ParseURL(cfg.RedisURL)
+	if err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("failed to parse Redis URL: %w", err)
+	}
+
+	return &Clients{
+		Postgres: pool,
+		Redis:    redis.NewClient(redisOpts),
+	}, nil
+}
+
+// Components returns lifecycle components that report the clients'
+// readiness and close them on shutdown. Register them before anything that
+// uses the clients so they are closed last.
+func (c *Clients) Components() []Component {
+	return []Component{
+		Hook{
+			ComponentName: "postgres",
+			OnStop: func(ctx context.Context) error {
+				c.Postgres.Close()
+				return nil
+			},
+			OnReady: c.Postgres.Ping,
+		},
+		Hook{
+			ComponentName: "redis",
+			OnStop: func(ctx context.Context) error {
+				return c.Redis.Close()
+			},
+			OnReady: health.RedisCheck(c.Redis),
+		},
+	}
+}
@@ -0,0 +1,105 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// Entry represents a single audited action in the system
+type Entry struct {
+	ID        string            `json:"id"`
+	Action    string            `json:"action"`
+	ActorID   string            `json:"actor_id"`
+	TargetID  string            `json:"target_id,omitempty"`
+	Resource  string            `json:"resource,omitempty"`
+	Reason    string            `json:"reason,omitempty"`
+	Metadata  map[string]string `json:"metadata,omitempty"`
+	Timestamp time.Time         `json:"timestamp"`
+}
+
+// Logger defines the interface for recording audit entries
+type Logger interface {
+	Record(ctx context.Context, entry Entry) error
+}
+
+// StdLogger implements Logger by writing JSON lines to the standard logger
+type StdLogger struct {
+	mu sync.Mutex
+}
+
+// NewStdLogger creates a new audit logger backed by the standard logger
+func NewStdLogger() *StdLogger {
+	return &StdLogger{}
+}
+
+// Record writes the audit entry as a single JSON line
+func (l *StdLogger) Record(ctx context.Context, entry Entry) error {
+	if entry.Timestamp.IsZero() {
+		entry.Timestamp = time.Now()
+	}
+	if entry.ID == "" {
+		entry.ID = generateEntryID()
+	}
+
+	entryBytes, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal audit entry: %w", err)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	log.Printf("AUDIT %s", entryBytes)
+	return nil
+}
+
+// Audit action constants
+const (
+	ActionShadowBan   = "moderation.shadow_ban"
+	ActionShadowUnban = "moderation.shadow_unban"
+	ActionRoleGrant   = "moderation.role_grant"
+	ActionRoleRevoke  = "moderation.role_revoke"
+
+	ActionContentScanFailed   = "safety.scan_failed"
+	ActionContentMatched      = "safety.hash_matched"
+	ActionContentQuarantined  = "safety.quarantined"
+	ActionContentReported     = "safety.reported"
+	ActionContentReportFailed = "safety.report_failed"
+
+	ActionGeoRestrict   = "compliance.geo_restrict"
+	ActionGeoUnrestrict = "compliance.geo_unrestrict"
+
+	ActionBrandedContentEnabled  = "compliance.branded_content_enabled"
+	ActionBrandedContentUpdated  = "compliance.branded_content_updated"
+	ActionBrandedContentDisabled = "compliance.branded_content_disabled"
+
+	ActionClaimSubmitted     = "copyright.claim_submitted"
+	ActionClaimTransition    = "copyright.claim_transition"
+	ActionClaimCounterNotice = "copyright.counter_notice"
+
+	ActionSCIMEnabled           = "scim.enabled"
+	ActionSCIMDisabled          = "scim.disabled"
+	ActionSCIMGroupRoles        = "scim.group_roles_updated"
+	ActionSCIMUserProvisioned   = "scim.user_provisioned"
+	ActionSCIMUserUpdated       = "scim.user_updated"
+	ActionSCIMUserDeprovisioned = "scim.user_deprovisioned"
+	ActionSCIMGroupCreated      = "scim.group_created"
+	ActionSCIMGroupUpdated      = "scim.group_updated"
+	ActionSCIMGroupDeleted      = "scim.group_deleted"
+	ActionSCIMRoleSynced        = "scim.role_synced"
+
+	ActionSSOConfigured      = "sso.configured"
+	ActionSSORemoved         = "sso.removed"
+	ActionSSOUserProvisioned = "sso.user_provisioned"
+	ActionSSOUserLinked      = "sso.user_linked"
+	ActionSSORoleSynced      = "sso.role_synced"
+)
+
+// generateEntryID generates a unique audit entry ID
+func generateEntryID() string {
+	return fmt.Sprintf("aud_%d", time.Now().UnixNano())
+}
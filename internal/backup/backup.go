@@ -0,0 +1,279 @@
+package backup
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// ErrBackupInProgress is returned when a backup is triggered while one is running
+var ErrBackupInProgress = errors.New("backup already in progress")
+
+// Artifact is a single component snapshot produced by a Snapshotter
+type Artifact struct {
+	Component string    `json:"component"`
+	Path      string    `json:"path"`
+	SizeBytes int64     `json:"size_bytes"`
+	SHA256    string    `json:"sha256"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Snapshotter produces and verifies snapshots of one component
+type Snapshotter interface {
+	Name() string
+	Snapshot(ctx context.Context, dir string) (Artifact, error)
+	Verify(ctx context.Context, artifact Artifact) error
+}
+
+// Rehearser restores an artifact into a scratch target to prove it is usable
+type Rehearser interface {
+	Rehearse(ctx context.Context, artifact Artifact) error
+}
+
+// Run records the outcome of one coordinated backup
+type Run struct {
+	ID         string     `json:"id"`
+	StartedAt  time.Time  `json:"started_at"`
+	FinishedAt time.Time  `json:"finished_at,omitempty"`
+	Artifacts  []Artifact `json:"artifacts"`
+	Verified   bool       `json:"verified"`
+	Rehearsed  bool       `json:"rehearsed"`
+	Error      string     `json:"error,omitempty"`
+}
+
+// Coordinator takes consistent multi-component backups on a schedule
+type Coordinator struct {
+	dir          string
+	snapshotters []Snapshotter
+	history      []Run
+	maxHistory   int
+	running      bool
+	mu           sync.Mutex
+}
+
+// NewCoordinator creates a backup coordinator writing artifacts under dir.
+// Snapshotters run in the order given, so list the database first and
+// checkpoints last.
+func NewCoordinator(dir string, snapshotters ...Snapshotter) *Coordinator {
+	return &Coordinator{
+		dir:          dir,
+		snapshotters: snapshotters,
+		maxHistory:   50,
+	}
+}
+
+// RunOnce takes a backup of every component and verifies each artifact
+func (c *Coordinator) RunOnce(ctx context.Context) (Run, error) {
+	c.mu.Lock()
+	if c.running {
+		c.mu.Unlock()
+		return Run{}, ErrBackupInProgress
+	}
+	c.running = true
+	c.mu.Unlock()
+
+	defer func() {
+		c.mu.Lock()
+		c.running = false
+		c.mu.Unlock()
+	}()
+
+	run := Run{
+		ID:        fmt.Sprintf("bkp_%d", time.Now().UnixNano()),
+		StartedAt: time.Now(),
+	}
+	err := c.snapshot(ctx, &run)
+	run.FinishedAt = time.Now()
+	if err != nil {
+		run.Error = err.Error()
+		log.Printf("Backup failed: id=%s, err=%v", run.ID, err)
+	} else {
+		log.Printf("Backup complete: id=%s, artifacts=%d, took=%v", run.ID, len(run.Artifacts), run.FinishedAt.Sub(run.StartedAt))
+	}
+
+	c.recordRun(run)
+	return run, err
+}
+
+// snapshot runs and verifies every snapshotter for a run
+func (c *Coordinator) snapshot(ctx context.Context, run *Run) error {
+	runDir := fmt.Sprintf("%s/%s", c.dir, run.ID)
+	if err := os.MkdirAll(runDir, 0o750); err != nil {
+		return fmt.Errorf("failed to create backup directory: %w", err)
+	}
+
+	for _, snapshotter := range c.snapshotters {
+		artifact, err := snapshotter.Snapshot(ctx, runDir)
+		if err != nil {
+			return fmt.Errorf("%s snapshot failed: %w", snapshotter.Name(), err)
+		}
+		artifact.Component = snapshotter.Name()
+		if artifact.CreatedAt.IsZero() {
+			artifact.CreatedAt = time.Now()
+		}
+		if err := fillChecksum(&artifact); err != nil {
+			return fmt.Errorf("%s checksum failed: %w", snapshotter.Name(), err)
+		}
+		run.Artifacts = append(run.Artifacts, artifact)
+	}
+
+	for i, snapshotter := range c.snapshotters {
+		if err := snapshotter.Verify(ctx, run.Artifacts[i]); err != nil {
+			return fmt.Errorf("%s verification failed: %w", snapshotter.Name(), err)
+		}
+	}
+	run.Verified = true
+	return nil
+}
+
+// Rehearse restores the most recent verified backup into scratch targets
+func (c *Coordinator) Rehearse(ctx context.Context) (Run, error) {
+	c.mu.Lock()
+	var latest *Run
+	for i := len(c.history) - 1; i >= 0; i-- {
+		if c.history[i].Verified {
+			latest = &c.history[i]
+			break
+		}
+	}
+	if latest == nil {
+		c.mu.Unlock()
+		return Run{}, errors.New("no verified backup to rehearse")
+	}
+	run := *latest
+	c.mu.Unlock()
+
+	for i, snapshotter := range c.snapshotters {
+		rehearser, ok := snapshotter.(Rehearser)
+		if !ok || i >= len(run.Artifacts) {
+			continue
+		}
+		if err := rehearser.Rehearse(ctx, run.Artifacts[i]); err != nil {
+			return run, fmt.Errorf("%s restore rehearsal failed: %w", snapshotter.Name(), err)
+		}
+	}
+
+	c.mu.Lock()
+	for i := range c.history {
+		if c.history[i].ID == run.ID {
+			c.history[i].Rehearsed = true
+			run = c.history[i]
+		}
+	}
+	c.mu.Unlock()
+
+	log.Printf("Restore rehearsal succeeded: id=%s", run.ID)
+	return run, nil
+}
+
+// Run takes a backup every interval until ctx is cancelled
+func (c *Coordinator) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			c.RunOnce(ctx)
+		}
+	}
+}
+
+// Status returns the backup history, most recent last
+func (c *Coordinator) Status() []Run {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]Run{}, c.history...)
+}
+
+// recordRun appends a run to the bounded history
+func (c *Coordinator) recordRun(run Run) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.history = append(c.history, run)
+	if len(c.history) > c.maxHistory {
+		c.history = c.history[len(c.history)-c.maxHistory:]
+	}
+}
+
+// Handler returns the admin API handler for backups.
+//
+//	GET  /admin/backups              backup history
+//	POST /admin/backups              trigger a backup now
+//	POST /admin/backups?rehearse=1   restore rehearsal of the latest backup
+func (c *Coordinator) Handler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var (
+			result interface{}
+			err    error
+		)
+
+		switch r.Method {
+		case http.MethodGet:
+			result = c.Status()
+		case http.MethodPost:
+			if r.URL.Query().Get("rehearse") != "" {
+				result, err = c.Rehearse(r.Context())
+			} else {
+				result, err = c.RunOnce(r.Context())
+			}
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case errors.Is(err, ErrBackupInProgress):
+			w.WriteHeader(http.StatusConflict)
+		case err != nil:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		json.NewEncoder(w).Encode(result)
+	}
+}
+
+// fillChecksum sets the size and SHA-256 of an artifact file
+func fillChecksum(artifact *Artifact) error {
+	f, err := os.Open(artifact.Path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	n, err := io.Copy(h, f)
+	if err != nil {
+		return err
+	}
+	artifact.SizeBytes = n
+	artifact.SHA256 = hex.EncodeToString(h.Sum(nil))
+	return nil
+}
+
+// verifyChecksum checks that an artifact file still matches its checksum
+func verifyChecksum(artifact Artifact) error {
+	check := artifact
+	if err := fillChecksum(&check); err != nil {
+		return err
+	}
+	if check.SHA256 != artifact.SHA256 {
+		return fmt.Errorf("checksum mismatch for %s", artifact.Path)
+	}
+	if check.SizeBytes == 0 {
+		return fmt.Errorf("empty artifact %s", artifact.Path)
+	}
+	return nil
+}
@@ -0,0 +1,437 @@
+package campaigns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/tinle0301/streaming-platform-api/internal/store"
+	"github.com/tinle0301/streaming-platform-api/internal/tenancy"
+)
+
+// Campaign errors
+var (
+	ErrNotFound       = errors.New("campaign not found")
+	ErrRewardNotFound = errors.New("reward not found")
+	ErrAlreadyClaimed = errors.New("reward already claimed")
+	ErrUserNotFound   = errors.New("user not found")
+)
+
+// Campaign rewards viewers for watching streams in its categories between
+// StartsAt and EndsAt
+type Campaign struct {
+	ID          string
+	PublisherID string
+	Name        string
+	Description string
+	Categories  []string
+	StartsAt    time.Time
+	EndsAt      time.Time
+	CreatedAt   time.Time
+	Rewards     []*Reward
+
+	// Claims are POSTed to WebhookURL, signed with WebhookSecret
+	WebhookURL    string
+	WebhookSecret string
+}
+
+// ActiveAt reports whether the campaign accrues watch time at t
+func (c *Campaign) ActiveAt(t time.Time) bool {
+	return !t.Before(c.StartsAt) && t.Before(c.EndsAt)
+}
+
+// Reward unlocks once a viewer has watched RequiredMinutes of a campaign
+type Reward struct {
+	ID              string
+	CampaignID      string
+	Name            string
+	Description     string
+	ImageURL        string
+	RequiredMinutes int
+}
+
+// Progress is a viewer's accrued watch time towards a campaign
+type Progress struct {
+	CampaignID string
+	UserID     string
+	Watched    time.Duration
+	UpdatedAt  time.Time
+}
+
+// Claim records a viewer claiming a reward
+type Claim struct {
+	ID         string
+	RewardID   string
+	CampaignID string
+	UserID     string
+	ClaimedAt  time.Time
+}
+
+// Filter narrows a campaign listing; zero values match everything
+type Filter struct {
+	PublisherID string
+	Category    string
+
+	// ActiveAt keeps only campaigns running at this time
+	ActiveAt *time.Time
+}
+
+// Repository persists campaigns, viewer progress, and claims. Campaigns are
+// created in the context's tenant; other tenants' campaigns, and their
+// rewards, progress, and claims, are treated as missing.
+type Repository interface {
+	// Create inserts a campaign with its rewards
+	Create(ctx context.Context, campaign *Campaign) error
+	Get(ctx context.Context, id string) (*Campaign, error)
+	List(ctx context.Context, filter Filter) ([]*Campaign, error)
+	Reward(ctx context.Context, id string) (*Reward, error)
+
+	// AddWatchTime credits watched to userID in each campaign
+	AddWatchTime(ctx context.Context, campaignIDs []string, userID string, watched time.Duration) error
+	Progress(ctx context.Context, campaignID, userID string) (*Progress, error)
+	ProgressForUser(ctx context.Context, userID string) ([]*Progress, error)
+
+	// Claim inserts a claim, returning ErrAlreadyClaimed for repeats
+	Claim(ctx context.Context, claim *Claim) error
+	Claims(ctx context.Context, userID string) ([]*Claim, error)
+}
+
+// campaignColumns is the column list shared by campaign queries
+const campaignColumns = `id::text, publisher_id::text, name, description, categories, starts_at, ends_at,
+	webhook_url, webhook_secret, created_at`
+
+// campaignScope restricts a query on a campaign ID column to campaigns in
+// the context's tenant; n is the placeholder number of the tenancy.Scope
+// argument
+func campaignScope(column string, n int) string {
+	return fmt.Sprintf(` AND ($%d::text IS NULL OR %s IN (SELECT id FROM campaigns WHERE tenant_id = $%d))`,
+		n, column, n)
+}
+
+// PostgresRepository implements Repository on PostgreSQL
+type PostgresRepository struct {
+	pool *pgxpool.Pool
+}
+
+// NewPostgresRepository creates a campaign repository on pool
+func NewPostgresRepository(pool *pgxpool.Pool) *PostgresRepository {
+	return &PostgresRepository{pool: pool}
+}
+
+// Create inserts a campaign and its rewards in one transaction
+func (r *PostgresRepository) Create(ctx context.Context, campaign *Campaign) error {
+	if !store.IsUUID(campaign.PublisherID) {
+		return ErrUserNotFound
+	}
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to create campaign: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	err = tx.QueryRow(ctx, `
+		INSERT INTO campaigns (publisher_id, name, description, categories, starts_at, ends_at,
+			webhook_url, webhook_secret, tenant_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING id::text, created_at`,
+		campaign.PublisherID, campaign.Name, campaign.Description, campaign.Categories,
+		campaign.StartsAt, campaign.EndsAt, campaign.WebhookURL, campaign.WebhookSecret, tenancy.ID(ctx),
+	).Scan(&campaign.ID, &campaign.CreatedAt)
+	if store.PgCode(err) == "23503" {
+		return ErrUserNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create campaign: %w", err)
+	}
+
+	for _, reward := range campaign.Rewards {
+		reward.CampaignID = campaign.ID
+		err := tx.QueryRow(ctx, `
+			INSERT INTO campaign_rewards (campaign_id, name, description, image_url, required_minutes)
+			VALUES ($1, $2, $3, $4, $5)
+			RETURNING id::text`,
+			reward.CampaignID, reward.Name, reward.Description, reward.ImageURL, reward.RequiredMinutes,
+		).Scan(&reward.ID)
+		if err != nil {
+			return fmt.Errorf("failed to create campaign reward: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to create campaign: %w", err)
+	}
+	return nil
+}
+
+// Get returns a campaign with its rewards
+func (r *PostgresRepository) Get(ctx context.Context, id string) (*Campaign, error) {
+	if !store.IsUUID(id) {
+		return nil, ErrNotFound
+	}
+
+	row := r.pool.QueryRow(ctx, `SELECT `+campaignColumns+` FROM campaigns WHERE id = $1`+tenancy.Condition("tenant_id", 2),
+		id, tenancy.Scope(ctx))
+	campaign, err := scanCampaign(row)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get campaign: %w", err)
+	}
+
+	if err := r.attachRewards(ctx, []*Campaign{campaign}); err != nil {
+		return nil, err
+	}
+	return campaign, nil
+}
+
+// List returns campaigns matching filter with their rewards, soonest ending first
+func (r *PostgresRepository) List(ctx context.Context, filter Filter) ([]*Campaign, error) {
+	query := `SELECT ` + campaignColumns + ` FROM campaigns WHERE TRUE`
+	var args []interface{}
+	if tenantID, ok := tenancy.FromContext(ctx); ok {
+		args = append(args, tenantID)
+		query += fmt.Sprintf(` AND tenant_id = $%d`, len(args))
+	}
+	if filter.PublisherID != "" {
+		if !store.IsUUID(filter.PublisherID) {
+			return []*Campaign{}, nil
+		}
+		args = append(args, filter.PublisherID)
+		query += fmt.Sprintf(` AND publisher_id = $%d`, len(args))
+	}
+	if filter.Category != "" {
+		args = append(args, filter.Category)
+		query += fmt.Sprintf(` AND $%d = ANY(categories)`, len(args))
+	}
+	if filter.ActiveAt != nil {
+		args = append(args, *filter.ActiveAt)
+		query += fmt.Sprintf(` AND starts_at <= $%d AND ends_at > $%d`, len(args), len(args))
+	}
+	query += ` ORDER BY ends_at, id`
+
+	rows, err := r.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list campaigns: %w", err)
+	}
+	defer rows.Close()
+
+	list := []*Campaign{}
+	for rows.Next() {
+		campaign, err := scanCampaign(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan campaign: %w", err)
+		}
+		list = append(list, campaign)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list campaigns: %w", err)
+	}
+
+	if err := r.attachRewards(ctx, list); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// Reward returns a reward by ID
+func (r *PostgresRepository) Reward(ctx context.Context, id string) (*Reward, error) {
+	if !store.IsUUID(id) {
+		return nil, ErrRewardNotFound
+	}
+
+	var reward Reward
+	err := r.pool.QueryRow(ctx, `
+		SELECT id::text, campaign_id::text, name, description, image_url, required_minutes
+		FROM campaign_rewards WHERE id = $1`+campaignScope("campaign_id", 2), id, tenancy.Scope(ctx),
+	).Scan(&reward.ID, &reward.CampaignID, &reward.Name, &reward.Description, &reward.ImageURL, &reward.RequiredMinutes)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrRewardNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get reward: %w", err)
+	}
+	return &reward, nil
+}
+
+// AddWatchTime upserts the viewer's progress in each campaign
+func (r *PostgresRepository) AddWatchTime(ctx context.Context, campaignIDs []string, userID string, watched time.Duration) error {
+	if len(campaignIDs) == 0 || !store.IsUUID(userID) {
+		return nil
+	}
+
+	_, err := r.pool.Exec(ctx, `
+		INSERT INTO campaign_progress (campaign_id, user_id, watched_seconds)
+		SELECT id, $2, $3 FROM campaigns WHERE id = ANY($1::uuid[])`+tenancy.Condition("tenant_id", 4)+`
+		ON CONFLICT (campaign_id, user_id) DO UPDATE SET
+			watched_seconds = campaign_progress.watched_seconds + EXCLUDED.watched_seconds,
+			updated_at = NOW()`,
+		campaignIDs, userID, int64(watched.Seconds()), tenancy.Scope(ctx))
+	if store.PgCode(err) == "23503" {
+		return ErrUserNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to add campaign watch time: %w", err)
+	}
+	return nil
+}
+
+// Progress returns a viewer's progress in one campaign; viewers who have not
+// watched yet have zero progress
+func (r *PostgresRepository) Progress(ctx context.Context, campaignID, userID string) (*Progress, error) {
+	progress := &Progress{CampaignID: campaignID, UserID: userID}
+	if !store.IsUUID(campaignID) || !store.IsUUID(userID) {
+		return progress, nil
+	}
+
+	var seconds int64
+	err := r.pool.QueryRow(ctx, `
+		SELECT watched_seconds, updated_at FROM campaign_progress
+		WHERE campaign_id = $1 AND user_id = $2`+campaignScope("campaign_id", 3), campaignID, userID, tenancy.Scope(ctx),
+	).Scan(&seconds, &progress.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return progress, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get campaign progress: %w", err)
+	}
+	progress.Watched = time.Duration(seconds) * time.Second
+	return progress, nil
+}
+
+// ProgressForUser lists a viewer's progress in every campaign they have watched
+func (r *PostgresRepository) ProgressForUser(ctx context.Context, userID string) ([]*Progress, error) {
+	list := []*Progress{}
+	if !store.IsUUID(userID) {
+		return list, nil
+	}
+
+	rows, err := r.pool.Query(ctx, `
+		SELECT campaign_id::text, watched_seconds, updated_at FROM campaign_progress
+		WHERE user_id = $1`+campaignScope("campaign_id", 2)+`
+		ORDER BY updated_at DESC`, userID, tenancy.Scope(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list campaign progress: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		progress := &Progress{UserID: userID}
+		var seconds int64
+		if err := rows.Scan(&progress.CampaignID, &seconds, &progress.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan campaign progress: %w", err)
+		}
+		progress.Watched = time.Duration(seconds) * time.Second
+		list = append(list, progress)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list campaign progress: %w", err)
+	}
+	return list, nil
+}
+
+// Claim inserts a reward claim
+func (r *PostgresRepository) Claim(ctx context.Context, claim *Claim) error {
+	if !store.IsUUID(claim.UserID) {
+		return ErrUserNotFound
+	}
+
+	err := r.pool.QueryRow(ctx, `
+		INSERT INTO campaign_claims (reward_id, campaign_id, user_id) VALUES ($1, $2, $3)
+		RETURNING id::text, claimed_at`,
+		claim.RewardID, claim.CampaignID, claim.UserID,
+	).Scan(&claim.ID, &claim.ClaimedAt)
+	switch store.PgCode(err) {
+	case "23505":
+		return ErrAlreadyClaimed
+	case "23503":
+		return ErrUserNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to claim reward: %w", err)
+	}
+	return nil
+}
+
+// Claims lists a viewer's claims, newest first
+func (r *PostgresRepository) Claims(ctx context.Context, userID string) ([]*Claim, error) {
+	list := []*Claim{}
+	if !store.IsUUID(userID) {
+		return list, nil
+	}
+
+	rows, err := r.pool.Query(ctx, `
+		SELECT id::text, reward_id::text, campaign_id::text, user_id::text, claimed_at
+		FROM campaign_claims WHERE user_id = $1`+campaignScope("campaign_id", 2)+`
+		ORDER BY claimed_at DESC`, userID, tenancy.Scope(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list reward claims: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var claim Claim
+		if err := rows.Scan(&claim.ID, &claim.RewardID, &claim.CampaignID, &claim.UserID, &claim.ClaimedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan reward claim: %w", err)
+		}
+		list = append(list, &claim)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list reward claims: %w", err)
+	}
+	return list, nil
+}
+
+// attachRewards loads the rewards of campaigns in one query, cheapest first
+func (r *PostgresRepository) attachRewards(ctx context.Context, list []*Campaign) error {
+	if len(list) == 0 {
+		return nil
+	}
+	byID := make(map[string]*Campaign, len(list))
+	ids := make([]string, 0, len(list))
+	for _, campaign := range list {
+		campaign.Rewards = []*Reward{}
+		byID[campaign.ID] = campaign
+		ids = append(ids, campaign.ID)
+	}
+
+	rows, err := r.pool.Query(ctx, `
+		SELECT id::text, campaign_id::text, name, description, image_url, required_minutes
+		FROM campaign_rewards WHERE campaign_id = ANY($1::uuid[])
+		ORDER BY required_minutes, id`, ids)
+	if err != nil {
+		return fmt.Errorf("failed to list campaign rewards: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var reward Reward
+		if err := rows.Scan(&reward.ID, &reward.CampaignID, &reward.Name, &reward.Description, &reward.ImageURL, &reward.RequiredMinutes); err != nil {
+			return fmt.Errorf("failed to scan campaign reward: %w", err)
+		}
+		if campaign, ok := byID[reward.CampaignID]; ok {
+			campaign.Rewards = append(campaign.Rewards, &reward)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to list campaign rewards: %w", err)
+	}
+	return nil
+}
+
+// scanCampaign reads a row selected with campaignColumns
+func scanCampaign(row pgx.Row) (*Campaign, error) {
+	var campaign Campaign
+	err := row.Scan(
+		&campaign.ID, &campaign.PublisherID, &campaign.Name, &campaign.Description, &campaign.Categories,
+		&campaign.StartsAt, &campaign.EndsAt, &campaign.WebhookURL, &campaign.WebhookSecret, &campaign.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &campaign, nil
+}
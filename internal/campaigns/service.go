@@ -0,0 +1,293 @@
+package campaigns
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/netip"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/tinle0301/streaming-platform-api/internal/events"
+	"github.com/tinle0301/streaming-platform-api/internal/store"
+	"github.com/tinle0301/streaming-platform-api/internal/tenancy"
+)
+
+// Campaign limits
+const (
+	maxNameLength        = 64
+	maxDescriptionLength = 500
+	maxCategories        = 20
+	maxRewards           = 10
+
+	// How long after a campaign ends its rewards can still be claimed
+	claimWindow = 7 * 24 * time.Hour
+)
+
+// Validation and claim errors
+var (
+	ErrInvalidName       = fmt.Errorf("name must be 1-%d characters", maxNameLength)
+	ErrInvalidSchedule   = errors.New("campaign must end after it starts and in the future")
+	ErrInvalidCategories = fmt.Errorf("campaigns need 1-%d categories", maxCategories)
+	ErrInvalidRewards    = fmt.Errorf("campaigns need 1-%d rewards, each with a name and positive required minutes", maxRewards)
+	ErrInvalidWebhook    = errors.New("webhook URL must be an absolute https URL on a public host")
+	ErrNotEarned         = errors.New("not enough watch time to claim this reward")
+	ErrClaimClosed       = errors.New("this campaign's claim window has closed")
+)
+
+// Service runs reward campaigns: it accrues viewers' watch time in matching
+// campaigns and records reward claims
+type Service struct {
+	repo     Repository
+	streams  store.StreamRepository
+	webhooks *WebhookNotifier
+
+	publisher events.Publisher
+}
+
+// NewService creates a campaign service. Watch time is matched to campaigns
+// by the category of the stream watched.
+func NewService(repo Repository, streams store.StreamRepository, webhooks *WebhookNotifier) *Service {
+	return &Service{repo: repo, streams: streams, webhooks: webhooks}
+}
+
+// SetPublisher also publishes reward claims to the event bus
+func (s *Service) SetPublisher(publisher events.Publisher) {
+	s.publisher = publisher
+}
+
+// Create validates and stores a campaign owned by publisherID. A webhook
+// secret is generated when the campaign has a webhook.
+func (s *Service) Create(ctx context.Context, publisherID string, campaign *Campaign) error {
+	campaign.PublisherID = publisherID
+	campaign.Name = strings.TrimSpace(campaign.Name)
+	campaign.Description = strings.TrimSpace(campaign.Description)
+	if campaign.Name == "" || len(campaign.Name) > maxNameLength || len(campaign.Description) > maxDescriptionLength {
+		return ErrInvalidName
+	}
+	if !campaign.EndsAt.After(campaign.StartsAt) || !campaign.EndsAt.After(time.Now()) {
+		return ErrInvalidSchedule
+	}
+
+	categories := make([]string, 0, len(campaign.Categories))
+	seen := make(map[string]bool)
+	for _, category := range campaign.Categories {
+		category = strings.TrimSpace(category)
+		if category == "" || seen[category] {
+			continue
+		}
+		seen[category] = true
+		categories = append(categories, category)
+	}
+	if len(categories) == 0 || len(categories) > maxCategories {
+		return ErrInvalidCategories
+	}
+	campaign.Categories = categories
+
+	if len(campaign.Rewards) == 0 || len(campaign.Rewards) > maxRewards {
+		return ErrInvalidRewards
+	}
+	for _, reward := range campaign.Rewards {
+		reward.Name = strings.TrimSpace(reward.Name)
+		if reward.Name == "" || len(reward.Name) > maxNameLength || reward.RequiredMinutes <= 0 {
+			return ErrInvalidRewards
+		}
+	}
+
+	if campaign.WebhookURL != "" {
+		if !validWebhookURL(campaign.WebhookURL) {
+			return ErrInvalidWebhook
+		}
+		secret, err := newWebhookSecret()
+		if err != nil {
+			return fmt.Errorf("failed to generate webhook secret: %w", err)
+		}
+		campaign.WebhookSecret = secret
+	}
+
+	if err := s.repo.Create(ctx, campaign); err != nil {
+		return err
+	}
+
+	log.Printf("Campaign created: id=%s, publisher=%s, categories=%v, rewards=%d",
+		campaign.ID, publisherID, campaign.Categories, len(campaign.Rewards))
+	return nil
+}
+
+// validWebhookURL reports whether raw is an https URL without credentials
+// whose host is not obviously internal. Hostnames are checked again when
+// the notifier dials, after they resolve.
+func validWebhookURL(raw string) bool {
+	u, err := url.Parse(raw)
+	if err != nil || u.Scheme != "https" || u.User != nil {
+		return false
+	}
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	if host == "" || host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return false
+	}
+	if addr, err := netip.ParseAddr(host); err == nil && !isPublicAddr(addr) {
+		return false
+	}
+	return true
+}
+
+// Get returns a campaign by ID
+func (s *Service) Get(ctx context.Context, id string) (*Campaign, error) {
+	return s.repo.Get(ctx, id)
+}
+
+// List returns campaigns matching filter
+func (s *Service) List(ctx context.Context, filter Filter) ([]*Campaign, error) {
+	return s.repo.List(ctx, filter)
+}
+
+// Progress returns a viewer's progress in a campaign
+func (s *Service) Progress(ctx context.Context, campaignID, userID string) (*Progress, error) {
+	return s.repo.Progress(ctx, campaignID, userID)
+}
+
+// ProgressForUser lists a viewer's progress in every campaign they have watched
+func (s *Service) ProgressForUser(ctx context.Context, userID string) ([]*Progress, error) {
+	return s.repo.ProgressForUser(ctx, userID)
+}
+
+// Claims lists a viewer's reward claims
+func (s *Service) Claims(ctx context.Context, userID string) ([]*Claim, error) {
+	return s.repo.Claims(ctx, userID)
+}
+
+// RecordWatch credits watch time in a stream to every active campaign
+// covering the stream's category
+func (s *Service) RecordWatch(ctx context.Context, userID, streamID string, watched time.Duration) error {
+	if watched <= 0 {
+		return nil
+	}
+
+	stream, err := s.streams.Get(ctx, streamID)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if stream.Category == "" {
+		return nil
+	}
+
+	now := time.Now()
+	active, err := s.repo.List(ctx, Filter{Category: stream.Category, ActiveAt: &now})
+	if err != nil {
+		return err
+	}
+	if len(active) == 0 {
+		return nil
+	}
+
+	ids := make([]string, 0, len(active))
+	for _, campaign := range active {
+		ids = append(ids, campaign.ID)
+	}
+	return s.repo.AddWatchTime(ctx, ids, userID, watched)
+}
+
+// HandleEvent accrues watch.progress events; it is an events.Handler
+func (s *Service) HandleEvent(ctx context.Context, event events.Event) error {
+	if event.Type != events.EventTypeWatchProgress || event.UserID == "" || event.StreamID == "" {
+		return nil
+	}
+
+	var seconds int64
+	switch v := event.Data["watched_seconds"].(type) {
+	case float64:
+		seconds = int64(v)
+	case int64:
+		seconds = v
+	case int:
+		seconds = int64(v)
+	}
+
+	// Watch time accrues in the campaigns of the stream's tenant
+	ctx = tenancy.EventContext(ctx, event)
+	err := s.RecordWatch(ctx, event.UserID, event.StreamID, time.Duration(seconds)*time.Second)
+	if errors.Is(err, ErrUserNotFound) {
+		// Deleted accounts have nothing to accrue
+		return nil
+	}
+	return err
+}
+
+// Claim claims a reward for userID once they have watched enough of its
+// campaign, then notifies the publisher
+func (s *Service) Claim(ctx context.Context, userID, rewardID string) (*Claim, *Reward, error) {
+	reward, err := s.repo.Reward(ctx, rewardID)
+	if err != nil {
+		return nil, nil, err
+	}
+	campaign, err := s.repo.Get(ctx, reward.CampaignID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if time.Now().After(campaign.EndsAt.Add(claimWindow)) {
+		return nil, nil, ErrClaimClosed
+	}
+
+	progress, err := s.repo.Progress(ctx, campaign.ID, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if progress.Watched < time.Duration(reward.RequiredMinutes)*time.Minute {
+		return nil, nil, ErrNotEarned
+	}
+
+	claim := &Claim{RewardID: reward.ID, CampaignID: campaign.ID, UserID: userID}
+	if err := s.repo.Claim(ctx, claim); err != nil {
+		return nil, nil, err
+	}
+
+	log.Printf("Campaign reward claimed: campaign=%s, reward=%s, user=%s", campaign.ID, reward.ID, userID)
+	s.notifyClaim(ctx, campaign, reward, claim)
+	return claim, reward, nil
+}
+
+// notifyClaim sends a reward claim to the publisher's webhook and the event
+// bus. The claim is already saved, so delivery failures are only logged.
+func (s *Service) notifyClaim(ctx context.Context, campaign *Campaign, reward *Reward, claim *Claim) {
+	event := events.Event{
+		ID:     "evt_claim_" + claim.ID,
+		Type:   events.EventTypeRewardClaimed,
+		UserID: claim.UserID,
+		Data: map[string]interface{}{
+			"claim_id":         claim.ID,
+			"campaign_id":      campaign.ID,
+			"reward_id":        reward.ID,
+			"reward_name":      reward.Name,
+			"required_minutes": reward.RequiredMinutes,
+			"claimed_at":       claim.ClaimedAt,
+		},
+		Timestamp: claim.ClaimedAt,
+		Version:   "1.0",
+	}
+
+	if s.webhooks != nil {
+		s.webhooks.Notify(campaign, event)
+	}
+	if s.publisher != nil {
+		if err := s.publisher.Publish(ctx, event); err != nil {
+			log.Printf("Error publishing %s event: claimID=%s, err=%v", event.Type, claim.ID, err)
+		}
+	}
+}
+
+// newWebhookSecret generates a random webhook signing secret
+func newWebhookSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(b), nil
+}
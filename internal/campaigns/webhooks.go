@@ -0,0 +1,189 @@
+package campaigns
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/tinle0301/streaming-platform-api/internal/events"
+)
+
+// Webhook request headers. The signature is the hex HMAC-SHA256 of
+// "<timestamp>.<body>" keyed with the campaign's webhook secret.
+const (
+	SignatureHeader = "X-Campaign-Signature"
+	TimestampHeader = "X-Campaign-Timestamp"
+	EventTypeHeader = "X-Campaign-Event"
+)
+
+// WebhookOptions tunes webhook delivery
+type WebhookOptions struct {
+	Timeout     time.Duration
+	MaxAttempts int
+
+	// Backoff is the delay before the second attempt; it doubles after each
+	// failure
+	Backoff time.Duration
+}
+
+// DefaultWebhookOptions returns the delivery options used when none are configured
+func DefaultWebhookOptions() WebhookOptions {
+	return WebhookOptions{
+		Timeout:     10 * time.Second,
+		MaxAttempts: 5,
+		Backoff:     2 * time.Second,
+	}
+}
+
+// WebhookNotifier delivers campaign events to publisher webhooks
+type WebhookNotifier struct {
+	client *http.Client
+	opts   WebhookOptions
+}
+
+// errBlockedAddress rejects connections to addresses webhooks must not reach
+var errBlockedAddress = errors.New("webhook address is not publicly routable")
+
+// Address ranges that are neither private nor loopback by netip's reckoning
+// but still must not be reachable from webhooks
+var blockedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("64:ff9b::/96"),
+}
+
+// NewWebhookNotifier creates a notifier. Webhooks are publisher-supplied
+// URLs, so the notifier only connects to public addresses, checked after
+// DNS resolution, and never follows redirects or uses a proxy.
+func NewWebhookNotifier(opts WebhookOptions) *WebhookNotifier {
+	dialer := &net.Dialer{Timeout: opts.Timeout, Control: dialPublicOnly}
+	transport := &http.Transport{
+		DialContext:         dialer.DialContext,
+		TLSHandshakeTimeout: opts.Timeout,
+		MaxIdleConnsPerHost: 2,
+		IdleConnTimeout:     90 * time.Second,
+	}
+	return &WebhookNotifier{
+		client: &http.Client{
+			Timeout:   opts.Timeout,
+			Transport: transport,
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		opts: opts,
+	}
+}
+
+// dialPublicOnly is a dialer Control hook refusing connections to
+// loopback, private, link-local and other non-public addresses. It sees
+// the resolved address, so hostnames pointing inside the network are
+// refused too.
+func dialPublicOnly(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return err
+	}
+	if !isPublicAddr(addr) {
+		return fmt.Errorf("%w: %s", errBlockedAddress, addr)
+	}
+	return nil
+}
+
+// isPublicAddr reports whether addr is a publicly routable unicast address
+func isPublicAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsGlobalUnicast() || addr.IsPrivate() {
+		return false
+	}
+	for _, prefix := range blockedPrefixes {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// Notify delivers event to the campaign's webhook in the background,
+// retrying failed attempts. Campaigns without a webhook are skipped.
+func (n *WebhookNotifier) Notify(campaign *Campaign, event events.Event) {
+	if campaign.WebhookURL == "" {
+		return
+	}
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("Error encoding campaign webhook: campaignID=%s, err=%v", campaign.ID, err)
+		return
+	}
+
+	go func() {
+		backoff := n.opts.Backoff
+		for attempt := 1; attempt <= n.opts.MaxAttempts; attempt++ {
+			err := n.deliver(context.Background(), campaign, event.Type, body)
+			if err == nil {
+				return
+			}
+			log.Printf("Campaign webhook failed: campaignID=%s, event=%s, attempt=%d, err=%v",
+				campaign.ID, event.ID, attempt, err)
+
+			if attempt < n.opts.MaxAttempts {
+				time.Sleep(backoff)
+				backoff *= 2
+			}
+		}
+		log.Printf("Campaign webhook dropped: campaignID=%s, event=%s", campaign.ID, event.ID)
+	}()
+}
+
+// deliver makes one signed webhook request
+func (n *WebhookNotifier) deliver(ctx context.Context, campaign *Campaign, eventType string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, campaign.WebhookURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to build webhook request: %w", err)
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventTypeHeader, eventType)
+	req.Header.Set(TimestampHeader, timestamp)
+	req.Header.Set(SignatureHeader, Sign(campaign.WebhookSecret, timestamp, body))
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send webhook: %w", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Sign computes the webhook signature publishers verify
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
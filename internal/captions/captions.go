@@ -0,0 +1,135 @@
+// Package captions stores closed captions produced by an external
+// speech-to-text service, timed from the start of each broadcast, and maps
+// them onto the VODs cut from it.
+package captions
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/tinle0301/streaming-platform-api/internal/store"
+	"github.com/tinle0301/streaming-platform-api/internal/tenancy"
+)
+
+// Segment is a caption shown from Start to End, measured from the start of
+// the broadcast (or of the VOD, for captions mapped onto one)
+type Segment struct {
+	ID        string
+	StreamID  string
+	Language  string
+	Start     time.Duration
+	End       time.Duration
+	Text      string
+	CreatedAt time.Time
+}
+
+// Repository persists caption segments, which belong to the tenant in the
+// request context
+type Repository interface {
+	// Save stores a segment and fills in its generated ID and timestamp. A
+	// segment starting at the same time in the same language is replaced.
+	Save(ctx context.Context, segment *Segment) error
+
+	// List returns a stream's segments in one language in broadcast order
+	List(ctx context.Context, streamID, language string) ([]*Segment, error)
+
+	// Languages returns the languages a stream has captions in
+	Languages(ctx context.Context, streamID string) ([]string, error)
+}
+
+// PostgresRepository implements Repository on PostgreSQL
+type PostgresRepository struct {
+	pool *pgxpool.Pool
+}
+
+// NewPostgresRepository creates a caption repository on pool
+func NewPostgresRepository(pool *pgxpool.Pool) *PostgresRepository {
+	return &PostgresRepository{pool: pool}
+}
+
+// Save upserts a segment
+func (r *PostgresRepository) Save(ctx context.Context, segment *Segment) error {
+	err := r.pool.QueryRow(ctx, `
+		INSERT INTO stream_captions (stream_id, language, start_ms, end_ms, text, tenant_id)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (stream_id, language, start_ms) DO UPDATE SET
+			end_ms = EXCLUDED.end_ms, text = EXCLUDED.text, created_at = NOW()
+		WHERE stream_captions.tenant_id = EXCLUDED.tenant_id
+		RETURNING id, created_at`,
+		segment.StreamID, segment.Language, segment.Start.Milliseconds(), segment.End.Milliseconds(), segment.Text,
+		tenancy.ID(ctx),
+	).Scan(&segment.ID, &segment.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save caption: %w", err)
+	}
+	return nil
+}
+
+// List returns a stream's segments in one language
+func (r *PostgresRepository) List(ctx context.Context, streamID, language string) ([]*Segment, error) {
+	if !store.IsUUID(streamID) {
+		return []*Segment{}, nil
+	}
+
+	rows, err := r.pool.Query(ctx, `
+		SELECT id, stream_id, language, start_ms, end_ms, text, created_at
+		FROM stream_captions
+		WHERE stream_id = $1 AND language = $2`+tenancy.Condition("tenant_id", 3)+`
+		ORDER BY start_ms`,
+		streamID, language, tenancy.Scope(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list captions: %w", err)
+	}
+	defer rows.Close()
+
+	segments := []*Segment{}
+	for rows.Next() {
+		var (
+			segment        Segment
+			startMs, endMs int64
+		)
+		if err := rows.Scan(&segment.ID, &segment.StreamID, &segment.Language, &startMs, &endMs,
+			&segment.Text, &segment.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan caption: %w", err)
+		}
+		segment.Start = time.Duration(startMs) * time.Millisecond
+		segment.End = time.Duration(endMs) * time.Millisecond
+		segments = append(segments, &segment)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list captions: %w", err)
+	}
+	return segments, nil
+}
+
+// Languages returns a stream's caption languages in alphabetical order
+func (r *PostgresRepository) Languages(ctx context.Context, streamID string) ([]string, error) {
+	if !store.IsUUID(streamID) {
+		return []string{}, nil
+	}
+
+	rows, err := r.pool.Query(ctx, `
+		SELECT DISTINCT language FROM stream_captions
+		WHERE stream_id = $1`+tenancy.Condition("tenant_id", 2)+`
+		ORDER BY language`,
+		streamID, tenancy.Scope(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list caption languages: %w", err)
+	}
+	defer rows.Close()
+
+	languages := []string{}
+	for rows.Next() {
+		var language string
+		if err := rows.Scan(&language); err != nil {
+			return nil, fmt.Errorf("failed to scan caption language: %w", err)
+		}
+		languages = append(languages, language)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list caption languages: %w", err)
+	}
+	return languages, nil
+}
@@ -0,0 +1,57 @@
+package captions
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// maxIngestBody bounds a batch of captions
+const maxIngestBody = 1 << 20
+
+// IngestHandler returns the HTTP handler the speech-to-text service posts
+// captions to. Requests must carry token as a bearer token.
+//
+//	POST /captions?stream_id=...   {"segments": [{"language": "en", "text": "...", "start_ms": 0, "end_ms": 1800}]}
+func IngestHandler(service *Service, token string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		presented := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(presented), []byte(token)) != 1 {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		var request struct {
+			Segments []Input `json:"segments"`
+		}
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxIngestBody)).Decode(&request); err != nil || len(request.Segments) == 0 {
+			http.Error(w, "Bad request", http.StatusBadRequest)
+			return
+		}
+
+		segments, err := service.Ingest(r.Context(), r.URL.Query().Get("stream_id"), request.Segments)
+		switch {
+		case errors.Is(err, ErrStreamNotFound):
+			http.Error(w, "Not found", http.StatusNotFound)
+		case errors.Is(err, ErrStreamNotStarted):
+			http.Error(w, err.Error(), http.StatusConflict)
+		case errors.Is(err, ErrInvalidLanguage), errors.Is(err, ErrInvalidTiming),
+			errors.Is(err, ErrEmptyText), errors.Is(err, ErrTextTooLong):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case err != nil:
+			log.Printf("Error ingesting captions: err=%v", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+		default:
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(map[string]interface{}{"stored": len(segments)})
+		}
+	}
+}
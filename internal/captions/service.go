@@ -0,0 +1,194 @@
+package captions
+
+import (
+	"context"
+	"errors"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/tinle0301/streaming-platform-api/internal/events"
+	"github.com/tinle0301/streaming-platform-api/internal/highlights"
+	"github.com/tinle0301/streaming-platform-api/internal/store"
+	"github.com/tinle0301/streaming-platform-api/internal/tenancy"
+)
+
+const (
+	// maxTextLength bounds a single caption's text
+	maxTextLength = 500
+
+	// maxSegmentLength bounds how long a single caption stays on screen
+	maxSegmentLength = 30 * time.Second
+)
+
+// languagePattern accepts BCP 47 tags like "en", "pt-BR", or "zh-Hant"
+var languagePattern = regexp.MustCompile(`^[a-z]{2,3}(-[A-Za-z0-9]{2,8})*$`)
+
+// Service errors
+var (
+	ErrStreamNotFound   = errors.New("stream not found")
+	ErrStreamNotStarted = errors.New("stream has not started")
+	ErrInvalidLanguage  = errors.New("language must be a BCP 47 tag such as en or pt-BR")
+	ErrInvalidTiming    = errors.New("caption must end after it starts, within the broadcast")
+	ErrEmptyText        = errors.New("caption text is required")
+	ErrTextTooLong      = errors.New("caption text is too long")
+)
+
+// Input is a caption as sent by the speech-to-text service. It is timed
+// either with offsets from the start of the broadcast or with wall-clock
+// times, which are aligned to the broadcast here.
+type Input struct {
+	Language string     `json:"language"`
+	Text     string     `json:"text"`
+	StartMs  *int64     `json:"start_ms,omitempty"`
+	EndMs    *int64     `json:"end_ms,omitempty"`
+	StartAt  *time.Time `json:"start_at,omitempty"`
+	EndAt    *time.Time `json:"end_at,omitempty"`
+}
+
+// Service stores captions and relays them to viewers
+type Service struct {
+	repo    Repository
+	streams store.StreamRepository
+
+	publisher events.Publisher
+}
+
+// NewService creates a caption service
+func NewService(repo Repository, streams store.StreamRepository) *Service {
+	return &Service{repo: repo, streams: streams}
+}
+
+// SetPublisher enables caption.segment events for the WebSocket servers
+func (s *Service) SetPublisher(publisher events.Publisher) {
+	s.publisher = publisher
+}
+
+// Ingest validates, aligns, and stores a batch of captions for a stream and
+// publishes each one. Nothing is stored if any caption is invalid.
+func (s *Service) Ingest(ctx context.Context, streamID string, inputs []Input) ([]*Segment, error) {
+	stream, err := s.streams.Get(ctx, streamID)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, ErrStreamNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if stream.StartedAt == nil {
+		return nil, ErrStreamNotStarted
+	}
+
+	segments := make([]*Segment, 0, len(inputs))
+	for _, input := range inputs {
+		segment, err := align(stream, input)
+		if err != nil {
+			return nil, err
+		}
+		segments = append(segments, segment)
+	}
+
+	// The speech-to-text service is shared, so captions follow the stream's tenant
+	ctx = tenancy.WithTenant(ctx, stream.TenantID)
+	for _, segment := range segments {
+		if err := s.repo.Save(ctx, segment); err != nil {
+			return nil, err
+		}
+		s.publish(ctx, segment)
+	}
+	return segments, nil
+}
+
+// Languages returns the languages a stream has captions in
+func (s *Service) Languages(ctx context.Context, streamID string) ([]string, error) {
+	return s.repo.Languages(ctx, streamID)
+}
+
+// ForVOD returns a VOD's captions in one language, timed from the start of
+// the VOD. Captions crossing a segment boundary are cut at it.
+func (s *Service) ForVOD(ctx context.Context, vod *highlights.VOD, language string) ([]*Segment, error) {
+	captions, err := s.repo.List(ctx, vod.StreamID, language)
+	if err != nil {
+		return nil, err
+	}
+	return mapToSegments(captions, vod.Segments), nil
+}
+
+// publish relays a stored caption to the stream's caption channel. The
+// caption is already saved, so a lost event only costs live viewers a line.
+func (s *Service) publish(ctx context.Context, segment *Segment) {
+	if s.publisher == nil {
+		return
+	}
+
+	event := events.NewCaptionSegmentEvent(segment.StreamID, map[string]interface{}{
+		"caption_id": segment.ID,
+		"language":   segment.Language,
+		"start_ms":   segment.Start.Milliseconds(),
+		"end_ms":     segment.End.Milliseconds(),
+		"text":       segment.Text,
+	})
+	if err := s.publisher.Publish(ctx, event); err != nil {
+		log.Printf("Error publishing caption: streamID=%s, captionID=%s, err=%v", segment.StreamID, segment.ID, err)
+	}
+}
+
+// align validates input and times it from the start of stream
+func align(stream *store.Stream, input Input) (*Segment, error) {
+	if !languagePattern.MatchString(input.Language) {
+		return nil, ErrInvalidLanguage
+	}
+	text := strings.TrimSpace(input.Text)
+	if text == "" {
+		return nil, ErrEmptyText
+	}
+	if len(text) > maxTextLength {
+		return nil, ErrTextTooLong
+	}
+
+	var start, end time.Duration
+	switch {
+	case input.StartMs != nil && input.EndMs != nil:
+		start = time.Duration(*input.StartMs) * time.Millisecond
+		end = time.Duration(*input.EndMs) * time.Millisecond
+	case input.StartAt != nil && input.EndAt != nil:
+		start = input.StartAt.Sub(*stream.StartedAt).Truncate(time.Millisecond)
+		end = input.EndAt.Sub(*stream.StartedAt).Truncate(time.Millisecond)
+	default:
+		return nil, ErrInvalidTiming
+	}
+	if start < 0 || end <= start || end-start > maxSegmentLength {
+		return nil, ErrInvalidTiming
+	}
+	if stream.EndedAt != nil && start > stream.EndedAt.Sub(*stream.StartedAt) {
+		return nil, ErrInvalidTiming
+	}
+
+	return &Segment{
+		StreamID: stream.ID,
+		Language: input.Language,
+		Start:    start,
+		End:      end,
+		Text:     text,
+	}, nil
+}
+
+// mapToSegments retimes broadcast captions onto a VOD made of ranges,
+// played back to back
+func mapToSegments(captions []*Segment, ranges []highlights.Segment) []*Segment {
+	mapped := []*Segment{}
+	var base time.Duration
+	for _, r := range ranges {
+		for _, caption := range captions {
+			if caption.End <= r.Start || caption.Start >= r.End {
+				continue
+			}
+			clipped := *caption
+			clipped.Start = base + max(caption.Start, r.Start) - r.Start
+			clipped.End = base + min(caption.End, r.End) - r.Start
+			mapped = append(mapped, &clipped)
+		}
+		base += r.Duration()
+	}
+	return mapped
+}
@@ -0,0 +1,255 @@
+// Package chatactivity records how many chat messages each stream room gets
+// in fixed time buckets. WebSocket nodes count messages in memory and add
+// them to per-stream Redis hashes, so the API server can find a broadcast's
+// busiest moments, live or after the fact. Messages that are mostly emotes
+// are counted separately to spot emote bursts.
+package chatactivity
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// BucketSize is the width of each activity bucket
+	BucketSize = 10 * time.Second
+
+	// keyPrefix namespaces activity hashes in Redis
+	keyPrefix = "chat_activity:"
+
+	// spikeKeyPrefix namespaces the spike claims of ClaimSpike
+	spikeKeyPrefix = "chat_spike:"
+
+	// FlushInterval is how often recorded counts are written to Redis, so
+	// how far Redis can lag behind the chat
+	FlushInterval = 5 * time.Second
+
+	// emotesSuffix marks the hash fields counting emote messages
+	emotesSuffix = ":emotes"
+
+	// retention is how long a stream's activity is kept after its last message
+	retention = 7 * 24 * time.Hour
+)
+
+// Bucket is the chat activity of a stream in one BucketSize window
+type Bucket struct {
+	Start    time.Time
+	Messages int
+
+	// Emotes counts the messages that were mostly emotes
+	Emotes int
+}
+
+// Store reads chat activity from Redis
+type Store struct {
+	client *redis.Client
+}
+
+// NewStore creates a chat activity reader on client
+func NewStore(client *redis.Client) *Store {
+	return &Store{client: client}
+}
+
+// Buckets returns a stream's non-empty buckets starting in [from, to), oldest first
+func (s *Store) Buckets(ctx context.Context, streamID string, from, to time.Time) ([]Bucket, error) {
+	fields, err := s.client.HGetAll(ctx, activityKey(streamID)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read chat activity: %w", err)
+	}
+
+	byStart := make(map[int64]*Bucket)
+	for field, value := range fields {
+		emotes := strings.HasSuffix(field, emotesSuffix)
+		start, err := strconv.ParseInt(strings.TrimSuffix(field, emotesSuffix), 10, 64)
+		if err != nil {
+			continue
+		}
+		count, err := strconv.Atoi(value)
+		if err != nil {
+			continue
+		}
+		if t := time.Unix(start, 0); t.Before(from) || !t.Before(to) {
+			continue
+		}
+
+		bucket := byStart[start]
+		if bucket == nil {
+			bucket = &Bucket{Start: time.Unix(start, 0)}
+			byStart[start] = bucket
+		}
+		if emotes {
+			bucket.Emotes = count
+		} else {
+			bucket.Messages = count
+		}
+	}
+
+	buckets := make([]Bucket, 0, len(byStart))
+	for _, bucket := range byStart {
+		buckets = append(buckets, *bucket)
+	}
+	sort.Slice(buckets, func(i, j int) bool { return buckets[i].Start.Before(buckets[j].Start) })
+	return buckets, nil
+}
+
+// Recent returns a stream's last n buckets ending at until, oldest first.
+// Buckets without chat are included with zero counts, so callers can compare
+// the newest bucket against the ones before it.
+func (s *Store) Recent(ctx context.Context, streamID string, n int, until time.Time) ([]Bucket, error) {
+	if n <= 0 {
+		return nil, nil
+	}
+	first := BucketStart(until).Add(-time.Duration(n) * BucketSize)
+
+	fields := make([]string, 0, 2*n)
+	for i := 0; i < n; i++ {
+		field := strconv.FormatInt(first.Add(time.Duration(i)*BucketSize).Unix(), 10)
+		fields = append(fields, field, field+emotesSuffix)
+	}
+	values, err := s.client.HMGet(ctx, activityKey(streamID), fields...).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read chat activity: %w", err)
+	}
+
+	buckets := make([]Bucket, n)
+	for i := range buckets {
+		buckets[i] = Bucket{
+			Start:    first.Add(time.Duration(i) * BucketSize),
+			Messages: countValue(values[2*i]),
+			Emotes:   countValue(values[2*i+1]),
+		}
+	}
+	return buckets, nil
+}
+
+// ClaimSpike reports whether the caller is the first to claim a spike in the
+// stream's chat within cooldown, so replicas watching the same stream mark
+// each spike once
+func (s *Store) ClaimSpike(ctx context.Context, streamID string, cooldown time.Duration) (bool, error) {
+	claimed, err := s.client.SetNX(ctx, spikeKeyPrefix+streamID, time.Now().Unix(), cooldown).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to claim chat spike: %w", err)
+	}
+	return claimed, nil
+}
+
+// Recorder counts chat messages per room on a WebSocket node and flushes
+// them to Redis. It implements the hub's chat observer.
+type Recorder struct {
+	client *redis.Client
+
+	mu sync.Mutex
+	// room -> bucket start (unix seconds) -> counts since the last flush
+	pending map[string]map[int64]*counts
+}
+
+// counts is a bucket's activity recorded since the last flush
+type counts struct {
+	messages int
+	emotes   int
+}
+
+// NewRecorder creates a recorder writing to client
+func NewRecorder(client *redis.Client) *Recorder {
+	return &Recorder{
+		client:  client,
+		pending: make(map[string]map[int64]*counts),
+	}
+}
+
+// ChatMessage counts a message relayed to room. It is called on the chat
+// path, so it only updates memory.
+func (r *Recorder) ChatMessage(room, userID, text string) {
+	bucket := BucketStart(time.Now()).Unix()
+	emotes := IsEmoteMessage(text)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.pending[room] == nil {
+		r.pending[room] = make(map[int64]*counts)
+	}
+	c := r.pending[room][bucket]
+	if c == nil {
+		c = &counts{}
+		r.pending[room][bucket] = c
+	}
+	c.messages++
+	if emotes {
+		c.emotes++
+	}
+}
+
+// Run flushes recorded counts until ctx is cancelled, then flushes once more
+func (r *Recorder) Run(ctx context.Context) {
+	ticker := time.NewTicker(FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			flushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			r.flush(flushCtx)
+			cancel()
+			return
+		case <-ticker.C:
+			r.flush(ctx)
+		}
+	}
+}
+
+// flush adds pending counts to each room's activity hash
+func (r *Recorder) flush(ctx context.Context) {
+	r.mu.Lock()
+	pending := r.pending
+	r.pending = make(map[string]map[int64]*counts)
+	r.mu.Unlock()
+
+	if len(pending) == 0 {
+		return
+	}
+
+	pipe := r.client.Pipeline()
+	for room, buckets := range pending {
+		key := activityKey(room)
+		for bucket, c := range buckets {
+			field := strconv.FormatInt(bucket, 10)
+			pipe.HIncrBy(ctx, key, field, int64(c.messages))
+			if c.emotes > 0 {
+				pipe.HIncrBy(ctx, key, field+emotesSuffix, int64(c.emotes))
+			}
+		}
+		pipe.Expire(ctx, key, retention)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		log.Printf("Error flushing chat activity: %v", err)
+	}
+}
+
+// BucketStart returns the start of the bucket containing t
+func BucketStart(t time.Time) time.Time {
+	return t.Truncate(BucketSize)
+}
+
+// countValue converts an HMGET value to a count; missing fields are zero
+func countValue(value interface{}) int {
+	s, ok := value.(string)
+	if !ok {
+		return 0
+	}
+	n, _ := strconv.Atoi(s)
+	return n
+}
+
+// activityKey is the Redis key of a stream's activity hash
+func activityKey(streamID string) string {
+	return keyPrefix + streamID
+}
@@ -0,0 +1,61 @@
+package chatactivity
+
+import (
+	"strings"
+	"unicode"
+)
+
+// minEmoteWall is how many times a lone word must repeat for a message to
+// count as an emote wall, e.g. "LUL LUL LUL"
+const minEmoteWall = 3
+
+// IsEmoteMessage reports whether a chat message is mostly emotes: at least
+// half its words are :shortcode: emotes or emoji, or it repeats one word
+// over and over the way chat spams a channel emote
+func IsEmoteMessage(text string) bool {
+	words := strings.Fields(text)
+	if len(words) == 0 {
+		return false
+	}
+
+	emotes := 0
+	repeated := true
+	for _, word := range words {
+		if isShortcode(word) || isEmoji(word) {
+			emotes++
+		}
+		if word != words[0] {
+			repeated = false
+		}
+	}
+	return 2*emotes >= len(words) || (repeated && len(words) >= minEmoteWall)
+}
+
+// isShortcode reports whether word is an emote shortcode like :pog:
+func isShortcode(word string) bool {
+	if len(word) < 3 || word[0] != ':' || word[len(word)-1] != ':' {
+		return false
+	}
+	for _, r := range word[1 : len(word)-1] {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' && r != '-' {
+			return false
+		}
+	}
+	return true
+}
+
+// isEmoji reports whether word is made of emoji, allowing the joiners and
+// modifiers that combine them
+func isEmoji(word string) bool {
+	symbols := 0
+	for _, r := range word {
+		switch {
+		case unicode.Is(unicode.So, r):
+			symbols++
+		case unicode.Is(unicode.Mn, r), unicode.Is(unicode.Cf, r), unicode.Is(unicode.Sk, r):
+		default:
+			return false
+		}
+	}
+	return symbols > 0
+}
@@ -0,0 +1,219 @@
+// Package chathistory keeps each chat room's latest messages in a capped
+// Redis list, so viewers joining a room can read what was said before they
+// arrived. WebSocket nodes append relayed messages and record deletions in
+// memory and flush them to Redis; the API server reads the history with
+// deleted messages left out.
+package chathistory
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// Capacity is how many of a room's latest messages are kept
+	Capacity = 200
+
+	// MaxLimit bounds how many messages one read returns
+	MaxLimit = 100
+
+	// FlushInterval is how often recorded messages are written to Redis, so
+	// how far the history can lag behind the chat
+	FlushInterval = time.Second
+
+	// keyPrefix namespaces history lists in Redis
+	keyPrefix = "chat_history:"
+
+	// deletedSuffix marks the set of a room's deleted message IDs
+	deletedSuffix = ":deleted"
+
+	// retention is how long a room's history is kept after its last message
+	retention = 24 * time.Hour
+)
+
+// Message is a chat message relayed to a room
+type Message struct {
+	ID     string    `json:"id"`
+	Room   string    `json:"room"`
+	UserID string    `json:"user_id"`
+	Text   string    `json:"message"`
+	SentAt time.Time `json:"sent_at"`
+
+	// Moderator is set for messages from the room's streamer and moderators
+	Moderator bool `json:"moderator,omitempty"`
+
+	// SubscriberTier is the sender's subscription tier to the channel, if
+	// they are subscribed
+	SubscriberTier string `json:"subscriber_tier,omitempty"`
+}
+
+// Store reads chat history from Redis
+type Store struct {
+	client *redis.Client
+}
+
+// NewStore creates a chat history reader on client
+func NewStore(client *redis.Client) *Store {
+	return &Store{client: client}
+}
+
+// Before returns up to limit of a room's latest messages sent before before
+// (or the latest if before is zero), oldest first. Deleted messages are
+// left out.
+func (s *Store) Before(ctx context.Context, room string, before time.Time, limit int) ([]Message, error) {
+	if limit <= 0 {
+		return []Message{}, nil
+	}
+
+	pipe := s.client.Pipeline()
+	entriesCmd := pipe.LRange(ctx, historyKey(room), 0, -1)
+	deletedCmd := pipe.SMembers(ctx, historyKey(room)+deletedSuffix)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, fmt.Errorf("failed to read chat history: %w", err)
+	}
+
+	deleted := make(map[string]bool)
+	for _, id := range deletedCmd.Val() {
+		deleted[id] = true
+	}
+
+	// The list is newest first
+	messages := make([]Message, 0, limit)
+	for _, entry := range entriesCmd.Val() {
+		var message Message
+		if err := json.Unmarshal([]byte(entry), &message); err != nil {
+			continue
+		}
+		if deleted[message.ID] || (!before.IsZero() && !message.SentAt.Before(before)) {
+			continue
+		}
+		messages = append(messages, message)
+		if len(messages) == limit {
+			break
+		}
+	}
+
+	for i, j := 0, len(messages)-1; i < j; i, j = i+1, j-1 {
+		messages[i], messages[j] = messages[j], messages[i]
+	}
+	return messages, nil
+}
+
+// Recorder collects the chat relayed on a WebSocket node and flushes it to
+// Redis. It implements the hub's chat history.
+type Recorder struct {
+	client *redis.Client
+
+	mu sync.Mutex
+	// room -> messages and deleted message IDs since the last flush
+	pending map[string][]Message
+	deleted map[string][]string
+}
+
+// NewRecorder creates a recorder writing to client
+func NewRecorder(client *redis.Client) *Recorder {
+	return &Recorder{
+		client:  client,
+		pending: make(map[string][]Message),
+		deleted: make(map[string][]string),
+	}
+}
+
+// Append records a message relayed to room. It is called on the chat path,
+// so it only updates memory.
+func (r *Recorder) Append(room, messageID, userID, text string, moderator bool, subscriberTier string, sentAt time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.pending[room] = append(r.pending[room], Message{
+		ID:             messageID,
+		Room:           room,
+		UserID:         userID,
+		Text:           text,
+		SentAt:         sentAt,
+		Moderator:      moderator,
+		SubscriberTier: subscriberTier,
+	})
+}
+
+// Delete records that a message was deleted from room
+func (r *Recorder) Delete(room, messageID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.deleted[room] = append(r.deleted[room], messageID)
+}
+
+// Run flushes recorded chat until ctx is cancelled, then flushes once more
+func (r *Recorder) Run(ctx context.Context) {
+	ticker := time.NewTicker(FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			flushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			r.flush(flushCtx)
+			cancel()
+			return
+		case <-ticker.C:
+			r.flush(ctx)
+		}
+	}
+}
+
+// flush pushes pending messages onto each room's list, trims it to
+// Capacity, and adds deleted IDs to the room's deleted set
+func (r *Recorder) flush(ctx context.Context) {
+	r.mu.Lock()
+	pending, deleted := r.pending, r.deleted
+	r.pending = make(map[string][]Message)
+	r.deleted = make(map[string][]string)
+	r.mu.Unlock()
+
+	if len(pending) == 0 && len(deleted) == 0 {
+		return
+	}
+
+	pipe := r.client.Pipeline()
+	for room, messages := range pending {
+		key := historyKey(room)
+		entries := make([]interface{}, 0, len(messages))
+		for _, message := range messages {
+			entry, err := json.Marshal(message)
+			if err != nil {
+				continue
+			}
+			entries = append(entries, entry)
+		}
+		if len(entries) == 0 {
+			continue
+		}
+		pipe.LPush(ctx, key, entries...)
+		pipe.LTrim(ctx, key, 0, Capacity-1)
+		pipe.Expire(ctx, key, retention)
+	}
+	for room, ids := range deleted {
+		key := historyKey(room) + deletedSuffix
+		members := make([]interface{}, 0, len(ids))
+		for _, id := range ids {
+			members = append(members, id)
+		}
+		pipe.SAdd(ctx, key, members...)
+		pipe.Expire(ctx, key, retention)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		log.Printf("Error flushing chat history: %v", err)
+	}
+}
+
+// historyKey is the Redis key of a room's history list
+func historyKey(room string) string {
+	return keyPrefix + room
+}
@@ -0,0 +1,162 @@
+// Package cheers records bits viewers cheer in streams and ranks their
+// top cheerers, per stream and over all of a channel's streams
+package cheers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/tinle0301/streaming-platform-api/internal/store"
+	"github.com/tinle0301/streaming-platform-api/internal/tenancy"
+)
+
+// Repository errors
+var (
+	ErrNotFound         = errors.New("stream not found")
+	ErrInsufficientBits = errors.New("you don't have enough bits for this cheer")
+)
+
+// Cheer is bits a viewer cheered in a stream
+type Cheer struct {
+	ID        string
+	StreamID  string
+	ChannelID string
+	UserID    string
+	Bits      int
+	Message   string
+	CreatedAt time.Time
+}
+
+// Cheerer is a viewer's place on a leaderboard
+type Cheerer struct {
+	UserID string
+	Bits   int64
+}
+
+// Repository persists cheers and leaderboards. Cheers are recorded in the
+// context's tenant, and leaderboards only count the context's tenant's.
+type Repository interface {
+	// Record debits a cheer's bits from the viewer's balance, stores the
+	// cheer, adds it to the viewer's all-time total for the channel, and
+	// fills in its generated ID and timestamp. It fails with
+	// ErrInsufficientBits, recording nothing, if the balance is too low.
+	Record(ctx context.Context, cheer *Cheer) error
+
+	// TopForStream returns the viewers who cheered the most bits in a
+	// stream, most first
+	TopForStream(ctx context.Context, streamID string, limit int) ([]Cheerer, error)
+
+	// TopForChannel returns the viewers who cheered the most bits in all of
+	// a channel's streams, most first
+	TopForChannel(ctx context.Context, channelID string, limit int) ([]Cheerer, error)
+}
+
+// PostgresRepository implements Repository on PostgreSQL
+type PostgresRepository struct {
+	pool *pgxpool.Pool
+}
+
+// NewPostgresRepository creates a cheer repository on pool
+func NewPostgresRepository(pool *pgxpool.Pool) *PostgresRepository {
+	return &PostgresRepository{pool: pool}
+}
+
+// Record debits the viewer's bits, inserts a cheer, and updates the
+// channel's all-time totals in one transaction
+func (r *PostgresRepository) Record(ctx context.Context, cheer *Cheer) error {
+	if !store.IsUUID(cheer.StreamID) || !store.IsUUID(cheer.UserID) {
+		return ErrNotFound
+	}
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, `
+		UPDATE bits_balances SET bits = bits - $3, updated_at = NOW()
+		WHERE tenant_id = $1 AND user_id = $2 AND bits >= $3`,
+		tenancy.ID(ctx), cheer.UserID, cheer.Bits)
+	if err != nil {
+		return fmt.Errorf("failed to debit bits: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrInsufficientBits
+	}
+
+	err = tx.QueryRow(ctx, `
+		INSERT INTO cheers (stream_id, channel_id, user_id, bits, message, tenant_id)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id::text, created_at`,
+		cheer.StreamID, cheer.ChannelID, cheer.UserID, cheer.Bits, cheer.Message, tenancy.ID(ctx),
+	).Scan(&cheer.ID, &cheer.CreatedAt)
+	if store.PgCode(err) == "23503" {
+		return ErrNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to record cheer: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO channel_cheer_totals (channel_id, user_id, bits, tenant_id)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (channel_id, user_id) DO UPDATE SET
+			bits = channel_cheer_totals.bits + EXCLUDED.bits, updated_at = NOW()`,
+		cheer.ChannelID, cheer.UserID, cheer.Bits, tenancy.ID(ctx))
+	if err != nil {
+		return fmt.Errorf("failed to update cheer totals: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit cheer: %w", err)
+	}
+	return nil
+}
+
+// TopForStream sums a stream's cheers per viewer
+func (r *PostgresRepository) TopForStream(ctx context.Context, streamID string, limit int) ([]Cheerer, error) {
+	if !store.IsUUID(streamID) {
+		return []Cheerer{}, nil
+	}
+	return r.top(ctx, `
+		SELECT user_id::text, SUM(bits) FROM cheers
+		WHERE stream_id = $1`+tenancy.Condition("tenant_id", 3)+`
+		GROUP BY user_id
+		ORDER BY SUM(bits) DESC, MIN(created_at)
+		LIMIT $2`, streamID, limit, tenancy.Scope(ctx))
+}
+
+// TopForChannel reads a channel's all-time totals
+func (r *PostgresRepository) TopForChannel(ctx context.Context, channelID string, limit int) ([]Cheerer, error) {
+	return r.top(ctx, `
+		SELECT user_id::text, bits FROM channel_cheer_totals
+		WHERE channel_id = $1`+tenancy.Condition("tenant_id", 3)+`
+		ORDER BY bits DESC, updated_at
+		LIMIT $2`, channelID, limit, tenancy.Scope(ctx))
+}
+
+// top runs a leaderboard query
+func (r *PostgresRepository) top(ctx context.Context, query string, args ...interface{}) ([]Cheerer, error) {
+	rows, err := r.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list top cheerers: %w", err)
+	}
+	defer rows.Close()
+
+	cheerers := []Cheerer{}
+	for rows.Next() {
+		var c Cheerer
+		if err := rows.Scan(&c.UserID, &c.Bits); err != nil {
+			return nil, fmt.Errorf("failed to scan cheerer: %w", err)
+		}
+		cheerers = append(cheerers, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list top cheerers: %w", err)
+	}
+	return cheerers, nil
+}
@@ -0,0 +1,182 @@
+package cheers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"unicode/utf8"
+
+	"github.com/tinle0301/streaming-platform-api/internal/events"
+	"github.com/tinle0301/streaming-platform-api/internal/store"
+	"github.com/tinle0301/streaming-platform-api/internal/users"
+)
+
+const (
+	// MaxBits bounds the bits of one cheer
+	MaxBits = 100000
+
+	// MaxMessageLength bounds a cheer's message, in characters
+	MaxMessageLength = 500
+
+	// MaxLeaderboard bounds how many cheerers a leaderboard returns
+	MaxLeaderboard = 100
+)
+
+// Leaderboard periods, matching the GraphQL CheerPeriod enum
+const (
+	PeriodStream  = "STREAM"
+	PeriodAllTime = "ALL_TIME"
+)
+
+// Validation errors
+var (
+	ErrInvalidBits    = errors.New("bits must be between 1 and 100000")
+	ErrMessageTooLong = errors.New("cheer messages are limited to 500 characters")
+	ErrNotLive        = errors.New("you can only cheer in a live stream")
+	ErrSelfCheer      = errors.New("you can't cheer in your own stream")
+	ErrInvalidPeriod  = errors.New("period must be STREAM or ALL_TIME")
+)
+
+// animations are the cheer animations by the least bits that play them,
+// largest first
+var animations = []struct {
+	minBits int
+	name    string
+}{
+	{10000, "cheer10000"},
+	{5000, "cheer5000"},
+	{1000, "cheer1000"},
+	{100, "cheer100"},
+	{1, "cheer1"},
+}
+
+// Animation names the animation played in the room for a cheer of bits
+func Animation(bits int) string {
+	for _, a := range animations {
+		if bits >= a.minBits {
+			return a.name
+		}
+	}
+	return animations[len(animations)-1].name
+}
+
+// Profiles looks up the users named in cheer events
+type Profiles interface {
+	GetMany(ctx context.Context, ids []string) (map[string]*users.User, error)
+}
+
+// Service records cheers, announces them in the stream's room, and ranks
+// cheerers
+type Service struct {
+	streams   store.StreamRepository
+	repo      Repository
+	profiles  Profiles
+	publisher events.Publisher
+}
+
+// NewService creates a cheer service; profiles name cheerers in events
+func NewService(streams store.StreamRepository, repo Repository, profiles Profiles) *Service {
+	return &Service{streams: streams, repo: repo, profiles: profiles}
+}
+
+// SetPublisher enables bits.cheered events, which play the cheer in the
+// stream's room and count toward the channel's goals
+func (s *Service) SetPublisher(publisher events.Publisher) {
+	s.publisher = publisher
+}
+
+// Cheer records bits userID cheered in a live stream and announces them
+func (s *Service) Cheer(ctx context.Context, streamID, userID string, bits int, message string) (*Cheer, error) {
+	if bits < 1 || bits > MaxBits {
+		return nil, ErrInvalidBits
+	}
+	if utf8.RuneCountInString(message) > MaxMessageLength {
+		return nil, ErrMessageTooLong
+	}
+	stream, err := s.stream(ctx, streamID)
+	if err != nil {
+		return nil, err
+	}
+	if stream.StreamerID == userID {
+		return nil, ErrSelfCheer
+	}
+	if stream.Status != store.StreamStatusLive {
+		return nil, ErrNotLive
+	}
+
+	cheer := &Cheer{
+		StreamID:  stream.ID,
+		ChannelID: stream.StreamerID,
+		UserID:    userID,
+		Bits:      bits,
+		Message:   message,
+	}
+	if err := s.repo.Record(ctx, cheer); err != nil {
+		return nil, err
+	}
+	s.publish(ctx, cheer)
+	return cheer, nil
+}
+
+// TopCheerers returns up to limit of a stream's top cheerers: in the
+// stream for PeriodStream, in all of its channel's streams for
+// PeriodAllTime
+func (s *Service) TopCheerers(ctx context.Context, streamID, period string, limit int) ([]Cheerer, error) {
+	if limit > MaxLeaderboard {
+		limit = MaxLeaderboard
+	}
+	if limit <= 0 {
+		return []Cheerer{}, nil
+	}
+
+	switch period {
+	case PeriodStream:
+		return s.repo.TopForStream(ctx, streamID, limit)
+	case PeriodAllTime:
+		stream, err := s.stream(ctx, streamID)
+		if err != nil {
+			return nil, err
+		}
+		return s.repo.TopForChannel(ctx, stream.StreamerID, limit)
+	default:
+		return nil, ErrInvalidPeriod
+	}
+}
+
+// stream looks up a stream, mapping a missing one to ErrNotFound
+func (s *Service) stream(ctx context.Context, streamID string) (*store.Stream, error) {
+	stream, err := s.streams.Get(ctx, streamID)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, ErrNotFound
+	}
+	return stream, err
+}
+
+// publish publishes bits.cheered; the event's data is the animated cheer
+// message shown in the room
+func (s *Service) publish(ctx context.Context, cheer *Cheer) {
+	if s.publisher == nil {
+		return
+	}
+
+	var username, displayName string
+	found, err := s.profiles.GetMany(ctx, []string{cheer.UserID})
+	if err != nil {
+		log.Printf("Error loading cheerer: %v", err)
+	} else if u := found[cheer.UserID]; u != nil {
+		username, displayName = u.Username, u.DisplayName
+	}
+
+	event := events.NewBitsCheeredEvent(cheer.StreamID, cheer.ChannelID, map[string]interface{}{
+		"cheer_id":             cheer.ID,
+		"cheerer_id":           cheer.UserID,
+		"cheerer_username":     username,
+		"cheerer_display_name": displayName,
+		"bits":                 cheer.Bits,
+		"message":              cheer.Message,
+		"animation":            Animation(cheer.Bits),
+	})
+	if err := s.publisher.Publish(ctx, event); err != nil {
+		log.Printf("Error publishing cheer: stream=%s, cheer=%s, err=%v", cheer.StreamID, cheer.ID, err)
+	}
+}
@@ -0,0 +1,202 @@
+package clips
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/tinle0301/streaming-platform-api/internal/store"
+	"github.com/tinle0301/streaming-platform-api/internal/tenancy"
+)
+
+// Clip statuses. Clips whose footage matched a known-illegal hash are
+// QUARANTINED by the safety scanner and treated as missing.
+const (
+	StatusDraft       = "DRAFT"
+	StatusPublic      = "PUBLIC"
+	StatusQuarantined = "QUARANTINED"
+)
+
+// Repository errors
+var (
+	ErrNotFound       = errors.New("clip not found")
+	ErrNotDraft       = errors.New("clip is already published")
+	ErrStreamNotFound = errors.New("stream not found")
+)
+
+// Clip is a segment cut from a stream. Offsets are measured from the start
+// of the stream.
+type Clip struct {
+	ID        string
+	StreamID  string
+	CreatorID string
+	Title     string
+	Status    string
+
+	// SourceStart and SourceEnd bound the footage captured for the clip;
+	// Start and End are the trimmed range within it
+	SourceStart time.Duration
+	SourceEnd   time.Duration
+	Start       time.Duration
+	End         time.Duration
+
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	PublishedAt *time.Time
+}
+
+// Duration is the length of the trimmed clip
+func (c *Clip) Duration() time.Duration {
+	return c.End - c.Start
+}
+
+// Repository persists clips. Clips are created in the context's tenant, and
+// other tenants' clips are treated as missing.
+type Repository interface {
+	// Create stores a draft and fills in its generated ID and timestamps
+	Create(ctx context.Context, clip *Clip) error
+	Get(ctx context.Context, id string) (*Clip, error)
+
+	// UpdateDraft saves a draft's title and trim range; it returns
+	// ErrNotDraft if the clip was published first
+	UpdateDraft(ctx context.Context, clip *Clip) error
+
+	// Publish makes a draft public; it returns ErrNotDraft if the clip was
+	// published first
+	Publish(ctx context.Context, id string) (*Clip, error)
+}
+
+// clipColumns is the column list shared by clip queries
+const clipColumns = `id::text, stream_id::text, creator_id::text, title, status,
+	source_start_ms, source_end_ms, start_ms, end_ms, created_at, updated_at, published_at`
+
+// PostgresRepository implements Repository on PostgreSQL
+type PostgresRepository struct {
+	pool *pgxpool.Pool
+}
+
+// NewPostgresRepository creates a clip repository on pool
+func NewPostgresRepository(pool *pgxpool.Pool) *PostgresRepository {
+	return &PostgresRepository{pool: pool}
+}
+
+// Create inserts a draft clip
+func (r *PostgresRepository) Create(ctx context.Context, clip *Clip) error {
+	if !store.IsUUID(clip.StreamID) {
+		return ErrStreamNotFound
+	}
+	if clip.Status == "" {
+		clip.Status = StatusDraft
+	}
+
+	err := r.pool.QueryRow(ctx, `
+		INSERT INTO clips (stream_id, creator_id, title, status, source_start_ms, source_end_ms, start_ms, end_ms,
+			tenant_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING id::text, created_at, updated_at`,
+		clip.StreamID, clip.CreatorID, clip.Title, clip.Status,
+		clip.SourceStart.Milliseconds(), clip.SourceEnd.Milliseconds(),
+		clip.Start.Milliseconds(), clip.End.Milliseconds(), tenancy.ID(ctx),
+	).Scan(&clip.ID, &clip.CreatedAt, &clip.UpdatedAt)
+	if store.PgCode(err) == "23503" {
+		return ErrStreamNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create clip: %w", err)
+	}
+	return nil
+}
+
+// Get returns a clip by ID
+func (r *PostgresRepository) Get(ctx context.Context, id string) (*Clip, error) {
+	if !store.IsUUID(id) {
+		return nil, ErrNotFound
+	}
+
+	row := r.pool.QueryRow(ctx, `SELECT `+clipColumns+` FROM clips WHERE id = $1 AND status <> 'QUARANTINED'`+
+		tenancy.Condition("tenant_id", 2), id, tenancy.Scope(ctx))
+	clip, err := scanClip(row)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get clip: %w", err)
+	}
+	return clip, nil
+}
+
+// UpdateDraft saves a draft's title and trim range
+func (r *PostgresRepository) UpdateDraft(ctx context.Context, clip *Clip) error {
+	if !store.IsUUID(clip.ID) {
+		return ErrNotFound
+	}
+
+	err := r.pool.QueryRow(ctx, `
+		UPDATE clips SET title = $2, start_ms = $3, end_ms = $4, updated_at = NOW()
+		WHERE id = $1 AND status = 'DRAFT'`+tenancy.Condition("tenant_id", 5)+`
+		RETURNING updated_at`,
+		clip.ID, clip.Title, clip.Start.Milliseconds(), clip.End.Milliseconds(), tenancy.Scope(ctx),
+	).Scan(&clip.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return r.missingOrPublished(ctx, clip.ID)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update clip: %w", err)
+	}
+	return nil
+}
+
+// Publish makes a draft public
+func (r *PostgresRepository) Publish(ctx context.Context, id string) (*Clip, error) {
+	if !store.IsUUID(id) {
+		return nil, ErrNotFound
+	}
+
+	row := r.pool.QueryRow(ctx, `
+		UPDATE clips SET status = 'PUBLIC', published_at = NOW(), updated_at = NOW()
+		WHERE id = $1 AND status = 'DRAFT'`+tenancy.Condition("tenant_id", 2)+`
+		RETURNING `+clipColumns, id, tenancy.Scope(ctx))
+	clip, err := scanClip(row)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, r.missingOrPublished(ctx, id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to publish clip: %w", err)
+	}
+	return clip, nil
+}
+
+// missingOrPublished explains why a draft-only update matched no rows
+func (r *PostgresRepository) missingOrPublished(ctx context.Context, id string) error {
+	var exists bool
+	err := r.pool.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM clips WHERE id = $1 AND status <> 'QUARANTINED'`+
+		tenancy.Condition("tenant_id", 2)+`)`, id, tenancy.Scope(ctx)).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("failed to get clip: %w", err)
+	}
+	if !exists {
+		return ErrNotFound
+	}
+	return ErrNotDraft
+}
+
+// scanClip reads a row selected with clipColumns
+func scanClip(row pgx.Row) (*Clip, error) {
+	var clip Clip
+	var sourceStart, sourceEnd, start, end int64
+	err := row.Scan(
+		&clip.ID, &clip.StreamID, &clip.CreatorID, &clip.Title, &clip.Status,
+		&sourceStart, &sourceEnd, &start, &end, &clip.CreatedAt, &clip.UpdatedAt, &clip.PublishedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	clip.SourceStart = time.Duration(sourceStart) * time.Millisecond
+	clip.SourceEnd = time.Duration(sourceEnd) * time.Millisecond
+	clip.Start = time.Duration(start) * time.Millisecond
+	clip.End = time.Duration(end) * time.Millisecond
+	return &clip, nil
+}
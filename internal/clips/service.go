@@ -0,0 +1,225 @@
+package clips
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/tinle0301/streaming-platform-api/internal/events"
+	"github.com/tinle0301/streaming-platform-api/internal/safety"
+	"github.com/tinle0301/streaming-platform-api/internal/store"
+)
+
+// Clip limits
+const (
+	// MaxDuration is the longest a published clip may run
+	MaxDuration = 60 * time.Second
+
+	// MinDuration is the shortest a clip may be trimmed to
+	MinDuration = 5 * time.Second
+
+	// captureWindow is how much footage before the clip moment a draft keeps
+	// for trimming
+	captureWindow = 90 * time.Second
+
+	// defaultDuration is the initial trim of a new draft, ending at the clip
+	// moment
+	defaultDuration = 30 * time.Second
+
+	maxTitleLength = 100
+)
+
+// Validation errors
+var (
+	ErrStreamNotLive = errors.New("clips can only be created from live streams")
+	ErrNotCreator    = errors.New("only the clip's creator can edit it")
+	ErrInvalidTitle  = fmt.Errorf("title must be 1-%d characters", maxTitleLength)
+	ErrInvalidTrim   = errors.New("trim range must lie within the clip's captured footage")
+	ErrTooLong       = fmt.Errorf("clips can be at most %s long", MaxDuration)
+	ErrTooShort      = fmt.Errorf("clips must be at least %s long", MinDuration)
+	ErrTitleRequired = errors.New("clips need a title before they are published")
+)
+
+// Service manages the clip lifecycle: a draft is captured from a live
+// stream, trimmed and titled by its creator, then published
+type Service struct {
+	repo    Repository
+	streams store.StreamRepository
+
+	publisher events.Publisher
+	scanner   *safety.Scanner
+}
+
+// NewService creates a clip service
+func NewService(repo Repository, streams store.StreamRepository) *Service {
+	return &Service{repo: repo, streams: streams}
+}
+
+// SetPublisher enables clip.published events
+func (s *Service) SetPublisher(publisher events.Publisher) {
+	s.publisher = publisher
+}
+
+// SetScanner has every clip's footage hash-matched before it is published.
+// Publishing fails closed: a clip the scanner can't clear stays a draft.
+func (s *Service) SetScanner(scanner *safety.Scanner) {
+	s.scanner = scanner
+}
+
+// Get returns a clip by ID
+func (s *Service) Get(ctx context.Context, id string) (*Clip, error) {
+	return s.repo.Get(ctx, id)
+}
+
+// CreateDraft captures a draft clip from a live stream, ending now. The
+// draft keeps up to captureWindow of footage for trimming.
+func (s *Service) CreateDraft(ctx context.Context, creatorID, streamID string) (*Clip, error) {
+	stream, err := s.streams.Get(ctx, streamID)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, ErrStreamNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if stream.Status != store.StreamStatusLive || stream.StartedAt == nil {
+		return nil, ErrStreamNotLive
+	}
+
+	moment := time.Since(*stream.StartedAt).Truncate(time.Millisecond)
+	if moment < MinDuration {
+		return nil, ErrTooShort
+	}
+	clip := &Clip{
+		StreamID:    stream.ID,
+		CreatorID:   creatorID,
+		Title:       stream.Title,
+		SourceStart: clampStart(moment, captureWindow),
+		SourceEnd:   moment,
+		Start:       clampStart(moment, defaultDuration),
+		End:         moment,
+	}
+	if runes := []rune(clip.Title); len(runes) > maxTitleLength {
+		clip.Title = strings.TrimSpace(string(runes[:maxTitleLength]))
+	}
+
+	if err := s.repo.Create(ctx, clip); err != nil {
+		return nil, err
+	}
+	log.Printf("Clip draft created: id=%s, stream=%s, creator=%s", clip.ID, clip.StreamID, creatorID)
+	return clip, nil
+}
+
+// Trim sets a draft's range; start and end are offsets into the stream
+func (s *Service) Trim(ctx context.Context, userID, id string, start, end time.Duration) (*Clip, error) {
+	clip, err := s.draft(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if start < clip.SourceStart || end > clip.SourceEnd || end <= start {
+		return nil, ErrInvalidTrim
+	}
+	if end-start > MaxDuration {
+		return nil, ErrTooLong
+	}
+	if end-start < MinDuration {
+		return nil, ErrTooShort
+	}
+
+	clip.Start, clip.End = start, end
+	if err := s.repo.UpdateDraft(ctx, clip); err != nil {
+		return nil, err
+	}
+	return clip, nil
+}
+
+// SetTitle renames a draft
+func (s *Service) SetTitle(ctx context.Context, userID, id, title string) (*Clip, error) {
+	clip, err := s.draft(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	title = strings.TrimSpace(title)
+	if title == "" || len([]rune(title)) > maxTitleLength {
+		return nil, ErrInvalidTitle
+	}
+
+	clip.Title = title
+	if err := s.repo.UpdateDraft(ctx, clip); err != nil {
+		return nil, err
+	}
+	return clip, nil
+}
+
+// Publish makes a draft public and emits clip.published
+func (s *Service) Publish(ctx context.Context, userID, id string) (*Clip, error) {
+	clip, err := s.draft(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if clip.Title == "" {
+		return nil, ErrTitleRequired
+	}
+	if clip.Duration() > MaxDuration {
+		return nil, ErrTooLong
+	}
+	if s.scanner != nil {
+		err := s.scanner.ScanUpload(ctx, safety.Upload{
+			ID:         clip.ID,
+			Kind:       safety.UploadKindClip,
+			UploaderID: clip.CreatorID,
+			StreamID:   clip.StreamID,
+			Start:      clip.Start,
+			End:        clip.End,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	clip, err = s.repo.Publish(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("Clip published: id=%s, stream=%s, creator=%s, duration=%s",
+		clip.ID, clip.StreamID, clip.CreatorID, clip.Duration())
+
+	if s.publisher != nil {
+		event := events.NewClipPublishedEvent(clip.ID, clip.StreamID, clip.CreatorID, map[string]interface{}{
+			"title":            clip.Title,
+			"start_ms":         clip.Start.Milliseconds(),
+			"end_ms":           clip.End.Milliseconds(),
+			"duration_seconds": clip.Duration().Seconds(),
+		})
+		// The clip is already public, so a lost event must not fail the publish
+		if err := s.publisher.Publish(ctx, event); err != nil {
+			log.Printf("Error publishing %s event: clipID=%s, err=%v", event.Type, clip.ID, err)
+		}
+	}
+	return clip, nil
+}
+
+// draft loads a clip userID may edit
+func (s *Service) draft(ctx context.Context, userID, id string) (*Clip, error) {
+	clip, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if clip.CreatorID != userID {
+		return nil, ErrNotCreator
+	}
+	if clip.Status != StatusDraft {
+		return nil, ErrNotDraft
+	}
+	return clip, nil
+}
+
+// clampStart returns end-length, floored at the start of the stream
+func clampStart(end, length time.Duration) time.Duration {
+	if end < length {
+		return 0
+	}
+	return end - length
+}
@@ -0,0 +1,74 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/tinle0301/streaming-platform-api/internal/websocket"
+)
+
+// deliveryStatsKeyPrefix namespaces per-room delivery stats; each key is a
+// hash of counters summed across nodes
+const deliveryStatsKeyPrefix = "ws:delivery_stats:"
+
+// deliveryStatsTTL expires stats for rooms no node has reported recently
+const deliveryStatsTTL = 24 * time.Hour
+
+// Delivery stats hash fields
+const (
+	fieldBroadcasts      = "broadcasts"
+	fieldMessagesSent    = "messages_sent"
+	fieldMessagesDropped = "messages_dropped"
+	fieldFanOutNanos     = "fan_out_nanos"
+)
+
+// RedisDeliveryStats sums room delivery stats across nodes in Redis
+type RedisDeliveryStats struct {
+	client *redis.Client
+}
+
+// NewRedisDeliveryStats creates a delivery stats store
+func NewRedisDeliveryStats(client *redis.Client) *RedisDeliveryStats {
+	return &RedisDeliveryStats{client: client}
+}
+
+// SaveDeliveryStats implements websocket.DeliveryStatsSink by adding stats
+// to each room's counters, so a node restarting or a room reopening only
+// adds to its totals
+func (s *RedisDeliveryStats) SaveDeliveryStats(ctx context.Context, stats map[string]websocket.DeliveryStats) error {
+	pipe := s.client.TxPipeline()
+	for room, roomStats := range stats {
+		key := deliveryStatsKeyPrefix + room
+		pipe.HIncrBy(ctx, key, fieldBroadcasts, roomStats.Broadcasts)
+		pipe.HIncrBy(ctx, key, fieldMessagesSent, roomStats.MessagesSent)
+		pipe.HIncrBy(ctx, key, fieldMessagesDropped, roomStats.MessagesDropped)
+		pipe.HIncrBy(ctx, key, fieldFanOutNanos, roomStats.FanOutNanos)
+		pipe.Expire(ctx, key, deliveryStatsTTL)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to save delivery stats: %w", err)
+	}
+	return nil
+}
+
+// RoomDeliveryStats returns a room's delivery stats summed across nodes
+func (s *RedisDeliveryStats) RoomDeliveryStats(ctx context.Context, room string) (websocket.DeliveryStats, error) {
+	counters, err := s.client.HGetAll(ctx, deliveryStatsKeyPrefix+room).Result()
+	if err != nil {
+		return websocket.DeliveryStats{}, fmt.Errorf("failed to load delivery stats: %w", err)
+	}
+
+	counter := func(field string) int64 {
+		value, _ := strconv.ParseInt(counters[field], 10, 64)
+		return value
+	}
+	return websocket.DeliveryStats{
+		Broadcasts:      counter(fieldBroadcasts),
+		MessagesSent:    counter(fieldMessagesSent),
+		MessagesDropped: counter(fieldMessagesDropped),
+		FanOutNanos:     counter(fieldFanOutNanos),
+	}, nil
+}
@@ -0,0 +1,153 @@
+package cluster
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// nodeKeyPrefix namespaces node registrations in Redis
+	nodeKeyPrefix = "ws:nodes:"
+
+	// nodeTTL is how long a registration survives without a heartbeat
+	nodeTTL = 30 * time.Second
+
+	// heartbeatInterval must be well under nodeTTL
+	heartbeatInterval = 10 * time.Second
+)
+
+// Node describes a WebSocket server instance
+type Node struct {
+	ID          string    `json:"id"`
+	PublicURL   string    `json:"public_url"`
+	Connections int       `json:"connections"`
+	Draining    bool      `json:"draining"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// Registry tracks live WebSocket nodes in Redis with TTL heartbeats
+type Registry struct {
+	client *redis.Client
+
+	// mu guards self.Draining and serializes heartbeats, so one that read
+	// the node before MarkDraining can't overwrite the draining registration
+	mu   sync.Mutex
+	self Node
+
+	// connections reports this node's current connection count
+	connections func() int
+}
+
+// NewRegistry creates a registry entry for this node
+func NewRegistry(client *redis.Client, nodeID, publicURL string, connections func() int) *Registry {
+	return &Registry{
+		client: client,
+		self: Node{
+			ID:        nodeID,
+			PublicURL: publicURL,
+		},
+		connections: connections,
+	}
+}
+
+// Run heartbeats this node's registration until ctx is cancelled, then
+// removes it
+func (r *Registry) Run(ctx context.Context) {
+	ticker := time.NewTicker(heartbeatInterval)
+	defer ticker.Stop()
+
+	r.heartbeat(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			r.deregister()
+			return
+		case <-ticker.C:
+			r.heartbeat(ctx)
+		}
+	}
+}
+
+// MarkDraining advertises that this node is shutting down so peers stop
+// sending it handoffs
+func (r *Registry) MarkDraining(ctx context.Context) {
+	r.mu.Lock()
+	r.self.Draining = true
+	r.mu.Unlock()
+	r.heartbeat(ctx)
+}
+
+// Peers returns the other live, non-draining nodes, least loaded first
+func (r *Registry) Peers(ctx context.Context) ([]Node, error) {
+	var keys []string
+	iter := r.client.Scan(ctx, 0, nodeKeyPrefix+"*", 100).Iterator()
+	for iter.Next(ctx) {
+		keys = append(keys, iter.Val())
+	}
+	if err := iter.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list nodes: %w", err)
+	}
+	if len(keys) == 0 {
+		return nil, nil
+	}
+
+	values, err := r.client.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read nodes: %w", err)
+	}
+
+	peers := make([]Node, 0, len(values))
+	for _, value := range values {
+		raw, ok := value.(string)
+		if !ok {
+			continue
+		}
+		var node Node
+		if err := json.Unmarshal([]byte(raw), &node); err != nil {
+			continue
+		}
+		if node.ID == r.self.ID || node.Draining {
+			continue
+		}
+		peers = append(peers, node)
+	}
+
+	sort.Slice(peers, func(i, j int) bool { return peers[i].Connections < peers[j].Connections })
+	return peers, nil
+}
+
+// heartbeat refreshes this node's registration
+func (r *Registry) heartbeat(ctx context.Context) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	node := r.self
+	node.Connections = r.connections()
+	node.UpdatedAt = time.Now()
+
+	nodeBytes, err := json.Marshal(node)
+	if err != nil {
+		log.Printf("Error marshaling node registration: %v", err)
+		return
+	}
+	if err := r.client.Set(ctx, nodeKeyPrefix+node.ID, nodeBytes, nodeTTL).Err(); err != nil {
+		log.Printf("Error heartbeating node registration: %v", err)
+	}
+}
+
+// deregister removes this node's registration
+func (r *Registry) deregister() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := r.client.Del(ctx, nodeKeyPrefix+r.self.ID).Err(); err != nil {
+		log.Printf("Error removing node registration: %v", err)
+	}
+}
@@ -0,0 +1,54 @@
+package cluster
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/tinle0301/streaming-platform-api/internal/websocket"
+)
+
+// handoffKeyPrefix namespaces transferred resume state in Redis
+const handoffKeyPrefix = "ws:handoff:"
+
+// RedisResumeStore implements websocket.ResumeStore in Redis
+type RedisResumeStore struct {
+	client *redis.Client
+}
+
+// NewRedisResumeStore creates a Redis-backed resume store
+func NewRedisResumeStore(client *redis.Client) *RedisResumeStore {
+	return &RedisResumeStore{client: client}
+}
+
+// Save stores resume state under token until ttl expires
+func (s *RedisResumeStore) Save(ctx context.Context, token string, state websocket.ResumeState, ttl time.Duration) error {
+	stateBytes, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("failed to marshal resume state: %w", err)
+	}
+	if err := s.client.Set(ctx, handoffKeyPrefix+token, stateBytes, ttl).Err(); err != nil {
+		return fmt.Errorf("failed to save resume state: %w", err)
+	}
+	return nil
+}
+
+// Take loads and deletes the resume state for token, so it is used once
+func (s *RedisResumeStore) Take(ctx context.Context, token string) (websocket.ResumeState, error) {
+	raw, err := s.client.GetDel(ctx, handoffKeyPrefix+token).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return websocket.ResumeState{}, websocket.ErrResumeNotFound
+	}
+	if err != nil {
+		return websocket.ResumeState{}, fmt.Errorf("failed to load resume state: %w", err)
+	}
+
+	var state websocket.ResumeState
+	if err := json.Unmarshal(raw, &state); err != nil {
+		return websocket.ResumeState{}, fmt.Errorf("failed to unmarshal resume state: %w", err)
+	}
+	return state, nil
+}
@@ -0,0 +1,119 @@
+package cluster
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/tinle0301/streaming-platform-api/internal/websocket"
+)
+
+// sessionKeyPrefix namespaces dropped sessions in Redis. Each session has
+// its state under the token, plus a :seq counter and a :buffer list of
+// missed messages.
+const sessionKeyPrefix = "ws:session:"
+
+// RedisSessionStore implements websocket.SessionStore in Redis
+type RedisSessionStore struct {
+	client *redis.Client
+}
+
+// NewRedisSessionStore creates a Redis-backed session store
+func NewRedisSessionStore(client *redis.Client) *RedisSessionStore {
+	return &RedisSessionStore{client: client}
+}
+
+// Detach stores a dropped session's state under token until ttl expires
+func (s *RedisSessionStore) Detach(ctx context.Context, token string, state websocket.ResumeState, ttl time.Duration) error {
+	stateBytes, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("failed to marshal session state: %w", err)
+	}
+	if err := s.client.Set(ctx, sessionKeyPrefix+token, stateBytes, ttl).Err(); err != nil {
+		return fmt.Errorf("failed to save session state: %w", err)
+	}
+	return nil
+}
+
+// Buffer numbers messages after the session's last one and appends them to
+// its buffer, trimmed to limit. The buffer expires with the session.
+func (s *RedisSessionStore) Buffer(ctx context.Context, token string, messages [][]byte, limit int) error {
+	key := sessionKeyPrefix + token
+
+	// A resumed or expired session has no state left and no remaining TTL
+	ttl, err := s.client.PTTL(ctx, key).Result()
+	if err != nil {
+		return fmt.Errorf("failed to check session: %w", err)
+	}
+	if ttl <= 0 {
+		return websocket.ErrResumeNotFound
+	}
+
+	last, err := s.client.IncrBy(ctx, key+":seq", int64(len(messages))).Result()
+	if err != nil {
+		return fmt.Errorf("failed to number missed messages: %w", err)
+	}
+
+	first := last - int64(len(messages)) + 1
+	entries := make([]interface{}, 0, len(messages))
+	for i, message := range messages {
+		entry, err := json.Marshal(websocket.BufferedMessage{Seq: first + int64(i), Message: message})
+		if err != nil {
+			return fmt.Errorf("failed to marshal missed message: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+
+	pipe := s.client.TxPipeline()
+	pipe.RPush(ctx, key+":buffer", entries...)
+	pipe.LTrim(ctx, key+":buffer", int64(-limit), -1)
+	pipe.PExpire(ctx, key+":buffer", ttl)
+	pipe.PExpire(ctx, key+":seq", ttl)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to buffer missed messages: %w", err)
+	}
+	return nil
+}
+
+// Resume loads and deletes the session for token with its buffered
+// messages, so it is resumed once
+func (s *RedisSessionStore) Resume(ctx context.Context, token string) (websocket.ResumeState, []websocket.BufferedMessage, error) {
+	key := sessionKeyPrefix + token
+
+	pipe := s.client.TxPipeline()
+	stateCmd := pipe.GetDel(ctx, key)
+	bufferCmd := pipe.LRange(ctx, key+":buffer", 0, -1)
+	pipe.Del(ctx, key+":buffer", key+":seq")
+	if _, err := pipe.Exec(ctx); err != nil && !errors.Is(err, redis.Nil) {
+		return websocket.ResumeState{}, nil, fmt.Errorf("failed to load session: %w", err)
+	}
+
+	raw, err := stateCmd.Bytes()
+	if errors.Is(err, redis.Nil) {
+		return websocket.ResumeState{}, nil, websocket.ErrResumeNotFound
+	}
+	if err != nil {
+		return websocket.ResumeState{}, nil, fmt.Errorf("failed to load session state: %w", err)
+	}
+
+	var state websocket.ResumeState
+	if err := json.Unmarshal(raw, &state); err != nil {
+		return websocket.ResumeState{}, nil, fmt.Errorf("failed to unmarshal session state: %w", err)
+	}
+
+	entries := bufferCmd.Val()
+	missed := make([]websocket.BufferedMessage, 0, len(entries))
+	for _, entry := range entries {
+		var message websocket.BufferedMessage
+		if err := json.Unmarshal([]byte(entry), &message); err != nil {
+			log.Printf("Skipping unreadable missed message: err=%v", err)
+			continue
+		}
+		missed = append(missed, message)
+	}
+	return state, missed, nil
+}
@@ -0,0 +1,115 @@
+package communitychat
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/tinle0301/streaming-platform-api/internal/chatactivity"
+	"github.com/tinle0301/streaming-platform-api/internal/websocket"
+)
+
+// claimKeyPrefix namespaces the Redis keys replicas claim sample slots with
+const claimKeyPrefix = "community_chat:sample:"
+
+// PresenceSource supplies cluster-wide room member counts
+type PresenceSource interface {
+	Counts(ctx context.Context, rooms []string) (map[string]int, error)
+}
+
+// ChatSource supplies recent chat activity
+type ChatSource interface {
+	Recent(ctx context.Context, room string, n int, until time.Time) ([]chatactivity.Bucket, error)
+}
+
+// Collector samples open community rooms into per-minute aggregates, apart
+// from the live viewers stream analytics collects
+type Collector struct {
+	repo     Repository
+	registry *Registry
+	presence PresenceSource
+	chat     ChatSource
+	claims   *redis.Client
+}
+
+// NewCollector creates a collector. Replicas sharing claims take turns, so
+// each sample slot is collected once.
+func NewCollector(repo Repository, registry *Registry, presence PresenceSource, chat ChatSource, claims *redis.Client) *Collector {
+	return &Collector{repo: repo, registry: registry, presence: presence, chat: chat, claims: claims}
+}
+
+// RunOnce samples every open community room's chatters into the current
+// minute and stores the previous minute's chat messages. It returns how many
+// rooms were sampled, or zero if another replica claimed the slot.
+func (c *Collector) RunOnce(ctx context.Context, interval time.Duration) (int, error) {
+	now := time.Now()
+	claimed, err := c.claims.SetNX(ctx, fmt.Sprintf("%s%d", claimKeyPrefix, now.Truncate(interval).Unix()), 1, interval).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to claim community chat sample: %w", err)
+	}
+	if !claimed {
+		return 0, nil
+	}
+
+	channels, err := c.registry.OpenChannels(ctx)
+	if err != nil {
+		return 0, err
+	}
+	rooms := make([]string, 0, len(channels))
+	for _, channelID := range channels {
+		rooms = append(rooms, websocket.CommunityRoom(channelID))
+	}
+	counts, err := c.presence.Counts(ctx, rooms)
+	if err != nil {
+		return 0, err
+	}
+
+	minute := now.Truncate(BucketSize)
+	previous := minute.Add(-BucketSize)
+
+	sampled := 0
+	for _, channelID := range channels {
+		room := websocket.CommunityRoom(channelID)
+		if err := c.repo.RecordChatters(ctx, channelID, minute, counts[room]); err != nil {
+			return sampled, err
+		}
+		if err := c.collectMessages(ctx, channelID, room, previous); err != nil {
+			log.Printf("Error collecting community chat activity: channelID=%s, err=%v", channelID, err)
+		}
+		sampled++
+	}
+	return sampled, nil
+}
+
+// Run samples every interval until ctx is done
+func (c *Collector) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := c.RunOnce(ctx, interval); err != nil {
+				log.Printf("Community chat analytics collection failed: err=%v", err)
+			}
+		}
+	}
+}
+
+// collectMessages stores a finished minute's chat messages
+func (c *Collector) collectMessages(ctx context.Context, channelID, room string, minute time.Time) error {
+	buckets, err := c.chat.Recent(ctx, room, int(BucketSize/chatactivity.BucketSize), minute.Add(BucketSize))
+	if err != nil {
+		return err
+	}
+
+	messages := 0
+	for _, bucket := range buckets {
+		messages += bucket.Messages
+	}
+	return c.repo.SetMessages(ctx, channelID, minute, messages)
+}
@@ -0,0 +1,165 @@
+// Package communitychat opens a channel's chat room while it is offline.
+// The community room has its own lifecycle: it opens when the channel
+// enables it or its stream ends, and closes when the channel goes live so
+// chatters move to the stream's room. Its presence and messages are
+// aggregated apart from live viewers.
+package communitychat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/tinle0301/streaming-platform-api/internal/tenancy"
+)
+
+// MaxSlowMode is the longest slow mode a channel can set
+const MaxSlowMode = 5 * time.Minute
+
+// BucketSize is the width of each stored aggregate
+const BucketSize = time.Minute
+
+// ErrNotFound is returned when saving settings of another tenant's channel
+var ErrNotFound = errors.New("channel not found")
+
+// Settings are a channel's community chat settings
+type Settings struct {
+	ChannelID string
+	Enabled   bool
+
+	// SlowMode is the least time between one chatter's messages; zero turns
+	// it off
+	SlowMode  time.Duration
+	UpdatedAt time.Time
+}
+
+// Summary totals a channel's community chat over a time range
+type Summary struct {
+	PeakChatters    int
+	AverageChatters float64
+	ChatMessages    int
+}
+
+// Repository persists community chat settings and per-minute aggregates.
+// Settings are saved in the context's tenant and aggregates in their
+// channel's, and other tenants' records are treated as missing.
+type Repository interface {
+	// Settings returns a channel's settings, defaulting a channel without any
+	Settings(ctx context.Context, channelID string) (*Settings, error)
+
+	SaveSettings(ctx context.Context, settings *Settings) error
+
+	// RecordChatters adds a presence sample to the minute's bucket
+	RecordChatters(ctx context.Context, channelID string, minute time.Time, chatters int) error
+
+	// SetMessages stores the minute's final chat message count, replacing
+	// an earlier value so collection can be repeated safely
+	SetMessages(ctx context.Context, channelID string, minute time.Time, messages int) error
+
+	// Summary totals the buckets starting in [from, to)
+	Summary(ctx context.Context, channelID string, from, to time.Time) (Summary, error)
+}
+
+// PostgresRepository implements Repository on PostgreSQL
+type PostgresRepository struct {
+	pool *pgxpool.Pool
+}
+
+// NewPostgresRepository creates a community chat repository on pool
+func NewPostgresRepository(pool *pgxpool.Pool) *PostgresRepository {
+	return &PostgresRepository{pool: pool}
+}
+
+// Settings reads a channel's settings
+func (r *PostgresRepository) Settings(ctx context.Context, channelID string) (*Settings, error) {
+	settings := &Settings{ChannelID: channelID}
+	var slowModeSeconds int
+	err := r.pool.QueryRow(ctx, `
+		SELECT enabled, slow_mode_seconds, updated_at FROM community_chat_settings
+		WHERE channel_id = $1`+tenancy.Condition("tenant_id", 2), channelID, tenancy.Scope(ctx),
+	).Scan(&settings.Enabled, &slowModeSeconds, &settings.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return settings, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get community chat settings: %w", err)
+	}
+	settings.SlowMode = time.Duration(slowModeSeconds) * time.Second
+	return settings, nil
+}
+
+// SaveSettings upserts a channel's settings
+func (r *PostgresRepository) SaveSettings(ctx context.Context, settings *Settings) error {
+	err := r.pool.QueryRow(ctx, `
+		INSERT INTO community_chat_settings (channel_id, enabled, slow_mode_seconds, tenant_id)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (channel_id) DO UPDATE SET
+			enabled = EXCLUDED.enabled, slow_mode_seconds = EXCLUDED.slow_mode_seconds, updated_at = NOW()
+		WHERE community_chat_settings.tenant_id = EXCLUDED.tenant_id
+		RETURNING updated_at`,
+		settings.ChannelID, settings.Enabled, int(settings.SlowMode/time.Second), tenancy.ID(ctx),
+	).Scan(&settings.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to save community chat settings: %w", err)
+	}
+	return nil
+}
+
+// channelTenant selects the tenant of the channel named by $1. The
+// collector samples every tenant's rooms, so aggregates take their
+// channel's tenant rather than the context's.
+const channelTenant = `COALESCE((SELECT tenant_id FROM users WHERE id::text = $1), 'default')`
+
+// RecordChatters upserts a presence sample
+func (r *PostgresRepository) RecordChatters(ctx context.Context, channelID string, minute time.Time, chatters int) error {
+	_, err := r.pool.Exec(ctx, `
+		INSERT INTO community_chat_analytics (channel_id, bucket_start, peak_chatters, chatter_total, chatter_samples,
+			tenant_id)
+		VALUES ($1, $2, $3, $3, 1, `+channelTenant+`)
+		ON CONFLICT (channel_id, bucket_start) DO UPDATE SET
+			peak_chatters = GREATEST(community_chat_analytics.peak_chatters, EXCLUDED.peak_chatters),
+			chatter_total = community_chat_analytics.chatter_total + EXCLUDED.chatter_total,
+			chatter_samples = community_chat_analytics.chatter_samples + 1`,
+		channelID, minute, chatters)
+	if err != nil {
+		return fmt.Errorf("failed to record community chatters: %w", err)
+	}
+	return nil
+}
+
+// SetMessages upserts a minute's chat messages
+func (r *PostgresRepository) SetMessages(ctx context.Context, channelID string, minute time.Time, messages int) error {
+	_, err := r.pool.Exec(ctx, `
+		INSERT INTO community_chat_analytics (channel_id, bucket_start, chat_messages, tenant_id)
+		VALUES ($1, $2, $3, `+channelTenant+`)
+		ON CONFLICT (channel_id, bucket_start) DO UPDATE SET
+			chat_messages = EXCLUDED.chat_messages`,
+		channelID, minute, messages)
+	if err != nil {
+		return fmt.Errorf("failed to set community chat messages: %w", err)
+	}
+	return nil
+}
+
+// Summary aggregates a channel's buckets
+func (r *PostgresRepository) Summary(ctx context.Context, channelID string, from, to time.Time) (Summary, error) {
+	var summary Summary
+	err := r.pool.QueryRow(ctx, `
+		SELECT COALESCE(MAX(peak_chatters), 0),
+			COALESCE(AVG(chatter_total::float8 / NULLIF(chatter_samples, 0)), 0),
+			COALESCE(SUM(chat_messages), 0)
+		FROM community_chat_analytics
+		WHERE channel_id = $1 AND bucket_start >= $2 AND bucket_start < $3`+tenancy.Condition("tenant_id", 4),
+		channelID, from, to, tenancy.Scope(ctx),
+	).Scan(&summary.PeakChatters, &summary.AverageChatters, &summary.ChatMessages)
+	if err != nil {
+		return summary, fmt.Errorf("failed to summarize community chat analytics: %w", err)
+	}
+	return summary, nil
+}
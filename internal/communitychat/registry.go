@@ -0,0 +1,91 @@
+package communitychat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// registryKeyPrefix namespaces open community rooms in Redis
+	registryKeyPrefix = "community_chat:"
+
+	// openChannelsKey is the set of channels whose community room is open
+	openChannelsKey = "community_chat:open"
+
+	// turnKeyPrefix namespaces chatters' slow mode waits
+	turnKeyPrefix = "community_chat_turn:"
+)
+
+// Registry shares open community rooms with the WebSocket servers through
+// Redis: which channels' rooms accept chat and their slow mode
+type Registry struct {
+	client *redis.Client
+}
+
+// NewRegistry creates a community room registry
+func NewRegistry(client *redis.Client) *Registry {
+	return &Registry{client: client}
+}
+
+// Open opens a channel's community room with its settings
+func (r *Registry) Open(ctx context.Context, settings *Settings) error {
+	pipe := r.client.TxPipeline()
+	pipe.HSet(ctx, registryKeyPrefix+settings.ChannelID,
+		"slow_mode_ms", settings.SlowMode.Milliseconds(),
+		"opened_at", time.Now().UnixMilli())
+	pipe.SAdd(ctx, openChannelsKey, settings.ChannelID)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to open community chat: %w", err)
+	}
+	return nil
+}
+
+// Close closes a channel's community room, reporting whether it was open
+func (r *Registry) Close(ctx context.Context, channelID string) (bool, error) {
+	pipe := r.client.TxPipeline()
+	pipe.Del(ctx, registryKeyPrefix+channelID)
+	removed := pipe.SRem(ctx, openChannelsKey, channelID)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return false, fmt.Errorf("failed to close community chat: %w", err)
+	}
+	return removed.Val() > 0, nil
+}
+
+// CommunityChat reports whether a channel's community room is open and its
+// slow mode
+func (r *Registry) CommunityChat(ctx context.Context, channelID string) (bool, time.Duration, error) {
+	value, err := r.client.HGet(ctx, registryKeyPrefix+channelID, "slow_mode_ms").Result()
+	if errors.Is(err, redis.Nil) {
+		return false, 0, nil
+	}
+	if err != nil {
+		return false, 0, fmt.Errorf("failed to get community chat: %w", err)
+	}
+	slowModeMs, _ := strconv.ParseInt(value, 10, 64)
+	return true, time.Duration(slowModeMs) * time.Millisecond, nil
+}
+
+// ClaimChatTurn reports whether userID may chat in a channel's community
+// room under slow mode, starting their wait if so. Waits are shared by every
+// WebSocket server.
+func (r *Registry) ClaimChatTurn(ctx context.Context, channelID, userID string, interval time.Duration) (bool, error) {
+	claimed, err := r.client.SetNX(ctx, turnKeyPrefix+channelID+":"+userID, 1, interval).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to claim community chat turn: %w", err)
+	}
+	return claimed, nil
+}
+
+// OpenChannels lists the channels whose community room is open
+func (r *Registry) OpenChannels(ctx context.Context) ([]string, error) {
+	channels, err := r.client.SMembers(ctx, openChannelsKey).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list open community chats: %w", err)
+	}
+	return channels, nil
+}
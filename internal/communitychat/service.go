@@ -0,0 +1,170 @@
+package communitychat
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/tinle0301/streaming-platform-api/internal/analytics"
+	"github.com/tinle0301/streaming-platform-api/internal/events"
+	"github.com/tinle0301/streaming-platform-api/internal/store"
+)
+
+// Reasons a community room closes, sent with community.closed
+const (
+	CloseReasonDisabled = "disabled"
+	CloseReasonLive     = "live"
+)
+
+// ErrInvalidSlowMode is returned for a slow mode outside 0 to 300 whole seconds
+var ErrInvalidSlowMode = errors.New("slow mode must be between 0 and 300 seconds")
+
+// Service manages channels' community chat settings and opens and closes
+// their rooms as they go live and offline
+type Service struct {
+	repo     Repository
+	registry *Registry
+	streams  store.StreamRepository
+
+	publisher events.Publisher
+}
+
+// NewService creates a community chat service
+func NewService(repo Repository, registry *Registry, streams store.StreamRepository) *Service {
+	return &Service{repo: repo, registry: registry, streams: streams}
+}
+
+// SetPublisher enables community.opened and community.closed events, which
+// tell chatters their room's state
+func (s *Service) SetPublisher(publisher events.Publisher) {
+	s.publisher = publisher
+}
+
+// Settings returns a channel's community chat settings
+func (s *Service) Settings(ctx context.Context, channelID string) (*Settings, error) {
+	return s.repo.Settings(ctx, channelID)
+}
+
+// Open reports whether a channel's community room is open
+func (s *Service) Open(ctx context.Context, channelID string) (bool, error) {
+	open, _, err := s.registry.CommunityChat(ctx, channelID)
+	return open, err
+}
+
+// Update changes a channel's settings. Enabling community chat opens the
+// room unless the channel is live; disabling it closes the room.
+func (s *Service) Update(ctx context.Context, channelID string, enabled bool, slowMode time.Duration) (*Settings, error) {
+	if slowMode < 0 || slowMode > MaxSlowMode || slowMode%time.Second != 0 {
+		return nil, ErrInvalidSlowMode
+	}
+
+	settings := &Settings{ChannelID: channelID, Enabled: enabled, SlowMode: slowMode}
+	if err := s.repo.SaveSettings(ctx, settings); err != nil {
+		return nil, err
+	}
+
+	if !enabled {
+		return settings, s.close(ctx, channelID, CloseReasonDisabled, nil)
+	}
+	stream, err := s.liveStream(ctx, channelID)
+	if err != nil {
+		return nil, err
+	}
+	if stream == nil {
+		// Reopening applies a changed slow mode
+		return settings, s.open(ctx, settings)
+	}
+	return settings, nil
+}
+
+// Report summarizes a channel's community chat over the named range, ending now
+func (s *Service) Report(ctx context.Context, channelID, rangeName string) (Summary, error) {
+	rng, err := analytics.LookupRange(rangeName)
+	if err != nil {
+		return Summary{}, err
+	}
+	to := time.Now()
+	return s.repo.Summary(ctx, channelID, rng.From(to), to)
+}
+
+// HandleEvent closes a channel's community room when it goes live, pointing
+// chatters at the stream, and reopens it when the stream ends
+func (s *Service) HandleEvent(ctx context.Context, event events.Event) error {
+	channelID := event.UserID
+	if channelID == "" {
+		return nil
+	}
+
+	switch event.Type {
+	case events.EventTypeStreamLive:
+		return s.close(ctx, channelID, CloseReasonLive, map[string]interface{}{
+			"stream_id": event.StreamID,
+		})
+	case events.EventTypeStreamOffline:
+		settings, err := s.repo.Settings(ctx, channelID)
+		if err != nil || !settings.Enabled {
+			return err
+		}
+		// A stream that started since this one ended keeps the room closed
+		stream, err := s.liveStream(ctx, channelID)
+		if err != nil || stream != nil {
+			return err
+		}
+		return s.open(ctx, settings)
+	}
+	return nil
+}
+
+// open opens a channel's community room and announces it
+func (s *Service) open(ctx context.Context, settings *Settings) error {
+	if err := s.registry.Open(ctx, settings); err != nil {
+		return err
+	}
+
+	log.Printf("Community chat opened: channelID=%s", settings.ChannelID)
+	s.publish(ctx, events.NewCommunityChatEvent(events.EventTypeCommunityChatOpened, settings.ChannelID, map[string]interface{}{
+		"slow_mode_seconds": int64(settings.SlowMode / time.Second),
+	}))
+	return nil
+}
+
+// close closes a channel's community room, announcing it if it was open
+func (s *Service) close(ctx context.Context, channelID, reason string, data map[string]interface{}) error {
+	closed, err := s.registry.Close(ctx, channelID)
+	if err != nil || !closed {
+		return err
+	}
+
+	if data == nil {
+		data = map[string]interface{}{}
+	}
+	data["reason"] = reason
+
+	log.Printf("Community chat closed: channelID=%s, reason=%s", channelID, reason)
+	s.publish(ctx, events.NewCommunityChatEvent(events.EventTypeCommunityChatClosed, channelID, data))
+	return nil
+}
+
+// publish sends a community room event when a publisher is set
+func (s *Service) publish(ctx context.Context, event events.Event) {
+	if s.publisher == nil {
+		return
+	}
+	if err := s.publisher.Publish(ctx, event); err != nil {
+		log.Printf("Error publishing community chat event: type=%s, err=%v", event.Type, err)
+	}
+}
+
+// liveStream returns the channel's live stream, or nil if it is offline
+func (s *Service) liveStream(ctx context.Context, channelID string) (*store.Stream, error) {
+	streams, _, err := s.streams.List(ctx, store.StreamFilter{
+		StreamerID: channelID,
+		Status:     store.StreamStatusLive,
+		Limit:      1,
+	})
+	if err != nil || len(streams) == 0 {
+		return nil, err
+	}
+	return streams[0], nil
+}
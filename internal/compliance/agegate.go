@@ -0,0 +1,167 @@
+package compliance
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Errors returned when a viewer may not play mature content
+var (
+	ErrAgeVerificationRequired = errors.New("age verification required")
+	ErrUnderage                = errors.New("viewer does not meet the minimum age")
+	ErrMatureConsentRequired   = errors.New("mature content consent required")
+	ErrInvalidBirthDate        = errors.New("birth date must be in the past")
+)
+
+// Content kinds subject to age gating
+const (
+	ContentKindStream = "stream"
+	ContentKindVOD    = "vod"
+)
+
+// MatureMinimumAge is the minimum viewer age for mature content
+const MatureMinimumAge = 18
+
+// ContentRating carries the age-gating attributes of a stream or VOD
+type ContentRating struct {
+	ContentID string `json:"content_id"`
+	Kind      string `json:"kind"`
+	Category  string `json:"category,omitempty"`
+	Mature    bool   `json:"mature"`
+}
+
+// ViewerProfile tracks a viewer's age and mature content consent
+type ViewerProfile struct {
+	UserID          string     `json:"user_id"`
+	BirthDate       *time.Time `json:"birth_date,omitempty"`
+	MatureConsentAt *time.Time `json:"mature_consent_at,omitempty"`
+}
+
+// ProfileRepository stores viewers' profiles
+type ProfileRepository interface {
+	// GetProfile returns a viewer's profile; viewers who never set one get
+	// an empty profile
+	GetProfile(ctx context.Context, userID string) (ViewerProfile, error)
+	SetBirthDate(ctx context.Context, userID string, birthDate time.Time) error
+	SetMatureConsent(ctx context.Context, userID string, at time.Time) error
+}
+
+// AgeGate enforces mature content rules for playback and recommendations
+type AgeGate struct {
+	// Categories that are always treated as mature regardless of the flag
+	matureCategories map[string]bool
+
+	profiles ProfileRepository
+}
+
+// NewAgeGate creates a new age gate keeping viewers' profiles in profiles;
+// content in matureCategories is always mature
+func NewAgeGate(profiles ProfileRepository, matureCategories ...string) *AgeGate {
+	categories := make(map[string]bool, len(matureCategories))
+	for _, category := range matureCategories {
+		categories[category] = true
+	}
+
+	return &AgeGate{
+		matureCategories: categories,
+		profiles:         profiles,
+	}
+}
+
+// IsMature reports whether content is mature, either flagged or by category
+func (g *AgeGate) IsMature(rating ContentRating) bool {
+	return rating.Mature || g.matureCategories[rating.Category]
+}
+
+// ResolveMatureFlag returns the mature flag to store for a stream update.
+// Streamers may not clear the flag for categories that are always mature.
+func (g *AgeGate) ResolveMatureFlag(category string, requested bool) bool {
+	return requested || g.matureCategories[category]
+}
+
+// RecordBirthDate stores a viewer's verified birth date
+func (g *AgeGate) RecordBirthDate(ctx context.Context, userID string, birthDate time.Time) error {
+	if !birthDate.Before(time.Now()) {
+		return ErrInvalidBirthDate
+	}
+	return g.profiles.SetBirthDate(ctx, userID, birthDate)
+}
+
+// RecordConsent stores the time a viewer consented to mature content. Only
+// viewers old enough to play mature content can consent.
+func (g *AgeGate) RecordConsent(ctx context.Context, userID string) error {
+	profile, err := g.profiles.GetProfile(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if err := checkAge(profile); err != nil {
+		return err
+	}
+	return g.profiles.SetMatureConsent(ctx, userID, time.Now())
+}
+
+// GetProfile returns a viewer's profile
+func (g *AgeGate) GetProfile(ctx context.Context, userID string) (ViewerProfile, error) {
+	return g.profiles.GetProfile(ctx, userID)
+}
+
+// CheckPlayback must be called before issuing a playback token
+func (g *AgeGate) CheckPlayback(ctx context.Context, userID string, rating ContentRating) error {
+	if !g.IsMature(rating) {
+		return nil
+	}
+
+	profile, err := g.profiles.GetProfile(ctx, userID)
+	if err != nil {
+		return err
+	}
+	return checkMature(profile)
+}
+
+// FilterRecommendations removes content the viewer may not play
+func (g *AgeGate) FilterRecommendations(ctx context.Context, userID string, ratings []ContentRating) ([]ContentRating, error) {
+	profile, err := g.profiles.GetProfile(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	filtered := make([]ContentRating, 0, len(ratings))
+	for _, rating := range ratings {
+		if !g.IsMature(rating) || checkMature(profile) == nil {
+			filtered = append(filtered, rating)
+		}
+	}
+	return filtered, nil
+}
+
+// checkMature reports why a viewer may not play mature content, if they may not
+func checkMature(profile ViewerProfile) error {
+	if err := checkAge(profile); err != nil {
+		return err
+	}
+	if profile.MatureConsentAt == nil {
+		return ErrMatureConsentRequired
+	}
+	return nil
+}
+
+// checkAge reports whether a viewer is verified to be old enough for mature content
+func checkAge(profile ViewerProfile) error {
+	if profile.BirthDate == nil {
+		return ErrAgeVerificationRequired
+	}
+	if ageAt(*profile.BirthDate, time.Now()) < MatureMinimumAge {
+		return ErrUnderage
+	}
+	return nil
+}
+
+// ageAt returns the age in whole years at the given time
+func ageAt(birthDate, now time.Time) int {
+	age := now.Year() - birthDate.Year()
+	if now.Month() < birthDate.Month() || (now.Month() == birthDate.Month() && now.Day() < birthDate.Day()) {
+		age--
+	}
+	return age
+}
@@ -0,0 +1,155 @@
+package compliance
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/tinle0301/streaming-platform-api/internal/audit"
+)
+
+// Disclosure types for branded content
+const (
+	DisclosurePaidPromotion = "PAID_PROMOTION"
+	DisclosureSponsorship   = "SPONSORSHIP"
+	DisclosureAffiliate     = "AFFILIATE"
+	DisclosureGifted        = "GIFTED"
+)
+
+// MaxSponsorNameLength bounds a branded content sponsor's name
+const MaxSponsorNameLength = 100
+
+// Errors returned for invalid branded content disclosures
+var (
+	ErrInvalidDisclosureType = errors.New("unknown disclosure type")
+	ErrInvalidSponsorName    = errors.New("sponsor name is too long")
+	ErrDisclosureRequired    = errors.New("branded content in this region must name its sponsor and disclosure type")
+)
+
+// validDisclosureTypes are the accepted disclosure types
+var validDisclosureTypes = map[string]bool{
+	DisclosurePaidPromotion: true,
+	DisclosureSponsorship:   true,
+	DisclosureAffiliate:     true,
+	DisclosureGifted:        true,
+}
+
+// Disclosure is the branded content state of a stream
+type Disclosure struct {
+	BrandedContent bool   `json:"branded_content"`
+	SponsorName    string `json:"sponsor_name,omitempty"`
+	DisclosureType string `json:"disclosure_type,omitempty"`
+}
+
+// Complete reports whether the disclosure names both its sponsor and type
+func (d Disclosure) Complete() bool {
+	return d.SponsorName != "" && d.DisclosureType != ""
+}
+
+// DisclosurePolicy enforces sponsorship disclosure rules. In regions whose
+// advertising rules require it, branded content must name its sponsor and
+// disclosure type; elsewhere both are optional.
+type DisclosurePolicy struct {
+	locator  GeoLocator
+	auditLog audit.Logger
+
+	// Countries where complete disclosure is mandatory
+	regions map[string]bool
+}
+
+// NewDisclosurePolicy creates a policy enforcing complete disclosure in
+// regions. Requests locator cannot place are treated as coming from an
+// enforced region; a nil locator places no requests.
+func NewDisclosurePolicy(locator GeoLocator, auditLog audit.Logger, regions ...string) *DisclosurePolicy {
+	enforced := make(map[string]bool, len(regions))
+	for _, region := range regions {
+		if region = strings.ToUpper(strings.TrimSpace(region)); region != "" {
+			enforced[region] = true
+		}
+	}
+
+	return &DisclosurePolicy{
+		locator:  locator,
+		auditLog: auditLog,
+		regions:  enforced,
+	}
+}
+
+// Required reports whether a streamer at clientIP must fully disclose
+// branded content
+func (p *DisclosurePolicy) Required(clientIP string) bool {
+	if len(p.regions) == 0 {
+		return false
+	}
+	if p.locator == nil {
+		return true
+	}
+
+	country, err := p.locator.CountryForIP(clientIP)
+	if err != nil {
+		// Fail closed: unknown regions get the strictest rules
+		return true
+	}
+	return p.regions[strings.ToUpper(country)]
+}
+
+// Normalize trims and validates a disclosure. Sponsor and type are cleared
+// when the content is not branded.
+func Normalize(d Disclosure) (Disclosure, error) {
+	if !d.BrandedContent {
+		return Disclosure{}, nil
+	}
+
+	d.SponsorName = strings.TrimSpace(d.SponsorName)
+	d.DisclosureType = strings.ToUpper(strings.TrimSpace(d.DisclosureType))
+	if len(d.SponsorName) > MaxSponsorNameLength {
+		return Disclosure{}, ErrInvalidSponsorName
+	}
+	if d.DisclosureType != "" && !validDisclosureTypes[d.DisclosureType] {
+		return Disclosure{}, fmt.Errorf("%w: %s", ErrInvalidDisclosureType, d.DisclosureType)
+	}
+	return d, nil
+}
+
+// Check normalizes a disclosure submitted from clientIP, rejecting branded
+// content without complete disclosure in enforced regions
+func (p *DisclosurePolicy) Check(d Disclosure, clientIP string) (Disclosure, error) {
+	d, err := Normalize(d)
+	if err != nil {
+		return Disclosure{}, err
+	}
+	if d.BrandedContent && !d.Complete() && p.Required(clientIP) {
+		return Disclosure{}, ErrDisclosureRequired
+	}
+	return d, nil
+}
+
+// RecordChange writes an audit entry when a stream's disclosure changes
+func (p *DisclosurePolicy) RecordChange(ctx context.Context, actorID, streamID string, before, after Disclosure) {
+	if p.auditLog == nil || before == after {
+		return
+	}
+
+	action := audit.ActionBrandedContentUpdated
+	switch {
+	case after.BrandedContent && !before.BrandedContent:
+		action = audit.ActionBrandedContentEnabled
+	case !after.BrandedContent:
+		action = audit.ActionBrandedContentDisabled
+	}
+
+	entry := audit.Entry{
+		Action:   action,
+		ActorID:  actorID,
+		Resource: streamID,
+		Metadata: map[string]string{
+			"sponsor_name":    after.SponsorName,
+			"disclosure_type": after.DisclosureType,
+		},
+	}
+	if err := p.auditLog.Record(ctx, entry); err != nil {
+		log.Printf("Error recording audit entry: %v", err)
+	}
+}
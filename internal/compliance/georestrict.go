@@ -0,0 +1,243 @@
+package compliance
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/tinle0301/streaming-platform-api/internal/audit"
+)
+
+// Restriction reasons surfaced to clients
+const (
+	RestrictionReasonDMCA       = "DMCA"
+	RestrictionReasonRegulatory = "REGULATORY"
+	RestrictionReasonLicensing  = "LICENSING"
+)
+
+// GeoLocator resolves a client IP address to an ISO 3166-1 alpha-2 country code
+type GeoLocator interface {
+	CountryForIP(ip string) (string, error)
+}
+
+// GeoRestriction blocks (or exclusively allows) playback of content by country
+type GeoRestriction struct {
+	ContentID string    `json:"content_id"`
+	Kind      string    `json:"kind"`
+	Countries []string  `json:"countries"`
+	AllowList bool      `json:"allow_list"`
+	Reason    string    `json:"reason"`
+	CreatedBy string    `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// RestrictionError is returned when content is unavailable in the viewer's region
+type RestrictionError struct {
+	ContentID string
+	Country   string
+	Reason    string
+}
+
+// Error implements the error interface
+func (e *RestrictionError) Error() string {
+	return fmt.Sprintf("content %s is not available in %s (%s)", e.ContentID, e.Country, e.Reason)
+}
+
+// Extensions returns the GraphQL error extensions for this restriction
+func (e *RestrictionError) Extensions() map[string]interface{} {
+	return map[string]interface{}{
+		"code":    "GEO_RESTRICTED",
+		"reason":  e.Reason,
+		"country": e.Country,
+	}
+}
+
+// ErrInvalidRestriction is returned for a restriction that can't be enforced
+var ErrInvalidRestriction = errors.New("invalid geo restriction")
+
+// RestrictionRepository stores geo restrictions by content ID
+type RestrictionRepository interface {
+	// GetRestriction returns the restriction on content, if any
+	GetRestriction(ctx context.Context, contentID string) (GeoRestriction, bool, error)
+	SaveRestriction(ctx context.Context, restriction GeoRestriction) error
+
+	// DeleteRestriction reports whether a restriction existed
+	DeleteRestriction(ctx context.Context, contentID string) (bool, error)
+}
+
+// GeoRestrictions stores per-content geo rules and enforces them at playback
+type GeoRestrictions struct {
+	locator  GeoLocator
+	rules    RestrictionRepository
+	auditLog audit.Logger
+}
+
+// NewGeoRestrictions creates a new geo restriction store keeping rules in
+// repository. A nil locator places no requests, so restricted content is
+// refused everywhere.
+func NewGeoRestrictions(locator GeoLocator, repository RestrictionRepository, auditLog audit.Logger) *GeoRestrictions {
+	return &GeoRestrictions{
+		locator:  locator,
+		rules:    repository,
+		auditLog: auditLog,
+	}
+}
+
+// SetRestriction creates or replaces the restriction for a stream or VOD
+func (g *GeoRestrictions) SetRestriction(ctx context.Context, restriction GeoRestriction) error {
+	if restriction.CreatedAt.IsZero() {
+		restriction.CreatedAt = time.Now()
+	}
+	for i, country := range restriction.Countries {
+		restriction.Countries[i] = strings.ToUpper(strings.TrimSpace(country))
+	}
+	if err := validateRestriction(restriction); err != nil {
+		return err
+	}
+
+	if err := g.rules.SaveRestriction(ctx, restriction); err != nil {
+		return err
+	}
+
+	g.record(ctx, audit.ActionGeoRestrict, restriction.CreatedBy, restriction.ContentID, restriction.Reason, map[string]string{
+		"kind":       restriction.Kind,
+		"countries":  strings.Join(restriction.Countries, ","),
+		"allow_list": fmt.Sprintf("%t", restriction.AllowList),
+	})
+	return nil
+}
+
+// RemoveRestriction lifts the restriction on content; it reports whether one existed
+func (g *GeoRestrictions) RemoveRestriction(ctx context.Context, contentID, actorID string) (bool, error) {
+	ok, err := g.rules.DeleteRestriction(ctx, contentID)
+	if err != nil {
+		return false, err
+	}
+
+	if ok {
+		g.record(ctx, audit.ActionGeoUnrestrict, actorID, contentID, "", nil)
+	}
+	return ok, nil
+}
+
+// GetRestriction returns the restriction for content, if any
+func (g *GeoRestrictions) GetRestriction(ctx context.Context, contentID string) (GeoRestriction, bool, error) {
+	return g.rules.GetRestriction(ctx, contentID)
+}
+
+// CheckPlayback must be called before issuing a playback token. It returns a
+// *RestrictionError when the client's region may not play the content.
+func (g *GeoRestrictions) CheckPlayback(ctx context.Context, contentID, clientIP string) error {
+	restriction, ok, err := g.rules.GetRestriction(ctx, contentID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return nil
+	}
+
+	if g.locator == nil {
+		return &RestrictionError{ContentID: contentID, Country: "unknown", Reason: restriction.Reason}
+	}
+	country, err := g.locator.CountryForIP(clientIP)
+	if err != nil {
+		// Fail closed: restricted content is not served to unknown regions
+		return &RestrictionError{ContentID: contentID, Country: "unknown", Reason: restriction.Reason}
+	}
+
+	listed := false
+	for _, c := range restriction.Countries {
+		if c == strings.ToUpper(country) {
+			listed = true
+			break
+		}
+	}
+
+	if listed != restriction.AllowList {
+		return &RestrictionError{ContentID: contentID, Country: country, Reason: restriction.Reason}
+	}
+	return nil
+}
+
+// validateRestriction checks a restriction names its content, a known kind
+// and reason, and ISO 3166-1 alpha-2 countries
+func validateRestriction(restriction GeoRestriction) error {
+	if restriction.ContentID == "" {
+		return fmt.Errorf("%w: content ID is required", ErrInvalidRestriction)
+	}
+	if restriction.Kind != ContentKindStream && restriction.Kind != ContentKindVOD {
+		return fmt.Errorf("%w: kind must be %s or %s", ErrInvalidRestriction, ContentKindStream, ContentKindVOD)
+	}
+	switch restriction.Reason {
+	case RestrictionReasonDMCA, RestrictionReasonRegulatory, RestrictionReasonLicensing:
+	default:
+		return fmt.Errorf("%w: reason must be %s, %s, or %s", ErrInvalidRestriction,
+			RestrictionReasonDMCA, RestrictionReasonRegulatory, RestrictionReasonLicensing)
+	}
+	if len(restriction.Countries) == 0 {
+		return fmt.Errorf("%w: at least one country is required", ErrInvalidRestriction)
+	}
+	for _, country := range restriction.Countries {
+		if len(country) != 2 || country[0] < 'A' || country[0] > 'Z' || country[1] < 'A' || country[1] > 'Z' {
+			return fmt.Errorf("%w: %q is not a two-letter country code", ErrInvalidRestriction, country)
+		}
+	}
+	return nil
+}
+
+// record writes an audit entry for a restriction change
+func (g *GeoRestrictions) record(ctx context.Context, action, actorID, contentID, reason string, metadata map[string]string) {
+	if g.auditLog == nil {
+		return
+	}
+
+	entry := audit.Entry{
+		Action:   action,
+		ActorID:  actorID,
+		Resource: contentID,
+		Reason:   reason,
+		Metadata: metadata,
+	}
+	if err := g.auditLog.Record(ctx, entry); err != nil {
+		log.Printf("Error recording audit entry: %v", err)
+	}
+}
+
+// CIDRGeoLocator implements GeoLocator from a static CIDR-to-country table
+type CIDRGeoLocator struct {
+	networks []*net.IPNet
+	country  []string
+}
+
+// NewCIDRGeoLocator creates a locator from a map of CIDR blocks to country codes
+func NewCIDRGeoLocator(table map[string]string) (*CIDRGeoLocator, error) {
+	locator := &CIDRGeoLocator{}
+	for cidr, country := range table {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse CIDR %q: %w", cidr, err)
+		}
+		locator.networks = append(locator.networks, network)
+		locator.country = append(locator.country, strings.ToUpper(country))
+	}
+	return locator, nil
+}
+
+// CountryForIP returns the country of a network containing ip
+func (l *CIDRGeoLocator) CountryForIP(ip string) (string, error) {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return "", fmt.Errorf("invalid IP address: %q", ip)
+	}
+
+	for i, network := range l.networks {
+		if network.Contains(parsed) {
+			return l.country[i], nil
+		}
+	}
+	return "", fmt.Errorf("no country found for IP %s", ip)
+}
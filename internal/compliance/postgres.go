@@ -0,0 +1,130 @@
+package compliance
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/tinle0301/streaming-platform-api/internal/store"
+	"github.com/tinle0301/streaming-platform-api/internal/tenancy"
+)
+
+// PostgresProfiles implements ProfileRepository on PostgreSQL, so the API
+// servers that record viewers' ages and the WebSocket servers that gate
+// mature rooms share them. Profiles are kept in the context's tenant, and
+// other tenants' are treated as missing.
+type PostgresProfiles struct {
+	pool *pgxpool.Pool
+}
+
+// NewPostgresProfiles creates a profile repository on pool
+func NewPostgresProfiles(pool *pgxpool.Pool) *PostgresProfiles {
+	return &PostgresProfiles{pool: pool}
+}
+
+// GetProfile returns a viewer's profile
+func (p *PostgresProfiles) GetProfile(ctx context.Context, userID string) (ViewerProfile, error) {
+	profile := ViewerProfile{UserID: userID}
+	if !store.IsUUID(userID) {
+		// Guests have no profile
+		return profile, nil
+	}
+
+	err := p.pool.QueryRow(ctx, `
+		SELECT birth_date, mature_consent_at FROM viewer_profiles WHERE user_id = $1`+tenancy.Condition("tenant_id", 2),
+		userID, tenancy.Scope(ctx),
+	).Scan(&profile.BirthDate, &profile.MatureConsentAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return profile, nil
+	}
+	if err != nil {
+		return profile, fmt.Errorf("failed to get viewer profile: %w", err)
+	}
+	return profile, nil
+}
+
+// SetBirthDate stores a viewer's birth date
+func (p *PostgresProfiles) SetBirthDate(ctx context.Context, userID string, birthDate time.Time) error {
+	_, err := p.pool.Exec(ctx, `
+		INSERT INTO viewer_profiles (user_id, birth_date, tenant_id) VALUES ($1, $2, $3)
+		ON CONFLICT (user_id) DO UPDATE SET birth_date = EXCLUDED.birth_date, updated_at = NOW()
+		WHERE viewer_profiles.tenant_id = EXCLUDED.tenant_id`,
+		userID, birthDate, tenancy.ID(ctx))
+	if err != nil {
+		return fmt.Errorf("failed to set birth date: %w", err)
+	}
+	return nil
+}
+
+// SetMatureConsent stores when a viewer consented to mature content
+func (p *PostgresProfiles) SetMatureConsent(ctx context.Context, userID string, at time.Time) error {
+	_, err := p.pool.Exec(ctx, `
+		INSERT INTO viewer_profiles (user_id, mature_consent_at, tenant_id) VALUES ($1, $2, $3)
+		ON CONFLICT (user_id) DO UPDATE SET mature_consent_at = EXCLUDED.mature_consent_at, updated_at = NOW()
+		WHERE viewer_profiles.tenant_id = EXCLUDED.tenant_id`,
+		userID, at, tenancy.ID(ctx))
+	if err != nil {
+		return fmt.Errorf("failed to set mature content consent: %w", err)
+	}
+	return nil
+}
+
+// PostgresRestrictions implements RestrictionRepository on PostgreSQL, so
+// restrictions set through the API are enforced by every server. Rules are
+// kept in the context's tenant, and other tenants' are treated as missing.
+type PostgresRestrictions struct {
+	pool *pgxpool.Pool
+}
+
+// NewPostgresRestrictions creates a restriction repository on pool
+func NewPostgresRestrictions(pool *pgxpool.Pool) *PostgresRestrictions {
+	return &PostgresRestrictions{pool: pool}
+}
+
+// GetRestriction returns the restriction on content, if any
+func (p *PostgresRestrictions) GetRestriction(ctx context.Context, contentID string) (GeoRestriction, bool, error) {
+	restriction := GeoRestriction{ContentID: contentID}
+	err := p.pool.QueryRow(ctx, `
+		SELECT kind, countries, allow_list, reason, created_by, created_at
+		FROM geo_restrictions WHERE content_id = $1`+tenancy.Condition("tenant_id", 2),
+		contentID, tenancy.Scope(ctx),
+	).Scan(&restriction.Kind, &restriction.Countries, &restriction.AllowList, &restriction.Reason,
+		&restriction.CreatedBy, &restriction.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return GeoRestriction{}, false, nil
+	}
+	if err != nil {
+		return GeoRestriction{}, false, fmt.Errorf("failed to get geo restriction: %w", err)
+	}
+	return restriction, true, nil
+}
+
+// SaveRestriction creates or replaces the restriction on its content
+func (p *PostgresRestrictions) SaveRestriction(ctx context.Context, restriction GeoRestriction) error {
+	_, err := p.pool.Exec(ctx, `
+		INSERT INTO geo_restrictions (content_id, kind, countries, allow_list, reason, created_by, created_at, tenant_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (content_id) DO UPDATE SET
+			kind = EXCLUDED.kind, countries = EXCLUDED.countries, allow_list = EXCLUDED.allow_list,
+			reason = EXCLUDED.reason, created_by = EXCLUDED.created_by, created_at = EXCLUDED.created_at
+		WHERE geo_restrictions.tenant_id = EXCLUDED.tenant_id`,
+		restriction.ContentID, restriction.Kind, restriction.Countries, restriction.AllowList,
+		restriction.Reason, restriction.CreatedBy, restriction.CreatedAt, tenancy.ID(ctx))
+	if err != nil {
+		return fmt.Errorf("failed to save geo restriction: %w", err)
+	}
+	return nil
+}
+
+// DeleteRestriction lifts the restriction on content
+func (p *PostgresRestrictions) DeleteRestriction(ctx context.Context, contentID string) (bool, error) {
+	tag, err := p.pool.Exec(ctx, `DELETE FROM geo_restrictions WHERE content_id = $1`+tenancy.Condition("tenant_id", 2),
+		contentID, tenancy.Scope(ctx))
+	if err != nil {
+		return false, fmt.Errorf("failed to delete geo restriction: %w", err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
@@ -0,0 +1,89 @@
+package compliance
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/tinle0301/streaming-platform-api/internal/store"
+	"github.com/tinle0301/streaming-platform-api/internal/tenancy"
+)
+
+// roomCheckTimeout bounds the lookups behind a room check
+const roomCheckTimeout = 5 * time.Second
+
+// premiereRoomPrefix starts the names of VOD premiere rooms, as
+// websocket.PremiereRoom names them
+const premiereRoomPrefix = "premiere:"
+
+// RoomGate keeps viewers out of WebSocket rooms of content they may not
+// play. It implements websocket.RoomAuthorizer.
+type RoomGate struct {
+	ageGate      *AgeGate
+	restrictions *GeoRestrictions
+	streams      store.StreamRepository
+}
+
+// NewRoomGate creates a room gate checking streams against ageGate and
+// streams and VODs against restrictions
+func NewRoomGate(ageGate *AgeGate, restrictions *GeoRestrictions, streams store.StreamRepository) *RoomGate {
+	return &RoomGate{ageGate: ageGate, restrictions: restrictions, streams: streams}
+}
+
+// CanJoin reports whether userID, connecting from clientIP, may be in room.
+// A stream's room and its caption rooms are named after the stream, and a
+// premiere's after its VOD. Restricted content's rooms are closed to other
+// regions, and mature streams' rooms to viewers who fail the age gate. The
+// check fails closed when it can't be made.
+func (g *RoomGate) CanJoin(userID, clientIP, room string) bool {
+	tenantID, name := tenancy.SplitRoom(room)
+	ctx, cancel := context.WithTimeout(tenancy.WithTenant(context.Background(), tenantID), roomCheckTimeout)
+	defer cancel()
+
+	if vodID, ok := strings.CutPrefix(name, premiereRoomPrefix); ok {
+		return g.allowed(room, userID, g.restrictions.CheckPlayback(ctx, vodID, clientIP))
+	}
+
+	streamID, _, _ := strings.Cut(name, ":")
+	if !store.IsUUID(streamID) {
+		return true
+	}
+	if err := g.restrictions.CheckPlayback(ctx, streamID, clientIP); err != nil {
+		return g.allowed(room, userID, err)
+	}
+
+	stream, err := g.streams.Get(ctx, streamID)
+	if errors.Is(err, store.ErrNotFound) {
+		return true
+	}
+	if err != nil {
+		log.Printf("Error loading stream for room check: room=%s, err=%v", room, err)
+		return false
+	}
+
+	return g.allowed(room, userID, g.ageGate.CheckPlayback(ctx, userID, ContentRating{
+		ContentID: stream.ID,
+		Kind:      ContentKindStream,
+		Category:  stream.Category,
+		Mature:    stream.IsMature,
+	}))
+}
+
+// allowed reports whether a check passed, logging checks that failed to run
+// rather than refusing the viewer
+func (g *RoomGate) allowed(room, userID string, err error) bool {
+	var restricted *RestrictionError
+	if err != nil && !errors.As(err, &restricted) && !isAgeGateError(err) {
+		log.Printf("Error checking room access: room=%s, userID=%s, err=%v", room, userID, err)
+	}
+	return err == nil
+}
+
+// isAgeGateError reports whether err is a viewer failing the age gate
+// rather than the check failing
+func isAgeGateError(err error) bool {
+	return errors.Is(err, ErrAgeVerificationRequired) || errors.Is(err, ErrUnderage) ||
+		errors.Is(err, ErrMatureConsentRequired)
+}
@@ -0,0 +1,180 @@
+package compliance
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/tinle0301/streaming-platform-api/internal/store"
+	"github.com/tinle0301/streaming-platform-api/internal/tenancy"
+)
+
+// memoryProfiles implements ProfileRepository in memory
+type memoryProfiles struct {
+	mu       sync.Mutex
+	profiles map[string]ViewerProfile
+}
+
+func (p *memoryProfiles) GetProfile(ctx context.Context, userID string) (ViewerProfile, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	profile, ok := p.profiles[userID]
+	if !ok {
+		return ViewerProfile{UserID: userID}, nil
+	}
+	return profile, nil
+}
+
+func (p *memoryProfiles) SetBirthDate(ctx context.Context, userID string, birthDate time.Time) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	profile := p.profiles[userID]
+	profile.BirthDate = &birthDate
+	p.profiles[userID] = profile
+	return nil
+}
+
+func (p *memoryProfiles) SetMatureConsent(ctx context.Context, userID string, at time.Time) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	profile := p.profiles[userID]
+	profile.MatureConsentAt = &at
+	p.profiles[userID] = profile
+	return nil
+}
+
+// memoryRestrictions implements RestrictionRepository in memory
+type memoryRestrictions map[string]GeoRestriction
+
+func (m memoryRestrictions) GetRestriction(ctx context.Context, contentID string) (GeoRestriction, bool, error) {
+	restriction, ok := m[contentID]
+	return restriction, ok, nil
+}
+
+func (m memoryRestrictions) SaveRestriction(ctx context.Context, restriction GeoRestriction) error {
+	m[restriction.ContentID] = restriction
+	return nil
+}
+
+func (m memoryRestrictions) DeleteRestriction(ctx context.Context, contentID string) (bool, error) {
+	_, ok := m[contentID]
+	delete(m, contentID)
+	return ok, nil
+}
+
+// matureStreams serves streams by ID; only Get is used
+type matureStreams struct {
+	store.StreamRepository
+	streams map[string]*store.Stream
+}
+
+func (s matureStreams) Get(ctx context.Context, id string) (*store.Stream, error) {
+	stream, ok := s.streams[id]
+	if !ok {
+		return nil, store.ErrNotFound
+	}
+	return stream, nil
+}
+
+func TestRoomGateAgeGatesMatureStreams(t *testing.T) {
+	const (
+		mature = "11111111-1111-1111-1111-111111111111"
+		casual = "22222222-2222-2222-2222-222222222222"
+	)
+	ageGate := NewAgeGate(&memoryProfiles{profiles: make(map[string]ViewerProfile)})
+	gate := NewRoomGate(ageGate, NewGeoRestrictions(nil, memoryRestrictions{}, nil), matureStreams{streams: map[string]*store.Stream{
+		mature: {ID: mature, IsMature: true},
+		casual: {ID: casual},
+	}})
+	ctx := context.Background()
+	room := tenancy.Room(tenancy.Default, mature)
+
+	if !gate.CanJoin("alice", "203.0.113.7", tenancy.Room(tenancy.Default, casual)) {
+		t.Error("alice was kept out of a stream that isn't mature")
+	}
+	if gate.CanJoin("alice", "203.0.113.7", room) {
+		t.Error("alice joined a mature stream without a verified age")
+	}
+
+	if err := ageGate.RecordConsent(ctx, "alice"); err != ErrAgeVerificationRequired {
+		t.Fatalf("RecordConsent before a birth date = %v, want %v", err, ErrAgeVerificationRequired)
+	}
+	if err := ageGate.RecordBirthDate(ctx, "alice", time.Now().AddDate(-30, 0, 0)); err != nil {
+		t.Fatal(err)
+	}
+	if gate.CanJoin("alice", "203.0.113.7", room) {
+		t.Error("alice joined a mature stream without consenting")
+	}
+	if err := ageGate.RecordConsent(ctx, "alice"); err != nil {
+		t.Fatal(err)
+	}
+	if !gate.CanJoin("alice", "203.0.113.7", room) || !gate.CanJoin("alice", "203.0.113.7", room+":captions:en") {
+		t.Error("alice was kept out of a mature stream after verifying and consenting")
+	}
+
+	if err := ageGate.RecordBirthDate(ctx, "bob", time.Now().AddDate(-15, 0, 0)); err != nil {
+		t.Fatal(err)
+	}
+	if err := ageGate.RecordConsent(ctx, "bob"); err != ErrUnderage {
+		t.Errorf("RecordConsent by a minor = %v, want %v", err, ErrUnderage)
+	}
+	if gate.CanJoin("bob", "203.0.113.7", room) {
+		t.Error("a minor joined a mature stream")
+	}
+}
+
+func TestRoomGateGeoRestrictsRooms(t *testing.T) {
+	const stream = "11111111-1111-1111-1111-111111111111"
+	locator, err := NewCIDRGeoLocator(map[string]string{"203.0.113.0/24": "au", "198.51.100.0/24": "US"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	restrictions := NewGeoRestrictions(locator, memoryRestrictions{}, nil)
+	gate := NewRoomGate(NewAgeGate(&memoryProfiles{profiles: make(map[string]ViewerProfile)}), restrictions,
+		matureStreams{streams: map[string]*store.Stream{stream: {ID: stream}}})
+	ctx := context.Background()
+
+	if err := restrictions.SetRestriction(ctx, GeoRestriction{ContentID: stream, Kind: ContentKindStream, Countries: []string{"au"}, Reason: "SOMETHING"}); !errors.Is(err, ErrInvalidRestriction) {
+		t.Fatalf("SetRestriction with an unknown reason = %v, want ErrInvalidRestriction", err)
+	}
+	err = restrictions.SetRestriction(ctx, GeoRestriction{
+		ContentID: stream, Kind: ContentKindStream, Countries: []string{"au"}, Reason: RestrictionReasonLicensing,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = restrictions.SetRestriction(ctx, GeoRestriction{
+		ContentID: "vod-1", Kind: ContentKindVOD, Countries: []string{"US"}, AllowList: true, Reason: RestrictionReasonDMCA,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	room := tenancy.Room(tenancy.Default, stream)
+	tests := []struct {
+		ip, room string
+		want     bool
+	}{
+		{"203.0.113.7", room, false},
+		{"203.0.113.7", room + ":captions:en", false},
+		{"198.51.100.7", room, true},
+		{"192.0.2.1", room, false}, // unplaced clients are refused restricted content
+		{"198.51.100.7", tenancy.Room(tenancy.Default, "premiere:vod-1"), true},
+		{"203.0.113.7", tenancy.Room(tenancy.Default, "premiere:vod-1"), false},
+		{"192.0.2.1", tenancy.Room(tenancy.Default, "premiere:vod-1"), false},
+	}
+	for _, tt := range tests {
+		if got := gate.CanJoin("alice", tt.ip, tt.room); got != tt.want {
+			t.Errorf("CanJoin from %s to %s = %t, want %t", tt.ip, tt.room, got, tt.want)
+		}
+	}
+
+	if removed, err := restrictions.RemoveRestriction(ctx, stream, "admin"); err != nil || !removed {
+		t.Fatalf("RemoveRestriction = %t, %v; want removed", removed, err)
+	}
+	if !gate.CanJoin("alice", "203.0.113.7", room) {
+		t.Error("room stayed closed after the restriction was removed")
+	}
+}
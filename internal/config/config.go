@@ -0,0 +1,894 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/netip"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tinle0301/streaming-platform-api/internal/chatactivity"
+	"github.com/tinle0301/streaming-platform-api/internal/dataloader"
+	"github.com/tinle0301/streaming-platform-api/internal/directmessages"
+	"github.com/tinle0301/streaming-platform-api/internal/events"
+	"github.com/tinle0301/streaming-platform-api/internal/httpmiddleware"
+	"github.com/tinle0301/streaming-platform-api/internal/logging"
+	"github.com/tinle0301/streaming-platform-api/internal/metrics"
+	"github.com/tinle0301/streaming-platform-api/internal/notifications"
+	"github.com/tinle0301/streaming-platform-api/internal/persistedqueries"
+	"github.com/tinle0301/streaming-platform-api/internal/presence"
+	"github.com/tinle0301/streaming-platform-api/internal/raids"
+	"github.com/tinle0301/streaming-platform-api/internal/ratelimit"
+	"github.com/tinle0301/streaming-platform-api/internal/slowops"
+	"github.com/tinle0301/streaming-platform-api/internal/startup"
+	"github.com/tinle0301/streaming-platform-api/internal/store"
+	"github.com/tinle0301/streaming-platform-api/internal/tracing"
+	"github.com/tinle0301/streaming-platform-api/internal/websocket"
+)
+
+// Servers a configuration is loaded for
+const (
+	ServiceAPI = "api-server"
+	ServiceWS  = "ws-server"
+)
+
+// EnvironmentDevelopment is the default environment, the only one where
+// development defaults such as DevelopmentJWTSecret are accepted
+const EnvironmentDevelopment = "development"
+
+// DevelopmentJWTSecret signs tokens when JWT_SECRET is not set; it is
+// rejected outside development
+const DevelopmentJWTSecret = "your-secret-key-change-in-production"
+
+const (
+	defaultDatabaseURL = "postgresql://localhost:5432/streamhub"
+
+	// defaultDisclosureRegions have advertising rules requiring sponsorship
+	// disclosure on branded content
+	defaultDisclosureRegions = "US,GB,FR,DE,IT,ES,NL,BE,IE,AU,CA"
+)
+
+// Config is the configuration of both servers, read from an optional
+// config file (CONFIG_FILE) overlaid by environment variables. Settings
+// shared by the servers are top-level; the rest are grouped per server.
+type Config struct {
+	// Service is the server the configuration was loaded for
+	Service string
+
+	// Environment is development, staging, or production (ENVIRONMENT)
+	Environment string
+
+	// File is the config file the environment overlays, if any
+	File string
+
+	Logging logging.Options
+	Tracing tracing.Options
+
+	// Events go to a Redis stream when EventBackend is redis-streams, to
+	// Kafka when it is kafka, to NATS JetStream when it is nats, to
+	// RabbitMQ when RabbitMQURL is set, and
+	// otherwise to Redis Pub/Sub. Streams are trimmed to about
+	// EventStreamMaxLen entries.
+	RedisURL          string
+	RabbitMQURL       string
+	EventBackend      string
+	EventStreamMaxLen int64
+
+	// Kafka brokers and topic layout, and how consumer groups rebalance
+	// (KAFKA_REBALANCE)
+	Kafka          events.KafkaOptions
+	KafkaRebalance string
+
+	// NATS server, and the JetStream stream's name and retention
+	NATS events.NATSOptions
+
+	JWTSecret string
+	JWTTTL    time.Duration
+
+	// A JWT_SECRET rotated in the secrets provider is picked up within
+	// SecretRotationInterval (SECRET_ROTATION_INTERVAL)
+	SecretRotationInterval time.Duration
+
+	// Browser origins allowed to call /graphql and upgrade WebSockets
+	// (ALLOWED_ORIGINS, comma-separated)
+	AllowedOrigins []string
+
+	// Networks of the load balancers and proxies in front of the servers,
+	// whose X-Forwarded-For entries are believed (TRUSTED_PROXIES,
+	// comma-separated CIDRs or IPs)
+	TrustedProxies []netip.Prefix
+
+	// Longest deadline clients can give a request with X-Request-Timeout
+	// (HTTP_MAX_REQUEST_TIMEOUT)
+	MaxRequestTimeout time.Duration
+
+	DependencyWait startup.WaitOptions
+
+	// Platform admins, who may use the servers' admin APIs, inspect and
+	// requeue dead letters, and see slow operations (ADMIN_USER_IDS,
+	// comma-separated)
+	AdminUserIDs []string
+
+	// Multi-tenancy isolation mode for white-label deployments (MULTI_TENANT)
+	MultiTenant bool
+
+	// Per-tenant usage metering (USAGE_METERING)
+	UsageMetering bool
+
+	// Stream categories that are always mature, whatever the streamer sets
+	// (MATURE_CATEGORIES, comma-separated)
+	MatureCategories []string
+
+	// Countries client networks are in, for enforcing geo restrictions
+	// (GEO_IP_RANGES, comma-separated CIDR=country pairs). Restricted
+	// content is refused to clients outside every listed network.
+	GeoIPRanges map[string]string
+
+	API APIConfig
+	WS  WSConfig
+
+	// settings are the resolved values for Redacted; loadErrs are the
+	// invalid values Validate reports
+	settings map[string]setting
+	loadErrs []error
+}
+
+// APIConfig configures the GraphQL API server
+type APIConfig struct {
+	Port              string
+	MetricsPort       string
+	DatabaseURL       string
+	DatabasePool      store.PoolConfig
+	GraphQLPlayground bool
+	GraphQLLoaders    dataloader.Options
+
+	// Automatic persisted queries (GRAPHQL_PERSISTED_QUERIES): documents are
+	// cached in Redis by hash for GRAPHQL_PERSISTED_QUERY_TTL, and the
+	// operations of the manifest at GRAPHQL_PERSISTED_QUERY_MANIFEST are
+	// always known. With GRAPHQL_PERSISTED_QUERIES_ONLY, only those run.
+	PersistedQueries persistedqueries.Options
+
+	// How go-live notifications reach followers and how long repeats are
+	// digested
+	NotificationFanOut notifications.FanOutOptions
+
+	// Who may direct message whom
+	DirectMessages directmessages.Policy
+
+	// Where consumers put events they keep failing on (EVENT_DLQ: redis,
+	// postgres, or none), after retrying with EventRetry's backoff
+	// (EVENT_MAX_RETRIES, EVENT_RETRY_DELAY, EVENT_RETRY_MAX_DELAY)
+	DeadLetters string
+	EventRetry  events.RetryPolicy
+
+	// Resolvers and queries slower than their thresholds are logged and
+	// kept for the slowOperations query (SLOW_RESOLVER_THRESHOLD,
+	// SLOW_QUERY_THRESHOLD; 0 disables)
+	SlowOperations slowops.Options
+
+	// How often live streams get tag suggestions (0 disables)
+	AutoTagInterval time.Duration
+
+	// How often live chat is checked for spikes to mark (0 disables)
+	AutoMarkerInterval time.Duration
+
+	// How often live streams' viewer counts are sampled for analytics (0 disables)
+	AnalyticsInterval time.Duration
+
+	// How often live streams are checked for viewer and duration milestones
+	// (0 disables)
+	MilestoneInterval time.Duration
+
+	// How often premieres are checked for countdowns, starts, and playback
+	// syncs (0 disables)
+	PremiereTickInterval time.Duration
+
+	// Countries where branded content must name its sponsor and disclosure
+	// type (BRANDED_CONTENT_DISCLOSURE_REGIONS, comma-separated)
+	DisclosureRegions []string
+
+	// Users besides the platform admins who may create reward campaigns
+	// (CAMPAIGN_PUBLISHER_IDS, comma-separated)
+	CampaignPublisherIDs []string
+
+	// Bearer token the speech-to-text service posts captions with; caption
+	// intake is disabled without one
+	CaptionIngestToken string
+
+	// Base64 32-byte key encrypting channels' own storage credentials;
+	// bring-your-own storage is disabled without one
+	StorageEncryptionKey string
+
+	// Single sign-on (SSO_CALLBACK_URL, SSO_ENCRYPTION_KEY): the public URL
+	// of /sso/callback and the base64 32-byte key encrypting providers'
+	// client secrets; disabled without both. Providers are configured
+	// through /admin/sso with SSOAdminToken (SSO_ADMIN_TOKEN).
+	SSOCallbackURL   string
+	SSOEncryptionKey string
+	SSOAdminToken    string
+
+	// How long a raid counts down before viewers are moved (RAID_COUNTDOWN)
+	RaidCountdown time.Duration
+
+	// How often expired renewing subscriptions are renewed
+	// (SUBSCRIPTION_RENEW_INTERVAL)
+	SubscriptionRenewInterval time.Duration
+
+	// How often tenants and their settings are reloaded
+	TenantRefreshInterval time.Duration
+
+	// Default quotas of tenants that don't override them in their settings
+	// (TENANT_MAX_CONNECTIONS, TENANT_EVENTS_PER_SECOND,
+	// TENANT_GRAPHQL_OPS_PER_MINUTE; 0 = unlimited)
+	TenantQuota ratelimit.Quota
+
+	// Daily usage is rolled up every UsageRollupInterval and exported to
+	// billing with BillingExportToken
+	UsageRollupInterval time.Duration
+	BillingExportToken  string
+
+	BackupDir          string
+	BackupInterval     time.Duration
+	BackupRehearsalURL string
+
+	// Expired chat and analytics are pruned every RetentionInterval; with
+	// RetentionDryRun the job only reports what it would delete. Ages are
+	// set per class with RETENTION_<CLASS>_DAYS.
+	RetentionInterval time.Duration
+	RetentionDryRun   bool
+
+	// With ArchiveBucket (ARCHIVE_BUCKET, in ArchiveRegion from AWS_REGION)
+	// expired rows are archived to S3 before they are pruned
+	ArchiveBucket string
+	ArchiveRegion string
+
+	// Hash matching service clips are checked against before publishing
+	// (HASH_MATCH_URL, HASH_MATCH_API_KEY); required in production
+	HashMatchURL    string
+	HashMatchAPIKey string
+
+	// Reporting service matched content is filed with as a mandatory
+	// report (HASH_MATCH_REPORT_URL, HASH_MATCH_REPORT_API_KEY); required
+	// in production
+	HashMatchReportURL    string
+	HashMatchReportAPIKey string
+
+	// Secret the payment provider signs subscription entitlement tokens
+	// with (PAYMENT_ENTITLEMENT_SECRET); without it nothing can be bought,
+	// and it is required in production
+	PaymentEntitlementSecret string
+
+	// Latest legal agreements users must accept before using the API
+	// (TERMS_OF_SERVICE_VERSION and _URL, PRIVACY_POLICY_VERSION and _URL);
+	// an agreement without a version isn't required
+	TermsOfServiceVersion string
+	TermsOfServiceURL     string
+	PrivacyPolicyVersion  string
+	PrivacyPolicyURL      string
+}
+
+// WSConfig configures the WebSocket server
+type WSConfig struct {
+	Port string
+
+	// NodeID names this node in the cluster registry; PublicURL is where
+	// peers send clients during a handoff, which lasts HandoffWindow
+	NodeID        string
+	PublicURL     string
+	HandoffWindow time.Duration
+
+	// Public endpoint named in /asyncapi.json (ASYNCAPI_SERVER_URL)
+	AsyncAPIServerURL string
+
+	// Whether X-Forwarded-For is trusted for client IPs from any peer
+	// (WS_TRUST_FORWARDED_FOR) when TRUSTED_PROXIES is unset; clients can
+	// forge it
+	TrustForwardedFor bool
+
+	// Keys bots registered here must HMAC-sign their inbound messages with
+	// (WS_BOT_KEYS, "userID:key,userID:key")
+	BotKeys map[string][]byte
+
+	// Bearer token for the hub operations API at /admin/hub
+	// (WS_ADMIN_TOKEN); the API is off without one
+	AdminToken string
+
+	GuestTokenTTL   time.Duration
+	GuestPolicy     websocket.GuestPolicy
+	ConnectionLimit websocket.ConnectionLimitPolicy
+	RateLimits      websocket.MessageRateLimits
+	Compression     websocket.CompressionOptions
+	ViewerCount     websocket.ViewerCountPolicy
+	Cardinality     metrics.CardinalityConfig
+	ChatSampling    websocket.ChatSamplingPolicy
+	Drain           websocket.DrainOptions
+	Sessions        websocket.SessionOptions
+	Presence        presence.Options
+
+	// Viewers must answer a heartbeat challenge this often to accrue watch time
+	WatchChallengeInterval time.Duration
+}
+
+// Load reads the configuration of service from CONFIG_FILE, if set, and
+// the environment, overlays secrets from the configured secrets provider,
+// and validates it. The configuration is returned even when it is invalid,
+// so logging can be set up to report the error.
+func Load(ctx context.Context, service string) (Config, error) {
+	var file map[string]string
+	path := os.Getenv("CONFIG_FILE")
+	if path != "" {
+		var err error
+		if file, err = ReadFile(path); err != nil {
+			return load(service, "", nil, os.LookupEnv), err
+		}
+	}
+
+	cfg := load(service, path, file, os.LookupEnv)
+	provider, err := NewSecretsProviderFromEnv()
+	if err != nil {
+		return cfg, err
+	}
+	if err := cfg.loadSecrets(ctx, provider); err != nil {
+		return cfg, err
+	}
+	return cfg, cfg.Validate()
+}
+
+// load resolves every setting from file values and the environment
+func load(service, path string, file map[string]string, lookup func(string) (string, bool)) Config {
+	src := newSource(file, lookup)
+	environment := src.String("ENVIRONMENT", EnvironmentDevelopment)
+
+	cfg := Config{
+		Service:     service,
+		Environment: environment,
+		File:        path,
+
+		Logging: logging.OptionsFromEnv(src.String("LOG_LEVEL", "info"), src.String("LOG_FORMAT", ""), environment, service),
+
+		RedisURL:     src.URL("REDIS_URL", "redis://localhost:6379"),
+		RabbitMQURL:  src.URL("RABBITMQ_URL", ""),
+		EventBackend: src.String("EVENT_BACKEND", ""),
+
+		JWTSecret: src.Secret("JWT_SECRET", DevelopmentJWTSecret),
+		JWTTTL:    src.Duration("JWT_TTL", 24*time.Hour),
+
+		SecretRotationInterval: src.Duration("SECRET_ROTATION_INTERVAL", 5*time.Minute),
+
+		AllowedOrigins: httpmiddleware.OriginsFromEnv(src.String("ALLOWED_ORIGINS", ""), environment),
+
+		TrustedProxies:    src.Prefixes("TRUSTED_PROXIES", ""),
+		MaxRequestTimeout: src.Duration("HTTP_MAX_REQUEST_TIMEOUT", 30*time.Second),
+
+		AdminUserIDs: src.List("ADMIN_USER_IDS", ""),
+
+		MultiTenant:   src.Bool("MULTI_TENANT", false),
+		UsageMetering: src.Bool("USAGE_METERING", false),
+
+		MatureCategories: src.List("MATURE_CATEGORIES", ""),
+		GeoIPRanges:      src.Countries("GEO_IP_RANGES", ""),
+	}
+	cfg.EventStreamMaxLen = int64(src.Int("EVENT_STREAM_MAX_LEN", int(events.DefaultRedisStreamsPublisherOptions().MaxLen)))
+
+	cfg.Kafka = events.DefaultKafkaOptions(src.List("KAFKA_BROKERS", ""))
+	cfg.Kafka.ClientID = service
+	cfg.Kafka.Layout = src.String("KAFKA_TOPIC_LAYOUT", cfg.Kafka.Layout)
+	cfg.Kafka.Topic = src.String("KAFKA_TOPIC", cfg.Kafka.Topic)
+	cfg.KafkaRebalance = src.String("KAFKA_REBALANCE", events.KafkaRebalanceSticky)
+
+	cfg.NATS = events.DefaultNATSOptions(src.URL("NATS_URL", ""))
+	cfg.NATS.Name = service
+	cfg.NATS.Stream = src.String("NATS_STREAM", cfg.NATS.Stream)
+	cfg.NATS.Subject = src.String("NATS_SUBJECT", cfg.NATS.Subject)
+	cfg.NATS.Retention = src.String("NATS_RETENTION", cfg.NATS.Retention)
+	cfg.NATS.MaxAge = src.Duration("NATS_MAX_AGE", cfg.NATS.MaxAge)
+	cfg.NATS.MaxBytes = int64(src.Int("NATS_MAX_BYTES", int(cfg.NATS.MaxBytes)))
+	cfg.NATS.Replicas = src.Int("NATS_REPLICAS", cfg.NATS.Replicas)
+
+	var err error
+	cfg.Tracing, err = tracing.OptionsFromEnv(src.URL("OTEL_EXPORTER_OTLP_ENDPOINT", ""),
+		src.URL("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", ""), src.Secret("OTEL_EXPORTER_OTLP_HEADERS", ""),
+		src.String("OTEL_TRACES_SAMPLER_ARG", ""), environment, src.String("OTEL_SERVICE_NAME", service))
+	if err != nil {
+		src.errs = append(src.errs, fmt.Errorf("invalid tracing configuration: %w", err))
+	}
+
+	wait := startup.DefaultWaitOptions()
+	cfg.DependencyWait = startup.WaitOptions{
+		MaxWait:        src.Duration("STARTUP_MAX_WAIT", wait.MaxWait),
+		InitialBackoff: wait.InitialBackoff,
+		MaxBackoff:     src.Duration("STARTUP_MAX_BACKOFF", wait.MaxBackoff),
+		CheckTimeout:   wait.CheckTimeout,
+	}
+
+	cfg.API = loadAPI(src)
+	cfg.WS = loadWS(src)
+
+	for _, key := range src.unknownFileKeys() {
+		src.errs = append(src.errs, fmt.Errorf("%s: unknown setting in %s", key, path))
+	}
+	cfg.settings = src.settings
+	cfg.loadErrs = src.errs
+	return cfg
+}
+
+// loadAPI resolves the API server's settings
+func loadAPI(src *source) APIConfig {
+	pool := store.DefaultPoolConfig()
+	loaders := dataloader.DefaultOptions()
+	fanOut := notifications.DefaultFanOutOptions()
+	retry := events.DefaultRetryPolicy()
+	slow := slowops.DefaultOptions()
+	persisted := persistedqueries.DefaultOptions()
+
+	return APIConfig{
+		Port:              src.String("API_PORT", "8080"),
+		MetricsPort:       src.String("METRICS_PORT", "9090"),
+		DatabaseURL:       src.URL("DATABASE_URL", defaultDatabaseURL),
+		GraphQLPlayground: src.Bool("GRAPHQL_PLAYGROUND", true),
+
+		DatabasePool: store.PoolConfig{
+			MaxConns:          int32(src.Int("DB_MAX_CONNS", int(pool.MaxConns))),
+			MinConns:          int32(src.Int("DB_MIN_CONNS", int(pool.MinConns))),
+			MaxConnLifetime:   src.Duration("DB_MAX_CONN_LIFETIME", pool.MaxConnLifetime),
+			MaxConnIdleTime:   src.Duration("DB_MAX_CONN_IDLE_TIME", pool.MaxConnIdleTime),
+			HealthCheckPeriod: pool.HealthCheckPeriod,
+		},
+
+		GraphQLLoaders: dataloader.Options{
+			Wait:      src.Duration("GRAPHQL_LOADER_WAIT", loaders.Wait),
+			MaxBatch:  src.Int("GRAPHQL_LOADER_MAX_BATCH", loaders.MaxBatch),
+			CacheSize: src.Int("GRAPHQL_LOADER_CACHE_SIZE", loaders.CacheSize),
+		},
+
+		PersistedQueries: persistedqueries.Options{
+			Enabled:       src.Bool("GRAPHQL_PERSISTED_QUERIES", persisted.Enabled),
+			TTL:           src.Duration("GRAPHQL_PERSISTED_QUERY_TTL", persisted.TTL),
+			Manifest:      src.String("GRAPHQL_PERSISTED_QUERY_MANIFEST", ""),
+			AllowlistOnly: src.Bool("GRAPHQL_PERSISTED_QUERIES_ONLY", false),
+		},
+
+		NotificationFanOut: notifications.FanOutOptions{
+			ReadThreshold:      src.Int("NOTIFICATION_FANOUT_READ_THRESHOLD", fanOut.ReadThreshold),
+			BatchSize:          src.Int("NOTIFICATION_FANOUT_BATCH_SIZE", fanOut.BatchSize),
+			Workers:            src.Int("NOTIFICATION_FANOUT_WORKERS", fanOut.Workers),
+			DigestWindow:       src.Duration("NOTIFICATION_DIGEST_WINDOW", fanOut.DigestWindow),
+			AnnouncementMaxAge: fanOut.AnnouncementMaxAge,
+		},
+
+		DirectMessages: directmessages.Policy{
+			RequireMutualFollow: src.Bool("DIRECT_MESSAGES_REQUIRE_MUTUAL_FOLLOW", false),
+		},
+
+		DeadLetters: src.String("EVENT_DLQ", events.DeadLetterRedis),
+		EventRetry: events.RetryPolicy{
+			MaxRetries: src.Int("EVENT_MAX_RETRIES", retry.MaxRetries),
+			Delay:      src.Duration("EVENT_RETRY_DELAY", retry.Delay),
+			MaxDelay:   src.Duration("EVENT_RETRY_MAX_DELAY", retry.MaxDelay),
+		},
+		SlowOperations: slowops.Options{
+			ResolverThreshold: src.Duration("SLOW_RESOLVER_THRESHOLD", slow.ResolverThreshold),
+			QueryThreshold:    src.Duration("SLOW_QUERY_THRESHOLD", slow.QueryThreshold),
+			MaxOffenders:      slow.MaxOffenders,
+		},
+
+		AutoTagInterval:      src.Duration("AUTO_TAG_INTERVAL", 10*time.Minute),
+		AutoMarkerInterval:   src.Duration("AUTO_MARKER_INTERVAL", chatactivity.BucketSize),
+		AnalyticsInterval:    src.Duration("ANALYTICS_SAMPLE_INTERVAL", 15*time.Second),
+		MilestoneInterval:    src.Duration("MILESTONE_CHECK_INTERVAL", 30*time.Second),
+		PremiereTickInterval: src.Duration("PREMIERE_TICK_INTERVAL", time.Second),
+
+		DisclosureRegions:    src.List("BRANDED_CONTENT_DISCLOSURE_REGIONS", defaultDisclosureRegions),
+		CampaignPublisherIDs: src.List("CAMPAIGN_PUBLISHER_IDS", ""),
+
+		CaptionIngestToken:   src.Secret("CAPTION_INGEST_TOKEN", ""),
+		StorageEncryptionKey: src.Secret("STORAGE_ENCRYPTION_KEY", ""),
+
+		SSOCallbackURL:   src.String("SSO_CALLBACK_URL", ""),
+		SSOEncryptionKey: src.Secret("SSO_ENCRYPTION_KEY", ""),
+		SSOAdminToken:    src.Secret("SSO_ADMIN_TOKEN", ""),
+
+		RaidCountdown: src.Duration("RAID_COUNTDOWN", raids.DefaultCountdown),
+
+		SubscriptionRenewInterval: src.Duration("SUBSCRIPTION_RENEW_INTERVAL", time.Minute),
+
+		TenantRefreshInterval: src.Duration("TENANT_REFRESH_INTERVAL", time.Minute),
+		TenantQuota: ratelimit.Quota{
+			MaxConnections:      src.Int("TENANT_MAX_CONNECTIONS", 0),
+			EventsPerSecond:     src.Int("TENANT_EVENTS_PER_SECOND", 0),
+			GraphQLOpsPerMinute: src.Int("TENANT_GRAPHQL_OPS_PER_MINUTE", 0),
+		},
+
+		UsageRollupInterval: src.Duration("USAGE_ROLLUP_INTERVAL", 5*time.Minute),
+		BillingExportToken:  src.Secret("BILLING_EXPORT_TOKEN", ""),
+
+		BackupDir:          src.String("BACKUP_DIR", ""),
+		BackupInterval:     src.Duration("BACKUP_INTERVAL", 6*time.Hour),
+		BackupRehearsalURL: src.URL("BACKUP_REHEARSAL_DATABASE_URL", ""),
+
+		RetentionInterval: src.Duration("RETENTION_INTERVAL", time.Hour),
+		RetentionDryRun:   src.Bool("RETENTION_DRY_RUN", false),
+
+		ArchiveBucket: src.String("ARCHIVE_BUCKET", ""),
+		ArchiveRegion: src.String("AWS_REGION", "us-east-1"),
+
+		HashMatchURL:    src.URL("HASH_MATCH_URL", ""),
+		HashMatchAPIKey: src.Secret("HASH_MATCH_API_KEY", ""),
+
+		HashMatchReportURL:    src.URL("HASH_MATCH_REPORT_URL", ""),
+		HashMatchReportAPIKey: src.Secret("HASH_MATCH_REPORT_API_KEY", ""),
+
+		PaymentEntitlementSecret: src.Secret("PAYMENT_ENTITLEMENT_SECRET", ""),
+
+		TermsOfServiceVersion: src.String("TERMS_OF_SERVICE_VERSION", ""),
+		TermsOfServiceURL:     src.String("TERMS_OF_SERVICE_URL", ""),
+		PrivacyPolicyVersion:  src.String("PRIVACY_POLICY_VERSION", ""),
+		PrivacyPolicyURL:      src.String("PRIVACY_POLICY_URL", ""),
+	}
+}
+
+// loadWS resolves the WebSocket server's settings
+func loadWS(src *source) WSConfig {
+	port := src.String("WS_PORT", "8081")
+	cfg := WSConfig{
+		Port:              port,
+		NodeID:            src.String("WS_NODE_ID", defaultNodeID(port)),
+		PublicURL:         src.String("WS_PUBLIC_URL", "ws://localhost:"+port+"/ws"),
+		HandoffWindow:     src.Duration("WS_HANDOFF_WINDOW", 10*time.Second),
+		AsyncAPIServerURL: src.String("ASYNCAPI_SERVER_URL", ""),
+		TrustForwardedFor: src.Bool("WS_TRUST_FORWARDED_FOR", false),
+
+		GuestTokenTTL:          src.Duration("WS_GUEST_TOKEN_TTL", 24*time.Hour),
+		WatchChallengeInterval: src.Duration("WS_WATCH_CHALLENGE_INTERVAL", 2*time.Minute),
+	}
+	cfg.BotKeys = parseBotKeys(src, src.Secret("WS_BOT_KEYS", ""))
+	cfg.AdminToken = src.Secret("WS_ADMIN_TOKEN", "")
+
+	cfg.GuestPolicy = websocket.DefaultGuestPolicy()
+	cfg.GuestPolicy.MaxRooms = src.Int("WS_GUEST_MAX_ROOMS", cfg.GuestPolicy.MaxRooms)
+
+	cfg.ConnectionLimit = websocket.DefaultConnectionLimitPolicy()
+	cfg.ConnectionLimit.MaxPerUser = src.Int("WS_MAX_CONNECTIONS_PER_USER", cfg.ConnectionLimit.MaxPerUser)
+	cfg.ConnectionLimit.OnExceeded = src.String("WS_CONNECTION_LIMIT_MODE", cfg.ConnectionLimit.OnExceeded)
+
+	cfg.RateLimits = loadRateLimits(src)
+
+	cfg.Compression = websocket.DefaultCompressionOptions()
+	cfg.Compression.Enabled = src.Bool("WS_COMPRESSION", cfg.Compression.Enabled)
+	cfg.Compression.Level = src.Int("WS_COMPRESSION_LEVEL", cfg.Compression.Level)
+	cfg.Compression.Threshold = src.Int("WS_COMPRESSION_THRESHOLD", cfg.Compression.Threshold)
+
+	cfg.ViewerCount = websocket.DefaultViewerCountPolicy()
+	cfg.ViewerCount.MinInterval = src.Duration("WS_VIEWER_COUNT_MIN_INTERVAL", cfg.ViewerCount.MinInterval)
+	cfg.ViewerCount.MaxInterval = src.Duration("WS_VIEWER_COUNT_MAX_INTERVAL", cfg.ViewerCount.MaxInterval)
+	cfg.ViewerCount.SmallRoomSize = src.Int("WS_VIEWER_COUNT_SMALL_ROOM", cfg.ViewerCount.SmallRoomSize)
+	cfg.ViewerCount.LargeRoomSize = src.Int("WS_VIEWER_COUNT_LARGE_ROOM", cfg.ViewerCount.LargeRoomSize)
+	cfg.ViewerCount.RelativeThreshold = src.Float("WS_VIEWER_COUNT_THRESHOLD", cfg.ViewerCount.RelativeThreshold)
+
+	cfg.Cardinality = metrics.DefaultCardinalityConfig()
+	cfg.Cardinality.TopN = src.Int("WS_METRICS_TOP_ROOMS", cfg.Cardinality.TopN)
+	cfg.Cardinality.MaxLabelValues = src.Int("WS_METRICS_MAX_ROOM_LABELS", cfg.Cardinality.MaxLabelValues)
+	cfg.Cardinality.HashLabels = src.Bool("WS_METRICS_HASH_ROOMS", cfg.Cardinality.HashLabels)
+
+	// The burst follows a configured sampling rate
+	cfg.ChatSampling = websocket.DefaultChatSamplingPolicy()
+	cfg.ChatSampling.RoomSize = src.Int("WS_CHAT_SAMPLING_ROOM_SIZE", cfg.ChatSampling.RoomSize)
+	if src.isSet("WS_CHAT_SAMPLING_RATE") {
+		cfg.ChatSampling.MessagesPerSecond = src.Float("WS_CHAT_SAMPLING_RATE", cfg.ChatSampling.MessagesPerSecond)
+		cfg.ChatSampling.Burst = int(cfg.ChatSampling.MessagesPerSecond*2) + 1
+	} else {
+		src.Float("WS_CHAT_SAMPLING_RATE", cfg.ChatSampling.MessagesPerSecond)
+	}
+
+	cfg.Drain = websocket.DefaultDrainOptions()
+	cfg.Drain.FlushTimeout = src.Duration("WS_DRAIN_FLUSH_TIMEOUT", cfg.Drain.FlushTimeout)
+	cfg.Drain.CloseTimeout = src.Duration("WS_DRAIN_CLOSE_TIMEOUT", cfg.Drain.CloseTimeout)
+
+	// A zero resume window disables resume
+	cfg.Sessions = websocket.DefaultSessionOptions()
+	cfg.Sessions.Window = src.Duration("WS_RESUME_WINDOW", cfg.Sessions.Window)
+	cfg.Sessions.BufferSize = src.Int("WS_RESUME_BUFFER_SIZE", cfg.Sessions.BufferSize)
+
+	cfg.Presence = presence.DefaultOptions()
+	cfg.Presence.TTL = src.Duration("WS_PRESENCE_TTL", cfg.Presence.TTL)
+	cfg.Presence.HeartbeatInterval = src.Duration("WS_PRESENCE_HEARTBEAT_INTERVAL", cfg.Presence.HeartbeatInterval)
+	if cfg.Presence.HeartbeatInterval >= cfg.Presence.TTL {
+		cfg.Presence.HeartbeatInterval = cfg.Presence.TTL / 3
+	}
+	return cfg
+}
+
+// loadRateLimits resolves the inbound message rate limits. Limits are
+// written rate:burst; WS_RATE_LIMITS lists them per message type, e.g.
+// "message=5:10,subscribe=1:5,*=10:20" where * sets the default.
+func loadRateLimits(src *source) websocket.MessageRateLimits {
+	limits := websocket.DefaultMessageRateLimits()
+
+	for _, entry := range src.List("WS_RATE_LIMITS", "") {
+		messageType, value, ok := strings.Cut(entry, "=")
+		limit, valid := parseMessageLimit(value)
+		if !ok || messageType == "" || !valid {
+			src.invalid("WS_RATE_LIMITS", entry, "type=rate:burst")
+			continue
+		}
+		if messageType == "*" {
+			limits.Default = limit
+		} else {
+			limits.PerType[messageType] = limit
+		}
+	}
+	if value := src.String("WS_RATE_LIMIT_PER_IP", ""); value != "" {
+		if limit, ok := parseMessageLimit(value); ok {
+			limits.PerIP = limit
+		} else {
+			src.invalid("WS_RATE_LIMIT_PER_IP", value, "rate:burst")
+		}
+	}
+	limits.MaxViolations = src.Int("WS_RATE_LIMIT_MAX_VIOLATIONS", limits.MaxViolations)
+	limits.ViolationWindow = src.Duration("WS_RATE_LIMIT_VIOLATION_WINDOW", limits.ViolationWindow)
+	return limits
+}
+
+// parseMessageLimit parses a "rate:burst" limit; "0:0" disables it
+func parseMessageLimit(value string) (websocket.MessageLimit, bool) {
+	rate, burst, ok := strings.Cut(strings.TrimSpace(value), ":")
+	if !ok {
+		return websocket.MessageLimit{}, false
+	}
+	r, err := strconv.ParseFloat(rate, 64)
+	if err != nil || r < 0 {
+		return websocket.MessageLimit{}, false
+	}
+	b, err := strconv.Atoi(burst)
+	if err != nil || b < 0 {
+		return websocket.MessageLimit{}, false
+	}
+	return websocket.MessageLimit{Rate: r, Burst: b}, true
+}
+
+// parseBotKeys parses "userID:key,userID:key" into a key map
+func parseBotKeys(src *source, value string) map[string][]byte {
+	keys := make(map[string][]byte)
+	for _, pair := range strings.Split(value, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		userID, key, ok := strings.Cut(strings.TrimSpace(pair), ":")
+		if !ok || userID == "" || key == "" {
+			src.invalid("WS_BOT_KEYS", userID, "userID:key pairs")
+			continue
+		}
+		keys[userID] = []byte(key)
+	}
+	return keys
+}
+
+// defaultNodeID identifies this node by hostname
+func defaultNodeID(port string) string {
+	if hostname, err := os.Hostname(); err == nil {
+		return hostname
+	}
+	return "ws-" + port
+}
+
+// loadSecrets overlays secrets from provider. The environment was already
+// read, so the plain environment provider is skipped.
+func (c *Config) loadSecrets(ctx context.Context, provider SecretsProvider) error {
+	if _, ok := provider.(EnvProvider); ok {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	for name, target := range map[string]*string{
+		"JWT_SECRET":                 &c.JWTSecret,
+		"DATABASE_URL":               &c.API.DatabaseURL,
+		"CAPTION_INGEST_TOKEN":       &c.API.CaptionIngestToken,
+		"STORAGE_ENCRYPTION_KEY":     &c.API.StorageEncryptionKey,
+		"BILLING_EXPORT_TOKEN":       &c.API.BillingExportToken,
+		"SSO_ENCRYPTION_KEY":         &c.API.SSOEncryptionKey,
+		"SSO_ADMIN_TOKEN":            &c.API.SSOAdminToken,
+		"HASH_MATCH_API_KEY":         &c.API.HashMatchAPIKey,
+		"HASH_MATCH_REPORT_API_KEY":  &c.API.HashMatchReportAPIKey,
+		"PAYMENT_ENTITLEMENT_SECRET": &c.API.PaymentEntitlementSecret,
+		"WS_ADMIN_TOKEN":             &c.WS.AdminToken,
+	} {
+		value, err := provider.GetSecret(ctx, name)
+		if errors.Is(err, ErrSecretNotFound) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to load %s: %w", name, err)
+		}
+		if value != *target {
+			*target = value
+			current := c.settings[name]
+			c.settings[name] = setting{value: value, origin: OriginSecrets, redaction: current.redaction}
+		}
+	}
+	return nil
+}
+
+// Validate reports every invalid setting, so a server can refuse to start
+// with all of them listed at once
+func (c Config) Validate() error {
+	errs := append([]error{}, c.loadErrs...)
+	invalid := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	if _, err := logging.ParseLevel(c.Logging.Level); err != nil {
+		invalid("LOG_LEVEL: %w", err)
+	}
+	if c.Logging.Format != logging.FormatJSON && c.Logging.Format != logging.FormatText {
+		invalid("LOG_FORMAT: unknown format %q, want json or text", c.Logging.Format)
+	}
+	if c.Tracing.SampleRatio > 1 {
+		invalid("OTEL_TRACES_SAMPLER_ARG: sample ratio %g is above 1", c.Tracing.SampleRatio)
+	}
+
+	// Tokens signed with a known secret can be forged by anyone
+	if c.Environment != EnvironmentDevelopment && (c.JWTSecret == "" || c.JWTSecret == DevelopmentJWTSecret) {
+		invalid("JWT_SECRET: must be set outside %s", EnvironmentDevelopment)
+	}
+	switch c.EventBackend {
+	case "", events.BackendRedisStreams:
+	case events.BackendKafka:
+		if len(c.Kafka.Brokers) == 0 {
+			invalid("KAFKA_BROKERS: must be set when EVENT_BACKEND is %s", events.BackendKafka)
+		}
+		if c.Kafka.Layout != events.KafkaTopicPerType && c.Kafka.Layout != events.KafkaSingleTopic {
+			invalid("KAFKA_TOPIC_LAYOUT: unknown layout %q, want %s or %s", c.Kafka.Layout, events.KafkaTopicPerType, events.KafkaSingleTopic)
+		}
+		switch c.KafkaRebalance {
+		case events.KafkaRebalanceSticky, events.KafkaRebalanceRange, events.KafkaRebalanceRoundRobin:
+		default:
+			invalid("KAFKA_REBALANCE: unknown strategy %q, want %s, %s, or %s", c.KafkaRebalance,
+				events.KafkaRebalanceSticky, events.KafkaRebalanceRange, events.KafkaRebalanceRoundRobin)
+		}
+	case events.BackendNATS:
+		if c.NATS.URL == "" {
+			invalid("NATS_URL: must be set when EVENT_BACKEND is %s", events.BackendNATS)
+		}
+		if c.NATS.Stream == "" || strings.ContainsAny(c.NATS.Stream, ".*> ") {
+			invalid("NATS_STREAM: %q is not a valid stream name", c.NATS.Stream)
+		}
+		if c.NATS.Subject == "" || strings.ContainsAny(c.NATS.Subject, "*> ") {
+			invalid("NATS_SUBJECT: %q is not a valid subject prefix", c.NATS.Subject)
+		}
+		if c.NATS.Retention != events.NATSRetentionLimits && c.NATS.Retention != events.NATSRetentionInterest {
+			invalid("NATS_RETENTION: unknown policy %q, want %s or %s", c.NATS.Retention, events.NATSRetentionLimits, events.NATSRetentionInterest)
+		}
+		if c.NATS.MaxAge < 0 {
+			invalid("NATS_MAX_AGE: must not be negative")
+		}
+		if c.NATS.Replicas < 1 || c.NATS.Replicas > 5 {
+			invalid("NATS_REPLICAS: must be between 1 and 5")
+		}
+	default:
+		invalid("EVENT_BACKEND: unknown backend %q, want %s, %s, %s, or unset", c.EventBackend,
+			events.BackendRedisStreams, events.BackendKafka, events.BackendNATS)
+	}
+	requirePositive := func(key string, positive bool) {
+		if !positive {
+			invalid("%s: must be greater than zero", key)
+		}
+	}
+	requirePositive("JWT_TTL", c.JWTTTL > 0)
+	requirePositive("SECRET_ROTATION_INTERVAL", c.SecretRotationInterval > 0)
+
+	switch c.Service {
+	case ServiceAPI:
+		if err := ValidateProductionSecrets(c.Environment,
+			map[string]string{
+				"DATABASE_URL":               c.API.DatabaseURL,
+				"HASH_MATCH_URL":             c.API.HashMatchURL,
+				"HASH_MATCH_API_KEY":         c.API.HashMatchAPIKey,
+				"HASH_MATCH_REPORT_URL":      c.API.HashMatchReportURL,
+				"HASH_MATCH_REPORT_API_KEY":  c.API.HashMatchReportAPIKey,
+				"PAYMENT_ENTITLEMENT_SECRET": c.API.PaymentEntitlementSecret,
+			},
+			map[string]string{"DATABASE_URL": defaultDatabaseURL},
+		); err != nil {
+			errs = append(errs, err)
+		}
+		for key, port := range map[string]string{"API_PORT": c.API.Port, "METRICS_PORT": c.API.MetricsPort} {
+			if !validPort(port) {
+				invalid("%s: invalid port %q", key, port)
+			}
+		}
+		if c.API.DatabasePool.MinConns > c.API.DatabasePool.MaxConns {
+			invalid("DB_MIN_CONNS: %d is above DB_MAX_CONNS %d", c.API.DatabasePool.MinConns, c.API.DatabasePool.MaxConns)
+		}
+		requirePositive("DB_MAX_CONNS", c.API.DatabasePool.MaxConns > 0)
+		switch c.API.DeadLetters {
+		case events.DeadLetterRedis, events.DeadLetterPostgres, events.DeadLetterNone:
+		default:
+			invalid("EVENT_DLQ: unknown queue %q, want %s, %s, or %s", c.API.DeadLetters,
+				events.DeadLetterRedis, events.DeadLetterPostgres, events.DeadLetterNone)
+		}
+		if c.API.EventRetry.MaxRetries < 0 {
+			invalid("EVENT_MAX_RETRIES: must not be negative")
+		}
+		requirePositive("EVENT_RETRY_DELAY", c.API.EventRetry.Delay > 0)
+		if c.API.EventRetry.MaxDelay < c.API.EventRetry.Delay {
+			invalid("EVENT_RETRY_MAX_DELAY: %s is below EVENT_RETRY_DELAY %s", c.API.EventRetry.MaxDelay, c.API.EventRetry.Delay)
+		}
+		if persisted := c.API.PersistedQueries; persisted.Enabled {
+			if persisted.TTL < 0 {
+				invalid("GRAPHQL_PERSISTED_QUERY_TTL: must not be negative")
+			}
+			if persisted.AllowlistOnly && persisted.Manifest == "" {
+				invalid("GRAPHQL_PERSISTED_QUERIES_ONLY: needs GRAPHQL_PERSISTED_QUERY_MANIFEST")
+			}
+		} else if c.API.PersistedQueries.AllowlistOnly {
+			invalid("GRAPHQL_PERSISTED_QUERIES_ONLY: needs GRAPHQL_PERSISTED_QUERIES")
+		}
+		requirePositive("SUBSCRIPTION_RENEW_INTERVAL", c.API.SubscriptionRenewInterval > 0)
+		requirePositive("TENANT_REFRESH_INTERVAL", c.API.TenantRefreshInterval > 0)
+		requirePositive("USAGE_ROLLUP_INTERVAL", c.API.UsageRollupInterval > 0)
+		requirePositive("RETENTION_INTERVAL", c.API.RetentionInterval > 0)
+		if c.API.BackupDir != "" {
+			requirePositive("BACKUP_INTERVAL", c.API.BackupInterval > 0)
+		}
+
+	case ServiceWS:
+		// Channel point balances are kept in Postgres
+		if err := ValidateProductionSecrets(c.Environment,
+			map[string]string{"DATABASE_URL": c.API.DatabaseURL},
+			map[string]string{"DATABASE_URL": defaultDatabaseURL},
+		); err != nil {
+			errs = append(errs, err)
+		}
+		if !validPort(c.WS.Port) {
+			invalid("WS_PORT: invalid port %q", c.WS.Port)
+		}
+		if mode := c.WS.ConnectionLimit.OnExceeded; mode != websocket.ConnectionLimitReject && mode != websocket.ConnectionLimitBumpOldest {
+			invalid("WS_CONNECTION_LIMIT_MODE: unknown mode %q, want %s or %s", mode,
+				websocket.ConnectionLimitReject, websocket.ConnectionLimitBumpOldest)
+		}
+		if level := c.WS.Compression.Level; level < 1 || level > 9 {
+			invalid("WS_COMPRESSION_LEVEL: %d is not between 1 and 9", level)
+		}
+		requirePositive("WS_GUEST_TOKEN_TTL", c.WS.GuestTokenTTL > 0)
+		requirePositive("WS_WATCH_CHALLENGE_INTERVAL", c.WS.WatchChallengeInterval > 0)
+		requirePositive("WS_VIEWER_COUNT_MIN_INTERVAL", c.WS.ViewerCount.MinInterval > 0)
+		requirePositive("WS_VIEWER_COUNT_MAX_INTERVAL", c.WS.ViewerCount.MaxInterval > 0)
+		requirePositive("WS_VIEWER_COUNT_SMALL_ROOM", c.WS.ViewerCount.SmallRoomSize > 0)
+		requirePositive("WS_VIEWER_COUNT_LARGE_ROOM", c.WS.ViewerCount.LargeRoomSize > 0)
+		requirePositive("WS_RESUME_BUFFER_SIZE", c.WS.Sessions.BufferSize > 0)
+		requirePositive("WS_PRESENCE_TTL", c.WS.Presence.TTL > 0)
+		requirePositive("WS_RATE_LIMIT_VIOLATION_WINDOW", c.WS.RateLimits.ViolationWindow > 0)
+		requirePositive("WS_CHAT_SAMPLING_RATE", c.WS.ChatSampling.MessagesPerSecond > 0)
+	}
+	return errors.Join(errs...)
+}
+
+// validPort reports whether port is a TCP port number
+func validPort(port string) bool {
+	n, err := strconv.Atoi(port)
+	return err == nil && n > 0 && n <= 65535
+}
+
+// Redacted prints every setting as KEY=value with where it came from, one
+// per line and sorted, for logging. Secrets are hidden, as are passwords
+// in URLs.
+func (c Config) Redacted() string {
+	keys := make([]string, 0, len(c.settings))
+	for key := range c.settings {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	for _, key := range keys {
+		s := c.settings[key]
+		fmt.Fprintf(&b, "%s=%s (%s)\n", key, redactedValue(s), s.origin)
+	}
+	return b.String()
+}
@@ -0,0 +1,287 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// ReadFile reads a YAML (.yaml, .yml) or TOML (.toml) configuration file
+// into settings keyed like their environment variables: nested keys are
+// joined with underscores and upper-cased, so db.max_conns sets
+// DB_MAX_CONNS. Lists become comma-separated values. Only flat scalars,
+// nested maps, and lists of scalars are supported.
+func ReadFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var values map[string]string
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		values, err = parseYAML(string(data))
+	case ".toml":
+		values, err = parseTOML(string(data))
+	default:
+		return nil, fmt.Errorf("unsupported config file type %q, want .yaml, .yml, or .toml", ext)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s:%w", path, err)
+	}
+	return values, nil
+}
+
+// yamlLevel is an open mapping in a YAML document
+type yamlLevel struct {
+	indent int
+	prefix string
+}
+
+// parseYAML parses the YAML subset described by ReadFile
+func parseYAML(data string) (map[string]string, error) {
+	values := make(map[string]string)
+	levels := []yamlLevel{{indent: -1}}
+
+	// A key with no inline value opens a mapping or a block list
+	var openKey string
+	openIndent := -1
+	var list []string
+
+	for number, line := range strings.Split(data, "\n") {
+		line = strings.TrimRight(stripComment(line), " \t\r")
+		trimmed := strings.TrimLeft(line, " ")
+		if trimmed == "" || trimmed == "---" {
+			continue
+		}
+		if strings.HasPrefix(trimmed, "\t") {
+			return nil, fmt.Errorf("%d: tabs are not allowed for indentation", number+1)
+		}
+		indent := len(line) - len(trimmed)
+
+		if item, ok := strings.CutPrefix(trimmed, "- "); ok || trimmed == "-" {
+			if openKey == "" || indent < openIndent {
+				return nil, fmt.Errorf("%d: list item outside a list", number+1)
+			}
+			value, err := yamlScalar(item)
+			if err != nil {
+				return nil, fmt.Errorf("%d: %w", number+1, err)
+			}
+			list = append(list, value)
+			continue
+		}
+
+		// A key deeper than an open key starts its mapping; otherwise the
+		// open key was an empty value or a finished list
+		if openKey != "" && indent > openIndent && list == nil {
+			levels = append(levels, yamlLevel{indent: indent, prefix: openKey + "_"})
+		} else {
+			if openKey != "" {
+				values[openKey] = strings.Join(list, ",")
+			}
+			for len(levels) > 1 && indent < levels[len(levels)-1].indent {
+				levels = levels[:len(levels)-1]
+			}
+			if levels[len(levels)-1].indent == -1 {
+				levels[len(levels)-1].indent = indent
+			}
+			if indent != levels[len(levels)-1].indent {
+				return nil, fmt.Errorf("%d: inconsistent indentation", number+1)
+			}
+		}
+		openKey, openIndent, list = "", -1, nil
+
+		name, raw, ok := strings.Cut(trimmed, ":")
+		if !ok || strings.TrimSpace(name) == "" {
+			return nil, fmt.Errorf("%d: expected key: value", number+1)
+		}
+		key := levels[len(levels)-1].prefix + settingKey(name)
+		raw = strings.TrimSpace(raw)
+		if raw == "" {
+			openKey, openIndent = key, indent
+			continue
+		}
+		if raw == "|" || raw == ">" || strings.HasPrefix(raw, "|") || strings.HasPrefix(raw, ">") {
+			return nil, fmt.Errorf("%d: multi-line strings are not supported", number+1)
+		}
+		value, err := yamlValue(raw)
+		if err != nil {
+			return nil, fmt.Errorf("%d: %w", number+1, err)
+		}
+		values[key] = value
+	}
+	if openKey != "" {
+		values[openKey] = strings.Join(list, ",")
+	}
+	return values, nil
+}
+
+// yamlValue parses an inline value: a scalar or a [flow, list]
+func yamlValue(raw string) (string, error) {
+	if strings.HasPrefix(raw, "[") {
+		return flowList(raw, yamlScalar)
+	}
+	if strings.HasPrefix(raw, "{") {
+		return "", fmt.Errorf("inline mappings are not supported")
+	}
+	return yamlScalar(raw)
+}
+
+// yamlScalar parses a plain, single-quoted, or double-quoted scalar
+func yamlScalar(raw string) (string, error) {
+	raw = strings.TrimSpace(raw)
+	switch {
+	case strings.HasPrefix(raw, `"`):
+		value, err := strconv.Unquote(raw)
+		if err != nil {
+			return "", fmt.Errorf("invalid double-quoted string %s", raw)
+		}
+		return value, nil
+	case strings.HasPrefix(raw, "'"):
+		if len(raw) < 2 || !strings.HasSuffix(raw, "'") {
+			return "", fmt.Errorf("invalid single-quoted string %s", raw)
+		}
+		return strings.ReplaceAll(raw[1:len(raw)-1], "''", "'"), nil
+	case raw == "~" || raw == "null":
+		return "", nil
+	}
+	return raw, nil
+}
+
+// parseTOML parses the TOML subset described by ReadFile
+func parseTOML(data string) (map[string]string, error) {
+	values := make(map[string]string)
+	prefix := ""
+	for number, line := range strings.Split(data, "\n") {
+		line = strings.TrimSpace(stripComment(line))
+		if line == "" {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") {
+			if strings.HasPrefix(line, "[[") || !strings.HasSuffix(line, "]") {
+				return nil, fmt.Errorf("%d: only [table] headers are supported", number+1)
+			}
+			prefix = ""
+			for _, part := range strings.Split(line[1:len(line)-1], ".") {
+				prefix += settingKey(part) + "_"
+			}
+			continue
+		}
+
+		name, raw, ok := strings.Cut(line, "=")
+		if !ok || strings.TrimSpace(name) == "" {
+			return nil, fmt.Errorf("%d: expected key = value", number+1)
+		}
+		raw = strings.TrimSpace(raw)
+		var value string
+		var err error
+		if strings.HasPrefix(raw, "[") {
+			value, err = flowList(raw, tomlScalar)
+		} else {
+			value, err = tomlScalar(raw)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%d: %w", number+1, err)
+		}
+		values[prefix+settingKey(strings.Trim(strings.TrimSpace(name), `"'`))] = value
+	}
+	return values, nil
+}
+
+// tomlScalar parses a basic or literal string, number, or boolean
+func tomlScalar(raw string) (string, error) {
+	raw = strings.TrimSpace(raw)
+	switch {
+	case raw == "":
+		return "", fmt.Errorf("missing value")
+	case strings.HasPrefix(raw, `"""`) || strings.HasPrefix(raw, "'''"):
+		return "", fmt.Errorf("multi-line strings are not supported")
+	case strings.HasPrefix(raw, `"`):
+		value, err := strconv.Unquote(raw)
+		if err != nil {
+			return "", fmt.Errorf("invalid string %s", raw)
+		}
+		return value, nil
+	case strings.HasPrefix(raw, "'"):
+		if len(raw) < 2 || !strings.HasSuffix(raw, "'") {
+			return "", fmt.Errorf("invalid literal string %s", raw)
+		}
+		return raw[1 : len(raw)-1], nil
+	case strings.HasPrefix(raw, "{"):
+		return "", fmt.Errorf("inline tables are not supported")
+	case raw == "true" || raw == "false":
+		return raw, nil
+	}
+	number := strings.ReplaceAll(raw, "_", "")
+	if _, err := strconv.ParseFloat(number, 64); err != nil {
+		return "", fmt.Errorf("strings must be quoted: %s", raw)
+	}
+	return number, nil
+}
+
+// flowList parses a single-line [a, b] list into a comma-separated value
+func flowList(raw string, scalar func(string) (string, error)) (string, error) {
+	if !strings.HasSuffix(raw, "]") {
+		return "", fmt.Errorf("lists must open and close on one line")
+	}
+	var items []string
+	for _, item := range splitList(raw[1 : len(raw)-1]) {
+		if strings.TrimSpace(item) == "" {
+			continue
+		}
+		value, err := scalar(item)
+		if err != nil {
+			return "", err
+		}
+		items = append(items, value)
+	}
+	return strings.Join(items, ","), nil
+}
+
+// splitList splits list items on commas outside quotes
+func splitList(s string) []string {
+	var items []string
+	var quote rune
+	start := 0
+	for i, r := range s {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == ',':
+			items = append(items, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(items, s[start:])
+}
+
+// stripComment removes a # comment that is not inside quotes
+func stripComment(line string) string {
+	var quote rune
+	for i, r := range line {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t'):
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// settingKey converts a file key to its environment variable form
+func settingKey(name string) string {
+	name = strings.TrimSpace(name)
+	return strings.ToUpper(strings.NewReplacer("-", "_", ".", "_").Replace(name))
+}
@@ -0,0 +1,324 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tinle0301/streaming-platform-api/internal/awsauth"
+)
+
+// ErrSecretNotFound is returned when a provider has no value for a secret
+var ErrSecretNotFound = errors.New("secret not found")
+
+// SecretsProvider defines the interface for fetching secrets by name
+type SecretsProvider interface {
+	GetSecret(ctx context.Context, name string) (string, error)
+}
+
+// EnvProvider implements SecretsProvider from environment variables
+type EnvProvider struct{}
+
+// GetSecret returns the environment variable with the given name
+func (EnvProvider) GetSecret(ctx context.Context, name string) (string, error) {
+	if value := os.Getenv(name); value != "" {
+		return value, nil
+	}
+	return "", ErrSecretNotFound
+}
+
+// VaultProvider implements SecretsProvider using the Vault KV v2 HTTP API.
+// Each secret name maps to a field in a single KV path.
+type VaultProvider struct {
+	addr   string
+	token  string
+	mount  string
+	path   string
+	client *http.Client
+}
+
+// NewVaultProvider creates a provider reading fields from mount/path in Vault
+func NewVaultProvider(addr, token, mount, path string) *VaultProvider {
+	return &VaultProvider{
+		addr:   strings.TrimRight(addr, "/"),
+		token:  token,
+		mount:  mount,
+		path:   path,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// GetSecret reads the named field from the configured KV v2 secret
+func (p *VaultProvider) GetSecret(ctx context.Context, name string) (string, error) {
+	url := fmt.Sprintf("%s/v1/%s/data/%s", p.addr, p.mount, p.path)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create Vault request: %w", err)
+	}
+	req.Header.Set("X-Vault-Token", p.token)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to read secret from Vault: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return "", ErrSecretNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("vault returned status %d", resp.StatusCode)
+	}
+
+	var body struct {
+		Data struct {
+			Data map[string]string `json:"data"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("failed to decode Vault response: %w", err)
+	}
+
+	value, ok := body.Data.Data[name]
+	if !ok {
+		return "", ErrSecretNotFound
+	}
+	return value, nil
+}
+
+// AWSSecretsManagerProvider implements SecretsProvider using AWS Secrets Manager.
+// Each secret name maps to a key in a single JSON secret.
+type AWSSecretsManagerProvider struct {
+	region   string
+	secretID string
+	creds    awsauth.Credentials
+	client   *http.Client
+}
+
+// NewAWSSecretsManagerProvider creates a provider reading keys from secretID,
+// authenticating with the standard AWS_* environment credentials
+func NewAWSSecretsManagerProvider(region, secretID string) *AWSSecretsManagerProvider {
+	return &AWSSecretsManagerProvider{
+		region:   region,
+		secretID: secretID,
+		creds:    awsauth.CredentialsFromEnv(),
+		client:   &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// GetSecret reads the named key from the configured JSON secret
+func (p *AWSSecretsManagerProvider) GetSecret(ctx context.Context, name string) (string, error) {
+	payload, err := json.Marshal(map[string]string{"SecretId": p.secretID})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	url := fmt.Sprintf("https://secretsmanager.%s.amazonaws.com/", p.region)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return "", fmt.Errorf("failed to create Secrets Manager request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "secretsmanager.GetSecretValue")
+
+	if err := awsauth.Sign(req, p.creds, p.region, "secretsmanager", awsauth.PayloadHash(payload), time.Now()); err != nil {
+		return "", err
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to read secret from AWS Secrets Manager: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("secrets manager returned status %d", resp.StatusCode)
+	}
+
+	var body struct {
+		SecretString string `json:"SecretString"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("failed to decode Secrets Manager response: %w", err)
+	}
+
+	var values map[string]string
+	if err := json.Unmarshal([]byte(body.SecretString), &values); err != nil {
+		return "", fmt.Errorf("secret %s is not a JSON object: %w", p.secretID, err)
+	}
+
+	value, ok := values[name]
+	if !ok {
+		return "", ErrSecretNotFound
+	}
+	return value, nil
+}
+
+// ChainProvider tries each provider in order until one has the secret
+type ChainProvider struct {
+	providers []SecretsProvider
+}
+
+// NewChainProvider creates a provider that falls through the given providers
+func NewChainProvider(providers ...SecretsProvider) *ChainProvider {
+	return &ChainProvider{providers: providers}
+}
+
+// GetSecret returns the first value found
+func (p *ChainProvider) GetSecret(ctx context.Context, name string) (string, error) {
+	for _, provider := range p.providers {
+		value, err := provider.GetSecret(ctx, name)
+		if err == nil {
+			return value, nil
+		}
+		if !errors.Is(err, ErrSecretNotFound) {
+			return "", err
+		}
+	}
+	return "", ErrSecretNotFound
+}
+
+// NewSecretsProviderFromEnv selects a provider from SECRETS_PROVIDER
+// (env, vault, or aws). Vault and AWS fall back to the environment.
+func NewSecretsProviderFromEnv() (SecretsProvider, error) {
+	switch provider := os.Getenv("SECRETS_PROVIDER"); provider {
+	case "", "env":
+		return EnvProvider{}, nil
+	case "vault":
+		vault := NewVaultProvider(
+			os.Getenv("VAULT_ADDR"),
+			os.Getenv("VAULT_TOKEN"),
+			getEnvDefault("VAULT_MOUNT", "secret"),
+			getEnvDefault("VAULT_SECRET_PATH", "streamhub"),
+		)
+		return NewChainProvider(vault, EnvProvider{}), nil
+	case "aws":
+		aws := NewAWSSecretsManagerProvider(
+			getEnvDefault("AWS_REGION", "us-east-1"),
+			getEnvDefault("AWS_SECRET_ID", "streamhub"),
+		)
+		return NewChainProvider(aws, EnvProvider{}), nil
+	default:
+		return nil, fmt.Errorf("unknown secrets provider: %s", provider)
+	}
+}
+
+// RotatingSecret caches a secret and periodically refreshes it from a provider
+type RotatingSecret struct {
+	provider SecretsProvider
+	name     string
+	value    string
+	onRotate []func(value string)
+	mu       sync.RWMutex
+}
+
+// NewRotatingSecret loads the current value of a secret
+func NewRotatingSecret(ctx context.Context, provider SecretsProvider, name string) (*RotatingSecret, error) {
+	value, err := provider.GetSecret(ctx, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load secret %s: %w", name, err)
+	}
+	return &RotatingSecret{
+		provider: provider,
+		name:     name,
+		value:    value,
+	}, nil
+}
+
+// RotatingSecretFromEnv loads a secret from the provider SECRETS_PROVIDER
+// selects, to keep it current with Watch. It fails with ErrSecretNotFound
+// when the provider doesn't have the secret.
+func RotatingSecretFromEnv(ctx context.Context, name string) (*RotatingSecret, error) {
+	provider, err := NewSecretsProviderFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	return NewRotatingSecret(ctx, provider, name)
+}
+
+// Value returns the current secret value
+func (s *RotatingSecret) Value() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.value
+}
+
+// OnRotate registers a callback invoked with the new value after rotation
+func (s *RotatingSecret) OnRotate(fn func(value string)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onRotate = append(s.onRotate, fn)
+}
+
+// Watch refreshes the secret every interval until ctx is cancelled
+func (s *RotatingSecret) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.refresh(ctx)
+		}
+	}
+}
+
+// refresh reloads the secret and notifies callbacks if it changed
+func (s *RotatingSecret) refresh(ctx context.Context) {
+	value, err := s.provider.GetSecret(ctx, s.name)
+	if err != nil {
+		log.Printf("Error refreshing secret %s: %v", s.name, err)
+		return
+	}
+
+	s.mu.Lock()
+	if value == s.value {
+		s.mu.Unlock()
+		return
+	}
+	s.value = value
+	callbacks := append([]func(string){}, s.onRotate...)
+	s.mu.Unlock()
+
+	log.Printf("Secret rotated: %s", s.name)
+	for _, fn := range callbacks {
+		fn(value)
+	}
+}
+
+// ValidateProductionSecrets fails when any secret still holds its insecure
+// development default in production
+func ValidateProductionSecrets(environment string, secrets map[string]string, defaults map[string]string) error {
+	if environment != "production" {
+		return nil
+	}
+
+	var insecure []string
+	for name, value := range secrets {
+		if value == "" || value == defaults[name] {
+			insecure = append(insecure, name)
+		}
+	}
+	if len(insecure) > 0 {
+		return fmt.Errorf("insecure default secrets in production: %s", strings.Join(insecure, ", "))
+	}
+	return nil
+}
+
+// getEnvDefault returns the environment variable or a default value
+func getEnvDefault(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
@@ -0,0 +1,245 @@
+package config
+
+import (
+	"fmt"
+	"net/netip"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Origins of a setting's value
+const (
+	OriginDefault = "default"
+	OriginFile    = "file"
+	OriginEnv     = "env"
+	OriginSecrets = "secrets"
+)
+
+// redaction says how a setting's value is hidden when printed
+type redaction int
+
+const (
+	redactNone redaction = iota
+	redactSecret
+	redactURL
+)
+
+// setting is the value a key resolved to and where it came from
+type setting struct {
+	value     string
+	origin    string
+	redaction redaction
+}
+
+// source resolves settings from the environment, then the config file,
+// then defaults. Empty environment variables count as unset. Invalid
+// values are collected instead of falling back, so startup fails on them.
+type source struct {
+	file     map[string]string
+	lookup   func(key string) (string, bool)
+	settings map[string]setting
+	errs     []error
+}
+
+// newSource creates a source over file values and an environment lookup
+func newSource(file map[string]string, lookup func(key string) (string, bool)) *source {
+	return &source{file: file, lookup: lookup, settings: make(map[string]setting)}
+}
+
+// raw returns a key's value and origin, or ok false when it is unset
+func (s *source) raw(key string) (value, origin string, ok bool) {
+	if value, ok := s.lookup(key); ok && value != "" {
+		return value, OriginEnv, true
+	}
+	if value, ok := s.file[key]; ok && value != "" {
+		return value, OriginFile, true
+	}
+	return "", OriginDefault, false
+}
+
+// isSet reports whether the environment or the file sets key
+func (s *source) isSet(key string) bool {
+	_, _, ok := s.raw(key)
+	return ok
+}
+
+// record stores a key's resolved value for printing
+func (s *source) record(key, value, origin string, redaction redaction) {
+	s.settings[key] = setting{value: value, origin: origin, redaction: redaction}
+}
+
+// invalid records an invalid value
+func (s *source) invalid(key, value, want string) {
+	s.errs = append(s.errs, fmt.Errorf("%s: invalid value %q, want %s", key, value, want))
+}
+
+// String returns a string setting
+func (s *source) String(key, defaultValue string) string {
+	return s.resolve(key, defaultValue, redactNone)
+}
+
+// Secret returns a string setting that is never printed
+func (s *source) Secret(key, defaultValue string) string {
+	return s.resolve(key, defaultValue, redactSecret)
+}
+
+// URL returns a URL setting whose password is never printed
+func (s *source) URL(key, defaultValue string) string {
+	return s.resolve(key, defaultValue, redactURL)
+}
+
+// resolve returns a string setting, recording it with redaction
+func (s *source) resolve(key, defaultValue string, redaction redaction) string {
+	value, origin, ok := s.raw(key)
+	if !ok {
+		value = defaultValue
+	}
+	s.record(key, value, origin, redaction)
+	return value
+}
+
+// List returns a comma-separated setting's non-empty items
+func (s *source) List(key, defaultValue string) []string {
+	var items []string
+	for _, item := range strings.Split(s.String(key, defaultValue), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// Prefixes returns a comma-separated setting of networks such as
+// 10.0.0.0/8; a bare IP is a network of one address
+func (s *source) Prefixes(key, defaultValue string) []netip.Prefix {
+	var prefixes []netip.Prefix
+	for _, item := range s.List(key, defaultValue) {
+		prefix, err := netip.ParsePrefix(item)
+		if err != nil {
+			addr, addrErr := netip.ParseAddr(item)
+			if addrErr != nil {
+				s.invalid(key, item, "a CIDR such as 10.0.0.0/8 or an IP")
+				continue
+			}
+			prefix = netip.PrefixFrom(addr, addr.BitLen())
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes
+}
+
+// Countries returns a comma-separated setting of network=country pairs
+// such as 203.0.113.0/24=AU, keyed by network
+func (s *source) Countries(key, defaultValue string) map[string]string {
+	countries := make(map[string]string)
+	for _, item := range s.List(key, defaultValue) {
+		network, country, ok := strings.Cut(item, "=")
+		prefix, err := netip.ParsePrefix(strings.TrimSpace(network))
+		country = strings.ToUpper(strings.TrimSpace(country))
+		if !ok || err != nil || len(country) != 2 {
+			s.invalid(key, item, "a CIDR and two-letter country such as 203.0.113.0/24=AU")
+			continue
+		}
+		countries[prefix.Masked().String()] = country
+	}
+	return countries
+}
+
+// Bool returns a boolean setting
+func (s *source) Bool(key string, defaultValue bool) bool {
+	value, origin, ok := s.raw(key)
+	if !ok {
+		s.record(key, strconv.FormatBool(defaultValue), origin, redactNone)
+		return defaultValue
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		s.invalid(key, value, "true or false")
+		b = defaultValue
+	}
+	s.record(key, value, origin, redactNone)
+	return b
+}
+
+// Int returns a non-negative integer setting
+func (s *source) Int(key string, defaultValue int) int {
+	value, origin, ok := s.raw(key)
+	if !ok {
+		s.record(key, strconv.Itoa(defaultValue), origin, redactNone)
+		return defaultValue
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		s.invalid(key, value, "a non-negative integer")
+		n = defaultValue
+	}
+	s.record(key, value, origin, redactNone)
+	return n
+}
+
+// Float returns a non-negative number setting
+func (s *source) Float(key string, defaultValue float64) float64 {
+	value, origin, ok := s.raw(key)
+	if !ok {
+		s.record(key, strconv.FormatFloat(defaultValue, 'g', -1, 64), origin, redactNone)
+		return defaultValue
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil || f < 0 {
+		s.invalid(key, value, "a non-negative number")
+		f = defaultValue
+	}
+	s.record(key, value, origin, redactNone)
+	return f
+}
+
+// Duration returns a non-negative duration setting such as 30s or 5m
+func (s *source) Duration(key string, defaultValue time.Duration) time.Duration {
+	value, origin, ok := s.raw(key)
+	if !ok {
+		s.record(key, defaultValue.String(), origin, redactNone)
+		return defaultValue
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		s.invalid(key, value, "a non-negative duration such as 30s")
+		d = defaultValue
+	}
+	s.record(key, value, origin, redactNone)
+	return d
+}
+
+// unknownFileKeys returns keys in the config file no setting reads
+func (s *source) unknownFileKeys() []string {
+	var unknown []string
+	for key := range s.file {
+		if _, ok := s.settings[key]; !ok {
+			unknown = append(unknown, key)
+		}
+	}
+	sort.Strings(unknown)
+	return unknown
+}
+
+// redactedValue is a setting's value as it may be printed
+func redactedValue(s setting) string {
+	switch {
+	case s.value == "":
+		return ""
+	case s.redaction == redactSecret:
+		return "[redacted]"
+	case s.redaction == redactURL:
+		u, err := url.Parse(s.value)
+		if err != nil {
+			return "[redacted]"
+		}
+		if _, ok := u.User.Password(); ok {
+			u.User = url.UserPassword(u.User.Username(), "redacted")
+		}
+		return u.String()
+	}
+	return s.value
+}
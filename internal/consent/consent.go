@@ -0,0 +1,158 @@
+package consent
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrUnknownDocument is returned when accepting a document version that was never published
+var ErrUnknownDocument = errors.New("unknown document version")
+
+// Document kinds that require acceptance
+const (
+	KindTermsOfService = "terms_of_service"
+	KindPrivacyPolicy  = "privacy_policy"
+)
+
+// Document is a published version of a legal agreement
+type Document struct {
+	Kind        string    `json:"kind"`
+	Version     string    `json:"version"`
+	URL         string    `json:"url"`
+	PublishedAt time.Time `json:"published_at"`
+}
+
+// Acceptance records a user's acceptance of a document version
+type Acceptance struct {
+	UserID     string    `json:"user_id"`
+	Kind       string    `json:"kind"`
+	Version    string    `json:"version"`
+	AcceptedAt time.Time `json:"accepted_at"`
+}
+
+// Registry tracks published agreement versions and per-user acceptances
+type Registry struct {
+	// kind -> latest published document
+	latest map[string]Document
+
+	// kind -> version -> document
+	documents map[string]map[string]Document
+
+	// userID -> kind -> acceptance of the most recent version accepted
+	acceptances map[string]map[string]Acceptance
+
+	mu sync.RWMutex
+}
+
+// NewRegistry creates a new agreement registry
+func NewRegistry() *Registry {
+	return &Registry{
+		latest:      make(map[string]Document),
+		documents:   make(map[string]map[string]Document),
+		acceptances: make(map[string]map[string]Acceptance),
+	}
+}
+
+// Publish makes doc the latest version of its kind
+func (r *Registry) Publish(doc Document) {
+	if doc.PublishedAt.IsZero() {
+		doc.PublishedAt = time.Now()
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.documents[doc.Kind] == nil {
+		r.documents[doc.Kind] = make(map[string]Document)
+	}
+	r.documents[doc.Kind][doc.Version] = doc
+	r.latest[doc.Kind] = doc
+}
+
+// Accept records that a user accepted a specific document version
+func (r *Registry) Accept(userID, kind, version string) (Acceptance, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.documents[kind][version]; !ok {
+		return Acceptance{}, ErrUnknownDocument
+	}
+
+	acceptance := Acceptance{
+		UserID:     userID,
+		Kind:       kind,
+		Version:    version,
+		AcceptedAt: time.Now(),
+	}
+	if r.acceptances[userID] == nil {
+		r.acceptances[userID] = make(map[string]Acceptance)
+	}
+	r.acceptances[userID][kind] = acceptance
+	return acceptance, nil
+}
+
+// Pending returns the latest documents the user has not yet accepted,
+// ordered by kind
+func (r *Registry) Pending(userID string) []Document {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var pending []Document
+	for kind, doc := range r.latest {
+		if r.acceptances[userID][kind].Version != doc.Version {
+			pending = append(pending, doc)
+		}
+	}
+	sort.Slice(pending, func(i, j int) bool { return pending[i].Kind < pending[j].Kind })
+	return pending
+}
+
+// HasAcceptedLatest reports whether the user accepted every latest document
+func (r *Registry) HasAcceptedLatest(userID string) bool {
+	return len(r.Pending(userID)) == 0
+}
+
+// UserIDFunc extracts the authenticated user ID from a request ("" if anonymous)
+type UserIDFunc func(r *http.Request) string
+
+// RequireLatest returns middleware that blocks authenticated requests until
+// the user has accepted the latest version of every published document.
+// Anonymous requests, and requests for exemptPaths, which must check
+// acceptance themselves, pass through unchanged.
+func RequireLatest(registry *Registry, userIDFunc UserIDFunc, exemptPaths ...string) func(http.Handler) http.Handler {
+	exempt := make(map[string]bool, len(exemptPaths))
+	for _, path := range exemptPaths {
+		exempt[path] = true
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if exempt[r.URL.Path] {
+				next.ServeHTTP(w, r)
+				return
+			}
+			userID := userIDFunc(r)
+			if userID == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			pending := registry.Pending(userID)
+			if len(pending) == 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusForbidden)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"error":   "TERMS_NOT_ACCEPTED",
+				"message": "The latest terms must be accepted before continuing",
+				"pending": pending,
+			})
+		})
+	}
+}
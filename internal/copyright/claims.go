@@ -0,0 +1,230 @@
+package copyright
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/tinle0301/streaming-platform-api/internal/audit"
+)
+
+// Errors returned by the claims store
+var (
+	ErrClaimNotFound     = errors.New("claim not found")
+	ErrVODNotFound       = errors.New("vod not found")
+	ErrInvalidTransition = errors.New("invalid claim state transition")
+	ErrInvalidClaim      = errors.New("invalid claim")
+)
+
+// ClaimStatus is the lifecycle state of a copyright claim
+type ClaimStatus string
+
+// Claim lifecycle states
+const (
+	ClaimStatusPending       ClaimStatus = "PENDING"
+	ClaimStatusAccepted      ClaimStatus = "ACCEPTED"
+	ClaimStatusRejected      ClaimStatus = "REJECTED"
+	ClaimStatusCounterNotice ClaimStatus = "COUNTER_NOTICE"
+	ClaimStatusReinstated    ClaimStatus = "REINSTATED"
+	ClaimStatusWithdrawn     ClaimStatus = "WITHDRAWN"
+)
+
+// allowedTransitions defines the claim state machine
+var allowedTransitions = map[ClaimStatus][]ClaimStatus{
+	ClaimStatusPending:       {ClaimStatusAccepted, ClaimStatusRejected, ClaimStatusWithdrawn},
+	ClaimStatusAccepted:      {ClaimStatusCounterNotice, ClaimStatusWithdrawn},
+	ClaimStatusCounterNotice: {ClaimStatusReinstated, ClaimStatusAccepted, ClaimStatusWithdrawn},
+}
+
+// maxSegments caps the segments one claim may mute
+const maxSegments = 100
+
+// MutedRange is a segment of a VOD whose audio must be muted, in seconds
+type MutedRange struct {
+	Start float64 `json:"start"`
+	End   float64 `json:"end"`
+}
+
+// CounterNotice records the uploader's response to a claim
+type CounterNotice struct {
+	UserID      string    `json:"user_id"`
+	Statement   string    `json:"statement"`
+	SubmittedAt time.Time `json:"submitted_at"`
+}
+
+// Claim is a copyright claim filed by a rights holder against a VOD
+type Claim struct {
+	ID            string         `json:"id"`
+	VODID         string         `json:"vod_id"`
+	ClaimantName  string         `json:"claimant_name"`
+	ClaimantEmail string         `json:"claimant_email"`
+	WorkTitle     string         `json:"work_title"`
+	Segments      []MutedRange   `json:"segments"`
+	Status        ClaimStatus    `json:"status"`
+	CounterNotice *CounterNotice `json:"counter_notice,omitempty"`
+	CreatedAt     time.Time      `json:"created_at"`
+	UpdatedAt     time.Time      `json:"updated_at"`
+}
+
+// ClaimRepository persists claims. Claims are created in the context's
+// tenant, and other tenants' claims are treated as missing.
+type ClaimRepository interface {
+	// CreateClaim stores a pending claim and fills in its ID and timestamps
+	CreateClaim(ctx context.Context, claim *Claim) error
+
+	// GetClaim returns a claim, or ErrClaimNotFound
+	GetClaim(ctx context.Context, claimID string) (Claim, error)
+
+	// UpdateStatus moves a claim from one status to another, recording
+	// notice if it isn't nil. It fails with ErrInvalidTransition if the
+	// claim is no longer in from, so concurrent rulings can't both apply.
+	UpdateStatus(ctx context.Context, claimID string, from, to ClaimStatus, notice *CounterNotice) (Claim, error)
+
+	// MutingSegments returns the segments of a VOD's claims that are
+	// accepted or under counter-notice, unmerged
+	MutingSegments(ctx context.Context, vodID string) ([]MutedRange, error)
+}
+
+// ClaimStore runs copyright claims through their state machine and
+// computes the muted ranges they produce
+type ClaimStore struct {
+	claims   ClaimRepository
+	auditLog audit.Logger
+}
+
+// NewClaimStore creates a claim store keeping claims in claims
+func NewClaimStore(claims ClaimRepository, auditLog audit.Logger) *ClaimStore {
+	return &ClaimStore{
+		claims:   claims,
+		auditLog: auditLog,
+	}
+}
+
+// Submit records a new claim from a rights holder
+func (s *ClaimStore) Submit(ctx context.Context, claim Claim) (Claim, error) {
+	if claim.VODID == "" || claim.ClaimantEmail == "" || len(claim.Segments) == 0 {
+		return Claim{}, ErrInvalidClaim
+	}
+	if len(claim.Segments) > maxSegments {
+		return Claim{}, fmt.Errorf("%w: more than %d segments", ErrInvalidClaim, maxSegments)
+	}
+	for _, segment := range claim.Segments {
+		if segment.Start < 0 || segment.End <= segment.Start {
+			return Claim{}, fmt.Errorf("%w: bad segment %v-%v", ErrInvalidClaim, segment.Start, segment.End)
+		}
+	}
+
+	claim.Status = ClaimStatusPending
+	claim.CounterNotice = nil
+	if err := s.claims.CreateClaim(ctx, &claim); err != nil {
+		return Claim{}, err
+	}
+
+	s.record(ctx, audit.ActionClaimSubmitted, claim.ClaimantEmail, claim)
+	return claim, nil
+}
+
+// Get returns a claim by ID
+func (s *ClaimStore) Get(ctx context.Context, claimID string) (Claim, error) {
+	return s.claims.GetClaim(ctx, claimID)
+}
+
+// Transition moves a claim to a new state if the state machine allows it
+func (s *ClaimStore) Transition(ctx context.Context, claimID string, to ClaimStatus, actorID string) (Claim, error) {
+	return s.transition(ctx, claimID, to, actorID, audit.ActionClaimTransition, nil)
+}
+
+// FileCounterNotice records the uploader's counter-notice against an
+// accepted claim, moving it to COUNTER_NOTICE
+func (s *ClaimStore) FileCounterNotice(ctx context.Context, claimID, userID, statement string) (Claim, error) {
+	return s.transition(ctx, claimID, ClaimStatusCounterNotice, userID, audit.ActionClaimCounterNotice, &CounterNotice{
+		UserID:      userID,
+		Statement:   statement,
+		SubmittedAt: time.Now(),
+	})
+}
+
+// transition moves a claim to a new state if the state machine allows it,
+// recording notice (if any) with it, and audits it as action
+func (s *ClaimStore) transition(ctx context.Context, claimID string, to ClaimStatus, actorID, action string, notice *CounterNotice) (Claim, error) {
+	claim, err := s.claims.GetClaim(ctx, claimID)
+	if err != nil {
+		return Claim{}, err
+	}
+	if !canTransition(claim.Status, to) {
+		return Claim{}, fmt.Errorf("%w: %s -> %s", ErrInvalidTransition, claim.Status, to)
+	}
+
+	claim, err = s.claims.UpdateStatus(ctx, claimID, claim.Status, to, notice)
+	if err != nil {
+		return Claim{}, err
+	}
+
+	s.record(ctx, action, actorID, claim)
+	return claim, nil
+}
+
+// MutedRanges returns the merged muted ranges the playback layer must honor
+// for a VOD. Claims stay muted while a counter-notice is under review.
+func (s *ClaimStore) MutedRanges(ctx context.Context, vodID string) ([]MutedRange, error) {
+	segments, err := s.claims.MutingSegments(ctx, vodID)
+	if err != nil {
+		return nil, err
+	}
+	return mergeRanges(segments), nil
+}
+
+// record writes an audit entry for a claim change
+func (s *ClaimStore) record(ctx context.Context, action, actorID string, claim Claim) {
+	if s.auditLog == nil {
+		return
+	}
+
+	entry := audit.Entry{
+		Action:   action,
+		ActorID:  actorID,
+		Resource: "vod:" + claim.VODID,
+		Metadata: map[string]string{
+			"claim_id": claim.ID,
+			"status":   string(claim.Status),
+		},
+	}
+	if err := s.auditLog.Record(ctx, entry); err != nil {
+		log.Printf("Error recording audit entry: %v", err)
+	}
+}
+
+// canTransition reports whether the state machine allows from -> to
+func canTransition(from, to ClaimStatus) bool {
+	for _, allowed := range allowedTransitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// mergeRanges sorts ranges and merges overlapping ones
+func mergeRanges(ranges []MutedRange) []MutedRange {
+	if len(ranges) == 0 {
+		return nil
+	}
+
+	sort.Slice(ranges, func(i, j int) bool { return ranges[i].Start < ranges[j].Start })
+
+	merged := []MutedRange{ranges[0]}
+	for _, r := range ranges[1:] {
+		last := &merged[len(merged)-1]
+		if r.Start <= last.End {
+			if r.End > last.End {
+				last.End = r.End
+			}
+			continue
+		}
+		merged = append(merged, r)
+	}
+	return merged
+}
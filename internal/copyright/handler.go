@@ -0,0 +1,220 @@
+package copyright
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/tinle0301/streaming-platform-api/internal/users"
+)
+
+// VODOwners looks up who uploaded a VOD
+type VODOwners interface {
+	// VODOwner returns the user ID of the VOD's owner, or ErrVODNotFound
+	VODOwner(ctx context.Context, vodID string) (string, error)
+}
+
+// VODOwnerFunc adapts a function to VODOwners
+type VODOwnerFunc func(ctx context.Context, vodID string) (string, error)
+
+// VODOwner calls f
+func (f VODOwnerFunc) VODOwner(ctx context.Context, vodID string) (string, error) {
+	return f(ctx, vodID)
+}
+
+// maxRequestBytes caps the body of a claim, counter-notice, or ruling
+const maxRequestBytes = 64 << 10
+
+// claimStatus is what anyone holding a claim's ID may see of it; the
+// claimant's contact details and any counter-notice stay private
+type claimStatus struct {
+	ID        string      `json:"id"`
+	Status    ClaimStatus `json:"status"`
+	UpdatedAt time.Time   `json:"updated_at"`
+}
+
+// IntakeHandler returns the HTTP handler rights holders use to file claims
+// against VODs found by owners.
+//
+//	POST /copyright/claims                 file a claim
+//	GET  /copyright/claims?id=...          check claim status
+//	GET  /copyright/claims?vod_id=...      list muted ranges for a VOD
+func IntakeHandler(store *ClaimStore, owners VODOwners) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost:
+			var claim Claim
+			if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBytes)).Decode(&claim); err != nil {
+				http.Error(w, "Bad request", http.StatusBadRequest)
+				return
+			}
+
+			if claim.VODID != "" {
+				_, err := owners.VODOwner(r.Context(), claim.VODID)
+				if errors.Is(err, ErrVODNotFound) {
+					http.Error(w, "Not found", http.StatusNotFound)
+					return
+				}
+				if err != nil {
+					http.Error(w, "Internal server error", http.StatusInternalServerError)
+					return
+				}
+			}
+
+			created, err := store.Submit(r.Context(), claim)
+			switch {
+			case errors.Is(err, ErrInvalidClaim):
+				http.Error(w, err.Error(), http.StatusBadRequest)
+			case errors.Is(err, ErrVODNotFound):
+				http.Error(w, "Not found", http.StatusNotFound)
+			case err != nil:
+				http.Error(w, "Internal server error", http.StatusInternalServerError)
+			default:
+				writeJSON(w, http.StatusCreated, created)
+			}
+
+		case http.MethodGet:
+			if vodID := r.URL.Query().Get("vod_id"); vodID != "" {
+				ranges, err := store.MutedRanges(r.Context(), vodID)
+				if err != nil {
+					http.Error(w, "Internal server error", http.StatusInternalServerError)
+					return
+				}
+				writeJSON(w, http.StatusOK, map[string]interface{}{
+					"vod_id":       vodID,
+					"muted_ranges": ranges,
+				})
+				return
+			}
+
+			claim, err := store.Get(r.Context(), r.URL.Query().Get("id"))
+			if errors.Is(err, ErrClaimNotFound) {
+				http.Error(w, "Not found", http.StatusNotFound)
+				return
+			}
+			if err != nil {
+				http.Error(w, "Internal server error", http.StatusInternalServerError)
+				return
+			}
+			writeJSON(w, http.StatusOK, claimStatus{ID: claim.ID, Status: claim.Status, UpdatedAt: claim.UpdatedAt})
+
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+// CounterNoticeHandler returns the HTTP handler uploaders use to contest a
+// claim. It must be wrapped in users.RequireUser; only the owner of the
+// claimed VOD may file a counter-notice.
+func CounterNoticeHandler(store *ClaimStore, owners VODOwners) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		claims, ok := users.ClaimsFromContext(r.Context())
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		var request struct {
+			ClaimID   string `json:"claim_id"`
+			Statement string `json:"statement"`
+		}
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBytes)).Decode(&request); err != nil || request.Statement == "" {
+			http.Error(w, "Bad request", http.StatusBadRequest)
+			return
+		}
+
+		existing, err := store.Get(r.Context(), request.ClaimID)
+		if errors.Is(err, ErrClaimNotFound) {
+			http.Error(w, "Not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		owner, err := owners.VODOwner(r.Context(), existing.VODID)
+		switch {
+		case errors.Is(err, ErrVODNotFound):
+			http.Error(w, "Not found", http.StatusNotFound)
+			return
+		case err != nil:
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		case owner != claims.UserID():
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+
+		claim, err := store.FileCounterNotice(r.Context(), request.ClaimID, claims.UserID(), request.Statement)
+		writeTransition(w, claim, err)
+	}
+}
+
+// reviewableStatuses are the states moderators may move a claim to; a
+// counter-notice can only be filed by the uploader
+var reviewableStatuses = map[ClaimStatus]bool{
+	ClaimStatusAccepted:   true,
+	ClaimStatusRejected:   true,
+	ClaimStatusReinstated: true,
+	ClaimStatusWithdrawn:  true,
+}
+
+// ReviewHandler returns the HTTP handler moderators use to rule on claims.
+// It must be wrapped in users.RequireAdmin.
+//
+//	POST /admin/copyright/claims   {"claim_id": "...", "status": "ACCEPTED"}
+func ReviewHandler(store *ClaimStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		claims, ok := users.ClaimsFromContext(r.Context())
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		var request struct {
+			ClaimID string      `json:"claim_id"`
+			Status  ClaimStatus `json:"status"`
+		}
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBytes)).Decode(&request); err != nil || !reviewableStatuses[request.Status] {
+			http.Error(w, "Bad request", http.StatusBadRequest)
+			return
+		}
+
+		claim, err := store.Transition(r.Context(), request.ClaimID, request.Status, claims.UserID())
+		writeTransition(w, claim, err)
+	}
+}
+
+// writeTransition writes the result of a claim state transition
+func writeTransition(w http.ResponseWriter, claim Claim, err error) {
+	switch {
+	case errors.Is(err, ErrClaimNotFound):
+		http.Error(w, "Not found", http.StatusNotFound)
+	case errors.Is(err, ErrInvalidTransition):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case err != nil:
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	default:
+		writeJSON(w, http.StatusOK, claim)
+	}
+}
+
+// writeJSON writes v as a JSON response with the given status code
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,168 @@
+package copyright
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/tinle0301/streaming-platform-api/internal/audit"
+	"github.com/tinle0301/streaming-platform-api/internal/users"
+)
+
+// discardAudit drops audit entries
+type discardAudit struct{}
+
+func (discardAudit) Record(ctx context.Context, entry audit.Entry) error { return nil }
+
+// memoryClaims is a ClaimRepository kept in a map
+type memoryClaims struct {
+	mu     sync.Mutex
+	claims map[string]Claim
+}
+
+func newMemoryClaims() *memoryClaims {
+	return &memoryClaims{claims: make(map[string]Claim)}
+}
+
+func (m *memoryClaims) CreateClaim(ctx context.Context, claim *Claim) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	claim.ID = fmt.Sprintf("claim-%d", len(m.claims)+1)
+	claim.CreatedAt = time.Now()
+	claim.UpdatedAt = claim.CreatedAt
+	m.claims[claim.ID] = *claim
+	return nil
+}
+
+func (m *memoryClaims) GetClaim(ctx context.Context, claimID string) (Claim, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	claim, ok := m.claims[claimID]
+	if !ok {
+		return Claim{}, ErrClaimNotFound
+	}
+	return claim, nil
+}
+
+func (m *memoryClaims) UpdateStatus(ctx context.Context, claimID string, from, to ClaimStatus, notice *CounterNotice) (Claim, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	claim, ok := m.claims[claimID]
+	if !ok {
+		return Claim{}, ErrClaimNotFound
+	}
+	if claim.Status != from {
+		return Claim{}, ErrInvalidTransition
+	}
+	claim.Status = to
+	if notice != nil {
+		claim.CounterNotice = notice
+	}
+	claim.UpdatedAt = time.Now()
+	m.claims[claimID] = claim
+	return claim, nil
+}
+
+func (m *memoryClaims) MutingSegments(ctx context.Context, vodID string) ([]MutedRange, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var ranges []MutedRange
+	for _, claim := range m.claims {
+		if claim.VODID == vodID && (claim.Status == ClaimStatusAccepted || claim.Status == ClaimStatusCounterNotice) {
+			ranges = append(ranges, claim.Segments...)
+		}
+	}
+	return ranges, nil
+}
+
+// vodOwners owns vod-1 as "uploader"
+var vodOwners = VODOwnerFunc(func(ctx context.Context, vodID string) (string, error) {
+	if vodID != "vod-1" {
+		return "", ErrVODNotFound
+	}
+	return "uploader", nil
+})
+
+// postAs sends body to handler as userID
+func postAs(handler http.Handler, userID, body string) *httptest.ResponseRecorder {
+	claims := &users.Claims{RegisteredClaims: jwt.RegisteredClaims{Subject: userID}}
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	req = req.WithContext(users.WithClaims(req.Context(), claims))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestCounterNoticeRequiresVODOwner(t *testing.T) {
+	store := NewClaimStore(newMemoryClaims(), discardAudit{})
+	claim, err := store.Submit(context.Background(), Claim{
+		VODID:         "vod-1",
+		ClaimantEmail: "rights@example.com",
+		Segments:      []MutedRange{{Start: 0, End: 10}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	counterNotice := CounterNoticeHandler(store, vodOwners)
+	review := ReviewHandler(store)
+	body := `{"claim_id": "` + claim.ID + `", "statement": "fair use"}`
+
+	if rec := postAs(counterNotice, "uploader", body); rec.Code != http.StatusConflict {
+		t.Fatalf("counter-notice on a pending claim = %d, want %d", rec.Code, http.StatusConflict)
+	}
+	if rec := postAs(review, "admin", `{"claim_id": "`+claim.ID+`", "status": "COUNTER_NOTICE"}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("review to COUNTER_NOTICE = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+	if rec := postAs(review, "admin", `{"claim_id": "`+claim.ID+`", "status": "ACCEPTED"}`); rec.Code != http.StatusOK {
+		t.Fatalf("review to ACCEPTED = %d, want %d", rec.Code, http.StatusOK)
+	}
+
+	if rec := postAs(counterNotice, "someone-else", body); rec.Code != http.StatusForbidden {
+		t.Fatalf("counter-notice by another user = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+	if rec := postAs(counterNotice, "uploader", body); rec.Code != http.StatusOK {
+		t.Fatalf("counter-notice by the uploader = %d, want %d", rec.Code, http.StatusOK)
+	}
+
+	got, _ := store.Get(context.Background(), claim.ID)
+	if got.Status != ClaimStatusCounterNotice || got.CounterNotice == nil || got.CounterNotice.UserID != "uploader" {
+		t.Errorf("claim after counter-notice = %+v, want COUNTER_NOTICE filed by the uploader", got)
+	}
+}
+
+func TestIntakeHidesClaimDetails(t *testing.T) {
+	store := NewClaimStore(newMemoryClaims(), discardAudit{})
+	intake := IntakeHandler(store, vodOwners)
+
+	missing := `{"vod_id": "vod-2", "claimant_email": "rights@example.com", "segments": [{"start": 0, "end": 10}]}`
+	if rec := postAs(intake, "", missing); rec.Code != http.StatusNotFound {
+		t.Fatalf("claim against a missing VOD = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+	oversized := `{"vod_id": "vod-1", "work_title": "` + strings.Repeat("x", maxRequestBytes) + `"}`
+	if rec := postAs(intake, "", oversized); rec.Code != http.StatusBadRequest {
+		t.Fatalf("oversized claim = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+
+	claim, err := store.Submit(context.Background(), Claim{
+		VODID:         "vod-1",
+		ClaimantEmail: "rights@example.com",
+		Segments:      []MutedRange{{Start: 0, End: 10}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := httptest.NewRecorder()
+	intake.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/?id="+claim.ID, nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("claim status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if body := rec.Body.String(); !strings.Contains(body, `"PENDING"`) || strings.Contains(body, "rights@example.com") {
+		t.Errorf("claim status body = %s, want the status without the claimant's email", body)
+	}
+}
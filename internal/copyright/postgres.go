@@ -0,0 +1,161 @@
+package copyright
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/tinle0301/streaming-platform-api/internal/store"
+	"github.com/tinle0301/streaming-platform-api/internal/tenancy"
+)
+
+// claimColumns is the column list shared by claim queries
+const claimColumns = `id::text, vod_id::text, claimant_name, claimant_email, work_title, segments, status,
+	counter_user_id, counter_statement, counter_submitted_at, created_at, updated_at`
+
+// PostgresClaims implements ClaimRepository on PostgreSQL, so claims and
+// the mutes they impose survive restarts and are shared by every API server
+type PostgresClaims struct {
+	pool *pgxpool.Pool
+}
+
+// NewPostgresClaims creates a claim repository on pool
+func NewPostgresClaims(pool *pgxpool.Pool) *PostgresClaims {
+	return &PostgresClaims{pool: pool}
+}
+
+// CreateClaim inserts a pending claim
+func (p *PostgresClaims) CreateClaim(ctx context.Context, claim *Claim) error {
+	if !store.IsUUID(claim.VODID) {
+		return ErrVODNotFound
+	}
+	segments, err := json.Marshal(claim.Segments)
+	if err != nil {
+		return fmt.Errorf("failed to encode claim segments: %w", err)
+	}
+
+	err = p.pool.QueryRow(ctx, `
+		INSERT INTO copyright_claims (vod_id, claimant_name, claimant_email, work_title, segments, status, tenant_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id::text, created_at, updated_at`,
+		claim.VODID, claim.ClaimantName, claim.ClaimantEmail, claim.WorkTitle, segments, claim.Status,
+		tenancy.ID(ctx),
+	).Scan(&claim.ID, &claim.CreatedAt, &claim.UpdatedAt)
+	if store.PgCode(err) == "23503" {
+		return ErrVODNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create claim: %w", err)
+	}
+	return nil
+}
+
+// GetClaim reads a claim
+func (p *PostgresClaims) GetClaim(ctx context.Context, claimID string) (Claim, error) {
+	if !store.IsUUID(claimID) {
+		return Claim{}, ErrClaimNotFound
+	}
+
+	claim, err := scanClaim(p.pool.QueryRow(ctx, `
+		SELECT `+claimColumns+` FROM copyright_claims WHERE id = $1`+tenancy.Condition("tenant_id", 2),
+		claimID, tenancy.Scope(ctx)))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return Claim{}, ErrClaimNotFound
+	}
+	if err != nil {
+		return Claim{}, fmt.Errorf("failed to get claim: %w", err)
+	}
+	return claim, nil
+}
+
+// UpdateStatus moves a claim from one status to another
+func (p *PostgresClaims) UpdateStatus(ctx context.Context, claimID string, from, to ClaimStatus, notice *CounterNotice) (Claim, error) {
+	if !store.IsUUID(claimID) {
+		return Claim{}, ErrClaimNotFound
+	}
+
+	var noticeUserID, noticeStatement *string
+	var noticeAt *time.Time
+	if notice != nil {
+		noticeUserID, noticeStatement, noticeAt = &notice.UserID, &notice.Statement, &notice.SubmittedAt
+	}
+	claim, err := scanClaim(p.pool.QueryRow(ctx, `
+		UPDATE copyright_claims SET status = $3,
+			counter_user_id = COALESCE($4, counter_user_id),
+			counter_statement = COALESCE($5, counter_statement),
+			counter_submitted_at = COALESCE($6, counter_submitted_at),
+			updated_at = NOW()
+		WHERE id = $1 AND status = $2`+tenancy.Condition("tenant_id", 7)+`
+		RETURNING `+claimColumns,
+		claimID, from, to, noticeUserID, noticeStatement, noticeAt, tenancy.Scope(ctx)))
+	if errors.Is(err, pgx.ErrNoRows) {
+		// Missing, or another ruling moved it first
+		current, err := p.GetClaim(ctx, claimID)
+		if err != nil {
+			return Claim{}, err
+		}
+		return Claim{}, fmt.Errorf("%w: %s -> %s", ErrInvalidTransition, current.Status, to)
+	}
+	if err != nil {
+		return Claim{}, fmt.Errorf("failed to update claim: %w", err)
+	}
+	return claim, nil
+}
+
+// MutingSegments reads the segments of a VOD's accepted and contested claims
+func (p *PostgresClaims) MutingSegments(ctx context.Context, vodID string) ([]MutedRange, error) {
+	if !store.IsUUID(vodID) {
+		return nil, nil
+	}
+
+	rows, err := p.pool.Query(ctx, `
+		SELECT segments FROM copyright_claims
+		WHERE vod_id = $1 AND status IN ('ACCEPTED', 'COUNTER_NOTICE')`+tenancy.Condition("tenant_id", 2),
+		vodID, tenancy.Scope(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get muted segments: %w", err)
+	}
+	defer rows.Close()
+
+	var ranges []MutedRange
+	for rows.Next() {
+		var stored []byte
+		if err := rows.Scan(&stored); err != nil {
+			return nil, fmt.Errorf("failed to scan muted segments: %w", err)
+		}
+		var segments []MutedRange
+		if err := json.Unmarshal(stored, &segments); err != nil {
+			return nil, fmt.Errorf("failed to decode claim segments: %w", err)
+		}
+		ranges = append(ranges, segments...)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read muted segments: %w", err)
+	}
+	return ranges, nil
+}
+
+// scanClaim scans claimColumns
+func scanClaim(row pgx.Row) (Claim, error) {
+	var claim Claim
+	var segments []byte
+	var status string
+	var noticeUserID, noticeStatement *string
+	var noticeAt *time.Time
+	if err := row.Scan(&claim.ID, &claim.VODID, &claim.ClaimantName, &claim.ClaimantEmail, &claim.WorkTitle,
+		&segments, &status, &noticeUserID, &noticeStatement, &noticeAt, &claim.CreatedAt, &claim.UpdatedAt); err != nil {
+		return Claim{}, err
+	}
+	if err := json.Unmarshal(segments, &claim.Segments); err != nil {
+		return Claim{}, fmt.Errorf("failed to decode claim segments: %w", err)
+	}
+	claim.Status = ClaimStatus(status)
+	if noticeUserID != nil && noticeAt != nil {
+		claim.CounterNotice = &CounterNotice{UserID: *noticeUserID, Statement: *noticeStatement, SubmittedAt: *noticeAt}
+	}
+	return claim, nil
+}
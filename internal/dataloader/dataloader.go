@@ -0,0 +1,225 @@
+// Package dataloader batches and caches lookups made while resolving one
+// request, so nested fields that each load one record cost one query per
+// batch instead of one query per record.
+package dataloader
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// BatchFunc loads values for keys. Keys missing from the result resolve to
+// the zero value; a returned error fails every key in the batch.
+type BatchFunc[K comparable, V any] func(ctx context.Context, keys []K) (map[K]V, error)
+
+// Options tunes batching and caching
+type Options struct {
+	// Wait is how long a batch collects keys after the first one
+	Wait time.Duration
+
+	// MaxBatch dispatches a batch early once it holds this many keys (0 = unlimited)
+	MaxBatch int
+
+	// CacheSize bounds how many loaded keys are remembered; the oldest are
+	// forgotten beyond it (0 = no caching beyond the current batch)
+	CacheSize int
+}
+
+// DefaultOptions returns the options used when none are configured
+func DefaultOptions() Options {
+	return Options{
+		Wait:      2 * time.Millisecond,
+		MaxBatch:  100,
+		CacheSize: 1000,
+	}
+}
+
+// call is one key's pending or completed load
+type call[V any] struct {
+	done  chan struct{}
+	value V
+	err   error
+}
+
+// batch collects keys until it is dispatched
+type batch[K comparable, V any] struct {
+	ctx   context.Context
+	keys  []K
+	calls map[K]*call[V]
+	timer *time.Timer
+}
+
+// Loader batches Load calls made within Wait of each other into one
+// BatchFunc call and caches the results. A Loader is meant to live for a
+// single request.
+type Loader[K comparable, V any] struct {
+	fetch BatchFunc[K, V]
+	opts  Options
+
+	mu    sync.Mutex
+	cache map[K]*call[V]
+	order []K
+	batch *batch[K, V]
+}
+
+// New creates a loader around fetch
+func New[K comparable, V any](fetch BatchFunc[K, V], opts Options) *Loader[K, V] {
+	return &Loader[K, V]{
+		fetch: fetch,
+		opts:  opts,
+		cache: make(map[K]*call[V]),
+	}
+}
+
+// Load returns the value for key, waiting for its batch to be fetched
+func (l *Loader[K, V]) Load(ctx context.Context, key K) (V, error) {
+	l.mu.Lock()
+	c, ok := l.cache[key]
+	if !ok {
+		c = l.enqueue(ctx, key)
+	}
+	l.mu.Unlock()
+
+	select {
+	case <-c.done:
+		return c.value, c.err
+	case <-ctx.Done():
+		var zero V
+		return zero, ctx.Err()
+	}
+}
+
+// LoadMany returns the values for keys in order
+func (l *Loader[K, V]) LoadMany(ctx context.Context, keys []K) ([]V, error) {
+	// Queue every key before waiting so they share a batch
+	calls := make([]*call[V], len(keys))
+	l.mu.Lock()
+	for i, key := range keys {
+		c, ok := l.cache[key]
+		if !ok {
+			c = l.enqueue(ctx, key)
+		}
+		calls[i] = c
+	}
+	l.mu.Unlock()
+
+	values := make([]V, len(keys))
+	for i, c := range calls {
+		select {
+		case <-c.done:
+			if c.err != nil {
+				return nil, c.err
+			}
+			values[i] = c.value
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	return values, nil
+}
+
+// Prime caches a value loaded some other way, e.g. by a list query
+func (l *Loader[K, V]) Prime(key K, value V) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if _, ok := l.cache[key]; ok {
+		return
+	}
+	c := &call[V]{done: make(chan struct{}), value: value}
+	close(c.done)
+	l.remember(key, c)
+}
+
+// enqueue adds key to the current batch, starting one if needed (caller
+// must hold l.mu)
+func (l *Loader[K, V]) enqueue(ctx context.Context, key K) *call[V] {
+	c := &call[V]{done: make(chan struct{})}
+	l.remember(key, c)
+
+	b := l.batch
+	if b == nil {
+		b = &batch[K, V]{ctx: ctx, calls: make(map[K]*call[V])}
+		b.timer = time.AfterFunc(l.opts.Wait, func() { l.dispatch(b) })
+		l.batch = b
+	}
+	b.keys = append(b.keys, key)
+	b.calls[key] = c
+
+	if l.opts.MaxBatch > 0 && len(b.keys) >= l.opts.MaxBatch {
+		b.timer.Stop()
+		l.batch = nil
+		go l.run(b)
+	}
+	return c
+}
+
+// dispatch runs b when its wait expires, unless it was already dispatched
+// for being full
+func (l *Loader[K, V]) dispatch(b *batch[K, V]) {
+	l.mu.Lock()
+	if l.batch != b {
+		l.mu.Unlock()
+		return
+	}
+	l.batch = nil
+	l.mu.Unlock()
+
+	l.run(b)
+}
+
+// run fetches a batch and completes its calls. Failed keys are dropped from
+// the cache so a later Load retries them.
+func (l *Loader[K, V]) run(b *batch[K, V]) {
+	values, err := l.fetch(b.ctx, b.keys)
+
+	if err != nil {
+		l.mu.Lock()
+		for key, c := range b.calls {
+			if l.cache[key] == c {
+				delete(l.cache, key)
+			}
+		}
+		l.mu.Unlock()
+	}
+
+	for key, c := range b.calls {
+		if err != nil {
+			c.err = err
+		} else {
+			c.value = values[key]
+		}
+		close(c.done)
+	}
+}
+
+// remember caches a call and forgets the oldest completed calls beyond
+// CacheSize. Calls stay cached while in flight so concurrent loads share
+// them. Caller must hold l.mu.
+func (l *Loader[K, V]) remember(key K, c *call[V]) {
+	l.cache[key] = c
+	l.order = append(l.order, key)
+
+	for len(l.order) > l.opts.CacheSize {
+		oldest := l.order[0]
+		if old, ok := l.cache[oldest]; ok {
+			if !isDone(old) {
+				// Evicted by a later call once it completes
+				break
+			}
+			delete(l.cache, oldest)
+		}
+		l.order = l.order[1:]
+	}
+}
+
+// isDone reports whether a call has completed
+func isDone[V any](c *call[V]) bool {
+	select {
+	case <-c.done:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,194 @@
+// Package directmessages delivers private messages between users. WebSocket
+// nodes publish what their clients send; the API server checks blocks and
+// the mutual-follow requirement, then publishes the message back for every
+// node to deliver to both users' connections, or stores a notification for
+// a recipient who isn't connected anywhere.
+package directmessages
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// onlineKey is the Redis sorted set of connected users, scored by expiry
+	onlineKey = "direct_online"
+
+	// flushInterval is how often connects and disconnects are written to Redis
+	flushInterval = time.Second
+)
+
+// OnlineOptions tunes online heartbeats
+type OnlineOptions struct {
+	// TTL is how long a user stays online without a heartbeat
+	TTL time.Duration
+
+	// HeartbeatInterval must be well under TTL
+	HeartbeatInterval time.Duration
+}
+
+// DefaultOnlineOptions returns the options used when none are configured
+func DefaultOnlineOptions() OnlineOptions {
+	return OnlineOptions{
+		TTL:               45 * time.Second,
+		HeartbeatInterval: 15 * time.Second,
+	}
+}
+
+// Online reads which users are connected to any WebSocket node
+type Online struct {
+	client *redis.Client
+}
+
+// NewOnline creates an online reader on client
+func NewOnline(client *redis.Client) *Online {
+	return &Online{client: client}
+}
+
+// IsOnline reports whether userID has a connection on any node
+func (o *Online) IsOnline(ctx context.Context, userID string) (bool, error) {
+	expiry, err := o.client.ZScore(ctx, onlineKey, userID).Result()
+	if errors.Is(err, redis.Nil) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to check online user: %w", err)
+	}
+	return expiry > float64(time.Now().UnixMilli()), nil
+}
+
+// OnlineTracker records the users connected to this node. The hub reports
+// them through UserOnline and UserOffline; Run writes them out and keeps
+// them alive with heartbeats, so a node that dies drops out once its
+// entries expire.
+type OnlineTracker struct {
+	*Online
+	opts OnlineOptions
+
+	mu sync.Mutex
+	// users connected to this node
+	local map[string]bool
+	// changes since the last flush: true for online, false for offline
+	pending map[string]bool
+}
+
+// NewOnlineTracker creates a tracker writing online users to client
+func NewOnlineTracker(client *redis.Client, opts OnlineOptions) *OnlineTracker {
+	return &OnlineTracker{
+		Online:  NewOnline(client),
+		opts:    opts,
+		local:   make(map[string]bool),
+		pending: make(map[string]bool),
+	}
+}
+
+// UserOnline records a user's first connection to this node. It is called
+// with the hub's lock held, so it only updates memory.
+func (t *OnlineTracker) UserOnline(userID string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.local[userID] = true
+	t.pending[userID] = true
+}
+
+// UserOffline records that a user's last connection to this node closed.
+// If the user is still connected to another node, that node's next
+// heartbeat restores them.
+func (t *OnlineTracker) UserOffline(userID string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.local, userID)
+	t.pending[userID] = false
+}
+
+// Run flushes changes and heartbeats this node's users until ctx is
+// cancelled, then removes them
+func (t *OnlineTracker) Run(ctx context.Context) {
+	flush := time.NewTicker(flushInterval)
+	defer flush.Stop()
+	heartbeat := time.NewTicker(t.opts.HeartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			t.removeAll()
+			return
+		case <-flush.C:
+			t.flush(ctx)
+		case <-heartbeat.C:
+			t.heartbeat(ctx)
+		}
+	}
+}
+
+// flush writes pending connects and disconnects
+func (t *OnlineTracker) flush(ctx context.Context) {
+	t.mu.Lock()
+	pending := t.pending
+	t.pending = make(map[string]bool)
+	t.mu.Unlock()
+
+	if len(pending) == 0 {
+		return
+	}
+
+	expiry := float64(time.Now().Add(t.opts.TTL).UnixMilli())
+	pipe := t.client.Pipeline()
+	for userID, online := range pending {
+		if online {
+			pipe.ZAdd(ctx, onlineKey, redis.Z{Score: expiry, Member: userID})
+		} else {
+			pipe.ZRem(ctx, onlineKey, userID)
+		}
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		log.Printf("Error flushing online users: %v", err)
+	}
+}
+
+// heartbeat extends every local user's expiry and prunes expired entries
+func (t *OnlineTracker) heartbeat(ctx context.Context) {
+	t.mu.Lock()
+	entries := make([]redis.Z, 0, len(t.local))
+	expiry := float64(time.Now().Add(t.opts.TTL).UnixMilli())
+	for userID := range t.local {
+		entries = append(entries, redis.Z{Score: expiry, Member: userID})
+	}
+	t.mu.Unlock()
+
+	pipe := t.client.Pipeline()
+	if len(entries) > 0 {
+		pipe.ZAdd(ctx, onlineKey, entries...)
+	}
+	pipe.ZRemRangeByScore(ctx, onlineKey, "-inf", fmt.Sprintf("%d", time.Now().UnixMilli()))
+	if _, err := pipe.Exec(ctx); err != nil {
+		log.Printf("Error heartbeating online users: %v", err)
+	}
+}
+
+// removeAll deletes this node's users on shutdown
+func (t *OnlineTracker) removeAll() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	t.mu.Lock()
+	members := make([]interface{}, 0, len(t.local))
+	for userID := range t.local {
+		members = append(members, userID)
+	}
+	t.mu.Unlock()
+
+	if len(members) == 0 {
+		return
+	}
+	if err := t.client.ZRem(ctx, onlineKey, members...).Err(); err != nil {
+		log.Printf("Error removing online users: %v", err)
+	}
+}
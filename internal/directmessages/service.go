@@ -0,0 +1,178 @@
+package directmessages
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/tinle0301/streaming-platform-api/internal/events"
+	"github.com/tinle0301/streaming-platform-api/internal/notifications"
+	"github.com/tinle0301/streaming-platform-api/internal/users"
+)
+
+// Why a direct message was rejected, sent to its sender in direct.rejected
+const (
+	ReasonRecipientNotFound = "recipient_not_found"
+	ReasonBlocked           = "blocked"
+	ReasonNotMutualFollow   = "not_mutual_follow"
+)
+
+// previewLength bounds the message shown in an offline notification
+const previewLength = 100
+
+// Policy decides who may direct message whom. Users who blocked each other
+// never can.
+type Policy struct {
+	// RequireMutualFollow only allows messages between users who follow
+	// each other
+	RequireMutualFollow bool
+}
+
+// Relationships looks up users, their follows, and their blocks
+type Relationships interface {
+	Get(ctx context.Context, id string) (*users.User, error)
+	IsFollowing(ctx context.Context, followerID, followedID string) (bool, error)
+	EitherBlocked(ctx context.Context, userID, otherID string) (bool, error)
+}
+
+// OnlineChecker reports whether a user is connected to any WebSocket node
+type OnlineChecker interface {
+	IsOnline(ctx context.Context, userID string) (bool, error)
+}
+
+// Notifier stores notifications for recipients who aren't connected
+type Notifier interface {
+	Deliver(ctx context.Context, n *notifications.Notification, actor string) error
+}
+
+// Service checks and delivers direct messages published by the WebSocket
+// servers
+type Service struct {
+	users     Relationships
+	online    OnlineChecker
+	notifier  Notifier
+	publisher events.Publisher
+	policy    Policy
+}
+
+// NewService creates a direct message service that publishes delivered and
+// rejected messages through publisher
+func NewService(relationships Relationships, online OnlineChecker, notifier Notifier, publisher events.Publisher, policy Policy) *Service {
+	return &Service{
+		users:     relationships,
+		online:    online,
+		notifier:  notifier,
+		publisher: publisher,
+		policy:    policy,
+	}
+}
+
+// HandleEvent checks a sent direct message against blocks and the policy.
+// Allowed messages are published for every node to deliver; the recipient
+// is also notified if they aren't connected. Rejected messages are
+// published back to the sender's connections with the reason.
+func (s *Service) HandleEvent(ctx context.Context, event events.Event) error {
+	if event.Type != events.EventTypeDirectMessageSent {
+		return nil
+	}
+	fromID := event.UserID
+	toID, _ := event.Data["to"].(string)
+	message, _ := event.Data["message"].(string)
+	if fromID == "" || toID == "" || message == "" {
+		return nil
+	}
+
+	reason, err := s.check(ctx, fromID, toID)
+	if err != nil {
+		return err
+	}
+	if reason != "" {
+		rejected := events.NewDirectMessageRejectedEvent(event.ID, fromID, toID, reason)
+		if err := s.publisher.Publish(ctx, rejected); err != nil {
+			return fmt.Errorf("failed to publish rejected direct message: %w", err)
+		}
+		return nil
+	}
+
+	delivered := events.NewDirectMessageEvent(event.ID, fromID, toID, message, event.Timestamp)
+	if err := s.publisher.Publish(ctx, delivered); err != nil {
+		return fmt.Errorf("failed to publish direct message: %w", err)
+	}
+
+	// A failed lookup notifies anyway; a redundant notification beats a
+	// message nobody sees
+	online, err := s.online.IsOnline(ctx, toID)
+	if err != nil {
+		log.Printf("Error checking direct message recipient: userID=%s, err=%v", toID, err)
+	}
+	if online {
+		return nil
+	}
+	return s.notifyOffline(ctx, event.ID, fromID, toID, message)
+}
+
+// check returns why fromID may not message toID, or "" if they may
+func (s *Service) check(ctx context.Context, fromID, toID string) (string, error) {
+	if _, err := s.users.Get(ctx, toID); errors.Is(err, users.ErrNotFound) {
+		return ReasonRecipientNotFound, nil
+	} else if err != nil {
+		return "", err
+	}
+
+	blocked, err := s.users.EitherBlocked(ctx, fromID, toID)
+	if err != nil {
+		return "", err
+	}
+	if blocked {
+		return ReasonBlocked, nil
+	}
+
+	if s.policy.RequireMutualFollow {
+		for _, pair := range [][2]string{{fromID, toID}, {toID, fromID}} {
+			following, err := s.users.IsFollowing(ctx, pair[0], pair[1])
+			if err != nil {
+				return "", err
+			}
+			if !following {
+				return ReasonNotMutualFollow, nil
+			}
+		}
+	}
+	return "", nil
+}
+
+// notifyOffline stores a DIRECT_MESSAGE notification for a recipient who
+// isn't connected; quick repeats from one sender merge into one
+func (s *Service) notifyOffline(ctx context.Context, messageID, fromID, toID, message string) error {
+	sender := "Someone"
+	if user, err := s.users.Get(ctx, fromID); err == nil {
+		sender = user.DisplayName
+		if sender == "" {
+			sender = user.Username
+		}
+	}
+
+	preview := message
+	if runes := []rune(preview); len(runes) > previewLength {
+		preview = strings.TrimSpace(string(runes[:previewLength])) + "…"
+	}
+
+	err := s.notifier.Deliver(ctx, &notifications.Notification{
+		UserID:     toID,
+		Type:       notifications.TypeDirectMessage,
+		Title:      "New message from " + sender,
+		Message:    preview,
+		FromUserID: fromID,
+		Data: map[string]interface{}{
+			"message_id": messageID,
+			"message":    message,
+		},
+		SourceEventID: messageID,
+	}, sender)
+	if errors.Is(err, notifications.ErrMissingReference) {
+		return nil
+	}
+	return err
+}
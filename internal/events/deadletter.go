@@ -0,0 +1,405 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/redis/go-redis/v9"
+)
+
+// Dead-letter queue backends, as set by the API server's EVENT_DLQ setting
+const (
+	DeadLetterRedis    = "redis"
+	DeadLetterPostgres = "postgres"
+	DeadLetterNone     = "none"
+)
+
+// DefaultDeadLetterQueue is the Redis stream dead letters are appended to
+const DefaultDeadLetterQueue = "events.dlq"
+
+// MaxDeadLetters bounds a page of dead letters
+const MaxDeadLetters = 100
+
+// ErrDeadLetterNotFound is returned for a dead letter that does not exist,
+// e.g. because it was already requeued
+var ErrDeadLetterNotFound = errors.New("dead letter not found")
+
+// DeadLetter is an event a consumer gave up on
+type DeadLetter struct {
+	ID       string    `json:"-"`
+	Event    Event     `json:"event"`
+	Consumer string    `json:"consumer"`
+	Error    string    `json:"error"`
+	Attempts int       `json:"attempts"`
+	FailedAt time.Time `json:"failed_at"`
+}
+
+// DeadLetterQueue stores events consumers gave up on, for operators to
+// inspect and requeue
+type DeadLetterQueue interface {
+	// Add stores a dead letter, assigning its ID
+	Add(ctx context.Context, letter DeadLetter) error
+
+	// List returns up to limit dead letters, newest first, starting after
+	// the dead letter with ID after if given
+	List(ctx context.Context, limit int, after string) ([]*DeadLetter, error)
+
+	// Get returns a dead letter by ID
+	Get(ctx context.Context, id string) (*DeadLetter, error)
+
+	// Delete removes a dead letter
+	Delete(ctx context.Context, id string) error
+}
+
+// RetryPolicy is how often and how long apart a consumer retries an event
+// before dead-lettering it
+type RetryPolicy struct {
+	// MaxRetries is how many times a failed event is retried
+	MaxRetries int
+
+	// Delay is the wait before the first retry; it doubles with every
+	// retry up to MaxDelay
+	Delay    time.Duration
+	MaxDelay time.Duration
+}
+
+// DefaultRetryPolicy returns a policy retrying three times over about 7s
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxRetries: 3,
+		Delay:      time.Second,
+		MaxDelay:   30 * time.Second,
+	}
+}
+
+// Backoff returns the wait before retry n, counting from 1
+func (p RetryPolicy) Backoff(n int) time.Duration {
+	delay := p.Delay
+	for i := 1; i < n && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	if p.MaxDelay > 0 && delay > p.MaxDelay {
+		return p.MaxDelay
+	}
+	return delay
+}
+
+// DeadLetterSubscriber retries the events another subscriber's handler
+// fails on, with backoff, and then moves them to a dead-letter queue and
+// reports them handled. If the queue cannot store an event, the error is
+// returned and the wrapped backend's own retries and dead-lettering apply.
+type DeadLetterSubscriber struct {
+	subscriber Subscriber
+	consumer   string
+	queue      DeadLetterQueue
+	policy     RetryPolicy
+}
+
+// NewDeadLetterSubscriber wraps subscriber, recording dead letters in queue
+// under the consumer name
+func NewDeadLetterSubscriber(subscriber Subscriber, consumer string, queue DeadLetterQueue, policy RetryPolicy) *DeadLetterSubscriber {
+	return &DeadLetterSubscriber{
+		subscriber: subscriber,
+		consumer:   consumer,
+		queue:      queue,
+		policy:     policy,
+	}
+}
+
+// Subscribe subscribes the wrapped subscriber with handler retried and
+// dead-lettered
+func (s *DeadLetterSubscriber) Subscribe(ctx context.Context, eventTypes []string, handler Handler) error {
+	return s.subscriber.Subscribe(ctx, eventTypes, func(ctx context.Context, event Event) error {
+		return s.handle(ctx, event, handler)
+	})
+}
+
+// handle runs handler until it succeeds or the policy's retries run out
+func (s *DeadLetterSubscriber) handle(ctx context.Context, event Event, handler Handler) error {
+	err := handler(ctx, event)
+	attempts := 1
+	for ; err != nil && attempts <= s.policy.MaxRetries; attempts++ {
+		delay := s.policy.Backoff(attempts)
+		slog.Warn("Event failed, retrying", "consumer", s.consumer, "retry", attempts, "max_retries", s.policy.MaxRetries,
+			"delay", delay, "type", event.Type, "id", event.ID, "error", err)
+		select {
+		case <-ctx.Done():
+			// Shutting down: leave the event to the backend's redelivery
+			return err
+		case <-time.After(delay):
+		}
+		err = handler(ctx, event)
+	}
+	if err == nil {
+		return nil
+	}
+
+	letter := DeadLetter{
+		Event:    event,
+		Consumer: s.consumer,
+		Error:    err.Error(),
+		Attempts: attempts,
+		FailedAt: time.Now(),
+	}
+	if dlqErr := s.queue.Add(ctx, letter); dlqErr != nil {
+		slog.Error("Error dead-lettering event", "consumer", s.consumer, "type", event.Type, "id", event.ID, "error", dlqErr)
+		return err
+	}
+	slog.Warn("Event dead-lettered", "consumer", s.consumer, "attempts", attempts, "type", event.Type, "id", event.ID, "error", err)
+	return nil
+}
+
+// Ready reports whether the wrapped subscriber is ready, if it can tell
+func (s *DeadLetterSubscriber) Ready(ctx context.Context) error {
+	if checker, ok := s.subscriber.(interface{ Ready(context.Context) error }); ok {
+		return checker.Ready(ctx)
+	}
+	return nil
+}
+
+// Close closes the wrapped subscriber
+func (s *DeadLetterSubscriber) Close() error {
+	return s.subscriber.Close()
+}
+
+// Requeue publishes a dead letter's event again, under its original ID, and
+// removes it from queue. Every consumer of the event's type receives it
+// again, so handlers must be idempotent.
+func Requeue(ctx context.Context, queue DeadLetterQueue, publisher Publisher, id string) (*DeadLetter, error) {
+	letter, err := queue.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := publisher.Publish(ctx, letter.Event); err != nil {
+		return nil, fmt.Errorf("failed to requeue dead letter %s: %w", id, err)
+	}
+	if err := queue.Delete(ctx, id); err != nil {
+		return nil, err
+	}
+	return letter, nil
+}
+
+// RedisDeadLetterQueue implements DeadLetterQueue on a Redis stream, whose
+// entry IDs are the dead letters' IDs
+type RedisDeadLetterQueue struct {
+	client *redis.Client
+	stream string
+	maxLen int64
+}
+
+// NewRedisDeadLetterQueue creates a dead-letter queue on client's
+// DefaultDeadLetterQueue stream, trimmed to about maxLen entries (0 =
+// unbounded)
+func NewRedisDeadLetterQueue(client *redis.Client, maxLen int64) *RedisDeadLetterQueue {
+	return &RedisDeadLetterQueue{
+		client: client,
+		stream: DefaultDeadLetterQueue,
+		maxLen: maxLen,
+	}
+}
+
+// Add appends a dead letter to the stream
+func (q *RedisDeadLetterQueue) Add(ctx context.Context, letter DeadLetter) error {
+	data, err := json.Marshal(letter)
+	if err != nil {
+		return fmt.Errorf("failed to marshal dead letter: %w", err)
+	}
+	err = q.client.XAdd(ctx, &redis.XAddArgs{
+		Stream: q.stream,
+		MaxLen: q.maxLen,
+		Approx: q.maxLen > 0,
+		Values: map[string]interface{}{
+			streamFieldType:  letter.Event.Type,
+			streamFieldEvent: data,
+		},
+	}).Err()
+	if err != nil {
+		return fmt.Errorf("failed to add dead letter: %w", err)
+	}
+	return nil
+}
+
+// List reads dead letters from the end of the stream
+func (q *RedisDeadLetterQueue) List(ctx context.Context, limit int, after string) ([]*DeadLetter, error) {
+	end := "+"
+	if after != "" {
+		end = "(" + after
+	}
+	messages, err := q.client.XRevRangeN(ctx, q.stream, end, "-", int64(limit)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list dead letters: %w", err)
+	}
+
+	letters := make([]*DeadLetter, 0, len(messages))
+	for _, message := range messages {
+		letter, err := decodeDeadLetter(message)
+		if err != nil {
+			return nil, err
+		}
+		letters = append(letters, letter)
+	}
+	return letters, nil
+}
+
+// Get reads one dead letter from the stream
+func (q *RedisDeadLetterQueue) Get(ctx context.Context, id string) (*DeadLetter, error) {
+	messages, err := q.client.XRangeN(ctx, q.stream, id, id, 1).Result()
+	if err != nil {
+		// Malformed IDs are missing rather than failing
+		if strings.Contains(err.Error(), "Invalid stream ID") {
+			return nil, ErrDeadLetterNotFound
+		}
+		return nil, fmt.Errorf("failed to get dead letter: %w", err)
+	}
+	if len(messages) == 0 {
+		return nil, ErrDeadLetterNotFound
+	}
+	return decodeDeadLetter(messages[0])
+}
+
+// Delete removes a dead letter from the stream
+func (q *RedisDeadLetterQueue) Delete(ctx context.Context, id string) error {
+	deleted, err := q.client.XDel(ctx, q.stream, id).Result()
+	if err != nil {
+		if strings.Contains(err.Error(), "Invalid stream ID") {
+			return ErrDeadLetterNotFound
+		}
+		return fmt.Errorf("failed to delete dead letter: %w", err)
+	}
+	if deleted == 0 {
+		return ErrDeadLetterNotFound
+	}
+	return nil
+}
+
+// decodeDeadLetter decodes a dead letter stream entry
+func decodeDeadLetter(message redis.XMessage) (*DeadLetter, error) {
+	data, _ := message.Values[streamFieldEvent].(string)
+	var letter DeadLetter
+	if err := json.Unmarshal([]byte(data), &letter); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal dead letter %s: %w", message.ID, err)
+	}
+	letter.ID = message.ID
+	return &letter, nil
+}
+
+// PostgresDeadLetterQueue implements DeadLetterQueue on the dead_letters
+// table
+type PostgresDeadLetterQueue struct {
+	pool *pgxpool.Pool
+}
+
+// NewPostgresDeadLetterQueue creates a dead-letter queue on pool
+func NewPostgresDeadLetterQueue(pool *pgxpool.Pool) *PostgresDeadLetterQueue {
+	return &PostgresDeadLetterQueue{pool: pool}
+}
+
+// deadLetterColumns are the columns scanDeadLetter reads
+const deadLetterColumns = `id, consumer, event, error, attempts, failed_at`
+
+// Add inserts a dead letter
+func (q *PostgresDeadLetterQueue) Add(ctx context.Context, letter DeadLetter) error {
+	event, err := json.Marshal(letter.Event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal dead letter: %w", err)
+	}
+	_, err = q.pool.Exec(ctx, `
+		INSERT INTO dead_letters (consumer, event_id, event_type, event, error, attempts, failed_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		letter.Consumer, letter.Event.ID, letter.Event.Type, event, letter.Error, letter.Attempts, letter.FailedAt)
+	if err != nil {
+		return fmt.Errorf("failed to add dead letter: %w", err)
+	}
+	return nil
+}
+
+// List returns dead letters by descending ID
+func (q *PostgresDeadLetterQueue) List(ctx context.Context, limit int, after string) ([]*DeadLetter, error) {
+	var cursor int64
+	if after != "" {
+		var err error
+		if cursor, err = strconv.ParseInt(after, 10, 64); err != nil {
+			return nil, ErrDeadLetterNotFound
+		}
+	}
+
+	rows, err := q.pool.Query(ctx, `
+		SELECT `+deadLetterColumns+` FROM dead_letters
+		WHERE $1 = 0 OR id < $1
+		ORDER BY id DESC
+		LIMIT $2`, cursor, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list dead letters: %w", err)
+	}
+	defer rows.Close()
+
+	var letters []*DeadLetter
+	for rows.Next() {
+		letter, err := scanDeadLetter(rows)
+		if err != nil {
+			return nil, err
+		}
+		letters = append(letters, letter)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list dead letters: %w", err)
+	}
+	return letters, nil
+}
+
+// Get returns a dead letter by ID
+func (q *PostgresDeadLetterQueue) Get(ctx context.Context, id string) (*DeadLetter, error) {
+	n, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return nil, ErrDeadLetterNotFound
+	}
+
+	letter, err := scanDeadLetter(q.pool.QueryRow(ctx, `SELECT `+deadLetterColumns+` FROM dead_letters WHERE id = $1`, n))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrDeadLetterNotFound
+	}
+	return letter, err
+}
+
+// Delete removes a dead letter
+func (q *PostgresDeadLetterQueue) Delete(ctx context.Context, id string) error {
+	n, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return ErrDeadLetterNotFound
+	}
+
+	tag, err := q.pool.Exec(ctx, `DELETE FROM dead_letters WHERE id = $1`, n)
+	if err != nil {
+		return fmt.Errorf("failed to delete dead letter: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrDeadLetterNotFound
+	}
+	return nil
+}
+
+// scanDeadLetter scans a row of deadLetterColumns
+func scanDeadLetter(row pgx.Row) (*DeadLetter, error) {
+	var letter DeadLetter
+	var id int64
+	var event []byte
+	if err := row.Scan(&id, &letter.Consumer, &event, &letter.Error, &letter.Attempts, &letter.FailedAt); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to scan dead letter: %w", err)
+	}
+	if err := json.Unmarshal(event, &letter.Event); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal dead letter %d: %w", id, err)
+	}
+	letter.ID = strconv.FormatInt(id, 10)
+	return &letter, nil
+}
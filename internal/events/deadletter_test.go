@@ -0,0 +1,139 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// memoryDeadLetters is an in-memory DeadLetterQueue
+type memoryDeadLetters struct {
+	letters []*DeadLetter
+	err     error
+}
+
+func (q *memoryDeadLetters) Add(ctx context.Context, letter DeadLetter) error {
+	if q.err != nil {
+		return q.err
+	}
+	letter.ID = strconv.Itoa(len(q.letters) + 1)
+	q.letters = append(q.letters, &letter)
+	return nil
+}
+
+func (q *memoryDeadLetters) List(ctx context.Context, limit int, after string) ([]*DeadLetter, error) {
+	return q.letters, nil
+}
+
+func (q *memoryDeadLetters) Get(ctx context.Context, id string) (*DeadLetter, error) {
+	for _, letter := range q.letters {
+		if letter.ID == id {
+			return letter, nil
+		}
+	}
+	return nil, ErrDeadLetterNotFound
+}
+
+func (q *memoryDeadLetters) Delete(ctx context.Context, id string) error {
+	for i, letter := range q.letters {
+		if letter.ID == id {
+			q.letters = append(q.letters[:i], q.letters[i+1:]...)
+			return nil
+		}
+	}
+	return ErrDeadLetterNotFound
+}
+
+// oneEventSubscriber delivers a single event and returns the handler's error
+type oneEventSubscriber struct {
+	event Event
+}
+
+func (s oneEventSubscriber) Subscribe(ctx context.Context, eventTypes []string, handler Handler) error {
+	return handler(ctx, s.event)
+}
+
+func (s oneEventSubscriber) Close() error { return nil }
+
+// recordingPublisher records published events
+type recordingPublisher struct {
+	published []Event
+}
+
+func (p *recordingPublisher) Publish(ctx context.Context, event Event) error {
+	p.published = append(p.published, event)
+	return nil
+}
+
+func (p *recordingPublisher) PublishBatch(ctx context.Context, events []Event) error {
+	p.published = append(p.published, events...)
+	return nil
+}
+
+func (p *recordingPublisher) Close() error { return nil }
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	policy := RetryPolicy{MaxRetries: 5, Delay: time.Second, MaxDelay: 5 * time.Second}
+	want := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second}
+	for i, delay := range want {
+		if got := policy.Backoff(i + 1); got != delay {
+			t.Errorf("Backoff(%d) = %s, want %s", i+1, got, delay)
+		}
+	}
+}
+
+func TestDeadLetterSubscriber(t *testing.T) {
+	policy := RetryPolicy{MaxRetries: 2, Delay: time.Millisecond, MaxDelay: time.Millisecond}
+	event := NewChatMessageEvent("stream-1", "user-1", "hello")
+	failure := errors.New("handler failed")
+
+	t.Run("recovers", func(t *testing.T) {
+		queue := &memoryDeadLetters{}
+		calls := 0
+		s := NewDeadLetterSubscriber(oneEventSubscriber{event}, "test", queue, policy)
+		err := s.Subscribe(context.Background(), nil, func(ctx context.Context, event Event) error {
+			if calls++; calls < 3 {
+				return failure
+			}
+			return nil
+		})
+		if err != nil || calls != 3 || len(queue.letters) != 0 {
+			t.Errorf("Subscribe() = %v after %d calls with %d dead letters, want nil after 3 with none", err, calls, len(queue.letters))
+		}
+	})
+
+	t.Run("dead-letters", func(t *testing.T) {
+		queue := &memoryDeadLetters{}
+		s := NewDeadLetterSubscriber(oneEventSubscriber{event}, "test", queue, policy)
+		err := s.Subscribe(context.Background(), nil, func(ctx context.Context, event Event) error { return failure })
+		if err != nil {
+			t.Fatalf("Subscribe() = %v, want nil once dead-lettered", err)
+		}
+		if len(queue.letters) != 1 {
+			t.Fatalf("got %d dead letters, want 1", len(queue.letters))
+		}
+		letter := queue.letters[0]
+		if letter.Event.ID != event.ID || letter.Consumer != "test" || letter.Attempts != 3 || letter.Error != failure.Error() {
+			t.Errorf("dead letter = %+v, want event %s by test after 3 attempts", letter, event.ID)
+		}
+
+		publisher := &recordingPublisher{}
+		if _, err := Requeue(context.Background(), queue, publisher, letter.ID); err != nil {
+			t.Fatalf("Requeue() = %v", err)
+		}
+		if len(publisher.published) != 1 || publisher.published[0].ID != event.ID || len(queue.letters) != 0 {
+			t.Errorf("Requeue() published %v leaving %d dead letters, want the event and none", publisher.published, len(queue.letters))
+		}
+	})
+
+	t.Run("queue unavailable", func(t *testing.T) {
+		queue := &memoryDeadLetters{err: errors.New("queue down")}
+		s := NewDeadLetterSubscriber(oneEventSubscriber{event}, "test", queue, policy)
+		err := s.Subscribe(context.Background(), nil, func(ctx context.Context, event Event) error { return failure })
+		if !errors.Is(err, failure) {
+			t.Errorf("Subscribe() = %v, want the handler's error for the backend to retry", err)
+		}
+	})
+}
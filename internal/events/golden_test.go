@@ -0,0 +1,196 @@
+package events
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// update rewrites the golden files from the current payloads. Use it only
+// for an intentional schema change, and bump the event's version when the
+// change would break consumers.
+var update = flag.Bool("update", false, "rewrite testdata/golden from the current event payloads")
+
+// goldenDir holds one JSON file per event type and version
+const goldenDir = "testdata/golden"
+
+// goldenTime is the fixed time used in golden events
+var goldenTime = time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+// goldenEvents returns one event per registered type and version, built
+// the way publishers build them, with every optional field set
+func goldenEvents() []Event {
+	streamInfo := map[string]interface{}{
+		"title": "Speedrun practice", "category": "games", "tags": []string{"speedrun", "english"},
+		"language": "en", "is_mature": true, "branded_content": true,
+	}
+	raid := func() map[string]interface{} {
+		return map[string]interface{}{
+			"raid_id": "raid-1", "from_stream_id": "stream-1", "from_streamer_id": "user-1", "from_title": "Speedrun practice",
+			"to_stream_id": "stream-2", "to_streamer_id": "user-2", "to_title": "Cozy farming",
+			"viewer_count": 120, "countdown_seconds": int64(10), "lands_at": goldenTime.Add(10 * time.Second).UnixMilli(),
+		}
+	}
+	goal := func() map[string]interface{} {
+		return map[string]interface{}{
+			"goal_id": "goal-1", "kind": "followers", "description": "Road to 1k", "status": "active",
+			"current": int64(500), "target": int64(1000), "progress": 0.5,
+		}
+	}
+
+	return []Event{
+		NewStreamLiveEvent("stream-1", "user-1", streamInfo),
+		NewStreamOfflineEvent("stream-1", "user-1", map[string]interface{}{"duration_seconds": int64(5400)}),
+		NewStreamUpdatedEvent("stream-1", "user-1", streamInfo),
+		func() Event {
+			event := NewFollowerEvent("user-2", "user-1")
+			event.Data["follower_username"] = "viewer"
+			event.Data["follower_display_name"] = "Viewer"
+			return event
+		}(),
+		NewChatMessageEvent("stream-1", "user-2", "hello chat"),
+		NewRaidEvent(EventTypeRaidOutgoing, "stream-1", raid()),
+		NewRaidEvent(EventTypeRaidIncoming, "stream-2", raid()),
+		NewSubscriptionEvent("user-1", map[string]interface{}{
+			"subscription_id": "sub-1", "subscriber_id": "user-2", "subscriber_username": "viewer",
+			"subscriber_display_name": "Viewer", "tier": "1000", "months": 3,
+		}),
+		NewGiftSubscriptionEvent("user-1", map[string]interface{}{
+			"subscription_id": "sub-2", "gifter_id": "user-2", "gifter_username": "viewer", "gifter_display_name": "Viewer",
+			"recipient_id": "user-3", "recipient_username": "lurker", "recipient_display_name": "Lurker", "tier": "1000", "count": 1,
+		}),
+		NewBitsCheeredEvent("stream-1", "user-1", map[string]interface{}{
+			"cheer_id": "cheer-1", "cheerer_id": "user-2", "cheerer_username": "viewer", "cheerer_display_name": "Viewer",
+			"bits": 100, "message": "cheer100 gg", "animation": "confetti",
+		}),
+		NewStreamMilestoneEvent("stream-1", "user-1", map[string]interface{}{
+			"milestone_id": "milestone-1", "kind": "viewers", "threshold": int64(100), "value": int64(101), "message": "100 viewers!",
+		}),
+		NewWatchProgressEvent("stream-1", "user-2", 5*time.Minute),
+		{
+			ID: "evt_claim_claim-1", Type: EventTypeRewardClaimed, UserID: "user-2",
+			Data: map[string]interface{}{
+				"claim_id": "claim-1", "campaign_id": "campaign-1", "reward_id": "reward-1",
+				"reward_name": "Golden badge", "required_minutes": 30, "claimed_at": goldenTime,
+			},
+			Timestamp: goldenTime, Version: "1.0",
+		},
+		NewClipPublishedEvent("clip-1", "stream-1", "user-2", map[string]interface{}{
+			"title": "Clutch", "start_ms": int64(60000), "end_ms": int64(90000), "duration_seconds": 30.0,
+		}),
+		NewVODRenderRequestedEvent("vod-1", "stream-1", "user-1", map[string]interface{}{
+			"kind": "highlight", "segments": []RenderSegment{{StartMS: 0, EndMS: 30000}, {StartMS: 60000, EndMS: 90000}},
+		}),
+		NewNotificationCreatedEvent("user-1", map[string]interface{}{
+			"id": "notification-1", "type": "NEW_FOLLOWER", "title": "New follower", "message": "Viewer followed you",
+			"data": map[string]interface{}{"follower_id": "user-2"}, "created_at": goldenTime.Format(time.RFC3339),
+			"from_user_id": "user-2", "stream_id": "stream-1",
+		}),
+		NewCaptionSegmentEvent("stream-1", map[string]interface{}{
+			"caption_id": "caption-1", "language": "en", "start_ms": int64(1000), "end_ms": int64(3000), "text": "welcome back",
+		}),
+		NewPartySyncEvent("party-1", "user-1", map[string]interface{}{
+			"host_id": "user-1", "position_ms": int64(42000), "playing": true, "rate": 1.0, "updated_at": goldenTime.UnixMilli(),
+		}),
+		NewPartyChatMessageEvent("party-1", "user-2", "this part!"),
+		NewPartyEndedEvent("party-1", "user-1"),
+		NewPremiereEvent(EventTypePremiereCountdown, "vod-1", map[string]interface{}{
+			"scheduled_at": goldenTime.Format(time.RFC3339), "remaining_ms": int64(60000),
+		}),
+		NewPremiereEvent(EventTypePremiereStarted, "vod-1", map[string]interface{}{
+			"started_at": goldenTime.Format(time.RFC3339), "position_ms": int64(0), "duration_ms": int64(3600000),
+		}),
+		NewPremiereEvent(EventTypePremiereSync, "vod-1", map[string]interface{}{
+			"position_ms": int64(1000), "duration_ms": int64(3600000), "server_time": goldenTime.Format(time.RFC3339Nano),
+		}),
+		NewPremiereEvent(EventTypePremiereEnded, "vod-1", map[string]interface{}{"cancelled": false}),
+		NewCommunityChatEvent(EventTypeCommunityChatOpened, "user-1", map[string]interface{}{"slow_mode_seconds": int64(30)}),
+		NewCommunityChatEvent(EventTypeCommunityChatClosed, "user-1", map[string]interface{}{"reason": "live", "stream_id": "stream-1"}),
+		NewDirectMessageSentEvent("user-2", "user-1", "hey"),
+		NewDirectMessageEvent("message-1", "user-2", "user-1", "hey", goldenTime),
+		NewDirectMessageRejectedEvent("message-1", "user-2", "user-1", "blocked"),
+		NewGoalEvent(EventTypeGoalProgress, "user-1", goal()),
+		NewGoalEvent(EventTypeGoalCompleted, "user-1", goal()),
+	}
+}
+
+// goldenPath is the golden file of an event type and version
+func goldenPath(eventType, version string) string {
+	return filepath.Join(goldenDir, eventType+"@"+version+".json")
+}
+
+// TestEventGoldenFiles compares every event's JSON with its golden file, so
+// a payload change consumers would see fails until the golden file is
+// regenerated with -update
+func TestEventGoldenFiles(t *testing.T) {
+	registry := DefaultRegistry()
+	if *update {
+		if err := os.MkdirAll(goldenDir, 0o755); err != nil {
+			t.Fatalf("failed to create %s: %v", goldenDir, err)
+		}
+	}
+
+	covered := make(map[string]bool)
+	for _, event := range goldenEvents() {
+		name := event.Type + "@" + event.Version
+		covered[name] = true
+
+		t.Run(name, func(t *testing.T) {
+			if err := registry.Validate(event); err != nil {
+				t.Fatalf("Validate() = %v; golden events must match their schema", err)
+			}
+
+			// IDs and publish times differ on every run
+			event.ID = "evt_golden"
+			event.Timestamp = goldenTime
+			got, err := json.MarshalIndent(event, "", "  ")
+			if err != nil {
+				t.Fatalf("failed to marshal event: %v", err)
+			}
+			got = append(got, '\n')
+
+			path := goldenPath(event.Type, event.Version)
+			if *update {
+				if err := os.WriteFile(path, got, 0o644); err != nil {
+					t.Fatalf("failed to write %s: %v", path, err)
+				}
+				return
+			}
+			want, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("failed to read golden file: %v; run go test ./internal/events -run TestEventGoldenFiles -update", err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("%s changed; consumers would see:\n%s\nwant:\n%s\nIf the change is intended, bump the event's version or run with -update", path, got, want)
+			}
+		})
+	}
+
+	// Every registered version needs a golden event, and every golden file
+	// an event that produces it
+	for _, eventType := range registry.Types() {
+		for _, version := range registry.Versions(eventType) {
+			if !covered[eventType+"@"+version] {
+				t.Errorf("no golden event for %s %s; add one to goldenEvents", eventType, version)
+			}
+		}
+	}
+	files, err := filepath.Glob(filepath.Join(goldenDir, "*.json"))
+	if err != nil {
+		t.Fatalf("failed to list golden files: %v", err)
+	}
+	for _, file := range files {
+		if name := strings.TrimSuffix(filepath.Base(file), ".json"); !covered[name] {
+			if *update {
+				os.Remove(file)
+				continue
+			}
+			t.Errorf("%s has no golden event; remove it or run with -update", name)
+		}
+	}
+}
@@ -0,0 +1,180 @@
+package events
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// maxEventIDLength bounds event IDs supplied by callers
+const maxEventIDLength = 128
+
+// Event ID errors
+var (
+	ErrInvalidEventID   = errors.New("event ID must be at most 128 printable characters without spaces")
+	ErrDuplicateEventID = errors.New("event ID appears more than once in the batch")
+)
+
+// IDGenerator creates event IDs. IDs must be unique across instances, since
+// consumers deduplicate redelivered events by ID.
+type IDGenerator interface {
+	NewID() string
+}
+
+// IDGeneratorFunc adapts a function to IDGenerator
+type IDGeneratorFunc func() string
+
+// NewID calls f
+func (f IDGeneratorFunc) NewID() string {
+	return f()
+}
+
+// UUIDv7Generator creates time-ordered UUIDv7 IDs (RFC 9562). IDs from one
+// generator are strictly increasing, even within a millisecond; across
+// instances the 74 random bits keep them apart.
+type UUIDv7Generator struct {
+	mu     sync.Mutex
+	lastMs int64
+	seq    uint16
+}
+
+// NewUUIDv7Generator creates a UUIDv7 generator
+func NewUUIDv7Generator() *UUIDv7Generator {
+	return &UUIDv7Generator{}
+}
+
+// NewID returns a new UUIDv7 in its canonical text form
+func (g *UUIDv7Generator) NewID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic(fmt.Sprintf("failed to read random bytes for event ID: %v", err))
+	}
+
+	ms, seq := g.next(b[6:8])
+
+	// 48-bit Unix milliseconds, then the version nibble and a 12-bit
+	// counter, then the variant and random bits
+	b[0] = byte(ms >> 40)
+	b[1] = byte(ms >> 32)
+	b[2] = byte(ms >> 24)
+	b[3] = byte(ms >> 16)
+	b[4] = byte(ms >> 8)
+	b[5] = byte(ms)
+	b[6] = 0x70 | byte(seq>>8)
+	b[7] = byte(seq)
+	b[8] = 0x80 | b[8]&0x3f
+
+	var s [36]byte
+	hex.Encode(s[0:8], b[0:4])
+	s[8] = '-'
+	hex.Encode(s[9:13], b[4:6])
+	s[13] = '-'
+	hex.Encode(s[14:18], b[6:8])
+	s[18] = '-'
+	hex.Encode(s[19:23], b[8:10])
+	s[23] = '-'
+	hex.Encode(s[24:], b[10:])
+	return string(s[:])
+}
+
+// next returns the timestamp and counter for the next ID. The counter starts
+// from random each millisecond, and when it runs out the timestamp is
+// borrowed from the next millisecond, so IDs never go backwards.
+func (g *UUIDv7Generator) next(random []byte) (int64, uint16) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	ms := time.Now().UnixMilli()
+	if ms > g.lastMs {
+		// Leave headroom above the random start for IDs in the same millisecond
+		g.lastMs = ms
+		g.seq = (uint16(random[0])<<8 | uint16(random[1])) & 0x07ff
+		return g.lastMs, g.seq
+	}
+
+	g.seq++
+	if g.seq > 0x0fff {
+		g.lastMs++
+		g.seq = 0
+	}
+	return g.lastMs, g.seq
+}
+
+var (
+	idGeneratorMu sync.RWMutex
+	idGenerator   IDGenerator = NewUUIDv7Generator()
+)
+
+// SetIDGenerator replaces the generator event IDs are created with, e.g.
+// with a deterministic one in tests. A nil generator restores UUIDv7.
+func SetIDGenerator(generator IDGenerator) {
+	if generator == nil {
+		generator = NewUUIDv7Generator()
+	}
+
+	idGeneratorMu.Lock()
+	defer idGeneratorMu.Unlock()
+	idGenerator = generator
+}
+
+// generateEventID generates a unique event ID
+func generateEventID() string {
+	idGeneratorMu.RLock()
+	defer idGeneratorMu.RUnlock()
+	return idGenerator.NewID()
+}
+
+// ValidateEventID checks a caller-supplied event ID. Besides generated
+// UUIDs, deterministic IDs such as "evt_claim_<id>" are accepted so
+// producers can make retried events deduplicate.
+func ValidateEventID(id string) error {
+	if id == "" || len(id) > maxEventIDLength {
+		return ErrInvalidEventID
+	}
+	for _, r := range id {
+		if !unicode.IsPrint(r) || unicode.IsSpace(r) {
+			return ErrInvalidEventID
+		}
+	}
+	return nil
+}
+
+// prepareEvent fills in a missing ID, timestamp, and version and validates
+// the ID and data
+func prepareEvent(event *Event) error {
+	if event.ID == "" {
+		event.ID = generateEventID()
+	}
+	if err := ValidateEventID(event.ID); err != nil {
+		return fmt.Errorf("invalid event %q: %w", event.ID, err)
+	}
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now()
+	}
+	if event.Version == "" {
+		event.Version = "1.0"
+	}
+	return ValidateEvent(*event)
+}
+
+// prepareBatch returns a prepared copy of a batch, refusing the whole batch
+// if any ID is invalid or repeated
+func prepareBatch(events []Event) ([]Event, error) {
+	prepared := make([]Event, len(events))
+	seen := make(map[string]bool, len(events))
+	for i, event := range events {
+		if err := prepareEvent(&event); err != nil {
+			return nil, err
+		}
+		if seen[event.ID] {
+			return nil, fmt.Errorf("invalid event %q: %w", event.ID, ErrDuplicateEventID)
+		}
+		seen[event.ID] = true
+		prepared[i] = event
+	}
+	return prepared, nil
+}
@@ -0,0 +1,595 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/tinle0301/streaming-platform-api/internal/tracing"
+)
+
+// BackendKafka selects the Kafka publisher and subscriber in the servers'
+// EVENT_BACKEND setting
+const BackendKafka = "kafka"
+
+// Kafka topic layouts
+const (
+	// KafkaTopicPerType publishes each event type to its own topic named
+	// "<topic>.<event type>", e.g. events.stream.live
+	KafkaTopicPerType = "per-type"
+
+	// KafkaSingleTopic publishes every event to one topic; consumers
+	// filter on the record's type header
+	KafkaSingleTopic = "single"
+)
+
+// Kafka consumer group rebalance strategies
+const (
+	KafkaRebalanceSticky     = "sticky"
+	KafkaRebalanceRange      = "range"
+	KafkaRebalanceRoundRobin = "roundrobin"
+)
+
+// DefaultKafkaTopic is the single topic, or the prefix of per-type topics
+const DefaultKafkaTopic = "events"
+
+// kafkaHeaderType is the record header carrying the event type
+const kafkaHeaderType = "type"
+
+// kafkaTopicPoll is how long a subscriber whose patterns match no topic
+// waits before looking again
+const kafkaTopicPoll = 30 * time.Second
+
+// KafkaOptions configures the brokers and topic layout the Kafka publisher
+// and subscriber share
+type KafkaOptions struct {
+	Brokers  []string
+	ClientID string
+
+	// Layout is KafkaTopicPerType or KafkaSingleTopic
+	Layout string
+
+	// Topic is the single topic, or the prefix of per-type topics
+	Topic string
+}
+
+// DefaultKafkaOptions returns options for per-type topics on brokers
+func DefaultKafkaOptions(brokers []string) KafkaOptions {
+	return KafkaOptions{
+		Brokers:  brokers,
+		ClientID: "streamhub",
+		Layout:   KafkaTopicPerType,
+		Topic:    DefaultKafkaTopic,
+	}
+}
+
+// topic returns the topic events of eventType are published to
+func (o KafkaOptions) topic(eventType string) string {
+	if o.Layout == KafkaSingleTopic {
+		return o.Topic
+	}
+	return o.Topic + "." + eventType
+}
+
+// KafkaPublisher implements Publisher using Kafka.
+//
+// Records are keyed by StreamID, so every event of a stream lands on the
+// same partition and is consumed in order; events without a stream are
+// spread across partitions. The producer is idempotent: retries after
+// broker failures never duplicate or reorder records.
+type KafkaPublisher struct {
+	client   sarama.Client
+	producer sarama.SyncProducer
+	opts     KafkaOptions
+}
+
+// NewKafkaPublisher creates a new Kafka event publisher
+func NewKafkaPublisher(opts KafkaOptions) (*KafkaPublisher, error) {
+	client, err := dialKafka(opts, newKafkaConfig(opts.ClientID))
+	if err != nil {
+		return nil, err
+	}
+
+	producer, err := sarama.NewSyncProducerFromClient(client)
+	if err != nil {
+		client.Close()
+		return nil, fmt.Errorf("failed to create Kafka producer: %w", err)
+	}
+
+	slog.Info("Connected to Kafka for event publishing", "layout", opts.Layout, "topic", opts.Topic)
+
+	return &KafkaPublisher{
+		client:   client,
+		producer: producer,
+		opts:     opts,
+	}, nil
+}
+
+// Publish sends a single event and waits for all in-sync replicas
+func (p *KafkaPublisher) Publish(ctx context.Context, event Event) error {
+	if err := prepareEvent(&event); err != nil {
+		return err
+	}
+	topic := p.opts.topic(event.Type)
+	_, span, event := startPublishSpan(ctx, event, systemKafka, topic)
+	defer span.End()
+
+	message, err := kafkaMessage(topic, event)
+	if err != nil {
+		return err
+	}
+
+	partition, offset, err := p.producer.SendMessage(message)
+	if err != nil {
+		span.RecordError(err)
+		return fmt.Errorf("failed to publish event to Kafka: %w", err)
+	}
+
+	slog.Debug("Published event", "type", event.Type, "id", event.ID, "topic", topic, "partition", partition, "offset", offset)
+	return nil
+}
+
+// PublishBatch sends multiple events in one request per broker
+func (p *KafkaPublisher) PublishBatch(ctx context.Context, events []Event) error {
+	events, err := prepareBatch(events)
+	if err != nil {
+		return err
+	}
+	_, span, events := startBatchSpan(ctx, events, systemKafka)
+	defer span.End()
+
+	messages := make([]*sarama.ProducerMessage, 0, len(events))
+	for _, event := range events {
+		message, err := kafkaMessage(p.opts.topic(event.Type), event)
+		if err != nil {
+			return err
+		}
+		messages = append(messages, message)
+	}
+
+	if err := p.producer.SendMessages(messages); err != nil {
+		span.RecordError(err)
+		return fmt.Errorf("failed to execute batch publish: %w", err)
+	}
+
+	slog.Debug("Published events in batch", "count", len(events))
+	return nil
+}
+
+// Ready reports whether the Kafka cluster is reachable
+func (p *KafkaPublisher) Ready(ctx context.Context) error {
+	return kafkaReady(p.client)
+}
+
+// Close flushes the producer and closes the Kafka connection
+func (p *KafkaPublisher) Close() error {
+	if err := p.producer.Close(); err != nil {
+		p.client.Close()
+		return err
+	}
+	return p.client.Close()
+}
+
+// kafkaMessage builds the record for a prepared event, with its type and
+// trace context in headers
+func kafkaMessage(topic string, event Event) (*sarama.ProducerMessage, error) {
+	eventBytes, err := json.Marshal(event)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal event: %w", err)
+	}
+
+	headers := []sarama.RecordHeader{{Key: []byte(kafkaHeaderType), Value: []byte(event.Type)}}
+	for _, key := range []string{tracing.TraceparentKey, tracing.TracestateKey} {
+		if value, ok := event.Metadata[key]; ok {
+			headers = append(headers, sarama.RecordHeader{Key: []byte(key), Value: []byte(value)})
+		}
+	}
+
+	message := &sarama.ProducerMessage{
+		Topic:     topic,
+		Value:     sarama.ByteEncoder(eventBytes),
+		Headers:   headers,
+		Timestamp: event.Timestamp,
+	}
+	if event.StreamID != "" {
+		message.Key = sarama.StringEncoder(event.StreamID)
+	}
+	return message, nil
+}
+
+// KafkaSubscriberOptions configures a Kafka consumer group member
+type KafkaSubscriberOptions struct {
+	KafkaOptions
+
+	// Group is the consumer group: members sharing a group split the
+	// topics' partitions, while each distinct group receives every event
+	Group string
+
+	// Durable groups outlive the subscriber; non-durable groups are
+	// deleted on Close
+	Durable bool
+
+	// FromOldest makes a new group start from the oldest retained events
+	// instead of new ones
+	FromOldest bool
+
+	// Rebalance is how partitions are assigned when members join or
+	// leave: sticky, range, or roundrobin
+	Rebalance string
+
+	// MaxRetries is how many times a failed event is retried, RetryDelay
+	// apart, before it is moved to the "<topic>.dlq" dead-letter topic
+	MaxRetries int
+	RetryDelay time.Duration
+}
+
+// DefaultKafkaSubscriberOptions returns options for a durable group
+func DefaultKafkaSubscriberOptions(kafka KafkaOptions, group string) KafkaSubscriberOptions {
+	return KafkaSubscriberOptions{
+		KafkaOptions: kafka,
+		Group:        group,
+		Durable:      true,
+		Rebalance:    KafkaRebalanceSticky,
+		MaxRetries:   3,
+		RetryDelay:   5 * time.Second,
+	}
+}
+
+// KafkaSubscriber implements Subscriber using a Kafka consumer group.
+//
+// Delivery is at least once: offsets are committed after the handler
+// succeeds. A failed event is retried in place, holding back its partition
+// so a stream's events stay in order, then dead-lettered. When members
+// join or leave, the group rebalances and each partition resumes from its
+// last committed offset on its new owner. Handlers must be idempotent.
+type KafkaSubscriber struct {
+	client   sarama.Client
+	group    sarama.ConsumerGroup
+	producer sarama.SyncProducer
+	opts     KafkaSubscriberOptions
+}
+
+// NewKafkaSubscriber creates a new Kafka event subscriber in its group
+func NewKafkaSubscriber(opts KafkaSubscriberOptions) (*KafkaSubscriber, error) {
+	strategy, err := kafkaBalanceStrategy(opts.Rebalance)
+	if err != nil {
+		return nil, err
+	}
+
+	config := newKafkaConfig(opts.ClientID)
+	config.Consumer.Group.Rebalance.GroupStrategies = []sarama.BalanceStrategy{strategy}
+	config.Consumer.Offsets.Initial = sarama.OffsetNewest
+	if opts.FromOldest {
+		config.Consumer.Offsets.Initial = sarama.OffsetOldest
+	}
+	config.Consumer.Return.Errors = true
+
+	client, err := dialKafka(opts.KafkaOptions, config)
+	if err != nil {
+		return nil, err
+	}
+
+	group, err := sarama.NewConsumerGroupFromClient(opts.Group, client)
+	if err != nil {
+		client.Close()
+		return nil, fmt.Errorf("failed to join Kafka consumer group: %w", err)
+	}
+
+	// Dead-lettered events are published with the subscriber's client
+	producer, err := sarama.NewSyncProducerFromClient(client)
+	if err != nil {
+		group.Close()
+		client.Close()
+		return nil, fmt.Errorf("failed to create Kafka producer: %w", err)
+	}
+
+	go func() {
+		for err := range group.Errors() {
+			slog.Warn("Kafka consumer group error", "group", opts.Group, "error", err)
+		}
+	}()
+
+	slog.Info("Connected to Kafka for event subscription", "group", opts.Group, "layout", opts.Layout, "rebalance", opts.Rebalance)
+
+	return &KafkaSubscriber{
+		client:   client,
+		group:    group,
+		producer: producer,
+		opts:     opts,
+	}, nil
+}
+
+// kafkaBalanceStrategy returns the rebalance strategy named name
+func kafkaBalanceStrategy(name string) (sarama.BalanceStrategy, error) {
+	switch name {
+	case KafkaRebalanceSticky, "":
+		return sarama.NewBalanceStrategySticky(), nil
+	case KafkaRebalanceRange:
+		return sarama.NewBalanceStrategyRange(), nil
+	case KafkaRebalanceRoundRobin:
+		return sarama.NewBalanceStrategyRoundRobin(), nil
+	default:
+		return nil, fmt.Errorf("unknown Kafka rebalance strategy: %s", name)
+	}
+}
+
+// Subscribe consumes events matching eventTypes until ctx is cancelled.
+// With per-type topics, wildcard patterns match the topics that exist when
+// the group joins or rebalances.
+func (s *KafkaSubscriber) Subscribe(ctx context.Context, eventTypes []string, handler Handler) error {
+	consumer := &kafkaGroupHandler{subscriber: s, eventTypes: eventTypes, handler: handler}
+
+	for {
+		topics, err := s.topics(eventTypes)
+		if err != nil {
+			return err
+		}
+		if len(topics) == 0 {
+			slog.Info("No Kafka topics match yet, waiting", "group", s.opts.Group, "types", eventTypes)
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(kafkaTopicPoll):
+			}
+			continue
+		}
+
+		slog.Info("Subscribed to Kafka events", "group", s.opts.Group, "topics", topics)
+
+		// Consume returns whenever the group rebalances; join again
+		err = s.group.Consume(ctx, topics, consumer)
+		if ctx.Err() != nil || errors.Is(err, sarama.ErrClosedConsumerGroup) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to consume from Kafka: %w", err)
+		}
+	}
+}
+
+// topics returns the topics carrying eventTypes
+func (s *KafkaSubscriber) topics(eventTypes []string) ([]string, error) {
+	if s.opts.Layout == KafkaSingleTopic {
+		return []string{s.opts.Topic}, nil
+	}
+
+	seen := make(map[string]bool)
+	var topics []string
+	add := func(topic string) {
+		if !seen[topic] {
+			seen[topic] = true
+			topics = append(topics, topic)
+		}
+	}
+
+	wildcard := false
+	for _, eventType := range eventTypes {
+		if strings.Contains(eventType, "*") {
+			wildcard = true
+			continue
+		}
+		add(s.opts.topic(eventType))
+	}
+	if !wildcard {
+		return topics, nil
+	}
+
+	if err := s.client.RefreshMetadata(); err != nil {
+		return nil, fmt.Errorf("failed to refresh Kafka metadata: %w", err)
+	}
+	existing, err := s.client.Topics()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list Kafka topics: %w", err)
+	}
+	for _, topic := range existing {
+		eventType, ok := strings.CutPrefix(topic, s.opts.Topic+".")
+		if ok && !strings.HasSuffix(topic, ".dlq") && matchesAny(eventTypes, eventType) {
+			add(topic)
+		}
+	}
+	return topics, nil
+}
+
+// eventType returns a record's event type from its header, or from its
+// topic when the header is missing
+func (s *KafkaSubscriber) eventType(message *sarama.ConsumerMessage, headers map[string]interface{}) string {
+	if eventType, _ := headers[kafkaHeaderType].(string); eventType != "" {
+		return eventType
+	}
+	if s.opts.Layout == KafkaSingleTopic {
+		return ""
+	}
+	return strings.TrimPrefix(message.Topic, s.opts.Topic+".")
+}
+
+// handleMessage runs the handler, retrying failures in place, and
+// dead-letters events that keep failing. An error leaves the record
+// uncommitted so it is redelivered.
+func (s *KafkaSubscriber) handleMessage(ctx context.Context, message *sarama.ConsumerMessage, eventTypes []string, handler Handler) error {
+	headers := kafkaHeaders(message.Headers)
+	if !matchesAny(eventTypes, s.eventType(message, headers)) {
+		return nil
+	}
+
+	var event Event
+	if err := json.Unmarshal(message.Value, &event); err != nil {
+		// Malformed events can never succeed: dead-letter immediately
+		slog.Warn("Error unmarshaling event, dead-lettering", "topic", message.Topic, "partition", message.Partition, "offset", message.Offset, "error", err)
+		return s.deadLetter(message, 0, err)
+	}
+
+	for attempt := 0; ; attempt++ {
+		err := handleTraced(ctx, event, systemKafka, headers, handler)
+		if err == nil {
+			return nil
+		}
+		if attempt >= s.opts.MaxRetries {
+			slog.Warn("Event failed, dead-lettering", "attempts", attempt+1, "type", event.Type, "id", event.ID, "error", err)
+			return s.deadLetter(message, attempt+1, err)
+		}
+
+		slog.Warn("Event failed, retrying", "retry", attempt+1, "max_retries", s.opts.MaxRetries, "type", event.Type, "id", event.ID, "error", err)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(s.opts.RetryDelay):
+		}
+	}
+}
+
+// deadLetter copies a record to its topic's dead-letter topic with the
+// group, delivery count, and failure in headers
+func (s *KafkaSubscriber) deadLetter(message *sarama.ConsumerMessage, deliveries int, cause error) error {
+	headers := make([]sarama.RecordHeader, 0, len(message.Headers)+3)
+	for _, header := range message.Headers {
+		headers = append(headers, *header)
+	}
+	headers = append(headers,
+		sarama.RecordHeader{Key: []byte("group"), Value: []byte(s.opts.Group)},
+		sarama.RecordHeader{Key: []byte(retryCountHeader), Value: []byte(strconv.Itoa(deliveries))},
+		sarama.RecordHeader{Key: []byte("error"), Value: []byte(cause.Error())},
+	)
+
+	dlq := &sarama.ProducerMessage{
+		Topic:   message.Topic + ".dlq",
+		Value:   sarama.ByteEncoder(message.Value),
+		Headers: headers,
+	}
+	if message.Key != nil {
+		dlq.Key = sarama.ByteEncoder(message.Key)
+	}
+	if _, _, err := s.producer.SendMessage(dlq); err != nil {
+		// Leave it uncommitted rather than lose it
+		return fmt.Errorf("failed to dead-letter event: %w", err)
+	}
+
+	slog.Warn("Event dead-lettered", "topic", dlq.Topic, "offset", message.Offset, "deliveries", deliveries)
+	return nil
+}
+
+// Ready reports whether the Kafka cluster is reachable
+func (s *KafkaSubscriber) Ready(ctx context.Context) error {
+	return kafkaReady(s.client)
+}
+
+// Close leaves the consumer group, deleting it if it is not durable, and
+// closes the Kafka connection
+func (s *KafkaSubscriber) Close() error {
+	err := s.group.Close()
+	if !s.opts.Durable {
+		// Closing the admin would close the shared client, so it is left open
+		admin, adminErr := sarama.NewClusterAdminFromClient(s.client)
+		if adminErr == nil {
+			adminErr = admin.DeleteConsumerGroup(s.opts.Group)
+		}
+		if adminErr != nil {
+			slog.Warn("Error deleting consumer group", "group", s.opts.Group, "error", adminErr)
+		}
+	}
+	s.producer.Close()
+	if closeErr := s.client.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// kafkaGroupHandler consumes the partitions assigned to a group member
+type kafkaGroupHandler struct {
+	subscriber *KafkaSubscriber
+	eventTypes []string
+	handler    Handler
+}
+
+// Setup runs when the member is assigned partitions after a rebalance
+func (h *kafkaGroupHandler) Setup(session sarama.ConsumerGroupSession) error {
+	slog.Info("Kafka partitions assigned", "group", h.subscriber.opts.Group,
+		"generation", session.GenerationID(), "claims", session.Claims())
+	return nil
+}
+
+// Cleanup runs when the member's partitions are revoked
+func (h *kafkaGroupHandler) Cleanup(session sarama.ConsumerGroupSession) error {
+	slog.Info("Kafka partitions revoked", "group", h.subscriber.opts.Group, "generation", session.GenerationID())
+	return nil
+}
+
+// ConsumeClaim handles one partition's records in order, committing each
+// after it is handled. Returning an error ends the session, so the group
+// rejoins and redelivers from the last committed offset.
+func (h *kafkaGroupHandler) ConsumeClaim(session sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
+	ctx := session.Context()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case message, ok := <-claim.Messages():
+			if !ok {
+				return nil
+			}
+			if err := h.subscriber.handleMessage(ctx, message, h.eventTypes, h.handler); err != nil {
+				if ctx.Err() != nil {
+					return nil
+				}
+				return err
+			}
+			session.MarkMessage(message, "")
+		}
+	}
+}
+
+// kafkaHeaders returns record headers as a map of strings
+func kafkaHeaders(headers []*sarama.RecordHeader) map[string]interface{} {
+	values := make(map[string]interface{}, len(headers))
+	for _, header := range headers {
+		values[string(header.Key)] = string(header.Value)
+	}
+	return values
+}
+
+// newKafkaConfig returns client settings for an idempotent producer:
+// records are acknowledged by all in-sync replicas, and the broker drops
+// duplicates and rejects reordering caused by retries
+func newKafkaConfig(clientID string) *sarama.Config {
+	config := sarama.NewConfig()
+	config.ClientID = clientID
+	config.Version = sarama.V2_1_0_0
+	config.Producer.Idempotent = true
+	config.Producer.RequiredAcks = sarama.WaitForAll
+	config.Producer.Retry.Max = 5
+	config.Producer.Return.Successes = true
+	config.Net.MaxOpenRequests = 1
+	return config
+}
+
+// dialKafka connects to the brokers
+func dialKafka(opts KafkaOptions, config *sarama.Config) (sarama.Client, error) {
+	if len(opts.Brokers) == 0 {
+		return nil, fmt.Errorf("no Kafka brokers configured")
+	}
+	client, err := sarama.NewClient(opts.Brokers, config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to Kafka: %w", err)
+	}
+	return client, nil
+}
+
+// errKafkaClosed is returned by readiness checks once the client is closed
+var errKafkaClosed = errors.New("Kafka client closed")
+
+// kafkaReady reports whether a Kafka client is open and can reach the
+// cluster's controller
+func kafkaReady(client sarama.Client) error {
+	if client.Closed() {
+		return errKafkaClosed
+	}
+	if _, err := client.Controller(); err != nil {
+		return fmt.Errorf("failed to reach Kafka: %w", err)
+	}
+	return nil
+}
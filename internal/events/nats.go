@@ -0,0 +1,555 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/tinle0301/streaming-platform-api/internal/tracing"
+)
+
+// BackendNATS selects the NATS JetStream publisher and subscriber in the
+// servers' EVENT_BACKEND setting
+const BackendNATS = "nats"
+
+// NATS stream retention policies
+const (
+	// NATSRetentionLimits keeps events until MaxAge or MaxBytes is reached,
+	// whether or not they were consumed
+	NATSRetentionLimits = "limits"
+
+	// NATSRetentionInterest drops events once every consumer that existed
+	// when they were published has acknowledged them
+	NATSRetentionInterest = "interest"
+)
+
+// Defaults for the JetStream stream events are published to
+const (
+	DefaultNATSStream  = "EVENTS"
+	DefaultNATSSubject = "events"
+)
+
+// natsDuplicateWindow is how long JetStream remembers message IDs to drop
+// duplicate publishes
+const natsDuplicateWindow = 2 * time.Minute
+
+// natsInactiveThreshold is how long the server keeps a non-durable
+// consumer whose subscriber went away without closing
+const natsInactiveThreshold = 5 * time.Minute
+
+// NATSOptions configures the connection and the JetStream stream the NATS
+// publisher and subscriber share
+type NATSOptions struct {
+	URL  string
+	Name string
+
+	// Stream is the JetStream stream capturing "<subject>.>"
+	Stream string
+
+	// Subject prefixes event types: stream.live is published to
+	// events.stream.live
+	Subject string
+
+	// Retention is NATSRetentionLimits or NATSRetentionInterest. Either
+	// way events older than MaxAge, or beyond MaxBytes, are discarded;
+	// zero means unlimited.
+	Retention string
+	MaxAge    time.Duration
+	MaxBytes  int64
+
+	// Replicas is how many servers store each event, at most 5
+	Replicas int
+
+	// ConnectAttempts is how many times the first connection is tried,
+	// ReconnectWait apart. Once connected, a dropped connection is retried
+	// every ReconnectWait until it is restored.
+	ConnectAttempts int
+	ReconnectWait   time.Duration
+}
+
+// DefaultNATSOptions returns options for a single-replica stream keeping a
+// day of events
+func DefaultNATSOptions(url string) NATSOptions {
+	return NATSOptions{
+		URL:             url,
+		Name:            "streamhub",
+		Stream:          DefaultNATSStream,
+		Subject:         DefaultNATSSubject,
+		Retention:       NATSRetentionLimits,
+		MaxAge:          24 * time.Hour,
+		Replicas:        1,
+		ConnectAttempts: 5,
+		ReconnectWait:   2 * time.Second,
+	}
+}
+
+// subject returns the subject events of eventType are published to. Event
+// type patterns map to subject wildcards, since "*" matches one word in both.
+func (o NATSOptions) subject(eventType string) string {
+	return o.Subject + "." + eventType
+}
+
+// deadLetterStream returns the stream failed events are moved to
+func (o NATSOptions) deadLetterStream() string {
+	return o.Stream + "_DLQ"
+}
+
+// streamConfig returns the configuration of the events stream
+func (o NATSOptions) streamConfig() jetstream.StreamConfig {
+	retention := jetstream.LimitsPolicy
+	if o.Retention == NATSRetentionInterest {
+		retention = jetstream.InterestPolicy
+	}
+	maxBytes := o.MaxBytes
+	if maxBytes <= 0 {
+		maxBytes = -1
+	}
+	// The duplicate window cannot outlast the events themselves
+	duplicates := natsDuplicateWindow
+	if o.MaxAge > 0 && o.MaxAge < duplicates {
+		duplicates = o.MaxAge
+	}
+	return jetstream.StreamConfig{
+		Name:      o.Stream,
+		Subjects:  []string{o.Subject + ".>"},
+		Retention: retention,
+		MaxAge:    o.MaxAge,
+		MaxBytes:  maxBytes,
+		MaxMsgs:   -1,
+		Storage:   jetstream.FileStorage,
+		Replicas:  o.Replicas,
+		// Publishes retried within the window are dropped by event ID
+		Duplicates: duplicates,
+	}
+}
+
+// NATSPublisher implements Publisher using NATS JetStream.
+//
+// Each event is published to "<subject>.<event type>" with its ID as the
+// message ID, so JetStream drops duplicates of a publish retried within
+// two minutes.
+type NATSPublisher struct {
+	conn *nats.Conn
+	js   jetstream.JetStream
+	opts NATSOptions
+}
+
+// NewNATSPublisher creates a new NATS event publisher, creating or
+// updating the events stream
+func NewNATSPublisher(opts NATSOptions) (*NATSPublisher, error) {
+	conn, js, err := dialNATS(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := ensureNATSStream(js, opts.streamConfig()); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	slog.Info("Connected to NATS for event publishing", "stream", opts.Stream, "subject", opts.Subject)
+
+	return &NATSPublisher{
+		conn: conn,
+		js:   js,
+		opts: opts,
+	}, nil
+}
+
+// Publish sends a single event and waits for JetStream to store it
+func (p *NATSPublisher) Publish(ctx context.Context, event Event) error {
+	if err := prepareEvent(&event); err != nil {
+		return err
+	}
+	subject := p.opts.subject(event.Type)
+	ctx, span, event := startPublishSpan(ctx, event, systemNATS, subject)
+	defer span.End()
+
+	message, err := natsMessage(subject, event)
+	if err != nil {
+		return err
+	}
+
+	ack, err := p.js.PublishMsg(ctx, message, jetstream.WithMsgID(event.ID))
+	if err != nil {
+		span.RecordError(err)
+		return fmt.Errorf("failed to publish event to NATS: %w", err)
+	}
+
+	slog.Debug("Published event", "type", event.Type, "id", event.ID, "subject", subject, "sequence", ack.Sequence, "duplicate", ack.Duplicate)
+	return nil
+}
+
+// PublishBatch sends multiple events without waiting between them, then
+// waits for JetStream to store them all
+func (p *NATSPublisher) PublishBatch(ctx context.Context, events []Event) error {
+	events, err := prepareBatch(events)
+	if err != nil {
+		return err
+	}
+	_, span, events := startBatchSpan(ctx, events, systemNATS)
+	defer span.End()
+
+	futures := make([]jetstream.PubAckFuture, 0, len(events))
+	for _, event := range events {
+		message, err := natsMessage(p.opts.subject(event.Type), event)
+		if err != nil {
+			return err
+		}
+		future, err := p.js.PublishMsgAsync(message, jetstream.WithMsgID(event.ID))
+		if err != nil {
+			span.RecordError(err)
+			return fmt.Errorf("failed to execute batch publish: %w", err)
+		}
+		futures = append(futures, future)
+	}
+
+	for _, future := range futures {
+		select {
+		case <-future.Ok():
+		case err := <-future.Err():
+			span.RecordError(err)
+			return fmt.Errorf("failed to execute batch publish: %w", err)
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	slog.Debug("Published events in batch", "count", len(events))
+	return nil
+}
+
+// Ready reports whether NATS is connected and JetStream answers
+func (p *NATSPublisher) Ready(ctx context.Context) error {
+	return natsReady(ctx, p.conn, p.js)
+}
+
+// Close waits for pending publishes and closes the NATS connection
+func (p *NATSPublisher) Close() error {
+	return p.conn.Drain()
+}
+
+// natsMessage builds the message for a prepared event, with its trace
+// context in headers
+func natsMessage(subject string, event Event) (*nats.Msg, error) {
+	eventBytes, err := json.Marshal(event)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal event: %w", err)
+	}
+
+	message := nats.NewMsg(subject)
+	message.Data = eventBytes
+	for _, key := range []string{tracing.TraceparentKey, tracing.TracestateKey} {
+		if value, ok := event.Metadata[key]; ok {
+			message.Header.Set(key, value)
+		}
+	}
+	return message, nil
+}
+
+// NATSSubscriberOptions configures a JetStream consumer
+type NATSSubscriberOptions struct {
+	NATSOptions
+
+	// Consumer names the JetStream consumer: subscribers sharing it split
+	// its events, while each distinct consumer receives every event
+	Consumer string
+
+	// Durable consumers outlive the subscriber; non-durable consumers are
+	// deleted on Close, or by the server once unused for five minutes
+	Durable bool
+
+	// FromOldest makes a new consumer start from the oldest retained
+	// events instead of new ones
+	FromOldest bool
+
+	// MaxRetries is how many times a failed event is redelivered,
+	// RetryDelay apart, before it is moved to the "<stream>_DLQ" stream
+	MaxRetries int
+	RetryDelay time.Duration
+}
+
+// DefaultNATSSubscriberOptions returns options for a durable consumer
+func DefaultNATSSubscriberOptions(opts NATSOptions, consumer string) NATSSubscriberOptions {
+	return NATSSubscriberOptions{
+		NATSOptions: opts,
+		Consumer:    consumer,
+		Durable:     true,
+		MaxRetries:  3,
+		RetryDelay:  5 * time.Second,
+	}
+}
+
+// consumerName returns Consumer with the characters JetStream forbids in
+// consumer names replaced, so ws-server.<node> becomes ws-server_<node>
+func (o NATSSubscriberOptions) consumerName() string {
+	return strings.NewReplacer(".", "_", "*", "_", ">", "_", " ", "_").Replace(o.Consumer)
+}
+
+// NATSSubscriber implements Subscriber using a JetStream pull consumer.
+//
+// Delivery is at least once: events are acknowledged after the handler
+// succeeds. A failed event is redelivered after RetryDelay while later
+// events carry on, so retried events may be handled out of order, then
+// dead-lettered. Handlers must be idempotent.
+type NATSSubscriber struct {
+	conn *nats.Conn
+	js   jetstream.JetStream
+	opts NATSSubscriberOptions
+}
+
+// NewNATSSubscriber creates a new NATS event subscriber, creating or
+// updating the events and dead-letter streams
+func NewNATSSubscriber(opts NATSSubscriberOptions) (*NATSSubscriber, error) {
+	conn, js, err := dialNATS(opts.NATSOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	// Dead letters are kept whatever the events stream's retention, since
+	// nothing consumes them
+	dlq := opts.streamConfig()
+	dlq.Name = opts.deadLetterStream()
+	dlq.Subjects = []string{"dlq." + opts.Subject + ".>"}
+	dlq.Retention = jetstream.LimitsPolicy
+	for _, config := range []jetstream.StreamConfig{opts.streamConfig(), dlq} {
+		if err := ensureNATSStream(js, config); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+
+	slog.Info("Connected to NATS for event subscription", "stream", opts.Stream, "consumer", opts.consumerName(), "durable", opts.Durable)
+
+	return &NATSSubscriber{
+		conn: conn,
+		js:   js,
+		opts: opts,
+	}, nil
+}
+
+// Subscribe consumes events matching eventTypes until ctx is cancelled.
+// The consumer's subject filters are replaced with eventTypes, so each
+// subscriber subscribes once.
+func (s *NATSSubscriber) Subscribe(ctx context.Context, eventTypes []string, handler Handler) error {
+	consumer, err := s.js.CreateOrUpdateConsumer(ctx, s.opts.Stream, s.consumerConfig(eventTypes))
+	if err != nil {
+		return fmt.Errorf("failed to create NATS consumer: %w", err)
+	}
+
+	consuming, err := consumer.Consume(func(message jetstream.Msg) {
+		s.handleMessage(ctx, message, handler)
+	}, jetstream.ConsumeErrHandler(func(_ jetstream.ConsumeContext, err error) {
+		slog.Warn("NATS consumer error", "consumer", s.opts.consumerName(), "error", err)
+	}))
+	if err != nil {
+		return fmt.Errorf("failed to consume from NATS: %w", err)
+	}
+
+	slog.Info("Subscribed to NATS events", "consumer", s.opts.consumerName(), "types", eventTypes)
+
+	<-ctx.Done()
+	consuming.Stop()
+	return nil
+}
+
+// consumerConfig returns the consumer's configuration filtered to the
+// subjects of eventTypes
+func (s *NATSSubscriber) consumerConfig(eventTypes []string) jetstream.ConsumerConfig {
+	config := jetstream.ConsumerConfig{
+		AckPolicy:     jetstream.AckExplicitPolicy,
+		DeliverPolicy: jetstream.DeliverNewPolicy,
+		// Redeliveries are counted and dead-lettered here rather than
+		// dropped by the server
+		MaxDeliver: -1,
+	}
+	if s.opts.FromOldest {
+		config.DeliverPolicy = jetstream.DeliverAllPolicy
+	}
+	if s.opts.Durable {
+		config.Durable = s.opts.consumerName()
+	} else {
+		config.Name = s.opts.consumerName()
+		config.InactiveThreshold = natsInactiveThreshold
+	}
+
+	// JetStream rejects overlapping filters, so drop patterns another
+	// pattern already covers
+	for i, eventType := range eventTypes {
+		covered := false
+		for j, other := range eventTypes {
+			if i != j && matchEventType(other, eventType) && (eventType != other || j < i) {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			config.FilterSubjects = append(config.FilterSubjects, s.opts.subject(eventType))
+		}
+	}
+	return config
+}
+
+// handleMessage runs the handler, acknowledging events it handled,
+// scheduling redelivery of those it failed, and dead-lettering events that
+// keep failing
+func (s *NATSSubscriber) handleMessage(ctx context.Context, message jetstream.Msg, handler Handler) {
+	var event Event
+	if err := json.Unmarshal(message.Data(), &event); err != nil {
+		// Malformed events can never succeed: dead-letter immediately
+		slog.Warn("Error unmarshaling event, dead-lettering", "subject", message.Subject(), "error", err)
+		s.deadLetter(message, 1, err)
+		return
+	}
+
+	err := handleTraced(ctx, event, systemNATS, natsHeaders(message.Headers()), handler)
+	if err == nil {
+		if err := message.Ack(); err != nil {
+			slog.Warn("Error acknowledging event", "type", event.Type, "id", event.ID, "error", err)
+		}
+		return
+	}
+
+	deliveries := 1
+	if metadata, metaErr := message.Metadata(); metaErr == nil {
+		deliveries = int(metadata.NumDelivered)
+	}
+	if deliveries > s.opts.MaxRetries {
+		slog.Warn("Event failed, dead-lettering", "attempts", deliveries, "type", event.Type, "id", event.ID, "error", err)
+		s.deadLetter(message, deliveries, err)
+		return
+	}
+
+	slog.Warn("Event failed, scheduling retry", "retry", deliveries, "max_retries", s.opts.MaxRetries, "type", event.Type, "id", event.ID, "error", err)
+	if err := message.NakWithDelay(s.opts.RetryDelay); err != nil {
+		slog.Warn("Error scheduling event retry", "type", event.Type, "id", event.ID, "error", err)
+	}
+}
+
+// deadLetter copies a message to the dead-letter stream with the consumer,
+// delivery count, and failure in headers, then stops its redelivery
+func (s *NATSSubscriber) deadLetter(message jetstream.Msg, deliveries int, cause error) {
+	dlq := nats.NewMsg("dlq." + message.Subject())
+	dlq.Data = message.Data()
+	for key, values := range message.Headers() {
+		dlq.Header[key] = values
+	}
+	dlq.Header.Set("consumer", s.opts.consumerName())
+	dlq.Header.Set(retryCountHeader, strconv.Itoa(deliveries))
+	dlq.Header.Set("error", cause.Error())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := s.js.PublishMsg(ctx, dlq); err != nil {
+		// Leave it to be redelivered rather than lose it
+		slog.Warn("Error dead-lettering event", "subject", message.Subject(), "error", err)
+		message.NakWithDelay(s.opts.RetryDelay)
+		return
+	}
+	if err := message.Term(); err != nil {
+		slog.Warn("Error terminating dead-lettered event", "subject", message.Subject(), "error", err)
+	}
+
+	slog.Warn("Event dead-lettered", "subject", dlq.Subject, "deliveries", deliveries)
+}
+
+// Ready reports whether NATS is connected and JetStream answers
+func (s *NATSSubscriber) Ready(ctx context.Context) error {
+	return natsReady(ctx, s.conn, s.js)
+}
+
+// Close deletes the consumer if it is not durable and closes the NATS
+// connection
+func (s *NATSSubscriber) Close() error {
+	if !s.opts.Durable {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		err := s.js.DeleteConsumer(ctx, s.opts.Stream, s.opts.consumerName())
+		cancel()
+		if err != nil && !errors.Is(err, jetstream.ErrConsumerNotFound) {
+			slog.Warn("Error deleting NATS consumer", "consumer", s.opts.consumerName(), "error", err)
+		}
+	}
+	return s.conn.Drain()
+}
+
+// natsHeaders returns message headers as a map of strings
+func natsHeaders(headers nats.Header) map[string]interface{} {
+	values := make(map[string]interface{}, len(headers))
+	for key := range headers {
+		values[key] = headers.Get(key)
+	}
+	return values
+}
+
+// dialNATS connects to NATS, retrying the first connection up to
+// ConnectAttempts times and reconnecting without limit after that
+func dialNATS(opts NATSOptions) (*nats.Conn, jetstream.JetStream, error) {
+	if opts.URL == "" {
+		return nil, nil, fmt.Errorf("no NATS URL configured")
+	}
+
+	options := []nats.Option{
+		nats.Name(opts.Name),
+		nats.MaxReconnects(-1),
+		nats.ReconnectWait(opts.ReconnectWait),
+		nats.DisconnectErrHandler(func(_ *nats.Conn, err error) {
+			slog.Warn("Disconnected from NATS, reconnecting", "error", err)
+		}),
+		nats.ReconnectHandler(func(conn *nats.Conn) {
+			slog.Info("Reconnected to NATS", "server", conn.ConnectedUrlRedacted())
+		}),
+	}
+
+	var conn *nats.Conn
+	var err error
+	for attempt := 1; ; attempt++ {
+		if conn, err = nats.Connect(opts.URL, options...); err == nil {
+			break
+		}
+		if attempt >= opts.ConnectAttempts {
+			return nil, nil, fmt.Errorf("failed to connect to NATS: %w", err)
+		}
+		slog.Warn("Failed to connect to NATS, retrying", "attempt", attempt, "max_attempts", opts.ConnectAttempts, "error", err)
+		time.Sleep(opts.ReconnectWait)
+	}
+
+	js, err := jetstream.New(conn)
+	if err != nil {
+		conn.Close()
+		return nil, nil, fmt.Errorf("failed to create JetStream context: %w", err)
+	}
+	return conn, js, nil
+}
+
+// ensureNATSStream creates a stream, or updates its subjects and
+// retention limits if it exists
+func ensureNATSStream(js jetstream.JetStream, config jetstream.StreamConfig) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if _, err := js.CreateOrUpdateStream(ctx, config); err != nil {
+		return fmt.Errorf("failed to create NATS stream %s: %w", config.Name, err)
+	}
+	return nil
+}
+
+// errNATSDisconnected is returned by readiness checks while the connection
+// is down or closed
+var errNATSDisconnected = errors.New("NATS connection is not connected")
+
+// natsReady reports whether a NATS connection is up and JetStream answers
+func natsReady(ctx context.Context, conn *nats.Conn, js jetstream.JetStream) error {
+	if !conn.IsConnected() {
+		return errNATSDisconnected
+	}
+	if _, err := js.AccountInfo(ctx); err != nil {
+		return fmt.Errorf("failed to reach JetStream: %w", err)
+	}
+	return nil
+}
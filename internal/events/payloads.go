@@ -0,0 +1,305 @@
+package events
+
+import "time"
+
+// StreamInfoPayload is the data of stream.live and stream.updated events
+type StreamInfoPayload struct {
+	Title          string   `json:"title"`
+	Category       string   `json:"category"`
+	Tags           []string `json:"tags,omitempty"`
+	Language       string   `json:"language,omitempty"`
+	IsMature       bool     `json:"is_mature,omitempty"`
+	BrandedContent bool     `json:"branded_content,omitempty"`
+}
+
+// StreamOfflinePayload is the data of stream.offline events
+type StreamOfflinePayload struct {
+	DurationSeconds int64 `json:"duration_seconds,omitempty"`
+}
+
+// FollowerPayload is the data of user.new_follower events
+type FollowerPayload struct {
+	FollowerID          string `json:"follower_id"`
+	FollowedID          string `json:"followed_id"`
+	FollowerUsername    string `json:"follower_username,omitempty"`
+	FollowerDisplayName string `json:"follower_display_name,omitempty"`
+}
+
+// ChatMessagePayload is the data of chat.message events
+type ChatMessagePayload struct {
+	Message string `json:"message"`
+}
+
+// RaidPayload is the data of raid.outgoing and raid.incoming events
+type RaidPayload struct {
+	RaidID           string `json:"raid_id"`
+	FromStreamID     string `json:"from_stream_id"`
+	FromStreamerID   string `json:"from_streamer_id"`
+	FromTitle        string `json:"from_title"`
+	ToStreamID       string `json:"to_stream_id"`
+	ToStreamerID     string `json:"to_streamer_id"`
+	ToTitle          string `json:"to_title"`
+	ViewerCount      int    `json:"viewer_count"`
+	CountdownSeconds int64  `json:"countdown_seconds"`
+	LandsAt          int64  `json:"lands_at"`
+}
+
+// SubscriptionPayload is the data of subscription.new events
+type SubscriptionPayload struct {
+	ChannelID             string `json:"channel_id"`
+	SubscriptionID        string `json:"subscription_id"`
+	SubscriberID          string `json:"subscriber_id"`
+	SubscriberUsername    string `json:"subscriber_username"`
+	SubscriberDisplayName string `json:"subscriber_display_name"`
+	Tier                  string `json:"tier"`
+	Months                int    `json:"months"`
+}
+
+// GiftSubscriptionPayload is the data of subscription.gift events
+type GiftSubscriptionPayload struct {
+	ChannelID            string `json:"channel_id"`
+	SubscriptionID       string `json:"subscription_id"`
+	GifterID             string `json:"gifter_id"`
+	GifterUsername       string `json:"gifter_username"`
+	GifterDisplayName    string `json:"gifter_display_name"`
+	RecipientID          string `json:"recipient_id"`
+	RecipientUsername    string `json:"recipient_username"`
+	RecipientDisplayName string `json:"recipient_display_name"`
+	Tier                 string `json:"tier"`
+	Count                int    `json:"count"`
+}
+
+// BitsCheeredPayload is the data of bits.cheered events
+type BitsCheeredPayload struct {
+	ChannelID          string `json:"channel_id"`
+	CheerID            string `json:"cheer_id"`
+	CheererID          string `json:"cheerer_id"`
+	CheererUsername    string `json:"cheerer_username"`
+	CheererDisplayName string `json:"cheerer_display_name"`
+	Bits               int    `json:"bits"`
+	Message            string `json:"message"`
+	Animation          string `json:"animation"`
+}
+
+// StreamMilestonePayload is the data of stream.milestone events
+type StreamMilestonePayload struct {
+	ChannelID   string `json:"channel_id"`
+	StreamID    string `json:"stream_id,omitempty"`
+	MilestoneID string `json:"milestone_id"`
+	Kind        string `json:"kind"`
+	Threshold   int64  `json:"threshold"`
+	Value       int64  `json:"value"`
+	Message     string `json:"message"`
+}
+
+// WatchProgressPayload is the data of watch.progress events
+type WatchProgressPayload struct {
+	WatchedSeconds int64 `json:"watched_seconds"`
+}
+
+// RewardClaimedPayload is the data of campaign.reward_claimed events
+type RewardClaimedPayload struct {
+	ClaimID         string    `json:"claim_id"`
+	CampaignID      string    `json:"campaign_id"`
+	RewardID        string    `json:"reward_id"`
+	RewardName      string    `json:"reward_name"`
+	RequiredMinutes int       `json:"required_minutes"`
+	ClaimedAt       time.Time `json:"claimed_at"`
+}
+
+// ClipPublishedPayload is the data of clip.published events
+type ClipPublishedPayload struct {
+	ClipID          string  `json:"clip_id"`
+	Title           string  `json:"title"`
+	StartMS         int64   `json:"start_ms"`
+	EndMS           int64   `json:"end_ms"`
+	DurationSeconds float64 `json:"duration_seconds"`
+}
+
+// RenderSegment is a range of a broadcast to render into a VOD
+type RenderSegment struct {
+	StartMS int64 `json:"start_ms"`
+	EndMS   int64 `json:"end_ms"`
+}
+
+// VODRenderRequestedPayload is the data of vod.render_requested events
+type VODRenderRequestedPayload struct {
+	VODID    string          `json:"vod_id"`
+	Kind     string          `json:"kind"`
+	Segments []RenderSegment `json:"segments"`
+}
+
+// NotificationPayload is the data of notification.created events
+type NotificationPayload struct {
+	ID         string                 `json:"id"`
+	Type       string                 `json:"type"`
+	Title      string                 `json:"title"`
+	Message    string                 `json:"message"`
+	Data       map[string]interface{} `json:"data"`
+	CreatedAt  string                 `json:"created_at"`
+	FromUserID string                 `json:"from_user_id,omitempty"`
+	StreamID   string                 `json:"stream_id,omitempty"`
+}
+
+// CaptionSegmentPayload is the data of caption.segment events
+type CaptionSegmentPayload struct {
+	CaptionID string `json:"caption_id"`
+	Language  string `json:"language"`
+	StartMS   int64  `json:"start_ms"`
+	EndMS     int64  `json:"end_ms"`
+	Text      string `json:"text"`
+}
+
+// PartySyncPayload is the data of party.sync events
+type PartySyncPayload struct {
+	PartyID    string  `json:"party_id"`
+	HostID     string  `json:"host_id"`
+	PositionMS int64   `json:"position_ms"`
+	Playing    bool    `json:"playing"`
+	Rate       float64 `json:"rate"`
+	UpdatedAt  int64   `json:"updated_at"`
+}
+
+// PartyChatMessagePayload is the data of party.chat_message events
+type PartyChatMessagePayload struct {
+	PartyID string `json:"party_id"`
+	Message string `json:"message"`
+}
+
+// PartyEndedPayload is the data of party.ended events
+type PartyEndedPayload struct {
+	PartyID string `json:"party_id"`
+}
+
+// PremiereCountdownPayload is the data of premiere.countdown events
+type PremiereCountdownPayload struct {
+	VODID       string `json:"vod_id"`
+	ScheduledAt string `json:"scheduled_at"`
+	RemainingMS int64  `json:"remaining_ms"`
+}
+
+// PremiereStartedPayload is the data of premiere.started events
+type PremiereStartedPayload struct {
+	VODID      string `json:"vod_id"`
+	StartedAt  string `json:"started_at"`
+	PositionMS int64  `json:"position_ms"`
+	DurationMS int64  `json:"duration_ms"`
+}
+
+// PremiereSyncPayload is the data of premiere.sync events
+type PremiereSyncPayload struct {
+	VODID      string `json:"vod_id"`
+	PositionMS int64  `json:"position_ms"`
+	DurationMS int64  `json:"duration_ms"`
+	ServerTime string `json:"server_time"`
+}
+
+// PremiereEndedPayload is the data of premiere.ended events
+type PremiereEndedPayload struct {
+	VODID     string `json:"vod_id"`
+	Cancelled bool   `json:"cancelled"`
+}
+
+// CommunityChatOpenedPayload is the data of community.opened events
+type CommunityChatOpenedPayload struct {
+	ChannelID       string `json:"channel_id"`
+	SlowModeSeconds int64  `json:"slow_mode_seconds"`
+}
+
+// CommunityChatClosedPayload is the data of community.closed events
+type CommunityChatClosedPayload struct {
+	ChannelID string `json:"channel_id"`
+	Reason    string `json:"reason"`
+	StreamID  string `json:"stream_id,omitempty"`
+}
+
+// DirectMessageSentPayload is the data of direct.sent events
+type DirectMessageSentPayload struct {
+	To      string `json:"to"`
+	Message string `json:"message"`
+}
+
+// DirectMessagePayload is the data of direct.message events
+type DirectMessagePayload struct {
+	MessageID string `json:"message_id"`
+	From      string `json:"from"`
+	To        string `json:"to"`
+	Message   string `json:"message"`
+	SentAt    int64  `json:"sent_at"`
+}
+
+// DirectMessageRejectedPayload is the data of direct.rejected events
+type DirectMessageRejectedPayload struct {
+	MessageID string `json:"message_id"`
+	To        string `json:"to"`
+	Reason    string `json:"reason"`
+}
+
+// GoalPayload is the data of goal.progress and goal.completed events
+type GoalPayload struct {
+	ChannelID   string  `json:"channel_id"`
+	GoalID      string  `json:"goal_id"`
+	Kind        string  `json:"kind"`
+	Description string  `json:"description"`
+	Status      string  `json:"status"`
+	Current     int64   `json:"current"`
+	Target      int64   `json:"target"`
+	Progress    float64 `json:"progress"`
+}
+
+// DefaultRegistry returns a registry with version 1.0 of every event type
+// this service publishes
+func DefaultRegistry() *Registry {
+	registry := NewRegistry()
+	for eventType, payload := range map[string]interface{}{
+		EventTypeStreamLive:            StreamInfoPayload{},
+		EventTypeStreamOffline:         StreamOfflinePayload{},
+		EventTypeStreamUpdated:         StreamInfoPayload{},
+		EventTypeNewFollower:           FollowerPayload{},
+		EventTypeChatMessage:           ChatMessagePayload{},
+		EventTypeRaidIncoming:          RaidPayload{},
+		EventTypeRaidOutgoing:          RaidPayload{},
+		EventTypeSubscription:          SubscriptionPayload{},
+		EventTypeGiftSubscription:      GiftSubscriptionPayload{},
+		EventTypeBitsCheered:           BitsCheeredPayload{},
+		EventTypeStreamMilestone:       StreamMilestonePayload{},
+		EventTypeWatchProgress:         WatchProgressPayload{},
+		EventTypeRewardClaimed:         RewardClaimedPayload{},
+		EventTypeClipPublished:         ClipPublishedPayload{},
+		EventTypeVODRenderRequested:    VODRenderRequestedPayload{},
+		EventTypeNotificationCreated:   NotificationPayload{},
+		EventTypeCaptionSegment:        CaptionSegmentPayload{},
+		EventTypePartySync:             PartySyncPayload{},
+		EventTypePartyChatMessage:      PartyChatMessagePayload{},
+		EventTypePartyEnded:            PartyEndedPayload{},
+		EventTypePremiereCountdown:     PremiereCountdownPayload{},
+		EventTypePremiereStarted:       PremiereStartedPayload{},
+		EventTypePremiereSync:          PremiereSyncPayload{},
+		EventTypePremiereEnded:         PremiereEndedPayload{},
+		EventTypeCommunityChatOpened:   CommunityChatOpenedPayload{},
+		EventTypeCommunityChatClosed:   CommunityChatClosedPayload{},
+		EventTypeDirectMessageSent:     DirectMessageSentPayload{},
+		EventTypeDirectMessage:         DirectMessagePayload{},
+		EventTypeDirectMessageRejected: DirectMessageRejectedPayload{},
+		EventTypeGoalProgress:          GoalPayload{},
+		EventTypeGoalCompleted:         GoalPayload{},
+	} {
+		registry.Register(eventType, "1.0", payload)
+	}
+	return registry
+}
+
+// DataInt returns a numeric event data value. Events decoded from JSON carry
+// numbers as float64.
+func DataInt(data map[string]interface{}, key string, fallback int64) int64 {
+	switch v := data[key].(type) {
+	case int:
+		return int64(v)
+	case int64:
+		return v
+	case float64:
+		return int64(v)
+	default:
+		return fallback
+	}
+}
@@ -0,0 +1,133 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"os"
+	"strings"
+	"testing"
+)
+
+// Publish benchmarks against real brokers run when the broker's variable
+// is set, e.g. BENCH_NATS_URL=nats://localhost:4222, and skip otherwise
+var benchBackends = []struct {
+	name string
+	env  string
+	dial func(address string) (Publisher, error)
+}{
+	{"redis", "BENCH_REDIS_URL", func(url string) (Publisher, error) { return NewRedisPublisher(url) }},
+	{"redis-streams", "BENCH_REDIS_URL", func(url string) (Publisher, error) {
+		return NewRedisStreamsPublisher(url, DefaultRedisStreamsPublisherOptions())
+	}},
+	{"rabbitmq", "BENCH_RABBITMQ_URL", func(url string) (Publisher, error) { return NewRabbitMQPublisher(url) }},
+	{"kafka", "BENCH_KAFKA_BROKERS", func(brokers string) (Publisher, error) {
+		return NewKafkaPublisher(DefaultKafkaOptions(strings.Split(brokers, ",")))
+	}},
+	{"nats", "BENCH_NATS_URL", func(url string) (Publisher, error) { return NewNATSPublisher(DefaultNATSOptions(url)) }},
+}
+
+// benchEvent returns a typical event without an ID, so each publish is
+// assigned a new one
+func benchEvent() Event {
+	return Event{
+		Type:     EventTypeStreamLive,
+		UserID:   "user-1",
+		StreamID: "stream-1",
+		Data: map[string]interface{}{
+			"title":    "Benchmark stream",
+			"category": "just-chatting",
+		},
+	}
+}
+
+// quietLogs discards log output until the benchmark is done, keeping
+// benchmark result lines intact for benchstat
+func quietLogs(b *testing.B) {
+	logger, writer, flags := slog.Default(), log.Writer(), log.Flags()
+	slog.SetDefault(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	b.Cleanup(func() {
+		slog.SetDefault(logger)
+		log.SetOutput(writer)
+		log.SetFlags(flags)
+	})
+}
+
+// dialBench connects the backend's publisher, skipping the benchmark when
+// its broker is not configured
+func dialBench(b *testing.B, env string, dial func(string) (Publisher, error)) Publisher {
+	address := os.Getenv(env)
+	if address == "" {
+		b.Skipf("%s is not set", env)
+	}
+	quietLogs(b)
+	publisher, err := dial(address)
+	if err != nil {
+		b.Fatalf("failed to connect: %v", err)
+	}
+	b.Cleanup(func() { publisher.Close() })
+	return publisher
+}
+
+// BenchmarkPublishEncode times the work every backend does per event
+// before it reaches the broker: filling in the ID, starting the span, and
+// marshaling
+func BenchmarkPublishEncode(b *testing.B) {
+	ctx := context.Background()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		event := benchEvent()
+		if err := prepareEvent(&event); err != nil {
+			b.Fatal(err)
+		}
+		_, span, event := startPublishSpan(ctx, event, systemRedis, "events:"+event.Type)
+		if _, err := json.Marshal(event); err != nil {
+			b.Fatal(err)
+		}
+		span.End()
+	}
+}
+
+func BenchmarkPublish(b *testing.B) {
+	for _, backend := range benchBackends {
+		b.Run(backend.name, func(b *testing.B) {
+			publisher := dialBench(b, backend.env, backend.dial)
+			ctx := context.Background()
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if err := publisher.Publish(ctx, benchEvent()); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.ReportMetric(float64(b.N)/b.Elapsed().Seconds(), "events/s")
+		})
+	}
+}
+
+func BenchmarkPublishBatch(b *testing.B) {
+	const batchSize = 100
+	for _, backend := range benchBackends {
+		b.Run(fmt.Sprintf("%s/size=%d", backend.name, batchSize), func(b *testing.B) {
+			publisher := dialBench(b, backend.env, backend.dial)
+			ctx := context.Background()
+			batch := make([]Event, batchSize)
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				for j := range batch {
+					batch[j] = benchEvent()
+				}
+				if err := publisher.PublishBatch(ctx, batch); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.ReportMetric(float64(b.N*batchSize)/b.Elapsed().Seconds(), "events/s")
+		})
+	}
+}
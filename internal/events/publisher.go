@@ -3,8 +3,9 @@ package events
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"time"
 
 	amqp "github.com/rabbitmq/amqp091-go"
@@ -13,13 +14,18 @@ import (
 
 // Event represents a domain event in the system
 type Event struct {
-	ID        string                 `json:"id"`
-	Type      string                 `json:"type"`
-	UserID    string                 `json:"user_id,omitempty"`
-	StreamID  string                 `json:"stream_id,omitempty"`
-	Data      map[string]interface{} `json:"data"`
-	Timestamp time.Time              `json:"timestamp"`
-	Version   string                 `json:"version"`
+	ID       string                 `json:"id"`
+	Type     string                 `json:"type"`
+	UserID   string                 `json:"user_id,omitempty"`
+	StreamID string                 `json:"stream_id,omitempty"`
+	TenantID string                 `json:"tenant_id,omitempty"`
+	Data     map[string]interface{} `json:"data"`
+
+	// Metadata carries cross-cutting context, e.g. the W3C traceparent of
+	// the span that published the event
+	Metadata  map[string]string `json:"metadata,omitempty"`
+	Timestamp time.Time         `json:"timestamp"`
+	Version   string            `json:"version"`
 }
 
 // Publisher defines the interface for publishing events
@@ -51,7 +57,7 @@ func NewRedisPublisher(redisURL string) (*RedisPublisher, error) {
 		return nil, fmt.Errorf("failed to connect to Redis: %w", err)
 	}
 
-	log.Println("Connected to Redis for event publishing")
+	slog.Info("Connected to Redis for event publishing")
 
 	return &RedisPublisher{
 		client: client,
@@ -60,15 +66,15 @@ func NewRedisPublisher(redisURL string) (*RedisPublisher, error) {
 
 // Publish publishes a single event to Redis
 func (p *RedisPublisher) Publish(ctx context.Context, event Event) error {
-	// Set timestamp if not set
-	if event.Timestamp.IsZero() {
-		event.Timestamp = time.Now()
+	// Fill in the ID, timestamp, and version if not set
+	if err := prepareEvent(&event); err != nil {
+		return err
 	}
 
-	// Set version if not set
-	if event.Version == "" {
-		event.Version = "1.0"
-	}
+	// Publish to Redis channel based on event type
+	channel := fmt.Sprintf("events:%s", event.Type)
+	ctx, span, event := startPublishSpan(ctx, event, systemRedis, channel)
+	defer span.End()
 
 	// Marshal event to JSON
 	eventBytes, err := json.Marshal(event)
@@ -76,29 +82,26 @@ func (p *RedisPublisher) Publish(ctx context.Context, event Event) error {
 		return fmt.Errorf("failed to marshal event: %w", err)
 	}
 
-	// Publish to Redis channel based on event type
-	channel := fmt.Sprintf("events:%s", event.Type)
 	if err := p.client.Publish(ctx, channel, eventBytes).Err(); err != nil {
+		span.RecordError(err)
 		return fmt.Errorf("failed to publish event to Redis: %w", err)
 	}
 
-	log.Printf("Published event: type=%s, id=%s, channel=%s", event.Type, event.ID, channel)
+	slog.Debug("Published event", "type", event.Type, "id", event.ID, "channel", channel)
 	return nil
 }
 
 // PublishBatch publishes multiple events in a batch
 func (p *RedisPublisher) PublishBatch(ctx context.Context, events []Event) error {
+	events, err := prepareBatch(events)
+	if err != nil {
+		return err
+	}
+	ctx, span, events := startBatchSpan(ctx, events, systemRedis)
+	defer span.End()
 	pipe := p.client.Pipeline()
 
 	for _, event := range events {
-		// Set defaults
-		if event.Timestamp.IsZero() {
-			event.Timestamp = time.Now()
-		}
-		if event.Version == "" {
-			event.Version = "1.0"
-		}
-
 		eventBytes, err := json.Marshal(event)
 		if err != nil {
 			return fmt.Errorf("failed to marshal event: %w", err)
@@ -108,15 +111,20 @@ func (p *RedisPublisher) PublishBatch(ctx context.Context, events []Event) error
 		pipe.Publish(ctx, channel, eventBytes)
 	}
 
-	_, err := pipe.Exec(ctx)
-	if err != nil {
+	if _, err := pipe.Exec(ctx); err != nil {
+		span.RecordError(err)
 		return fmt.Errorf("failed to execute batch publish: %w", err)
 	}
 
-	log.Printf("Published %d events in batch", len(events))
+	slog.Debug("Published events in batch", "count", len(events))
 	return nil
 }
 
+// Ready pings Redis
+func (p *RedisPublisher) Ready(ctx context.Context) error {
+	return p.client.Ping(ctx).Err()
+}
+
 // Close closes the Redis connection
 func (p *RedisPublisher) Close() error {
 	return p.client.Close()
@@ -157,7 +165,7 @@ func NewRabbitMQPublisher(amqpURL string) (*RabbitMQPublisher, error) {
 		return nil, fmt.Errorf("failed to declare exchange: %w", err)
 	}
 
-	log.Println("Connected to RabbitMQ for event publishing")
+	slog.Info("Connected to RabbitMQ for event publishing")
 
 	return &RabbitMQPublisher{
 		conn:    conn,
@@ -167,21 +175,20 @@ func NewRabbitMQPublisher(amqpURL string) (*RabbitMQPublisher, error) {
 
 // Publish publishes a single event to RabbitMQ
 func (p *RabbitMQPublisher) Publish(ctx context.Context, event Event) error {
-	if event.Timestamp.IsZero() {
-		event.Timestamp = time.Now()
-	}
-	if event.Version == "" {
-		event.Version = "1.0"
+	if err := prepareEvent(&event); err != nil {
+		return err
 	}
 
+	// Create routing key from event type (e.g., "stream.live")
+	routingKey := event.Type
+	ctx, span, event := startPublishSpan(ctx, event, systemRabbitMQ, routingKey)
+	defer span.End()
+
 	eventBytes, err := json.Marshal(event)
 	if err != nil {
 		return fmt.Errorf("failed to marshal event: %w", err)
 	}
 
-	// Create routing key from event type (e.g., "stream.live")
-	routingKey := event.Type
-
 	err = p.channel.PublishWithContext(
 		ctx,
 		"events",   // exchange
@@ -194,29 +201,40 @@ func (p *RabbitMQPublisher) Publish(ctx context.Context, event Event) error {
 			DeliveryMode: amqp.Persistent,
 			Timestamp:    event.Timestamp,
 			MessageId:    event.ID,
+			Headers:      traceHeaders(event),
 		},
 	)
 
 	if err != nil {
+		span.RecordError(err)
 		return fmt.Errorf("failed to publish event to RabbitMQ: %w", err)
 	}
 
-	log.Printf("Published event: type=%s, id=%s, routingKey=%s", event.Type, event.ID, routingKey)
+	slog.Debug("Published event", "type", event.Type, "id", event.ID, "routing_key", routingKey)
 	return nil
 }
 
 // PublishBatch publishes multiple events
 func (p *RabbitMQPublisher) PublishBatch(ctx context.Context, events []Event) error {
+	events, err := prepareBatch(events)
+	if err != nil {
+		return err
+	}
 	for _, event := range events {
 		if err := p.Publish(ctx, event); err != nil {
 			return err
 		}
 	}
 
-	log.Printf("Published %d events in batch", len(events))
+	slog.Debug("Published events in batch", "count", len(events))
 	return nil
 }
 
+// Ready reports whether the RabbitMQ connection and channel are still open
+func (p *RabbitMQPublisher) Ready(ctx context.Context) error {
+	return amqpReady(p.conn, p.channel)
+}
+
 // Close closes the RabbitMQ connection
 func (p *RabbitMQPublisher) Close() error {
 	if err := p.channel.Close(); err != nil {
@@ -237,11 +255,14 @@ func NewMultiPublisher(publishers ...Publisher) *MultiPublisher {
 	}
 }
 
-// Publish publishes to all configured publishers
+// Publish publishes to all configured publishers, under the same event ID
 func (p *MultiPublisher) Publish(ctx context.Context, event Event) error {
+	if err := prepareEvent(&event); err != nil {
+		return err
+	}
 	for _, publisher := range p.publishers {
 		if err := publisher.Publish(ctx, event); err != nil {
-			log.Printf("Error publishing to backend: %v", err)
+			slog.Error("Error publishing to backend", "type", event.Type, "id", event.ID, "err", err)
 			// Continue with other publishers instead of failing fast
 		}
 	}
@@ -250,19 +271,36 @@ func (p *MultiPublisher) Publish(ctx context.Context, event Event) error {
 
 // PublishBatch publishes batches to all configured publishers
 func (p *MultiPublisher) PublishBatch(ctx context.Context, events []Event) error {
+	events, err := prepareBatch(events)
+	if err != nil {
+		return err
+	}
 	for _, publisher := range p.publishers {
 		if err := publisher.PublishBatch(ctx, events); err != nil {
-			log.Printf("Error batch publishing to backend: %v", err)
+			slog.Error("Error batch publishing to backend", "count", len(events), "err", err)
 		}
 	}
 	return nil
 }
 
+// Ready checks every backend that can report its readiness
+func (p *MultiPublisher) Ready(ctx context.Context) error {
+	var errs []error
+	for _, publisher := range p.publishers {
+		if checker, ok := publisher.(interface{ Ready(context.Context) error }); ok {
+			if err := checker.Ready(ctx); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
 // Close closes all publishers
 func (p *MultiPublisher) Close() error {
 	for _, publisher := range p.publishers {
 		if err := publisher.Close(); err != nil {
-			log.Printf("Error closing publisher: %v", err)
+			slog.Error("Error closing publisher", "err", err)
 		}
 	}
 	return nil
@@ -270,16 +308,39 @@ func (p *MultiPublisher) Close() error {
 
 // EventType constants for common events
 const (
-	EventTypeStreamLive       = "stream.live"
-	EventTypeStreamOffline    = "stream.offline"
-	EventTypeNewFollower      = "user.new_follower"
-	EventTypeChatMessage      = "chat.message"
-	EventTypeRaidIncoming     = "raid.incoming"
-	EventTypeRaidOutgoing     = "raid.outgoing"
-	EventTypeSubscription     = "subscription.new"
-	EventTypeGiftSubscription = "subscription.gift"
-	EventTypeBitsCheered      = "bits.cheered"
-	EventTypeStreamMilestone  = "stream.milestone"
+	EventTypeStreamLive            = "stream.live"
+	EventTypeStreamOffline         = "stream.offline"
+	EventTypeStreamUpdated         = "stream.updated"
+	EventTypeNewFollower           = "user.new_follower"
+	EventTypeChatMessage           = "chat.message"
+	EventTypeRaidIncoming          = "raid.incoming"
+	EventTypeRaidOutgoing          = "raid.outgoing"
+	EventTypeSubscription          = "subscription.new"
+	EventTypeGiftSubscription      = "subscription.gift"
+	EventTypeBitsCheered           = "bits.cheered"
+	EventTypeStreamMilestone       = "stream.milestone"
+	EventTypeModerationQueued      = "moderation.queued"
+	EventTypeAutoModHeld           = "automod.held"
+	EventTypeWatchProgress         = "watch.progress"
+	EventTypeRewardClaimed         = "campaign.reward_claimed"
+	EventTypeClipPublished         = "clip.published"
+	EventTypeVODRenderRequested    = "vod.render_requested"
+	EventTypeNotificationCreated   = "notification.created"
+	EventTypeCaptionSegment        = "caption.segment"
+	EventTypePartySync             = "party.sync"
+	EventTypePartyChatMessage      = "party.chat_message"
+	EventTypePartyEnded            = "party.ended"
+	EventTypePremiereCountdown     = "premiere.countdown"
+	EventTypePremiereStarted       = "premiere.started"
+	EventTypePremiereSync          = "premiere.sync"
+	EventTypePremiereEnded         = "premiere.ended"
+	EventTypeCommunityChatOpened   = "community.opened"
+	EventTypeCommunityChatClosed   = "community.closed"
+	EventTypeDirectMessageSent     = "direct.sent"
+	EventTypeDirectMessage         = "direct.message"
+	EventTypeDirectMessageRejected = "direct.rejected"
+	EventTypeGoalProgress          = "goal.progress"
+	EventTypeGoalCompleted         = "goal.completed"
 )
 
 // Helper functions to create common events
@@ -297,6 +358,32 @@ func NewStreamLiveEvent(streamID, streamerID string, data map[string]interface{}
 	}
 }
 
+// NewStreamOfflineEvent creates a stream offline event
+func NewStreamOfflineEvent(streamID, streamerID string, data map[string]interface{}) Event {
+	return Event{
+		ID:        generateEventID(),
+		Type:      EventTypeStreamOffline,
+		UserID:    streamerID,
+		StreamID:  streamID,
+		Data:      data,
+		Timestamp: time.Now(),
+		Version:   "1.0",
+	}
+}
+
+// NewStreamUpdatedEvent creates an event for changed viewer-facing stream info
+func NewStreamUpdatedEvent(streamID, streamerID string, data map[string]interface{}) Event {
+	return Event{
+		ID:        generateEventID(),
+		Type:      EventTypeStreamUpdated,
+		UserID:    streamerID,
+		StreamID:  streamID,
+		Data:      data,
+		Timestamp: time.Now(),
+		Version:   "1.0",
+	}
+}
+
 // NewFollowerEvent creates a new follower event
 func NewFollowerEvent(followerID, followedID string) Event {
 	return Event{
@@ -327,7 +414,288 @@ func NewChatMessageEvent(streamID, userID, message string) Event {
 	}
 }
 
-// generateEventID generates a unique event ID
-func generateEventID() string {
-	return fmt.Sprintf("evt_%d", time.Now().UnixNano())
+// NewWatchProgressEvent creates an event for watch time a viewer accrued in
+// a stream room
+func NewWatchProgressEvent(streamID, userID string, watched time.Duration) Event {
+	return Event{
+		ID:       generateEventID(),
+		Type:     EventTypeWatchProgress,
+		UserID:   userID,
+		StreamID: streamID,
+		Data: map[string]interface{}{
+			"watched_seconds": int64(watched.Seconds()),
+		},
+		Timestamp: time.Now(),
+		Version:   "1.0",
+	}
+}
+
+// NewClipPublishedEvent creates an event for a clip made public
+func NewClipPublishedEvent(clipID, streamID, creatorID string, data map[string]interface{}) Event {
+	if data == nil {
+		data = map[string]interface{}{}
+	}
+	data["clip_id"] = clipID
+	return Event{
+		ID:        generateEventID(),
+		Type:      EventTypeClipPublished,
+		UserID:    creatorID,
+		StreamID:  streamID,
+		Data:      data,
+		Timestamp: time.Now(),
+		Version:   "1.0",
+	}
+}
+
+// NewVODRenderRequestedEvent creates an event asking the transcoding workers
+// to render a VOD from ranges of its broadcast
+func NewVODRenderRequestedEvent(vodID, streamID, streamerID string, data map[string]interface{}) Event {
+	if data == nil {
+		data = map[string]interface{}{}
+	}
+	data["vod_id"] = vodID
+	return Event{
+		ID:        generateEventID(),
+		Type:      EventTypeVODRenderRequested,
+		UserID:    streamerID,
+		StreamID:  streamID,
+		Data:      data,
+		Timestamp: time.Now(),
+		Version:   "1.0",
+	}
+}
+
+// NewNotificationCreatedEvent creates an event for a notification stored
+// for userID, for delivery to the user's connections
+func NewNotificationCreatedEvent(userID string, data map[string]interface{}) Event {
+	return Event{
+		ID:        generateEventID(),
+		Type:      EventTypeNotificationCreated,
+		UserID:    userID,
+		Data:      data,
+		Timestamp: time.Now(),
+		Version:   "1.0",
+	}
+}
+
+// NewCaptionSegmentEvent creates an event for a closed caption of a stream
+func NewCaptionSegmentEvent(streamID string, data map[string]interface{}) Event {
+	return Event{
+		ID:        generateEventID(),
+		Type:      EventTypeCaptionSegment,
+		StreamID:  streamID,
+		Data:      data,
+		Timestamp: time.Now(),
+		Version:   "1.0",
+	}
+}
+
+// NewPartySyncEvent creates an event for a watch party host's playback
+// change, relayed to the party's room on every WebSocket server
+func NewPartySyncEvent(partyID, hostID string, data map[string]interface{}) Event {
+	if data == nil {
+		data = map[string]interface{}{}
+	}
+	data["party_id"] = partyID
+	return Event{
+		ID:        generateEventID(),
+		Type:      EventTypePartySync,
+		UserID:    hostID,
+		Data:      data,
+		Timestamp: time.Now(),
+		Version:   "1.0",
+	}
+}
+
+// NewPartyChatMessageEvent creates an event for a chat message in a watch party
+func NewPartyChatMessageEvent(partyID, userID, message string) Event {
+	return Event{
+		ID:     generateEventID(),
+		Type:   EventTypePartyChatMessage,
+		UserID: userID,
+		Data: map[string]interface{}{
+			"party_id": partyID,
+			"message":  message,
+		},
+		Timestamp: time.Now(),
+		Version:   "1.0",
+	}
+}
+
+// NewPartyEndedEvent creates an event for a watch party its host ended
+func NewPartyEndedEvent(partyID, hostID string) Event {
+	return Event{
+		ID:     generateEventID(),
+		Type:   EventTypePartyEnded,
+		UserID: hostID,
+		Data: map[string]interface{}{
+			"party_id": partyID,
+		},
+		Timestamp: time.Now(),
+		Version:   "1.0",
+	}
+}
+
+// NewPremiereEvent creates a premiere.* event for a VOD's premiere, relayed
+// to the premiere's room on every WebSocket server
+func NewPremiereEvent(eventType, vodID string, data map[string]interface{}) Event {
+	if data == nil {
+		data = map[string]interface{}{}
+	}
+	data["vod_id"] = vodID
+	return Event{
+		ID:        generateEventID(),
+		Type:      eventType,
+		Data:      data,
+		Timestamp: time.Now(),
+		Version:   "1.0",
+	}
+}
+
+// NewCommunityChatEvent creates a community.* event for a channel's offline
+// community room, relayed to the room on every WebSocket server
+func NewCommunityChatEvent(eventType, channelID string, data map[string]interface{}) Event {
+	if data == nil {
+		data = map[string]interface{}{}
+	}
+	data["channel_id"] = channelID
+	return Event{
+		ID:        generateEventID(),
+		Type:      eventType,
+		UserID:    channelID,
+		Data:      data,
+		Timestamp: time.Now(),
+		Version:   "1.0",
+	}
+}
+
+// NewDirectMessageSentEvent creates an event for a direct message a user
+// sent over WebSocket, for the API server to check and deliver. The event ID
+// identifies the message.
+func NewDirectMessageSentEvent(fromID, toID, message string) Event {
+	return Event{
+		ID:     generateEventID(),
+		Type:   EventTypeDirectMessageSent,
+		UserID: fromID,
+		Data: map[string]interface{}{
+			"to":      toID,
+			"message": message,
+		},
+		Timestamp: time.Now(),
+		Version:   "1.0",
+	}
+}
+
+// NewDirectMessageEvent creates an event delivering an allowed direct
+// message to every connection of both users on every WebSocket server
+func NewDirectMessageEvent(messageID, fromID, toID, message string, sentAt time.Time) Event {
+	return Event{
+		ID:     generateEventID(),
+		Type:   EventTypeDirectMessage,
+		UserID: toID,
+		Data: map[string]interface{}{
+			"message_id": messageID,
+			"from":       fromID,
+			"to":         toID,
+			"message":    message,
+			"sent_at":    sentAt.UnixMilli(),
+		},
+		Timestamp: time.Now(),
+		Version:   "1.0",
+	}
+}
+
+// NewDirectMessageRejectedEvent creates an event telling the sender of a
+// direct message why it was not delivered
+func NewDirectMessageRejectedEvent(messageID, fromID, toID, reason string) Event {
+	return Event{
+		ID:     generateEventID(),
+		Type:   EventTypeDirectMessageRejected,
+		UserID: fromID,
+		Data: map[string]interface{}{
+			"message_id": messageID,
+			"to":         toID,
+			"reason":     reason,
+		},
+		Timestamp: time.Now(),
+		Version:   "1.0",
+	}
+}
+
+// NewGoalEvent creates a goal.* event for a channel's goal, relayed to the
+// channel's overlay room on every WebSocket server
+func NewGoalEvent(eventType, channelID string, data map[string]interface{}) Event {
+	if data == nil {
+		data = map[string]interface{}{}
+	}
+	data["channel_id"] = channelID
+	return Event{
+		ID:        generateEventID(),
+		Type:      eventType,
+		UserID:    channelID,
+		Data:      data,
+		Timestamp: time.Now(),
+		Version:   "1.0",
+	}
+}
+
+// NewRaidEvent creates a raid.* event. Outgoing events are for the raiding
+// stream's room and incoming events for the raided stream's room.
+func NewRaidEvent(eventType, streamID string, data map[string]interface{}) Event {
+	return Event{
+		ID:        generateEventID(),
+		Type:      eventType,
+		StreamID:  streamID,
+		Data:      data,
+		Timestamp: time.Now(),
+		Version:   "1.0",
+	}
+}
+
+// NewSubscriptionEvent creates a subscription.new event addressed to the
+// channel that was subscribed to
+func NewSubscriptionEvent(channelID string, data map[string]interface{}) Event {
+	return newChannelEvent(EventTypeSubscription, channelID, data)
+}
+
+// NewGiftSubscriptionEvent creates a subscription.gift event addressed to
+// the channel a subscription was gifted for
+func NewGiftSubscriptionEvent(channelID string, data map[string]interface{}) Event {
+	return newChannelEvent(EventTypeGiftSubscription, channelID, data)
+}
+
+// NewBitsCheeredEvent creates a bits.cheered event addressed to the channel
+// and delivered to the room of the stream that was cheered in
+func NewBitsCheeredEvent(streamID, channelID string, data map[string]interface{}) Event {
+	event := newChannelEvent(EventTypeBitsCheered, channelID, data)
+	event.StreamID = streamID
+	return event
+}
+
+// NewStreamMilestoneEvent creates a stream.milestone event addressed to the
+// channel and delivered to the room of the stream that reached it; without
+// a stream, it goes to the channel only
+func NewStreamMilestoneEvent(streamID, channelID string, data map[string]interface{}) Event {
+	event := newChannelEvent(EventTypeStreamMilestone, channelID, data)
+	event.StreamID = streamID
+	if streamID != "" {
+		event.Data["stream_id"] = streamID
+	}
+	return event
+}
+
+// newChannelEvent creates an event addressed to a channel
+func newChannelEvent(eventType, channelID string, data map[string]interface{}) Event {
+	if data == nil {
+		data = map[string]interface{}{}
+	}
+	data["channel_id"] = channelID
+	return Event{
+		ID:        generateEventID(),
+		Type:      eventType,
+		UserID:    channelID,
+		Data:      data,
+		Timestamp: time.Now(),
+		Version:   "1.0",
+	}
 }
@@ -0,0 +1,317 @@
+package events
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Schema errors
+var (
+	ErrUnknownSchema  = errors.New("no schema registered for event version")
+	ErrInvalidPayload = errors.New("event data does not match its schema")
+)
+
+// Upcaster converts the data of one version of an event type to the next
+type Upcaster func(data map[string]interface{}) (map[string]interface{}, error)
+
+// schema is a registered payload struct for one version of an event type
+type schema struct {
+	payload  reflect.Type
+	required []string
+}
+
+// upcaster converts data to the version it is registered for
+type upcaster struct {
+	to string
+	fn Upcaster
+}
+
+// Registry maps event types and versions to payload structs. Publishers
+// validate event data against the schema of the event's version; consumers
+// receive events upcast to the latest version of their type.
+//
+// A payload struct's JSON fields are its schema: data with a key the struct
+// does not have is rejected, as is data missing a top-level field not tagged
+// omitempty. Event types without schemas are not validated.
+type Registry struct {
+	mu        sync.RWMutex
+	schemas   map[string]map[string]*schema
+	latest    map[string]string
+	upcasters map[string]map[string]upcaster
+}
+
+// NewRegistry creates an empty schema registry
+func NewRegistry() *Registry {
+	return &Registry{
+		schemas:   make(map[string]map[string]*schema),
+		latest:    make(map[string]string),
+		upcasters: make(map[string]map[string]upcaster),
+	}
+}
+
+// Register registers payload, a struct or pointer to one, as the schema of
+// version of eventType
+func (r *Registry) Register(eventType, version string, payload interface{}) {
+	t := reflect.TypeOf(payload)
+	if t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("events: schema for %s %s must be a struct, got %T", eventType, version, payload))
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.schemas[eventType] == nil {
+		r.schemas[eventType] = make(map[string]*schema)
+	}
+	r.schemas[eventType][version] = &schema{payload: t, required: requiredFields(t)}
+	if latest, ok := r.latest[eventType]; !ok || compareVersions(version, latest) > 0 {
+		r.latest[eventType] = version
+	}
+}
+
+// RegisterUpcaster registers fn to convert the data of version from of
+// eventType to version to
+func (r *Registry) RegisterUpcaster(eventType, from, to string, fn Upcaster) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.upcasters[eventType] == nil {
+		r.upcasters[eventType] = make(map[string]upcaster)
+	}
+	r.upcasters[eventType][from] = upcaster{to: to, fn: fn}
+}
+
+// Latest returns the latest registered version of eventType
+func (r *Registry) Latest(eventType string) (string, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	version, ok := r.latest[eventType]
+	return version, ok
+}
+
+// Types returns the event types with schemas, sorted
+func (r *Registry) Types() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	types := make([]string, 0, len(r.schemas))
+	for eventType := range r.schemas {
+		types = append(types, eventType)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// Versions returns the registered versions of eventType, oldest first
+func (r *Registry) Versions(eventType string) []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	versions := make([]string, 0, len(r.schemas[eventType]))
+	for version := range r.schemas[eventType] {
+		versions = append(versions, version)
+	}
+	sort.Slice(versions, func(i, j int) bool { return compareVersions(versions[i], versions[j]) < 0 })
+	return versions
+}
+
+// Validate checks event's data against the schema of its type and version
+func (r *Registry) Validate(event Event) error {
+	s, err := r.lookup(event.Type, event.Version)
+	if err != nil || s == nil {
+		return err
+	}
+
+	raw, err := json.Marshal(event.Data)
+	if err != nil {
+		return fmt.Errorf("invalid %s %s event %q: %w: %v", event.Type, event.Version, event.ID, ErrInvalidPayload, err)
+	}
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(reflect.New(s.payload).Interface()); err != nil {
+		return fmt.Errorf("invalid %s %s event %q: %w: %v", event.Type, event.Version, event.ID, ErrInvalidPayload, err)
+	}
+
+	var missing []string
+	for _, field := range s.required {
+		if _, ok := event.Data[field]; !ok {
+			missing = append(missing, field)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("invalid %s %s event %q: %w: missing %s", event.Type, event.Version, event.ID,
+			ErrInvalidPayload, strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// Upcast returns event with its data converted to the latest version of its
+// type. Events of types without schemas, or already at the latest version,
+// are returned as is.
+func (r *Registry) Upcast(event Event) (Event, error) {
+	r.mu.RLock()
+	latest, ok := r.latest[event.Type]
+	chain := r.upcasters[event.Type]
+	r.mu.RUnlock()
+	if !ok {
+		return event, nil
+	}
+
+	version := event.Version
+	if version == "" {
+		version = "1.0"
+	}
+	data := event.Data
+	// Registered upcasters only go forwards, so a chain longer than the
+	// number of upcasters is a cycle
+	for steps := 0; version != latest; steps++ {
+		next, ok := chain[version]
+		if !ok || steps > len(chain) {
+			return event, fmt.Errorf("failed to upcast %s event %q from %s to %s: %w",
+				event.Type, event.ID, version, latest, ErrUnknownSchema)
+		}
+
+		converted, err := next.fn(copyData(data))
+		if err != nil {
+			return event, fmt.Errorf("failed to upcast %s event %q from %s to %s: %w", event.Type, event.ID, version, next.to, err)
+		}
+		data, version = converted, next.to
+	}
+
+	event.Data, event.Version = data, version
+	return event, nil
+}
+
+// Decode upcasts event to the latest version of its type and decodes its
+// data into payload, a pointer to that version's payload struct
+func (r *Registry) Decode(event Event, payload interface{}) error {
+	event, err := r.Upcast(event)
+	if err != nil {
+		return err
+	}
+
+	raw, err := json.Marshal(event.Data)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s event %q data: %w", event.Type, event.ID, err)
+	}
+	if err := json.Unmarshal(raw, payload); err != nil {
+		return fmt.Errorf("failed to decode %s event %q data: %w", event.Type, event.ID, err)
+	}
+	return nil
+}
+
+// lookup returns the schema of version of eventType, or nil if the type has
+// no schemas
+func (r *Registry) lookup(eventType, version string) (*schema, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	versions, ok := r.schemas[eventType]
+	if !ok {
+		return nil, nil
+	}
+	s, ok := versions[version]
+	if !ok {
+		return nil, fmt.Errorf("invalid %s event version %q: %w", eventType, version, ErrUnknownSchema)
+	}
+	return s, nil
+}
+
+// requiredFields returns the JSON names of t's fields not tagged omitempty
+func requiredFields(t reflect.Type) []string {
+	var required []string
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, options, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		if !strings.Contains(","+options+",", ",omitempty,") {
+			required = append(required, name)
+		}
+	}
+	return required
+}
+
+// compareVersions orders dotted numeric versions such as "1.0" and "2.1"
+func compareVersions(a, b string) int {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		var x, y int
+		if i < len(as) {
+			x, _ = strconv.Atoi(as[i])
+		}
+		if i < len(bs) {
+			y, _ = strconv.Atoi(bs[i])
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// copyData returns a shallow copy of event data, so upcasters can change it
+// without touching the caller's event
+func copyData(data map[string]interface{}) map[string]interface{} {
+	copied := make(map[string]interface{}, len(data))
+	for key, value := range data {
+		copied[key] = value
+	}
+	return copied
+}
+
+var (
+	schemasMu sync.RWMutex
+	schemas   = DefaultRegistry()
+)
+
+// SetRegistry replaces the registry events are validated and upcast with.
+// A nil registry restores the default one.
+func SetRegistry(registry *Registry) {
+	if registry == nil {
+		registry = DefaultRegistry()
+	}
+
+	schemasMu.Lock()
+	defer schemasMu.Unlock()
+	schemas = registry
+}
+
+// Schemas returns the registry events are validated and upcast with, e.g.
+// to register a new version and its upcaster
+func Schemas() *Registry {
+	schemasMu.RLock()
+	defer schemasMu.RUnlock()
+	return schemas
+}
+
+// ValidateEvent checks event's data against its registered schema
+func ValidateEvent(event Event) error {
+	return Schemas().Validate(event)
+}
+
+// UpcastEvent converts event's data to the latest version of its type
+func UpcastEvent(event Event) (Event, error) {
+	return Schemas().Upcast(event)
+}
+
+// DecodeData upcasts event and decodes its data into payload, e.g. a
+// *StreamInfoPayload for stream.live events
+func DecodeData(event Event, payload interface{}) error {
+	return Schemas().Decode(event, payload)
+}
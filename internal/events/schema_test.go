@@ -0,0 +1,135 @@
+package events
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestDefaultRegistryAcceptsPublishedEvents(t *testing.T) {
+	registry := DefaultRegistry()
+	events := []Event{
+		NewStreamLiveEvent("stream-1", "user-1", map[string]interface{}{
+			"title": "Live", "category": "games", "tags": []string(nil), "language": "en", "is_mature": false, "branded_content": false,
+		}),
+		NewStreamOfflineEvent("stream-1", "user-1", map[string]interface{}{}),
+		NewFollowerEvent("user-2", "user-1"),
+		NewChatMessageEvent("stream-1", "user-2", "hello"),
+		NewWatchProgressEvent("stream-1", "user-2", time.Minute),
+		NewClipPublishedEvent("clip-1", "stream-1", "user-2", map[string]interface{}{
+			"title": "Clip", "start_ms": int64(0), "end_ms": int64(30000), "duration_seconds": 30.0,
+		}),
+		NewVODRenderRequestedEvent("vod-1", "stream-1", "user-1", map[string]interface{}{
+			"kind": "highlight", "segments": []map[string]interface{}{{"start_ms": int64(0), "end_ms": int64(1000)}},
+		}),
+		NewPartySyncEvent("party-1", "user-1", map[string]interface{}{
+			"host_id": "user-1", "position_ms": int64(0), "playing": true, "rate": 1.0, "updated_at": time.Now().UnixMilli(),
+		}),
+		NewPartyChatMessageEvent("party-1", "user-2", "hi"),
+		NewPartyEndedEvent("party-1", "user-1"),
+		NewPremiereEvent(EventTypePremiereEnded, "vod-1", map[string]interface{}{"cancelled": true}),
+		NewCommunityChatEvent(EventTypeCommunityChatClosed, "user-1", map[string]interface{}{"reason": "live", "stream_id": "stream-1"}),
+		NewDirectMessageSentEvent("user-1", "user-2", "hey"),
+		NewDirectMessageEvent("msg-1", "user-1", "user-2", "hey", time.Now()),
+		NewDirectMessageRejectedEvent("msg-1", "user-1", "user-2", "blocked"),
+		NewStreamMilestoneEvent("stream-1", "user-1", map[string]interface{}{
+			"milestone_id": "m-1", "kind": "viewers", "threshold": int64(100), "value": int64(101), "message": "100 viewers",
+		}),
+		NewGoalEvent(EventTypeGoalProgress, "user-1", map[string]interface{}{
+			"goal_id": "g-1", "kind": "followers", "description": "", "status": "active", "current": int64(5), "target": int64(10), "progress": 0.5,
+		}),
+		NewRaidEvent(EventTypeRaidIncoming, "stream-2", map[string]interface{}{
+			"raid_id": "r-1", "from_stream_id": "stream-1", "from_streamer_id": "user-1", "from_title": "A",
+			"to_stream_id": "stream-2", "to_streamer_id": "user-2", "to_title": "B",
+			"viewer_count": 12, "countdown_seconds": int64(10), "lands_at": time.Now().UnixMilli(),
+		}),
+		NewGiftSubscriptionEvent("user-1", map[string]interface{}{
+			"subscription_id": "s-1", "gifter_id": "user-2", "gifter_username": "two", "gifter_display_name": "Two",
+			"recipient_id": "user-3", "recipient_username": "three", "recipient_display_name": "Three", "tier": "1000", "count": 1,
+		}),
+		NewNotificationCreatedEvent("user-1", map[string]interface{}{
+			"id": "n-1", "type": "NEW_FOLLOWER", "title": "New follower", "message": "Two followed you",
+			"data": map[string]interface{}{"follower_id": "user-2"}, "created_at": time.Now().Format(time.RFC3339),
+		}),
+		{Type: EventTypeRewardClaimed, Version: "1.0", Data: map[string]interface{}{
+			"claim_id": "c-1", "campaign_id": "camp-1", "reward_id": "rw-1", "reward_name": "Badge", "required_minutes": 30, "claimed_at": time.Now(),
+		}},
+		NewPremiereEvent(EventTypePremiereSync, "vod-1", map[string]interface{}{
+			"position_ms": int64(1000), "duration_ms": int64(60000), "server_time": time.Now().Format(time.RFC3339Nano),
+		}),
+	}
+	for _, event := range events {
+		if err := registry.Validate(event); err != nil {
+			t.Errorf("Validate(%s) = %v, want nil", event.Type, err)
+		}
+	}
+}
+
+func TestValidateRejectsInvalidData(t *testing.T) {
+	registry := DefaultRegistry()
+	tests := []struct {
+		name  string
+		event Event
+		want  error
+	}{
+		{"unknown field", Event{Type: EventTypeChatMessage, Version: "1.0", Data: map[string]interface{}{"message": "hi", "colour": "red"}}, ErrInvalidPayload},
+		{"missing field", Event{Type: EventTypeNewFollower, Version: "1.0", Data: map[string]interface{}{"follower_id": "user-2"}}, ErrInvalidPayload},
+		{"wrong type", Event{Type: EventTypeWatchProgress, Version: "1.0", Data: map[string]interface{}{"watched_seconds": "60"}}, ErrInvalidPayload},
+		{"unknown version", Event{Type: EventTypeChatMessage, Version: "9.0", Data: map[string]interface{}{"message": "hi"}}, ErrUnknownSchema},
+		{"unregistered type", Event{Type: "custom.thing", Version: "1.0", Data: map[string]interface{}{"anything": 1}}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := registry.Validate(tt.event); !errors.Is(err, tt.want) {
+				t.Errorf("Validate() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestUpcast(t *testing.T) {
+	type chatMessageV2 struct {
+		Text string `json:"text"`
+		Lang string `json:"lang,omitempty"`
+	}
+	registry := DefaultRegistry()
+	registry.Register(EventTypeChatMessage, "2.0", chatMessageV2{})
+	registry.RegisterUpcaster(EventTypeChatMessage, "1.0", "2.0", func(data map[string]interface{}) (map[string]interface{}, error) {
+		data["text"] = data["message"]
+		delete(data, "message")
+		return data, nil
+	})
+
+	v1 := NewChatMessageEvent("stream-1", "user-2", "hello")
+	if err := registry.Validate(v1); err != nil {
+		t.Fatalf("Validate(v1) = %v, want nil", err)
+	}
+
+	upcast, err := registry.Upcast(v1)
+	if err != nil {
+		t.Fatalf("Upcast() = %v", err)
+	}
+	if upcast.Version != "2.0" || upcast.Data["text"] != "hello" {
+		t.Errorf("Upcast() = %s %v, want 2.0 with text", upcast.Version, upcast.Data)
+	}
+	if _, ok := v1.Data["message"]; !ok {
+		t.Errorf("Upcast() changed the original event's data: %v", v1.Data)
+	}
+
+	var payload chatMessageV2
+	if err := registry.Decode(v1, &payload); err != nil || payload.Text != "hello" {
+		t.Errorf("Decode() = %+v, %v, want text hello", payload, err)
+	}
+
+	registry.Register(EventTypeChatMessage, "3.0", chatMessageV2{})
+	if _, err := registry.Upcast(v1); !errors.Is(err, ErrUnknownSchema) {
+		t.Errorf("Upcast() without a 2.0 to 3.0 upcaster = %v, want ErrUnknownSchema", err)
+	}
+}
+
+func TestPrepareEventValidates(t *testing.T) {
+	event := Event{Type: EventTypeChatMessage, Data: map[string]interface{}{"text": "hi"}}
+	if err := prepareEvent(&event); !errors.Is(err, ErrInvalidPayload) {
+		t.Errorf("prepareEvent() = %v, want ErrInvalidPayload", err)
+	}
+}
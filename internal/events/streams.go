@@ -0,0 +1,487 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// BackendRedisStreams selects the Redis Streams publisher and subscriber
+// in the servers' EVENT_BACKEND setting
+const BackendRedisStreams = "redis-streams"
+
+// DefaultEventStream is the Redis stream events are appended to
+const DefaultEventStream = "events:stream"
+
+// Fields of a stream entry
+const (
+	streamFieldType  = "type"
+	streamFieldEvent = "event"
+)
+
+// RedisStreamsPublisherOptions configures a Redis Streams publisher
+type RedisStreamsPublisherOptions struct {
+	// Stream is the key events are appended to
+	Stream string
+
+	// MaxLen approximately caps the stream length; the oldest entries are
+	// trimmed beyond it (0 = unbounded)
+	MaxLen int64
+}
+
+// DefaultRedisStreamsPublisherOptions returns options for the shared event stream
+func DefaultRedisStreamsPublisherOptions() RedisStreamsPublisherOptions {
+	return RedisStreamsPublisherOptions{
+		Stream: DefaultEventStream,
+		MaxLen: 100000,
+	}
+}
+
+// RedisStreamsPublisher implements Publisher using a Redis stream.
+//
+// Unlike Pub/Sub, events are kept in the stream until trimmed, so consumer
+// groups that were offline pick up where they left off.
+type RedisStreamsPublisher struct {
+	client *redis.Client
+	opts   RedisStreamsPublisherOptions
+}
+
+// NewRedisStreamsPublisher creates a new Redis Streams event publisher
+func NewRedisStreamsPublisher(redisURL string, opts RedisStreamsPublisherOptions) (*RedisStreamsPublisher, error) {
+	client, err := dialRedis(redisURL)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("Connected to Redis for event publishing: stream=%s", opts.Stream)
+
+	return &RedisStreamsPublisher{
+		client: client,
+		opts:   opts,
+	}, nil
+}
+
+// Publish appends a single event to the stream
+func (p *RedisStreamsPublisher) Publish(ctx context.Context, event Event) error {
+	if err := prepareEvent(&event); err != nil {
+		return err
+	}
+	ctx, span, event := startPublishSpan(ctx, event, systemRedisStreams, p.opts.Stream)
+	defer span.End()
+
+	args, err := p.addArgs(event)
+	if err != nil {
+		return err
+	}
+
+	id, err := p.client.XAdd(ctx, args).Result()
+	if err != nil {
+		span.RecordError(err)
+		return fmt.Errorf("failed to append event to Redis stream: %w", err)
+	}
+
+	log.Printf("Published event: type=%s, id=%s, stream=%s, entry=%s", event.Type, event.ID, p.opts.Stream, id)
+	return nil
+}
+
+// PublishBatch appends multiple events in one round trip
+func (p *RedisStreamsPublisher) PublishBatch(ctx context.Context, events []Event) error {
+	events, err := prepareBatch(events)
+	if err != nil {
+		return err
+	}
+	ctx, span, events := startBatchSpan(ctx, events, systemRedisStreams)
+	defer span.End()
+	pipe := p.client.Pipeline()
+
+	for _, event := range events {
+		args, err := p.addArgs(event)
+		if err != nil {
+			return err
+		}
+		pipe.XAdd(ctx, args)
+	}
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		span.RecordError(err)
+		return fmt.Errorf("failed to execute batch publish: %w", err)
+	}
+
+	log.Printf("Published %d events in batch", len(events))
+	return nil
+}
+
+// Ready pings Redis
+func (p *RedisStreamsPublisher) Ready(ctx context.Context) error {
+	return p.client.Ping(ctx).Err()
+}
+
+// Close closes the Redis connection
+func (p *RedisStreamsPublisher) Close() error {
+	return p.client.Close()
+}
+
+// addArgs builds the XADD arguments for a prepared event
+func (p *RedisStreamsPublisher) addArgs(event Event) (*redis.XAddArgs, error) {
+	eventBytes, err := json.Marshal(event)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal event: %w", err)
+	}
+
+	return &redis.XAddArgs{
+		Stream: p.opts.Stream,
+		MaxLen: p.opts.MaxLen,
+		Approx: p.opts.MaxLen > 0,
+		Values: map[string]interface{}{
+			streamFieldType:  event.Type,
+			streamFieldEvent: eventBytes,
+		},
+	}, nil
+}
+
+// RedisStreamsSubscriberOptions configures a Redis Streams consumer
+type RedisStreamsSubscriberOptions struct {
+	// Stream is the key events are read from
+	Stream string
+
+	// Group is the consumer group: consumers sharing a group split its
+	// events, while each distinct group receives every event
+	Group string
+
+	// Consumer names this consumer within the group. Restarting with the
+	// same name resumes its unacknowledged events.
+	Consumer string
+
+	// Durable groups outlive the subscriber; non-durable groups are
+	// destroyed on Close
+	Durable bool
+
+	// StartID is where a newly created group starts reading: "$" for new
+	// events only, "0" for everything still in the stream
+	StartID string
+
+	// BatchSize bounds events read per XREADGROUP call
+	BatchSize int64
+
+	// Block is how long a read waits for new events
+	Block time.Duration
+
+	// MinIdle is how long an event may stay unacknowledged, e.g. because
+	// its consumer crashed or its handler failed, before it is reclaimed
+	// and redelivered
+	MinIdle time.Duration
+
+	// MaxRetries is how many times an event is redelivered before it is
+	// moved to the "<stream>.dlq" dead-letter stream
+	MaxRetries int64
+}
+
+// DefaultRedisStreamsSubscriberOptions returns options for a durable group
+func DefaultRedisStreamsSubscriberOptions(group, consumer string) RedisStreamsSubscriberOptions {
+	return RedisStreamsSubscriberOptions{
+		Stream:     DefaultEventStream,
+		Group:      group,
+		Consumer:   consumer,
+		Durable:    true,
+		StartID:    "$",
+		BatchSize:  50,
+		Block:      5 * time.Second,
+		MinIdle:    30 * time.Second,
+		MaxRetries: 3,
+	}
+}
+
+// RedisStreamsSubscriber implements Subscriber using a Redis stream and a
+// consumer group.
+//
+// Delivery is at least once: events are acknowledged after the handler
+// succeeds. Failed events stay pending and are reclaimed after MinIdle, by
+// this or any other consumer in the group; after MaxRetries they are moved
+// to the dead-letter stream for inspection. Handlers must be idempotent.
+type RedisStreamsSubscriber struct {
+	client *redis.Client
+	opts   RedisStreamsSubscriberOptions
+}
+
+// NewRedisStreamsSubscriber creates a new Redis Streams event subscriber and
+// its consumer group if it does not exist yet
+func NewRedisStreamsSubscriber(redisURL string, opts RedisStreamsSubscriberOptions) (*RedisStreamsSubscriber, error) {
+	client, err := dialRedis(redisURL)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &RedisStreamsSubscriber{
+		client: client,
+		opts:   opts,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := s.createGroup(ctx); err != nil {
+		client.Close()
+		return nil, err
+	}
+
+	log.Printf("Connected to Redis for event subscription: stream=%s, group=%s, consumer=%s",
+		opts.Stream, opts.Group, opts.Consumer)
+
+	return s, nil
+}
+
+// createGroup creates the consumer group, and the stream with it
+func (s *RedisStreamsSubscriber) createGroup(ctx context.Context) error {
+	startID := s.opts.StartID
+	if startID == "" {
+		startID = "$"
+	}
+
+	err := s.client.XGroupCreateMkStream(ctx, s.opts.Stream, s.opts.Group, startID).Err()
+	if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return fmt.Errorf("failed to create consumer group: %w", err)
+	}
+	return nil
+}
+
+// Subscribe consumes events matching eventTypes until ctx is cancelled.
+// Events of other types are acknowledged without calling the handler.
+func (s *RedisStreamsSubscriber) Subscribe(ctx context.Context, eventTypes []string, handler Handler) error {
+	log.Printf("Subscribed to Redis stream events: stream=%s, group=%s, types=%v", s.opts.Stream, s.opts.Group, eventTypes)
+
+	// Events this consumer received before a restart but never acknowledged
+	if err := s.consume(ctx, "0", eventTypes, handler); err != nil {
+		return s.subscribeError(ctx, err)
+	}
+
+	lastReclaim := time.Now()
+	for {
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		if time.Since(lastReclaim) >= s.opts.MinIdle {
+			if err := s.reclaim(ctx, eventTypes, handler); err != nil {
+				return s.subscribeError(ctx, err)
+			}
+			lastReclaim = time.Now()
+		}
+
+		if err := s.consume(ctx, ">", eventTypes, handler); err != nil {
+			return s.subscribeError(ctx, err)
+		}
+	}
+}
+
+// subscribeError drops errors caused by ctx being cancelled
+func (s *RedisStreamsSubscriber) subscribeError(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return nil
+	}
+	return err
+}
+
+// consume reads one batch for this consumer: new events for ">", or its own
+// pending events for "0"
+func (s *RedisStreamsSubscriber) consume(ctx context.Context, id string, eventTypes []string, handler Handler) error {
+	block := s.opts.Block
+	if id != ">" {
+		// Pending entries are returned immediately; never block on them
+		block = -1
+	}
+
+	streams, err := s.client.XReadGroup(ctx, &redis.XReadGroupArgs{
+		Group:    s.opts.Group,
+		Consumer: s.opts.Consumer,
+		Streams:  []string{s.opts.Stream, id},
+		Count:    s.opts.BatchSize,
+		Block:    block,
+	}).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read from Redis stream: %w", err)
+	}
+
+	for _, stream := range streams {
+		for _, message := range stream.Messages {
+			s.handleMessage(ctx, message, eventTypes, handler)
+		}
+	}
+	return nil
+}
+
+// reclaim takes over events left pending longer than MinIdle, dead-lettering
+// those that have already been retried MaxRetries times
+func (s *RedisStreamsSubscriber) reclaim(ctx context.Context, eventTypes []string, handler Handler) error {
+	pending, err := s.client.XPendingExt(ctx, &redis.XPendingExtArgs{
+		Stream: s.opts.Stream,
+		Group:  s.opts.Group,
+		Idle:   s.opts.MinIdle,
+		Start:  "-",
+		End:    "+",
+		Count:  s.opts.BatchSize,
+	}).Result()
+	if err != nil {
+		return fmt.Errorf("failed to list pending events: %w", err)
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+
+	ids := make([]string, 0, len(pending))
+	retries := make(map[string]int64, len(pending))
+	for _, entry := range pending {
+		ids = append(ids, entry.ID)
+		retries[entry.ID] = entry.RetryCount
+	}
+
+	// Claiming bumps the delivery count; entries another consumer claimed
+	// first are simply not returned
+	messages, err := s.client.XClaim(ctx, &redis.XClaimArgs{
+		Stream:   s.opts.Stream,
+		Group:    s.opts.Group,
+		Consumer: s.opts.Consumer,
+		MinIdle:  s.opts.MinIdle,
+		Messages: ids,
+	}).Result()
+	if err != nil {
+		return fmt.Errorf("failed to claim pending events: %w", err)
+	}
+
+	for _, message := range messages {
+		if retries[message.ID] > s.opts.MaxRetries {
+			s.deadLetter(ctx, message, retries[message.ID])
+			continue
+		}
+		log.Printf("Redelivering pending event: entry=%s, delivery=%d", message.ID, retries[message.ID]+1)
+		s.handleMessage(ctx, message, eventTypes, handler)
+	}
+	return nil
+}
+
+// handleMessage runs the handler and acknowledges the entry on success.
+// Failed entries stay pending until reclaimed.
+func (s *RedisStreamsSubscriber) handleMessage(ctx context.Context, message redis.XMessage, eventTypes []string, handler Handler) {
+	eventType, _ := message.Values[streamFieldType].(string)
+	if !matchesAny(eventTypes, eventType) {
+		s.ack(ctx, message.ID)
+		return
+	}
+
+	payload, _ := message.Values[streamFieldEvent].(string)
+	var event Event
+	if err := json.Unmarshal([]byte(payload), &event); err != nil {
+		// Malformed events can never succeed: dead-letter immediately
+		log.Printf("Error unmarshaling event, dead-lettering: entry=%s, err=%v", message.ID, err)
+		s.deadLetter(ctx, message, 0)
+		return
+	}
+
+	if err := handleTraced(ctx, event, systemRedisStreams, nil, handler); err != nil {
+		log.Printf("Event failed, will retry after %s: type=%s, id=%s, err=%v", s.opts.MinIdle, event.Type, event.ID, err)
+		return
+	}
+	s.ack(ctx, message.ID)
+}
+
+// deadLetter copies an entry to the dead-letter stream and acknowledges it
+func (s *RedisStreamsSubscriber) deadLetter(ctx context.Context, message redis.XMessage, deliveries int64) {
+	values := make(map[string]interface{}, len(message.Values)+3)
+	for key, value := range message.Values {
+		values[key] = value
+	}
+	values["group"] = s.opts.Group
+	values["entry"] = message.ID
+	values["deliveries"] = deliveries
+
+	err := s.client.XAdd(ctx, &redis.XAddArgs{
+		Stream: s.opts.Stream + ".dlq",
+		Values: values,
+	}).Err()
+	if err != nil {
+		// Leave it pending rather than lose it
+		log.Printf("Error dead-lettering event: entry=%s, err=%v", message.ID, err)
+		return
+	}
+
+	log.Printf("Event dead-lettered: entry=%s, deliveries=%d", message.ID, deliveries)
+	s.ack(ctx, message.ID)
+}
+
+// ack acknowledges an entry for the group
+func (s *RedisStreamsSubscriber) ack(ctx context.Context, id string) {
+	if err := s.client.XAck(ctx, s.opts.Stream, s.opts.Group, id).Err(); err != nil {
+		log.Printf("Error acknowledging event: entry=%s, err=%v", id, err)
+	}
+}
+
+// Ready pings Redis
+func (s *RedisStreamsSubscriber) Ready(ctx context.Context) error {
+	return s.client.Ping(ctx).Err()
+}
+
+// Close destroys a non-durable consumer group and closes the Redis connection
+func (s *RedisStreamsSubscriber) Close() error {
+	if !s.opts.Durable {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		if err := s.client.XGroupDestroy(ctx, s.opts.Stream, s.opts.Group).Err(); err != nil {
+			log.Printf("Error destroying consumer group %s: %v", s.opts.Group, err)
+		}
+	}
+	return s.client.Close()
+}
+
+// dialRedis connects to Redis and checks the connection
+func dialRedis(redisURL string) (*redis.Client, error) {
+	opts, err := redis.ParseURL(redisURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse Redis URL: %w", err)
+	}
+
+	client := redis.NewClient(opts)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("failed to connect to Redis: %w", err)
+	}
+	return client, nil
+}
+
+// matchesAny reports whether eventType matches one of the patterns, where
+// "*" matches one dot-separated word
+func matchesAny(patterns []string, eventType string) bool {
+	for _, pattern := range patterns {
+		if matchEventType(pattern, eventType) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchEventType reports whether eventType matches pattern
+func matchEventType(pattern, eventType string) bool {
+	patternWords := strings.Split(pattern, ".")
+	typeWords := strings.Split(eventType, ".")
+	if len(patternWords) != len(typeWords) {
+		return false
+	}
+	for i, word := range patternWords {
+		if word != "*" && word != typeWords[i] {
+			return false
+		}
+	}
+	return true
+}
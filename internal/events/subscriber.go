@@ -0,0 +1,339 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/redis/go-redis/v9"
+)
+
+// Handler processes a single event; a returned error marks it for retry
+type Handler func(ctx context.Context, event Event) error
+
+// Subscriber defines the interface for consuming events.
+//
+// Event types may use "*" to match one dot-separated word, e.g. "stream.*".
+// Subscribe blocks until ctx is cancelled or the subscription fails.
+type Subscriber interface {
+	Subscribe(ctx context.Context, eventTypes []string, handler Handler) error
+	Close() error
+}
+
+// RedisSubscriber implements Subscriber using Redis Pub/Sub.
+//
+// Pub/Sub delivers each event to every subscriber at most once, with no
+// acknowledgement: handler errors are logged and the event is dropped.
+type RedisSubscriber struct {
+	client *redis.Client
+}
+
+// NewRedisSubscriber creates a new Redis-based event subscriber
+func NewRedisSubscriber(redisURL string) (*RedisSubscriber, error) {
+	opts, err := redis.ParseURL(redisURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse Redis URL: %w", err)
+	}
+
+	client := redis.NewClient(opts)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := client.Ping(ctx).Err(); err != nil {
+		return nil, fmt.Errorf("failed to connect to Redis: %w", err)
+	}
+
+	log.Println("Connected to Redis for event subscription")
+
+	return &RedisSubscriber{
+		client: client,
+	}, nil
+}
+
+// Subscribe consumes events published by RedisPublisher
+func (s *RedisSubscriber) Subscribe(ctx context.Context, eventTypes []string, handler Handler) error {
+	channels := make([]string, 0, len(eventTypes))
+	for _, eventType := range eventTypes {
+		channels = append(channels, fmt.Sprintf("events:%s", eventType))
+	}
+
+	// Patterns cover both exact channels and "*" wildcards
+	pubsub := s.client.PSubscribe(ctx, channels...)
+	defer pubsub.Close()
+
+	if _, err := pubsub.Receive(ctx); err != nil {
+		return fmt.Errorf("failed to subscribe to Redis channels: %w", err)
+	}
+
+	log.Printf("Subscribed to Redis events: %v", eventTypes)
+
+	messages := pubsub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case message, ok := <-messages:
+			if !ok {
+				return fmt.Errorf("redis subscription closed")
+			}
+
+			var event Event
+			if err := json.Unmarshal([]byte(message.Payload), &event); err != nil {
+				log.Printf("Error unmarshaling event from %s: %v", message.Channel, err)
+				continue
+			}
+
+			if err := handleTraced(ctx, event, systemRedis, nil, handler); err != nil {
+				log.Printf("Error handling event: type=%s, id=%s, err=%v", event.Type, event.ID, err)
+			}
+		}
+	}
+}
+
+// Ready pings Redis
+func (s *RedisSubscriber) Ready(ctx context.Context) error {
+	return s.client.Ping(ctx).Err()
+}
+
+// Close closes the Redis connection
+func (s *RedisSubscriber) Close() error {
+	return s.client.Close()
+}
+
+// RabbitMQSubscriberOptions configures a RabbitMQ consumer queue
+type RabbitMQSubscriberOptions struct {
+	// Queue is the consumer group: instances sharing a queue split its
+	// events, while each distinct queue receives every event
+	Queue string
+
+	// Durable queues survive broker restarts; non-durable queues are
+	// deleted when the last consumer disconnects
+	Durable bool
+
+	// Prefetch bounds unacknowledged deliveries per consumer
+	Prefetch int
+
+	// MaxRetries is how many times a failed event is redelivered before it
+	// is dead-lettered
+	MaxRetries int
+
+	// RetryDelay is how long a failed event waits before redelivery
+	RetryDelay time.Duration
+}
+
+// DefaultRabbitMQSubscriberOptions returns options for a durable shared queue
+func DefaultRabbitMQSubscriberOptions(queue string) RabbitMQSubscriberOptions {
+	return RabbitMQSubscriberOptions{
+		Queue:      queue,
+		Durable:    true,
+		Prefetch:   50,
+		MaxRetries: 3,
+		RetryDelay: 5 * time.Second,
+	}
+}
+
+// retryCountHeader records how many times an event has been retried
+const retryCountHeader = "x-retry-count"
+
+// RabbitMQSubscriber implements Subscriber using RabbitMQ.
+//
+// Events are consumed from a queue bound to the "events" topic exchange and
+// acknowledged after the handler succeeds. Failed events are parked in a
+// "<queue>.retry" queue for RetryDelay, then redelivered; after MaxRetries
+// they are moved to the "<queue>.dlq" dead-letter queue for inspection.
+type RabbitMQSubscriber struct {
+	conn    *amqp.Connection
+	channel *amqp.Channel
+	opts    RabbitMQSubscriberOptions
+}
+
+// NewRabbitMQSubscriber creates a new RabbitMQ-based event subscriber
+func NewRabbitMQSubscriber(amqpURL string, opts RabbitMQSubscriberOptions) (*RabbitMQSubscriber, error) {
+	conn, err := amqp.Dial(amqpURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to RabbitMQ: %w", err)
+	}
+
+	channel, err := conn.Channel()
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to open channel: %w", err)
+	}
+
+	s := &RabbitMQSubscriber{
+		conn:    conn,
+		channel: channel,
+		opts:    opts,
+	}
+	if err := s.declareTopology(); err != nil {
+		channel.Close()
+		conn.Close()
+		return nil, err
+	}
+
+	log.Printf("Connected to RabbitMQ for event subscription: queue=%s", opts.Queue)
+
+	return s, nil
+}
+
+// declareTopology declares the exchange, consumer queue, retry queue, and DLQ
+func (s *RabbitMQSubscriber) declareTopology() error {
+	err := s.channel.ExchangeDeclare("events", "topic", true, false, false, false, nil)
+	if err != nil {
+		return fmt.Errorf("failed to declare exchange: %w", err)
+	}
+
+	autoDelete := !s.opts.Durable
+
+	// Dead-letter queue: rejected events land here
+	dlq := s.opts.Queue + ".dlq"
+	if _, err := s.channel.QueueDeclare(dlq, s.opts.Durable, autoDelete, false, false, nil); err != nil {
+		return fmt.Errorf("failed to declare dead-letter queue: %w", err)
+	}
+
+	// Consumer queue: rejections are routed to the DLQ via the default exchange
+	_, err = s.channel.QueueDeclare(s.opts.Queue, s.opts.Durable, autoDelete, false, false, amqp.Table{
+		"x-dead-letter-exchange":    "",
+		"x-dead-letter-routing-key": dlq,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to declare queue: %w", err)
+	}
+
+	// Retry queue: events expire back into the consumer queue after the delay
+	_, err = s.channel.QueueDeclare(s.opts.Queue+".retry", s.opts.Durable, autoDelete, false, false, amqp.Table{
+		"x-message-ttl":             s.opts.RetryDelay.Milliseconds(),
+		"x-dead-letter-exchange":    "",
+		"x-dead-letter-routing-key": s.opts.Queue,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to declare retry queue: %w", err)
+	}
+
+	if s.opts.Prefetch > 0 {
+		if err := s.channel.Qos(s.opts.Prefetch, 0, false); err != nil {
+			return fmt.Errorf("failed to set prefetch: %w", err)
+		}
+	}
+	return nil
+}
+
+// Subscribe binds the queue to eventTypes and consumes until ctx is cancelled
+func (s *RabbitMQSubscriber) Subscribe(ctx context.Context, eventTypes []string, handler Handler) error {
+	for _, eventType := range eventTypes {
+		if err := s.channel.QueueBind(s.opts.Queue, eventType, "events", false, nil); err != nil {
+			return fmt.Errorf("failed to bind %s: %w", eventType, err)
+		}
+	}
+
+	deliveries, err := s.channel.ConsumeWithContext(ctx, s.opts.Queue, "", false, false, false, false, nil)
+	if err != nil {
+		return fmt.Errorf("failed to start consuming: %w", err)
+	}
+
+	log.Printf("Subscribed to RabbitMQ events: queue=%s, types=%v", s.opts.Queue, eventTypes)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case delivery, ok := <-deliveries:
+			if !ok {
+				return fmt.Errorf("rabbitmq delivery channel closed")
+			}
+			s.handleDelivery(ctx, delivery, handler)
+		}
+	}
+}
+
+// handleDelivery runs the handler and acks, retries, or dead-letters the delivery
+func (s *RabbitMQSubscriber) handleDelivery(ctx context.Context, delivery amqp.Delivery, handler Handler) {
+	var event Event
+	if err := json.Unmarshal(delivery.Body, &event); err != nil {
+		// Malformed events can never succeed: dead-letter immediately
+		log.Printf("Error unmarshaling event, dead-lettering: routingKey=%s, err=%v", delivery.RoutingKey, err)
+		delivery.Nack(false, false)
+		return
+	}
+
+	err := handleTraced(ctx, event, systemRabbitMQ, delivery.Headers, handler)
+	if err == nil {
+		delivery.Ack(false)
+		return
+	}
+
+	retries := retryCount(delivery.Headers)
+	if retries >= s.opts.MaxRetries {
+		log.Printf("Event failed %d times, dead-lettering: type=%s, id=%s, err=%v", retries+1, event.Type, event.ID, err)
+		delivery.Nack(false, false)
+		return
+	}
+
+	log.Printf("Event failed, scheduling retry %d/%d: type=%s, id=%s, err=%v", retries+1, s.opts.MaxRetries, event.Type, event.ID, err)
+
+	headers := amqp.Table{}
+	for key, value := range delivery.Headers {
+		headers[key] = value
+	}
+	headers[retryCountHeader] = int32(retries + 1)
+
+	err = s.channel.PublishWithContext(ctx, "", s.opts.Queue+".retry", false, false, amqp.Publishing{
+		ContentType:  delivery.ContentType,
+		Body:         delivery.Body,
+		DeliveryMode: amqp.Persistent,
+		Timestamp:    delivery.Timestamp,
+		MessageId:    delivery.MessageId,
+		Headers:      headers,
+	})
+	if err != nil {
+		// Could not park it for retry; requeue rather than lose it
+		log.Printf("Error scheduling retry: %v", err)
+		delivery.Nack(false, true)
+		return
+	}
+	delivery.Ack(false)
+}
+
+// Ready reports whether the RabbitMQ connection and channel are still open
+func (s *RabbitMQSubscriber) Ready(ctx context.Context) error {
+	return amqpReady(s.conn, s.channel)
+}
+
+// Close closes the RabbitMQ connection
+func (s *RabbitMQSubscriber) Close() error {
+	if err := s.channel.Close(); err != nil {
+		return err
+	}
+	return s.conn.Close()
+}
+
+// retryCount reads the retry counter from delivery headers
+func retryCount(headers amqp.Table) int {
+	switch n := headers[retryCountHeader].(type) {
+	case int32:
+		return int(n)
+	case int64:
+		return int(n)
+	case int:
+		return n
+	default:
+		return 0
+	}
+}
+
+// errAMQPClosed is returned by readiness checks once RabbitMQ has closed
+// the connection or channel
+var errAMQPClosed = errors.New("RabbitMQ connection closed")
+
+// amqpReady reports whether a RabbitMQ connection and its channel are open
+func amqpReady(conn *amqp.Connection, channel *amqp.Channel) error {
+	if conn.IsClosed() || channel.IsClosed() {
+		return errAMQPClosed
+	}
+	return nil
+}
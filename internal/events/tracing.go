@@ -0,0 +1,96 @@
+package events
+
+import (
+	"context"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/tinle0301/streaming-platform-api/internal/tracing"
+)
+
+// Messaging systems recorded on event spans
+const (
+	systemRedis        = "redis"
+	systemRedisStreams = "redis_streams"
+	systemRabbitMQ     = "rabbitmq"
+	systemKafka        = "kafka"
+	systemNATS         = "nats"
+)
+
+// startPublishSpan starts a producer span for publishing event and returns
+// a copy of the event carrying the span's trace context in its metadata
+func startPublishSpan(ctx context.Context, event Event, system, destination string) (context.Context, *tracing.Span, Event) {
+	ctx, span := tracing.Start(ctx, event.Type+" publish", tracing.KindProducer,
+		tracing.String("messaging.system", system),
+		tracing.String("messaging.operation", "publish"),
+		tracing.String("messaging.destination.name", destination),
+		tracing.String("messaging.message.id", event.ID),
+	)
+	return ctx, span, withTraceContext(ctx, event)
+}
+
+// startBatchSpan starts a producer span for publishing a batch and returns
+// copies of the events carrying the span's trace context
+func startBatchSpan(ctx context.Context, batch []Event, system string) (context.Context, *tracing.Span, []Event) {
+	ctx, span := tracing.Start(ctx, "batch publish", tracing.KindProducer,
+		tracing.String("messaging.system", system),
+		tracing.String("messaging.operation", "publish"),
+		tracing.Int("messaging.batch.message_count", len(batch)),
+	)
+	traced := make([]Event, len(batch))
+	for i, event := range batch {
+		traced[i] = withTraceContext(ctx, event)
+	}
+	return ctx, span, traced
+}
+
+// withTraceContext returns event with ctx's trace context in a copy of its
+// metadata, leaving other metadata as it was
+func withTraceContext(ctx context.Context, event Event) Event {
+	if !tracing.SpanContextFromContext(ctx).IsValid() {
+		return event
+	}
+	metadata := make(map[string]string, len(event.Metadata)+2)
+	for key, value := range event.Metadata {
+		metadata[key] = value
+	}
+	delete(metadata, tracing.TracestateKey)
+	tracing.Inject(ctx, func(key, value string) { metadata[key] = value })
+	event.Metadata = metadata
+	return event
+}
+
+// traceHeaders returns AMQP headers carrying the event's trace context
+func traceHeaders(event Event) amqp.Table {
+	headers := amqp.Table{}
+	for _, key := range []string{tracing.TraceparentKey, tracing.TracestateKey} {
+		if value, ok := event.Metadata[key]; ok {
+			headers[key] = value
+		}
+	}
+	return headers
+}
+
+// handleTraced runs handler inside a consumer span, continuing the trace
+// its publisher propagated in message headers, if given, or in its metadata.
+// The handler receives the event upcast to the latest version of its type.
+func handleTraced(ctx context.Context, event Event, system string, headers map[string]interface{}, handler Handler) error {
+	ctx = tracing.Extract(ctx, func(key string) string {
+		if value, ok := headers[key].(string); ok {
+			return value
+		}
+		return event.Metadata[key]
+	})
+	ctx, span := tracing.Start(ctx, event.Type+" process", tracing.KindConsumer,
+		tracing.String("messaging.system", system),
+		tracing.String("messaging.operation", "process"),
+		tracing.String("messaging.message.id", event.ID),
+	)
+	defer span.End()
+
+	event, err := UpcastEvent(event)
+	if err == nil {
+		err = handler(ctx, event)
+	}
+	span.RecordError(err)
+	return err
+}
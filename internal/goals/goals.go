@@ -0,0 +1,276 @@
+// Package goals tracks channels' follower, subscription, and cheer goals.
+// Follower goals count the channel's followers; subscription and cheer
+// goals count what arrived since the goal was set. Progress changes are
+// published to the channel's overlay room, and a goal that reaches its
+// target completes with a celebration event.
+package goals
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/tinle0301/streaming-platform-api/internal/store"
+	"github.com/tinle0301/streaming-platform-api/internal/tenancy"
+)
+
+// Goal kinds, matching the GraphQL GoalKind enum
+const (
+	KindFollowers     = "FOLLOWERS"
+	KindSubscriptions = "SUBSCRIPTIONS"
+	KindCheers        = "CHEERS"
+)
+
+// Goal statuses, matching the GraphQL GoalStatus enum
+const (
+	StatusActive    = "ACTIVE"
+	StatusCompleted = "COMPLETED"
+	StatusCancelled = "CANCELLED"
+)
+
+// Repository errors
+var (
+	ErrNotFound      = errors.New("goal not found")
+	ErrAlreadyActive = errors.New("channel already has an active goal of this kind")
+	ErrNotActive     = errors.New("goal has already ended")
+	ErrTargetReached = errors.New("goal target must be above the current count")
+)
+
+// Goal is a target a channel counts toward
+type Goal struct {
+	ID          string
+	ChannelID   string
+	Kind        string
+	Description string
+	Target      int64
+	Status      string
+	CreatedAt   time.Time
+	EndedAt     *time.Time
+
+	// Current is the progress so far, frozen once the goal ends
+	Current int64
+}
+
+// Progress is the fraction of the target reached, at most 1
+func (g *Goal) Progress() float64 {
+	if g.Current >= g.Target {
+		return 1
+	}
+	return float64(g.Current) / float64(g.Target)
+}
+
+// Reached reports whether the goal's target has been reached
+func (g *Goal) Reached() bool {
+	return g.Current >= g.Target
+}
+
+// Repository persists goals and the contributions counted toward them.
+// Goals are created in the context's tenant, and other tenants' goals are
+// treated as missing.
+type Repository interface {
+	// Create stores an active goal and fills in its ID, timestamp, and
+	// current progress. A follower goal the channel has already reached is
+	// not stored and fails with ErrTargetReached.
+	Create(ctx context.Context, goal *Goal) error
+
+	Get(ctx context.Context, id string) (*Goal, error)
+
+	// List returns a channel's goals, newest first; ended goals only if
+	// includeEnded
+	List(ctx context.Context, channelID string, includeEnded bool) ([]*Goal, error)
+
+	// Active returns a channel's active goal of kind, or nil
+	Active(ctx context.Context, channelID, kind string) (*Goal, error)
+
+	// AddContribution counts a subscription or cheer event toward the
+	// channel's goals. It reports false if the event was already counted.
+	AddContribution(ctx context.Context, channelID, kind, eventID string, amount int64) (bool, error)
+
+	// End moves an active goal to status, freezing its progress. It reports
+	// false if the goal had already ended, so only one caller ends it.
+	End(ctx context.Context, id, status string) (*Goal, bool, error)
+}
+
+// goalColumns is the column list shared by goal queries; queries alias
+// channel_goals as g
+const goalColumns = `g.id::text, g.channel_id::text, g.kind, g.description, g.target, g.status, g.created_at, g.ended_at`
+
+// currentValue computes a goal's progress from the follower and
+// contribution counters; queries alias channel_goals as g
+const currentValue = `CASE g.kind
+	WHEN 'FOLLOWERS' THEN (SELECT COUNT(*) FROM follows WHERE followed_id = g.channel_id)
+	ELSE (SELECT COALESCE(SUM(c.amount), 0) FROM channel_goal_contributions c
+		WHERE c.channel_id = g.channel_id AND c.kind = g.kind AND c.created_at >= g.created_at)
+	END`
+
+// selectGoals reads goals with their progress
+const selectGoals = `SELECT ` + goalColumns + `, COALESCE(g.final_value, ` + currentValue + `) FROM channel_goals g`
+
+// PostgresRepository implements Repository on PostgreSQL
+type PostgresRepository struct {
+	pool *pgxpool.Pool
+}
+
+// NewPostgresRepository creates a goal repository on pool
+func NewPostgresRepository(pool *pgxpool.Pool) *PostgresRepository {
+	return &PostgresRepository{pool: pool}
+}
+
+// Create inserts an active goal
+func (r *PostgresRepository) Create(ctx context.Context, goal *Goal) error {
+	if !store.IsUUID(goal.ChannelID) {
+		return ErrNotFound
+	}
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	err = tx.QueryRow(ctx, `
+		WITH g AS (
+			INSERT INTO channel_goals (channel_id, kind, description, target, tenant_id)
+			VALUES ($1, $2, $3, $4, $5)
+			RETURNING id, channel_id, kind, created_at
+		)
+		SELECT g.id::text, g.created_at, `+currentValue+` FROM g`,
+		goal.ChannelID, goal.Kind, goal.Description, goal.Target, tenancy.ID(ctx),
+	).Scan(&goal.ID, &goal.CreatedAt, &goal.Current)
+
+	switch store.PgCode(err) {
+	case "23505":
+		return ErrAlreadyActive
+	case "23503":
+		return ErrNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create goal: %w", err)
+	}
+	if goal.Reached() {
+		return ErrTargetReached
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit goal: %w", err)
+	}
+	goal.Status = StatusActive
+	return nil
+}
+
+// Get reads a goal
+func (r *PostgresRepository) Get(ctx context.Context, id string) (*Goal, error) {
+	if !store.IsUUID(id) {
+		return nil, ErrNotFound
+	}
+
+	goal, err := scanGoal(r.pool.QueryRow(ctx, selectGoals+` WHERE g.id = $1`+tenancy.Condition("g.tenant_id", 2),
+		id, tenancy.Scope(ctx)))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get goal: %w", err)
+	}
+	return goal, nil
+}
+
+// List reads a channel's goals
+func (r *PostgresRepository) List(ctx context.Context, channelID string, includeEnded bool) ([]*Goal, error) {
+	if !store.IsUUID(channelID) {
+		return []*Goal{}, nil
+	}
+
+	rows, err := r.pool.Query(ctx, selectGoals+`
+		WHERE g.channel_id = $1 AND ($2 OR g.status = 'ACTIVE')`+tenancy.Condition("g.tenant_id", 3)+`
+		ORDER BY g.created_at DESC`, channelID, includeEnded, tenancy.Scope(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list goals: %w", err)
+	}
+	defer rows.Close()
+
+	goals := []*Goal{}
+	for rows.Next() {
+		goal, err := scanGoal(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan goal: %w", err)
+		}
+		goals = append(goals, goal)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list goals: %w", err)
+	}
+	return goals, nil
+}
+
+// Active reads a channel's active goal of kind
+func (r *PostgresRepository) Active(ctx context.Context, channelID, kind string) (*Goal, error) {
+	if !store.IsUUID(channelID) {
+		return nil, nil
+	}
+
+	goal, err := scanGoal(r.pool.QueryRow(ctx, selectGoals+`
+		WHERE g.channel_id = $1 AND g.kind = $2 AND g.status = 'ACTIVE'`+tenancy.Condition("g.tenant_id", 3),
+		channelID, kind, tenancy.Scope(ctx)))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get active goal: %w", err)
+	}
+	return goal, nil
+}
+
+// AddContribution records a contribution once per event
+func (r *PostgresRepository) AddContribution(ctx context.Context, channelID, kind, eventID string, amount int64) (bool, error) {
+	if !store.IsUUID(channelID) {
+		return false, nil
+	}
+
+	tag, err := r.pool.Exec(ctx, `
+		INSERT INTO channel_goal_contributions (channel_id, event_id, kind, amount, tenant_id)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT DO NOTHING`, channelID, eventID, kind, amount, tenancy.ID(ctx))
+
+	if store.PgCode(err) == "23503" {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to add goal contribution: %w", err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// End ends an active goal
+func (r *PostgresRepository) End(ctx context.Context, id, status string) (*Goal, bool, error) {
+	if !store.IsUUID(id) {
+		return nil, false, ErrNotFound
+	}
+
+	goal, err := scanGoal(r.pool.QueryRow(ctx, `
+		UPDATE channel_goals g SET status = $2, ended_at = NOW(), final_value = `+currentValue+`
+		WHERE g.id = $1 AND g.status = 'ACTIVE'`+tenancy.Condition("g.tenant_id", 3)+`
+		RETURNING `+goalColumns+`, g.final_value`, id, status, tenancy.Scope(ctx)))
+	if errors.Is(err, pgx.ErrNoRows) {
+		// Already ended, or never existed
+		goal, err := r.Get(ctx, id)
+		return goal, false, err
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to end goal: %w", err)
+	}
+	return goal, true, nil
+}
+
+// scanGoal scans goalColumns followed by the goal's progress
+func scanGoal(row pgx.Row) (*Goal, error) {
+	var g Goal
+	if err := row.Scan(&g.ID, &g.ChannelID, &g.Kind, &g.Description, &g.Target, &g.Status,
+		&g.CreatedAt, &g.EndedAt, &g.Current); err != nil {
+		return nil, err
+	}
+	return &g, nil
+}
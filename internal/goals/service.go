@@ -0,0 +1,181 @@
+package goals
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+
+	"github.com/tinle0301/streaming-platform-api/internal/events"
+)
+
+// Goal limits
+const (
+	MaxTarget            = 1_000_000_000
+	maxDescriptionLength = 100
+)
+
+// Validation errors
+var (
+	ErrInvalidKind        = errors.New("unknown goal kind")
+	ErrInvalidTarget      = errors.New("goal target must be between 1 and 1000000000")
+	ErrDescriptionTooLong = errors.New("goal description must be at most 100 characters")
+)
+
+// SourceEventTypes are the domain events that count toward goals
+var SourceEventTypes = []string{
+	events.EventTypeNewFollower,
+	events.EventTypeSubscription,
+	events.EventTypeGiftSubscription,
+	events.EventTypeBitsCheered,
+}
+
+// Service manages channels' goals and moves them along as followers,
+// subscriptions, and cheers arrive
+type Service struct {
+	repo Repository
+
+	publisher events.Publisher
+}
+
+// NewService creates a goal service
+func NewService(repo Repository) *Service {
+	return &Service{repo: repo}
+}
+
+// SetPublisher enables goal.progress and goal.completed events for the
+// channels' overlays
+func (s *Service) SetPublisher(publisher events.Publisher) {
+	s.publisher = publisher
+}
+
+// Create starts a goal for a channel; it may run one goal of each kind
+func (s *Service) Create(ctx context.Context, channelID, kind, description string, target int64) (*Goal, error) {
+	switch kind {
+	case KindFollowers, KindSubscriptions, KindCheers:
+	default:
+		return nil, ErrInvalidKind
+	}
+	if target < 1 || target > MaxTarget {
+		return nil, ErrInvalidTarget
+	}
+	description = strings.TrimSpace(description)
+	if len([]rune(description)) > maxDescriptionLength {
+		return nil, ErrDescriptionTooLong
+	}
+
+	goal := &Goal{ChannelID: channelID, Kind: kind, Description: description, Target: target}
+	if err := s.repo.Create(ctx, goal); err != nil {
+		return nil, err
+	}
+
+	s.publish(ctx, events.EventTypeGoalProgress, goal)
+	return goal, nil
+}
+
+// Get returns a goal
+func (s *Service) Get(ctx context.Context, id string) (*Goal, error) {
+	return s.repo.Get(ctx, id)
+}
+
+// List returns a channel's active goals, and its ended ones if includeEnded
+func (s *Service) List(ctx context.Context, channelID string, includeEnded bool) ([]*Goal, error) {
+	return s.repo.List(ctx, channelID, includeEnded)
+}
+
+// Cancel ends an active goal early
+func (s *Service) Cancel(ctx context.Context, id string) (*Goal, error) {
+	goal, ended, err := s.repo.End(ctx, id, StatusCancelled)
+	if err != nil {
+		return nil, err
+	}
+	if !ended {
+		return nil, ErrNotActive
+	}
+	s.publish(ctx, events.EventTypeGoalProgress, goal)
+	return goal, nil
+}
+
+// HandleEvent counts an event toward the channel's active goal of its kind,
+// publishing the new progress, and completes the goal once it reaches its
+// target. Events are addressed to the channel (event.UserID):
+//
+//	user.new_follower   counts toward FOLLOWERS
+//	subscription.new    1 toward SUBSCRIPTIONS
+//	subscription.gift   count toward SUBSCRIPTIONS
+//	bits.cheered        bits toward CHEERS
+func (s *Service) HandleEvent(ctx context.Context, event events.Event) error {
+	channelID := event.UserID
+	if channelID == "" {
+		return nil
+	}
+
+	var kind string
+	var amount int64
+	switch event.Type {
+	case events.EventTypeNewFollower:
+		kind = KindFollowers
+	case events.EventTypeSubscription:
+		kind, amount = KindSubscriptions, 1
+	case events.EventTypeGiftSubscription:
+		kind, amount = KindSubscriptions, events.DataInt(event.Data, "count", 1)
+	case events.EventTypeBitsCheered:
+		kind, amount = KindCheers, events.DataInt(event.Data, "bits", 0)
+	default:
+		return nil
+	}
+
+	// Followers are counted from the follows themselves
+	if kind != KindFollowers {
+		if amount <= 0 {
+			return nil
+		}
+		added, err := s.repo.AddContribution(ctx, channelID, kind, event.ID, amount)
+		if err != nil || !added {
+			return err
+		}
+	}
+
+	goal, err := s.repo.Active(ctx, channelID, kind)
+	if err != nil || goal == nil {
+		return err
+	}
+	if !goal.Reached() {
+		s.publish(ctx, events.EventTypeGoalProgress, goal)
+		return nil
+	}
+
+	// Only the replica that ends the goal celebrates it
+	completed, ended, err := s.repo.End(ctx, goal.ID, StatusCompleted)
+	if err != nil || !ended {
+		return err
+	}
+	s.publish(ctx, events.EventTypeGoalCompleted, completed)
+	return nil
+}
+
+// publish tells the channel's overlays about a goal. The change is already
+// stored, so a lost event only delays the overlay until its next update.
+func (s *Service) publish(ctx context.Context, eventType string, goal *Goal) {
+	if s.publisher == nil {
+		return
+	}
+
+	event := events.NewGoalEvent(eventType, goal.ChannelID, eventData(goal))
+	if err := s.publisher.Publish(ctx, event); err != nil {
+		log.Printf("Error publishing %s event: goalID=%s, err=%v", eventType, goal.ID, err)
+	}
+}
+
+// eventData is the goal.* event data for goal
+func eventData(goal *Goal) map[string]interface{} {
+	return map[string]interface{}{
+		"goal_id":     goal.ID,
+		"kind":        goal.Kind,
+		"description": goal.Description,
+		"status":      goal.Status,
+		"current":     goal.Current,
+		"target":      goal.Target,
+		"progress":    goal.Progress(),
+	}
+}
@@ -0,0 +1,69 @@
+package graphql
+
+import (
+	"context"
+	"errors"
+
+	gql "github.com/graph-gophers/graphql-go"
+	"github.com/tinle0301/streaming-platform-api/internal/compliance"
+	"github.com/tinle0301/streaming-platform-api/internal/users"
+)
+
+// SetAgeGate enables Mutation.setBirthDate and Mutation.acceptMatureContent
+func (r *Resolver) SetAgeGate(gate *compliance.AgeGate) {
+	r.ageGate = gate
+}
+
+// SetBirthDate resolves Mutation.setBirthDate
+func (r *Resolver) SetBirthDate(ctx context.Context, args struct{ BirthDate gql.Time }) (*User, error) {
+	if r.ageGate == nil || r.users == nil {
+		return nil, errNotImplemented("setBirthDate")
+	}
+	claims, ok := users.ClaimsFromContext(ctx)
+	if !ok {
+		return nil, newError(CodeUnauthenticated, "sign in to set your birth date")
+	}
+
+	err := r.ageGate.RecordBirthDate(ctx, claims.UserID(), args.BirthDate.Time)
+	if errors.Is(err, compliance.ErrInvalidBirthDate) {
+		return nil, newError(CodeBadUserInput, err.Error())
+	}
+	if err != nil {
+		return nil, internalError("setBirthDate", err)
+	}
+	return r.ageGateViewer(ctx, "setBirthDate", claims.UserID())
+}
+
+// AcceptMatureContent resolves Mutation.acceptMatureContent
+func (r *Resolver) AcceptMatureContent(ctx context.Context) (*User, error) {
+	if r.ageGate == nil || r.users == nil {
+		return nil, errNotImplemented("acceptMatureContent")
+	}
+	claims, ok := users.ClaimsFromContext(ctx)
+	if !ok {
+		return nil, newError(CodeUnauthenticated, "sign in to accept mature content")
+	}
+
+	err := r.ageGate.RecordConsent(ctx, claims.UserID())
+	switch {
+	case errors.Is(err, compliance.ErrAgeVerificationRequired):
+		return nil, newError(CodeBadUserInput, "set your birth date before accepting mature content")
+	case errors.Is(err, compliance.ErrUnderage):
+		return nil, newError(CodeForbidden, err.Error())
+	case err != nil:
+		return nil, internalError("acceptMatureContent", err)
+	}
+	return r.ageGateViewer(ctx, "acceptMatureContent", claims.UserID())
+}
+
+// ageGateViewer returns the viewer's account after an age gate change
+func (r *Resolver) ageGateViewer(ctx context.Context, field, userID string) (*User, error) {
+	user, err := r.users.Get(ctx, userID)
+	if errors.Is(err, users.ErrNotFound) {
+		return nil, newError(CodeUnauthenticated, "account not found")
+	}
+	if err != nil {
+		return nil, internalError(field, err)
+	}
+	return userFromAccount(user, r.users, true), nil
+}
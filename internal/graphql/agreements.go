@@ -0,0 +1,97 @@
+package graphql
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	gql "github.com/graph-gophers/graphql-go"
+	"github.com/tinle0301/streaming-platform-api/internal/consent"
+	"github.com/tinle0301/streaming-platform-api/internal/users"
+)
+
+// agreementFields are the root fields a user who hasn't accepted the latest
+// agreements may still use, to read and accept them
+var agreementFields = map[string]bool{
+	"__typename":        true,
+	"pendingAgreements": true,
+	"acceptAgreement":   true,
+}
+
+// SetAgreements enables Query.pendingAgreements and Mutation.acceptAgreement,
+// and refuses every other operation from users who haven't accepted the
+// latest agreements in registry
+func (r *Resolver) SetAgreements(registry *consent.Registry) {
+	r.agreements = registry
+}
+
+// agreementsAccepted reports whether request may run: anonymous requests,
+// users who accepted every latest agreement, and operations that only read
+// and accept agreements may
+func (r *Resolver) agreementsAccepted(ctx context.Context, request Request) bool {
+	if r.agreements == nil {
+		return true
+	}
+	claims, ok := users.ClaimsFromContext(ctx)
+	if !ok || r.agreements.HasAcceptedLatest(claims.UserID()) {
+		return true
+	}
+
+	op, ok := selectOperation(request.Query, request.OperationName)
+	if !ok {
+		// graphql-go refuses the document; let it say why
+		return true
+	}
+	for _, field := range op.fields {
+		if !agreementFields[field] {
+			return false
+		}
+	}
+	return true
+}
+
+// PendingAgreements resolves Query.pendingAgreements
+func (r *Resolver) PendingAgreements(ctx context.Context) ([]*LegalDocument, error) {
+	if r.agreements == nil {
+		return nil, errNotImplemented("pendingAgreements")
+	}
+	claims, ok := users.ClaimsFromContext(ctx)
+	if !ok {
+		return nil, newError(CodeUnauthenticated, "sign in to see pending agreements")
+	}
+
+	pending := r.agreements.Pending(claims.UserID())
+	result := make([]*LegalDocument, 0, len(pending))
+	for _, doc := range pending {
+		result = append(result, &LegalDocument{
+			Kind:        strings.ToUpper(doc.Kind),
+			Version:     doc.Version,
+			URL:         doc.URL,
+			PublishedAt: gql.Time{Time: doc.PublishedAt},
+		})
+	}
+	return result, nil
+}
+
+// AcceptAgreement resolves Mutation.acceptAgreement
+func (r *Resolver) AcceptAgreement(ctx context.Context, args struct {
+	Kind    string
+	Version string
+}) (bool, error) {
+	if r.agreements == nil {
+		return false, errNotImplemented("acceptAgreement")
+	}
+	claims, ok := users.ClaimsFromContext(ctx)
+	if !ok {
+		return false, newError(CodeUnauthenticated, "sign in to accept agreements")
+	}
+
+	_, err := r.agreements.Accept(claims.UserID(), strings.ToLower(args.Kind), args.Version)
+	if errors.Is(err, consent.ErrUnknownDocument) {
+		return false, newError(CodeBadUserInput, err.Error())
+	}
+	if err != nil {
+		return false, internalError("acceptAgreement", err)
+	}
+	return true, nil
+}
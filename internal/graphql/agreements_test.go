@@ -0,0 +1,51 @@
+package graphql
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/tinle0301/streaming-platform-api/internal/consent"
+	"github.com/tinle0301/streaming-platform-api/internal/users"
+)
+
+func TestPendingAgreementsGateOperations(t *testing.T) {
+	agreements := consent.NewRegistry()
+	agreements.Publish(consent.Document{Kind: consent.KindTermsOfService, Version: "2", URL: "https://example.com/tos"})
+
+	resolver := NewResolver(emptyStreams{})
+	resolver.SetAgreements(agreements)
+	schema, err := NewSchema(resolver)
+	if err != nil {
+		t.Fatalf("failed to parse schema: %v", err)
+	}
+
+	claims := &users.Claims{RegisteredClaims: jwt.RegisteredClaims{Subject: "alice"}}
+	post := func(query string) *httptest.ResponseRecorder {
+		raw, _ := json.Marshal(map[string]interface{}{"query": query})
+		req := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(string(raw)))
+		req = req.WithContext(users.WithClaims(req.Context(), claims))
+		rec := httptest.NewRecorder()
+		Handler(schema, resolver)(rec, req)
+		return rec
+	}
+
+	if rec := post("{ __typename tags { slug } }"); rec.Code != http.StatusForbidden || !strings.Contains(rec.Body.String(), CodeTermsNotAccepted) {
+		t.Fatalf("query before accepting = %d %s, want 403 %s", rec.Code, rec.Body, CodeTermsNotAccepted)
+	}
+	if rec := post("{ pendingAgreements { kind version } }"); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"TERMS_OF_SERVICE"`) {
+		t.Fatalf("pendingAgreements = %d %s, want the terms of service", rec.Code, rec.Body)
+	}
+	if rec := post(`mutation { acceptAgreement(kind: TERMS_OF_SERVICE, version: "2") }`); rec.Code != http.StatusOK || strings.Contains(rec.Body.String(), "errors") {
+		t.Fatalf("acceptAgreement = %d %s, want accepted", rec.Code, rec.Body)
+	}
+	if rec := post("{ __typename }"); rec.Code != http.StatusOK {
+		t.Errorf("query after accepting = %d %s, want 200", rec.Code, rec.Body)
+	}
+	if !agreements.HasAcceptedLatest("alice") {
+		t.Error("alice has not accepted the latest terms")
+	}
+}
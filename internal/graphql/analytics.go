@@ -0,0 +1,103 @@
+package graphql
+
+import (
+	"context"
+	"errors"
+	"math"
+	"time"
+
+	gql "github.com/graph-gophers/graphql-go"
+	"github.com/tinle0301/streaming-platform-api/internal/analytics"
+	"github.com/tinle0301/streaming-platform-api/internal/users"
+	"github.com/tinle0301/streaming-platform-api/internal/websocket"
+)
+
+// DeliveryStatsReader loads a stream room's delivery stats across WebSocket nodes
+type DeliveryStatsReader interface {
+	RoomDeliveryStats(ctx context.Context, room string) (websocket.DeliveryStats, error)
+}
+
+// SetAnalytics enables the audience metrics in StreamAnalytics
+func (r *Resolver) SetAnalytics(service *analytics.Service) {
+	r.analytics = service
+}
+
+// SetDeliveryStats enables delivery stats in StreamAnalytics
+func (r *Resolver) SetDeliveryStats(reader DeliveryStatsReader) {
+	r.deliveryStats = reader
+}
+
+// StreamAnalytics resolves Query.streamAnalytics for the stream's owner and
+// their organization's managers. Audience metrics cover the time range;
+// delivery stats cover the broadcast so far.
+func (r *Resolver) StreamAnalytics(ctx context.Context, args struct {
+	StreamID  gql.ID
+	TimeRange string
+}) (*StreamAnalytics, error) {
+	claims, ok := users.ClaimsFromContext(ctx)
+	if !ok {
+		return nil, newError(CodeUnauthenticated, "sign in to view stream analytics")
+	}
+	stream, err := r.ownStream(ctx, "streamAnalytics", claims, string(args.StreamID))
+	if err != nil {
+		return nil, err
+	}
+
+	result := &StreamAnalytics{
+		StreamID:   gql.ID(stream.ID),
+		DataPoints: []*AnalyticsDataPoint{},
+		Delivery:   &BroadcastDeliveryStats{},
+	}
+
+	if r.analytics != nil {
+		report, err := r.analytics.StreamReport(ctx, stream.ID, args.TimeRange)
+		if errors.Is(err, analytics.ErrUnknownRange) {
+			return nil, newError(CodeBadUserInput, err.Error())
+		}
+		if err != nil {
+			return nil, internalError("streamAnalytics", err)
+		}
+		applyReport(result, report)
+	}
+
+	if r.deliveryStats != nil {
+		stats, err := r.deliveryStats.RoomDeliveryStats(ctx, stream.ID)
+		if err != nil {
+			return nil, internalError("streamAnalytics", err)
+		}
+		result.Delivery = deliveryStatsFromHub(stats)
+	}
+
+	return result, nil
+}
+
+// applyReport fills in the audience metrics of an analytics report
+func applyReport(result *StreamAnalytics, report *analytics.Report) {
+	result.TotalViews = int32(report.Views)
+	result.PeakViewers = int32(report.PeakViewers)
+	result.AverageViewers = int32(math.Round(report.AverageViewers))
+	result.TotalWatchTime = int32(report.WatchTime / time.Minute)
+	result.NewFollowers = int32(report.NewFollowers)
+	result.ChatMessageCount = int32(report.ChatMessages)
+	for _, point := range report.Points {
+		result.DataPoints = append(result.DataPoints, &AnalyticsDataPoint{
+			Timestamp:    gql.Time{Time: point.Start},
+			ViewerCount:  int32(math.Round(point.AverageViewers)),
+			ChatActivity: int32(point.ChatMessages),
+		})
+	}
+}
+
+// deliveryStatsFromHub converts hub delivery counters to the GraphQL model
+func deliveryStatsFromHub(stats websocket.DeliveryStats) *BroadcastDeliveryStats {
+	delivery := &BroadcastDeliveryStats{
+		Broadcasts:             float64(stats.Broadcasts),
+		MessagesSent:           float64(stats.MessagesSent),
+		MessagesDropped:        float64(stats.MessagesDropped),
+		AverageFanOutLatencyMs: float64(stats.AverageFanOutLatency().Microseconds()) / 1000,
+	}
+	if attempted := stats.MessagesSent + stats.MessagesDropped; attempted > 0 {
+		delivery.DropRate = float64(stats.MessagesDropped) / float64(attempted)
+	}
+	return delivery
+}
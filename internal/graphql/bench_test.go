@@ -0,0 +1,147 @@
+package graphql
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tinle0301/streaming-platform-api/internal/dataloader"
+	"github.com/tinle0301/streaming-platform-api/internal/store"
+)
+
+// benchStreams serves a fixed page of live streams from memory, so resolver
+// benchmarks measure the resolvers rather than Postgres
+type benchStreams struct {
+	emptyStreams
+	streams map[string]*store.Stream
+	page    []*store.Stream
+}
+
+// newBenchStreams returns n live streams with IDs "1" through n
+func newBenchStreams(n int) *benchStreams {
+	repo := &benchStreams{streams: make(map[string]*store.Stream, n)}
+	startedAt := time.Now().Add(-time.Hour)
+	for i := 1; i <= n; i++ {
+		stream := &store.Stream{
+			ID:          fmt.Sprint(i),
+			StreamerID:  fmt.Sprintf("streamer-%d", i),
+			Title:       fmt.Sprintf("Benchmark stream %d", i),
+			Status:      store.StreamStatusLive,
+			Category:    "just-chatting",
+			Language:    "en",
+			Tags:        []string{"english", "chill"},
+			ChatEnabled: true,
+			ViewerCount: 100 * i,
+			StartedAt:   &startedAt,
+		}
+		repo.streams[stream.ID] = stream
+		repo.page = append(repo.page, stream)
+	}
+	return repo
+}
+
+func (r *benchStreams) Get(ctx context.Context, id string) (*store.Stream, error) {
+	if stream, ok := r.streams[id]; ok {
+		return stream, nil
+	}
+	return nil, store.ErrNotFound
+}
+
+func (r *benchStreams) GetMany(ctx context.Context, ids []string) (map[string]*store.Stream, error) {
+	found := make(map[string]*store.Stream, len(ids))
+	for _, id := range ids {
+		if stream, ok := r.streams[id]; ok {
+			found[id] = stream
+		}
+	}
+	return found, nil
+}
+
+func (r *benchStreams) List(ctx context.Context, filter store.StreamFilter) ([]*store.Stream, int, error) {
+	page := r.page
+	if filter.Limit < len(page) {
+		page = page[:filter.Limit]
+	}
+	return page, len(r.page), nil
+}
+
+// benchLoaderWait is the loaders' batch window in benchmarks
+const benchLoaderWait = 50 * time.Microsecond
+
+// benchExec executes query once per iteration with fresh loaders, as the
+// handler does for each request
+func benchExec(b *testing.B, query string, variables map[string]interface{}) {
+	resolver := NewResolver(newBenchStreams(20))
+	// The loaders' batch window is a timer, not work; shorten it so it does
+	// not dominate the timings
+	opts := dataloader.DefaultOptions()
+	opts.Wait = benchLoaderWait
+	resolver.SetLoaderOptions(opts)
+	schema, err := NewSchema(resolver)
+	if err != nil {
+		b.Fatalf("failed to parse schema: %v", err)
+	}
+
+	// Fail fast on a query the schema rejects rather than timing errors
+	if response := schema.Exec(resolver.WithLoaders(context.Background()), query, "", variables); len(response.Errors) > 0 {
+		b.Fatalf("query failed: %v", response.Errors)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		schema.Exec(resolver.WithLoaders(context.Background()), query, "", variables)
+	}
+}
+
+func BenchmarkResolveStream(b *testing.B) {
+	benchExec(b, `query($id: ID!) {
+		stream(id: $id) { id title viewerCount status tags language startedAt }
+	}`, map[string]interface{}{"id": "1"})
+}
+
+func BenchmarkResolveStreams(b *testing.B) {
+	benchExec(b, `{
+		streams(limit: 20) {
+			totalCount
+			edges { cursor node { id title viewerCount status tags language } }
+			pageInfo { hasNextPage endCursor }
+		}
+	}`, nil)
+}
+
+// BenchmarkResolveStreamsBatched resolves ten streams by ID in one
+// operation, which the stream loader batches into a single GetMany
+func BenchmarkResolveStreamsBatched(b *testing.B) {
+	var query strings.Builder
+	query.WriteString("{")
+	for i := 1; i <= 10; i++ {
+		fmt.Fprintf(&query, " s%d: stream(id: %q) { id title viewerCount }", i, fmt.Sprint(i))
+	}
+	query.WriteString(" }")
+	benchExec(b, query.String(), nil)
+}
+
+// BenchmarkParseAndValidate measures the per-request cost of parsing and
+// validating a query, before any resolver runs
+func BenchmarkParseAndValidate(b *testing.B) {
+	schema, err := NewSchema(NewResolver(emptyStreams{}))
+	if err != nil {
+		b.Fatalf("failed to parse schema: %v", err)
+	}
+	const query = `query($id: ID!) {
+		stream(id: $id) { id title viewerCount status tags language startedAt }
+		streams(limit: 20) { totalCount edges { node { id title } } }
+	}`
+	variables := map[string]interface{}{"id": "1"}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if errs := schema.ValidateWithVariables(query, variables); len(errs) > 0 {
+			b.Fatalf("query is invalid: %v", errs)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package graphql
+
+import (
+	"context"
+	"errors"
+
+	gql "github.com/graph-gophers/graphql-go"
+	"github.com/tinle0301/streaming-platform-api/internal/users"
+)
+
+// BlockUser resolves Mutation.blockUser and returns the blocked user
+func (r *Resolver) BlockUser(ctx context.Context, args struct{ UserID gql.ID }) (*User, error) {
+	return r.changeBlock(ctx, "blockUser", string(args.UserID), true)
+}
+
+// UnblockUser resolves Mutation.unblockUser and returns the unblocked user
+func (r *Resolver) UnblockUser(ctx context.Context, args struct{ UserID gql.ID }) (*User, error) {
+	return r.changeBlock(ctx, "unblockUser", string(args.UserID), false)
+}
+
+// changeBlock blocks or unblocks userID as the authenticated viewer
+func (r *Resolver) changeBlock(ctx context.Context, field, userID string, block bool) (*User, error) {
+	if r.users == nil {
+		return nil, errNotImplemented(field)
+	}
+
+	claims, ok := users.ClaimsFromContext(ctx)
+	if !ok {
+		return nil, newError(CodeUnauthenticated, "sign in to block users")
+	}
+
+	target, err := r.users.Get(ctx, userID)
+	if errors.Is(err, users.ErrNotFound) {
+		return nil, newError(CodeNotFound, "user not found")
+	}
+	if err != nil {
+		return nil, internalError(field, err)
+	}
+
+	if block {
+		err = r.users.Block(ctx, claims.UserID(), target.ID)
+	} else {
+		err = r.users.Unblock(ctx, claims.UserID(), target.ID)
+	}
+	switch {
+	case errors.Is(err, users.ErrSelfBlock):
+		return nil, newError(CodeBadUserInput, err.Error())
+	case errors.Is(err, users.ErrNotFound):
+		// The viewer's own account no longer exists
+		return nil, newError(CodeUnauthenticated, "account not found")
+	case err != nil:
+		return nil, internalError(field, err)
+	}
+
+	return userFromAccount(target, r.users, target.ID == claims.UserID()), nil
+}
+
+// IsBlockedByViewer resolves User.isBlockedByViewer
+func (u *User) IsBlockedByViewer(ctx context.Context) (bool, error) {
+	claims, ok := users.ClaimsFromContext(ctx)
+	if u.follows == nil || !ok {
+		return false, nil
+	}
+	blocked, err := u.follows.IsBlocked(ctx, claims.UserID(), string(u.ID))
+	if err != nil {
+		return false, internalError("isBlockedByViewer", err)
+	}
+	return blocked, nil
+}
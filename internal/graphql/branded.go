@@ -0,0 +1,93 @@
+package graphql
+
+import (
+	"context"
+	"net"
+	"net/http"
+
+	"github.com/tinle0301/streaming-platform-api/internal/compliance"
+	"github.com/tinle0301/streaming-platform-api/internal/httpmiddleware"
+	"github.com/tinle0301/streaming-platform-api/internal/store"
+)
+
+// clientIPKey is the context key for the requesting client's IP address
+type clientIPKey struct{}
+
+// SetDisclosurePolicy enables regional enforcement and audit logging of
+// branded content disclosures
+func (r *Resolver) SetDisclosurePolicy(policy *compliance.DisclosurePolicy) {
+	r.disclosures = policy
+}
+
+// withClientIP returns a context carrying the IP address req came from,
+// as resolved behind proxies by httpmiddleware.ClientIP when it ran
+func withClientIP(ctx context.Context, req *http.Request) context.Context {
+	if ip := httpmiddleware.ClientIPFromContext(req.Context()); ip != "" {
+		return context.WithValue(ctx, clientIPKey{}, ip)
+	}
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		host = req.RemoteAddr
+	}
+	return context.WithValue(ctx, clientIPKey{}, host)
+}
+
+// clientIPFrom returns the request's client IP, or "" outside a request
+func clientIPFrom(ctx context.Context) string {
+	ip, _ := ctx.Value(clientIPKey{}).(string)
+	return ip
+}
+
+// applyBrandedContent validates input against the disclosure policy and
+// copies it onto stream
+func (r *Resolver) applyBrandedContent(ctx context.Context, stream *store.Stream, input BrandedContentInput) error {
+	disclosure := compliance.Disclosure{
+		BrandedContent: input.Enabled,
+		SponsorName:    stringValue(input.SponsorName),
+		DisclosureType: stringValue(input.DisclosureType),
+	}
+
+	var err error
+	if r.disclosures != nil {
+		disclosure, err = r.disclosures.Check(disclosure, clientIPFrom(ctx))
+	} else {
+		disclosure, err = compliance.Normalize(disclosure)
+	}
+	if err != nil {
+		return newError(CodeBadUserInput, err.Error())
+	}
+
+	stream.BrandedContent = disclosure.BrandedContent
+	stream.SponsorName = disclosure.SponsorName
+	stream.DisclosureType = disclosure.DisclosureType
+	return nil
+}
+
+// recordDisclosure audits a change to stream's branded content disclosure
+func (r *Resolver) recordDisclosure(ctx context.Context, actorID string, stream *store.Stream, before compliance.Disclosure) {
+	if r.disclosures == nil {
+		return
+	}
+	r.disclosures.RecordChange(ctx, actorID, stream.ID, before, disclosureOf(stream))
+}
+
+// disclosureOf returns a stored stream's branded content disclosure
+func disclosureOf(stream *store.Stream) compliance.Disclosure {
+	return compliance.Disclosure{
+		BrandedContent: stream.BrandedContent,
+		SponsorName:    stream.SponsorName,
+		DisclosureType: stream.DisclosureType,
+	}
+}
+
+// brandedContentFromStore converts a stream's disclosure, or nil when the
+// stream is not branded content
+func brandedContentFromStore(s *store.Stream) *BrandedContentDisclosure {
+	if !s.BrandedContent {
+		return nil
+	}
+	return &BrandedContentDisclosure{
+		SponsorName:    optionalString(s.SponsorName),
+		DisclosureType: optionalString(s.DisclosureType),
+	}
+}
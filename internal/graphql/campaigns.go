@@ -0,0 +1,209 @@
+package graphql
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	gql "github.com/graph-gophers/graphql-go"
+	"github.com/tinle0301/streaming-platform-api/internal/campaigns"
+	"github.com/tinle0301/streaming-platform-api/internal/users"
+)
+
+// SetCampaigns enables reward campaigns. Only publisherIDs and platform
+// admins may create campaigns, since their webhooks make the server send
+// requests to URLs they choose.
+func (r *Resolver) SetCampaigns(service *campaigns.Service, publisherIDs []string) {
+	r.campaigns = service
+	r.campaignPublishers = make(map[string]bool, len(publisherIDs))
+	for _, id := range publisherIDs {
+		r.campaignPublishers[id] = true
+	}
+}
+
+// Campaigns resolves Query.campaigns
+func (r *Resolver) Campaigns(ctx context.Context, args struct {
+	Category   *string
+	ActiveOnly bool
+}) ([]*Campaign, error) {
+	if r.campaigns == nil {
+		return nil, errNotImplemented("campaigns")
+	}
+
+	filter := campaigns.Filter{Category: stringValue(args.Category)}
+	if args.ActiveOnly {
+		now := time.Now()
+		filter.ActiveAt = &now
+	}
+	list, err := r.campaigns.List(ctx, filter)
+	if err != nil {
+		return nil, internalError("campaigns", err)
+	}
+
+	claims, _ := users.ClaimsFromContext(ctx)
+	result := make([]*Campaign, 0, len(list))
+	for _, campaign := range list {
+		result = append(result, campaignFromStore(campaign, claims))
+	}
+	return result, nil
+}
+
+// MyCampaignProgress resolves Query.myCampaignProgress
+func (r *Resolver) MyCampaignProgress(ctx context.Context) ([]*CampaignProgress, error) {
+	if r.campaigns == nil {
+		return nil, errNotImplemented("myCampaignProgress")
+	}
+	claims, ok := users.ClaimsFromContext(ctx)
+	if !ok {
+		return nil, newError(CodeUnauthenticated, "sign in to see your campaign progress")
+	}
+
+	progress, err := r.campaigns.ProgressForUser(ctx, claims.UserID())
+	if err != nil {
+		return nil, internalError("myCampaignProgress", err)
+	}
+	claimed, err := r.campaigns.Claims(ctx, claims.UserID())
+	if err != nil {
+		return nil, internalError("myCampaignProgress", err)
+	}
+	claimedByCampaign := make(map[string][]gql.ID)
+	for _, claim := range claimed {
+		claimedByCampaign[claim.CampaignID] = append(claimedByCampaign[claim.CampaignID], gql.ID(claim.RewardID))
+	}
+
+	result := make([]*CampaignProgress, 0, len(progress))
+	for _, p := range progress {
+		campaign, err := r.campaigns.Get(ctx, p.CampaignID)
+		if errors.Is(err, campaigns.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, internalError("myCampaignProgress", err)
+		}
+
+		ids := claimedByCampaign[p.CampaignID]
+		if ids == nil {
+			ids = []gql.ID{}
+		}
+		result = append(result, &CampaignProgress{
+			Campaign:         campaignFromStore(campaign, claims),
+			WatchedMinutes:   int32(p.Watched / time.Minute),
+			ClaimedRewardIDs: ids,
+		})
+	}
+	return result, nil
+}
+
+// CreateCampaign resolves Mutation.createCampaign
+func (r *Resolver) CreateCampaign(ctx context.Context, args struct{ Input CreateCampaignInput }) (*CreateCampaignPayload, error) {
+	if r.campaigns == nil {
+		return nil, errNotImplemented("createCampaign")
+	}
+	claims, ok := users.ClaimsFromContext(ctx)
+	if !ok {
+		return nil, newError(CodeUnauthenticated, "sign in to create a campaign")
+	}
+	if !r.campaignPublishers[claims.UserID()] && !r.admins[claims.UserID()] {
+		return nil, newError(CodeForbidden, "only campaign publishers can create campaigns")
+	}
+
+	input := args.Input
+	campaign := &campaigns.Campaign{
+		Name:        input.Name,
+		Description: stringValue(input.Description),
+		Categories:  input.Categories,
+		StartsAt:    input.StartsAt.Time,
+		EndsAt:      input.EndsAt.Time,
+		WebhookURL:  stringValue(input.WebhookURL),
+	}
+	for _, reward := range input.Rewards {
+		campaign.Rewards = append(campaign.Rewards, &campaigns.Reward{
+			Name:            reward.Name,
+			Description:     stringValue(reward.Description),
+			ImageURL:        stringValue(reward.ImageURL),
+			RequiredMinutes: int(reward.RequiredMinutes),
+		})
+	}
+
+	if err := r.campaigns.Create(ctx, claims.UserID(), campaign); err != nil {
+		return nil, campaignError("createCampaign", err)
+	}
+
+	payload := &CreateCampaignPayload{Campaign: campaignFromStore(campaign, claims)}
+	if campaign.WebhookSecret != "" {
+		payload.WebhookSecret = &campaign.WebhookSecret
+	}
+	return payload, nil
+}
+
+// ClaimCampaignReward resolves Mutation.claimCampaignReward
+func (r *Resolver) ClaimCampaignReward(ctx context.Context, args struct{ RewardID gql.ID }) (*CampaignRewardClaim, error) {
+	if r.campaigns == nil {
+		return nil, errNotImplemented("claimCampaignReward")
+	}
+	claims, ok := users.ClaimsFromContext(ctx)
+	if !ok {
+		return nil, newError(CodeUnauthenticated, "sign in to claim rewards")
+	}
+
+	claim, reward, err := r.campaigns.Claim(ctx, claims.UserID(), string(args.RewardID))
+	if err != nil {
+		return nil, campaignError("claimCampaignReward", err)
+	}
+	return &CampaignRewardClaim{
+		ID:        gql.ID(claim.ID),
+		Reward:    campaignRewardFromStore(reward),
+		ClaimedAt: gql.Time{Time: claim.ClaimedAt},
+	}, nil
+}
+
+// campaignFromStore converts a stored campaign; the webhook URL is shown only
+// to the campaign's publisher
+func campaignFromStore(c *campaigns.Campaign, claims *users.Claims) *Campaign {
+	campaign := &Campaign{
+		ID:          gql.ID(c.ID),
+		Name:        c.Name,
+		Description: optionalString(c.Description),
+		PublisherID: gql.ID(c.PublisherID),
+		Categories:  c.Categories,
+		StartsAt:    gql.Time{Time: c.StartsAt},
+		EndsAt:      gql.Time{Time: c.EndsAt},
+		Active:      c.ActiveAt(time.Now()),
+		Rewards:     make([]*CampaignReward, 0, len(c.Rewards)),
+	}
+	for _, reward := range c.Rewards {
+		campaign.Rewards = append(campaign.Rewards, campaignRewardFromStore(reward))
+	}
+	if claims != nil && claims.UserID() == c.PublisherID {
+		campaign.WebhookURL = optionalString(c.WebhookURL)
+	}
+	return campaign
+}
+
+// campaignRewardFromStore converts a stored reward
+func campaignRewardFromStore(r *campaigns.Reward) *CampaignReward {
+	return &CampaignReward{
+		ID:              gql.ID(r.ID),
+		Name:            r.Name,
+		Description:     optionalString(r.Description),
+		ImageURL:        optionalString(r.ImageURL),
+		RequiredMinutes: int32(r.RequiredMinutes),
+	}
+}
+
+// campaignError maps campaign errors to GraphQL errors
+func campaignError(field string, err error) error {
+	switch {
+	case errors.Is(err, campaigns.ErrNotFound), errors.Is(err, campaigns.ErrRewardNotFound),
+		errors.Is(err, campaigns.ErrUserNotFound):
+		return newError(CodeNotFound, err.Error())
+	case errors.Is(err, campaigns.ErrNotEarned), errors.Is(err, campaigns.ErrClaimClosed):
+		return newError(CodeForbidden, err.Error())
+	case errors.Is(err, campaigns.ErrAlreadyClaimed), errors.Is(err, campaigns.ErrInvalidName),
+		errors.Is(err, campaigns.ErrInvalidSchedule), errors.Is(err, campaigns.ErrInvalidCategories),
+		errors.Is(err, campaigns.ErrInvalidRewards), errors.Is(err, campaigns.ErrInvalidWebhook):
+		return newError(CodeBadUserInput, err.Error())
+	default:
+		return internalError(field, err)
+	}
+}
@@ -0,0 +1,46 @@
+package graphql
+
+import (
+	"context"
+
+	"github.com/tinle0301/streaming-platform-api/internal/captions"
+)
+
+// SetCaptions enables closed captions on VODs
+func (r *Resolver) SetCaptions(service *captions.Service) {
+	r.captions = service
+}
+
+// CaptionLanguages resolves Vod.captionLanguages
+func (v *Vod) CaptionLanguages(ctx context.Context) ([]string, error) {
+	if v.resolver.captions == nil {
+		return []string{}, nil
+	}
+
+	languages, err := v.resolver.captions.Languages(ctx, v.streamID)
+	if err != nil {
+		return nil, internalError("captionLanguages", err)
+	}
+	return languages, nil
+}
+
+// Captions resolves Vod.captions
+func (v *Vod) Captions(ctx context.Context, args struct{ Language string }) ([]*Caption, error) {
+	if v.resolver.captions == nil {
+		return []*Caption{}, nil
+	}
+
+	segments, err := v.resolver.captions.ForVOD(ctx, v.source, args.Language)
+	if err != nil {
+		return nil, internalError("captions", err)
+	}
+	result := make([]*Caption, 0, len(segments))
+	for _, segment := range segments {
+		result = append(result, &Caption{
+			StartOffset: segment.Start.Seconds(),
+			EndOffset:   segment.End.Seconds(),
+			Text:        segment.Text,
+		})
+	}
+	return result, nil
+}
@@ -0,0 +1,72 @@
+package graphql
+
+import (
+	"context"
+	"time"
+
+	gql "github.com/graph-gophers/graphql-go"
+	"github.com/tinle0301/streaming-platform-api/internal/chathistory"
+	"github.com/tinle0301/streaming-platform-api/internal/users"
+)
+
+// SetChatHistory enables reading streams' latest chat
+func (r *Resolver) SetChatHistory(history *chathistory.Store) {
+	r.chatHistory = history
+}
+
+// ChatHistory resolves Query.chatHistory
+func (r *Resolver) ChatHistory(ctx context.Context, args struct {
+	StreamID gql.ID
+	Before   *gql.Time
+	Limit    int32
+}) ([]*ChatMessage, error) {
+	if r.chatHistory == nil || r.users == nil {
+		return nil, errNotImplemented("chatHistory")
+	}
+	if args.Limit < 1 || args.Limit > chathistory.MaxLimit {
+		return nil, newError(CodeBadUserInput, "limit must be between 1 and 100")
+	}
+	var before time.Time
+	if args.Before != nil {
+		before = args.Before.Time
+	}
+
+	messages, err := r.chatHistory.Before(ctx, string(args.StreamID), before, int(args.Limit))
+	if err != nil {
+		return nil, internalError("chatHistory", err)
+	}
+	if len(messages) == 0 {
+		return []*ChatMessage{}, nil
+	}
+
+	ids := make([]string, len(messages))
+	for i, m := range messages {
+		ids[i] = m.UserID
+	}
+	accounts, err := r.loadAccounts(ctx, ids)
+	if err != nil {
+		return nil, internalError("chatHistory", err)
+	}
+	claims, _ := users.ClaimsFromContext(ctx)
+
+	// Messages from since-deleted accounts are left out too
+	result := make([]*ChatMessage, 0, len(messages))
+	for i, m := range messages {
+		account := accounts[i]
+		if account == nil {
+			continue
+		}
+		result = append(result, &ChatMessage{
+			ID:           gql.ID(m.ID),
+			StreamID:     args.StreamID,
+			User:         userFromAccount(account, r.users, claims != nil && claims.UserID() == account.ID),
+			Message:      m.Text,
+			Timestamp:    gql.Time{Time: m.SentAt},
+			Badges:       subscriberBadges(m.SubscriberTier),
+			Emotes:       []*Emote{},
+			IsModerator:  m.Moderator,
+			IsSubscriber: m.SubscriberTier != "",
+		})
+	}
+	return result, nil
+}
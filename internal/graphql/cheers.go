@@ -0,0 +1,118 @@
+package graphql
+
+import (
+	"context"
+	"errors"
+	"math"
+
+	gql "github.com/graph-gophers/graphql-go"
+	"github.com/tinle0301/streaming-platform-api/internal/cheers"
+	"github.com/tinle0301/streaming-platform-api/internal/users"
+)
+
+// SetCheers enables cheering bits and cheer leaderboards
+func (r *Resolver) SetCheers(service *cheers.Service) {
+	r.cheers = service
+}
+
+// TopCheerers resolves Query.topCheerers
+func (r *Resolver) TopCheerers(ctx context.Context, args struct {
+	StreamID gql.ID
+	Period   string
+	Limit    int32
+}) ([]*CheerLeaderboardEntry, error) {
+	if r.cheers == nil || r.users == nil {
+		return nil, errNotImplemented("topCheerers")
+	}
+	if args.Limit < 1 || args.Limit > cheers.MaxLeaderboard {
+		return nil, newError(CodeBadUserInput, "limit must be between 1 and 100")
+	}
+
+	top, err := r.cheers.TopCheerers(ctx, string(args.StreamID), args.Period, int(args.Limit))
+	if err != nil {
+		return nil, cheersError("topCheerers", err)
+	}
+	if len(top) == 0 {
+		return []*CheerLeaderboardEntry{}, nil
+	}
+
+	ids := make([]string, len(top))
+	for i, c := range top {
+		ids[i] = c.UserID
+	}
+	accounts, err := r.loadAccounts(ctx, ids)
+	if err != nil {
+		return nil, internalError("topCheerers", err)
+	}
+	claims, _ := users.ClaimsFromContext(ctx)
+
+	// Ranks count deleted accounts, so the places shown don't shift
+	result := make([]*CheerLeaderboardEntry, 0, len(top))
+	for i, c := range top {
+		account := accounts[i]
+		if account == nil {
+			continue
+		}
+		result = append(result, &CheerLeaderboardEntry{
+			Rank: int32(i + 1),
+			User: userFromAccount(account, r.users, claims != nil && claims.UserID() == account.ID),
+			Bits: int32(min(c.Bits, math.MaxInt32)),
+		})
+	}
+	return result, nil
+}
+
+// CheerBits resolves Mutation.cheerBits
+func (r *Resolver) CheerBits(ctx context.Context, args struct {
+	StreamID gql.ID
+	Bits     int32
+	Message  *string
+}) (*Cheer, error) {
+	if r.cheers == nil {
+		return nil, errNotImplemented("cheerBits")
+	}
+	claims, ok := users.ClaimsFromContext(ctx)
+	if !ok {
+		return nil, newError(CodeUnauthenticated, "sign in to cheer")
+	}
+
+	cheer, err := r.cheers.Cheer(ctx, string(args.StreamID), claims.UserID(), int(args.Bits), stringValue(args.Message))
+	if err != nil {
+		return nil, cheersError("cheerBits", err)
+	}
+	return &Cheer{
+		ID:        gql.ID(cheer.ID),
+		StreamID:  gql.ID(cheer.StreamID),
+		Bits:      int32(cheer.Bits),
+		Message:   cheer.Message,
+		Animation: cheers.Animation(cheer.Bits),
+		CreatedAt: gql.Time{Time: cheer.CreatedAt},
+		userID:    cheer.UserID,
+		resolver:  r,
+	}, nil
+}
+
+// User resolves Cheer.user
+func (c *Cheer) User(ctx context.Context) (*User, error) {
+	user, err := c.resolver.userByID(ctx, "user", c.userID)
+	if err != nil || user != nil {
+		return user, err
+	}
+	return &User{ID: gql.ID(c.userID)}, nil
+}
+
+// cheersError maps cheer errors to GraphQL errors
+func cheersError(field string, err error) error {
+	switch {
+	case errors.Is(err, cheers.ErrNotFound):
+		return newError(CodeNotFound, err.Error())
+	case errors.Is(err, cheers.ErrInvalidBits), errors.Is(err, cheers.ErrMessageTooLong),
+		errors.Is(err, cheers.ErrNotLive), errors.Is(err, cheers.ErrSelfCheer),
+		errors.Is(err, cheers.ErrInvalidPeriod):
+		return newError(CodeBadUserInput, err.Error())
+	case errors.Is(err, cheers.ErrInsufficientBits):
+		return newError(CodePaymentRequired, err.Error())
+	default:
+		return internalError(field, err)
+	}
+}
@@ -0,0 +1,194 @@
+package graphql
+
+import (
+	"context"
+	"errors"
+	"math"
+	"time"
+
+	gql "github.com/graph-gophers/graphql-go"
+	"github.com/tinle0301/streaming-platform-api/internal/clips"
+	"github.com/tinle0301/streaming-platform-api/internal/safety"
+	"github.com/tinle0301/streaming-platform-api/internal/users"
+)
+
+// SetClips enables clip queries and the clip editor mutations
+func (r *Resolver) SetClips(service *clips.Service) {
+	r.clips = service
+}
+
+// Clip resolves Query.clip
+func (r *Resolver) Clip(ctx context.Context, args struct{ ID gql.ID }) (*Clip, error) {
+	if r.clips == nil {
+		return nil, errNotImplemented("clip")
+	}
+
+	clip, err := r.clips.Get(ctx, string(args.ID))
+	if errors.Is(err, clips.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, internalError("clip", err)
+	}
+
+	// Drafts are private to their creator
+	if clip.Status == clips.StatusDraft {
+		claims, ok := users.ClaimsFromContext(ctx)
+		if !ok || claims.UserID() != clip.CreatorID {
+			return nil, nil
+		}
+	}
+	return r.clipFromStore(clip), nil
+}
+
+// CreateClip resolves Mutation.createClip
+func (r *Resolver) CreateClip(ctx context.Context, args struct{ StreamID gql.ID }) (*Clip, error) {
+	if r.clips == nil {
+		return nil, errNotImplemented("createClip")
+	}
+	claims, ok := users.ClaimsFromContext(ctx)
+	if !ok {
+		return nil, newError(CodeUnauthenticated, "sign in to create clips")
+	}
+
+	clip, err := r.clips.CreateDraft(ctx, claims.UserID(), string(args.StreamID))
+	if err != nil {
+		return nil, clipError("createClip", err)
+	}
+	return r.clipFromStore(clip), nil
+}
+
+// TrimClip resolves Mutation.trimClip
+func (r *Resolver) TrimClip(ctx context.Context, args struct {
+	ID          gql.ID
+	StartOffset float64
+	EndOffset   float64
+}) (*Clip, error) {
+	if r.clips == nil {
+		return nil, errNotImplemented("trimClip")
+	}
+	claims, ok := users.ClaimsFromContext(ctx)
+	if !ok {
+		return nil, newError(CodeUnauthenticated, "sign in to edit clips")
+	}
+	if args.StartOffset < 0 || args.EndOffset < 0 {
+		return nil, newError(CodeBadUserInput, clips.ErrInvalidTrim.Error())
+	}
+
+	clip, err := r.clips.Trim(ctx, claims.UserID(), string(args.ID), seconds(args.StartOffset), seconds(args.EndOffset))
+	if err != nil {
+		return nil, clipError("trimClip", err)
+	}
+	return r.clipFromStore(clip), nil
+}
+
+// UpdateClipTitle resolves Mutation.updateClipTitle
+func (r *Resolver) UpdateClipTitle(ctx context.Context, args struct {
+	ID    gql.ID
+	Title string
+}) (*Clip, error) {
+	if r.clips == nil {
+		return nil, errNotImplemented("updateClipTitle")
+	}
+	claims, ok := users.ClaimsFromContext(ctx)
+	if !ok {
+		return nil, newError(CodeUnauthenticated, "sign in to edit clips")
+	}
+
+	clip, err := r.clips.SetTitle(ctx, claims.UserID(), string(args.ID), args.Title)
+	if err != nil {
+		return nil, clipError("updateClipTitle", err)
+	}
+	return r.clipFromStore(clip), nil
+}
+
+// PublishClip resolves Mutation.publishClip
+func (r *Resolver) PublishClip(ctx context.Context, args struct{ ID gql.ID }) (*Clip, error) {
+	if r.clips == nil {
+		return nil, errNotImplemented("publishClip")
+	}
+	claims, ok := users.ClaimsFromContext(ctx)
+	if !ok {
+		return nil, newError(CodeUnauthenticated, "sign in to publish clips")
+	}
+
+	clip, err := r.clips.Publish(ctx, claims.UserID(), string(args.ID))
+	if err != nil {
+		return nil, clipError("publishClip", err)
+	}
+	return r.clipFromStore(clip), nil
+}
+
+// Stream resolves Clip.stream; it is null once the stream is deleted
+func (c *Clip) Stream(ctx context.Context) (*Stream, error) {
+	stream, err := c.resolver.loadStream(ctx, c.streamID)
+	if err != nil {
+		return nil, internalError("stream", err)
+	}
+	if stream == nil {
+		return nil, nil
+	}
+	return streamFromStore(stream), nil
+}
+
+// Creator resolves Clip.creator
+func (c *Clip) Creator(ctx context.Context) (*User, error) {
+	if c.resolver.users == nil {
+		return nil, nil
+	}
+	account, err := c.resolver.loadAccount(ctx, c.creatorID)
+	if err != nil {
+		return nil, internalError("creator", err)
+	}
+	if account == nil {
+		return nil, nil
+	}
+	claims, _ := users.ClaimsFromContext(ctx)
+	return userFromAccount(account, c.resolver.users, claims != nil && claims.UserID() == account.ID), nil
+}
+
+// clipFromStore converts a stored clip
+func (r *Resolver) clipFromStore(c *clips.Clip) *Clip {
+	clip := &Clip{
+		ID:                gql.ID(c.ID),
+		Title:             c.Title,
+		Status:            c.Status,
+		StartOffset:       c.Start.Seconds(),
+		EndOffset:         c.End.Seconds(),
+		Duration:          c.Duration().Seconds(),
+		SourceStartOffset: c.SourceStart.Seconds(),
+		SourceEndOffset:   c.SourceEnd.Seconds(),
+		CreatedAt:         gql.Time{Time: c.CreatedAt},
+		streamID:          c.StreamID,
+		creatorID:         c.CreatorID,
+		resolver:          r,
+	}
+	if c.PublishedAt != nil {
+		clip.PublishedAt = &gql.Time{Time: *c.PublishedAt}
+	}
+	return clip
+}
+
+// seconds converts a GraphQL offset to a duration, rounded to milliseconds
+func seconds(s float64) time.Duration {
+	return time.Duration(math.Round(s*1000)) * time.Millisecond
+}
+
+// clipError maps clip errors to GraphQL errors
+func clipError(field string, err error) error {
+	switch {
+	case errors.Is(err, clips.ErrNotFound), errors.Is(err, clips.ErrStreamNotFound):
+		return newError(CodeNotFound, err.Error())
+	case errors.Is(err, clips.ErrNotCreator):
+		return newError(CodeForbidden, err.Error())
+	case errors.Is(err, safety.ErrContentQuarantined):
+		return newError(CodeForbidden, "clip cannot be published")
+	case errors.Is(err, clips.ErrNotDraft), errors.Is(err, clips.ErrStreamNotLive),
+		errors.Is(err, clips.ErrInvalidTitle), errors.Is(err, clips.ErrInvalidTrim),
+		errors.Is(err, clips.ErrTooLong), errors.Is(err, clips.ErrTooShort),
+		errors.Is(err, clips.ErrTitleRequired):
+		return newError(CodeBadUserInput, err.Error())
+	default:
+		return internalError(field, err)
+	}
+}
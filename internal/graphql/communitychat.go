@@ -0,0 +1,117 @@
+package graphql
+
+import (
+	"context"
+	"errors"
+	"math"
+	"time"
+
+	gql "github.com/graph-gophers/graphql-go"
+	"github.com/tinle0301/streaming-platform-api/internal/analytics"
+	"github.com/tinle0301/streaming-platform-api/internal/communitychat"
+	"github.com/tinle0301/streaming-platform-api/internal/users"
+	"github.com/tinle0301/streaming-platform-api/internal/websocket"
+)
+
+// SetCommunityChat enables channels' offline community chat
+func (r *Resolver) SetCommunityChat(service *communitychat.Service) {
+	r.communityChat = service
+}
+
+// CommunityChat resolves Query.communityChat
+func (r *Resolver) CommunityChat(ctx context.Context, args struct{ ChannelID gql.ID }) (*CommunityChat, error) {
+	if r.communityChat == nil {
+		return nil, errNotImplemented("communityChat")
+	}
+
+	settings, err := r.communityChat.Settings(ctx, string(args.ChannelID))
+	if err != nil {
+		return nil, internalError("communityChat", err)
+	}
+	return r.communityChatFromSettings(ctx, "communityChat", settings)
+}
+
+// CommunityChatAnalytics resolves Query.communityChatAnalytics
+func (r *Resolver) CommunityChatAnalytics(ctx context.Context, args struct {
+	ChannelID gql.ID
+	TimeRange string
+}) (*CommunityChatAnalytics, error) {
+	if r.communityChat == nil {
+		return nil, errNotImplemented("communityChatAnalytics")
+	}
+
+	summary, err := r.communityChat.Report(ctx, string(args.ChannelID), args.TimeRange)
+	if errors.Is(err, analytics.ErrUnknownRange) {
+		return nil, newError(CodeBadUserInput, err.Error())
+	}
+	if err != nil {
+		return nil, internalError("communityChatAnalytics", err)
+	}
+	return &CommunityChatAnalytics{
+		ChannelID:       args.ChannelID,
+		PeakChatters:    int32(summary.PeakChatters),
+		AverageChatters: int32(math.Round(summary.AverageChatters)),
+		ChatMessages:    int32(summary.ChatMessages),
+	}, nil
+}
+
+// UpdateCommunityChat resolves Mutation.updateCommunityChat
+func (r *Resolver) UpdateCommunityChat(ctx context.Context, args struct {
+	ChannelID *gql.ID
+	Input     CommunityChatInput
+}) (*CommunityChat, error) {
+	if r.communityChat == nil {
+		return nil, errNotImplemented("updateCommunityChat")
+	}
+	claims, ok := users.ClaimsFromContext(ctx)
+	if !ok {
+		return nil, newError(CodeUnauthenticated, "sign in to manage community chat")
+	}
+	channelID := claims.UserID()
+	if args.ChannelID != nil && string(*args.ChannelID) != channelID {
+		if !r.managesChannel(ctx, string(*args.ChannelID), claims.UserID()) {
+			return nil, newError(CodeForbidden, "only the channel owner or their organization's managers can manage its community chat")
+		}
+		channelID = string(*args.ChannelID)
+	}
+
+	slowMode := time.Duration(args.Input.SlowModeSeconds) * time.Second
+	settings, err := r.communityChat.Update(ctx, channelID, args.Input.Enabled, slowMode)
+	if errors.Is(err, communitychat.ErrInvalidSlowMode) {
+		return nil, newError(CodeBadUserInput, err.Error())
+	}
+	if errors.Is(err, communitychat.ErrNotFound) {
+		return nil, newError(CodeNotFound, "channel not found")
+	}
+	if err != nil {
+		return nil, internalError("updateCommunityChat", err)
+	}
+	return r.communityChatFromSettings(ctx, "updateCommunityChat", settings)
+}
+
+// communityChatFromSettings converts a channel's settings, with whether its
+// room is open and how many are in it
+func (r *Resolver) communityChatFromSettings(ctx context.Context, field string, settings *communitychat.Settings) (*CommunityChat, error) {
+	room := websocket.CommunityRoom(settings.ChannelID)
+	result := &CommunityChat{
+		ChannelID:       gql.ID(settings.ChannelID),
+		Enabled:         settings.Enabled,
+		Room:            room,
+		SlowModeSeconds: int32(settings.SlowMode / time.Second),
+	}
+
+	open, err := r.communityChat.Open(ctx, settings.ChannelID)
+	if err != nil {
+		return nil, internalError(field, err)
+	}
+	result.Open = open
+
+	if open && r.presence != nil {
+		chatters, err := r.presence.GetViewers(ctx, room)
+		if err != nil {
+			return nil, internalError(field, err)
+		}
+		result.Chatters = int32(chatters.Total())
+	}
+	return result, nil
+}
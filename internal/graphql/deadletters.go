@@ -0,0 +1,159 @@
+package graphql
+
+import (
+	"context"
+	"errors"
+
+	gql "github.com/graph-gophers/graphql-go"
+	"github.com/tinle0301/streaming-platform-api/internal/events"
+	"github.com/tinle0301/streaming-platform-api/internal/users"
+)
+
+// SetDeadLetters enables the dead-letter queries and mutations for
+// platform admins
+func (r *Resolver) SetDeadLetters(queue events.DeadLetterQueue) {
+	r.deadLetters = queue
+}
+
+// SetPlatformAdmins sets the users allowed to run platform admin queries
+// and mutations
+func (r *Resolver) SetPlatformAdmins(adminIDs []string) {
+	r.admins = make(map[string]bool, len(adminIDs))
+	for _, id := range adminIDs {
+		r.admins[id] = true
+	}
+}
+
+// DeadLetters resolves Query.deadLetters
+func (r *Resolver) DeadLetters(ctx context.Context, args struct {
+	Limit int32
+	After *gql.ID
+}) ([]*DeadLetter, error) {
+	if err := r.requireAdmin(ctx, "deadLetters"); err != nil {
+		return nil, err
+	}
+	if args.Limit < 1 || args.Limit > events.MaxDeadLetters {
+		return nil, newError(CodeBadUserInput, "limit must be between 1 and 100")
+	}
+	var after string
+	if args.After != nil {
+		after = string(*args.After)
+	}
+
+	letters, err := r.deadLetters.List(ctx, int(args.Limit), after)
+	if errors.Is(err, events.ErrDeadLetterNotFound) {
+		return nil, newError(CodeBadUserInput, "after is not a dead letter ID")
+	}
+	if err != nil {
+		return nil, internalError("deadLetters", err)
+	}
+	result := make([]*DeadLetter, 0, len(letters))
+	for _, letter := range letters {
+		result = append(result, deadLetterFromEvents(letter))
+	}
+	return result, nil
+}
+
+// DeadLetter resolves Query.deadLetter
+func (r *Resolver) DeadLetter(ctx context.Context, args struct{ ID gql.ID }) (*DeadLetter, error) {
+	if err := r.requireAdmin(ctx, "deadLetter"); err != nil {
+		return nil, err
+	}
+
+	letter, err := r.deadLetters.Get(ctx, string(args.ID))
+	if errors.Is(err, events.ErrDeadLetterNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, internalError("deadLetter", err)
+	}
+	return deadLetterFromEvents(letter), nil
+}
+
+// RequeueDeadLetter resolves Mutation.requeueDeadLetter
+func (r *Resolver) RequeueDeadLetter(ctx context.Context, args struct{ ID gql.ID }) (*DeadLetter, error) {
+	if err := r.requireAdmin(ctx, "requeueDeadLetter"); err != nil {
+		return nil, err
+	}
+	if r.publisher == nil {
+		return nil, errNotImplemented("requeueDeadLetter")
+	}
+
+	letter, err := events.Requeue(ctx, r.deadLetters, r.publisher, string(args.ID))
+	if errors.Is(err, events.ErrDeadLetterNotFound) {
+		return nil, newError(CodeNotFound, err.Error())
+	}
+	if err != nil {
+		return nil, internalError("requeueDeadLetter", err)
+	}
+	return deadLetterFromEvents(letter), nil
+}
+
+// DiscardDeadLetter resolves Mutation.discardDeadLetter
+func (r *Resolver) DiscardDeadLetter(ctx context.Context, args struct{ ID gql.ID }) (bool, error) {
+	if err := r.requireAdmin(ctx, "discardDeadLetter"); err != nil {
+		return false, err
+	}
+
+	err := r.deadLetters.Delete(ctx, string(args.ID))
+	if errors.Is(err, events.ErrDeadLetterNotFound) {
+		return false, newError(CodeNotFound, err.Error())
+	}
+	if err != nil {
+		return false, internalError("discardDeadLetter", err)
+	}
+	return true, nil
+}
+
+// requireAdmin allows only platform admins, when dead letters are enabled
+func (r *Resolver) requireAdmin(ctx context.Context, field string) error {
+	if r.deadLetters == nil {
+		return errNotImplemented(field)
+	}
+	return r.requirePlatformAdmin(ctx, "manage dead letters")
+}
+
+// requirePlatformAdmin returns an error unless the viewer is a platform
+// admin; action completes "sign in to" and "only platform admins can"
+func (r *Resolver) requirePlatformAdmin(ctx context.Context, action string) error {
+	claims, ok := users.ClaimsFromContext(ctx)
+	if !ok {
+		return newError(CodeUnauthenticated, "sign in to "+action)
+	}
+	if !r.admins[claims.UserID()] {
+		return newError(CodeForbidden, "only platform admins can "+action)
+	}
+	return nil
+}
+
+// deadLetterFromEvents converts a dead letter
+func deadLetterFromEvents(letter *events.DeadLetter) *DeadLetter {
+	event := letter.Event
+	result := &DeadLetter{
+		ID:           gql.ID(letter.ID),
+		Consumer:     letter.Consumer,
+		Error:        letter.Error,
+		Attempts:     int32(letter.Attempts),
+		FailedAt:     gql.Time{Time: letter.FailedAt},
+		EventID:      gql.ID(event.ID),
+		EventType:    event.Type,
+		EventVersion: event.Version,
+		UserID:       optionalID(event.UserID),
+		StreamID:     optionalID(event.StreamID),
+		TenantID:     optionalID(event.TenantID),
+		PublishedAt:  gql.Time{Time: event.Timestamp},
+	}
+	if len(event.Data) > 0 {
+		result.Data = &JSON{Value: event.Data}
+	}
+	return result
+}
+
+// optionalID returns nil for an empty ID
+func optionalID(id string) *gql.ID {
+	if id == "" {
+		return nil
+	}
+	gid := gql.ID(id)
+	return &gid
+}
@@ -0,0 +1,46 @@
+package graphql
+
+// Error codes returned in the extensions of GraphQL errors
+const (
+	CodeBadUserInput     = "BAD_USER_INPUT"
+	CodeUnauthenticated  = "UNAUTHENTICATED"
+	CodeForbidden        = "FORBIDDEN"
+	CodeNotFound         = "NOT_FOUND"
+	CodeNotImplemented   = "NOT_IMPLEMENTED"
+	CodeInternal         = "INTERNAL"
+	CodeReadOnly         = "READ_ONLY"
+	CodeTermsNotAccepted = "TERMS_NOT_ACCEPTED"
+	CodePaymentRequired  = "PAYMENT_REQUIRED"
+
+	// Automatic persisted query codes, as Apollo clients expect them
+	CodePersistedQueryNotFound     = "PERSISTED_QUERY_NOT_FOUND"
+	CodePersistedQueryNotSupported = "PERSISTED_QUERY_NOT_SUPPORTED"
+)
+
+// Error is a resolver error carrying a machine-readable code
+type Error struct {
+	Code    string
+	Message string
+}
+
+// Error implements the error interface
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Extensions exposes the error code in the GraphQL response
+func (e *Error) Extensions() map[string]interface{} {
+	return map[string]interface{}{
+		"code": e.Code,
+	}
+}
+
+// newError creates a resolver error with a code
+func newError(code, message string) *Error {
+	return &Error{Code: code, Message: message}
+}
+
+// errNotImplemented is returned by fields that have no backing service yet
+func errNotImplemented(field string) *Error {
+	return newError(CodeNotImplemented, field+" is not implemented yet")
+}
@@ -0,0 +1,152 @@
+package graphql
+
+import (
+	"context"
+	"errors"
+
+	gql "github.com/graph-gophers/graphql-go"
+	"github.com/tinle0301/streaming-platform-api/internal/users"
+)
+
+// FollowUser resolves Mutation.followUser and returns the followed user
+func (r *Resolver) FollowUser(ctx context.Context, args struct{ UserID gql.ID }) (*User, error) {
+	return r.changeFollow(ctx, "followUser", string(args.UserID), true)
+}
+
+// UnfollowUser resolves Mutation.unfollowUser and returns the unfollowed user
+func (r *Resolver) UnfollowUser(ctx context.Context, args struct{ UserID gql.ID }) (*User, error) {
+	return r.changeFollow(ctx, "unfollowUser", string(args.UserID), false)
+}
+
+// changeFollow follows or unfollows userID as the authenticated viewer
+func (r *Resolver) changeFollow(ctx context.Context, field, userID string, follow bool) (*User, error) {
+	if r.users == nil {
+		return nil, errNotImplemented(field)
+	}
+
+	claims, ok := users.ClaimsFromContext(ctx)
+	if !ok {
+		return nil, newError(CodeUnauthenticated, "sign in to follow users")
+	}
+
+	target, err := r.users.Get(ctx, userID)
+	if errors.Is(err, users.ErrNotFound) {
+		return nil, newError(CodeNotFound, "user not found")
+	}
+	if err != nil {
+		return nil, internalError(field, err)
+	}
+
+	if follow {
+		err = r.users.Follow(ctx, claims.UserID(), target.ID)
+	} else {
+		err = r.users.Unfollow(ctx, claims.UserID(), target.ID)
+	}
+	switch {
+	case errors.Is(err, users.ErrSelfFollow):
+		return nil, newError(CodeBadUserInput, err.Error())
+	case errors.Is(err, users.ErrBlocked):
+		return nil, newError(CodeForbidden, err.Error())
+	case errors.Is(err, users.ErrNotFound):
+		// The viewer's own account no longer exists
+		return nil, newError(CodeUnauthenticated, "account not found")
+	case err != nil:
+		return nil, internalError(field, err)
+	}
+
+	return userFromAccount(target, r.users, target.ID == claims.UserID()), nil
+}
+
+// FollowerCount resolves User.followerCount
+func (u *User) FollowerCount(ctx context.Context) (int32, error) {
+	if u.follows == nil {
+		return 0, nil
+	}
+	counts, err := loadFollowCounts(ctx, u.follows, string(u.ID))
+	if err != nil {
+		return 0, internalError("followerCount", err)
+	}
+	return int32(counts.Followers), nil
+}
+
+// FollowingCount resolves User.followingCount
+func (u *User) FollowingCount(ctx context.Context) (int32, error) {
+	if u.follows == nil {
+		return 0, nil
+	}
+	counts, err := loadFollowCounts(ctx, u.follows, string(u.ID))
+	if err != nil {
+		return 0, internalError("followingCount", err)
+	}
+	return int32(counts.Following), nil
+}
+
+// IsFollowedByViewer resolves User.isFollowedByViewer
+func (u *User) IsFollowedByViewer(ctx context.Context) (bool, error) {
+	claims, ok := users.ClaimsFromContext(ctx)
+	if u.follows == nil || !ok {
+		return false, nil
+	}
+	following, err := u.follows.IsFollowing(ctx, claims.UserID(), string(u.ID))
+	if err != nil {
+		return false, internalError("isFollowedByViewer", err)
+	}
+	return following, nil
+}
+
+// followPageArgs are the pagination arguments of User.followers and User.following
+type followPageArgs struct {
+	First int32
+	After *string
+}
+
+// Followers resolves User.followers
+func (u *User) Followers(ctx context.Context, args followPageArgs) (*UserConnection, error) {
+	return u.followConnection(ctx, "followers", args, u.follows.Followers)
+}
+
+// Following resolves User.following
+func (u *User) Following(ctx context.Context, args followPageArgs) (*UserConnection, error) {
+	return u.followConnection(ctx, "following", args, u.follows.Following)
+}
+
+// followConnection loads one page of follows and converts it to a connection
+func (u *User) followConnection(ctx context.Context, field string, args followPageArgs,
+	list func(ctx context.Context, userID string, first int, after string) (*users.FollowPage, error)) (*UserConnection, error) {
+	connection := &UserConnection{Edges: []*UserEdge{}, PageInfo: &PageInfo{}}
+	if u.follows == nil {
+		return connection, nil
+	}
+	if args.First < 0 {
+		return nil, newError(CodeBadUserInput, "first must not be negative")
+	}
+	first := int(args.First)
+	if first > maxPageSize {
+		first = maxPageSize
+	}
+
+	page, err := list(ctx, string(u.ID), first, stringValue(args.After))
+	if errors.Is(err, users.ErrInvalidCursor) {
+		return nil, newError(CodeBadUserInput, "invalid cursor")
+	}
+	if err != nil {
+		return nil, internalError(field, err)
+	}
+
+	for _, follow := range page.Follows {
+		connection.Edges = append(connection.Edges, &UserEdge{
+			Node:       userFromAccount(follow.User, u.follows, false),
+			Cursor:     follow.Cursor(),
+			FollowedAt: gql.Time{Time: follow.FollowedAt},
+		})
+	}
+
+	connection.TotalCount = int32(page.TotalCount)
+	connection.PageInfo.HasNextPage = page.HasNext
+	connection.PageInfo.HasPreviousPage = args.After != nil
+	if len(connection.Edges) > 0 {
+		connection.PageInfo.StartCursor = &connection.Edges[0].Cursor
+		connection.PageInfo.EndCursor = &connection.Edges[len(connection.Edges)-1].Cursor
+	}
+	return connection, nil
+}
@@ -0,0 +1,83 @@
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/tinle0301/streaming-platform-api/internal/store"
+)
+
+// emptyStreams is a StreamRepository with no streams
+type emptyStreams struct{}
+
+func (emptyStreams) Create(ctx context.Context, stream *store.Stream) error { return nil }
+func (emptyStreams) Get(ctx context.Context, id string) (*store.Stream, error) {
+	return nil, store.ErrNotFound
+}
+func (emptyStreams) GetMany(ctx context.Context, ids []string) (map[string]*store.Stream, error) {
+	return map[string]*store.Stream{}, nil
+}
+func (emptyStreams) List(ctx context.Context, filter store.StreamFilter) ([]*store.Stream, int, error) {
+	return nil, 0, nil
+}
+func (emptyStreams) Update(ctx context.Context, stream *store.Stream) error { return nil }
+func (emptyStreams) Transition(ctx context.Context, id, from, to string) (*store.Stream, error) {
+	return nil, store.ErrNotFound
+}
+func (emptyStreams) Delete(ctx context.Context, id string) error { return nil }
+func (emptyStreams) SetSuggestedTags(ctx context.Context, id string, tags []string) error {
+	return nil
+}
+
+// FuzzHandler posts arbitrary bodies to the GraphQL handler, covering
+// request decoding, query parsing, validation, and variable coercion.
+// Every response must be a JSON GraphQL response. Inputs that once
+// crashed the server live in testdata/fuzz.
+func FuzzHandler(f *testing.F) {
+	seeds := []string{
+		`{"query":"{ __typename }"}`,
+		`{"query":"query($id: ID!) { stream(id: $id) { id title } }","variables":{"id":"1"}}`,
+		`{"query":"query($id: ID!) { stream(id: $id) { id } }","variables":{"id":{"nested":[1,2]}}}`,
+		`{"query":"query A { __typename } query B { __typename }","operationName":"B"}`,
+		`{"query":"query A { __typename }","operationName":"missing"}`,
+		`{"query":"mutation($input: GoLiveInput!) { goLive(input: $input) { id } }","variables":{"input":{"title":7}}}`,
+		`{"query":"{ __schema { types { name fields { name } } } }"}`,
+		`{"query":"fragment F on Query { ...F } { ...F }"}`,
+		`{"query":"{ a: __typename b: __typename","variables":null}`,
+		`{"query":""}`,
+		`{"query":12}`,
+		`{"variables":[]}`,
+		`[]`,
+		`{`,
+	}
+	for _, seed := range seeds {
+		f.Add([]byte(seed))
+	}
+
+	resolver := NewResolver(emptyStreams{})
+	schema, err := NewSchema(resolver)
+	if err != nil {
+		f.Fatalf("failed to parse schema: %v", err)
+	}
+	handler := Handler(schema, resolver)
+
+	f.Fuzz(func(t *testing.T, body []byte) {
+		rec := httptest.NewRecorder()
+		handler(rec, httptest.NewRequest(http.MethodPost, "/graphql", bytes.NewReader(body)))
+
+		if rec.Code != http.StatusOK && rec.Code != http.StatusBadRequest {
+			t.Fatalf("status = %d, want 200 or 400", rec.Code)
+		}
+		var response struct {
+			Data   json.RawMessage   `json:"data"`
+			Errors []json.RawMessage `json:"errors"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+			t.Fatalf("response is not JSON: %v: %s", err, rec.Body.Bytes())
+		}
+	})
+}
@@ -0,0 +1,79 @@
+package graphql
+
+import (
+	"context"
+	"errors"
+
+	gql "github.com/graph-gophers/graphql-go"
+	"github.com/tinle0301/streaming-platform-api/internal/compliance"
+	"github.com/tinle0301/streaming-platform-api/internal/users"
+)
+
+// SetGeoRestrictions enables Mutation.setGeoRestriction and
+// Mutation.removeGeoRestriction, and refuses restricted streams and VODs to
+// other regions
+func (r *Resolver) SetGeoRestrictions(restrictions *compliance.GeoRestrictions) {
+	r.restrictions = restrictions
+}
+
+// SetGeoRestriction resolves Mutation.setGeoRestriction
+func (r *Resolver) SetGeoRestriction(ctx context.Context, args struct{ Input GeoRestrictionInput }) (bool, error) {
+	if r.restrictions == nil {
+		return false, errNotImplemented("setGeoRestriction")
+	}
+	if err := r.requirePlatformAdmin(ctx, "restrict content by region"); err != nil {
+		return false, err
+	}
+	claims, _ := users.ClaimsFromContext(ctx)
+
+	err := r.restrictions.SetRestriction(ctx, compliance.GeoRestriction{
+		ContentID: string(args.Input.ContentID),
+		Kind:      args.Input.Kind,
+		Countries: args.Input.Countries,
+		AllowList: args.Input.AllowList,
+		Reason:    args.Input.Reason,
+		CreatedBy: claims.UserID(),
+	})
+	if errors.Is(err, compliance.ErrInvalidRestriction) {
+		return false, newError(CodeBadUserInput, err.Error())
+	}
+	if err != nil {
+		return false, internalError("setGeoRestriction", err)
+	}
+	return true, nil
+}
+
+// RemoveGeoRestriction resolves Mutation.removeGeoRestriction; it reports
+// whether the content was restricted
+func (r *Resolver) RemoveGeoRestriction(ctx context.Context, args struct{ ContentID gql.ID }) (bool, error) {
+	if r.restrictions == nil {
+		return false, errNotImplemented("removeGeoRestriction")
+	}
+	if err := r.requirePlatformAdmin(ctx, "restrict content by region"); err != nil {
+		return false, err
+	}
+	claims, _ := users.ClaimsFromContext(ctx)
+
+	removed, err := r.restrictions.RemoveRestriction(ctx, string(args.ContentID), claims.UserID())
+	if err != nil {
+		return false, internalError("removeGeoRestriction", err)
+	}
+	return removed, nil
+}
+
+// checkRegion returns a GEO_RESTRICTED error if the client's region may not
+// play content
+func (r *Resolver) checkRegion(ctx context.Context, field, contentID string) error {
+	if r.restrictions == nil {
+		return nil
+	}
+	err := r.restrictions.CheckPlayback(ctx, contentID, clientIPFrom(ctx))
+	var restricted *compliance.RestrictionError
+	if errors.As(err, &restricted) {
+		return restricted
+	}
+	if err != nil {
+		return internalError(field, err)
+	}
+	return nil
+}
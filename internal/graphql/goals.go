@@ -0,0 +1,123 @@
+package graphql
+
+import (
+	"context"
+	"errors"
+	"math"
+
+	gql "github.com/graph-gophers/graphql-go"
+	"github.com/tinle0301/streaming-platform-api/internal/goals"
+	"github.com/tinle0301/streaming-platform-api/internal/users"
+	"github.com/tinle0301/streaming-platform-api/internal/websocket"
+)
+
+// SetGoals enables channels' follower, subscription, and cheer goals
+func (r *Resolver) SetGoals(service *goals.Service) {
+	r.goals = service
+}
+
+// ChannelGoals resolves Query.channelGoals; overlays read it without signing in
+func (r *Resolver) ChannelGoals(ctx context.Context, args struct {
+	ChannelID    gql.ID
+	IncludeEnded bool
+}) ([]*ChannelGoal, error) {
+	if r.goals == nil {
+		return nil, errNotImplemented("channelGoals")
+	}
+
+	list, err := r.goals.List(ctx, string(args.ChannelID), args.IncludeEnded)
+	if err != nil {
+		return nil, internalError("channelGoals", err)
+	}
+	result := make([]*ChannelGoal, 0, len(list))
+	for _, goal := range list {
+		result = append(result, goalFromStore(goal))
+	}
+	return result, nil
+}
+
+// CreateChannelGoal resolves Mutation.createChannelGoal
+func (r *Resolver) CreateChannelGoal(ctx context.Context, args struct {
+	ChannelID *gql.ID
+	Input     ChannelGoalInput
+}) (*ChannelGoal, error) {
+	if r.goals == nil {
+		return nil, errNotImplemented("createChannelGoal")
+	}
+	claims, ok := users.ClaimsFromContext(ctx)
+	if !ok {
+		return nil, newError(CodeUnauthenticated, "sign in to manage channel goals")
+	}
+	channelID := claims.UserID()
+	if args.ChannelID != nil && string(*args.ChannelID) != channelID {
+		if !r.managesChannel(ctx, string(*args.ChannelID), claims.UserID()) {
+			return nil, newError(CodeForbidden, "only the channel owner or their organization's managers can manage its goals")
+		}
+		channelID = string(*args.ChannelID)
+	}
+
+	goal, err := r.goals.Create(ctx, channelID, args.Input.Kind, stringValue(args.Input.Description), int64(args.Input.Target))
+	if err != nil {
+		return nil, goalsError("createChannelGoal", err)
+	}
+	return goalFromStore(goal), nil
+}
+
+// CancelChannelGoal resolves Mutation.cancelChannelGoal
+func (r *Resolver) CancelChannelGoal(ctx context.Context, args struct{ ID gql.ID }) (*ChannelGoal, error) {
+	if r.goals == nil {
+		return nil, errNotImplemented("cancelChannelGoal")
+	}
+	claims, ok := users.ClaimsFromContext(ctx)
+	if !ok {
+		return nil, newError(CodeUnauthenticated, "sign in to manage channel goals")
+	}
+
+	goal, err := r.goals.Get(ctx, string(args.ID))
+	if err != nil {
+		return nil, goalsError("cancelChannelGoal", err)
+	}
+	if goal.ChannelID != claims.UserID() && !r.managesChannel(ctx, goal.ChannelID, claims.UserID()) {
+		return nil, newError(CodeForbidden, "only the channel owner or their organization's managers can manage its goals")
+	}
+
+	goal, err = r.goals.Cancel(ctx, goal.ID)
+	if err != nil {
+		return nil, goalsError("cancelChannelGoal", err)
+	}
+	return goalFromStore(goal), nil
+}
+
+// goalFromStore converts a stored goal
+func goalFromStore(g *goals.Goal) *ChannelGoal {
+	goal := &ChannelGoal{
+		ID:          gql.ID(g.ID),
+		ChannelID:   gql.ID(g.ChannelID),
+		Kind:        g.Kind,
+		Description: g.Description,
+		Current:     int32(min(g.Current, math.MaxInt32)),
+		Target:      int32(g.Target),
+		Progress:    g.Progress(),
+		Status:      g.Status,
+		Room:        websocket.OverlayRoom(g.ChannelID),
+		CreatedAt:   gql.Time{Time: g.CreatedAt},
+	}
+	if g.EndedAt != nil {
+		goal.EndedAt = &gql.Time{Time: *g.EndedAt}
+	}
+	return goal
+}
+
+// goalsError maps goal errors to GraphQL errors
+func goalsError(field string, err error) error {
+	switch {
+	case errors.Is(err, goals.ErrNotFound):
+		return newError(CodeNotFound, err.Error())
+	case errors.Is(err, goals.ErrInvalidKind), errors.Is(err, goals.ErrInvalidTarget),
+		errors.Is(err, goals.ErrDescriptionTooLong), errors.Is(err, goals.ErrTargetReached),
+		errors.Is(err, goals.ErrAlreadyActive), errors.Is(err, goals.ErrNotActive):
+		return newError(CodeBadUserInput, err.Error())
+	default:
+		return internalError(field, err)
+	}
+}
@@ -0,0 +1,111 @@
+package graphql
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	gql "github.com/graph-gophers/graphql-go"
+	"github.com/graph-gophers/graphql-go/errors"
+	schema "github.com/tinle0301/streaming-platform-api/api/graphql"
+	"github.com/tinle0301/streaming-platform-api/internal/permissions"
+)
+
+const (
+	// maxRequestBytes bounds the size of a GraphQL request body
+	maxRequestBytes = 1 << 20
+
+	// maxQueryDepth bounds query nesting to stop pathological queries
+	maxQueryDepth = 12
+)
+
+// Request is a GraphQL-over-HTTP request body
+type Request struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+	Extensions    RequestExtensions      `json:"extensions"`
+}
+
+// NewSchema parses the StreamHub schema against resolver and gives the
+// resolver the permission matrix its annotations declare
+func NewSchema(resolver *Resolver) (*gql.Schema, error) {
+	s, err := gql.ParseSchema(schema.Schema, resolver,
+		gql.UseFieldResolvers(),
+		gql.MaxDepth(maxQueryDepth),
+		gql.Tracer(tracer{slow: resolver.slowOperations}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	if resolver.permissions, err = permissions.FromSchema(s.ASTSchema()); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// SetReadOnly refuses mutations and subscriptions, for serving a schema
+// older than this build until migrations are applied
+func (r *Resolver) SetReadOnly(readOnly bool) {
+	r.readOnly = readOnly
+}
+
+// Handler executes GraphQL requests against s, giving each request its own
+// loaders from resolver
+func Handler(s *gql.Schema, resolver *Resolver) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var request Request
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBytes)).Decode(&request); err != nil {
+			writeErrors(w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+			return
+		}
+		if status, err := resolver.resolvePersistedQuery(r.Context(), &request); err != nil {
+			writeError(w, status, err)
+			return
+		}
+		if request.Query == "" {
+			writeErrors(w, http.StatusBadRequest, "Missing query")
+			return
+		}
+		// Checked after persisted queries resolve, so a hash can't hide a mutation
+		if resolver.readOnly && operationType(request.Query, request.OperationName) != operationQuery {
+			writeError(w, http.StatusServiceUnavailable, newError(CodeReadOnly, "Service is read-only during a deploy"))
+			return
+		}
+		if !resolver.agreementsAccepted(r.Context(), request) {
+			writeError(w, http.StatusForbidden, newError(CodeTermsNotAccepted, "The latest terms must be accepted before continuing"))
+			return
+		}
+
+		response := s.Exec(withClientIP(resolver.WithLoaders(r.Context()), r), request.Query, request.OperationName, request.Variables)
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			log.Printf("Error encoding GraphQL response: %v", err)
+		}
+	}
+}
+
+// writeError writes a GraphQL error response with err's code for a request
+// that could not be executed
+func writeError(w http.ResponseWriter, status int, err *Error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(&gql.Response{
+		Errors: []*errors.QueryError{{Message: err.Message, Extensions: err.Extensions()}},
+	})
+}
+
+// writeErrors writes a GraphQL error response for a request that could not be executed
+func writeErrors(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(&gql.Response{
+		Errors: []*errors.QueryError{errors.Errorf("%s", message)},
+	})
+}
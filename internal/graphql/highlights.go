@@ -0,0 +1,229 @@
+package graphql
+
+import (
+	"context"
+	"errors"
+
+	gql "github.com/graph-gophers/graphql-go"
+	"github.com/tinle0301/streaming-platform-api/internal/highlights"
+	"github.com/tinle0301/streaming-platform-api/internal/users"
+)
+
+// SetHighlights enables stream markers and highlight VODs
+func (r *Resolver) SetHighlights(service *highlights.Service) {
+	r.highlights = service
+}
+
+// Vod resolves Query.vod
+func (r *Resolver) Vod(ctx context.Context, args struct{ ID gql.ID }) (*Vod, error) {
+	if r.highlights == nil {
+		return nil, errNotImplemented("vod")
+	}
+
+	vod, err := r.highlights.GetVOD(ctx, string(args.ID))
+	if errors.Is(err, highlights.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, internalError("vod", err)
+	}
+
+	// Drafts are private to the streamer; premieres are listed before they air
+	if vod.Status == highlights.StatusDraft {
+		claims, ok := users.ClaimsFromContext(ctx)
+		if !ok || claims.UserID() != vod.StreamerID {
+			return nil, nil
+		}
+	}
+	if err := r.checkRegion(ctx, "vod", vod.ID); err != nil {
+		return nil, err
+	}
+	return r.vodFromStore(vod), nil
+}
+
+// StreamMarkers resolves Query.streamMarkers
+func (r *Resolver) StreamMarkers(ctx context.Context, args struct{ StreamID gql.ID }) ([]*StreamMarker, error) {
+	if r.highlights == nil {
+		return nil, errNotImplemented("streamMarkers")
+	}
+	claims, ok := users.ClaimsFromContext(ctx)
+	if !ok {
+		return nil, newError(CodeUnauthenticated, "sign in to view stream markers")
+	}
+	stream, err := r.ownStream(ctx, "streamMarkers", claims, string(args.StreamID))
+	if err != nil {
+		return nil, err
+	}
+
+	markers, err := r.highlights.Markers(ctx, stream.ID)
+	if err != nil {
+		return nil, internalError("streamMarkers", err)
+	}
+	result := make([]*StreamMarker, 0, len(markers))
+	for _, marker := range markers {
+		result = append(result, markerFromStore(marker))
+	}
+	return result, nil
+}
+
+// CreateStreamMarker resolves Mutation.createStreamMarker
+func (r *Resolver) CreateStreamMarker(ctx context.Context, args struct {
+	StreamID    gql.ID
+	Description *string
+}) (*StreamMarker, error) {
+	if r.highlights == nil {
+		return nil, errNotImplemented("createStreamMarker")
+	}
+	claims, ok := users.ClaimsFromContext(ctx)
+	if !ok {
+		return nil, newError(CodeUnauthenticated, "sign in to add stream markers")
+	}
+	stream, err := r.ownStream(ctx, "createStreamMarker", claims, string(args.StreamID))
+	if err != nil {
+		return nil, err
+	}
+
+	marker, err := r.highlights.AddMarker(ctx, stream, claims.UserID(), stringValue(args.Description), highlights.MarkerSourceManual)
+	if err != nil {
+		return nil, highlightsError("createStreamMarker", err)
+	}
+	return markerFromStore(marker), nil
+}
+
+// CompileHighlights resolves Mutation.compileHighlights
+func (r *Resolver) CompileHighlights(ctx context.Context, args struct{ StreamID gql.ID }) (*Vod, error) {
+	if r.highlights == nil {
+		return nil, errNotImplemented("compileHighlights")
+	}
+	claims, ok := users.ClaimsFromContext(ctx)
+	if !ok {
+		return nil, newError(CodeUnauthenticated, "sign in to compile highlights")
+	}
+	stream, err := r.ownStream(ctx, "compileHighlights", claims, string(args.StreamID))
+	if err != nil {
+		return nil, err
+	}
+
+	vod, err := r.highlights.Compile(ctx, stream.ID)
+	if err != nil {
+		return nil, highlightsError("compileHighlights", err)
+	}
+	return r.vodFromStore(vod), nil
+}
+
+// ChatSpikeSensitivity resolves Query.chatSpikeSensitivity
+func (r *Resolver) ChatSpikeSensitivity(ctx context.Context, args struct{ ChannelID *gql.ID }) (string, error) {
+	if r.highlights == nil {
+		return "", errNotImplemented("chatSpikeSensitivity")
+	}
+	channelID, err := r.highlightChannel(ctx, args.ChannelID)
+	if err != nil {
+		return "", err
+	}
+
+	sensitivity, err := r.highlights.SpikeSensitivity(ctx, channelID)
+	if err != nil {
+		return "", internalError("chatSpikeSensitivity", err)
+	}
+	return sensitivity, nil
+}
+
+// SetChatSpikeSensitivity resolves Mutation.setChatSpikeSensitivity
+func (r *Resolver) SetChatSpikeSensitivity(ctx context.Context, args struct {
+	ChannelID   *gql.ID
+	Sensitivity string
+}) (string, error) {
+	if r.highlights == nil {
+		return "", errNotImplemented("setChatSpikeSensitivity")
+	}
+	channelID, err := r.highlightChannel(ctx, args.ChannelID)
+	if err != nil {
+		return "", err
+	}
+
+	if err := r.highlights.SetSpikeSensitivity(ctx, channelID, args.Sensitivity); err != nil {
+		return "", highlightsError("setChatSpikeSensitivity", err)
+	}
+	return args.Sensitivity, nil
+}
+
+// highlightChannel returns the channel whose highlight settings a request
+// targets: the viewer's own unless another channel they manage is named
+func (r *Resolver) highlightChannel(ctx context.Context, channelID *gql.ID) (string, error) {
+	claims, ok := users.ClaimsFromContext(ctx)
+	if !ok {
+		return "", newError(CodeUnauthenticated, "sign in to manage highlight settings")
+	}
+	if channelID == nil || string(*channelID) == claims.UserID() {
+		return claims.UserID(), nil
+	}
+	if !r.managesChannel(ctx, string(*channelID), claims.UserID()) {
+		return "", newError(CodeForbidden, "only the channel owner or their organization's managers can manage its highlight settings")
+	}
+	return string(*channelID), nil
+}
+
+// Stream resolves Vod.stream; it is null once the stream is deleted
+func (v *Vod) Stream(ctx context.Context) (*Stream, error) {
+	stream, err := v.resolver.loadStream(ctx, v.streamID)
+	if err != nil {
+		return nil, internalError("stream", err)
+	}
+	if stream == nil {
+		return nil, nil
+	}
+	return streamFromStore(stream), nil
+}
+
+// vodFromStore converts a stored VOD
+func (r *Resolver) vodFromStore(v *highlights.VOD) *Vod {
+	segments := make([]*VodSegment, 0, len(v.Segments))
+	for _, segment := range v.Segments {
+		reasons := segment.Reasons
+		if reasons == nil {
+			reasons = []string{}
+		}
+		segments = append(segments, &VodSegment{
+			StartOffset: segment.Start.Seconds(),
+			EndOffset:   segment.End.Seconds(),
+			Reasons:     reasons,
+		})
+	}
+	return &Vod{
+		ID:        gql.ID(v.ID),
+		Title:     v.Title,
+		Kind:      v.Kind,
+		Status:    v.Status,
+		Segments:  segments,
+		Duration:  v.Duration().Seconds(),
+		CreatedAt: gql.Time{Time: v.CreatedAt},
+		streamID:  v.StreamID,
+		source:    v,
+		resolver:  r,
+	}
+}
+
+// markerFromStore converts a stored marker
+func markerFromStore(m *highlights.Marker) *StreamMarker {
+	return &StreamMarker{
+		ID:          gql.ID(m.ID),
+		Offset:      m.Offset.Seconds(),
+		Description: m.Description,
+		Source:      m.Source,
+		CreatedAt:   gql.Time{Time: m.CreatedAt},
+	}
+}
+
+// highlightsError maps marker and highlight errors to GraphQL errors
+func highlightsError(field string, err error) error {
+	switch {
+	case errors.Is(err, highlights.ErrNotFound), errors.Is(err, highlights.ErrStreamNotFound):
+		return newError(CodeNotFound, err.Error())
+	case errors.Is(err, highlights.ErrStreamNotLive), errors.Is(err, highlights.ErrStreamNotFinished),
+		errors.Is(err, highlights.ErrNothingToCompile), errors.Is(err, highlights.ErrInvalidMarkerOffset),
+		errors.Is(err, highlights.ErrDescriptionTooLong), errors.Is(err, highlights.ErrInvalidSensitivity):
+		return newError(CodeBadUserInput, err.Error())
+	default:
+		return internalError(field, err)
+	}
+}
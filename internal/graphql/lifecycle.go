@@ -0,0 +1,243 @@
+package graphql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	gql "github.com/graph-gophers/graphql-go"
+	"github.com/tinle0301/streaming-platform-api/internal/events"
+	"github.com/tinle0301/streaming-platform-api/internal/store"
+	"github.com/tinle0301/streaming-platform-api/internal/users"
+)
+
+// Stream info limits
+const (
+	maxTitleLength       = 140
+	maxDescriptionLength = 300
+)
+
+// SetPublisher enables stream.live, stream.offline, and stream.updated events
+func (r *Resolver) SetPublisher(publisher events.Publisher) {
+	r.publisher = publisher
+}
+
+// GoLive resolves Mutation.goLive
+func (r *Resolver) GoLive(ctx context.Context, args struct{ Input GoLiveInput }) (*Stream, error) {
+	claims, ok := users.ClaimsFromContext(ctx)
+	if !ok {
+		return nil, newError(CodeUnauthenticated, "sign in to go live")
+	}
+
+	input := args.Input
+	info := UpdateStreamInfoInput{
+		Title:       input.Title,
+		Description: input.Description,
+		CategoryID:  input.CategoryID,
+		Tags:        input.Tags,
+		Language:    input.Language,
+		IsMature:    input.IsMature,
+	}
+
+	// Streamers broadcast one stream at a time
+	_, live, err := r.streams.List(ctx, store.StreamFilter{
+		StreamerID: claims.UserID(),
+		Status:     store.StreamStatusLive,
+		Limit:      1,
+	})
+	if err != nil {
+		return nil, internalError("goLive", err)
+	}
+	if live > 0 {
+		return nil, newError(CodeBadUserInput, "you already have a live stream")
+	}
+
+	var stream *store.Stream
+	if input.StreamID == nil {
+		if input.Title == nil {
+			return nil, newError(CodeBadUserInput, "title is required for a new stream")
+		}
+		now := time.Now()
+		stream = &store.Stream{
+			StreamerID:  claims.UserID(),
+			Status:      store.StreamStatusLive,
+			ChatEnabled: true,
+			StartedAt:   &now,
+		}
+		if err := r.applyStreamInfo(stream, info); err != nil {
+			return nil, err
+		}
+		if err := r.streams.Create(ctx, stream); err != nil {
+			return nil, internalError("goLive", err)
+		}
+	} else {
+		stream, err = r.ownStream(ctx, "goLive", claims, string(*input.StreamID))
+		if err != nil {
+			return nil, err
+		}
+		if stream.Status != store.StreamStatusOffline {
+			return nil, newError(CodeBadUserInput, "only offline streams can go live")
+		}
+		if err := r.applyStreamInfo(stream, info); err != nil {
+			return nil, err
+		}
+		if err := r.streams.Update(ctx, stream); err != nil {
+			return nil, internalError("goLive", err)
+		}
+		if stream, err = r.transition(ctx, "goLive", stream, store.StreamStatusLive); err != nil {
+			return nil, err
+		}
+	}
+
+	r.publish(ctx, events.NewStreamLiveEvent(stream.ID, stream.StreamerID, streamInfoData(stream)))
+	return streamFromStore(stream), nil
+}
+
+// EndStream resolves Mutation.endStream
+func (r *Resolver) EndStream(ctx context.Context, args struct{ ID gql.ID }) (*Stream, error) {
+	claims, ok := users.ClaimsFromContext(ctx)
+	if !ok {
+		return nil, newError(CodeUnauthenticated, "sign in to end your stream")
+	}
+
+	stream, err := r.ownStream(ctx, "endStream", claims, string(args.ID))
+	if err != nil {
+		return nil, err
+	}
+	if stream.Status != store.StreamStatusLive {
+		return nil, newError(CodeBadUserInput, "stream is not live")
+	}
+
+	if stream, err = r.transition(ctx, "endStream", stream, store.StreamStatusOffline); err != nil {
+		return nil, err
+	}
+
+	data := map[string]interface{}{}
+	if stream.StartedAt != nil && stream.EndedAt != nil {
+		data["duration_seconds"] = int64(stream.EndedAt.Sub(*stream.StartedAt).Seconds())
+	}
+	r.publish(ctx, events.NewStreamOfflineEvent(stream.ID, stream.StreamerID, data))
+	return streamFromStore(stream), nil
+}
+
+// UpdateStreamInfo resolves Mutation.updateStreamInfo
+func (r *Resolver) UpdateStreamInfo(ctx context.Context, args struct {
+	ID    gql.ID
+	Input UpdateStreamInfoInput
+}) (*Stream, error) {
+	claims, ok := users.ClaimsFromContext(ctx)
+	if !ok {
+		return nil, newError(CodeUnauthenticated, "sign in to update your stream")
+	}
+
+	stream, err := r.ownStream(ctx, "updateStreamInfo", claims, string(args.ID))
+	if err != nil {
+		return nil, err
+	}
+	before := disclosureOf(stream)
+	if err := r.applyStreamInfo(stream, args.Input); err != nil {
+		return nil, err
+	}
+	if args.Input.BrandedContent != nil {
+		if err := r.applyBrandedContent(ctx, stream, *args.Input.BrandedContent); err != nil {
+			return nil, err
+		}
+	}
+	if err := r.streams.Update(ctx, stream); err != nil {
+		return nil, internalError("updateStreamInfo", err)
+	}
+	r.recordDisclosure(ctx, claims.UserID(), stream, before)
+
+	r.publish(ctx, events.NewStreamUpdatedEvent(stream.ID, stream.StreamerID, streamInfoData(stream)))
+	return streamFromStore(stream), nil
+}
+
+// ownStream loads a stream the authenticated viewer owns or manages through
+// an organization
+func (r *Resolver) ownStream(ctx context.Context, field string, claims *users.Claims, id string) (*store.Stream, error) {
+	stream, err := r.streams.Get(ctx, id)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, newError(CodeNotFound, "stream not found")
+	}
+	if err != nil {
+		return nil, internalError(field, err)
+	}
+	if stream.StreamerID != claims.UserID() && !r.managesChannel(ctx, stream.StreamerID, claims.UserID()) {
+		return nil, newError(CodeForbidden, "only the stream owner or their organization's managers can change this stream")
+	}
+	return stream, nil
+}
+
+// transition moves stream to status to, failing if another request changed
+// its status first
+func (r *Resolver) transition(ctx context.Context, field string, stream *store.Stream, to string) (*store.Stream, error) {
+	updated, err := r.streams.Transition(ctx, stream.ID, stream.Status, to)
+	if errors.Is(err, store.ErrStatusConflict) {
+		return nil, newError(CodeBadUserInput, "stream status changed; try again")
+	}
+	if err != nil {
+		return nil, internalError(field, err)
+	}
+	return updated, nil
+}
+
+// publish sends a stream event. The change is already saved, so a lost
+// event must not fail the mutation.
+func (r *Resolver) publish(ctx context.Context, event events.Event) {
+	if r.publisher == nil {
+		return
+	}
+	if err := r.publisher.Publish(ctx, event); err != nil {
+		log.Printf("Error publishing %s event: streamID=%s, err=%v", event.Type, event.StreamID, err)
+	}
+}
+
+// applyStreamInfo validates input and copies its set fields onto stream
+func (r *Resolver) applyStreamInfo(stream *store.Stream, input UpdateStreamInfoInput) error {
+	if input.Title != nil {
+		title := strings.TrimSpace(*input.Title)
+		if title == "" || len(title) > maxTitleLength {
+			return newError(CodeBadUserInput, fmt.Sprintf("title must be 1-%d characters", maxTitleLength))
+		}
+		stream.Title = title
+	}
+	if input.Description != nil {
+		description := strings.TrimSpace(*input.Description)
+		if len(description) > maxDescriptionLength {
+			return newError(CodeBadUserInput, fmt.Sprintf("description must be at most %d characters", maxDescriptionLength))
+		}
+		stream.Description = description
+	}
+	if input.CategoryID != nil {
+		stream.Category = strings.TrimSpace(string(*input.CategoryID))
+	}
+	if input.Tags != nil {
+		tags, err := r.validateTags(*input.Tags)
+		if err != nil {
+			return err
+		}
+		stream.Tags = tags
+	}
+	if input.Language != nil {
+		stream.Language = strings.TrimSpace(*input.Language)
+	}
+	if input.IsMature != nil {
+		stream.IsMature = *input.IsMature
+	}
+	return nil
+}
+
+// streamInfoData is the viewer-facing stream info carried by stream events
+func streamInfoData(stream *store.Stream) map[string]interface{} {
+	return map[string]interface{}{
+		"title":           stream.Title,
+		"category":        stream.Category,
+		"tags":            stream.Tags,
+		"language":        stream.Language,
+		"is_mature":       stream.IsMature,
+		"branded_content": stream.BrandedContent,
+	}
+}
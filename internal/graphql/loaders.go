@@ -0,0 +1,116 @@
+package graphql
+
+import (
+	"context"
+	"errors"
+
+	"github.com/tinle0301/streaming-platform-api/internal/dataloader"
+	"github.com/tinle0301/streaming-platform-api/internal/store"
+	"github.com/tinle0301/streaming-platform-api/internal/users"
+)
+
+// loadersKey is the context key for a request's loaders
+type loadersKey struct{}
+
+// Loaders batches the lookups made while resolving one request, so nested
+// fields such as stream.streamer cost one query per batch rather than one
+// per stream
+type Loaders struct {
+	users        *dataloader.Loader[string, *users.User]
+	streams      *dataloader.Loader[string, *store.Stream]
+	followCounts *dataloader.Loader[string, users.FollowCounts]
+
+	// Channels' active subscriber counts; nil without subscriptions
+	subscriberCounts *dataloader.Loader[string, int]
+
+	accounts *users.Service
+}
+
+// SetLoaderOptions tunes the per-request loaders' batching and caching
+func (r *Resolver) SetLoaderOptions(opts dataloader.Options) {
+	r.loaderOptions = opts
+}
+
+// WithLoaders returns a context carrying fresh loaders for one request
+func (r *Resolver) WithLoaders(ctx context.Context) context.Context {
+	loaders := &Loaders{
+		streams: dataloader.New(r.streams.GetMany, r.loaderOptions),
+	}
+	if r.users != nil {
+		loaders.accounts = r.users
+		loaders.users = dataloader.New(r.users.GetMany, r.loaderOptions)
+		loaders.followCounts = dataloader.New(r.users.FollowCountsMany, r.loaderOptions)
+	}
+	if r.subscriptions != nil {
+		loaders.subscriberCounts = dataloader.New(r.subscriptions.CountMany, r.loaderOptions)
+	}
+	return context.WithValue(ctx, loadersKey{}, loaders)
+}
+
+// loadersFrom returns the request's loaders, or nil outside a request
+func loadersFrom(ctx context.Context) *Loaders {
+	loaders, _ := ctx.Value(loadersKey{}).(*Loaders)
+	return loaders
+}
+
+// loadStream returns a stream by ID, or nil if it does not exist
+func (r *Resolver) loadStream(ctx context.Context, id string) (*store.Stream, error) {
+	if loaders := loadersFrom(ctx); loaders != nil {
+		return loaders.streams.Load(ctx, id)
+	}
+
+	stream, err := r.streams.Get(ctx, id)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, nil
+	}
+	return stream, err
+}
+
+// loadAccount returns a user account by ID, or nil if it does not exist
+func (r *Resolver) loadAccount(ctx context.Context, id string) (*users.User, error) {
+	if loaders := loadersFrom(ctx); loaders != nil && loaders.users != nil {
+		return loaders.users.Load(ctx, id)
+	}
+
+	account, err := r.users.Get(ctx, id)
+	if errors.Is(err, users.ErrNotFound) {
+		return nil, nil
+	}
+	return account, err
+}
+
+// loadAccounts returns user accounts by ID in order; missing accounts are nil
+func (r *Resolver) loadAccounts(ctx context.Context, ids []string) ([]*users.User, error) {
+	if loaders := loadersFrom(ctx); loaders != nil && loaders.users != nil {
+		return loaders.users.LoadMany(ctx, ids)
+	}
+
+	found, err := r.users.GetMany(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	accounts := make([]*users.User, len(ids))
+	for i, id := range ids {
+		accounts[i] = found[id]
+	}
+	return accounts, nil
+}
+
+// loadFollowCounts returns a user's follower and following counts
+func loadFollowCounts(ctx context.Context, service *users.Service, userID string) (users.FollowCounts, error) {
+	if loaders := loadersFrom(ctx); loaders != nil && loaders.followCounts != nil {
+		return loaders.followCounts.Load(ctx, userID)
+	}
+
+	followers, following, err := service.FollowCounts(ctx, userID)
+	return users.FollowCounts{Followers: followers, Following: following}, err
+}
+
+// loadSubscriberCount returns a channel's active subscribers; it is 0
+// outside a request or without subscriptions
+func loadSubscriberCount(ctx context.Context, channelID string) (int, error) {
+	if loaders := loadersFrom(ctx); loaders != nil && loaders.subscriberCounts != nil {
+		return loaders.subscriberCounts.Load(ctx, channelID)
+	}
+	return 0, nil
+}
@@ -0,0 +1,146 @@
+package graphql
+
+import (
+	"context"
+	"errors"
+	"math"
+
+	gql "github.com/graph-gophers/graphql-go"
+	"github.com/tinle0301/streaming-platform-api/internal/milestones"
+	"github.com/tinle0301/streaming-platform-api/internal/users"
+)
+
+// SetMilestones enables channels' viewer, follower, and duration milestones
+func (r *Resolver) SetMilestones(service *milestones.Service) {
+	r.milestones = service
+}
+
+// MilestoneSettings resolves Query.milestoneSettings
+func (r *Resolver) MilestoneSettings(ctx context.Context, args struct{ ChannelID *gql.ID }) (*MilestoneSettings, error) {
+	if r.milestones == nil {
+		return nil, errNotImplemented("milestoneSettings")
+	}
+	channelID, err := r.milestoneChannel(ctx, args.ChannelID)
+	if err != nil {
+		return nil, err
+	}
+
+	settings, err := r.milestones.Settings(ctx, channelID)
+	if err != nil {
+		return nil, milestonesError("milestoneSettings", err)
+	}
+	return milestoneSettingsFromStore(settings), nil
+}
+
+// StreamMilestones resolves Query.streamMilestones
+func (r *Resolver) StreamMilestones(ctx context.Context, args struct{ StreamID gql.ID }) ([]*StreamMilestone, error) {
+	if r.milestones == nil {
+		return nil, errNotImplemented("streamMilestones")
+	}
+
+	list, err := r.milestones.Reached(ctx, string(args.StreamID))
+	if err != nil {
+		return nil, internalError("streamMilestones", err)
+	}
+	result := make([]*StreamMilestone, 0, len(list))
+	for _, m := range list {
+		result = append(result, &StreamMilestone{
+			ID:        gql.ID(m.ID),
+			StreamID:  gql.ID(m.StreamID),
+			Kind:      m.Kind,
+			Threshold: int32(m.Threshold),
+			Value:     int32(min(m.Value, math.MaxInt32)),
+			Message:   milestones.Message(m.Kind, m.Threshold),
+			ReachedAt: gql.Time{Time: m.ReachedAt},
+		})
+	}
+	return result, nil
+}
+
+// UpdateMilestoneSettings resolves Mutation.updateMilestoneSettings
+func (r *Resolver) UpdateMilestoneSettings(ctx context.Context, args struct {
+	ChannelID *gql.ID
+	Input     MilestoneSettingsInput
+}) (*MilestoneSettings, error) {
+	if r.milestones == nil {
+		return nil, errNotImplemented("updateMilestoneSettings")
+	}
+	channelID, err := r.milestoneChannel(ctx, args.ChannelID)
+	if err != nil {
+		return nil, err
+	}
+
+	settings := &milestones.Settings{
+		ChannelID:          channelID,
+		Enabled:            args.Input.Enabled,
+		ViewerThresholds:   thresholdsFromInput(args.Input.ViewerThresholds),
+		FollowerThresholds: thresholdsFromInput(args.Input.FollowerThresholds),
+		DurationMinutes:    thresholdsFromInput(args.Input.DurationMinutes),
+	}
+	if err := r.milestones.UpdateSettings(ctx, settings); err != nil {
+		return nil, milestonesError("updateMilestoneSettings", err)
+	}
+	return milestoneSettingsFromStore(settings), nil
+}
+
+// milestoneChannel returns the channel whose milestones the viewer manages:
+// their own, or one their organization manages
+func (r *Resolver) milestoneChannel(ctx context.Context, channelID *gql.ID) (string, error) {
+	claims, ok := users.ClaimsFromContext(ctx)
+	if !ok {
+		return "", newError(CodeUnauthenticated, "sign in to manage milestones")
+	}
+	if channelID == nil || string(*channelID) == claims.UserID() {
+		return claims.UserID(), nil
+	}
+	if !r.managesChannel(ctx, string(*channelID), claims.UserID()) {
+		return "", newError(CodeForbidden, "only the channel owner or their organization's managers can manage its milestones")
+	}
+	return string(*channelID), nil
+}
+
+// milestoneSettingsFromStore converts stored milestone settings
+func milestoneSettingsFromStore(s *milestones.Settings) *MilestoneSettings {
+	settings := &MilestoneSettings{
+		ChannelID:          gql.ID(s.ChannelID),
+		Enabled:            s.Enabled,
+		ViewerThresholds:   thresholdsToOutput(s.ViewerThresholds),
+		FollowerThresholds: thresholdsToOutput(s.FollowerThresholds),
+		DurationMinutes:    thresholdsToOutput(s.DurationMinutes),
+	}
+	if s.UpdatedAt != nil {
+		settings.UpdatedAt = &gql.Time{Time: *s.UpdatedAt}
+	}
+	return settings
+}
+
+// thresholdsFromInput widens GraphQL thresholds
+func thresholdsFromInput(values []int32) []int64 {
+	thresholds := make([]int64, 0, len(values))
+	for _, v := range values {
+		thresholds = append(thresholds, int64(v))
+	}
+	return thresholds
+}
+
+// thresholdsToOutput narrows stored thresholds, which are validated to fit
+func thresholdsToOutput(values []int64) []int32 {
+	thresholds := make([]int32, 0, len(values))
+	for _, v := range values {
+		thresholds = append(thresholds, int32(v))
+	}
+	return thresholds
+}
+
+// milestonesError maps milestone errors to GraphQL errors
+func milestonesError(field string, err error) error {
+	switch {
+	case errors.Is(err, milestones.ErrNotFound):
+		return newError(CodeNotFound, err.Error())
+	case errors.Is(err, milestones.ErrTooManyThresholds), errors.Is(err, milestones.ErrInvalidThreshold),
+		errors.Is(err, milestones.ErrInvalidDuration):
+		return newError(CodeBadUserInput, err.Error())
+	default:
+		return internalError(field, err)
+	}
+}
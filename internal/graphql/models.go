@@ -0,0 +1,770 @@
+package graphql
+
+import (
+	"encoding/json"
+	"fmt"
+
+	gql "github.com/graph-gophers/graphql-go"
+	"github.com/tinle0301/streaming-platform-api/internal/highlights"
+	"github.com/tinle0301/streaming-platform-api/internal/users"
+)
+
+// Object types resolve through their exported fields (UseFieldResolvers);
+// only fields that take arguments need resolver methods.
+
+// Stream is a live or past broadcast
+type Stream struct {
+	ID                gql.ID
+	Title             string
+	Description       *string
+	ViewerCount       int32
+	Status            string
+	StartedAt         *gql.Time
+	EndedAt           *gql.Time
+	ThumbnailURL      *string
+	PreviewURL        *string
+	Tags              []string
+	SuggestedTags     []string
+	Category          *Category
+	Language          string
+	IsPartner         bool
+	IsMature          bool
+	IsBrandedContent  bool
+	BrandedContent    *BrandedContentDisclosure
+	ChatEnabled       bool
+	FollowersWatching int32
+	Uptime            *int32
+
+	// Resolved through Streamer, batched per request
+	streamerID string
+	createdAt  gql.Time
+}
+
+// BrandedContentDisclosure is a branded stream's sponsorship disclosure
+type BrandedContentDisclosure struct {
+	SponsorName    *string
+	DisclosureType *string
+}
+
+// User is a viewer or streamer account
+type User struct {
+	ID            gql.ID
+	Username      string
+	DisplayName   string
+	Email         *string
+	Bio           *string
+	AvatarURL     *string
+	BannerURL     *string
+	IsLive        bool
+	IsPartner     bool
+	IsAffiliate   bool
+	CreatedAt     gql.Time
+	CurrentStream *Stream
+
+	// Most recent first; trimmed by the recentStreams limit argument
+	recentStreams []*Stream
+
+	// Follow lookups; nil for users without an account (follow fields
+	// resolve to zero values)
+	follows *users.Service
+}
+
+// RecentStreams resolves User.recentStreams
+func (u *User) RecentStreams(args struct{ Limit int32 }) []*Stream {
+	if int(args.Limit) < len(u.recentStreams) {
+		return u.recentStreams[:args.Limit]
+	}
+	return u.recentStreams
+}
+
+// Notification is a message delivered to a user
+type Notification struct {
+	ID        gql.ID
+	Type      string
+	Title     string
+	Message   string
+	Data      *JSON
+	Count     int32
+	CreatedAt gql.Time
+	Read      bool
+	ReadAt    *gql.Time
+
+	fromUserID string
+	streamID   string
+	resolver   *Resolver
+}
+
+// ChatMessage is a single chat line in a stream
+type ChatMessage struct {
+	ID           gql.ID
+	StreamID     gql.ID
+	User         *User
+	Message      string
+	Timestamp    gql.Time
+	Badges       []*Badge
+	Emotes       []*Emote
+	Color        *string
+	IsDeleted    bool
+	IsModerator  bool
+	IsSubscriber bool
+}
+
+// Category is a game or topic streams are listed under
+type Category struct {
+	ID          gql.ID
+	Name        string
+	BoxArtURL   *string
+	ViewerCount int32
+}
+
+// AuthPayload is returned by register and login
+type AuthPayload struct {
+	Token     string
+	ExpiresAt gql.Time
+	User      *User
+}
+
+// StreamAnalytics summarizes a stream's audience
+type StreamAnalytics struct {
+	StreamID         gql.ID
+	TotalViews       int32
+	PeakViewers      int32
+	AverageViewers   int32
+	TotalWatchTime   int32
+	NewFollowers     int32
+	ChatMessageCount int32
+	DataPoints       []*AnalyticsDataPoint
+	Delivery         *BroadcastDeliveryStats
+}
+
+// BroadcastDeliveryStats reports platform-side delivery quality for a stream
+type BroadcastDeliveryStats struct {
+	Broadcasts             float64
+	MessagesSent           float64
+	MessagesDropped        float64
+	DropRate               float64
+	AverageFanOutLatencyMs float64
+}
+
+// AnalyticsDataPoint is one sample in a stream's analytics time series
+type AnalyticsDataPoint struct {
+	Timestamp    gql.Time
+	ViewerCount  int32
+	ChatActivity int32
+}
+
+// ViewerCountUpdate is pushed when a stream's viewer count changes
+type ViewerCountUpdate struct {
+	StreamID  gql.ID
+	Count     int32
+	Timestamp gql.Time
+}
+
+// RaidEvent is pushed when a raid starts
+type RaidEvent struct {
+	ID          gql.ID
+	FromStream  *Stream
+	ToStream    *Stream
+	ViewerCount int32
+	Timestamp   gql.Time
+}
+
+// RaidResult is returned when a streamer raids another stream
+type RaidResult struct {
+	Success     bool
+	FromStream  *Stream
+	ToStream    *Stream
+	ViewerCount int32
+}
+
+// LegalDocument is a published terms of service or privacy policy version
+type LegalDocument struct {
+	Kind        string
+	Version     string
+	URL         string
+	PublishedAt gql.Time
+}
+
+// Badge is a chat badge shown next to a user's name
+type Badge struct {
+	ID       gql.ID
+	Name     string
+	ImageURL string
+}
+
+// Emote is a custom chat emote
+type Emote struct {
+	ID       gql.ID
+	Name     string
+	ImageURL string
+}
+
+// RaidSuggestion is a live channel suggested as a raid target
+type RaidSuggestion struct {
+	Stream  *Stream
+	Score   float64
+	Reasons []string
+}
+
+// Tag is a managed or freeform stream tag
+type Tag struct {
+	Slug            string
+	Name            string
+	Description     *string
+	Aliases         []string
+	Managed         bool
+	LiveStreamCount int32
+}
+
+// Campaign is a publisher's watch-time reward campaign
+type Campaign struct {
+	ID          gql.ID
+	Name        string
+	Description *string
+	PublisherID gql.ID
+	Categories  []string
+	StartsAt    gql.Time
+	EndsAt      gql.Time
+	Active      bool
+	Rewards     []*CampaignReward
+	WebhookURL  *string
+}
+
+// CampaignReward is unlocked by watching a campaign long enough
+type CampaignReward struct {
+	ID              gql.ID
+	Name            string
+	Description     *string
+	ImageURL        *string
+	RequiredMinutes int32
+}
+
+// CampaignProgress is the viewer's watch time towards a campaign
+type CampaignProgress struct {
+	Campaign         *Campaign
+	WatchedMinutes   int32
+	ClaimedRewardIDs []gql.ID
+}
+
+// CampaignRewardClaim is a claimed reward
+type CampaignRewardClaim struct {
+	ID        gql.ID
+	Reward    *CampaignReward
+	ClaimedAt gql.Time
+}
+
+// CreateCampaignPayload is the result of createCampaign
+type CreateCampaignPayload struct {
+	Campaign      *Campaign
+	WebhookSecret *string
+}
+
+// Clip is a segment cut from a stream; offsets are in seconds
+type Clip struct {
+	ID                gql.ID
+	Title             string
+	Status            string
+	StartOffset       float64
+	EndOffset         float64
+	Duration          float64
+	SourceStartOffset float64
+	SourceEndOffset   float64
+	CreatedAt         gql.Time
+	PublishedAt       *gql.Time
+
+	streamID  string
+	creatorID string
+	resolver  *Resolver
+}
+
+// StreamMarker flags a moment of a broadcast; offset is in seconds
+type StreamMarker struct {
+	ID          gql.ID
+	Offset      float64
+	Description string
+	Source      string
+	CreatedAt   gql.Time
+}
+
+// Vod is a video assembled from ranges of a broadcast
+type Vod struct {
+	ID        gql.ID
+	Title     string
+	Kind      string
+	Status    string
+	Segments  []*VodSegment
+	Duration  float64
+	CreatedAt gql.Time
+
+	streamID string
+	source   *highlights.VOD
+	resolver *Resolver
+}
+
+// CommunityChat is a channel's chat room while it is offline
+type CommunityChat struct {
+	ChannelID       gql.ID
+	Enabled         bool
+	Open            bool
+	Room            string
+	SlowModeSeconds int32
+	Chatters        int32
+}
+
+// CommunityChatAnalytics totals a channel's community chat over a range
+type CommunityChatAnalytics struct {
+	ChannelID       gql.ID
+	PeakChatters    int32
+	AverageChatters int32
+	ChatMessages    int32
+}
+
+// CommunityChatInput changes a channel's community chat settings
+type CommunityChatInput struct {
+	Enabled         bool
+	SlowModeSeconds int32
+}
+
+// ChannelGoal is a target a channel counts toward
+type ChannelGoal struct {
+	ID          gql.ID
+	ChannelID   gql.ID
+	Kind        string
+	Description string
+	Current     int32
+	Target      int32
+	Progress    float64
+	Status      string
+	Room        string
+	CreatedAt   gql.Time
+	EndedAt     *gql.Time
+}
+
+// ChannelGoalInput starts a channel goal
+type ChannelGoalInput struct {
+	Kind        string
+	Target      int32
+	Description *string
+}
+
+// Permission is an operation gated by an organization role
+type Permission struct {
+	Operation   string
+	Resource    string
+	MinimumRole string
+	Roles       []string
+	Allowed     *bool
+}
+
+// MilestoneSettings are the thresholds a channel celebrates
+type MilestoneSettings struct {
+	ChannelID          gql.ID
+	Enabled            bool
+	ViewerThresholds   []int32
+	FollowerThresholds []int32
+	DurationMinutes    []int32
+	UpdatedAt          *gql.Time
+}
+
+// MilestoneSettingsInput replaces a channel's milestone thresholds
+type MilestoneSettingsInput struct {
+	Enabled            bool
+	ViewerThresholds   []int32
+	FollowerThresholds []int32
+	DurationMinutes    []int32
+}
+
+// StreamMilestone is a threshold a stream reached
+type StreamMilestone struct {
+	ID        gql.ID
+	StreamID  gql.ID
+	Kind      string
+	Threshold int32
+	Value     int32
+	Message   string
+	ReachedAt gql.Time
+}
+
+// ChannelSubscription is a viewer's paid subscription to a channel
+type ChannelSubscription struct {
+	ID        gql.ID
+	ChannelID gql.ID
+	Tier      string
+	AutoRenew bool
+	Months    int32
+	IsActive  bool
+	StartedAt gql.Time
+	ExpiresAt gql.Time
+	Badge     *Badge
+
+	// Resolved through Subscriber and GiftedBy
+	subscriberID string
+	giftedByID   string
+	resolver     *Resolver
+}
+
+// Cheer is bits a viewer cheered in a stream
+type Cheer struct {
+	ID        gql.ID
+	StreamID  gql.ID
+	Bits      int32
+	Message   string
+	Animation string
+	CreatedAt gql.Time
+
+	// Resolved through User
+	userID   string
+	resolver *Resolver
+}
+
+// CheerLeaderboardEntry is a viewer's place among a stream's top cheerers
+type CheerLeaderboardEntry struct {
+	Rank int32
+	User *User
+	Bits int32
+}
+
+// ChannelStorage is an organization channel's own bucket
+type ChannelStorage struct {
+	ChannelID     gql.ID
+	Bucket        string
+	Region        string
+	Prefix        string
+	AccessKeyID   string
+	Status        string
+	LastError     *string
+	LastCheckedAt gql.Time
+	UpdatedAt     gql.Time
+}
+
+// ChannelStorageInput configures a channel's own bucket
+type ChannelStorageInput struct {
+	Bucket          string
+	Region          string
+	Prefix          *string
+	AccessKeyID     string
+	SecretAccessKey string
+}
+
+// ScimDirectory is an organization's SCIM directory
+type ScimDirectory struct {
+	OrganizationID gql.ID
+	Endpoint       string
+	GroupRoles     []*ScimGroupRole
+	CreatedAt      gql.Time
+	UpdatedAt      gql.Time
+}
+
+// ScimGroupRole is the organization role a directory group grants
+type ScimGroupRole struct {
+	Group string
+	Role  string
+}
+
+// ScimDirectoryPayload is a directory with its new bearer token
+type ScimDirectoryPayload struct {
+	Directory *ScimDirectory
+	Token     string
+}
+
+// DeadLetter is an event a consumer gave up on
+type DeadLetter struct {
+	ID           gql.ID
+	Consumer     string
+	Error        string
+	Attempts     int32
+	FailedAt     gql.Time
+	EventID      gql.ID
+	EventType    string
+	EventVersion string
+	UserID       *gql.ID
+	StreamID     *gql.ID
+	TenantID     *gql.ID
+	Data         *JSON
+	PublishedAt  gql.Time
+}
+
+// ScimGroupRoleInput maps a directory group to an organization role
+type ScimGroupRoleInput struct {
+	Group string
+	Role  string
+}
+
+// Tenant is a white-label deployment
+type Tenant struct {
+	ID       gql.ID
+	Name     string
+	Domain   *string
+	Settings []TenantSetting
+}
+
+// TenantSetting is a setting a tenant overrides
+type TenantSetting struct {
+	Key   string
+	Value string
+}
+
+// VodPremiere is a VOD's scheduled first airing
+type VodPremiere struct {
+	ScheduledAt gql.Time
+	Status      string
+	Room        string
+	StartedAt   *gql.Time
+	EndedAt     *gql.Time
+}
+
+// WatchParty is a group watching a VOD together
+type WatchParty struct {
+	ID        gql.ID
+	Room      string
+	Active    bool
+	CreatedAt gql.Time
+	EndedAt   *gql.Time
+
+	vodID    string
+	hostID   string
+	resolver *Resolver
+}
+
+// WatchPartyMessage is a chat message sent in a watch party
+type WatchPartyMessage struct {
+	ID        gql.ID
+	Message   string
+	CreatedAt gql.Time
+
+	userID   string
+	resolver *Resolver
+}
+
+// Caption is a closed caption of a VOD; offsets are in seconds from the
+// start of the VOD
+type Caption struct {
+	StartOffset float64
+	EndOffset   float64
+	Text        string
+}
+
+// VodSegment is a range of a broadcast included in a VOD; offsets are in seconds
+type VodSegment struct {
+	StartOffset float64
+	EndOffset   float64
+	Reasons     []string
+}
+
+// Organization owns channels managed together by its members
+type Organization struct {
+	ID        gql.ID
+	Slug      string
+	Name      string
+	CreatedAt gql.Time
+
+	resolver *Resolver
+}
+
+// OrganizationMember is a user's role in an organization
+type OrganizationMember struct {
+	User     *User
+	Role     string
+	JoinedAt gql.Time
+}
+
+// OrganizationInvitation asks a user to join an organization
+type OrganizationInvitation struct {
+	ID        gql.ID
+	Role      string
+	Status    string
+	CreatedAt gql.Time
+	ExpiresAt gql.Time
+
+	organizationID string
+	inviteeID      string
+	invitedBy      string
+	resolver       *Resolver
+}
+
+// OrganizationAnalytics aggregates an organization's channels
+type OrganizationAnalytics struct {
+	ChannelCount      int32
+	LiveChannelCount  int32
+	ConcurrentViewers int32
+	TotalFollowers    int32
+	UniqueFollowers   int32
+}
+
+// StreamConnection is a page of streams
+type StreamConnection struct {
+	Edges      []*StreamEdge
+	PageInfo   *PageInfo
+	TotalCount int32
+}
+
+// StreamEdge is a stream and its pagination cursor
+type StreamEdge struct {
+	Node   *Stream
+	Cursor string
+}
+
+// UserConnection is a page of users
+type UserConnection struct {
+	Edges      []*UserEdge
+	PageInfo   *PageInfo
+	TotalCount int32
+}
+
+// UserEdge is a user in a followers or following list
+type UserEdge struct {
+	Node       *User
+	Cursor     string
+	FollowedAt gql.Time
+}
+
+// ViewerConnection is a page of the signed-in viewers in a stream room
+type ViewerConnection struct {
+	Edges      []*ViewerEdge
+	PageInfo   *PageInfo
+	TotalCount int32
+	GuestCount int32
+}
+
+// ViewerEdge is a viewer and their pagination cursor
+type ViewerEdge struct {
+	Node   *User
+	Cursor string
+}
+
+// PageInfo describes the position of a page within a connection
+type PageInfo struct {
+	HasNextPage     bool
+	HasPreviousPage bool
+	StartCursor     *string
+	EndCursor       *string
+}
+
+// Input types
+
+// StreamFilter narrows the streams query
+type StreamFilter struct {
+	Status     *string
+	Category   *string
+	Language   *string
+	Tags       *[]string
+	AnyTags    *[]string
+	IsPartner  *bool
+	MinViewers *int32
+	MaxViewers *int32
+}
+
+// RegisterInput is the input to register
+type RegisterInput struct {
+	Username    string
+	Email       string
+	Password    string
+	DisplayName *string
+}
+
+// LoginInput is the input to login
+type LoginInput struct {
+	Login    string
+	Password string
+}
+
+// GoLiveInput is the input to goLive
+type GoLiveInput struct {
+	StreamID    *gql.ID
+	Title       *string
+	Description *string
+	CategoryID  *gql.ID
+	Tags        *[]string
+	Language    *string
+	IsMature    *bool
+}
+
+// CreateCampaignInput is the input to createCampaign
+type CreateCampaignInput struct {
+	Name        string
+	Description *string
+	Categories  []string
+	StartsAt    gql.Time
+	EndsAt      gql.Time
+	WebhookURL  *string
+	Rewards     []CampaignRewardInput
+}
+
+// CampaignRewardInput describes one reward of a new campaign
+type CampaignRewardInput struct {
+	Name            string
+	Description     *string
+	ImageURL        *string
+	RequiredMinutes int32
+}
+
+// CreateOrganizationInput is the input to createOrganization
+type CreateOrganizationInput struct {
+	Slug string
+	Name string
+}
+
+// UpdateStreamInfoInput is the input to updateStreamInfo
+type UpdateStreamInfoInput struct {
+	Title       *string
+	Description *string
+	CategoryID  *gql.ID
+	Tags        *[]string
+	Language    *string
+	IsMature    *bool
+
+	BrandedContent *BrandedContentInput
+}
+
+// BrandedContentInput toggles a stream's branded content flag
+type BrandedContentInput struct {
+	Enabled        bool
+	SponsorName    *string
+	DisclosureType *string
+}
+
+// NotificationInput is the input to sendNotification
+type NotificationInput struct {
+	UserID     gql.ID
+	Type       string
+	Title      string
+	Message    string
+	Data       *JSON
+	FromUserID *gql.ID
+	StreamID   *gql.ID
+}
+
+// GeoRestrictionInput is the input to setGeoRestriction
+type GeoRestrictionInput struct {
+	ContentID gql.ID
+	Kind      string
+	Countries []string
+	AllowList bool
+	Reason    string
+}
+
+// JSON is the JSON scalar: an arbitrary JSON value
+type JSON struct {
+	Value interface{}
+}
+
+// ImplementsGraphQLType maps JSON to the JSON scalar
+func (JSON) ImplementsGraphQLType(name string) bool {
+	return name == "JSON"
+}
+
+// UnmarshalGraphQL accepts any input value
+func (j *JSON) UnmarshalGraphQL(input interface{}) error {
+	switch input.(type) {
+	case map[string]interface{}, []interface{}, string, bool, float64, int32, int64, nil:
+		j.Value = input
+		return nil
+	default:
+		return fmt.Errorf("unsupported JSON value %T", input)
+	}
+}
+
+// MarshalJSON writes the wrapped value
+func (j JSON) MarshalJSON() ([]byte, error) {
+	return json.Marshal(j.Value)
+}
@@ -0,0 +1,132 @@
+package graphql
+
+import (
+	"context"
+	"errors"
+
+	gql "github.com/graph-gophers/graphql-go"
+	"github.com/tinle0301/streaming-platform-api/internal/notifications"
+	"github.com/tinle0301/streaming-platform-api/internal/users"
+)
+
+// SetNotifications enables the viewer's notification queries and mutations
+func (r *Resolver) SetNotifications(service *notifications.Service) {
+	r.notifications = service
+}
+
+// Notifications resolves Query.notifications
+func (r *Resolver) Notifications(ctx context.Context, args struct {
+	Limit      int32
+	UnreadOnly bool
+}) ([]*Notification, error) {
+	if r.notifications == nil {
+		return nil, errNotImplemented("notifications")
+	}
+	claims, ok := users.ClaimsFromContext(ctx)
+	if !ok {
+		return nil, newError(CodeUnauthenticated, "sign in to view notifications")
+	}
+	if args.Limit < 1 || args.Limit > notifications.MaxLimit {
+		return nil, newError(CodeBadUserInput, "limit must be between 1 and 100")
+	}
+
+	list, err := r.notifications.List(ctx, claims.UserID(), int(args.Limit), args.UnreadOnly)
+	if err != nil {
+		return nil, internalError("notifications", err)
+	}
+	result := make([]*Notification, 0, len(list))
+	for _, n := range list {
+		result = append(result, r.notificationFromStore(n))
+	}
+	return result, nil
+}
+
+// MarkNotificationRead resolves Mutation.markNotificationRead
+func (r *Resolver) MarkNotificationRead(ctx context.Context, args struct{ ID gql.ID }) (*Notification, error) {
+	if r.notifications == nil {
+		return nil, errNotImplemented("markNotificationRead")
+	}
+	claims, ok := users.ClaimsFromContext(ctx)
+	if !ok {
+		return nil, newError(CodeUnauthenticated, "sign in to manage notifications")
+	}
+
+	n, err := r.notifications.MarkRead(ctx, claims.UserID(), string(args.ID))
+	if errors.Is(err, notifications.ErrNotFound) {
+		return nil, newError(CodeNotFound, err.Error())
+	}
+	if err != nil {
+		return nil, internalError("markNotificationRead", err)
+	}
+	return r.notificationFromStore(n), nil
+}
+
+// MarkAllNotificationsRead resolves Mutation.markAllNotificationsRead
+func (r *Resolver) MarkAllNotificationsRead(ctx context.Context) (bool, error) {
+	if r.notifications == nil {
+		return false, errNotImplemented("markAllNotificationsRead")
+	}
+	claims, ok := users.ClaimsFromContext(ctx)
+	if !ok {
+		return false, newError(CodeUnauthenticated, "sign in to manage notifications")
+	}
+
+	if _, err := r.notifications.MarkAllRead(ctx, claims.UserID()); err != nil {
+		return false, internalError("markAllNotificationsRead", err)
+	}
+	return true, nil
+}
+
+// FromUser resolves Notification.fromUser
+func (n *Notification) FromUser(ctx context.Context) (*User, error) {
+	if n.fromUserID == "" || n.resolver.users == nil {
+		return nil, nil
+	}
+	account, err := n.resolver.loadAccount(ctx, n.fromUserID)
+	if err != nil {
+		return nil, internalError("fromUser", err)
+	}
+	if account == nil {
+		return nil, nil
+	}
+	claims, _ := users.ClaimsFromContext(ctx)
+	return userFromAccount(account, n.resolver.users, claims != nil && claims.UserID() == account.ID), nil
+}
+
+// Stream resolves Notification.stream
+func (n *Notification) Stream(ctx context.Context) (*Stream, error) {
+	if n.streamID == "" {
+		return nil, nil
+	}
+	stream, err := n.resolver.loadStream(ctx, n.streamID)
+	if err != nil {
+		return nil, internalError("stream", err)
+	}
+	if stream == nil {
+		return nil, nil
+	}
+	return streamFromStore(stream), nil
+}
+
+// notificationFromStore converts a stored notification
+func (r *Resolver) notificationFromStore(n *notifications.Notification) *Notification {
+	notification := &Notification{
+		ID:         gql.ID(n.ID),
+		Type:       n.Type,
+		Title:      n.Title,
+		Message:    n.Message,
+		Count:      int32(n.DigestCount),
+		CreatedAt:  gql.Time{Time: n.CreatedAt},
+		Read:       n.Read(),
+		fromUserID: n.FromUserID,
+		streamID:   n.StreamID,
+		resolver:   r,
+	}
+	if len(n.Data) > 0 {
+		notification.Data = &JSON{Value: n.Data}
+	}
+	if n.ReadAt != nil {
+		notification.ReadAt = &gql.Time{Time: *n.ReadAt}
+	}
+	return notification
+}
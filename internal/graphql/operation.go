@@ -0,0 +1,197 @@
+package graphql
+
+import "strings"
+
+// Operation types
+const (
+	operationQuery        = "query"
+	operationMutation     = "mutation"
+	operationSubscription = "subscription"
+)
+
+// operation is an executable definition in a GraphQL document
+type operation struct {
+	kind, name string
+
+	// fields are the response names' underlying root fields, with "..." for
+	// each fragment spread or inline fragment
+	fields []string
+}
+
+// operationType returns the type of the operation in document that
+// operationName selects, or of its only operation when operationName is
+// empty. It returns "" when no operation is selected, e.g. for an unknown
+// name or a document graphql-go would refuse to execute.
+func operationType(document, operationName string) string {
+	op, ok := selectOperation(document, operationName)
+	if !ok {
+		return ""
+	}
+	return op.kind
+}
+
+// selectOperation returns the operation in document that operationName
+// selects, or its only operation when operationName is empty
+func selectOperation(document, operationName string) (operation, bool) {
+	operations, ok := parseOperations(document)
+	if !ok {
+		return operation{}, false
+	}
+	if operationName == "" {
+		if len(operations) != 1 {
+			return operation{}, false
+		}
+		return operations[0], true
+	}
+	for _, op := range operations {
+		if op.name == operationName {
+			return op, true
+		}
+	}
+	return operation{}, false
+}
+
+// parseOperations lists the operations in document. It reads only the top
+// level of the document and of each operation's selection set: definitions,
+// their names, and root fields, skipping comments, strings, and everything
+// inside nested selection sets and argument lists. It reports false for an
+// unbalanced document.
+func parseOperations(document string) ([]operation, bool) {
+	var (
+		operations []operation
+		pending    string // keyword of the definition whose selection set comes next
+		named      bool   // whether pending's name has been read
+		current    = -1   // operation whose root selection set is open
+		alias      bool   // whether the next root name is the field behind an alias
+		directive  bool   // whether the next root name is a directive's
+		spread     bool   // whether the next root name is a fragment's or type condition's
+		braces     int
+		parens     int
+	)
+
+	for i := 0; i < len(document); {
+		c := document[i]
+		root := current >= 0 && braces == 1 && parens == 0
+		switch {
+		case c == '#':
+			for i < len(document) && document[i] != '\n' && document[i] != '\r' {
+				i++
+			}
+			continue
+		case c == '"':
+			i = skipString(document, i)
+			continue
+		case c == '(':
+			parens++
+		case c == ')':
+			parens--
+		case c == '{':
+			if braces == 0 && parens == 0 {
+				switch pending {
+				case "":
+					// Query shorthand: an anonymous selection set
+					operations = append(operations, operation{kind: operationQuery})
+					current = len(operations) - 1
+				case operationQuery, operationMutation, operationSubscription:
+					current = len(operations) - 1
+				}
+				pending, named = "", false
+			}
+			braces++
+			spread = false
+		case c == '}':
+			braces--
+			if braces == 0 {
+				current = -1
+			}
+		case root && c == ':':
+			alias = true
+		case root && c == '@':
+			directive = true
+		case root && strings.HasPrefix(document[i:], "..."):
+			operations[current].fields = append(operations[current].fields, "...")
+			spread = true
+			i += 3
+			continue
+		case isNameStart(c):
+			start := i
+			for i < len(document) && isNameContinue(document[i]) {
+				i++
+			}
+			name := document[start:i]
+			if root {
+				fields := operations[current].fields
+				switch {
+				case directive:
+					directive = false
+				case spread:
+					spread = name == "on"
+				case alias && len(fields) > 0:
+					fields[len(fields)-1] = name
+					alias = false
+				default:
+					operations[current].fields = append(fields, name)
+				}
+				continue
+			}
+			if braces != 0 || parens != 0 {
+				continue
+			}
+			switch {
+			case pending == "":
+				pending = name
+				if name == operationQuery || name == operationMutation || name == operationSubscription {
+					operations = append(operations, operation{kind: name})
+				}
+			case !named:
+				named = true
+				if pending != "fragment" && len(operations) > 0 {
+					operations[len(operations)-1].name = name
+				}
+			}
+			continue
+		}
+		i++
+	}
+
+	if braces != 0 || parens != 0 {
+		return nil, false
+	}
+	return operations, true
+}
+
+// skipString returns the index after the string or block string starting
+// at document[i]
+func skipString(document string, i int) int {
+	if strings.HasPrefix(document[i:], `"""`) {
+		for j := i + 3; j < len(document); j++ {
+			if document[j] == '\\' && strings.HasPrefix(document[j:], `\"""`) {
+				j += 3
+				continue
+			}
+			if strings.HasPrefix(document[j:], `"""`) {
+				return j + 3
+			}
+		}
+		return len(document)
+	}
+	for j := i + 1; j < len(document); j++ {
+		switch document[j] {
+		case '\\':
+			j++
+		case '"', '\n', '\r':
+			return j + 1
+		}
+	}
+	return len(document)
+}
+
+// isNameStart reports whether c can start a GraphQL name
+func isNameStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// isNameContinue reports whether c can continue a GraphQL name
+func isNameContinue(c byte) bool {
+	return isNameStart(c) || (c >= '0' && c <= '9')
+}
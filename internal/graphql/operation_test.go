@@ -0,0 +1,107 @@
+package graphql
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/tinle0301/streaming-platform-api/internal/persistedqueries"
+)
+
+func TestOperationType(t *testing.T) {
+	tests := []struct {
+		name          string
+		document      string
+		operationName string
+		want          string
+	}{
+		{"shorthand", "{ __typename }", "", operationQuery},
+		{"anonymous mutation", "mutation { follow(id: 1) { id } }", "", operationMutation},
+		{"named query", "query Streams($n: Int = 10) { streams(first: $n) { id } }", "", operationQuery},
+		{"mutation keyword in a string", `query { search(q: "mutation") { id } }`, "", operationQuery},
+		{"mutation keyword in a comment", "# mutation\n{ __typename }", "", operationQuery},
+		{"mutation keyword as a field", "{ mutation: __typename }", "", operationQuery},
+		{"block string", `mutation { post(body: """a "quoted" } brace""") { id } }`, "", operationMutation},
+		{"select by name", "query A { a } mutation B { b }", "B", operationMutation},
+		{"select query by name", "query A { a } mutation B { b }", "A", operationQuery},
+		{"ambiguous", "query A { a } mutation B { b }", "", ""},
+		{"unknown name", "mutation B { b }", "A", ""},
+		{"fragment", "fragment F on User { id } mutation M { m { ...F } }", "", operationMutation},
+		{"directive object argument", "mutation M @cost(limit: {max: 1}) { m }", "", operationMutation},
+		{"subscription", "subscription { chat { id } }", "", operationSubscription},
+		{"unbalanced", "mutation { m", "", ""},
+	}
+	for _, tt := range tests {
+		if got := operationType(tt.document, tt.operationName); got != tt.want {
+			t.Errorf("%s: operationType(%q, %q) = %q, want %q", tt.name, tt.document, tt.operationName, got, tt.want)
+		}
+	}
+}
+
+func TestOperationRootFields(t *testing.T) {
+	tests := []struct {
+		document      string
+		operationName string
+		want          []string
+	}{
+		{"{ me { id } streams(first: 10) { id } }", "", []string{"me", "streams"}},
+		{"{ mine: pendingAgreements { kind } }", "", []string{"pendingAgreements"}},
+		{"mutation M($v: String!) { acceptAgreement(kind: TERMS_OF_SERVICE, version: $v) }", "", []string{"acceptAgreement"}},
+		{"{ a @include(if: true) b { c { d } } }", "", []string{"a", "b"}},
+		{"query { ...F } fragment F on Query { me { id } }", "", []string{"..."}},
+		{"query A { a } query B { b }", "B", []string{"b"}},
+	}
+	for _, tt := range tests {
+		op, ok := selectOperation(tt.document, tt.operationName)
+		if !ok || strings.Join(op.fields, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("selectOperation(%q, %q) fields = %v, %v; want %v", tt.document, tt.operationName, op.fields, ok, tt.want)
+		}
+	}
+}
+
+func TestReadOnlyRefusesPersistedMutations(t *testing.T) {
+	mutation := "mutation { follow(channelId: \"1\") }"
+	query := "{ __typename }"
+	cache := memoryQueries{}
+	registry, err := persistedqueries.New(cache, persistedqueries.DefaultOptions())
+	if err != nil {
+		t.Fatalf("New() = %v", err)
+	}
+	cache[persistedqueries.Hash(mutation)] = mutation
+	cache[persistedqueries.Hash(query)] = query
+
+	resolver := NewResolver(emptyStreams{})
+	resolver.SetPersistedQueries(registry)
+	resolver.SetReadOnly(true)
+	schema, err := NewSchema(resolver)
+	if err != nil {
+		t.Fatalf("failed to parse schema: %v", err)
+	}
+
+	post := func(body map[string]interface{}) *httptest.ResponseRecorder {
+		raw, _ := json.Marshal(body)
+		rec := httptest.NewRecorder()
+		Handler(schema, resolver)(rec, httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(string(raw))))
+		return rec
+	}
+	hashOnly := func(document string) map[string]interface{} {
+		return map[string]interface{}{"extensions": map[string]interface{}{
+			"persistedQuery": map[string]interface{}{"version": 1, "sha256Hash": persistedqueries.Hash(document)},
+		}}
+	}
+
+	if rec := post(hashOnly(mutation)); rec.Code != http.StatusServiceUnavailable || !strings.Contains(rec.Body.String(), CodeReadOnly) {
+		t.Errorf("hash-only mutation = %d %s, want 503 %s", rec.Code, rec.Body, CodeReadOnly)
+	}
+	if rec := post(map[string]interface{}{"query": mutation}); rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("plain mutation = %d, want 503", rec.Code)
+	}
+	if rec := post(hashOnly(query)); rec.Code != http.StatusOK {
+		t.Errorf("hash-only query = %d %s, want 200", rec.Code, rec.Body)
+	}
+	if rec := post(map[string]interface{}{"query": `{ mutation: __typename }`}); rec.Code != http.StatusOK {
+		t.Errorf("query aliasing a field as mutation = %d %s, want 200", rec.Code, rec.Body)
+	}
+}
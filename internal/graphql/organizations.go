@@ -0,0 +1,434 @@
+package graphql
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	gql "github.com/graph-gophers/graphql-go"
+	"github.com/tinle0301/streaming-platform-api/internal/orgs"
+	"github.com/tinle0301/streaming-platform-api/internal/users"
+)
+
+// SetOrganizations enables organizations and lets organization managers
+// change the streams of organization channels
+func (r *Resolver) SetOrganizations(service *orgs.Service) {
+	r.orgs = service
+}
+
+// Organization resolves Query.organization
+func (r *Resolver) Organization(ctx context.Context, args struct{ Slug string }) (*Organization, error) {
+	if r.orgs == nil {
+		return nil, errNotImplemented("organization")
+	}
+
+	org, err := r.orgs.GetBySlug(ctx, args.Slug)
+	if errors.Is(err, orgs.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, internalError("organization", err)
+	}
+	return r.organizationFromStore(org), nil
+}
+
+// MyOrganizations resolves Query.myOrganizations
+func (r *Resolver) MyOrganizations(ctx context.Context) ([]*Organization, error) {
+	if r.orgs == nil {
+		return nil, errNotImplemented("myOrganizations")
+	}
+	claims, ok := users.ClaimsFromContext(ctx)
+	if !ok {
+		return nil, newError(CodeUnauthenticated, "sign in to see your organizations")
+	}
+
+	list, err := r.orgs.ListForUser(ctx, claims.UserID())
+	if err != nil {
+		return nil, internalError("myOrganizations", err)
+	}
+	result := make([]*Organization, 0, len(list))
+	for _, org := range list {
+		result = append(result, r.organizationFromStore(org))
+	}
+	return result, nil
+}
+
+// MyOrganizationInvitations resolves Query.myOrganizationInvitations
+func (r *Resolver) MyOrganizationInvitations(ctx context.Context) ([]*OrganizationInvitation, error) {
+	if r.orgs == nil {
+		return nil, errNotImplemented("myOrganizationInvitations")
+	}
+	claims, ok := users.ClaimsFromContext(ctx)
+	if !ok {
+		return nil, newError(CodeUnauthenticated, "sign in to see your invitations")
+	}
+
+	invitations, err := r.orgs.PendingInvitationsFor(ctx, claims.UserID())
+	if err != nil {
+		return nil, internalError("myOrganizationInvitations", err)
+	}
+	return r.invitationsFromStore(invitations), nil
+}
+
+// CreateOrganization resolves Mutation.createOrganization
+func (r *Resolver) CreateOrganization(ctx context.Context, args struct{ Input CreateOrganizationInput }) (*Organization, error) {
+	claims, err := r.orgClaims(ctx, "createOrganization")
+	if err != nil {
+		return nil, err
+	}
+
+	org, err := r.orgs.Create(ctx, claims.UserID(), args.Input.Slug, args.Input.Name)
+	if err != nil {
+		return nil, orgError("createOrganization", err)
+	}
+	return r.organizationFromStore(org), nil
+}
+
+// InviteToOrganization resolves Mutation.inviteToOrganization
+func (r *Resolver) InviteToOrganization(ctx context.Context, args struct {
+	OrganizationID gql.ID
+	UserID         gql.ID
+	Role           string
+}) (*OrganizationInvitation, error) {
+	claims, err := r.orgClaims(ctx, "inviteToOrganization")
+	if err != nil {
+		return nil, err
+	}
+
+	invitation, err := r.orgs.Invite(ctx, claims.UserID(), string(args.OrganizationID), string(args.UserID), orgs.Role(args.Role))
+	if err != nil {
+		return nil, orgError("inviteToOrganization", err)
+	}
+	return r.invitationFromStore(invitation), nil
+}
+
+// RespondToOrganizationInvitation resolves Mutation.respondToOrganizationInvitation
+func (r *Resolver) RespondToOrganizationInvitation(ctx context.Context, args struct {
+	ID     gql.ID
+	Accept bool
+}) (*OrganizationInvitation, error) {
+	claims, err := r.orgClaims(ctx, "respondToOrganizationInvitation")
+	if err != nil {
+		return nil, err
+	}
+
+	invitation, err := r.orgs.Respond(ctx, claims.UserID(), string(args.ID), args.Accept)
+	if err != nil {
+		return nil, orgError("respondToOrganizationInvitation", err)
+	}
+	return r.invitationFromStore(invitation), nil
+}
+
+// RevokeOrganizationInvitation resolves Mutation.revokeOrganizationInvitation
+func (r *Resolver) RevokeOrganizationInvitation(ctx context.Context, args struct{ ID gql.ID }) (*OrganizationInvitation, error) {
+	claims, err := r.orgClaims(ctx, "revokeOrganizationInvitation")
+	if err != nil {
+		return nil, err
+	}
+
+	invitation, err := r.orgs.Revoke(ctx, claims.UserID(), string(args.ID))
+	if err != nil {
+		return nil, orgError("revokeOrganizationInvitation", err)
+	}
+	return r.invitationFromStore(invitation), nil
+}
+
+// SetOrganizationMemberRole resolves Mutation.setOrganizationMemberRole
+func (r *Resolver) SetOrganizationMemberRole(ctx context.Context, args struct {
+	OrganizationID gql.ID
+	UserID         gql.ID
+	Role           string
+}) (*OrganizationMember, error) {
+	claims, err := r.orgClaims(ctx, "setOrganizationMemberRole")
+	if err != nil {
+		return nil, err
+	}
+
+	member, err := r.orgs.SetMemberRole(ctx, claims.UserID(), string(args.OrganizationID), string(args.UserID), orgs.Role(args.Role))
+	if err != nil {
+		return nil, orgError("setOrganizationMemberRole", err)
+	}
+	return r.memberFromStore(ctx, "setOrganizationMemberRole", member)
+}
+
+// RemoveOrganizationMember resolves Mutation.removeOrganizationMember
+func (r *Resolver) RemoveOrganizationMember(ctx context.Context, args struct {
+	OrganizationID gql.ID
+	UserID         gql.ID
+}) (bool, error) {
+	claims, err := r.orgClaims(ctx, "removeOrganizationMember")
+	if err != nil {
+		return false, err
+	}
+
+	if err := r.orgs.RemoveMember(ctx, claims.UserID(), string(args.OrganizationID), string(args.UserID)); err != nil {
+		return false, orgError("removeOrganizationMember", err)
+	}
+	return true, nil
+}
+
+// ViewerRole resolves Organization.viewerRole
+func (o *Organization) ViewerRole(ctx context.Context) (*string, error) {
+	claims, ok := users.ClaimsFromContext(ctx)
+	if !ok {
+		return nil, nil
+	}
+	role, ok, err := o.resolver.orgs.Role(ctx, string(o.ID), claims.UserID())
+	if err != nil {
+		return nil, internalError("viewerRole", err)
+	}
+	if !ok {
+		return nil, nil
+	}
+	name := string(role)
+	return &name, nil
+}
+
+// Channels resolves Organization.channels
+func (o *Organization) Channels(ctx context.Context) ([]*User, error) {
+	channelIDs, err := o.resolver.orgs.Channels(ctx, string(o.ID))
+	if err != nil {
+		return nil, internalError("channels", err)
+	}
+	return o.resolver.usersByID(ctx, "channels", channelIDs)
+}
+
+// Members resolves Organization.members
+func (o *Organization) Members(ctx context.Context) ([]*OrganizationMember, error) {
+	members, err := o.members(ctx, "members")
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*OrganizationMember, 0, len(members))
+	for _, member := range members {
+		converted, err := o.resolver.memberFromStore(ctx, "members", member)
+		if err != nil {
+			return nil, err
+		}
+		if converted != nil {
+			result = append(result, converted)
+		}
+	}
+	return result, nil
+}
+
+// Moderators resolves Organization.moderators
+func (o *Organization) Moderators(ctx context.Context) ([]*User, error) {
+	members, err := o.members(ctx, "moderators")
+	if err != nil {
+		return nil, err
+	}
+
+	var moderatorIDs []string
+	for _, member := range members {
+		if member.Role != orgs.RoleStreamer {
+			moderatorIDs = append(moderatorIDs, member.UserID)
+		}
+	}
+	return o.resolver.usersByID(ctx, "moderators", moderatorIDs)
+}
+
+// PendingInvitations resolves Organization.pendingInvitations
+func (o *Organization) PendingInvitations(ctx context.Context) ([]*OrganizationInvitation, error) {
+	claims, ok := users.ClaimsFromContext(ctx)
+	if !ok {
+		return nil, newError(CodeUnauthenticated, "sign in to see invitations")
+	}
+
+	invitations, err := o.resolver.orgs.PendingInvitations(ctx, claims.UserID(), string(o.ID))
+	if err != nil {
+		return nil, orgError("pendingInvitations", err)
+	}
+	return o.resolver.invitationsFromStore(invitations), nil
+}
+
+// Analytics resolves Organization.analytics
+func (o *Organization) Analytics(ctx context.Context) (*OrganizationAnalytics, error) {
+	claims, ok := users.ClaimsFromContext(ctx)
+	if !ok {
+		return nil, newError(CodeUnauthenticated, "sign in to see organization analytics")
+	}
+
+	analytics, err := o.resolver.orgs.Analytics(ctx, claims.UserID(), string(o.ID))
+	if err != nil {
+		return nil, orgError("analytics", err)
+	}
+	return &OrganizationAnalytics{
+		ChannelCount:      int32(analytics.Channels),
+		LiveChannelCount:  int32(analytics.LiveChannels),
+		ConcurrentViewers: int32(analytics.ConcurrentViewers),
+		TotalFollowers:    int32(analytics.TotalFollowers),
+		UniqueFollowers:   int32(analytics.UniqueFollowers),
+	}, nil
+}
+
+// members loads the organization's members for the authenticated viewer
+func (o *Organization) members(ctx context.Context, field string) ([]*orgs.Member, error) {
+	claims, ok := users.ClaimsFromContext(ctx)
+	if !ok {
+		return nil, newError(CodeUnauthenticated, "sign in to see organization members")
+	}
+
+	members, err := o.resolver.orgs.Members(ctx, claims.UserID(), string(o.ID))
+	if err != nil {
+		return nil, orgError(field, err)
+	}
+	return members, nil
+}
+
+// Organization resolves OrganizationInvitation.organization
+func (i *OrganizationInvitation) Organization(ctx context.Context) (*Organization, error) {
+	org, err := i.resolver.orgs.Get(ctx, i.organizationID)
+	if err != nil {
+		return nil, internalError("organization", err)
+	}
+	return i.resolver.organizationFromStore(org), nil
+}
+
+// Invitee resolves OrganizationInvitation.invitee
+func (i *OrganizationInvitation) Invitee(ctx context.Context) (*User, error) {
+	return i.resolver.userByID(ctx, "invitee", i.inviteeID)
+}
+
+// InvitedBy resolves OrganizationInvitation.invitedBy
+func (i *OrganizationInvitation) InvitedBy(ctx context.Context) (*User, error) {
+	if i.invitedBy == "" {
+		return nil, nil
+	}
+	return i.resolver.userByID(ctx, "invitedBy", i.invitedBy)
+}
+
+// orgClaims checks that organizations are enabled and the request is
+// authenticated
+func (r *Resolver) orgClaims(ctx context.Context, field string) (*users.Claims, error) {
+	if r.orgs == nil {
+		return nil, errNotImplemented(field)
+	}
+	claims, ok := users.ClaimsFromContext(ctx)
+	if !ok {
+		return nil, newError(CodeUnauthenticated, "sign in to manage organizations")
+	}
+	return claims, nil
+}
+
+// managesChannel reports whether userID manages channelID through an
+// organization; lookup errors deny access
+func (r *Resolver) managesChannel(ctx context.Context, channelID, userID string) bool {
+	if r.orgs == nil {
+		return false
+	}
+	ok, err := r.orgs.CanManageChannel(ctx, channelID, userID)
+	if err != nil {
+		log.Printf("Error checking organization channel access: channel=%s, user=%s, err=%v", channelID, userID, err)
+		return false
+	}
+	return ok
+}
+
+// userByID loads a user for an organization field; deleted accounts resolve
+// to nil
+func (r *Resolver) userByID(ctx context.Context, field, id string) (*User, error) {
+	if r.users == nil {
+		return nil, errNotImplemented(field)
+	}
+	claims, _ := users.ClaimsFromContext(ctx)
+
+	account, err := r.loadAccount(ctx, id)
+	if err != nil {
+		return nil, internalError(field, err)
+	}
+	if account == nil {
+		return nil, nil
+	}
+	return userFromAccount(account, r.users, claims != nil && claims.UserID() == account.ID), nil
+}
+
+// usersByID loads users in order, skipping deleted accounts
+func (r *Resolver) usersByID(ctx context.Context, field string, ids []string) ([]*User, error) {
+	if r.users == nil {
+		return nil, errNotImplemented(field)
+	}
+	claims, _ := users.ClaimsFromContext(ctx)
+
+	accounts, err := r.loadAccounts(ctx, ids)
+	if err != nil {
+		return nil, internalError(field, err)
+	}
+	result := make([]*User, 0, len(accounts))
+	for _, account := range accounts {
+		if account != nil {
+			result = append(result, userFromAccount(account, r.users, claims != nil && claims.UserID() == account.ID))
+		}
+	}
+	return result, nil
+}
+
+// organizationFromStore converts an organization to its GraphQL model
+func (r *Resolver) organizationFromStore(org *orgs.Organization) *Organization {
+	return &Organization{
+		ID:        gql.ID(org.ID),
+		Slug:      org.Slug,
+		Name:      org.Name,
+		CreatedAt: gql.Time{Time: org.CreatedAt},
+		resolver:  r,
+	}
+}
+
+// memberFromStore converts a membership to its GraphQL model; it is nil if
+// the member's account was deleted
+func (r *Resolver) memberFromStore(ctx context.Context, field string, member *orgs.Member) (*OrganizationMember, error) {
+	user, err := r.userByID(ctx, field, member.UserID)
+	if err != nil || user == nil {
+		return nil, err
+	}
+	return &OrganizationMember{
+		User:     user,
+		Role:     string(member.Role),
+		JoinedAt: gql.Time{Time: member.JoinedAt},
+	}, nil
+}
+
+// invitationFromStore converts an invitation to its GraphQL model
+func (r *Resolver) invitationFromStore(invitation *orgs.Invitation) *OrganizationInvitation {
+	return &OrganizationInvitation{
+		ID:             gql.ID(invitation.ID),
+		Role:           string(invitation.Role),
+		Status:         invitation.Status,
+		CreatedAt:      gql.Time{Time: invitation.CreatedAt},
+		ExpiresAt:      gql.Time{Time: invitation.ExpiresAt},
+		organizationID: invitation.OrganizationID,
+		inviteeID:      invitation.InviteeID,
+		invitedBy:      invitation.InvitedBy,
+		resolver:       r,
+	}
+}
+
+// invitationsFromStore converts a list of invitations
+func (r *Resolver) invitationsFromStore(invitations []*orgs.Invitation) []*OrganizationInvitation {
+	result := make([]*OrganizationInvitation, 0, len(invitations))
+	for _, invitation := range invitations {
+		result = append(result, r.invitationFromStore(invitation))
+	}
+	return result
+}
+
+// orgError maps organization service errors to GraphQL errors
+func orgError(field string, err error) error {
+	switch {
+	case errors.Is(err, orgs.ErrNotFound), errors.Is(err, orgs.ErrInvitationNotFound), errors.Is(err, orgs.ErrUserNotFound):
+		return newError(CodeNotFound, err.Error())
+	case errors.Is(err, orgs.ErrNotMember):
+		return newError(CodeForbidden, "you are not a member of this organization")
+	case errors.Is(err, orgs.ErrForbidden):
+		return newError(CodeForbidden, err.Error())
+	case errors.Is(err, orgs.ErrSlugTaken), errors.Is(err, orgs.ErrAlreadyMember),
+		errors.Is(err, orgs.ErrInvitationPending), errors.Is(err, orgs.ErrInvitationClosed),
+		errors.Is(err, orgs.ErrChannelTaken), errors.Is(err, orgs.ErrOwnerRole),
+		errors.Is(err, orgs.ErrInvalidRole), errors.Is(err, orgs.ErrInvalidSlug),
+		errors.Is(err, orgs.ErrInvalidName):
+		return newError(CodeBadUserInput, err.Error())
+	default:
+		return internalError(field, err)
+	}
+}
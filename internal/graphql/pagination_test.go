@@ -0,0 +1,88 @@
+package graphql
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/tinle0301/streaming-platform-api/internal/store"
+	"pgregory.net/rapid"
+)
+
+// pagedStreams lists streams from memory honouring the filter's limit and
+// offset, as the Postgres repository does
+type pagedStreams struct {
+	emptyStreams
+	streams []*store.Stream
+}
+
+func (r pagedStreams) List(ctx context.Context, filter store.StreamFilter) ([]*store.Stream, int, error) {
+	start := filter.Offset
+	if start > len(r.streams) {
+		start = len(r.streams)
+	}
+	end := start + filter.Limit
+	if end > len(r.streams) {
+		end = len(r.streams)
+	}
+	return r.streams[start:end], len(r.streams), nil
+}
+
+// TestStreamsPagesEachStreamOnce pages through any number of streams with
+// any page size and checks every stream is returned exactly once, in
+// order, with cursors and page info that agree
+func TestStreamsPagesEachStreamOnce(t *testing.T) {
+	rapid.Check(t, func(t *rapid.T) {
+		n := rapid.IntRange(0, 250).Draw(t, "streams")
+		limit := rapid.IntRange(1, maxPageSize+20).Draw(t, "limit")
+
+		repo := pagedStreams{}
+		for i := 0; i < n; i++ {
+			repo.streams = append(repo.streams, &store.Stream{ID: fmt.Sprint(i), Status: store.StreamStatusLive})
+		}
+		resolver := NewResolver(repo)
+
+		seen := 0
+		for offset := 0; ; {
+			connection, err := resolver.Streams(context.Background(), struct {
+				Filter *StreamFilter
+				Limit  int32
+				Offset int32
+			}{Limit: int32(limit), Offset: int32(offset)})
+			if err != nil {
+				t.Fatalf("Streams(offset %d) = %v", offset, err)
+			}
+			if int(connection.TotalCount) != n {
+				t.Fatalf("TotalCount = %d, want %d", connection.TotalCount, n)
+			}
+			if len(connection.Edges) > maxPageSize {
+				t.Fatalf("page has %d edges, more than %d", len(connection.Edges), maxPageSize)
+			}
+			if connection.PageInfo.HasPreviousPage != (offset > 0) {
+				t.Fatalf("HasPreviousPage = %v at offset %d", connection.PageInfo.HasPreviousPage, offset)
+			}
+
+			for i, edge := range connection.Edges {
+				if string(edge.Node.ID) != fmt.Sprint(seen) {
+					t.Fatalf("got stream %s, want %d", edge.Node.ID, seen)
+				}
+				if edge.Cursor != offsetCursor(offset+i) {
+					t.Fatalf("stream %s has cursor %s, want offset %d", edge.Node.ID, edge.Cursor, offset+i)
+				}
+				seen++
+			}
+
+			if !connection.PageInfo.HasNextPage {
+				break
+			}
+			if len(connection.Edges) == 0 {
+				t.Fatalf("empty page at offset %d reports a next page", offset)
+			}
+			offset += len(connection.Edges)
+		}
+
+		if seen != n {
+			t.Fatalf("paged through %d streams, want %d", seen, n)
+		}
+	})
+}
@@ -0,0 +1,166 @@
+package graphql
+
+import (
+	"context"
+	"errors"
+
+	gql "github.com/graph-gophers/graphql-go"
+	"github.com/tinle0301/streaming-platform-api/internal/highlights"
+	"github.com/tinle0301/streaming-platform-api/internal/parties"
+	"github.com/tinle0301/streaming-platform-api/internal/users"
+	"github.com/tinle0301/streaming-platform-api/internal/websocket"
+)
+
+// SetParties enables watch parties
+func (r *Resolver) SetParties(service *parties.Service) {
+	r.parties = service
+}
+
+// WatchParty resolves Query.watchParty
+func (r *Resolver) WatchParty(ctx context.Context, args struct{ ID gql.ID }) (*WatchParty, error) {
+	if r.parties == nil {
+		return nil, errNotImplemented("watchParty")
+	}
+
+	party, err := r.parties.Get(ctx, string(args.ID))
+	if errors.Is(err, parties.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, internalError("watchParty", err)
+	}
+	return r.partyFromStore(party), nil
+}
+
+// CreateWatchParty resolves Mutation.createWatchParty
+func (r *Resolver) CreateWatchParty(ctx context.Context, args struct{ VodID gql.ID }) (*WatchParty, error) {
+	if r.parties == nil {
+		return nil, errNotImplemented("createWatchParty")
+	}
+	claims, ok := users.ClaimsFromContext(ctx)
+	if !ok {
+		return nil, newError(CodeUnauthenticated, "sign in to host a watch party")
+	}
+
+	party, err := r.parties.Create(ctx, claims.UserID(), string(args.VodID))
+	if err != nil {
+		return nil, partiesError("createWatchParty", err)
+	}
+	return r.partyFromStore(party), nil
+}
+
+// EndWatchParty resolves Mutation.endWatchParty
+func (r *Resolver) EndWatchParty(ctx context.Context, args struct{ ID gql.ID }) (*WatchParty, error) {
+	if r.parties == nil {
+		return nil, errNotImplemented("endWatchParty")
+	}
+	claims, ok := users.ClaimsFromContext(ctx)
+	if !ok {
+		return nil, newError(CodeUnauthenticated, "sign in to end a watch party")
+	}
+
+	party, err := r.parties.End(ctx, string(args.ID), claims.UserID())
+	if err != nil {
+		return nil, partiesError("endWatchParty", err)
+	}
+	return r.partyFromStore(party), nil
+}
+
+// Vod resolves WatchParty.vod; drafts are only visible to their streamer
+func (p *WatchParty) Vod(ctx context.Context) (*Vod, error) {
+	if p.resolver.highlights == nil {
+		return nil, nil
+	}
+	vod, err := p.resolver.highlights.GetVOD(ctx, p.vodID)
+	if errors.Is(err, highlights.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, internalError("vod", err)
+	}
+	if vod.Status == highlights.StatusDraft {
+		claims, ok := users.ClaimsFromContext(ctx)
+		if !ok || claims.UserID() != vod.StreamerID {
+			return nil, nil
+		}
+	}
+	return p.resolver.vodFromStore(vod), nil
+}
+
+// Host resolves WatchParty.host
+func (p *WatchParty) Host(ctx context.Context) (*User, error) {
+	return p.resolver.partyUser(ctx, "host", p.hostID)
+}
+
+// Messages resolves WatchParty.messages
+func (p *WatchParty) Messages(ctx context.Context, args struct{ Limit int32 }) ([]*WatchPartyMessage, error) {
+	if args.Limit < 1 || args.Limit > parties.MaxMessageLimit {
+		return nil, newError(CodeBadUserInput, "limit must be between 1 and 200")
+	}
+
+	messages, err := p.resolver.parties.Messages(ctx, string(p.ID), int(args.Limit))
+	if err != nil {
+		return nil, internalError("messages", err)
+	}
+	result := make([]*WatchPartyMessage, 0, len(messages))
+	for _, m := range messages {
+		result = append(result, &WatchPartyMessage{
+			ID:        gql.ID(m.ID),
+			Message:   m.Text,
+			CreatedAt: gql.Time{Time: m.CreatedAt},
+			userID:    m.UserID,
+			resolver:  p.resolver,
+		})
+	}
+	return result, nil
+}
+
+// User resolves WatchPartyMessage.user
+func (m *WatchPartyMessage) User(ctx context.Context) (*User, error) {
+	return m.resolver.partyUser(ctx, "user", m.userID)
+}
+
+// partyUser loads a party participant; it is null for deleted accounts
+func (r *Resolver) partyUser(ctx context.Context, field, userID string) (*User, error) {
+	if r.users == nil {
+		return nil, nil
+	}
+	account, err := r.loadAccount(ctx, userID)
+	if err != nil {
+		return nil, internalError(field, err)
+	}
+	if account == nil {
+		return nil, nil
+	}
+	claims, _ := users.ClaimsFromContext(ctx)
+	return userFromAccount(account, r.users, claims != nil && claims.UserID() == account.ID), nil
+}
+
+// partyFromStore converts a stored party
+func (r *Resolver) partyFromStore(p *parties.Party) *WatchParty {
+	party := &WatchParty{
+		ID:        gql.ID(p.ID),
+		Room:      websocket.WatchPartyRoom(p.ID),
+		Active:    p.Active(),
+		CreatedAt: gql.Time{Time: p.CreatedAt},
+		vodID:     p.VODID,
+		hostID:    p.HostID,
+		resolver:  r,
+	}
+	if p.EndedAt != nil {
+		party.EndedAt = &gql.Time{Time: *p.EndedAt}
+	}
+	return party
+}
+
+// partiesError maps watch party errors to GraphQL errors
+func partiesError(field string, err error) error {
+	switch {
+	case errors.Is(err, parties.ErrNotFound), errors.Is(err, parties.ErrVODNotFound):
+		return newError(CodeNotFound, err.Error())
+	case errors.Is(err, parties.ErrNotHost):
+		return newError(CodeForbidden, err.Error())
+	default:
+		return internalError(field, err)
+	}
+}
@@ -0,0 +1,52 @@
+package graphql
+
+import (
+	"context"
+
+	gql "github.com/graph-gophers/graphql-go"
+	"github.com/tinle0301/streaming-platform-api/internal/orgs"
+	"github.com/tinle0301/streaming-platform-api/internal/users"
+)
+
+// Permissions resolves Query.permissions from the schema's @requiresRole
+// annotations
+func (r *Resolver) Permissions(ctx context.Context, args struct{ OrganizationID *gql.ID }) ([]*Permission, error) {
+	if r.permissions == nil {
+		return nil, errNotImplemented("permissions")
+	}
+
+	// Non-members have no role, which allows nothing
+	var role orgs.Role
+	if args.OrganizationID != nil {
+		claims, ok := users.ClaimsFromContext(ctx)
+		if !ok {
+			return nil, newError(CodeUnauthenticated, "sign in to check your permissions")
+		}
+		if r.orgs == nil {
+			return nil, errNotImplemented("permissions")
+		}
+		var err error
+		if role, _, err = r.orgs.Role(ctx, string(*args.OrganizationID), claims.UserID()); err != nil {
+			return nil, orgError("permissions", err)
+		}
+	}
+
+	result := make([]*Permission, 0, len(r.permissions.Permissions))
+	for _, p := range r.permissions.Permissions {
+		permission := &Permission{
+			Operation:   p.Operation,
+			Resource:    p.Resource,
+			MinimumRole: string(p.MinimumRole),
+			Roles:       make([]string, 0, len(p.Roles)),
+		}
+		for _, allowed := range p.Roles {
+			permission.Roles = append(permission.Roles, string(allowed))
+		}
+		if args.OrganizationID != nil {
+			allowed := p.Allows(role)
+			permission.Allowed = &allowed
+		}
+		result = append(result, permission)
+	}
+	return result, nil
+}
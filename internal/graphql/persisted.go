@@ -0,0 +1,77 @@
+package graphql
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/tinle0301/streaming-platform-api/internal/persistedqueries"
+)
+
+// persistedQueryVersion is the only automatic persisted query protocol version
+const persistedQueryVersion = 1
+
+// PersistedQuery is the persistedQuery request extension: the hash of the
+// query document, sent instead of or alongside the document
+type PersistedQuery struct {
+	Version    int    `json:"version"`
+	Sha256Hash string `json:"sha256Hash"`
+}
+
+// RequestExtensions are the extensions of a GraphQL request
+type RequestExtensions struct {
+	PersistedQuery *PersistedQuery `json:"persistedQuery"`
+}
+
+// SetPersistedQueries enables automatic persisted queries
+func (r *Resolver) SetPersistedQueries(registry *persistedqueries.Registry) {
+	r.persistedQueries = registry
+}
+
+// resolvePersistedQuery fills in the query of a request that sent only its
+// hash, and registers the query of one that sent both. In allowlist-only
+// mode it also refuses plain queries not in the allowlist. It returns the
+// HTTP status to fail the request with, if any.
+func (r *Resolver) resolvePersistedQuery(ctx context.Context, request *Request) (int, *Error) {
+	registry := r.persistedQueries
+	persisted := request.Extensions.PersistedQuery
+	if persisted == nil {
+		if registry != nil && request.Query != "" && !registry.Allowed(request.Query) {
+			return http.StatusForbidden, newError(CodeForbidden, "only persisted queries are allowed")
+		}
+		return 0, nil
+	}
+
+	// Apollo clients match these messages to fall back to sending queries
+	if registry == nil {
+		return http.StatusOK, newError(CodePersistedQueryNotSupported, "PersistedQueryNotSupported")
+	}
+	if persisted.Version != persistedQueryVersion {
+		return http.StatusBadRequest, newError(CodeBadUserInput, "unsupported persisted query version")
+	}
+
+	if request.Query == "" {
+		query, err := registry.Lookup(ctx, persisted.Sha256Hash)
+		if errors.Is(err, persistedqueries.ErrNotFound) {
+			return http.StatusOK, newError(CodePersistedQueryNotFound, "PersistedQueryNotFound")
+		}
+		if err != nil {
+			return http.StatusInternalServerError, internalError("persisted query", err)
+		}
+		request.Query = query
+		return 0, nil
+	}
+
+	err := registry.Register(ctx, persisted.Sha256Hash, request.Query)
+	switch {
+	case errors.Is(err, persistedqueries.ErrHashMismatch):
+		return http.StatusBadRequest, newError(CodeBadUserInput, err.Error())
+	case errors.Is(err, persistedqueries.ErrNotAllowed):
+		return http.StatusForbidden, newError(CodeForbidden, err.Error())
+	case err != nil:
+		// The query is here; it only runs uncached
+		log.Printf("Error registering persisted query: hash=%s, err=%v", persisted.Sha256Hash, err)
+	}
+	return 0, nil
+}
@@ -0,0 +1,137 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/tinle0301/streaming-platform-api/internal/persistedqueries"
+)
+
+// memoryQueries is an in-memory persisted query cache
+type memoryQueries map[string]string
+
+func (c memoryQueries) Get(ctx context.Context, hash string) (string, error) {
+	if query, ok := c[hash]; ok {
+		return query, nil
+	}
+	return "", persistedqueries.ErrNotFound
+}
+
+func (c memoryQueries) Put(ctx context.Context, hash, query string) error {
+	c[hash] = query
+	return nil
+}
+
+// persistedResponse is a response to a persisted query request
+type persistedResponse struct {
+	status int
+	data   map[string]interface{}
+	code   string
+}
+
+// postPersisted sends query and its hash, either of which may be empty, to
+// a handler using registry
+func postPersisted(t *testing.T, registry *persistedqueries.Registry, query, hash string) persistedResponse {
+	t.Helper()
+	resolver := NewResolver(emptyStreams{})
+	if registry != nil {
+		resolver.SetPersistedQueries(registry)
+	}
+	schema, err := NewSchema(resolver)
+	if err != nil {
+		t.Fatalf("failed to parse schema: %v", err)
+	}
+
+	body := map[string]interface{}{"query": query}
+	if hash != "" {
+		body["extensions"] = map[string]interface{}{
+			"persistedQuery": map[string]interface{}{"version": 1, "sha256Hash": hash},
+		}
+	}
+	raw, _ := json.Marshal(body)
+	rec := httptest.NewRecorder()
+	Handler(schema, resolver)(rec, httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(string(raw))))
+
+	var response struct {
+		Data   map[string]interface{} `json:"data"`
+		Errors []struct {
+			Extensions map[string]interface{} `json:"extensions"`
+		} `json:"errors"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+		t.Fatalf("response is not JSON: %v", err)
+	}
+	result := persistedResponse{status: rec.Code, data: response.Data}
+	if len(response.Errors) > 0 {
+		result.code, _ = response.Errors[0].Extensions["code"].(string)
+	}
+	return result
+}
+
+func TestAutomaticPersistedQueries(t *testing.T) {
+	query := "{ __typename }"
+	hash := persistedqueries.Hash(query)
+	registry, err := persistedqueries.New(memoryQueries{}, persistedqueries.DefaultOptions())
+	if err != nil {
+		t.Fatalf("New() = %v", err)
+	}
+
+	if got := postPersisted(t, registry, "", hash); got.code != CodePersistedQueryNotFound {
+		t.Fatalf("unknown hash = %+v, want %s", got, CodePersistedQueryNotFound)
+	}
+	if got := postPersisted(t, registry, query, hash); got.code != "" || got.data["__typename"] != "Query" {
+		t.Fatalf("registering = %+v, want the query's data", got)
+	}
+	if got := postPersisted(t, registry, "", hash); got.code != "" || got.data["__typename"] != "Query" {
+		t.Fatalf("hash only = %+v, want the registered query's data", got)
+	}
+	if got := postPersisted(t, registry, "{ a: __typename }", hash); got.status != http.StatusBadRequest || got.code != CodeBadUserInput {
+		t.Fatalf("mismatched hash = %+v, want 400 %s", got, CodeBadUserInput)
+	}
+	if got := postPersisted(t, nil, "", hash); got.code != CodePersistedQueryNotSupported {
+		t.Fatalf("without persisted queries = %+v, want %s", got, CodePersistedQueryNotSupported)
+	}
+}
+
+func TestPersistedQueryAllowlist(t *testing.T) {
+	allowed := "{ __typename }"
+	other := "{ a: __typename }"
+	manifest := filepath.Join(t.TempDir(), "manifest.json")
+	content := fmt.Sprintf(`{"format":"apollo-persisted-query-manifest","version":1,"operations":[{"id":%q,"name":"Typename","type":"query","body":%q}]}`,
+		persistedqueries.Hash(allowed), allowed)
+	if err := os.WriteFile(manifest, []byte(content), 0o644); err != nil {
+		t.Fatalf("failed to write manifest: %v", err)
+	}
+
+	cache := memoryQueries{}
+	registry, err := persistedqueries.New(cache, persistedqueries.Options{Enabled: true, Manifest: manifest, AllowlistOnly: true})
+	if err != nil {
+		t.Fatalf("New() = %v", err)
+	}
+
+	if got := postPersisted(t, registry, "", persistedqueries.Hash(allowed)); got.code != "" || got.data["__typename"] != "Query" {
+		t.Errorf("allowlisted hash = %+v, want its data", got)
+	}
+	if got := postPersisted(t, registry, allowed, ""); got.code != "" {
+		t.Errorf("allowlisted plain query = %+v, want its data", got)
+	}
+	if got := postPersisted(t, registry, "", persistedqueries.Hash(other)); got.code != CodePersistedQueryNotFound {
+		t.Errorf("unknown hash = %+v, want %s", got, CodePersistedQueryNotFound)
+	}
+	if got := postPersisted(t, registry, other, persistedqueries.Hash(other)); got.status != http.StatusForbidden {
+		t.Errorf("registering = %+v, want 403", got)
+	}
+	if got := postPersisted(t, registry, other, ""); got.status != http.StatusForbidden || got.code != CodeForbidden {
+		t.Errorf("plain query = %+v, want 403 %s", got, CodeForbidden)
+	}
+	if len(cache) != 0 {
+		t.Errorf("allowlist-only mode cached %d queries, want none", len(cache))
+	}
+}
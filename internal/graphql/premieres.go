@@ -0,0 +1,100 @@
+package graphql
+
+import (
+	"context"
+	"errors"
+
+	gql "github.com/graph-gophers/graphql-go"
+	"github.com/tinle0301/streaming-platform-api/internal/premieres"
+	"github.com/tinle0301/streaming-platform-api/internal/users"
+	"github.com/tinle0301/streaming-platform-api/internal/websocket"
+)
+
+// SetPremieres enables VOD premieres
+func (r *Resolver) SetPremieres(service *premieres.Service) {
+	r.premieres = service
+}
+
+// SchedulePremiere resolves Mutation.schedulePremiere
+func (r *Resolver) SchedulePremiere(ctx context.Context, args struct {
+	VodID gql.ID
+	At    gql.Time
+}) (*VodPremiere, error) {
+	if r.premieres == nil {
+		return nil, errNotImplemented("schedulePremiere")
+	}
+	claims, ok := users.ClaimsFromContext(ctx)
+	if !ok {
+		return nil, newError(CodeUnauthenticated, "sign in to schedule a premiere")
+	}
+
+	premiere, err := r.premieres.Schedule(ctx, claims.UserID(), string(args.VodID), args.At.Time)
+	if err != nil {
+		return nil, premieresError("schedulePremiere", err)
+	}
+	return premiereFromStore(premiere), nil
+}
+
+// CancelPremiere resolves Mutation.cancelPremiere
+func (r *Resolver) CancelPremiere(ctx context.Context, args struct{ VodID gql.ID }) (*VodPremiere, error) {
+	if r.premieres == nil {
+		return nil, errNotImplemented("cancelPremiere")
+	}
+	claims, ok := users.ClaimsFromContext(ctx)
+	if !ok {
+		return nil, newError(CodeUnauthenticated, "sign in to cancel a premiere")
+	}
+
+	premiere, err := r.premieres.Cancel(ctx, claims.UserID(), string(args.VodID))
+	if err != nil {
+		return nil, premieresError("cancelPremiere", err)
+	}
+	return premiereFromStore(premiere), nil
+}
+
+// Premiere resolves Vod.premiere
+func (v *Vod) Premiere(ctx context.Context) (*VodPremiere, error) {
+	if v.resolver.premieres == nil {
+		return nil, nil
+	}
+
+	premiere, err := v.resolver.premieres.Get(ctx, string(v.ID))
+	if errors.Is(err, premieres.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, internalError("premiere", err)
+	}
+	return premiereFromStore(premiere), nil
+}
+
+// premiereFromStore converts a stored premiere
+func premiereFromStore(p *premieres.Premiere) *VodPremiere {
+	premiere := &VodPremiere{
+		ScheduledAt: gql.Time{Time: p.ScheduledAt},
+		Status:      p.Status,
+		Room:        websocket.PremiereRoom(p.VODID),
+	}
+	if p.StartedAt != nil {
+		premiere.StartedAt = &gql.Time{Time: *p.StartedAt}
+	}
+	if p.EndedAt != nil {
+		premiere.EndedAt = &gql.Time{Time: *p.EndedAt}
+	}
+	return premiere
+}
+
+// premieresError maps premiere errors to GraphQL errors
+func premieresError(field string, err error) error {
+	switch {
+	case errors.Is(err, premieres.ErrNotFound), errors.Is(err, premieres.ErrVODNotFound):
+		return newError(CodeNotFound, err.Error())
+	case errors.Is(err, premieres.ErrNotOwner):
+		return newError(CodeForbidden, err.Error())
+	case errors.Is(err, premieres.ErrInvalidSchedule), errors.Is(err, premieres.ErrAlreadyScheduled),
+		errors.Is(err, premieres.ErrNotDraft), errors.Is(err, premieres.ErrNotScheduled):
+		return newError(CodeBadUserInput, err.Error())
+	default:
+		return internalError(field, err)
+	}
+}
@@ -0,0 +1,88 @@
+package graphql
+
+import (
+	"context"
+	"encoding/base64"
+	"sort"
+
+	gql "github.com/graph-gophers/graphql-go"
+	"github.com/tinle0301/streaming-platform-api/internal/presence"
+	"github.com/tinle0301/streaming-platform-api/internal/users"
+)
+
+// SetPresence enables Query.streamViewers
+func (r *Resolver) SetPresence(store *presence.Store) {
+	r.presence = store
+}
+
+// StreamViewers resolves Query.streamViewers
+func (r *Resolver) StreamViewers(ctx context.Context, args struct {
+	StreamID gql.ID
+	First    int32
+	After    *string
+}) (*ViewerConnection, error) {
+	if r.presence == nil || r.users == nil {
+		return nil, errNotImplemented("streamViewers")
+	}
+	if args.First < 0 {
+		return nil, newError(CodeBadUserInput, "first must not be negative")
+	}
+	first := int(args.First)
+	if first > maxPageSize {
+		first = maxPageSize
+	}
+
+	viewers, err := r.presence.GetViewers(ctx, string(args.StreamID))
+	if err != nil {
+		return nil, internalError("streamViewers", err)
+	}
+
+	// Viewers are sorted by user ID; the cursor is the last ID seen
+	start := 0
+	if args.After != nil {
+		after, err := base64.StdEncoding.DecodeString(*args.After)
+		if err != nil {
+			return nil, newError(CodeBadUserInput, "invalid cursor")
+		}
+		start = sort.SearchStrings(viewers.UserIDs, string(after))
+		if start < len(viewers.UserIDs) && viewers.UserIDs[start] == string(after) {
+			start++
+		}
+	}
+	end := start + first
+	if end > len(viewers.UserIDs) {
+		end = len(viewers.UserIDs)
+	}
+	page := viewers.UserIDs[start:end]
+
+	accounts, err := r.loadAccounts(ctx, page)
+	if err != nil {
+		return nil, internalError("streamViewers", err)
+	}
+
+	claims, _ := users.ClaimsFromContext(ctx)
+	connection := &ViewerConnection{
+		Edges:      make([]*ViewerEdge, 0, len(page)),
+		PageInfo:   &PageInfo{},
+		TotalCount: int32(viewers.Total()),
+		GuestCount: int32(viewers.Guests),
+	}
+	for i, account := range accounts {
+		// Accounts deleted while watching are left out
+		if account == nil {
+			continue
+		}
+		connection.Edges = append(connection.Edges, &ViewerEdge{
+			Node:   userFromAccount(account, r.users, claims != nil && claims.UserID() == account.ID),
+			Cursor: base64.StdEncoding.EncodeToString([]byte(page[i])),
+		})
+	}
+
+	if len(connection.Edges) > 0 {
+		connection.PageInfo.StartCursor = &connection.Edges[0].Cursor
+		connection.PageInfo.EndCursor = &connection.Edges[len(connection.Edges)-1].Cursor
+	}
+	connection.PageInfo.HasPreviousPage = start > 0
+	connection.PageInfo.HasNextPage = end < len(viewers.UserIDs)
+	return connection, nil
+}
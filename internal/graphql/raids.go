@@ -0,0 +1,109 @@
+package graphql
+
+import (
+	"context"
+	"errors"
+
+	gql "github.com/graph-gophers/graphql-go"
+	"github.com/tinle0301/streaming-platform-api/internal/raids"
+	"github.com/tinle0301/streaming-platform-api/internal/users"
+)
+
+// maxRaidSuggestions caps suggestRaidTargets results
+const maxRaidSuggestions = 25
+
+// SetRaidSuggester enables Query.suggestRaidTargets
+func (r *Resolver) SetRaidSuggester(suggester *raids.Suggester) {
+	r.raidTargets = suggester
+}
+
+// SuggestRaidTargets resolves Query.suggestRaidTargets
+func (r *Resolver) SuggestRaidTargets(ctx context.Context, args struct {
+	StreamID gql.ID
+	Limit    int32
+}) ([]*RaidSuggestion, error) {
+	if r.raidTargets == nil {
+		return nil, errNotImplemented("suggestRaidTargets")
+	}
+	claims, ok := users.ClaimsFromContext(ctx)
+	if !ok {
+		return nil, newError(CodeUnauthenticated, "sign in to find raid targets")
+	}
+
+	stream, err := r.ownStream(ctx, "suggestRaidTargets", claims, string(args.StreamID))
+	if err != nil {
+		return nil, err
+	}
+
+	limit := int(args.Limit)
+	if limit <= 0 || limit > maxRaidSuggestions {
+		limit = maxRaidSuggestions
+	}
+	suggestions, err := r.raidTargets.Suggest(ctx, stream, limit)
+	if err != nil {
+		return nil, internalError("suggestRaidTargets", err)
+	}
+
+	result := make([]*RaidSuggestion, 0, len(suggestions))
+	for _, suggestion := range suggestions {
+		result = append(result, &RaidSuggestion{
+			Stream:  streamFromStore(suggestion.Stream),
+			Score:   suggestion.Score,
+			Reasons: suggestion.Reasons,
+		})
+	}
+	return result, nil
+}
+
+// SetRaids enables Mutation.startRaid
+func (r *Resolver) SetRaids(service *raids.Service) {
+	r.raids = service
+}
+
+// StartRaid resolves Mutation.startRaid
+func (r *Resolver) StartRaid(ctx context.Context, args struct {
+	FromStreamID gql.ID
+	ToStreamID   gql.ID
+}) (*RaidResult, error) {
+	return r.startRaid(ctx, "startRaid", string(args.FromStreamID), string(args.ToStreamID))
+}
+
+// RaidStream resolves the deprecated Mutation.raidStream like startRaid
+func (r *Resolver) RaidStream(ctx context.Context, args struct {
+	FromStreamID gql.ID
+	ToStreamID   gql.ID
+}) (*RaidResult, error) {
+	return r.startRaid(ctx, "raidStream", string(args.FromStreamID), string(args.ToStreamID))
+}
+
+// startRaid raids toStreamID from one of the viewer's streams
+func (r *Resolver) startRaid(ctx context.Context, field, fromStreamID, toStreamID string) (*RaidResult, error) {
+	if r.raids == nil {
+		return nil, errNotImplemented(field)
+	}
+	claims, ok := users.ClaimsFromContext(ctx)
+	if !ok {
+		return nil, newError(CodeUnauthenticated, "sign in to raid")
+	}
+	from, err := r.ownStream(ctx, field, claims, fromStreamID)
+	if err != nil {
+		return nil, err
+	}
+
+	raid, to, err := r.raids.Start(ctx, from, toStreamID)
+	switch {
+	case errors.Is(err, raids.ErrTargetMissing):
+		return nil, newError(CodeNotFound, err.Error())
+	case errors.Is(err, raids.ErrSourceNotLive), errors.Is(err, raids.ErrTargetNotLive), errors.Is(err, raids.ErrSelfRaid):
+		return nil, newError(CodeBadUserInput, err.Error())
+	case err != nil:
+		return nil, internalError(field, err)
+	}
+
+	return &RaidResult{
+		Success:     true,
+		FromStream:  streamFromStore(from),
+		ToStream:    streamFromStore(to),
+		ViewerCount: int32(raid.ViewerCount),
+	}, nil
+}
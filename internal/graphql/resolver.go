@@ -0,0 +1,149 @@
+package graphql
+
+import (
+	"context"
+	"errors"
+
+	gql "github.com/graph-gophers/graphql-go"
+	"github.com/tinle0301/streaming-platform-api/internal/analytics"
+	"github.com/tinle0301/streaming-platform-api/internal/campaigns"
+	"github.com/tinle0301/streaming-platform-api/internal/captions"
+	"github.com/tinle0301/streaming-platform-api/internal/chathistory"
+	"github.com/tinle0301/streaming-platform-api/internal/cheers"
+	"github.com/tinle0301/streaming-platform-api/internal/clips"
+	"github.com/tinle0301/streaming-platform-api/internal/communitychat"
+	"github.com/tinle0301/streaming-platform-api/internal/compliance"
+	"github.com/tinle0301/streaming-platform-api/internal/consent"
+	"github.com/tinle0301/streaming-platform-api/internal/dataloader"
+	"github.com/tinle0301/streaming-platform-api/internal/events"
+	"github.com/tinle0301/streaming-platform-api/internal/goals"
+	"github.com/tinle0301/streaming-platform-api/internal/highlights"
+	"github.com/tinle0301/streaming-platform-api/internal/milestones"
+	"github.com/tinle0301/streaming-platform-api/internal/notifications"
+	"github.com/tinle0301/streaming-platform-api/internal/orgs"
+	"github.com/tinle0301/streaming-platform-api/internal/parties"
+	"github.com/tinle0301/streaming-platform-api/internal/permissions"
+	"github.com/tinle0301/streaming-platform-api/internal/persistedqueries"
+	"github.com/tinle0301/streaming-platform-api/internal/premieres"
+	"github.com/tinle0301/streaming-platform-api/internal/presence"
+	"github.com/tinle0301/streaming-platform-api/internal/raids"
+	"github.com/tinle0301/streaming-platform-api/internal/scim"
+	"github.com/tinle0301/streaming-platform-api/internal/slowops"
+	"github.com/tinle0301/streaming-platform-api/internal/storage"
+	"github.com/tinle0301/streaming-platform-api/internal/store"
+	"github.com/tinle0301/streaming-platform-api/internal/subscriptions"
+	"github.com/tinle0301/streaming-platform-api/internal/tags"
+	"github.com/tinle0301/streaming-platform-api/internal/tenancy"
+	"github.com/tinle0301/streaming-platform-api/internal/users"
+)
+
+// errSubscriptionsOverWebSocket is returned when a subscription is sent to
+// the HTTP endpoint; live updates are delivered by the WebSocket server
+var errSubscriptionsOverWebSocket = errors.New("subscriptions are served by the WebSocket API")
+
+// Resolver is the root resolver for queries, mutations, and subscriptions.
+// Fields without a backing service return a NOT_IMPLEMENTED error.
+type Resolver struct {
+	streams       store.StreamRepository
+	users         *users.Service
+	deliveryStats DeliveryStatsReader
+	analytics     *analytics.Service
+	publisher     events.Publisher
+	raidTargets   *raids.Suggester
+	raids         *raids.Service
+	orgs          *orgs.Service
+	permissions   *permissions.Matrix
+	taxonomy      *tags.Taxonomy
+	campaigns     *campaigns.Service
+	clips         *clips.Service
+	highlights    *highlights.Service
+	captions      *captions.Service
+	parties       *parties.Service
+	premieres     *premieres.Service
+	communityChat *communitychat.Service
+	goals         *goals.Service
+	milestones    *milestones.Service
+	subscriptions *subscriptions.Service
+	cheers        *cheers.Service
+	chatHistory   *chathistory.Store
+	storage       *storage.Service
+	scim          *scim.Service
+	tenants       *tenancy.Registry
+	notifications *notifications.Service
+	presence      *presence.Store
+	disclosures   *compliance.DisclosurePolicy
+	ageGate       *compliance.AgeGate
+	restrictions  *compliance.GeoRestrictions
+	agreements    *consent.Registry
+	deadLetters   events.DeadLetterQueue
+	admins        map[string]bool
+	loaderOptions dataloader.Options
+
+	campaignPublishers map[string]bool
+
+	persistedQueries *persistedqueries.Registry
+	slowOperations   *slowops.Recorder
+
+	// readOnly refuses mutations while the schema is behind this build
+	readOnly bool
+}
+
+// NewResolver creates the root resolver
+func NewResolver(streams store.StreamRepository) *Resolver {
+	return &Resolver{
+		streams:       streams,
+		loaderOptions: dataloader.DefaultOptions(),
+	}
+}
+
+// Queries
+
+// SearchUsers resolves Query.searchUsers
+func (r *Resolver) SearchUsers(ctx context.Context, args struct {
+	Query string
+	Limit int32
+}) ([]*User, error) {
+	return nil, errNotImplemented("searchUsers")
+}
+
+// Mutations
+
+// SendNotification resolves Mutation.sendNotification
+func (r *Resolver) SendNotification(ctx context.Context, args struct{ Input NotificationInput }) (*Notification, error) {
+	return nil, errNotImplemented("sendNotification")
+}
+
+// SendChatMessage resolves Mutation.sendChatMessage
+func (r *Resolver) SendChatMessage(ctx context.Context, args struct {
+	StreamID gql.ID
+	Message  string
+}) (*ChatMessage, error) {
+	return nil, errNotImplemented("sendChatMessage")
+}
+
+// Subscriptions
+
+// StreamStatusChanged resolves Subscription.streamStatusChanged
+func (r *Resolver) StreamStatusChanged(ctx context.Context, args struct{ StreamID gql.ID }) (<-chan *Stream, error) {
+	return nil, errSubscriptionsOverWebSocket
+}
+
+// NotificationReceived resolves Subscription.notificationReceived
+func (r *Resolver) NotificationReceived(ctx context.Context) (<-chan *Notification, error) {
+	return nil, errSubscriptionsOverWebSocket
+}
+
+// ChatMessage resolves Subscription.chatMessage
+func (r *Resolver) ChatMessage(ctx context.Context, args struct{ StreamID gql.ID }) (<-chan *ChatMessage, error) {
+	return nil, errSubscriptionsOverWebSocket
+}
+
+// ViewerCountChanged resolves Subscription.viewerCountChanged
+func (r *Resolver) ViewerCountChanged(ctx context.Context, args struct{ StreamID gql.ID }) (<-chan *ViewerCountUpdate, error) {
+	return nil, errSubscriptionsOverWebSocket
+}
+
+// RaidEvent resolves Subscription.raidEvent
+func (r *Resolver) RaidEvent(ctx context.Context, args struct{ StreamID gql.ID }) (<-chan *RaidEvent, error) {
+	return nil, errSubscriptionsOverWebSocket
+}
@@ -0,0 +1,125 @@
+package graphql
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+
+	gql "github.com/graph-gophers/graphql-go"
+	"github.com/tinle0301/streaming-platform-api/internal/orgs"
+	"github.com/tinle0301/streaming-platform-api/internal/scim"
+)
+
+// SetSCIM enables SCIM provisioning for organizations
+func (r *Resolver) SetSCIM(service *scim.Service) {
+	r.scim = service
+}
+
+// ScimDirectory resolves Query.scimDirectory
+func (r *Resolver) ScimDirectory(ctx context.Context, args struct{ OrganizationID gql.ID }) (*ScimDirectory, error) {
+	if r.scim == nil {
+		return nil, errNotImplemented("scimDirectory")
+	}
+	claims, err := r.orgClaims(ctx, "scimDirectory")
+	if err != nil {
+		return nil, err
+	}
+
+	dir, err := r.scim.Directory(ctx, claims.UserID(), string(args.OrganizationID))
+	if errors.Is(err, scim.ErrDirectoryNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, scimError("scimDirectory", err)
+	}
+	return directoryFromStore(dir), nil
+}
+
+// EnableScim resolves Mutation.enableScim
+func (r *Resolver) EnableScim(ctx context.Context, args struct{ OrganizationID gql.ID }) (*ScimDirectoryPayload, error) {
+	if r.scim == nil {
+		return nil, errNotImplemented("enableScim")
+	}
+	claims, err := r.orgClaims(ctx, "enableScim")
+	if err != nil {
+		return nil, err
+	}
+
+	dir, token, err := r.scim.Enable(ctx, claims.UserID(), string(args.OrganizationID))
+	if err != nil {
+		return nil, scimError("enableScim", err)
+	}
+	return &ScimDirectoryPayload{Directory: directoryFromStore(dir), Token: token}, nil
+}
+
+// SetScimGroupRoles resolves Mutation.setScimGroupRoles
+func (r *Resolver) SetScimGroupRoles(ctx context.Context, args struct {
+	OrganizationID gql.ID
+	GroupRoles     []ScimGroupRoleInput
+}) (*ScimDirectory, error) {
+	if r.scim == nil {
+		return nil, errNotImplemented("setScimGroupRoles")
+	}
+	claims, err := r.orgClaims(ctx, "setScimGroupRoles")
+	if err != nil {
+		return nil, err
+	}
+
+	roles := make(map[string]orgs.Role, len(args.GroupRoles))
+	for _, input := range args.GroupRoles {
+		roles[input.Group] = orgs.Role(input.Role)
+	}
+	dir, err := r.scim.SetGroupRoles(ctx, claims.UserID(), string(args.OrganizationID), roles)
+	if err != nil {
+		return nil, scimError("setScimGroupRoles", err)
+	}
+	return directoryFromStore(dir), nil
+}
+
+// DisableScim resolves Mutation.disableScim
+func (r *Resolver) DisableScim(ctx context.Context, args struct{ OrganizationID gql.ID }) (bool, error) {
+	if r.scim == nil {
+		return false, errNotImplemented("disableScim")
+	}
+	claims, err := r.orgClaims(ctx, "disableScim")
+	if err != nil {
+		return false, err
+	}
+
+	if err := r.scim.Disable(ctx, claims.UserID(), string(args.OrganizationID)); err != nil {
+		return false, scimError("disableScim", err)
+	}
+	return true, nil
+}
+
+// directoryFromStore converts a directory, listing group roles by group name
+func directoryFromStore(dir *scim.Directory) *ScimDirectory {
+	roles := make([]*ScimGroupRole, 0, len(dir.GroupRoles))
+	for group, role := range dir.GroupRoles {
+		roles = append(roles, &ScimGroupRole{Group: group, Role: string(role)})
+	}
+	sort.Slice(roles, func(i, j int) bool {
+		return strings.ToLower(roles[i].Group) < strings.ToLower(roles[j].Group)
+	})
+
+	return &ScimDirectory{
+		OrganizationID: gql.ID(dir.OrganizationID),
+		Endpoint:       strings.TrimSuffix(scim.BasePath, "/"),
+		GroupRoles:     roles,
+		CreatedAt:      gql.Time{Time: dir.CreatedAt},
+		UpdatedAt:      gql.Time{Time: dir.UpdatedAt},
+	}
+}
+
+// scimError maps SCIM service errors to GraphQL errors
+func scimError(field string, err error) error {
+	switch {
+	case errors.Is(err, scim.ErrDirectoryNotFound):
+		return newError(CodeNotFound, err.Error())
+	case errors.Is(err, scim.ErrInvalidGroupRole):
+		return newError(CodeBadUserInput, err.Error())
+	default:
+		return orgError(field, err)
+	}
+}
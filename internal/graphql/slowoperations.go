@@ -0,0 +1,62 @@
+package graphql
+
+import (
+	"context"
+	"strings"
+
+	gql "github.com/graph-gophers/graphql-go"
+	"github.com/tinle0301/streaming-platform-api/internal/slowops"
+)
+
+// maxSlowOperations bounds Query.slowOperations' limit
+const maxSlowOperations = 100
+
+// SlowOperation is a resolver or database query that ran past its threshold
+type SlowOperation struct {
+	Kind       string
+	Name       string
+	Count      int32
+	TotalMs    float64
+	MaxMs      float64
+	LastSeenAt gql.Time
+}
+
+// SetSlowOperations reports slow resolvers to recorder and enables the
+// slowOperations query. Call before NewSchema.
+func (r *Resolver) SetSlowOperations(recorder *slowops.Recorder) {
+	r.slowOperations = recorder
+}
+
+// SlowOperations resolves Query.slowOperations
+func (r *Resolver) SlowOperations(ctx context.Context, args struct {
+	Kind  *string
+	Limit int32
+}) ([]*SlowOperation, error) {
+	if r.slowOperations == nil {
+		return nil, errNotImplemented("slowOperations")
+	}
+	if err := r.requirePlatformAdmin(ctx, "see slow operations"); err != nil {
+		return nil, err
+	}
+	if args.Limit < 1 || args.Limit > maxSlowOperations {
+		return nil, newError(CodeBadUserInput, "limit must be between 1 and 100")
+	}
+	var kind string
+	if args.Kind != nil {
+		kind = strings.ToLower(*args.Kind)
+	}
+
+	offenders := r.slowOperations.Top(kind, int(args.Limit))
+	result := make([]*SlowOperation, 0, len(offenders))
+	for _, offender := range offenders {
+		result = append(result, &SlowOperation{
+			Kind:       strings.ToUpper(offender.Kind),
+			Name:       offender.Name,
+			Count:      int32(offender.Count),
+			TotalMs:    float64(offender.Total.Microseconds()) / 1000,
+			MaxMs:      float64(offender.Max.Microseconds()) / 1000,
+			LastSeenAt: gql.Time{Time: offender.LastSeen},
+		})
+	}
+	return result, nil
+}
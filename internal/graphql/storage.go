@@ -0,0 +1,144 @@
+package graphql
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	gql "github.com/graph-gophers/graphql-go"
+	"github.com/tinle0301/streaming-platform-api/internal/storage"
+	"github.com/tinle0301/streaming-platform-api/internal/users"
+)
+
+// SetStorage enables organization channels' own buckets for VODs and clips
+func (r *Resolver) SetStorage(service *storage.Service) {
+	r.storage = service
+}
+
+// ChannelStorage resolves Query.channelStorage
+func (r *Resolver) ChannelStorage(ctx context.Context, args struct{ ChannelID gql.ID }) (*ChannelStorage, error) {
+	if r.storage == nil {
+		return nil, errNotImplemented("channelStorage")
+	}
+	if err := r.requireChannelAdmin(ctx, string(args.ChannelID)); err != nil {
+		return nil, err
+	}
+
+	cfg, err := r.storage.Get(ctx, string(args.ChannelID))
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, internalError("channelStorage", err)
+	}
+	return storageFromConfig(cfg), nil
+}
+
+// SetChannelStorage resolves Mutation.setChannelStorage
+func (r *Resolver) SetChannelStorage(ctx context.Context, args struct {
+	ChannelID gql.ID
+	Input     ChannelStorageInput
+}) (*ChannelStorage, error) {
+	if r.storage == nil {
+		return nil, errNotImplemented("setChannelStorage")
+	}
+	if err := r.requireChannelAdmin(ctx, string(args.ChannelID)); err != nil {
+		return nil, err
+	}
+
+	cfg, err := r.storage.Configure(ctx, &storage.Config{
+		ChannelID:   string(args.ChannelID),
+		Bucket:      args.Input.Bucket,
+		Region:      args.Input.Region,
+		Prefix:      stringValue(args.Input.Prefix),
+		AccessKeyID: args.Input.AccessKeyID,
+		SecretKey:   args.Input.SecretAccessKey,
+	})
+	if err != nil {
+		return nil, storageError("setChannelStorage", err)
+	}
+	return storageFromConfig(cfg), nil
+}
+
+// CheckChannelStorage resolves Mutation.checkChannelStorage
+func (r *Resolver) CheckChannelStorage(ctx context.Context, args struct{ ChannelID gql.ID }) (*ChannelStorage, error) {
+	if r.storage == nil {
+		return nil, errNotImplemented("checkChannelStorage")
+	}
+	if err := r.requireChannelAdmin(ctx, string(args.ChannelID)); err != nil {
+		return nil, err
+	}
+
+	cfg, err := r.storage.Check(ctx, string(args.ChannelID))
+	if err != nil {
+		return nil, storageError("checkChannelStorage", err)
+	}
+	return storageFromConfig(cfg), nil
+}
+
+// RemoveChannelStorage resolves Mutation.removeChannelStorage
+func (r *Resolver) RemoveChannelStorage(ctx context.Context, args struct{ ChannelID gql.ID }) (bool, error) {
+	if r.storage == nil {
+		return false, errNotImplemented("removeChannelStorage")
+	}
+	if err := r.requireChannelAdmin(ctx, string(args.ChannelID)); err != nil {
+		return false, err
+	}
+
+	if err := r.storage.Remove(ctx, string(args.ChannelID)); err != nil {
+		return false, storageError("removeChannelStorage", err)
+	}
+	return true, nil
+}
+
+// requireChannelAdmin checks that the viewer is an owner or admin of the
+// organization owning channelID; only organization channels may bring their
+// own storage
+func (r *Resolver) requireChannelAdmin(ctx context.Context, channelID string) error {
+	claims, ok := users.ClaimsFromContext(ctx)
+	if !ok {
+		return newError(CodeUnauthenticated, "sign in to manage channel storage")
+	}
+	if r.orgs != nil {
+		ok, err := r.orgs.CanAdministerChannel(ctx, channelID, claims.UserID())
+		if err != nil {
+			log.Printf("Error checking organization channel access: channel=%s, user=%s, err=%v", channelID, claims.UserID(), err)
+		}
+		if ok {
+			return nil
+		}
+	}
+	return newError(CodeForbidden, "only owners and admins of the channel's organization can manage its storage")
+}
+
+// storageFromConfig converts a channel's storage configuration
+func storageFromConfig(cfg *storage.Config) *ChannelStorage {
+	result := &ChannelStorage{
+		ChannelID:     gql.ID(cfg.ChannelID),
+		Bucket:        cfg.Bucket,
+		Region:        cfg.Region,
+		Prefix:        cfg.Prefix,
+		AccessKeyID:   cfg.AccessKeyID,
+		Status:        cfg.Status,
+		LastCheckedAt: gql.Time{Time: cfg.LastCheckedAt},
+		UpdatedAt:     gql.Time{Time: cfg.UpdatedAt},
+	}
+	if cfg.LastError != "" {
+		result.LastError = &cfg.LastError
+	}
+	return result
+}
+
+// storageError maps storage errors to GraphQL errors
+func storageError(field string, err error) error {
+	switch {
+	case errors.Is(err, storage.ErrNotFound), errors.Is(err, storage.ErrChannelNotFound):
+		return newError(CodeNotFound, err.Error())
+	case errors.Is(err, storage.ErrInvalidBucket), errors.Is(err, storage.ErrInvalidRegion),
+		errors.Is(err, storage.ErrInvalidPrefix), errors.Is(err, storage.ErrMissingCredentials),
+		errors.Is(err, storage.ErrCheckFailed):
+		return newError(CodeBadUserInput, err.Error())
+	default:
+		return internalError(field, err)
+	}
+}
@@ -0,0 +1,189 @@
+package graphql
+
+import (
+	"context"
+	"encoding/base64"
+	"log"
+	"strconv"
+	"time"
+
+	gql "github.com/graph-gophers/graphql-go"
+	"github.com/tinle0301/streaming-platform-api/internal/store"
+	"github.com/tinle0301/streaming-platform-api/internal/users"
+)
+
+// maxPageSize caps the limit argument of list queries
+const maxPageSize = 100
+
+// Stream resolves Query.stream
+func (r *Resolver) Stream(ctx context.Context, args struct{ ID gql.ID }) (*Stream, error) {
+	stream, err := r.loadStream(ctx, string(args.ID))
+	if err != nil {
+		return nil, internalError("stream", err)
+	}
+	if stream == nil {
+		return nil, nil
+	}
+	if err := r.checkRegion(ctx, "stream", stream.ID); err != nil {
+		return nil, err
+	}
+	return streamFromStore(stream), nil
+}
+
+// Streams resolves Query.streams
+func (r *Resolver) Streams(ctx context.Context, args struct {
+	Filter *StreamFilter
+	Limit  int32
+	Offset int32
+}) (*StreamConnection, error) {
+	if args.Limit < 0 || args.Offset < 0 {
+		return nil, newError(CodeBadUserInput, "limit and offset must not be negative")
+	}
+	limit := int(args.Limit)
+	if limit > maxPageSize {
+		limit = maxPageSize
+	}
+
+	filter := store.StreamFilter{Limit: limit, Offset: int(args.Offset)}
+	if f := args.Filter; f != nil {
+		filter.Status = stringValue(f.Status)
+		filter.Category = stringValue(f.Category)
+		filter.Language = stringValue(f.Language)
+		if f.Tags != nil {
+			filter.Tags = r.filterTags(*f.Tags)
+		}
+		if f.AnyTags != nil {
+			filter.AnyTags = r.filterTags(*f.AnyTags)
+		}
+		if f.MinViewers != nil {
+			minViewers := int(*f.MinViewers)
+			filter.MinViewers = &minViewers
+		}
+		if f.MaxViewers != nil {
+			maxViewers := int(*f.MaxViewers)
+			filter.MaxViewers = &maxViewers
+		}
+	}
+
+	streams, total, err := r.streams.List(ctx, filter)
+	if err != nil {
+		return nil, internalError("streams", err)
+	}
+
+	connection := &StreamConnection{
+		Edges:      make([]*StreamEdge, 0, len(streams)),
+		PageInfo:   &PageInfo{},
+		TotalCount: int32(total),
+	}
+	loaders := loadersFrom(ctx)
+	for i, stream := range streams {
+		if loaders != nil {
+			loaders.streams.Prime(stream.ID, stream)
+		}
+		connection.Edges = append(connection.Edges, &StreamEdge{
+			Node:   streamFromStore(stream),
+			Cursor: offsetCursor(filter.Offset + i),
+		})
+	}
+
+	if len(connection.Edges) > 0 {
+		connection.PageInfo.StartCursor = &connection.Edges[0].Cursor
+		connection.PageInfo.EndCursor = &connection.Edges[len(connection.Edges)-1].Cursor
+	}
+	connection.PageInfo.HasPreviousPage = filter.Offset > 0
+	connection.PageInfo.HasNextPage = filter.Offset+len(streams) < total
+
+	return connection, nil
+}
+
+// streamFromStore converts a stored stream to its GraphQL model
+func streamFromStore(s *store.Stream) *Stream {
+	stream := &Stream{
+		ID:               gql.ID(s.ID),
+		Title:            s.Title,
+		Description:      optionalString(s.Description),
+		ViewerCount:      int32(s.ViewerCount),
+		Status:           s.Status,
+		ThumbnailURL:     optionalString(s.ThumbnailURL),
+		Tags:             s.Tags,
+		SuggestedTags:    s.SuggestedTags,
+		Language:         s.Language,
+		IsMature:         s.IsMature,
+		ChatEnabled:      s.ChatEnabled,
+		IsBrandedContent: s.BrandedContent,
+		BrandedContent:   brandedContentFromStore(s),
+		streamerID:       s.StreamerID,
+		createdAt:        gql.Time{Time: s.CreatedAt},
+	}
+
+	if s.Category != "" {
+		stream.Category = &Category{ID: gql.ID(s.Category), Name: s.Category}
+	}
+	if s.StartedAt != nil {
+		stream.StartedAt = &gql.Time{Time: *s.StartedAt}
+	}
+	if s.EndedAt != nil {
+		stream.EndedAt = &gql.Time{Time: *s.EndedAt}
+	}
+	if s.Status == store.StreamStatusLive && s.StartedAt != nil {
+		uptime := int32(time.Since(*s.StartedAt).Seconds())
+		stream.Uptime = &uptime
+	}
+	if stream.Tags == nil {
+		stream.Tags = []string{}
+	}
+	if stream.SuggestedTags == nil {
+		stream.SuggestedTags = []string{}
+	}
+
+	return stream
+}
+
+// Streamer resolves Stream.streamer from the streamer's account. Streams
+// whose streamer has no account get a placeholder built from the stream.
+func (s *Stream) Streamer(ctx context.Context) (*User, error) {
+	if loaders := loadersFrom(ctx); loaders != nil && loaders.users != nil {
+		account, err := loaders.users.Load(ctx, s.streamerID)
+		if err != nil {
+			return nil, internalError("streamer", err)
+		}
+		if account != nil {
+			claims, _ := users.ClaimsFromContext(ctx)
+			return userFromAccount(account, loaders.accounts, claims != nil && claims.UserID() == account.ID), nil
+		}
+	}
+
+	return &User{
+		ID:          gql.ID(s.streamerID),
+		Username:    s.streamerID,
+		DisplayName: s.streamerID,
+		CreatedAt:   s.createdAt,
+	}, nil
+}
+
+// offsetCursor encodes a list position as an opaque cursor
+func offsetCursor(offset int) string {
+	return base64.StdEncoding.EncodeToString([]byte("offset:" + strconv.Itoa(offset)))
+}
+
+// internalError logs a backend failure and hides its details from clients
+func internalError(field string, err error) *Error {
+	log.Printf("Error resolving %s: %v", field, err)
+	return newError(CodeInternal, "internal error")
+}
+
+// optionalString returns nil for an empty string
+func optionalString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+// stringValue dereferences an optional string
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -0,0 +1,173 @@
+package graphql
+
+import (
+	"context"
+	"errors"
+
+	gql "github.com/graph-gophers/graphql-go"
+	"github.com/tinle0301/streaming-platform-api/internal/subscriptions"
+	"github.com/tinle0301/streaming-platform-api/internal/users"
+)
+
+// SetSubscriptions enables paid channel subscriptions and gifting
+func (r *Resolver) SetSubscriptions(service *subscriptions.Service) {
+	r.subscriptions = service
+}
+
+// MySubscription resolves Query.mySubscription
+func (r *Resolver) MySubscription(ctx context.Context, args struct{ ChannelID gql.ID }) (*ChannelSubscription, error) {
+	if r.subscriptions == nil {
+		return nil, errNotImplemented("mySubscription")
+	}
+	claims, ok := users.ClaimsFromContext(ctx)
+	if !ok {
+		return nil, newError(CodeUnauthenticated, "sign in to see your subscriptions")
+	}
+
+	sub, err := r.subscriptions.Get(ctx, string(args.ChannelID), claims.UserID())
+	if errors.Is(err, subscriptions.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, internalError("mySubscription", err)
+	}
+	return r.subscriptionFromStore(sub), nil
+}
+
+// Subscribe resolves Mutation.subscribe
+func (r *Resolver) Subscribe(ctx context.Context, args struct {
+	ChannelID    gql.ID
+	Tier         string
+	PaymentToken string
+}) (*ChannelSubscription, error) {
+	if r.subscriptions == nil {
+		return nil, errNotImplemented("subscribe")
+	}
+	claims, ok := users.ClaimsFromContext(ctx)
+	if !ok {
+		return nil, newError(CodeUnauthenticated, "sign in to subscribe")
+	}
+
+	sub, err := r.subscriptions.Subscribe(ctx, string(args.ChannelID), claims.UserID(), args.Tier, args.PaymentToken)
+	if err != nil {
+		return nil, subscriptionsError("subscribe", err)
+	}
+	return r.subscriptionFromStore(sub), nil
+}
+
+// GiftSubscription resolves Mutation.giftSubscription
+func (r *Resolver) GiftSubscription(ctx context.Context, args struct {
+	ChannelID    gql.ID
+	RecipientID  gql.ID
+	Tier         string
+	PaymentToken string
+}) (*ChannelSubscription, error) {
+	if r.subscriptions == nil {
+		return nil, errNotImplemented("giftSubscription")
+	}
+	claims, ok := users.ClaimsFromContext(ctx)
+	if !ok {
+		return nil, newError(CodeUnauthenticated, "sign in to gift subscriptions")
+	}
+
+	sub, err := r.subscriptions.Gift(ctx, string(args.ChannelID), string(args.RecipientID), claims.UserID(), args.Tier, args.PaymentToken)
+	if err != nil {
+		return nil, subscriptionsError("giftSubscription", err)
+	}
+	return r.subscriptionFromStore(sub), nil
+}
+
+// CancelSubscription resolves Mutation.cancelSubscription
+func (r *Resolver) CancelSubscription(ctx context.Context, args struct{ ChannelID gql.ID }) (*ChannelSubscription, error) {
+	if r.subscriptions == nil {
+		return nil, errNotImplemented("cancelSubscription")
+	}
+	claims, ok := users.ClaimsFromContext(ctx)
+	if !ok {
+		return nil, newError(CodeUnauthenticated, "sign in to manage your subscriptions")
+	}
+
+	sub, err := r.subscriptions.Cancel(ctx, string(args.ChannelID), claims.UserID())
+	if err != nil {
+		return nil, subscriptionsError("cancelSubscription", err)
+	}
+	return r.subscriptionFromStore(sub), nil
+}
+
+// SubscriberCount resolves Stream.subscriberCount
+func (s *Stream) SubscriberCount(ctx context.Context) (int32, error) {
+	count, err := loadSubscriberCount(ctx, s.streamerID)
+	if err != nil {
+		return 0, internalError("subscriberCount", err)
+	}
+	return int32(count), nil
+}
+
+// SubscriberCount resolves User.subscriberCount
+func (u *User) SubscriberCount(ctx context.Context) (int32, error) {
+	count, err := loadSubscriberCount(ctx, string(u.ID))
+	if err != nil {
+		return 0, internalError("subscriberCount", err)
+	}
+	return int32(count), nil
+}
+
+// Subscriber resolves ChannelSubscription.subscriber
+func (s *ChannelSubscription) Subscriber(ctx context.Context) (*User, error) {
+	user, err := s.resolver.userByID(ctx, "subscriber", s.subscriberID)
+	if err != nil || user != nil {
+		return user, err
+	}
+	return &User{ID: gql.ID(s.subscriberID)}, nil
+}
+
+// GiftedBy resolves ChannelSubscription.giftedBy
+func (s *ChannelSubscription) GiftedBy(ctx context.Context) (*User, error) {
+	if s.giftedByID == "" {
+		return nil, nil
+	}
+	return s.resolver.userByID(ctx, "giftedBy", s.giftedByID)
+}
+
+// subscriptionFromStore converts a stored subscription
+func (r *Resolver) subscriptionFromStore(s *subscriptions.Subscription) *ChannelSubscription {
+	badge := subscriptions.BadgeFor(s.Tier)
+	return &ChannelSubscription{
+		ID:           gql.ID(s.ID),
+		ChannelID:    gql.ID(s.ChannelID),
+		Tier:         s.Tier,
+		AutoRenew:    s.AutoRenew,
+		Months:       int32(s.Months),
+		IsActive:     s.Active(),
+		StartedAt:    gql.Time{Time: s.StartedAt},
+		ExpiresAt:    gql.Time{Time: s.ExpiresAt},
+		Badge:        &Badge{ID: gql.ID(badge.ID), Name: badge.Name, ImageURL: badge.ImageURL},
+		subscriberID: s.SubscriberID,
+		giftedByID:   s.GiftedBy,
+		resolver:     r,
+	}
+}
+
+// subscriberBadges returns the chat badges of a subscriber tier; none for ""
+func subscriberBadges(tier string) []*Badge {
+	if tier == "" {
+		return []*Badge{}
+	}
+	badge := subscriptions.BadgeFor(tier)
+	return []*Badge{{ID: gql.ID(badge.ID), Name: badge.Name, ImageURL: badge.ImageURL}}
+}
+
+// subscriptionsError maps subscription errors to GraphQL errors
+func subscriptionsError(field string, err error) error {
+	switch {
+	case errors.Is(err, subscriptions.ErrNotFound), errors.Is(err, subscriptions.ErrChannelNotFound):
+		return newError(CodeNotFound, err.Error())
+	case errors.Is(err, subscriptions.ErrInvalidTier), errors.Is(err, subscriptions.ErrSelfSubscribe),
+		errors.Is(err, subscriptions.ErrSelfGift), errors.Is(err, subscriptions.ErrAlreadySubscribed):
+		return newError(CodeBadUserInput, err.Error())
+	case errors.Is(err, subscriptions.ErrPaymentRequired), errors.Is(err, subscriptions.ErrEntitlementRedeemed):
+		return newError(CodePaymentRequired, err.Error())
+	default:
+		return internalError(field, err)
+	}
+}
@@ -0,0 +1,86 @@
+package graphql
+
+import (
+	"context"
+
+	"github.com/tinle0301/streaming-platform-api/internal/tags"
+)
+
+// maxTagResults caps the tags query
+const maxTagResults = 100
+
+// SetTaxonomy enables Query.tags and resolves tag aliases in stream info and
+// stream filters
+func (r *Resolver) SetTaxonomy(taxonomy *tags.Taxonomy) {
+	r.taxonomy = taxonomy
+}
+
+// Tags resolves Query.tags
+func (r *Resolver) Tags(ctx context.Context, args struct {
+	Query *string
+	Limit int32
+}) ([]*Tag, error) {
+	if r.taxonomy == nil {
+		return nil, errNotImplemented("tags")
+	}
+
+	limit := int(args.Limit)
+	if limit <= 0 || limit > maxTagResults {
+		limit = maxTagResults
+	}
+
+	listings, err := r.taxonomy.Search(ctx, stringValue(args.Query), limit)
+	if err != nil {
+		return nil, internalError("tags", err)
+	}
+
+	result := make([]*Tag, 0, len(listings))
+	for _, listing := range listings {
+		tag := &Tag{
+			Slug:            listing.Slug,
+			Name:            listing.Name,
+			Managed:         listing.Managed,
+			LiveStreamCount: int32(listing.LiveStreams),
+			Aliases:         listing.Aliases,
+		}
+		if listing.Description != "" {
+			tag.Description = &listing.Description
+		}
+		if tag.Aliases == nil {
+			tag.Aliases = []string{}
+		}
+		result = append(result, tag)
+	}
+	return result, nil
+}
+
+// validateTags normalizes tags from stream info, resolving aliases when the
+// taxonomy is loaded
+func (r *Resolver) validateTags(raw []string) ([]string, error) {
+	validated, err := tags.Validate(raw)
+	if r.taxonomy != nil {
+		validated, err = r.taxonomy.Validate(raw)
+	}
+	if err != nil {
+		return nil, newError(CodeBadUserInput, err.Error())
+	}
+	return validated, nil
+}
+
+// filterTags normalizes tags from a stream filter; tags that can never match
+// are kept as given so the filter finds nothing rather than everything
+func (r *Resolver) filterTags(raw []string) []string {
+	filtered := make([]string, 0, len(raw))
+	for _, tag := range raw {
+		slug, err := tags.Normalize(tag)
+		if err != nil {
+			filtered = append(filtered, tag)
+			continue
+		}
+		if r.taxonomy != nil {
+			slug = r.taxonomy.Canonical(slug)
+		}
+		filtered = append(filtered, slug)
+	}
+	return filtered
+}
@@ -0,0 +1,43 @@
+package graphql
+
+import (
+	"context"
+	"sort"
+
+	gql "github.com/graph-gophers/graphql-go"
+	"github.com/tinle0301/streaming-platform-api/internal/tenancy"
+)
+
+// SetTenants enables multi-tenancy; requests are scoped to the tenant the
+// tenancy middleware resolved
+func (r *Resolver) SetTenants(registry *tenancy.Registry) {
+	r.tenants = registry
+}
+
+// Tenant resolves Query.tenant
+func (r *Resolver) Tenant(ctx context.Context) (*Tenant, error) {
+	if r.tenants == nil {
+		return nil, errNotImplemented("tenant")
+	}
+
+	tenant, err := r.tenants.Get(tenancy.ID(ctx))
+	if err != nil {
+		return nil, newError(CodeNotFound, err.Error())
+	}
+
+	result := &Tenant{
+		ID:       gql.ID(tenant.ID),
+		Name:     tenant.Name,
+		Settings: make([]TenantSetting, 0, len(tenant.Settings)),
+	}
+	if tenant.Domain != "" {
+		result.Domain = &tenant.Domain
+	}
+	for key, value := range tenant.Settings {
+		result.Settings = append(result.Settings, TenantSetting{Key: key, Value: value})
+	}
+	sort.Slice(result.Settings, func(i, j int) bool {
+		return result.Settings[i].Key < result.Settings[j].Key
+	})
+	return result, nil
+}
@@ -0,0 +1,85 @@
+package graphql
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/graph-gophers/graphql-go/errors"
+	"github.com/graph-gophers/graphql-go/introspection"
+	"github.com/tinle0301/streaming-platform-api/internal/slowops"
+	"github.com/tinle0301/streaming-platform-api/internal/tracing"
+)
+
+// tracer records a span for each GraphQL operation and each resolver it
+// runs, and reports slow resolvers to slow. Fields read straight from a
+// struct are not traced. Documents, variables, and arguments are left out
+// of spans, since they can hold credentials and personal data.
+type tracer struct {
+	slow *slowops.Recorder
+}
+
+// fieldPathKey carries the path of the resolver running in a context
+type fieldPathKey struct{}
+
+// fieldPath returns the path of a resolver running inside ctx's, e.g.
+// Query.streams.streamer. List indexes are left out, so every item's
+// resolver counts as one.
+func fieldPath(ctx context.Context, typeName, fieldName string) string {
+	if parent, ok := ctx.Value(fieldPathKey{}).(string); ok {
+		return parent + "." + fieldName
+	}
+	return typeName + "." + fieldName
+}
+
+// TraceQuery starts the operation's span
+func (tracer) TraceQuery(ctx context.Context, queryString string, operationName string, variables map[string]interface{}, varTypes map[string]*introspection.Type) (context.Context, func([]*errors.QueryError)) {
+	name := "GraphQL"
+	if operationName != "" {
+		name += " " + operationName
+	}
+	ctx, span := tracing.Start(ctx, name, tracing.KindInternal,
+		tracing.String("graphql.operation.name", operationName),
+	)
+	return ctx, func(errs []*errors.QueryError) {
+		if len(errs) > 0 {
+			message := errs[0].Message
+			if len(errs) > 1 {
+				message += fmt.Sprintf(" (and %d more errors)", len(errs)-1)
+			}
+			span.SetError(message)
+			span.SetAttributes(tracing.Int("graphql.errors", len(errs)))
+		}
+		span.End()
+	}
+}
+
+// TraceField starts a resolver's span
+func (t tracer) TraceField(ctx context.Context, label, typeName, fieldName string, trivial bool, args map[string]interface{}) (context.Context, func(*errors.QueryError)) {
+	if trivial {
+		return ctx, func(*errors.QueryError) {}
+	}
+	ctx, span := tracing.Start(ctx, typeName+"."+fieldName, tracing.KindInternal,
+		tracing.String("graphql.field.type", typeName),
+		tracing.String("graphql.field.name", fieldName),
+	)
+	var path string
+	var start time.Time
+	if t.slow != nil {
+		path = fieldPath(ctx, typeName, fieldName)
+		ctx = context.WithValue(ctx, fieldPathKey{}, path)
+		start = time.Now()
+	}
+	return ctx, func(err *errors.QueryError) {
+		if t.slow != nil {
+			t.slow.Observe(ctx, slowops.KindResolver, path, time.Since(start))
+		}
+		if err != nil {
+			span.SetError(err.Message)
+			if code, ok := err.Extensions["code"].(string); ok {
+				span.SetAttributes(tracing.String("graphql.error.code", code))
+			}
+		}
+		span.End()
+	}
+}
@@ -0,0 +1,110 @@
+package graphql
+
+import (
+	"context"
+	"errors"
+
+	gql "github.com/graph-gophers/graphql-go"
+	"github.com/tinle0301/streaming-platform-api/internal/users"
+)
+
+// SetUsers enables accounts: register, login, and viewer
+func (r *Resolver) SetUsers(service *users.Service) {
+	r.users = service
+}
+
+// Viewer resolves Query.viewer; it is null for anonymous requests
+func (r *Resolver) Viewer(ctx context.Context) (*User, error) {
+	if r.users == nil {
+		return nil, errNotImplemented("viewer")
+	}
+
+	claims, ok := users.ClaimsFromContext(ctx)
+	if !ok {
+		return nil, nil
+	}
+
+	user, err := r.users.Get(ctx, claims.UserID())
+	if errors.Is(err, users.ErrNotFound) {
+		// The account was deleted after the token was issued
+		return nil, nil
+	}
+	if err != nil {
+		return nil, internalError("viewer", err)
+	}
+	return userFromAccount(user, r.users, true), nil
+}
+
+// Register resolves Mutation.register
+func (r *Resolver) Register(ctx context.Context, args struct{ Input RegisterInput }) (*AuthPayload, error) {
+	if r.users == nil {
+		return nil, errNotImplemented("register")
+	}
+
+	session, err := r.users.Register(ctx, users.Registration{
+		Username:    args.Input.Username,
+		Email:       args.Input.Email,
+		Password:    args.Input.Password,
+		DisplayName: stringValue(args.Input.DisplayName),
+	})
+
+	var validationErr *users.ValidationError
+	switch {
+	case errors.As(err, &validationErr):
+		return nil, newError(CodeBadUserInput, validationErr.Error())
+	case errors.Is(err, users.ErrUsernameTaken), errors.Is(err, users.ErrEmailTaken):
+		return nil, newError(CodeBadUserInput, err.Error())
+	case err != nil:
+		return nil, internalError("register", err)
+	}
+	return r.authPayload(session), nil
+}
+
+// Login resolves Mutation.login
+func (r *Resolver) Login(ctx context.Context, args struct{ Input LoginInput }) (*AuthPayload, error) {
+	if r.users == nil {
+		return nil, errNotImplemented("login")
+	}
+
+	session, err := r.users.Login(ctx, args.Input.Login, args.Input.Password)
+	if errors.Is(err, users.ErrInvalidCredentials) {
+		return nil, newError(CodeUnauthenticated, err.Error())
+	}
+	if errors.Is(err, users.ErrDeactivated) {
+		return nil, newError(CodeForbidden, err.Error())
+	}
+	if err != nil {
+		return nil, internalError("login", err)
+	}
+	return r.authPayload(session), nil
+}
+
+// authPayload converts a session to its GraphQL model
+func (r *Resolver) authPayload(session *users.Session) *AuthPayload {
+	return &AuthPayload{
+		Token:     session.Token,
+		ExpiresAt: gql.Time{Time: session.ExpiresAt},
+		User:      userFromAccount(session.User, r.users, true),
+	}
+}
+
+// userFromAccount converts an account to its GraphQL model; the email is
+// only exposed to the account owner
+func userFromAccount(u *users.User, follows *users.Service, self bool) *User {
+	user := &User{
+		ID:          gql.ID(u.ID),
+		Username:    u.Username,
+		DisplayName: u.DisplayName,
+		Bio:         optionalString(u.Bio),
+		AvatarURL:   optionalString(u.AvatarURL),
+		BannerURL:   optionalString(u.BannerURL),
+		IsPartner:   u.IsPartner,
+		IsAffiliate: u.IsAffiliate,
+		CreatedAt:   gql.Time{Time: u.CreatedAt},
+		follows:     follows,
+	}
+	if self {
+		user.Email = &u.Email
+	}
+	return user
+}
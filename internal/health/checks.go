@@ -0,0 +1,122 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// checkTimeout bounds each dependency check
+const checkTimeout = 2 * time.Second
+
+// Check probes one dependency and returns an error if it is unavailable
+type Check func(ctx context.Context) error
+
+// Checker is implemented by clients that can probe their own connection
+type Checker interface {
+	Ready(ctx context.Context) error
+}
+
+// DependencyStatus is one dependency's entry in the readiness report
+type DependencyStatus struct {
+	Status    string  `json:"status"`
+	Error     string  `json:"error,omitempty"`
+	LatencyMs float64 `json:"latency_ms"`
+}
+
+// Checks is the set of dependency checks behind /ready. /health stays a
+// cheap liveness probe that never touches dependencies.
+type Checks struct {
+	checks map[string]Check
+	mu     sync.RWMutex
+}
+
+// NewChecks creates an empty set of checks
+func NewChecks() *Checks {
+	return &Checks{checks: make(map[string]Check)}
+}
+
+// Register adds or replaces the check for a dependency
+func (c *Checks) Register(name string, check Check) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.checks[name] = check
+}
+
+// Run checks every dependency in parallel and reports whether all of them
+// are available
+func (c *Checks) Run(ctx context.Context) (map[string]DependencyStatus, bool) {
+	c.mu.RLock()
+	checks := make(map[string]Check, len(c.checks))
+	for name, check := range c.checks {
+		checks[name] = check
+	}
+	c.mu.RUnlock()
+
+	statuses := make(map[string]DependencyStatus, len(checks))
+	ready := true
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	for name, check := range checks {
+		wg.Add(1)
+		go func(name string, check Check) {
+			defer wg.Done()
+
+			checkCtx, cancel := context.WithTimeout(ctx, checkTimeout)
+			start := time.Now()
+			err := check(checkCtx)
+			latency := milliseconds(time.Since(start))
+			cancel()
+
+			status := DependencyStatus{Status: "ready", LatencyMs: latency}
+			if err != nil {
+				status = DependencyStatus{Status: "not_ready", Error: err.Error(), LatencyMs: latency}
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			statuses[name] = status
+			if err != nil {
+				ready = false
+			}
+		}(name, check)
+	}
+	wg.Wait()
+
+	return statuses, ready
+}
+
+// Handler serves /ready: 200 when every dependency is available, 503
+// otherwise, with per-dependency status in the body
+func (c *Checks) Handler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		statuses, ready := c.Run(r.Context())
+
+		status := "ready"
+		code := http.StatusOK
+		if !ready {
+			status = "not_ready"
+			code = http.StatusServiceUnavailable
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(code)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"status":       status,
+			"dependencies": statuses,
+			"timestamp":    time.Now().Format(time.RFC3339),
+		})
+	}
+}
+
+// RedisCheck pings a Redis client
+func RedisCheck(client *redis.Client) Check {
+	return func(ctx context.Context) error {
+		return client.Ping(ctx).Err()
+	}
+}
@@ -0,0 +1,244 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Build information, set at link time:
+//
+//	go build -ldflags "-X github.com/tinle0301/streaming-platform-api/internal/health.Version=v1.2.3 \
+//	  -X github.com/tinle0301/streaming-platform-api/internal/health.Commit=abc123"
+var (
+	Version   = "dev"
+	Commit    = "unknown"
+	BuildTime = ""
+)
+
+// SchemaVersion identifies the layout of Report; bump it on breaking changes
+// so fleet dashboards can tell old and new payloads apart
+const SchemaVersion = "1"
+
+// latencySamples is how many recent samples are kept per component
+const latencySamples = 1024
+
+// startTime is when the process started serving
+var startTime = time.Now()
+
+// Report is the /health response body
+type Report struct {
+	SchemaVersion string                      `json:"schema_version"`
+	Status        string                      `json:"status"`
+	Service       string                      `json:"service"`
+	Build         BuildInfo                   `json:"build"`
+	UptimeSeconds int64                       `json:"uptime_seconds"`
+	Runtime       RuntimeStats                `json:"runtime"`
+	Components    map[string]ComponentLatency `json:"components"`
+
+	// Problems maps each failing status check to its error; any makes
+	// the status "degraded"
+	Problems  map[string]string `json:"problems,omitempty"`
+	Timestamp time.Time         `json:"timestamp"`
+}
+
+// BuildInfo describes the running binary
+type BuildInfo struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildTime string `json:"build_time,omitempty"`
+	GoVersion string `json:"go_version"`
+}
+
+// RuntimeStats is a snapshot of Go runtime statistics
+type RuntimeStats struct {
+	Goroutines     int    `json:"goroutines"`
+	GOMAXPROCS     int    `json:"gomaxprocs"`
+	HeapAllocBytes uint64 `json:"heap_alloc_bytes"`
+	HeapSysBytes   uint64 `json:"heap_sys_bytes"`
+	NumGC          uint32 `json:"num_gc"`
+	LastGCPauseNs  uint64 `json:"last_gc_pause_ns"`
+}
+
+// ComponentLatency summarizes recent latencies for one component
+type ComponentLatency struct {
+	Samples int     `json:"samples"`
+	P50Ms   float64 `json:"p50_ms"`
+	P99Ms   float64 `json:"p99_ms"`
+	MaxMs   float64 `json:"max_ms"`
+}
+
+// latencyRing holds the most recent samples for a component
+type latencyRing struct {
+	samples []time.Duration
+	next    int
+	full    bool
+}
+
+// Tracker records recent latencies per component (http, postgres, ...)
+// and checks the status of the service's own components
+type Tracker struct {
+	service    string
+	components map[string]*latencyRing
+	statuses   map[string]Check
+	mu         sync.Mutex
+}
+
+// NewTracker creates a latency tracker for a service
+func NewTracker(service string) *Tracker {
+	return &Tracker{
+		service:    service,
+		components: make(map[string]*latencyRing),
+		statuses:   make(map[string]Check),
+	}
+}
+
+// AddStatus registers a status check for a component, run on every report.
+// Unlike readiness checks it must not reach dependencies over the network.
+// Its latency is recorded for the component, and a failure reports the
+// service as degraded.
+func (t *Tracker) AddStatus(component string, check Check) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.statuses[component] = check
+}
+
+// Observe records one latency sample for a component
+func (t *Tracker) Observe(component string, d time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	ring, ok := t.components[component]
+	if !ok {
+		ring = &latencyRing{samples: make([]time.Duration, latencySamples)}
+		t.components[component] = ring
+	}
+
+	ring.samples[ring.next] = d
+	ring.next = (ring.next + 1) % len(ring.samples)
+	if ring.next == 0 {
+		ring.full = true
+	}
+}
+
+// Latencies summarizes the recent samples of every component
+func (t *Tracker) Latencies() map[string]ComponentLatency {
+	t.mu.Lock()
+	snapshots := make(map[string][]time.Duration, len(t.components))
+	for component, ring := range t.components {
+		n := ring.next
+		if ring.full {
+			n = len(ring.samples)
+		}
+		snapshot := make([]time.Duration, n)
+		copy(snapshot, ring.samples[:n])
+		snapshots[component] = snapshot
+	}
+	t.mu.Unlock()
+
+	latencies := make(map[string]ComponentLatency, len(snapshots))
+	for component, samples := range snapshots {
+		sort.Slice(samples, func(i, j int) bool { return samples[i] < samples[j] })
+		latencies[component] = ComponentLatency{
+			Samples: len(samples),
+			P50Ms:   milliseconds(percentile(samples, 0.50)),
+			P99Ms:   milliseconds(percentile(samples, 0.99)),
+			MaxMs:   milliseconds(percentile(samples, 1)),
+		}
+	}
+	return latencies
+}
+
+// checkStatuses runs the status checks, recording their latencies, and
+// returns the errors of those that failed
+func (t *Tracker) checkStatuses(ctx context.Context) map[string]string {
+	t.mu.Lock()
+	statuses := make(map[string]Check, len(t.statuses))
+	for component, check := range t.statuses {
+		statuses[component] = check
+	}
+	t.mu.Unlock()
+
+	var problems map[string]string
+	for component, check := range statuses {
+		checkCtx, cancel := context.WithTimeout(ctx, checkTimeout)
+		start := time.Now()
+		err := check(checkCtx)
+		t.Observe(component, time.Since(start))
+		cancel()
+
+		if err != nil {
+			if problems == nil {
+				problems = make(map[string]string)
+			}
+			problems[component] = err.Error()
+		}
+	}
+	return problems
+}
+
+// Report builds the current health report
+func (t *Tracker) Report(ctx context.Context) Report {
+	problems := t.checkStatuses(ctx)
+	status := "healthy"
+	if len(problems) > 0 {
+		status = "degraded"
+	}
+
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	return Report{
+		SchemaVersion: SchemaVersion,
+		Status:        status,
+		Service:       t.service,
+		Build: BuildInfo{
+			Version:   Version,
+			Commit:    Commit,
+			BuildTime: BuildTime,
+			GoVersion: runtime.Version(),
+		},
+		UptimeSeconds: int64(time.Since(startTime).Seconds()),
+		Runtime: RuntimeStats{
+			Goroutines:     runtime.NumGoroutine(),
+			GOMAXPROCS:     runtime.GOMAXPROCS(0),
+			HeapAllocBytes: mem.HeapAlloc,
+			HeapSysBytes:   mem.HeapSys,
+			NumGC:          mem.NumGC,
+			LastGCPauseNs:  mem.PauseNs[(mem.NumGC+255)%256],
+		},
+		Components: t.Latencies(),
+		Problems:   problems,
+		Timestamp:  time.Now(),
+	}
+}
+
+// Handler serves the health report as JSON, with 503 when it is degraded
+func (t *Tracker) Handler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		report := t.Report(r.Context())
+		w.Header().Set("Content-Type", "application/json")
+		if len(report.Problems) > 0 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		json.NewEncoder(w).Encode(report)
+	}
+}
+
+// percentile returns the p-th percentile of sorted samples
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	i := int(float64(len(sorted)-1) * p)
+	return sorted[i]
+}
+
+// milliseconds converts a duration to fractional milliseconds
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
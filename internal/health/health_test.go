@@ -0,0 +1,39 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHealthReportsFailingStatuses(t *testing.T) {
+	tracker := NewTracker("ws-server")
+	var hubErr error
+	tracker.AddStatus("hub", func(ctx context.Context) error { return hubErr })
+
+	get := func() (int, Report) {
+		rec := httptest.NewRecorder()
+		tracker.Handler()(rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+		var report Report
+		if err := json.NewDecoder(rec.Body).Decode(&report); err != nil {
+			t.Fatal(err)
+		}
+		return rec.Code, report
+	}
+
+	if code, report := get(); code != http.StatusOK || report.Status != "healthy" || len(report.Problems) != 0 {
+		t.Errorf("healthy hub: %d %s %v", code, report.Status, report.Problems)
+	}
+
+	hubErr = errors.New("hub event loop is not running")
+	code, report := get()
+	if code != http.StatusServiceUnavailable || report.Status != "degraded" || report.Problems["hub"] != hubErr.Error() {
+		t.Errorf("stuck hub: %d %s %v, want 503 degraded with the hub's error", code, report.Status, report.Problems)
+	}
+	if report.Components["hub"].Samples != 2 {
+		t.Errorf("hub latency samples = %d, want one per report", report.Components["hub"].Samples)
+	}
+}
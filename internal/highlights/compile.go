@@ -0,0 +1,180 @@
+package highlights
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/tinle0301/streaming-platform-api/internal/chatactivity"
+)
+
+// Segment reasons
+const (
+	ReasonMarker    = "marker"
+	ReasonChatSpike = "chat_spike"
+)
+
+// CompileOptions tunes how highlight reels are assembled
+type CompileOptions struct {
+	// Footage kept before and after each marker
+	MarkerLeadIn time.Duration
+	MarkerTail   time.Duration
+
+	// Footage kept before and after each chat spike bucket; chat reacts to
+	// what just happened, so spikes lead in further
+	SpikeLeadIn time.Duration
+	SpikeTail   time.Duration
+
+	// A bucket is a spike when its message count is SpikeThreshold standard
+	// deviations above the broadcast's mean and at least MinSpikeMessages
+	SpikeThreshold   float64
+	MinSpikeMessages int
+
+	// MaxDuration caps the reel's total length
+	MaxDuration time.Duration
+}
+
+// DefaultCompileOptions returns the options used when none are configured
+func DefaultCompileOptions() CompileOptions {
+	return CompileOptions{
+		MarkerLeadIn:     30 * time.Second,
+		MarkerTail:       15 * time.Second,
+		SpikeLeadIn:      20 * time.Second,
+		SpikeTail:        5 * time.Second,
+		SpikeThreshold:   2.5,
+		MinSpikeMessages: 10,
+		MaxDuration:      5 * time.Minute,
+	}
+}
+
+// markerScore ranks marker segments above all but the strongest chat spikes,
+// since streamers placed them deliberately
+const markerScore = 5.0
+
+// candidate is a range considered for the reel
+type candidate struct {
+	Segment
+	score float64
+}
+
+// compileSegments picks the reel's segments: a window around each marker
+// and chat spike, merged where they overlap, highest scoring first until
+// MaxDuration is used, then returned in broadcast order
+func compileSegments(opts CompileOptions, startedAt time.Time, length time.Duration, markers []*Marker, buckets []chatactivity.Bucket) []Segment {
+	var candidates []candidate
+	for _, marker := range markers {
+		candidates = append(candidates, candidate{
+			Segment: Segment{
+				Start:   marker.Offset - opts.MarkerLeadIn,
+				End:     marker.Offset + opts.MarkerTail,
+				Reasons: []string{ReasonMarker},
+			},
+			score: markerScore,
+		})
+	}
+	candidates = append(candidates, chatSpikes(opts, startedAt, length, buckets)...)
+
+	merged := mergeCandidates(candidates, length)
+	sort.SliceStable(merged, func(i, j int) bool { return merged[i].score > merged[j].score })
+
+	var segments []Segment
+	var total time.Duration
+	for _, c := range merged {
+		if total+c.Duration() > opts.MaxDuration {
+			continue
+		}
+		segments = append(segments, c.Segment)
+		total += c.Duration()
+	}
+	sort.Slice(segments, func(i, j int) bool { return segments[i].Start < segments[j].Start })
+	return segments
+}
+
+// chatSpikes returns a candidate for each bucket whose chat volume stands
+// out from the rest of the broadcast, scored by how far it stands out
+func chatSpikes(opts CompileOptions, startedAt time.Time, length time.Duration, buckets []chatactivity.Bucket) []candidate {
+	slots := int(length / chatactivity.BucketSize)
+	if slots == 0 || len(buckets) == 0 {
+		return nil
+	}
+
+	// Buckets without chat are absent, so they count as zeros
+	var sum, squares float64
+	for _, bucket := range buckets {
+		n := float64(bucket.Messages)
+		sum += n
+		squares += n * n
+	}
+	mean := sum / float64(slots)
+	stddev := math.Sqrt(math.Max(squares/float64(slots)-mean*mean, 0))
+	if stddev == 0 {
+		return nil
+	}
+
+	var spikes []candidate
+	for _, bucket := range buckets {
+		z := (float64(bucket.Messages) - mean) / stddev
+		if z < opts.SpikeThreshold || bucket.Messages < opts.MinSpikeMessages {
+			continue
+		}
+		offset := bucket.Start.Sub(startedAt)
+		spikes = append(spikes, candidate{
+			Segment: Segment{
+				Start:   offset - opts.SpikeLeadIn,
+				End:     offset + chatactivity.BucketSize + opts.SpikeTail,
+				Reasons: []string{ReasonChatSpike},
+			},
+			score: z,
+		})
+	}
+	return spikes
+}
+
+// mergeCandidates clamps candidates to the broadcast and merges overlapping
+// ones, keeping the higher score and both reasons
+func mergeCandidates(candidates []candidate, length time.Duration) []candidate {
+	for i := range candidates {
+		if candidates[i].Start < 0 {
+			candidates[i].Start = 0
+		}
+		if candidates[i].End > length {
+			candidates[i].End = length
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].Start < candidates[j].Start })
+
+	var merged []candidate
+	for _, c := range candidates {
+		if c.End <= c.Start {
+			continue
+		}
+		if n := len(merged); n > 0 && c.Start <= merged[n-1].End {
+			last := &merged[n-1]
+			if c.End > last.End {
+				last.End = c.End
+			}
+			last.score = math.Max(last.score, c.score)
+			last.Reasons = addReasons(last.Reasons, c.Reasons)
+			continue
+		}
+		merged = append(merged, c)
+	}
+	return merged
+}
+
+// addReasons appends the reasons in add that reasons lacks
+func addReasons(reasons, add []string) []string {
+	for _, reason := range add {
+		found := false
+		for _, existing := range reasons {
+			if existing == reason {
+				found = true
+				break
+			}
+		}
+		if !found {
+			reasons = append(reasons, reason)
+		}
+	}
+	return reasons
+}
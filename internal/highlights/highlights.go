@@ -0,0 +1,333 @@
+package highlights
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/tinle0301/streaming-platform-api/internal/store"
+	"github.com/tinle0301/streaming-platform-api/internal/tenancy"
+)
+
+// Marker sources
+const (
+	MarkerSourceManual = "MANUAL"
+	MarkerSourceAuto   = "AUTO"
+)
+
+// VOD kinds and statuses
+const (
+	KindHighlight = "HIGHLIGHT"
+
+	StatusDraft    = "DRAFT"
+	StatusPremiere = "PREMIERE"
+	StatusPublic   = "PUBLIC"
+)
+
+// Chat spike sensitivities, from never marking spikes to marking modest ones
+const (
+	SensitivityOff    = "OFF"
+	SensitivityLow    = "LOW"
+	SensitivityMedium = "MEDIUM"
+	SensitivityHigh   = "HIGH"
+
+	DefaultSensitivity = SensitivityMedium
+)
+
+// Repository errors
+var (
+	ErrNotFound       = errors.New("vod not found")
+	ErrStreamNotFound = errors.New("stream not found")
+)
+
+// Marker flags a moment in a broadcast, measured from the stream's start
+type Marker struct {
+	ID          string
+	StreamID    string
+	CreatedBy   string
+	Offset      time.Duration
+	Description string
+	Source      string
+	CreatedAt   time.Time
+}
+
+// Segment is a time range of a broadcast included in a VOD
+type Segment struct {
+	Start time.Duration
+	End   time.Duration
+
+	// Why the range was picked, e.g. "marker" or "chat_spike"
+	Reasons []string
+}
+
+// Duration is the length of the segment
+func (s Segment) Duration() time.Duration {
+	return s.End - s.Start
+}
+
+// VOD is a video on demand assembled from ranges of a broadcast
+type VOD struct {
+	ID         string
+	StreamID   string
+	StreamerID string
+	Kind       string
+	Status     string
+	Title      string
+	Segments   []Segment
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+// Duration is the total length of the VOD's segments
+func (v *VOD) Duration() time.Duration {
+	var total time.Duration
+	for _, segment := range v.Segments {
+		total += segment.Duration()
+	}
+	return total
+}
+
+// Repository persists stream markers and VODs. Markers, VODs and settings
+// belong to the tenant in the request context.
+type Repository interface {
+	// CreateMarker stores a marker and fills in its generated ID and timestamp
+	CreateMarker(ctx context.Context, marker *Marker) error
+
+	// Markers lists a stream's markers in broadcast order
+	Markers(ctx context.Context, streamID string) ([]*Marker, error)
+
+	// SaveHighlight stores a stream's highlight reel. A draft reel is
+	// replaced; a published one is left alone and returned as is.
+	SaveHighlight(ctx context.Context, vod *VOD) error
+
+	GetVOD(ctx context.Context, id string) (*VOD, error)
+
+	// SpikeSensitivities returns the chat spike sensitivity of each channel,
+	// defaulting channels without a setting
+	SpikeSensitivities(ctx context.Context, channelIDs []string) (map[string]string, error)
+
+	SetSpikeSensitivity(ctx context.Context, channelID, sensitivity string) error
+}
+
+// segmentJSON is how segments are stored in the vods.segments column
+type segmentJSON struct {
+	StartMs int64    `json:"start_ms"`
+	EndMs   int64    `json:"end_ms"`
+	Reasons []string `json:"reasons,omitempty"`
+}
+
+// vodColumns is the column list shared by VOD queries
+const vodColumns = `id::text, stream_id::text, streamer_id, kind, status, title, segments, created_at, updated_at`
+
+// PostgresRepository implements Repository on PostgreSQL
+type PostgresRepository struct {
+	pool *pgxpool.Pool
+}
+
+// NewPostgresRepository creates a marker and VOD repository on pool
+func NewPostgresRepository(pool *pgxpool.Pool) *PostgresRepository {
+	return &PostgresRepository{pool: pool}
+}
+
+// CreateMarker inserts a marker; automatic markers have no creator
+func (r *PostgresRepository) CreateMarker(ctx context.Context, marker *Marker) error {
+	if !store.IsUUID(marker.StreamID) {
+		return ErrStreamNotFound
+	}
+	if marker.Source == "" {
+		marker.Source = MarkerSourceManual
+	}
+
+	var createdBy interface{}
+	if marker.CreatedBy != "" {
+		createdBy = marker.CreatedBy
+	}
+	err := r.pool.QueryRow(ctx, `
+		INSERT INTO stream_markers (stream_id, created_by, offset_ms, description, source, tenant_id)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id::text, created_at`,
+		marker.StreamID, createdBy, marker.Offset.Milliseconds(), marker.Description, marker.Source, tenancy.ID(ctx),
+	).Scan(&marker.ID, &marker.CreatedAt)
+	if store.PgCode(err) == "23503" {
+		return ErrStreamNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create stream marker: %w", err)
+	}
+	return nil
+}
+
+// Markers lists a stream's markers
+func (r *PostgresRepository) Markers(ctx context.Context, streamID string) ([]*Marker, error) {
+	if !store.IsUUID(streamID) {
+		return []*Marker{}, nil
+	}
+
+	rows, err := r.pool.Query(ctx, `
+		SELECT id::text, stream_id::text, COALESCE(created_by::text, ''), offset_ms, description, source, created_at
+		FROM stream_markers
+		WHERE stream_id = $1`+tenancy.Condition("tenant_id", 2)+`
+		ORDER BY offset_ms, id`, streamID, tenancy.Scope(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list stream markers: %w", err)
+	}
+	defer rows.Close()
+
+	markers := []*Marker{}
+	for rows.Next() {
+		var marker Marker
+		var offsetMs int64
+		if err := rows.Scan(&marker.ID, &marker.StreamID, &marker.CreatedBy, &offsetMs,
+			&marker.Description, &marker.Source, &marker.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan stream marker: %w", err)
+		}
+		marker.Offset = time.Duration(offsetMs) * time.Millisecond
+		markers = append(markers, &marker)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list stream markers: %w", err)
+	}
+	return markers, nil
+}
+
+// SaveHighlight upserts a stream's highlight reel while it is still a draft
+func (r *PostgresRepository) SaveHighlight(ctx context.Context, vod *VOD) error {
+	if !store.IsUUID(vod.StreamID) {
+		return ErrStreamNotFound
+	}
+
+	segments, err := encodeSegments(vod.Segments)
+	if err != nil {
+		return err
+	}
+
+	row := r.pool.QueryRow(ctx, `
+		INSERT INTO vods (stream_id, streamer_id, kind, status, title, segments, tenant_id)
+		VALUES ($1, $2, 'HIGHLIGHT', 'DRAFT', $3, $4, $5)
+		ON CONFLICT (stream_id, kind) DO UPDATE SET
+			title = EXCLUDED.title, segments = EXCLUDED.segments, updated_at = NOW()
+		WHERE vods.status = 'DRAFT' AND vods.tenant_id = EXCLUDED.tenant_id
+		RETURNING `+vodColumns,
+		vod.StreamID, vod.StreamerID, vod.Title, segments, tenancy.ID(ctx))
+	saved, err := scanVOD(row)
+	if errors.Is(err, pgx.ErrNoRows) {
+		// Already published; keep the published reel
+		row = r.pool.QueryRow(ctx, `SELECT `+vodColumns+` FROM vods WHERE stream_id = $1 AND kind = 'HIGHLIGHT'`+tenancy.Condition("tenant_id", 2),
+			vod.StreamID, tenancy.Scope(ctx))
+		saved, err = scanVOD(row)
+	}
+	if errors.Is(err, pgx.ErrNoRows) || store.PgCode(err) == "23503" {
+		return ErrStreamNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to save highlight: %w", err)
+	}
+
+	*vod = *saved
+	return nil
+}
+
+// GetVOD returns a VOD by ID
+func (r *PostgresRepository) GetVOD(ctx context.Context, id string) (*VOD, error) {
+	if !store.IsUUID(id) {
+		return nil, ErrNotFound
+	}
+
+	row := r.pool.QueryRow(ctx, `SELECT `+vodColumns+` FROM vods WHERE id = $1`+tenancy.Condition("tenant_id", 2), id, tenancy.Scope(ctx))
+	vod, err := scanVOD(row)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get vod: %w", err)
+	}
+	return vod, nil
+}
+
+// SpikeSensitivities reads the channels' settings
+func (r *PostgresRepository) SpikeSensitivities(ctx context.Context, channelIDs []string) (map[string]string, error) {
+	sensitivities := make(map[string]string, len(channelIDs))
+	for _, id := range channelIDs {
+		sensitivities[id] = DefaultSensitivity
+	}
+	if len(channelIDs) == 0 {
+		return sensitivities, nil
+	}
+
+	rows, err := r.pool.Query(ctx, `
+		SELECT channel_id, spike_sensitivity FROM highlight_settings
+		WHERE channel_id = ANY($1)`+tenancy.Condition("tenant_id", 2), channelIDs, tenancy.Scope(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get highlight settings: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id, sensitivity string
+		if err := rows.Scan(&id, &sensitivity); err != nil {
+			return nil, fmt.Errorf("failed to scan highlight settings: %w", err)
+		}
+		sensitivities[id] = sensitivity
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get highlight settings: %w", err)
+	}
+	return sensitivities, nil
+}
+
+// SetSpikeSensitivity upserts a channel's setting
+func (r *PostgresRepository) SetSpikeSensitivity(ctx context.Context, channelID, sensitivity string) error {
+	_, err := r.pool.Exec(ctx, `
+		INSERT INTO highlight_settings (channel_id, spike_sensitivity, tenant_id)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (channel_id) DO UPDATE SET
+			spike_sensitivity = EXCLUDED.spike_sensitivity, updated_at = NOW()
+		WHERE highlight_settings.tenant_id = EXCLUDED.tenant_id`,
+		channelID, sensitivity, tenancy.ID(ctx))
+	if err != nil {
+		return fmt.Errorf("failed to set spike sensitivity: %w", err)
+	}
+	return nil
+}
+
+// scanVOD reads a row selected with vodColumns
+func scanVOD(row pgx.Row) (*VOD, error) {
+	var vod VOD
+	var segments []byte
+	err := row.Scan(&vod.ID, &vod.StreamID, &vod.StreamerID, &vod.Kind, &vod.Status, &vod.Title,
+		&segments, &vod.CreatedAt, &vod.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	var stored []segmentJSON
+	if err := json.Unmarshal(segments, &stored); err != nil {
+		return nil, fmt.Errorf("failed to decode vod segments: %w", err)
+	}
+	vod.Segments = make([]Segment, 0, len(stored))
+	for _, s := range stored {
+		vod.Segments = append(vod.Segments, Segment{
+			Start:   time.Duration(s.StartMs) * time.Millisecond,
+			End:     time.Duration(s.EndMs) * time.Millisecond,
+			Reasons: s.Reasons,
+		})
+	}
+	return &vod, nil
+}
+
+// encodeSegments converts segments to their stored JSON
+func encodeSegments(segments []Segment) ([]byte, error) {
+	stored := make([]segmentJSON, 0, len(segments))
+	for _, s := range segments {
+		stored = append(stored, segmentJSON{StartMs: s.Start.Milliseconds(), EndMs: s.End.Milliseconds(), Reasons: s.Reasons})
+	}
+	data, err := json.Marshal(stored)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode vod segments: %w", err)
+	}
+	return data, nil
+}
@@ -0,0 +1,216 @@
+package highlights
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/tinle0301/streaming-platform-api/internal/chatactivity"
+	"github.com/tinle0301/streaming-platform-api/internal/events"
+	"github.com/tinle0301/streaming-platform-api/internal/store"
+	"github.com/tinle0301/streaming-platform-api/internal/tenancy"
+)
+
+// maxMarkerDescriptionLength bounds a marker's description
+const maxMarkerDescriptionLength = 140
+
+// Service errors
+var (
+	ErrStreamNotLive       = errors.New("markers can only be added to live streams")
+	ErrStreamNotFinished   = errors.New("highlights can only be compiled from finished broadcasts")
+	ErrNothingToCompile    = errors.New("broadcast has no markers or chat spikes to build highlights from")
+	ErrInvalidMarkerOffset = errors.New("marker offset is outside the broadcast")
+	ErrDescriptionTooLong  = errors.New("marker description is too long")
+	ErrInvalidSensitivity  = errors.New("sensitivity must be OFF, LOW, MEDIUM, or HIGH")
+)
+
+// ChatActivitySource supplies a stream's chat activity over time
+type ChatActivitySource interface {
+	Buckets(ctx context.Context, streamID string, from, to time.Time) ([]chatactivity.Bucket, error)
+}
+
+// Service records stream markers and compiles highlight reels from them
+type Service struct {
+	repo    Repository
+	streams store.StreamRepository
+	chat    ChatActivitySource
+	opts    CompileOptions
+
+	publisher events.Publisher
+}
+
+// NewService creates a highlight service. chat may be nil, in which case
+// reels are built from markers alone.
+func NewService(repo Repository, streams store.StreamRepository, chat ChatActivitySource, opts CompileOptions) *Service {
+	return &Service{repo: repo, streams: streams, chat: chat, opts: opts}
+}
+
+// SetPublisher enables vod.render_requested events for the transcoding workers
+func (s *Service) SetPublisher(publisher events.Publisher) {
+	s.publisher = publisher
+}
+
+// AddMarker flags the current moment of a live stream
+func (s *Service) AddMarker(ctx context.Context, stream *store.Stream, createdBy, description, source string) (*Marker, error) {
+	if stream.Status != store.StreamStatusLive || stream.StartedAt == nil {
+		return nil, ErrStreamNotLive
+	}
+	return s.addMarker(ctx, stream, createdBy, time.Since(*stream.StartedAt), description, source)
+}
+
+// AddMarkerAt flags a moment of a stream at offset from its start
+func (s *Service) AddMarkerAt(ctx context.Context, stream *store.Stream, createdBy string, offset time.Duration, description, source string) (*Marker, error) {
+	return s.addMarker(ctx, stream, createdBy, offset, description, source)
+}
+
+// addMarker validates and stores a marker
+func (s *Service) addMarker(ctx context.Context, stream *store.Stream, createdBy string, offset time.Duration, description, source string) (*Marker, error) {
+	description = strings.TrimSpace(description)
+	if len(description) > maxMarkerDescriptionLength {
+		return nil, ErrDescriptionTooLong
+	}
+	if offset < 0 || (stream.StartedAt != nil && stream.EndedAt != nil && offset > stream.EndedAt.Sub(*stream.StartedAt)) {
+		return nil, ErrInvalidMarkerOffset
+	}
+
+	// Markers belong to the stream's tenant, including the spike detector's
+	ctx = tenancy.WithTenant(ctx, stream.TenantID)
+	marker := &Marker{
+		StreamID:    stream.ID,
+		CreatedBy:   createdBy,
+		Offset:      offset.Truncate(time.Millisecond),
+		Description: description,
+		Source:      source,
+	}
+	if err := s.repo.CreateMarker(ctx, marker); err != nil {
+		return nil, err
+	}
+	return marker, nil
+}
+
+// Markers lists a stream's markers in broadcast order
+func (s *Service) Markers(ctx context.Context, streamID string) ([]*Marker, error) {
+	return s.repo.Markers(ctx, streamID)
+}
+
+// GetVOD returns a VOD by ID
+func (s *Service) GetVOD(ctx context.Context, id string) (*VOD, error) {
+	return s.repo.GetVOD(ctx, id)
+}
+
+// SpikeSensitivity returns how readily chat spikes mark a channel's streams
+func (s *Service) SpikeSensitivity(ctx context.Context, channelID string) (string, error) {
+	sensitivities, err := s.repo.SpikeSensitivities(ctx, []string{channelID})
+	if err != nil {
+		return "", err
+	}
+	return sensitivities[channelID], nil
+}
+
+// SetSpikeSensitivity changes how readily chat spikes mark a channel's streams
+func (s *Service) SetSpikeSensitivity(ctx context.Context, channelID, sensitivity string) error {
+	if _, ok := spikeThresholds[sensitivity]; !ok && sensitivity != SensitivityOff {
+		return ErrInvalidSensitivity
+	}
+	return s.repo.SetSpikeSensitivity(ctx, channelID, sensitivity)
+}
+
+// Compile assembles a finished broadcast's highlight reel from its markers
+// and chat spikes and stores it as a draft VOD. Rendering is left to the
+// transcoding workers, which are sent the reel's time ranges.
+func (s *Service) Compile(ctx context.Context, streamID string) (*VOD, error) {
+	stream, err := s.streams.Get(ctx, streamID)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, ErrStreamNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if stream.Status == store.StreamStatusLive || stream.StartedAt == nil || stream.EndedAt == nil {
+		return nil, ErrStreamNotFinished
+	}
+
+	ctx = tenancy.WithTenant(ctx, stream.TenantID)
+	markers, err := s.repo.Markers(ctx, stream.ID)
+	if err != nil {
+		return nil, err
+	}
+	var buckets []chatactivity.Bucket
+	if s.chat != nil {
+		if buckets, err = s.chat.Buckets(ctx, stream.ID, *stream.StartedAt, *stream.EndedAt); err != nil {
+			// Markers alone still make a reel
+			log.Printf("Error loading chat activity for highlights: streamID=%s, err=%v", stream.ID, err)
+		}
+	}
+
+	segments := compileSegments(s.opts, *stream.StartedAt, stream.EndedAt.Sub(*stream.StartedAt), markers, buckets)
+	if len(segments) == 0 {
+		return nil, ErrNothingToCompile
+	}
+
+	vod := &VOD{
+		StreamID:   stream.ID,
+		StreamerID: stream.StreamerID,
+		Kind:       KindHighlight,
+		Status:     StatusDraft,
+		Title:      highlightTitle(stream.Title),
+		Segments:   segments,
+	}
+	if err := s.repo.SaveHighlight(ctx, vod); err != nil {
+		return nil, err
+	}
+	if vod.Status != StatusDraft {
+		return vod, nil
+	}
+
+	log.Printf("Highlight compiled: vodID=%s, streamID=%s, segments=%d, duration=%s",
+		vod.ID, stream.ID, len(vod.Segments), vod.Duration())
+	s.requestRender(ctx, vod)
+	return vod, nil
+}
+
+// HandleEvent compiles highlights when a broadcast ends
+func (s *Service) HandleEvent(ctx context.Context, event events.Event) error {
+	if event.Type != events.EventTypeStreamOffline || event.StreamID == "" {
+		return nil
+	}
+
+	_, err := s.Compile(tenancy.EventContext(ctx, event), event.StreamID)
+	if errors.Is(err, ErrNothingToCompile) || errors.Is(err, ErrStreamNotFound) || errors.Is(err, ErrStreamNotFinished) {
+		return nil
+	}
+	return err
+}
+
+// requestRender asks the transcoding workers to render vod. The draft is
+// already saved, so a lost event only delays rendering until the next compile.
+func (s *Service) requestRender(ctx context.Context, vod *VOD) {
+	if s.publisher == nil {
+		return
+	}
+
+	segments := make([]map[string]interface{}, 0, len(vod.Segments))
+	for _, segment := range vod.Segments {
+		segments = append(segments, map[string]interface{}{
+			"start_ms": segment.Start.Milliseconds(),
+			"end_ms":   segment.End.Milliseconds(),
+		})
+	}
+	event := events.NewVODRenderRequestedEvent(vod.ID, vod.StreamID, vod.StreamerID, map[string]interface{}{
+		"kind":     vod.Kind,
+		"segments": segments,
+	})
+	if err := s.publisher.Publish(ctx, event); err != nil {
+		log.Printf("Error publishing %s event: vodID=%s, err=%v", event.Type, vod.ID, err)
+	}
+}
+
+// highlightTitle names a broadcast's highlight reel
+func highlightTitle(streamTitle string) string {
+	if streamTitle == "" {
+		return "Highlights"
+	}
+	return "Highlights: " + streamTitle
+}
@@ -0,0 +1,231 @@
+package highlights
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"github.com/tinle0301/streaming-platform-api/internal/chatactivity"
+	"github.com/tinle0301/streaming-platform-api/internal/store"
+)
+
+// spikeThresholds is how many standard deviations above its recent baseline
+// a bucket must be to count as a spike, per sensitivity
+var spikeThresholds = map[string]float64{
+	SensitivityLow:    4.5,
+	SensitivityMedium: 3,
+	SensitivityHigh:   2,
+}
+
+// spikePageSize is how many live streams are read per page
+const spikePageSize = 100
+
+// RecentActivitySource supplies live chat activity to the spike detector
+type RecentActivitySource interface {
+	Recent(ctx context.Context, streamID string, n int, until time.Time) ([]chatactivity.Bucket, error)
+	ClaimSpike(ctx context.Context, streamID string, cooldown time.Duration) (bool, error)
+}
+
+// SpikeOptions tunes chat spike detection
+type SpikeOptions struct {
+	// Baseline is how many buckets before the newest one it is compared to
+	Baseline int
+
+	// Cooldown is the least time between automatic markers on a stream
+	Cooldown time.Duration
+
+	// A velocity spike needs at least MinMessages in the bucket
+	MinMessages int
+
+	// An emote burst needs at least MinEmotes emote messages making up
+	// EmoteShare of the bucket
+	MinEmotes  int
+	EmoteShare float64
+}
+
+// DefaultSpikeOptions returns the options used when none are configured
+func DefaultSpikeOptions() SpikeOptions {
+	return SpikeOptions{
+		Baseline:    30,
+		Cooldown:    time.Minute,
+		MinMessages: 15,
+		MinEmotes:   10,
+		EmoteShare:  0.5,
+	}
+}
+
+// SpikeDetector watches the chat velocity and emote bursts of live streams
+// and marks spikes automatically, for highlight compilation to pick up.
+// Each channel's sensitivity decides how far above normal chat must go.
+type SpikeDetector struct {
+	streams  store.StreamRepository
+	activity RecentActivitySource
+	service  *Service
+	opts     SpikeOptions
+
+	// Newest bucket already checked per stream; only Run's goroutine uses it
+	checked map[string]time.Time
+}
+
+// NewSpikeDetector creates a spike detector that adds markers through service
+func NewSpikeDetector(streams store.StreamRepository, activity RecentActivitySource, service *Service, opts SpikeOptions) *SpikeDetector {
+	return &SpikeDetector{
+		streams:  streams,
+		activity: activity,
+		service:  service,
+		opts:     opts,
+		checked:  make(map[string]time.Time),
+	}
+}
+
+// RunOnce checks the newest settled bucket of every live stream and returns
+// how many markers were added
+func (d *SpikeDetector) RunOnce(ctx context.Context) (int, error) {
+	// Buckets still receiving flushes from the WebSocket nodes aren't checked
+	until := chatactivity.BucketStart(time.Now().Add(-chatactivity.FlushInterval))
+	checked := make(map[string]time.Time, len(d.checked))
+
+	marked := 0
+	for offset := 0; ; offset += spikePageSize {
+		streams, total, err := d.streams.List(ctx, store.StreamFilter{
+			Status: store.StreamStatusLive,
+			Limit:  spikePageSize,
+			Offset: offset,
+		})
+		if err != nil {
+			return marked, err
+		}
+
+		channelIDs := make([]string, 0, len(streams))
+		for _, stream := range streams {
+			channelIDs = append(channelIDs, stream.StreamerID)
+		}
+		sensitivities, err := d.service.repo.SpikeSensitivities(ctx, channelIDs)
+		if err != nil {
+			return marked, err
+		}
+
+		for _, stream := range streams {
+			checked[stream.ID] = until
+			if d.checked[stream.ID].Equal(until) || stream.StartedAt == nil {
+				continue
+			}
+			threshold, ok := spikeThresholds[sensitivities[stream.StreamerID]]
+			if !ok {
+				continue
+			}
+
+			added, err := d.check(ctx, stream, threshold, until)
+			if err != nil {
+				log.Printf("Error checking chat spikes: streamID=%s, err=%v", stream.ID, err)
+				continue
+			}
+			if added {
+				marked++
+			}
+		}
+
+		if offset+len(streams) >= total || len(streams) == 0 {
+			break
+		}
+	}
+
+	// Streams that went offline drop out here
+	d.checked = checked
+	return marked, nil
+}
+
+// Run checks for spikes every interval until ctx is done
+func (d *SpikeDetector) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			marked, err := d.RunOnce(ctx)
+			if err != nil {
+				log.Printf("Chat spike detection failed: err=%v", err)
+				continue
+			}
+			if marked > 0 {
+				log.Printf("Chat spike detection complete: marked=%d", marked)
+			}
+		}
+	}
+}
+
+// check marks the stream if its newest bucket before until is a spike
+func (d *SpikeDetector) check(ctx context.Context, stream *store.Stream, threshold float64, until time.Time) (bool, error) {
+	buckets, err := d.activity.Recent(ctx, stream.ID, d.opts.Baseline+1, until)
+	if err != nil {
+		return false, err
+	}
+	latest := buckets[len(buckets)-1]
+	if latest.Start.Before(*stream.StartedAt) {
+		return false, nil
+	}
+
+	description, ok := d.spike(buckets[:len(buckets)-1], latest, threshold)
+	if !ok {
+		return false, nil
+	}
+	claimed, err := d.activity.ClaimSpike(ctx, stream.ID, d.opts.Cooldown)
+	if err != nil || !claimed {
+		return false, err
+	}
+
+	_, err = d.service.AddMarkerAt(ctx, stream, "", latest.Start.Sub(*stream.StartedAt), description, MarkerSourceAuto)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// spike reports whether latest stands out from baseline as a chat velocity
+// spike or an emote burst, with a description for the marker
+func (d *SpikeDetector) spike(baseline []chatactivity.Bucket, latest chatactivity.Bucket, threshold float64) (string, bool) {
+	messages := make([]float64, len(baseline))
+	emotes := make([]float64, len(baseline))
+	for i, bucket := range baseline {
+		messages[i] = float64(bucket.Messages)
+		emotes[i] = float64(bucket.Emotes)
+	}
+
+	if latest.Emotes >= d.opts.MinEmotes &&
+		float64(latest.Emotes) >= d.opts.EmoteShare*float64(latest.Messages) &&
+		zScore(emotes, float64(latest.Emotes)) >= threshold {
+		return "Emote burst in chat", true
+	}
+	if latest.Messages >= d.opts.MinMessages && zScore(messages, float64(latest.Messages)) >= threshold {
+		mean, _ := meanStddev(messages)
+		return fmt.Sprintf("Chat spike: %.1fx usual activity", float64(latest.Messages)/math.Max(mean, 1)), true
+	}
+	return "", false
+}
+
+// zScore is how many standard deviations value is above the mean of
+// baseline. The deviation is floored at 1 so a near-silent chat doesn't turn
+// a couple of messages into a spike.
+func zScore(baseline []float64, value float64) float64 {
+	mean, stddev := meanStddev(baseline)
+	return (value - mean) / math.Max(stddev, 1)
+}
+
+// meanStddev returns the mean and population standard deviation of values
+func meanStddev(values []float64) (float64, float64) {
+	if len(values) == 0 {
+		return 0, 0
+	}
+	var sum, squares float64
+	for _, v := range values {
+		sum += v
+		squares += v * v
+	}
+	mean := sum / float64(len(values))
+	return mean, math.Sqrt(math.Max(squares/float64(len(values))-mean*mean, 0))
+}
@@ -0,0 +1,145 @@
+package httpcache
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Policy is the Cache-Control policy for a route
+type Policy struct {
+	// MaxAge is how long clients and shared caches may reuse a response
+	MaxAge time.Duration
+
+	// Private restricts caching to the client (per-user responses)
+	Private bool
+
+	// NoStore forbids caching entirely
+	NoStore bool
+
+	// StaleWhileRevalidate lets caches serve stale responses while refetching
+	StaleWhileRevalidate time.Duration
+}
+
+// Header renders the policy as a Cache-Control header value
+func (p Policy) Header() string {
+	if p.NoStore {
+		return "no-store"
+	}
+
+	directives := []string{"public"}
+	if p.Private {
+		directives[0] = "private"
+	}
+	if p.MaxAge > 0 {
+		directives = append(directives, "max-age="+strconv.Itoa(int(p.MaxAge.Seconds())))
+	} else {
+		directives = append(directives, "no-cache")
+	}
+	if p.StaleWhileRevalidate > 0 {
+		directives = append(directives, "stale-while-revalidate="+strconv.Itoa(int(p.StaleWhileRevalidate.Seconds())))
+	}
+	return strings.Join(directives, ", ")
+}
+
+// ContentETag returns a strong ETag for a response body
+func ContentETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// NotModified sets the ETag header and reports whether the request's
+// If-None-Match already matches it; if so a 304 has been written and the
+// handler should return without a body
+func NotModified(w http.ResponseWriter, r *http.Request, etag string) bool {
+	w.Header().Set("ETag", etag)
+	if !matches(r.Header.Get("If-None-Match"), etag) {
+		return false
+	}
+	w.WriteHeader(http.StatusNotModified)
+	return true
+}
+
+// Middleware applies per-route Cache-Control policies and answers
+// conditional GET and HEAD requests. Handlers that can derive their ETag
+// cheaply should call NotModified themselves; for any other successful
+// response the ETag is computed from the buffered body.
+func Middleware(policies map[string]Policy, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		policy, ok := policyFor(policies, r.URL.Path)
+		if !ok || (r.Method != http.MethodGet && r.Method != http.MethodHead) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Set("Cache-Control", policy.Header())
+		if policy.NoStore {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		recorder := &bufferedWriter{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(recorder, r)
+
+		if recorder.status == http.StatusOK && w.Header().Get("ETag") == "" {
+			if NotModified(w, r, ContentETag(recorder.body.Bytes())) {
+				return
+			}
+		}
+
+		w.WriteHeader(recorder.status)
+		w.Write(recorder.body.Bytes())
+	})
+}
+
+// policyFor returns the policy for the longest matching path prefix
+func policyFor(policies map[string]Policy, path string) (Policy, bool) {
+	var best string
+	var policy Policy
+	found := false
+	for prefix, p := range policies {
+		if strings.HasPrefix(path, prefix) && len(prefix) >= len(best) {
+			best, policy, found = prefix, p, true
+		}
+	}
+	return policy, found
+}
+
+// matches reports whether an If-None-Match header matches etag, using the
+// weak comparison required for If-None-Match
+func matches(header, etag string) bool {
+	if header == "" {
+		return false
+	}
+	if strings.TrimSpace(header) == "*" {
+		return true
+	}
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(header, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(candidate), "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// bufferedWriter holds a response so its ETag can be computed before sending
+type bufferedWriter struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+// WriteHeader records the status code
+func (b *bufferedWriter) WriteHeader(status int) {
+	b.status = status
+}
+
+// Write buffers the body
+func (b *bufferedWriter) Write(p []byte) (int, error) {
+	return b.body.Write(p)
+}
@@ -0,0 +1,62 @@
+package httpmiddleware
+
+import (
+	"log/slog"
+	"net/http"
+	"net/netip"
+	"time"
+)
+
+// Middleware wraps a handler
+type Middleware func(http.Handler) http.Handler
+
+// Chain is middleware applied in order: the first wraps all the others
+type Chain []Middleware
+
+// NewChain creates a chain of middleware
+func NewChain(middleware ...Middleware) Chain {
+	return append(Chain(nil), middleware...)
+}
+
+// Append returns a chain running middleware inside c's, leaving c unchanged,
+// so routes can extend a shared chain
+func (c Chain) Append(middleware ...Middleware) Chain {
+	chain := make(Chain, 0, len(c)+len(middleware))
+	return append(append(chain, c...), middleware...)
+}
+
+// Then wraps h in the chain
+func (c Chain) Then(h http.Handler) http.Handler {
+	for i := len(c) - 1; i >= 0; i-- {
+		h = c[i](h)
+	}
+	return h
+}
+
+// StackOptions configures the middleware every request runs through
+type StackOptions struct {
+	// TrustedProxies are the networks of load balancers and proxies whose
+	// X-Forwarded-For entries are believed
+	TrustedProxies []netip.Prefix
+
+	// MaxRequestTimeout caps the time budget clients ask for with
+	// X-Request-Timeout (0 = no cap)
+	MaxRequestTimeout time.Duration
+
+	// Observe, if set, is called with each request's latency
+	Observe func(time.Duration)
+}
+
+// Stack returns the chain the API and WebSocket servers run every request
+// through, outermost first: a request ID, the client's IP, the request log,
+// a trace span, and the client's deadline. Routes that serve signed-in
+// users append their authentication to it.
+func Stack(logger *slog.Logger, opts StackOptions) Chain {
+	return NewChain(
+		RequestID,
+		ClientIP(opts.TrustedProxies),
+		func(next http.Handler) http.Handler { return RequestLog(logger, opts.Observe, next) },
+		Trace,
+		Deadline(opts.MaxRequestTimeout),
+	)
+}
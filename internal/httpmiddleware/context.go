@@ -0,0 +1,123 @@
+package httpmiddleware
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"time"
+
+	"github.com/tinle0301/streaming-platform-api/internal/logging"
+)
+
+// RequestTimeoutHeader carries the time budget a client gives a request,
+// as a duration like "2s" or "750ms"
+const RequestTimeoutHeader = "X-Request-Timeout"
+
+// clientIPKey is the context key of the client's IP
+type clientIPKey struct{}
+
+// RequestID gives each request an ID, kept from a proxy's X-Request-ID or
+// generated, in its context and in the response's X-Request-ID
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if logging.RequestID(r.Context()) != "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		id := r.Header.Get(RequestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+		w.Header().Set(RequestIDHeader, id)
+		next.ServeHTTP(w, r.WithContext(logging.WithRequestID(r.Context(), id)))
+	})
+}
+
+// ClientIP puts the client's IP in the request context. Behind proxies in
+// trusted, X-Forwarded-For is read from the right, skipping the proxies'
+// own entries, so clients cannot forge their IP by sending the header.
+func ClientIP(trusted []netip.Prefix) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ip := clientIP(r, trusted)
+			next.ServeHTTP(w, r.WithContext(WithClientIP(r.Context(), ip)))
+		})
+	}
+}
+
+// clientIP returns the address of the first hop that isn't a trusted proxy
+func clientIP(r *http.Request, trusted []netip.Prefix) string {
+	peer := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(peer); err == nil {
+		peer = host
+	}
+	if !isTrusted(peer, trusted) {
+		return peer
+	}
+
+	hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+		if hop == "" {
+			continue
+		}
+		if !isTrusted(hop, trusted) {
+			return hop
+		}
+		peer = hop
+	}
+	// Every hop is a proxy; the farthest one is the best guess
+	return peer
+}
+
+// isTrusted reports whether ip is in one of the trusted networks
+func isTrusted(ip string, trusted []netip.Prefix) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, prefix := range trusted {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// WithClientIP returns a context carrying the client's IP
+func WithClientIP(ctx context.Context, ip string) context.Context {
+	return context.WithValue(ctx, clientIPKey{}, ip)
+}
+
+// ClientIPFromContext returns the client's IP, or "" outside ClientIP
+func ClientIPFromContext(ctx context.Context) string {
+	ip, _ := ctx.Value(clientIPKey{}).(string)
+	return ip
+}
+
+// Deadline gives a request's context the deadline its client asked for
+// with X-Request-Timeout, capped at max (0 = uncapped), so work the client
+// will no longer wait for is abandoned. Requests without the header get no
+// deadline.
+func Deadline(max time.Duration) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			timeout, err := time.ParseDuration(r.Header.Get(RequestTimeoutHeader))
+			if err != nil || timeout <= 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if max > 0 && timeout > max {
+				timeout = max
+			}
+
+			ctx, cancel := context.WithTimeout(r.Context(), timeout)
+			defer cancel()
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
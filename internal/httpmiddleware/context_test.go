@@ -0,0 +1,142 @@
+package httpmiddleware
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/tinle0301/streaming-platform-api/internal/logging"
+)
+
+func TestChainOrder(t *testing.T) {
+	var order []string
+	mark := func(name string) Middleware {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				order = append(order, name)
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+
+	base := NewChain(mark("a"), mark("b"))
+	extended := base.Append(mark("c"))
+	base.Append(mark("other"))
+	extended.Then(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		order = append(order, "handler")
+	})).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if got := len(base); got != 2 {
+		t.Errorf("Append changed the base chain to %d middleware", got)
+	}
+	want := []string{"a", "b", "c", "handler"}
+	if len(order) != len(want) {
+		t.Fatalf("order = %v, want %v", order, want)
+	}
+	for i := range want {
+		if order[i] != want[i] {
+			t.Fatalf("order = %v, want %v", order, want)
+		}
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	trusted := []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}
+	tests := []struct {
+		name       string
+		remoteAddr string
+		forwarded  []string
+		want       string
+	}{
+		{"direct", "203.0.113.7:4000", nil, "203.0.113.7"},
+		{"untrusted peer forging the header", "203.0.113.7:4000", []string{"198.51.100.1"}, "203.0.113.7"},
+		{"behind a proxy", "10.0.0.2:4000", []string{"198.51.100.1"}, "198.51.100.1"},
+		{"client forging behind a proxy", "10.0.0.2:4000", []string{"1.2.3.4, 198.51.100.1"}, "198.51.100.1"},
+		{"behind two proxies", "10.0.0.2:4000", []string{"198.51.100.1, 10.0.0.9"}, "198.51.100.1"},
+		{"repeated headers", "10.0.0.2:4000", []string{"198.51.100.1", "10.0.0.9"}, "198.51.100.1"},
+		{"only proxies", "10.0.0.2:4000", []string{"10.0.0.9"}, "10.0.0.9"},
+		{"proxy without the header", "10.0.0.2:4000", nil, "10.0.0.2"},
+		{"IPv4-mapped proxy", "[::ffff:10.0.0.2]:4000", []string{"2001:db8::1"}, "2001:db8::1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			for _, value := range tt.forwarded {
+				req.Header.Add("X-Forwarded-For", value)
+			}
+
+			var got string
+			ClientIP(trusted)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = ClientIPFromContext(r.Context())
+			})).ServeHTTP(httptest.NewRecorder(), req)
+			if got != tt.want {
+				t.Errorf("client IP = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRequestID(t *testing.T) {
+	var ids []string
+	handler := Stack(slog.New(slog.NewTextHandler(io.Discard, nil)), StackOptions{}).Then(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ids = append(ids, logging.RequestID(r.Context()))
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(RequestIDHeader, "proxy-id.1")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if ids[0] != "proxy-id.1" || rec.Header().Get(RequestIDHeader) != "proxy-id.1" {
+		t.Errorf("request ID = %q, header %q, want the proxy's", ids[0], rec.Header().Get(RequestIDHeader))
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(RequestIDHeader, "bad id\n")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if ids[1] == "" || ids[1] == "bad id\n" || rec.Header().Get(RequestIDHeader) != ids[1] {
+		t.Errorf("request ID = %q, header %q, want a generated one", ids[1], rec.Header().Get(RequestIDHeader))
+	}
+}
+
+func TestDeadline(t *testing.T) {
+	tests := []struct {
+		name    string
+		header  string
+		wantMax time.Duration
+	}{
+		{"no header", "", 0},
+		{"invalid header", "soon", 0},
+		{"within the cap", "2s", 2 * time.Second},
+		{"over the cap", "1h", 5 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.header != "" {
+				req.Header.Set(RequestTimeoutHeader, tt.header)
+			}
+
+			var deadline time.Time
+			var ok bool
+			Deadline(5*time.Second)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				deadline, ok = r.Context().Deadline()
+			})).ServeHTTP(httptest.NewRecorder(), req)
+
+			if tt.wantMax == 0 {
+				if ok {
+					t.Errorf("deadline set to %v, want none", deadline)
+				}
+				return
+			}
+			if remaining := time.Until(deadline); !ok || remaining > tt.wantMax || remaining < tt.wantMax-time.Second {
+				t.Errorf("deadline in %v, want about %v", remaining, tt.wantMax)
+			}
+		})
+	}
+}
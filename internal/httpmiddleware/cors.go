@@ -0,0 +1,176 @@
+// Package httpmiddleware holds HTTP middleware shared by the API and
+// WebSocket servers.
+package httpmiddleware
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// devOrigins are allowed outside production when ALLOWED_ORIGINS is unset,
+// covering common local frontend dev servers
+var devOrigins = []string{
+	"http://localhost:3000",
+	"http://localhost:5173",
+	"http://localhost:8080",
+	"http://127.0.0.1:3000",
+	"http://127.0.0.1:5173",
+	"http://127.0.0.1:8080",
+}
+
+// OriginsFromEnv parses a comma-separated ALLOWED_ORIGINS value. When it is
+// empty, development environments get the local dev servers and production
+// gets no cross-origin access.
+func OriginsFromEnv(value, environment string) []string {
+	var origins []string
+	for _, origin := range strings.Split(value, ",") {
+		if origin = strings.TrimSpace(origin); origin != "" {
+			origins = append(origins, origin)
+		}
+	}
+	if len(origins) == 0 && environment != "production" {
+		return append([]string{}, devOrigins...)
+	}
+	return origins
+}
+
+// OriginPolicy decides which browser origins may call the servers. Entries
+// are exact origins ("https://app.example.com"), subdomain wildcards
+// ("https://*.example.com"), or "*" for any origin.
+type OriginPolicy struct {
+	any       bool
+	exact     map[string]bool
+	wildcards []wildcardOrigin
+}
+
+// wildcardOrigin matches any subdomain of suffix over scheme
+type wildcardOrigin struct {
+	scheme string
+	suffix string
+}
+
+// NewOriginPolicy creates a policy allowing origins
+func NewOriginPolicy(origins []string) *OriginPolicy {
+	p := &OriginPolicy{exact: make(map[string]bool)}
+	for _, origin := range origins {
+		origin = strings.TrimRight(strings.ToLower(strings.TrimSpace(origin)), "/")
+		switch {
+		case origin == "*":
+			p.any = true
+		case strings.Contains(origin, "://*."):
+			scheme, host, _ := strings.Cut(origin, "://*.")
+			p.wildcards = append(p.wildcards, wildcardOrigin{scheme: scheme, suffix: "." + host})
+		case origin != "":
+			p.exact[origin] = true
+		}
+	}
+	return p
+}
+
+// Allowed reports whether a browser origin may make cross-origin requests
+func (p *OriginPolicy) Allowed(origin string) bool {
+	if origin == "" {
+		return false
+	}
+	if p.any {
+		return true
+	}
+	origin = strings.ToLower(origin)
+	if p.exact[origin] {
+		return true
+	}
+
+	u, err := url.Parse(origin)
+	if err != nil || u.Host == "" {
+		return false
+	}
+	for _, w := range p.wildcards {
+		if u.Scheme == w.scheme && strings.HasSuffix(u.Host, w.suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckOrigin validates a WebSocket upgrade. Requests without an Origin
+// header come from non-browser clients and are allowed, as are same-host
+// origins; other origins must be allowed by the policy.
+func (p *OriginPolicy) CheckOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	if u, err := url.Parse(origin); err == nil && strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+	return p.Allowed(origin)
+}
+
+// CORSOptions configures CORS responses
+type CORSOptions struct {
+	AllowedMethods   []string
+	AllowedHeaders   []string
+	AllowCredentials bool
+
+	// MaxAge is how long browsers may cache a preflight response
+	MaxAge time.Duration
+}
+
+// DefaultCORSOptions returns the options used for the GraphQL endpoint
+func DefaultCORSOptions() CORSOptions {
+	return CORSOptions{
+		AllowedMethods:   []string{http.MethodGet, http.MethodPost, http.MethodOptions},
+		AllowedHeaders:   []string{"Authorization", "Content-Type", "If-None-Match"},
+		AllowCredentials: true,
+		MaxAge:           10 * time.Minute,
+	}
+}
+
+// CORS answers preflight requests and adds CORS headers for allowed origins.
+// Preflights from other origins are rejected; their simple requests are
+// served without CORS headers, so browsers withhold the response.
+func CORS(policy *OriginPolicy, opts CORSOptions, next http.Handler) http.Handler {
+	methods := strings.Join(opts.AllowedMethods, ", ")
+	headers := strings.Join(opts.AllowedHeaders, ", ")
+	maxAge := strconv.Itoa(int(opts.MaxAge.Seconds()))
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		w.Header().Add("Vary", "Origin")
+		preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+
+		if origin == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if !policy.Allowed(origin) {
+			if preflight {
+				http.Error(w, "Origin not allowed", http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		if opts.AllowCredentials {
+			w.Header().Set("Access-Control-Allow-Credentials", "true")
+		}
+
+		if preflight {
+			w.Header().Add("Vary", "Access-Control-Request-Method")
+			w.Header().Add("Vary", "Access-Control-Request-Headers")
+			w.Header().Set("Access-Control-Allow-Methods", methods)
+			w.Header().Set("Access-Control-Allow-Headers", headers)
+			w.Header().Set("Access-Control-Max-Age", maxAge)
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		w.Header().Set("Access-Control-Expose-Headers", "ETag")
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,38 @@
+package httpmiddleware
+
+import (
+	"net/http"
+
+	"github.com/tinle0301/streaming-platform-api/internal/logging"
+	"github.com/tinle0301/streaming-platform-api/internal/tracing"
+)
+
+// Trace records a server span for each request, continuing the caller's
+// trace from its traceparent header. Server errors mark the span failed.
+// It runs inside RequestLog, so spans carry the request ID.
+func Trace(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := tracing.Extract(r.Context(), r.Header.Get)
+		ctx, span := tracing.Start(ctx, r.Method+" "+r.URL.Path, tracing.KindServer,
+			tracing.String("http.request.method", r.Method),
+			tracing.String("url.path", r.URL.Path),
+			tracing.String("client.address", r.RemoteAddr),
+		)
+		defer span.End()
+		if id := logging.RequestID(ctx); id != "" {
+			span.SetAttributes(tracing.String("http.request.id", id))
+		}
+
+		recorder := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(recorder, r.WithContext(ctx))
+
+		status := recorder.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		span.SetAttributes(tracing.Int("http.response.status_code", status))
+		if status >= http.StatusInternalServerError {
+			span.SetError(http.StatusText(status))
+		}
+	})
+}
@@ -0,0 +1,146 @@
+// Package logging sets up the servers' structured logger. Production logs
+// are JSON lines; development logs are text. The logger becomes slog's
+// default, and the standard library log package writes through it too, so
+// packages that still call log.Printf land in the same output.
+package logging
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+
+	"github.com/tinle0301/streaming-platform-api/internal/tracing"
+)
+
+// Log formats
+const (
+	FormatJSON = "json"
+	FormatText = "text"
+)
+
+// Options configures the logger
+type Options struct {
+	// Level is debug, info, warn, or error
+	Level string
+
+	// Format is json or text; empty picks json in production
+	Format string
+
+	// Service names the server in every record
+	Service string
+}
+
+// OptionsFromEnv builds options from LOG_LEVEL and LOG_FORMAT values
+func OptionsFromEnv(level, format, environment, service string) Options {
+	if format == "" {
+		format = FormatText
+		if environment == "production" {
+			format = FormatJSON
+		}
+	}
+	return Options{Level: level, Format: format, Service: service}
+}
+
+// ParseLevel parses a level name; empty means info
+func ParseLevel(name string) (slog.Level, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "", "info":
+		return slog.LevelInfo, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	default:
+		return slog.LevelInfo, fmt.Errorf("unknown log level %q", name)
+	}
+}
+
+// New creates a logger writing to w
+func New(opts Options, w io.Writer) (*slog.Logger, error) {
+	level, err := ParseLevel(opts.Level)
+	if err != nil {
+		return nil, err
+	}
+	handlerOptions := &slog.HandlerOptions{Level: level}
+
+	var handler slog.Handler
+	switch opts.Format {
+	case FormatJSON:
+		handler = slog.NewJSONHandler(w, handlerOptions)
+	case FormatText, "":
+		handler = slog.NewTextHandler(w, handlerOptions)
+	default:
+		return nil, fmt.Errorf("unknown log format %q", opts.Format)
+	}
+
+	logger := slog.New(handler)
+	if opts.Service != "" {
+		logger = logger.With("service", opts.Service)
+	}
+	return logger, nil
+}
+
+// Setup creates a logger writing to w and makes it the default for slog
+// and the log package. Invalid options fall back to text at info level,
+// with a warning.
+func Setup(opts Options, w io.Writer) *slog.Logger {
+	logger, err := New(opts, w)
+	if err != nil {
+		logger, _ = New(Options{Service: opts.Service}, w)
+	}
+	slog.SetDefault(logger)
+
+	if err != nil {
+		logger.Warn("Invalid logging configuration, using defaults", "err", err)
+	}
+	return logger
+}
+
+// requestIDKey is the context key of the request ID
+type requestIDKey struct{}
+
+// WithRequestID returns a context carrying a request ID
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID returns the context's request ID, or ""
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// userIDKey is the context key of the authenticated user's ID
+type userIDKey struct{}
+
+// WithUserID returns a context carrying the authenticated user's ID
+func WithUserID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, userIDKey{}, id)
+}
+
+// UserID returns the context's authenticated user ID, or ""
+func UserID(ctx context.Context) string {
+	id, _ := ctx.Value(userIDKey{}).(string)
+	return id
+}
+
+// FromContext returns the default logger with the context's request ID,
+// user ID, and trace ID
+func FromContext(ctx context.Context) *slog.Logger {
+	logger := slog.Default()
+	if id := RequestID(ctx); id != "" {
+		logger = logger.With("request_id", id)
+	}
+	if id := UserID(ctx); id != "" {
+		logger = logger.With("user_id", id)
+	}
+	if sc := tracing.SpanContextFromContext(ctx); sc.IsValid() {
+		logger = logger.With("trace_id", hex.EncodeToString(sc.TraceID[:]))
+	}
+	return logger
+}
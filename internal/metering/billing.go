@@ -0,0 +1,143 @@
+package metering
+
+import (
+	"context"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Rate prices a metric: CentsPerUnit for every Unit of quantity, e.g. 50
+// cents per 1,000,000 messages delivered
+type Rate struct {
+	Unit         float64 `json:"unit"`
+	CentsPerUnit float64 `json:"cents_per_unit"`
+}
+
+// DefaultRates are the prices of tenants without overrides
+func DefaultRates() map[string]Rate {
+	return map[string]Rate{
+		ConnectionMinutes: {Unit: 1000, CentsPerUnit: 5},
+		MessagesDelivered: {Unit: 1_000_000, CentsPerUnit: 50},
+		StorageGB:         {Unit: 1, CentsPerUnit: 2},
+		APICalls:          {Unit: 10_000, CentsPerUnit: 10},
+	}
+}
+
+// RateSetting prefixes the tenant setting overriding a metric's cents per
+// unit, e.g. "billing_rate_api_calls"
+const RateSetting = "billing_rate_"
+
+// Settings reads per-tenant settings overrides
+type Settings interface {
+	Setting(tenantID, key, fallback string) string
+}
+
+// LineItem is a billable charge for a tenant's use of one metric
+type LineItem struct {
+	Metric      string  `json:"metric"`
+	Quantity    float64 `json:"quantity"`
+	Rate        Rate    `json:"rate"`
+	AmountCents int64   `json:"amount_cents"`
+}
+
+// Statement is a tenant's usage and charges over a billing period
+type Statement struct {
+	TenantID   string     `json:"tenant_id"`
+	From       string     `json:"from"`
+	To         string     `json:"to"`
+	LineItems  []LineItem `json:"line_items"`
+	TotalCents int64      `json:"total_cents"`
+
+	// Daily is the usage the line items were summed from
+	Daily []Usage `json:"daily"`
+}
+
+// Service prices tenants' daily usage into statements
+type Service struct {
+	repo     Repository
+	rates    map[string]Rate
+	settings Settings
+}
+
+// NewService creates a billing service pricing usage at rates
+func NewService(repo Repository, rates map[string]Rate) *Service {
+	return &Service{repo: repo, rates: rates}
+}
+
+// SetSettings enables per-tenant rate overrides
+func (s *Service) SetSettings(settings Settings) {
+	s.settings = settings
+}
+
+// Statements returns a statement per tenant with usage in [from, to], or
+// only tenantID's if it isn't empty, ordered by tenant
+func (s *Service) Statements(ctx context.Context, tenantID string, from, to time.Time) ([]Statement, error) {
+	usage, err := s.repo.List(ctx, tenantID, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	byTenant := make(map[string][]Usage)
+	for _, u := range usage {
+		byTenant[u.TenantID] = append(byTenant[u.TenantID], u)
+	}
+
+	statements := make([]Statement, 0, len(byTenant))
+	for id, daily := range byTenant {
+		statements = append(statements, s.statement(id, from, to, daily))
+	}
+	sort.Slice(statements, func(i, j int) bool {
+		return statements[i].TenantID < statements[j].TenantID
+	})
+	return statements, nil
+}
+
+// statement sums a tenant's daily usage per metric and prices it
+func (s *Service) statement(tenantID string, from, to time.Time, daily []Usage) Statement {
+	totals := make(map[string]float64)
+	for _, u := range daily {
+		totals[u.Metric] += u.Quantity
+	}
+
+	statement := Statement{
+		TenantID:  tenantID,
+		From:      from.Format(dayLayout),
+		To:        to.Format(dayLayout),
+		LineItems: []LineItem{},
+		Daily:     daily,
+	}
+	for _, metric := range Metrics {
+		quantity, ok := totals[metric]
+		if !ok {
+			continue
+		}
+		rate := s.rate(tenantID, metric)
+		item := LineItem{
+			Metric:      metric,
+			Quantity:    quantity,
+			Rate:        rate,
+			AmountCents: int64(math.Round(quantity / rate.Unit * rate.CentsPerUnit)),
+		}
+		statement.LineItems = append(statement.LineItems, item)
+		statement.TotalCents += item.AmountCents
+	}
+	return statement
+}
+
+// rate returns a tenant's rate for a metric: its override of the cents per
+// unit if it has a valid one, else the default
+func (s *Service) rate(tenantID, metric string) Rate {
+	rate := s.rates[metric]
+	if rate.Unit <= 0 {
+		rate.Unit = 1
+	}
+	if s.settings == nil {
+		return rate
+	}
+	if cents, err := strconv.ParseFloat(s.settings.Setting(tenantID, RateSetting+metric, ""), 64); err == nil && cents >= 0 {
+		rate.CentsPerUnit = cents
+	}
+	return rate
+}
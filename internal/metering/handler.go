@@ -0,0 +1,65 @@
+package metering
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/tinle0301/streaming-platform-api/internal/tenancy"
+)
+
+// maxExportDays bounds the billing period of one export
+const maxExportDays = 366
+
+// ExportHandler serves tenants' statements to the billing system. Requests
+// must carry token as a bearer token. from and to are inclusive UTC days;
+// tenant limits the export to one tenant.
+//
+//	GET /admin/billing/usage?from=2026-10-01&to=2026-10-31[&tenant=acme]
+func ExportHandler(service *Service, token string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		presented := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(presented), []byte(token)) != 1 {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		query := r.URL.Query()
+		from, fromErr := time.Parse(dayLayout, query.Get("from"))
+		to, toErr := time.Parse(dayLayout, query.Get("to"))
+		if fromErr != nil || toErr != nil || to.Before(from) || to.Sub(from) > maxExportDays*24*time.Hour {
+			http.Error(w, "from and to must be YYYY-MM-DD days at most a year apart", http.StatusBadRequest)
+			return
+		}
+
+		statements, err := service.Statements(r.Context(), query.Get("tenant"), from, to)
+		if err != nil {
+			log.Printf("Error exporting usage: from=%s, to=%s, err=%v", query.Get("from"), query.Get("to"), err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"from":       query.Get("from"),
+			"to":         query.Get("to"),
+			"statements": statements,
+		})
+	}
+}
+
+// Middleware counts each request as an API call of its tenant. It goes
+// inside tenancy.Middleware.
+func Middleware(meter *Meter, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		meter.Add(tenancy.ID(r.Context()), APICalls, 1)
+		next.ServeHTTP(w, r)
+	})
+}
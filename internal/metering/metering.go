@@ -0,0 +1,159 @@
+// Package metering measures each tenant's use of the platform for
+// usage-based billing. WebSocket and API servers add usage to an in-memory
+// Meter that flushes per-day counters to Redis; the API server rolls the
+// counters up into daily rows in PostgreSQL, prices them into line items,
+// and exports them to the billing system.
+package metering
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Metered resources
+const (
+	// ConnectionMinutes is minutes of WebSocket connections
+	ConnectionMinutes = "connection_minutes"
+
+	// MessagesDelivered is WebSocket messages written to clients
+	MessagesDelivered = "messages_delivered"
+
+	// StorageGB is gigabytes of VODs and clips written to object storage
+	StorageGB = "storage_gb"
+
+	// APICalls is GraphQL API requests
+	APICalls = "api_calls"
+)
+
+// Metrics lists the metered resources in line item order
+var Metrics = []string{ConnectionMinutes, MessagesDelivered, StorageGB, APICalls}
+
+const (
+	// FlushInterval is how often a meter writes its counters to Redis
+	FlushInterval = 10 * time.Second
+
+	// keyPrefix namespaces the per-day usage hashes in Redis
+	keyPrefix = "metering:"
+
+	// counterRetention is how long a day's counters stay in Redis after it
+	// ends, giving the rollup time to copy them
+	counterRetention = 8 * 24 * time.Hour
+
+	// dayLayout formats days in keys and exports
+	dayLayout = "2006-01-02"
+)
+
+// Usage is a tenant's use of one resource on one day (UTC)
+type Usage struct {
+	TenantID string    `json:"tenant_id"`
+	Day      time.Time `json:"day"`
+	Metric   string    `json:"metric"`
+	Quantity float64   `json:"quantity"`
+}
+
+// counterKey identifies a meter's counter
+type counterKey struct {
+	day      string
+	tenantID string
+	metric   string
+}
+
+// Meter counts usage in memory and flushes it to Redis, so the metered
+// paths never wait on the network
+type Meter struct {
+	client *redis.Client
+
+	mu      sync.Mutex
+	pending map[counterKey]float64
+}
+
+// NewMeter creates a meter writing to client
+func NewMeter(client *redis.Client) *Meter {
+	return &Meter{
+		client:  client,
+		pending: make(map[counterKey]float64),
+	}
+}
+
+// Add records quantity of a metric used by a tenant today
+func (m *Meter) Add(tenantID, metric string, quantity float64) {
+	if quantity <= 0 {
+		return
+	}
+	key := counterKey{day: time.Now().UTC().Format(dayLayout), tenantID: tenantID, metric: metric}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.pending[key] += quantity
+}
+
+// Run flushes counters until ctx is cancelled, then flushes once more
+func (m *Meter) Run(ctx context.Context) {
+	ticker := time.NewTicker(FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			flushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			m.flush(flushCtx)
+			cancel()
+			return
+		case <-ticker.C:
+			m.flush(ctx)
+		}
+	}
+}
+
+// flush adds pending counters to each day's hash in one transaction, so
+// counters that fail to flush can be kept for the next attempt without
+// being counted twice
+func (m *Meter) flush(ctx context.Context) {
+	m.mu.Lock()
+	pending := m.pending
+	m.pending = make(map[counterKey]float64)
+	m.mu.Unlock()
+
+	if len(pending) == 0 {
+		return
+	}
+
+	pipe := m.client.TxPipeline()
+	days := make(map[string]bool)
+	for key, quantity := range pending {
+		pipe.HIncrByFloat(ctx, keyPrefix+key.day, field(key.tenantID, key.metric), quantity)
+		days[key.day] = true
+	}
+	for day := range days {
+		pipe.Expire(ctx, keyPrefix+day, counterRetention)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		log.Printf("Error flushing usage counters: %v", err)
+
+		m.mu.Lock()
+		for key, quantity := range pending {
+			m.pending[key] += quantity
+		}
+		m.mu.Unlock()
+	}
+}
+
+// field names a tenant's counter of a metric within a day's hash
+func field(tenantID, metric string) string {
+	return tenantID + "|" + metric
+}
+
+// parseField splits a counter field into its tenant and metric
+func parseField(f string) (tenantID, metric string, err error) {
+	tenantID, metric, ok := strings.Cut(f, "|")
+	if !ok {
+		return "", "", fmt.Errorf("malformed usage counter %q", f)
+	}
+	return tenantID, metric, nil
+}
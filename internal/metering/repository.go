@@ -0,0 +1,145 @@
+package metering
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/redis/go-redis/v9"
+)
+
+// Repository persists daily usage
+type Repository interface {
+	// Save stores usage, replacing any earlier quantity for the same
+	// tenant, day, and metric
+	Save(ctx context.Context, usage []Usage) error
+
+	// List returns the usage of days in [from, to], oldest first; an empty
+	// tenantID lists every tenant
+	List(ctx context.Context, tenantID string, from, to time.Time) ([]Usage, error)
+}
+
+// PostgresRepository implements Repository on PostgreSQL
+type PostgresRepository struct {
+	pool *pgxpool.Pool
+}
+
+// NewPostgresRepository creates a usage repository on pool
+func NewPostgresRepository(pool *pgxpool.Pool) *PostgresRepository {
+	return &PostgresRepository{pool: pool}
+}
+
+// Save upserts daily usage in one transaction
+func (r *PostgresRepository) Save(ctx context.Context, usage []Usage) error {
+	if len(usage) == 0 {
+		return nil
+	}
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	for _, u := range usage {
+		_, err := tx.Exec(ctx, `
+			INSERT INTO usage_daily (tenant_id, day, metric, quantity)
+			VALUES ($1, $2, $3, $4)
+			ON CONFLICT (tenant_id, day, metric) DO UPDATE SET quantity = EXCLUDED.quantity, updated_at = NOW()`,
+			u.TenantID, u.Day, u.Metric, u.Quantity)
+		if err != nil {
+			return fmt.Errorf("failed to save usage: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit usage: %w", err)
+	}
+	return nil
+}
+
+// List returns daily usage in a date range
+func (r *PostgresRepository) List(ctx context.Context, tenantID string, from, to time.Time) ([]Usage, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT tenant_id, day, metric, quantity FROM usage_daily
+		WHERE day BETWEEN $1 AND $2 AND ($3 = '' OR tenant_id = $3)
+		ORDER BY day, tenant_id, metric`, from, to, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list usage: %w", err)
+	}
+	defer rows.Close()
+
+	usage := []Usage{}
+	for rows.Next() {
+		var u Usage
+		if err := rows.Scan(&u.TenantID, &u.Day, &u.Metric, &u.Quantity); err != nil {
+			return nil, fmt.Errorf("failed to scan usage: %w", err)
+		}
+		usage = append(usage, u)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list usage: %w", err)
+	}
+	return usage, nil
+}
+
+// Rollup copies the meters' per-day counters from Redis into daily rows.
+// Copies replace earlier ones, so any number of API servers may roll up
+// concurrently.
+type Rollup struct {
+	client *redis.Client
+	repo   Repository
+}
+
+// NewRollup creates a rollup from client into repo
+func NewRollup(client *redis.Client, repo Repository) *Rollup {
+	return &Rollup{client: client, repo: repo}
+}
+
+// Run rolls up today and yesterday every interval until ctx is cancelled.
+// Yesterday is included so its last counters are copied after midnight.
+func (r *Rollup) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			today := time.Now().UTC().Truncate(24 * time.Hour)
+			for _, day := range []time.Time{today.AddDate(0, 0, -1), today} {
+				if err := r.RollUp(ctx, day); err != nil {
+					log.Printf("Error rolling up usage: day=%s, err=%v", day.Format(dayLayout), err)
+				}
+			}
+		}
+	}
+}
+
+// RollUp copies one day's counters
+func (r *Rollup) RollUp(ctx context.Context, day time.Time) error {
+	counters, err := r.client.HGetAll(ctx, keyPrefix+day.Format(dayLayout)).Result()
+	if err != nil {
+		return fmt.Errorf("failed to read usage counters: %w", err)
+	}
+
+	usage := make([]Usage, 0, len(counters))
+	for f, value := range counters {
+		tenantID, metric, err := parseField(f)
+		if err != nil {
+			log.Printf("Skipping usage counter: %v", err)
+			continue
+		}
+		quantity, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			log.Printf("Skipping usage counter: field=%s, err=%v", f, err)
+			continue
+		}
+		usage = append(usage, Usage{TenantID: tenantID, Day: day, Metric: metric, Quantity: quantity})
+	}
+	return r.repo.Save(ctx, usage)
+}
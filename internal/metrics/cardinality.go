@@ -0,0 +1,112 @@
+package metrics
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"sync"
+)
+
+// OtherLabel is the label value that absorbs everything past a limit
+const OtherLabel = "other"
+
+// CardinalityConfig bounds how many distinct values a label may take
+type CardinalityConfig struct {
+	// TopN is how many of the largest entries get their own series
+	TopN int
+
+	// MaxLabelValues caps distinct values a LabelLimiter will pass through
+	MaxLabelValues int
+
+	// HashLabels replaces label values (e.g. room names, user IDs) with a
+	// short stable hash so they don't leak identifiers or long strings
+	HashLabels bool
+}
+
+// DefaultCardinalityConfig returns limits suitable for a single Prometheus
+func DefaultCardinalityConfig() CardinalityConfig {
+	return CardinalityConfig{
+		TopN:           50,
+		MaxLabelValues: 500,
+		HashLabels:     false,
+	}
+}
+
+// Label applies the hashing setting to a label value
+func (c CardinalityConfig) Label(value string) string {
+	if c.HashLabels {
+		return HashLabel(value)
+	}
+	return value
+}
+
+// HashLabel returns a short, stable hash of value for use in labels and logs
+func HashLabel(value string) string {
+	h := fnv.New32a()
+	h.Write([]byte(value))
+	return fmt.Sprintf("h%08x", h.Sum32())
+}
+
+// Entry is a labelled count
+type Entry struct {
+	Label string
+	Count int
+}
+
+// TopN returns the n largest counts, largest first, and the sum of the rest
+func TopN(counts map[string]int, n int) ([]Entry, int) {
+	entries := make([]Entry, 0, len(counts))
+	for label, count := range counts {
+		entries = append(entries, Entry{Label: label, Count: count})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Count != entries[j].Count {
+			return entries[i].Count > entries[j].Count
+		}
+		return entries[i].Label < entries[j].Label
+	})
+
+	if n < 0 {
+		n = 0
+	}
+	if len(entries) <= n {
+		return entries, 0
+	}
+
+	other := 0
+	for _, entry := range entries[n:] {
+		other += entry.Count
+	}
+	return entries[:n], other
+}
+
+// LabelLimiter admits the first MaxLabelValues distinct values of a label
+// and maps every later value to OtherLabel, so counter vectors keyed by
+// unbounded values (rooms, categories) stay bounded
+type LabelLimiter struct {
+	config CardinalityConfig
+	seen   map[string]struct{}
+	mu     sync.Mutex
+}
+
+// NewLabelLimiter creates a limiter for one label
+func NewLabelLimiter(config CardinalityConfig) *LabelLimiter {
+	return &LabelLimiter{
+		config: config,
+		seen:   make(map[string]struct{}),
+	}
+}
+
+// Label returns the label value to record for value
+func (l *LabelLimiter) Label(value string) string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if _, ok := l.seen[value]; !ok {
+		if len(l.seen) >= l.config.MaxLabelValues {
+			return OtherLabel
+		}
+		l.seen[value] = struct{}{}
+	}
+	return l.config.Label(value)
+}
@@ -0,0 +1,143 @@
+package milestones
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/tinle0301/streaming-platform-api/internal/store"
+)
+
+// detectPageSize is how many live streams are read per page
+const detectPageSize = 100
+
+// ViewerSource supplies cluster-wide viewer counts
+type ViewerSource interface {
+	Counts(ctx context.Context, streamIDs []string) (map[string]int, error)
+}
+
+// Detector watches live streams' viewer counts and running time and
+// celebrates the thresholds they pass
+type Detector struct {
+	service *Service
+	streams store.StreamRepository
+	viewers ViewerSource
+
+	// Highest threshold each live stream reached per kind, so passed
+	// thresholds aren't claimed again every check; only Run's goroutine
+	// uses it
+	reached map[streamKind]int64
+}
+
+// streamKind keys a live stream's milestones of one kind
+type streamKind struct {
+	streamID string
+	kind     string
+}
+
+// NewDetector creates a milestone detector. Replicas sharing the service's
+// repository claim each milestone once, so only one of them celebrates it.
+func NewDetector(service *Service, streams store.StreamRepository, viewers ViewerSource) *Detector {
+	return &Detector{
+		service: service,
+		streams: streams,
+		viewers: viewers,
+		reached: make(map[streamKind]int64),
+	}
+}
+
+// RunOnce checks every live stream's viewer and duration milestones. It
+// returns how many streams were checked.
+func (d *Detector) RunOnce(ctx context.Context) (int, error) {
+	now := time.Now()
+	live := make(map[string]bool)
+	checked := 0
+
+	for offset := 0; ; offset += detectPageSize {
+		streams, total, err := d.streams.List(ctx, store.StreamFilter{
+			Status: store.StreamStatusLive,
+			Limit:  detectPageSize,
+			Offset: offset,
+		})
+		if err != nil {
+			return checked, err
+		}
+
+		ids := make([]string, 0, len(streams))
+		channelIDs := make([]string, 0, len(streams))
+		for _, stream := range streams {
+			ids = append(ids, stream.ID)
+			channelIDs = append(channelIDs, stream.StreamerID)
+		}
+		counts, err := d.viewers.Counts(ctx, ids)
+		if err != nil {
+			return checked, err
+		}
+		settings, err := d.service.repo.Settings(ctx, channelIDs)
+		if err != nil {
+			return checked, err
+		}
+
+		for _, stream := range streams {
+			live[stream.ID] = true
+			channelSettings, ok := settings[stream.StreamerID]
+			if !ok {
+				channelSettings = DefaultSettings(stream.StreamerID)
+			}
+			if !channelSettings.Enabled {
+				continue
+			}
+
+			target := target{tenantID: stream.TenantID, channelID: stream.StreamerID, streamID: stream.ID}
+			if err := d.check(ctx, target, KindViewers, int64(counts[stream.ID]), channelSettings); err != nil {
+				log.Printf("Error checking viewer milestones: streamID=%s, err=%v", stream.ID, err)
+			}
+			if stream.StartedAt != nil {
+				minutes := int64(now.Sub(*stream.StartedAt) / time.Minute)
+				if err := d.check(ctx, target, KindDuration, minutes, channelSettings); err != nil {
+					log.Printf("Error checking duration milestones: streamID=%s, err=%v", stream.ID, err)
+				}
+			}
+			checked++
+		}
+
+		if offset+len(streams) >= total || len(streams) == 0 {
+			break
+		}
+	}
+
+	// Streams that ended are forgotten
+	for key := range d.reached {
+		if !live[key.streamID] {
+			delete(d.reached, key)
+		}
+	}
+	return checked, nil
+}
+
+// Run checks every interval until ctx is done
+func (d *Detector) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := d.RunOnce(ctx); err != nil {
+				log.Printf("Stream milestone detection failed: err=%v", err)
+			}
+		}
+	}
+}
+
+// check claims the thresholds of kind that value passed since the last check
+func (d *Detector) check(ctx context.Context, target target, kind string, value int64, settings *Settings) error {
+	key := streamKind{streamID: target.streamID, kind: kind}
+	highest, err := d.service.reach(ctx, target, kind, value, settings.Thresholds(kind), d.reached[key])
+	if highest > 0 {
+		d.reached[key] = highest
+	}
+	return err
+}
@@ -0,0 +1,258 @@
+// Package milestones celebrates streams reaching viewer, follower, and
+// duration thresholds. A detector watches live streams' viewer counts and
+// running time, follows are checked as they arrive, and each milestone is
+// claimed once and published as a stream.milestone event to the stream's
+// room. Channels choose their own thresholds or use the defaults.
+package milestones
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/tinle0301/streaming-platform-api/internal/store"
+	"github.com/tinle0301/streaming-platform-api/internal/tenancy"
+)
+
+// Milestone kinds, matching the GraphQL MilestoneKind enum
+const (
+	KindViewers   = "VIEWERS"
+	KindFollowers = "FOLLOWERS"
+	KindDuration  = "DURATION"
+)
+
+// Default thresholds for channels that haven't chosen their own
+var (
+	DefaultViewerThresholds   = []int64{10, 50, 100, 500, 1_000, 5_000, 10_000, 50_000, 100_000}
+	DefaultFollowerThresholds = []int64{10, 50, 100, 500, 1_000, 5_000, 10_000, 50_000, 100_000, 500_000, 1_000_000}
+	DefaultDurationMinutes    = []int64{60, 120, 240, 480, 720, 1440}
+)
+
+// Settings are the thresholds a channel celebrates
+type Settings struct {
+	ChannelID          string
+	Enabled            bool
+	ViewerThresholds   []int64
+	FollowerThresholds []int64
+	DurationMinutes    []int64
+
+	// UpdatedAt is nil for channels using the defaults
+	UpdatedAt *time.Time
+}
+
+// DefaultSettings returns the settings of a channel that hasn't chosen its own
+func DefaultSettings(channelID string) *Settings {
+	return &Settings{
+		ChannelID:          channelID,
+		Enabled:            true,
+		ViewerThresholds:   DefaultViewerThresholds,
+		FollowerThresholds: DefaultFollowerThresholds,
+		DurationMinutes:    DefaultDurationMinutes,
+	}
+}
+
+// Thresholds returns the settings' thresholds for kind; durations are in
+// minutes
+func (s *Settings) Thresholds(kind string) []int64 {
+	switch kind {
+	case KindViewers:
+		return s.ViewerThresholds
+	case KindFollowers:
+		return s.FollowerThresholds
+	case KindDuration:
+		return s.DurationMinutes
+	default:
+		return nil
+	}
+}
+
+// Milestone is a threshold a stream or channel reached
+type Milestone struct {
+	ID        string
+	ChannelID string
+	StreamID  string
+	Kind      string
+	Threshold int64
+
+	// Value is the count or minutes when the milestone was detected
+	Value     int64
+	ReachedAt time.Time
+}
+
+// Repository errors
+var ErrNotFound = errors.New("milestone settings not found")
+
+// Repository persists channels' milestone settings and the milestones
+// reached. Reach is a conditional insert, so when several API replicas run
+// the detector only one of them celebrates each milestone. Records are
+// created in the context's tenant, and other tenants' are treated as missing.
+type Repository interface {
+	// Settings returns the channels' stored settings, keyed by channel;
+	// channels using the defaults are missing from the map
+	Settings(ctx context.Context, channelIDs []string) (map[string]*Settings, error)
+
+	// SaveSettings stores a channel's settings and fills in UpdatedAt
+	SaveSettings(ctx context.Context, settings *Settings) error
+
+	// Reach records a milestone and fills in its ID and timestamp. It
+	// reports false if the stream (or, for followers, the channel) already
+	// reached it.
+	Reach(ctx context.Context, milestone *Milestone) (bool, error)
+
+	// Reached lists a stream's milestones, newest first
+	Reached(ctx context.Context, streamID string) ([]*Milestone, error)
+
+	// FollowerCount counts a channel's followers
+	FollowerCount(ctx context.Context, channelID string) (int64, error)
+}
+
+// PostgresRepository implements Repository on PostgreSQL
+type PostgresRepository struct {
+	pool *pgxpool.Pool
+}
+
+// NewPostgresRepository creates a milestone repository on pool
+func NewPostgresRepository(pool *pgxpool.Pool) *PostgresRepository {
+	return &PostgresRepository{pool: pool}
+}
+
+// Settings reads the channels' settings rows
+func (r *PostgresRepository) Settings(ctx context.Context, channelIDs []string) (map[string]*Settings, error) {
+	ids := make([]string, 0, len(channelIDs))
+	for _, id := range channelIDs {
+		if store.IsUUID(id) {
+			ids = append(ids, id)
+		}
+	}
+	settings := make(map[string]*Settings, len(ids))
+	if len(ids) == 0 {
+		return settings, nil
+	}
+
+	rows, err := r.pool.Query(ctx, `
+		SELECT channel_id::text, enabled, viewer_thresholds, follower_thresholds, duration_minutes, updated_at
+		FROM milestone_settings
+		WHERE channel_id = ANY($1::uuid[])`+tenancy.Condition("tenant_id", 2), ids, tenancy.Scope(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get milestone settings: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var s Settings
+		var updatedAt time.Time
+		if err := rows.Scan(&s.ChannelID, &s.Enabled, &s.ViewerThresholds, &s.FollowerThresholds,
+			&s.DurationMinutes, &updatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan milestone settings: %w", err)
+		}
+		s.UpdatedAt = &updatedAt
+		settings[s.ChannelID] = &s
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read milestone settings: %w", err)
+	}
+	return settings, nil
+}
+
+// SaveSettings upserts a channel's settings row
+func (r *PostgresRepository) SaveSettings(ctx context.Context, settings *Settings) error {
+	if !store.IsUUID(settings.ChannelID) {
+		return ErrNotFound
+	}
+
+	var updatedAt time.Time
+	err := r.pool.QueryRow(ctx, `
+		INSERT INTO milestone_settings (channel_id, enabled, viewer_thresholds, follower_thresholds, duration_minutes,
+			tenant_id)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (channel_id) DO UPDATE SET
+			enabled = EXCLUDED.enabled, viewer_thresholds = EXCLUDED.viewer_thresholds,
+			follower_thresholds = EXCLUDED.follower_thresholds, duration_minutes = EXCLUDED.duration_minutes,
+			updated_at = NOW()
+		WHERE milestone_settings.tenant_id = EXCLUDED.tenant_id
+		RETURNING updated_at`,
+		settings.ChannelID, settings.Enabled, settings.ViewerThresholds, settings.FollowerThresholds,
+		settings.DurationMinutes, tenancy.ID(ctx)).Scan(&updatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		// Another tenant's channel
+		return ErrNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to save milestone settings: %w", err)
+	}
+	settings.UpdatedAt = &updatedAt
+	return nil
+}
+
+// Reach inserts a milestone unless its unique index already holds it
+func (r *PostgresRepository) Reach(ctx context.Context, milestone *Milestone) (bool, error) {
+	var streamID *string
+	if milestone.StreamID != "" {
+		streamID = &milestone.StreamID
+	}
+
+	conflict := `(stream_id, kind, threshold) WHERE kind <> 'FOLLOWERS'`
+	if milestone.Kind == KindFollowers {
+		conflict = `(channel_id, threshold) WHERE kind = 'FOLLOWERS'`
+	}
+	err := r.pool.QueryRow(ctx, `
+		INSERT INTO stream_milestones (channel_id, stream_id, kind, threshold, value, tenant_id)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT `+conflict+` DO NOTHING
+		RETURNING id::text, reached_at`,
+		milestone.ChannelID, streamID, milestone.Kind, milestone.Threshold, milestone.Value, tenancy.ID(ctx),
+	).Scan(&milestone.ID, &milestone.ReachedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to record milestone: %w", err)
+	}
+	return true, nil
+}
+
+// Reached lists the milestones recorded against a stream
+func (r *PostgresRepository) Reached(ctx context.Context, streamID string) ([]*Milestone, error) {
+	if !store.IsUUID(streamID) {
+		return []*Milestone{}, nil
+	}
+
+	rows, err := r.pool.Query(ctx, `
+		SELECT id::text, channel_id::text, stream_id::text, kind, threshold, value, reached_at
+		FROM stream_milestones
+		WHERE stream_id = $1`+tenancy.Condition("tenant_id", 2)+`
+		ORDER BY reached_at DESC, threshold DESC`, streamID, tenancy.Scope(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list milestones: %w", err)
+	}
+	defer rows.Close()
+
+	milestones := []*Milestone{}
+	for rows.Next() {
+		var m Milestone
+		if err := rows.Scan(&m.ID, &m.ChannelID, &m.StreamID, &m.Kind, &m.Threshold, &m.Value, &m.ReachedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan milestone: %w", err)
+		}
+		milestones = append(milestones, &m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read milestones: %w", err)
+	}
+	return milestones, nil
+}
+
+// FollowerCount counts the channel's rows in follows
+func (r *PostgresRepository) FollowerCount(ctx context.Context, channelID string) (int64, error) {
+	if !store.IsUUID(channelID) {
+		return 0, nil
+	}
+
+	var count int64
+	if err := r.pool.QueryRow(ctx, `SELECT COUNT(*) FROM follows WHERE followed_id = $1`, channelID).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count followers: %w", err)
+	}
+	return count, nil
+}
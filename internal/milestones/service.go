@@ -0,0 +1,250 @@
+package milestones
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+
+	"github.com/tinle0301/streaming-platform-api/internal/events"
+	"github.com/tinle0301/streaming-platform-api/internal/store"
+	"github.com/tinle0301/streaming-platform-api/internal/tenancy"
+)
+
+// Threshold limits
+const (
+	MaxThresholds      = 20
+	MaxThreshold       = 1_000_000_000
+	MaxDurationMinutes = 7 * 24 * 60
+)
+
+// Validation errors
+var (
+	ErrTooManyThresholds = errors.New("at most 20 thresholds of each kind")
+	ErrInvalidThreshold  = errors.New("thresholds must be between 1 and 1000000000")
+	ErrInvalidDuration   = errors.New("duration milestones must be between 1 and 10080 minutes")
+)
+
+// Service manages channels' milestone settings and celebrates the
+// milestones their streams reach
+type Service struct {
+	repo    Repository
+	streams store.StreamRepository
+
+	publisher events.Publisher
+}
+
+// NewService creates a milestone service
+func NewService(repo Repository, streams store.StreamRepository) *Service {
+	return &Service{repo: repo, streams: streams}
+}
+
+// SetPublisher enables stream.milestone events for the streams' rooms
+func (s *Service) SetPublisher(publisher events.Publisher) {
+	s.publisher = publisher
+}
+
+// Settings returns a channel's settings, or the defaults if it has none
+func (s *Service) Settings(ctx context.Context, channelID string) (*Settings, error) {
+	stored, err := s.repo.Settings(ctx, []string{channelID})
+	if err != nil {
+		return nil, err
+	}
+	if settings, ok := stored[channelID]; ok {
+		return settings, nil
+	}
+	return DefaultSettings(channelID), nil
+}
+
+// UpdateSettings validates and stores a channel's settings. Thresholds are
+// sorted and deduplicated; an empty list turns that kind of milestone off.
+func (s *Service) UpdateSettings(ctx context.Context, settings *Settings) error {
+	var err error
+	if settings.ViewerThresholds, err = normalize(settings.ViewerThresholds, MaxThreshold, ErrInvalidThreshold); err != nil {
+		return err
+	}
+	if settings.FollowerThresholds, err = normalize(settings.FollowerThresholds, MaxThreshold, ErrInvalidThreshold); err != nil {
+		return err
+	}
+	if settings.DurationMinutes, err = normalize(settings.DurationMinutes, MaxDurationMinutes, ErrInvalidDuration); err != nil {
+		return err
+	}
+	return s.repo.SaveSettings(ctx, settings)
+}
+
+// Reached lists the milestones a stream reached, newest first
+func (s *Service) Reached(ctx context.Context, streamID string) ([]*Milestone, error) {
+	return s.repo.Reached(ctx, streamID)
+}
+
+// HandleEvent checks a channel's follower milestones when it gains a
+// follower. The celebration goes to the channel's live stream, or to the
+// channel itself while it is offline.
+func (s *Service) HandleEvent(ctx context.Context, event events.Event) error {
+	if event.Type != events.EventTypeNewFollower || event.UserID == "" {
+		return nil
+	}
+	channelID := event.UserID
+
+	settings, err := s.Settings(ctx, channelID)
+	if err != nil || !settings.Enabled || len(settings.FollowerThresholds) == 0 {
+		return err
+	}
+	followers, err := s.repo.FollowerCount(ctx, channelID)
+	if err != nil || followers < settings.FollowerThresholds[0] {
+		return err
+	}
+
+	target := target{tenantID: event.TenantID, channelID: channelID}
+	streams, _, err := s.streams.List(ctx, store.StreamFilter{
+		StreamerID: channelID,
+		Status:     store.StreamStatusLive,
+		Limit:      1,
+	})
+	if err != nil {
+		return err
+	}
+	if len(streams) > 0 {
+		target.tenantID, target.streamID = streams[0].TenantID, streams[0].ID
+	}
+
+	_, err = s.reach(ctx, target, KindFollowers, followers, settings.FollowerThresholds, 0)
+	return err
+}
+
+// target is where a milestone is recorded and celebrated
+type target struct {
+	tenantID  string
+	channelID string
+
+	// streamID is empty for follower milestones of offline channels
+	streamID string
+}
+
+// reach claims the thresholds above after that value has reached and
+// celebrates the highest one this caller claimed. It returns the highest
+// threshold reached so far, claimed by this caller or not.
+func (s *Service) reach(ctx context.Context, target target, kind string, value int64, thresholds []int64, after int64) (int64, error) {
+	// Milestones are recorded in the tenant of the stream or channel
+	if target.tenantID != "" {
+		ctx = tenancy.WithTenant(ctx, target.tenantID)
+	}
+
+	highest := after
+	var newest *Milestone
+	for _, threshold := range thresholds {
+		if threshold <= after {
+			continue
+		}
+		if threshold > value {
+			break
+		}
+
+		milestone := &Milestone{
+			ChannelID: target.channelID,
+			StreamID:  target.streamID,
+			Kind:      kind,
+			Threshold: threshold,
+			Value:     value,
+		}
+		claimed, err := s.repo.Reach(ctx, milestone)
+		if err != nil {
+			return highest, err
+		}
+		if claimed {
+			newest = milestone
+		}
+		highest = threshold
+	}
+
+	// Milestones passed together, e.g. when a stream starts with a crowd,
+	// are celebrated once, for the highest
+	if newest != nil {
+		s.publish(ctx, target.tenantID, newest)
+	}
+	return highest, nil
+}
+
+// publish celebrates a milestone. It is already recorded, so a lost event
+// only means the room misses the celebration.
+func (s *Service) publish(ctx context.Context, tenantID string, milestone *Milestone) {
+	if s.publisher == nil {
+		return
+	}
+	if tenantID != "" {
+		ctx = tenancy.WithTenant(ctx, tenantID)
+	}
+
+	event := events.NewStreamMilestoneEvent(milestone.StreamID, milestone.ChannelID, map[string]interface{}{
+		"milestone_id": milestone.ID,
+		"kind":         milestone.Kind,
+		"threshold":    milestone.Threshold,
+		"value":        milestone.Value,
+		"message":      Message(milestone.Kind, milestone.Threshold),
+	})
+	if err := s.publisher.Publish(ctx, event); err != nil {
+		log.Printf("Error publishing stream.milestone event: channelID=%s, kind=%s, threshold=%d, err=%v",
+			milestone.ChannelID, milestone.Kind, milestone.Threshold, err)
+	}
+}
+
+// Message is the celebration shown in the room for a milestone
+func Message(kind string, threshold int64) string {
+	switch kind {
+	case KindViewers:
+		return formatCount(threshold) + " viewers are watching!"
+	case KindFollowers:
+		return formatCount(threshold) + " followers!"
+	case KindDuration:
+		if threshold%60 == 0 {
+			hours := threshold / 60
+			if hours == 1 {
+				return "Live for an hour!"
+			}
+			return fmt.Sprintf("Live for %d hours!", hours)
+		}
+		return fmt.Sprintf("Live for %d minutes!", threshold)
+	default:
+		return "Milestone reached!"
+	}
+}
+
+// formatCount writes n with thousands separators
+func formatCount(n int64) string {
+	digits := strconv.FormatInt(n, 10)
+	formatted := make([]byte, 0, len(digits)+len(digits)/3)
+	for i := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			formatted = append(formatted, ',')
+		}
+		formatted = append(formatted, digits[i])
+	}
+	return string(formatted)
+}
+
+// normalize sorts and deduplicates thresholds, checking they are in
+// [1, limit]
+func normalize(thresholds []int64, limit int64, invalid error) ([]int64, error) {
+	if len(thresholds) > MaxThresholds {
+		return nil, ErrTooManyThresholds
+	}
+
+	normalized := make([]int64, 0, len(thresholds))
+	for _, threshold := range thresholds {
+		if threshold < 1 || threshold > limit {
+			return nil, invalid
+		}
+		normalized = append(normalized, threshold)
+	}
+	sort.Slice(normalized, func(i, j int) bool { return normalized[i] < normalized[j] })
+
+	unique := normalized[:0]
+	for _, threshold := range normalized {
+		if len(unique) == 0 || threshold != unique[len(unique)-1] {
+			unique = append(unique, threshold)
+		}
+	}
+	return unique, nil
+}
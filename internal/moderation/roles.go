@@ -0,0 +1,239 @@
+package moderation
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/tinle0301/streaming-platform-api/internal/store"
+	"github.com/tinle0301/streaming-platform-api/internal/tenancy"
+)
+
+// Role is a user's elevated role within a channel
+type Role string
+
+// Channel roles
+const (
+	RoleStreamer  Role = "streamer"
+	RoleModerator Role = "moderator"
+)
+
+// ChannelRoles tracks streamers and moderators per channel.
+//
+// A stream's room belongs to the channel broadcasting it: the stream's
+// owner is its streamer, and roles granted in the channel (its user ID)
+// apply in it, as do roles granted in the room itself. Grants are kept in
+// PostgreSQL, so they survive restarts and apply on every WebSocket node.
+// Lookups only read this node's copy, which Run reloads along with the
+// owners of rooms looked up since the last reload.
+type ChannelRoles struct {
+	pool *pgxpool.Pool
+
+	mu sync.Mutex
+	// channel -> userID -> role; a channel is a channel's user ID or a room
+	roles map[string]map[string]Role
+	// room -> channel streaming in it, and the rooms looked up since the
+	// last reload
+	owners map[string]string
+	recent map[string]bool
+}
+
+// NewChannelRoles creates a role list on pool
+func NewChannelRoles(pool *pgxpool.Pool) *ChannelRoles {
+	return &ChannelRoles{
+		pool:   pool,
+		roles:  make(map[string]map[string]Role),
+		owners: make(map[string]string),
+		recent: make(map[string]bool),
+	}
+}
+
+// Grant gives userID a role in channel, replacing any previous role
+func (r *ChannelRoles) Grant(ctx context.Context, channel, userID string, role Role) error {
+	_, err := r.pool.Exec(ctx, `
+		INSERT INTO channel_roles (channel, user_id, role) VALUES ($1, $2, $3)
+		ON CONFLICT (channel, user_id) DO UPDATE SET role = EXCLUDED.role, created_at = NOW()`,
+		channel, userID, string(role))
+	if err != nil {
+		return fmt.Errorf("failed to grant channel role: %w", err)
+	}
+
+	// This node applies it now; others on their next reload
+	r.mu.Lock()
+	if r.roles[channel] == nil {
+		r.roles[channel] = make(map[string]Role)
+	}
+	r.roles[channel][userID] = role
+	r.mu.Unlock()
+	return nil
+}
+
+// Revoke removes userID's role in channel; it reports whether a role was removed
+func (r *ChannelRoles) Revoke(ctx context.Context, channel, userID string) (bool, error) {
+	tag, err := r.pool.Exec(ctx, `DELETE FROM channel_roles WHERE channel = $1 AND user_id = $2`, channel, userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to revoke channel role: %w", err)
+	}
+
+	r.mu.Lock()
+	delete(r.roles[channel], userID)
+	if len(r.roles[channel]) == 0 {
+		delete(r.roles, channel)
+	}
+	r.mu.Unlock()
+	return tag.RowsAffected() > 0, nil
+}
+
+// RoleOf returns userID's role in channel, if any. In a stream's room the
+// stream's owner is the streamer.
+func (r *ChannelRoles) RoleOf(channel, userID string) (Role, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.recent[channel] = true
+	if role, ok := r.roles[channel][userID]; ok {
+		return role, true
+	}
+	owner, ok := r.owners[channel]
+	if !ok {
+		return "", false
+	}
+	if owner == userID {
+		return RoleStreamer, true
+	}
+	role, ok := r.roles[owner][userID]
+	return role, ok
+}
+
+// IsPrivileged reports whether userID is the streamer or a moderator of channel
+func (r *ChannelRoles) IsPrivileged(channel, userID string) bool {
+	_, ok := r.RoleOf(channel, userID)
+	return ok
+}
+
+// IsStreamer reports whether userID is the streamer of channel
+func (r *ChannelRoles) IsStreamer(channel, userID string) bool {
+	role, ok := r.RoleOf(channel, userID)
+	return ok && role == RoleStreamer
+}
+
+// List returns the role of every user granted one in channel
+func (r *ChannelRoles) List(ctx context.Context, channel string) (map[string]Role, error) {
+	rows, err := r.pool.Query(ctx, `SELECT user_id, role FROM channel_roles WHERE channel = $1`, channel)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list channel roles: %w", err)
+	}
+	defer rows.Close()
+
+	roles := make(map[string]Role)
+	for rows.Next() {
+		var userID, role string
+		if err := rows.Scan(&userID, &role); err != nil {
+			return nil, fmt.Errorf("failed to scan channel role: %w", err)
+		}
+		roles[userID] = Role(role)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read channel roles: %w", err)
+	}
+	return roles, nil
+}
+
+// Run loads the grants, then reloads them and the owners of recently
+// looked up rooms every interval until ctx is cancelled. A failed reload
+// keeps the previous copy.
+func (r *ChannelRoles) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := r.reload(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("Error reloading channel roles: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// reload replaces this node's copy of the grants and room owners
+func (r *ChannelRoles) reload(ctx context.Context) error {
+	roles, err := r.loadRoles(ctx)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	recent := r.recent
+	r.recent = make(map[string]bool)
+	r.mu.Unlock()
+
+	owners, err := r.loadOwners(ctx, recent)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	r.roles = roles
+	r.owners = owners
+	r.mu.Unlock()
+	return nil
+}
+
+// loadRoles reads every grant
+func (r *ChannelRoles) loadRoles(ctx context.Context) (map[string]map[string]Role, error) {
+	rows, err := r.pool.Query(ctx, `SELECT channel, user_id, role FROM channel_roles`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	roles := make(map[string]map[string]Role)
+	for rows.Next() {
+		var channel, userID, role string
+		if err := rows.Scan(&channel, &userID, &role); err != nil {
+			return nil, err
+		}
+		if roles[channel] == nil {
+			roles[channel] = make(map[string]Role)
+		}
+		roles[channel][userID] = Role(role)
+	}
+	return roles, rows.Err()
+}
+
+// loadOwners reads the channel streaming in each of rooms that is a
+// stream's room
+func (r *ChannelRoles) loadOwners(ctx context.Context, rooms map[string]bool) (map[string]string, error) {
+	owners := make(map[string]string, len(rooms))
+	ids := make([]string, 0, len(rooms))
+	for room := range rooms {
+		if _, streamID := tenancy.SplitRoom(room); store.IsUUID(streamID) {
+			ids = append(ids, streamID)
+		}
+	}
+	if len(ids) == 0 {
+		return owners, nil
+	}
+
+	rows, err := r.pool.Query(ctx, `
+		SELECT id::text, streamer_id, tenant_id FROM streams WHERE id = ANY($1::uuid[])`, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var streamID, streamerID, tenantID string
+		if err := rows.Scan(&streamID, &streamerID, &tenantID); err != nil {
+			return nil, err
+		}
+		owners[tenancy.Room(tenantID, streamID)] = streamerID
+	}
+	return owners, rows.Err()
+}
@@ -0,0 +1,207 @@
+package moderation
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/tinle0301/streaming-platform-api/internal/audit"
+)
+
+// PlatformWide is the channel value used for platform-wide shadow bans
+const PlatformWide = ""
+
+// ShadowBan describes a single shadow ban record
+type ShadowBan struct {
+	UserID    string    `json:"user_id"`
+	Channel   string    `json:"channel,omitempty"`
+	ActorID   string    `json:"actor_id"`
+	Reason    string    `json:"reason,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ShadowBanList tracks shadow-banned users per channel and platform-wide.
+//
+// A shadow-banned user's chat messages are echoed back only to themselves;
+// everyone else in the room never sees them. Bans are kept in PostgreSQL,
+// so they survive restarts and apply on every WebSocket node; each node
+// checks chat against its copy, reloaded by Run.
+type ShadowBanList struct {
+	pool     *pgxpool.Pool
+	auditLog audit.Logger
+
+	mu sync.RWMutex
+	// channel -> userID -> ban ("" is platform-wide)
+	bans map[string]map[string]ShadowBan
+}
+
+// NewShadowBanList creates a shadow ban list on pool that records changes
+// to auditLog
+func NewShadowBanList(pool *pgxpool.Pool, auditLog audit.Logger) *ShadowBanList {
+	return &ShadowBanList{
+		pool:     pool,
+		auditLog: auditLog,
+		bans:     make(map[string]map[string]ShadowBan),
+	}
+}
+
+// Ban shadow-bans a user in a channel, or platform-wide if channel is PlatformWide
+func (l *ShadowBanList) Ban(ctx context.Context, channel, userID, actorID, reason string) error {
+	ban := ShadowBan{
+		UserID:  userID,
+		Channel: channel,
+		ActorID: actorID,
+		Reason:  reason,
+	}
+	err := l.pool.QueryRow(ctx, `
+		INSERT INTO shadow_bans (channel, user_id, actor_id, reason) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (channel, user_id) DO UPDATE SET
+			actor_id = EXCLUDED.actor_id, reason = EXCLUDED.reason, created_at = NOW()
+		RETURNING created_at`, channel, userID, actorID, reason).Scan(&ban.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save shadow ban: %w", err)
+	}
+
+	// This node applies it now; others on their next reload
+	l.mu.Lock()
+	if l.bans[channel] == nil {
+		l.bans[channel] = make(map[string]ShadowBan)
+	}
+	l.bans[channel][userID] = ban
+	l.mu.Unlock()
+
+	l.record(ctx, audit.ActionShadowBan, channel, userID, actorID, reason)
+	return nil
+}
+
+// Unban lifts a shadow ban; it reports whether a ban was removed
+func (l *ShadowBanList) Unban(ctx context.Context, channel, userID, actorID string) (bool, error) {
+	tag, err := l.pool.Exec(ctx, `DELETE FROM shadow_bans WHERE channel = $1 AND user_id = $2`, channel, userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete shadow ban: %w", err)
+	}
+
+	l.mu.Lock()
+	delete(l.bans[channel], userID)
+	if len(l.bans[channel]) == 0 {
+		delete(l.bans, channel)
+	}
+	l.mu.Unlock()
+
+	if tag.RowsAffected() == 0 {
+		return false, nil
+	}
+	l.record(ctx, audit.ActionShadowUnban, channel, userID, actorID, "")
+	return true, nil
+}
+
+// IsShadowBanned reports whether userID is shadow-banned in channel or
+// platform-wide. It only reads this node's copy of the bans.
+func (l *ShadowBanList) IsShadowBanned(channel, userID string) bool {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	if _, ok := l.bans[PlatformWide][userID]; ok {
+		return true
+	}
+	_, ok := l.bans[channel][userID]
+	return ok
+}
+
+// List returns all shadow bans for a channel (PlatformWide for global bans)
+func (l *ShadowBanList) List(ctx context.Context, channel string) ([]ShadowBan, error) {
+	rows, err := l.pool.Query(ctx, `
+		SELECT channel, user_id, actor_id, reason, created_at FROM shadow_bans
+		WHERE channel = $1 ORDER BY created_at`, channel)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list shadow bans: %w", err)
+	}
+	defer rows.Close()
+
+	bans := []ShadowBan{}
+	for rows.Next() {
+		var ban ShadowBan
+		if err := rows.Scan(&ban.Channel, &ban.UserID, &ban.ActorID, &ban.Reason, &ban.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan shadow ban: %w", err)
+		}
+		bans = append(bans, ban)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read shadow bans: %w", err)
+	}
+	return bans, nil
+}
+
+// Run loads the bans, then reloads them every interval until ctx is
+// cancelled, picking up bans made on other nodes. A failed reload keeps
+// the previous copy.
+func (l *ShadowBanList) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := l.reload(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("Error reloading shadow bans: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// reload replaces this node's copy of the bans
+func (l *ShadowBanList) reload(ctx context.Context) error {
+	rows, err := l.pool.Query(ctx, `SELECT channel, user_id, actor_id, reason, created_at FROM shadow_bans`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	bans := make(map[string]map[string]ShadowBan)
+	for rows.Next() {
+		var ban ShadowBan
+		if err := rows.Scan(&ban.Channel, &ban.UserID, &ban.ActorID, &ban.Reason, &ban.CreatedAt); err != nil {
+			return err
+		}
+		if bans[ban.Channel] == nil {
+			bans[ban.Channel] = make(map[string]ShadowBan)
+		}
+		bans[ban.Channel][ban.UserID] = ban
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	l.bans = bans
+	l.mu.Unlock()
+	return nil
+}
+
+// record writes an audit entry for a shadow ban change
+func (l *ShadowBanList) record(ctx context.Context, action, channel, userID, actorID, reason string) {
+	if l.auditLog == nil {
+		return
+	}
+
+	scope := channel
+	if scope == PlatformWide {
+		scope = "platform"
+	}
+
+	entry := audit.Entry{
+		Action:   action,
+		ActorID:  actorID,
+		TargetID: userID,
+		Resource: scope,
+		Reason:   reason,
+	}
+	if err := l.auditLog.Record(ctx, entry); err != nil {
+		log.Printf("Error recording audit entry: %v", err)
+	}
+}
@@ -0,0 +1,218 @@
+package notifications
+
+import (
+	"context"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tinle0301/streaming-platform-api/internal/events"
+)
+
+// FanOutOptions tunes how notifications reach many users at once
+type FanOutOptions struct {
+	// Channels with at least ReadThreshold followers fan out on read: their
+	// go-live is stored once and copied into each follower's notifications
+	// when they next read them. Smaller channels fan out on write, a row
+	// and a push per follower.
+	ReadThreshold int
+
+	// BatchSize is how many followers each write fan-out batch covers, and
+	// Workers how many batches are stored and pushed at once
+	BatchSize int
+	Workers   int
+
+	// DigestWindow is how long rapid repeats of a notification are merged
+	// into the user's unread one instead of notifying again
+	DigestWindow time.Duration
+
+	// AnnouncementMaxAge bounds how far back a user's first read copies
+	// announcements
+	AnnouncementMaxAge time.Duration
+}
+
+// DefaultFanOutOptions returns the options used when none are configured
+func DefaultFanOutOptions() FanOutOptions {
+	return FanOutOptions{
+		ReadThreshold:      10000,
+		BatchSize:          500,
+		Workers:            4,
+		DigestWindow:       2 * time.Minute,
+		AnnouncementMaxAge: 7 * 24 * time.Hour,
+	}
+}
+
+// digestRule describes how repeats of a notification type are merged
+type digestRule struct {
+	// sameSender only merges notifications from the same user
+	sameSender bool
+
+	// verb ends the merged message, e.g. "Ana and 3 others followed you";
+	// without one the newest message is kept
+	verb string
+}
+
+// digestRules are the notification types merged when they repeat quickly
+var digestRules = map[string]digestRule{
+	TypeNewFollower:   {verb: "followed you"},
+	TypeSubscription:  {verb: "subscribed"},
+	TypeStreamLive:    {sameSender: true},
+	TypeDirectMessage: {sameSender: true},
+}
+
+// digest merges n into the user's recent unread notification of its type,
+// reporting whether it was merged
+func (s *Service) digest(ctx context.Context, n *Notification, actor string) (bool, error) {
+	rule, ok := digestRules[n.Type]
+	if !ok || s.opts.DigestWindow <= 0 {
+		return false, nil
+	}
+
+	// The format's verbs take the number of others merged and its plural
+	// suffix; names can't add verbs of their own
+	format := strings.ReplaceAll(n.Message, "%", "%%")
+	if rule.verb != "" {
+		format = strings.ReplaceAll(actor, "%", "%%") + " and %s other%s " + rule.verb
+	}
+	return s.repo.Digest(ctx, n, time.Now().Add(-s.opts.DigestWindow), rule.sameSender, format)
+}
+
+// announceLive notifies a channel's followers that it went live, fanning out
+// on write or on read by its follower count
+func (s *Service) announceLive(ctx context.Context, event events.Event) error {
+	title := dataString(event.Data, "title")
+	if title == "" {
+		title = "A channel you follow went live"
+	}
+	n := &Notification{
+		Type:          TypeStreamLive,
+		Title:         "Live now",
+		Message:       title,
+		Data:          event.Data,
+		FromUserID:    event.UserID,
+		StreamID:      event.StreamID,
+		SourceEventID: event.ID,
+	}
+
+	followers, err := s.repo.FollowerCount(ctx, event.UserID)
+	if err != nil || followers == 0 {
+		return err
+	}
+	if followers >= s.opts.ReadThreshold {
+		created, err := s.repo.CreateAnnouncement(ctx, n)
+		if err != nil || !created {
+			return err
+		}
+		log.Printf("Go-live announced for fan-out on read: channelID=%s, followers=%d", event.UserID, followers)
+		return nil
+	}
+	return s.fanOut(ctx, n)
+}
+
+// fanOut stores and pushes a copy of n for every follower of its sender.
+// A producer pages through the followers while workers store and push each
+// page as one batch. Followers with an unread go-live from the channel
+// within the digest window are skipped.
+func (s *Service) fanOut(ctx context.Context, n *Notification) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var digestSince time.Time
+	if s.opts.DigestWindow > 0 {
+		digestSince = time.Now().Add(-s.opts.DigestWindow)
+	}
+
+	batches := make(chan []string)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var firstErr error
+	delivered := 0
+
+	for i := 0; i < s.opts.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for userIDs := range batches {
+				created, err := s.repo.CreateMany(ctx, n, userIDs, digestSince)
+				if err == nil {
+					s.pushBatch(ctx, created)
+				}
+
+				mu.Lock()
+				if err != nil && firstErr == nil {
+					firstErr = err
+					cancel()
+				}
+				delivered += len(created)
+				mu.Unlock()
+			}
+		}()
+	}
+
+	var pageErr error
+	after := ""
+	for {
+		userIDs, err := s.repo.FollowerIDs(ctx, n.FromUserID, after, s.opts.BatchSize)
+		if err != nil {
+			pageErr = err
+			break
+		}
+		if len(userIDs) == 0 {
+			break
+		}
+		select {
+		case batches <- userIDs:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil || len(userIDs) < s.opts.BatchSize {
+			break
+		}
+		after = userIDs[len(userIDs)-1]
+	}
+	close(batches)
+	wg.Wait()
+
+	if firstErr != nil {
+		return firstErr
+	}
+	if pageErr != nil {
+		return pageErr
+	}
+	log.Printf("Go-live fanned out on write: channelID=%s, delivered=%d", n.FromUserID, delivered)
+	return nil
+}
+
+// pushBatch publishes a batch of new notifications in one call. They are
+// already stored, so a lost batch only means users see them on next fetch.
+func (s *Service) pushBatch(ctx context.Context, created []*Notification) {
+	if s.publisher == nil || len(created) == 0 {
+		return
+	}
+
+	batch := make([]events.Event, 0, len(created))
+	for _, n := range created {
+		batch = append(batch, events.NewNotificationCreatedEvent(n.UserID, pushData(n)))
+	}
+	if err := s.publisher.PublishBatch(ctx, batch); err != nil {
+		log.Printf("Error publishing notification batch: size=%d, err=%v", len(batch), err)
+	}
+}
+
+// validateFanOut fills in unusable options with defaults
+func validateFanOut(opts FanOutOptions) FanOutOptions {
+	defaults := DefaultFanOutOptions()
+	if opts.ReadThreshold <= 0 {
+		opts.ReadThreshold = defaults.ReadThreshold
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = defaults.BatchSize
+	}
+	if opts.Workers <= 0 {
+		opts.Workers = defaults.Workers
+	}
+	if opts.AnnouncementMaxAge <= 0 {
+		opts.AnnouncementMaxAge = defaults.AnnouncementMaxAge
+	}
+	return opts
+}
@@ -0,0 +1,493 @@
+package notifications
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/tinle0301/streaming-platform-api/internal/store"
+	"github.com/tinle0301/streaming-platform-api/internal/tenancy"
+)
+
+// Notification types, matching the GraphQL NotificationType enum
+const (
+	TypeStreamLive       = "STREAM_LIVE"
+	TypeNewFollower      = "NEW_FOLLOWER"
+	TypeRaidIncoming     = "RAID_INCOMING"
+	TypeRaidOutgoing     = "RAID_OUTGOING"
+	TypeSubscription     = "SUBSCRIPTION"
+	TypeGiftSubscription = "GIFT_SUBSCRIPTION"
+	TypeDirectMessage    = "DIRECT_MESSAGE"
+)
+
+// Repository errors
+var (
+	ErrNotFound         = errors.New("notification not found")
+	ErrMissingReference = errors.New("notification refers to a user or stream that does not exist")
+)
+
+// Notification is a message delivered to a user
+type Notification struct {
+	ID         string
+	UserID     string
+	Type       string
+	Title      string
+	Message    string
+	Data       map[string]interface{}
+	FromUserID string
+	StreamID   string
+	CreatedAt  time.Time
+	ReadAt     *time.Time
+
+	// SourceEventID is the event the notification was created from, if any
+	SourceEventID string
+
+	// DigestCount is how many events were merged into the notification
+	DigestCount int
+}
+
+// Read reports whether the user has read the notification
+func (n *Notification) Read() bool {
+	return n.ReadAt != nil
+}
+
+// Repository persists notifications. Notifications and announcements are
+// created in the context's tenant, and reads and updates only see the
+// context's tenant's.
+type Repository interface {
+	// Create stores a notification and fills in its generated ID and
+	// timestamp. It reports false, storing nothing, if the user was already
+	// notified of the same source event.
+	Create(ctx context.Context, n *Notification) (bool, error)
+
+	// List returns a user's notifications, newest first
+	List(ctx context.Context, userID string, limit int, unreadOnly bool) ([]*Notification, error)
+
+	// MarkRead marks one of a user's notifications read
+	MarkRead(ctx context.Context, userID, id string) (*Notification, error)
+
+	// MarkAllRead marks all of a user's notifications read and returns how
+	// many were unread
+	MarkAllRead(ctx context.Context, userID string) (int, error)
+
+	// Digest merges n into the user's newest unread notification of the
+	// same type created since the given time, from the same sender if
+	// sameSender is set. The merged notification's message becomes
+	// messageFormat with the count of other merged events. It reports false,
+	// changing nothing, if there is no such notification.
+	Digest(ctx context.Context, n *Notification, since time.Time, sameSender bool, messageFormat string) (bool, error)
+
+	FollowerCount(ctx context.Context, channelID string) (int, error)
+
+	// FollowerIDs returns a page of a channel's followers ordered by ID,
+	// starting after the given ID
+	FollowerIDs(ctx context.Context, channelID, after string, limit int) ([]string, error)
+
+	// CreateMany stores a copy of n for each user, skipping users already
+	// notified of the same source event and, when digestSince is set, users
+	// with an unread notification of the same type from the same sender
+	// created since then. It returns the notifications created.
+	CreateMany(ctx context.Context, n *Notification, userIDs []string, digestSince time.Time) ([]*Notification, error)
+
+	// CreateAnnouncement stores n once for every follower of its sender, to
+	// be copied into their notifications by SyncAnnouncements. It reports
+	// false if the source event was already announced.
+	CreateAnnouncement(ctx context.Context, n *Notification) (bool, error)
+
+	// SyncAnnouncements copies announcements from the channels a user follows
+	// made since their last sync, and no older than since, into their
+	// notifications. It returns how many were copied.
+	SyncAnnouncements(ctx context.Context, userID string, since time.Time) (int, error)
+}
+
+// notificationColumns is the column list shared by notification queries
+const notificationColumns = `id::text, user_id::text, type, title, message, data,
+	COALESCE(from_user_id::text, ''), COALESCE(stream_id::text, ''), COALESCE(source_event_id, ''),
+	created_at, read_at, digest_count`
+
+// PostgresRepository implements Repository on PostgreSQL
+type PostgresRepository struct {
+	pool *pgxpool.Pool
+}
+
+// NewPostgresRepository creates a notification repository on pool
+func NewPostgresRepository(pool *pgxpool.Pool) *PostgresRepository {
+	return &PostgresRepository{pool: pool}
+}
+
+// Create inserts a notification, skipping duplicates of the same source event
+func (r *PostgresRepository) Create(ctx context.Context, n *Notification) (bool, error) {
+	if !store.IsUUID(n.UserID) {
+		return false, ErrMissingReference
+	}
+	encoded, err := encodeData(n.Data)
+	if err != nil {
+		return false, err
+	}
+
+	err = r.pool.QueryRow(ctx, `
+		INSERT INTO notifications (user_id, type, title, message, data, from_user_id, stream_id, source_event_id,
+			tenant_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		ON CONFLICT (user_id, source_event_id) DO NOTHING
+		RETURNING id::text, created_at`,
+		n.UserID, n.Type, n.Title, n.Message, encoded,
+		nullableUUID(n.FromUserID), nullableUUID(n.StreamID), nullableString(n.SourceEventID), tenancy.ID(ctx),
+	).Scan(&n.ID, &n.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	n.DigestCount = 1
+	if store.PgCode(err) == "23503" {
+		return false, ErrMissingReference
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to create notification: %w", err)
+	}
+	return true, nil
+}
+
+// List returns a user's notifications
+func (r *PostgresRepository) List(ctx context.Context, userID string, limit int, unreadOnly bool) ([]*Notification, error) {
+	if !store.IsUUID(userID) {
+		return []*Notification{}, nil
+	}
+
+	rows, err := r.pool.Query(ctx, `
+		SELECT `+notificationColumns+`
+		FROM notifications
+		WHERE user_id = $1 AND (NOT $2 OR read_at IS NULL)`+tenancy.Condition("tenant_id", 4)+`
+		ORDER BY created_at DESC, id
+		LIMIT $3`, userID, unreadOnly, limit, tenancy.Scope(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list notifications: %w", err)
+	}
+	defer rows.Close()
+
+	notifications := []*Notification{}
+	for rows.Next() {
+		n, err := scanNotification(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan notification: %w", err)
+		}
+		notifications = append(notifications, n)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list notifications: %w", err)
+	}
+	return notifications, nil
+}
+
+// MarkRead sets read_at on one of the user's notifications, keeping the
+// original time if it was already read
+func (r *PostgresRepository) MarkRead(ctx context.Context, userID, id string) (*Notification, error) {
+	if !store.IsUUID(userID) || !store.IsUUID(id) {
+		return nil, ErrNotFound
+	}
+
+	row := r.pool.QueryRow(ctx, `
+		UPDATE notifications SET read_at = COALESCE(read_at, NOW())
+		WHERE id = $1 AND user_id = $2`+tenancy.Condition("tenant_id", 3)+`
+		RETURNING `+notificationColumns, id, userID, tenancy.Scope(ctx))
+	n, err := scanNotification(row)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to mark notification read: %w", err)
+	}
+	return n, nil
+}
+
+// MarkAllRead sets read_at on every unread notification of the user
+func (r *PostgresRepository) MarkAllRead(ctx context.Context, userID string) (int, error) {
+	if !store.IsUUID(userID) {
+		return 0, nil
+	}
+
+	tag, err := r.pool.Exec(ctx, `
+		UPDATE notifications SET read_at = NOW()
+		WHERE user_id = $1 AND read_at IS NULL`+tenancy.Condition("tenant_id", 2), userID, tenancy.Scope(ctx))
+	if err != nil {
+		return 0, fmt.Errorf("failed to mark notifications read: %w", err)
+	}
+	return int(tag.RowsAffected()), nil
+}
+
+// Digest updates the newest matching unread notification
+func (r *PostgresRepository) Digest(ctx context.Context, n *Notification, since time.Time, sameSender bool, messageFormat string) (bool, error) {
+	if !store.IsUUID(n.UserID) {
+		return false, ErrMissingReference
+	}
+	data, err := encodeData(n.Data)
+	if err != nil {
+		return false, err
+	}
+
+	// A redelivered event that was already merged changes nothing but still
+	// counts as merged
+	var merged bool
+	err = r.pool.QueryRow(ctx, `
+		WITH target AS (
+			SELECT id, digested_event_ids FROM notifications
+			WHERE user_id = $1 AND type = $2 AND read_at IS NULL AND created_at >= $3
+				AND (NOT $4 OR from_user_id IS NOT DISTINCT FROM $5)`+tenancy.Condition("tenant_id", 9)+`
+			ORDER BY created_at DESC
+			LIMIT 1
+		), updated AS (
+			UPDATE notifications n SET
+				digest_count = n.digest_count + 1,
+				digested_event_ids = array_append(n.digested_event_ids, $6),
+				message = format($7, n.digest_count, CASE WHEN n.digest_count = 1 THEN '' ELSE 's' END),
+				from_user_id = $5, data = $8
+			FROM target
+			WHERE n.id = target.id AND $6 <> '' AND NOT ($6 = ANY(target.digested_event_ids))
+				AND n.source_event_id IS DISTINCT FROM $6
+			RETURNING n.id
+		)
+		SELECT EXISTS (SELECT 1 FROM updated) OR EXISTS (
+			SELECT 1 FROM target WHERE $6 <> '' AND $6 = ANY(target.digested_event_ids))`,
+		n.UserID, n.Type, since, sameSender, nullableUUID(n.FromUserID),
+		n.SourceEventID, messageFormat, data, tenancy.Scope(ctx),
+	).Scan(&merged)
+	if err != nil {
+		return false, fmt.Errorf("failed to digest notification: %w", err)
+	}
+	return merged, nil
+}
+
+// FollowerCount counts a channel's followers
+func (r *PostgresRepository) FollowerCount(ctx context.Context, channelID string) (int, error) {
+	if !store.IsUUID(channelID) {
+		return 0, nil
+	}
+
+	var count int
+	if err := r.pool.QueryRow(ctx, `SELECT COUNT(*) FROM follows WHERE followed_id = $1`, channelID).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count followers: %w", err)
+	}
+	return count, nil
+}
+
+// FollowerIDs pages a channel's followers by ID
+func (r *PostgresRepository) FollowerIDs(ctx context.Context, channelID, after string, limit int) ([]string, error) {
+	if !store.IsUUID(channelID) {
+		return []string{}, nil
+	}
+	if after == "" {
+		after = "00000000-0000-0000-0000-000000000000"
+	}
+
+	rows, err := r.pool.Query(ctx, `
+		SELECT follower_id::text FROM follows
+		WHERE followed_id = $1 AND follower_id > $2
+		ORDER BY follower_id
+		LIMIT $3`, channelID, after, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list followers: %w", err)
+	}
+	defer rows.Close()
+
+	ids := []string{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan follower: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list followers: %w", err)
+	}
+	return ids, nil
+}
+
+// CreateMany inserts a copy of n per user in one statement
+func (r *PostgresRepository) CreateMany(ctx context.Context, n *Notification, userIDs []string, digestSince time.Time) ([]*Notification, error) {
+	if len(userIDs) == 0 {
+		return []*Notification{}, nil
+	}
+	data, err := encodeData(n.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.pool.Query(ctx, `
+		INSERT INTO notifications (user_id, type, title, message, data, from_user_id, stream_id, source_event_id,
+			tenant_id)
+		SELECT u.id, $2, $3, $4, $5, $6, $7, $8, $10
+		FROM unnest($1::uuid[]) AS u (id)
+		WHERE $9::timestamptz IS NULL OR NOT EXISTS (
+			SELECT 1 FROM notifications x
+			WHERE x.user_id = u.id AND x.type = $2 AND x.from_user_id IS NOT DISTINCT FROM $6
+				AND x.read_at IS NULL AND x.created_at >= $9)
+		ON CONFLICT (user_id, source_event_id) DO NOTHING
+		RETURNING id::text, user_id::text, created_at`,
+		userIDs, n.Type, n.Title, n.Message, data,
+		nullableUUID(n.FromUserID), nullableUUID(n.StreamID), nullableString(n.SourceEventID),
+		nullableTime(digestSince), tenancy.ID(ctx))
+	if store.PgCode(err) == "23503" {
+		return nil, ErrMissingReference
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create notifications: %w", err)
+	}
+	defer rows.Close()
+
+	created := []*Notification{}
+	for rows.Next() {
+		copied := *n
+		if err := rows.Scan(&copied.ID, &copied.UserID, &copied.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan notification: %w", err)
+		}
+		copied.DigestCount = 1
+		created = append(created, &copied)
+	}
+	if err := rows.Err(); err != nil {
+		if store.PgCode(err) == "23503" {
+			return nil, ErrMissingReference
+		}
+		return nil, fmt.Errorf("failed to create notifications: %w", err)
+	}
+	return created, nil
+}
+
+// CreateAnnouncement inserts an announcement from n's sender
+func (r *PostgresRepository) CreateAnnouncement(ctx context.Context, n *Notification) (bool, error) {
+	if !store.IsUUID(n.FromUserID) {
+		return false, ErrMissingReference
+	}
+	data, err := encodeData(n.Data)
+	if err != nil {
+		return false, err
+	}
+
+	err = r.pool.QueryRow(ctx, `
+		INSERT INTO channel_announcements (channel_id, type, title, message, data, stream_id, source_event_id,
+			tenant_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (source_event_id) DO NOTHING
+		RETURNING id::text, created_at`,
+		n.FromUserID, n.Type, n.Title, n.Message, data, nullableUUID(n.StreamID), nullableString(n.SourceEventID),
+		tenancy.ID(ctx),
+	).Scan(&n.ID, &n.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if store.PgCode(err) == "23503" {
+		return false, ErrMissingReference
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to create announcement: %w", err)
+	}
+	return true, nil
+}
+
+// SyncAnnouncements copies a user's new announcements in one transaction
+// with their cursor, so concurrent reads don't copy twice
+func (r *PostgresRepository) SyncAnnouncements(ctx context.Context, userID string, since time.Time) (int, error) {
+	if !store.IsUUID(userID) {
+		return 0, nil
+	}
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var synced time.Time
+	err = tx.QueryRow(ctx, `
+		INSERT INTO notification_announcement_cursors (user_id, synced_at)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE SET synced_at = notification_announcement_cursors.synced_at
+		RETURNING synced_at`, userID, since,
+	).Scan(&synced)
+	if store.PgCode(err) == "23503" {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to read announcement cursor: %w", err)
+	}
+	if synced.Before(since) {
+		synced = since
+	}
+
+	// Announcements are only copied for follows that predate them
+	now := time.Now()
+	tag, err := tx.Exec(ctx, `
+		INSERT INTO notifications (user_id, type, title, message, data, from_user_id, stream_id, source_event_id,
+			created_at, tenant_id)
+		SELECT $1, a.type, a.title, a.message, a.data, a.channel_id, a.stream_id, a.source_event_id,
+			a.created_at, a.tenant_id
+		FROM channel_announcements a
+		JOIN follows f ON f.followed_id = a.channel_id AND f.follower_id = $1
+		WHERE a.created_at > $2 AND a.created_at <= $3 AND a.created_at >= f.created_at`+tenancy.Condition("a.tenant_id", 4)+`
+		ON CONFLICT (user_id, source_event_id) DO NOTHING`,
+		userID, synced, now, tenancy.Scope(ctx))
+	if err != nil {
+		return 0, fmt.Errorf("failed to copy announcements: %w", err)
+	}
+	if _, err := tx.Exec(ctx, `UPDATE notification_announcement_cursors SET synced_at = $2 WHERE user_id = $1`, userID, now); err != nil {
+		return 0, fmt.Errorf("failed to advance announcement cursor: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed to commit announcements: %w", err)
+	}
+	return int(tag.RowsAffected()), nil
+}
+
+// scanNotification reads a row selected with notificationColumns
+func scanNotification(row pgx.Row) (*Notification, error) {
+	var n Notification
+	var data []byte
+	err := row.Scan(&n.ID, &n.UserID, &n.Type, &n.Title, &n.Message, &data,
+		&n.FromUserID, &n.StreamID, &n.SourceEventID, &n.CreatedAt, &n.ReadAt, &n.DigestCount)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &n.Data); err != nil {
+		return nil, fmt.Errorf("failed to decode notification data: %w", err)
+	}
+	return &n, nil
+}
+
+// nullableUUID passes id through, or NULL if it is not a UUID
+func nullableUUID(id string) interface{} {
+	if !store.IsUUID(id) {
+		return nil
+	}
+	return id
+}
+
+// encodeData encodes notification data for a JSONB column
+func encodeData(data map[string]interface{}) ([]byte, error) {
+	if data == nil {
+		data = map[string]interface{}{}
+	}
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode notification data: %w", err)
+	}
+	return encoded, nil
+}
+
+// nullableTime passes t through, or NULL if it is zero
+func nullableTime(t time.Time) interface{} {
+	if t.IsZero() {
+		return nil
+	}
+	return t
+}
+
+// nullableString passes s through, or NULL if it is empty
+func nullableString(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
@@ -0,0 +1,242 @@
+package notifications
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/tinle0301/streaming-platform-api/internal/events"
+	"github.com/tinle0301/streaming-platform-api/internal/tenancy"
+)
+
+// List limits
+const (
+	DefaultLimit = 50
+	MaxLimit     = 100
+)
+
+// SourceEventTypes are the domain events that notify users
+var SourceEventTypes = []string{
+	events.EventTypeStreamLive,
+	events.EventTypeNewFollower,
+	events.EventTypeRaidIncoming,
+	events.EventTypeRaidOutgoing,
+	events.EventTypeSubscription,
+	events.EventTypeGiftSubscription,
+}
+
+// Service stores notifications and pushes them to connected clients
+type Service struct {
+	repo Repository
+	opts FanOutOptions
+
+	publisher events.Publisher
+}
+
+// NewService creates a notification service
+func NewService(repo Repository) *Service {
+	return &Service{repo: repo, opts: DefaultFanOutOptions()}
+}
+
+// SetFanOutOptions tunes go-live fan-out and digesting; unset fields keep
+// their defaults, except a zero DigestWindow, which turns digesting off
+func (s *Service) SetFanOutOptions(opts FanOutOptions) {
+	s.opts = validateFanOut(opts)
+}
+
+// SetPublisher pushes new notifications to the WebSocket servers as
+// notification.created events
+func (s *Service) SetPublisher(publisher events.Publisher) {
+	s.publisher = publisher
+}
+
+// Send stores n and pushes it to the user's connections. It returns false if
+// n duplicates a notification already sent for the same source event.
+func (s *Service) Send(ctx context.Context, n *Notification) (bool, error) {
+	created, err := s.repo.Create(ctx, n)
+	if err != nil || !created {
+		return false, err
+	}
+	s.push(ctx, n)
+	return true, nil
+}
+
+// Deliver sends n unless it merges into the user's recent unread
+// notification of its type; actor names the other party in merged messages
+func (s *Service) Deliver(ctx context.Context, n *Notification, actor string) error {
+	merged, err := s.digest(ctx, n, actor)
+	if err != nil || merged {
+		return err
+	}
+	_, err = s.Send(ctx, n)
+	return err
+}
+
+// List returns a user's notifications, newest first, including go-lives
+// announced by large channels they follow
+func (s *Service) List(ctx context.Context, userID string, limit int, unreadOnly bool) ([]*Notification, error) {
+	if err := s.syncAnnouncements(ctx, userID); err != nil {
+		return nil, err
+	}
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+	if limit > MaxLimit {
+		limit = MaxLimit
+	}
+	return s.repo.List(ctx, userID, limit, unreadOnly)
+}
+
+// MarkRead marks one of a user's notifications read
+func (s *Service) MarkRead(ctx context.Context, userID, id string) (*Notification, error) {
+	return s.repo.MarkRead(ctx, userID, id)
+}
+
+// MarkAllRead marks all of a user's notifications read, including
+// announcements they haven't fetched yet
+func (s *Service) MarkAllRead(ctx context.Context, userID string) (int, error) {
+	if err := s.syncAnnouncements(ctx, userID); err != nil {
+		return 0, err
+	}
+	return s.repo.MarkAllRead(ctx, userID)
+}
+
+// HandleEvent turns go-live, follower, subscription, and raid events into
+// notifications in the event's tenant. Rapid repeats are merged into the
+// user's unread notification instead of notifying again.
+func (s *Service) HandleEvent(ctx context.Context, event events.Event) error {
+	ctx = tenancy.EventContext(ctx, event)
+
+	var err error
+	if event.Type == events.EventTypeStreamLive {
+		err = s.announceLive(ctx, event)
+	} else if n, actor, ok := fromEvent(event); ok {
+		err = s.Deliver(ctx, n, actor)
+	}
+	if err != nil && !errors.Is(err, ErrMissingReference) {
+		return err
+	}
+	return nil
+}
+
+// syncAnnouncements copies a user's new announcements into their notifications
+func (s *Service) syncAnnouncements(ctx context.Context, userID string) error {
+	_, err := s.repo.SyncAnnouncements(ctx, userID, time.Now().Add(-s.opts.AnnouncementMaxAge))
+	return err
+}
+
+// push publishes n for the WebSocket servers to deliver. The notification is
+// already stored, so a lost event only means the user sees it on next fetch.
+func (s *Service) push(ctx context.Context, n *Notification) {
+	if s.publisher == nil {
+		return
+	}
+
+	event := events.NewNotificationCreatedEvent(n.UserID, pushData(n))
+	if err := s.publisher.Publish(ctx, event); err != nil {
+		log.Printf("Error publishing %s event: notificationID=%s, err=%v", event.Type, n.ID, err)
+	}
+}
+
+// pushData is the notification.created event data for n
+func pushData(n *Notification) map[string]interface{} {
+	data := map[string]interface{}{
+		"id":         n.ID,
+		"type":       n.Type,
+		"title":      n.Title,
+		"message":    n.Message,
+		"data":       n.Data,
+		"created_at": n.CreatedAt.Format(time.RFC3339),
+	}
+	if n.FromUserID != "" {
+		data["from_user_id"] = n.FromUserID
+	}
+	if n.StreamID != "" {
+		data["stream_id"] = n.StreamID
+	}
+	return data
+}
+
+// fromEvent builds the notification for a source event, with the name of the
+// other party for digests. Events are addressed to the user to notify
+// (event.UserID); the other party is named in the event data:
+//
+//	user.new_follower   follower_id, follower_display_name
+//	subscription.new    subscriber_id, subscriber_display_name, tier
+//	subscription.gift   gifter_id, gifter_display_name, count
+//	raid.incoming       from_streamer_id, from_display_name, viewer_count
+//	raid.outgoing       to_streamer_id, to_display_name, viewer_count
+func fromEvent(event events.Event) (*Notification, string, bool) {
+	if event.UserID == "" {
+		return nil, "", false
+	}
+
+	n := &Notification{
+		UserID:        event.UserID,
+		StreamID:      event.StreamID,
+		Data:          event.Data,
+		SourceEventID: event.ID,
+	}
+	var actor string
+	switch event.Type {
+	case events.EventTypeNewFollower:
+		n.Type = TypeNewFollower
+		n.FromUserID = dataString(event.Data, "follower_id")
+		n.Title = "New follower"
+		actor = displayName(event.Data, "follower_display_name", "follower_username")
+		n.Message = fmt.Sprintf("%s followed you", actor)
+	case events.EventTypeSubscription:
+		n.Type = TypeSubscription
+		n.FromUserID = dataString(event.Data, "subscriber_id")
+		n.Title = "New subscriber"
+		actor = displayName(event.Data, "subscriber_display_name", "subscriber_username")
+		n.Message = fmt.Sprintf("%s subscribed", actor)
+	case events.EventTypeGiftSubscription:
+		n.Type = TypeGiftSubscription
+		n.FromUserID = dataString(event.Data, "gifter_id")
+		n.Title = "Gifted subscriptions"
+		n.Message = fmt.Sprintf("%s gifted %s", displayName(event.Data, "gifter_display_name", "gifter_username"),
+			plural(int(events.DataInt(event.Data, "count", 1)), "subscription"))
+	case events.EventTypeRaidIncoming:
+		n.Type = TypeRaidIncoming
+		n.FromUserID = dataString(event.Data, "from_streamer_id")
+		n.Title = "Incoming raid"
+		n.Message = fmt.Sprintf("%s is raiding with %s", displayName(event.Data, "from_display_name", "from_username"),
+			plural(int(events.DataInt(event.Data, "viewer_count", 0)), "viewer"))
+	case events.EventTypeRaidOutgoing:
+		n.Type = TypeRaidOutgoing
+		n.FromUserID = dataString(event.Data, "to_streamer_id")
+		n.Title = "Raid sent"
+		n.Message = fmt.Sprintf("You raided %s with %s", displayName(event.Data, "to_display_name", "to_username"),
+			plural(int(events.DataInt(event.Data, "viewer_count", 0)), "viewer"))
+	default:
+		return nil, "", false
+	}
+	return n, actor, true
+}
+
+// displayName returns the first non-empty name among keys
+func displayName(data map[string]interface{}, keys ...string) string {
+	for _, key := range keys {
+		if name := dataString(data, key); name != "" {
+			return name
+		}
+	}
+	return "Someone"
+}
+
+// dataString returns a string event data value
+func dataString(data map[string]interface{}, key string) string {
+	s, _ := data[key].(string)
+	return s
+}
+
+// plural formats a count with its noun
+func plural(n int, noun string) string {
+	if n == 1 {
+		return "1 " + noun
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
@@ -0,0 +1,670 @@
+package orgs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/tinle0301/streaming-platform-api/internal/store"
+	"github.com/tinle0301/streaming-platform-api/internal/tenancy"
+)
+
+// Organization errors
+var (
+	ErrNotFound           = errors.New("organization not found")
+	ErrSlugTaken          = errors.New("organization slug is already taken")
+	ErrNotMember          = errors.New("user is not a member of this organization")
+	ErrAlreadyMember      = errors.New("user is already a member of this organization")
+	ErrInvitationNotFound = errors.New("invitation not found")
+	ErrInvitationPending  = errors.New("user already has a pending invitation")
+	ErrInvitationClosed   = errors.New("invitation is no longer pending")
+	ErrChannelTaken       = errors.New("channel already belongs to an organization")
+	ErrUserNotFound       = errors.New("user not found")
+)
+
+// Role is a member's role within an organization
+type Role string
+
+// Organization roles, most privileged first
+const (
+	// RoleOwner created the organization and cannot be removed
+	RoleOwner Role = "OWNER"
+
+	// RoleAdmin manages members, invitations, and channels
+	RoleAdmin Role = "ADMIN"
+
+	// RoleManager edits stream info on every organization channel
+	RoleManager Role = "MANAGER"
+
+	// RoleModerator is part of the moderator pool shared by every channel
+	RoleModerator Role = "MODERATOR"
+
+	// RoleStreamer is a member whose channel belongs to the organization
+	RoleStreamer Role = "STREAMER"
+)
+
+// Roles lists the organization roles, most privileged first
+var Roles = []Role{RoleOwner, RoleAdmin, RoleManager, RoleModerator, RoleStreamer}
+
+// Valid reports whether r is a known role
+func (r Role) Valid() bool {
+	switch r {
+	case RoleOwner, RoleAdmin, RoleManager, RoleModerator, RoleStreamer:
+		return true
+	}
+	return false
+}
+
+// Invitation statuses
+const (
+	InvitationPending  = "PENDING"
+	InvitationAccepted = "ACCEPTED"
+	InvitationDeclined = "DECLINED"
+	InvitationRevoked  = "REVOKED"
+)
+
+// Organization owns channels and shares their management between members
+type Organization struct {
+	ID        string
+	Slug      string
+	Name      string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// Member is a user's membership in an organization
+type Member struct {
+	OrganizationID string
+	UserID         string
+	Role           Role
+	JoinedAt       time.Time
+}
+
+// Invitation asks a user to join an organization with a role. Accepting a
+// streamer invitation also brings the invitee's channel into the organization.
+type Invitation struct {
+	ID             string
+	OrganizationID string
+	InviteeID      string
+	InvitedBy      string
+	Role           Role
+	Status         string
+	CreatedAt      time.Time
+	ExpiresAt      time.Time
+	RespondedAt    *time.Time
+}
+
+// Analytics aggregates activity across an organization's channels
+type Analytics struct {
+	Channels          int
+	LiveChannels      int
+	ConcurrentViewers int
+	TotalFollowers    int
+	UniqueFollowers   int
+}
+
+// Repository persists organizations, members, channels, and invitations.
+// Organizations are created in the context's tenant; other tenants'
+// organizations, and their members, channels, and invitations, are treated
+// as missing. Slugs are unique within a tenant.
+type Repository interface {
+	// Create inserts an organization with ownerID as its owner
+	Create(ctx context.Context, org *Organization, ownerID string) error
+	Get(ctx context.Context, id string) (*Organization, error)
+	GetBySlug(ctx context.Context, slug string) (*Organization, error)
+	ListForUser(ctx context.Context, userID string) ([]*Organization, error)
+
+	Member(ctx context.Context, orgID, userID string) (*Member, error)
+	Members(ctx context.Context, orgID string) ([]*Member, error)
+	SetRole(ctx context.Context, orgID, userID string, role Role) error
+
+	// AddMember adds userID to an organization with role
+	AddMember(ctx context.Context, orgID, userID string, role Role) error
+
+	// RemoveMember deletes a membership and, if the member's channel belongs
+	// to the organization, the channel too
+	RemoveMember(ctx context.Context, orgID, userID string) error
+
+	Channels(ctx context.Context, orgID string) ([]string, error)
+
+	// ChannelRole returns userID's role in the organization owning channelID
+	ChannelRole(ctx context.Context, channelID, userID string) (Role, bool, error)
+
+	// ChannelStaff lists the members of the organization owning channelID
+	// whose role is one of roles
+	ChannelStaff(ctx context.Context, channelID string, roles []Role) ([]string, error)
+
+	CreateInvitation(ctx context.Context, invitation *Invitation) error
+	Invitation(ctx context.Context, id string) (*Invitation, error)
+	PendingInvitations(ctx context.Context, orgID string) ([]*Invitation, error)
+	PendingInvitationsFor(ctx context.Context, userID string) ([]*Invitation, error)
+
+	// AcceptInvitation marks a pending invitation accepted and adds the
+	// membership, plus the invitee's channel for streamer invitations
+	AcceptInvitation(ctx context.Context, invitation *Invitation) error
+
+	// CloseInvitation moves a pending invitation to status
+	CloseInvitation(ctx context.Context, id, status string) error
+
+	Analytics(ctx context.Context, orgID string) (*Analytics, error)
+}
+
+// orgColumns is the column list shared by organization queries
+const orgColumns = `o.id::text, o.slug, o.name, o.created_at, o.updated_at`
+
+// invitationColumns is the column list shared by invitation queries
+const invitationColumns = `i.id::text, i.organization_id::text, i.invitee_id::text, COALESCE(i.invited_by::text, ''),
+	i.role, i.status, i.created_at, i.expires_at, i.responded_at`
+
+// orgScope restricts a query on an organization ID column to organizations
+// in the context's tenant; n is the placeholder number of the tenancy.Scope
+// argument
+func orgScope(column string, n int) string {
+	return fmt.Sprintf(` AND ($%d::text IS NULL OR %s IN (SELECT id FROM organizations WHERE tenant_id = $%d))`,
+		n, column, n)
+}
+
+// PostgresRepository implements Repository on PostgreSQL
+type PostgresRepository struct {
+	pool *pgxpool.Pool
+}
+
+// NewPostgresRepository creates an organization repository on pool
+func NewPostgresRepository(pool *pgxpool.Pool) *PostgresRepository {
+	return &PostgresRepository{pool: pool}
+}
+
+// Create inserts an organization and its owner membership
+func (r *PostgresRepository) Create(ctx context.Context, org *Organization, ownerID string) error {
+	if !store.IsUUID(ownerID) {
+		return ErrUserNotFound
+	}
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to create organization: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	err = tx.QueryRow(ctx, `
+		INSERT INTO organizations (slug, name, tenant_id) VALUES ($1, $2, $3)
+		RETURNING id::text, created_at, updated_at`,
+		org.Slug, org.Name, tenancy.ID(ctx),
+	).Scan(&org.ID, &org.CreatedAt, &org.UpdatedAt)
+	if store.PgCode(err) == "23505" {
+		return ErrSlugTaken
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create organization: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO organization_members (organization_id, user_id, role) VALUES ($1, $2, $3)`,
+		org.ID, ownerID, RoleOwner)
+	if store.PgCode(err) == "23503" {
+		return ErrUserNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to add organization owner: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to create organization: %w", err)
+	}
+	return nil
+}
+
+// Get returns an organization by ID
+func (r *PostgresRepository) Get(ctx context.Context, id string) (*Organization, error) {
+	if !store.IsUUID(id) {
+		return nil, ErrNotFound
+	}
+	return r.getOne(ctx, `SELECT `+orgColumns+` FROM organizations o WHERE o.id = $1`+tenancy.Condition("o.tenant_id", 2),
+		id, tenancy.Scope(ctx))
+}
+
+// GetBySlug returns an organization by slug
+func (r *PostgresRepository) GetBySlug(ctx context.Context, slug string) (*Organization, error) {
+	return r.getOne(ctx, `SELECT `+orgColumns+` FROM organizations o WHERE o.slug = LOWER($1)`+tenancy.Condition("o.tenant_id", 2),
+		slug, tenancy.Scope(ctx))
+}
+
+// ListForUser returns the organizations userID belongs to, oldest first
+func (r *PostgresRepository) ListForUser(ctx context.Context, userID string) ([]*Organization, error) {
+	if !store.IsUUID(userID) {
+		return []*Organization{}, nil
+	}
+
+	rows, err := r.pool.Query(ctx, `
+		SELECT `+orgColumns+` FROM organizations o
+		JOIN organization_members m ON m.organization_id = o.id
+		WHERE m.user_id = $1`+tenancy.Condition("o.tenant_id", 2)+`
+		ORDER BY o.created_at, o.id`, userID, tenancy.Scope(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list organizations: %w", err)
+	}
+	defer rows.Close()
+
+	orgs := []*Organization{}
+	for rows.Next() {
+		var org Organization
+		if err := rows.Scan(&org.ID, &org.Slug, &org.Name, &org.CreatedAt, &org.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan organization: %w", err)
+		}
+		orgs = append(orgs, &org)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list organizations: %w", err)
+	}
+	return orgs, nil
+}
+
+// Member returns userID's membership in an organization
+func (r *PostgresRepository) Member(ctx context.Context, orgID, userID string) (*Member, error) {
+	if !store.IsUUID(orgID) || !store.IsUUID(userID) {
+		return nil, ErrNotMember
+	}
+
+	member := Member{OrganizationID: orgID, UserID: userID}
+	err := r.pool.QueryRow(ctx, `
+		SELECT role, created_at FROM organization_members
+		WHERE organization_id = $1 AND user_id = $2`+orgScope("organization_id", 3),
+		orgID, userID, tenancy.Scope(ctx),
+	).Scan(&member.Role, &member.JoinedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotMember
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get organization member: %w", err)
+	}
+	return &member, nil
+}
+
+// Members lists an organization's members, longest-standing first
+func (r *PostgresRepository) Members(ctx context.Context, orgID string) ([]*Member, error) {
+	if !store.IsUUID(orgID) {
+		return []*Member{}, nil
+	}
+
+	rows, err := r.pool.Query(ctx, `
+		SELECT user_id::text, role, created_at FROM organization_members
+		WHERE organization_id = $1`+orgScope("organization_id", 2)+`
+		ORDER BY created_at, user_id`, orgID, tenancy.Scope(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list organization members: %w", err)
+	}
+	defer rows.Close()
+
+	members := []*Member{}
+	for rows.Next() {
+		member := Member{OrganizationID: orgID}
+		if err := rows.Scan(&member.UserID, &member.Role, &member.JoinedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan organization member: %w", err)
+		}
+		members = append(members, &member)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list organization members: %w", err)
+	}
+	return members, nil
+}
+
+// SetRole changes a member's role
+func (r *PostgresRepository) SetRole(ctx context.Context, orgID, userID string, role Role) error {
+	if !store.IsUUID(orgID) || !store.IsUUID(userID) {
+		return ErrNotMember
+	}
+
+	tag, err := r.pool.Exec(ctx, `
+		UPDATE organization_members SET role = $3
+		WHERE organization_id = $1 AND user_id = $2`+orgScope("organization_id", 4),
+		orgID, userID, role, tenancy.Scope(ctx))
+	if err != nil {
+		return fmt.Errorf("failed to set member role: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotMember
+	}
+	return nil
+}
+
+// AddMember inserts a membership
+func (r *PostgresRepository) AddMember(ctx context.Context, orgID, userID string, role Role) error {
+	if !store.IsUUID(orgID) {
+		return ErrNotFound
+	}
+	if !store.IsUUID(userID) {
+		return ErrUserNotFound
+	}
+
+	tag, err := r.pool.Exec(ctx, `
+		INSERT INTO organization_members (organization_id, user_id, role)
+		SELECT id, $2::uuid, $3 FROM organizations WHERE id = $1`+tenancy.Condition("tenant_id", 4),
+		orgID, userID, role, tenancy.Scope(ctx))
+	switch store.PgCode(err) {
+	case "23505":
+		return ErrAlreadyMember
+	case "23503":
+		return ErrUserNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to add member: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// RemoveMember deletes a membership and the member's channel
+func (r *PostgresRepository) RemoveMember(ctx context.Context, orgID, userID string) error {
+	if !store.IsUUID(orgID) || !store.IsUUID(userID) {
+		return ErrNotMember
+	}
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to remove member: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, `
+		DELETE FROM organization_members WHERE organization_id = $1 AND user_id = $2`+orgScope("organization_id", 3),
+		orgID, userID, tenancy.Scope(ctx))
+	if err != nil {
+		return fmt.Errorf("failed to remove member: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotMember
+	}
+
+	_, err = tx.Exec(ctx, `
+		DELETE FROM organization_channels WHERE organization_id = $1 AND channel_id = $2`, orgID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to remove member channel: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to remove member: %w", err)
+	}
+	return nil
+}
+
+// Channels lists the channel IDs an organization owns, oldest first
+func (r *PostgresRepository) Channels(ctx context.Context, orgID string) ([]string, error) {
+	if !store.IsUUID(orgID) {
+		return []string{}, nil
+	}
+
+	rows, err := r.pool.Query(ctx, `
+		SELECT channel_id::text FROM organization_channels
+		WHERE organization_id = $1`+orgScope("organization_id", 2)+`
+		ORDER BY created_at, channel_id`, orgID, tenancy.Scope(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list organization channels: %w", err)
+	}
+	return collectIDs(rows, "organization channels")
+}
+
+// ChannelRole returns userID's role in the organization owning channelID
+func (r *PostgresRepository) ChannelRole(ctx context.Context, channelID, userID string) (Role, bool, error) {
+	if !store.IsUUID(channelID) || !store.IsUUID(userID) {
+		return "", false, nil
+	}
+
+	var role Role
+	err := r.pool.QueryRow(ctx, `
+		SELECT m.role FROM organization_channels c
+		JOIN organization_members m ON m.organization_id = c.organization_id
+		WHERE c.channel_id = $1 AND m.user_id = $2`+orgScope("c.organization_id", 3),
+		channelID, userID, tenancy.Scope(ctx),
+	).Scan(&role)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, fmt.Errorf("failed to get channel role: %w", err)
+	}
+	return role, true, nil
+}
+
+// ChannelStaff lists members with one of roles in the organization owning channelID
+func (r *PostgresRepository) ChannelStaff(ctx context.Context, channelID string, roles []Role) ([]string, error) {
+	if !store.IsUUID(channelID) {
+		return []string{}, nil
+	}
+
+	names := make([]string, 0, len(roles))
+	for _, role := range roles {
+		names = append(names, string(role))
+	}
+
+	rows, err := r.pool.Query(ctx, `
+		SELECT m.user_id::text FROM organization_channels c
+		JOIN organization_members m ON m.organization_id = c.organization_id
+		WHERE c.channel_id = $1 AND m.role = ANY($2)`+orgScope("c.organization_id", 3)+`
+		ORDER BY m.created_at, m.user_id`, channelID, names, tenancy.Scope(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list channel staff: %w", err)
+	}
+	return collectIDs(rows, "channel staff")
+}
+
+// CreateInvitation inserts a pending invitation
+func (r *PostgresRepository) CreateInvitation(ctx context.Context, invitation *Invitation) error {
+	if !store.IsUUID(invitation.InviteeID) {
+		return ErrUserNotFound
+	}
+
+	err := r.pool.QueryRow(ctx, `
+		INSERT INTO organization_invitations (organization_id, invitee_id, invited_by, role, expires_at)
+		VALUES ($1, $2, NULLIF($3, '')::uuid, $4, $5)
+		RETURNING id::text, status, created_at`,
+		invitation.OrganizationID, invitation.InviteeID, invitation.InvitedBy, invitation.Role, invitation.ExpiresAt,
+	).Scan(&invitation.ID, &invitation.Status, &invitation.CreatedAt)
+	switch store.PgCode(err) {
+	case "23505":
+		return ErrInvitationPending
+	case "23503":
+		return ErrUserNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create invitation: %w", err)
+	}
+	return nil
+}
+
+// Invitation returns an invitation by ID
+func (r *PostgresRepository) Invitation(ctx context.Context, id string) (*Invitation, error) {
+	if !store.IsUUID(id) {
+		return nil, ErrInvitationNotFound
+	}
+
+	invitations, err := r.listInvitations(ctx, `SELECT `+invitationColumns+` FROM organization_invitations i
+		WHERE i.id = $1`+orgScope("i.organization_id", 2), id, tenancy.Scope(ctx))
+	if err != nil {
+		return nil, err
+	}
+	if len(invitations) == 0 {
+		return nil, ErrInvitationNotFound
+	}
+	return invitations[0], nil
+}
+
+// PendingInvitations lists an organization's unexpired pending invitations, newest first
+func (r *PostgresRepository) PendingInvitations(ctx context.Context, orgID string) ([]*Invitation, error) {
+	if !store.IsUUID(orgID) {
+		return []*Invitation{}, nil
+	}
+	return r.listInvitations(ctx, `
+		SELECT `+invitationColumns+` FROM organization_invitations i
+		WHERE i.organization_id = $1 AND i.status = 'PENDING' AND i.expires_at > NOW()`+orgScope("i.organization_id", 2)+`
+		ORDER BY i.created_at DESC`, orgID, tenancy.Scope(ctx))
+}
+
+// PendingInvitationsFor lists a user's unexpired pending invitations, newest first
+func (r *PostgresRepository) PendingInvitationsFor(ctx context.Context, userID string) ([]*Invitation, error) {
+	if !store.IsUUID(userID) {
+		return []*Invitation{}, nil
+	}
+	return r.listInvitations(ctx, `
+		SELECT `+invitationColumns+` FROM organization_invitations i
+		WHERE i.invitee_id = $1 AND i.status = 'PENDING' AND i.expires_at > NOW()`+orgScope("i.organization_id", 2)+`
+		ORDER BY i.created_at DESC`, userID, tenancy.Scope(ctx))
+}
+
+// AcceptInvitation accepts a pending invitation and adds the membership
+func (r *PostgresRepository) AcceptInvitation(ctx context.Context, invitation *Invitation) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to accept invitation: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	err = tx.QueryRow(ctx, `
+		UPDATE organization_invitations SET status = 'ACCEPTED', responded_at = NOW()
+		WHERE id = $1 AND status = 'PENDING'`+orgScope("organization_id", 2)+`
+		RETURNING status, responded_at`, invitation.ID, tenancy.Scope(ctx),
+	).Scan(&invitation.Status, &invitation.RespondedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrInvitationClosed
+	}
+	if err != nil {
+		return fmt.Errorf("failed to accept invitation: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO organization_members (organization_id, user_id, role) VALUES ($1, $2, $3)`,
+		invitation.OrganizationID, invitation.InviteeID, invitation.Role)
+	if store.PgCode(err) == "23505" {
+		return ErrAlreadyMember
+	}
+	if err != nil {
+		return fmt.Errorf("failed to add organization member: %w", err)
+	}
+
+	if invitation.Role == RoleStreamer {
+		_, err = tx.Exec(ctx, `
+			INSERT INTO organization_channels (channel_id, organization_id) VALUES ($1, $2)`,
+			invitation.InviteeID, invitation.OrganizationID)
+		if store.PgCode(err) == "23505" {
+			return ErrChannelTaken
+		}
+		if err != nil {
+			return fmt.Errorf("failed to add organization channel: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to accept invitation: %w", err)
+	}
+	return nil
+}
+
+// CloseInvitation declines or revokes a pending invitation
+func (r *PostgresRepository) CloseInvitation(ctx context.Context, id, status string) error {
+	if !store.IsUUID(id) {
+		return ErrInvitationNotFound
+	}
+
+	tag, err := r.pool.Exec(ctx, `
+		UPDATE organization_invitations SET status = $2, responded_at = NOW()
+		WHERE id = $1 AND status = 'PENDING'`+orgScope("organization_id", 3), id, status, tenancy.Scope(ctx))
+	if err != nil {
+		return fmt.Errorf("failed to close invitation: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrInvitationClosed
+	}
+	return nil
+}
+
+// Analytics aggregates live and follower stats across an organization's channels
+func (r *PostgresRepository) Analytics(ctx context.Context, orgID string) (*Analytics, error) {
+	var analytics Analytics
+	if !store.IsUUID(orgID) {
+		return &analytics, nil
+	}
+
+	err := r.pool.QueryRow(ctx, `
+		SELECT
+			(SELECT COUNT(*) FROM organization_channels WHERE organization_id = $1),
+			COUNT(DISTINCT s.streamer_id),
+			COALESCE(SUM(s.viewer_count), 0)
+		FROM organization_channels c
+		JOIN streams s ON s.streamer_id = c.channel_id::text AND s.status = 'LIVE'
+		WHERE c.organization_id = $1`+orgScope("c.organization_id", 2), orgID, tenancy.Scope(ctx),
+	).Scan(&analytics.Channels, &analytics.LiveChannels, &analytics.ConcurrentViewers)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate organization streams: %w", err)
+	}
+
+	err = r.pool.QueryRow(ctx, `
+		SELECT COUNT(*), COUNT(DISTINCT f.follower_id)
+		FROM organization_channels c
+		JOIN follows f ON f.followed_id = c.channel_id
+		WHERE c.organization_id = $1`+orgScope("c.organization_id", 2), orgID, tenancy.Scope(ctx),
+	).Scan(&analytics.TotalFollowers, &analytics.UniqueFollowers)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate organization followers: %w", err)
+	}
+	return &analytics, nil
+}
+
+// getOne runs a query selecting orgColumns and scans a single organization
+func (r *PostgresRepository) getOne(ctx context.Context, query string, args ...interface{}) (*Organization, error) {
+	var org Organization
+	err := r.pool.QueryRow(ctx, query, args...).Scan(&org.ID, &org.Slug, &org.Name, &org.CreatedAt, &org.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get organization: %w", err)
+	}
+	return &org, nil
+}
+
+// listInvitations runs a query selecting invitationColumns
+func (r *PostgresRepository) listInvitations(ctx context.Context, query string, args ...interface{}) ([]*Invitation, error) {
+	rows, err := r.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list invitations: %w", err)
+	}
+	defer rows.Close()
+
+	invitations := []*Invitation{}
+	for rows.Next() {
+		var invitation Invitation
+		err := rows.Scan(&invitation.ID, &invitation.OrganizationID, &invitation.InviteeID, &invitation.InvitedBy,
+			&invitation.Role, &invitation.Status, &invitation.CreatedAt, &invitation.ExpiresAt, &invitation.RespondedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan invitation: %w", err)
+		}
+		invitations = append(invitations, &invitation)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list invitations: %w", err)
+	}
+	return invitations, nil
+}
+
+// collectIDs scans a single-column ID result set
+func collectIDs(rows pgx.Rows, what string) ([]string, error) {
+	defer rows.Close()
+
+	ids := []string{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan %s: %w", what, err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list %s: %w", what, err)
+	}
+	return ids, nil
+}
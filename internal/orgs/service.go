@@ -0,0 +1,379 @@
+package orgs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Organization limits
+const (
+	maxNameLength = 64
+
+	// How long an invitation can be accepted
+	invitationTTL = 7 * 24 * time.Hour
+)
+
+// slugPattern is lowercase letters, digits, and inner hyphens, 3-32 characters
+var slugPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{1,30}[a-z0-9]$`)
+
+// Authorization errors
+var (
+	ErrForbidden   = errors.New("insufficient organization role")
+	ErrOwnerRole   = errors.New("the owner role cannot be granted or removed")
+	ErrInvalidRole = errors.New("invalid organization role")
+	ErrInvalidSlug = errors.New("slug must be 3-32 lowercase letters, digits, or hyphens")
+	ErrInvalidName = errors.New("name must be 1-64 characters")
+)
+
+// moderatingRoles make up the moderator pool shared by an organization's channels
+var moderatingRoles = []Role{RoleOwner, RoleAdmin, RoleManager, RoleModerator}
+
+// Service manages organizations and enforces organization roles
+type Service struct {
+	repo Repository
+}
+
+// NewService creates an organization service
+func NewService(repo Repository) *Service {
+	return &Service{repo: repo}
+}
+
+// Create creates an organization owned by ownerID
+func (s *Service) Create(ctx context.Context, ownerID, slug, name string) (*Organization, error) {
+	slug = strings.ToLower(strings.TrimSpace(slug))
+	name = strings.TrimSpace(name)
+	if !slugPattern.MatchString(slug) {
+		return nil, ErrInvalidSlug
+	}
+	if name == "" || len(name) > maxNameLength {
+		return nil, ErrInvalidName
+	}
+
+	org := &Organization{Slug: slug, Name: name}
+	if err := s.repo.Create(ctx, org, ownerID); err != nil {
+		return nil, err
+	}
+
+	log.Printf("Organization created: id=%s, slug=%s, owner=%s", org.ID, org.Slug, ownerID)
+	return org, nil
+}
+
+// Get returns an organization by ID
+func (s *Service) Get(ctx context.Context, id string) (*Organization, error) {
+	return s.repo.Get(ctx, id)
+}
+
+// GetBySlug returns an organization by slug
+func (s *Service) GetBySlug(ctx context.Context, slug string) (*Organization, error) {
+	return s.repo.GetBySlug(ctx, strings.TrimSpace(slug))
+}
+
+// ListForUser returns the organizations userID belongs to
+func (s *Service) ListForUser(ctx context.Context, userID string) ([]*Organization, error) {
+	return s.repo.ListForUser(ctx, userID)
+}
+
+// Role returns userID's role in an organization; ok is false for non-members
+func (s *Service) Role(ctx context.Context, orgID, userID string) (Role, bool, error) {
+	member, err := s.repo.Member(ctx, orgID, userID)
+	if errors.Is(err, ErrNotMember) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	return member.Role, true, nil
+}
+
+// Members lists an organization's members; only members may see them
+func (s *Service) Members(ctx context.Context, actorID, orgID string) ([]*Member, error) {
+	if _, err := s.require(ctx, orgID, actorID, RoleStreamer); err != nil {
+		return nil, err
+	}
+	return s.repo.Members(ctx, orgID)
+}
+
+// Channels lists the channel IDs an organization owns
+func (s *Service) Channels(ctx context.Context, orgID string) ([]string, error) {
+	return s.repo.Channels(ctx, orgID)
+}
+
+// Invite asks inviteeID to join an organization with role. Admins invite;
+// only the owner may invite admins.
+func (s *Service) Invite(ctx context.Context, actorID, orgID, inviteeID string, role Role) (*Invitation, error) {
+	if !role.Valid() {
+		return nil, ErrInvalidRole
+	}
+	if role == RoleOwner {
+		return nil, ErrOwnerRole
+	}
+	actorRole, err := s.require(ctx, orgID, actorID, RoleAdmin)
+	if err != nil {
+		return nil, err
+	}
+	if role == RoleAdmin && actorRole != RoleOwner {
+		return nil, ErrForbidden
+	}
+
+	if _, ok, err := s.Role(ctx, orgID, inviteeID); err != nil {
+		return nil, err
+	} else if ok {
+		return nil, ErrAlreadyMember
+	}
+
+	invitation := &Invitation{
+		OrganizationID: orgID,
+		InviteeID:      inviteeID,
+		InvitedBy:      actorID,
+		Role:           role,
+		ExpiresAt:      time.Now().Add(invitationTTL),
+	}
+	if err := s.repo.CreateInvitation(ctx, invitation); err != nil {
+		return nil, err
+	}
+
+	log.Printf("Organization invitation sent: org=%s, invitee=%s, role=%s, by=%s", orgID, inviteeID, role, actorID)
+	return invitation, nil
+}
+
+// Respond accepts or declines an invitation addressed to userID
+func (s *Service) Respond(ctx context.Context, userID, invitationID string, accept bool) (*Invitation, error) {
+	invitation, err := s.repo.Invitation(ctx, invitationID)
+	if err != nil {
+		return nil, err
+	}
+	if invitation.InviteeID != userID {
+		// Don't reveal other users' invitations
+		return nil, ErrInvitationNotFound
+	}
+	if invitation.Status != InvitationPending || time.Now().After(invitation.ExpiresAt) {
+		return nil, ErrInvitationClosed
+	}
+
+	if !accept {
+		if err := s.repo.CloseInvitation(ctx, invitation.ID, InvitationDeclined); err != nil {
+			return nil, err
+		}
+		return s.repo.Invitation(ctx, invitation.ID)
+	}
+
+	if err := s.repo.AcceptInvitation(ctx, invitation); err != nil {
+		return nil, err
+	}
+
+	log.Printf("Organization invitation accepted: org=%s, user=%s, role=%s", invitation.OrganizationID, userID, invitation.Role)
+	return invitation, nil
+}
+
+// Revoke withdraws a pending invitation
+func (s *Service) Revoke(ctx context.Context, actorID, invitationID string) (*Invitation, error) {
+	invitation, err := s.repo.Invitation(ctx, invitationID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.require(ctx, invitation.OrganizationID, actorID, RoleAdmin); err != nil {
+		if errors.Is(err, ErrNotMember) {
+			return nil, ErrInvitationNotFound
+		}
+		return nil, err
+	}
+
+	if err := s.repo.CloseInvitation(ctx, invitation.ID, InvitationRevoked); err != nil {
+		return nil, err
+	}
+	return s.repo.Invitation(ctx, invitation.ID)
+}
+
+// PendingInvitations lists an organization's open invitations for its admins
+func (s *Service) PendingInvitations(ctx context.Context, actorID, orgID string) ([]*Invitation, error) {
+	if _, err := s.require(ctx, orgID, actorID, RoleAdmin); err != nil {
+		return nil, err
+	}
+	return s.repo.PendingInvitations(ctx, orgID)
+}
+
+// PendingInvitationsFor lists the open invitations addressed to userID
+func (s *Service) PendingInvitationsFor(ctx context.Context, userID string) ([]*Invitation, error) {
+	return s.repo.PendingInvitationsFor(ctx, userID)
+}
+
+// SetMemberRole changes a member's role. Admins manage roles below admin;
+// only the owner may grant or remove admin.
+func (s *Service) SetMemberRole(ctx context.Context, actorID, orgID, userID string, role Role) (*Member, error) {
+	if !role.Valid() {
+		return nil, ErrInvalidRole
+	}
+	if role == RoleOwner {
+		return nil, ErrOwnerRole
+	}
+	actorRole, err := s.require(ctx, orgID, actorID, RoleAdmin)
+	if err != nil {
+		return nil, err
+	}
+
+	member, err := s.repo.Member(ctx, orgID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if member.Role == RoleOwner {
+		return nil, ErrOwnerRole
+	}
+	if (member.Role == RoleAdmin || role == RoleAdmin) && actorRole != RoleOwner {
+		return nil, ErrForbidden
+	}
+
+	if err := s.repo.SetRole(ctx, orgID, userID, role); err != nil {
+		return nil, err
+	}
+	member.Role = role
+
+	log.Printf("Organization role changed: org=%s, user=%s, role=%s, by=%s", orgID, userID, role, actorID)
+	return member, nil
+}
+
+// RemoveMember removes a member and their channel. Members may leave on
+// their own; removing others needs admin, and removing admins needs the owner.
+func (s *Service) RemoveMember(ctx context.Context, actorID, orgID, userID string) error {
+	member, err := s.repo.Member(ctx, orgID, userID)
+	if err != nil {
+		return err
+	}
+	if member.Role == RoleOwner {
+		return ErrOwnerRole
+	}
+
+	if actorID != userID {
+		actorRole, err := s.require(ctx, orgID, actorID, RoleAdmin)
+		if err != nil {
+			return err
+		}
+		if member.Role == RoleAdmin && actorRole != RoleOwner {
+			return ErrForbidden
+		}
+	}
+
+	if err := s.repo.RemoveMember(ctx, orgID, userID); err != nil {
+		return err
+	}
+
+	log.Printf("Organization member removed: org=%s, user=%s, by=%s", orgID, userID, actorID)
+	return nil
+}
+
+// SyncMemberRole sets the role a directory grants userID, adding them to
+// the organization if needed; an empty role removes them. The owner's
+// membership is left alone.
+func (s *Service) SyncMemberRole(ctx context.Context, orgID, userID string, role Role) error {
+	if role != "" && (!role.Valid() || role == RoleOwner) {
+		return ErrInvalidRole
+	}
+	member, err := s.repo.Member(ctx, orgID, userID)
+	if err != nil && !errors.Is(err, ErrNotMember) {
+		return err
+	}
+	if member != nil && (member.Role == RoleOwner || member.Role == role) {
+		return nil
+	}
+
+	switch {
+	case role == "" && member == nil:
+		return nil
+	case role == "":
+		err = s.repo.RemoveMember(ctx, orgID, userID)
+	case member == nil:
+		err = s.repo.AddMember(ctx, orgID, userID, role)
+	default:
+		err = s.repo.SetRole(ctx, orgID, userID, role)
+	}
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Organization role synced: org=%s, user=%s, role=%q", orgID, userID, role)
+	return nil
+}
+
+// Analytics aggregates an organization's channels for its members
+func (s *Service) Analytics(ctx context.Context, actorID, orgID string) (*Analytics, error) {
+	if _, err := s.require(ctx, orgID, actorID, RoleStreamer); err != nil {
+		return nil, err
+	}
+	return s.repo.Analytics(ctx, orgID)
+}
+
+// CanManageChannel reports whether userID may manage channelID's streams on
+// behalf of the organization owning it
+func (s *Service) CanManageChannel(ctx context.Context, channelID, userID string) (bool, error) {
+	role, ok, err := s.repo.ChannelRole(ctx, channelID, userID)
+	if err != nil || !ok {
+		return false, err
+	}
+	return role.atLeast(RoleManager), nil
+}
+
+// CanAdministerChannel reports whether userID is an owner or admin of the
+// organization owning channelID
+func (s *Service) CanAdministerChannel(ctx context.Context, channelID, userID string) (bool, error) {
+	role, ok, err := s.repo.ChannelRole(ctx, channelID, userID)
+	if err != nil || !ok {
+		return false, err
+	}
+	return role.atLeast(RoleAdmin), nil
+}
+
+// IsChannelModerator reports whether userID is in the moderator pool of the
+// organization owning channelID
+func (s *Service) IsChannelModerator(ctx context.Context, channelID, userID string) (bool, error) {
+	role, ok, err := s.repo.ChannelRole(ctx, channelID, userID)
+	if err != nil || !ok {
+		return false, err
+	}
+	return role.atLeast(RoleModerator), nil
+}
+
+// ChannelModerators lists the shared moderator pool for channelID
+func (s *Service) ChannelModerators(ctx context.Context, channelID string) ([]string, error) {
+	return s.repo.ChannelStaff(ctx, channelID, moderatingRoles)
+}
+
+// require checks that actorID is a member of orgID with at least role min
+func (s *Service) require(ctx context.Context, orgID, actorID string, min Role) (Role, error) {
+	member, err := s.repo.Member(ctx, orgID, actorID)
+	if err != nil {
+		return "", err
+	}
+	if !member.Role.atLeast(min) {
+		return "", fmt.Errorf("%w: %s required", ErrForbidden, strings.ToLower(string(min)))
+	}
+	return member.Role, nil
+}
+
+// roleRanks orders roles by privilege
+var roleRanks = map[Role]int{
+	RoleStreamer:  1,
+	RoleModerator: 2,
+	RoleManager:   3,
+	RoleAdmin:     4,
+	RoleOwner:     5,
+}
+
+// atLeast reports whether r is as privileged as min
+func (r Role) atLeast(min Role) bool {
+	return roleRanks[r] >= roleRanks[min]
+}
+
+// MostPrivileged returns the most privileged of roles, or "" for none
+func MostPrivileged(roles ...Role) Role {
+	var most Role
+	for _, role := range roles {
+		if roleRanks[role] > roleRanks[most] {
+			most = role
+		}
+	}
+	return most
+}
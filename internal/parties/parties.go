@@ -0,0 +1,212 @@
+// Package parties runs co-watching parties for VODs. A host creates a party
+// for a VOD; everyone in the party's WebSocket room plays it in sync with
+// the host, who alone controls playback, and chats in the room as usual.
+package parties
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/tinle0301/streaming-platform-api/internal/store"
+	"github.com/tinle0301/streaming-platform-api/internal/tenancy"
+)
+
+// Repository errors
+var (
+	ErrNotFound         = errors.New("watch party not found")
+	ErrMissingReference = errors.New("watch party refers to a VOD or user that does not exist")
+)
+
+// Party is a group watching a VOD together
+type Party struct {
+	ID        string
+	VODID     string
+	HostID    string
+	CreatedAt time.Time
+	EndedAt   *time.Time
+}
+
+// Active reports whether the party is still running
+func (p *Party) Active() bool {
+	return p.EndedAt == nil
+}
+
+// Message is a chat message sent in a party
+type Message struct {
+	ID        string
+	PartyID   string
+	UserID    string
+	Text      string
+	CreatedAt time.Time
+}
+
+// Repository persists parties and their chat. Parties are created in the
+// context's tenant, and other tenants' parties and chat are treated as
+// missing.
+type Repository interface {
+	// Create stores a party and fills in its generated ID and timestamp
+	Create(ctx context.Context, party *Party) error
+
+	Get(ctx context.Context, id string) (*Party, error)
+
+	// End marks a party ended; ending it again keeps the first end time
+	End(ctx context.Context, id string) (*Party, error)
+
+	// AddMessage stores a chat message once per source event, reporting
+	// false for a duplicate or a missing party
+	AddMessage(ctx context.Context, message *Message, sourceEventID string) (bool, error)
+
+	// Messages returns a party's latest messages, oldest first
+	Messages(ctx context.Context, partyID string, limit int) ([]*Message, error)
+}
+
+// partyColumns is the column list shared by party queries
+const partyColumns = `id::text, vod_id::text, host_id::text, created_at, ended_at`
+
+// partyScope restricts a query on a party ID column to parties in the
+// context's tenant; n is the placeholder number of the tenancy.Scope
+// argument
+func partyScope(column string, n int) string {
+	return fmt.Sprintf(` AND ($%d::text IS NULL OR %s IN (SELECT id FROM watch_parties WHERE tenant_id = $%d))`,
+		n, column, n)
+}
+
+// PostgresRepository implements Repository on PostgreSQL
+type PostgresRepository struct {
+	pool *pgxpool.Pool
+}
+
+// NewPostgresRepository creates a watch party repository on pool
+func NewPostgresRepository(pool *pgxpool.Pool) *PostgresRepository {
+	return &PostgresRepository{pool: pool}
+}
+
+// Create inserts a party
+func (r *PostgresRepository) Create(ctx context.Context, party *Party) error {
+	if !store.IsUUID(party.VODID) || !store.IsUUID(party.HostID) {
+		return ErrMissingReference
+	}
+
+	err := r.pool.QueryRow(ctx, `
+		INSERT INTO watch_parties (vod_id, host_id, tenant_id)
+		VALUES ($1, $2, $3)
+		RETURNING id::text, created_at`,
+		party.VODID, party.HostID, tenancy.ID(ctx),
+	).Scan(&party.ID, &party.CreatedAt)
+	if store.PgCode(err) == "23503" {
+		return ErrMissingReference
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create watch party: %w", err)
+	}
+	return nil
+}
+
+// Get returns a party by ID
+func (r *PostgresRepository) Get(ctx context.Context, id string) (*Party, error) {
+	if !store.IsUUID(id) {
+		return nil, ErrNotFound
+	}
+
+	party, err := scanParty(r.pool.QueryRow(ctx, `SELECT `+partyColumns+` FROM watch_parties
+		WHERE id = $1`+tenancy.Condition("tenant_id", 2), id, tenancy.Scope(ctx)))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get watch party: %w", err)
+	}
+	return party, nil
+}
+
+// End sets a party's end time
+func (r *PostgresRepository) End(ctx context.Context, id string) (*Party, error) {
+	if !store.IsUUID(id) {
+		return nil, ErrNotFound
+	}
+
+	party, err := scanParty(r.pool.QueryRow(ctx, `
+		UPDATE watch_parties SET ended_at = COALESCE(ended_at, NOW())
+		WHERE id = $1`+tenancy.Condition("tenant_id", 2)+`
+		RETURNING `+partyColumns, id, tenancy.Scope(ctx)))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to end watch party: %w", err)
+	}
+	return party, nil
+}
+
+// AddMessage inserts a chat message, skipping duplicates of the same event
+func (r *PostgresRepository) AddMessage(ctx context.Context, message *Message, sourceEventID string) (bool, error) {
+	if !store.IsUUID(message.PartyID) {
+		return false, ErrMissingReference
+	}
+
+	err := r.pool.QueryRow(ctx, `
+		INSERT INTO watch_party_messages (party_id, user_id, message, source_event_id)
+		SELECT id, $2, $3, $4 FROM watch_parties
+		WHERE id = $1`+tenancy.Condition("tenant_id", 5)+`
+		ON CONFLICT (source_event_id) DO NOTHING
+		RETURNING id::text, created_at`,
+		message.PartyID, message.UserID, message.Text, sourceEventID, tenancy.Scope(ctx),
+	).Scan(&message.ID, &message.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if store.PgCode(err) == "23503" {
+		return false, ErrMissingReference
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to add watch party message: %w", err)
+	}
+	return true, nil
+}
+
+// Messages returns a party's latest messages
+func (r *PostgresRepository) Messages(ctx context.Context, partyID string, limit int) ([]*Message, error) {
+	if !store.IsUUID(partyID) {
+		return []*Message{}, nil
+	}
+
+	rows, err := r.pool.Query(ctx, `
+		SELECT id::text, party_id::text, user_id, message, created_at
+		FROM (
+			SELECT * FROM watch_party_messages
+			WHERE party_id = $1`+partyScope("party_id", 3)+`
+			ORDER BY created_at DESC, id
+			LIMIT $2
+		) latest
+		ORDER BY created_at, id`, partyID, limit, tenancy.Scope(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list watch party messages: %w", err)
+	}
+	defer rows.Close()
+
+	messages := []*Message{}
+	for rows.Next() {
+		var m Message
+		if err := rows.Scan(&m.ID, &m.PartyID, &m.UserID, &m.Text, &m.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan watch party message: %w", err)
+		}
+		messages = append(messages, &m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list watch party messages: %w", err)
+	}
+	return messages, nil
+}
+
+// scanParty reads a row selected with partyColumns
+func scanParty(row pgx.Row) (*Party, error) {
+	var p Party
+	if err := row.Scan(&p.ID, &p.VODID, &p.HostID, &p.CreatedAt, &p.EndedAt); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
@@ -0,0 +1,98 @@
+package parties
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// registryKeyPrefix namespaces running parties in Redis
+	registryKeyPrefix = "watch_party:"
+
+	// registryTTL is how long an idle party stays registered; each playback
+	// change extends it
+	registryTTL = 12 * time.Hour
+)
+
+// Registry shares running parties with the WebSocket servers through Redis:
+// who hosts each one and where its playback is, for viewers who join late
+type Registry struct {
+	client *redis.Client
+}
+
+// NewRegistry creates a party registry
+func NewRegistry(client *redis.Client) *Registry {
+	return &Registry{client: client}
+}
+
+// Open registers a party so its host can control playback
+func (r *Registry) Open(ctx context.Context, party *Party) error {
+	key := registryKeyPrefix + party.ID
+	pipe := r.client.TxPipeline()
+	pipe.HSet(ctx, key, "host_id", party.HostID, "vod_id", party.VODID)
+	pipe.Expire(ctx, key, registryTTL)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to register watch party: %w", err)
+	}
+	return nil
+}
+
+// Close unregisters a party; its room no longer accepts playback changes
+func (r *Registry) Close(ctx context.Context, partyID string) error {
+	if err := r.client.Del(ctx, registryKeyPrefix+partyID).Err(); err != nil {
+		return fmt.Errorf("failed to unregister watch party: %w", err)
+	}
+	return nil
+}
+
+// PartyHost returns the host of a running party, or "" if it isn't running
+func (r *Registry) PartyHost(ctx context.Context, partyID string) (string, error) {
+	host, err := r.client.HGet(ctx, registryKeyPrefix+partyID, "host_id").Result()
+	if errors.Is(err, redis.Nil) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get watch party host: %w", err)
+	}
+	return host, nil
+}
+
+// SavePlayback records a running party's latest playback state
+func (r *Registry) SavePlayback(ctx context.Context, partyID string, playback map[string]interface{}) error {
+	encoded, err := json.Marshal(playback)
+	if err != nil {
+		return fmt.Errorf("failed to encode watch party playback: %w", err)
+	}
+
+	key := registryKeyPrefix + partyID
+	pipe := r.client.TxPipeline()
+	pipe.HSet(ctx, key, "playback", encoded)
+	pipe.Expire(ctx, key, registryTTL)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to save watch party playback: %w", err)
+	}
+	return nil
+}
+
+// Playback returns a running party's latest playback state, or nil if the
+// host hasn't started playback or the party isn't running
+func (r *Registry) Playback(ctx context.Context, partyID string) (map[string]interface{}, error) {
+	encoded, err := r.client.HGet(ctx, registryKeyPrefix+partyID, "playback").Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get watch party playback: %w", err)
+	}
+
+	var playback map[string]interface{}
+	if err := json.Unmarshal([]byte(encoded), &playback); err != nil {
+		return nil, fmt.Errorf("failed to decode watch party playback: %w", err)
+	}
+	return playback, nil
+}
@@ -0,0 +1,139 @@
+package parties
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+
+	"github.com/tinle0301/streaming-platform-api/internal/events"
+	"github.com/tinle0301/streaming-platform-api/internal/highlights"
+	"github.com/tinle0301/streaming-platform-api/internal/tenancy"
+)
+
+// Message list limits
+const (
+	DefaultMessageLimit = 50
+	MaxMessageLimit     = 200
+)
+
+// Service errors
+var (
+	ErrVODNotFound = errors.New("vod not found")
+	ErrNotHost     = errors.New("only the host can end the watch party")
+)
+
+// VODSource looks up the VODs parties are created for
+type VODSource interface {
+	GetVOD(ctx context.Context, id string) (*highlights.VOD, error)
+}
+
+// Service creates and ends watch parties and stores their chat
+type Service struct {
+	repo     Repository
+	vods     VODSource
+	registry *Registry
+
+	publisher events.Publisher
+}
+
+// NewService creates a watch party service
+func NewService(repo Repository, vods VODSource, registry *Registry) *Service {
+	return &Service{repo: repo, vods: vods, registry: registry}
+}
+
+// SetPublisher enables party.ended events, which close parties' rooms
+func (s *Service) SetPublisher(publisher events.Publisher) {
+	s.publisher = publisher
+}
+
+// Create starts a party for a VOD hosted by hostID. Unpublished VODs, drafts
+// and premieres yet to air, can only be watched in parties their streamer
+// hosts.
+func (s *Service) Create(ctx context.Context, hostID, vodID string) (*Party, error) {
+	vod, err := s.vods.GetVOD(ctx, vodID)
+	if errors.Is(err, highlights.ErrNotFound) {
+		return nil, ErrVODNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if vod.Status != highlights.StatusPublic && vod.StreamerID != hostID {
+		return nil, ErrVODNotFound
+	}
+
+	party := &Party{VODID: vod.ID, HostID: hostID}
+	if err := s.repo.Create(ctx, party); err != nil {
+		return nil, err
+	}
+	if err := s.registry.Open(ctx, party); err != nil {
+		return nil, err
+	}
+
+	log.Printf("Watch party created: partyID=%s, vodID=%s, hostID=%s", party.ID, vod.ID, hostID)
+	return party, nil
+}
+
+// Get returns a party by ID
+func (s *Service) Get(ctx context.Context, id string) (*Party, error) {
+	return s.repo.Get(ctx, id)
+}
+
+// End stops a party. Its room stops accepting playback changes and
+// participants are sent party.ended.
+func (s *Service) End(ctx context.Context, id, userID string) (*Party, error) {
+	party, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if party.HostID != userID {
+		return nil, ErrNotHost
+	}
+	if !party.Active() {
+		return party, nil
+	}
+
+	if party, err = s.repo.End(ctx, id); err != nil {
+		return nil, err
+	}
+	if err := s.registry.Close(ctx, party.ID); err != nil {
+		log.Printf("Error unregistering watch party: partyID=%s, err=%v", party.ID, err)
+	}
+	if s.publisher != nil {
+		if err := s.publisher.Publish(ctx, events.NewPartyEndedEvent(party.ID, party.HostID)); err != nil {
+			log.Printf("Error publishing watch party end: partyID=%s, err=%v", party.ID, err)
+		}
+	}
+	return party, nil
+}
+
+// Messages returns a party's latest chat messages, oldest first
+func (s *Service) Messages(ctx context.Context, partyID string, limit int) ([]*Message, error) {
+	if limit <= 0 {
+		limit = DefaultMessageLimit
+	}
+	if limit > MaxMessageLimit {
+		limit = MaxMessageLimit
+	}
+	return s.repo.Messages(ctx, partyID, limit)
+}
+
+// HandleEvent stores party chat relayed by the WebSocket servers
+func (s *Service) HandleEvent(ctx context.Context, event events.Event) error {
+	if event.Type != events.EventTypePartyChatMessage {
+		return nil
+	}
+	partyID, _ := event.Data["party_id"].(string)
+	text, _ := event.Data["message"].(string)
+	if partyID == "" || event.UserID == "" || strings.TrimSpace(text) == "" {
+		return nil
+	}
+
+	// A message for a party deleted along with its VOD is dropped
+	_, err := s.repo.AddMessage(tenancy.EventContext(ctx, event),
+		&Message{PartyID: partyID, UserID: event.UserID, Text: text}, event.ID)
+	if errors.Is(err, ErrMissingReference) {
+		return nil
+	}
+	return err
+}
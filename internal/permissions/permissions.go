@@ -0,0 +1,140 @@
+// Package permissions derives the effective permission matrix from the
+// GraphQL schema. Fields gated by an organization role are annotated with
+// @requiresRole(role, resource); the resolvers enforce the rule, and the
+// annotation states it, so admin UIs and exports read the rules from the
+// schema instead of hardcoding them.
+package permissions
+
+import (
+	"fmt"
+	"sort"
+
+	gql "github.com/graph-gophers/graphql-go"
+	"github.com/graph-gophers/graphql-go/types"
+	"github.com/tinle0301/streaming-platform-api/internal/orgs"
+)
+
+// Directive is the schema directive annotating role-gated fields
+const Directive = "requiresRole"
+
+// Resources a role is checked against, matching the GraphQL
+// PermissionResource enum
+const (
+	// ResourceOrganization is checked against the viewer's role in the
+	// organization the field names
+	ResourceOrganization = "ORGANIZATION"
+
+	// ResourceChannel is always allowed to the channel's owner and otherwise
+	// checked against the viewer's role in the organization owning it
+	ResourceChannel = "CHANNEL"
+)
+
+// Resources lists the resources permissions are checked against
+var Resources = []string{ResourceOrganization, ResourceChannel}
+
+// Permission is one role-gated operation
+type Permission struct {
+	// Operation is the type and field, e.g. Mutation.inviteToOrganization
+	Operation   string    `json:"operation"`
+	Type        string    `json:"type"`
+	Field       string    `json:"field"`
+	Resource    string    `json:"resource"`
+	MinimumRole orgs.Role `json:"minimum_role"`
+
+	// Roles are the roles allowed, most privileged first
+	Roles []orgs.Role `json:"roles"`
+}
+
+// Allows reports whether role may perform the operation
+func (p Permission) Allows(role orgs.Role) bool {
+	for _, allowed := range p.Roles {
+		if allowed == role {
+			return true
+		}
+	}
+	return false
+}
+
+// Matrix is every role-gated operation with the roles allowed to perform it
+type Matrix struct {
+	Roles       []orgs.Role  `json:"roles"`
+	Resources   []string     `json:"resources"`
+	Permissions []Permission `json:"permissions"`
+}
+
+// Parse parses a schema and derives its matrix
+func Parse(sdl string) (*Matrix, error) {
+	schema, err := gql.ParseSchema(sdl, nil, gql.UseFieldResolvers())
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse schema: %w", err)
+	}
+	return FromSchema(schema.ASTSchema())
+}
+
+// FromSchema derives the matrix from a parsed schema's annotations,
+// ordered by operation
+func FromSchema(schema *types.Schema) (*Matrix, error) {
+	matrix := &Matrix{Roles: orgs.Roles, Resources: Resources, Permissions: []Permission{}}
+
+	for name, namedType := range schema.Types {
+		object, ok := namedType.(*types.ObjectTypeDefinition)
+		if !ok {
+			continue
+		}
+		for _, field := range object.Fields {
+			directive := field.Directives.Get(Directive)
+			if directive == nil {
+				continue
+			}
+			permission, err := permissionFor(name, field.Name, directive)
+			if err != nil {
+				return nil, err
+			}
+			matrix.Permissions = append(matrix.Permissions, permission)
+		}
+	}
+
+	sort.Slice(matrix.Permissions, func(i, j int) bool {
+		return matrix.Permissions[i].Operation < matrix.Permissions[j].Operation
+	})
+	return matrix, nil
+}
+
+// permissionFor reads a field's @requiresRole annotation
+func permissionFor(typeName, fieldName string, directive *types.Directive) (Permission, error) {
+	operation := typeName + "." + fieldName
+	role, _ := argument(directive, "role")
+	resource, _ := argument(directive, "resource")
+	if !orgs.Role(role).Valid() {
+		return Permission{}, fmt.Errorf("%s: unknown role %q", operation, role)
+	}
+	if resource != ResourceOrganization && resource != ResourceChannel {
+		return Permission{}, fmt.Errorf("%s: unknown resource %q", operation, resource)
+	}
+
+	minimum := orgs.Role(role)
+	var roles []orgs.Role
+	for _, candidate := range orgs.Roles {
+		if orgs.MostPrivileged(candidate, minimum) == candidate {
+			roles = append(roles, candidate)
+		}
+	}
+	return Permission{
+		Operation:   operation,
+		Type:        typeName,
+		Field:       fieldName,
+		Resource:    resource,
+		MinimumRole: minimum,
+		Roles:       roles,
+	}, nil
+}
+
+// argument returns a directive's enum or string argument
+func argument(directive *types.Directive, name string) (string, bool) {
+	value, ok := directive.Arguments.Get(name)
+	if !ok {
+		return "", false
+	}
+	s, ok := value.Deserialize(nil).(string)
+	return s, ok
+}
@@ -0,0 +1,211 @@
+// Package persistedqueries implements automatic persisted queries (APQ):
+// clients send the SHA-256 hash of a GraphQL document instead of the
+// document, registering it once with the hash when the server does not know
+// it yet. Registered documents are cached in Redis so every API server
+// knows them. An allowlist loaded from a persisted query manifest can be
+// served alongside the cache, or in allowlist-only mode instead of it, so
+// only the operations the clients were built with run.
+package persistedqueries
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// keyPrefix namespaces cached documents in Redis by hash
+const keyPrefix = "graphql:apq:"
+
+// Registry errors
+var (
+	ErrNotFound     = errors.New("persisted query not found")
+	ErrHashMismatch = errors.New("sha256Hash does not match the query")
+	ErrNotAllowed   = errors.New("query is not in the persisted query allowlist")
+)
+
+// Options configures persisted queries
+type Options struct {
+	// Enabled accepts persisted query hashes
+	Enabled bool
+
+	// TTL is how long a registered document stays cached after it was
+	// last registered (0 keeps it until Redis evicts it)
+	TTL time.Duration
+
+	// Manifest is the path of a persisted query manifest whose operations
+	// are always known
+	Manifest string
+
+	// AllowlistOnly runs only the manifest's operations: unknown hashes are
+	// not registered and plain queries are refused
+	AllowlistOnly bool
+}
+
+// DefaultOptions enables persisted queries cached for a day
+func DefaultOptions() Options {
+	return Options{Enabled: true, TTL: 24 * time.Hour}
+}
+
+// Cache stores registered documents by hash
+type Cache interface {
+	// Get returns the document for hash, or ErrNotFound
+	Get(ctx context.Context, hash string) (string, error)
+	Put(ctx context.Context, hash, query string) error
+}
+
+// Hash returns the hex SHA-256 hash clients send for query
+func Hash(query string) string {
+	sum := sha256.Sum256([]byte(query))
+	return hex.EncodeToString(sum[:])
+}
+
+// validHash reports whether hash is a lowercase hex SHA-256 hash
+func validHash(hash string) bool {
+	if len(hash) != sha256.Size*2 {
+		return false
+	}
+	for _, c := range hash {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// Registry resolves hashes to documents from the allowlist and the cache
+type Registry struct {
+	cache         Cache
+	allowlist     map[string]string
+	allowlistOnly bool
+}
+
+// New creates a registry caching documents in cache, loading opts.Manifest
+// into the allowlist. The cache may be nil in allowlist-only mode.
+func New(cache Cache, opts Options) (*Registry, error) {
+	r := &Registry{cache: cache, allowlist: map[string]string{}, allowlistOnly: opts.AllowlistOnly}
+	if opts.Manifest != "" {
+		allowlist, err := LoadManifest(opts.Manifest)
+		if err != nil {
+			return nil, err
+		}
+		r.allowlist = allowlist
+	}
+	if cache == nil && !r.allowlistOnly {
+		return nil, errors.New("persisted queries need a cache unless allowlist-only")
+	}
+	return r, nil
+}
+
+// AllowlistOnly reports whether only allowlisted operations may run
+func (r *Registry) AllowlistOnly() bool {
+	return r.allowlistOnly
+}
+
+// Lookup returns the document for hash
+func (r *Registry) Lookup(ctx context.Context, hash string) (string, error) {
+	if query, ok := r.allowlist[hash]; ok {
+		return query, nil
+	}
+	if r.allowlistOnly || !validHash(hash) {
+		return "", ErrNotFound
+	}
+	return r.cache.Get(ctx, hash)
+}
+
+// Register records query under hash, checking the hash matches. In
+// allowlist-only mode it only accepts allowlisted queries.
+func (r *Registry) Register(ctx context.Context, hash, query string) error {
+	if Hash(query) != hash {
+		return ErrHashMismatch
+	}
+	if _, ok := r.allowlist[hash]; ok {
+		return nil
+	}
+	if r.allowlistOnly {
+		return ErrNotAllowed
+	}
+	return r.cache.Put(ctx, hash, query)
+}
+
+// Allowed reports whether query may run as a plain query
+func (r *Registry) Allowed(query string) bool {
+	if !r.allowlistOnly {
+		return true
+	}
+	_, ok := r.allowlist[Hash(query)]
+	return ok
+}
+
+// manifest is the Apollo persisted query manifest format
+type manifest struct {
+	Format     string `json:"format"`
+	Version    int    `json:"version"`
+	Operations []struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+		Body string `json:"body"`
+	} `json:"operations"`
+}
+
+// LoadManifest reads a persisted query manifest and returns its documents
+// by hash. Each operation's ID must be the SHA-256 hash of its body.
+func LoadManifest(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read persisted query manifest: %w", err)
+	}
+	var m manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("failed to parse persisted query manifest: %w", err)
+	}
+	if m.Format != "apollo-persisted-query-manifest" || m.Version != 1 {
+		return nil, fmt.Errorf("unsupported persisted query manifest %q version %d", m.Format, m.Version)
+	}
+
+	allowlist := make(map[string]string, len(m.Operations))
+	for _, op := range m.Operations {
+		if Hash(op.Body) != op.ID {
+			return nil, fmt.Errorf("persisted query manifest operation %s: id is not the SHA-256 hash of its body", op.Name)
+		}
+		allowlist[op.ID] = op.Body
+	}
+	return allowlist, nil
+}
+
+// RedisCache caches registered documents in Redis
+type RedisCache struct {
+	client *redis.Client
+	ttl    time.Duration
+}
+
+// NewRedisCache creates a Redis cache keeping documents for ttl
+func NewRedisCache(client *redis.Client, ttl time.Duration) *RedisCache {
+	return &RedisCache{client: client, ttl: ttl}
+}
+
+// Get returns the document cached for hash
+func (c *RedisCache) Get(ctx context.Context, hash string) (string, error) {
+	query, err := c.client.Get(ctx, keyPrefix+hash).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", ErrNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to load persisted query: %w", err)
+	}
+	return query, nil
+}
+
+// Put caches query under hash, renewing its TTL
+func (c *RedisCache) Put(ctx context.Context, hash, query string) error {
+	if err := c.client.Set(ctx, keyPrefix+hash, query, c.ttl).Err(); err != nil {
+		return fmt.Errorf("failed to save persisted query: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,304 @@
+// Package premieres airs VODs once at a scheduled time. Countdown events
+// lead up to the premiere; while it airs, the server broadcasts the playback
+// position everyone in the premiere's room should be at; afterwards the VOD
+// becomes an ordinary public VOD.
+package premieres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/tinle0301/streaming-platform-api/internal/store"
+	"github.com/tinle0301/streaming-platform-api/internal/tenancy"
+)
+
+// Premiere statuses
+const (
+	StatusScheduled = "SCHEDULED"
+	StatusLive      = "LIVE"
+	StatusEnded     = "ENDED"
+	StatusCancelled = "CANCELLED"
+)
+
+// Repository errors
+var (
+	ErrNotFound         = errors.New("premiere not found")
+	ErrAlreadyScheduled = errors.New("vod already has a premiere")
+	ErrNotDraft         = errors.New("only draft VODs can premiere")
+	ErrNotScheduled     = errors.New("premiere has already started")
+)
+
+// Premiere is a VOD's scheduled first airing
+type Premiere struct {
+	VODID       string
+	ScheduledAt time.Time
+	Status      string
+	StartedAt   *time.Time
+	EndedAt     *time.Time
+	CreatedAt   time.Time
+
+	// TenantID is the white-label tenant the premiere airs in
+	TenantID string
+}
+
+// Repository persists premieres. The state changes are conditional updates,
+// so when several API replicas run the scheduler only one of them sends
+// each countdown, start, playback sync, and end. Premieres are created in
+// the context's tenant, and other tenants' premieres and VODs are treated
+// as missing.
+type Repository interface {
+	// Create schedules a premiere and moves its draft VOD to PREMIERE
+	Create(ctx context.Context, premiere *Premiere) error
+
+	Get(ctx context.Context, vodID string) (*Premiere, error)
+
+	// Cancel cancels a premiere that hasn't started and returns its VOD to
+	// DRAFT
+	Cancel(ctx context.Context, vodID string) (*Premiere, error)
+
+	// Upcoming returns scheduled premieres due by the given time
+	Upcoming(ctx context.Context, by time.Time) ([]*Premiere, error)
+
+	// Live returns the premieres airing now
+	Live(ctx context.Context) ([]*Premiere, error)
+
+	// ClaimCountdown reports whether the countdown mark is due to be
+	// announced, recording it as announced
+	ClaimCountdown(ctx context.Context, vodID string, mark time.Duration) (bool, error)
+
+	// Start moves a scheduled premiere to LIVE, reporting false if another
+	// caller already did
+	Start(ctx context.Context, vodID string, at time.Time) (bool, error)
+
+	// ClaimSync reports whether a playback sync is due, at most one per
+	// interval, recording it as sent
+	ClaimSync(ctx context.Context, vodID string, at time.Time, interval time.Duration) (bool, error)
+
+	// Finish ends a live premiere and publishes its VOD, reporting false if
+	// another caller already did
+	Finish(ctx context.Context, vodID string, at time.Time) (bool, error)
+}
+
+// premiereColumns is the column list shared by premiere queries
+const premiereColumns = `vod_id::text, scheduled_at, status, started_at, ended_at, created_at, tenant_id`
+
+// PostgresRepository implements Repository on PostgreSQL
+type PostgresRepository struct {
+	pool *pgxpool.Pool
+}
+
+// NewPostgresRepository creates a premiere repository on pool
+func NewPostgresRepository(pool *pgxpool.Pool) *PostgresRepository {
+	return &PostgresRepository{pool: pool}
+}
+
+// Create inserts a premiere and moves its VOD to PREMIERE in one transaction
+func (r *PostgresRepository) Create(ctx context.Context, premiere *Premiere) error {
+	if !store.IsUUID(premiere.VODID) {
+		return ErrNotFound
+	}
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, `UPDATE vods SET status = 'PREMIERE', updated_at = NOW()
+		WHERE id = $1 AND status = 'DRAFT'`+tenancy.Condition("tenant_id", 2), premiere.VODID, tenancy.Scope(ctx))
+	if err != nil {
+		return fmt.Errorf("failed to schedule premiere: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotDraft
+	}
+
+	// A cancelled premiere's row is reused
+	row := tx.QueryRow(ctx, `
+		INSERT INTO vod_premieres (vod_id, scheduled_at, tenant_id)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (vod_id) DO UPDATE SET
+			scheduled_at = EXCLUDED.scheduled_at, status = 'SCHEDULED', countdown_sent_ms = NULL,
+			started_at = NULL, last_sync_at = NULL, ended_at = NULL, created_at = NOW()
+		WHERE vod_premieres.status = 'CANCELLED'
+		RETURNING `+premiereColumns,
+		premiere.VODID, premiere.ScheduledAt, tenancy.ID(ctx))
+	saved, err := scanPremiere(row)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrAlreadyScheduled
+	}
+	if err != nil {
+		return fmt.Errorf("failed to schedule premiere: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit premiere: %w", err)
+	}
+	*premiere = *saved
+	return nil
+}
+
+// Get returns a VOD's premiere
+func (r *PostgresRepository) Get(ctx context.Context, vodID string) (*Premiere, error) {
+	if !store.IsUUID(vodID) {
+		return nil, ErrNotFound
+	}
+
+	premiere, err := scanPremiere(r.pool.QueryRow(ctx, `SELECT `+premiereColumns+` FROM vod_premieres
+		WHERE vod_id = $1`+tenancy.Condition("tenant_id", 2), vodID, tenancy.Scope(ctx)))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get premiere: %w", err)
+	}
+	return premiere, nil
+}
+
+// Cancel cancels a scheduled premiere in one transaction with its VOD
+func (r *PostgresRepository) Cancel(ctx context.Context, vodID string) (*Premiere, error) {
+	if !store.IsUUID(vodID) {
+		return nil, ErrNotFound
+	}
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	premiere, err := scanPremiere(tx.QueryRow(ctx, `
+		UPDATE vod_premieres SET status = 'CANCELLED'
+		WHERE vod_id = $1 AND status = 'SCHEDULED'`+tenancy.Condition("tenant_id", 2)+`
+		RETURNING `+premiereColumns, vodID, tenancy.Scope(ctx)))
+	if errors.Is(err, pgx.ErrNoRows) {
+		if _, err := r.Get(ctx, vodID); err != nil {
+			return nil, err
+		}
+		return nil, ErrNotScheduled
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to cancel premiere: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, `UPDATE vods SET status = 'DRAFT', updated_at = NOW() WHERE id = $1 AND status = 'PREMIERE'`, vodID); err != nil {
+		return nil, fmt.Errorf("failed to cancel premiere: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit premiere cancellation: %w", err)
+	}
+	return premiere, nil
+}
+
+// Upcoming lists scheduled premieres due by the given time
+func (r *PostgresRepository) Upcoming(ctx context.Context, by time.Time) ([]*Premiere, error) {
+	return r.list(ctx, `SELECT `+premiereColumns+` FROM vod_premieres
+		WHERE status = 'SCHEDULED' AND scheduled_at <= $1`+tenancy.Condition("tenant_id", 2)+`
+		ORDER BY scheduled_at`, by, tenancy.Scope(ctx))
+}
+
+// Live lists the premieres airing now
+func (r *PostgresRepository) Live(ctx context.Context) ([]*Premiere, error) {
+	return r.list(ctx, `SELECT `+premiereColumns+` FROM vod_premieres
+		WHERE status = 'LIVE'`+tenancy.Condition("tenant_id", 1)+`
+		ORDER BY started_at`, tenancy.Scope(ctx))
+}
+
+// ClaimCountdown records a countdown mark unless a smaller one was announced
+func (r *PostgresRepository) ClaimCountdown(ctx context.Context, vodID string, mark time.Duration) (bool, error) {
+	tag, err := r.pool.Exec(ctx, `
+		UPDATE vod_premieres SET countdown_sent_ms = $2
+		WHERE vod_id = $1 AND status = 'SCHEDULED' AND (countdown_sent_ms IS NULL OR countdown_sent_ms > $2)`+
+		tenancy.Condition("tenant_id", 3), vodID, mark.Milliseconds(), tenancy.Scope(ctx))
+	if err != nil {
+		return false, fmt.Errorf("failed to claim premiere countdown: %w", err)
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+// Start moves a premiere to LIVE
+func (r *PostgresRepository) Start(ctx context.Context, vodID string, at time.Time) (bool, error) {
+	tag, err := r.pool.Exec(ctx, `
+		UPDATE vod_premieres SET status = 'LIVE', started_at = $2, last_sync_at = $2
+		WHERE vod_id = $1 AND status = 'SCHEDULED'`+tenancy.Condition("tenant_id", 3),
+		vodID, at, tenancy.Scope(ctx))
+	if err != nil {
+		return false, fmt.Errorf("failed to start premiere: %w", err)
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+// ClaimSync records a playback sync unless one was sent within interval
+func (r *PostgresRepository) ClaimSync(ctx context.Context, vodID string, at time.Time, interval time.Duration) (bool, error) {
+	tag, err := r.pool.Exec(ctx, `
+		UPDATE vod_premieres SET last_sync_at = $2
+		WHERE vod_id = $1 AND status = 'LIVE' AND last_sync_at <= $3`+tenancy.Condition("tenant_id", 4),
+		vodID, at, at.Add(-interval), tenancy.Scope(ctx))
+	if err != nil {
+		return false, fmt.Errorf("failed to claim premiere sync: %w", err)
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+// Finish ends a premiere and publishes its VOD in one transaction
+func (r *PostgresRepository) Finish(ctx context.Context, vodID string, at time.Time) (bool, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, `
+		UPDATE vod_premieres SET status = 'ENDED', ended_at = $2
+		WHERE vod_id = $1 AND status = 'LIVE'`+tenancy.Condition("tenant_id", 3),
+		vodID, at, tenancy.Scope(ctx))
+	if err != nil {
+		return false, fmt.Errorf("failed to end premiere: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return false, nil
+	}
+
+	if _, err := tx.Exec(ctx, `UPDATE vods SET status = 'PUBLIC', updated_at = NOW() WHERE id = $1 AND status = 'PREMIERE'`, vodID); err != nil {
+		return false, fmt.Errorf("failed to publish premiered vod: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return false, fmt.Errorf("failed to commit premiere end: %w", err)
+	}
+	return true, nil
+}
+
+// list runs a premiere query
+func (r *PostgresRepository) list(ctx context.Context, query string, args ...interface{}) ([]*Premiere, error) {
+	rows, err := r.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list premieres: %w", err)
+	}
+	defer rows.Close()
+
+	premieres := []*Premiere{}
+	for rows.Next() {
+		premiere, err := scanPremiere(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan premiere: %w", err)
+		}
+		premieres = append(premieres, premiere)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list premieres: %w", err)
+	}
+	return premieres, nil
+}
+
+// scanPremiere reads a row selected with premiereColumns
+func scanPremiere(row pgx.Row) (*Premiere, error) {
+	var p Premiere
+	if err := row.Scan(&p.VODID, &p.ScheduledAt, &p.Status, &p.StartedAt, &p.EndedAt, &p.CreatedAt, &p.TenantID); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
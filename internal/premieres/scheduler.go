@@ -0,0 +1,215 @@
+package premieres
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/tinle0301/streaming-platform-api/internal/events"
+	"github.com/tinle0301/streaming-platform-api/internal/highlights"
+	"github.com/tinle0301/streaming-platform-api/internal/tenancy"
+)
+
+// countdownMarks are the times before a premiere its countdown is announced,
+// longest first
+var countdownMarks = []time.Duration{
+	time.Hour,
+	30 * time.Minute,
+	10 * time.Minute,
+	5 * time.Minute,
+	time.Minute,
+	30 * time.Second,
+	10 * time.Second,
+}
+
+// DefaultSyncInterval is how often a live premiere's playback position is
+// broadcast when no interval is configured
+const DefaultSyncInterval = 5 * time.Second
+
+// Scheduler airs premieres: it announces countdowns, starts premieres on
+// time, broadcasts the playback position viewers should be at, and ends
+// them when the VOD runs out
+type Scheduler struct {
+	repo         Repository
+	vods         VODSource
+	publisher    events.Publisher
+	syncInterval time.Duration
+
+	// Durations of live premieres' VODs; only Run's goroutine uses it
+	durations map[string]time.Duration
+}
+
+// NewScheduler creates a premiere scheduler. Replicas sharing repo take
+// turns, so each event is published once.
+func NewScheduler(repo Repository, vods VODSource, publisher events.Publisher, syncInterval time.Duration) *Scheduler {
+	if syncInterval <= 0 {
+		syncInterval = DefaultSyncInterval
+	}
+	return &Scheduler{
+		repo:         repo,
+		vods:         vods,
+		publisher:    publisher,
+		syncInterval: syncInterval,
+		durations:    make(map[string]time.Duration),
+	}
+}
+
+// RunOnce announces due countdowns, starts due premieres, and syncs or ends
+// live ones. It returns how many events were published.
+func (s *Scheduler) RunOnce(ctx context.Context) (int, error) {
+	now := time.Now()
+	published := 0
+
+	upcoming, err := s.repo.Upcoming(ctx, now.Add(countdownMarks[0]))
+	if err != nil {
+		return published, err
+	}
+	for _, premiere := range upcoming {
+		sent, err := s.advance(ctx, premiere, now)
+		if err != nil {
+			log.Printf("Error advancing premiere: vodID=%s, err=%v", premiere.VODID, err)
+		}
+		published += sent
+	}
+
+	live, err := s.repo.Live(ctx)
+	if err != nil {
+		return published, err
+	}
+	durations := make(map[string]time.Duration, len(live))
+	for _, premiere := range live {
+		sent, err := s.air(ctx, premiere, now, durations)
+		if err != nil {
+			log.Printf("Error airing premiere: vodID=%s, err=%v", premiere.VODID, err)
+		}
+		published += sent
+	}
+
+	// Premieres that ended drop out here
+	s.durations = durations
+	return published, nil
+}
+
+// Run airs premieres every interval until ctx is done
+func (s *Scheduler) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := s.RunOnce(ctx); err != nil {
+				log.Printf("Premiere scheduling failed: err=%v", err)
+			}
+		}
+	}
+}
+
+// advance starts a scheduled premiere that is due, or announces the
+// countdown mark it has reached
+func (s *Scheduler) advance(ctx context.Context, premiere *Premiere, now time.Time) (int, error) {
+	// The premiere's updates and events stay within its tenant
+	ctx = tenancy.WithTenant(ctx, premiere.TenantID)
+	remaining := premiere.ScheduledAt.Sub(now)
+	if remaining <= 0 {
+		started, err := s.repo.Start(ctx, premiere.VODID, now)
+		if err != nil || !started {
+			return 0, err
+		}
+		duration, err := s.duration(ctx, premiere.VODID)
+		if err != nil {
+			return 0, err
+		}
+
+		log.Printf("Premiere started: vodID=%s", premiere.VODID)
+		return 1, s.publish(ctx, events.EventTypePremiereStarted, premiere.VODID, map[string]interface{}{
+			"started_at":  now.UTC().Format(time.RFC3339Nano),
+			"position_ms": int64(0),
+			"duration_ms": duration.Milliseconds(),
+		})
+	}
+
+	mark, ok := currentMark(remaining)
+	if !ok {
+		return 0, nil
+	}
+	claimed, err := s.repo.ClaimCountdown(ctx, premiere.VODID, mark)
+	if err != nil || !claimed {
+		return 0, err
+	}
+	return 1, s.publish(ctx, events.EventTypePremiereCountdown, premiere.VODID, map[string]interface{}{
+		"scheduled_at": premiere.ScheduledAt.UTC().Format(time.RFC3339),
+		"remaining_ms": remaining.Milliseconds(),
+	})
+}
+
+// air ends a live premiere whose VOD has run out, or broadcasts its playback
+// position when a sync is due
+func (s *Scheduler) air(ctx context.Context, premiere *Premiere, now time.Time, durations map[string]time.Duration) (int, error) {
+	ctx = tenancy.WithTenant(ctx, premiere.TenantID)
+	if premiere.StartedAt == nil {
+		return 0, nil
+	}
+	duration, err := s.duration(ctx, premiere.VODID)
+	if errors.Is(err, highlights.ErrNotFound) {
+		// The VOD was deleted along with its premiere
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	durations[premiere.VODID] = duration
+
+	position := now.Sub(*premiere.StartedAt)
+	if position >= duration {
+		finished, err := s.repo.Finish(ctx, premiere.VODID, now)
+		if err != nil || !finished {
+			return 0, err
+		}
+
+		log.Printf("Premiere ended: vodID=%s", premiere.VODID)
+		return 1, s.publish(ctx, events.EventTypePremiereEnded, premiere.VODID, map[string]interface{}{
+			"cancelled": false,
+		})
+	}
+
+	claimed, err := s.repo.ClaimSync(ctx, premiere.VODID, now, s.syncInterval)
+	if err != nil || !claimed {
+		return 0, err
+	}
+	return 1, s.publish(ctx, events.EventTypePremiereSync, premiere.VODID, map[string]interface{}{
+		"position_ms": position.Milliseconds(),
+		"duration_ms": duration.Milliseconds(),
+		"server_time": now.UTC().Format(time.RFC3339Nano),
+	})
+}
+
+// duration returns the length of a premiere's VOD, cached while it airs
+func (s *Scheduler) duration(ctx context.Context, vodID string) (time.Duration, error) {
+	if duration, ok := s.durations[vodID]; ok {
+		return duration, nil
+	}
+	vod, err := s.vods.GetVOD(ctx, vodID)
+	if err != nil {
+		return 0, err
+	}
+	return vod.Duration(), nil
+}
+
+// publish sends a premiere event to the premiere's room
+func (s *Scheduler) publish(ctx context.Context, eventType, vodID string, data map[string]interface{}) error {
+	return s.publisher.Publish(ctx, events.NewPremiereEvent(eventType, vodID, data))
+}
+
+// currentMark returns the shortest countdown mark at or above remaining
+func currentMark(remaining time.Duration) (time.Duration, bool) {
+	for i := len(countdownMarks) - 1; i >= 0; i-- {
+		if countdownMarks[i] >= remaining {
+			return countdownMarks[i], true
+		}
+	}
+	return 0, false
+}
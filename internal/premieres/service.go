@@ -0,0 +1,109 @@
+package premieres
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/tinle0301/streaming-platform-api/internal/events"
+	"github.com/tinle0301/streaming-platform-api/internal/highlights"
+)
+
+// Scheduling limits
+const (
+	MinLeadTime = time.Minute
+	MaxLeadTime = 30 * 24 * time.Hour
+)
+
+// Service errors
+var (
+	ErrVODNotFound     = errors.New("vod not found")
+	ErrNotOwner        = errors.New("only the VOD's streamer can schedule its premiere")
+	ErrInvalidSchedule = errors.New("premieres must be scheduled between one minute and 30 days ahead")
+)
+
+// VODSource looks up the VODs premieres air
+type VODSource interface {
+	GetVOD(ctx context.Context, id string) (*highlights.VOD, error)
+}
+
+// Service schedules and cancels premieres
+type Service struct {
+	repo Repository
+	vods VODSource
+
+	publisher events.Publisher
+}
+
+// NewService creates a premiere service
+func NewService(repo Repository, vods VODSource) *Service {
+	return &Service{repo: repo, vods: vods}
+}
+
+// SetPublisher enables premiere.ended events for cancelled premieres
+func (s *Service) SetPublisher(publisher events.Publisher) {
+	s.publisher = publisher
+}
+
+// Schedule sets a draft VOD to premiere at the given time. Until it ends the
+// VOD is listed as PREMIERE and can't be watched on demand.
+func (s *Service) Schedule(ctx context.Context, userID, vodID string, at time.Time) (*Premiere, error) {
+	if _, err := s.ownVOD(ctx, userID, vodID); err != nil {
+		return nil, err
+	}
+	lead := time.Until(at)
+	if lead < MinLeadTime || lead > MaxLeadTime {
+		return nil, ErrInvalidSchedule
+	}
+
+	premiere := &Premiere{VODID: vodID, ScheduledAt: at.UTC()}
+	if err := s.repo.Create(ctx, premiere); err != nil {
+		return nil, err
+	}
+
+	log.Printf("Premiere scheduled: vodID=%s, scheduledAt=%s", vodID, premiere.ScheduledAt.Format(time.RFC3339))
+	return premiere, nil
+}
+
+// Cancel calls off a premiere that hasn't started and returns its VOD to
+// draft. Viewers waiting in the premiere's room are sent premiere.ended.
+func (s *Service) Cancel(ctx context.Context, userID, vodID string) (*Premiere, error) {
+	if _, err := s.ownVOD(ctx, userID, vodID); err != nil {
+		return nil, err
+	}
+
+	premiere, err := s.repo.Cancel(ctx, vodID)
+	if err != nil {
+		return nil, err
+	}
+	if s.publisher != nil {
+		event := events.NewPremiereEvent(events.EventTypePremiereEnded, vodID, map[string]interface{}{
+			"cancelled": true,
+		})
+		if err := s.publisher.Publish(ctx, event); err != nil {
+			log.Printf("Error publishing premiere cancellation: vodID=%s, err=%v", vodID, err)
+		}
+	}
+	return premiere, nil
+}
+
+// Get returns a VOD's premiere
+func (s *Service) Get(ctx context.Context, vodID string) (*Premiere, error) {
+	return s.repo.Get(ctx, vodID)
+}
+
+// ownVOD returns a VOD if userID streamed it
+func (s *Service) ownVOD(ctx context.Context, userID, vodID string) (*highlights.VOD, error) {
+	vod, err := s.vods.GetVOD(ctx, vodID)
+	if errors.Is(err, highlights.ErrNotFound) {
+		return nil, ErrVODNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if vod.StreamerID != userID {
+		return nil, ErrNotOwner
+	}
+	return vod, nil
+}
@@ -0,0 +1,380 @@
+// Package presence tracks who is in each stream room across WebSocket
+// nodes. Each node records its rooms' members in Redis sorted sets scored by
+// expiry and refreshes them with heartbeats, so a node that dies without
+// cleaning up drops out once its entries expire. Joins are also counted per
+// minute as views for stream analytics.
+package presence
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// keyPrefix namespaces presence sets in Redis
+	keyPrefix = "presence:"
+
+	// guestMemberPrefix marks anonymous viewers within a presence set
+	guestMemberPrefix = "guest:"
+
+	// viewsKeyPrefix namespaces the per-minute view counts in Redis
+	viewsKeyPrefix = "presence_views:"
+
+	// viewsRetention is how long view counts are kept for analytics to collect
+	viewsRetention = 24 * time.Hour
+
+	// flushInterval is how often joins and leaves are written to Redis
+	flushInterval = time.Second
+)
+
+// Options tunes presence heartbeats
+type Options struct {
+	// TTL is how long an entry survives without a heartbeat
+	TTL time.Duration
+
+	// HeartbeatInterval must be well under TTL
+	HeartbeatInterval time.Duration
+}
+
+// DefaultOptions returns the options used when none are configured
+func DefaultOptions() Options {
+	return Options{
+		TTL:               45 * time.Second,
+		HeartbeatInterval: 15 * time.Second,
+	}
+}
+
+// Viewers is who is in a stream room right now
+type Viewers struct {
+	// UserIDs are the signed-in viewers, sorted
+	UserIDs []string
+
+	// Guests is how many anonymous viewers are present
+	Guests int
+}
+
+// Total is the number of distinct viewers, signed in or not
+func (v Viewers) Total() int {
+	return len(v.UserIDs) + v.Guests
+}
+
+// Store reads presence from Redis
+type Store struct {
+	client *redis.Client
+}
+
+// NewStore creates a presence reader on client
+func NewStore(client *redis.Client) *Store {
+	return &Store{client: client}
+}
+
+// GetViewers returns the viewers whose presence in streamID has not expired
+func (s *Store) GetViewers(ctx context.Context, streamID string) (Viewers, error) {
+	members, err := s.client.ZRangeByScore(ctx, presenceKey(streamID), &redis.ZRangeBy{
+		Min: fmt.Sprintf("(%d", time.Now().UnixMilli()),
+		Max: "+inf",
+	}).Result()
+	if err != nil {
+		return Viewers{}, fmt.Errorf("failed to read presence: %w", err)
+	}
+
+	viewers := Viewers{UserIDs: make([]string, 0, len(members))}
+	for _, member := range members {
+		if strings.HasPrefix(member, guestMemberPrefix) {
+			viewers.Guests++
+			continue
+		}
+		viewers.UserIDs = append(viewers.UserIDs, member)
+	}
+	sort.Strings(viewers.UserIDs)
+	return viewers, nil
+}
+
+// Counts returns the number of unexpired viewers in each stream
+func (s *Store) Counts(ctx context.Context, streamIDs []string) (map[string]int, error) {
+	min := fmt.Sprintf("(%d", time.Now().UnixMilli())
+	pipe := s.client.Pipeline()
+	cmds := make(map[string]*redis.IntCmd, len(streamIDs))
+	for _, streamID := range streamIDs {
+		cmds[streamID] = pipe.ZCount(ctx, presenceKey(streamID), min, "+inf")
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, fmt.Errorf("failed to count presence: %w", err)
+	}
+
+	counts := make(map[string]int, len(cmds))
+	for streamID, cmd := range cmds {
+		counts[streamID] = int(cmd.Val())
+	}
+	return counts, nil
+}
+
+// Views returns how many viewers joined a stream during the minute starting
+// at minute. A viewer counts once per join, across all nodes.
+func (s *Store) Views(ctx context.Context, streamID string, minute time.Time) (int, error) {
+	views, err := s.client.HGet(ctx, viewsKey(streamID), strconv.FormatInt(minute.Unix(), 10)).Int()
+	if errors.Is(err, redis.Nil) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to read views: %w", err)
+	}
+	return views, nil
+}
+
+// Tracker records this node's room members in Redis. The hub reports joins
+// and leaves through RoomJoined and RoomLeft; Run writes them out, heartbeats
+// every member, and caches cluster-wide counts for viewer_count broadcasts.
+type Tracker struct {
+	*Store
+	opts Options
+
+	mu sync.Mutex
+	// room -> member -> connections on this node
+	local map[string]map[string]int
+	// members that left since the last flush, to remove from Redis
+	left map[string]map[string]bool
+	// members that joined since the last flush, to add to Redis
+	joined map[string]map[string]bool
+	// cluster-wide viewers per local room, as of the last heartbeat
+	totals map[string]int
+}
+
+// NewTracker creates a tracker writing presence to client
+func NewTracker(client *redis.Client, opts Options) *Tracker {
+	return &Tracker{
+		Store:  NewStore(client),
+		opts:   opts,
+		local:  make(map[string]map[string]int),
+		left:   make(map[string]map[string]bool),
+		joined: make(map[string]map[string]bool),
+		totals: make(map[string]int),
+	}
+}
+
+// RoomJoined records a connection joining a room. It is called with the
+// hub's lock held, so it only updates memory.
+func (t *Tracker) RoomJoined(room, userID string, guest bool) {
+	member := memberID(userID, guest)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.local[room] == nil {
+		t.local[room] = make(map[string]int)
+	}
+	t.local[room][member]++
+	if t.local[room][member] == 1 {
+		mark(t.joined, room, member)
+		unmark(t.left, room, member)
+	}
+}
+
+// RoomLeft records a connection leaving a room. The member is removed from
+// Redis once its last connection on this node leaves; if it is still in the
+// room on another node, that node's next heartbeat restores it.
+func (t *Tracker) RoomLeft(room, userID string, guest bool) {
+	member := memberID(userID, guest)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	members, ok := t.local[room]
+	if !ok || members[member] == 0 {
+		return
+	}
+	members[member]--
+	if members[member] > 0 {
+		return
+	}
+
+	delete(members, member)
+	if len(members) == 0 {
+		delete(t.local, room)
+		delete(t.totals, room)
+	}
+	mark(t.left, room, member)
+	unmark(t.joined, room, member)
+}
+
+// ViewerCount returns a room's cluster-wide viewer count as of the last
+// heartbeat; ok is false until the room has been counted
+func (t *Tracker) ViewerCount(room string) (int, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	count, ok := t.totals[room]
+	return count, ok
+}
+
+// Run flushes joins and leaves and heartbeats this node's members until ctx
+// is cancelled, then removes them
+func (t *Tracker) Run(ctx context.Context) {
+	flush := time.NewTicker(flushInterval)
+	defer flush.Stop()
+	heartbeat := time.NewTicker(t.opts.HeartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			t.removeAll()
+			return
+		case <-flush.C:
+			t.flush(ctx)
+		case <-heartbeat.C:
+			t.heartbeat(ctx)
+		}
+	}
+}
+
+// flush writes pending joins and leaves
+func (t *Tracker) flush(ctx context.Context) {
+	t.mu.Lock()
+	joined, left := t.joined, t.left
+	t.joined = make(map[string]map[string]bool)
+	t.left = make(map[string]map[string]bool)
+	t.mu.Unlock()
+
+	if len(joined) == 0 && len(left) == 0 {
+		return
+	}
+
+	now := time.Now()
+	expiry := float64(now.Add(t.opts.TTL).UnixMilli())
+	minute := strconv.FormatInt(now.Truncate(time.Minute).Unix(), 10)
+	pipe := t.client.Pipeline()
+	for room, members := range joined {
+		key := presenceKey(room)
+		for member := range members {
+			pipe.ZAdd(ctx, key, redis.Z{Score: expiry, Member: member})
+		}
+		pipe.Expire(ctx, key, t.opts.TTL)
+
+		// Joins are also counted as views for stream analytics
+		pipe.HIncrBy(ctx, viewsKey(room), minute, int64(len(members)))
+		pipe.Expire(ctx, viewsKey(room), viewsRetention)
+	}
+	for room, members := range left {
+		for member := range members {
+			pipe.ZRem(ctx, presenceKey(room), member)
+		}
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		log.Printf("Error flushing presence: %v", err)
+	}
+}
+
+// heartbeat extends every local member's expiry, prunes expired entries,
+// and refreshes the cached counts
+func (t *Tracker) heartbeat(ctx context.Context) {
+	t.mu.Lock()
+	rooms := make(map[string][]string, len(t.local))
+	for room, members := range t.local {
+		for member := range members {
+			rooms[room] = append(rooms[room], member)
+		}
+	}
+	t.mu.Unlock()
+
+	if len(rooms) == 0 {
+		return
+	}
+
+	now := time.Now()
+	expiry := float64(now.Add(t.opts.TTL).UnixMilli())
+	pipe := t.client.Pipeline()
+	roomIDs := make([]string, 0, len(rooms))
+	for room, members := range rooms {
+		key := presenceKey(room)
+		entries := make([]redis.Z, 0, len(members))
+		for _, member := range members {
+			entries = append(entries, redis.Z{Score: expiry, Member: member})
+		}
+		pipe.ZAdd(ctx, key, entries...)
+		pipe.ZRemRangeByScore(ctx, key, "-inf", fmt.Sprintf("%d", now.UnixMilli()))
+		pipe.Expire(ctx, key, t.opts.TTL)
+		roomIDs = append(roomIDs, room)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		log.Printf("Error heartbeating presence: %v", err)
+		return
+	}
+
+	counts, err := t.Counts(ctx, roomIDs)
+	if err != nil {
+		log.Printf("Error refreshing presence counts: %v", err)
+		return
+	}
+
+	t.mu.Lock()
+	for room, count := range counts {
+		if _, ok := t.local[room]; ok {
+			t.totals[room] = count
+		}
+	}
+	t.mu.Unlock()
+}
+
+// removeAll deletes this node's members on shutdown
+func (t *Tracker) removeAll() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	t.mu.Lock()
+	pipe := t.client.Pipeline()
+	for room, members := range t.local {
+		for member := range members {
+			pipe.ZRem(ctx, presenceKey(room), member)
+		}
+	}
+	t.mu.Unlock()
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		log.Printf("Error removing presence: %v", err)
+	}
+}
+
+// presenceKey is the Redis key of a stream's presence set
+func presenceKey(streamID string) string {
+	return keyPrefix + streamID
+}
+
+// viewsKey is the Redis key of a stream's per-minute view counts
+func viewsKey(streamID string) string {
+	return viewsKeyPrefix + streamID
+}
+
+// memberID is a viewer's entry in a presence set
+func memberID(userID string, guest bool) string {
+	if guest {
+		return guestMemberPrefix + userID
+	}
+	return userID
+}
+
+// mark adds member to set[room]
+func mark(set map[string]map[string]bool, room, member string) {
+	if set[room] == nil {
+		set[room] = make(map[string]bool)
+	}
+	set[room][member] = true
+}
+
+// unmark removes member from set[room]
+func unmark(set map[string]map[string]bool, room, member string) {
+	if members, ok := set[room]; ok {
+		delete(members, member)
+		if len(members) == 0 {
+			delete(set, room)
+		}
+	}
+}
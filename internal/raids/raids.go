@@ -0,0 +1,87 @@
+package raids
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/tinle0301/streaming-platform-api/internal/tenancy"
+)
+
+// Raid records one streamer sending their viewers to another channel
+type Raid struct {
+	ID             string
+	FromStreamID   string
+	ToStreamID     string
+	FromStreamerID string
+	ToStreamerID   string
+	ViewerCount    int
+	CreatedAt      time.Time
+}
+
+// Repository persists raids. Raids are recorded in the context's tenant,
+// and other tenants' raids are not counted.
+type Repository interface {
+	// Record stores a raid and fills in its generated ID and timestamp
+	Record(ctx context.Context, raid *Raid) error
+
+	// RecentPartners counts raids between streamerID and each other streamer
+	// since the given time, in either direction
+	RecentPartners(ctx context.Context, streamerID string, since time.Time) (map[string]int, error)
+}
+
+// PostgresRepository implements Repository on PostgreSQL
+type PostgresRepository struct {
+	pool *pgxpool.Pool
+}
+
+// NewPostgresRepository creates a raid repository on pool
+func NewPostgresRepository(pool *pgxpool.Pool) *PostgresRepository {
+	return &PostgresRepository{pool: pool}
+}
+
+// Record inserts a raid
+func (r *PostgresRepository) Record(ctx context.Context, raid *Raid) error {
+	err := r.pool.QueryRow(ctx, `
+		INSERT INTO raids (from_stream_id, to_stream_id, from_streamer_id, to_streamer_id, viewer_count, tenant_id)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at`,
+		raid.FromStreamID, raid.ToStreamID, raid.FromStreamerID, raid.ToStreamerID, raid.ViewerCount, tenancy.ID(ctx),
+	).Scan(&raid.ID, &raid.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to record raid: %w", err)
+	}
+	return nil
+}
+
+// RecentPartners counts raids sent to or received from each other streamer
+func (r *PostgresRepository) RecentPartners(ctx context.Context, streamerID string, since time.Time) (map[string]int, error) {
+	partners := make(map[string]int)
+	rows, err := r.pool.Query(ctx, `
+		SELECT partner, COUNT(*) FROM (
+			SELECT to_streamer_id AS partner FROM raids
+			WHERE from_streamer_id = $1 AND created_at >= $2`+tenancy.Condition("tenant_id", 3)+`
+			UNION ALL
+			SELECT from_streamer_id AS partner FROM raids
+			WHERE to_streamer_id = $1 AND created_at >= $2`+tenancy.Condition("tenant_id", 3)+`
+		) r
+		GROUP BY partner`, streamerID, since, tenancy.Scope(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list raid partners: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var partner string
+		var count int
+		if err := rows.Scan(&partner, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan raid partner: %w", err)
+		}
+		partners[partner] = count
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list raid partners: %w", err)
+	}
+	return partners, nil
+}
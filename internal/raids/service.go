@@ -0,0 +1,113 @@
+package raids
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/tinle0301/streaming-platform-api/internal/events"
+	"github.com/tinle0301/streaming-platform-api/internal/store"
+)
+
+// DefaultCountdown is how long a raid counts down in the raiding stream's
+// room before its viewers are moved
+const DefaultCountdown = 10 * time.Second
+
+// Raid errors
+var (
+	ErrSourceNotLive = errors.New("you can only raid from a live stream")
+	ErrTargetNotLive = errors.New("the raided stream is not live")
+	ErrTargetMissing = errors.New("the raided stream does not exist")
+	ErrSelfRaid      = errors.New("you can't raid your own channel")
+)
+
+// Service starts raids: it records them and publishes the raid events the
+// WebSocket servers count down and move viewers with
+type Service struct {
+	streams   store.StreamRepository
+	repo      Repository
+	publisher events.Publisher
+	countdown time.Duration
+}
+
+// NewService creates a raid service; raids land countdown after they start
+func NewService(streams store.StreamRepository, repo Repository, countdown time.Duration) *Service {
+	return &Service{streams: streams, repo: repo, countdown: countdown}
+}
+
+// SetPublisher enables the raid.outgoing and raid.incoming events; without
+// them raids are recorded but viewers aren't moved
+func (s *Service) SetPublisher(publisher events.Publisher) {
+	s.publisher = publisher
+}
+
+// Start raids toStreamID from a live stream, taking its current viewers
+// along, and returns the raid and the raided stream. Callers check that the
+// user may act for from.
+func (s *Service) Start(ctx context.Context, from *store.Stream, toStreamID string) (*Raid, *store.Stream, error) {
+	if from.Status != store.StreamStatusLive {
+		return nil, nil, ErrSourceNotLive
+	}
+	to, err := s.streams.Get(ctx, toStreamID)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, nil, ErrTargetMissing
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if to.StreamerID == from.StreamerID {
+		return nil, nil, ErrSelfRaid
+	}
+	if to.Status != store.StreamStatusLive {
+		return nil, nil, ErrTargetNotLive
+	}
+
+	raid := &Raid{
+		FromStreamID:   from.ID,
+		ToStreamID:     to.ID,
+		FromStreamerID: from.StreamerID,
+		ToStreamerID:   to.StreamerID,
+		ViewerCount:    from.ViewerCount,
+	}
+	if err := s.repo.Record(ctx, raid); err != nil {
+		return nil, nil, err
+	}
+
+	s.publish(ctx, raid, from, to)
+	return raid, to, nil
+}
+
+// publish announces a raid to both rooms. Both events carry when the raid
+// lands: the raiding room counts down to it, then its viewers are moved and
+// the raided room is told they arrived. The raid is already recorded, so a
+// lost event only means viewers stay where they are.
+func (s *Service) publish(ctx context.Context, raid *Raid, from, to *store.Stream) {
+	if s.publisher == nil {
+		return
+	}
+
+	landsAt := time.Now().Add(s.countdown)
+	data := func() map[string]interface{} {
+		return map[string]interface{}{
+			"raid_id":           raid.ID,
+			"from_stream_id":    raid.FromStreamID,
+			"from_streamer_id":  raid.FromStreamerID,
+			"from_title":        from.Title,
+			"to_stream_id":      raid.ToStreamID,
+			"to_streamer_id":    raid.ToStreamerID,
+			"to_title":          to.Title,
+			"viewer_count":      raid.ViewerCount,
+			"countdown_seconds": int64(s.countdown / time.Second),
+			"lands_at":          landsAt.UnixMilli(),
+		}
+	}
+
+	batch := []events.Event{
+		events.NewRaidEvent(events.EventTypeRaidOutgoing, raid.FromStreamID, data()),
+		events.NewRaidEvent(events.EventTypeRaidIncoming, raid.ToStreamID, data()),
+	}
+	if err := s.publisher.PublishBatch(ctx, batch); err != nil {
+		log.Printf("Error publishing raid events: raidID=%s, err=%v", raid.ID, err)
+	}
+}
@@ -0,0 +1,180 @@
+package raids
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/tinle0301/streaming-platform-api/internal/store"
+)
+
+// Suggestion tuning
+const (
+	// Most live streams considered per match criterion
+	maxCandidates = 200
+
+	// How far back raids count towards mutual raid history
+	mutualRaidWindow = 90 * 24 * time.Hour
+)
+
+// Suggestion scores
+const (
+	scoreSameCategory = 3.0
+	scoreSameLanguage = 2.0
+	scoreSimilarSize  = 2.0
+	scoreMutualRaid   = 1.5
+	scoreMaxMutual    = 3.0
+	scoreSharedFans   = 1.0
+)
+
+// Reasons explaining why a channel was suggested
+const (
+	ReasonSameCategory   = "same_category"
+	ReasonSameLanguage   = "same_language"
+	ReasonSimilarSize    = "similar_size"
+	ReasonMutualRaids    = "mutual_raids"
+	ReasonSharedAudience = "shared_audience"
+)
+
+// AudienceCounter counts followers a streamer shares with other streamers
+type AudienceCounter interface {
+	AudienceOverlap(ctx context.Context, userID string, candidateIDs []string) (map[string]int, error)
+}
+
+// Suggestion is a live channel worth raiding, best first
+type Suggestion struct {
+	Stream  *store.Stream
+	Score   float64
+	Reasons []string
+}
+
+// Suggester ranks live channels as raid targets for an ending stream
+type Suggester struct {
+	streams  store.StreamRepository
+	raids    Repository
+	audience AudienceCounter
+}
+
+// NewSuggester creates a suggester. raids and audience are optional; without
+// them mutual raids and shared followers are not considered.
+func NewSuggester(streams store.StreamRepository, raids Repository, audience AudienceCounter) *Suggester {
+	return &Suggester{streams: streams, raids: raids, audience: audience}
+}
+
+// Suggest returns up to limit live channels to raid from the given stream
+func (s *Suggester) Suggest(ctx context.Context, from *store.Stream, limit int) ([]Suggestion, error) {
+	candidates, err := s.candidates(ctx, from)
+	if err != nil {
+		return nil, err
+	}
+	if len(candidates) == 0 {
+		return []Suggestion{}, nil
+	}
+
+	streamerIDs := make([]string, 0, len(candidates))
+	for _, stream := range candidates {
+		streamerIDs = append(streamerIDs, stream.StreamerID)
+	}
+
+	// Raid history and follower overlap only improve the ranking, so
+	// suggestions are still returned if either lookup fails
+	var partners map[string]int
+	if s.raids != nil {
+		partners, err = s.raids.RecentPartners(ctx, from.StreamerID, time.Now().Add(-mutualRaidWindow))
+		if err != nil {
+			log.Printf("Error loading raid history: streamerID=%s, err=%v", from.StreamerID, err)
+		}
+	}
+	var overlap map[string]int
+	if s.audience != nil {
+		overlap, err = s.audience.AudienceOverlap(ctx, from.StreamerID, streamerIDs)
+		if err != nil {
+			log.Printf("Error loading shared audience: streamerID=%s, err=%v", from.StreamerID, err)
+		}
+	}
+
+	suggestions := make([]Suggestion, 0, len(candidates))
+	for _, stream := range candidates {
+		suggestions = append(suggestions, score(from, stream, partners[stream.StreamerID], overlap[stream.StreamerID]))
+	}
+
+	sort.SliceStable(suggestions, func(i, j int) bool {
+		if suggestions[i].Score != suggestions[j].Score {
+			return suggestions[i].Score > suggestions[j].Score
+		}
+		return suggestions[i].Stream.ViewerCount > suggestions[j].Stream.ViewerCount
+	})
+	if limit > 0 && len(suggestions) > limit {
+		suggestions = suggestions[:limit]
+	}
+	return suggestions, nil
+}
+
+// candidates lists live streams sharing the category or language, excluding
+// the raiding streamer's own
+func (s *Suggester) candidates(ctx context.Context, from *store.Stream) ([]*store.Stream, error) {
+	var filters []store.StreamFilter
+	if from.Category != "" {
+		filters = append(filters, store.StreamFilter{Status: store.StreamStatusLive, Category: from.Category, Limit: maxCandidates})
+	}
+	if from.Language != "" {
+		filters = append(filters, store.StreamFilter{Status: store.StreamStatusLive, Language: from.Language, Limit: maxCandidates})
+	}
+
+	seen := make(map[string]bool)
+	var candidates []*store.Stream
+	for _, filter := range filters {
+		streams, _, err := s.streams.List(ctx, filter)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list raid candidates: %w", err)
+		}
+		for _, stream := range streams {
+			if seen[stream.ID] || stream.StreamerID == from.StreamerID {
+				continue
+			}
+			seen[stream.ID] = true
+			candidates = append(candidates, stream)
+		}
+	}
+	return candidates, nil
+}
+
+// score rates a candidate against the raiding stream
+func score(from, to *store.Stream, mutualRaids, sharedFollowers int) Suggestion {
+	suggestion := Suggestion{Stream: to, Reasons: []string{}}
+
+	if from.Category != "" && from.Category == to.Category {
+		suggestion.Score += scoreSameCategory
+		suggestion.Reasons = append(suggestion.Reasons, ReasonSameCategory)
+	}
+	if from.Language != "" && strings.EqualFold(from.Language, to.Language) {
+		suggestion.Score += scoreSameLanguage
+		suggestion.Reasons = append(suggestion.Reasons, ReasonSameLanguage)
+	}
+
+	// Ratio of the smaller audience to the larger; +1 keeps empty channels
+	// comparable
+	small, large := float64(from.ViewerCount+1), float64(to.ViewerCount+1)
+	if small > large {
+		small, large = large, small
+	}
+	similarity := small / large
+	suggestion.Score += scoreSimilarSize * similarity
+	if similarity >= 0.5 {
+		suggestion.Reasons = append(suggestion.Reasons, ReasonSimilarSize)
+	}
+
+	if mutualRaids > 0 {
+		suggestion.Score += math.Min(scoreMutualRaid*float64(mutualRaids), scoreMaxMutual)
+		suggestion.Reasons = append(suggestion.Reasons, ReasonMutualRaids)
+	}
+	if sharedFollowers > 0 {
+		suggestion.Score += scoreSharedFans * math.Log1p(float64(sharedFollowers))
+		suggestion.Reasons = append(suggestion.Reasons, ReasonSharedAudience)
+	}
+	return suggestion
+}
@@ -0,0 +1,261 @@
+package ratelimit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// quotaTableKey holds the quota table written by the API server
+	quotaTableKey = "ratelimit:quotas"
+
+	// windowKeyPrefix namespaces tenants' per-window counters
+	windowKeyPrefix = "ratelimit:window:"
+
+	// connectionsKeyPrefix namespaces each tenant's per-node connection counts
+	connectionsKeyPrefix = "ratelimit:connections:"
+)
+
+// QuotaStore shares the quota table between the API server, which derives
+// it from tenants' settings, and the WebSocket servers
+type QuotaStore struct {
+	client *redis.Client
+}
+
+// NewQuotaStore creates a quota store on client
+func NewQuotaStore(client *redis.Client) *QuotaStore {
+	return &QuotaStore{client: client}
+}
+
+// Save replaces the shared quota table
+func (s *QuotaStore) Save(ctx context.Context, table QuotaTable) error {
+	data, err := json.Marshal(table)
+	if err != nil {
+		return fmt.Errorf("failed to encode quotas: %w", err)
+	}
+	if err := s.client.Set(ctx, quotaTableKey, data, 0).Err(); err != nil {
+		return fmt.Errorf("failed to save quotas: %w", err)
+	}
+	return nil
+}
+
+// Load returns the shared quota table, or false if none was saved yet
+func (s *QuotaStore) Load(ctx context.Context) (QuotaTable, bool, error) {
+	data, err := s.client.Get(ctx, quotaTableKey).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return QuotaTable{}, false, nil
+	}
+	if err != nil {
+		return QuotaTable{}, false, fmt.Errorf("failed to load quotas: %w", err)
+	}
+	var table QuotaTable
+	if err := json.Unmarshal(data, &table); err != nil {
+		return QuotaTable{}, false, fmt.Errorf("failed to decode quotas: %w", err)
+	}
+	return table, true, nil
+}
+
+// Distribute sets quotas to table() and saves them to store for the
+// WebSocket servers every interval until ctx is cancelled
+func (q *Quotas) Distribute(ctx context.Context, store *QuotaStore, table func() QuotaTable, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		current := table()
+		q.Set(current)
+		if err := store.Save(ctx, current); err != nil {
+			log.Printf("Error saving tenant quotas: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Follow reloads quotas from store every interval until ctx is cancelled
+func (q *Quotas) Follow(ctx context.Context, store *QuotaStore, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if table, ok, err := store.Load(ctx); err != nil {
+			log.Printf("Error loading tenant quotas: %v", err)
+		} else if ok {
+			q.Set(table)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Enforcer enforces tenants' quotas across nodes with counters in Redis.
+// Checks fail open: while Redis is unavailable tenants are not limited.
+type Enforcer struct {
+	client *redis.Client
+	quotas *Quotas
+	nodeID string
+
+	mu sync.Mutex
+	// Tenants this node last reported connections for, and how long other
+	// nodes' reports are taken as current
+	reported  map[string]bool
+	reportTTL time.Duration
+}
+
+// NewEnforcer creates an enforcer of quotas; nodeID distinguishes this
+// node's connection counts from other nodes'
+func NewEnforcer(client *redis.Client, quotas *Quotas, nodeID string) *Enforcer {
+	return &Enforcer{
+		client:   client,
+		quotas:   quotas,
+		nodeID:   nodeID,
+		reported: make(map[string]bool),
+	}
+}
+
+// AllowGraphQLOp counts a GraphQL request against the tenant's per-minute
+// quota, reporting whether it is within it
+func (e *Enforcer) AllowGraphQLOp(ctx context.Context, tenantID string) bool {
+	limit := e.quotas.For(tenantID).GraphQLOpsPerMinute
+	return e.allow(ctx, "graphql", tenantID, 1, limit, time.Minute)
+}
+
+// AllowEvents counts count events against the tenant's per-second quota,
+// reporting whether they are within it
+func (e *Enforcer) AllowEvents(ctx context.Context, tenantID string, count int) bool {
+	limit := e.quotas.For(tenantID).EventsPerSecond
+	return e.allow(ctx, "events", tenantID, count, limit, time.Second)
+}
+
+// allow adds count to the tenant's counter for the current fixed window
+func (e *Enforcer) allow(ctx context.Context, kind, tenantID string, count, limit int, window time.Duration) bool {
+	if limit <= 0 {
+		return true
+	}
+
+	index := time.Now().UnixNano() / int64(window)
+	key := windowKeyPrefix + kind + ":" + tenantID + ":" + strconv.FormatInt(index, 10)
+
+	pipe := e.client.TxPipeline()
+	incr := pipe.IncrBy(ctx, key, int64(count))
+	pipe.Expire(ctx, key, 2*window)
+	if _, err := pipe.Exec(ctx); err != nil {
+		log.Printf("Error checking tenant quota: kind=%s, tenant=%s, err=%v", kind, tenantID, err)
+		return true
+	}
+	return incr.Val() <= int64(limit)
+}
+
+// RetryAfter is how long until the next fixed window of length window,
+// when a tenant's quota for it is restored
+func RetryAfter(window time.Duration) time.Duration {
+	return window - time.Duration(time.Now().UnixNano()%int64(window))
+}
+
+// AdmitConnection reports whether a tenant with local connections on this
+// node may open another, counting its connections on other nodes too
+func (e *Enforcer) AdmitConnection(ctx context.Context, tenantID string, local int) bool {
+	limit := e.quotas.For(tenantID).MaxConnections
+	if limit <= 0 {
+		return true
+	}
+	if local >= limit {
+		return false
+	}
+
+	counts, err := e.client.HGetAll(ctx, connectionsKeyPrefix+tenantID).Result()
+	if err != nil {
+		log.Printf("Error checking tenant connections: tenant=%s, err=%v", tenantID, err)
+		return true
+	}
+	total := local
+	for nodeID, value := range counts {
+		if nodeID == e.nodeID {
+			continue
+		}
+		if count, fresh := parseNodeCount(value, e.staleAfter()); fresh {
+			total += count
+		}
+	}
+	return total < limit
+}
+
+// ReportConnections records this node's connections per tenant for the
+// other nodes' admission checks. Reports are taken as current for three
+// reporting intervals, so a node that stops reporting stops counting.
+func (e *Enforcer) ReportConnections(ctx context.Context, counts map[string]int, interval time.Duration) {
+	e.mu.Lock()
+	e.reportTTL = 3 * interval
+	previous := e.reported
+	e.reported = make(map[string]bool, len(counts))
+	for tenantID, count := range counts {
+		if count > 0 {
+			e.reported[tenantID] = true
+		}
+	}
+	e.mu.Unlock()
+
+	now := strconv.FormatInt(time.Now().UnixMilli(), 10)
+	pipe := e.client.Pipeline()
+	for tenantID, count := range counts {
+		if count <= 0 {
+			continue
+		}
+		key := connectionsKeyPrefix + tenantID
+		pipe.HSet(ctx, key, e.nodeID, strconv.Itoa(count)+":"+now)
+		pipe.Expire(ctx, key, 3*interval)
+	}
+	for tenantID := range previous {
+		if counts[tenantID] <= 0 {
+			pipe.HDel(ctx, connectionsKeyPrefix+tenantID, e.nodeID)
+		}
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		log.Printf("Error reporting tenant connections: %v", err)
+	}
+}
+
+// staleAfter is how old another node's connection report may be
+func (e *Enforcer) staleAfter() time.Duration {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.reportTTL <= 0 {
+		return time.Minute
+	}
+	return e.reportTTL
+}
+
+// parseNodeCount parses a "<count>:<unix ms>" report, reporting whether it
+// is newer than maxAge
+func parseNodeCount(value string, maxAge time.Duration) (int, bool) {
+	countPart, atPart, ok := strings.Cut(value, ":")
+	if !ok {
+		return 0, false
+	}
+	count, err := strconv.Atoi(countPart)
+	if err != nil {
+		return 0, false
+	}
+	at, err := strconv.ParseInt(atPart, 10, 64)
+	if err != nil || time.Since(time.UnixMilli(at)) > maxAge {
+		return 0, false
+	}
+	return count, true
+}
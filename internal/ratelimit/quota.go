@@ -0,0 +1,92 @@
+package ratelimit
+
+import (
+	"errors"
+	"strconv"
+	"sync"
+)
+
+// ErrQuotaExceeded is returned when a tenant is over one of its quotas
+var ErrQuotaExceeded = errors.New("tenant quota exceeded")
+
+// Tenant settings overriding a tenant's quotas; values are integers and 0
+// means unlimited
+const (
+	MaxConnectionsSetting      = "quota_max_connections"
+	EventsPerSecondSetting     = "quota_events_per_second"
+	GraphQLOpsPerMinuteSetting = "quota_graphql_ops_per_minute"
+)
+
+// Quota bounds a tenant's use of the platform across every node. Zero
+// fields are unlimited.
+type Quota struct {
+	// MaxConnections bounds the tenant's concurrent WebSocket connections
+	MaxConnections int `json:"max_connections,omitempty"`
+
+	// EventsPerSecond bounds the events the tenant's activity publishes on
+	// the event bus
+	EventsPerSecond int `json:"events_per_second,omitempty"`
+
+	// GraphQLOpsPerMinute bounds the tenant's GraphQL requests
+	GraphQLOpsPerMinute int `json:"graphql_ops_per_minute,omitempty"`
+}
+
+// QuotaFromSettings applies a tenant's quota settings to defaults; invalid
+// values are ignored
+func QuotaFromSettings(defaults Quota, settings map[string]string) Quota {
+	quota := defaults
+	for key, target := range map[string]*int{
+		MaxConnectionsSetting:      &quota.MaxConnections,
+		EventsPerSecondSetting:     &quota.EventsPerSecond,
+		GraphQLOpsPerMinuteSetting: &quota.GraphQLOpsPerMinute,
+	} {
+		value, ok := settings[key]
+		if !ok {
+			continue
+		}
+		if n, err := strconv.Atoi(value); err == nil && n >= 0 {
+			*target = n
+		}
+	}
+	return quota
+}
+
+// QuotaTable is every tenant's quota; tenants without an entry get Default
+type QuotaTable struct {
+	Default Quota            `json:"default"`
+	Tenants map[string]Quota `json:"tenants,omitempty"`
+}
+
+// For returns a tenant's quota
+func (t QuotaTable) For(tenantID string) Quota {
+	if quota, ok := t.Tenants[tenantID]; ok {
+		return quota
+	}
+	return t.Default
+}
+
+// Quotas holds the current quota table; it is replaced as tenants' quotas
+// are reconfigured
+type Quotas struct {
+	mu    sync.RWMutex
+	table QuotaTable
+}
+
+// NewQuotas creates quotas starting from table
+func NewQuotas(table QuotaTable) *Quotas {
+	return &Quotas{table: table}
+}
+
+// Set replaces the quota table
+func (q *Quotas) Set(table QuotaTable) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.table = table
+}
+
+// For returns a tenant's quota
+func (q *Quotas) For(tenantID string) Quota {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	return q.table.For(tenantID)
+}
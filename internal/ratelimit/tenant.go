@@ -0,0 +1,79 @@
+package ratelimit
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/tinle0301/streaming-platform-api/internal/events"
+	"github.com/tinle0301/streaming-platform-api/internal/tenancy"
+)
+
+// TenantQuotaTable derives the quota table from tenants' settings, each
+// overriding defaults
+func TenantQuotaTable(defaults Quota, tenants []*tenancy.Tenant) QuotaTable {
+	table := QuotaTable{Default: defaults, Tenants: make(map[string]Quota, len(tenants))}
+	for _, tenant := range tenants {
+		table.Tenants[tenant.ID] = QuotaFromSettings(defaults, tenant.Settings)
+	}
+	return table
+}
+
+// Middleware rejects GraphQL requests over their tenant's per-minute quota
+// with 429 Too Many Requests. It must run inside tenancy.Middleware.
+func Middleware(enforcer *Enforcer, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !enforcer.AllowGraphQLOp(r.Context(), tenancy.ID(r.Context())) {
+			retryAfter := RetryAfter(time.Minute)
+			w.Header().Set("Retry-After", strconv.Itoa(int(retryAfter.Seconds())+1))
+			http.Error(w, "Tenant request quota exceeded", http.StatusTooManyRequests)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Publisher refuses events over their tenant's per-second quota with
+// ErrQuotaExceeded. Events are counted against their TenantID, so it wraps
+// a tenancy.Publisher.
+type Publisher struct {
+	events.Publisher
+	enforcer *Enforcer
+}
+
+// NewPublisher wraps publisher
+func NewPublisher(publisher events.Publisher, enforcer *Enforcer) *Publisher {
+	return &Publisher{Publisher: publisher, enforcer: enforcer}
+}
+
+// Publish publishes event if its tenant is within its quota
+func (p *Publisher) Publish(ctx context.Context, event events.Event) error {
+	if !p.enforcer.AllowEvents(ctx, eventTenant(event), 1) {
+		return ErrQuotaExceeded
+	}
+	return p.Publisher.Publish(ctx, event)
+}
+
+// PublishBatch publishes events if each of their tenants is within its
+// quota; a batch is published whole or not at all
+func (p *Publisher) PublishBatch(ctx context.Context, batch []events.Event) error {
+	counts := make(map[string]int)
+	for _, event := range batch {
+		counts[eventTenant(event)]++
+	}
+	for tenantID, count := range counts {
+		if !p.enforcer.AllowEvents(ctx, tenantID, count) {
+			return ErrQuotaExceeded
+		}
+	}
+	return p.Publisher.PublishBatch(ctx, batch)
+}
+
+// eventTenant is the tenant an event is counted against
+func eventTenant(event events.Event) string {
+	if event.TenantID == "" {
+		return tenancy.Default
+	}
+	return event.TenantID
+}
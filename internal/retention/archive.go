@@ -0,0 +1,263 @@
+package retention
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ArchivingPruner implements Pruner by compacting expired rows into
+// compressed NDJSON objects before deleting them
+type ArchivingPruner struct {
+	db        *sql.DB
+	store     ObjectStore
+	table     string
+	column    string
+	batchSize int
+	pruner    *SQLPruner
+}
+
+// NewArchivingPruner creates a pruner that archives rows of table to store
+// before deleting rows where column < cutoff, batchSize rows per object
+func NewArchivingPruner(db *sql.DB, store ObjectStore, table, column string, batchSize int) *ArchivingPruner {
+	return &ArchivingPruner{
+		db:        db,
+		store:     store,
+		table:     table,
+		column:    column,
+		batchSize: batchSize,
+		pruner:    NewSQLPruner(db, table, column, batchSize),
+	}
+}
+
+// Prune archives expired rows and deletes them a batch at a time, so only
+// one batch's archive is held in memory. Rows are only deleted once their
+// archive object has been written successfully.
+func (p *ArchivingPruner) Prune(ctx context.Context, cutoff time.Time, dryRun bool) (int64, error) {
+	if dryRun {
+		return p.pruner.Prune(ctx, cutoff, true)
+	}
+
+	var total int64
+	for {
+		rows, err := p.archiveBatch(ctx, cutoff)
+		total += rows
+		if err != nil {
+			return total, err
+		}
+		if rows < int64(p.batchSize) {
+			return total, nil
+		}
+	}
+}
+
+// archiveBatch locks up to batchSize of the oldest expired rows, streams
+// them into one gzipped NDJSON object, and deletes them once it is stored
+func (p *ArchivingPruner) archiveBatch(ctx context.Context, cutoff time.Time) (int64, error) {
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := fmt.Sprintf(
+		"SELECT ctid::text, %s, row_to_json(t)::text FROM %s t WHERE %s < $1 ORDER BY %s LIMIT $2 FOR UPDATE SKIP LOCKED",
+		p.column, p.table, p.column, p.column,
+	)
+	rows, err := tx.QueryContext(ctx, query, cutoff, p.batchSize)
+	if err != nil {
+		return 0, fmt.Errorf("failed to export %s: %w", p.table, err)
+	}
+	defer rows.Close()
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+
+	var ctids []string
+	var from, to time.Time
+	for rows.Next() {
+		var ctid, row string
+		var ts time.Time
+		if err := rows.Scan(&ctid, &ts, &row); err != nil {
+			return 0, fmt.Errorf("failed to scan %s row: %w", p.table, err)
+		}
+		if len(ctids) == 0 {
+			from = ts
+		}
+		to = ts
+		ctids = append(ctids, ctid)
+
+		gz.Write([]byte(row))
+		gz.Write([]byte{'\n'})
+	}
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to read %s rows: %w", p.table, err)
+	}
+	rows.Close()
+	if len(ctids) == 0 {
+		return 0, nil
+	}
+	if err := gz.Close(); err != nil {
+		return 0, fmt.Errorf("failed to compress archive: %w", err)
+	}
+
+	key := archiveKey(p.table, from, to)
+	if err := p.store.Put(ctx, key, buf.Bytes()); err != nil {
+		return 0, fmt.Errorf("failed to upload archive %s: %w", key, err)
+	}
+
+	result, err := tx.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s WHERE ctid = ANY($1::tid[])", p.table), ctids)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune %s: %w", p.table, err)
+	}
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to read affected rows: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit prune of %s: %w", p.table, err)
+	}
+	log.Printf("Archived %d rows from %s to %s", deleted, p.table, key)
+	return deleted, nil
+}
+
+// Restorer rehydrates archived rows back into a queryable table
+type Restorer struct {
+	db    *sql.DB
+	store ObjectStore
+}
+
+// NewRestorer creates a new archive restorer
+func NewRestorer(db *sql.DB, store ObjectStore) *Restorer {
+	return &Restorer{db: db, store: store}
+}
+
+// Restore loads archived rows of sourceTable whose column falls within
+// [from, to] into targetTable, which must have a compatible row type
+func (r *Restorer) Restore(ctx context.Context, sourceTable, targetTable, column string, from, to time.Time) (int64, error) {
+	keys, err := r.store.List(ctx, "archive/"+sourceTable+"/")
+	if err != nil {
+		return 0, fmt.Errorf("failed to list archives: %w", err)
+	}
+
+	insert := fmt.Sprintf("INSERT INTO %s SELECT * FROM json_populate_record(NULL::%s, $1::json)", targetTable, targetTable)
+
+	var restored int64
+	for _, key := range keys {
+		objectFrom, objectTo, ok := parseArchiveKey(key)
+		if !ok || objectTo.Before(from) || objectFrom.After(to) {
+			continue
+		}
+
+		data, err := r.store.Get(ctx, key)
+		if err != nil {
+			return restored, fmt.Errorf("failed to download %s: %w", key, err)
+		}
+
+		n, err := r.restoreObject(ctx, data, insert, column, from, to)
+		restored += n
+		if err != nil {
+			return restored, fmt.Errorf("failed to restore %s: %w", key, err)
+		}
+		log.Printf("Restored %d rows from %s into %s", n, key, targetTable)
+	}
+
+	return restored, nil
+}
+
+// restoreObject inserts the rows of one archive object that fall within range
+func (r *Restorer) restoreObject(ctx context.Context, data []byte, insert, column string, from, to time.Time) (int64, error) {
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return 0, err
+	}
+	defer gz.Close()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var restored int64
+	reader := bufio.NewReader(gz)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) > 0 {
+			var row map[string]interface{}
+			if jsonErr := json.Unmarshal(line, &row); jsonErr != nil {
+				return 0, jsonErr
+			}
+			if ts, ok := rowTime(row, column); ok && !ts.Before(from) && !ts.After(to) {
+				if _, execErr := tx.ExecContext(ctx, insert, string(line)); execErr != nil {
+					return 0, execErr
+				}
+				restored++
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return restored, nil
+}
+
+// rowTime extracts a timestamp column from a row_to_json object
+func rowTime(row map[string]interface{}, column string) (time.Time, bool) {
+	value, ok := row[column].(string)
+	if !ok {
+		return time.Time{}, false
+	}
+	ts, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		// timestamp columns without a time zone are encoded without an offset
+		ts, err = time.Parse("2006-01-02T15:04:05.999999999", value)
+		if err != nil {
+			return time.Time{}, false
+		}
+	}
+	return ts, true
+}
+
+// archiveKey builds the object key for an archive covering [from, to]. The
+// key ends in the time it was built, so batches covering the same seconds
+// don't overwrite each other.
+func archiveKey(table string, from, to time.Time) string {
+	return fmt.Sprintf("archive/%s/%d_%d_%d.ndjson.gz", table, from.Unix(), to.Unix(), time.Now().UnixNano())
+}
+
+// parseArchiveKey extracts the time range encoded in an archive key
+func parseArchiveKey(key string) (time.Time, time.Time, bool) {
+	name := key[strings.LastIndex(key, "/")+1:]
+	name = strings.TrimSuffix(name, ".ndjson.gz")
+
+	// Older archives have no trailing build time
+	parts := strings.Split(name, "_")
+	if len(parts) != 2 && len(parts) != 3 {
+		return time.Time{}, time.Time{}, false
+	}
+	fromStr, toStr := parts[0], parts[1]
+	from, err1 := strconv.ParseInt(fromStr, 10, 64)
+	to, err2 := strconv.ParseInt(toStr, 10, 64)
+	if err1 != nil || err2 != nil {
+		return time.Time{}, time.Time{}, false
+	}
+	// Keys are truncated to seconds, so the upper bound is rounded up
+	return time.Unix(from, 0), time.Unix(to+1, 0), true
+}
@@ -0,0 +1,189 @@
+package retention
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/tinle0301/streaming-platform-api/internal/awsauth"
+)
+
+// ObjectStore stores archive objects by key
+type ObjectStore interface {
+	Put(ctx context.Context, key string, data []byte) error
+	Get(ctx context.Context, key string) ([]byte, error)
+	List(ctx context.Context, prefix string) ([]string, error)
+}
+
+// S3Store implements ObjectStore against an S3 bucket
+type S3Store struct {
+	bucket string
+	region string
+	creds  awsauth.Credentials
+	client *http.Client
+}
+
+// NewS3Store creates an S3-backed object store using AWS_* environment credentials
+func NewS3Store(bucket, region string) *S3Store {
+	return NewS3StoreWithCredentials(bucket, region, awsauth.CredentialsFromEnv())
+}
+
+// NewS3StoreWithCredentials creates an S3-backed object store that signs
+// with creds, e.g. a customer's own bucket
+func NewS3StoreWithCredentials(bucket, region string, creds awsauth.Credentials) *S3Store {
+	return &S3Store{
+		bucket: bucket,
+		region: region,
+		creds:  creds,
+		client: &http.Client{Timeout: 5 * time.Minute},
+	}
+}
+
+// Put uploads an object
+func (s *S3Store) Put(ctx context.Context, key string, data []byte) error {
+	resp, err := s.do(ctx, http.MethodPut, "/"+key, nil, data)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("s3 put %s returned status %d", key, resp.StatusCode)
+	}
+	return nil
+}
+
+// Get downloads an object
+func (s *S3Store) Get(ctx context.Context, key string) ([]byte, error) {
+	resp, err := s.do(ctx, http.MethodGet, "/"+key, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("s3 get %s returned status %d", key, resp.StatusCode)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// List returns the keys under prefix in lexical order
+func (s *S3Store) List(ctx context.Context, prefix string) ([]string, error) {
+	var keys []string
+	token := ""
+
+	for {
+		query := url.Values{"list-type": {"2"}, "prefix": {prefix}}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+
+		resp, err := s.do(ctx, http.MethodGet, "/", query, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		var result struct {
+			Contents []struct {
+				Key string `xml:"Key"`
+			} `xml:"Contents"`
+			IsTruncated           bool   `xml:"IsTruncated"`
+			NextContinuationToken string `xml:"NextContinuationToken"`
+		}
+		err = xml.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode S3 list response: %w", err)
+		}
+
+		for _, object := range result.Contents {
+			keys = append(keys, object.Key)
+		}
+		if !result.IsTruncated {
+			return keys, nil
+		}
+		token = result.NextContinuationToken
+	}
+}
+
+// do sends a signed request to the bucket endpoint
+func (s *S3Store) do(ctx context.Context, method, path string, query url.Values, body []byte) (*http.Response, error) {
+	endpoint := url.URL{
+		Scheme:   "https",
+		Host:     fmt.Sprintf("%s.s3.%s.amazonaws.com", s.bucket, s.region),
+		Path:     path,
+		RawQuery: query.Encode(),
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, endpoint.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create S3 request: %w", err)
+	}
+
+	if err := awsauth.Sign(req, s.creds, s.region, "s3", awsauth.PayloadHash(body), time.Now()); err != nil {
+		return nil, err
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("s3 request failed: %w", err)
+	}
+	return resp, nil
+}
+
+// DirStore implements ObjectStore on the local filesystem (development only)
+type DirStore struct {
+	root string
+}
+
+// NewDirStore creates an object store rooted at dir
+func NewDirStore(dir string) *DirStore {
+	return &DirStore{root: dir}
+}
+
+// Put writes an object to disk
+func (s *DirStore) Put(ctx context.Context, key string, data []byte) error {
+	path := filepath.Join(s.root, filepath.FromSlash(key))
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create archive directory: %w", err)
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// Get reads an object from disk
+func (s *DirStore) Get(ctx context.Context, key string) ([]byte, error) {
+	return os.ReadFile(filepath.Join(s.root, filepath.FromSlash(key)))
+}
+
+// List returns the keys under prefix in lexical order
+func (s *DirStore) List(ctx context.Context, prefix string) ([]string, error) {
+	var keys []string
+	err := filepath.Walk(s.root, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(s.root, path)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(rel)
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+		return nil
+	})
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	sort.Strings(keys)
+	return keys, err
+}
@@ -0,0 +1,214 @@
+package retention
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Data classes with retention policies
+const (
+	ClassChat         = "chat"
+	ClassAnalyticsRaw = "analytics_raw"
+	ClassAuditLog     = "audit_log"
+)
+
+// Policy defines how long a class of data is kept
+type Policy struct {
+	Class  string
+	MaxAge time.Duration
+}
+
+// DefaultPolicies returns the platform's default retention policies
+func DefaultPolicies() []Policy {
+	return []Policy{
+		{Class: ClassChat, MaxAge: 90 * 24 * time.Hour},
+		{Class: ClassAnalyticsRaw, MaxAge: 30 * 24 * time.Hour},
+		{Class: ClassAuditLog, MaxAge: 2 * 365 * 24 * time.Hour},
+	}
+}
+
+// PoliciesFromEnv returns the default policies with per-class overrides from
+// RETENTION_<CLASS>_DAYS (e.g. RETENTION_CHAT_DAYS=30)
+func PoliciesFromEnv() ([]Policy, error) {
+	policies := DefaultPolicies()
+	for i, policy := range policies {
+		key := "RETENTION_" + strings.ToUpper(policy.Class) + "_DAYS"
+		value := os.Getenv(key)
+		if value == "" {
+			continue
+		}
+
+		days, err := strconv.Atoi(value)
+		if err != nil || days <= 0 {
+			return nil, fmt.Errorf("invalid %s: %q", key, value)
+		}
+		policies[i].MaxAge = time.Duration(days) * 24 * time.Hour
+	}
+	return policies, nil
+}
+
+// Pruner deletes (or counts, in dry-run mode) rows older than cutoff
+type Pruner interface {
+	Prune(ctx context.Context, cutoff time.Time, dryRun bool) (int64, error)
+}
+
+// Report summarizes one pruning pass for a policy
+type Report struct {
+	Class    string        `json:"class"`
+	Cutoff   time.Time     `json:"cutoff"`
+	Rows     int64         `json:"rows"`
+	DryRun   bool          `json:"dry_run"`
+	Duration time.Duration `json:"duration"`
+	Error    string        `json:"error,omitempty"`
+}
+
+var (
+	rowsDeleted = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "streamhub_retention_rows_deleted_total",
+		Help: "Rows deleted by retention pruning jobs",
+	}, []string{"class"})
+
+	pruneErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "streamhub_retention_errors_total",
+		Help: "Failed retention pruning passes",
+	}, []string{"class"})
+)
+
+// Job runs retention policies against their pruners on a schedule
+type Job struct {
+	policies []Policy
+	pruners  map[string]Pruner
+	dryRun   bool
+}
+
+// NewJob creates a retention job; dryRun only reports what would be deleted
+func NewJob(policies []Policy, dryRun bool) *Job {
+	return &Job{
+		policies: policies,
+		pruners:  make(map[string]Pruner),
+		dryRun:   dryRun,
+	}
+}
+
+// RegisterPruner sets the pruner responsible for a data class
+func (j *Job) RegisterPruner(class string, pruner Pruner) {
+	j.pruners[class] = pruner
+}
+
+// RunOnce applies every policy once and returns a report per policy
+func (j *Job) RunOnce(ctx context.Context) []Report {
+	now := time.Now()
+	reports := make([]Report, 0, len(j.policies))
+
+	for _, policy := range j.policies {
+		pruner, ok := j.pruners[policy.Class]
+		if !ok {
+			continue
+		}
+
+		start := time.Now()
+		report := Report{
+			Class:  policy.Class,
+			Cutoff: now.Add(-policy.MaxAge),
+			DryRun: j.dryRun,
+		}
+
+		rows, err := pruner.Prune(ctx, report.Cutoff, j.dryRun)
+		report.Rows = rows
+		report.Duration = time.Since(start)
+
+		if err != nil {
+			report.Error = err.Error()
+			pruneErrors.WithLabelValues(policy.Class).Inc()
+			log.Printf("Retention prune failed: class=%s, err=%v", policy.Class, err)
+		} else if j.dryRun {
+			log.Printf("Retention dry run: class=%s, cutoff=%s, would_delete=%d",
+				policy.Class, report.Cutoff.Format(time.RFC3339), rows)
+		} else {
+			rowsDeleted.WithLabelValues(policy.Class).Add(float64(rows))
+			log.Printf("Retention prune: class=%s, cutoff=%s, deleted=%d, took=%v",
+				policy.Class, report.Cutoff.Format(time.RFC3339), rows, report.Duration)
+		}
+
+		reports = append(reports, report)
+	}
+
+	return reports
+}
+
+// Run applies policies every interval until ctx is cancelled
+func (j *Job) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			j.RunOnce(ctx)
+		}
+	}
+}
+
+// SQLPruner implements Pruner by deleting rows from a table in batches
+type SQLPruner struct {
+	db        *sql.DB
+	table     string
+	column    string
+	batchSize int
+}
+
+// NewSQLPruner creates a pruner deleting rows from table where column < cutoff
+func NewSQLPruner(db *sql.DB, table, column string, batchSize int) *SQLPruner {
+	return &SQLPruner{
+		db:        db,
+		table:     table,
+		column:    column,
+		batchSize: batchSize,
+	}
+}
+
+// Prune deletes expired rows in batches to avoid long-running locks
+func (p *SQLPruner) Prune(ctx context.Context, cutoff time.Time, dryRun bool) (int64, error) {
+	if dryRun {
+		var count int64
+		query := fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE %s < $1", p.table, p.column)
+		if err := p.db.QueryRowContext(ctx, query, cutoff).Scan(&count); err != nil {
+			return 0, fmt.Errorf("failed to count expired rows in %s: %w", p.table, err)
+		}
+		return count, nil
+	}
+
+	query := fmt.Sprintf(
+		"DELETE FROM %s WHERE ctid IN (SELECT ctid FROM %s WHERE %s < $1 LIMIT $2)",
+		p.table, p.table, p.column,
+	)
+
+	var total int64
+	for {
+		result, err := p.db.ExecContext(ctx, query, cutoff, p.batchSize)
+		if err != nil {
+			return total, fmt.Errorf("failed to prune %s: %w", p.table, err)
+		}
+
+		rows, err := result.RowsAffected()
+		if err != nil {
+			return total, fmt.Errorf("failed to read affected rows: %w", err)
+		}
+		total += rows
+
+		if rows < int64(p.batchSize) {
+			return total, nil
+		}
+	}
+}
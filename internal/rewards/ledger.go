@@ -0,0 +1,74 @@
+package rewards
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/tinle0301/streaming-platform-api/internal/store"
+	"github.com/tinle0301/streaming-platform-api/internal/tenancy"
+)
+
+// Balance is a viewer's accrued watch time and channel points in one channel
+type Balance struct {
+	UserID    string        `json:"user_id"`
+	Channel   string        `json:"channel"`
+	WatchTime time.Duration `json:"watch_time_ns"`
+	Points    int           `json:"points"`
+}
+
+// PostgresLedger keeps watch time and channel point balances in PostgreSQL,
+// so they survive restarts and are shared by every WebSocket server.
+// Channels are WebSocket rooms; a tenant's rooms are namespaced, and
+// balances are stored under the tenant and the plain channel name.
+type PostgresLedger struct {
+	pool *pgxpool.Pool
+}
+
+// NewPostgresLedger creates a ledger on pool
+func NewPostgresLedger(pool *pgxpool.Pool) *PostgresLedger {
+	return &PostgresLedger{pool: pool}
+}
+
+// Accrue credits watch time and points to a viewer in a channel
+func (l *PostgresLedger) Accrue(ctx context.Context, userID, channel string, watched time.Duration, points int) error {
+	tenantID, name := tenancy.SplitRoom(channel)
+	_, err := l.pool.Exec(ctx, `
+		INSERT INTO channel_point_balances (tenant_id, user_id, channel, watch_time_ms, points)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (tenant_id, user_id, channel) DO UPDATE SET
+			watch_time_ms = channel_point_balances.watch_time_ms + EXCLUDED.watch_time_ms,
+			points = channel_point_balances.points + EXCLUDED.points,
+			updated_at = NOW()`,
+		tenantID, userID, name, watched.Milliseconds(), points)
+	if err != nil {
+		return fmt.Errorf("failed to accrue channel points: %w", err)
+	}
+	return nil
+}
+
+// Balance returns a viewer's balance in a channel of the context's tenant
+func (l *PostgresLedger) Balance(ctx context.Context, userID, channel string) (Balance, error) {
+	balance := Balance{UserID: userID, Channel: channel}
+	if !store.IsUUID(userID) {
+		return balance, nil
+	}
+
+	var watchedMs int64
+	err := l.pool.QueryRow(ctx, `
+		SELECT watch_time_ms, points FROM channel_point_balances
+		WHERE tenant_id = $1 AND user_id = $2 AND channel = $3`,
+		tenancy.ID(ctx), userID, channel,
+	).Scan(&watchedMs, &balance.Points)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return balance, nil
+	}
+	if err != nil {
+		return balance, fmt.Errorf("failed to get channel point balance: %w", err)
+	}
+	balance.WatchTime = time.Duration(watchedMs) * time.Millisecond
+	return balance, nil
+}
@@ -0,0 +1,45 @@
+package rewards
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/tinle0301/streaming-platform-api/internal/events"
+	"github.com/tinle0301/streaming-platform-api/internal/tenancy"
+)
+
+// Accruer records watch time and channel points earned by a viewer
+type Accruer interface {
+	Accrue(ctx context.Context, userID, channel string, watched time.Duration, points int) error
+}
+
+// WatchPublisher accrues to a ledger and publishes each accrual as a
+// watch.progress event, so services without access to the WebSocket server
+// (such as reward campaigns) see viewers' watch sessions
+type WatchPublisher struct {
+	next      Accruer
+	publisher events.Publisher
+}
+
+// NewWatchPublisher wraps next, publishing accruals to publisher
+func NewWatchPublisher(next Accruer, publisher events.Publisher) *WatchPublisher {
+	return &WatchPublisher{next: next, publisher: publisher}
+}
+
+// Accrue credits next and publishes the accrual. A lost event must not undo
+// the credit, so publish failures are only logged.
+func (w *WatchPublisher) Accrue(ctx context.Context, userID, channel string, watched time.Duration, points int) error {
+	if err := w.next.Accrue(ctx, userID, channel, watched, points); err != nil {
+		return err
+	}
+
+	// Tenants' rooms are namespaced; the event names the stream and carries
+	// the tenant instead
+	tenantID, streamID := tenancy.SplitRoom(channel)
+	event := events.NewWatchProgressEvent(streamID, userID, watched)
+	if err := w.publisher.Publish(tenancy.WithTenant(ctx, tenantID), event); err != nil {
+		log.Printf("Error publishing %s event: userID=%s, room=%s, err=%v", event.Type, userID, channel, err)
+	}
+	return nil
+}
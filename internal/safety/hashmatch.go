@@ -0,0 +1,313 @@
+package safety
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/tinle0301/streaming-platform-api/internal/audit"
+)
+
+// ErrContentQuarantined is returned when an upload matched a known-illegal hash
+var ErrContentQuarantined = errors.New("content quarantined")
+
+// Upload kinds that must be scanned before they are published
+const (
+	UploadKindThumbnail = "thumbnail"
+	UploadKindClip      = "clip"
+)
+
+// Upload describes user-supplied media awaiting publication
+type Upload struct {
+	ID         string
+	Kind       string
+	UploaderID string
+	StreamID   string
+	Content    []byte
+
+	// Start and End bound a clip's footage within its stream. Clips are
+	// cut from footage this service never holds, so their Content is empty
+	// and the matching service fetches the range itself.
+	Start time.Duration
+	End   time.Duration
+}
+
+// MatchResult is the verdict returned by a hash matching service
+type MatchResult struct {
+	Matched   bool   `json:"matched"`
+	ListName  string `json:"list_name,omitempty"`
+	MatchID   string `json:"match_id,omitempty"`
+	Signature string `json:"signature,omitempty"`
+}
+
+// HashMatcher defines the interface for perceptual/cryptographic hash matching
+// services (e.g. PhotoDNA-style external providers)
+type HashMatcher interface {
+	Match(ctx context.Context, upload Upload) (MatchResult, error)
+}
+
+// Quarantine stores matched content out of reach of normal serving paths
+type Quarantine interface {
+	Quarantine(ctx context.Context, upload Upload, result MatchResult) error
+
+	// IsQuarantined reports whether the upload of kind with uploadID was
+	// quarantined
+	IsQuarantined(ctx context.Context, kind, uploadID string) (bool, error)
+}
+
+// Reporter files mandatory reports for matched content (e.g. to NCMEC)
+type Reporter interface {
+	Report(ctx context.Context, upload Upload, result MatchResult) error
+}
+
+// Scanner runs uploads through a HashMatcher and handles any matches.
+//
+// Matching fails closed: if the matcher is unavailable the upload is rejected
+// rather than published unscanned.
+type Scanner struct {
+	matcher    HashMatcher
+	quarantine Quarantine
+	reporters  []Reporter
+	auditLog   audit.Logger
+}
+
+// NewScanner creates a new upload scanner
+func NewScanner(matcher HashMatcher, quarantine Quarantine, auditLog audit.Logger, reporters ...Reporter) *Scanner {
+	return &Scanner{
+		matcher:    matcher,
+		quarantine: quarantine,
+		reporters:  reporters,
+		auditLog:   auditLog,
+	}
+}
+
+// ScanUpload must be invoked on every thumbnail and clip upload before it is
+// stored or published. It returns ErrContentQuarantined for matched content,
+// and for content quarantined earlier without matching it again.
+func (s *Scanner) ScanUpload(ctx context.Context, upload Upload) error {
+	quarantined, err := s.quarantine.IsQuarantined(ctx, upload.Kind, upload.ID)
+	if err != nil {
+		return fmt.Errorf("failed to check quarantine: %w", err)
+	}
+	if quarantined {
+		return ErrContentQuarantined
+	}
+
+	result, err := s.matcher.Match(ctx, upload)
+	if err != nil {
+		s.record(ctx, audit.ActionContentScanFailed, upload, MatchResult{}, err.Error())
+		return fmt.Errorf("failed to scan upload: %w", err)
+	}
+
+	if !result.Matched {
+		return nil
+	}
+
+	s.record(ctx, audit.ActionContentMatched, upload, result, "")
+
+	if err := s.quarantine.Quarantine(ctx, upload, result); err != nil {
+		// Reporting still proceeds; the upload is rejected either way
+		log.Printf("Error quarantining upload: id=%s, err=%v", upload.ID, err)
+	} else {
+		s.record(ctx, audit.ActionContentQuarantined, upload, result, "")
+	}
+
+	for _, reporter := range s.reporters {
+		if err := reporter.Report(ctx, upload, result); err != nil {
+			log.Printf("Error filing mandatory report: id=%s, err=%v", upload.ID, err)
+			s.record(ctx, audit.ActionContentReportFailed, upload, result, err.Error())
+			continue
+		}
+		s.record(ctx, audit.ActionContentReported, upload, result, "")
+	}
+
+	return ErrContentQuarantined
+}
+
+// record writes an audit entry for a scan outcome
+func (s *Scanner) record(ctx context.Context, action string, upload Upload, result MatchResult, reason string) {
+	if s.auditLog == nil {
+		return
+	}
+
+	entry := audit.Entry{
+		Action:   action,
+		ActorID:  "system",
+		TargetID: upload.UploaderID,
+		Resource: fmt.Sprintf("%s:%s", upload.Kind, upload.ID),
+		Reason:   reason,
+		Metadata: map[string]string{
+			"sha256":    contentDigest(upload.Content),
+			"stream_id": upload.StreamID,
+		},
+	}
+	if result.Matched {
+		entry.Metadata["list_name"] = result.ListName
+		entry.Metadata["match_id"] = result.MatchID
+	}
+
+	if err := s.auditLog.Record(ctx, entry); err != nil {
+		log.Printf("Error recording audit entry: %v", err)
+	}
+}
+
+// HTTPHashMatcher implements HashMatcher against an external matching service
+type HTTPHashMatcher struct {
+	endpoint string
+	apiKey   string
+	client   *http.Client
+}
+
+// NewHTTPHashMatcher creates a matcher that POSTs content digests to endpoint
+func NewHTTPHashMatcher(endpoint, apiKey string) *HTTPHashMatcher {
+	return &HTTPHashMatcher{
+		endpoint: endpoint,
+		apiKey:   apiKey,
+		client:   &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Match submits the upload to the external service and returns its verdict
+func (m *HTTPHashMatcher) Match(ctx context.Context, upload Upload) (MatchResult, error) {
+	request := map[string]interface{}{
+		"id":        upload.ID,
+		"kind":      upload.Kind,
+		"stream_id": upload.StreamID,
+	}
+	if len(upload.Content) > 0 {
+		request["sha256"] = contentDigest(upload.Content)
+		request["content"] = upload.Content
+	} else {
+		request["start_ms"] = upload.Start.Milliseconds()
+		request["end_ms"] = upload.End.Milliseconds()
+	}
+	payload, err := json.Marshal(request)
+	if err != nil {
+		return MatchResult{}, fmt.Errorf("failed to marshal match request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, m.endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return MatchResult{}, fmt.Errorf("failed to create match request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+m.apiKey)
+
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return MatchResult{}, fmt.Errorf("failed to call hash matching service: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return MatchResult{}, fmt.Errorf("hash matching service returned status %d", resp.StatusCode)
+	}
+
+	var result MatchResult
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return MatchResult{}, fmt.Errorf("failed to decode match response: %w", err)
+	}
+	return result, nil
+}
+
+// HTTPReporter implements Reporter against an external reporting service,
+// which files the mandatory report with the authorities
+type HTTPReporter struct {
+	endpoint string
+	apiKey   string
+	client   *http.Client
+}
+
+// NewHTTPReporter creates a reporter that POSTs matches to endpoint
+func NewHTTPReporter(endpoint, apiKey string) *HTTPReporter {
+	return &HTTPReporter{
+		endpoint: endpoint,
+		apiKey:   apiKey,
+		client:   &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Report files a report of the matched upload
+func (r *HTTPReporter) Report(ctx context.Context, upload Upload, result MatchResult) error {
+	report := map[string]interface{}{
+		"id":          upload.ID,
+		"kind":        upload.Kind,
+		"uploader_id": upload.UploaderID,
+		"stream_id":   upload.StreamID,
+		"list_name":   result.ListName,
+		"match_id":    result.MatchID,
+		"signature":   result.Signature,
+	}
+	if len(upload.Content) > 0 {
+		report["sha256"] = contentDigest(upload.Content)
+		report["content"] = upload.Content
+	} else {
+		report["start_ms"] = upload.Start.Milliseconds()
+		report["end_ms"] = upload.End.Milliseconds()
+	}
+	payload, err := json.Marshal(report)
+	if err != nil {
+		return fmt.Errorf("failed to marshal report: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("failed to create report request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+r.apiKey)
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call reporting service: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("reporting service returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// MemoryQuarantine implements Quarantine in memory (development only)
+type MemoryQuarantine struct {
+	items map[string]Upload
+	mu    sync.Mutex
+}
+
+// NewMemoryQuarantine creates a new in-memory quarantine
+func NewMemoryQuarantine() *MemoryQuarantine {
+	return &MemoryQuarantine{
+		items: make(map[string]Upload),
+	}
+}
+
+// Quarantine stores the upload under its kind and ID
+func (q *MemoryQuarantine) Quarantine(ctx context.Context, upload Upload, result MatchResult) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.items[upload.Kind+":"+upload.ID] = upload
+	return nil
+}
+
+// IsQuarantined reports whether an upload is quarantined
+func (q *MemoryQuarantine) IsQuarantined(ctx context.Context, kind, uploadID string) (bool, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	_, ok := q.items[kind+":"+uploadID]
+	return ok, nil
+}
+
+// contentDigest returns the hex SHA-256 of content
+func contentDigest(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,74 @@
+package safety
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestScanUploadFailsClosed(t *testing.T) {
+	var request map[string]interface{}
+	status := http.StatusServiceUnavailable
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&request)
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(MatchResult{Matched: true, ListName: "test-list"})
+	}))
+	defer server.Close()
+
+	quarantine := NewMemoryQuarantine()
+	scanner := NewScanner(NewHTTPHashMatcher(server.URL, "key"), quarantine, nil)
+	clip := Upload{ID: "clip-1", Kind: UploadKindClip, StreamID: "stream-1", Start: time.Second, End: 6 * time.Second}
+
+	if err := scanner.ScanUpload(context.Background(), clip); err == nil || errors.Is(err, ErrContentQuarantined) {
+		t.Fatalf("ScanUpload with the matcher down = %v, want a scan failure", err)
+	}
+	if request["end_ms"] != float64(6000) || request["content"] != nil {
+		t.Errorf("match request = %v, want the clip's range and no content", request)
+	}
+
+	status = http.StatusOK
+	if err := scanner.ScanUpload(context.Background(), clip); !errors.Is(err, ErrContentQuarantined) {
+		t.Fatalf("ScanUpload of matched content = %v, want ErrContentQuarantined", err)
+	}
+	if quarantined, _ := quarantine.IsQuarantined(context.Background(), UploadKindClip, "clip-1"); !quarantined {
+		t.Error("matched clip was not quarantined")
+	}
+}
+
+func TestScanUploadReportsMatchesOnce(t *testing.T) {
+	matches := 0
+	matcher := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		matches++
+		json.NewEncoder(w).Encode(MatchResult{Matched: true, ListName: "test-list", MatchID: "m-1"})
+	}))
+	defer matcher.Close()
+	var report map[string]interface{}
+	reporter := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&report)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer reporter.Close()
+
+	scanner := NewScanner(NewHTTPHashMatcher(matcher.URL, "key"), NewMemoryQuarantine(), nil,
+		NewHTTPReporter(reporter.URL, "key"))
+	clip := Upload{ID: "clip-1", Kind: UploadKindClip, UploaderID: "user-1", Start: time.Second, End: 6 * time.Second}
+
+	if err := scanner.ScanUpload(context.Background(), clip); !errors.Is(err, ErrContentQuarantined) {
+		t.Fatalf("ScanUpload of matched content = %v, want ErrContentQuarantined", err)
+	}
+	if report["id"] != "clip-1" || report["uploader_id"] != "user-1" || report["match_id"] != "m-1" {
+		t.Errorf("report = %v, want the clip, its uploader, and the match", report)
+	}
+
+	if err := scanner.ScanUpload(context.Background(), clip); !errors.Is(err, ErrContentQuarantined) {
+		t.Fatalf("ScanUpload of quarantined content = %v, want ErrContentQuarantined", err)
+	}
+	if matches != 1 {
+		t.Errorf("matcher called %d times, want once", matches)
+	}
+}
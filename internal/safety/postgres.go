@@ -0,0 +1,67 @@
+package safety
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/tinle0301/streaming-platform-api/internal/store"
+	"github.com/tinle0301/streaming-platform-api/internal/tenancy"
+)
+
+// PostgresQuarantine implements Quarantine on PostgreSQL. Matches are
+// recorded in quarantined_uploads, and a matched clip is moved to the
+// QUARANTINED status, which the clip repository treats as missing, so it
+// can no longer be served, edited, or published.
+type PostgresQuarantine struct {
+	pool *pgxpool.Pool
+}
+
+// NewPostgresQuarantine creates a quarantine on pool
+func NewPostgresQuarantine(pool *pgxpool.Pool) *PostgresQuarantine {
+	return &PostgresQuarantine{pool: pool}
+}
+
+// Quarantine records the match and withdraws a matched clip
+func (q *PostgresQuarantine) Quarantine(ctx context.Context, upload Upload, result MatchResult) error {
+	tx, err := q.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO quarantined_uploads (kind, upload_id, uploader_id, stream_id, sha256, list_name, match_id,
+			signature, tenant_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		ON CONFLICT (kind, upload_id) DO NOTHING`,
+		upload.Kind, upload.ID, upload.UploaderID, upload.StreamID, contentDigest(upload.Content),
+		result.ListName, result.MatchID, result.Signature, tenancy.ID(ctx))
+	if err != nil {
+		return fmt.Errorf("failed to quarantine upload: %w", err)
+	}
+
+	if upload.Kind == UploadKindClip && store.IsUUID(upload.ID) {
+		_, err := tx.Exec(ctx, `UPDATE clips SET status = 'QUARANTINED', updated_at = NOW() WHERE id = $1`, upload.ID)
+		if err != nil {
+			return fmt.Errorf("failed to quarantine clip: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit quarantine: %w", err)
+	}
+	return nil
+}
+
+// IsQuarantined reports whether an upload was quarantined
+func (q *PostgresQuarantine) IsQuarantined(ctx context.Context, kind, uploadID string) (bool, error) {
+	var quarantined bool
+	err := q.pool.QueryRow(ctx, `
+		SELECT EXISTS (SELECT 1 FROM quarantined_uploads WHERE kind = $1 AND upload_id = $2)`,
+		kind, uploadID).Scan(&quarantined)
+	if err != nil {
+		return false, fmt.Errorf("failed to check quarantine: %w", err)
+	}
+	return quarantined, nil
+}
@@ -0,0 +1,798 @@
+package scim
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tinle0301/streaming-platform-api/internal/tenancy"
+	"github.com/tinle0301/streaming-platform-api/internal/users"
+)
+
+// BasePath is where Handler is mounted
+const BasePath = "/scim/v2/"
+
+// SCIM schema URNs
+const (
+	schemaUser      = "urn:ietf:params:scim:schemas:core:2.0:User"
+	schemaGroup     = "urn:ietf:params:scim:schemas:core:2.0:Group"
+	schemaList      = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	schemaError     = "urn:ietf:params:scim:api:messages:2.0:Error"
+	schemaProviders = "urn:ietf:params:scim:schemas:core:2.0:ServiceProviderConfig"
+)
+
+// List and request limits
+const (
+	defaultCount = 100
+	maxCount     = 200
+	maxBodyBytes = 1 << 20
+)
+
+// filterPattern matches the one filter form supported: attribute eq "value"
+var filterPattern = regexp.MustCompile(`(?i)^\s*([a-z.]+)\s+eq\s+("(?:[^"\\]|\\.)*")\s*$`)
+
+// errBadRequest is returned for malformed requests; its message is shown
+type errBadRequest struct {
+	scimType string
+	detail   string
+}
+
+// Error implements the error interface
+func (e *errBadRequest) Error() string {
+	return e.detail
+}
+
+// Handler serves the SCIM 2.0 API under BasePath. Identity providers
+// authenticate with their directory's bearer token, and every request is
+// scoped to the tenant the directory was enabled in.
+func Handler(service *Service) http.Handler {
+	h := &handler{service: service}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		dir, err := service.Authenticate(r.Context(), token)
+		if errors.Is(err, ErrInvalidToken) {
+			writeError(w, http.StatusUnauthorized, "", "Invalid SCIM token")
+			return
+		}
+		if err != nil {
+			log.Printf("Error authenticating SCIM request: %v", err)
+			writeError(w, http.StatusInternalServerError, "", "Internal server error")
+			return
+		}
+		r = r.WithContext(tenancy.WithTenant(r.Context(), dir.TenantID))
+		r.Body = http.MaxBytesReader(w, r.Body, maxBodyBytes)
+
+		resource, id, _ := strings.Cut(strings.Trim(strings.TrimPrefix(r.URL.Path, BasePath), "/"), "/")
+		switch resource {
+		case "ServiceProviderConfig":
+			h.serviceProviderConfig(w, r)
+		case "Users":
+			h.users(w, r, dir, id)
+		case "Groups":
+			h.groups(w, r, dir, id)
+		default:
+			writeError(w, http.StatusNotFound, "", "Unknown resource")
+		}
+	})
+}
+
+// handler serves SCIM resources
+type handler struct {
+	service *Service
+}
+
+// nameResource is a user's name
+type nameResource struct {
+	GivenName  string `json:"givenName,omitempty"`
+	FamilyName string `json:"familyName,omitempty"`
+	Formatted  string `json:"formatted,omitempty"`
+}
+
+// emailResource is one of a user's emails
+type emailResource struct {
+	Value   string `json:"value"`
+	Type    string `json:"type,omitempty"`
+	Primary bool   `json:"primary,omitempty"`
+}
+
+// memberResource references a user or group
+type memberResource struct {
+	Value   string `json:"value"`
+	Display string `json:"display,omitempty"`
+}
+
+// metaResource describes a resource
+type metaResource struct {
+	ResourceType string    `json:"resourceType"`
+	Created      time.Time `json:"created"`
+	LastModified time.Time `json:"lastModified"`
+	Location     string    `json:"location"`
+}
+
+// userResource is the SCIM User representation
+type userResource struct {
+	Schemas     []string         `json:"schemas"`
+	ID          string           `json:"id,omitempty"`
+	ExternalID  string           `json:"externalId,omitempty"`
+	UserName    string           `json:"userName"`
+	Name        *nameResource    `json:"name,omitempty"`
+	DisplayName string           `json:"displayName,omitempty"`
+	Emails      []emailResource  `json:"emails,omitempty"`
+	Active      *bool            `json:"active,omitempty"`
+	Groups      []memberResource `json:"groups,omitempty"`
+	Meta        *metaResource    `json:"meta,omitempty"`
+}
+
+// groupResource is the SCIM Group representation
+type groupResource struct {
+	Schemas     []string         `json:"schemas"`
+	ID          string           `json:"id,omitempty"`
+	ExternalID  string           `json:"externalId,omitempty"`
+	DisplayName string           `json:"displayName"`
+	Members     []memberResource `json:"members"`
+	Meta        *metaResource    `json:"meta,omitempty"`
+}
+
+// patchRequest is a PATCH body
+type patchRequest struct {
+	Operations []patchOperation `json:"Operations"`
+}
+
+// patchOperation is one PATCH operation
+type patchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	Value json.RawMessage `json:"value"`
+}
+
+// serviceProviderConfig describes what the API supports
+func (h *handler) serviceProviderConfig(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "", "Method not allowed")
+		return
+	}
+	supported := func(ok bool) map[string]bool { return map[string]bool{"supported": ok} }
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"schemas":        []string{schemaProviders},
+		"patch":          supported(true),
+		"bulk":           map[string]interface{}{"supported": false, "maxOperations": 0, "maxPayloadSize": 0},
+		"filter":         map[string]interface{}{"supported": true, "maxResults": maxCount},
+		"changePassword": supported(false),
+		"sort":           supported(false),
+		"etag":           supported(false),
+		"authenticationSchemes": []map[string]string{{
+			"type":        "oauthbearertoken",
+			"name":        "OAuth Bearer Token",
+			"description": "The token shown when SCIM was enabled for the organization",
+		}},
+	})
+}
+
+// users serves /Users and /Users/{id}
+func (h *handler) users(w http.ResponseWriter, r *http.Request, dir *Directory, id string) {
+	ctx := r.Context()
+	switch {
+	case id == "" && r.Method == http.MethodGet:
+		filter, offset, limit, err := listParams(r, "userName", "externalId")
+		if err != nil {
+			h.fail(w, r, err)
+			return
+		}
+		list, total, err := h.service.Users(ctx, dir, filter, offset, limit)
+		if err != nil {
+			h.fail(w, r, err)
+			return
+		}
+		resources := make([]*userResource, len(list))
+		for i, user := range list {
+			resources[i] = toUserResource(r, user, nil)
+		}
+		writeList(w, resources, total, offset, len(resources))
+
+	case id == "" && r.Method == http.MethodPost:
+		var body userResource
+		if err := decode(r, &body); err != nil {
+			h.fail(w, r, err)
+			return
+		}
+		user, err := fromUserResource(&body)
+		if err != nil {
+			h.fail(w, r, err)
+			return
+		}
+		if err := h.service.CreateUser(ctx, dir, user); err != nil {
+			h.fail(w, r, err)
+			return
+		}
+		writeJSON(w, http.StatusCreated, toUserResource(r, user, []GroupRef{}))
+
+	case id == "":
+		writeError(w, http.StatusMethodNotAllowed, "", "Method not allowed")
+
+	case r.Method == http.MethodGet:
+		user, err := h.service.User(ctx, dir, id)
+		if err != nil {
+			h.fail(w, r, err)
+			return
+		}
+		h.writeUser(w, r, dir, user)
+
+	case r.Method == http.MethodPut:
+		var body userResource
+		if err := decode(r, &body); err != nil {
+			h.fail(w, r, err)
+			return
+		}
+		user, err := fromUserResource(&body)
+		if err != nil {
+			h.fail(w, r, err)
+			return
+		}
+		user.ID = id
+		if err := h.service.ReplaceUser(ctx, dir, user); err != nil {
+			h.fail(w, r, err)
+			return
+		}
+		h.writeUser(w, r, dir, user)
+
+	case r.Method == http.MethodPatch:
+		var body patchRequest
+		if err := decode(r, &body); err != nil {
+			h.fail(w, r, err)
+			return
+		}
+		user, err := h.service.User(ctx, dir, id)
+		if err != nil {
+			h.fail(w, r, err)
+			return
+		}
+		for _, op := range body.Operations {
+			if err := patchUser(user, op); err != nil {
+				h.fail(w, r, err)
+				return
+			}
+		}
+		if err := h.service.ReplaceUser(ctx, dir, user); err != nil {
+			h.fail(w, r, err)
+			return
+		}
+		h.writeUser(w, r, dir, user)
+
+	case r.Method == http.MethodDelete:
+		if err := h.service.DeleteUser(ctx, dir, id); err != nil {
+			h.fail(w, r, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "", "Method not allowed")
+	}
+}
+
+// writeUser writes a user with their groups
+func (h *handler) writeUser(w http.ResponseWriter, r *http.Request, dir *Directory, user *User) {
+	groups, err := h.service.UserGroups(r.Context(), dir, user.ID)
+	if err != nil {
+		h.fail(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, toUserResource(r, user, groups))
+}
+
+// groups serves /Groups and /Groups/{id}
+func (h *handler) groups(w http.ResponseWriter, r *http.Request, dir *Directory, id string) {
+	ctx := r.Context()
+	switch {
+	case id == "" && r.Method == http.MethodGet:
+		filter, offset, limit, err := listParams(r, "displayName", "externalId")
+		if err != nil {
+			h.fail(w, r, err)
+			return
+		}
+		list, total, err := h.service.Groups(ctx, dir, filter, offset, limit)
+		if err != nil {
+			h.fail(w, r, err)
+			return
+		}
+		resources := make([]*groupResource, len(list))
+		for i, group := range list {
+			resources[i] = toGroupResource(r, group)
+		}
+		writeList(w, resources, total, offset, len(resources))
+
+	case id == "" && r.Method == http.MethodPost:
+		var body groupResource
+		if err := decode(r, &body); err != nil {
+			h.fail(w, r, err)
+			return
+		}
+		group, err := fromGroupResource(&body)
+		if err != nil {
+			h.fail(w, r, err)
+			return
+		}
+		if err := h.service.CreateGroup(ctx, dir, group); err != nil {
+			h.fail(w, r, err)
+			return
+		}
+		writeJSON(w, http.StatusCreated, toGroupResource(r, group))
+
+	case id == "":
+		writeError(w, http.StatusMethodNotAllowed, "", "Method not allowed")
+
+	case r.Method == http.MethodGet:
+		group, err := h.service.Group(ctx, dir, id)
+		if err != nil {
+			h.fail(w, r, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, toGroupResource(r, group))
+
+	case r.Method == http.MethodPut:
+		var body groupResource
+		if err := decode(r, &body); err != nil {
+			h.fail(w, r, err)
+			return
+		}
+		group, err := fromGroupResource(&body)
+		if err != nil {
+			h.fail(w, r, err)
+			return
+		}
+		group.ID = id
+		if err := h.service.ReplaceGroup(ctx, dir, group); err != nil {
+			h.fail(w, r, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, toGroupResource(r, group))
+
+	case r.Method == http.MethodPatch:
+		var body patchRequest
+		if err := decode(r, &body); err != nil {
+			h.fail(w, r, err)
+			return
+		}
+		group, err := h.service.Group(ctx, dir, id)
+		if err != nil {
+			h.fail(w, r, err)
+			return
+		}
+		for _, op := range body.Operations {
+			if err := patchGroup(group, op); err != nil {
+				h.fail(w, r, err)
+				return
+			}
+		}
+		if err := h.service.ReplaceGroup(ctx, dir, group); err != nil {
+			h.fail(w, r, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, toGroupResource(r, group))
+
+	case r.Method == http.MethodDelete:
+		if err := h.service.DeleteGroup(ctx, dir, id); err != nil {
+			h.fail(w, r, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "", "Method not allowed")
+	}
+}
+
+// fail writes the SCIM error for err
+func (h *handler) fail(w http.ResponseWriter, r *http.Request, err error) {
+	var badRequest *errBadRequest
+	var validation *users.ValidationError
+	switch {
+	case errors.As(err, &badRequest):
+		writeError(w, http.StatusBadRequest, badRequest.scimType, badRequest.detail)
+	case errors.As(err, &validation):
+		writeError(w, http.StatusBadRequest, "invalidValue", validation.Error())
+	case errors.Is(err, ErrUserNotFound), errors.Is(err, ErrGroupNotFound), errors.Is(err, users.ErrNotFound):
+		writeError(w, http.StatusNotFound, "", err.Error())
+	case errors.Is(err, ErrUserExists), errors.Is(err, ErrGroupExists),
+		errors.Is(err, users.ErrUsernameTaken), errors.Is(err, users.ErrEmailTaken):
+		writeError(w, http.StatusConflict, "uniqueness", err.Error())
+	default:
+		log.Printf("Error serving SCIM request: method=%s, path=%s, err=%v", r.Method, r.URL.Path, err)
+		writeError(w, http.StatusInternalServerError, "", "Internal server error")
+	}
+}
+
+// listParams parses a list request's filter, startIndex, and count into a
+// filter, offset, and limit; filters may compare the given attributes
+func listParams(r *http.Request, attributes ...string) (Filter, int, int, error) {
+	query := r.URL.Query()
+
+	var filter Filter
+	if raw := query.Get("filter"); raw != "" {
+		match := filterPattern.FindStringSubmatch(raw)
+		if match == nil {
+			return Filter{}, 0, 0, &errBadRequest{scimType: "invalidFilter", detail: `Only "attribute eq \"value\"" filters are supported`}
+		}
+		value, err := strconv.Unquote(match[2])
+		if err != nil {
+			return Filter{}, 0, 0, &errBadRequest{scimType: "invalidFilter", detail: "Invalid filter value"}
+		}
+		for _, attribute := range attributes {
+			if strings.EqualFold(attribute, match[1]) {
+				filter = Filter{Attribute: attribute, Value: value}
+			}
+		}
+		if filter.Attribute == "" {
+			return Filter{}, 0, 0, &errBadRequest{scimType: "invalidFilter", detail: "Filtering on " + match[1] + " is not supported"}
+		}
+	}
+
+	start, count := 1, defaultCount
+	if raw := query.Get("startIndex"); raw != "" {
+		if n, err := strconv.Atoi(raw); err == nil && n > 1 {
+			start = n
+		}
+	}
+	if raw := query.Get("count"); raw != "" {
+		if n, err := strconv.Atoi(raw); err == nil {
+			count = max(0, min(n, maxCount))
+		}
+	}
+	return filter, start - 1, count, nil
+}
+
+// fromUserResource converts a POST or PUT body
+func fromUserResource(body *userResource) (*User, error) {
+	user := &User{
+		UserName:    strings.TrimSpace(body.UserName),
+		ExternalID:  body.ExternalID,
+		DisplayName: body.DisplayName,
+		Email:       primaryEmail(body.Emails),
+		Active:      body.Active == nil || *body.Active,
+	}
+	if user.UserName == "" {
+		return nil, &errBadRequest{scimType: "invalidValue", detail: "userName is required"}
+	}
+	if body.Name != nil {
+		user.GivenName, user.FamilyName = body.Name.GivenName, body.Name.FamilyName
+	}
+	if user.DisplayName == "" {
+		user.DisplayName = strings.TrimSpace(user.GivenName + " " + user.FamilyName)
+	}
+	return user, nil
+}
+
+// toUserResource converts a user; groups are left out when nil
+func toUserResource(r *http.Request, user *User, groups []GroupRef) *userResource {
+	active := user.Active
+	resource := &userResource{
+		Schemas:     []string{schemaUser},
+		ID:          user.ID,
+		ExternalID:  user.ExternalID,
+		UserName:    user.UserName,
+		Name:        &nameResource{GivenName: user.GivenName, FamilyName: user.FamilyName},
+		DisplayName: user.DisplayName,
+		Emails:      []emailResource{{Value: user.Email, Type: "work", Primary: true}},
+		Active:      &active,
+		Meta:        meta(r, "User", "Users", user.ID, user.CreatedAt, user.UpdatedAt),
+	}
+	for _, g := range groups {
+		resource.Groups = append(resource.Groups, memberResource{Value: g.ID, Display: g.DisplayName})
+	}
+	return resource
+}
+
+// fromGroupResource converts a POST or PUT body
+func fromGroupResource(body *groupResource) (*Group, error) {
+	group := &Group{
+		DisplayName: strings.TrimSpace(body.DisplayName),
+		ExternalID:  body.ExternalID,
+		Members:     make([]string, 0, len(body.Members)),
+	}
+	if group.DisplayName == "" {
+		return nil, &errBadRequest{scimType: "invalidValue", detail: "displayName is required"}
+	}
+	for _, m := range body.Members {
+		group.Members = append(group.Members, m.Value)
+	}
+	return group, nil
+}
+
+// toGroupResource converts a group
+func toGroupResource(r *http.Request, group *Group) *groupResource {
+	resource := &groupResource{
+		Schemas:     []string{schemaGroup},
+		ID:          group.ID,
+		ExternalID:  group.ExternalID,
+		DisplayName: group.DisplayName,
+		Members:     make([]memberResource, len(group.Members)),
+		Meta:        meta(r, "Group", "Groups", group.ID, group.CreatedAt, group.UpdatedAt),
+	}
+	for i, id := range group.Members {
+		resource.Members[i] = memberResource{Value: id}
+	}
+	return resource
+}
+
+// patchUser applies a PATCH operation to a user. Attributes this API
+// doesn't store are ignored, so identity providers can send their usual
+// payloads.
+func patchUser(user *User, op patchOperation) error {
+	switch strings.ToLower(op.Op) {
+	case "add", "replace":
+		if op.Path != "" {
+			return setUserAttribute(user, op.Path, op.Value)
+		}
+		var values map[string]json.RawMessage
+		if err := json.Unmarshal(op.Value, &values); err != nil {
+			return &errBadRequest{scimType: "invalidValue", detail: "value must be an object when path is omitted"}
+		}
+		for path, value := range values {
+			if err := setUserAttribute(user, path, value); err != nil {
+				return err
+			}
+		}
+		return nil
+	case "remove":
+		switch strings.ToLower(op.Path) {
+		case "externalid":
+			user.ExternalID = ""
+		case "name.givenname":
+			user.GivenName = ""
+		case "name.familyname":
+			user.FamilyName = ""
+		}
+		return nil
+	default:
+		return &errBadRequest{scimType: "invalidSyntax", detail: "Unknown PATCH op " + op.Op}
+	}
+}
+
+// setUserAttribute sets the attribute at path
+func setUserAttribute(user *User, path string, value json.RawMessage) error {
+	lower := strings.ToLower(path)
+	switch {
+	case lower == "active":
+		active, err := parseBool(value)
+		if err != nil {
+			return err
+		}
+		user.Active = active
+		return nil
+	case lower == "username":
+		return decodeValue(value, &user.UserName)
+	case lower == "externalid":
+		return decodeValue(value, &user.ExternalID)
+	case lower == "displayname":
+		return decodeValue(value, &user.DisplayName)
+	case lower == "name.givenname":
+		return decodeValue(value, &user.GivenName)
+	case lower == "name.familyname":
+		return decodeValue(value, &user.FamilyName)
+	case lower == "name":
+		var name nameResource
+		if err := decodeValue(value, &name); err != nil {
+			return err
+		}
+		user.GivenName, user.FamilyName = name.GivenName, name.FamilyName
+		return nil
+	case lower == "emails":
+		var emails []emailResource
+		if err := decodeValue(value, &emails); err != nil {
+			return err
+		}
+		if email := primaryEmail(emails); email != "" {
+			user.Email = email
+		}
+		return nil
+	case strings.HasPrefix(lower, "emails["):
+		// e.g. emails[type eq "work"].value
+		return decodeValue(value, &user.Email)
+	default:
+		return nil
+	}
+}
+
+// patchGroup applies a PATCH operation to a group
+func patchGroup(group *Group, op patchOperation) error {
+	path := strings.ToLower(op.Path)
+	switch strings.ToLower(op.Op) {
+	case "add", "replace":
+		switch path {
+		case "displayname":
+			return decodeValue(op.Value, &group.DisplayName)
+		case "externalid":
+			return decodeValue(op.Value, &group.ExternalID)
+		case "members":
+			members, err := memberIDs(op.Value)
+			if err != nil {
+				return err
+			}
+			if strings.EqualFold(op.Op, "replace") {
+				group.Members = members
+			} else {
+				group.Members = append(group.Members, members...)
+			}
+			return nil
+		case "":
+			var body groupResource
+			if err := decodeValue(op.Value, &body); err != nil {
+				return err
+			}
+			if body.DisplayName != "" {
+				group.DisplayName = body.DisplayName
+			}
+			if body.ExternalID != "" {
+				group.ExternalID = body.ExternalID
+			}
+			if body.Members != nil {
+				group.Members = group.Members[:0]
+				for _, m := range body.Members {
+					group.Members = append(group.Members, m.Value)
+				}
+			}
+			return nil
+		default:
+			return nil
+		}
+	case "remove":
+		var removed []string
+		switch {
+		case path == "members" && len(op.Value) == 0:
+			group.Members = []string{}
+			return nil
+		case path == "members":
+			members, err := memberIDs(op.Value)
+			if err != nil {
+				return err
+			}
+			removed = members
+		case strings.HasPrefix(path, "members["):
+			// members[value eq "id"]
+			match := filterPattern.FindStringSubmatch(strings.TrimSuffix(op.Path[len("members["):], "]"))
+			if match == nil || !strings.EqualFold(match[1], "value") {
+				return &errBadRequest{scimType: "invalidPath", detail: "Unsupported members filter"}
+			}
+			id, err := strconv.Unquote(match[2])
+			if err != nil {
+				return &errBadRequest{scimType: "invalidPath", detail: "Unsupported members filter"}
+			}
+			removed = []string{id}
+		case path == "externalid":
+			group.ExternalID = ""
+			return nil
+		default:
+			return nil
+		}
+
+		kept := group.Members[:0]
+		for _, id := range group.Members {
+			if !contains(removed, id) {
+				kept = append(kept, id)
+			}
+		}
+		group.Members = kept
+		return nil
+	default:
+		return &errBadRequest{scimType: "invalidSyntax", detail: "Unknown PATCH op " + op.Op}
+	}
+}
+
+// memberIDs decodes a list of member references
+func memberIDs(value json.RawMessage) ([]string, error) {
+	var members []memberResource
+	if err := decodeValue(value, &members); err != nil {
+		return nil, err
+	}
+	ids := make([]string, len(members))
+	for i, m := range members {
+		ids[i] = m.Value
+	}
+	return ids, nil
+}
+
+// primaryEmail returns the primary email, or the first
+func primaryEmail(emails []emailResource) string {
+	for _, e := range emails {
+		if e.Primary {
+			return e.Value
+		}
+	}
+	if len(emails) > 0 {
+		return emails[0].Value
+	}
+	return ""
+}
+
+// parseBool decodes a boolean, also accepting strings like "False", which
+// some identity providers send in PATCH operations
+func parseBool(value json.RawMessage) (bool, error) {
+	var b bool
+	if err := json.Unmarshal(value, &b); err == nil {
+		return b, nil
+	}
+	var s string
+	if err := json.Unmarshal(value, &s); err == nil {
+		if b, err := strconv.ParseBool(s); err == nil {
+			return b, nil
+		}
+	}
+	return false, &errBadRequest{scimType: "invalidValue", detail: "active must be a boolean"}
+}
+
+// decodeValue decodes a PATCH value into v
+func decodeValue(value json.RawMessage, v interface{}) error {
+	if err := json.Unmarshal(value, v); err != nil {
+		return &errBadRequest{scimType: "invalidValue", detail: fmt.Sprintf("Invalid value: %v", err)}
+	}
+	return nil
+}
+
+// contains reports whether ids includes id
+func contains(ids []string, id string) bool {
+	for _, candidate := range ids {
+		if candidate == id {
+			return true
+		}
+	}
+	return false
+}
+
+// decode decodes a JSON request body into v
+func decode(r *http.Request, v interface{}) error {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		return &errBadRequest{scimType: "invalidSyntax", detail: "Invalid JSON body"}
+	}
+	return nil
+}
+
+// meta describes a resource, locating it on the host the request came to
+func meta(r *http.Request, resourceType, collection, id string, created, modified time.Time) *metaResource {
+	scheme := "https"
+	if r.TLS == nil && r.Header.Get("X-Forwarded-Proto") != "https" {
+		scheme = "http"
+	}
+	return &metaResource{
+		ResourceType: resourceType,
+		Created:      created,
+		LastModified: modified,
+		Location:     scheme + "://" + r.Host + BasePath + collection + "/" + id,
+	}
+}
+
+// writeList writes a ListResponse
+func writeList(w http.ResponseWriter, resources interface{}, total, offset, count int) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"schemas":      []string{schemaList},
+		"totalResults": total,
+		"startIndex":   offset + 1,
+		"itemsPerPage": count,
+		"Resources":    resources,
+	})
+}
+
+// writeError writes a SCIM Error response
+func writeError(w http.ResponseWriter, status int, scimType, detail string) {
+	body := map[string]interface{}{
+		"schemas": []string{schemaError},
+		"status":  strconv.Itoa(status),
+		"detail":  detail,
+	}
+	if scimType != "" {
+		body["scimType"] = scimType
+	}
+	writeJSON(w, status, body)
+}
+
+// writeJSON writes a SCIM JSON response
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/scim+json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,655 @@
+// Package scim provisions enterprise organizations' accounts from their
+// identity providers over SCIM 2.0 (RFC 7643, RFC 7644). Each organization
+// may enable one directory: its identity provider creates, updates, and
+// deactivates accounts in the organization's tenant and syncs groups,
+// whose memberships grant organization roles.
+package scim
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/tinle0301/streaming-platform-api/internal/orgs"
+	"github.com/tinle0301/streaming-platform-api/internal/store"
+)
+
+// Repository errors
+var (
+	ErrDirectoryNotFound = errors.New("SCIM is not enabled for this organization")
+	ErrUserNotFound      = errors.New("user not found")
+	ErrGroupNotFound     = errors.New("group not found")
+	ErrUserExists        = errors.New("a user with this userName or externalId already exists")
+	ErrGroupExists       = errors.New("a group with this displayName already exists")
+)
+
+// Directory is an organization's SCIM directory
+type Directory struct {
+	OrganizationID string
+	TenantID       string
+
+	// GroupRoles maps group display names, ignoring case, to the
+	// organization role their members get
+	GroupRoles map[string]orgs.Role
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// RoleFor returns the role a group grants, or "" for none
+func (d *Directory) RoleFor(group string) orgs.Role {
+	for name, role := range d.GroupRoles {
+		if strings.EqualFold(name, group) {
+			return role
+		}
+	}
+	return ""
+}
+
+// User is an account a directory provisioned
+type User struct {
+	ID             string
+	OrganizationID string
+	UserName       string
+	ExternalID     string
+	GivenName      string
+	FamilyName     string
+	Active         bool
+
+	// Kept on the account itself
+	DisplayName string
+	Email       string
+
+	// ManagedRole is the organization role the directory last granted
+	ManagedRole orgs.Role
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// Group is a directory group
+type Group struct {
+	ID             string
+	OrganizationID string
+	DisplayName    string
+	ExternalID     string
+	Members        []string
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+// GroupRef names a group a user belongs to
+type GroupRef struct {
+	ID          string
+	DisplayName string
+}
+
+// Filter is an equality filter on one attribute, e.g. userName eq "jane";
+// a zero Filter matches everything
+type Filter struct {
+	Attribute string
+	Value     string
+}
+
+// Repository persists directories and what they provisioned
+type Repository interface {
+	// SaveDirectory creates a directory or replaces its token
+	SaveDirectory(ctx context.Context, dir *Directory, tokenHash string) error
+	SetGroupRoles(ctx context.Context, orgID string, roles map[string]orgs.Role) (*Directory, error)
+	DeleteDirectory(ctx context.Context, orgID string) error
+	Directory(ctx context.Context, orgID string) (*Directory, error)
+	DirectoryByToken(ctx context.Context, tokenHash string) (*Directory, error)
+
+	CreateUser(ctx context.Context, user *User) error
+	User(ctx context.Context, orgID, userID string) (*User, error)
+
+	// Users lists provisioned users matching filter (userName or
+	// externalId), oldest first, and counts every match
+	Users(ctx context.Context, orgID string, filter Filter, offset, limit int) ([]*User, int, error)
+
+	// UpdateUser saves a user's SCIM attributes
+	UpdateUser(ctx context.Context, user *User) error
+	SetManagedRole(ctx context.Context, orgID, userID string, role orgs.Role) error
+
+	// DeleteUser unlinks a user from the directory and its groups
+	DeleteUser(ctx context.Context, orgID, userID string) error
+
+	// CreateGroup inserts a group with its members; members the directory
+	// didn't provision are left out
+	CreateGroup(ctx context.Context, group *Group) error
+	Group(ctx context.Context, orgID, id string) (*Group, error)
+
+	// Groups lists groups matching filter (displayName or externalId),
+	// oldest first, and counts every match
+	Groups(ctx context.Context, orgID string, filter Filter, offset, limit int) ([]*Group, int, error)
+
+	// UpdateGroup saves a group's name and replaces its members
+	UpdateGroup(ctx context.Context, group *Group) error
+	DeleteGroup(ctx context.Context, orgID, id string) error
+
+	// UserGroups lists the groups a user belongs to
+	UserGroups(ctx context.Context, orgID, userID string) ([]GroupRef, error)
+}
+
+// PostgresRepository implements Repository on PostgreSQL
+type PostgresRepository struct {
+	pool *pgxpool.Pool
+}
+
+// NewPostgresRepository creates a SCIM repository on pool
+func NewPostgresRepository(pool *pgxpool.Pool) *PostgresRepository {
+	return &PostgresRepository{pool: pool}
+}
+
+// directoryColumns is the column list shared by directory queries
+const directoryColumns = `organization_id::text, tenant_id, group_roles, created_at, updated_at`
+
+// SaveDirectory upserts a directory
+func (r *PostgresRepository) SaveDirectory(ctx context.Context, dir *Directory, tokenHash string) error {
+	if !store.IsUUID(dir.OrganizationID) {
+		return orgs.ErrNotFound
+	}
+
+	saved, err := scanDirectory(r.pool.QueryRow(ctx, `
+		INSERT INTO scim_directories (organization_id, tenant_id, token_hash)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (organization_id) DO UPDATE SET token_hash = EXCLUDED.token_hash, updated_at = NOW()
+		RETURNING `+directoryColumns, dir.OrganizationID, dir.TenantID, tokenHash))
+	if store.PgCode(err) == "23503" {
+		return orgs.ErrNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to save SCIM directory: %w", err)
+	}
+	*dir = *saved
+	return nil
+}
+
+// SetGroupRoles replaces a directory's group role mapping
+func (r *PostgresRepository) SetGroupRoles(ctx context.Context, orgID string, roles map[string]orgs.Role) (*Directory, error) {
+	if !store.IsUUID(orgID) {
+		return nil, ErrDirectoryNotFound
+	}
+	data, err := json.Marshal(roles)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode group roles: %w", err)
+	}
+
+	dir, err := scanDirectory(r.pool.QueryRow(ctx, `
+		UPDATE scim_directories SET group_roles = $2, updated_at = NOW()
+		WHERE organization_id = $1
+		RETURNING `+directoryColumns, orgID, data))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrDirectoryNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to set group roles: %w", err)
+	}
+	return dir, nil
+}
+
+// DeleteDirectory deletes a directory with its users and groups
+func (r *PostgresRepository) DeleteDirectory(ctx context.Context, orgID string) error {
+	if !store.IsUUID(orgID) {
+		return ErrDirectoryNotFound
+	}
+	tag, err := r.pool.Exec(ctx, `DELETE FROM scim_directories WHERE organization_id = $1`, orgID)
+	if err != nil {
+		return fmt.Errorf("failed to delete SCIM directory: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrDirectoryNotFound
+	}
+	return nil
+}
+
+// Directory reads an organization's directory
+func (r *PostgresRepository) Directory(ctx context.Context, orgID string) (*Directory, error) {
+	if !store.IsUUID(orgID) {
+		return nil, ErrDirectoryNotFound
+	}
+	return r.directory(ctx, `SELECT `+directoryColumns+` FROM scim_directories WHERE organization_id = $1`, orgID)
+}
+
+// DirectoryByToken reads the directory a token hash belongs to
+func (r *PostgresRepository) DirectoryByToken(ctx context.Context, tokenHash string) (*Directory, error) {
+	return r.directory(ctx, `SELECT `+directoryColumns+` FROM scim_directories WHERE token_hash = $1`, tokenHash)
+}
+
+// directory reads one directory
+func (r *PostgresRepository) directory(ctx context.Context, query string, arg string) (*Directory, error) {
+	dir, err := scanDirectory(r.pool.QueryRow(ctx, query, arg))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrDirectoryNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get SCIM directory: %w", err)
+	}
+	return dir, nil
+}
+
+// userColumns is the column list shared by user queries; they alias
+// scim_users as s and users as u
+const userColumns = `s.user_id::text, s.organization_id::text, s.user_name, COALESCE(s.external_id, ''),
+	s.given_name, s.family_name, s.active, u.display_name, u.email, s.managed_role, s.created_at, s.updated_at`
+
+// CreateUser links a provisioned account to the directory
+func (r *PostgresRepository) CreateUser(ctx context.Context, user *User) error {
+	err := r.pool.QueryRow(ctx, `
+		INSERT INTO scim_users (organization_id, user_id, user_name, external_id, given_name, family_name, active)
+		VALUES ($1, $2, $3, NULLIF($4, ''), $5, $6, $7)
+		RETURNING created_at, updated_at`,
+		user.OrganizationID, user.ID, user.UserName, user.ExternalID, user.GivenName, user.FamilyName, user.Active,
+	).Scan(&user.CreatedAt, &user.UpdatedAt)
+	if store.PgCode(err) == "23505" {
+		return ErrUserExists
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create SCIM user: %w", err)
+	}
+	return nil
+}
+
+// User reads a provisioned user
+func (r *PostgresRepository) User(ctx context.Context, orgID, userID string) (*User, error) {
+	if !store.IsUUID(orgID) || !store.IsUUID(userID) {
+		return nil, ErrUserNotFound
+	}
+
+	user, err := scanUser(r.pool.QueryRow(ctx, `
+		SELECT `+userColumns+` FROM scim_users s JOIN users u ON u.id = s.user_id
+		WHERE s.organization_id = $1 AND s.user_id = $2`, orgID, userID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get SCIM user: %w", err)
+	}
+	return user, nil
+}
+
+// userFilterColumns are the user attributes filters may compare
+var userFilterColumns = map[string]string{
+	"username":   "LOWER(s.user_name) = LOWER($2)",
+	"externalid": "s.external_id = $2",
+}
+
+// Users lists provisioned users
+func (r *PostgresRepository) Users(ctx context.Context, orgID string, filter Filter, offset, limit int) ([]*User, int, error) {
+	if !store.IsUUID(orgID) {
+		return []*User{}, 0, nil
+	}
+	where, args := "s.organization_id = $1", []interface{}{orgID}
+	if filter.Attribute != "" {
+		condition, ok := userFilterColumns[strings.ToLower(filter.Attribute)]
+		if !ok {
+			return []*User{}, 0, nil
+		}
+		where, args = where+" AND "+condition, append(args, filter.Value)
+	}
+
+	var total int
+	if err := r.pool.QueryRow(ctx, `SELECT COUNT(*) FROM scim_users s WHERE `+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count SCIM users: %w", err)
+	}
+
+	n := len(args)
+	rows, err := r.pool.Query(ctx, `
+		SELECT `+userColumns+` FROM scim_users s JOIN users u ON u.id = s.user_id
+		WHERE `+where+`
+		ORDER BY s.created_at, s.user_id
+		OFFSET $`+fmt.Sprint(n+1)+` LIMIT $`+fmt.Sprint(n+2), append(args, offset, limit)...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list SCIM users: %w", err)
+	}
+	defer rows.Close()
+
+	list := []*User{}
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan SCIM user: %w", err)
+		}
+		list = append(list, user)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("failed to list SCIM users: %w", err)
+	}
+	return list, total, nil
+}
+
+// UpdateUser saves a user's SCIM attributes
+func (r *PostgresRepository) UpdateUser(ctx context.Context, user *User) error {
+	if !store.IsUUID(user.OrganizationID) || !store.IsUUID(user.ID) {
+		return ErrUserNotFound
+	}
+
+	err := r.pool.QueryRow(ctx, `
+		UPDATE scim_users SET user_name = $3, external_id = NULLIF($4, ''), given_name = $5, family_name = $6,
+			active = $7, updated_at = NOW()
+		WHERE organization_id = $1 AND user_id = $2
+		RETURNING updated_at`,
+		user.OrganizationID, user.ID, user.UserName, user.ExternalID, user.GivenName, user.FamilyName, user.Active,
+	).Scan(&user.UpdatedAt)
+	if store.PgCode(err) == "23505" {
+		return ErrUserExists
+	}
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrUserNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update SCIM user: %w", err)
+	}
+	return nil
+}
+
+// SetManagedRole records the role the directory granted a user
+func (r *PostgresRepository) SetManagedRole(ctx context.Context, orgID, userID string, role orgs.Role) error {
+	_, err := r.pool.Exec(ctx, `
+		UPDATE scim_users SET managed_role = $3 WHERE organization_id = $1 AND user_id = $2`, orgID, userID, role)
+	if err != nil {
+		return fmt.Errorf("failed to set managed role: %w", err)
+	}
+	return nil
+}
+
+// DeleteUser removes a user from the directory and its groups
+func (r *PostgresRepository) DeleteUser(ctx context.Context, orgID, userID string) error {
+	if !store.IsUUID(orgID) || !store.IsUUID(userID) {
+		return ErrUserNotFound
+	}
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, `DELETE FROM scim_users WHERE organization_id = $1 AND user_id = $2`, orgID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete SCIM user: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrUserNotFound
+	}
+	_, err = tx.Exec(ctx, `
+		DELETE FROM scim_group_members m USING scim_groups g
+		WHERE m.group_id = g.id AND g.organization_id = $1 AND m.user_id = $2`, orgID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete SCIM group memberships: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to delete SCIM user: %w", err)
+	}
+	return nil
+}
+
+// groupColumns is the column list shared by group queries; they alias
+// scim_groups as g
+const groupColumns = `g.id::text, g.organization_id::text, g.display_name, COALESCE(g.external_id, ''),
+	COALESCE((SELECT array_agg(m.user_id::text ORDER BY m.user_id) FROM scim_group_members m WHERE m.group_id = g.id), '{}'),
+	g.created_at, g.updated_at`
+
+// CreateGroup inserts a group and its members
+func (r *PostgresRepository) CreateGroup(ctx context.Context, group *Group) error {
+	if !store.IsUUID(group.OrganizationID) {
+		return ErrDirectoryNotFound
+	}
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	err = tx.QueryRow(ctx, `
+		INSERT INTO scim_groups (organization_id, display_name, external_id)
+		VALUES ($1, $2, NULLIF($3, ''))
+		RETURNING id::text, created_at, updated_at`,
+		group.OrganizationID, group.DisplayName, group.ExternalID,
+	).Scan(&group.ID, &group.CreatedAt, &group.UpdatedAt)
+	if store.PgCode(err) == "23505" {
+		return ErrGroupExists
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create SCIM group: %w", err)
+	}
+	if group.Members, err = setMembers(ctx, tx, group); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to create SCIM group: %w", err)
+	}
+	return nil
+}
+
+// Group reads a group with its members
+func (r *PostgresRepository) Group(ctx context.Context, orgID, id string) (*Group, error) {
+	if !store.IsUUID(orgID) || !store.IsUUID(id) {
+		return nil, ErrGroupNotFound
+	}
+
+	group, err := scanGroup(r.pool.QueryRow(ctx, `
+		SELECT `+groupColumns+` FROM scim_groups g WHERE g.organization_id = $1 AND g.id = $2`, orgID, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrGroupNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get SCIM group: %w", err)
+	}
+	return group, nil
+}
+
+// groupFilterColumns are the group attributes filters may compare
+var groupFilterColumns = map[string]string{
+	"displayname": "LOWER(g.display_name) = LOWER($2)",
+	"externalid":  "g.external_id = $2",
+}
+
+// Groups lists groups
+func (r *PostgresRepository) Groups(ctx context.Context, orgID string, filter Filter, offset, limit int) ([]*Group, int, error) {
+	if !store.IsUUID(orgID) {
+		return []*Group{}, 0, nil
+	}
+	where, args := "g.organization_id = $1", []interface{}{orgID}
+	if filter.Attribute != "" {
+		condition, ok := groupFilterColumns[strings.ToLower(filter.Attribute)]
+		if !ok {
+			return []*Group{}, 0, nil
+		}
+		where, args = where+" AND "+condition, append(args, filter.Value)
+	}
+
+	var total int
+	if err := r.pool.QueryRow(ctx, `SELECT COUNT(*) FROM scim_groups g WHERE `+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count SCIM groups: %w", err)
+	}
+
+	n := len(args)
+	rows, err := r.pool.Query(ctx, `
+		SELECT `+groupColumns+` FROM scim_groups g
+		WHERE `+where+`
+		ORDER BY g.created_at, g.id
+		OFFSET $`+fmt.Sprint(n+1)+` LIMIT $`+fmt.Sprint(n+2), append(args, offset, limit)...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list SCIM groups: %w", err)
+	}
+	defer rows.Close()
+
+	list := []*Group{}
+	for rows.Next() {
+		group, err := scanGroup(rows)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan SCIM group: %w", err)
+		}
+		list = append(list, group)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("failed to list SCIM groups: %w", err)
+	}
+	return list, total, nil
+}
+
+// UpdateGroup saves a group's name and members
+func (r *PostgresRepository) UpdateGroup(ctx context.Context, group *Group) error {
+	if !store.IsUUID(group.OrganizationID) || !store.IsUUID(group.ID) {
+		return ErrGroupNotFound
+	}
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	err = tx.QueryRow(ctx, `
+		UPDATE scim_groups SET display_name = $3, external_id = NULLIF($4, ''), updated_at = NOW()
+		WHERE organization_id = $1 AND id = $2
+		RETURNING updated_at`,
+		group.OrganizationID, group.ID, group.DisplayName, group.ExternalID,
+	).Scan(&group.UpdatedAt)
+	if store.PgCode(err) == "23505" {
+		return ErrGroupExists
+	}
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrGroupNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update SCIM group: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM scim_group_members WHERE group_id = $1`, group.ID); err != nil {
+		return fmt.Errorf("failed to replace SCIM group members: %w", err)
+	}
+	if group.Members, err = setMembers(ctx, tx, group); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to update SCIM group: %w", err)
+	}
+	return nil
+}
+
+// setMembers adds a group's members the directory provisioned, returning them
+func setMembers(ctx context.Context, tx pgx.Tx, group *Group) ([]string, error) {
+	ids := make([]string, 0, len(group.Members))
+	for _, id := range group.Members {
+		if store.IsUUID(id) {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		return []string{}, nil
+	}
+
+	rows, err := tx.Query(ctx, `
+		INSERT INTO scim_group_members (group_id, user_id)
+		SELECT $1, s.user_id FROM scim_users s
+		WHERE s.organization_id = $2 AND s.user_id = ANY($3::uuid[])
+		ON CONFLICT DO NOTHING
+		RETURNING user_id::text`, group.ID, group.OrganizationID, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to add SCIM group members: %w", err)
+	}
+	defer rows.Close()
+
+	members := []string{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan SCIM group member: %w", err)
+		}
+		members = append(members, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to add SCIM group members: %w", err)
+	}
+	return members, nil
+}
+
+// DeleteGroup deletes a group and its memberships
+func (r *PostgresRepository) DeleteGroup(ctx context.Context, orgID, id string) error {
+	if !store.IsUUID(orgID) || !store.IsUUID(id) {
+		return ErrGroupNotFound
+	}
+	tag, err := r.pool.Exec(ctx, `DELETE FROM scim_groups WHERE organization_id = $1 AND id = $2`, orgID, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete SCIM group: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrGroupNotFound
+	}
+	return nil
+}
+
+// UserGroups lists a user's groups by name
+func (r *PostgresRepository) UserGroups(ctx context.Context, orgID, userID string) ([]GroupRef, error) {
+	if !store.IsUUID(orgID) || !store.IsUUID(userID) {
+		return []GroupRef{}, nil
+	}
+
+	rows, err := r.pool.Query(ctx, `
+		SELECT g.id::text, g.display_name FROM scim_groups g
+		JOIN scim_group_members m ON m.group_id = g.id
+		WHERE g.organization_id = $1 AND m.user_id = $2
+		ORDER BY g.display_name`, orgID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list user's SCIM groups: %w", err)
+	}
+	defer rows.Close()
+
+	groups := []GroupRef{}
+	for rows.Next() {
+		var g GroupRef
+		if err := rows.Scan(&g.ID, &g.DisplayName); err != nil {
+			return nil, fmt.Errorf("failed to scan SCIM group: %w", err)
+		}
+		groups = append(groups, g)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list user's SCIM groups: %w", err)
+	}
+	return groups, nil
+}
+
+// scanDirectory scans directoryColumns
+func scanDirectory(row pgx.Row) (*Directory, error) {
+	var dir Directory
+	var roles []byte
+	if err := row.Scan(&dir.OrganizationID, &dir.TenantID, &roles, &dir.CreatedAt, &dir.UpdatedAt); err != nil {
+		return nil, err
+	}
+	dir.GroupRoles = map[string]orgs.Role{}
+	if err := json.Unmarshal(roles, &dir.GroupRoles); err != nil {
+		return nil, fmt.Errorf("failed to decode group roles: %w", err)
+	}
+	return &dir, nil
+}
+
+// scanUser scans userColumns
+func scanUser(row pgx.Row) (*User, error) {
+	var u User
+	if err := row.Scan(&u.ID, &u.OrganizationID, &u.UserName, &u.ExternalID, &u.GivenName, &u.FamilyName,
+		&u.Active, &u.DisplayName, &u.Email, &u.ManagedRole, &u.CreatedAt, &u.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
+
+// scanGroup scans groupColumns
+func scanGroup(row pgx.Row) (*Group, error) {
+	var g Group
+	if err := row.Scan(&g.ID, &g.OrganizationID, &g.DisplayName, &g.ExternalID, &g.Members,
+		&g.CreatedAt, &g.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return &g, nil
+}
@@ -0,0 +1,405 @@
+package scim
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/tinle0301/streaming-platform-api/internal/audit"
+	"github.com/tinle0301/streaming-platform-api/internal/orgs"
+	"github.com/tinle0301/streaming-platform-api/internal/tenancy"
+	"github.com/tinle0301/streaming-platform-api/internal/users"
+)
+
+// tokenPrefix marks SCIM bearer tokens, so leaked ones are easy to spot
+const tokenPrefix = "scim_"
+
+// syncBatchSize is how many users a group role change re-syncs at a time
+const syncBatchSize = 200
+
+// Service errors
+var (
+	ErrInvalidToken     = errors.New("invalid SCIM token")
+	ErrInvalidGroupRole = errors.New("groups may only grant the ADMIN, MANAGER, or MODERATOR role")
+)
+
+// Service manages organizations' SCIM directories and applies what their
+// identity providers provision: accounts, deactivations, and the
+// organization roles group memberships grant
+type Service struct {
+	repo     Repository
+	accounts *users.Service
+	orgs     *orgs.Service
+	auditLog audit.Logger
+}
+
+// NewService creates a SCIM service; every change is written to auditLog
+func NewService(repo Repository, accounts *users.Service, organizations *orgs.Service, auditLog audit.Logger) *Service {
+	return &Service{repo: repo, accounts: accounts, orgs: organizations, auditLog: auditLog}
+}
+
+// Enable enables SCIM for an organization in the request's tenant, or
+// replaces its token, and returns the new token. Only the owner may; the
+// token is not stored and can't be shown again.
+func (s *Service) Enable(ctx context.Context, actorID, orgID string) (*Directory, string, error) {
+	if err := s.require(ctx, orgID, actorID, orgs.RoleOwner); err != nil {
+		return nil, "", err
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, "", fmt.Errorf("failed to generate SCIM token: %w", err)
+	}
+	token := tokenPrefix + hex.EncodeToString(secret)
+
+	dir := &Directory{OrganizationID: orgID, TenantID: tenancy.ID(ctx)}
+	if err := s.repo.SaveDirectory(ctx, dir, hashToken(token)); err != nil {
+		return nil, "", err
+	}
+
+	s.record(ctx, audit.ActionSCIMEnabled, actorID, orgID, "", nil)
+	log.Printf("SCIM enabled: org=%s, tenant=%s", orgID, dir.TenantID)
+	return dir, token, nil
+}
+
+// SetGroupRoles replaces which groups grant which organization roles and
+// re-syncs every provisioned user's role
+func (s *Service) SetGroupRoles(ctx context.Context, actorID, orgID string, roles map[string]orgs.Role) (*Directory, error) {
+	if err := s.require(ctx, orgID, actorID, orgs.RoleOwner); err != nil {
+		return nil, err
+	}
+	mapping := make(map[string]orgs.Role, len(roles))
+	for group, role := range roles {
+		group = strings.TrimSpace(group)
+		if group == "" || (role != orgs.RoleAdmin && role != orgs.RoleManager && role != orgs.RoleModerator) {
+			return nil, ErrInvalidGroupRole
+		}
+		mapping[group] = role
+	}
+
+	dir, err := s.repo.SetGroupRoles(ctx, orgID, mapping)
+	if err != nil {
+		return nil, err
+	}
+	metadata := make(map[string]string, len(mapping))
+	for group, role := range mapping {
+		metadata["group:"+group] = string(role)
+	}
+	s.record(ctx, audit.ActionSCIMGroupRoles, actorID, orgID, "", metadata)
+
+	ctx = tenancy.WithTenant(ctx, dir.TenantID)
+	for offset := 0; ; offset += syncBatchSize {
+		batch, _, err := s.repo.Users(ctx, orgID, Filter{}, offset, syncBatchSize)
+		if err != nil {
+			return nil, err
+		}
+		for _, user := range batch {
+			if err := s.syncRole(ctx, dir, user); err != nil {
+				return nil, err
+			}
+		}
+		if len(batch) < syncBatchSize {
+			return dir, nil
+		}
+	}
+}
+
+// Disable disables SCIM for an organization. Accounts it provisioned and
+// the roles it granted are kept, but it no longer manages them.
+func (s *Service) Disable(ctx context.Context, actorID, orgID string) error {
+	if err := s.require(ctx, orgID, actorID, orgs.RoleOwner); err != nil {
+		return err
+	}
+	if err := s.repo.DeleteDirectory(ctx, orgID); err != nil {
+		return err
+	}
+
+	s.record(ctx, audit.ActionSCIMDisabled, actorID, orgID, "", nil)
+	log.Printf("SCIM disabled: org=%s", orgID)
+	return nil
+}
+
+// Directory returns an organization's directory; only its owner and admins
+// may see it
+func (s *Service) Directory(ctx context.Context, actorID, orgID string) (*Directory, error) {
+	if err := s.require(ctx, orgID, actorID, orgs.RoleAdmin); err != nil {
+		return nil, err
+	}
+	return s.repo.Directory(ctx, orgID)
+}
+
+// Authenticate returns the directory a bearer token belongs to
+func (s *Service) Authenticate(ctx context.Context, token string) (*Directory, error) {
+	if !strings.HasPrefix(token, tokenPrefix) {
+		return nil, ErrInvalidToken
+	}
+	dir, err := s.repo.DirectoryByToken(ctx, hashToken(token))
+	if errors.Is(err, ErrDirectoryNotFound) {
+		return nil, ErrInvalidToken
+	}
+	return dir, err
+}
+
+// CreateUser provisions an account in the directory's tenant and links it
+// to the directory. The account's username is derived from userName.
+func (s *Service) CreateUser(ctx context.Context, dir *Directory, user *User) error {
+	email := user.Email
+	if email == "" && strings.Contains(user.UserName, "@") {
+		email = user.UserName
+	}
+	account, err := s.accounts.Provision(ctx, users.Registration{
+		Username:    users.SuggestUsername(user.UserName),
+		Email:       email,
+		DisplayName: user.DisplayName,
+	})
+	if err != nil {
+		return err
+	}
+	if !user.Active {
+		if err := s.accounts.SetDeactivated(ctx, account.ID, true); err != nil {
+			return err
+		}
+	}
+
+	user.ID, user.OrganizationID = account.ID, dir.OrganizationID
+	user.Email, user.DisplayName = account.Email, account.DisplayName
+	if err := s.repo.CreateUser(ctx, user); err != nil {
+		// The account can't be deleted once created, so leave it
+		// deactivated rather than usable but unmanaged
+		if deactivateErr := s.accounts.SetDeactivated(ctx, account.ID, true); deactivateErr != nil {
+			log.Printf("Error deactivating unlinked SCIM account: user=%s, err=%v", account.ID, deactivateErr)
+		}
+		return err
+	}
+
+	s.record(ctx, audit.ActionSCIMUserProvisioned, s.actor(dir), dir.OrganizationID, user.ID,
+		map[string]string{"user_name": user.UserName, "username": account.Username})
+	return nil
+}
+
+// User returns a provisioned user
+func (s *Service) User(ctx context.Context, dir *Directory, id string) (*User, error) {
+	return s.repo.User(ctx, dir.OrganizationID, id)
+}
+
+// Users lists provisioned users and counts every match
+func (s *Service) Users(ctx context.Context, dir *Directory, filter Filter, offset, limit int) ([]*User, int, error) {
+	return s.repo.Users(ctx, dir.OrganizationID, filter, offset, limit)
+}
+
+// UserGroups lists the groups a provisioned user belongs to
+func (s *Service) UserGroups(ctx context.Context, dir *Directory, id string) ([]GroupRef, error) {
+	return s.repo.UserGroups(ctx, dir.OrganizationID, id)
+}
+
+// ReplaceUser saves a provisioned user's attributes. Deactivating a user
+// deactivates their account and removes the role the directory granted.
+func (s *Service) ReplaceUser(ctx context.Context, dir *Directory, user *User) error {
+	existing, err := s.repo.User(ctx, dir.OrganizationID, user.ID)
+	if err != nil {
+		return err
+	}
+	if user.Email == "" {
+		user.Email = existing.Email
+	}
+
+	account, err := s.accounts.UpdateProfile(ctx, user.ID, user.Email, user.DisplayName)
+	if err != nil {
+		return err
+	}
+	if user.Active != existing.Active {
+		if err := s.accounts.SetDeactivated(ctx, user.ID, !user.Active); err != nil {
+			return err
+		}
+	}
+
+	user.OrganizationID, user.ManagedRole, user.CreatedAt = dir.OrganizationID, existing.ManagedRole, existing.CreatedAt
+	user.Email, user.DisplayName = account.Email, account.DisplayName
+	if err := s.repo.UpdateUser(ctx, user); err != nil {
+		return err
+	}
+
+	s.record(ctx, audit.ActionSCIMUserUpdated, s.actor(dir), dir.OrganizationID, user.ID,
+		map[string]string{"active": fmt.Sprint(user.Active)})
+	return s.syncRole(ctx, dir, user)
+}
+
+// DeleteUser deprovisions a user: their account is deactivated, the role
+// the directory granted is removed, and they are unlinked from the directory
+func (s *Service) DeleteUser(ctx context.Context, dir *Directory, id string) error {
+	user, err := s.repo.User(ctx, dir.OrganizationID, id)
+	if err != nil {
+		return err
+	}
+	if err := s.accounts.SetDeactivated(ctx, id, true); err != nil {
+		return err
+	}
+	if user.ManagedRole != "" {
+		if err := s.orgs.SyncMemberRole(ctx, dir.OrganizationID, id, ""); err != nil {
+			return err
+		}
+	}
+	if err := s.repo.DeleteUser(ctx, dir.OrganizationID, id); err != nil {
+		return err
+	}
+
+	s.record(ctx, audit.ActionSCIMUserDeprovisioned, s.actor(dir), dir.OrganizationID, id,
+		map[string]string{"user_name": user.UserName, "removed_role": string(user.ManagedRole)})
+	return nil
+}
+
+// CreateGroup creates a group and syncs its members' roles
+func (s *Service) CreateGroup(ctx context.Context, dir *Directory, group *Group) error {
+	group.OrganizationID = dir.OrganizationID
+	if err := s.repo.CreateGroup(ctx, group); err != nil {
+		return err
+	}
+
+	s.record(ctx, audit.ActionSCIMGroupCreated, s.actor(dir), dir.OrganizationID, group.ID,
+		map[string]string{"display_name": group.DisplayName, "members": fmt.Sprint(len(group.Members))})
+	return s.syncMembers(ctx, dir, group.Members)
+}
+
+// Group returns a group
+func (s *Service) Group(ctx context.Context, dir *Directory, id string) (*Group, error) {
+	return s.repo.Group(ctx, dir.OrganizationID, id)
+}
+
+// Groups lists groups and counts every match
+func (s *Service) Groups(ctx context.Context, dir *Directory, filter Filter, offset, limit int) ([]*Group, int, error) {
+	return s.repo.Groups(ctx, dir.OrganizationID, filter, offset, limit)
+}
+
+// ReplaceGroup saves a group's name and members and syncs the roles of
+// everyone who was or is a member
+func (s *Service) ReplaceGroup(ctx context.Context, dir *Directory, group *Group) error {
+	existing, err := s.repo.Group(ctx, dir.OrganizationID, group.ID)
+	if err != nil {
+		return err
+	}
+	group.OrganizationID, group.CreatedAt = dir.OrganizationID, existing.CreatedAt
+	if err := s.repo.UpdateGroup(ctx, group); err != nil {
+		return err
+	}
+
+	s.record(ctx, audit.ActionSCIMGroupUpdated, s.actor(dir), dir.OrganizationID, group.ID,
+		map[string]string{"display_name": group.DisplayName, "members": fmt.Sprint(len(group.Members))})
+	return s.syncMembers(ctx, dir, append(existing.Members, group.Members...))
+}
+
+// DeleteGroup deletes a group and syncs its former members' roles
+func (s *Service) DeleteGroup(ctx context.Context, dir *Directory, id string) error {
+	group, err := s.repo.Group(ctx, dir.OrganizationID, id)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.DeleteGroup(ctx, dir.OrganizationID, id); err != nil {
+		return err
+	}
+
+	s.record(ctx, audit.ActionSCIMGroupDeleted, s.actor(dir), dir.OrganizationID, id,
+		map[string]string{"display_name": group.DisplayName})
+	return s.syncMembers(ctx, dir, group.Members)
+}
+
+// syncMembers syncs the roles of the users in ids, once each
+func (s *Service) syncMembers(ctx context.Context, dir *Directory, ids []string) error {
+	seen := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		user, err := s.repo.User(ctx, dir.OrganizationID, id)
+		if errors.Is(err, ErrUserNotFound) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if err := s.syncRole(ctx, dir, user); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// syncRole grants a user the most privileged role their groups map to, or
+// removes the role the directory granted when they're inactive or no group
+// maps to one
+func (s *Service) syncRole(ctx context.Context, dir *Directory, user *User) error {
+	var desired orgs.Role
+	if user.Active {
+		groups, err := s.repo.UserGroups(ctx, dir.OrganizationID, user.ID)
+		if err != nil {
+			return err
+		}
+		roles := make([]orgs.Role, 0, len(groups))
+		for _, g := range groups {
+			roles = append(roles, dir.RoleFor(g.DisplayName))
+		}
+		desired = orgs.MostPrivileged(roles...)
+	}
+	if desired == user.ManagedRole {
+		return nil
+	}
+
+	if err := s.orgs.SyncMemberRole(ctx, dir.OrganizationID, user.ID, desired); err != nil {
+		return fmt.Errorf("failed to sync organization role: %w", err)
+	}
+	if err := s.repo.SetManagedRole(ctx, dir.OrganizationID, user.ID, desired); err != nil {
+		return err
+	}
+
+	s.record(ctx, audit.ActionSCIMRoleSynced, s.actor(dir), dir.OrganizationID, user.ID,
+		map[string]string{"from": string(user.ManagedRole), "to": string(desired)})
+	user.ManagedRole = desired
+	return nil
+}
+
+// require checks that actorID holds at least min in an organization
+func (s *Service) require(ctx context.Context, orgID, actorID string, min orgs.Role) error {
+	role, ok, err := s.orgs.Role(ctx, orgID, actorID)
+	if err != nil {
+		return err
+	}
+	if !ok || orgs.MostPrivileged(role, min) != role {
+		return orgs.ErrForbidden
+	}
+	return nil
+}
+
+// actor names a directory as the actor of the changes it makes
+func (s *Service) actor(dir *Directory) string {
+	return "scim:" + dir.OrganizationID
+}
+
+// record writes an audit entry for a change to an organization's directory
+func (s *Service) record(ctx context.Context, action, actorID, orgID, targetID string, metadata map[string]string) {
+	if s.auditLog == nil {
+		return
+	}
+
+	entry := audit.Entry{
+		Action:   action,
+		ActorID:  actorID,
+		TargetID: targetID,
+		Resource: "organization:" + orgID,
+		Metadata: metadata,
+	}
+	if err := s.auditLog.Record(ctx, entry); err != nil {
+		log.Printf("Error recording audit entry: %v", err)
+	}
+}
+
+// hashToken returns the stored form of a token
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,570 @@
+// Package seed generates believable fake content for local development:
+// channels that broadcast on weekly schedules, viewers who follow them,
+// past and live broadcasts with per-minute analytics, emote-heavy chat
+// history for live streams, and upcoming premieres. Generation is
+// deterministic for a given random seed, so demos and screenshots can be
+// reproduced.
+package seed
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/tinle0301/streaming-platform-api/internal/chathistory"
+	"github.com/tinle0301/streaming-platform-api/internal/highlights"
+	"github.com/tinle0301/streaming-platform-api/internal/store"
+	"github.com/tinle0301/streaming-platform-api/internal/tenancy"
+)
+
+// EmailDomain is the email domain of every seeded user, which is how
+// seeded data is found again to reset it
+const EmailDomain = "seed.streamhub.test"
+
+// Password is the password of every seeded user
+const Password = "streamhub"
+
+// Options are the volume knobs of a seed
+type Options struct {
+	// Channels is how many users stream; Live of them are live now
+	Channels int
+	Live     int
+
+	// Viewers is how many users only watch, each following about
+	// FollowsPerViewer channels, popular ones more often
+	Viewers          int
+	FollowsPerViewer int
+
+	// Days is how many days of past broadcasts, with analytics, each
+	// channel has
+	Days int
+
+	// ChatPerLiveStream is how many recent chat messages each live stream
+	// has in its chat history
+	ChatPerLiveStream int
+
+	// Premieres is how many channels have a premiere scheduled
+	Premieres int
+
+	// Seed makes the generated content reproducible
+	Seed int64
+
+	// Tenant owns the seeded users and streams
+	Tenant string
+}
+
+// DefaultOptions returns a small but lively platform
+func DefaultOptions() Options {
+	return Options{
+		Channels:          20,
+		Live:              5,
+		Viewers:           200,
+		FollowsPerViewer:  8,
+		Days:              14,
+		ChatPerLiveStream: 150,
+		Premieres:         3,
+		Seed:              1,
+		Tenant:            tenancy.Default,
+	}
+}
+
+// Validate checks the options describe a seed that can be generated
+func (o Options) Validate() error {
+	var errs []error
+	for name, n := range map[string]int{
+		"channels": o.Channels, "live": o.Live, "viewers": o.Viewers, "follows": o.FollowsPerViewer,
+		"days": o.Days, "chat": o.ChatPerLiveStream, "premieres": o.Premieres,
+	} {
+		if n < 0 {
+			errs = append(errs, fmt.Errorf("%s must not be negative", name))
+		}
+	}
+	if o.Live > o.Channels {
+		errs = append(errs, fmt.Errorf("live (%d) must not exceed channels (%d)", o.Live, o.Channels))
+	}
+	if o.Premieres > o.Channels {
+		errs = append(errs, fmt.Errorf("premieres (%d) must not exceed channels (%d)", o.Premieres, o.Channels))
+	}
+	if o.FollowsPerViewer > o.Channels {
+		errs = append(errs, fmt.Errorf("follows (%d) must not exceed channels (%d)", o.FollowsPerViewer, o.Channels))
+	}
+	if o.ChatPerLiveStream > chathistory.Capacity {
+		errs = append(errs, fmt.Errorf("chat (%d) must not exceed the chat history capacity (%d)", o.ChatPerLiveStream, chathistory.Capacity))
+	}
+	if o.Tenant == "" {
+		errs = append(errs, errors.New("tenant is required"))
+	}
+	return errors.Join(errs...)
+}
+
+// User is a seeded account
+type User struct {
+	ID          string
+	Username    string
+	DisplayName string
+	Bio         string
+	AvatarURL   string
+	BannerURL   string
+	IsPartner   bool
+	IsAffiliate bool
+	CreatedAt   time.Time
+}
+
+// Email is the user's seeded email address
+func (u *User) Email() string {
+	return strings.ToLower(u.Username) + "@" + EmailDomain
+}
+
+// Follow is a seeded follow
+type Follow struct {
+	FollowerID string
+	FollowedID string
+	CreatedAt  time.Time
+}
+
+// Schedule is when a channel usually goes live
+type Schedule struct {
+	ChannelID string
+	Weekdays  []time.Weekday
+
+	// Start is the time of day in UTC the channel goes live, and Length
+	// how long it usually streams
+	Start  time.Duration
+	Length time.Duration
+}
+
+// AnalyticsBucket is one minute of a stream's audience
+type AnalyticsBucket struct {
+	StreamID      string
+	BucketStart   time.Time
+	PeakViewers   int
+	ViewerTotal   int64
+	ViewerSamples int
+	Views         int
+	ChatMessages  int
+	NewFollowers  int
+}
+
+// Premiere is a highlight reel of a past broadcast scheduled to premiere
+type Premiere struct {
+	StreamID    string
+	StreamerID  string
+	Title       string
+	Segments    []highlights.Segment
+	ScheduledAt time.Time
+}
+
+// Dataset is everything a seed writes
+type Dataset struct {
+	Tenant    string
+	Channels  []*User
+	Viewers   []*User
+	Follows   []Follow
+	Schedules []Schedule
+	Streams   []*store.Stream
+	Analytics []AnalyticsBucket
+	Chat      []chathistory.Message
+	Premieres []Premiere
+}
+
+// viewerSamples is how many viewer count samples analytics takes a minute
+const viewerSamples = 4
+
+// generator draws seeded content
+type generator struct {
+	rng  *rand.Rand
+	now  time.Time
+	opts Options
+	used map[string]bool
+}
+
+// Generate builds a dataset for opts as of now
+func Generate(opts Options, now time.Time) (*Dataset, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	g := &generator{rng: rand.New(rand.NewSource(opts.Seed)), now: now.UTC().Truncate(time.Minute), opts: opts, used: map[string]bool{}}
+	data := &Dataset{Tenant: opts.Tenant}
+
+	for i := 0; i < opts.Channels; i++ {
+		data.Channels = append(data.Channels, g.user(true))
+	}
+	for i := 0; i < opts.Viewers; i++ {
+		data.Viewers = append(data.Viewers, g.user(false))
+	}
+
+	// Earlier channels are more popular: they get more follows, bigger
+	// audiences, and partner status
+	popularity := make([]float64, len(data.Channels))
+	for i := range data.Channels {
+		popularity[i] = 1 / math.Pow(float64(i+1), 0.9)
+		data.Channels[i].IsPartner = i < len(data.Channels)/5
+		data.Channels[i].IsAffiliate = !data.Channels[i].IsPartner && g.rng.Float64() < 0.6
+	}
+	followers := g.follows(data, popularity)
+
+	for i, channel := range data.Channels {
+		schedule := g.schedule(channel.ID)
+		data.Schedules = append(data.Schedules, schedule)
+		profile := g.profile()
+		audience := 5 + followers[channel.ID]/4 + int(200*popularity[i])
+
+		for _, start := range g.pastStarts(schedule) {
+			end := start.Add(schedule.Length + time.Duration(g.rng.Intn(61)-30)*time.Minute)
+			stream := g.stream(channel, profile, store.StreamStatusOffline, start, &end)
+			data.Streams = append(data.Streams, stream)
+			data.Analytics = append(data.Analytics, g.analytics(stream.ID, start, end, audience)...)
+		}
+
+		if i < opts.Live {
+			start := g.now.Add(-time.Duration(10+g.rng.Intn(170)) * time.Minute)
+			stream := g.stream(channel, profile, store.StreamStatusLive, start, nil)
+			buckets := g.analytics(stream.ID, start, g.now, audience)
+			if len(buckets) > 0 {
+				stream.ViewerCount = buckets[len(buckets)-1].PeakViewers
+			}
+			data.Streams = append(data.Streams, stream)
+			data.Analytics = append(data.Analytics, buckets...)
+			data.Chat = append(data.Chat, g.chat(stream, data.Viewers)...)
+		}
+	}
+
+	data.Premieres = g.premieres(data)
+	return data, nil
+}
+
+// user creates a channel or viewer account with a unique username
+func (g *generator) user(channel bool) *User {
+	adjective := pick(g.rng, adjectives)
+	noun := pick(g.rng, nouns)
+	username := adjective + "_" + noun
+	if g.rng.Intn(2) == 0 {
+		username += fmt.Sprint(g.rng.Intn(100))
+	}
+	if g.used[username] {
+		// No random suffix has a second underscore, and the count only grows
+		username = adjective + "_" + noun + "_" + fmt.Sprint(len(g.used))
+	}
+	g.used[username] = true
+	display := capitalize(adjective) + capitalize(noun)
+
+	user := &User{
+		ID:          newUUID(g.rng),
+		Username:    username,
+		DisplayName: display,
+		AvatarURL:   "https://api.dicebear.com/7.x/thumbs/svg?seed=" + username,
+		CreatedAt:   g.now.AddDate(0, 0, -g.opts.Days-30-g.rng.Intn(700)),
+	}
+	if channel {
+		user.Bio = pick(g.rng, bios)
+		user.BannerURL = "https://picsum.photos/seed/" + username + "/1200/300"
+	}
+	return user
+}
+
+// follows has each viewer follow channels, favouring popular ones, and
+// returns each channel's follower count
+func (g *generator) follows(data *Dataset, popularity []float64) map[string]int {
+	counts := make(map[string]int, len(data.Channels))
+	var total float64
+	for _, p := range popularity {
+		total += p
+	}
+
+	for _, viewer := range data.Viewers {
+		n := g.opts.FollowsPerViewer
+		if n > 0 {
+			n = 1 + g.rng.Intn(2*n)
+		}
+		if n > len(data.Channels) {
+			n = len(data.Channels)
+		}
+		followed := map[int]bool{}
+		for len(followed) < n {
+			// Weighted draw without replacement
+			target := g.rng.Float64() * total
+			i := 0
+			for ; i < len(popularity)-1 && target > popularity[i]; i++ {
+				target -= popularity[i]
+			}
+			if followed[i] {
+				continue
+			}
+			followed[i] = true
+			channel := data.Channels[i]
+			data.Follows = append(data.Follows, Follow{
+				FollowerID: viewer.ID,
+				FollowedID: channel.ID,
+				CreatedAt:  g.between(maxTime(viewer.CreatedAt, channel.CreatedAt), g.now),
+			})
+			counts[channel.ID]++
+		}
+	}
+	return counts
+}
+
+// schedule picks the weekdays and time a channel streams
+func (g *generator) schedule(channelID string) Schedule {
+	days := g.rng.Perm(7)[:2+g.rng.Intn(4)]
+	sort.Ints(days)
+	schedule := Schedule{
+		ChannelID: channelID,
+		Start:     time.Duration(12+g.rng.Intn(11))*time.Hour + time.Duration(g.rng.Intn(2)*30)*time.Minute,
+		Length:    time.Duration(90+g.rng.Intn(5)*30) * time.Minute,
+	}
+	for _, day := range days {
+		schedule.Weekdays = append(schedule.Weekdays, time.Weekday(day))
+	}
+	return schedule
+}
+
+// pastStarts returns when a channel went live over the past days, on its
+// schedule, give or take a few minutes and the odd missed day
+func (g *generator) pastStarts(schedule Schedule) []time.Time {
+	var starts []time.Time
+	today := g.now.Truncate(24 * time.Hour)
+	for day := g.opts.Days; day >= 1; day-- {
+		date := today.AddDate(0, 0, -day)
+		if !containsWeekday(schedule.Weekdays, date.Weekday()) || g.rng.Float64() < 0.1 {
+			continue
+		}
+		start := date.Add(schedule.Start + time.Duration(g.rng.Intn(21)-5)*time.Minute)
+		if start.Add(schedule.Length).Before(g.now) {
+			starts = append(starts, start)
+		}
+	}
+	return starts
+}
+
+// profile is what a channel usually streams
+type profile struct {
+	category string
+	language string
+	tags     []string
+	mature   bool
+}
+
+// profile picks a channel's category, language, and tags
+func (g *generator) profile() profile {
+	category := pick(g.rng, categories)
+	p := profile{
+		category: category.slug,
+		language: pick(g.rng, languages),
+		mature:   category.mature && g.rng.Float64() < 0.5,
+	}
+	p.tags = append(p.tags, category.tags...)
+	if p.language == "en" {
+		p.tags = append(p.tags, "english")
+	}
+	return p
+}
+
+// stream creates a broadcast of a channel
+func (g *generator) stream(channel *User, p profile, status string, start time.Time, end *time.Time) *store.Stream {
+	category := categoryBySlug(p.category)
+	startedAt := start
+	updatedAt := start
+	if end != nil {
+		updatedAt = *end
+	}
+	return &store.Stream{
+		ID:           newUUID(g.rng),
+		StreamerID:   channel.ID,
+		Title:        fmt.Sprintf(pick(g.rng, category.titles), pick(g.rng, category.subjects)),
+		Description:  channel.Bio,
+		Status:       status,
+		Category:     p.category,
+		Language:     p.language,
+		Tags:         p.tags,
+		IsMature:     p.mature,
+		ChatEnabled:  true,
+		ThumbnailURL: "https://picsum.photos/seed/" + channel.Username + start.Format("20060102") + "/640/360",
+		StartedAt:    &startedAt,
+		EndedAt:      end,
+		CreatedAt:    start,
+		UpdatedAt:    updatedAt,
+		TenantID:     g.opts.Tenant,
+	}
+}
+
+// analytics builds a broadcast's per-minute audience: viewers arrive over
+// the first half hour, hold with some noise, and drift off at the end
+func (g *generator) analytics(streamID string, start, end time.Time, audience int) []AnalyticsBucket {
+	var buckets []AnalyticsBucket
+	length := end.Sub(start).Minutes()
+	if length <= 0 {
+		return nil
+	}
+	chatRate := 0.05 + g.rng.Float64()*0.25
+	previous := 0.0
+	for minute := 0.0; minute < length; minute++ {
+		shape := math.Min(1, (minute+1)/30)
+		if remaining := length - minute; remaining < 15 {
+			shape *= 0.6 + 0.4*remaining/15
+		}
+		average := float64(audience) * shape * (0.85 + 0.3*g.rng.Float64())
+		peak := int(math.Ceil(average * (1 + 0.1*g.rng.Float64())))
+		arrivals := math.Max(0, average-previous*0.9)
+		previous = average
+
+		buckets = append(buckets, AnalyticsBucket{
+			StreamID:      streamID,
+			BucketStart:   start.Truncate(time.Minute).Add(time.Duration(minute) * time.Minute),
+			PeakViewers:   peak,
+			ViewerTotal:   int64(math.Round(average * viewerSamples)),
+			ViewerSamples: viewerSamples,
+			Views:         int(math.Round(arrivals)),
+			ChatMessages:  int(math.Round(average * chatRate * (0.5 + g.rng.Float64()))),
+			NewFollowers:  g.rng.Intn(1 + int(average)/80),
+		})
+	}
+	return buckets
+}
+
+// chat builds a live stream's recent chat from its viewers, heavy on emotes
+func (g *generator) chat(stream *store.Stream, viewers []*User) []chathistory.Message {
+	if len(viewers) == 0 {
+		return nil
+	}
+	// Regulars do most of the talking
+	regulars := viewers
+	if len(regulars) > 25 {
+		regulars = regulars[:25]
+	}
+
+	messages := make([]chathistory.Message, 0, g.opts.ChatPerLiveStream)
+	sentAt := g.now.Add(-time.Duration(g.opts.ChatPerLiveStream) * 4 * time.Second)
+	if sentAt.Before(*stream.StartedAt) {
+		sentAt = *stream.StartedAt
+	}
+	for i := 0; i < g.opts.ChatPerLiveStream; i++ {
+		sentAt = sentAt.Add(time.Duration(500+g.rng.Intn(6000)) * time.Millisecond)
+		if sentAt.After(g.now) {
+			sentAt = g.now
+		}
+		sender := pick(g.rng, regulars)
+		if g.rng.Float64() < 0.3 {
+			sender = pick(g.rng, viewers)
+		}
+
+		message := chathistory.Message{
+			ID:     newUUID(g.rng),
+			Room:   stream.ID,
+			UserID: sender.ID,
+			Text:   g.chatLine(),
+			SentAt: sentAt,
+		}
+		switch roll := g.rng.Float64(); {
+		case roll < 0.03:
+			message.UserID = stream.StreamerID
+			message.Moderator = true
+			message.Text = pick(g.rng, streamerLines)
+		case roll < 0.25:
+			message.SubscriberTier = pick(g.rng, []string{"1000", "1000", "1000", "2000", "3000"})
+		}
+		messages = append(messages, message)
+	}
+	return messages
+}
+
+// chatLine draws a chat message: a phrase, an emote wall, or both
+func (g *generator) chatLine() string {
+	emote := pick(g.rng, emotes)
+	switch roll := g.rng.Float64(); {
+	case roll < 0.2:
+		return strings.TrimSpace(strings.Repeat(emote+" ", 3+g.rng.Intn(4)))
+	case roll < 0.5:
+		return pick(g.rng, chatLines) + " " + emote
+	default:
+		return pick(g.rng, chatLines)
+	}
+}
+
+// premieres schedules highlight reels of the latest broadcasts of the
+// first channels over the coming week
+func (g *generator) premieres(data *Dataset) []Premiere {
+	var premieres []Premiere
+	for _, channel := range data.Channels {
+		if len(premieres) == g.opts.Premieres {
+			break
+		}
+		var latest *store.Stream
+		for _, stream := range data.Streams {
+			if stream.StreamerID == channel.ID && stream.Status == store.StreamStatusOffline {
+				latest = stream
+			}
+		}
+		if latest == nil {
+			continue
+		}
+
+		// Three moments, in broadcast order
+		var segments []highlights.Segment
+		length := latest.EndedAt.Sub(*latest.StartedAt)
+		for i := 0; i < 3; i++ {
+			start := time.Duration(i)*length/3 + time.Duration(g.rng.Int63n(int64(length/3-2*time.Minute))).Truncate(time.Second)
+			segments = append(segments, highlights.Segment{
+				Start:   start,
+				End:     start + time.Duration(30+g.rng.Intn(90))*time.Second,
+				Reasons: []string{"chat_spike"},
+			})
+		}
+		premieres = append(premieres, Premiere{
+			StreamID:    latest.ID,
+			StreamerID:  channel.ID,
+			Title:       "Best of: " + latest.Title,
+			Segments:    segments,
+			ScheduledAt: g.now.Add(time.Duration(1+g.rng.Intn(7*24)) * time.Hour),
+		})
+	}
+	return premieres
+}
+
+// between returns a random time in [from, to)
+func (g *generator) between(from, to time.Time) time.Time {
+	if !to.After(from) {
+		return from
+	}
+	return from.Add(time.Duration(g.rng.Int63n(int64(to.Sub(from))))).Truncate(time.Second)
+}
+
+// newUUID returns a random version 4 UUID drawn from rng
+func newUUID(rng *rand.Rand) string {
+	var b [16]byte
+	rng.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// pick returns a random element of items
+func pick[T any](rng *rand.Rand, items []T) T {
+	return items[rng.Intn(len(items))]
+}
+
+// capitalize upper-cases the first letter of an ASCII word
+func capitalize(word string) string {
+	return strings.ToUpper(word[:1]) + word[1:]
+}
+
+// maxTime returns the later of a and b
+func maxTime(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
+
+// containsWeekday reports whether days includes day
+func containsWeekday(days []time.Weekday, day time.Weekday) bool {
+	for _, d := range days {
+		if d == day {
+			return true
+		}
+	}
+	return false
+}
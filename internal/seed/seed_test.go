@@ -0,0 +1,182 @@
+package seed
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tinle0301/streaming-platform-api/internal/chatactivity"
+	"github.com/tinle0301/streaming-platform-api/internal/store"
+)
+
+var seedTime = time.Date(2024, 6, 1, 18, 30, 0, 0, time.UTC)
+
+func TestGenerateIsDeterministic(t *testing.T) {
+	a, err := Generate(DefaultOptions(), seedTime)
+	if err != nil {
+		t.Fatalf("Generate() = %v", err)
+	}
+	b, _ := Generate(DefaultOptions(), seedTime)
+	if !reflect.DeepEqual(a, b) {
+		t.Fatal("the same seed generated different datasets")
+	}
+
+	opts := DefaultOptions()
+	opts.Seed = 2
+	c, _ := Generate(opts, seedTime)
+	if c.Channels[0].Username == a.Channels[0].Username && c.Channels[1].Username == a.Channels[1].Username {
+		t.Error("different seeds generated the same channels")
+	}
+}
+
+func TestGenerateHonoursVolumes(t *testing.T) {
+	opts := DefaultOptions()
+	data, err := Generate(opts, seedTime)
+	if err != nil {
+		t.Fatalf("Generate() = %v", err)
+	}
+
+	if len(data.Channels) != opts.Channels || len(data.Viewers) != opts.Viewers {
+		t.Errorf("got %d channels and %d viewers, want %d and %d", len(data.Channels), len(data.Viewers), opts.Channels, opts.Viewers)
+	}
+	if len(data.Premieres) != opts.Premieres {
+		t.Errorf("got %d premieres, want %d", len(data.Premieres), opts.Premieres)
+	}
+
+	live := 0
+	for _, stream := range data.Streams {
+		if stream.Status == store.StreamStatusLive {
+			live++
+			if stream.EndedAt != nil || stream.ViewerCount == 0 {
+				t.Errorf("live stream %s: ended_at=%v viewers=%d", stream.ID, stream.EndedAt, stream.ViewerCount)
+			}
+			continue
+		}
+		if stream.EndedAt == nil || !stream.EndedAt.After(*stream.StartedAt) || stream.EndedAt.After(seedTime) {
+			t.Errorf("past stream %s runs %v to %v", stream.ID, stream.StartedAt, stream.EndedAt)
+		}
+		if stream.StartedAt.Before(seedTime.AddDate(0, 0, -opts.Days-1)) {
+			t.Errorf("past stream %s started %v, more than %d days ago", stream.ID, stream.StartedAt, opts.Days)
+		}
+	}
+	if live != opts.Live {
+		t.Errorf("got %d live streams, want %d", live, opts.Live)
+	}
+	if len(data.Chat) != opts.Live*opts.ChatPerLiveStream {
+		t.Errorf("got %d chat messages, want %d", len(data.Chat), opts.Live*opts.ChatPerLiveStream)
+	}
+}
+
+func TestGenerateIsConsistent(t *testing.T) {
+	data, err := Generate(DefaultOptions(), seedTime)
+	if err != nil {
+		t.Fatalf("Generate() = %v", err)
+	}
+
+	users := map[string]*User{}
+	usernames := map[string]bool{}
+	for _, user := range append(append([]*User{}, data.Channels...), data.Viewers...) {
+		if !store.IsUUID(user.ID) || users[user.ID] != nil {
+			t.Fatalf("user ID %q is invalid or repeated", user.ID)
+		}
+		if usernames[strings.ToLower(user.Username)] {
+			t.Fatalf("username %q is repeated", user.Username)
+		}
+		users[user.ID] = user
+		usernames[strings.ToLower(user.Username)] = true
+	}
+
+	follows := map[[2]string]bool{}
+	for _, follow := range data.Follows {
+		key := [2]string{follow.FollowerID, follow.FollowedID}
+		if follow.FollowerID == follow.FollowedID || follows[key] {
+			t.Fatalf("follow %v is a self-follow or repeated", key)
+		}
+		if users[follow.FollowerID] == nil || users[follow.FollowedID] == nil {
+			t.Fatalf("follow %v references an unknown user", key)
+		}
+		if follow.CreatedAt.Before(users[follow.FollowerID].CreatedAt) || follow.CreatedAt.After(seedTime) {
+			t.Errorf("follow %v created at %v, outside the follower's lifetime", key, follow.CreatedAt)
+		}
+		follows[key] = true
+	}
+
+	streams := map[string]*store.Stream{}
+	for _, stream := range data.Streams {
+		if users[stream.StreamerID] == nil {
+			t.Fatalf("stream %s belongs to an unknown user", stream.ID)
+		}
+		streams[stream.ID] = stream
+	}
+	buckets := map[string]bool{}
+	for _, bucket := range data.Analytics {
+		stream := streams[bucket.StreamID]
+		key := bucket.StreamID + bucket.BucketStart.String()
+		if stream == nil || buckets[key] {
+			t.Fatalf("analytics bucket %s is orphaned or repeated", key)
+		}
+		if bucket.BucketStart.Before(stream.StartedAt.Truncate(time.Minute)) || bucket.PeakViewers*bucket.ViewerSamples < int(bucket.ViewerTotal) {
+			t.Errorf("analytics bucket %s = %+v is inconsistent with its stream", key, bucket)
+		}
+		buckets[key] = true
+	}
+
+	emotes := 0
+	for _, message := range data.Chat {
+		stream := streams[message.Room]
+		if stream == nil || stream.Status != store.StreamStatusLive || users[message.UserID] == nil {
+			t.Fatalf("chat message %s is in an unknown room or from an unknown user", message.ID)
+		}
+		if message.SentAt.Before(*stream.StartedAt) || message.SentAt.After(seedTime) {
+			t.Errorf("chat message %s sent at %v, outside its stream", message.ID, message.SentAt)
+		}
+		if chatactivity.IsEmoteMessage(message.Text) {
+			emotes++
+		}
+	}
+	if emotes == 0 {
+		t.Error("chat has no emote messages")
+	}
+
+	for _, premiere := range data.Premieres {
+		stream := streams[premiere.StreamID]
+		if stream == nil || stream.Status != store.StreamStatusOffline || !premiere.ScheduledAt.After(seedTime) {
+			t.Fatalf("premiere %+v is not of a past stream or not upcoming", premiere)
+		}
+		length := stream.EndedAt.Sub(*stream.StartedAt)
+		for _, segment := range premiere.Segments {
+			if segment.Start < 0 || segment.End <= segment.Start || segment.End > length {
+				t.Errorf("premiere segment %+v is outside its %v stream", segment, length)
+			}
+		}
+	}
+}
+
+func TestOptionsValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(*Options)
+	}{
+		{"negative viewers", func(o *Options) { o.Viewers = -1 }},
+		{"more live than channels", func(o *Options) { o.Live = o.Channels + 1 }},
+		{"more follows than channels", func(o *Options) { o.FollowsPerViewer = o.Channels + 1 }},
+		{"chat beyond history", func(o *Options) { o.ChatPerLiveStream = 10000 }},
+		{"no tenant", func(o *Options) { o.Tenant = "" }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := DefaultOptions()
+			tt.modify(&opts)
+			if err := opts.Validate(); err == nil {
+				t.Error("Validate() = nil, want an error")
+			}
+		})
+	}
+
+	empty := DefaultOptions()
+	empty.Channels, empty.Live, empty.Viewers, empty.FollowsPerViewer, empty.Premieres = 0, 0, 0, 0, 0
+	if data, err := Generate(empty, seedTime); err != nil || len(data.Streams) != 0 {
+		t.Errorf("Generate(empty) = %d streams, %v", len(data.Streams), err)
+	}
+}
@@ -0,0 +1,122 @@
+package seed
+
+// category is a kind of content channels stream, with the managed tags and
+// titles that fit it. Titles take one subject.
+type category struct {
+	slug     string
+	tags     []string
+	mature   bool
+	titles   []string
+	subjects []string
+}
+
+var categories = []category{
+	{
+		slug: "just-chatting",
+		tags: []string{"irl", "casual"},
+		titles: []string{
+			"%s and chill",
+			"Morning coffee: %s",
+			"Q&A — ask me about %s",
+			"Catching up on %s",
+		},
+		subjects: []string{"life updates", "your questions", "the weekend", "reacting to clips", "the news"},
+	},
+	{
+		slug:   "speedrunning",
+		tags:   []string{"speedrun", "challenge"},
+		titles: []string{"%s any%% attempts", "PB hunting in %s", "Learning new routes in %s", "%s glitchless, day 12"},
+		subjects: []string{
+			"Celeste", "Super Metroid", "Hollow Knight", "Ocarina of Time", "Portal 2",
+		},
+	},
+	{
+		slug:     "fps",
+		tags:     []string{"competitive"},
+		mature:   true,
+		titles:   []string{"Ranked grind: %s", "%s scrims with the team", "Road to top 500 in %s", "%s tournament qualifiers"},
+		subjects: []string{"Valorant", "Counter-Strike 2", "Apex Legends", "Overwatch 2"},
+	},
+	{
+		slug:     "rpg",
+		tags:     []string{"first-playthrough"},
+		titles:   []string{"Blind playthrough of %s", "%s, part 7", "Finally finishing %s", "Side quests only in %s"},
+		subjects: []string{"Elden Ring", "Baldur's Gate 3", "Final Fantasy XVI", "Persona 5", "The Witcher 3"},
+	},
+	{
+		slug:     "art",
+		tags:     []string{"creative"},
+		titles:   []string{"Painting %s", "Sketchbook session: %s", "Commission work — %s", "Learning to draw %s"},
+		subjects: []string{"landscapes", "character portraits", "a dragon", "pixel art tiles", "city skylines"},
+	},
+	{
+		slug:     "music",
+		tags:     []string{"creative"},
+		titles:   []string{"Making %s live", "Chill %s all evening", "Requests open: %s", "Writing %s from scratch"},
+		subjects: []string{"lo-fi beats", "synthwave", "acoustic covers", "a game soundtrack", "jazz piano"},
+	},
+	{
+		slug:     "software-development",
+		tags:     []string{"educational"},
+		titles:   []string{"Building %s", "Refactoring %s", "Debugging %s with chat", "%s from zero"},
+		subjects: []string{"a Go web server", "a game engine", "a Discord bot", "my portfolio site", "a compiler"},
+	},
+	{
+		slug:     "strategy",
+		tags:     []string{"co-op", "casual"},
+		titles:   []string{"%s with viewers", "Late game %s", "Teaching %s to chat", "%s marathon"},
+		subjects: []string{"Civilization VI", "Stardew Valley", "Factorio", "Age of Empires IV", "Into the Breach"},
+	},
+}
+
+// categoryBySlug returns the category with slug
+func categoryBySlug(slug string) category {
+	for _, c := range categories {
+		if c.slug == slug {
+			return c
+		}
+	}
+	return categories[0]
+}
+
+var languages = []string{"en", "en", "en", "en", "es", "pt", "de", "fr", "ja"}
+
+var adjectives = []string{
+	"cozy", "pixel", "turbo", "sleepy", "cosmic", "lucky", "frosty", "neon", "quiet", "rapid",
+	"salty", "golden", "wild", "tiny", "mighty", "clever", "spicy", "misty", "brave", "lazy",
+}
+
+var nouns = []string{
+	"otter", "panda", "falcon", "wizard", "gecko", "knight", "comet", "badger", "raven", "noodle",
+	"bard", "fox", "pilot", "golem", "yeti", "moth", "ranger", "waffle", "cactus", "dragon",
+}
+
+var bios = []string{
+	"Variety streamer. Bad at games, good at vibes.",
+	"Streaming most evenings. Be kind in chat!",
+	"Speedrunner, coffee drinker, occasional musician.",
+	"Here to learn in public. Questions always welcome.",
+	"Full-time artist, part-time gremlin.",
+	"Competitive player and coach. VOD reviews on weekends.",
+	"Building things on stream since 2019.",
+	"Cozy games and cozier chat.",
+}
+
+// emotes are chat shortcodes, rendered by clients and counted as emote
+// bursts by chat activity
+var emotes = []string{":pog:", ":lul:", ":kekw:", ":hype:", ":gg:", ":heart:", ":clap:", ":sadge:", ":monkas:", ":wave:"}
+
+var chatLines = []string{
+	"hi chat", "hello from Brazil", "let's gooo", "no way", "that was clean", "gg",
+	"first time here, love the stream", "what's the song?", "how long have you been live?",
+	"chat is moving fast today", "W", "LMAO", "so close", "you got this", "huge",
+	"can you explain that again?", "back from dinner, what did I miss?", "this part is so hard",
+	"clip it", "o7",
+}
+
+var streamerLines = []string{
+	"thanks for hanging out everyone",
+	"welcome in new folks!",
+	"going to take a quick break after this",
+	"appreciate the follows :heart:",
+}
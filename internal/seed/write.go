@@ -0,0 +1,200 @@
+package seed
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/redis/go-redis/v9"
+	"github.com/tinle0301/streaming-platform-api/internal/chathistory"
+	"github.com/tinle0301/streaming-platform-api/internal/highlights"
+	"github.com/tinle0301/streaming-platform-api/internal/premieres"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// ErrAlreadySeeded is returned when writing over an earlier seed
+var ErrAlreadySeeded = errors.New("database already has seeded data; reset it first")
+
+// seededUsers matches the users a seed created
+const seededUsers = `email LIKE '%@` + EmailDomain + `'`
+
+// Summary counts what a seed wrote
+type Summary struct {
+	Channels         int `json:"channels"`
+	Viewers          int `json:"viewers"`
+	Follows          int `json:"follows"`
+	Streams          int `json:"streams"`
+	LiveStreams      int `json:"live_streams"`
+	AnalyticsBuckets int `json:"analytics_buckets"`
+	ChatMessages     int `json:"chat_messages"`
+	Premieres        int `json:"premieres"`
+}
+
+// Write stores a dataset: users, follows, streams, and analytics in one
+// transaction, then the premieres and the live streams' chat history
+func Write(ctx context.Context, pool *pgxpool.Pool, redisClient *redis.Client, data *Dataset) (*Summary, error) {
+	var seeded bool
+	if err := pool.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM users WHERE `+seededUsers+`)`).Scan(&seeded); err != nil {
+		return nil, fmt.Errorf("failed to check for seeded data: %w", err)
+	}
+	if seeded {
+		return nil, ErrAlreadySeeded
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(Password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash seed password: %w", err)
+	}
+
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	users := make([][]interface{}, 0, len(data.Channels)+len(data.Viewers))
+	for _, user := range append(append([]*User{}, data.Channels...), data.Viewers...) {
+		users = append(users, []interface{}{
+			user.ID, user.Username, user.Email(), string(hash), user.DisplayName, nullString(user.Bio),
+			user.AvatarURL, nullString(user.BannerURL), user.IsPartner, user.IsAffiliate, data.Tenant,
+			user.CreatedAt, user.CreatedAt,
+		})
+	}
+	if err := copyRows(ctx, tx, "users", []string{
+		"id", "username", "email", "password_hash", "display_name", "bio",
+		"avatar_url", "banner_url", "is_partner", "is_affiliate", "tenant_id",
+		"created_at", "updated_at",
+	}, users); err != nil {
+		return nil, err
+	}
+
+	follows := make([][]interface{}, 0, len(data.Follows))
+	for _, follow := range data.Follows {
+		follows = append(follows, []interface{}{follow.FollowerID, follow.FollowedID, follow.CreatedAt})
+	}
+	if err := copyRows(ctx, tx, "follows", []string{"follower_id", "followed_id", "created_at"}, follows); err != nil {
+		return nil, err
+	}
+
+	summary := &Summary{
+		Channels:         len(data.Channels),
+		Viewers:          len(data.Viewers),
+		Follows:          len(data.Follows),
+		Streams:          len(data.Streams),
+		AnalyticsBuckets: len(data.Analytics),
+	}
+	streams := make([][]interface{}, 0, len(data.Streams))
+	for _, stream := range data.Streams {
+		if stream.EndedAt == nil {
+			summary.LiveStreams++
+		}
+		streams = append(streams, []interface{}{
+			stream.ID, stream.StreamerID, stream.Title, nullString(stream.Description), stream.Status,
+			stream.Category, stream.Language, stream.Tags, stream.IsMature, stream.ChatEnabled,
+			stream.ViewerCount, stream.ThumbnailURL, stream.StartedAt, stream.EndedAt,
+			stream.CreatedAt, stream.UpdatedAt, stream.TenantID,
+		})
+	}
+	if err := copyRows(ctx, tx, "streams", []string{
+		"id", "streamer_id", "title", "description", "status",
+		"category", "language", "tags", "is_mature", "chat_enabled",
+		"viewer_count", "thumbnail_url", "started_at", "ended_at",
+		"created_at", "updated_at", "tenant_id",
+	}, streams); err != nil {
+		return nil, err
+	}
+
+	analytics := make([][]interface{}, 0, len(data.Analytics))
+	for _, bucket := range data.Analytics {
+		analytics = append(analytics, []interface{}{
+			bucket.StreamID, bucket.BucketStart, bucket.PeakViewers, bucket.ViewerTotal,
+			bucket.ViewerSamples, bucket.Views, bucket.ChatMessages, bucket.NewFollowers,
+		})
+	}
+	if err := copyRows(ctx, tx, "stream_analytics", []string{
+		"stream_id", "bucket_start", "peak_viewers", "viewer_total",
+		"viewer_samples", "views", "chat_messages", "new_followers",
+	}, analytics); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit seed: %w", err)
+	}
+
+	// Premieres go through the repositories so their VODs follow the
+	// usual draft to premiere lifecycle
+	vods := highlights.NewPostgresRepository(pool)
+	scheduled := premieres.NewPostgresRepository(pool)
+	for _, premiere := range data.Premieres {
+		vod := &highlights.VOD{
+			StreamID:   premiere.StreamID,
+			StreamerID: premiere.StreamerID,
+			Title:      premiere.Title,
+			Segments:   premiere.Segments,
+		}
+		if err := vods.SaveHighlight(ctx, vod); err != nil {
+			return summary, fmt.Errorf("failed to seed highlight: %w", err)
+		}
+		if err := scheduled.Create(ctx, &premieres.Premiere{VODID: vod.ID, ScheduledAt: premiere.ScheduledAt}); err != nil {
+			return summary, fmt.Errorf("failed to seed premiere: %w", err)
+		}
+		summary.Premieres++
+	}
+
+	if redisClient != nil && len(data.Chat) > 0 {
+		recorder := chathistory.NewRecorder(redisClient)
+		for _, message := range data.Chat {
+			recorder.Append(message.Room, message.ID, message.UserID, message.Text, message.Moderator, message.SubscriberTier, message.SentAt)
+		}
+		// A cancelled recorder flushes once and returns
+		flushCtx, cancel := context.WithCancel(ctx)
+		cancel()
+		recorder.Run(flushCtx)
+		summary.ChatMessages = len(data.Chat)
+	}
+	return summary, nil
+}
+
+// Reset deletes seeded users and their streams, along with everything that
+// cascades from them. Chat history expires on its own.
+func Reset(ctx context.Context, pool *pgxpool.Pool) (int64, error) {
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `DELETE FROM streams WHERE streamer_id IN (SELECT id::text FROM users WHERE `+seededUsers+`)`); err != nil {
+		return 0, fmt.Errorf("failed to delete seeded streams: %w", err)
+	}
+	tag, err := tx.Exec(ctx, `DELETE FROM users WHERE `+seededUsers)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete seeded users: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed to commit reset: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+// copyRows bulk inserts rows into table
+func copyRows(ctx context.Context, tx pgx.Tx, table string, columns []string, rows [][]interface{}) error {
+	if len(rows) == 0 {
+		return nil
+	}
+	if _, err := tx.CopyFrom(ctx, pgx.Identifier{table}, columns, pgx.CopyFromRows(rows)); err != nil {
+		return fmt.Errorf("failed to seed %s: %w", table, err)
+	}
+	return nil
+}
+
+// nullString maps an empty string to NULL
+func nullString(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
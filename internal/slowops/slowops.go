@@ -0,0 +1,170 @@
+// Package slowops logs and counts GraphQL resolvers and database queries
+// that run longer than a threshold, and keeps the worst offenders for
+// operators to look into
+package slowops
+
+import (
+	"context"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/tinle0301/streaming-platform-api/internal/logging"
+)
+
+// Kinds of operations
+const (
+	KindResolver = "resolver"
+	KindQuery    = "query"
+)
+
+var slowOperations = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "streamhub_slow_operations_total",
+	Help: "Resolvers and database queries slower than their threshold",
+}, []string{"kind"})
+
+// Options sets the thresholds operations are slow past
+type Options struct {
+	// Resolvers slower than ResolverThreshold are slow (0 = never)
+	ResolverThreshold time.Duration
+
+	// Database queries slower than QueryThreshold are slow (0 = never)
+	QueryThreshold time.Duration
+
+	// MaxOffenders bounds the operations kept for Top; the least slow are
+	// forgotten first
+	MaxOffenders int
+}
+
+// DefaultOptions returns the options used when none are configured
+func DefaultOptions() Options {
+	return Options{
+		ResolverThreshold: 500 * time.Millisecond,
+		QueryThreshold:    200 * time.Millisecond,
+		MaxOffenders:      200,
+	}
+}
+
+// Offender is an operation that has been slow, and how slow
+type Offender struct {
+	Kind string
+
+	// Name is a resolver's GraphQL path or a query's normalized SQL
+	Name string
+
+	Count    int64
+	Total    time.Duration
+	Max      time.Duration
+	LastSeen time.Time
+}
+
+// Recorder logs, counts, and collects slow operations
+type Recorder struct {
+	options Options
+
+	mu        sync.Mutex
+	offenders map[offenderKey]*Offender
+}
+
+// offenderKey identifies an operation
+type offenderKey struct {
+	kind, name string
+}
+
+// NewRecorder creates a recorder with the given thresholds
+func NewRecorder(options Options) *Recorder {
+	return &Recorder{
+		options:   options,
+		offenders: make(map[offenderKey]*Offender),
+	}
+}
+
+// threshold returns the threshold for kind, or 0 if it is never slow
+func (r *Recorder) threshold(kind string) time.Duration {
+	switch kind {
+	case KindResolver:
+		return r.options.ResolverThreshold
+	case KindQuery:
+		return r.options.QueryThreshold
+	}
+	return 0
+}
+
+// Observe records an operation that took elapsed, logging it with ctx's
+// request if it was slow. Queries are named by their SQL, which is
+// normalized here.
+func (r *Recorder) Observe(ctx context.Context, kind, name string, elapsed time.Duration) {
+	threshold := r.threshold(kind)
+	if threshold <= 0 || elapsed < threshold {
+		return
+	}
+	if kind == KindQuery {
+		name = NormalizeSQL(name)
+	}
+
+	slowOperations.WithLabelValues(kind).Inc()
+	logging.FromContext(ctx).LogAttrs(ctx, slog.LevelWarn, "Slow "+kind,
+		slog.String("name", name),
+		slog.Float64("elapsed_ms", float64(elapsed.Microseconds())/1000),
+		slog.Float64("threshold_ms", float64(threshold.Microseconds())/1000),
+	)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := offenderKey{kind: kind, name: name}
+	offender, ok := r.offenders[key]
+	if !ok {
+		if r.options.MaxOffenders > 0 && len(r.offenders) >= r.options.MaxOffenders {
+			r.forgetLeastSlow()
+		}
+		offender = &Offender{Kind: kind, Name: name}
+		r.offenders[key] = offender
+	}
+	offender.Count++
+	offender.Total += elapsed
+	if elapsed > offender.Max {
+		offender.Max = elapsed
+	}
+	offender.LastSeen = time.Now()
+}
+
+// forgetLeastSlow drops the offender with the least total time (caller
+// must hold the lock)
+func (r *Recorder) forgetLeastSlow() {
+	var least offenderKey
+	var leastTotal time.Duration = -1
+	for key, offender := range r.offenders {
+		if leastTotal < 0 || offender.Total < leastTotal {
+			least, leastTotal = key, offender.Total
+		}
+	}
+	delete(r.offenders, least)
+}
+
+// Top returns up to limit offenders of kind ("" for every kind), the most
+// total time spent slow first
+func (r *Recorder) Top(kind string, limit int) []Offender {
+	r.mu.Lock()
+	offenders := make([]Offender, 0, len(r.offenders))
+	for _, offender := range r.offenders {
+		if kind == "" || offender.Kind == kind {
+			offenders = append(offenders, *offender)
+		}
+	}
+	r.mu.Unlock()
+
+	sort.Slice(offenders, func(i, j int) bool {
+		if offenders[i].Total != offenders[j].Total {
+			return offenders[i].Total > offenders[j].Total
+		}
+		return offenders[i].Name < offenders[j].Name
+	})
+	if limit >= 0 && limit < len(offenders) {
+		offenders = offenders[:limit]
+	}
+	return offenders
+}
@@ -0,0 +1,76 @@
+package slowops
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestNormalizeSQL(t *testing.T) {
+	tests := []struct {
+		sql  string
+		want string
+	}{
+		{
+			"SELECT id, title\n  FROM streams\n  WHERE streamer_id = $1   AND is_live",
+			"SELECT id, title FROM streams WHERE streamer_id = $1 AND is_live",
+		},
+		{
+			"SELECT * FROM users WHERE email = 'o''brien@example.com' AND age > 42 LIMIT 10",
+			"SELECT * FROM users WHERE email = ? AND age > ? LIMIT ?",
+		},
+		{
+			"SELECT id FROM streams WHERE id IN ($1, $2, $3) -- batch\nORDER BY id",
+			"SELECT id FROM streams WHERE id IN (...) ORDER BY id",
+		},
+		{
+			"INSERT INTO follows (follower_id, streamer_id) VALUES ($1, $2), ($3, $4), ($5,$6)",
+			"INSERT INTO follows (follower_id, streamer_id) VALUES (...)",
+		},
+		{
+			"SELECT /* hint */ v1.col2 FROM t3 WHERE score > 1.5",
+			"SELECT v1.col2 FROM t3 WHERE score > ?",
+		},
+	}
+	for _, tt := range tests {
+		if got := NormalizeSQL(tt.sql); got != tt.want {
+			t.Errorf("NormalizeSQL(%q)\n got %q\nwant %q", tt.sql, got, tt.want)
+		}
+	}
+}
+
+func TestRecorder(t *testing.T) {
+	recorder := NewRecorder(Options{ResolverThreshold: 100 * time.Millisecond, QueryThreshold: 50 * time.Millisecond, MaxOffenders: 2})
+	ctx := context.Background()
+
+	recorder.Observe(ctx, KindResolver, "Query.streams", 99*time.Millisecond)
+	if top := recorder.Top("", -1); len(top) != 0 {
+		t.Fatalf("offenders = %+v, want none below the threshold", top)
+	}
+
+	recorder.Observe(ctx, KindResolver, "Query.streams", 300*time.Millisecond)
+	recorder.Observe(ctx, KindResolver, "Query.streams", 200*time.Millisecond)
+	recorder.Observe(ctx, KindQuery, "SELECT * FROM streams WHERE id = 7", 60*time.Millisecond)
+	recorder.Observe(ctx, KindQuery, "SELECT * FROM streams  WHERE id = 8", 60*time.Millisecond)
+
+	top := recorder.Top("", -1)
+	if len(top) != 2 {
+		t.Fatalf("offenders = %+v, want the resolver and the query", top)
+	}
+	if top[0].Name != "Query.streams" || top[0].Count != 2 || top[0].Max != 300*time.Millisecond || top[0].Total != 500*time.Millisecond {
+		t.Errorf("top offender = %+v", top[0])
+	}
+	if top[1].Name != "SELECT * FROM streams WHERE id = ?" || top[1].Count != 2 {
+		t.Errorf("query offender = %+v, want both runs under one normalized query", top[1])
+	}
+
+	// A new offender replaces the least slow one
+	recorder.Observe(ctx, KindResolver, "Stream.streamer", time.Second)
+	top = recorder.Top(KindResolver, 1)
+	if len(top) != 1 || top[0].Name != "Stream.streamer" {
+		t.Errorf("top resolver = %+v, want Stream.streamer", top)
+	}
+	if queries := recorder.Top(KindQuery, -1); len(queries) != 0 {
+		t.Errorf("queries = %+v, want the least slow forgotten", queries)
+	}
+}
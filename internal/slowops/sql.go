@@ -0,0 +1,106 @@
+package slowops
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	// placeholderList matches lists of placeholders, e.g. IN ($1, $2, $3)
+	placeholderList = regexp.MustCompile(`\((?:\?|\$\d+)(?:, ?(?:\?|\$\d+))+\)`)
+
+	// tupleList matches repeated collapsed tuples, e.g. VALUES (...), (...)
+	tupleList = regexp.MustCompile(`\(\.\.\.\)(?:, ?\(\.\.\.\))+`)
+)
+
+// NormalizeSQL reduces a query to its shape, so runs with different values
+// count as one operation and literals holding personal data are not
+// logged: whitespace is collapsed, comments are dropped, string and number
+// literals become ?, and lists of values become (...)
+func NormalizeSQL(sql string) string {
+	var b strings.Builder
+	b.Grow(len(sql))
+	space := false
+	write := func(s string) {
+		if space && b.Len() > 0 {
+			b.WriteByte(' ')
+		}
+		space = false
+		b.WriteString(s)
+	}
+
+	for i := 0; i < len(sql); {
+		c := sql[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			space = true
+			i++
+
+		case c == '-' && strings.HasPrefix(sql[i:], "--"):
+			end := strings.IndexByte(sql[i:], '\n')
+			if end < 0 {
+				end = len(sql) - i
+			}
+			space = true
+			i += end
+
+		case c == '/' && strings.HasPrefix(sql[i:], "/*"):
+			end := strings.Index(sql[i+2:], "*/")
+			if end < 0 {
+				i = len(sql)
+			} else {
+				i += end + 4
+			}
+			space = true
+
+		case c == '\'':
+			// Quotes inside a string are doubled
+			i++
+			for i < len(sql) {
+				if sql[i] == '\'' {
+					if i+1 < len(sql) && sql[i+1] == '\'' {
+						i += 2
+						continue
+					}
+					i++
+					break
+				}
+				i++
+			}
+			write("?")
+
+		case c == '$' && i+1 < len(sql) && isDigit(sql[i+1]):
+			end := i + 1
+			for end < len(sql) && isDigit(sql[end]) {
+				end++
+			}
+			write(sql[i:end])
+			i = end
+
+		case isDigit(c) && !(b.Len() > 0 && !space && isIdentifier(b.String()[b.Len()-1])):
+			end := i
+			for end < len(sql) && (isDigit(sql[end]) || sql[end] == '.') {
+				end++
+			}
+			write("?")
+			i = end
+
+		default:
+			write(sql[i : i+1])
+			i++
+		}
+	}
+
+	normalized := placeholderList.ReplaceAllString(b.String(), "(...)")
+	return tupleList.ReplaceAllString(normalized, "(...)")
+}
+
+// isDigit reports whether c is an ASCII digit
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// isIdentifier reports whether c can be part of an identifier
+func isIdentifier(c byte) bool {
+	return isDigit(c) || c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
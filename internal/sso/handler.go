@@ -0,0 +1,199 @@
+package sso
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/tinle0301/streaming-platform-api/internal/orgs"
+	"github.com/tinle0301/streaming-platform-api/internal/tenancy"
+	"github.com/tinle0301/streaming-platform-api/internal/users"
+)
+
+// Paths Handler serves
+const (
+	LoginPath    = "/sso/login"
+	CallbackPath = "/sso/callback"
+)
+
+// maxConfigBytes bounds an admin API request body
+const maxConfigBytes = 64 << 10
+
+// Handler serves the browser sign-in flow. LoginPath?tenant=<id>&return_to=<url>
+// sends the user to their tenant's provider (default's without tenant);
+// CallbackPath completes the sign-in and redirects to return_to with
+// #access_token=...&expires_at=... or, without one, responds with the
+// session as JSON.
+func Handler(service *Service) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		query := r.URL.Query()
+
+		switch r.URL.Path {
+		case LoginPath:
+			tenantID := query.Get("tenant")
+			if tenantID == "" {
+				tenantID = tenancy.Default
+			}
+			if !tenancy.ValidID(tenantID) {
+				http.Error(w, "Unknown tenant", http.StatusBadRequest)
+				return
+			}
+
+			location, err := service.Begin(r.Context(), tenantID, query.Get("return_to"))
+			if err != nil {
+				writeSignInError(w, err)
+				return
+			}
+			http.Redirect(w, r, location, http.StatusFound)
+
+		case CallbackPath:
+			if reason := query.Get("error"); reason != "" {
+				http.Error(w, "Sign-in was not completed: "+reason, http.StatusUnauthorized)
+				return
+			}
+
+			session, returnTo, err := service.Complete(r.Context(), query.Get("state"), query.Get("code"))
+			if err != nil {
+				writeSignInError(w, err)
+				return
+			}
+			expiresAt := session.ExpiresAt.UTC().Format(time.RFC3339)
+			if returnTo != "" {
+				fragment := url.Values{
+					"access_token": {session.Token},
+					"expires_at":   {expiresAt},
+				}
+				http.Redirect(w, r, returnTo+"#"+fragment.Encode(), http.StatusFound)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Cache-Control", "no-store")
+			json.NewEncoder(w).Encode(map[string]string{
+				"access_token": session.Token,
+				"expires_at":   expiresAt,
+				"user_id":      session.User.ID,
+			})
+
+		default:
+			http.NotFound(w, r)
+		}
+	})
+}
+
+// writeSignInError writes the response for a failed sign-in
+func writeSignInError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrNotConfigured):
+		http.Error(w, "Single sign-on is not configured for this tenant", http.StatusNotFound)
+	case errors.Is(err, ErrInvalidReturnURL), errors.Is(err, ErrInvalidState):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, ErrInvalidIDToken):
+		log.Printf("SSO sign-in rejected: %v", err)
+		http.Error(w, "Sign-in failed: "+ErrInvalidIDToken.Error(), http.StatusUnauthorized)
+	case errors.Is(err, ErrEmailRequired):
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+	case errors.Is(err, users.ErrDeactivated):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, users.ErrEmailTaken):
+		http.Error(w, "An account with this email already exists and the provider did not verify the email", http.StatusConflict)
+	default:
+		log.Printf("Error completing SSO sign-in: %v", err)
+		http.Error(w, "Sign-in failed", http.StatusBadGateway)
+	}
+}
+
+// providerConfig is a provider as the admin API reads and writes it
+type providerConfig struct {
+	Issuer         string               `json:"issuer"`
+	ClientID       string               `json:"client_id"`
+	ClientSecret   string               `json:"client_secret,omitempty"`
+	OrganizationID string               `json:"organization_id,omitempty"`
+	RoleClaim      string               `json:"role_claim"`
+	RoleMappings   map[string]orgs.Role `json:"role_mappings"`
+	ReturnURLs     []string             `json:"return_urls"`
+	UpdatedAt      *time.Time           `json:"updated_at,omitempty"`
+}
+
+// AdminHandler serves /admin/sso?tenant=<id> for platform operators: GET
+// reads a tenant's provider (without its client secret), PUT configures
+// it, and DELETE removes it. Requests authenticate with token.
+func AdminHandler(service *Service, token string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		presented := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(presented), []byte(token)) != 1 {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		tenantID := r.URL.Query().Get("tenant")
+		if !tenancy.ValidID(tenantID) {
+			http.Error(w, "tenant is required", http.StatusBadRequest)
+			return
+		}
+
+		var provider *Provider
+		var err error
+		switch r.Method {
+		case http.MethodGet:
+			provider, err = service.Provider(r.Context(), tenantID)
+		case http.MethodPut:
+			var body providerConfig
+			if decodeErr := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxConfigBytes)).Decode(&body); decodeErr != nil {
+				http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+				return
+			}
+			provider = &Provider{
+				TenantID:       tenantID,
+				Issuer:         body.Issuer,
+				ClientID:       body.ClientID,
+				ClientSecret:   body.ClientSecret,
+				OrganizationID: body.OrganizationID,
+				RoleClaim:      body.RoleClaim,
+				RoleMappings:   body.RoleMappings,
+				ReturnURLs:     body.ReturnURLs,
+			}
+			err = service.Configure(r.Context(), provider)
+		case http.MethodDelete:
+			if err = service.Remove(r.Context(), tenantID); err == nil {
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		switch {
+		case err == nil:
+		case errors.Is(err, ErrNotConfigured), errors.Is(err, tenancy.ErrNotFound), errors.Is(err, orgs.ErrNotFound):
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		case errors.Is(err, ErrInvalidConfig), errors.Is(err, tenancy.ErrInvalidID):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		default:
+			log.Printf("Error managing SSO provider: tenant=%s, err=%v", tenantID, err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(providerConfig{
+			Issuer:         provider.Issuer,
+			ClientID:       provider.ClientID,
+			OrganizationID: provider.OrganizationID,
+			RoleClaim:      provider.RoleClaim,
+			RoleMappings:   provider.RoleMappings,
+			ReturnURLs:     provider.ReturnURLs,
+			UpdatedAt:      &provider.UpdatedAt,
+		})
+	}
+}
@@ -0,0 +1,310 @@
+package sso
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Discovery and key caching
+const (
+	discoveryTTL = time.Hour
+
+	// minKeyRefresh bounds how often an unknown key ID refetches the keys
+	minKeyRefresh = time.Minute
+
+	maxResponseBytes = 1 << 20
+)
+
+// ErrInvalidIDToken is returned for id_tokens that fail validation
+var ErrInvalidIDToken = errors.New("invalid id_token")
+
+// Discovery is the part of an issuer's OpenID configuration used here
+type Discovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// IDClaims are an id_token's claims; Extra has all of them, for reading
+// the provider's role claim
+type IDClaims struct {
+	jwt.RegisteredClaims
+	Nonce             string `json:"nonce"`
+	Email             string `json:"email"`
+	EmailVerified     *bool  `json:"email_verified"`
+	Name              string `json:"name"`
+	PreferredUsername string `json:"preferred_username"`
+
+	Extra map[string]interface{} `json:"-"`
+}
+
+// issuerKeys are an issuer's cached configuration and signing keys
+type issuerKeys struct {
+	discovery    *Discovery
+	discoveredAt time.Time
+	keys         map[string]interface{}
+	keysAt       time.Time
+}
+
+// OIDC discovers issuers, exchanges authorization codes, and validates
+// id_tokens against the issuers' published keys
+type OIDC struct {
+	client *http.Client
+
+	mu      sync.Mutex
+	issuers map[string]*issuerKeys
+}
+
+// NewOIDC creates an OpenID Connect client
+func NewOIDC(client *http.Client) *OIDC {
+	return &OIDC{client: client, issuers: make(map[string]*issuerKeys)}
+}
+
+// Discover returns an issuer's OpenID configuration, cached for an hour
+func (o *OIDC) Discover(ctx context.Context, issuer string) (*Discovery, error) {
+	o.mu.Lock()
+	cached := o.issuers[issuer]
+	o.mu.Unlock()
+	if cached != nil && time.Since(cached.discoveredAt) < discoveryTTL {
+		return cached.discovery, nil
+	}
+
+	var discovery Discovery
+	if err := o.getJSON(ctx, strings.TrimSuffix(issuer, "/")+"/.well-known/openid-configuration", &discovery); err != nil {
+		return nil, fmt.Errorf("failed to discover %s: %w", issuer, err)
+	}
+	if discovery.Issuer != issuer {
+		return nil, fmt.Errorf("discovery document of %s names issuer %q", issuer, discovery.Issuer)
+	}
+	if discovery.AuthorizationEndpoint == "" || discovery.TokenEndpoint == "" || discovery.JWKSURI == "" {
+		return nil, fmt.Errorf("discovery document of %s is missing endpoints", issuer)
+	}
+
+	o.mu.Lock()
+	if o.issuers[issuer] == nil {
+		o.issuers[issuer] = &issuerKeys{}
+	}
+	o.issuers[issuer].discovery = &discovery
+	o.issuers[issuer].discoveredAt = time.Now()
+	o.mu.Unlock()
+	return &discovery, nil
+}
+
+// Exchange redeems an authorization code for its id_token
+func (o *OIDC) Exchange(ctx context.Context, provider *Provider, code, redirectURI, verifier string) (string, error) {
+	discovery, err := o.Discover(ctx, provider.Issuer)
+	if err != nil {
+		return "", err
+	}
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {redirectURI},
+		"client_id":     {provider.ClientID},
+		"client_secret": {provider.ClientSecret},
+		"code_verifier": {verifier},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, discovery.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", fmt.Errorf("failed to create token request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	var response struct {
+		IDToken string `json:"id_token"`
+	}
+	if err := o.do(req, &response); err != nil {
+		return "", fmt.Errorf("failed to exchange authorization code: %w", err)
+	}
+	if response.IDToken == "" {
+		return "", fmt.Errorf("token response has no id_token")
+	}
+	return response.IDToken, nil
+}
+
+// Verify validates an id_token's signature, issuer, audience, expiry, and
+// nonce and returns its claims
+func (o *OIDC) Verify(ctx context.Context, provider *Provider, rawToken, nonce string) (*IDClaims, error) {
+	discovery, err := o.Discover(ctx, provider.Issuer)
+	if err != nil {
+		return nil, err
+	}
+
+	var claims IDClaims
+	token, err := jwt.ParseWithClaims(rawToken, &claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return o.key(ctx, provider.Issuer, discovery.JWKSURI, kid)
+	},
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "ES256", "ES384", "ES512"}),
+		jwt.WithIssuer(provider.Issuer),
+		jwt.WithAudience(provider.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(time.Minute),
+	)
+	if err != nil || !token.Valid {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidIDToken, err)
+	}
+	if claims.Nonce != nonce || claims.Subject == "" {
+		return nil, fmt.Errorf("%w: nonce or subject mismatch", ErrInvalidIDToken)
+	}
+
+	// The signature was checked above, so the payload can be read as is
+	payload, err := jwt.NewParser().DecodeSegment(strings.Split(rawToken, ".")[1])
+	if err != nil || json.Unmarshal(payload, &claims.Extra) != nil {
+		return nil, fmt.Errorf("%w: malformed payload", ErrInvalidIDToken)
+	}
+	return &claims, nil
+}
+
+// key returns an issuer's signing key by ID, refetching the issuer's keys
+// when the ID is unknown, at most once a minute
+func (o *OIDC) key(ctx context.Context, issuer, jwksURI, kid string) (interface{}, error) {
+	o.mu.Lock()
+	cached := o.issuers[issuer]
+	if cached != nil {
+		if key, ok := cached.keys[kid]; ok {
+			o.mu.Unlock()
+			return key, nil
+		}
+		if time.Since(cached.keysAt) < minKeyRefresh {
+			o.mu.Unlock()
+			return nil, fmt.Errorf("unknown signing key %q", kid)
+		}
+	}
+	o.mu.Unlock()
+
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := o.getJSON(ctx, jwksURI, &set); err != nil {
+		return nil, fmt.Errorf("failed to fetch signing keys: %w", err)
+	}
+	keys := make(map[string]interface{}, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		if key, err := k.publicKey(); err == nil {
+			keys[k.Kid] = key
+		}
+	}
+
+	o.mu.Lock()
+	if o.issuers[issuer] == nil {
+		o.issuers[issuer] = &issuerKeys{}
+	}
+	o.issuers[issuer].keys = keys
+	o.issuers[issuer].keysAt = time.Now()
+	o.mu.Unlock()
+
+	key, ok := keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	return key, nil
+}
+
+// getJSON fetches a JSON document
+func (o *OIDC) getJSON(ctx context.Context, url string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	return o.do(req, v)
+}
+
+// do sends a request and decodes its JSON response
+func (o *OIDC) do(req *http.Request, v interface{}) error {
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseBytes))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned %d", req.URL.Host, resp.StatusCode)
+	}
+	return json.Unmarshal(body, v)
+}
+
+// jsonWebKey is an RSA or EC public key of a JWK set
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// publicKey decodes the key
+func (k jsonWebKey) publicKey() (interface{}, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, err
+		}
+		exponent := new(big.Int).SetBytes(e)
+		if !exponent.IsInt64() || exponent.Int64() > 1<<31-1 {
+			return nil, errors.New("RSA exponent too large")
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := base64.RawURLEncoding.DecodeString(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		key := &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !curve.IsOnCurve(key.X, key.Y) {
+			return nil, errors.New("EC point is not on the curve")
+		}
+		return key, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
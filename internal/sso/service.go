@@ -0,0 +1,465 @@
+package sso
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/tinle0301/streaming-platform-api/internal/audit"
+	"github.com/tinle0301/streaming-platform-api/internal/orgs"
+	"github.com/tinle0301/streaming-platform-api/internal/storage"
+	"github.com/tinle0301/streaming-platform-api/internal/tenancy"
+	"github.com/tinle0301/streaming-platform-api/internal/users"
+)
+
+// Sign-in limits
+const (
+	// stateTTL is how long a user has to sign in at the provider
+	stateTTL = 10 * time.Minute
+
+	stateKeyPrefix = "sso:state:"
+
+	// httpTimeout bounds each request to a provider
+	httpTimeout = 10 * time.Second
+
+	// usernameAttempts is how many usernames are tried for a new account
+	usernameAttempts = 3
+)
+
+// adminActor is the audit actor of changes made through the admin API
+const adminActor = "admin"
+
+// Service errors
+var (
+	ErrInvalidConfig    = errors.New("invalid single sign-on configuration")
+	ErrInvalidState     = errors.New("sign-in expired or was already completed")
+	ErrInvalidReturnURL = errors.New("return URL is not allowed for this tenant")
+	ErrEmailRequired    = errors.New("the identity provider did not share a verified email")
+)
+
+// state is a sign-in in progress, kept until the provider redirects back
+type state struct {
+	TenantID string `json:"tenant_id"`
+	Nonce    string `json:"nonce"`
+	Verifier string `json:"verifier"`
+	ReturnTo string `json:"return_to"`
+}
+
+// StateStore keeps sign-ins in progress in Redis, so any API server can
+// complete them
+type StateStore struct {
+	client *redis.Client
+}
+
+// NewStateStore creates a sign-in state store on client
+func NewStateStore(client *redis.Client) *StateStore {
+	return &StateStore{client: client}
+}
+
+// save stores a sign-in under its state parameter
+func (s *StateStore) save(ctx context.Context, key string, st *state) error {
+	data, err := json.Marshal(st)
+	if err != nil {
+		return fmt.Errorf("failed to encode sign-in state: %w", err)
+	}
+	if err := s.client.Set(ctx, stateKeyPrefix+key, data, stateTTL).Err(); err != nil {
+		return fmt.Errorf("failed to save sign-in state: %w", err)
+	}
+	return nil
+}
+
+// take removes and returns a sign-in, so each can complete once
+func (s *StateStore) take(ctx context.Context, key string) (*state, error) {
+	data, err := s.client.GetDel(ctx, stateKeyPrefix+key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrInvalidState
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load sign-in state: %w", err)
+	}
+	var st state
+	if err := json.Unmarshal(data, &st); err != nil {
+		return nil, fmt.Errorf("failed to decode sign-in state: %w", err)
+	}
+	return &st, nil
+}
+
+// Service configures tenants' identity providers and signs their users in,
+// creating accounts on first sign-in and syncing the organization roles
+// their groups map to
+type Service struct {
+	repo        Repository
+	cipher      *storage.Cipher
+	states      *StateStore
+	oidc        *OIDC
+	accounts    *users.Service
+	orgs        *orgs.Service
+	auditLog    audit.Logger
+	callbackURL string
+}
+
+// NewService creates a single sign-on service. Client secrets are sealed
+// with cipher; providers redirect users back to callbackURL, where Handler
+// serves the callback.
+func NewService(repo Repository, cipher *storage.Cipher, states *StateStore, accounts *users.Service,
+	organizations *orgs.Service, auditLog audit.Logger, callbackURL string) *Service {
+	return &Service{
+		repo:        repo,
+		cipher:      cipher,
+		states:      states,
+		oidc:        NewOIDC(&http.Client{Timeout: httpTimeout}),
+		accounts:    accounts,
+		orgs:        organizations,
+		auditLog:    auditLog,
+		callbackURL: callbackURL,
+	}
+}
+
+// Configure creates or replaces a tenant's provider after checking its
+// issuer's discovery document. An empty client secret keeps the current one.
+func (s *Service) Configure(ctx context.Context, provider *Provider) error {
+	if err := s.validate(provider); err != nil {
+		return err
+	}
+	if _, err := s.oidc.Discover(ctx, provider.Issuer); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidConfig, err)
+	}
+
+	var sealed []byte
+	if provider.ClientSecret == "" {
+		_, current, err := s.repo.Provider(ctx, provider.TenantID)
+		if errors.Is(err, ErrNotConfigured) {
+			return fmt.Errorf("%w: client_secret is required", ErrInvalidConfig)
+		}
+		if err != nil {
+			return err
+		}
+		sealed = current
+	} else {
+		var err error
+		if sealed, err = s.cipher.Encrypt(provider.TenantID, provider.ClientSecret); err != nil {
+			return err
+		}
+	}
+	if err := s.repo.SaveProvider(ctx, provider, sealed); err != nil {
+		return err
+	}
+
+	metadata := map[string]string{"issuer": provider.Issuer, "organization_id": provider.OrganizationID}
+	for value, role := range provider.RoleMappings {
+		metadata["role:"+value] = string(role)
+	}
+	s.record(ctx, audit.ActionSSOConfigured, adminActor, "", "tenant:"+provider.TenantID, metadata)
+	log.Printf("SSO configured: tenant=%s, issuer=%s", provider.TenantID, provider.Issuer)
+	return nil
+}
+
+// Provider returns a tenant's provider without its client secret
+func (s *Service) Provider(ctx context.Context, tenantID string) (*Provider, error) {
+	provider, _, err := s.repo.Provider(ctx, tenantID)
+	return provider, err
+}
+
+// Remove deletes a tenant's provider; accounts created through it are kept
+// and can still sign in with a password once it is reset
+func (s *Service) Remove(ctx context.Context, tenantID string) error {
+	if err := s.repo.DeleteProvider(ctx, tenantID); err != nil {
+		return err
+	}
+	s.record(ctx, audit.ActionSSORemoved, adminActor, "", "tenant:"+tenantID, nil)
+	log.Printf("SSO removed: tenant=%s", tenantID)
+	return nil
+}
+
+// Begin starts a sign-in and returns the provider URL to send the user
+// to. returnTo, if set, must be one of the tenant's return URLs.
+func (s *Service) Begin(ctx context.Context, tenantID, returnTo string) (string, error) {
+	provider, _, err := s.repo.Provider(ctx, tenantID)
+	if err != nil {
+		return "", err
+	}
+	if returnTo != "" && !contains(provider.ReturnURLs, returnTo) {
+		return "", ErrInvalidReturnURL
+	}
+	discovery, err := s.oidc.Discover(ctx, provider.Issuer)
+	if err != nil {
+		return "", err
+	}
+
+	key, err := randomString()
+	if err != nil {
+		return "", err
+	}
+	st := &state{TenantID: tenantID, ReturnTo: returnTo}
+	if st.Nonce, err = randomString(); err != nil {
+		return "", err
+	}
+	if st.Verifier, err = randomString(); err != nil {
+		return "", err
+	}
+	if err := s.states.save(ctx, key, st); err != nil {
+		return "", err
+	}
+
+	challenge := sha256.Sum256([]byte(st.Verifier))
+	query := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {provider.ClientID},
+		"redirect_uri":          {s.callbackURL},
+		"scope":                 {"openid email profile"},
+		"state":                 {key},
+		"nonce":                 {st.Nonce},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(challenge[:])},
+		"code_challenge_method": {"S256"},
+	}
+	separator := "?"
+	if strings.Contains(discovery.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+	return discovery.AuthorizationEndpoint + separator + query.Encode(), nil
+}
+
+// Complete finishes a sign-in the provider redirected back with: the code
+// is exchanged for an id_token, whose user is signed in, or created on
+// first sign-in. It returns the session and where to send the user.
+func (s *Service) Complete(ctx context.Context, key, code string) (*users.Session, string, error) {
+	st, err := s.states.take(ctx, key)
+	if err != nil {
+		return nil, "", err
+	}
+	ctx = tenancy.WithTenant(ctx, st.TenantID)
+
+	provider, sealed, err := s.repo.Provider(ctx, st.TenantID)
+	if err != nil {
+		return nil, "", err
+	}
+	if provider.ClientSecret, err = s.cipher.Decrypt(st.TenantID, sealed); err != nil {
+		return nil, "", err
+	}
+
+	rawToken, err := s.oidc.Exchange(ctx, provider, code, s.callbackURL, st.Verifier)
+	if err != nil {
+		return nil, "", err
+	}
+	claims, err := s.oidc.Verify(ctx, provider, rawToken, st.Nonce)
+	if err != nil {
+		return nil, "", err
+	}
+
+	identity, err := s.repo.Identity(ctx, st.TenantID, provider.Issuer, claims.Subject)
+	if errors.Is(err, ErrIdentityNotFound) {
+		identity, err = s.provision(ctx, provider, claims)
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	if err := s.repo.Link(ctx, identity); err != nil {
+		return nil, "", err
+	}
+	if err := s.syncRole(ctx, provider, identity, claims); err != nil {
+		return nil, "", err
+	}
+
+	session, err := s.accounts.SignIn(ctx, identity.UserID)
+	if err != nil {
+		return nil, "", err
+	}
+	return session, st.ReturnTo, nil
+}
+
+// provision finds or creates the account of a first sign-in. An account
+// with the same email is linked if the provider verified the email.
+func (s *Service) provision(ctx context.Context, provider *Provider, claims *IDClaims) (*Identity, error) {
+	if claims.Email == "" || (claims.EmailVerified != nil && !*claims.EmailVerified) {
+		return nil, ErrEmailRequired
+	}
+	identity := &Identity{TenantID: provider.TenantID, Issuer: provider.Issuer, Subject: claims.Subject}
+
+	existing, err := s.accounts.GetByEmail(ctx, claims.Email)
+	switch {
+	case err == nil:
+		if claims.EmailVerified == nil {
+			return nil, users.ErrEmailTaken
+		}
+		identity.UserID = existing.ID
+		s.record(ctx, audit.ActionSSOUserLinked, "sso:"+provider.TenantID, existing.ID, "tenant:"+provider.TenantID,
+			map[string]string{"issuer": provider.Issuer, "subject": claims.Subject})
+		return identity, nil
+	case !errors.Is(err, users.ErrNotFound):
+		return nil, err
+	}
+
+	name := claims.PreferredUsername
+	if name == "" {
+		name = claims.Email
+	}
+	base := users.SuggestUsername(name)
+	username := base
+	for attempt := 1; ; attempt++ {
+		account, err := s.accounts.Provision(ctx, users.Registration{
+			Username:    username,
+			Email:       claims.Email,
+			DisplayName: claims.Name,
+		})
+		if errors.Is(err, users.ErrUsernameTaken) && attempt < usernameAttempts {
+			suffix, suffixErr := randomSuffix()
+			if suffixErr != nil {
+				return nil, suffixErr
+			}
+			username = base[:min(len(base), 20)] + "_" + suffix
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		identity.UserID = account.ID
+		s.record(ctx, audit.ActionSSOUserProvisioned, "sso:"+provider.TenantID, account.ID, "tenant:"+provider.TenantID,
+			map[string]string{"issuer": provider.Issuer, "subject": claims.Subject, "username": account.Username})
+		return identity, nil
+	}
+}
+
+// syncRole grants the most privileged organization role the user's groups
+// map to, or removes the role single sign-on granted when none do
+func (s *Service) syncRole(ctx context.Context, provider *Provider, identity *Identity, claims *IDClaims) error {
+	if provider.OrganizationID == "" {
+		return nil
+	}
+
+	var roles []orgs.Role
+	for _, value := range claimValues(claims.Extra[provider.RoleClaim]) {
+		roles = append(roles, provider.RoleMappings[value])
+	}
+	desired := orgs.MostPrivileged(roles...)
+	if desired == identity.ManagedRole {
+		return nil
+	}
+
+	if err := s.orgs.SyncMemberRole(ctx, provider.OrganizationID, identity.UserID, desired); err != nil {
+		return fmt.Errorf("failed to sync organization role: %w", err)
+	}
+	if err := s.repo.SetManagedRole(ctx, identity, desired); err != nil {
+		return err
+	}
+
+	s.record(ctx, audit.ActionSSORoleSynced, "sso:"+provider.TenantID, identity.UserID, "organization:"+provider.OrganizationID,
+		map[string]string{"from": string(identity.ManagedRole), "to": string(desired)})
+	identity.ManagedRole = desired
+	return nil
+}
+
+// validate normalizes and checks a provider configuration
+func (s *Service) validate(provider *Provider) error {
+	provider.Issuer = strings.TrimSpace(provider.Issuer)
+	provider.ClientID = strings.TrimSpace(provider.ClientID)
+	provider.RoleClaim = strings.TrimSpace(provider.RoleClaim)
+	if provider.RoleClaim == "" {
+		provider.RoleClaim = "groups"
+	}
+	if provider.RoleMappings == nil {
+		provider.RoleMappings = map[string]orgs.Role{}
+	}
+	if provider.ReturnURLs == nil {
+		provider.ReturnURLs = []string{}
+	}
+
+	if !tenancy.ValidID(provider.TenantID) {
+		return tenancy.ErrInvalidID
+	}
+	if issuer, err := url.Parse(provider.Issuer); err != nil || issuer.Scheme != "https" || issuer.Host == "" {
+		return fmt.Errorf("%w: issuer must be an https URL", ErrInvalidConfig)
+	}
+	if provider.ClientID == "" {
+		return fmt.Errorf("%w: client_id is required", ErrInvalidConfig)
+	}
+	for value, role := range provider.RoleMappings {
+		if value == "" || (role != orgs.RoleAdmin && role != orgs.RoleManager && role != orgs.RoleModerator) {
+			return fmt.Errorf("%w: role mappings may only grant ADMIN, MANAGER, or MODERATOR", ErrInvalidConfig)
+		}
+	}
+	if len(provider.RoleMappings) > 0 && provider.OrganizationID == "" {
+		return fmt.Errorf("%w: role mappings need an organization_id", ErrInvalidConfig)
+	}
+	for _, raw := range provider.ReturnURLs {
+		if u, err := url.Parse(raw); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" || u.Fragment != "" {
+			return fmt.Errorf("%w: return URLs must be absolute http(s) URLs without a fragment", ErrInvalidConfig)
+		}
+	}
+	return nil
+}
+
+// record writes an audit entry
+func (s *Service) record(ctx context.Context, action, actorID, targetID, resource string, metadata map[string]string) {
+	if s.auditLog == nil {
+		return
+	}
+
+	entry := audit.Entry{
+		Action:   action,
+		ActorID:  actorID,
+		TargetID: targetID,
+		Resource: resource,
+		Metadata: metadata,
+	}
+	if err := s.auditLog.Record(ctx, entry); err != nil {
+		log.Printf("Error recording audit entry: %v", err)
+	}
+}
+
+// claimValues reads a claim that is a string or a list of strings
+func claimValues(claim interface{}) []string {
+	switch v := claim.(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	default:
+		return nil
+	}
+}
+
+// randomString returns 32 random bytes, base64url-encoded
+func randomString() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate random value: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// randomSuffix returns 4 random hex digits
+func randomSuffix() (string, error) {
+	b := make([]byte, 2)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate random value: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// contains reports whether values includes value
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,204 @@
+// Package sso signs users in through their tenant's OpenID Connect
+// identity provider. Accounts are created on first sign-in, and the
+// provider's groups can grant roles in one of the tenant's organizations.
+package sso
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/tinle0301/streaming-platform-api/internal/orgs"
+	"github.com/tinle0301/streaming-platform-api/internal/store"
+	"github.com/tinle0301/streaming-platform-api/internal/tenancy"
+)
+
+// Repository errors
+var (
+	ErrNotConfigured    = errors.New("single sign-on is not configured for this tenant")
+	ErrIdentityNotFound = errors.New("identity not found")
+)
+
+// Provider is a tenant's OpenID Connect identity provider
+type Provider struct {
+	TenantID string
+	Issuer   string
+	ClientID string
+
+	// ClientSecret is decrypted when the provider is loaded by the
+	// service and never returned by the admin API
+	ClientSecret string
+
+	// OrganizationID is the organization RoleMappings grant roles in, or ""
+	OrganizationID string
+
+	// RoleClaim names the id_token claim holding the user's groups, a
+	// string or list of strings
+	RoleClaim string
+
+	// RoleMappings maps RoleClaim values to organization roles
+	RoleMappings map[string]orgs.Role
+
+	// ReturnURLs are where the callback may send users after signing in
+	ReturnURLs []string
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// Identity links an account to the identity provider user it signs in as
+type Identity struct {
+	TenantID string
+	Issuer   string
+	Subject  string
+	UserID   string
+
+	// ManagedRole is the organization role single sign-on last granted
+	ManagedRole orgs.Role
+}
+
+// Repository persists providers and identities
+type Repository interface {
+	// SaveProvider creates or replaces a tenant's provider; secret is the
+	// sealed client secret
+	SaveProvider(ctx context.Context, provider *Provider, secret []byte) error
+
+	// Provider returns a tenant's provider and its sealed client secret
+	Provider(ctx context.Context, tenantID string) (*Provider, []byte, error)
+	DeleteProvider(ctx context.Context, tenantID string) error
+
+	Identity(ctx context.Context, tenantID, issuer, subject string) (*Identity, error)
+
+	// Link records an identity, or records a sign-in of an existing one
+	Link(ctx context.Context, identity *Identity) error
+	SetManagedRole(ctx context.Context, identity *Identity, role orgs.Role) error
+}
+
+// PostgresRepository implements Repository on PostgreSQL
+type PostgresRepository struct {
+	pool *pgxpool.Pool
+}
+
+// NewPostgresRepository creates an SSO repository on pool
+func NewPostgresRepository(pool *pgxpool.Pool) *PostgresRepository {
+	return &PostgresRepository{pool: pool}
+}
+
+// SaveProvider upserts a provider
+func (r *PostgresRepository) SaveProvider(ctx context.Context, provider *Provider, secret []byte) error {
+	mappings, err := json.Marshal(provider.RoleMappings)
+	if err != nil {
+		return fmt.Errorf("failed to encode role mappings: %w", err)
+	}
+
+	var orgID *string
+	if provider.OrganizationID != "" {
+		if !store.IsUUID(provider.OrganizationID) {
+			return orgs.ErrNotFound
+		}
+		orgID = &provider.OrganizationID
+	}
+
+	err = r.pool.QueryRow(ctx, `
+		INSERT INTO sso_providers (tenant_id, issuer, client_id, client_secret, organization_id, role_claim, role_mappings, return_urls)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (tenant_id) DO UPDATE SET
+			issuer = EXCLUDED.issuer, client_id = EXCLUDED.client_id, client_secret = EXCLUDED.client_secret,
+			organization_id = EXCLUDED.organization_id, role_claim = EXCLUDED.role_claim,
+			role_mappings = EXCLUDED.role_mappings, return_urls = EXCLUDED.return_urls, updated_at = NOW()
+		RETURNING created_at, updated_at`,
+		provider.TenantID, provider.Issuer, provider.ClientID, secret, orgID, provider.RoleClaim, mappings, provider.ReturnURLs,
+	).Scan(&provider.CreatedAt, &provider.UpdatedAt)
+	if store.PgCode(err) == "23503" {
+		if store.PgConstraint(err) == "sso_providers_tenant_id_fkey" {
+			return tenancy.ErrNotFound
+		}
+		return orgs.ErrNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to save SSO provider: %w", err)
+	}
+	return nil
+}
+
+// Provider reads a tenant's provider
+func (r *PostgresRepository) Provider(ctx context.Context, tenantID string) (*Provider, []byte, error) {
+	var p Provider
+	var secret, mappings []byte
+	err := r.pool.QueryRow(ctx, `
+		SELECT tenant_id, issuer, client_id, client_secret, COALESCE(organization_id::text, ''), role_claim,
+			role_mappings, return_urls, created_at, updated_at
+		FROM sso_providers WHERE tenant_id = $1`, tenantID,
+	).Scan(&p.TenantID, &p.Issuer, &p.ClientID, &secret, &p.OrganizationID, &p.RoleClaim,
+		&mappings, &p.ReturnURLs, &p.CreatedAt, &p.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil, ErrNotConfigured
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get SSO provider: %w", err)
+	}
+
+	p.RoleMappings = map[string]orgs.Role{}
+	if err := json.Unmarshal(mappings, &p.RoleMappings); err != nil {
+		return nil, nil, fmt.Errorf("failed to decode role mappings: %w", err)
+	}
+	return &p, secret, nil
+}
+
+// DeleteProvider deletes a tenant's provider; identities are kept, so
+// configuring the same issuer again signs users into the same accounts
+func (r *PostgresRepository) DeleteProvider(ctx context.Context, tenantID string) error {
+	tag, err := r.pool.Exec(ctx, `DELETE FROM sso_providers WHERE tenant_id = $1`, tenantID)
+	if err != nil {
+		return fmt.Errorf("failed to delete SSO provider: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotConfigured
+	}
+	return nil
+}
+
+// Identity reads an identity
+func (r *PostgresRepository) Identity(ctx context.Context, tenantID, issuer, subject string) (*Identity, error) {
+	identity := Identity{TenantID: tenantID, Issuer: issuer, Subject: subject}
+	err := r.pool.QueryRow(ctx, `
+		SELECT user_id::text, managed_role FROM sso_identities
+		WHERE tenant_id = $1 AND issuer = $2 AND subject = $3`, tenantID, issuer, subject,
+	).Scan(&identity.UserID, &identity.ManagedRole)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrIdentityNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get SSO identity: %w", err)
+	}
+	return &identity, nil
+}
+
+// Link upserts an identity and stamps its sign-in
+func (r *PostgresRepository) Link(ctx context.Context, identity *Identity) error {
+	_, err := r.pool.Exec(ctx, `
+		INSERT INTO sso_identities (tenant_id, issuer, subject, user_id)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (tenant_id, issuer, subject) DO UPDATE SET last_login_at = NOW()`,
+		identity.TenantID, identity.Issuer, identity.Subject, identity.UserID)
+	if err != nil {
+		return fmt.Errorf("failed to link SSO identity: %w", err)
+	}
+	return nil
+}
+
+// SetManagedRole records the role single sign-on granted an identity's user
+func (r *PostgresRepository) SetManagedRole(ctx context.Context, identity *Identity, role orgs.Role) error {
+	_, err := r.pool.Exec(ctx, `
+		UPDATE sso_identities SET managed_role = $4
+		WHERE tenant_id = $1 AND issuer = $2 AND subject = $3`,
+		identity.TenantID, identity.Issuer, identity.Subject, role)
+	if err != nil {
+		return fmt.Errorf("failed to set managed role: %w", err)
+	}
+	return nil
+}
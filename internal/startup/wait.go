@@ -0,0 +1,152 @@
+package startup
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/redis/go-redis/v9"
+)
+
+// Dependency is an external service the server needs before serving
+type Dependency struct {
+	Name string
+
+	// Required dependencies fail startup if they never come up; optional
+	// ones are logged and the server starts degraded
+	Required bool
+
+	// Check returns nil once the dependency is reachable
+	Check func(ctx context.Context) error
+}
+
+// WaitOptions bounds the dependency wait
+type WaitOptions struct {
+	MaxWait        time.Duration
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	CheckTimeout   time.Duration
+}
+
+// DefaultWaitOptions returns backoff settings suited to docker-compose startup
+func DefaultWaitOptions() WaitOptions {
+	return WaitOptions{
+		MaxWait:        30 * time.Second,
+		InitialBackoff: 250 * time.Millisecond,
+		MaxBackoff:     5 * time.Second,
+		CheckTimeout:   3 * time.Second,
+	}
+}
+
+// WaitFor checks every dependency concurrently, retrying each with
+// exponential backoff until it is reachable or MaxWait elapses. It returns
+// an error only if a required dependency is still unreachable. A MaxWait of
+// zero skips the wait entirely.
+func WaitFor(ctx context.Context, opts WaitOptions, deps ...Dependency) error {
+	if opts.MaxWait <= 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, opts.MaxWait)
+	defer cancel()
+
+	start := time.Now()
+	errs := make([]error, len(deps))
+
+	var wg sync.WaitGroup
+	for i, dep := range deps {
+		wg.Add(1)
+		go func(i int, dep Dependency) {
+			defer wg.Done()
+			errs[i] = waitForOne(ctx, opts, dep)
+		}(i, dep)
+	}
+	wg.Wait()
+
+	for i, dep := range deps {
+		if errs[i] == nil {
+			continue
+		}
+		if dep.Required {
+			return fmt.Errorf("dependency %s unavailable after %v: %w", dep.Name, time.Since(start).Round(time.Millisecond), errs[i])
+		}
+		log.Printf("Dependency %s unavailable, continuing without it: %v", dep.Name, errs[i])
+	}
+	return nil
+}
+
+// waitForOne retries a single dependency check until it passes or ctx ends
+func waitForOne(ctx context.Context, opts WaitOptions, dep Dependency) error {
+	backoff := opts.InitialBackoff
+	start := time.Now()
+
+	for attempt := 1; ; attempt++ {
+		checkCtx, cancel := context.WithTimeout(ctx, opts.CheckTimeout)
+		err := dep.Check(checkCtx)
+		cancel()
+
+		if err == nil {
+			log.Printf("Dependency %s is ready (attempt %d, %v)", dep.Name, attempt, time.Since(start).Round(time.Millisecond))
+			return nil
+		}
+
+		log.Printf("Waiting for %s (attempt %d, retrying in %v): %v", dep.Name, attempt, backoff, err)
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+
+		backoff *= 2
+		if backoff > opts.MaxBackoff {
+			backoff = opts.MaxBackoff
+		}
+	}
+}
+
+// PostgresCheck pings PostgreSQL at databaseURL
+func PostgresCheck(databaseURL string) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		conn, err := pgx.Connect(ctx, databaseURL)
+		if err != nil {
+			return err
+		}
+		defer conn.Close(context.Background())
+		return conn.Ping(ctx)
+	}
+}
+
+// RedisCheck pings Redis at redisURL
+func RedisCheck(redisURL string) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		opts, err := redis.ParseURL(redisURL)
+		if err != nil {
+			return err
+		}
+		// WaitFor owns the retry loop
+		opts.MaxRetries = -1
+		client := redis.NewClient(opts)
+		defer client.Close()
+		return client.Ping(ctx).Err()
+	}
+}
+
+// RabbitMQCheck opens and closes a connection to RabbitMQ at amqpURL
+func RabbitMQCheck(amqpURL string) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		config := amqp.Config{Heartbeat: 10 * time.Second, Locale: "en_US"}
+		if deadline, ok := ctx.Deadline(); ok {
+			config.Dial = amqp.DefaultDial(time.Until(deadline))
+		}
+		conn, err := amqp.DialConfig(amqpURL, config)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	}
+}
@@ -0,0 +1,59 @@
+package storage
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+)
+
+// ErrInvalidKey is returned for encryption keys that aren't 32 bytes
+var ErrInvalidKey = errors.New("storage encryption key must be 32 bytes, base64-encoded")
+
+// Cipher encrypts channels' storage secrets with AES-256-GCM. Each secret
+// is bound to its channel, so ciphertexts can't be moved between channels.
+type Cipher struct {
+	aead cipher.AEAD
+}
+
+// NewCipher creates a cipher from a base64-encoded 32-byte key
+func NewCipher(encodedKey string) (*Cipher, error) {
+	key, err := base64.StdEncoding.DecodeString(encodedKey)
+	if err != nil || len(key) != 32 {
+		return nil, ErrInvalidKey
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create storage cipher: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create storage cipher: %w", err)
+	}
+	return &Cipher{aead: aead}, nil
+}
+
+// Encrypt seals a channel's secret; the nonce is prepended
+func (c *Cipher) Encrypt(channelID, secret string) ([]byte, error) {
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	return c.aead.Seal(nonce, nonce, []byte(secret), []byte(channelID)), nil
+}
+
+// Decrypt opens a secret sealed by Encrypt for the same channel
+func (c *Cipher) Decrypt(channelID string, sealed []byte) (string, error) {
+	size := c.aead.NonceSize()
+	if len(sealed) < size {
+		return "", errors.New("storage secret is truncated")
+	}
+	secret, err := c.aead.Open(nil, sealed[:size], sealed[size:], []byte(channelID))
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt storage secret: %w", err)
+	}
+	return string(secret), nil
+}
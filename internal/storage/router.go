@@ -0,0 +1,112 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/tinle0301/streaming-platform-api/internal/metering"
+	"github.com/tinle0301/streaming-platform-api/internal/retention"
+	"github.com/tinle0301/streaming-platform-api/internal/tenancy"
+)
+
+// bytesPerGB converts written bytes to metered gigabytes
+const bytesPerGB = 1e9
+
+// ErrUnavailable is returned for a channel whose own bucket failed its
+// latest check. Its media is not written to the platform's storage instead,
+// since the channel chose where its media may live.
+var ErrUnavailable = errors.New("channel storage failed its latest check")
+
+// VODKey is where a file of a VOD is stored
+func VODKey(channelID, vodID, name string) string {
+	return "vods/" + channelID + "/" + vodID + "/" + name
+}
+
+// ClipKey is where a file of a clip is stored
+func ClipKey(channelID, clipID, name string) string {
+	return "clips/" + channelID + "/" + clipID + "/" + name
+}
+
+// Router picks where each channel's VODs and clips are stored: the
+// channel's own bucket if it has one, the platform's store otherwise
+type Router struct {
+	service  *Service
+	platform retention.ObjectStore
+	meter    *metering.Meter
+}
+
+// NewRouter creates a router that falls back to platform
+func NewRouter(service *Service, platform retention.ObjectStore) *Router {
+	return &Router{service: service, platform: platform}
+}
+
+// SetMeter meters media written to the platform's storage as usage of the
+// writing context's tenant
+func (r *Router) SetMeter(meter *metering.Meter) {
+	r.meter = meter
+}
+
+// For returns the store for a channel's media. Keys are the same in either
+// store; a channel's own bucket keeps them under its prefix.
+func (r *Router) For(ctx context.Context, channelID string) (retention.ObjectStore, error) {
+	cfg, err := r.service.decrypted(ctx, channelID)
+	if errors.Is(err, ErrNotFound) {
+		if r.meter != nil {
+			return &meteredStore{ObjectStore: r.platform, meter: r.meter, tenantID: tenancy.ID(ctx)}, nil
+		}
+		return r.platform, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Status != StatusVerified {
+		return nil, ErrUnavailable
+	}
+	return &prefixedStore{store: r.service.open(cfg), prefix: cfg.Prefix}, nil
+}
+
+// meteredStore counts the bytes put into the platform's store; channels'
+// own buckets are paid for by their owners
+type meteredStore struct {
+	retention.ObjectStore
+	meter    *metering.Meter
+	tenantID string
+}
+
+// Put uploads an object and meters its size
+func (s *meteredStore) Put(ctx context.Context, key string, data []byte) error {
+	if err := s.ObjectStore.Put(ctx, key, data); err != nil {
+		return err
+	}
+	s.meter.Add(s.tenantID, metering.StorageGB, float64(len(data))/bytesPerGB)
+	return nil
+}
+
+// prefixedStore keeps keys under a prefix within another store
+type prefixedStore struct {
+	store  retention.ObjectStore
+	prefix string
+}
+
+// Put uploads an object under the prefix
+func (s *prefixedStore) Put(ctx context.Context, key string, data []byte) error {
+	return s.store.Put(ctx, s.prefix+key, data)
+}
+
+// Get downloads an object under the prefix
+func (s *prefixedStore) Get(ctx context.Context, key string) ([]byte, error) {
+	return s.store.Get(ctx, s.prefix+key)
+}
+
+// List returns the keys under prefix, without the store's own prefix
+func (s *prefixedStore) List(ctx context.Context, prefix string) ([]string, error) {
+	keys, err := s.store.List(ctx, s.prefix+prefix)
+	if err != nil {
+		return nil, err
+	}
+	for i, key := range keys {
+		keys[i] = strings.TrimPrefix(key, s.prefix)
+	}
+	return keys, nil
+}
@@ -0,0 +1,199 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/tinle0301/streaming-platform-api/internal/awsauth"
+	"github.com/tinle0301/streaming-platform-api/internal/retention"
+)
+
+// maxPrefixLength bounds a channel's key prefix within its bucket
+const maxPrefixLength = 200
+
+// checkTimeout bounds a bucket check
+const checkTimeout = 15 * time.Second
+
+// probeObject is written under a channel's prefix to check its bucket
+const probeObject = ".streamhub-check"
+
+// Validation errors
+var (
+	ErrInvalidBucket      = errors.New("bucket name must be 3 to 63 lowercase letters, digits, dots, or hyphens")
+	ErrInvalidRegion      = errors.New("region must be an AWS region such as us-east-1")
+	ErrInvalidPrefix      = errors.New("prefix must be a relative path of up to 200 characters")
+	ErrMissingCredentials = errors.New("access key ID and secret access key are required")
+	ErrCheckFailed        = errors.New("storage check failed")
+)
+
+var (
+	bucketPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9.-]{1,61}[a-z0-9]$`)
+	regionPattern = regexp.MustCompile(`^[a-z]{2}(-[a-z]+)+-[0-9]$`)
+)
+
+// StoreFactory opens an object store on a channel's bucket
+type StoreFactory func(cfg *Config) retention.ObjectStore
+
+// S3Stores opens channels' buckets on S3 with their own credentials
+func S3Stores(cfg *Config) retention.ObjectStore {
+	return retention.NewS3StoreWithCredentials(cfg.Bucket, cfg.Region, awsauth.Credentials{
+		AccessKey: cfg.AccessKeyID,
+		SecretKey: cfg.SecretKey,
+	})
+}
+
+// Service configures and checks channels' own buckets
+type Service struct {
+	repo   Repository
+	cipher *Cipher
+	open   StoreFactory
+}
+
+// NewService creates a storage service; secrets are encrypted with cipher
+// and buckets opened with open
+func NewService(repo Repository, cipher *Cipher, open StoreFactory) *Service {
+	return &Service{repo: repo, cipher: cipher, open: open}
+}
+
+// Get returns a channel's configuration without its secret, or ErrNotFound
+// if it uses the platform's storage
+func (s *Service) Get(ctx context.Context, channelID string) (*Config, error) {
+	cfg, err := s.repo.Get(ctx, channelID)
+	if err != nil {
+		return nil, err
+	}
+	cfg.SecretKeyEncrypted = nil
+	return cfg, nil
+}
+
+// Configure checks that a channel's bucket can be written, read, and listed
+// with the given credentials, then saves it. A bucket that fails the check
+// is not saved; the error wraps ErrCheckFailed with the cause.
+func (s *Service) Configure(ctx context.Context, cfg *Config) (*Config, error) {
+	cfg.Bucket = strings.TrimSpace(cfg.Bucket)
+	cfg.Region = strings.TrimSpace(cfg.Region)
+	cfg.AccessKeyID = strings.TrimSpace(cfg.AccessKeyID)
+	prefix, err := normalizePrefix(cfg.Prefix)
+	if err != nil {
+		return nil, err
+	}
+	cfg.Prefix = prefix
+
+	switch {
+	case !bucketPattern.MatchString(cfg.Bucket) || strings.Contains(cfg.Bucket, ".."):
+		return nil, ErrInvalidBucket
+	case !regionPattern.MatchString(cfg.Region):
+		return nil, ErrInvalidRegion
+	case cfg.AccessKeyID == "" || cfg.SecretKey == "":
+		return nil, ErrMissingCredentials
+	}
+
+	if err := s.check(ctx, cfg); err != nil {
+		return nil, err
+	}
+
+	sealed, err := s.cipher.Encrypt(cfg.ChannelID, cfg.SecretKey)
+	if err != nil {
+		return nil, err
+	}
+	saved := *cfg
+	saved.SecretKey = ""
+	saved.SecretKeyEncrypted = sealed
+	saved.Status = StatusVerified
+	saved.LastError = ""
+	if err := s.repo.Save(ctx, &saved); err != nil {
+		return nil, err
+	}
+	saved.SecretKeyEncrypted = nil
+	return &saved, nil
+}
+
+// Check checks a channel's saved bucket again, e.g. after its credentials
+// were rotated, and records the result. Media isn't routed to a bucket
+// whose latest check failed.
+func (s *Service) Check(ctx context.Context, channelID string) (*Config, error) {
+	cfg, err := s.decrypted(ctx, channelID)
+	if err != nil {
+		return nil, err
+	}
+
+	status, lastError := StatusVerified, ""
+	if err := s.check(ctx, cfg); err != nil {
+		status, lastError = StatusFailed, err.Error()
+	}
+	checked, err := s.repo.SetStatus(ctx, channelID, status, lastError)
+	if err != nil {
+		return nil, err
+	}
+	checked.SecretKeyEncrypted = nil
+	return checked, nil
+}
+
+// Remove moves a channel back to the platform's storage. Media already in
+// the channel's bucket stays there.
+func (s *Service) Remove(ctx context.Context, channelID string) error {
+	return s.repo.Delete(ctx, channelID)
+}
+
+// decrypted loads a channel's configuration with its secret
+func (s *Service) decrypted(ctx context.Context, channelID string) (*Config, error) {
+	cfg, err := s.repo.Get(ctx, channelID)
+	if err != nil {
+		return nil, err
+	}
+	cfg.SecretKey, err = s.cipher.Decrypt(channelID, cfg.SecretKeyEncrypted)
+	if err != nil {
+		return nil, err
+	}
+	cfg.SecretKeyEncrypted = nil
+	return cfg, nil
+}
+
+// check writes a probe object under the channel's prefix, reads it back,
+// and lists it
+func (s *Service) check(ctx context.Context, cfg *Config) error {
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+
+	bucket := s.open(cfg)
+	key := cfg.Prefix + probeObject
+	probe := []byte("streamhub storage check " + time.Now().UTC().Format(time.RFC3339))
+
+	if err := bucket.Put(ctx, key, probe); err != nil {
+		return fmt.Errorf("%w: write: %v", ErrCheckFailed, err)
+	}
+	data, err := bucket.Get(ctx, key)
+	if err != nil {
+		return fmt.Errorf("%w: read: %v", ErrCheckFailed, err)
+	}
+	if !bytes.Equal(data, probe) {
+		return fmt.Errorf("%w: read back different content", ErrCheckFailed)
+	}
+	if _, err := bucket.List(ctx, key); err != nil {
+		return fmt.Errorf("%w: list: %v", ErrCheckFailed, err)
+	}
+	return nil
+}
+
+// normalizePrefix trims slashes from a key prefix and ends a non-empty one
+// with a single slash
+func normalizePrefix(prefix string) (string, error) {
+	prefix = strings.Trim(strings.TrimSpace(prefix), "/")
+	if prefix == "" {
+		return "", nil
+	}
+	if len(prefix) > maxPrefixLength {
+		return "", ErrInvalidPrefix
+	}
+	for _, part := range strings.Split(prefix, "/") {
+		if part == "" || part == "." || part == ".." {
+			return "", ErrInvalidPrefix
+		}
+	}
+	return prefix + "/", nil
+}
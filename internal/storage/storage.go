@@ -0,0 +1,182 @@
+// Package storage lets enterprise channels keep their VODs and clips in
+// their own S3 bucket. A channel's bucket and credentials are checked
+// before they are saved, the secret key is stored encrypted, and Router
+// sends each channel's media to its own bucket or the platform's.
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/tinle0301/streaming-platform-api/internal/store"
+	"github.com/tinle0301/streaming-platform-api/internal/tenancy"
+)
+
+// Check statuses, matching the GraphQL StorageStatus enum
+const (
+	StatusVerified = "VERIFIED"
+	StatusFailed   = "FAILED"
+)
+
+// Repository errors
+var (
+	ErrNotFound        = errors.New("channel storage not configured")
+	ErrChannelNotFound = errors.New("channel not found")
+)
+
+// Config is a channel's own bucket. SecretKey is only set once decrypted;
+// the repository reads and writes SecretKeyEncrypted.
+type Config struct {
+	ChannelID   string
+	Bucket      string
+	Region      string
+	Prefix      string
+	AccessKeyID string
+
+	SecretKey          string
+	SecretKeyEncrypted []byte
+
+	// Status and LastError record the latest check of the bucket
+	Status        string
+	LastError     string
+	LastCheckedAt time.Time
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// Repository persists channels' storage configuration. Configurations are
+// saved in the context's tenant, and other tenants' are treated as missing.
+type Repository interface {
+	// Get returns ErrNotFound if the channel uses the platform's storage
+	Get(ctx context.Context, channelID string) (*Config, error)
+
+	// Save creates or replaces a channel's configuration and fills in its
+	// timestamps
+	Save(ctx context.Context, cfg *Config) error
+
+	// SetStatus records the result of checking a channel's bucket
+	SetStatus(ctx context.Context, channelID, status, lastError string) (*Config, error)
+
+	Delete(ctx context.Context, channelID string) error
+}
+
+// configColumns is the column list shared by storage queries
+const configColumns = `channel_id::text, bucket, region, prefix, access_key_id, secret_key_encrypted,
+	status, COALESCE(last_error, ''), last_checked_at, created_at, updated_at`
+
+// PostgresRepository implements Repository on PostgreSQL
+type PostgresRepository struct {
+	pool *pgxpool.Pool
+}
+
+// NewPostgresRepository creates a storage repository on pool
+func NewPostgresRepository(pool *pgxpool.Pool) *PostgresRepository {
+	return &PostgresRepository{pool: pool}
+}
+
+// Get reads a channel's configuration
+func (r *PostgresRepository) Get(ctx context.Context, channelID string) (*Config, error) {
+	if !store.IsUUID(channelID) {
+		return nil, ErrNotFound
+	}
+
+	cfg, err := scanConfig(r.pool.QueryRow(ctx, `SELECT `+configColumns+` FROM channel_storage WHERE channel_id = $1`+
+		tenancy.Condition("tenant_id", 2), channelID, tenancy.Scope(ctx)))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get channel storage: %w", err)
+	}
+	return cfg, nil
+}
+
+// Save upserts a channel's configuration
+func (r *PostgresRepository) Save(ctx context.Context, cfg *Config) error {
+	if !store.IsUUID(cfg.ChannelID) {
+		return ErrChannelNotFound
+	}
+
+	err := r.pool.QueryRow(ctx, `
+		INSERT INTO channel_storage (channel_id, bucket, region, prefix, access_key_id, secret_key_encrypted, status, last_error,
+			tenant_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NULLIF($8, ''), $9)
+		ON CONFLICT (channel_id) DO UPDATE SET
+			bucket = EXCLUDED.bucket,
+			region = EXCLUDED.region,
+			prefix = EXCLUDED.prefix,
+			access_key_id = EXCLUDED.access_key_id,
+			secret_key_encrypted = EXCLUDED.secret_key_encrypted,
+			status = EXCLUDED.status,
+			last_error = EXCLUDED.last_error,
+			last_checked_at = NOW(),
+			updated_at = NOW()
+		WHERE channel_storage.tenant_id = EXCLUDED.tenant_id
+		RETURNING last_checked_at, created_at, updated_at`,
+		cfg.ChannelID, cfg.Bucket, cfg.Region, cfg.Prefix, cfg.AccessKeyID, cfg.SecretKeyEncrypted, cfg.Status, cfg.LastError,
+		tenancy.ID(ctx),
+	).Scan(&cfg.LastCheckedAt, &cfg.CreatedAt, &cfg.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		// Another tenant's channel
+		return ErrChannelNotFound
+	}
+
+	if store.PgCode(err) == "23503" {
+		return ErrChannelNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to save channel storage: %w", err)
+	}
+	return nil
+}
+
+// SetStatus records a check result
+func (r *PostgresRepository) SetStatus(ctx context.Context, channelID, status, lastError string) (*Config, error) {
+	if !store.IsUUID(channelID) {
+		return nil, ErrNotFound
+	}
+
+	cfg, err := scanConfig(r.pool.QueryRow(ctx, `
+		UPDATE channel_storage SET status = $2, last_error = NULLIF($3, ''), last_checked_at = NOW()
+		WHERE channel_id = $1`+tenancy.Condition("tenant_id", 4)+`
+		RETURNING `+configColumns, channelID, status, lastError, tenancy.Scope(ctx)))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update channel storage status: %w", err)
+	}
+	return cfg, nil
+}
+
+// Delete removes a channel's configuration
+func (r *PostgresRepository) Delete(ctx context.Context, channelID string) error {
+	if !store.IsUUID(channelID) {
+		return ErrNotFound
+	}
+
+	tag, err := r.pool.Exec(ctx, `DELETE FROM channel_storage WHERE channel_id = $1`+tenancy.Condition("tenant_id", 2),
+		channelID, tenancy.Scope(ctx))
+	if err != nil {
+		return fmt.Errorf("failed to delete channel storage: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// scanConfig scans configColumns
+func scanConfig(row pgx.Row) (*Config, error) {
+	var c Config
+	if err := row.Scan(&c.ChannelID, &c.Bucket, &c.Region, &c.Prefix, &c.AccessKeyID, &c.SecretKeyEncrypted,
+		&c.Status, &c.LastError, &c.LastCheckedAt, &c.CreatedAt, &c.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
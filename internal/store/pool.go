@@ -0,0 +1,105 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// PoolConfig sizes the PostgreSQL connection pool
+type PoolConfig struct {
+	MaxConns          int32
+	MinConns          int32
+	MaxConnLifetime   time.Duration
+	MaxConnIdleTime   time.Duration
+	HealthCheckPeriod time.Duration
+
+	// OnQuery, if set, is called with the duration of every query
+	OnQuery func(time.Duration)
+
+	// OnQuerySQL, if set, is called with every query's SQL and duration,
+	// and the context it ran in
+	OnQuerySQL func(ctx context.Context, sql string, elapsed time.Duration)
+}
+
+// DefaultPoolConfig returns pool settings suitable for a single API node
+func DefaultPoolConfig() PoolConfig {
+	return PoolConfig{
+		MaxConns:          20,
+		MinConns:          2,
+		MaxConnLifetime:   time.Hour,
+		MaxConnIdleTime:   30 * time.Minute,
+		HealthCheckPeriod: time.Minute,
+	}
+}
+
+// NewPool creates a connection pool for databaseURL. Connections are opened
+// lazily, so an unreachable database surfaces on first use rather than here.
+func NewPool(ctx context.Context, databaseURL string, cfg PoolConfig) (*pgxpool.Pool, error) {
+	poolConfig, err := pgxpool.ParseConfig(databaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse database URL: %w", err)
+	}
+
+	if cfg.MaxConns > 0 {
+		poolConfig.MaxConns = cfg.MaxConns
+	}
+	poolConfig.MinConns = cfg.MinConns
+	if cfg.MaxConnLifetime > 0 {
+		poolConfig.MaxConnLifetime = cfg.MaxConnLifetime
+	}
+	if cfg.MaxConnIdleTime > 0 {
+		poolConfig.MaxConnIdleTime = cfg.MaxConnIdleTime
+	}
+	if cfg.HealthCheckPeriod > 0 {
+		poolConfig.HealthCheckPeriod = cfg.HealthCheckPeriod
+	}
+
+	if cfg.OnQuery != nil || cfg.OnQuerySQL != nil {
+		poolConfig.ConnConfig.Tracer = &queryTimer{observe: cfg.OnQuery, observeSQL: cfg.OnQuerySQL}
+	}
+
+	pool, err := pgxpool.NewWithConfig(ctx, poolConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create connection pool: %w", err)
+	}
+	return pool, nil
+}
+
+// queryStartKey carries a query's start time and SQL through its context
+type queryStartKey struct{}
+
+// queryStart is when a query began and what it ran
+type queryStart struct {
+	at  time.Time
+	sql string
+}
+
+// queryTimer is a pgx tracer that reports query durations
+type queryTimer struct {
+	observe    func(time.Duration)
+	observeSQL func(context.Context, string, time.Duration)
+}
+
+// TraceQueryStart records when a query began
+func (t *queryTimer) TraceQueryStart(ctx context.Context, conn *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	return context.WithValue(ctx, queryStartKey{}, queryStart{at: time.Now(), sql: data.SQL})
+}
+
+// TraceQueryEnd reports how long the query took
+func (t *queryTimer) TraceQueryEnd(ctx context.Context, conn *pgx.Conn, data pgx.TraceQueryEndData) {
+	start, ok := ctx.Value(queryStartKey{}).(queryStart)
+	if !ok {
+		return
+	}
+	elapsed := time.Since(start.at)
+	if t.observe != nil {
+		t.observe(elapsed)
+	}
+	if t.observeSQL != nil {
+		t.observeSQL(ctx, start.sql, elapsed)
+	}
+}
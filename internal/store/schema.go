@@ -0,0 +1,108 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// Schema versions this build understands. Bump ExpectedSchemaVersion with
+// every new migration in ./migrations.
+const (
+	// ExpectedSchemaVersion is the migration version this code was written against
+	ExpectedSchemaVersion = 40
+
+	// ForwardCompatibleVersions is how many newer (additive) migrations this
+	// build tolerates, so old nodes keep serving while new nodes migrate
+	ForwardCompatibleVersions = 1
+
+	// MinReadOnlyVersion is the oldest schema this build can still read from
+	// (version 1 creates the streams table every read path depends on)
+	MinReadOnlyVersion = 1
+)
+
+// ServingMode describes how a node may serve against the current schema
+type ServingMode string
+
+// Serving modes
+const (
+	ModeReadWrite ServingMode = "read_write"
+	ModeReadOnly  ServingMode = "read_only"
+)
+
+// ErrSchemaIncompatible is returned when the database schema cannot be served
+var ErrSchemaIncompatible = errors.New("incompatible database schema")
+
+// SchemaStatus is the result of a schema compatibility check
+type SchemaStatus struct {
+	DatabaseVersion int64       `json:"database_version"`
+	ExpectedVersion int64       `json:"expected_version"`
+	Dirty           bool        `json:"dirty"`
+	Mode            ServingMode `json:"mode"`
+}
+
+// CheckSchema compares the migration version recorded by the migration
+// runner (golang-migrate's schema_migrations table) with this build.
+//
+// A newer schema within ForwardCompatibleVersions is served read-write; an
+// older schema not below MinReadOnlyVersion is served read-only until the
+// migration runs; anything else, or a dirty migration, is refused.
+func CheckSchema(ctx context.Context, db *sql.DB) (SchemaStatus, error) {
+	status := SchemaStatus{ExpectedVersion: ExpectedSchemaVersion}
+
+	version, dirty, err := currentVersion(ctx, db)
+	if err != nil {
+		return status, err
+	}
+	status.DatabaseVersion = version
+	status.Dirty = dirty
+
+	return status, evaluate(&status)
+}
+
+// evaluate sets the serving mode for a status
+func evaluate(status *SchemaStatus) error {
+	switch {
+	case status.Dirty:
+		return fmt.Errorf("%w: migration %d is dirty", ErrSchemaIncompatible, status.DatabaseVersion)
+	case status.DatabaseVersion == status.ExpectedVersion:
+		status.Mode = ModeReadWrite
+	case status.DatabaseVersion > status.ExpectedVersion:
+		if status.DatabaseVersion-status.ExpectedVersion > ForwardCompatibleVersions {
+			return fmt.Errorf("%w: database version %d is newer than supported %d",
+				ErrSchemaIncompatible, status.DatabaseVersion, status.ExpectedVersion+ForwardCompatibleVersions)
+		}
+		status.Mode = ModeReadWrite
+	default:
+		if status.DatabaseVersion < MinReadOnlyVersion {
+			return fmt.Errorf("%w: database version %d is older than minimum %d",
+				ErrSchemaIncompatible, status.DatabaseVersion, MinReadOnlyVersion)
+		}
+		status.Mode = ModeReadOnly
+	}
+	return nil
+}
+
+// currentVersion reads the migration runner's version; a missing table is version 0
+func currentVersion(ctx context.Context, db *sql.DB) (int64, bool, error) {
+	var exists bool
+	err := db.QueryRowContext(ctx, "SELECT to_regclass('schema_migrations') IS NOT NULL").Scan(&exists)
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to check schema_migrations: %w", err)
+	}
+	if !exists {
+		return 0, false, nil
+	}
+
+	var version int64
+	var dirty bool
+	err = db.QueryRowContext(ctx, "SELECT version, dirty FROM schema_migrations LIMIT 1").Scan(&version, &dirty)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to read schema version: %w", err)
+	}
+	return version, dirty, nil
+}
@@ -0,0 +1,399 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/tinle0301/streaming-platform-api/internal/tenancy"
+)
+
+// ErrNotFound is returned when a record does not exist
+var ErrNotFound = errors.New("not found")
+
+// ErrStatusConflict is returned when a stream is not in the status a
+// transition expected
+var ErrStatusConflict = errors.New("stream status changed")
+
+// Stream statuses
+const (
+	StreamStatusOffline  = "OFFLINE"
+	StreamStatusLive     = "LIVE"
+	StreamStatusStarting = "STARTING"
+	StreamStatusEnding   = "ENDING"
+	StreamStatusArchived = "ARCHIVED"
+)
+
+// Stream is a stored broadcast
+type Stream struct {
+	ID           string
+	StreamerID   string
+	Title        string
+	Description  string
+	Status       string
+	Category     string
+	Language     string
+	Tags         []string
+	IsMature     bool
+	ChatEnabled  bool
+	ViewerCount  int
+	ThumbnailURL string
+	StartedAt    *time.Time
+	EndedAt      *time.Time
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+
+	// SuggestedTags are proposed by the auto-tagger; the streamer's own
+	// Tags are never overwritten
+	SuggestedTags []string
+
+	// Branded content carries sponsorship disclosure metadata
+	BrandedContent bool
+	SponsorName    string
+	DisclosureType string
+
+	// TenantID is the white-label tenant the stream belongs to
+	TenantID string
+}
+
+// StreamFilter narrows a stream listing; zero values match everything
+type StreamFilter struct {
+	StreamerID string
+	Status     string
+	Category   string
+	Language   string
+	Tags       []string
+	AnyTags    []string
+	MinViewers *int
+	MaxViewers *int
+	Limit      int
+	Offset     int
+}
+
+// StreamRepository persists streams. Streams are created in the context's
+// tenant, and other tenants' streams are treated as missing.
+type StreamRepository interface {
+	Create(ctx context.Context, stream *Stream) error
+	Get(ctx context.Context, id string) (*Stream, error)
+	GetMany(ctx context.Context, ids []string) (map[string]*Stream, error)
+	List(ctx context.Context, filter StreamFilter) ([]*Stream, int, error)
+	Update(ctx context.Context, stream *Stream) error
+	Transition(ctx context.Context, id, from, to string) (*Stream, error)
+	Delete(ctx context.Context, id string) error
+	SetSuggestedTags(ctx context.Context, id string, tags []string) error
+}
+
+// streamColumns is the column list shared by stream queries
+const streamColumns = `id::text, streamer_id, title, COALESCE(description, ''), status,
+	COALESCE(category, ''), language, tags, is_mature, chat_enabled, viewer_count,
+	COALESCE(thumbnail_url, ''), started_at, ended_at, created_at, updated_at, suggested_tags,
+	branded_content, COALESCE(sponsor_name, ''), COALESCE(disclosure_type, ''), tenant_id`
+
+// tenantScope restricts a stream query to the context's tenant; n is the
+// placeholder number of the tenancy.Scope argument
+func tenantScope(n int) string {
+	return tenancy.Condition("tenant_id", n)
+}
+
+// PostgresStreamRepository implements StreamRepository on PostgreSQL
+type PostgresStreamRepository struct {
+	pool *pgxpool.Pool
+}
+
+// NewPostgresStreamRepository creates a stream repository on pool
+func NewPostgresStreamRepository(pool *pgxpool.Pool) *PostgresStreamRepository {
+	return &PostgresStreamRepository{pool: pool}
+}
+
+// Create inserts a stream and fills in its generated ID and timestamps
+func (r *PostgresStreamRepository) Create(ctx context.Context, stream *Stream) error {
+	if stream.Status == "" {
+		stream.Status = StreamStatusOffline
+	}
+	if stream.Language == "" {
+		stream.Language = "en"
+	}
+	if stream.Tags == nil {
+		stream.Tags = []string{}
+	}
+
+	err := r.pool.QueryRow(ctx, `
+		INSERT INTO streams (streamer_id, title, description, status, category, language,
+			tags, is_mature, chat_enabled, viewer_count, thumbnail_url, started_at, ended_at,
+			branded_content, sponsor_name, disclosure_type, tenant_id)
+		VALUES ($1, $2, NULLIF($3, ''), $4, NULLIF($5, ''), $6, $7, $8, $9, $10, NULLIF($11, ''), $12, $13,
+			$14, NULLIF($15, ''), NULLIF($16, ''), $17)
+		RETURNING id::text, created_at, updated_at`,
+		stream.StreamerID, stream.Title, stream.Description, stream.Status, stream.Category,
+		stream.Language, stream.Tags, stream.IsMature, stream.ChatEnabled, stream.ViewerCount,
+		stream.ThumbnailURL, stream.StartedAt, stream.EndedAt,
+		stream.BrandedContent, stream.SponsorName, stream.DisclosureType, tenancy.ID(ctx),
+	).Scan(&stream.ID, &stream.CreatedAt, &stream.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create stream: %w", err)
+	}
+	stream.TenantID = tenancy.ID(ctx)
+	return nil
+}
+
+// Get returns a stream by ID
+func (r *PostgresStreamRepository) Get(ctx context.Context, id string) (*Stream, error) {
+	if !IsUUID(id) {
+		return nil, ErrNotFound
+	}
+
+	row := r.pool.QueryRow(ctx, `SELECT `+streamColumns+` FROM streams WHERE id = $1`+tenantScope(2), id, tenancy.Scope(ctx))
+
+	stream, err := scanStream(row)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stream: %w", err)
+	}
+	return stream, nil
+}
+
+// GetMany returns the streams with the given IDs, keyed by ID; unknown IDs
+// are left out
+func (r *PostgresStreamRepository) GetMany(ctx context.Context, ids []string) (map[string]*Stream, error) {
+	streams := make(map[string]*Stream, len(ids))
+	valid := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if IsUUID(id) {
+			valid = append(valid, id)
+		}
+	}
+	if len(valid) == 0 {
+		return streams, nil
+	}
+
+	rows, err := r.pool.Query(ctx, `SELECT `+streamColumns+` FROM streams WHERE id = ANY($1::uuid[])`+tenantScope(2),
+		valid, tenancy.Scope(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get streams: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		stream, err := scanStream(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan stream: %w", err)
+		}
+		streams[stream.ID] = stream
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get streams: %w", err)
+	}
+	return streams, nil
+}
+
+// List returns a page of streams matching filter, most-watched first, and
+// the total number of matches
+func (r *PostgresStreamRepository) List(ctx context.Context, filter StreamFilter) ([]*Stream, int, error) {
+	var conditions []string
+	var args []interface{}
+	addCondition := func(condition string, value interface{}) {
+		args = append(args, value)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	if tenantID, ok := tenancy.FromContext(ctx); ok {
+		addCondition("tenant_id = $%d", tenantID)
+	}
+	if filter.StreamerID != "" {
+		addCondition("streamer_id = $%d", filter.StreamerID)
+	}
+	if filter.Status != "" {
+		addCondition("status = $%d", filter.Status)
+	}
+	if filter.Category != "" {
+		addCondition("category = $%d", filter.Category)
+	}
+	if filter.Language != "" {
+		addCondition("language = $%d", filter.Language)
+	}
+	if len(filter.Tags) > 0 {
+		addCondition("tags @> $%d", filter.Tags)
+	}
+	if len(filter.AnyTags) > 0 {
+		addCondition("tags && $%d", filter.AnyTags)
+	}
+	if filter.MinViewers != nil {
+		addCondition("viewer_count >= $%d", *filter.MinViewers)
+	}
+	if filter.MaxViewers != nil {
+		addCondition("viewer_count <= $%d", *filter.MaxViewers)
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	var total int
+	if err := r.pool.QueryRow(ctx, `SELECT COUNT(*) FROM streams`+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count streams: %w", err)
+	}
+
+	args = append(args, filter.Limit, filter.Offset)
+	query := fmt.Sprintf(`SELECT %s FROM streams%s ORDER BY viewer_count DESC, created_at DESC, id LIMIT $%d OFFSET $%d`,
+		streamColumns, where, len(args)-1, len(args))
+
+	rows, err := r.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list streams: %w", err)
+	}
+	defer rows.Close()
+
+	streams := make([]*Stream, 0, filter.Limit)
+	for rows.Next() {
+		stream, err := scanStream(rows)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan stream: %w", err)
+		}
+		streams = append(streams, stream)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("failed to list streams: %w", err)
+	}
+
+	return streams, total, nil
+}
+
+// Update saves all mutable fields of a stream
+func (r *PostgresStreamRepository) Update(ctx context.Context, stream *Stream) error {
+	if !IsUUID(stream.ID) {
+		return ErrNotFound
+	}
+	if stream.Tags == nil {
+		stream.Tags = []string{}
+	}
+
+	err := r.pool.QueryRow(ctx, `
+		UPDATE streams SET
+			title = $2, description = NULLIF($3, ''), status = $4, category = NULLIF($5, ''),
+			language = $6, tags = $7, is_mature = $8, chat_enabled = $9, viewer_count = $10,
+			thumbnail_url = NULLIF($11, ''), started_at = $12, ended_at = $13,
+			branded_content = $14, sponsor_name = NULLIF($15, ''), disclosure_type = NULLIF($16, ''),
+			updated_at = NOW()
+		WHERE id = $1`+tenantScope(17)+`
+		RETURNING updated_at`,
+		stream.ID, stream.Title, stream.Description, stream.Status, stream.Category,
+		stream.Language, stream.Tags, stream.IsMature, stream.ChatEnabled, stream.ViewerCount,
+		stream.ThumbnailURL, stream.StartedAt, stream.EndedAt,
+		stream.BrandedContent, stream.SponsorName, stream.DisclosureType, tenancy.Scope(ctx),
+	).Scan(&stream.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update stream: %w", err)
+	}
+	return nil
+}
+
+// Transition atomically moves a stream from one status to another. Going
+// live stamps started_at and clears ended_at; any other transition stamps
+// ended_at and resets the viewer count. It returns ErrStatusConflict if the
+// stream is not in status from.
+func (r *PostgresStreamRepository) Transition(ctx context.Context, id, from, to string) (*Stream, error) {
+	if !IsUUID(id) {
+		return nil, ErrNotFound
+	}
+
+	row := r.pool.QueryRow(ctx, `
+		UPDATE streams SET
+			status = $3::text,
+			started_at = CASE WHEN $3::text = 'LIVE' THEN NOW() ELSE started_at END,
+			ended_at = CASE WHEN $3::text = 'LIVE' THEN NULL ELSE NOW() END,
+			viewer_count = CASE WHEN $3::text = 'LIVE' THEN viewer_count ELSE 0 END,
+			updated_at = NOW()
+		WHERE id = $1 AND status = $2`+tenantScope(4)+`
+		RETURNING `+streamColumns, id, from, to, tenancy.Scope(ctx))
+
+	stream, err := scanStream(row)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrStatusConflict
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to transition stream: %w", err)
+	}
+	return stream, nil
+}
+
+// Delete removes a stream
+func (r *PostgresStreamRepository) Delete(ctx context.Context, id string) error {
+	if !IsUUID(id) {
+		return ErrNotFound
+	}
+
+	tag, err := r.pool.Exec(ctx, `DELETE FROM streams WHERE id = $1`+tenantScope(2), id, tenancy.Scope(ctx))
+	if err != nil {
+		return fmt.Errorf("failed to delete stream: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// SetSuggestedTags replaces a stream's auto-tagger suggestions without
+// touching its other fields
+func (r *PostgresStreamRepository) SetSuggestedTags(ctx context.Context, id string, tags []string) error {
+	if !IsUUID(id) {
+		return ErrNotFound
+	}
+	if tags == nil {
+		tags = []string{}
+	}
+
+	tag, err := r.pool.Exec(ctx, `UPDATE streams SET suggested_tags = $2 WHERE id = $1`+tenantScope(3),
+		id, tags, tenancy.Scope(ctx))
+	if err != nil {
+		return fmt.Errorf("failed to set suggested tags: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// scanStream reads a row selected with streamColumns
+func scanStream(row pgx.Row) (*Stream, error) {
+	var stream Stream
+	err := row.Scan(
+		&stream.ID, &stream.StreamerID, &stream.Title, &stream.Description, &stream.Status,
+		&stream.Category, &stream.Language, &stream.Tags, &stream.IsMature, &stream.ChatEnabled,
+		&stream.ViewerCount, &stream.ThumbnailURL, &stream.StartedAt, &stream.EndedAt,
+		&stream.CreatedAt, &stream.UpdatedAt, &stream.SuggestedTags,
+		&stream.BrandedContent, &stream.SponsorName, &stream.DisclosureType, &stream.TenantID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &stream, nil
+}
+
+// IsUUID reports whether id is a canonical UUID, so malformed IDs can be
+// treated as missing instead of failing the query
+func IsUUID(id string) bool {
+	if len(id) != 36 {
+		return false
+	}
+	for i, c := range id {
+		switch {
+		case i == 8 || i == 13 || i == 18 || i == 23:
+			if c != '-' {
+				return false
+			}
+		case (c < '0' || c > '9') && (c < 'a' || c > 'f') && (c < 'A' || c > 'F'):
+			return false
+		}
+	}
+	return true
+}
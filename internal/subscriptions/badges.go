@@ -0,0 +1,185 @@
+package subscriptions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/tinle0301/streaming-platform-api/internal/tenancy"
+)
+
+const (
+	// subscribersKeyPrefix namespaces each channel's subscriber badges
+	subscribersKeyPrefix = "subscribers:"
+
+	// streamChannelKeyPrefix namespaces the channel each live stream belongs to
+	streamChannelKeyPrefix = "subscribers:stream:"
+
+	// streamChannelTTL is how long a stream's channel is remembered after it
+	// went live
+	streamChannelTTL = 48 * time.Hour
+)
+
+// Badge is the chat badge of a subscription tier
+type Badge struct {
+	ID       string
+	Name     string
+	ImageURL string
+}
+
+// BadgeFor returns the badge of a tier
+func BadgeFor(tier string) Badge {
+	n := strings.TrimPrefix(tier, "TIER_")
+	id := "subscriber-tier-" + n
+	return Badge{ID: id, Name: "Tier " + n + " Subscriber", ImageURL: "/badges/" + id + ".png"}
+}
+
+// BadgeStore shares subscribers' badges with the WebSocket servers, which
+// add them to chat. Each channel's subscribers are a hash of subscriber ID
+// to "<tier>:<expiry unix ms>".
+type BadgeStore struct {
+	client *redis.Client
+}
+
+// NewBadgeStore creates a badge store on client
+func NewBadgeStore(client *redis.Client) *BadgeStore {
+	return &BadgeStore{client: client}
+}
+
+// Save records a subscription's badge until it expires
+func (s *BadgeStore) Save(ctx context.Context, sub *Subscription) error {
+	value := sub.Tier + ":" + strconv.FormatInt(sub.ExpiresAt.UnixMilli(), 10)
+	if err := s.client.HSet(ctx, subscribersKeyPrefix+sub.ChannelID, sub.SubscriberID, value).Err(); err != nil {
+		return fmt.Errorf("failed to save subscriber badge: %w", err)
+	}
+	return nil
+}
+
+// SetStreamChannel records the channel a live stream belongs to
+func (s *BadgeStore) SetStreamChannel(ctx context.Context, streamID, channelID string) error {
+	if err := s.client.Set(ctx, streamChannelKeyPrefix+streamID, channelID, streamChannelTTL).Err(); err != nil {
+		return fmt.Errorf("failed to save stream channel: %w", err)
+	}
+	return nil
+}
+
+// badgeEntry is a subscriber's tier and when it expires
+type badgeEntry struct {
+	tier      string
+	expiresAt time.Time
+}
+
+// BadgeCache keeps the subscriber badges of rooms with recent chat on a
+// WebSocket node. Lookups only read memory: a room's badges are loaded on
+// the refresh after its first lookup.
+type BadgeCache struct {
+	client *redis.Client
+
+	mu sync.Mutex
+	// room -> subscriber ID -> badge, and the rooms looked up since the
+	// last refresh
+	rooms  map[string]map[string]badgeEntry
+	recent map[string]bool
+}
+
+// NewBadgeCache creates a badge cache reading from client
+func NewBadgeCache(client *redis.Client) *BadgeCache {
+	return &BadgeCache{
+		client: client,
+		rooms:  make(map[string]map[string]badgeEntry),
+		recent: make(map[string]bool),
+	}
+}
+
+// SubscriberTier returns the tier of a user's active subscription to the
+// channel streaming in room, or "" if they have none
+func (c *BadgeCache) SubscriberTier(room, userID string) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.recent[room] = true
+	entry, ok := c.rooms[room][userID]
+	if !ok || !time.Now().Before(entry.expiresAt) {
+		return ""
+	}
+	return entry.tier
+}
+
+// Run reloads the badges of rooms looked up since the last refresh every
+// interval until ctx is cancelled, dropping the other rooms
+func (c *BadgeCache) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			c.refresh(ctx)
+		}
+	}
+}
+
+// refresh reloads the recently looked up rooms
+func (c *BadgeCache) refresh(ctx context.Context) {
+	c.mu.Lock()
+	recent := c.recent
+	c.recent = make(map[string]bool)
+	c.mu.Unlock()
+
+	rooms := make(map[string]map[string]badgeEntry, len(recent))
+	for room := range recent {
+		badges, err := c.load(ctx, room)
+		if err != nil {
+			log.Printf("Error loading subscriber badges: room=%s, err=%v", room, err)
+			c.mu.Lock()
+			badges = c.rooms[room]
+			c.mu.Unlock()
+		}
+		rooms[room] = badges
+	}
+
+	c.mu.Lock()
+	c.rooms = rooms
+	c.mu.Unlock()
+}
+
+// load reads the badges of the channel streaming in room
+func (c *BadgeCache) load(ctx context.Context, room string) (map[string]badgeEntry, error) {
+	_, streamID := tenancy.SplitRoom(room)
+	channelID, err := c.client.Get(ctx, streamChannelKeyPrefix+streamID).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	values, err := c.client.HGetAll(ctx, subscribersKeyPrefix+channelID).Result()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	badges := make(map[string]badgeEntry, len(values))
+	for subscriberID, value := range values {
+		tier, expiresPart, ok := strings.Cut(value, ":")
+		if !ok {
+			continue
+		}
+		ms, err := strconv.ParseInt(expiresPart, 10, 64)
+		if err != nil {
+			continue
+		}
+		if expiresAt := time.UnixMilli(ms); now.Before(expiresAt) {
+			badges[subscriberID] = badgeEntry{tier: tier, expiresAt: expiresAt}
+		}
+	}
+	return badges, nil
+}
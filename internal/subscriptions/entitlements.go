@@ -0,0 +1,78 @@
+package subscriptions
+
+import (
+	"context"
+	"errors"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Payment errors
+var (
+	ErrPaymentRequired     = errors.New("a verified payment is required")
+	ErrEntitlementRedeemed = errors.New("payment has already been redeemed")
+)
+
+// Entitlement is a verified payment for one subscription or gift. The
+// payment provider issues it once the viewer has been charged.
+type Entitlement struct {
+	// ID identifies the payment; each is redeemed once
+	ID string
+
+	BuyerID   string
+	ChannelID string
+	Tier      string
+
+	// RecipientID is the viewer a gift is for; "" pays for the buyer's own
+	// subscription
+	RecipientID string
+}
+
+// EntitlementVerifier verifies the payment tokens clients pass to subscribe
+// and giftSubscription
+type EntitlementVerifier interface {
+	// Verify returns the entitlement token pays for, or ErrPaymentRequired
+	Verify(ctx context.Context, token string) (*Entitlement, error)
+}
+
+// entitlementClaims are the claims of a payment token: the subject is the
+// buyer and the ID is the payment's
+type entitlementClaims struct {
+	ChannelID   string `json:"channel_id"`
+	Tier        string `json:"tier"`
+	RecipientID string `json:"recipient_id,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// SignedEntitlements verifies payment tokens signed with HS256 by the payment
+// provider, using a secret shared with it
+type SignedEntitlements struct {
+	secret []byte
+}
+
+// NewSignedEntitlements creates a verifier of tokens signed with secret
+func NewSignedEntitlements(secret string) *SignedEntitlements {
+	return &SignedEntitlements{secret: []byte(secret)}
+}
+
+// Verify checks a token's signature and expiry and returns its entitlement
+func (s *SignedEntitlements) Verify(ctx context.Context, token string) (*Entitlement, error) {
+	var claims entitlementClaims
+	_, err := jwt.ParseWithClaims(token, &claims, func(token *jwt.Token) (interface{}, error) {
+		return s.secret, nil
+	},
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil || claims.ID == "" || claims.Subject == "" || claims.ChannelID == "" {
+		return nil, ErrPaymentRequired
+	}
+
+	return &Entitlement{
+		ID:          claims.ID,
+		BuyerID:     claims.Subject,
+		ChannelID:   claims.ChannelID,
+		Tier:        claims.Tier,
+		RecipientID: claims.RecipientID,
+	}, nil
+}
@@ -0,0 +1,116 @@
+package subscriptions
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/tinle0301/streaming-platform-api/internal/users"
+)
+
+// redeemingRepository records subscriptions and redeemed entitlements; only
+// Subscribe and Gift are used
+type redeemingRepository struct {
+	Repository
+	redeemed map[string]bool
+}
+
+func (r *redeemingRepository) Subscribe(ctx context.Context, channelID, subscriberID, tier, entitlementID string) (*Subscription, error) {
+	if r.redeemed[entitlementID] {
+		return nil, ErrEntitlementRedeemed
+	}
+	r.redeemed[entitlementID] = true
+	return &Subscription{ChannelID: channelID, SubscriberID: subscriberID, Tier: tier}, nil
+}
+
+func (r *redeemingRepository) Gift(ctx context.Context, channelID, recipientID, gifterID, tier, entitlementID string) (*Subscription, error) {
+	if r.redeemed[entitlementID] {
+		return nil, ErrEntitlementRedeemed
+	}
+	r.redeemed[entitlementID] = true
+	return &Subscription{ChannelID: channelID, SubscriberID: recipientID, Tier: tier, GiftedBy: gifterID}, nil
+}
+
+// noProfiles finds no users
+type noProfiles struct{}
+
+func (noProfiles) GetMany(ctx context.Context, ids []string) (map[string]*users.User, error) {
+	return map[string]*users.User{}, nil
+}
+
+func signEntitlement(t *testing.T, secret string, claims entitlementClaims) string {
+	t.Helper()
+	if claims.ExpiresAt == nil {
+		claims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(time.Hour))
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(secret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func TestSubscribeRequiresPayment(t *testing.T) {
+	repo := &redeemingRepository{redeemed: make(map[string]bool)}
+	service := NewService(repo, noProfiles{})
+	ctx := context.Background()
+	paid := signEntitlement(t, "secret", entitlementClaims{
+		ChannelID:        "channel",
+		Tier:             Tier2,
+		RegisteredClaims: jwt.RegisteredClaims{ID: "payment-1", Subject: "alice"},
+	})
+
+	if _, err := service.Subscribe(ctx, "channel", "alice", Tier2, paid); !errors.Is(err, ErrPaymentRequired) {
+		t.Fatalf("Subscribe without a verifier = %v, want ErrPaymentRequired", err)
+	}
+	service.SetEntitlements(NewSignedEntitlements("secret"))
+
+	tests := []struct {
+		name         string
+		subscriberID string
+		tier         string
+		token        string
+	}{
+		{"no token", "alice", Tier2, ""},
+		{"forged", "alice", Tier2, signEntitlement(t, "forged", entitlementClaims{
+			ChannelID: "channel", Tier: Tier2, RegisteredClaims: jwt.RegisteredClaims{ID: "payment-2", Subject: "alice"},
+		})},
+		{"expired", "alice", Tier2, signEntitlement(t, "secret", entitlementClaims{
+			ChannelID: "channel", Tier: Tier2, RegisteredClaims: jwt.RegisteredClaims{
+				ID: "payment-3", Subject: "alice", ExpiresAt: jwt.NewNumericDate(time.Now().Add(-time.Minute)),
+			},
+		})},
+		{"another buyer", "bob", Tier2, paid},
+		{"higher tier", "alice", Tier3, paid},
+		{"gift token", "alice", Tier2, signEntitlement(t, "secret", entitlementClaims{
+			ChannelID: "channel", Tier: Tier2, RecipientID: "bob", RegisteredClaims: jwt.RegisteredClaims{ID: "payment-4", Subject: "alice"},
+		})},
+	}
+	for _, tt := range tests {
+		if _, err := service.Subscribe(ctx, "channel", tt.subscriberID, tt.tier, tt.token); !errors.Is(err, ErrPaymentRequired) {
+			t.Errorf("Subscribe with %s = %v, want ErrPaymentRequired", tt.name, err)
+		}
+	}
+	if len(repo.redeemed) != 0 {
+		t.Fatalf("unpaid subscriptions were written: %v", repo.redeemed)
+	}
+
+	if _, err := service.Subscribe(ctx, "channel", "alice", Tier2, paid); err != nil {
+		t.Fatalf("Subscribe with a payment = %v", err)
+	}
+	if _, err := service.Subscribe(ctx, "channel", "alice", Tier2, paid); !errors.Is(err, ErrEntitlementRedeemed) {
+		t.Errorf("Subscribe with a redeemed payment = %v, want ErrEntitlementRedeemed", err)
+	}
+
+	gift := signEntitlement(t, "secret", entitlementClaims{
+		ChannelID: "channel", Tier: Tier1, RecipientID: "bob", RegisteredClaims: jwt.RegisteredClaims{ID: "payment-5", Subject: "alice"},
+	})
+	if _, err := service.Gift(ctx, "channel", "carol", "alice", Tier1, gift); !errors.Is(err, ErrPaymentRequired) {
+		t.Errorf("Gift to another recipient = %v, want ErrPaymentRequired", err)
+	}
+	if _, err := service.Gift(ctx, "channel", "bob", "alice", Tier1, gift); err != nil {
+		t.Errorf("Gift with a payment = %v", err)
+	}
+}
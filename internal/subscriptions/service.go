@@ -0,0 +1,264 @@
+package subscriptions
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/tinle0301/streaming-platform-api/internal/events"
+	"github.com/tinle0301/streaming-platform-api/internal/users"
+)
+
+// renewBatch bounds the subscriptions renewed per query
+const renewBatch = 500
+
+// Validation errors
+var (
+	ErrInvalidTier   = errors.New("tier must be TIER_1, TIER_2, or TIER_3")
+	ErrSelfSubscribe = errors.New("channels cannot subscribe to themselves")
+	ErrSelfGift      = errors.New("use subscribe to subscribe yourself")
+)
+
+// Profiles looks up the users named in subscription events
+type Profiles interface {
+	GetMany(ctx context.Context, ids []string) (map[string]*users.User, error)
+}
+
+// Service subscribes viewers to channels, renews their subscriptions, and
+// keeps subscriber badges current
+type Service struct {
+	repo     Repository
+	profiles Profiles
+
+	publisher    events.Publisher
+	badges       *BadgeStore
+	entitlements EntitlementVerifier
+}
+
+// NewService creates a subscription service; profiles name the users in
+// published events
+func NewService(repo Repository, profiles Profiles) *Service {
+	return &Service{repo: repo, profiles: profiles}
+}
+
+// SetPublisher enables subscription.new and subscription.gift events
+func (s *Service) SetPublisher(publisher events.Publisher) {
+	s.publisher = publisher
+}
+
+// SetEntitlements verifies the payments subscriptions and gifts are bought
+// with; without a verifier nothing can be bought
+func (s *Service) SetEntitlements(verifier EntitlementVerifier) {
+	s.entitlements = verifier
+}
+
+// SetBadges keeps subscriber badges for the WebSocket servers' chat in badges
+func (s *Service) SetBadges(badges *BadgeStore) {
+	s.badges = badges
+}
+
+// Get returns a viewer's subscription to a channel, active or lapsed
+func (s *Service) Get(ctx context.Context, channelID, subscriberID string) (*Subscription, error) {
+	return s.repo.Get(ctx, channelID, subscriberID)
+}
+
+// CountMany returns the active subscribers of each channel
+func (s *Service) CountMany(ctx context.Context, channelIDs []string) (map[string]int, error) {
+	return s.repo.CountMany(ctx, channelIDs)
+}
+
+// Subscribe subscribes a viewer to a channel at tier, paid for by
+// paymentToken
+func (s *Service) Subscribe(ctx context.Context, channelID, subscriberID, tier, paymentToken string) (*Subscription, error) {
+	if !validTier(tier) {
+		return nil, ErrInvalidTier
+	}
+	if channelID == subscriberID {
+		return nil, ErrSelfSubscribe
+	}
+	entitlement, err := s.verifyPayment(ctx, paymentToken, Entitlement{
+		BuyerID: subscriberID, ChannelID: channelID, Tier: tier,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sub, err := s.repo.Subscribe(ctx, channelID, subscriberID, tier, entitlement.ID)
+	if err != nil {
+		return nil, err
+	}
+	s.saveBadge(ctx, sub)
+
+	profiles := s.lookup(ctx, subscriberID)
+	s.publish(ctx, events.NewSubscriptionEvent(channelID, map[string]interface{}{
+		"subscription_id":         sub.ID,
+		"subscriber_id":           subscriberID,
+		"subscriber_username":     username(profiles[subscriberID]),
+		"subscriber_display_name": displayName(profiles[subscriberID]),
+		"tier":                    sub.Tier,
+		"months":                  sub.Months,
+	}))
+	return sub, nil
+}
+
+// Gift gifts a Period of a channel's subscription at tier to recipientID,
+// paid for by paymentToken
+func (s *Service) Gift(ctx context.Context, channelID, recipientID, gifterID, tier, paymentToken string) (*Subscription, error) {
+	if !validTier(tier) {
+		return nil, ErrInvalidTier
+	}
+	if recipientID == gifterID {
+		return nil, ErrSelfGift
+	}
+	if channelID == recipientID {
+		return nil, ErrSelfSubscribe
+	}
+	entitlement, err := s.verifyPayment(ctx, paymentToken, Entitlement{
+		BuyerID: gifterID, ChannelID: channelID, Tier: tier, RecipientID: recipientID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sub, err := s.repo.Gift(ctx, channelID, recipientID, gifterID, tier, entitlement.ID)
+	if err != nil {
+		return nil, err
+	}
+	s.saveBadge(ctx, sub)
+
+	profiles := s.lookup(ctx, gifterID, recipientID)
+	s.publish(ctx, events.NewGiftSubscriptionEvent(channelID, map[string]interface{}{
+		"subscription_id":        sub.ID,
+		"gifter_id":              gifterID,
+		"gifter_username":        username(profiles[gifterID]),
+		"gifter_display_name":    displayName(profiles[gifterID]),
+		"recipient_id":           recipientID,
+		"recipient_username":     username(profiles[recipientID]),
+		"recipient_display_name": displayName(profiles[recipientID]),
+		"tier":                   tier,
+		"count":                  1,
+	}))
+	return sub, nil
+}
+
+// Cancel stops a viewer's subscription from renewing
+func (s *Service) Cancel(ctx context.Context, channelID, subscriberID string) (*Subscription, error) {
+	return s.repo.Cancel(ctx, channelID, subscriberID)
+}
+
+// Run renews due subscriptions every interval until ctx is cancelled
+func (s *Service) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.renewDue(ctx)
+		}
+	}
+}
+
+// renewDue renews every due subscription, a batch at a time
+func (s *Service) renewDue(ctx context.Context) {
+	for {
+		renewed, err := s.repo.RenewDue(ctx, renewBatch)
+		if err != nil {
+			log.Printf("Error renewing subscriptions: %v", err)
+			return
+		}
+		for _, sub := range renewed {
+			s.saveBadge(ctx, sub)
+		}
+		if len(renewed) < renewBatch {
+			return
+		}
+	}
+}
+
+// HandleEvent records which channel a stream that went live belongs to, so
+// its chat shows the channel's subscriber badges
+func (s *Service) HandleEvent(ctx context.Context, event events.Event) error {
+	if s.badges == nil || event.Type != events.EventTypeStreamLive || event.StreamID == "" || event.UserID == "" {
+		return nil
+	}
+	return s.badges.SetStreamChannel(ctx, event.StreamID, event.UserID)
+}
+
+// verifyPayment verifies paymentToken and checks it pays for want, ignoring
+// its ID
+func (s *Service) verifyPayment(ctx context.Context, paymentToken string, want Entitlement) (*Entitlement, error) {
+	if s.entitlements == nil || paymentToken == "" {
+		return nil, ErrPaymentRequired
+	}
+	entitlement, err := s.entitlements.Verify(ctx, paymentToken)
+	if err != nil {
+		return nil, err
+	}
+
+	want.ID = entitlement.ID
+	if *entitlement != want {
+		return nil, ErrPaymentRequired
+	}
+	return entitlement, nil
+}
+
+// saveBadge updates a subscriber's badge; chat falls back to no badge if
+// it can't be saved
+func (s *Service) saveBadge(ctx context.Context, sub *Subscription) {
+	if s.badges == nil {
+		return
+	}
+	if err := s.badges.Save(ctx, sub); err != nil {
+		log.Printf("Error saving subscriber badge: channel=%s, subscriber=%s, err=%v", sub.ChannelID, sub.SubscriberID, err)
+	}
+}
+
+// lookup loads the named users for an event; events still go out with IDs
+// only if the lookup fails
+func (s *Service) lookup(ctx context.Context, ids ...string) map[string]*users.User {
+	found, err := s.profiles.GetMany(ctx, ids)
+	if err != nil {
+		log.Printf("Error loading subscription event users: %v", err)
+		return map[string]*users.User{}
+	}
+	return found
+}
+
+// publish publishes a subscription event
+func (s *Service) publish(ctx context.Context, event events.Event) {
+	if s.publisher == nil {
+		return
+	}
+	if err := s.publisher.Publish(ctx, event); err != nil {
+		log.Printf("Error publishing subscription event: type=%s, channel=%s, err=%v", event.Type, event.UserID, err)
+	}
+}
+
+// validTier reports whether tier is a subscription tier
+func validTier(tier string) bool {
+	switch tier {
+	case Tier1, Tier2, Tier3:
+		return true
+	}
+	return false
+}
+
+// username returns a user's username, or "" for a missing user
+func username(u *users.User) string {
+	if u == nil {
+		return ""
+	}
+	return u.Username
+}
+
+// displayName returns a user's display name, or "" for a missing user
+func displayName(u *users.User) string {
+	if u == nil {
+		return ""
+	}
+	return u.DisplayName
+}
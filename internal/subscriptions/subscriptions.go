@@ -0,0 +1,316 @@
+// Package subscriptions manages viewers' paid subscriptions to channels.
+// A subscription runs for a Period and renews unless it is cancelled;
+// gifted subscriptions run for one Period and extend an active one.
+// Subscribers get a badge of their tier in the channel's chat.
+package subscriptions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/tinle0301/streaming-platform-api/internal/store"
+	"github.com/tinle0301/streaming-platform-api/internal/tenancy"
+)
+
+// Period is how long a subscription runs before it renews or expires
+const Period = 30 * 24 * time.Hour
+
+// Subscription tiers, matching the GraphQL SubscriptionTier enum
+const (
+	Tier1 = "TIER_1"
+	Tier2 = "TIER_2"
+	Tier3 = "TIER_3"
+)
+
+// Repository errors
+var (
+	ErrNotFound          = errors.New("subscription not found")
+	ErrChannelNotFound   = errors.New("channel not found")
+	ErrAlreadySubscribed = errors.New("already subscribed to this channel")
+)
+
+// Subscription is a viewer's subscription to a channel
+type Subscription struct {
+	ID           string
+	ChannelID    string
+	SubscriberID string
+	Tier         string
+	AutoRenew    bool
+	StartedAt    time.Time
+	ExpiresAt    time.Time
+
+	// GiftedBy is the user who gifted the current period, if it was gifted
+	GiftedBy string
+
+	// Months counts every period the viewer has been subscribed
+	Months int
+}
+
+// Active reports whether the subscription has not expired
+func (s *Subscription) Active() bool {
+	return time.Now().Before(s.ExpiresAt)
+}
+
+// Repository persists subscriptions. Subscriptions are started in the
+// context's tenant, and other tenants' subscriptions are treated as missing.
+type Repository interface {
+	// Get returns a viewer's subscription to a channel, active or lapsed
+	Get(ctx context.Context, channelID, subscriberID string) (*Subscription, error)
+
+	// Subscribe starts a renewing subscription, reusing a lapsed one, and
+	// redeems the entitlement paying for it. It fails with
+	// ErrAlreadySubscribed while one is active, and with
+	// ErrEntitlementRedeemed if the entitlement was redeemed before.
+	Subscribe(ctx context.Context, channelID, subscriberID, tier, entitlementID string) (*Subscription, error)
+
+	// Gift adds a gifted Period: it extends an active subscription, keeping
+	// the higher tier, or starts one that doesn't renew. It redeems the
+	// entitlement like Subscribe.
+	Gift(ctx context.Context, channelID, recipientID, gifterID, tier, entitlementID string) (*Subscription, error)
+
+	// Cancel stops an active subscription from renewing; it runs until it
+	// expires
+	Cancel(ctx context.Context, channelID, subscriberID string) (*Subscription, error)
+
+	// RenewDue renews up to limit renewing subscriptions that have expired
+	RenewDue(ctx context.Context, limit int) ([]*Subscription, error)
+
+	// CountMany returns the active subscribers of each channel
+	CountMany(ctx context.Context, channelIDs []string) (map[string]int, error)
+}
+
+// subscriptionColumns is the column list shared by subscription queries
+const subscriptionColumns = `id::text, channel_id::text, subscriber_id::text, tier, COALESCE(gifted_by::text, ''),
+	auto_renew, months, started_at, expires_at`
+
+// PostgresRepository implements Repository on PostgreSQL
+type PostgresRepository struct {
+	pool *pgxpool.Pool
+}
+
+// NewPostgresRepository creates a subscription repository on pool
+func NewPostgresRepository(pool *pgxpool.Pool) *PostgresRepository {
+	return &PostgresRepository{pool: pool}
+}
+
+// Get reads a subscription
+func (r *PostgresRepository) Get(ctx context.Context, channelID, subscriberID string) (*Subscription, error) {
+	if !store.IsUUID(channelID) || !store.IsUUID(subscriberID) {
+		return nil, ErrNotFound
+	}
+
+	sub, err := scanSubscription(r.pool.QueryRow(ctx, `SELECT `+subscriptionColumns+`
+		FROM subscriptions WHERE channel_id = $1 AND subscriber_id = $2`+tenancy.Condition("tenant_id", 3),
+		channelID, subscriberID, tenancy.Scope(ctx)))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get subscription: %w", err)
+	}
+	return sub, nil
+}
+
+// Subscribe inserts a subscription or restarts a lapsed one
+func (r *PostgresRepository) Subscribe(ctx context.Context, channelID, subscriberID, tier, entitlementID string) (*Subscription, error) {
+	if !store.IsUUID(channelID) {
+		return nil, ErrChannelNotFound
+	}
+	if !store.IsUUID(subscriberID) {
+		return nil, ErrNotFound
+	}
+
+	tx, err := r.redeem(ctx, entitlementID, subscriberID)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	sub, err := scanSubscription(tx.QueryRow(ctx, `
+		INSERT INTO subscriptions (channel_id, subscriber_id, tier, expires_at, tenant_id)
+		VALUES ($1, $2, $3, NOW() + $4::interval, $5)
+		ON CONFLICT (channel_id, subscriber_id) DO UPDATE SET
+			tier = EXCLUDED.tier, gifted_by = NULL, auto_renew = TRUE, months = subscriptions.months + 1,
+			started_at = NOW(), expires_at = EXCLUDED.expires_at, updated_at = NOW()
+		WHERE subscriptions.expires_at <= NOW() AND subscriptions.tenant_id = EXCLUDED.tenant_id
+		RETURNING `+subscriptionColumns, channelID, subscriberID, tier, Period, tenancy.ID(ctx)))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrAlreadySubscribed
+	}
+	if err != nil {
+		return nil, writeError("subscribe", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit subscription: %w", err)
+	}
+	return sub, nil
+}
+
+// Gift inserts a gifted subscription or extends an existing one
+func (r *PostgresRepository) Gift(ctx context.Context, channelID, recipientID, gifterID, tier, entitlementID string) (*Subscription, error) {
+	if !store.IsUUID(channelID) {
+		return nil, ErrChannelNotFound
+	}
+	if !store.IsUUID(recipientID) || !store.IsUUID(gifterID) {
+		return nil, ErrNotFound
+	}
+
+	tx, err := r.redeem(ctx, entitlementID, gifterID)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	sub, err := scanSubscription(tx.QueryRow(ctx, `
+		INSERT INTO subscriptions (channel_id, subscriber_id, tier, gifted_by, auto_renew, expires_at, tenant_id)
+		VALUES ($1, $2, $3, $4, FALSE, NOW() + $5::interval, $6)
+		ON CONFLICT (channel_id, subscriber_id) DO UPDATE SET
+			gifted_by = EXCLUDED.gifted_by, months = subscriptions.months + 1, updated_at = NOW(),
+			tier = CASE WHEN subscriptions.expires_at > NOW() THEN GREATEST(subscriptions.tier, EXCLUDED.tier) ELSE EXCLUDED.tier END,
+			auto_renew = subscriptions.auto_renew AND subscriptions.expires_at > NOW(),
+			started_at = CASE WHEN subscriptions.expires_at > NOW() THEN subscriptions.started_at ELSE NOW() END,
+			expires_at = GREATEST(subscriptions.expires_at, NOW()) + $5::interval
+		WHERE subscriptions.tenant_id = EXCLUDED.tenant_id
+		RETURNING `+subscriptionColumns, channelID, recipientID, tier, gifterID, Period, tenancy.ID(ctx)))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrChannelNotFound
+	}
+	if err != nil {
+		return nil, writeError("gift subscription", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit gift subscription: %w", err)
+	}
+	return sub, nil
+}
+
+// redeem begins a transaction that records entitlementID as redeemed by
+// buyerID; the caller writes the subscription in it and commits
+func (r *PostgresRepository) redeem(ctx context.Context, entitlementID, buyerID string) (pgx.Tx, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	tag, err := tx.Exec(ctx, `
+		INSERT INTO redeemed_entitlements (id, buyer_id, tenant_id) VALUES ($1, $2, $3)
+		ON CONFLICT (id) DO NOTHING`, entitlementID, buyerID, tenancy.ID(ctx))
+	if err != nil {
+		tx.Rollback(ctx)
+		return nil, fmt.Errorf("failed to redeem entitlement: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		tx.Rollback(ctx)
+		return nil, ErrEntitlementRedeemed
+	}
+	return tx, nil
+}
+
+// Cancel turns off renewal of an active subscription
+func (r *PostgresRepository) Cancel(ctx context.Context, channelID, subscriberID string) (*Subscription, error) {
+	if !store.IsUUID(channelID) || !store.IsUUID(subscriberID) {
+		return nil, ErrNotFound
+	}
+
+	sub, err := scanSubscription(r.pool.QueryRow(ctx, `
+		UPDATE subscriptions SET auto_renew = FALSE, updated_at = NOW()
+		WHERE channel_id = $1 AND subscriber_id = $2 AND expires_at > NOW()`+tenancy.Condition("tenant_id", 3)+`
+		RETURNING `+subscriptionColumns, channelID, subscriberID, tenancy.Scope(ctx)))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to cancel subscription: %w", err)
+	}
+	return sub, nil
+}
+
+// RenewDue extends expired renewing subscriptions by a Period each. Rows
+// are locked with SKIP LOCKED, so API servers renew different rows.
+func (r *PostgresRepository) RenewDue(ctx context.Context, limit int) ([]*Subscription, error) {
+	rows, err := r.pool.Query(ctx, `
+		UPDATE subscriptions SET expires_at = expires_at + $1::interval, months = months + 1, updated_at = NOW()
+		WHERE id IN (
+			SELECT id FROM subscriptions WHERE auto_renew AND expires_at <= NOW()`+tenancy.Condition("tenant_id", 3)+`
+			ORDER BY expires_at LIMIT $2 FOR UPDATE SKIP LOCKED
+		)
+		RETURNING `+subscriptionColumns, Period, limit, tenancy.Scope(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("failed to renew subscriptions: %w", err)
+	}
+	defer rows.Close()
+
+	renewed := []*Subscription{}
+	for rows.Next() {
+		sub, err := scanSubscription(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan subscription: %w", err)
+		}
+		renewed = append(renewed, sub)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to renew subscriptions: %w", err)
+	}
+	return renewed, nil
+}
+
+// CountMany counts channels' active subscribers
+func (r *PostgresRepository) CountMany(ctx context.Context, channelIDs []string) (map[string]int, error) {
+	ids := make([]string, 0, len(channelIDs))
+	for _, id := range channelIDs {
+		if store.IsUUID(id) {
+			ids = append(ids, id)
+		}
+	}
+	counts := make(map[string]int, len(ids))
+	if len(ids) == 0 {
+		return counts, nil
+	}
+
+	rows, err := r.pool.Query(ctx, `
+		SELECT channel_id::text, COUNT(*) FROM subscriptions
+		WHERE channel_id = ANY($1::uuid[]) AND expires_at > NOW()`+tenancy.Condition("tenant_id", 2)+`
+		GROUP BY channel_id`, ids, tenancy.Scope(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("failed to count subscribers: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var channelID string
+		var count int
+		if err := rows.Scan(&channelID, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan subscriber count: %w", err)
+		}
+		counts[channelID] = count
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to count subscribers: %w", err)
+	}
+	return counts, nil
+}
+
+// writeError maps a missing channel or user to the repository's errors
+func writeError(action string, err error) error {
+	if store.PgCode(err) == "23503" {
+		if store.PgConstraint(err) == "subscriptions_channel_id_fkey" {
+			return ErrChannelNotFound
+		}
+		return ErrNotFound
+	}
+	return fmt.Errorf("failed to %s: %w", action, err)
+}
+
+// scanSubscription scans subscriptionColumns
+func scanSubscription(row pgx.Row) (*Subscription, error) {
+	var s Subscription
+	if err := row.Scan(&s.ID, &s.ChannelID, &s.SubscriberID, &s.Tier, &s.GiftedBy,
+		&s.AutoRenew, &s.Months, &s.StartedAt, &s.ExpiresAt); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
@@ -0,0 +1,162 @@
+package tags
+
+import (
+	"context"
+	"log"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/tinle0301/streaming-platform-api/internal/store"
+	"github.com/tinle0301/streaming-platform-api/internal/tenancy"
+)
+
+// Auto-tagging limits
+const (
+	// Most suggestions kept per stream
+	maxSuggestions = 5
+
+	// Tags borrowed from a category's recent streams
+	categoryHistoryTags = 3
+	categoryHistoryAge  = 30 * 24 * time.Hour
+
+	// Live streams read per page
+	autoTagPageSize = 100
+)
+
+// AutoTagger suggests tags for live streams from their titles and from the
+// tags other streams in their category have used. Suggestions are stored
+// apart from the streamer's own tags for the streamer to accept or ignore.
+type AutoTagger struct {
+	streams  store.StreamRepository
+	repo     Repository
+	taxonomy *Taxonomy
+}
+
+// NewAutoTagger creates an auto-tagger
+func NewAutoTagger(streams store.StreamRepository, repo Repository, taxonomy *Taxonomy) *AutoTagger {
+	return &AutoTagger{streams: streams, repo: repo, taxonomy: taxonomy}
+}
+
+// RunOnce refreshes the suggestions of every live stream and returns how
+// many streams changed
+func (a *AutoTagger) RunOnce(ctx context.Context) (int, error) {
+	if err := a.taxonomy.Reload(ctx); err != nil {
+		return 0, err
+	}
+	terms := a.taxonomy.Terms()
+	history := make(map[string][]string)
+
+	changed := 0
+	for offset := 0; ; offset += autoTagPageSize {
+		streams, total, err := a.streams.List(ctx, store.StreamFilter{
+			Status: store.StreamStatusLive,
+			Limit:  autoTagPageSize,
+			Offset: offset,
+		})
+		if err != nil {
+			return changed, err
+		}
+
+		for _, stream := range streams {
+			// Category history comes from the stream's own tenant
+			historyKey := stream.TenantID + "/" + stream.Category
+			categoryTags, ok := history[historyKey]
+			if !ok && stream.Category != "" {
+				tenantCtx := tenancy.WithTenant(ctx, stream.TenantID)
+				categoryTags, err = a.repo.CategoryHistory(tenantCtx, stream.Category, time.Now().Add(-categoryHistoryAge), categoryHistoryTags)
+				if err != nil {
+					return changed, err
+				}
+				history[historyKey] = categoryTags
+			}
+
+			suggested := a.suggest(stream, terms, categoryTags)
+			if equalTags(suggested, stream.SuggestedTags) {
+				continue
+			}
+			if err := a.streams.SetSuggestedTags(ctx, stream.ID, suggested); err != nil {
+				return changed, err
+			}
+			changed++
+		}
+
+		if offset+len(streams) >= total || len(streams) == 0 {
+			break
+		}
+	}
+	return changed, nil
+}
+
+// Run refreshes suggestions every interval until ctx is done
+func (a *AutoTagger) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			start := time.Now()
+			changed, err := a.RunOnce(ctx)
+			if err != nil {
+				log.Printf("Auto-tagging failed: err=%v", err)
+				continue
+			}
+			log.Printf("Auto-tagging complete: changed=%d, took=%v", changed, time.Since(start))
+		}
+	}
+}
+
+// suggest picks managed tags named in the title, then the category's
+// favourite tags, skipping tags the stream already has
+func (a *AutoTagger) suggest(stream *store.Stream, terms map[string]string, categoryTags []string) []string {
+	have := make(map[string]bool, len(stream.Tags))
+	for _, tag := range stream.Tags {
+		have[a.taxonomy.Canonical(tag)] = true
+	}
+
+	suggested := []string{}
+	add := func(slug string) {
+		if have[slug] || len(suggested) >= maxSuggestions {
+			return
+		}
+		have[slug] = true
+		suggested = append(suggested, slug)
+	}
+
+	// Match single words and adjacent word pairs, so "first playthrough"
+	// finds first-playthrough
+	words := strings.FieldsFunc(strings.ToLower(stream.Title), func(c rune) bool {
+		return !unicode.IsLetter(c) && !unicode.IsDigit(c)
+	})
+	for i, word := range words {
+		if i+1 < len(words) {
+			if slug, ok := terms[word+"-"+words[i+1]]; ok {
+				add(slug)
+			}
+		}
+		if slug, ok := terms[word]; ok {
+			add(slug)
+		}
+	}
+
+	for _, tag := range categoryTags {
+		add(a.taxonomy.Canonical(tag))
+	}
+	return suggested
+}
+
+// equalTags reports whether two tag lists hold the same tags in order
+func equalTags(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
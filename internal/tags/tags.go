@@ -0,0 +1,133 @@
+package tags
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/tinle0301/streaming-platform-api/internal/tenancy"
+)
+
+// Tag is a stream tag. Managed tags come from the taxonomy; freeform tags
+// exist only on the streams that use them.
+type Tag struct {
+	Slug        string
+	Name        string
+	Description string
+	Aliases     []string
+	Managed     bool
+	CreatedAt   time.Time
+}
+
+// Usage is how many live streams carry a tag
+type Usage struct {
+	Slug  string
+	Count int
+}
+
+// Repository persists the tag taxonomy and reads tag usage. The taxonomy
+// is shared by every tenant; usage only counts the context's tenant's
+// streams.
+type Repository interface {
+	// Managed returns every managed tag
+	Managed(ctx context.Context) ([]*Tag, error)
+
+	// LiveUsage counts live streams per tag, most used first
+	LiveUsage(ctx context.Context, limit int) ([]Usage, error)
+
+	// CategoryHistory returns the tags most used by streams in category
+	// since the given time, most used first
+	CategoryHistory(ctx context.Context, category string, since time.Time, limit int) ([]string, error)
+}
+
+// PostgresRepository implements Repository on PostgreSQL
+type PostgresRepository struct {
+	pool *pgxpool.Pool
+}
+
+// NewPostgresRepository creates a tag repository on pool
+func NewPostgresRepository(pool *pgxpool.Pool) *PostgresRepository {
+	return &PostgresRepository{pool: pool}
+}
+
+// Managed lists the managed tags by slug
+func (r *PostgresRepository) Managed(ctx context.Context) ([]*Tag, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT slug, name, description, aliases, created_at
+		FROM tags
+		ORDER BY slug`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tags: %w", err)
+	}
+	defer rows.Close()
+
+	var tags []*Tag
+	for rows.Next() {
+		tag := &Tag{Managed: true}
+		if err := rows.Scan(&tag.Slug, &tag.Name, &tag.Description, &tag.Aliases, &tag.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan tag: %w", err)
+		}
+		tags = append(tags, tag)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list tags: %w", err)
+	}
+	return tags, nil
+}
+
+// LiveUsage counts the tags of live streams
+func (r *PostgresRepository) LiveUsage(ctx context.Context, limit int) ([]Usage, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT tag, COUNT(*) AS streams
+		FROM streams, unnest(tags) AS tag
+		WHERE status = 'LIVE'`+tenancy.Condition("tenant_id", 2)+`
+		GROUP BY tag
+		ORDER BY streams DESC, tag
+		LIMIT $1`, limit, tenancy.Scope(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("failed to count tag usage: %w", err)
+	}
+	defer rows.Close()
+
+	var usage []Usage
+	for rows.Next() {
+		var u Usage
+		if err := rows.Scan(&u.Slug, &u.Count); err != nil {
+			return nil, fmt.Errorf("failed to scan tag usage: %w", err)
+		}
+		usage = append(usage, u)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to count tag usage: %w", err)
+	}
+	return usage, nil
+}
+
+// CategoryHistory returns the tags streams in a category used recently
+func (r *PostgresRepository) CategoryHistory(ctx context.Context, category string, since time.Time, limit int) ([]string, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT tag
+		FROM streams, unnest(tags) AS tag
+		WHERE category = $1 AND COALESCE(started_at, created_at) >= $2`+tenancy.Condition("tenant_id", 4)+`
+		GROUP BY tag
+		ORDER BY COUNT(*) DESC, tag
+		LIMIT $3`, category, since, limit, tenancy.Scope(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read category tag history: %w", err)
+	}
+	defer rows.Close()
+
+	var tags []string
+	for rows.Next() {
+		var tag string
+		if err := rows.Scan(&tag); err != nil {
+			return nil, fmt.Errorf("failed to scan category tag: %w", err)
+		}
+		tags = append(tags, tag)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read category tag history: %w", err)
+	}
+	return tags, nil
+}
@@ -0,0 +1,228 @@
+package tags
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Tag limits
+const (
+	MaxTags   = 10
+	MaxLength = 25
+
+	// How many freeform tags Search considers beyond the managed ones
+	searchUsageLimit = 500
+)
+
+// Validation errors
+var (
+	ErrInvalidTag  = fmt.Errorf("tags must be 1-%d letters, digits, or hyphens", MaxLength)
+	ErrTooManyTags = fmt.Errorf("at most %d tags are allowed", MaxTags)
+)
+
+// Normalize turns a raw tag into its slug form: lowercase, with spaces and
+// underscores folded into single hyphens
+func Normalize(raw string) (string, error) {
+	var b strings.Builder
+	hyphen := false
+	for _, c := range strings.ToLower(strings.TrimSpace(raw)) {
+		switch {
+		case c >= 'a' && c <= 'z', c >= '0' && c <= '9':
+			if hyphen && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			hyphen = false
+			b.WriteRune(c)
+		case c == '-' || c == '_' || c == ' ':
+			hyphen = true
+		default:
+			return "", ErrInvalidTag
+		}
+	}
+
+	slug := b.String()
+	if slug == "" || len(slug) > MaxLength {
+		return "", ErrInvalidTag
+	}
+	return slug, nil
+}
+
+// Validate normalizes raw tags, dropping duplicates
+func Validate(raw []string) ([]string, error) {
+	return validate(raw, func(slug string) string { return slug })
+}
+
+// validate normalizes raw tags, maps each through canonical, and drops duplicates
+func validate(raw []string, canonical func(string) string) ([]string, error) {
+	seen := make(map[string]bool, len(raw))
+	tags := make([]string, 0, len(raw))
+	for _, r := range raw {
+		slug, err := Normalize(r)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %q", err, r)
+		}
+		slug = canonical(slug)
+		if seen[slug] {
+			continue
+		}
+		seen[slug] = true
+		tags = append(tags, slug)
+	}
+	if len(tags) > MaxTags {
+		return nil, ErrTooManyTags
+	}
+	return tags, nil
+}
+
+// IsValidationError reports whether err came from tag validation
+func IsValidationError(err error) bool {
+	return errors.Is(err, ErrInvalidTag) || errors.Is(err, ErrTooManyTags)
+}
+
+// Taxonomy is an in-memory copy of the managed tags that resolves aliases
+// to their canonical slugs. Freeform tags pass through unchanged.
+type Taxonomy struct {
+	repo Repository
+
+	mu      sync.RWMutex
+	managed map[string]*Tag
+	aliases map[string]string
+}
+
+// NewTaxonomy creates a taxonomy backed by repo; call Reload to fill it
+func NewTaxonomy(repo Repository) *Taxonomy {
+	return &Taxonomy{
+		repo:    repo,
+		managed: make(map[string]*Tag),
+		aliases: make(map[string]string),
+	}
+}
+
+// Reload refreshes the managed tags from the repository
+func (t *Taxonomy) Reload(ctx context.Context) error {
+	tags, err := t.repo.Managed(ctx)
+	if err != nil {
+		return err
+	}
+
+	managed := make(map[string]*Tag, len(tags))
+	aliases := make(map[string]string)
+	for _, tag := range tags {
+		managed[tag.Slug] = tag
+		for _, alias := range tag.Aliases {
+			if slug, err := Normalize(alias); err == nil {
+				aliases[slug] = tag.Slug
+			}
+		}
+	}
+
+	t.mu.Lock()
+	t.managed = managed
+	t.aliases = aliases
+	t.mu.Unlock()
+	return nil
+}
+
+// Validate normalizes raw tags and resolves aliases to managed slugs
+func (t *Taxonomy) Validate(raw []string) ([]string, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return validate(raw, t.canonical)
+}
+
+// Canonical returns the managed slug an alias stands for, or slug itself
+func (t *Taxonomy) Canonical(slug string) string {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.canonical(slug)
+}
+
+// canonical resolves an alias (caller must hold t.mu)
+func (t *Taxonomy) canonical(slug string) string {
+	if managed, ok := t.aliases[slug]; ok {
+		return managed
+	}
+	return slug
+}
+
+// Managed returns the managed tag with slug, or nil for freeform tags
+func (t *Taxonomy) Managed(slug string) *Tag {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.managed[slug]
+}
+
+// Terms maps every managed slug and alias to the managed slug it stands for
+func (t *Taxonomy) Terms() map[string]string {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	terms := make(map[string]string, len(t.managed)+len(t.aliases))
+	for slug := range t.managed {
+		terms[slug] = slug
+	}
+	for alias, slug := range t.aliases {
+		terms[alias] = slug
+	}
+	return terms
+}
+
+// Listing is a tag with its live stream count
+type Listing struct {
+	*Tag
+	LiveStreams int
+}
+
+// Search lists managed tags and freeform tags in use on live streams whose
+// slug or name contains query, most used first. An empty query lists all.
+func (t *Taxonomy) Search(ctx context.Context, query string, limit int) ([]Listing, error) {
+	usage, err := t.repo.LiveUsage(ctx, searchUsageLimit)
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[string]int, len(usage))
+	for _, u := range usage {
+		counts[t.Canonical(u.Slug)] += u.Count
+	}
+
+	query = strings.ToLower(strings.TrimSpace(query))
+	matches := func(tag *Tag) bool {
+		if query == "" {
+			return true
+		}
+		return strings.Contains(tag.Slug, query) || strings.Contains(strings.ToLower(tag.Name), query)
+	}
+
+	var listings []Listing
+	t.mu.RLock()
+	for _, tag := range t.managed {
+		if matches(tag) {
+			listings = append(listings, Listing{Tag: tag, LiveStreams: counts[tag.Slug]})
+		}
+	}
+	for slug, count := range counts {
+		if _, ok := t.managed[slug]; ok {
+			continue
+		}
+		tag := &Tag{Slug: slug, Name: slug}
+		if matches(tag) {
+			listings = append(listings, Listing{Tag: tag, LiveStreams: count})
+		}
+	}
+	t.mu.RUnlock()
+
+	sort.Slice(listings, func(i, j int) bool {
+		if listings[i].LiveStreams != listings[j].LiveStreams {
+			return listings[i].LiveStreams > listings[j].LiveStreams
+		}
+		return listings[i].Slug < listings[j].Slug
+	})
+	if len(listings) > limit {
+		listings = listings[:limit]
+	}
+	return listings, nil
+}
@@ -0,0 +1,29 @@
+package tenancy
+
+import (
+	"net/http"
+)
+
+// Header names the tenant of a request explicitly, e.g. for API clients
+// that don't call the tenant's own domain
+const Header = "X-Tenant-ID"
+
+// Middleware scopes each request to its tenant: the one named by the
+// X-Tenant-ID header, else the one serving the request's domain, else
+// Default. Requests naming an unknown tenant are rejected.
+func Middleware(registry *Registry, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tenantID := Default
+		if id := r.Header.Get(Header); id != "" {
+			if _, err := registry.Get(id); err != nil {
+				http.Error(w, "Unknown tenant", http.StatusBadRequest)
+				return
+			}
+			tenantID = id
+		} else if tenant, err := registry.ByDomain(r.Host); err == nil {
+			tenantID = tenant.ID
+		}
+
+		next.ServeHTTP(w, r.WithContext(WithTenant(r.Context(), tenantID)))
+	})
+}
@@ -0,0 +1,50 @@
+package tenancy
+
+import (
+	"context"
+
+	"github.com/tinle0301/streaming-platform-api/internal/events"
+)
+
+// Publisher stamps events with the tenant of the context they are
+// published in, so consumers deliver them within that tenant only
+type Publisher struct {
+	events.Publisher
+}
+
+// NewPublisher wraps publisher
+func NewPublisher(publisher events.Publisher) *Publisher {
+	return &Publisher{Publisher: publisher}
+}
+
+// Publish stamps event with ctx's tenant and publishes it
+func (p *Publisher) Publish(ctx context.Context, event events.Event) error {
+	return p.Publisher.Publish(ctx, stamp(ctx, event))
+}
+
+// PublishBatch stamps events with ctx's tenant and publishes them
+func (p *Publisher) PublishBatch(ctx context.Context, batch []events.Event) error {
+	stamped := make([]events.Event, len(batch))
+	for i, event := range batch {
+		stamped[i] = stamp(ctx, event)
+	}
+	return p.Publisher.PublishBatch(ctx, stamped)
+}
+
+// stamp sets an event's tenant unless it already has one
+func stamp(ctx context.Context, event events.Event) events.Event {
+	if tenantID, ok := FromContext(ctx); ok && event.TenantID == "" {
+		event.TenantID = tenantID
+	}
+	return event
+}
+
+// EventContext scopes ctx to the tenant an event was published in, so a
+// consumer reads and writes that tenant's records. Events without a tenant
+// leave ctx unscoped.
+func EventContext(ctx context.Context, event events.Event) context.Context {
+	if event.TenantID == "" {
+		return ctx
+	}
+	return WithTenant(ctx, event.TenantID)
+}
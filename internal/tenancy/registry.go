@@ -0,0 +1,165 @@
+package tenancy
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Repository persists tenants
+type Repository interface {
+	List(ctx context.Context) ([]*Tenant, error)
+}
+
+// PostgresRepository implements Repository on PostgreSQL
+type PostgresRepository struct {
+	pool *pgxpool.Pool
+}
+
+// NewPostgresRepository creates a tenant repository on pool
+func NewPostgresRepository(pool *pgxpool.Pool) *PostgresRepository {
+	return &PostgresRepository{pool: pool}
+}
+
+// List returns every tenant
+func (r *PostgresRepository) List(ctx context.Context) ([]*Tenant, error) {
+	rows, err := r.pool.Query(ctx, `SELECT id, name, COALESCE(domain, ''), settings, created_at FROM tenants ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tenants: %w", err)
+	}
+	defer rows.Close()
+
+	var tenants []*Tenant
+	for rows.Next() {
+		var tenant Tenant
+		if err := rows.Scan(&tenant.ID, &tenant.Name, &tenant.Domain, &tenant.Settings, &tenant.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan tenant: %w", err)
+		}
+		tenants = append(tenants, &tenant)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list tenants: %w", err)
+	}
+	return tenants, nil
+}
+
+// Registry keeps the tenants in memory for resolving requests and reading
+// per-tenant settings, reloading them periodically
+type Registry struct {
+	repo Repository
+
+	mu       sync.RWMutex
+	byID     map[string]*Tenant
+	byDomain map[string]*Tenant
+}
+
+// NewRegistry creates a registry loading tenants from repo. It is empty
+// until Refresh is called.
+func NewRegistry(repo Repository) *Registry {
+	return &Registry{
+		repo:     repo,
+		byID:     make(map[string]*Tenant),
+		byDomain: make(map[string]*Tenant),
+	}
+}
+
+// Refresh reloads the tenants
+func (r *Registry) Refresh(ctx context.Context) error {
+	tenants, err := r.repo.List(ctx)
+	if err != nil {
+		return err
+	}
+
+	byID := make(map[string]*Tenant, len(tenants))
+	byDomain := make(map[string]*Tenant, len(tenants))
+	for _, tenant := range tenants {
+		byID[tenant.ID] = tenant
+		if tenant.Domain != "" {
+			byDomain[strings.ToLower(tenant.Domain)] = tenant
+		}
+	}
+
+	r.mu.Lock()
+	r.byID, r.byDomain = byID, byDomain
+	r.mu.Unlock()
+	return nil
+}
+
+// Run reloads the tenants every interval until ctx is cancelled, so new
+// tenants and changed settings are picked up without a restart
+func (r *Registry) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := r.Refresh(ctx); err != nil {
+				log.Printf("Error refreshing tenants: %v", err)
+			}
+		}
+	}
+}
+
+// List returns every tenant, ordered by ID
+func (r *Registry) List() []*Tenant {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	tenants := make([]*Tenant, 0, len(r.byID))
+	for _, tenant := range r.byID {
+		tenants = append(tenants, tenant)
+	}
+	sort.Slice(tenants, func(i, j int) bool { return tenants[i].ID < tenants[j].ID })
+	return tenants
+}
+
+// Get returns a tenant by ID
+func (r *Registry) Get(id string) (*Tenant, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	tenant, ok := r.byID[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return tenant, nil
+}
+
+// ByDomain returns the tenant serving host, ignoring case and any port
+func (r *Registry) ByDomain(host string) (*Tenant, error) {
+	if name, _, ok := strings.Cut(host, ":"); ok {
+		host = name
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	tenant, ok := r.byDomain[strings.ToLower(host)]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return tenant, nil
+}
+
+// Setting returns a tenant's override of a setting, or fallback if the
+// tenant doesn't override it
+func (r *Registry) Setting(tenantID, key, fallback string) string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if tenant, ok := r.byID[tenantID]; ok {
+		if value, ok := tenant.Settings[key]; ok {
+			return value
+		}
+	}
+	return fallback
+}
@@ -0,0 +1,331 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// maxQueuedSpans bounds spans waiting for export; spans ending while
+	// the queue is full are dropped
+	maxQueuedSpans = 2048
+
+	// maxExportBatch is the most spans sent in one request
+	maxExportBatch = 512
+
+	// exportInterval is how often queued spans are sent
+	exportInterval = 5 * time.Second
+
+	// exportTimeout bounds one export request
+	exportTimeout = 10 * time.Second
+
+	// scopeName is the instrumentation scope of every span
+	scopeName = "github.com/tinle0301/streaming-platform-api"
+)
+
+// exporter batches ended spans and posts them to an OTLP/HTTP collector as
+// JSON
+type exporter struct {
+	endpoint string
+	headers  map[string]string
+	resource []Attribute
+	client   *http.Client
+
+	mu      sync.Mutex
+	queue   []*Span
+	dropped int
+	closed  bool
+
+	flush chan struct{}
+	stop  chan struct{}
+	done  chan struct{}
+}
+
+// newExporter creates an exporter and starts its export loop
+func newExporter(opts Options) (*exporter, error) {
+	endpoint, err := url.Parse(opts.Endpoint)
+	if err != nil || (endpoint.Scheme != "http" && endpoint.Scheme != "https") || endpoint.Host == "" {
+		return nil, fmt.Errorf("invalid OTLP endpoint %q", opts.Endpoint)
+	}
+
+	resource := []Attribute{String("telemetry.sdk.language", "go")}
+	if opts.Service != "" {
+		resource = append(resource, String("service.name", opts.Service))
+	}
+	if opts.Environment != "" {
+		resource = append(resource, String("deployment.environment", opts.Environment))
+	}
+
+	e := &exporter{
+		endpoint: endpoint.String(),
+		headers:  opts.Headers,
+		resource: resource,
+		client:   &http.Client{Timeout: exportTimeout},
+		flush:    make(chan struct{}, 1),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	go e.run()
+	return e, nil
+}
+
+// enqueue queues an ended span, waking the loop once a batch is full
+func (e *exporter) enqueue(span *Span) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.closed {
+		return
+	}
+	if len(e.queue) >= maxQueuedSpans {
+		e.dropped++
+		return
+	}
+	e.queue = append(e.queue, span)
+	if len(e.queue) >= maxExportBatch {
+		select {
+		case e.flush <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// run exports queued spans every interval, when a batch fills, and once
+// more when stopped
+func (e *exporter) run() {
+	defer close(e.done)
+	ticker := time.NewTicker(exportInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-e.flush:
+		case <-e.stop:
+			e.exportAll()
+			return
+		}
+		e.exportAll()
+	}
+}
+
+// exportAll sends every queued span in batches
+func (e *exporter) exportAll() {
+	for {
+		e.mu.Lock()
+		n := min(len(e.queue), maxExportBatch)
+		batch := e.queue[:n:n]
+		e.queue = e.queue[n:]
+		dropped := e.dropped
+		e.dropped = 0
+		e.mu.Unlock()
+
+		if dropped > 0 {
+			slog.Warn("Dropped spans, export queue full", "count", dropped)
+		}
+		if n == 0 {
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), exportTimeout)
+		if err := e.export(ctx, batch); err != nil {
+			slog.Warn("Error exporting spans", "count", n, "err", err)
+		}
+		cancel()
+	}
+}
+
+// export posts one batch of spans
+func (e *exporter) export(ctx context.Context, spans []*Span) error {
+	body, err := json.Marshal(e.request(spans))
+	if err != nil {
+		return fmt.Errorf("failed to marshal spans: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create export request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range e.headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post spans: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("collector responded %s", resp.Status)
+	}
+	return nil
+}
+
+// shutdown stops accepting spans and exports the queue, waiting until ctx
+// is done at most
+func (e *exporter) shutdown(ctx context.Context) error {
+	e.mu.Lock()
+	if e.closed {
+		e.mu.Unlock()
+		return nil
+	}
+	e.closed = true
+	e.mu.Unlock()
+
+	close(e.stop)
+	select {
+	case <-e.done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("failed to export spans before shutdown: %w", ctx.Err())
+	}
+}
+
+// OTLP/JSON request body; IDs are hex and 64-bit integers are strings, as
+// the OTLP JSON encoding requires
+
+type otlpRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpKeyValue `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	TraceID           string         `json:"traceId"`
+	SpanID            string         `json:"spanId"`
+	ParentSpanID      string         `json:"parentSpanId,omitempty"`
+	TraceState        string         `json:"traceState,omitempty"`
+	Name              string         `json:"name"`
+	Kind              SpanKind       `json:"kind"`
+	StartTimeUnixNano string         `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+	Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+	Events            []otlpEvent    `json:"events,omitempty"`
+	Status            *otlpStatus    `json:"status,omitempty"`
+}
+
+type otlpEvent struct {
+	TimeUnixNano string         `json:"timeUnixNano"`
+	Name         string         `json:"name"`
+	Attributes   []otlpKeyValue `json:"attributes,omitempty"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+type otlpKeyValue struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+type otlpValue struct {
+	StringValue *string  `json:"stringValue,omitempty"`
+	IntValue    *string  `json:"intValue,omitempty"`
+	DoubleValue *float64 `json:"doubleValue,omitempty"`
+	BoolValue   *bool    `json:"boolValue,omitempty"`
+}
+
+// request builds the export request for a batch
+func (e *exporter) request(spans []*Span) otlpRequest {
+	converted := make([]otlpSpan, 0, len(spans))
+	for _, span := range spans {
+		converted = append(converted, toOTLP(span))
+	}
+	return otlpRequest{ResourceSpans: []otlpResourceSpans{{
+		Resource:   otlpResource{Attributes: keyValues(e.resource)},
+		ScopeSpans: []otlpScopeSpans{{Scope: otlpScope{Name: scopeName}, Spans: converted}},
+	}}}
+}
+
+// toOTLP converts an ended span
+func toOTLP(span *Span) otlpSpan {
+	span.mu.Lock()
+	defer span.mu.Unlock()
+
+	converted := otlpSpan{
+		TraceID:           hex.EncodeToString(span.sc.TraceID[:]),
+		SpanID:            hex.EncodeToString(span.sc.SpanID[:]),
+		TraceState:        span.sc.TraceState,
+		Name:              span.name,
+		Kind:              span.kind,
+		StartTimeUnixNano: unixNano(span.start),
+		EndTimeUnixNano:   unixNano(span.end),
+		Attributes:        keyValues(span.attributes),
+	}
+	if span.parent != (SpanID{}) {
+		converted.ParentSpanID = hex.EncodeToString(span.parent[:])
+	}
+	for _, event := range span.events {
+		converted.Events = append(converted.Events, otlpEvent{
+			TimeUnixNano: unixNano(event.time),
+			Name:         event.name,
+			Attributes:   keyValues(event.attributes),
+		})
+	}
+	if span.status != statusUnset {
+		converted.Status = &otlpStatus{Code: span.status, Message: span.statusMessage}
+	}
+	return converted
+}
+
+// keyValues converts attributes, formatting unsupported values as strings
+func keyValues(attributes []Attribute) []otlpKeyValue {
+	converted := make([]otlpKeyValue, 0, len(attributes))
+	for _, attribute := range attributes {
+		var value otlpValue
+		switch v := attribute.Value.(type) {
+		case string:
+			value.StringValue = &v
+		case int64:
+			s := strconv.FormatInt(v, 10)
+			value.IntValue = &s
+		case int:
+			s := strconv.Itoa(v)
+			value.IntValue = &s
+		case float64:
+			value.DoubleValue = &v
+		case bool:
+			value.BoolValue = &v
+		default:
+			s := fmt.Sprint(v)
+			value.StringValue = &s
+		}
+		converted = append(converted, otlpKeyValue{Key: attribute.Key, Value: value})
+	}
+	return converted
+}
+
+// unixNano formats a time as OTLP's nanosecond timestamp
+func unixNano(t time.Time) string {
+	return strconv.FormatInt(t.UnixNano(), 10)
+}
@@ -0,0 +1,460 @@
+// Package tracing records OpenTelemetry-compatible spans and exports them to
+// an OTLP/HTTP collector. Trace context crosses process boundaries as W3C
+// traceparent and tracestate values: in HTTP headers, AMQP headers, and
+// event metadata. Without an endpoint spans are not recorded, but incoming
+// trace context is still passed on, so traces stay connected through
+// services that don't export.
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// W3C trace context keys, used as header and metadata names
+const (
+	TraceparentKey = "traceparent"
+	TracestateKey  = "tracestate"
+)
+
+// SpanKind describes a span's role, with OTLP's values
+type SpanKind int
+
+// Span kinds
+const (
+	KindInternal SpanKind = iota + 1
+	KindServer
+	KindClient
+	KindProducer
+	KindConsumer
+)
+
+// TraceID identifies a trace
+type TraceID [16]byte
+
+// SpanID identifies a span within a trace
+type SpanID [8]byte
+
+// SpanContext is the part of a span that propagates
+type SpanContext struct {
+	TraceID    TraceID
+	SpanID     SpanID
+	Sampled    bool
+	TraceState string
+}
+
+// IsValid reports whether the trace and span IDs are set
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID != TraceID{} && sc.SpanID != SpanID{}
+}
+
+// Traceparent formats the span context as a W3C traceparent value
+func (sc SpanContext) Traceparent() string {
+	flags := "00"
+	if sc.Sampled {
+		flags = "01"
+	}
+	return "00-" + hex.EncodeToString(sc.TraceID[:]) + "-" + hex.EncodeToString(sc.SpanID[:]) + "-" + flags
+}
+
+// ParseTraceparent parses a W3C traceparent value and its tracestate
+func ParseTraceparent(traceparent, tracestate string) (SpanContext, bool) {
+	parts := strings.Split(strings.TrimSpace(traceparent), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" ||
+		len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return SpanContext{}, false
+	}
+	// Version 00 has exactly four fields; later versions may append more
+	if parts[0] == "00" && len(parts) != 4 {
+		return SpanContext{}, false
+	}
+
+	var sc SpanContext
+	var flags [1]byte
+	if _, err := hex.Decode(sc.TraceID[:], []byte(parts[1])); err != nil {
+		return SpanContext{}, false
+	}
+	if _, err := hex.Decode(sc.SpanID[:], []byte(parts[2])); err != nil {
+		return SpanContext{}, false
+	}
+	if _, err := hex.Decode(flags[:], []byte(parts[3])); err != nil {
+		return SpanContext{}, false
+	}
+	if !sc.IsValid() {
+		return SpanContext{}, false
+	}
+	sc.Sampled = flags[0]&1 == 1
+	sc.TraceState = strings.TrimSpace(tracestate)
+	return sc, true
+}
+
+// Inject writes ctx's span context into a carrier, e.g. headers or event
+// metadata, through set
+func Inject(ctx context.Context, set func(key, value string)) {
+	sc := SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return
+	}
+	set(TraceparentKey, sc.Traceparent())
+	if sc.TraceState != "" {
+		set(TracestateKey, sc.TraceState)
+	}
+}
+
+// Extract returns ctx with the remote parent read from a carrier through
+// get, if it holds a valid one
+func Extract(ctx context.Context, get func(key string) string) context.Context {
+	sc, ok := ParseTraceparent(get(TraceparentKey), get(TracestateKey))
+	if !ok {
+		return ctx
+	}
+	return context.WithValue(ctx, spanKey{}, &Span{sc: sc})
+}
+
+// spanKey is the context key of the current span
+type spanKey struct{}
+
+// SpanFromContext returns the context's current span; it is never nil, and
+// a span from a context without one records nothing
+func SpanFromContext(ctx context.Context) *Span {
+	if span, ok := ctx.Value(spanKey{}).(*Span); ok {
+		return span
+	}
+	return &Span{}
+}
+
+// SpanContextFromContext returns the context's current span context
+func SpanContextFromContext(ctx context.Context) SpanContext {
+	return SpanFromContext(ctx).sc
+}
+
+// Attribute is a span attribute; values are strings, integers, floats, or
+// booleans
+type Attribute struct {
+	Key   string
+	Value interface{}
+}
+
+// String creates a string attribute
+func String(key, value string) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// Int creates an integer attribute
+func Int(key string, value int) Attribute {
+	return Attribute{Key: key, Value: int64(value)}
+}
+
+// Bool creates a boolean attribute
+func Bool(key string, value bool) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// Status codes, with OTLP's values
+const (
+	statusUnset = 0
+	statusError = 2
+)
+
+// spanEvent is a timestamped annotation on a span, e.g. an exception
+type spanEvent struct {
+	name       string
+	time       time.Time
+	attributes []Attribute
+}
+
+// Span is one timed operation. Spans that are not recording, because
+// tracing is disabled or the trace was not sampled, ignore attributes and
+// errors but still carry their context to children.
+type Span struct {
+	tracer *Tracer
+	sc     SpanContext
+	parent SpanID
+	name   string
+	kind   SpanKind
+	start  time.Time
+
+	mu            sync.Mutex
+	end           time.Time
+	attributes    []Attribute
+	events        []spanEvent
+	status        int
+	statusMessage string
+	ended         bool
+}
+
+// SpanContext returns the span's propagated context
+func (s *Span) SpanContext() SpanContext {
+	return s.sc
+}
+
+// IsRecording reports whether the span will be exported
+func (s *Span) IsRecording() bool {
+	return s.tracer != nil
+}
+
+// SetAttributes adds attributes to the span
+func (s *Span) SetAttributes(attributes ...Attribute) {
+	if !s.IsRecording() {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.ended {
+		s.attributes = append(s.attributes, attributes...)
+	}
+}
+
+// RecordError marks the span failed with err, recording it as an exception
+// event. A nil err is ignored.
+func (s *Span) RecordError(err error) {
+	if err == nil || !s.IsRecording() {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ended {
+		return
+	}
+	s.events = append(s.events, spanEvent{
+		name: "exception",
+		time: time.Now(),
+		attributes: []Attribute{
+			String("exception.type", fmt.Sprintf("%T", err)),
+			String("exception.message", err.Error()),
+		},
+	})
+	s.status = statusError
+	s.statusMessage = err.Error()
+}
+
+// SetError marks the span failed without recording an exception, e.g. for
+// HTTP 5xx responses
+func (s *Span) SetError(message string) {
+	if !s.IsRecording() {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.ended {
+		s.status = statusError
+		s.statusMessage = message
+	}
+}
+
+// End finishes the span and queues it for export; later calls do nothing
+func (s *Span) End() {
+	if !s.IsRecording() {
+		return
+	}
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.end = time.Now()
+	s.mu.Unlock()
+
+	s.tracer.exporter.enqueue(s)
+}
+
+// Options configures the tracer
+type Options struct {
+	// Endpoint is the collector's OTLP/HTTP traces URL; empty disables
+	// export
+	Endpoint string
+
+	// Headers are sent with every export, e.g. collector credentials
+	Headers map[string]string
+
+	// Service names the server in every span's resource
+	Service string
+
+	// Environment is recorded as deployment.environment
+	Environment string
+
+	// SampleRatio is the fraction of new traces recorded, from 0 to 1.
+	// Traces started elsewhere follow their sampled flag.
+	SampleRatio float64
+}
+
+// OptionsFromEnv builds options from the standard OpenTelemetry variables:
+// OTEL_EXPORTER_OTLP_ENDPOINT, to which /v1/traces is appended, or
+// OTEL_EXPORTER_OTLP_TRACES_ENDPOINT, used as is; OTEL_EXPORTER_OTLP_HEADERS
+// as comma-separated key=value pairs; and OTEL_TRACES_SAMPLER_ARG, the
+// sample ratio, which defaults to 1
+func OptionsFromEnv(endpoint, tracesEndpoint, headers, sampleRatio, environment, service string) (Options, error) {
+	opts := Options{Endpoint: tracesEndpoint, Service: service, Environment: environment, SampleRatio: 1}
+	if opts.Endpoint == "" && endpoint != "" {
+		opts.Endpoint = strings.TrimRight(endpoint, "/") + "/v1/traces"
+	}
+
+	var err error
+	if opts.Headers, err = parseHeaders(headers); err != nil {
+		return Options{}, err
+	}
+	if sampleRatio != "" {
+		if opts.SampleRatio, err = strconv.ParseFloat(sampleRatio, 64); err != nil {
+			return Options{}, fmt.Errorf("invalid sample ratio %q", sampleRatio)
+		}
+	}
+	return opts, nil
+}
+
+// parseHeaders parses comma-separated key=value pairs
+func parseHeaders(value string) (map[string]string, error) {
+	headers := make(map[string]string)
+	for _, pair := range strings.Split(value, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		key, val, ok := strings.Cut(pair, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid header %q, want key=value", pair)
+		}
+		headers[key] = strings.TrimSpace(val)
+	}
+	return headers, nil
+}
+
+// Tracer starts spans and exports the recorded ones
+type Tracer struct {
+	exporter    *exporter
+	sampleRatio float64
+}
+
+// New creates a tracer. Without an endpoint the tracer only propagates
+// trace context.
+func New(opts Options) (*Tracer, error) {
+	if opts.SampleRatio < 0 || opts.SampleRatio > 1 || math.IsNaN(opts.SampleRatio) {
+		return nil, fmt.Errorf("sample ratio must be between 0 and 1, got %v", opts.SampleRatio)
+	}
+	t := &Tracer{sampleRatio: opts.SampleRatio}
+	if opts.Endpoint == "" {
+		return t, nil
+	}
+
+	exporter, err := newExporter(opts)
+	if err != nil {
+		return nil, err
+	}
+	t.exporter = exporter
+	return t, nil
+}
+
+// Enabled reports whether the tracer exports spans
+func (t *Tracer) Enabled() bool {
+	return t.exporter != nil
+}
+
+// Start starts a span as a child of ctx's current span, or a new trace
+func (t *Tracer) Start(ctx context.Context, name string, kind SpanKind, attributes ...Attribute) (context.Context, *Span) {
+	parent := SpanContextFromContext(ctx)
+	if !parent.IsValid() && !t.Enabled() {
+		return ctx, &Span{}
+	}
+
+	span := &Span{name: name, kind: kind, start: time.Now()}
+	span.sc.SpanID = newSpanID()
+	if parent.IsValid() {
+		span.parent = parent.SpanID
+		span.sc.TraceID = parent.TraceID
+		span.sc.Sampled = parent.Sampled
+		span.sc.TraceState = parent.TraceState
+	} else {
+		span.sc.TraceID = newTraceID()
+		span.sc.Sampled = t.sample(span.sc.TraceID)
+	}
+	if t.Enabled() && span.sc.Sampled {
+		span.tracer = t
+		span.attributes = attributes
+	}
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+// sample decides whether a new trace is recorded from its ID, so every
+// service makes the same decision for the same ratio
+func (t *Tracer) sample(id TraceID) bool {
+	if t.sampleRatio >= 1 {
+		return true
+	}
+	threshold := uint64(t.sampleRatio * (1 << 63))
+	return binary.BigEndian.Uint64(id[8:])>>1 < threshold
+}
+
+// Shutdown exports queued spans and stops exporting
+func (t *Tracer) Shutdown(ctx context.Context) error {
+	if !t.Enabled() {
+		return nil
+	}
+	return t.exporter.shutdown(ctx)
+}
+
+var (
+	defaultMu     sync.RWMutex
+	defaultTracer = &Tracer{}
+)
+
+// Setup creates a tracer and makes it the default for Start. Invalid
+// options leave tracing disabled, with a warning.
+func Setup(opts Options) *Tracer {
+	tracer, err := New(opts)
+	if err != nil {
+		slog.Warn("Invalid tracing configuration, tracing disabled", "err", err)
+		tracer = &Tracer{}
+	}
+	SetDefault(tracer)
+
+	if tracer.Enabled() {
+		slog.Info("Tracing enabled", "endpoint", opts.Endpoint, "sample_ratio", opts.SampleRatio)
+	}
+	return tracer
+}
+
+// SetDefault makes t the tracer Start uses
+func SetDefault(t *Tracer) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	defaultTracer = t
+}
+
+// Start starts a span with the default tracer
+func Start(ctx context.Context, name string, kind SpanKind, attributes ...Attribute) (context.Context, *Span) {
+	defaultMu.RLock()
+	t := defaultTracer
+	defaultMu.RUnlock()
+	return t.Start(ctx, name, kind, attributes...)
+}
+
+// newTraceID generates a random trace ID
+func newTraceID() TraceID {
+	var id TraceID
+	for id == (TraceID{}) {
+		if _, err := rand.Read(id[:]); err != nil {
+			panic(fmt.Sprintf("failed to read random bytes for trace ID: %v", err))
+		}
+	}
+	return id
+}
+
+// newSpanID generates a random span ID
+func newSpanID() SpanID {
+	var id SpanID
+	for id == (SpanID{}) {
+		if _, err := rand.Read(id[:]); err != nil {
+			panic(fmt.Sprintf("failed to read random bytes for span ID: %v", err))
+		}
+	}
+	return id
+}
@@ -0,0 +1,112 @@
+package users
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/tinle0301/streaming-platform-api/internal/store"
+)
+
+// Block errors
+var (
+	ErrSelfBlock = errors.New("users cannot block themselves")
+	ErrBlocked   = errors.New("one of these users has blocked the other")
+)
+
+// BlockRepository persists the users each user has blocked
+type BlockRepository interface {
+	// Block records that blockerID blocked blockedID and removes any follow
+	// between them
+	Block(ctx context.Context, blockerID, blockedID string) error
+	Unblock(ctx context.Context, blockerID, blockedID string) error
+
+	// IsBlocked reports whether blockerID blocked blockedID
+	IsBlocked(ctx context.Context, blockerID, blockedID string) (bool, error)
+
+	// EitherBlocked reports whether either user blocked the other
+	EitherBlocked(ctx context.Context, userID, otherID string) (bool, error)
+}
+
+// Block records a block and drops the follows between both users
+func (r *PostgresRepository) Block(ctx context.Context, blockerID, blockedID string) error {
+	if !store.IsUUID(blockerID) || !store.IsUUID(blockedID) {
+		return ErrNotFound
+	}
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO user_blocks (blocker_id, blocked_id) VALUES ($1, $2)
+		ON CONFLICT DO NOTHING`, blockerID, blockedID)
+	if store.PgCode(err) == "23503" {
+		return ErrNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to block user: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, `
+		DELETE FROM follows
+		WHERE (follower_id = $1 AND followed_id = $2) OR (follower_id = $2 AND followed_id = $1)`,
+		blockerID, blockedID)
+	if err != nil {
+		return fmt.Errorf("failed to remove follows of blocked user: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit block: %w", err)
+	}
+	return nil
+}
+
+// Unblock removes a block; unblocking someone not blocked is a no-op
+func (r *PostgresRepository) Unblock(ctx context.Context, blockerID, blockedID string) error {
+	if !store.IsUUID(blockerID) || !store.IsUUID(blockedID) {
+		return nil
+	}
+
+	_, err := r.pool.Exec(ctx, `DELETE FROM user_blocks WHERE blocker_id = $1 AND blocked_id = $2`, blockerID, blockedID)
+	if err != nil {
+		return fmt.Errorf("failed to unblock user: %w", err)
+	}
+	return nil
+}
+
+// IsBlocked reports whether blockerID blocked blockedID
+func (r *PostgresRepository) IsBlocked(ctx context.Context, blockerID, blockedID string) (bool, error) {
+	if !store.IsUUID(blockerID) || !store.IsUUID(blockedID) {
+		return false, nil
+	}
+
+	var blocked bool
+	err := r.pool.QueryRow(ctx, `
+		SELECT EXISTS (SELECT 1 FROM user_blocks WHERE blocker_id = $1 AND blocked_id = $2)`,
+		blockerID, blockedID).Scan(&blocked)
+	if err != nil {
+		return false, fmt.Errorf("failed to check block: %w", err)
+	}
+	return blocked, nil
+}
+
+// EitherBlocked reports whether either user blocked the other
+func (r *PostgresRepository) EitherBlocked(ctx context.Context, userID, otherID string) (bool, error) {
+	if !store.IsUUID(userID) || !store.IsUUID(otherID) {
+		return false, nil
+	}
+
+	var blocked bool
+	err := r.pool.QueryRow(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM user_blocks
+			WHERE (blocker_id = $1 AND blocked_id = $2) OR (blocker_id = $2 AND blocked_id = $1))`,
+		userID, otherID).Scan(&blocked)
+	if err != nil {
+		return false, fmt.Errorf("failed to check blocks: %w", err)
+	}
+	return blocked, nil
+}
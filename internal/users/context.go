@@ -0,0 +1,118 @@
+package users
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/tinle0301/streaming-platform-api/internal/logging"
+	"github.com/tinle0301/streaming-platform-api/internal/tenancy"
+)
+
+// claimsKey carries verified token claims in a request context
+type claimsKey struct{}
+
+// WithClaims returns a context carrying claims
+func WithClaims(ctx context.Context, claims *Claims) context.Context {
+	return context.WithValue(ctx, claimsKey{}, claims)
+}
+
+// ClaimsFromContext returns the authenticated user's claims, if any
+func ClaimsFromContext(ctx context.Context) (*Claims, bool) {
+	claims, ok := ctx.Value(claimsKey{}).(*Claims)
+	return claims, ok
+}
+
+// Middleware authenticates requests carrying "Authorization: Bearer <token>".
+// Requests without a token or with a guest token pass through anonymously;
+// requests with an invalid token, or one issued in another tenant than the
+// request's, are rejected so clients know to sign in again.
+func Middleware(tokens *TokenIssuer, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header := r.Header.Get("Authorization")
+		if header == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		token, ok := strings.CutPrefix(header, "Bearer ")
+		if !ok {
+			http.Error(w, "Authorization header must be a Bearer token", http.StatusUnauthorized)
+			return
+		}
+
+		claims, err := tokens.Verify(token)
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		if tenantID, ok := tenancy.FromContext(r.Context()); ok && claims.TenantID() != tenantID {
+			w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+			http.Error(w, "token was issued for another tenant", http.StatusUnauthorized)
+			return
+		}
+		if claims.Guest {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		ctx := logging.WithUserID(WithClaims(r.Context(), claims), claims.UserID())
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// Authenticate returns Middleware as a link of a middleware chain
+func Authenticate(tokens *TokenIssuer) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return Middleware(tokens, next)
+	}
+}
+
+// BearerUserID returns a function that reads the signed-in user's ID from a
+// request's bearer token, or "" for anonymous, guest, and invalid tokens
+func BearerUserID(tokens *TokenIssuer) func(r *http.Request) string {
+	return func(r *http.Request) string {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok {
+			return ""
+		}
+		claims, err := tokens.Verify(token)
+		if err != nil || claims.Guest {
+			return ""
+		}
+		return claims.UserID()
+	}
+}
+
+// RequireUser authenticates requests like Middleware, but rejects those
+// without a signed-in user and those authorize refuses. authorize may be
+// nil to admit every signed-in user.
+func RequireUser(tokens *TokenIssuer, authorize func(r *http.Request, claims *Claims) bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return Middleware(tokens, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims, ok := ClaimsFromContext(r.Context())
+			if !ok {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				http.Error(w, "Sign in required", http.StatusUnauthorized)
+				return
+			}
+			if authorize != nil && !authorize(r, claims) {
+				http.Error(w, "Forbidden", http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		}))
+	}
+}
+
+// RequireAdmin admits only the platform admins in adminIDs
+func RequireAdmin(tokens *TokenIssuer, adminIDs []string) func(http.Handler) http.Handler {
+	admins := make(map[string]bool, len(adminIDs))
+	for _, id := range adminIDs {
+		admins[id] = true
+	}
+	return RequireUser(tokens, func(_ *http.Request, claims *Claims) bool {
+		return admins[claims.UserID()]
+	})
+}
@@ -0,0 +1,50 @@
+package users
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRequireAdmin(t *testing.T) {
+	tokens := NewTokenIssuer("test-secret", time.Hour)
+	handler := RequireAdmin(tokens, []string{"admin-1"})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	token := func(userID string) string {
+		signed, _, err := tokens.Issue(&User{ID: userID, Username: userID})
+		if err != nil {
+			t.Fatalf("issue token: %v", err)
+		}
+		return "Bearer " + signed
+	}
+	guest, _, err := tokens.IssueGuest("guest-1", "")
+	if err != nil {
+		t.Fatalf("issue guest token: %v", err)
+	}
+
+	tests := []struct {
+		name          string
+		authorization string
+		want          int
+	}{
+		{"anonymous", "", http.StatusUnauthorized},
+		{"guest", "Bearer " + guest, http.StatusUnauthorized},
+		{"forged", "Bearer not-a-token", http.StatusUnauthorized},
+		{"user", token("user-1"), http.StatusForbidden},
+		{"admin", token("admin-1"), http.StatusNoContent},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodPost, "/admin/backups", nil)
+		if tt.authorization != "" {
+			r.Header.Set("Authorization", tt.authorization)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		if w.Code != tt.want {
+			t.Errorf("%s: status = %d, want %d", tt.name, w.Code, tt.want)
+		}
+	}
+}
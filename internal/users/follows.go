@@ -0,0 +1,297 @@
+package users
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tinle0301/streaming-platform-api/internal/store"
+)
+
+// Follow errors
+var (
+	ErrSelfFollow    = errors.New("users cannot follow themselves")
+	ErrInvalidCursor = errors.New("invalid cursor")
+)
+
+// FollowCounts is how many users follow a user and how many it follows
+type FollowCounts struct {
+	Followers int
+	Following int
+}
+
+// Follow links a user to someone they follow or who follows them
+type Follow struct {
+	User       *User
+	FollowedAt time.Time
+}
+
+// FollowPage is one page of a followers or following list, newest first
+type FollowPage struct {
+	Follows    []Follow
+	HasNext    bool
+	TotalCount int
+}
+
+// Cursor is the opaque position after a follow in a list
+func (f Follow) Cursor() string {
+	raw := strconv.FormatInt(f.FollowedAt.UnixNano(), 10) + ":" + f.User.ID
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// followCursor is a decoded Cursor
+type followCursor struct {
+	followedAt time.Time
+	userID     string
+}
+
+// parseFollowCursor decodes a cursor returned by Follow.Cursor
+func parseFollowCursor(cursor string) (*followCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	nanos, userID, ok := strings.Cut(string(raw), ":")
+	if !ok || !store.IsUUID(userID) {
+		return nil, ErrInvalidCursor
+	}
+	n, err := strconv.ParseInt(nanos, 10, 64)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	return &followCursor{followedAt: time.Unix(0, n), userID: userID}, nil
+}
+
+// FollowRepository persists follow relationships
+type FollowRepository interface {
+	// Follow records that followerID follows followedID and reports whether
+	// the relationship is new
+	Follow(ctx context.Context, followerID, followedID string) (bool, error)
+	Unfollow(ctx context.Context, followerID, followedID string) error
+	IsFollowing(ctx context.Context, followerID, followedID string) (bool, error)
+	Counts(ctx context.Context, userID string) (followers, following int, err error)
+	CountsMany(ctx context.Context, userIDs []string) (map[string]FollowCounts, error)
+	Followers(ctx context.Context, userID string, first int, after string) (*FollowPage, error)
+	Following(ctx context.Context, userID string, first int, after string) (*FollowPage, error)
+
+	// AudienceOverlap counts, for each candidate, how many of userID's
+	// followers also follow the candidate
+	AudienceOverlap(ctx context.Context, userID string, candidateIDs []string) (map[string]int, error)
+}
+
+// Follow records that followerID follows followedID
+func (r *PostgresRepository) Follow(ctx context.Context, followerID, followedID string) (bool, error) {
+	if !store.IsUUID(followerID) || !store.IsUUID(followedID) {
+		return false, ErrNotFound
+	}
+
+	tag, err := r.pool.Exec(ctx, `
+		INSERT INTO follows (follower_id, followed_id) VALUES ($1, $2)
+		ON CONFLICT DO NOTHING`, followerID, followedID)
+
+	if store.PgCode(err) == "23503" {
+		return false, ErrNotFound
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to follow user: %w", err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// Unfollow removes a follow; unfollowing someone not followed is a no-op
+func (r *PostgresRepository) Unfollow(ctx context.Context, followerID, followedID string) error {
+	if !store.IsUUID(followerID) || !store.IsUUID(followedID) {
+		return nil
+	}
+
+	_, err := r.pool.Exec(ctx, `DELETE FROM follows WHERE follower_id = $1 AND followed_id = $2`, followerID, followedID)
+	if err != nil {
+		return fmt.Errorf("failed to unfollow user: %w", err)
+	}
+	return nil
+}
+
+// IsFollowing reports whether followerID follows followedID
+func (r *PostgresRepository) IsFollowing(ctx context.Context, followerID, followedID string) (bool, error) {
+	if !store.IsUUID(followerID) || !store.IsUUID(followedID) {
+		return false, nil
+	}
+
+	var following bool
+	err := r.pool.QueryRow(ctx, `
+		SELECT EXISTS (SELECT 1 FROM follows WHERE follower_id = $1 AND followed_id = $2)`,
+		followerID, followedID).Scan(&following)
+	if err != nil {
+		return false, fmt.Errorf("failed to check follow: %w", err)
+	}
+	return following, nil
+}
+
+// Counts returns how many users follow userID and how many it follows
+func (r *PostgresRepository) Counts(ctx context.Context, userID string) (int, int, error) {
+	if !store.IsUUID(userID) {
+		return 0, 0, nil
+	}
+
+	var followers, following int
+	err := r.pool.QueryRow(ctx, `
+		SELECT
+			(SELECT COUNT(*) FROM follows WHERE followed_id = $1),
+			(SELECT COUNT(*) FROM follows WHERE follower_id = $1)`,
+		userID).Scan(&followers, &following)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to count follows: %w", err)
+	}
+	return followers, following, nil
+}
+
+// CountsMany returns follow counts for several users, keyed by user ID
+func (r *PostgresRepository) CountsMany(ctx context.Context, userIDs []string) (map[string]FollowCounts, error) {
+	counts := make(map[string]FollowCounts, len(userIDs))
+	valid := make([]string, 0, len(userIDs))
+	for _, id := range userIDs {
+		if store.IsUUID(id) {
+			valid = append(valid, id)
+		}
+	}
+	if len(valid) == 0 {
+		return counts, nil
+	}
+
+	rows, err := r.pool.Query(ctx, `
+		SELECT u.id::text,
+			(SELECT COUNT(*) FROM follows WHERE followed_id = u.id),
+			(SELECT COUNT(*) FROM follows WHERE follower_id = u.id)
+		FROM UNNEST($1::uuid[]) AS u(id)`, valid)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count follows: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id string
+		var c FollowCounts
+		if err := rows.Scan(&id, &c.Followers, &c.Following); err != nil {
+			return nil, fmt.Errorf("failed to scan follow counts: %w", err)
+		}
+		counts[id] = c
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to count follows: %w", err)
+	}
+	return counts, nil
+}
+
+// Followers returns a page of the users following userID
+func (r *PostgresRepository) Followers(ctx context.Context, userID string, first int, after string) (*FollowPage, error) {
+	return r.followPage(ctx, "followed_id", "follower_id", userID, first, after)
+}
+
+// Following returns a page of the users userID follows
+func (r *PostgresRepository) Following(ctx context.Context, userID string, first int, after string) (*FollowPage, error) {
+	return r.followPage(ctx, "follower_id", "followed_id", userID, first, after)
+}
+
+// AudienceOverlap counts the followers userID shares with each candidate
+func (r *PostgresRepository) AudienceOverlap(ctx context.Context, userID string, candidateIDs []string) (map[string]int, error) {
+	overlap := make(map[string]int)
+	candidates := make([]string, 0, len(candidateIDs))
+	for _, id := range candidateIDs {
+		if store.IsUUID(id) {
+			candidates = append(candidates, id)
+		}
+	}
+	if !store.IsUUID(userID) || len(candidates) == 0 {
+		return overlap, nil
+	}
+
+	rows, err := r.pool.Query(ctx, `
+		SELECT theirs.followed_id::text, COUNT(*)
+		FROM follows mine
+		JOIN follows theirs ON theirs.follower_id = mine.follower_id
+		WHERE mine.followed_id = $1 AND theirs.followed_id = ANY($2::uuid[])
+		GROUP BY theirs.followed_id`, userID, candidates)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count shared followers: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id string
+		var count int
+		if err := rows.Scan(&id, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan shared followers: %w", err)
+		}
+		overlap[id] = count
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to count shared followers: %w", err)
+	}
+	return overlap, nil
+}
+
+// followPage lists the otherColumn side of follows where ownerColumn is
+// userID, newest first, using keyset pagination on (created_at, user ID)
+func (r *PostgresRepository) followPage(ctx context.Context, ownerColumn, otherColumn, userID string, first int, after string) (*FollowPage, error) {
+	page := &FollowPage{Follows: []Follow{}}
+	if !store.IsUUID(userID) {
+		return page, nil
+	}
+
+	err := r.pool.QueryRow(ctx, `SELECT COUNT(*) FROM follows WHERE `+ownerColumn+` = $1`, userID).Scan(&page.TotalCount)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count follows: %w", err)
+	}
+
+	args := []interface{}{userID, first + 1}
+	keyset := ""
+	if after != "" {
+		cursor, err := parseFollowCursor(after)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, cursor.followedAt, cursor.userID)
+		keyset = fmt.Sprintf(" AND (f.created_at, f.%s) < ($3, $4)", otherColumn)
+	}
+
+	query := fmt.Sprintf(`
+		SELECT f.created_at, %s
+		FROM follows f JOIN users u ON u.id = f.%s
+		WHERE f.%s = $1%s
+		ORDER BY f.created_at DESC, f.%s DESC
+		LIMIT $2`,
+		userColumns, otherColumn, ownerColumn, keyset, otherColumn)
+
+	rows, err := r.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list follows: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var follow Follow
+		var user User
+		err := rows.Scan(&follow.FollowedAt,
+			&user.ID, &user.Username, &user.Email, &user.PasswordHash, &user.DisplayName, &user.Bio,
+			&user.AvatarURL, &user.BannerURL, &user.IsPartner, &user.IsAffiliate, &user.TenantID, &user.CreatedAt, &user.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan follow: %w", err)
+		}
+		follow.User = &user
+		page.Follows = append(page.Follows, follow)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list follows: %w", err)
+	}
+
+	if len(page.Follows) > first {
+		page.Follows = page.Follows[:first]
+		page.HasNext = true
+	}
+	return page, nil
+}
@@ -0,0 +1,28 @@
+package users
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"pgregory.net/rapid"
+)
+
+// TestFollowCursorRoundTrip checks a cursor decodes to the follow it was
+// made from
+func TestFollowCursorRoundTrip(t *testing.T) {
+	rapid.Check(t, func(t *rapid.T) {
+		follow := Follow{
+			User:       &User{ID: fmt.Sprintf("00000000-0000-4000-8000-%012d", rapid.IntRange(0, 999999).Draw(t, "user"))},
+			FollowedAt: time.Unix(0, rapid.Int64Range(0, 1<<62).Draw(t, "nanos")),
+		}
+
+		cursor, err := parseFollowCursor(follow.Cursor())
+		if err != nil {
+			t.Fatalf("parseFollowCursor(%s) = %v", follow.Cursor(), err)
+		}
+		if !cursor.followedAt.Equal(follow.FollowedAt) || cursor.userID != follow.User.ID {
+			t.Fatalf("cursor = %v %s, want %v %s", cursor.followedAt, cursor.userID, follow.FollowedAt, follow.User.ID)
+		}
+	})
+}
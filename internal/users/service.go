@@ -0,0 +1,387 @@
+package users
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/mail"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tinle0301/streaming-platform-api/internal/events"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Registration limits
+const (
+	minUsernameLength = 3
+	maxUsernameLength = 25
+	minPasswordLength = 8
+
+	// bcrypt ignores everything past 72 bytes
+	maxPasswordLength = 72
+)
+
+// ValidationError describes invalid registration input
+type ValidationError struct {
+	Field   string
+	Message string
+}
+
+// Error implements the error interface
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("%s %s", e.Field, e.Message)
+}
+
+// Registration is the input to Register
+type Registration struct {
+	Username    string
+	Email       string
+	Password    string
+	DisplayName string
+}
+
+// Session is an authenticated user and their access token
+type Session struct {
+	User      *User
+	Token     string
+	ExpiresAt time.Time
+}
+
+// Service registers and authenticates users and manages follows and blocks
+type Service struct {
+	repo      Repository
+	follows   FollowRepository
+	blocks    BlockRepository
+	tokens    *TokenIssuer
+	publisher events.Publisher
+}
+
+// NewService creates a user service
+func NewService(repo Repository, follows FollowRepository, blocks BlockRepository, tokens *TokenIssuer) *Service {
+	return &Service{
+		repo:    repo,
+		follows: follows,
+		blocks:  blocks,
+		tokens:  tokens,
+	}
+}
+
+// SetPublisher enables domain events such as user.new_follower
+func (s *Service) SetPublisher(publisher events.Publisher) {
+	s.publisher = publisher
+}
+
+// Register creates an account and signs the new user in
+func (s *Service) Register(ctx context.Context, input Registration) (*Session, error) {
+	user, err := s.create(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+	return s.newSession(user)
+}
+
+// Provision creates an account on behalf of an identity provider. Without
+// a password the account gets a random one, so it can't sign in with a
+// password until it is reset.
+func (s *Service) Provision(ctx context.Context, input Registration) (*User, error) {
+	if input.Password == "" {
+		password := make([]byte, 32)
+		if _, err := rand.Read(password); err != nil {
+			return nil, fmt.Errorf("failed to generate password: %w", err)
+		}
+		input.Password = hex.EncodeToString(password)[:maxPasswordLength]
+	}
+	return s.create(ctx, input)
+}
+
+// create validates a registration and stores the account
+func (s *Service) create(ctx context.Context, input Registration) (*User, error) {
+	input.Username = strings.TrimSpace(input.Username)
+	input.Email = strings.TrimSpace(input.Email)
+	input.DisplayName = strings.TrimSpace(input.DisplayName)
+
+	if err := validateRegistration(input); err != nil {
+		return nil, err
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(input.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	displayName := input.DisplayName
+	if displayName == "" {
+		displayName = input.Username
+	}
+
+	user := &User{
+		Username:     input.Username,
+		Email:        input.Email,
+		PasswordHash: string(hash),
+		DisplayName:  displayName,
+	}
+	if err := s.repo.Create(ctx, user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// UpdateProfile changes a user's email and display name
+func (s *Service) UpdateProfile(ctx context.Context, id, email, displayName string) (*User, error) {
+	email, displayName = strings.TrimSpace(email), strings.TrimSpace(displayName)
+	if address, err := mail.ParseAddress(email); err != nil || address.Address != email {
+		return nil, &ValidationError{Field: "email", Message: "is not a valid address"}
+	}
+
+	user, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	user.Email = email
+	if displayName != "" {
+		user.DisplayName = displayName
+	}
+	if err := s.repo.Update(ctx, user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// SetDeactivated deactivates or reactivates a user. Deactivated users can't
+// sign in; tokens already issued stay valid until they expire.
+func (s *Service) SetDeactivated(ctx context.Context, id string, deactivated bool) error {
+	return s.repo.SetDeactivated(ctx, id, deactivated)
+}
+
+// Login verifies a username or email and password and signs the user in
+func (s *Service) Login(ctx context.Context, login, password string) (*Session, error) {
+	user, err := s.repo.GetByLogin(ctx, strings.TrimSpace(login))
+	if errors.Is(err, ErrNotFound) {
+		// Spend the same time as a real comparison so response timing
+		// doesn't reveal which accounts exist
+		bcrypt.CompareHashAndPassword(dummyPasswordHash(), []byte(password))
+		return nil, ErrInvalidCredentials
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
+		return nil, ErrInvalidCredentials
+	}
+	if user.DeactivatedAt != nil {
+		return nil, ErrDeactivated
+	}
+
+	return s.newSession(user)
+}
+
+// SignIn signs a user in whose identity was verified elsewhere, e.g. by
+// their tenant's single sign-on provider
+func (s *Service) SignIn(ctx context.Context, id string) (*Session, error) {
+	user, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if user.DeactivatedAt != nil {
+		return nil, ErrDeactivated
+	}
+	return s.newSession(user)
+}
+
+// GetByEmail returns a user by email, ignoring case
+func (s *Service) GetByEmail(ctx context.Context, email string) (*User, error) {
+	email = strings.TrimSpace(email)
+	if !strings.Contains(email, "@") {
+		return nil, ErrNotFound
+	}
+	return s.repo.GetByLogin(ctx, email)
+}
+
+// Get returns a user by ID
+func (s *Service) Get(ctx context.Context, id string) (*User, error) {
+	return s.repo.Get(ctx, id)
+}
+
+// GetMany returns the users with the given IDs, keyed by ID
+func (s *Service) GetMany(ctx context.Context, ids []string) (map[string]*User, error) {
+	return s.repo.GetMany(ctx, ids)
+}
+
+// Follow makes followerID follow followedID. Following someone already
+// followed succeeds without publishing another event; users who blocked
+// each other can't follow.
+func (s *Service) Follow(ctx context.Context, followerID, followedID string) error {
+	if followerID == followedID {
+		return ErrSelfFollow
+	}
+	// Users of other tenants are not found in ctx's tenant
+	if _, err := s.repo.Get(ctx, followedID); err != nil {
+		return err
+	}
+	blocked, err := s.blocks.EitherBlocked(ctx, followerID, followedID)
+	if err != nil {
+		return err
+	}
+	if blocked {
+		return ErrBlocked
+	}
+
+	created, err := s.follows.Follow(ctx, followerID, followedID)
+	if err != nil || !created {
+		return err
+	}
+
+	if s.publisher != nil {
+		event := events.NewFollowerEvent(followerID, followedID)
+		if follower, err := s.repo.Get(ctx, followerID); err == nil {
+			event.Data["follower_username"] = follower.Username
+			event.Data["follower_display_name"] = follower.DisplayName
+		}
+		// The follow is committed; a lost notification must not fail it
+		if err := s.publisher.Publish(ctx, event); err != nil {
+			log.Printf("Error publishing follower event: follower=%s, followed=%s, err=%v", followerID, followedID, err)
+		}
+	}
+	return nil
+}
+
+// Unfollow makes followerID stop following followedID
+func (s *Service) Unfollow(ctx context.Context, followerID, followedID string) error {
+	return s.follows.Unfollow(ctx, followerID, followedID)
+}
+
+// IsFollowing reports whether followerID follows followedID
+func (s *Service) IsFollowing(ctx context.Context, followerID, followedID string) (bool, error) {
+	return s.follows.IsFollowing(ctx, followerID, followedID)
+}
+
+// Block makes blockerID block blockedID; neither can direct message the
+// other, and any follow between them is removed
+func (s *Service) Block(ctx context.Context, blockerID, blockedID string) error {
+	if blockerID == blockedID {
+		return ErrSelfBlock
+	}
+	return s.blocks.Block(ctx, blockerID, blockedID)
+}
+
+// Unblock lifts a block blockerID placed on blockedID
+func (s *Service) Unblock(ctx context.Context, blockerID, blockedID string) error {
+	return s.blocks.Unblock(ctx, blockerID, blockedID)
+}
+
+// IsBlocked reports whether blockerID blocked blockedID
+func (s *Service) IsBlocked(ctx context.Context, blockerID, blockedID string) (bool, error) {
+	return s.blocks.IsBlocked(ctx, blockerID, blockedID)
+}
+
+// EitherBlocked reports whether either user blocked the other
+func (s *Service) EitherBlocked(ctx context.Context, userID, otherID string) (bool, error) {
+	return s.blocks.EitherBlocked(ctx, userID, otherID)
+}
+
+// FollowCounts returns a user's follower and following counts
+func (s *Service) FollowCounts(ctx context.Context, userID string) (followers, following int, err error) {
+	return s.follows.Counts(ctx, userID)
+}
+
+// FollowCountsMany returns follower and following counts for several users
+func (s *Service) FollowCountsMany(ctx context.Context, userIDs []string) (map[string]FollowCounts, error) {
+	return s.follows.CountsMany(ctx, userIDs)
+}
+
+// Followers returns a page of a user's followers, newest first
+func (s *Service) Followers(ctx context.Context, userID string, first int, after string) (*FollowPage, error) {
+	return s.follows.Followers(ctx, userID, first, after)
+}
+
+// Following returns a page of the users a user follows, newest first
+func (s *Service) Following(ctx context.Context, userID string, first int, after string) (*FollowPage, error) {
+	return s.follows.Following(ctx, userID, first, after)
+}
+
+// AudienceOverlap counts the followers userID shares with each candidate
+func (s *Service) AudienceOverlap(ctx context.Context, userID string, candidateIDs []string) (map[string]int, error) {
+	return s.follows.AudienceOverlap(ctx, userID, candidateIDs)
+}
+
+// Tokens returns the issuer used to sign and verify access tokens
+func (s *Service) Tokens() *TokenIssuer {
+	return s.tokens
+}
+
+// newSession issues a token for user
+func (s *Service) newSession(user *User) (*Session, error) {
+	token, expiresAt, err := s.tokens.Issue(user)
+	if err != nil {
+		return nil, err
+	}
+	return &Session{
+		User:      user,
+		Token:     token,
+		ExpiresAt: expiresAt,
+	}, nil
+}
+
+var (
+	dummyHash     []byte
+	dummyHashOnce sync.Once
+)
+
+// dummyPasswordHash is compared against when a login names no account
+func dummyPasswordHash() []byte {
+	dummyHashOnce.Do(func() {
+		dummyHash, _ = bcrypt.GenerateFromPassword([]byte("streamhub-dummy-password"), bcrypt.DefaultCost)
+	})
+	return dummyHash
+}
+
+// SuggestUsername derives a valid username from an identity provider's
+// name for a user, such as an email or login: its local part, with
+// anything but letters, digits, and underscores replaced, fitted to the
+// allowed length
+func SuggestUsername(name string) string {
+	local, _, _ := strings.Cut(strings.TrimSpace(name), "@")
+	username := strings.Map(func(c rune) rune {
+		if (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') || c == '_' {
+			return c
+		}
+		return '_'
+	}, local)
+	if len(username) < minUsernameLength {
+		username = "u_" + username
+		for len(username) < minUsernameLength {
+			username += "_"
+		}
+	}
+	if len(username) > maxUsernameLength {
+		username = username[:maxUsernameLength]
+	}
+	return username
+}
+
+// validateRegistration checks usernames, emails, and password length
+func validateRegistration(input Registration) error {
+	if n := len(input.Username); n < minUsernameLength || n > maxUsernameLength {
+		return &ValidationError{Field: "username", Message: fmt.Sprintf("must be %d-%d characters", minUsernameLength, maxUsernameLength)}
+	}
+	for _, c := range input.Username {
+		if (c < 'a' || c > 'z') && (c < 'A' || c > 'Z') && (c < '0' || c > '9') && c != '_' {
+			return &ValidationError{Field: "username", Message: "may only contain letters, digits, and underscores"}
+		}
+	}
+
+	if address, err := mail.ParseAddress(input.Email); err != nil || address.Address != input.Email {
+		return &ValidationError{Field: "email", Message: "is not a valid address"}
+	}
+
+	if len(input.Password) < minPasswordLength || len(input.Password) > maxPasswordLength {
+		return &ValidationError{Field: "password", Message: fmt.Sprintf("must be %d-%d bytes", minPasswordLength, maxPasswordLength)}
+	}
+	return nil
+}
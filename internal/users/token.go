@@ -0,0 +1,141 @@
+package users
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/tinle0301/streaming-platform-api/internal/tenancy"
+)
+
+// ErrInvalidToken is returned for malformed, expired, or forged tokens
+var ErrInvalidToken = errors.New("invalid or expired token")
+
+// tokenIssuer is the iss claim of tokens issued by this service
+const tokenIssuer = "streamhub"
+
+// Claims are the JWT claims identifying an authenticated user or a guest
+type Claims struct {
+	Username string `json:"username,omitempty"`
+
+	// Guest tokens identify an anonymous viewer rather than an account
+	Guest bool `json:"guest,omitempty"`
+
+	// Tenant pins the token to the tenant it was issued in; empty for
+	// tokens issued before multi-tenancy, which belong to the default tenant
+	Tenant string `json:"tid,omitempty"`
+
+	jwt.RegisteredClaims
+}
+
+// UserID returns the authenticated user's ID
+func (c *Claims) UserID() string {
+	return c.Subject
+}
+
+// TenantID returns the tenant the token was issued in
+func (c *Claims) TenantID() string {
+	if c.Tenant == "" {
+		return tenancy.Default
+	}
+	return c.Tenant
+}
+
+// TokenIssuer signs and verifies HS256 access tokens
+type TokenIssuer struct {
+	ttl time.Duration
+
+	mu     sync.RWMutex
+	secret []byte
+
+	// previous is the secret before the last rotation; tokens it signed
+	// are still accepted until they expire
+	previous []byte
+}
+
+// NewTokenIssuer creates a token issuer; tokens expire after ttl
+func NewTokenIssuer(secret string, ttl time.Duration) *TokenIssuer {
+	return &TokenIssuer{
+		secret: []byte(secret),
+		ttl:    ttl,
+	}
+}
+
+// SetSecret rotates the signing secret. New tokens are signed with secret;
+// tokens signed with the secret it replaces stay valid until they expire.
+func (t *TokenIssuer) SetSecret(secret string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.previous = t.secret
+	t.secret = []byte(secret)
+}
+
+// keys returns the current secret and the one before it, if any
+func (t *TokenIssuer) keys() ([]byte, []byte) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.secret, t.previous
+}
+
+// Issue signs a token for user and returns it with its expiry
+func (t *TokenIssuer) Issue(user *User) (string, time.Time, error) {
+	return t.sign(Claims{Username: user.Username, Tenant: user.TenantID}, user.ID)
+}
+
+// IssueGuest signs a guest token for an anonymous viewer of a tenant and
+// returns it with its expiry
+func (t *TokenIssuer) IssueGuest(guestID, tenantID string) (string, time.Time, error) {
+	return t.sign(Claims{Guest: true, Tenant: tenantID}, guestID)
+}
+
+// sign fills in the registered claims for subject and signs the token
+func (t *TokenIssuer) sign(claims Claims, subject string) (string, time.Time, error) {
+	now := time.Now()
+	expiresAt := now.Add(t.ttl)
+
+	claims.RegisteredClaims = jwt.RegisteredClaims{
+		Issuer:    tokenIssuer,
+		Subject:   subject,
+		IssuedAt:  jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(expiresAt),
+	}
+
+	secret, _ := t.keys()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(secret)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to sign token: %w", err)
+	}
+	return token, expiresAt, nil
+}
+
+// Verify checks a token's signature and expiry and returns its claims.
+// Tokens signed with the secret before the last rotation are accepted too.
+func (t *TokenIssuer) Verify(tokenString string) (*Claims, error) {
+	secret, previous := t.keys()
+	claims, err := verifyWith(tokenString, secret)
+	if err != nil && previous != nil && errors.Is(err, jwt.ErrTokenSignatureInvalid) {
+		claims, err = verifyWith(tokenString, previous)
+	}
+	if err != nil || claims.Subject == "" {
+		return nil, ErrInvalidToken
+	}
+	return claims, nil
+}
+
+// verifyWith checks a token against one secret
+func verifyWith(tokenString string, secret []byte) (*Claims, error) {
+	var claims Claims
+	_, err := jwt.ParseWithClaims(tokenString, &claims, func(token *jwt.Token) (interface{}, error) {
+		return secret, nil
+	},
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithIssuer(tokenIssuer),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &claims, nil
+}
@@ -0,0 +1,37 @@
+package users
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTokenIssuerRotatesSecret(t *testing.T) {
+	issuer := NewTokenIssuer("first-secret", time.Hour)
+	user := &User{ID: "user-1", Username: "alice"}
+
+	before, _, err := issuer.Issue(user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	issuer.SetSecret("second-secret")
+
+	if claims, err := issuer.Verify(before); err != nil || claims.UserID() != "user-1" {
+		t.Fatalf("Verify of a token signed before rotation = %v, %v; want user-1", claims, err)
+	}
+	after, _, err := issuer.Issue(user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewTokenIssuer("second-secret", time.Hour).Verify(after); err != nil {
+		t.Errorf("token issued after rotation was not signed with the new secret: %v", err)
+	}
+
+	issuer.SetSecret("third-secret")
+	if _, err := issuer.Verify(before); err != ErrInvalidToken {
+		t.Errorf("Verify of a token two rotations old = %v, want ErrInvalidToken", err)
+	}
+	forged, _, _ := NewTokenIssuer("unknown-secret", time.Hour).Issue(user)
+	if _, err := issuer.Verify(forged); err != ErrInvalidToken {
+		t.Errorf("Verify of a token from an unknown secret = %v, want ErrInvalidToken", err)
+	}
+}
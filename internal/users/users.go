@@ -0,0 +1,219 @@
+package users
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/tinle0301/streaming-platform-api/internal/store"
+	"github.com/tinle0301/streaming-platform-api/internal/tenancy"
+)
+
+// Account errors
+var (
+	ErrNotFound           = errors.New("user not found")
+	ErrUsernameTaken      = errors.New("username is already taken")
+	ErrEmailTaken         = errors.New("email is already registered")
+	ErrInvalidCredentials = errors.New("invalid username/email or password")
+	ErrDeactivated        = errors.New("this account has been deactivated")
+)
+
+// User is a registered account
+type User struct {
+	ID           string
+	Username     string
+	Email        string
+	PasswordHash string
+	DisplayName  string
+	Bio          string
+	AvatarURL    string
+	BannerURL    string
+	IsPartner    bool
+	IsAffiliate  bool
+	TenantID     string
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+
+	// DeactivatedAt is set while the account is deactivated, e.g. by its
+	// organization's directory; deactivated accounts can't sign in
+	DeactivatedAt *time.Time
+}
+
+// Repository persists user accounts. Reads only see users of the context's
+// tenant, and users are created in it; usernames and emails are unique
+// within a tenant.
+type Repository interface {
+	Create(ctx context.Context, user *User) error
+	Get(ctx context.Context, id string) (*User, error)
+	GetMany(ctx context.Context, ids []string) (map[string]*User, error)
+	GetByLogin(ctx context.Context, login string) (*User, error)
+
+	// Update saves a user's email and display name
+	Update(ctx context.Context, user *User) error
+
+	// SetDeactivated deactivates or reactivates a user
+	SetDeactivated(ctx context.Context, id string, deactivated bool) error
+}
+
+// userColumns is the column list shared by user queries; queries alias users as u
+const userColumns = `u.id::text, u.username, u.email, u.password_hash, u.display_name, COALESCE(u.bio, ''),
+	COALESCE(u.avatar_url, ''), COALESCE(u.banner_url, ''), u.is_partner, u.is_affiliate, u.tenant_id, u.created_at, u.updated_at, u.deactivated_at`
+
+// tenantScope restricts a user query to the context's tenant; n is the
+// placeholder number of the tenancy.Scope argument
+func tenantScope(n int) string {
+	return tenancy.Condition("u.tenant_id", n)
+}
+
+// PostgresRepository implements Repository on PostgreSQL
+type PostgresRepository struct {
+	pool *pgxpool.Pool
+}
+
+// NewPostgresRepository creates a user repository on pool
+func NewPostgresRepository(pool *pgxpool.Pool) *PostgresRepository {
+	return &PostgresRepository{pool: pool}
+}
+
+// Create inserts a user and fills in its generated ID and timestamps
+func (r *PostgresRepository) Create(ctx context.Context, user *User) error {
+	err := r.pool.QueryRow(ctx, `
+		INSERT INTO users (username, email, password_hash, display_name, bio, avatar_url, banner_url, tenant_id)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''), NULLIF($6, ''), NULLIF($7, ''), $8)
+		RETURNING id::text, created_at, updated_at`,
+		user.Username, user.Email, user.PasswordHash, user.DisplayName, user.Bio, user.AvatarURL, user.BannerURL,
+		tenancy.ID(ctx),
+	).Scan(&user.ID, &user.CreatedAt, &user.UpdatedAt)
+
+	if store.PgCode(err) == "23505" {
+		if strings.Contains(store.PgConstraint(err), "email") {
+			return ErrEmailTaken
+		}
+		return ErrUsernameTaken
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create user: %w", err)
+	}
+	user.TenantID = tenancy.ID(ctx)
+	return nil
+}
+
+// Get returns a user by ID
+func (r *PostgresRepository) Get(ctx context.Context, id string) (*User, error) {
+	if !store.IsUUID(id) {
+		return nil, ErrNotFound
+	}
+	return r.getOne(ctx, `SELECT `+userColumns+` FROM users u WHERE u.id = $1`+tenantScope(2), id, tenancy.Scope(ctx))
+}
+
+// GetMany returns the users with the given IDs, keyed by ID; unknown IDs
+// are left out
+func (r *PostgresRepository) GetMany(ctx context.Context, ids []string) (map[string]*User, error) {
+	found := make(map[string]*User, len(ids))
+	valid := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if store.IsUUID(id) {
+			valid = append(valid, id)
+		}
+	}
+	if len(valid) == 0 {
+		return found, nil
+	}
+
+	rows, err := r.pool.Query(ctx, `SELECT `+userColumns+` FROM users u WHERE u.id = ANY($1::uuid[])`+tenantScope(2),
+		valid, tenancy.Scope(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get users: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan user: %w", err)
+		}
+		found[user.ID] = user
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get users: %w", err)
+	}
+	return found, nil
+}
+
+// GetByLogin returns a user by username or email, ignoring case
+func (r *PostgresRepository) GetByLogin(ctx context.Context, login string) (*User, error) {
+	return r.getOne(ctx, `SELECT `+userColumns+` FROM users u
+		WHERE (LOWER(u.username) = LOWER($1) OR LOWER(u.email) = LOWER($1))`+tenantScope(2), login, tenancy.Scope(ctx))
+}
+
+// Update saves a user's email and display name
+func (r *PostgresRepository) Update(ctx context.Context, user *User) error {
+	if !store.IsUUID(user.ID) {
+		return ErrNotFound
+	}
+
+	err := r.pool.QueryRow(ctx, `
+		UPDATE users u SET email = $2, display_name = $3, updated_at = NOW()
+		WHERE u.id = $1`+tenantScope(4)+`
+		RETURNING u.updated_at`, user.ID, user.Email, user.DisplayName, tenancy.Scope(ctx),
+	).Scan(&user.UpdatedAt)
+	if store.PgCode(err) == "23505" {
+		return ErrEmailTaken
+	}
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update user: %w", err)
+	}
+	return nil
+}
+
+// SetDeactivated sets or clears a user's deactivated_at
+func (r *PostgresRepository) SetDeactivated(ctx context.Context, id string, deactivated bool) error {
+	if !store.IsUUID(id) {
+		return ErrNotFound
+	}
+
+	tag, err := r.pool.Exec(ctx, `
+		UPDATE users u SET updated_at = NOW(),
+			deactivated_at = CASE WHEN $2 THEN COALESCE(u.deactivated_at, NOW()) END
+		WHERE u.id = $1`+tenantScope(3), id, deactivated, tenancy.Scope(ctx))
+	if err != nil {
+		return fmt.Errorf("failed to deactivate user: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// getOne runs a query selecting userColumns and scans a single user
+func (r *PostgresRepository) getOne(ctx context.Context, query string, args ...interface{}) (*User, error) {
+	user, err := scanUser(r.pool.QueryRow(ctx, query, args...))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	return user, nil
+}
+
+// scanUser reads a row selected with userColumns
+func scanUser(row pgx.Row) (*User, error) {
+	var user User
+	err := row.Scan(
+		&user.ID, &user.Username, &user.Email, &user.PasswordHash, &user.DisplayName, &user.Bio,
+		&user.AvatarURL, &user.BannerURL, &user.IsPartner, &user.IsAffiliate, &user.TenantID, &user.CreatedAt, &user.UpdatedAt,
+		&user.DeactivatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
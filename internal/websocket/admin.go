@@ -0,0 +1,215 @@
+package websocket
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tinle0301/streaming-platform-api/internal/websocket/closecode"
+)
+
+// AdminPath is where AdminHandler is mounted
+const AdminPath = "/admin/hub"
+
+// HubStatus is a node's live metrics and feature switches
+type HubStatus struct {
+	ActiveConnections   int32           `json:"active_connections"`
+	TotalConnections    int64           `json:"total_connections"`
+	MessagesSent        int64           `json:"messages_sent"`
+	MessagesReceived    int64           `json:"messages_received"`
+	RateLimitedMessages int64           `json:"rate_limited_messages"`
+	LastMessageAt       time.Time       `json:"last_message_at"`
+	Rooms               int             `json:"rooms"`
+	Draining            bool            `json:"draining"`
+	Features            map[string]bool `json:"features"`
+}
+
+// RoomSummary is a room and how many clients are in it
+type RoomSummary struct {
+	Room    string `json:"room"`
+	Clients int    `json:"clients"`
+}
+
+// RoomClient is a connection in a room
+type RoomClient struct {
+	UserID string `json:"user_id"`
+	Session
+}
+
+// Status returns the node's live metrics and feature switches
+func (h *Hub) Status() HubStatus {
+	metrics := h.GetMetrics()
+
+	h.mu.RLock()
+	rooms := len(h.rooms)
+	h.mu.RUnlock()
+
+	return HubStatus{
+		ActiveConnections:   metrics.ActiveConnections,
+		TotalConnections:    metrics.TotalConnections,
+		MessagesSent:        metrics.TotalMessagesSent,
+		MessagesReceived:    metrics.TotalMessagesRecv,
+		RateLimitedMessages: metrics.RateLimitedMessages,
+		LastMessageAt:       metrics.LastMessageTime,
+		Rooms:               rooms,
+		Draining:            h.Draining(),
+		Features:            h.Features(),
+	}
+}
+
+// Rooms lists the node's rooms, busiest first
+func (h *Hub) Rooms() []RoomSummary {
+	h.mu.RLock()
+	rooms := make([]RoomSummary, 0, len(h.rooms))
+	for room, clients := range h.rooms {
+		rooms = append(rooms, RoomSummary{Room: room, Clients: len(clients)})
+	}
+	h.mu.RUnlock()
+
+	sort.Slice(rooms, func(i, j int) bool {
+		if rooms[i].Clients != rooms[j].Clients {
+			return rooms[i].Clients > rooms[j].Clients
+		}
+		return rooms[i].Room < rooms[j].Room
+	})
+	return rooms
+}
+
+// RoomClients lists the connections in a room on this node, oldest first
+func (h *Hub) RoomClients(room string) []RoomClient {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	clients := make([]RoomClient, 0, len(h.rooms[room]))
+	for client := range h.rooms[room] {
+		client.mu.RLock()
+		clients = append(clients, RoomClient{
+			UserID: client.userID,
+			Session: Session{
+				ID:          client.sessionID,
+				Device:      client.device,
+				RemoteIP:    client.remoteIP,
+				ConnectedAt: client.connectedAt,
+				Rooms:       len(client.rooms),
+				Spectator:   client.spectator,
+			},
+		})
+		client.mu.RUnlock()
+	}
+
+	sort.Slice(clients, func(i, j int) bool {
+		return clients[i].ConnectedAt.Before(clients[j].ConnectedAt)
+	})
+	return clients
+}
+
+// Disconnect closes one of a user's connections on an operator's request
+// and reports whether it existed. The client may reconnect after a delay.
+func (h *Hub) Disconnect(userID, sessionID string) bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for client := range h.userClients[userID] {
+		if client.sessionID == sessionID {
+			client.Disconnect(closecode.Disconnected)
+			return true
+		}
+	}
+	return false
+}
+
+// AdminHandler serves the hub's operations API to bearers of token:
+//
+//	GET    /admin/hub                          status and feature switches
+//	GET    /admin/hub/rooms?limit=N            rooms, busiest first
+//	GET    /admin/hub/rooms/clients?room=R     connections in a room
+//	DELETE /admin/hub/sessions?user_id=U&id=S  disconnect a connection
+//	PUT    /admin/hub/features                 {"feature": "...", "enabled": false}
+//	GET    /admin/hub/snapshot?redact=R        full state dump; R is all (the
+//	                                           default), none, or a list of
+//	                                           user_ids, ips, metadata
+func AdminHandler(hub *Hub, token string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		presented := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(presented), []byte(token)) != 1 {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		query := r.URL.Query()
+		switch route := strings.TrimPrefix(r.URL.Path, AdminPath); {
+		case route == "" && r.Method == http.MethodGet:
+			writeAdminJSON(w, hub.Status())
+
+		case route == "/rooms" && r.Method == http.MethodGet:
+			rooms := hub.Rooms()
+			if limit, err := strconv.Atoi(query.Get("limit")); err == nil && limit >= 0 && limit < len(rooms) {
+				rooms = rooms[:limit]
+			}
+			writeAdminJSON(w, rooms)
+
+		case route == "/rooms/clients" && r.Method == http.MethodGet:
+			if query.Get("room") == "" {
+				http.Error(w, "room is required", http.StatusBadRequest)
+				return
+			}
+			writeAdminJSON(w, hub.RoomClients(query.Get("room")))
+
+		case route == "/sessions" && r.Method == http.MethodDelete:
+			if query.Get("user_id") == "" || query.Get("id") == "" {
+				http.Error(w, "user_id and id are required", http.StatusBadRequest)
+				return
+			}
+			if !hub.Disconnect(query.Get("user_id"), query.Get("id")) {
+				http.Error(w, "Not found", http.StatusNotFound)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+
+		case route == "/features" && r.Method == http.MethodPut:
+			var request struct {
+				Feature string `json:"feature"`
+				Enabled bool   `json:"enabled"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+				http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+				return
+			}
+			if err := hub.SetFeature(request.Feature, request.Enabled); errors.Is(err, ErrUnknownFeature) {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			writeAdminJSON(w, hub.Features())
+
+		case route == "/snapshot" && r.Method == http.MethodGet:
+			redaction := RedactAll
+			if value := query.Get("redact"); value != "" {
+				var err error
+				if redaction, err = ParseSnapshotRedaction(value); err != nil {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+					return
+				}
+			}
+			snapshot := hub.Snapshot(redaction)
+			w.Header().Set("Content-Disposition", `attachment; filename="hub-snapshot-`+snapshot.TakenAt.UTC().Format("20060102T150405Z")+`.json"`)
+			writeAdminJSON(w, snapshot)
+
+		case route == "" || route == "/rooms" || route == "/rooms/clients" || route == "/sessions" || route == "/features" || route == "/snapshot":
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+
+		default:
+			http.NotFound(w, r)
+		}
+	})
+}
+
+// writeAdminJSON writes an admin API response
+func writeAdminJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,141 @@
+package websocket
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// adminRequest sends a request to the hub's admin API
+func adminRequest(t *testing.T, handler http.Handler, method, target, body, token string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestAdminHandler(t *testing.T) {
+	hub := startRaceHub(t)
+	handler := AdminHandler(hub, "secret")
+
+	alice := newDrainedClient(hub, "alice")
+	bob := newDrainedClient(hub, "bob")
+	hub.AddClient(alice)
+	hub.AddClient(bob)
+	waitForHub(t, hub)
+	t.Cleanup(func() { hub.unregister(alice) })
+	hub.JoinRoom("stream-1", alice)
+	hub.JoinRoom("stream-1", bob)
+	hub.JoinRoom("stream-2", bob)
+
+	if rec := adminRequest(t, handler, http.MethodGet, AdminPath, "", "wrong"); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("wrong token = %d, want 401", rec.Code)
+	}
+
+	var status HubStatus
+	rec := adminRequest(t, handler, http.MethodGet, AdminPath, "", "secret")
+	if err := json.Unmarshal(rec.Body.Bytes(), &status); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("status = %d %s", rec.Code, rec.Body)
+	}
+	if status.ActiveConnections != 2 || status.Rooms != 2 || !status.Features[FeatureChatHistory] {
+		t.Errorf("status = %+v, want 2 connections in 2 rooms with features on", status)
+	}
+
+	var rooms []RoomSummary
+	rec = adminRequest(t, handler, http.MethodGet, AdminPath+"/rooms?limit=1", "", "secret")
+	json.Unmarshal(rec.Body.Bytes(), &rooms)
+	if len(rooms) != 1 || rooms[0] != (RoomSummary{Room: "stream-1", Clients: 2}) {
+		t.Errorf("rooms = %+v, want only stream-1 with 2 clients", rooms)
+	}
+
+	var clients []RoomClient
+	rec = adminRequest(t, handler, http.MethodGet, AdminPath+"/rooms/clients?room=stream-2", "", "secret")
+	json.Unmarshal(rec.Body.Bytes(), &clients)
+	if len(clients) != 1 || clients[0].UserID != "bob" || clients[0].ID != bob.sessionID || clients[0].Rooms != 2 {
+		t.Fatalf("stream-2 clients = %+v, want bob in 2 rooms", clients)
+	}
+
+	rec = adminRequest(t, handler, http.MethodDelete, AdminPath+"/sessions?user_id=bob&id="+bob.sessionID, "", "secret")
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("disconnect = %d %s, want 204", rec.Code, rec.Body)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for hub.GetRoomCount("stream-2") != 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("disconnected client is still in its room")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if rec := adminRequest(t, handler, http.MethodDelete, AdminPath+"/sessions?user_id=bob&id="+bob.sessionID, "", "secret"); rec.Code != http.StatusNotFound {
+		t.Errorf("disconnecting again = %d, want 404", rec.Code)
+	}
+}
+
+func TestAdminHandlerSwitchesFeatures(t *testing.T) {
+	hub := NewHub()
+	handler := AdminHandler(hub, "secret")
+
+	rec := adminRequest(t, handler, http.MethodPut, AdminPath+"/features", `{"feature":"viewer_counts","enabled":false}`, "secret")
+	var features map[string]bool
+	if err := json.Unmarshal(rec.Body.Bytes(), &features); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("switching off = %d %s", rec.Code, rec.Body)
+	}
+	if features[FeatureViewerCounts] || !features[FeatureChatSampling] || hub.featureEnabled(FeatureViewerCounts) {
+		t.Errorf("features = %v, want only viewer_counts off", features)
+	}
+
+	if rec := adminRequest(t, handler, http.MethodPut, AdminPath+"/features", `{"feature":"nope","enabled":false}`, "secret"); rec.Code != http.StatusBadRequest {
+		t.Errorf("unknown feature = %d, want 400", rec.Code)
+	}
+	if rec := adminRequest(t, handler, http.MethodPost, AdminPath+"/features", `{}`, "secret"); rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("POST = %d, want 405", rec.Code)
+	}
+}
+
+func TestAdminHandlerSnapshot(t *testing.T) {
+	hub := startRaceHub(t)
+	handler := AdminHandler(hub, "secret")
+
+	alice := newDrainedClient(hub, "alice")
+	alice.SetRemoteIP("203.0.113.7")
+	alice.SetMetadata("email", "alice@example.com")
+	hub.AddClient(alice)
+	waitForHub(t, hub)
+	t.Cleanup(func() { hub.unregister(alice) })
+	hub.JoinRoom("stream-1", alice)
+	alice.allowMessage(&Message{Type: "message"}, time.Now())
+
+	var snapshot HubSnapshot
+	rec := adminRequest(t, handler, http.MethodGet, AdminPath+"/snapshot", "", "secret")
+	if err := json.Unmarshal(rec.Body.Bytes(), &snapshot); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("snapshot = %d %s", rec.Code, rec.Body)
+	}
+	if len(snapshot.Clients) != 1 || len(snapshot.Rooms) != 1 || snapshot.Status.ActiveConnections != 1 {
+		t.Fatalf("snapshot = %+v, want alice in stream-1", snapshot)
+	}
+	client := snapshot.Clients[0]
+	if client.UserID == "alice" || client.RemoteIP != "203.0.113.0/24" || client.Metadata["email"] != "redacted" {
+		t.Errorf("redacted client = %+v, want no personal data", client)
+	}
+	if client.SendCapacity != sendBufferSize || client.RateLimit.Tokens["message"] >= 10 {
+		t.Errorf("client buffers and limits = %+v", client)
+	}
+	if len(snapshot.IPRateLimits) != 1 || snapshot.IPRateLimits[0].IP != "203.0.113.0/24" {
+		t.Errorf("IP rate limits = %+v, want alice's network", snapshot.IPRateLimits)
+	}
+
+	rec = adminRequest(t, handler, http.MethodGet, AdminPath+"/snapshot?redact=metadata", "", "secret")
+	json.Unmarshal(rec.Body.Bytes(), &snapshot)
+	if client := snapshot.Clients[0]; client.UserID != "alice" || client.RemoteIP != "203.0.113.7" || client.Metadata["email"] != "redacted" {
+		t.Errorf("client = %+v, want only metadata redacted", client)
+	}
+
+	if rec := adminRequest(t, handler, http.MethodGet, AdminPath+"/snapshot?redact=names", "", "secret"); rec.Code != http.StatusBadRequest {
+		t.Errorf("unknown redaction = %d, want 400", rec.Code)
+	}
+}
@@ -0,0 +1,171 @@
+package websocket
+
+import (
+	"context"
+	"errors"
+	mathrand "math/rand"
+	"sync"
+	"time"
+
+	"github.com/tinle0301/streaming-platform-api/internal/ratelimit"
+)
+
+// ErrUpgradeQueueFull is returned when too many upgrades are already waiting
+var ErrUpgradeQueueFull = errors.New("upgrade queue full")
+
+// UpgradeLimiter admits WebSocket upgrades at a steady rate. Requests over
+// the rate wait in a bounded queue instead of being rejected outright, so a
+// reconnect storm is smoothed out rather than bounced back immediately.
+type UpgradeLimiter struct {
+	bucket  *ratelimit.TokenBucket
+	slots   chan struct{}
+	maxWait time.Duration
+}
+
+// NewUpgradeLimiter admits rate upgrades/sec with the given burst; up to
+// queueSize requests may wait at most maxWait for admission
+func NewUpgradeLimiter(rate float64, burst, queueSize int, maxWait time.Duration) *UpgradeLimiter {
+	return &UpgradeLimiter{
+		bucket:  ratelimit.NewTokenBucket(rate, burst),
+		slots:   make(chan struct{}, queueSize),
+		maxWait: maxWait,
+	}
+}
+
+// Wait blocks until the upgrade is admitted, the queue is full, or maxWait elapses
+func (l *UpgradeLimiter) Wait(ctx context.Context) error {
+	if l.bucket.Allow() {
+		return nil
+	}
+
+	select {
+	case l.slots <- struct{}{}:
+		defer func() { <-l.slots }()
+	default:
+		return ErrUpgradeQueueFull
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, l.maxWait)
+	defer cancel()
+
+	for {
+		delay := l.bucket.Delay()
+		if delay <= 0 {
+			delay = time.Millisecond
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+			if l.bucket.Allow() {
+				return nil
+			}
+		}
+	}
+}
+
+// ReconnectJitter returns a randomized reconnect delay in [base, base+spread)
+func ReconnectJitter(base, spread time.Duration) time.Duration {
+	if spread <= 0 {
+		return base
+	}
+	return base + time.Duration(mathrand.Int63n(int64(spread)))
+}
+
+// StateLoader loads one section of a room's state snapshot
+type StateLoader func(ctx context.Context, room string) (map[string]interface{}, error)
+
+// stateCacheEntry is a cached snapshot or an in-flight load
+type stateCacheEntry struct {
+	state     map[string]interface{}
+	err       error
+	expiresAt time.Time
+	ready     chan struct{}
+}
+
+// StateCache caches stream state snapshots per room with a short TTL.
+// Concurrent requests for the same room share a single load, so a burst of
+// reconnecting clients costs one backend query per room. Expired entries
+// are swept as new rooms load, and Evict drops a closed room's entry.
+type StateCache struct {
+	loader  StateLoader
+	ttl     time.Duration
+	entries map[string]*stateCacheEntry
+	sweptAt time.Time
+	mu      sync.Mutex
+}
+
+// NewStateCache creates a snapshot cache around loader
+func NewStateCache(loader StateLoader, ttl time.Duration) *StateCache {
+	return &StateCache{
+		loader:  loader,
+		ttl:     ttl,
+		entries: make(map[string]*stateCacheEntry),
+	}
+}
+
+// Get returns the cached snapshot for room, loading it if needed
+func (c *StateCache) Get(ctx context.Context, room string) (map[string]interface{}, error) {
+	c.mu.Lock()
+	entry, ok := c.entries[room]
+	if ok {
+		select {
+		case <-entry.ready:
+			if time.Now().Before(entry.expiresAt) {
+				c.mu.Unlock()
+				return entry.state, entry.err
+			}
+		default:
+			// Load in flight: wait for it below
+			c.mu.Unlock()
+			<-entry.ready
+			return entry.state, entry.err
+		}
+	}
+
+	c.sweep(time.Now())
+	entry = &stateCacheEntry{ready: make(chan struct{})}
+	c.entries[room] = entry
+	c.mu.Unlock()
+
+	entry.state, entry.err = c.loader(ctx, room)
+	ttl := c.ttl
+	if entry.err != nil {
+		// Cache failures briefly so a broken backend is not hammered
+		ttl = c.ttl / 4
+	}
+	entry.expiresAt = time.Now().Add(ttl)
+	close(entry.ready)
+
+	return entry.state, entry.err
+}
+
+// Evict drops the cached snapshot for room, as when the room closes. A load
+// in flight still completes for the requests waiting on it.
+func (c *StateCache) Evict(room string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, room)
+}
+
+// sweep removes expired entries, at most once per ttl. c.mu must be held.
+func (c *StateCache) sweep(now time.Time) {
+	if now.Sub(c.sweptAt) < c.ttl {
+		return
+	}
+	c.sweptAt = now
+
+	for room, entry := range c.entries {
+		select {
+		case <-entry.ready:
+			if !now.Before(entry.expiresAt) {
+				delete(c.entries, room)
+			}
+		default:
+			// Load in flight
+		}
+	}
+}
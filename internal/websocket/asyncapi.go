@@ -0,0 +1,202 @@
+package websocket
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// AsyncAPIVersion is the AsyncAPI specification the document follows
+const AsyncAPIVersion = "2.6.0"
+
+// asyncAPIChannel is the channel every message travels over
+const asyncAPIChannel = "/ws"
+
+// AsyncAPIInfo describes the server in the AsyncAPI document
+type AsyncAPIInfo struct {
+	Title       string
+	Description string
+
+	// ServerURL is the public WebSocket endpoint, e.g.
+	// wss://ws.example.com/ws; the document lists no servers without it
+	ServerURL string
+}
+
+// DefaultAsyncAPIInfo returns the document's title and description
+func DefaultAsyncAPIInfo() AsyncAPIInfo {
+	return AsyncAPIInfo{
+		Title: "StreamHub WebSocket API",
+		Description: "Real-time chat, room updates, and notifications. Every message is a JSON envelope " +
+			"with a type, an optional room, and data. Rooms in a white-label tenant's namespace are " +
+			"prefixed with tenant:<tenant_id>:.",
+	}
+}
+
+// AsyncAPI generates the AsyncAPI document describing every message type,
+// its payload, and the rooms it is scoped to, from the message registry
+func AsyncAPI(info AsyncAPIInfo) map[string]interface{} {
+	messages := make(map[string]interface{}, len(Messages))
+	var sent, received []interface{}
+	for _, spec := range Messages {
+		name := string(spec.Direction) + "." + spec.Type
+		messages[name] = asyncAPIMessage(spec)
+
+		ref := map[string]interface{}{"$ref": "#/components/messages/" + name}
+		if spec.Direction == FromClient {
+			sent = append(sent, ref)
+		} else {
+			received = append(received, ref)
+		}
+	}
+
+	rooms := make([]interface{}, 0, len(Rooms))
+	for _, room := range Rooms {
+		rooms = append(rooms, map[string]interface{}{
+			"kind":        room.Kind,
+			"pattern":     room.Pattern,
+			"description": room.Description,
+		})
+	}
+
+	doc := map[string]interface{}{
+		"asyncapi": AsyncAPIVersion,
+		"info": map[string]interface{}{
+			"title":       info.Title,
+			"version":     ProtocolVersion,
+			"description": info.Description,
+		},
+		"defaultContentType": "application/json",
+		"channels": map[string]interface{}{
+			asyncAPIChannel: map[string]interface{}{
+				"description": "One connection carries every room the client subscribes to.",
+				"bindings": map[string]interface{}{
+					"ws": map[string]interface{}{
+						"method":         http.MethodGet,
+						"query":          handshakeQuery(),
+						"bindingVersion": "0.1.0",
+					},
+				},
+				"publish": map[string]interface{}{
+					"operationId": "sendMessage",
+					"summary":     "Messages clients send",
+					"message":     map[string]interface{}{"oneOf": sent},
+				},
+				"subscribe": map[string]interface{}{
+					"operationId": "receiveMessage",
+					"summary":     "Messages clients receive",
+					"message":     map[string]interface{}{"oneOf": received},
+				},
+			},
+		},
+		"components": map[string]interface{}{
+			"messages": messages,
+		},
+		"x-rooms": rooms,
+	}
+
+	// Servers hold the host and any base path; the channel adds /ws
+	if server, err := url.Parse(info.ServerURL); err == nil && server.Host != "" {
+		doc["servers"] = map[string]interface{}{
+			"production": map[string]interface{}{
+				"url":      server.Host + strings.TrimSuffix(server.Path, asyncAPIChannel),
+				"protocol": server.Scheme,
+			},
+		}
+	}
+	return doc
+}
+
+// asyncAPIMessage describes one message type
+func asyncAPIMessage(spec MessageSpec) map[string]interface{} {
+	properties := make(map[string]interface{}, len(spec.Fields))
+	required := []string{}
+	for _, field := range spec.Fields {
+		properties[field.Name] = fieldSchema(field)
+		if field.Required {
+			required = append(required, field.Name)
+		}
+	}
+	data := map[string]interface{}{
+		"type":                 "object",
+		"properties":           properties,
+		"additionalProperties": spec.Open,
+	}
+	if len(required) > 0 {
+		data["required"] = required
+	}
+
+	envelope := map[string]interface{}{
+		"type": map[string]interface{}{"type": "string", "const": spec.Type},
+		"room": map[string]interface{}{"type": "string", "description": "Room the message belongs to"},
+		"data": data,
+		"id":   map[string]interface{}{"type": "string", "description": "Client-chosen ID echoed in the ref of error replies"},
+		"sig":  map[string]interface{}{"type": "string", "description": "Hex HMAC-SHA256 of the frame without sig, as its last field; required from registered bots"},
+	}
+	if spec.Direction == FromServer {
+		delete(envelope, "id")
+		delete(envelope, "sig")
+		envelope["timestamp"] = map[string]interface{}{"type": "string", "format": "date-time"}
+	}
+
+	message := map[string]interface{}{
+		"name":    spec.Type,
+		"title":   spec.Type,
+		"summary": spec.Summary,
+		"payload": map[string]interface{}{
+			"type":       "object",
+			"required":   []string{"type"},
+			"properties": envelope,
+		},
+		"x-direction": string(spec.Direction),
+	}
+	if spec.Room != "" {
+		message["x-room"] = spec.Room
+	}
+	return message
+}
+
+// fieldSchema is the JSON Schema of a data field
+func fieldSchema(field Field) map[string]interface{} {
+	schema := map[string]interface{}{"type": field.Type}
+	if field.Description != "" {
+		schema["description"] = field.Description
+	}
+	if field.Type == FieldArray && field.Items != "" {
+		schema["items"] = map[string]interface{}{"type": field.Items}
+	}
+	return schema
+}
+
+// handshakeQuery is the JSON Schema of the /ws query parameters
+func handshakeQuery() map[string]interface{} {
+	param := func(description string) map[string]interface{} {
+		return map[string]interface{}{"type": "string", "description": description}
+	}
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"token":         param("Access token, if not sent as an Authorization bearer token; connections without one are guests"),
+			"tenant":        param("White-label tenant a guest connects to"),
+			"mode":          param("spectator for receive-only viewers"),
+			"rooms":         param("Comma-separated rooms a spectator joins"),
+			"device":        param("Label for this connection in the user's session list"),
+			"resume_token":  param("Token from a session message, to resume a dropped session"),
+			"handoff_token": param("Token from a reconnect message, to keep rooms across a node handoff"),
+			"compression":   param("off to decline permessage-deflate, e.g. on CPU-constrained devices"),
+		},
+	}
+}
+
+// AsyncAPIHandler serves the AsyncAPI document, generated once
+func AsyncAPIHandler(info AsyncAPIInfo) http.HandlerFunc {
+	body, err := json.MarshalIndent(AsyncAPI(info), "", "  ")
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err != nil {
+			http.Error(w, "Failed to generate AsyncAPI document", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(body)
+	}
+}
@@ -0,0 +1,206 @@
+package websocket
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"github.com/tinle0301/streaming-platform-api/internal/tenancy"
+	"github.com/tinle0301/streaming-platform-api/internal/websocket/closecode"
+)
+
+// authExpiryWarning is how long before token expiry clients are asked to refresh
+const authExpiryWarning = time.Minute
+
+// ErrTokenVerifierMissing is returned when tokens are presented to a hub
+// without a TokenVerifier
+var ErrTokenVerifierMissing = errors.New("token authentication is not configured")
+
+// TokenClaims are the verified contents of an access or guest token
+type TokenClaims struct {
+	UserID    string
+	ExpiresAt time.Time
+	Guest     bool
+
+	// TenantID is the tenant the token was issued in; empty means
+	// tenancy.Default
+	TenantID string
+}
+
+// TokenVerifier validates access tokens presented by clients
+type TokenVerifier interface {
+	VerifyToken(token string) (TokenClaims, error)
+}
+
+// TokenVerifierFunc adapts a function to TokenVerifier
+type TokenVerifierFunc func(token string) (TokenClaims, error)
+
+// VerifyToken calls f
+func (f TokenVerifierFunc) VerifyToken(token string) (TokenClaims, error) {
+	return f(token)
+}
+
+// RoomAuthorizer decides whether a user, connecting from clientIP, may be
+// in a room. It is checked on subscribe and again whenever a client's
+// credentials change.
+type RoomAuthorizer interface {
+	CanJoin(userID, clientIP, room string) bool
+}
+
+// authState tracks when a client's access token expires
+type authState struct {
+	expiresAt time.Time
+	warned    bool
+}
+
+// SetTokenVerifier configures access token validation for auth_refresh
+func (h *Hub) SetTokenVerifier(verifier TokenVerifier) {
+	h.tokenVerifier = verifier
+}
+
+// SetRoomAuthorizer configures the room access check
+func (h *Hub) SetRoomAuthorizer(authorizer RoomAuthorizer) {
+	h.roomAuthorizer = authorizer
+}
+
+// TokenAuthEnabled reports whether the hub can verify access tokens
+func (h *Hub) TokenAuthEnabled() bool {
+	return h.tokenVerifier != nil
+}
+
+// Authenticate verifies an access token presented at connect time
+func (h *Hub) Authenticate(token string) (TokenClaims, error) {
+	if h.tokenVerifier == nil {
+		return TokenClaims{}, ErrTokenVerifierMissing
+	}
+	return h.tokenVerifier.VerifyToken(token)
+}
+
+// canJoin reports whether client may be in room. Rooms outside the
+// client's tenant namespace are never joinable. Guests already in a room
+// keep it; otherwise they are held to the guest room limit. Community rooms
+// can only be joined while open.
+func (h *Hub) canJoin(client *Client, room string) bool {
+	if tenantID, _ := tenancy.SplitRoom(room); tenantID != client.Tenant() {
+		return false
+	}
+	if client.IsGuest() && !client.IsInRoom(room) {
+		if max := h.guestPolicy.MaxRooms; max > 0 && len(client.GetRooms()) >= max {
+			return false
+		}
+	}
+	if !h.communityRoomOpen(room) {
+		return false
+	}
+	return h.roomAuthorizer == nil || h.roomAuthorizer.CanJoin(client.GetUserID(), client.RemoteIP(), room)
+}
+
+// SetClaims records the client's verified token: whether it is a guest and
+// when the token expires. Unless it is refreshed, the connection is closed
+// with closecode.AuthExpired at expiry.
+func (c *Client) SetClaims(claims TokenClaims) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.guest = claims.Guest
+	c.tenant = tenantOf(claims)
+	c.auth = authState{expiresAt: claims.ExpiresAt}
+}
+
+// Tenant returns the white-label tenant the client belongs to
+func (c *Client) Tenant() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.tenant == "" {
+		return tenancy.Default
+	}
+	return c.tenant
+}
+
+// handleAuthRefresh validates a new token presented mid-connection. A guest
+// presenting an account token is upgraded in place.
+func (c *Client) handleAuthRefresh(msg *Message) {
+	token, _ := msg.Data["token"].(string)
+	if token == "" {
+		c.sendError(ErrorCodeAuthInvalid, "token is required", msg)
+		return
+	}
+
+	claims, err := c.hub.Authenticate(token)
+	if err != nil {
+		c.sendError(ErrorCodeAuthInvalid, "invalid or expired token", msg)
+		return
+	}
+
+	switch {
+	case tenantOf(claims) != c.Tenant():
+		c.sendError(ErrorCodeAuthInvalid, "token belongs to a different tenant", msg)
+		return
+	case c.IsGuest() && !claims.Guest:
+		c.hub.upgradeGuest(c, claims)
+	case claims.UserID == c.GetUserID() && claims.Guest == c.IsGuest():
+		c.SetClaims(claims)
+	default:
+		c.sendError(ErrorCodeAuthInvalid, "token belongs to a different user", msg)
+		return
+	}
+	c.reauthorizeRooms()
+
+	c.sendMessage("auth_refreshed", map[string]interface{}{
+		"user_id":    claims.UserID,
+		"guest":      claims.Guest,
+		"expires_at": claims.ExpiresAt.Unix(),
+	})
+}
+
+// reauthorizeRooms leaves rooms the client may no longer be in
+func (c *Client) reauthorizeRooms() {
+	for _, room := range c.GetRooms() {
+		if c.hub.canJoin(c, room) {
+			continue
+		}
+		c.hub.LeaveRoom(room, c)
+		c.sendAck("revoked", room)
+	}
+}
+
+// expireSessions warns clients whose tokens are about to expire, renews
+// guest tokens, and disconnects clients whose tokens have expired
+func (h *Hub) expireSessions(now time.Time) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for client := range h.clients {
+		client.mu.Lock()
+		expiresAt := client.auth.expiresAt
+		guest := client.guest
+		warn := !expiresAt.IsZero() && !client.auth.warned && now.After(expiresAt.Add(-authExpiryWarning))
+		if warn {
+			client.auth.warned = true
+		}
+		client.mu.Unlock()
+
+		switch {
+		case expiresAt.IsZero():
+			continue
+		case now.After(expiresAt):
+			log.Printf("Access token expired, disconnecting: userID=%s", client.userID)
+			client.Disconnect(closecode.AuthExpired)
+		case warn && guest:
+			// Guests have no way to sign a new token themselves
+			go client.renewGuestSession()
+		case warn:
+			client.sendMessage("auth_expiring", map[string]interface{}{
+				"expires_at": expiresAt.Unix(),
+			})
+		}
+	}
+}
+
+// tenantOf returns the tenant of verified token claims
+func tenantOf(claims TokenClaims) string {
+	if claims.TenantID == "" {
+		return tenancy.Default
+	}
+	return claims.TenantID
+}
@@ -0,0 +1,84 @@
+package websocket
+
+import "log"
+
+// App states signaled by mobile clients
+const (
+	AppStateForeground = "foreground"
+	AppStateBackground = "background"
+)
+
+// backgroundMessageTypes are the only message types delivered to a
+// backgrounded client; everything else waits until it foregrounds. Replies
+// to the client's own messages are always delivered.
+var backgroundMessageTypes = map[string]bool{
+	"direct":                true,
+	"critical_notification": true,
+	"reconnect":             true,
+	"ack":                   true,
+	"error":                 true,
+	"pong":                  true,
+}
+
+// SetBackground marks the client as backgrounded or foregrounded
+func (c *Client) SetBackground(background bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.background = background
+}
+
+// IsBackground reports whether the client's app is in the background
+func (c *Client) IsBackground() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.background
+}
+
+// accepts reports whether a message of the given type should be sent to
+// the client now (caller must hold c.mu)
+func (c *Client) accepts(messageType string) bool {
+	return backgroundMessageTypes[messageType] || !c.background
+}
+
+// handleAppState switches the client between foreground and background.
+// On foregrounding, each room's state is resent so the client can catch up
+// on what it missed.
+func (c *Client) handleAppState(msg *Message) {
+	state, _ := msg.Data["state"].(string)
+
+	switch state {
+	case AppStateBackground:
+		c.SetBackground(true)
+	case AppStateForeground:
+		wasBackground := c.IsBackground()
+		c.SetBackground(false)
+		if wasBackground {
+			for _, room := range c.GetRooms() {
+				go c.sendRoomState(room)
+			}
+		}
+	default:
+		log.Printf("Unknown app state from client %s: %s", c.userID, state)
+		c.sendError(ErrorCodeInvalidMessage, "unknown app state", msg)
+		return
+	}
+
+	c.sendMessage("ack", map[string]interface{}{
+		"action": "app_state",
+		"state":  state,
+	})
+}
+
+// SendToUser sends a message directly to every connection of a user
+func (h *Hub) SendToUser(userID, messageType string, data map[string]interface{}) {
+	h.mu.RLock()
+	targets := make([]*Client, 0, len(h.userClients[userID]))
+	for client := range h.userClients[userID] {
+		targets = append(targets, client)
+	}
+	h.mu.RUnlock()
+
+	for _, client := range targets {
+		client.sendMessage(messageType, data)
+	}
+}
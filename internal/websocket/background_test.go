@@ -0,0 +1,36 @@
+package websocket
+
+import "testing"
+
+func TestBackgroundedClientQueue(t *testing.T) {
+	client := NewClient(NewHub(), nil, "alice")
+	client.SetBackground(true)
+
+	queued := func(messageType string) bool {
+		encoded, err := newEncodedMessage(&Message{Type: messageType, Data: map[string]interface{}{}})
+		if err != nil {
+			t.Fatalf("newEncodedMessage(%s) = %v", messageType, err)
+		}
+		before := len(client.send)
+		if !client.queue(encoded) {
+			t.Fatalf("queue(%s) reported a full buffer", messageType)
+		}
+		return len(client.send) > before
+	}
+
+	for _, messageType := range []string{"message", "data_update", "data_patch", "chat_summary", "notification"} {
+		if queued(messageType) {
+			t.Errorf("%s was queued for a backgrounded client", messageType)
+		}
+	}
+	for _, messageType := range []string{"direct", "critical_notification", "ack"} {
+		if !queued(messageType) {
+			t.Errorf("%s was not queued for a backgrounded client", messageType)
+		}
+	}
+
+	client.SetBackground(false)
+	if !queued("message") {
+		t.Error("message was not queued once the client foregrounded")
+	}
+}
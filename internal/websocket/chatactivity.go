@@ -0,0 +1,26 @@
+package websocket
+
+// ChatObserver is told about each chat message relayed to a room, e.g. to
+// record chat activity. Calls are made on the sender's read goroutine and
+// must not block.
+type ChatObserver interface {
+	ChatMessage(room, userID, text string)
+}
+
+// SetChatObserver registers an observer for relayed chat messages
+func (h *Hub) SetChatObserver(observer ChatObserver) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.chatObserver = observer
+}
+
+// observeChat reports a relayed chat message to the chat observer, if any
+func (h *Hub) observeChat(room, userID, text string) {
+	h.mu.RLock()
+	observer := h.chatObserver
+	h.mu.RUnlock()
+
+	if observer != nil {
+		observer.ChatMessage(room, userID, text)
+	}
+}
@@ -0,0 +1,96 @@
+package websocket
+
+import (
+	"log"
+	"time"
+)
+
+// ChatHistory keeps rooms' latest chat for viewers who join later. Calls
+// are made on the chat path and must not block.
+type ChatHistory interface {
+	Append(room, messageID, userID, text string, moderator bool, subscriberTier string, sentAt time.Time)
+	Delete(room, messageID string)
+}
+
+// SetChatHistory records relayed and deleted chat messages in history
+func (h *Hub) SetChatHistory(history ChatHistory) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.chatHistory = history
+}
+
+// recordChat adds a relayed chat message to the room's history, if kept
+func (h *Hub) recordChat(room, messageID, userID, text, subscriberTier string, sentAt time.Time) {
+	h.mu.RLock()
+	history := h.chatHistory
+	moderator := h.roomRoles != nil && h.roomRoles.IsPrivileged(room, userID)
+	h.mu.RUnlock()
+
+	if history != nil && h.featureEnabled(FeatureChatHistory) {
+		history.Append(room, messageID, userID, text, moderator, subscriberTier, sentAt)
+	}
+}
+
+// handleDeleteMessage removes a chat message for everyone in the room and
+// from its history. Only the room's streamer, moderators, and managers may
+// delete messages:
+//
+//	{"type":"delete_message","room":"<room>","data":{"message_id":"..."}}
+//
+// The room receives chat_message_deleted with the message_id.
+func (c *Client) handleDeleteMessage(msg *Message) {
+	room := msg.Room
+	if room == "" {
+		room, _ = msg.Data["room"].(string)
+	}
+	messageID, _ := msg.Data["message_id"].(string)
+	if room == "" || messageID == "" {
+		c.sendError(ErrorCodeInvalidMessage, "room and message_id are required", msg)
+		return
+	}
+
+	c.hub.mu.RLock()
+	allowed := c.hub.isPrivileged(room, c)
+	history := c.hub.chatHistory
+	c.hub.mu.RUnlock()
+	if !allowed && !c.hub.isManager(room, c) {
+		c.sendError(ErrorCodeForbidden, "only the streamer and moderators can delete messages", msg)
+		return
+	}
+
+	if history != nil {
+		history.Delete(room, messageID)
+	}
+	log.Printf("Chat message deleted: room=%s, messageID=%s, by=%s", room, messageID, c.userID)
+	c.hub.BroadcastToRoom(room, "chat_message_deleted", map[string]interface{}{
+		"message_id": messageID,
+		"deleted_by": c.userID,
+	})
+	c.sendAck("delete_message", room)
+}
+
+// SubscriberBadges reports chat senders' subscriptions to the channel
+// streaming in a room. Calls are made on the chat path and must not block.
+type SubscriberBadges interface {
+	// SubscriberTier returns the user's tier, or "" if not subscribed
+	SubscriberTier(room, userID string) string
+}
+
+// SetSubscriberBadges adds senders' subscriber tiers to chat messages
+func (h *Hub) SetSubscriberBadges(badges SubscriberBadges) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.subscriberBadges = badges
+}
+
+// subscriberTier returns a chat sender's subscriber tier in room, if known
+func (h *Hub) subscriberTier(room, userID string) string {
+	h.mu.RLock()
+	badges := h.subscriberBadges
+	h.mu.RUnlock()
+
+	if badges == nil {
+		return ""
+	}
+	return badges.SubscriberTier(room, userID)
+}
@@ -0,0 +1,128 @@
+package websocket
+
+import (
+	"log"
+	"time"
+
+	"github.com/tinle0301/streaming-platform-api/internal/ratelimit"
+)
+
+// ChatSamplingPolicy controls chat sampling in very large rooms. Above
+// RoomSize, ordinary viewers receive at most MessagesPerSecond chat messages
+// plus a periodic chat_summary counting what they missed. Streamers and
+// moderators always receive every message.
+type ChatSamplingPolicy struct {
+	// RoomSize is the viewer count above which sampling applies (0 disables)
+	RoomSize int
+
+	MessagesPerSecond float64
+	Burst             int
+}
+
+// DefaultChatSamplingPolicy returns the default sampling policy
+func DefaultChatSamplingPolicy() ChatSamplingPolicy {
+	return ChatSamplingPolicy{
+		RoomSize:          10000,
+		MessagesPerSecond: 10,
+		Burst:             20,
+	}
+}
+
+// RoomRoleChecker reports whether a user is the streamer or a moderator of a room
+type RoomRoleChecker interface {
+	IsPrivileged(room, userID string) bool
+}
+
+// chatSampler tracks the sampled chat rate for one room
+type chatSampler struct {
+	bucket     *ratelimit.TokenBucket
+	suppressed int
+}
+
+// SetChatSamplingPolicy replaces the chat sampling policy
+func (h *Hub) SetChatSamplingPolicy(policy ChatSamplingPolicy) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.chatSampling = policy
+}
+
+// SetRoomRoleChecker configures the lookup for streamers and moderators
+func (h *Hub) SetRoomRoleChecker(checker RoomRoleChecker) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.roomRoles = checker
+}
+
+// sampleChat decides which clients receive a chat message in a sampled room.
+// Must be called from the Run goroutine with h.mu held.
+func (h *Hub) sampleChat(room string, targets []*Client) []*Client {
+	policy := h.chatSampling
+	if policy.RoomSize <= 0 || len(targets) <= policy.RoomSize || !h.featureEnabled(FeatureChatSampling) {
+		return targets
+	}
+
+	sampler, ok := h.chatSamplers[room]
+	if !ok {
+		sampler = &chatSampler{bucket: ratelimit.NewTokenBucket(policy.MessagesPerSecond, policy.Burst)}
+		h.chatSamplers[room] = sampler
+	}
+
+	if sampler.bucket.Allow() {
+		return targets
+	}
+
+	// Over the sampled rate: only the firehose audience gets this one
+	sampler.suppressed++
+	privileged := make([]*Client, 0)
+	for _, client := range targets {
+		if h.isPrivileged(room, client) {
+			privileged = append(privileged, client)
+		}
+	}
+	return privileged
+}
+
+// flushChatSummaries tells sampled viewers how many messages they missed.
+// Must be called from the Run goroutine.
+func (h *Hub) flushChatSummaries(now time.Time) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for room, sampler := range h.chatSamplers {
+		roomClients, ok := h.rooms[room]
+		if !ok || len(roomClients) <= h.chatSampling.RoomSize {
+			// Room emptied or shrank below the sampling size
+			delete(h.chatSamplers, room)
+		}
+		if sampler.suppressed == 0 || !ok {
+			continue
+		}
+
+		encoded, err := newEncodedMessage(&Message{
+			Type: "chat_summary",
+			Room: room,
+			Data: map[string]interface{}{
+				"suppressed": sampler.suppressed,
+			},
+			Timestamp: now,
+		})
+		if err != nil {
+			log.Printf("Error marshaling message: %v", err)
+			continue
+		}
+		sampler.suppressed = 0
+
+		targets := make([]*Client, 0, len(roomClients))
+		for client := range roomClients {
+			if !h.isPrivileged(room, client) {
+				targets = append(targets, client)
+			}
+		}
+		h.deliver(encoded, targets)
+	}
+}
+
+// isPrivileged reports whether client sees the full chat in room (caller must hold lock)
+func (h *Hub) isPrivileged(room string, client *Client) bool {
+	return h.roomRoles != nil && h.roomRoles.IsPrivileged(room, client.userID)
+}
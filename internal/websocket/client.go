@@ -1,22 +1,49 @@
 package websocket
 
 import (
+	"context"
 	"encoding/json"
-	"log"
+	"log/slog"
 	"time"
 
 	"github.com/gorilla/websocket"
+	"github.com/tinle0301/streaming-platform-api/internal/tracing"
 )
 
 // NewClient creates a new Client instance
 func NewClient(hub *Hub, conn *websocket.Conn, userID string) *Client {
 	return &Client{
-		hub:      hub,
-		conn:     conn,
-		send:     make(chan []byte, sendBufferSize),
-		userID:   userID,
-		rooms:    make(map[string]bool),
-		metadata: make(map[string]string),
+		hub:          hub,
+		conn:         conn,
+		send:         make(chan outbound, sendBufferSize),
+		userID:       userID,
+		rooms:        make(map[string]bool),
+		metadata:     make(map[string]string),
+		capabilities: make(map[string]bool),
+		encoding:     EncodingJSON,
+		sessionID:    newSessionID(),
+		connectedAt:  time.Now(),
+		writeDone:    make(chan struct{}),
+	}
+}
+
+// NewSpectatorClient creates a receive-only client for viewers who never chat.
+// Spectators skip inbound message handling and use smaller buffers; their
+// rooms are fixed at handshake since they cannot send subscribe messages.
+func NewSpectatorClient(hub *Hub, conn *websocket.Conn, userID string) *Client {
+	return &Client{
+		hub:          hub,
+		conn:         conn,
+		send:         make(chan outbound, spectatorSendBufferSize),
+		userID:       userID,
+		rooms:        make(map[string]bool),
+		metadata:     make(map[string]string),
+		capabilities: make(map[string]bool),
+		encoding:     EncodingJSON,
+		sessionID:    newSessionID(),
+		connectedAt:  time.Now(),
+		writeDone:    make(chan struct{}),
+		spectator:    true,
 	}
 }
 
@@ -27,11 +54,15 @@ func NewClient(hub *Hub, conn *websocket.Conn, userID string) *Client {
 // reads from this goroutine.
 func (c *Client) ReadPump() {
 	defer func() {
-		c.hub.Unregister <- c
+		c.hub.unregister(c)
 		c.conn.Close()
 	}()
 
-	c.conn.SetReadLimit(maxMessageSize)
+	if c.spectator {
+		c.conn.SetReadLimit(spectatorMaxMessageSize)
+	} else {
+		c.conn.SetReadLimit(maxMessageSize)
+	}
 	c.conn.SetReadDeadline(time.Now().Add(pongWait))
 	c.conn.SetPongHandler(func(string) error {
 		c.conn.SetReadDeadline(time.Now().Add(pongWait))
@@ -39,27 +70,92 @@ func (c *Client) ReadPump() {
 	})
 
 	for {
-		_, messageBytes, err := c.conn.ReadMessage()
+		frameType, messageBytes, err := c.conn.ReadMessage()
 		if err != nil {
 			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
-				log.Printf("WebSocket error: %v", err)
+				slog.Warn("WebSocket error", "user_id", c.userID, "err", err)
+			}
+			if code := readCloseCode(err); code != 0 {
+				c.mu.Lock()
+				c.closeCode = code
+				c.mu.Unlock()
+				c.conn.WriteControl(websocket.CloseMessage, c.closeMessage(), time.Now().Add(writeWait))
+			} else if websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				// The client left on purpose; there is no session to resume
+				c.mu.Lock()
+				c.closedByPeer = true
+				c.mu.Unlock()
 			}
 			break
 		}
 
-		// Parse the incoming message
-		var message Message
-		if err := json.Unmarshal(messageBytes, &message); err != nil {
-			log.Printf("Error unmarshaling message: %v", err)
+		// Spectators only need reads for pongs and close frames
+		if c.spectator {
 			continue
 		}
 
-		// Handle the message based on type
-		c.handleMessage(&message)
+		if frameType == websocket.BinaryMessage {
+			c.handleBinaryFrame(messageBytes)
+		} else {
+			c.handleFrame(messageBytes)
+		}
+	}
+}
 
-		// Update metrics
-		c.hub.metrics.TotalMessagesRecv++
+// handleFrame parses and dispatches one JSON message read from the
+// connection. Its input is untrusted, so it must not panic on any frame.
+func (c *Client) handleFrame(messageBytes []byte) {
+	// Parse the incoming message
+	var message Message
+	if err := json.Unmarshal(messageBytes, &message); err != nil {
+		slog.Debug("Error unmarshaling message", "user_id", c.userID, "err", err)
+		c.sendError(ErrorCodeInvalidMessage, "message is not valid JSON", nil)
+		return
 	}
+	c.dispatch(&message, EncodingJSON, messageBytes)
+}
+
+// handleBinaryFrame parses and dispatches one message read from the
+// connection in the client's negotiated binary encoding. Its input is
+// untrusted, so it must not panic on any frame.
+func (c *Client) handleBinaryFrame(messageBytes []byte) {
+	var message Message
+	encoding := c.Encoding()
+	if err := decodeMessage(encoding, messageBytes, &message); err != nil {
+		slog.Debug("Error decoding message", "user_id", c.userID, "encoding", encoding, "err", err)
+		reason := "message is not valid " + encoding
+		if encoding == EncodingJSON {
+			reason = "binary frames need an encoding negotiated in hello"
+		}
+		c.sendError(ErrorCodeInvalidMessage, reason, nil)
+		return
+	}
+	c.dispatch(&message, encoding, messageBytes)
+}
+
+// dispatch checks a parsed inbound message against the rate limits and bot
+// signatures, then handles it. frame is the message as received in encoding.
+func (c *Client) dispatch(message *Message, encoding string, frame []byte) {
+	// Drop messages over the connection's or IP's rate limits
+	if !c.allowMessage(message, time.Now()) {
+		return
+	}
+
+	// Registered bots must sign every message so a hijacked session
+	// cannot issue actions on the bot's behalf
+	if key, ok := c.hub.botKey(c.userID); ok {
+		if err := VerifyFrame(key, encoding, frame, message, time.Now()); err != nil {
+			slog.Warn("Rejecting unsigned bot message", "user_id", c.userID, "type", message.Type, "err", err)
+			c.sendError(ErrorCodeInvalidSignature, "bot messages must be signed", message)
+			return
+		}
+	}
+
+	// Handle the message based on type
+	c.handleTracedMessage(message)
+
+	// Update metrics
+	c.hub.metrics.messageReceived()
 }
 
 // WritePump pumps messages from the hub to the websocket connection.
@@ -72,6 +168,7 @@ func (c *Client) WritePump() {
 	defer func() {
 		ticker.Stop()
 		c.conn.Close()
+		close(c.writeDone)
 	}()
 
 	for {
@@ -79,26 +176,26 @@ func (c *Client) WritePump() {
 		case message, ok := <-c.send:
 			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
 			if !ok {
-				// The hub closed the channel
-				c.conn.WriteMessage(websocket.CloseMessage, []byte{})
-				return
-			}
-
-			w, err := c.conn.NextWriter(websocket.TextMessage)
-			if err != nil {
+				// The hub closed the channel; tell the client why
+				c.conn.WriteMessage(websocket.CloseMessage, c.closeMessage())
 				return
 			}
-			w.Write(message)
 
-			// Add queued messages to the current websocket message
-			n := len(c.send)
-			for i := 0; i < n; i++ {
-				w.Write([]byte{'\n'})
-				w.Write(<-c.send)
+			// JSON messages queued together share a websocket message, one
+			// per line; binary messages are sent one per websocket message
+			pending := []outbound{message}
+			for n := len(c.send); n > 0; n-- {
+				pending = append(pending, <-c.send)
 			}
-
-			if err := w.Close(); err != nil {
-				return
+			for len(pending) > 0 {
+				n := 1
+				for !pending[0].binary && n < len(pending) && !pending[n].binary {
+					n++
+				}
+				if err := c.writeFrame(pending[:n]); err != nil {
+					return
+				}
+				pending = pending[n:]
 			}
 
 		case <-ticker.C:
@@ -110,14 +207,80 @@ func (c *Client) WritePump() {
 	}
 }
 
+// writeFrame writes messages as one websocket message: a single binary
+// message, or JSON messages separated by newlines
+func (c *Client) writeFrame(messages []outbound) error {
+	size := len(messages) - 1
+	for _, message := range messages {
+		size += len(message.data)
+	}
+
+	// Only frames large enough to be worth the CPU are compressed
+	wire, compressed := c.conn.UnderlyingConn().(*wireConn)
+	compressed = compressed && wire.compress && size >= c.hub.compression.Threshold
+	c.conn.EnableWriteCompression(compressed)
+	var written int64
+	if compressed {
+		written = wire.bytesWritten()
+	}
+
+	frameType := websocket.TextMessage
+	if messages[0].binary {
+		frameType = websocket.BinaryMessage
+	}
+	w, err := c.conn.NextWriter(frameType)
+	if err != nil {
+		return err
+	}
+	for i, message := range messages {
+		if i > 0 {
+			w.Write([]byte{'\n'})
+		}
+		w.Write(message.data)
+	}
+
+	if err := w.Close(); err != nil {
+		return err
+	}
+	if compressed {
+		countCompression(int64(size), wire.bytesWritten()-written)
+	}
+	c.countDelivered(int64(len(messages)))
+	return nil
+}
+
+// handleTracedMessage handles a message inside a span of its own, so events
+// it publishes continue the message's trace
+func (c *Client) handleTracedMessage(msg *Message) {
+	ctx, span := tracing.Start(context.Background(), "websocket "+msg.Type, tracing.KindServer,
+		tracing.String("websocket.message.type", msg.Type),
+		tracing.String("websocket.session.id", c.sessionID),
+		tracing.String("user.id", c.userID),
+	)
+	defer span.End()
+	if msg.Room != "" {
+		span.SetAttributes(tracing.String("websocket.room", msg.Room))
+	}
+	c.handleMessage(ctx, msg)
+}
+
 // handleMessage processes incoming messages from the client
-func (c *Client) handleMessage(msg *Message) {
+func (c *Client) handleMessage(ctx context.Context, msg *Message) {
 	switch msg.Type {
+	case "hello":
+		// Capability handshake
+		c.handleHello(msg)
+
 	case "subscribe":
 		// Subscribe to a room (e.g., stream-specific notifications)
-		if room, ok := msg.Data["room"].(string); ok {
-			c.hub.JoinRoom(room, c)
+		room, ok := msg.Data["room"].(string)
+		switch {
+		case !ok || room == "":
+			c.sendError(ErrorCodeInvalidMessage, "room is required", msg)
+		case c.Subscribe(room):
 			c.sendAck("subscribed", room)
+		default:
+			c.sendError(ErrorCodeForbidden, "you cannot join this room", msg)
 		}
 
 	case "unsubscribe":
@@ -125,6 +288,8 @@ func (c *Client) handleMessage(msg *Message) {
 		if room, ok := msg.Data["room"].(string); ok {
 			c.hub.LeaveRoom(room, c)
 			c.sendAck("unsubscribed", room)
+		} else {
+			c.sendError(ErrorCodeInvalidMessage, "room is required", msg)
 		}
 
 	case "ping":
@@ -134,13 +299,119 @@ func (c *Client) handleMessage(msg *Message) {
 		})
 
 	case "message":
-		// Handle custom messages (e.g., chat messages)
-		// This could be forwarded to a message queue or processed directly
-		log.Printf("Received message from client %s: %+v", c.userID, msg.Data)
+		// Handle chat messages sent to a room
+		c.handleChatMessage(ctx, msg)
+
+	case "delete_message":
+		// Streamer or moderator removing a chat message
+		c.handleDeleteMessage(msg)
+
+	case "direct", "whisper":
+		// Private message to another user, checked and delivered cluster-wide.
+		// whisper is the older name, kept for clients that still send it.
+		c.handleDirect(ctx, msg)
+
+	case "heartbeat":
+		// Answer to a watch-time heartbeat challenge
+		c.handleHeartbeat(msg)
+
+	case "resync":
+		// Delta client asking for the full value of a room data key
+		c.handleResync(msg)
+
+	case "app_state":
+		// Mobile apps signal when they move to and from the background
+		c.handleAppState(msg)
+
+	case "manager_subscribe":
+		// Broadcaster switching a room to stream manager mode
+		c.handleManagerSubscribe(msg)
+
+	case "manager_unsubscribe":
+		c.handleManagerUnsubscribe(msg)
+
+	case "party_sync":
+		// Watch party host moving everyone's playback
+		c.handlePartySync(ctx, msg)
+
+	case "auth_refresh":
+		// New access token presented before the current one expires
+		c.handleAuthRefresh(msg)
 
 	default:
-		log.Printf("Unknown message type from client %s: %s", c.userID, msg.Type)
+		slog.Debug("Unknown message type", "user_id", c.userID, "type", msg.Type)
+		c.sendError(ErrorCodeUnknownType, "unknown message type", msg)
+	}
+}
+
+// handleChatMessage relays a chat message to the client's room.
+//
+// Messages from shadow-banned users are echoed back only to the sender so
+// they are unaware of the ban, and are never broadcast to the room.
+func (c *Client) handleChatMessage(ctx context.Context, msg *Message) {
+	if c.rejectGuest(msg) {
+		return
+	}
+
+	room := msg.Room
+	if room == "" {
+		room, _ = msg.Data["room"].(string)
+	}
+	text, _ := msg.Data["message"].(string)
+
+	if room == "" || text == "" || (!c.IsInRoom(room) && !c.hub.isManager(room, c)) {
+		slog.Debug("Dropping invalid chat message", "user_id", c.userID)
+		c.sendError(ErrorCodeInvalidMessage, "chat messages need a room you are in and a message", msg)
+		return
+	}
+
+	messageID, sentAt := newSessionID(), time.Now()
+	data := map[string]interface{}{
+		"message_id": messageID,
+		"user_id":    c.userID,
+		"message":    text,
+		"sent_at":    sentAt.UnixMilli(),
+	}
+	tier := c.hub.subscriberTier(room, c.userID)
+	if tier != "" {
+		data["subscriber_tier"] = tier
+	}
+
+	if !c.allowCommunityChat(room, msg) {
+		return
+	}
+
+	if c.hub.isShadowBanned(room, c.userID) {
+		slog.Info("Suppressing chat from shadow-banned user", "user_id", c.userID, "room", room)
+		c.sendRoomMessage(room, "chat_message", data)
+		return
+	}
+
+	c.hub.observeChat(room, c.userID, text)
+	if c.hub.relayPartyChat(ctx, room, c.userID, text) {
+		return
+	}
+	c.hub.recordChat(room, messageID, c.userID, text, tier, sentAt)
+	c.hub.BroadcastToRoom(room, "chat_message", data)
+}
+
+// Subscribe joins a room and sends its room_state snapshot. It reports
+// false if the room authorizer denies the client access.
+func (c *Client) Subscribe(room string) bool {
+	if !c.hub.canJoin(c, room) {
+		return false
 	}
+	c.hub.JoinRoom(room, c)
+	go c.sendRoomState(room)
+	return true
+}
+
+// sendRoomState sends the composed room_state snapshot for a room
+func (c *Client) sendRoomState(room string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	c.sendRoomMessage(room, "room_state", c.hub.composeRoomState(ctx, room))
 }
 
 // sendAck sends an acknowledgment message to the client
@@ -159,20 +430,39 @@ func (c *Client) sendMessage(messageType string, data map[string]interface{}) {
 		Timestamp: time.Now(),
 	}
 
-	messageBytes, err := json.Marshal(message)
+	encoded, err := newEncodedMessage(&message)
 	if err != nil {
-		log.Printf("Error marshaling message: %v", err)
+		slog.Error("Error marshaling message", "err", err)
 		return
 	}
 
-	select {
-	case c.send <- messageBytes:
-	default:
-		log.Printf("Client send buffer full, message dropped: userID=%s", c.userID)
+	if !c.queue(encoded) {
+		slog.Warn("Client send buffer full, message dropped", "user_id", c.userID)
 	}
 }
 
-// SendNotification sends a notification to this specific client
+// sendRoomMessage sends a room-scoped message to this client only
+func (c *Client) sendRoomMessage(room, messageType string, data map[string]interface{}) {
+	message := Message{
+		Type:      messageType,
+		Room:      room,
+		Data:      data,
+		Timestamp: time.Now(),
+	}
+
+	encoded, err := newEncodedMessage(&message)
+	if err != nil {
+		slog.Error("Error marshaling message", "err", err)
+		return
+	}
+
+	if !c.queue(encoded) {
+		slog.Warn("Client send buffer full, message dropped", "user_id", c.userID)
+	}
+}
+
+// SendNotification sends a notification to this specific client.
+// Notifications are dropped while the client is in the background.
 func (c *Client) SendNotification(notificationType string, data map[string]interface{}) {
 	c.sendMessage("notification", map[string]interface{}{
 		"type": notificationType,
@@ -180,11 +470,27 @@ func (c *Client) SendNotification(notificationType string, data map[string]inter
 	})
 }
 
+// SendCriticalNotification sends a notification that is delivered even while
+// the client is in the background
+func (c *Client) SendCriticalNotification(notificationType string, data map[string]interface{}) {
+	c.sendMessage("critical_notification", map[string]interface{}{
+		"type": notificationType,
+		"data": data,
+	})
+}
+
 // GetUserID returns the user ID associated with this client
 func (c *Client) GetUserID() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
 	return c.userID
 }
 
+// IsSpectator reports whether this is a receive-only spectator connection
+func (c *Client) IsSpectator() bool {
+	return c.spectator
+}
+
 // SetMetadata sets custom metadata for the client
 func (c *Client) SetMetadata(key, value string) {
 	c.mu.Lock()
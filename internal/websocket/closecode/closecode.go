@@ -0,0 +1,108 @@
+// Package closecode defines the close codes and reasons the WebSocket server
+// sends when it ends a connection.
+//
+// Every close frame the server sends carries one of the codes below and a
+// JSON reason, for example:
+//
+//	{"reason":"server_drain","reconnect":true,"retry_ms":12345}
+//
+// Clients should reconnect only when "reconnect" is true, waiting at least
+// "retry_ms" milliseconds when it is present. Standard codes (1000-2999) keep
+// their RFC 6455 meaning; 4000-4999 are StreamHub-specific.
+//
+//	Code  Reason            Reconnect  Meaning
+//	1000  normal            no         Connection finished normally
+//	1008  policy_violation  no         Client broke protocol rules (e.g. unsigned bot message)
+//	1009  message_too_big   yes        Inbound message exceeded the size limit
+//	1012  server_drain      yes        Node is restarting or draining; retry after retry_ms
+//	4000  idle_timeout      yes        No pong or message within the idle window
+//	4001  auth_expired      yes        Access token expired; refresh it, then reconnect
+//	4002  rate_limited      yes        Client sent too many messages; back off retry_ms
+//	4003  slow_consumer     yes        Client fell too far behind reading messages
+//	4004  auth_failed       no         Credentials were rejected; sign in again first
+//	4005  session_replaced  no         Closed for a newer connection of the same user, or ended by the user
+//	4006  disconnected      yes        Disconnected by an operator; retry after retry_ms
+package closecode
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Close codes sent by the server
+const (
+	Normal          = 1000
+	PolicyViolation = 1008
+	MessageTooBig   = 1009
+	ServerDrain     = 1012
+	IdleTimeout     = 4000
+	AuthExpired     = 4001
+	RateLimited     = 4002
+	SlowConsumer    = 4003
+	AuthFailed      = 4004
+	SessionReplaced = 4005
+	Disconnected    = 4006
+)
+
+// Info describes a close code
+type Info struct {
+	// Reason is the stable, machine-readable name of the code
+	Reason string
+
+	// Reconnect reports whether a client should reconnect automatically
+	Reconnect bool
+}
+
+// codes maps each close code to its description
+var codes = map[int]Info{
+	Normal:          {Reason: "normal", Reconnect: false},
+	PolicyViolation: {Reason: "policy_violation", Reconnect: false},
+	MessageTooBig:   {Reason: "message_too_big", Reconnect: true},
+	ServerDrain:     {Reason: "server_drain", Reconnect: true},
+	IdleTimeout:     {Reason: "idle_timeout", Reconnect: true},
+	AuthExpired:     {Reason: "auth_expired", Reconnect: true},
+	RateLimited:     {Reason: "rate_limited", Reconnect: true},
+	SlowConsumer:    {Reason: "slow_consumer", Reconnect: true},
+	AuthFailed:      {Reason: "auth_failed", Reconnect: false},
+	SessionReplaced: {Reason: "session_replaced", Reconnect: false},
+	Disconnected:    {Reason: "disconnected", Reconnect: true},
+}
+
+// Describe returns the description of code. Unknown codes are treated as
+// transient so clients fail open to reconnecting.
+func Describe(code int) Info {
+	if info, ok := codes[code]; ok {
+		return info
+	}
+	return Info{Reason: "unknown", Reconnect: true}
+}
+
+// Reason is the JSON payload of a close frame. Close frame payloads are
+// limited to 123 bytes, so fields are kept short.
+type Reason struct {
+	Reason    string `json:"reason"`
+	Reconnect bool   `json:"reconnect"`
+	RetryMs   int64  `json:"retry_ms,omitempty"`
+}
+
+// Text encodes the close reason for code, with an optional retry delay
+func Text(code int, retryAfter time.Duration) string {
+	info := Describe(code)
+	reason, _ := json.Marshal(Reason{
+		Reason:    info.Reason,
+		Reconnect: info.Reconnect,
+		RetryMs:   retryAfter.Milliseconds(),
+	})
+	return string(reason)
+}
+
+// Parse decodes a close frame's reason text. Frames without a JSON reason
+// (e.g. from proxies) are described from the code alone.
+func Parse(code int, text string) Reason {
+	var reason Reason
+	if err := json.Unmarshal([]byte(text), &reason); err == nil && reason.Reason != "" {
+		return reason
+	}
+	info := Describe(code)
+	return Reason{Reason: info.Reason, Reconnect: info.Reconnect}
+}
@@ -0,0 +1,177 @@
+package websocket
+
+import (
+	"context"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/tinle0301/streaming-platform-api/internal/events"
+	"github.com/tinle0301/streaming-platform-api/internal/tenancy"
+)
+
+const (
+	// communityRoomPrefix marks channels' offline community rooms
+	communityRoomPrefix = "community:"
+
+	// RoomStateCommunityChat is the room_state section with whether a
+	// community room is open and its slow mode
+	RoomStateCommunityChat = "community_chat"
+
+	// communityStateTTL bounds how stale the open state sent to joiners is
+	communityStateTTL = time.Second
+)
+
+// CommunityChatStore knows which channels' community rooms are open
+type CommunityChatStore interface {
+	// CommunityChat reports whether a channel's community room is open and
+	// its slow mode
+	CommunityChat(ctx context.Context, channelID string) (bool, time.Duration, error)
+
+	// ClaimChatTurn reports whether userID may chat under slow mode,
+	// starting their wait if so
+	ClaimChatTurn(ctx context.Context, channelID, userID string, interval time.Duration) (bool, error)
+}
+
+// CommunityRoom names a channel's community room, where its chat stays open
+// while it is offline
+func CommunityRoom(channelID string) string {
+	return communityRoomPrefix + channelID
+}
+
+// communityChannelID returns the channel a room belongs to, if it is a
+// community room in any tenant's namespace
+func communityChannelID(room string) (string, bool) {
+	_, name := tenancy.SplitRoom(room)
+	channelID := strings.TrimPrefix(name, communityRoomPrefix)
+	return channelID, channelID != name && channelID != ""
+}
+
+// SetCommunityChat enables channels' community rooms. Clients can only join
+// and chat in a room while it is open.
+func (h *Hub) SetCommunityChat(store CommunityChatStore) {
+	h.mu.Lock()
+	h.communityChat = store
+	h.mu.Unlock()
+
+	h.AddRoomStateSection(RoomStateCommunityChat, func(ctx context.Context, room string) (map[string]interface{}, error) {
+		channelID, ok := communityChannelID(room)
+		if !ok {
+			return nil, nil
+		}
+		open, slowMode, err := store.CommunityChat(ctx, channelID)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{
+			"channel_id":        channelID,
+			"open":              open,
+			"slow_mode_seconds": int64(slowMode / time.Second),
+		}, nil
+	}, communityStateTTL)
+
+	// Slow mode only applies in community rooms; the channel itself is exempt
+	h.AddRoomStateSection(RoomStateSlowMode, func(ctx context.Context, room string) (map[string]interface{}, error) {
+		channelID, ok := communityChannelID(room)
+		if !ok {
+			return nil, nil
+		}
+		_, slowMode, err := store.CommunityChat(ctx, channelID)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{
+			"enabled": slowMode > 0,
+			"seconds": int64(slowMode / time.Second),
+		}, nil
+	}, communityStateTTL)
+}
+
+// communityRoomOpen reports false for a community room that isn't open;
+// other rooms, and every room without community chat enabled, pass
+func (h *Hub) communityRoomOpen(room string) bool {
+	channelID, ok := communityChannelID(room)
+	if !ok {
+		return true
+	}
+
+	h.mu.RLock()
+	store := h.communityChat
+	h.mu.RUnlock()
+	if store == nil {
+		return false
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	open, _, err := store.CommunityChat(ctx, channelID)
+	if err != nil {
+		log.Printf("Error checking community chat: channelID=%s, err=%v", channelID, err)
+		return false
+	}
+	return open
+}
+
+// allowCommunityChat checks a chat message sent to a community room against
+// the room being open and its slow mode, replying with an error if it is
+// refused. The channel owner isn't held to slow mode.
+func (c *Client) allowCommunityChat(room string, msg *Message) bool {
+	channelID, ok := communityChannelID(room)
+	if !ok {
+		return true
+	}
+
+	c.hub.mu.RLock()
+	store := c.hub.communityChat
+	c.hub.mu.RUnlock()
+	if store == nil {
+		c.sendError(ErrorCodeForbidden, "community chat is not enabled", msg)
+		return false
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	open, slowMode, err := store.CommunityChat(ctx, channelID)
+	if err != nil {
+		log.Printf("Error checking community chat: channelID=%s, err=%v", channelID, err)
+		return false
+	}
+	if !open {
+		c.sendError(ErrorCodeForbidden, "this channel's community chat is closed", msg)
+		return false
+	}
+	if slowMode <= 0 || c.GetUserID() == channelID {
+		return true
+	}
+
+	claimed, err := store.ClaimChatTurn(ctx, channelID, c.GetUserID(), slowMode)
+	if err != nil {
+		log.Printf("Error checking community chat slow mode: channelID=%s, err=%v", channelID, err)
+		return false
+	}
+	if !claimed {
+		c.sendError(ErrorCodeRateLimited, "slow mode is on; wait before chatting again", msg)
+		return false
+	}
+	return true
+}
+
+// dispatchCommunityEvent delivers a community room's opening or closing to
+// the room on this node. Chatters are told a closing room's live stream, if
+// any, so they can move to its room.
+func (h *Hub) dispatchCommunityEvent(event events.Event, data map[string]interface{}) {
+	channelID, _ := data["channel_id"].(string)
+	if channelID == "" {
+		return
+	}
+	room := tenancy.Room(event.TenantID, CommunityRoom(channelID))
+
+	switch event.Type {
+	case events.EventTypeCommunityChatOpened:
+		h.BroadcastToRoom(room, "community_chat_opened", data)
+	case events.EventTypeCommunityChatClosed:
+		h.BroadcastToRoom(room, "community_chat_closed", data)
+	}
+}
@@ -0,0 +1,158 @@
+package websocket
+
+import (
+	"bufio"
+	"compress/flate"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/gorilla/websocket"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// CompressionOptOutParam is the query parameter clients on CPU-constrained
+// devices connect with, set to "off", to decline compression
+const CompressionOptOutParam = "compression"
+
+var (
+	compressedFrames = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "streamhub_ws_compressed_frames_total",
+		Help: "Frames sent with permessage-deflate",
+	})
+	compressionPayloadBytes = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "streamhub_ws_compression_payload_bytes_total",
+		Help: "Bytes of compressed frames before compression",
+	})
+	compressionWireBytes = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "streamhub_ws_compression_wire_bytes_total",
+		Help: "Bytes of compressed frames written to connections, headers included",
+	})
+	compressionSavedBytes = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "streamhub_ws_compression_saved_bytes_total",
+		Help: "Bytes permessage-deflate kept off the wire",
+	})
+)
+
+// CompressionOptions configures permessage-deflate (RFC 7692) on outbound
+// frames. Chat-heavy rooms send JSON that compresses well; small frames
+// cost more CPU than they save, so they are sent as is.
+type CompressionOptions struct {
+	// Enabled offers compression to clients that support it
+	Enabled bool
+
+	// Level is the flate level, from 1 (fastest) to 9 (smallest)
+	Level int
+
+	// Frames smaller than Threshold bytes are sent uncompressed
+	Threshold int
+}
+
+// DefaultCompressionOptions returns the options used when none are configured
+func DefaultCompressionOptions() CompressionOptions {
+	return CompressionOptions{
+		Enabled:   true,
+		Level:     flate.BestSpeed,
+		Threshold: 512,
+	}
+}
+
+// SetCompression configures compression of new connections' frames
+func (h *Hub) SetCompression(options CompressionOptions) {
+	h.compression = options
+}
+
+// Upgrade upgrades r to a WebSocket connection with upgrader, negotiating
+// compression when the hub enables it, the client offers it, and the
+// client hasn't opted out with ?compression=off
+func (h *Hub) Upgrade(upgrader *websocket.Upgrader, w http.ResponseWriter, r *http.Request, header http.Header) (*websocket.Conn, error) {
+	options := h.compression
+	compress := options.Enabled && offersDeflate(r) && r.URL.Query().Get(CompressionOptOutParam) != "off"
+
+	connUpgrader := *upgrader
+	connUpgrader.EnableCompression = compress
+	conn, err := connUpgrader.Upgrade(&wireHijacker{ResponseWriter: w, compress: compress}, r, header)
+	if err != nil {
+		return nil, err
+	}
+	if compress {
+		if err := conn.SetCompressionLevel(options.Level); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return conn, nil
+}
+
+// offersDeflate reports whether the client offers permessage-deflate
+func offersDeflate(r *http.Request) bool {
+	for _, header := range r.Header.Values("Sec-WebSocket-Extensions") {
+		for _, extension := range strings.Split(header, ",") {
+			name, _, _ := strings.Cut(extension, ";")
+			if strings.TrimSpace(name) == "permessage-deflate" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// wireHijacker hands the upgrader a connection that counts the bytes
+// written to it
+type wireHijacker struct {
+	http.ResponseWriter
+	compress bool
+}
+
+// Hijack takes over the connection, wrapping it to count written bytes
+func (w *wireHijacker) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("response writer does not support hijacking")
+	}
+	conn, rw, err := hijacker.Hijack()
+	if err != nil {
+		return nil, nil, err
+	}
+	return &wireConn{Conn: conn, compress: w.compress}, rw, nil
+}
+
+// wireConn counts the bytes written to a connection, so compressed frames
+// can be compared with their payloads
+type wireConn struct {
+	net.Conn
+	compress bool
+
+	mu      sync.Mutex
+	written int64
+}
+
+// Write counts the bytes written
+func (c *wireConn) Write(b []byte) (int, error) {
+	n, err := c.Conn.Write(b)
+	c.mu.Lock()
+	c.written += int64(n)
+	c.mu.Unlock()
+	return n, err
+}
+
+// bytesWritten returns the bytes written so far
+func (c *wireConn) bytesWritten() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.written
+}
+
+// countCompression records a compressed frame of payload bytes that took
+// wire bytes
+func countCompression(payload, wire int64) {
+	compressedFrames.Inc()
+	compressionPayloadBytes.Add(float64(payload))
+	compressionWireBytes.Add(float64(wire))
+	if payload > wire {
+		compressionSavedBytes.Add(float64(payload - wire))
+	}
+}
@@ -0,0 +1,102 @@
+package websocket
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// dialCompressed connects to a hub served by Upgrade, offering compression,
+// and returns the connection and its server-side client
+func dialCompressed(t *testing.T, hub *Hub, query string) (*websocket.Conn, *http.Response, *Client) {
+	t.Helper()
+	clients := make(chan *Client, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := hub.Upgrade(&websocket.Upgrader{}, w, r, nil)
+		if err != nil {
+			return
+		}
+		client := NewClient(hub, conn, "viewer")
+		if !hub.AddClient(client) {
+			return
+		}
+		go client.WritePump()
+		go client.ReadPump()
+		clients <- client
+	}))
+	t.Cleanup(server.Close)
+
+	dialer := websocket.Dialer{EnableCompression: true}
+	conn, resp, err := dialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/"+query, nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn, resp, <-clients
+}
+
+// receive queues frame to client and returns what the connection reads
+func receive(t *testing.T, conn *websocket.Conn, client *Client, frame []byte) []byte {
+	t.Helper()
+	client.send <- outbound{data: frame}
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, received, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	return received
+}
+
+func TestCompression(t *testing.T) {
+	hub := startRaceHub(t)
+	hub.SetCompression(CompressionOptions{Enabled: true, Level: 6, Threshold: 256})
+	conn, resp, client := dialCompressed(t, hub, "")
+
+	if !strings.Contains(resp.Header.Get("Sec-WebSocket-Extensions"), "permessage-deflate") {
+		t.Fatalf("extensions = %q, want permessage-deflate", resp.Header.Get("Sec-WebSocket-Extensions"))
+	}
+
+	frames, saved := testutil.ToFloat64(compressedFrames), testutil.ToFloat64(compressionSavedBytes)
+	small := []byte(`{"type":"viewer_count","data":{"count":12}}`)
+	if got := receive(t, conn, client, small); !bytes.Equal(got, small) {
+		t.Fatalf("received %s, want %s", got, small)
+	}
+	if testutil.ToFloat64(compressedFrames) != frames {
+		t.Error("a frame below the threshold was compressed")
+	}
+
+	chat := []byte(`{"type":"chat","room":"stream-1","data":{"message":"` + strings.Repeat("pog ", 500) + `"}}`)
+	if got := receive(t, conn, client, chat); !bytes.Equal(got, chat) {
+		t.Fatalf("received %d bytes, want the %d sent", len(got), len(chat))
+	}
+	if testutil.ToFloat64(compressedFrames) != frames+1 {
+		t.Error("a frame above the threshold was not compressed")
+	}
+	if delta := testutil.ToFloat64(compressionSavedBytes) - saved; delta < float64(len(chat))/2 {
+		t.Errorf("saved %v bytes of %d repetitive ones", delta, len(chat))
+	}
+}
+
+func TestCompressionOptOut(t *testing.T) {
+	hub := startRaceHub(t)
+	conn, resp, client := dialCompressed(t, hub, "?"+CompressionOptOutParam+"=off")
+
+	if extensions := resp.Header.Get("Sec-WebSocket-Extensions"); extensions != "" {
+		t.Fatalf("extensions = %q, want none after opting out", extensions)
+	}
+
+	frames := testutil.ToFloat64(compressedFrames)
+	chat := []byte(`{"type":"chat","data":{"message":"` + strings.Repeat("pog ", 500) + `"}}`)
+	if got := receive(t, conn, client, chat); !bytes.Equal(got, chat) {
+		t.Fatalf("received %d bytes, want the %d sent", len(got), len(chat))
+	}
+	if testutil.ToFloat64(compressedFrames) != frames {
+		t.Error("a frame was compressed for a client that opted out")
+	}
+}
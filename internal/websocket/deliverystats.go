@@ -0,0 +1,141 @@
+package websocket
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// DeliveryStats summarizes platform-side delivery quality for one room
+type DeliveryStats struct {
+	// Broadcasts is how many messages were fanned out to the room
+	Broadcasts int64 `json:"broadcasts"`
+
+	// MessagesSent counts per-client deliveries queued successfully
+	MessagesSent int64 `json:"messages_sent"`
+
+	// MessagesDropped counts per-client deliveries dropped because the
+	// client's send buffer was full (backpressure)
+	MessagesDropped int64 `json:"messages_dropped"`
+
+	// FanOutNanos is the total time spent fanning broadcasts out
+	FanOutNanos int64 `json:"fan_out_nanos"`
+}
+
+// Add returns the sum of two stats
+func (s DeliveryStats) Add(other DeliveryStats) DeliveryStats {
+	return DeliveryStats{
+		Broadcasts:      s.Broadcasts + other.Broadcasts,
+		MessagesSent:    s.MessagesSent + other.MessagesSent,
+		MessagesDropped: s.MessagesDropped + other.MessagesDropped,
+		FanOutNanos:     s.FanOutNanos + other.FanOutNanos,
+	}
+}
+
+// AverageFanOutLatency is the mean time to fan one broadcast out
+func (s DeliveryStats) AverageFanOutLatency() time.Duration {
+	if s.Broadcasts == 0 {
+		return 0
+	}
+	return time.Duration(s.FanOutNanos / s.Broadcasts)
+}
+
+// DeliveryStatsSink adds a node's per-room delivery stats to totals
+// aggregated across nodes
+type DeliveryStatsSink interface {
+	SaveDeliveryStats(ctx context.Context, stats map[string]DeliveryStats) error
+}
+
+// deliveryStatsStore accumulates per-room delivery stats not yet reported.
+// Reporting takes them, so only rooms active since the last report are
+// kept, and rooms that have closed are pruned.
+type deliveryStatsStore struct {
+	rooms map[string]DeliveryStats
+	mu    sync.Mutex
+
+	// Nothing reads the stats until a reporter starts, so they aren't kept
+	reporting bool
+}
+
+func newDeliveryStatsStore() *deliveryStatsStore {
+	return &deliveryStatsStore{
+		rooms: make(map[string]DeliveryStats),
+	}
+}
+
+// record adds one broadcast's outcome to a room's stats
+func (s *deliveryStatsStore) record(room string, sent, dropped int, elapsed time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.reporting {
+		return
+	}
+	s.rooms[room] = s.rooms[room].Add(DeliveryStats{
+		Broadcasts:      1,
+		MessagesSent:    int64(sent),
+		MessagesDropped: int64(dropped),
+		FanOutNanos:     elapsed.Nanoseconds(),
+	})
+}
+
+// take returns the accumulated stats and starts over
+func (s *deliveryStatsStore) take() map[string]DeliveryStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	taken := s.rooms
+	s.rooms = make(map[string]DeliveryStats, len(taken))
+	return taken
+}
+
+// restore adds back stats that could not be reported
+func (s *deliveryStatsStore) restore(stats map[string]DeliveryStats) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for room, roomStats := range stats {
+		s.rooms[room] = s.rooms[room].Add(roomStats)
+	}
+}
+
+// DeliveryStats returns a snapshot of the per-room delivery stats on this
+// node that have not been reported yet
+func (h *Hub) DeliveryStats() map[string]DeliveryStats {
+	h.deliveryStats.mu.Lock()
+	defer h.deliveryStats.mu.Unlock()
+
+	snapshot := make(map[string]DeliveryStats, len(h.deliveryStats.rooms))
+	for room, stats := range h.deliveryStats.rooms {
+		snapshot[room] = stats
+	}
+	return snapshot
+}
+
+// ReportDeliveryStats adds the delivery stats accumulated since the last
+// report to sink every interval until ctx is cancelled
+func (h *Hub) ReportDeliveryStats(ctx context.Context, sink DeliveryStatsSink, interval time.Duration) {
+	h.deliveryStats.mu.Lock()
+	h.deliveryStats.reporting = true
+	h.deliveryStats.mu.Unlock()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			stats := h.deliveryStats.take()
+			if len(stats) == 0 {
+				continue
+			}
+			if err := sink.SaveDeliveryStats(ctx, stats); err != nil {
+				log.Printf("Error saving delivery stats: %v", err)
+				h.deliveryStats.restore(stats)
+			}
+		}
+	}
+}
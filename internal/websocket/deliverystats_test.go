@@ -0,0 +1,36 @@
+package websocket
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDeliveryStatsStoreKeepsOnlyUnreportedRooms(t *testing.T) {
+	store := newDeliveryStatsStore()
+	store.record("stream-1", 3, 0, time.Millisecond)
+	if len(store.rooms) != 0 {
+		t.Fatalf("rooms = %v before reporting started, want none kept", store.rooms)
+	}
+
+	store.reporting = true
+	store.record("stream-1", 3, 1, time.Millisecond)
+	store.record("stream-2", 2, 0, time.Millisecond)
+
+	taken := store.take()
+	if len(taken) != 2 || taken["stream-1"].MessagesSent != 3 || taken["stream-1"].MessagesDropped != 1 {
+		t.Fatalf("take() = %+v, want both rooms' stats", taken)
+	}
+	if len(store.rooms) != 0 {
+		t.Fatalf("rooms = %v after take, want the reported rooms pruned", store.rooms)
+	}
+
+	// A failed report is retried with the next one
+	store.record("stream-1", 1, 0, time.Millisecond)
+	store.restore(taken)
+	if got := store.rooms["stream-1"]; got.Broadcasts != 2 || got.MessagesSent != 4 {
+		t.Errorf("stream-1 after restore = %+v, want 2 broadcasts and 4 sent", got)
+	}
+	if got := store.rooms["stream-2"]; got.Broadcasts != 1 {
+		t.Errorf("stream-2 after restore = %+v, want 1 broadcast", got)
+	}
+}
@@ -0,0 +1,245 @@
+package websocket
+
+import (
+	"log"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Capabilities a client may request in its hello message
+const (
+	// CapabilityDelta receives keyed room data as JSON Patch-style deltas
+	CapabilityDelta = "delta"
+)
+
+// supportedCapabilities are the capabilities this server can grant
+var supportedCapabilities = map[string]bool{
+	CapabilityDelta: true,
+}
+
+// fullResyncEvery forces a full-state data_update to delta clients every
+// N versions so a client that missed a patch converges without asking
+const fullResyncEvery = 20
+
+// PatchOp is a single JSON Patch (RFC 6902) operation
+type PatchOp struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+// roomDataEntry is the latest value published under a room data key
+type roomDataEntry struct {
+	value   map[string]interface{}
+	version int64
+}
+
+// roomDataStore holds frequently changing room data (poll tallies,
+// hype-train progress) keyed by room and data key
+type roomDataStore struct {
+	entries map[string]map[string]*roomDataEntry
+	mu      sync.Mutex
+}
+
+// newRoomDataStore creates an empty store
+func newRoomDataStore() *roomDataStore {
+	return &roomDataStore{
+		entries: make(map[string]map[string]*roomDataEntry),
+	}
+}
+
+// HasCapability reports whether the client negotiated a capability
+func (c *Client) HasCapability(capability string) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.capabilities[capability]
+}
+
+// handleHello negotiates capabilities and the message encoding: the client
+// lists what it supports and the server replies with what it will use
+func (c *Client) handleHello(msg *Message) {
+	requested, _ := msg.Data["capabilities"].([]interface{})
+
+	granted := make([]string, 0, len(requested))
+	c.mu.Lock()
+	for _, value := range requested {
+		capability, ok := value.(string)
+		if ok && supportedCapabilities[capability] {
+			c.capabilities[capability] = true
+			granted = append(granted, capability)
+		}
+	}
+	c.mu.Unlock()
+
+	// The welcome is the last message in the encoding the client had
+	encoding, changed := negotiateEncoding(msg)
+	welcome := map[string]interface{}{
+		"capabilities": granted,
+	}
+	if changed {
+		welcome["encoding"] = encoding
+	}
+	c.sendMessage("welcome", welcome)
+
+	if changed {
+		c.mu.Lock()
+		c.encoding = encoding
+		c.mu.Unlock()
+	}
+}
+
+// handleResync sends the full current value of a room data key, for delta
+// clients that detected a gap in patch versions
+func (c *Client) handleResync(msg *Message) {
+	room := msg.Room
+	key, _ := msg.Data["key"].(string)
+	if room == "" || key == "" || !c.IsInRoom(room) {
+		return
+	}
+
+	c.hub.roomData.mu.Lock()
+	entry, ok := c.hub.roomData.entries[room][key]
+	var data map[string]interface{}
+	if ok {
+		data = fullDataUpdate(key, entry)
+	}
+	c.hub.roomData.mu.Unlock()
+
+	if ok {
+		c.sendRoomMessage(room, "data_update", data)
+	}
+}
+
+// PublishRoomData publishes the new value of a frequently changing room
+// data key. Clients that negotiated CapabilityDelta receive a data_patch
+// against the previous version; all others receive the full value. The
+// value must not be modified after it is published. Data for a room
+// without clients is dropped, and a room's data is cleared when its last
+// client leaves.
+func (h *Hub) PublishRoomData(room, key string, value map[string]interface{}) {
+	h.mu.RLock()
+	_, open := h.rooms[room]
+	h.mu.RUnlock()
+	if !open {
+		return
+	}
+
+	h.roomData.mu.Lock()
+	if h.roomData.entries[room] == nil {
+		h.roomData.entries[room] = make(map[string]*roomDataEntry)
+	}
+	entry, existed := h.roomData.entries[room][key]
+	if !existed {
+		entry = &roomDataEntry{}
+		h.roomData.entries[room][key] = entry
+	}
+
+	previous := entry.value
+	entry.value = value
+	entry.version++
+
+	full := fullDataUpdate(key, entry)
+	var patch map[string]interface{}
+	if existed && entry.version%fullResyncEvery != 0 {
+		patch = map[string]interface{}{
+			"key":          key,
+			"version":      entry.version,
+			"base_version": entry.version - 1,
+			"ops":          diffPatch("", previous, value),
+		}
+	}
+	h.roomData.mu.Unlock()
+
+	fullMessage, err := newEncodedMessage(&Message{Type: "data_update", Room: room, Data: full, Timestamp: time.Now()})
+	if err != nil {
+		log.Printf("Error marshaling message: %v", err)
+		return
+	}
+	patchMessage := fullMessage
+	if patch != nil {
+		patchMessage, err = newEncodedMessage(&Message{Type: "data_patch", Room: room, Data: patch, Timestamp: time.Now()})
+		if err != nil {
+			log.Printf("Error marshaling message: %v", err)
+			return
+		}
+	}
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for client := range h.rooms[room] {
+		message := fullMessage
+		if client.HasCapability(CapabilityDelta) {
+			message = patchMessage
+		}
+
+		if !client.queue(message) {
+			// A delta client that misses a patch will resync on the version gap
+			log.Printf("Client send buffer full, message dropped: userID=%s", client.userID)
+		}
+	}
+}
+
+// ClearRoomData discards all data published for a room, e.g. when a stream ends
+func (h *Hub) ClearRoomData(room string) {
+	h.roomData.mu.Lock()
+	defer h.roomData.mu.Unlock()
+	delete(h.roomData.entries, room)
+}
+
+// fullDataUpdate builds a data_update payload (caller must hold the store lock)
+func fullDataUpdate(key string, entry *roomDataEntry) map[string]interface{} {
+	return map[string]interface{}{
+		"key":     key,
+		"version": entry.version,
+		"value":   entry.value,
+	}
+}
+
+// diffPatch returns the operations that turn old into new. Nested objects
+// are diffed recursively; any other changed value is replaced whole.
+func diffPatch(path string, old, new map[string]interface{}) []PatchOp {
+	keys := make([]string, 0, len(old)+len(new))
+	for key := range old {
+		keys = append(keys, key)
+	}
+	for key := range new {
+		if _, ok := old[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	ops := make([]PatchOp, 0)
+	for _, key := range keys {
+		keyPath := path + "/" + escapePointer(key)
+		oldValue, inOld := old[key]
+		newValue, inNew := new[key]
+
+		switch {
+		case !inNew:
+			ops = append(ops, PatchOp{Op: "remove", Path: keyPath})
+		case !inOld:
+			ops = append(ops, PatchOp{Op: "add", Path: keyPath, Value: newValue})
+		case reflect.DeepEqual(oldValue, newValue):
+			// Unchanged
+		default:
+			oldMap, oldIsMap := oldValue.(map[string]interface{})
+			newMap, newIsMap := newValue.(map[string]interface{})
+			if oldIsMap && newIsMap {
+				ops = append(ops, diffPatch(keyPath, oldMap, newMap)...)
+			} else {
+				ops = append(ops, PatchOp{Op: "replace", Path: keyPath, Value: newValue})
+			}
+		}
+	}
+	return ops
+}
+
+// escapePointer escapes a key for use in a JSON Pointer (RFC 6901)
+func escapePointer(key string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(key)
+}
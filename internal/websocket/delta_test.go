@@ -0,0 +1,89 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/tinle0301/streaming-platform-api/internal/events"
+	"github.com/tinle0301/streaming-platform-api/internal/tenancy"
+)
+
+// nextMessage reads the next message queued for a JSON client
+func nextMessage(t *testing.T, client *Client) Message {
+	t.Helper()
+	select {
+	case out := <-client.send:
+		var msg Message
+		if err := json.Unmarshal(out.data, &msg); err != nil {
+			t.Fatal(err)
+		}
+		return msg
+	default:
+		t.Fatal("no message queued")
+		return Message{}
+	}
+}
+
+func TestGoalProgressIsPublishedAsRoomData(t *testing.T) {
+	hub := NewHub()
+	room := tenancy.Room(tenancy.Default, OverlayRoom("channel-1"))
+	plain := NewClient(hub, nil, "overlay")
+	delta := NewClient(hub, nil, "delta-overlay")
+	delta.capabilities[CapabilityDelta] = true
+	hub.JoinRoom(room, plain)
+	hub.JoinRoom(room, delta)
+
+	progress := func(current int) events.Event {
+		return events.Event{
+			ID:       "event",
+			Type:     events.EventTypeGoalProgress,
+			TenantID: tenancy.Default,
+			Data: map[string]interface{}{
+				"channel_id": "channel-1",
+				"goal_id":    "goal-1",
+				"current":    current,
+				"target":     100,
+			},
+		}
+	}
+
+	hub.DispatchEvent(context.Background(), progress(10))
+	for _, client := range []*Client{plain, delta} {
+		msg := nextMessage(t, client)
+		value, _ := msg.Data["value"].(map[string]interface{})
+		if msg.Type != "data_update" || msg.Data["key"] != GoalDataKey("goal-1") || value["current"] != float64(10) {
+			t.Errorf("first progress to %s = %s %v, want the goal's data_update", client.userID, msg.Type, msg.Data)
+		}
+		if value["event_id"] != nil {
+			t.Errorf("goal data carries the event ID: %v", value)
+		}
+	}
+
+	hub.DispatchEvent(context.Background(), progress(20))
+	if msg := nextMessage(t, plain); msg.Type != "data_update" {
+		t.Errorf("second progress to a plain client = %s, want data_update", msg.Type)
+	}
+	msg := nextMessage(t, delta)
+	ops, _ := msg.Data["ops"].([]interface{})
+	if msg.Type != "data_patch" || len(ops) != 1 {
+		t.Fatalf("second progress to a delta client = %s %v, want a data_patch of current", msg.Type, msg.Data)
+	}
+
+	hub.LeaveRoom(room, plain)
+	hub.LeaveRoom(room, delta)
+	hub.roomData.mu.Lock()
+	_, kept := hub.roomData.entries[room]
+	hub.roomData.mu.Unlock()
+	if kept {
+		t.Error("room data was kept after the room's last client left")
+	}
+
+	hub.DispatchEvent(context.Background(), progress(30))
+	hub.roomData.mu.Lock()
+	_, kept = hub.roomData.entries[room]
+	hub.roomData.mu.Unlock()
+	if kept {
+		t.Error("room data was stored for a room without clients")
+	}
+}
@@ -0,0 +1,137 @@
+package websocket
+
+import (
+	"context"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/tinle0301/streaming-platform-api/internal/events"
+	"github.com/tinle0301/streaming-platform-api/internal/tenancy"
+)
+
+// maxDirectMessageLength caps a direct message, in characters
+const maxDirectMessageLength = 500
+
+// UserObserver is told when a user's first connection to this node opens
+// and their last one closes, e.g. to track who is online across nodes.
+// Guests are not reported. Calls are made with the hub's lock held and must
+// not block.
+type UserObserver interface {
+	UserOnline(userID string)
+	UserOffline(userID string)
+}
+
+// SetDirectMessages enables direct messages. Sent messages are published
+// for the API server to check against blocks and the messaging policy; it
+// publishes allowed ones back for every node to deliver. online is told
+// which users are connected, so messages to offline users become
+// notifications instead.
+func (h *Hub) SetDirectMessages(publisher events.Publisher, online UserObserver) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.directPublisher = publisher
+	h.userObserver = online
+}
+
+// handleDirect sends a private message to another user:
+//
+//	{"type":"direct","id":"7","data":{"to":"<user id>","message":"hi"}}
+//
+// The sender is acked with the message_id once it is accepted for delivery.
+// Every connection of both users then receives it as
+//
+//	{"type":"direct","data":{"message_id":"...","from":"...","to":"...","message":"hi","sent_at":1700000000000}}
+//
+// or, if blocks or the mutual-follow requirement forbid it, the sender's
+// connections receive direct_rejected with the message_id and a reason.
+func (c *Client) handleDirect(ctx context.Context, msg *Message) {
+	if c.rejectGuest(msg) {
+		return
+	}
+
+	to, _ := msg.Data["to"].(string)
+	text, _ := msg.Data["message"].(string)
+	text = strings.TrimSpace(text)
+	switch {
+	case to == "" || text == "":
+		c.sendError(ErrorCodeInvalidMessage, "direct messages need a recipient and a message", msg)
+		return
+	case to == c.userID:
+		c.sendError(ErrorCodeInvalidMessage, "you cannot message yourself", msg)
+		return
+	case len([]rune(text)) > maxDirectMessageLength:
+		c.sendError(ErrorCodeInvalidMessage, "direct message is too long", msg)
+		return
+	}
+
+	c.hub.mu.RLock()
+	publisher := c.hub.directPublisher
+	c.hub.mu.RUnlock()
+	if publisher == nil {
+		c.sendError(ErrorCodeUnavailable, "direct messages are not available", msg)
+		return
+	}
+
+	event := events.NewDirectMessageSentEvent(c.userID, to, text)
+	ack := map[string]interface{}{
+		"action":     "direct",
+		"message_id": event.ID,
+	}
+
+	// Platform-wide shadow bans apply: the sender sees their message
+	// delivered, but it never leaves this connection
+	if c.hub.isShadowBanned("", c.userID) {
+		c.sendMessage("ack", ack)
+		c.sendMessage("direct", map[string]interface{}{
+			"message_id": event.ID,
+			"from":       c.userID,
+			"to":         to,
+			"message":    text,
+			"sent_at":    event.Timestamp.UnixMilli(),
+		})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(tenancy.WithTenant(ctx, c.Tenant()), 2*time.Second)
+	defer cancel()
+	if err := publisher.Publish(ctx, event); err != nil {
+		log.Printf("Error publishing direct message: userID=%s, err=%v", c.userID, err)
+		c.sendError(ErrorCodeUnavailable, "direct message could not be sent, try again", msg)
+		return
+	}
+	c.sendMessage("ack", ack)
+}
+
+// dispatchDirectEvent delivers a checked direct message to every connection
+// of both users on this node, or tells the sender's connections it was
+// rejected
+func (h *Hub) dispatchDirectEvent(event events.Event, data map[string]interface{}) {
+	switch event.Type {
+	case events.EventTypeDirectMessage:
+		from, _ := data["from"].(string)
+		to, _ := data["to"].(string)
+		h.SendToUser(to, "direct", data)
+		if from != to {
+			h.SendToUser(from, "direct", data)
+		}
+	case events.EventTypeDirectMessageRejected:
+		h.SendToUser(event.UserID, "direct_rejected", data)
+	}
+}
+
+// observeUserOnline reports a user's first connection to this node (caller
+// must hold h.mu)
+func (h *Hub) observeUserOnline(client *Client) {
+	if h.userObserver != nil && !client.IsGuest() && len(h.userClients[client.userID]) == 1 {
+		h.userObserver.UserOnline(client.userID)
+	}
+}
+
+// observeUserOffline reports that a user's last connection to this node
+// closed (caller must hold h.mu)
+func (h *Hub) observeUserOffline(client *Client) {
+	if h.userObserver != nil && !client.IsGuest() && len(h.userClients[client.userID]) == 0 {
+		h.userObserver.UserOffline(client.userID)
+	}
+}
@@ -0,0 +1,115 @@
+package websocket
+
+import (
+	"errors"
+	"log/slog"
+	"net"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/tinle0301/streaming-platform-api/internal/websocket/closecode"
+)
+
+// Disconnect closes the client's connection with a code from package
+// closecode. It is safe to call while holding the hub lock.
+func (c *Client) Disconnect(code int) {
+	c.mu.Lock()
+	if c.closeCode == 0 {
+		c.closeCode = code
+	}
+	c.mu.Unlock()
+
+	// The hub may be the caller, so unregister without blocking it
+	go c.hub.unregister(c)
+}
+
+// closeMessage builds the close frame for the client's close code. A hub
+// that closes the send channel without a code is draining the node.
+func (c *Client) closeMessage() []byte {
+	c.mu.RLock()
+	code, retryAfter := c.closeCode, c.retryAfter
+	c.mu.RUnlock()
+
+	if code == 0 {
+		code = closecode.ServerDrain
+	}
+	if retryAfter == 0 {
+		retryAfter = retryDelay(code)
+	}
+	return websocket.FormatCloseMessage(code, closecode.Text(code, retryAfter))
+}
+
+// queue adds a message to the send buffer in the client's encoding without
+// blocking. It reports false if the buffer is full. Messages a backgrounded
+// client doesn't accept are discarded, as are messages for a client whose
+// send buffer the hub has closed, unless its connection dropped and they
+// can be buffered as JSON for resume.
+func (c *Client) queue(message *encodedMessage) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if !c.accepts(message.message.Type) {
+		return true
+	}
+	if c.sendClosed {
+		if c.detached != nil {
+			jsonBytes, _ := message.encode(EncodingJSON)
+			c.detached.add(jsonBytes)
+		}
+		return true
+	}
+	data, err := message.encode(c.encoding)
+	if err != nil {
+		slog.Error("Error encoding message", "user_id", c.userID, "encoding", c.encoding, "err", err)
+		return true
+	}
+	select {
+	case c.send <- outbound{data: data, binary: c.encoding != EncodingJSON}:
+		return true
+	default:
+		return false
+	}
+}
+
+// closeSend closes the send buffer once, making the write pump send the
+// close frame and exit
+func (c *Client) closeSend() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.sendClosed {
+		c.sendClosed = true
+		close(c.send)
+	}
+}
+
+// retryDelay is the jittered reconnect delay hinted for a close code, so
+// disconnected clients don't all return at the same instant
+func retryDelay(code int) time.Duration {
+	switch code {
+	case closecode.ServerDrain:
+		return ReconnectJitter(time.Second, 30*time.Second)
+	case closecode.RateLimited:
+		return ReconnectJitter(10*time.Second, 20*time.Second)
+	case closecode.Disconnected:
+		return ReconnectJitter(5*time.Second, 30*time.Second)
+	case closecode.IdleTimeout, closecode.SlowConsumer, closecode.MessageTooBig:
+		return ReconnectJitter(0, 5*time.Second)
+	default:
+		return 0
+	}
+}
+
+// readCloseCode maps a ReadPump error to the close code the server should
+// send, or 0 if the peer closed or the connection is already gone
+func readCloseCode(err error) int {
+	var netErr net.Error
+	switch {
+	case errors.Is(err, websocket.ErrReadLimit):
+		return closecode.MessageTooBig
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return closecode.IdleTimeout
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,276 @@
+package websocket
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/vmihailenco/msgpack/v5"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Encodings a client may negotiate in its hello message. Messages are JSON
+// text frames until the welcome reply; after it, each message is a binary
+// frame of its own in the negotiated encoding.
+const (
+	EncodingJSON = "json"
+
+	// EncodingMsgpack is the envelope as a MessagePack map with the JSON
+	// field names and the timestamp as a timestamp extension
+	EncodingMsgpack = "msgpack"
+
+	// EncodingProtobuf is streamhub.websocket.v1.Message from
+	// api/websocket/message.proto
+	EncodingProtobuf = "protobuf"
+)
+
+// supportedEncodings are the encodings this server can negotiate
+var supportedEncodings = map[string]bool{
+	EncodingJSON:     true,
+	EncodingMsgpack:  true,
+	EncodingProtobuf: true,
+}
+
+// Field numbers of streamhub.websocket.v1.Message
+const (
+	protoFieldID        protowire.Number = 1
+	protoFieldType      protowire.Number = 2
+	protoFieldRoom      protowire.Number = 3
+	protoFieldData      protowire.Number = 4
+	protoFieldTimestamp protowire.Number = 5
+	protoFieldSignature protowire.Number = 6
+)
+
+// outbound is an encoded message waiting in a client's send buffer
+type outbound struct {
+	data   []byte
+	binary bool
+}
+
+// encodedMessage marshals a message at most once per encoding, so a
+// broadcast costs one marshal for each encoding its clients negotiated
+// rather than one per client. It is not safe for concurrent use.
+type encodedMessage struct {
+	message *Message
+	encoded map[string][]byte
+
+	// data is the message's data as JSON decodes it, shared by the binary
+	// encodings so every encoding carries the same fields and values
+	data       map[string]interface{}
+	normalized bool
+}
+
+// newEncodedMessage marshals message as JSON, which every message needs
+// for session resume, and leaves the binary encodings until a client
+// asks for them. The message must not be modified afterwards.
+func newEncodedMessage(message *Message) (*encodedMessage, error) {
+	jsonBytes, err := json.Marshal(message)
+	if err != nil {
+		return nil, err
+	}
+	return &encodedMessage{
+		message: message,
+		encoded: map[string][]byte{EncodingJSON: jsonBytes},
+	}, nil
+}
+
+// encode returns the message in encoding
+func (m *encodedMessage) encode(encoding string) ([]byte, error) {
+	if encoding == "" {
+		encoding = EncodingJSON
+	}
+	if encoded, ok := m.encoded[encoding]; ok {
+		return encoded, nil
+	}
+
+	var encoded []byte
+	var err error
+	switch encoding {
+	case EncodingMsgpack:
+		encoded, err = m.marshalMsgpack()
+	case EncodingProtobuf:
+		encoded, err = m.marshalProtobuf()
+	default:
+		err = fmt.Errorf("unknown encoding %q", encoding)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode message as %s: %w", encoding, err)
+	}
+	m.encoded[encoding] = encoded
+	return encoded, nil
+}
+
+// normalizedData returns the message's data decoded from its JSON
+func (m *encodedMessage) normalizedData() (map[string]interface{}, error) {
+	if !m.normalized {
+		var envelope struct {
+			Data map[string]interface{} `json:"data"`
+		}
+		if err := json.Unmarshal(m.encoded[EncodingJSON], &envelope); err != nil {
+			return nil, err
+		}
+		m.data, m.normalized = envelope.Data, true
+	}
+	return m.data, nil
+}
+
+// marshalMsgpack encodes the message as MessagePack. Whole numbers are
+// written as integers, although JSON decoded them as floats.
+func (m *encodedMessage) marshalMsgpack() ([]byte, error) {
+	data, err := m.normalizedData()
+	if err != nil {
+		return nil, err
+	}
+	envelope := *m.message
+	envelope.Data = data
+
+	var buf bytes.Buffer
+	enc := msgpack.NewEncoder(&buf)
+	enc.UseCompactInts(true)
+	enc.UseCompactFloats(true)
+	if err := enc.Encode(&envelope); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// marshalProtobuf encodes the message as a streamhub.websocket.v1.Message
+func (m *encodedMessage) marshalProtobuf() ([]byte, error) {
+	data, err := m.normalizedData()
+	if err != nil {
+		return nil, err
+	}
+	dataStruct, err := structpb.NewStruct(data)
+	if err != nil {
+		return nil, err
+	}
+	dataBytes, err := proto.Marshal(dataStruct)
+	if err != nil {
+		return nil, err
+	}
+
+	msg := m.message
+	b := make([]byte, 0, len(dataBytes)+len(msg.Type)+len(msg.Room)+64)
+	b = appendProtoString(b, protoFieldID, msg.ID)
+	b = appendProtoString(b, protoFieldType, msg.Type)
+	b = appendProtoString(b, protoFieldRoom, msg.Room)
+	b = protowire.AppendTag(b, protoFieldData, protowire.BytesType)
+	b = protowire.AppendBytes(b, dataBytes)
+	if !msg.Timestamp.IsZero() {
+		timestampBytes, err := proto.Marshal(timestamppb.New(msg.Timestamp))
+		if err != nil {
+			return nil, err
+		}
+		b = protowire.AppendTag(b, protoFieldTimestamp, protowire.BytesType)
+		b = protowire.AppendBytes(b, timestampBytes)
+	}
+	b = appendProtoString(b, protoFieldSignature, msg.Signature)
+	return b, nil
+}
+
+// appendProtoString appends a string field, leaving it out when empty as
+// proto3 does
+func appendProtoString(b []byte, num protowire.Number, value string) []byte {
+	if value == "" {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendString(b, value)
+}
+
+// decodeMessage parses a binary frame in encoding. Data comes out with the
+// types JSON decodes to, so handlers need not care how the client encodes.
+func decodeMessage(encoding string, frame []byte, msg *Message) error {
+	switch encoding {
+	case EncodingMsgpack:
+		if err := msgpack.Unmarshal(frame, msg); err != nil {
+			return err
+		}
+		// MessagePack integers and binary strings become JSON's floats and strings
+		dataBytes, err := json.Marshal(msg.Data)
+		if err != nil {
+			return err
+		}
+		msg.Data = nil
+		return json.Unmarshal(dataBytes, &msg.Data)
+	case EncodingProtobuf:
+		return unmarshalProtobuf(frame, msg)
+	default:
+		return fmt.Errorf("no binary encoding negotiated")
+	}
+}
+
+// unmarshalProtobuf parses a streamhub.websocket.v1.Message. Unknown
+// fields are skipped.
+func unmarshalProtobuf(frame []byte, msg *Message) error {
+	for len(frame) > 0 {
+		num, typ, n := protowire.ConsumeTag(frame)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		frame = frame[n:]
+		if typ != protowire.BytesType {
+			n = protowire.ConsumeFieldValue(num, typ, frame)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			frame = frame[n:]
+			continue
+		}
+		value, n := protowire.ConsumeBytes(frame)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		frame = frame[n:]
+
+		switch num {
+		case protoFieldID:
+			msg.ID = string(value)
+		case protoFieldType:
+			msg.Type = string(value)
+		case protoFieldRoom:
+			msg.Room = string(value)
+		case protoFieldData:
+			var data structpb.Struct
+			if err := proto.Unmarshal(value, &data); err != nil {
+				return err
+			}
+			msg.Data = data.AsMap()
+		case protoFieldTimestamp:
+			var timestamp timestamppb.Timestamp
+			if err := proto.Unmarshal(value, &timestamp); err != nil {
+				return err
+			}
+			msg.Timestamp = timestamp.AsTime()
+		case protoFieldSignature:
+			msg.Signature = string(value)
+		}
+	}
+	return nil
+}
+
+// Encoding returns the encoding the client negotiated
+func (c *Client) Encoding() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.encoding
+}
+
+// negotiateEncoding picks the first supported encoding from a hello's
+// encodings, listed in the client's order of preference. It reports false
+// if the client did not list any, keeping its current encoding.
+func negotiateEncoding(msg *Message) (string, bool) {
+	requested, ok := msg.Data["encodings"].([]interface{})
+	if !ok {
+		return "", false
+	}
+	for _, value := range requested {
+		if encoding, ok := value.(string); ok && supportedEncodings[encoding] {
+			return encoding, true
+		}
+	}
+	return EncodingJSON, true
+}
@@ -0,0 +1,122 @@
+package websocket
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestEncodingRoundTrip(t *testing.T) {
+	message := &Message{
+		ID:   "42",
+		Type: "data_update",
+		Room: "stream:1",
+		Data: map[string]interface{}{
+			"count":  12,
+			"ratio":  0.25,
+			"value":  map[string]interface{}{"options": []string{"a", "b"}, "open": true},
+			"ops":    []PatchOp{{Op: "replace", Path: "/count", Value: 12}},
+			"winner": nil,
+		},
+		Timestamp: time.Date(2024, 5, 1, 12, 30, 0, 123456789, time.UTC),
+		Signature: "sig",
+	}
+	encoded, err := newEncodedMessage(message)
+	if err != nil {
+		t.Fatalf("newEncodedMessage: %v", err)
+	}
+	var want Message
+	if err := json.Unmarshal(encoded.encoded[EncodingJSON], &want); err != nil {
+		t.Fatalf("unmarshal JSON: %v", err)
+	}
+
+	for _, encoding := range []string{EncodingMsgpack, EncodingProtobuf} {
+		t.Run(encoding, func(t *testing.T) {
+			frame, err := encoded.encode(encoding)
+			if err != nil {
+				t.Fatalf("encode: %v", err)
+			}
+			if len(frame) >= len(encoded.encoded[EncodingJSON]) {
+				t.Errorf("encoded %d bytes, no smaller than JSON's %d", len(frame), len(encoded.encoded[EncodingJSON]))
+			}
+			if again, _ := encoded.encode(encoding); &again[0] != &frame[0] {
+				t.Error("message was marshaled again instead of reusing its encoding")
+			}
+
+			var got Message
+			if err := decodeMessage(encoding, frame, &got); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			if !got.Timestamp.Equal(want.Timestamp) {
+				t.Errorf("timestamp = %v, want %v", got.Timestamp, want.Timestamp)
+			}
+			got.Timestamp = want.Timestamp
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("decoded %+v\nwant %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestHelloNegotiatesEncoding(t *testing.T) {
+	hub := startRaceHub(t)
+	clients := make([]*Client, 3)
+	for i, encodings := range [][]interface{}{{"cbor", "protobuf"}, {"protobuf", "msgpack"}, {"cbor"}} {
+		client := NewClient(hub, nil, "viewer")
+		if !hub.AddClient(client) {
+			t.Fatal("client was refused")
+		}
+		t.Cleanup(func() { hub.unregister(client) })
+		hub.JoinRoom("stream:1", client)
+		clients[i] = client
+
+		client.handleHello(&Message{Type: "hello", Data: map[string]interface{}{"encodings": encodings}})
+		welcome := nextOfType(t, client, "welcome")
+		if welcome.binary {
+			t.Error("welcome was sent in the negotiated encoding")
+		}
+	}
+	if clients[0].Encoding() != EncodingProtobuf || clients[2].Encoding() != EncodingJSON {
+		t.Fatalf("encodings = %s, %s, want protobuf, json", clients[0].Encoding(), clients[2].Encoding())
+	}
+
+	hub.BroadcastToRoom("stream:1", "viewer_count", map[string]interface{}{"count": 3})
+	first := nextOfType(t, clients[0], "viewer_count")
+	second := nextOfType(t, clients[1], "viewer_count")
+	if !first.binary || !second.binary {
+		t.Fatal("protobuf clients received a text frame")
+	}
+	if &first.data[0] != &second.data[0] {
+		t.Error("the broadcast was marshaled once per client instead of once per encoding")
+	}
+	if jsonClient := nextOfType(t, clients[2], "viewer_count"); jsonClient.binary {
+		t.Error("a JSON client received a binary frame")
+	}
+}
+
+// nextOfType reads a client's send buffer until a message of messageType
+func nextOfType(t *testing.T, client *Client, messageType string) outbound {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case message := <-client.send:
+			var decoded Message
+			var err error
+			if message.binary {
+				err = decodeMessage(client.Encoding(), message.data, &decoded)
+			} else {
+				err = json.Unmarshal(message.data, &decoded)
+			}
+			if err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			if decoded.Type == messageType {
+				return message
+			}
+		case <-timeout:
+			t.Fatalf("no %s message", messageType)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package websocket
+
+// Protocol error codes sent in "error" messages. Clients should branch on
+// the code; the message is for humans and may change.
+const (
+	// The message was not valid JSON or is missing required fields
+	ErrorCodeInvalidMessage = "invalid_message"
+
+	// The message type is not part of the protocol
+	ErrorCodeUnknownType = "unknown_type"
+
+	// The client may not do this, e.g. join a room it has no access to
+	ErrorCodeForbidden = "forbidden"
+
+	// The message exceeded the connection's or IP's rate limit
+	ErrorCodeRateLimited = "rate_limited"
+
+	// The action needs a signed-in account rather than a guest session
+	ErrorCodeAuthRequired = "auth_required"
+
+	// A presented token was missing, invalid, expired, or for another user
+	ErrorCodeAuthInvalid = "auth_invalid"
+
+	// A registered bot's message was unsigned or its signature was invalid
+	ErrorCodeInvalidSignature = "invalid_signature"
+
+	// The feature is not enabled on this server or its backend is down
+	ErrorCodeUnavailable = "unavailable"
+)
+
+// sendError tells the client a message was rejected. ref is the offending
+// message, or nil if it could not be parsed. Error messages look like:
+//
+//	{"type":"error","data":{"code":"forbidden","message":"...",
+//	 "ref":{"type":"subscribe","id":"42","room":"abc"}}}
+func (c *Client) sendError(code, message string, ref *Message) {
+	data := map[string]interface{}{
+		"code":    code,
+		"message": message,
+	}
+	if ref != nil {
+		offending := map[string]interface{}{"type": ref.Type}
+		if ref.ID != "" {
+			offending["id"] = ref.ID
+		}
+		if ref.Room != "" {
+			offending["room"] = ref.Room
+		}
+		data["ref"] = offending
+	}
+	c.sendMessage("error", data)
+}
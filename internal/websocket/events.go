@@ -0,0 +1,100 @@
+package websocket
+
+import (
+	"context"
+	"strings"
+
+	"github.com/tinle0301/streaming-platform-api/internal/events"
+	"github.com/tinle0301/streaming-platform-api/internal/tenancy"
+)
+
+// DispatchEvent fans a domain event out to connected clients. Stream events
+// go to the stream's room, in the namespace of the event's tenant; user
+// events go to every connection of the user.
+// Stored notifications are delivered as "notification" messages.
+// It satisfies events.Handler so a Subscriber can feed the hub directly.
+func (h *Hub) DispatchEvent(ctx context.Context, event events.Event) error {
+	data := make(map[string]interface{}, len(event.Data)+1)
+	for key, value := range event.Data {
+		data[key] = value
+	}
+	data["event_id"] = event.ID
+
+	switch {
+	case event.Type == events.EventTypeNotificationCreated:
+		h.notifyUser(event.UserID, data)
+	case strings.HasPrefix(event.Type, "party."):
+		h.dispatchPartyEvent(event, data)
+	case strings.HasPrefix(event.Type, "community."):
+		h.dispatchCommunityEvent(event, data)
+	case strings.HasPrefix(event.Type, "direct."):
+		h.dispatchDirectEvent(event, data)
+	case strings.HasPrefix(event.Type, "raid."):
+		h.dispatchRaidEvent(event, data)
+	case strings.HasPrefix(event.Type, "premiere."):
+		vodID, _ := data["vod_id"].(string)
+		h.BroadcastToRoom(tenancy.Room(event.TenantID, PremiereRoom(vodID)), event.Type, data)
+	case event.Type == events.EventTypeGoalProgress:
+		// Progress changes with every contribution, so overlays get it as
+		// room data: a patch for delta clients, the whole goal for others
+		channelID, _ := data["channel_id"].(string)
+		goalID, _ := data["goal_id"].(string)
+		delete(data, "event_id")
+		h.PublishRoomData(tenancy.Room(event.TenantID, OverlayRoom(channelID)), GoalDataKey(goalID), data)
+	case strings.HasPrefix(event.Type, "goal."):
+		channelID, _ := data["channel_id"].(string)
+		h.BroadcastToRoom(tenancy.Room(event.TenantID, OverlayRoom(channelID)), event.Type, data)
+	case event.Type == events.EventTypeCaptionSegment:
+		language, _ := data["language"].(string)
+		h.BroadcastToRoom(tenancy.Room(event.TenantID, CaptionRoom(event.StreamID, language)), event.Type, data)
+	case event.Type == events.EventTypeStreamOffline:
+		room := tenancy.Room(event.TenantID, event.StreamID)
+		h.ClearRoomData(room)
+		h.BroadcastToRoom(room, event.Type, data)
+	case event.StreamID != "":
+		h.BroadcastToRoom(tenancy.Room(event.TenantID, event.StreamID), event.Type, data)
+	case event.UserID != "":
+		h.SendToUser(event.UserID, event.Type, data)
+	}
+	return nil
+}
+
+// CaptionRoom names the sub-channel of a stream's room carrying its captions
+// in one language. Viewers subscribe to it alongside the stream's room.
+func CaptionRoom(streamID, language string) string {
+	return streamID + ":captions:" + language
+}
+
+// PremiereRoom names the room viewers of a VOD's premiere join for its
+// countdown and playback position
+func PremiereRoom(vodID string) string {
+	return "premiere:" + vodID
+}
+
+// OverlayRoom names the room a channel's stream overlays join for goal
+// progress and celebrations
+func OverlayRoom(channelID string) string {
+	return "overlay:" + channelID
+}
+
+// GoalDataKey names the room data key of a goal's progress in its
+// channel's overlay room
+func GoalDataKey(goalID string) string {
+	return "goal:" + goalID
+}
+
+// notifyUser delivers a stored notification to every connection of a user
+func (h *Hub) notifyUser(userID string, data map[string]interface{}) {
+	notificationType, _ := data["type"].(string)
+
+	h.mu.RLock()
+	targets := make([]*Client, 0, len(h.userClients[userID]))
+	for client := range h.userClients[userID] {
+		targets = append(targets, client)
+	}
+	h.mu.RUnlock()
+
+	for _, client := range targets {
+		client.SendNotification(notificationType, data)
+	}
+}
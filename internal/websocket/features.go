@@ -0,0 +1,61 @@
+package websocket
+
+import (
+	"errors"
+	"log"
+)
+
+// Features operators can switch off on a running node, e.g. to shed load
+// during an incident. All are on when a node starts.
+const (
+	// FeatureChatHistory records relayed chat for viewers who join later
+	FeatureChatHistory = "chat_history"
+
+	// FeatureChatSampling delivers a sample of chat in very large rooms;
+	// off, every viewer gets every message
+	FeatureChatSampling = "chat_sampling"
+
+	// FeatureViewerCounts broadcasts rooms' viewer counts
+	FeatureViewerCounts = "viewer_counts"
+)
+
+// features lists every feature that can be switched
+var features = []string{FeatureChatHistory, FeatureChatSampling, FeatureViewerCounts}
+
+// ErrUnknownFeature is returned when switching a feature that doesn't exist
+var ErrUnknownFeature = errors.New("unknown feature")
+
+// Features reports whether each feature is on
+func (h *Hub) Features() map[string]bool {
+	h.featuresMu.RLock()
+	defer h.featuresMu.RUnlock()
+
+	states := make(map[string]bool, len(features))
+	for _, feature := range features {
+		states[feature] = !h.disabledFeatures[feature]
+	}
+	return states
+}
+
+// SetFeature switches a feature on or off on this node
+func (h *Hub) SetFeature(feature string, enabled bool) error {
+	if _, ok := h.Features()[feature]; !ok {
+		return ErrUnknownFeature
+	}
+
+	h.featuresMu.Lock()
+	defer h.featuresMu.Unlock()
+	if h.disabledFeatures[feature] == !enabled {
+		return nil
+	}
+	h.disabledFeatures[feature] = !enabled
+	log.Printf("Feature switched: feature=%s, enabled=%v", feature, enabled)
+	return nil
+}
+
+// featureEnabled reports whether a feature is on
+func (h *Hub) featureEnabled(feature string) bool {
+	h.featuresMu.RLock()
+	defer h.featuresMu.RUnlock()
+	return !h.disabledFeatures[feature]
+}
@@ -0,0 +1,78 @@
+package websocket
+
+import (
+	"testing"
+)
+
+// FuzzHandleFrame feeds arbitrary frames through the same path ReadPump
+// uses. Inputs that once crashed the server live in testdata/fuzz.
+func FuzzHandleFrame(f *testing.F) {
+	seeds := []string{
+		`{"type":"hello","data":{"capabilities":["delta","batch"]}}`,
+		`{"type":"subscribe","data":{"room":"stream:1"}}`,
+		`{"type":"unsubscribe","data":{"room":"stream:1"}}`,
+		`{"type":"ping"}`,
+		`{"type":"message","room":"stream:1","data":{"message":"hello"}}`,
+		`{"type":"delete_message","room":"stream:1","data":{"message_id":"m1"}}`,
+		`{"type":"whisper","data":{"to":"user-2","message":"hi"}}`,
+		`{"type":"direct","data":{"to":"user-2","message":"hi"}}`,
+		`{"type":"heartbeat","data":{"nonce":"abc"}}`,
+		`{"type":"resync","room":"stream:1","data":{"key":"viewers"}}`,
+		`{"type":"app_state","data":{"state":"background"}}`,
+		`{"type":"subscribe","data":{"room":42}}`,
+		`{"type":"message","room":"stream:1","data":null}`,
+		`{"type":null,"data":[]}`,
+		`[]`,
+		`null`,
+		`{`,
+	}
+	for _, seed := range seeds {
+		f.Add([]byte(seed))
+	}
+
+	hub := startRaceHub(f)
+	hub.SetMessageRateLimits(MessageRateLimits{})
+
+	f.Fuzz(func(t *testing.T, frame []byte) {
+		client := newDrainedClient(hub, "fuzz-user")
+		if !hub.AddClient(client) {
+			t.Fatal("client was refused")
+		}
+		defer hub.unregister(client)
+
+		client.handleFrame(frame)
+	})
+}
+
+// FuzzHandleBinaryFrame feeds arbitrary binary frames through each binary
+// encoding's decoder
+func FuzzHandleBinaryFrame(f *testing.F) {
+	for _, encoding := range []string{EncodingMsgpack, EncodingProtobuf} {
+		encoded, err := newEncodedMessage(&Message{Type: "subscribe", Data: map[string]interface{}{"room": "stream:1"}})
+		if err != nil {
+			f.Fatal(err)
+		}
+		frame, err := encoded.encode(encoding)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(encoding == EncodingProtobuf, frame)
+	}
+
+	hub := startRaceHub(f)
+	hub.SetMessageRateLimits(MessageRateLimits{})
+
+	f.Fuzz(func(t *testing.T, protobuf bool, frame []byte) {
+		client := newDrainedClient(hub, "fuzz-user")
+		client.encoding = EncodingMsgpack
+		if protobuf {
+			client.encoding = EncodingProtobuf
+		}
+		if !hub.AddClient(client) {
+			t.Fatal("client was refused")
+		}
+		defer hub.unregister(client)
+
+		client.handleBinaryFrame(frame)
+	})
+}
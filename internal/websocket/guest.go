@@ -0,0 +1,155 @@
+package websocket
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"time"
+)
+
+// guestIDPrefix marks the user IDs of anonymous viewers
+const guestIDPrefix = "guest_"
+
+// GuestTokenIssuer signs guest tokens so anonymous viewers keep the same
+// identity, and tenant, across reconnects
+type GuestTokenIssuer interface {
+	IssueGuestToken(guestID, tenantID string) (string, time.Time, error)
+}
+
+// GuestTokenIssuerFunc adapts a function to GuestTokenIssuer
+type GuestTokenIssuerFunc func(guestID, tenantID string) (string, time.Time, error)
+
+// IssueGuestToken calls f
+func (f GuestTokenIssuerFunc) IssueGuestToken(guestID, tenantID string) (string, time.Time, error) {
+	return f(guestID, tenantID)
+}
+
+// GuestPolicy limits what anonymous viewers may do. Guests can watch and
+// read chat but never send chat or direct messages.
+type GuestPolicy struct {
+	// MaxRooms caps how many rooms a guest may be in at once (0 = unlimited)
+	MaxRooms int
+}
+
+// DefaultGuestPolicy returns the guest limits used when none are configured
+func DefaultGuestPolicy() GuestPolicy {
+	return GuestPolicy{MaxRooms: 3}
+}
+
+// GuestSession is a newly created anonymous identity
+type GuestSession struct {
+	Claims TokenClaims
+
+	// Token is empty when the hub has no GuestTokenIssuer
+	Token string
+}
+
+// SetGuestTokenIssuer enables signed guest tokens
+func (h *Hub) SetGuestTokenIssuer(issuer GuestTokenIssuer) {
+	h.guestTokens = issuer
+}
+
+// SetGuestPolicy configures the limits applied to guests
+func (h *Hub) SetGuestPolicy(policy GuestPolicy) {
+	h.guestPolicy = policy
+}
+
+// NewGuestSession creates an anonymous identity in a tenant for a viewer
+// who connected without a token
+func (h *Hub) NewGuestSession(tenantID string) (*GuestSession, error) {
+	id, err := newGuestID()
+	if err != nil {
+		return nil, err
+	}
+	return h.issueGuestSession(id, tenantID)
+}
+
+// issueGuestSession signs a guest token for guestID in a tenant
+func (h *Hub) issueGuestSession(guestID, tenantID string) (*GuestSession, error) {
+	session := &GuestSession{Claims: TokenClaims{UserID: guestID, Guest: true, TenantID: tenantID}}
+	if h.guestTokens == nil {
+		return session, nil
+	}
+
+	token, expiresAt, err := h.guestTokens.IssueGuestToken(guestID, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to issue guest token: %w", err)
+	}
+	session.Token = token
+	session.Claims.ExpiresAt = expiresAt
+	return session, nil
+}
+
+// SendGuestSession tells a guest its identity and the token to reconnect with
+func (c *Client) SendGuestSession(session *GuestSession) {
+	data := map[string]interface{}{
+		"user_id": session.Claims.UserID,
+	}
+	if session.Token != "" {
+		data["token"] = session.Token
+		data["expires_at"] = session.Claims.ExpiresAt.Unix()
+	}
+	c.sendMessage("guest_session", data)
+}
+
+// renewGuestSession replaces a guest's token before it expires, keeping
+// the same guest identity
+func (c *Client) renewGuestSession() {
+	session, err := c.hub.issueGuestSession(c.userID, c.Tenant())
+	if err != nil {
+		log.Printf("Failed to renew guest session: userID=%s, err=%v", c.userID, err)
+		return
+	}
+	c.SetClaims(session.Claims)
+	c.SendGuestSession(session)
+}
+
+// IsGuest reports whether the client is an anonymous viewer
+func (c *Client) IsGuest() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.guest
+}
+
+// rejectGuest tells a guest that msg needs an account and reports whether
+// the client is a guest
+func (c *Client) rejectGuest(msg *Message) bool {
+	if !c.IsGuest() {
+		return false
+	}
+	c.sendError(ErrorCodeAuthRequired, "sign in to do this", msg)
+	return true
+}
+
+// upgradeGuest turns a guest connection into an authenticated one in place.
+// The connection and its room subscriptions are kept.
+func (h *Hub) upgradeGuest(client *Client, claims TokenClaims) {
+	h.mu.Lock()
+	h.untrackSession(client)
+	client.mu.Lock()
+	guestID := client.userID
+	client.userID = claims.UserID
+	client.guest = false
+	client.auth = authState{expiresAt: claims.ExpiresAt}
+	client.mu.Unlock()
+	h.trackSession(client)
+	if h.roomObserver != nil {
+		for room := range client.rooms {
+			h.roomObserver.RoomLeft(room, guestID, true)
+			h.roomObserver.RoomJoined(room, claims.UserID, false)
+		}
+	}
+	h.mu.Unlock()
+
+	log.Printf("Guest signed in: guestID=%s, userID=%s", guestID, claims.UserID)
+}
+
+// newGuestID returns a random guest user ID
+func newGuestID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate guest ID: %w", err)
+	}
+	return guestIDPrefix + hex.EncodeToString(b), nil
+}
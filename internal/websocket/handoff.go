@@ -0,0 +1,109 @@
+package websocket
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"log"
+	mathrand "math/rand"
+	"time"
+)
+
+// ErrResumeNotFound is returned when a handoff token is unknown or expired
+var ErrResumeNotFound = errors.New("resume state not found")
+
+// handoffTTL bounds how long transferred state waits for the client to reconnect
+const handoffTTL = 2 * time.Minute
+
+// ResumeState is the per-client state transferred between nodes on handoff
+type ResumeState struct {
+	UserID string   `json:"user_id"`
+	Rooms  []string `json:"rooms"`
+}
+
+// ResumeStore persists resume state so another node can restore it
+type ResumeStore interface {
+	Save(ctx context.Context, token string, state ResumeState, ttl time.Duration) error
+	Take(ctx context.Context, token string) (ResumeState, error)
+}
+
+// Handoff tells every client which node to reconnect to before this node
+// shuts down. Each client's rooms are saved to store under a one-time token,
+// and reconnects are staggered across window so the remaining nodes are
+// not hit by every client at once. Clients are spread over targets in order.
+func (h *Hub) Handoff(ctx context.Context, store ResumeStore, targets []string, window time.Duration) int {
+	h.mu.RLock()
+	clients := make([]*Client, 0, len(h.clients))
+	for client := range h.clients {
+		clients = append(clients, client)
+	}
+	h.mu.RUnlock()
+
+	if len(clients) == 0 {
+		return 0
+	}
+
+	spacing := window / time.Duration(len(clients))
+	handedOff := 0
+
+	for i, client := range clients {
+		data := map[string]interface{}{
+			"delay_ms": staggeredDelay(i, spacing).Milliseconds(),
+		}
+		if len(targets) > 0 {
+			data["url"] = targets[i%len(targets)]
+		}
+
+		token, err := newHandoffToken()
+		if err == nil {
+			state := ResumeState{UserID: client.userID, Rooms: client.GetRooms()}
+			err = store.Save(ctx, token, state, handoffTTL)
+		}
+		if err != nil {
+			log.Printf("Error saving handoff state: userID=%s, err=%v", client.userID, err)
+		} else {
+			data["handoff_token"] = token
+			handedOff++
+		}
+
+		client.sendMessage("reconnect", data)
+	}
+
+	log.Printf("Handoff sent to %d clients across %d nodes over %v", len(clients), len(targets), window)
+	return handedOff
+}
+
+// Resume restores the rooms saved under a handoff token for a reconnecting client
+func (h *Hub) Resume(ctx context.Context, store ResumeStore, client *Client, token string) error {
+	state, err := store.Take(ctx, token)
+	if err != nil {
+		return err
+	}
+	if state.UserID != client.userID {
+		return ErrResumeNotFound
+	}
+
+	client.sendMessage("resumed", map[string]interface{}{
+		"rooms": h.rejoinRooms(client, state.Rooms),
+	})
+	return nil
+}
+
+// staggeredDelay spreads client i across its slot with random jitter
+func staggeredDelay(i int, spacing time.Duration) time.Duration {
+	delay := time.Duration(i) * spacing
+	if spacing > 0 {
+		delay += time.Duration(mathrand.Int63n(int64(spacing)))
+	}
+	return delay
+}
+
+// newHandoffToken generates a random one-time handoff token
+func newHandoffToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -2,12 +2,14 @@ package websocket
 
 import (
 	"context"
-	"encoding/json"
-	"log"
+	"log/slog"
 	"sync"
 	"time"
 
 	"github.com/gorilla/websocket"
+	"github.com/tinle0301/streaming-platform-api/internal/events"
+	"github.com/tinle0301/streaming-platform-api/internal/metrics"
+	"github.com/tinle0301/streaming-platform-api/internal/websocket/closecode"
 )
 
 // Hub maintains the set of active clients and broadcasts messages to the clients.
@@ -32,6 +34,114 @@ type Hub struct {
 
 	// Metrics
 	metrics *HubMetrics
+
+	// Shadow ban lookups for chat delivery (optional)
+	shadowBans ShadowBanChecker
+
+	// Signing keys for bots and extensions (optional)
+	botKeys BotKeyStore
+
+	// Cached room state sections composed into room_state on join
+	roomState *roomStateComposer
+
+	// Viewer count broadcast policy and last broadcast per room
+	// (viewerCounts is only touched from the Run goroutine)
+	viewerCountPolicy ViewerCountPolicy
+	viewerCounts      map[string]viewerCountState
+	viewerCountSource ViewerCountSource
+
+	// Told about room joins and leaves, e.g. for presence (optional)
+	roomObserver RoomObserver
+
+	// Told about relayed chat messages, e.g. for chat activity (optional)
+	chatObserver ChatObserver
+
+	// Rooms' latest chat for viewers who join later (optional)
+	chatHistory ChatHistory
+
+	// Chat senders' subscriber tiers, for their badges (optional)
+	subscriberBadges SubscriberBadges
+
+	// Chat sampling for very large rooms; chatSamplers is only touched
+	// from the Run goroutine
+	chatSampling ChatSamplingPolicy
+	chatSamplers map[string]*chatSampler
+	roomRoles    RoomRoleChecker
+
+	// Frequently changing room data sent as full values or deltas
+	roomData *roomDataStore
+
+	// Bounds on room labels in exported metrics
+	cardinality metrics.CardinalityConfig
+	roomLabels  *metrics.LabelLimiter
+
+	// Per-room delivery quality reported to creators
+	deliveryStats *deliveryStatsStore
+
+	// Access token validation and room access checks (optional)
+	tokenVerifier  TokenVerifier
+	roomAuthorizer RoomAuthorizer
+
+	// Anonymous viewer tokens and limits
+	guestTokens GuestTokenIssuer
+	guestPolicy GuestPolicy
+
+	// Compression of outbound frames
+	compression CompressionOptions
+
+	// Inbound message rate limits per connection and per IP
+	rateLimits MessageRateLimits
+	ipLimiter  *ipRateLimiter
+
+	// Each user's connections, for connection limits and the sessions API
+	userClients     map[string]map[*Client]bool
+	connectionLimit ConnectionLimitPolicy
+
+	// Stream manager mode: broadcasters receiving a prioritized room feed
+	managers  map[string]map[*Client]bool
+	streamers StreamerChecker
+
+	// Watch party hosts and playback, and where party events are published
+	// for every node (optional)
+	watchParties   WatchPartyStore
+	partyPublisher events.Publisher
+
+	// Channels' offline community rooms (optional)
+	communityChat CommunityChatStore
+
+	// Where direct messages are published for checking, and who is told
+	// which users are connected (optional)
+	directPublisher events.Publisher
+	userObserver    UserObserver
+
+	// Where dropped connections' sessions are held for resume (optional)
+	sessions       SessionStore
+	sessionOptions SessionOptions
+
+	// Messages delivered to clients that disconnected since usage was last
+	// metered, by tenant
+	usageCarry map[string]int64
+
+	// Tenants' connection quotas across nodes (optional)
+	tenantQuotas TenantQuotas
+
+	// Graceful shutdown: draining refuses new clients; done is closed once
+	// every connection has been closed
+	draining     bool
+	drainOptions DrainOptions
+	done         chan struct{}
+
+	// Readiness probes answered by the Run loop
+	probes chan chan struct{}
+
+	// Features operators switched off at runtime
+	featuresMu       sync.RWMutex
+	disabledFeatures map[string]bool
+}
+
+// ShadowBanChecker reports whether a user is shadow-banned in a room
+type ShadowBanChecker interface {
+	IsShadowBanned(room, userID string) bool
 }
 
 // HubMetrics tracks hub statistics
@@ -41,16 +151,87 @@ type HubMetrics struct {
 	TotalMessagesSent int64
 	TotalMessagesRecv int64
 	LastMessageTime   time.Time
-	RoomCounts        map[string]int
-	mu                sync.RWMutex
+
+	// Inbound messages rejected by rate limits
+	RateLimitedMessages int64
+
+	RoomCounts map[string]int
+	mu         sync.RWMutex
+}
+
+// connectionOpened counts a registered connection
+func (m *HubMetrics) connectionOpened() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.ActiveConnections++
+	m.TotalConnections++
+}
+
+// connectionClosed counts a connection that is no longer active
+func (m *HubMetrics) connectionClosed() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.ActiveConnections--
+}
+
+// connectionsCleared records that every connection was closed
+func (m *HubMetrics) connectionsCleared() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.ActiveConnections = 0
+}
+
+// messagesSent counts messages queued to clients
+func (m *HubMetrics) messagesSent(n int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.TotalMessagesSent += int64(n)
+}
+
+// messageBroadcast records when a broadcast was last delivered
+func (m *HubMetrics) messageBroadcast(at time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.LastMessageTime = at
+}
+
+// messageReceived counts a message handled from a client
+func (m *HubMetrics) messageReceived() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.TotalMessagesRecv++
+}
+
+// roomJoined counts a client joining room
+func (m *HubMetrics) roomJoined(room string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.RoomCounts[room]++
+}
+
+// roomLeft counts a client leaving room; empty reports it has no clients left
+func (m *HubMetrics) roomLeft(room string, empty bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.RoomCounts[room]--
+	if empty {
+		delete(m.RoomCounts, room)
+	}
 }
 
 // Message represents a WebSocket message
 type Message struct {
-	Type      string                 `json:"type"`
-	Room      string                 `json:"room,omitempty"`
-	Data      map[string]interface{} `json:"data"`
-	Timestamp time.Time              `json:"timestamp"`
+	// Optional client-chosen ID echoed in the ref of error replies
+	ID string `json:"id,omitempty" msgpack:"id,omitempty"`
+
+	Type      string                 `json:"type" msgpack:"type"`
+	Room      string                 `json:"room,omitempty" msgpack:"room,omitempty"`
+	Data      map[string]interface{} `json:"data" msgpack:"data"`
+	Timestamp time.Time              `json:"timestamp" msgpack:"timestamp"`
+
+	// HMAC signature of the frame, required on inbound messages from
+	// registered bots (see SignFrame)
+	Signature string `json:"sig,omitempty" msgpack:"sig,omitempty"`
 }
 
 // Client represents a single WebSocket connection
@@ -61,7 +242,10 @@ type Client struct {
 	conn *websocket.Conn
 
 	// Buffered channel of outbound messages
-	send chan []byte
+	send chan outbound
+
+	// Encoding negotiated in the hello handshake (see EncodingJSON)
+	encoding string
 
 	// User ID associated with this client
 	userID string
@@ -72,6 +256,65 @@ type Client struct {
 	// Client metadata
 	metadata map[string]string
 
+	// Spectators are receive-only: inbound messages other than control
+	// frames are discarded
+	spectator bool
+
+	// Backgrounded mobile clients only receive direct messages and critical notifications
+	background bool
+
+	// Capabilities negotiated in the hello handshake
+	capabilities map[string]bool
+
+	// Outstanding watch-time heartbeat challenge
+	heartbeat heartbeatState
+
+	// Close code sent when the hub closes the connection (see closecode)
+	closeCode int
+
+	// Access token expiry for authenticated clients
+	auth authState
+
+	// Guests are anonymous viewers with limited rooms and no chat
+	guest bool
+
+	// White-label tenant the client belongs to; it may only join rooms in
+	// the tenant's namespace
+	tenant string
+
+	// Remote IP and inbound message rate limiting state
+	remoteIP    string
+	rateLimiter clientRateLimiter
+
+	// Identifies the connection in the sessions API
+	sessionID   string
+	device      string
+	connectedAt time.Time
+
+	// Prioritized feed for stream manager mode
+	managerFeed *managerFeed
+
+	// Set when the hub closes the send buffer; queued messages are then
+	// discarded instead of panicking on the closed channel
+	sendClosed bool
+
+	// Reconnect delay hinted when the node drains, repeated in the close frame
+	retryAfter time.Duration
+
+	// Resumable sessions: the token the client resumes with, whether it
+	// closed the connection itself, and the buffer of missed messages once
+	// the connection dropped
+	resumeToken  string
+	closedByPeer bool
+	detached     *detachedSession
+
+	// Messages written to the connection since usage was last metered
+	delivered int64
+	usageMu   sync.Mutex
+
+	// Closed when WritePump exits
+	writeDone chan struct{}
+
 	// Mutex for client operations
 	mu sync.RWMutex
 }
@@ -91,6 +334,10 @@ const (
 
 	// Send buffer size
 	sendBufferSize = 256
+
+	// Spectators only send control frames and get a smaller send buffer
+	spectatorMaxMessageSize = 512
+	spectatorSendBufferSize = 64
 )
 
 // NewHub creates a new Hub instance
@@ -104,6 +351,27 @@ func NewHub() *Hub {
 		metrics: &HubMetrics{
 			RoomCounts: make(map[string]int),
 		},
+		roomState:         newRoomStateComposer(),
+		viewerCountPolicy: DefaultViewerCountPolicy(),
+		viewerCounts:      make(map[string]viewerCountState),
+		chatSampling:      DefaultChatSamplingPolicy(),
+		chatSamplers:      make(map[string]*chatSampler),
+		roomData:          newRoomDataStore(),
+		cardinality:       metrics.DefaultCardinalityConfig(),
+		guestPolicy:       DefaultGuestPolicy(),
+		compression:       DefaultCompressionOptions(),
+		rateLimits:        DefaultMessageRateLimits(),
+		ipLimiter:         newIPRateLimiter(),
+		userClients:       make(map[string]map[*Client]bool),
+		connectionLimit:   DefaultConnectionLimitPolicy(),
+		managers:          make(map[string]map[*Client]bool),
+		roomLabels:        metrics.NewLabelLimiter(metrics.DefaultCardinalityConfig()),
+		deliveryStats:     newDeliveryStatsStore(),
+		drainOptions:      DefaultDrainOptions(),
+		done:              make(chan struct{}),
+		probes:            make(chan chan struct{}),
+		usageCarry:        make(map[string]int64),
+		disabledFeatures:  make(map[string]bool),
 	}
 }
 
@@ -112,10 +380,18 @@ func (h *Hub) Run(ctx context.Context) {
 	ticker := time.NewTicker(10 * time.Second)
 	defer ticker.Stop()
 
+	// Per-room updates are checked every second; each room's own interval
+	// decides whether a viewer count is due
+	roomTicker := time.NewTicker(time.Second)
+	defer roomTicker.Stop()
+
+	managerTicker := time.NewTicker(managerFlushInterval)
+	defer managerTicker.Stop()
+
 	for {
 		select {
 		case <-ctx.Done():
-			log.Println("Hub shutting down...")
+			slog.Info("Hub shutting down")
 			h.shutdown()
 			return
 
@@ -131,6 +407,18 @@ func (h *Hub) Run(ctx context.Context) {
 		case <-ticker.C:
 			// Periodic maintenance tasks
 			h.logMetrics()
+			h.ipLimiter.prune(time.Now())
+
+		case now := <-roomTicker.C:
+			h.broadcastViewerCounts(now)
+			h.flushChatSummaries(now)
+			h.expireSessions(now)
+
+		case <-managerTicker.C:
+			h.flushManagerFeeds()
+
+		case reply := <-h.probes:
+			close(reply)
 		}
 	}
 }
@@ -141,10 +429,11 @@ func (h *Hub) registerClient(client *Client) {
 	defer h.mu.Unlock()
 
 	h.clients[client] = true
-	h.metrics.ActiveConnections++
-	h.metrics.TotalConnections++
+	h.trackSession(client)
+	h.metrics.connectionOpened()
+	h.issueResumeToken(client)
 
-	log.Printf("Client registered: userID=%s, total=%d", client.userID, len(h.clients))
+	slog.Debug("Client registered", "user_id", client.userID, "total", len(h.clients))
 }
 
 // unregisterClient removes a client connection
@@ -153,16 +442,28 @@ func (h *Hub) unregisterClient(client *Client) {
 	defer h.mu.Unlock()
 
 	if _, ok := h.clients[client]; ok {
+		// A dropped connection keeps its rooms until it resumes or expires
+		if h.detachClient(client) {
+			return
+		}
+
 		// Remove from all rooms
 		for room := range client.rooms {
 			h.removeFromRoom(room, client)
 		}
 
 		delete(h.clients, client)
-		close(client.send)
-		h.metrics.ActiveConnections--
+		h.untrackSession(client)
+		for room := range h.managers {
+			h.removeManagerLocked(room, client)
+		}
+		if client.detachedSession() == nil {
+			h.metrics.connectionClosed()
+		}
+		client.closeSend()
+		h.usageCarry[client.Tenant()] += client.takeDelivered()
 
-		log.Printf("Client unregistered: userID=%s, total=%d", client.userID, len(h.clients))
+		slog.Debug("Client unregistered", "user_id", client.userID, "total", len(h.clients))
 	}
 }
 
@@ -171,15 +472,22 @@ func (h *Hub) broadcastMessage(message *Message) {
 	h.mu.RLock()
 	defer h.mu.RUnlock()
 
-	messageBytes, err := json.Marshal(message)
+	encoded, err := newEncodedMessage(message)
 	if err != nil {
-		log.Printf("Error marshaling message: %v", err)
+		slog.Error("Error marshaling message", "err", err)
 		return
 	}
 
 	var targetClients []*Client
 
 	if message.Room != "" {
+		// Stream managers get room activity in their prioritized feed;
+		// moderation items go to them alone
+		h.feedManagers(message)
+		if managersOnly(message.Type) {
+			return
+		}
+
 		// Send to specific room
 		if roomClients, ok := h.rooms[message.Room]; ok {
 			targetClients = make([]*Client, 0, len(roomClients))
@@ -187,6 +495,13 @@ func (h *Hub) broadcastMessage(message *Message) {
 				targetClients = append(targetClients, client)
 			}
 		}
+
+		// Mega rooms get a sampled subset of chat
+		if message.Type == "chat_message" {
+			targetClients = h.sampleChat(message.Room, targetClients)
+		}
+
+		h.countRoomMessage(message.Room)
 	} else {
 		// Broadcast to all clients
 		targetClients = make([]*Client, 0, len(h.clients))
@@ -195,19 +510,30 @@ func (h *Hub) broadcastMessage(message *Message) {
 		}
 	}
 
+	start := time.Now()
+	sent, dropped := h.deliver(encoded, targetClients)
+	if message.Room != "" {
+		h.deliveryStats.record(message.Room, sent, dropped, time.Since(start))
+	}
+	h.metrics.messageBroadcast(time.Now())
+}
+
+// deliver queues an encoded message for each client and reports how many
+// were queued and dropped (caller must hold lock)
+func (h *Hub) deliver(message *encodedMessage, targetClients []*Client) (sent, dropped int) {
 	// Send messages asynchronously
 	for _, client := range targetClients {
-		select {
-		case client.send <- messageBytes:
-			h.metrics.TotalMessagesSent++
-		default:
+		if client.queue(message) {
+			sent++
+		} else {
 			// Client's send buffer is full, close the connection
-			log.Printf("Client send buffer full, closing connection: userID=%s", client.userID)
-			h.Unregister <- client
+			slog.Warn("Client send buffer full, closing connection", "user_id", client.userID)
+			client.Disconnect(closecode.SlowConsumer)
+			dropped++
 		}
 	}
-
-	h.metrics.LastMessageTime = time.Now()
+	h.metrics.messagesSent(sent)
+	return sent, dropped
 }
 
 // JoinRoom adds a client to a room
@@ -218,13 +544,21 @@ func (h *Hub) JoinRoom(room string, client *Client) {
 	if h.rooms[room] == nil {
 		h.rooms[room] = make(map[*Client]bool)
 	}
+	// Joining a room twice is a no-op for observers and metrics
+	if !h.rooms[room][client] {
+		if h.roomObserver != nil {
+			h.roomObserver.RoomJoined(room, client.GetUserID(), client.IsGuest())
+		}
+		h.metrics.roomJoined(room)
+	}
 
 	h.rooms[room][client] = true
+	client.mu.Lock()
 	client.rooms[room] = true
-	h.metrics.RoomCounts[room]++
+	client.mu.Unlock()
 
-	log.Printf("Client joined room: userID=%s, room=%s, count=%d",
-		client.userID, room, len(h.rooms[room]))
+	slog.Debug("Client joined room", "user_id", h.cardinality.Label(client.userID), "room", h.cardinality.Label(room),
+		"count", len(h.rooms[room]))
 }
 
 // LeaveRoom removes a client from a room
@@ -237,17 +571,23 @@ func (h *Hub) LeaveRoom(room string, client *Client) {
 
 // removeFromRoom is an internal helper (caller must hold lock)
 func (h *Hub) removeFromRoom(room string, client *Client) {
-	if roomClients, ok := h.rooms[room]; ok {
+	if roomClients, ok := h.rooms[room]; ok && roomClients[client] {
+		if h.roomObserver != nil {
+			h.roomObserver.RoomLeft(room, client.GetUserID(), client.IsGuest())
+		}
 		delete(roomClients, client)
+		client.mu.Lock()
 		delete(client.rooms, room)
-		h.metrics.RoomCounts[room]--
+		client.mu.Unlock()
+		h.metrics.roomLeft(room, len(roomClients) == 0)
 
 		if len(roomClients) == 0 {
 			delete(h.rooms, room)
-			delete(h.metrics.RoomCounts, room)
+			h.ClearRoomData(room)
+			h.evictRoomState(room)
 		}
 
-		log.Printf("Client left room: userID=%s, room=%s", client.userID, room)
+		slog.Debug("Client left room", "user_id", h.cardinality.Label(client.userID), "room", h.cardinality.Label(room))
 	}
 }
 
@@ -260,7 +600,7 @@ func (h *Hub) BroadcastToRoom(room string, messageType string, data map[string]i
 		Timestamp: time.Now(),
 	}
 
-	h.Broadcast <- message
+	h.enqueue(message)
 }
 
 // BroadcastToAll sends a message to all connected clients
@@ -271,7 +611,7 @@ func (h *Hub) BroadcastToAll(messageType string, data map[string]interface{}) {
 		Timestamp: time.Now(),
 	}
 
-	h.Broadcast <- message
+	h.enqueue(message)
 }
 
 // GetMetrics returns current hub metrics
@@ -287,6 +627,8 @@ func (h *Hub) GetMetrics() *HubMetrics {
 		TotalMessagesRecv: h.metrics.TotalMessagesRecv,
 		LastMessageTime:   h.metrics.LastMessageTime,
 		RoomCounts:        make(map[string]int),
+
+		RateLimitedMessages: h.metrics.RateLimitedMessages,
 	}
 
 	for room, count := range h.metrics.RoomCounts {
@@ -299,29 +641,50 @@ func (h *Hub) GetMetrics() *HubMetrics {
 // logMetrics logs current hub metrics
 func (h *Hub) logMetrics() {
 	metrics := h.GetMetrics()
-	log.Printf("Hub Metrics - Active: %d, Total: %d, Messages Sent: %d, Rooms: %d",
-		metrics.ActiveConnections,
-		metrics.TotalConnections,
-		metrics.TotalMessagesSent,
-		len(metrics.RoomCounts))
+	slog.Info("Hub metrics",
+		"active", metrics.ActiveConnections,
+		"total", metrics.TotalConnections,
+		"messages_sent", metrics.TotalMessagesSent,
+		"rate_limited", metrics.RateLimitedMessages,
+		"rooms", len(metrics.RoomCounts))
 }
 
-// shutdown gracefully shuts down the hub
-func (h *Hub) shutdown() {
+// SetShadowBanChecker configures the shadow ban lookup used for chat delivery
+func (h *Hub) SetShadowBanChecker(checker ShadowBanChecker) {
 	h.mu.Lock()
 	defer h.mu.Unlock()
+	h.shadowBans = checker
+}
 
-	log.Println("Closing all client connections...")
+// SetBotKeyStore configures the signing keys used to verify bot messages
+func (h *Hub) SetBotKeyStore(store BotKeyStore) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.botKeys = store
+}
+
+// botKey returns the signing key registered for userID, if any
+func (h *Hub) botKey(userID string) ([]byte, bool) {
+	h.mu.RLock()
+	store := h.botKeys
+	h.mu.RUnlock()
 
-	for client := range h.clients {
-		close(client.send)
-		client.conn.Close()
+	if store == nil {
+		return nil, false
 	}
+	return store.KeyFor(userID)
+}
 
-	h.clients = make(map[*Client]bool)
-	h.rooms = make(map[string]map[*Client]bool)
+// isShadowBanned reports whether a user's chat should be hidden from a room
+func (h *Hub) isShadowBanned(room, userID string) bool {
+	h.mu.RLock()
+	checker := h.shadowBans
+	h.mu.RUnlock()
 
-	log.Println("Hub shutdown complete")
+	if checker == nil {
+		return false
+	}
+	return checker.IsShadowBanned(room, userID)
 }
 
 // GetRoomCount returns the number of clients in a specific room
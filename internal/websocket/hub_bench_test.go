@@ -0,0 +1,107 @@
+package websocket
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"runtime"
+	"testing"
+	"time"
+)
+
+// benchDrainEvery is how many broadcasts a benchmark sends between waits for
+// clients to drain, well under the send buffer's size
+const benchDrainEvery = 64
+
+// waitForDrain returns once every client's send buffer is empty
+func waitForDrain(clients []*Client) {
+	for _, client := range clients {
+		for len(client.send) > 0 {
+			runtime.Gosched()
+		}
+	}
+}
+
+// quietLogs discards log output until the benchmark and its hub are done,
+// keeping benchmark result lines intact for benchstat
+func quietLogs(b *testing.B) {
+	logger, writer, flags := slog.Default(), log.Writer(), log.Flags()
+	slog.SetDefault(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	b.Cleanup(func() {
+		slog.SetDefault(logger)
+		log.SetOutput(writer)
+		log.SetFlags(flags)
+	})
+}
+
+// benchmarkFanOut broadcasts one message per iteration to a room of
+// clients, timing the marshal and the queueing to every client
+func benchmarkFanOut(b *testing.B, clients int, messageType string) {
+	quietLogs(b)
+	hub := startRaceHub(b)
+	room := make([]*Client, clients)
+	for i := range room {
+		room[i] = newDrainedClient(hub, fmt.Sprintf("user-%d", i))
+		hub.AddClient(room[i])
+		hub.JoinRoom("bench", room[i])
+	}
+	waitForHub(b, hub)
+
+	// Shutdown would close the clients' missing connections
+	b.Cleanup(func() {
+		for _, client := range room {
+			hub.unregister(client)
+		}
+	})
+
+	message := &Message{
+		Type: messageType,
+		Room: "bench",
+		Data: map[string]interface{}{
+			"user_id":  "user-0",
+			"username": "benchmark",
+			"message":  "hello from the benchmark",
+		},
+		Timestamp: time.Now(),
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		hub.broadcastMessage(message)
+
+		// Let the stand-in write pumps catch up before any send buffer
+		// fills, untimed, so no client is dropped as a slow consumer
+		if i%benchDrainEvery == benchDrainEvery-1 {
+			b.StopTimer()
+			waitForDrain(room)
+			b.StartTimer()
+		}
+	}
+	b.StopTimer()
+	b.ReportMetric(float64(clients), "deliveries/op")
+
+	// A client whose buffer filled was disconnected, skewing the result
+	waitForHub(b, hub)
+	if active := hub.GetMetrics().ActiveConnections; int(active) != clients {
+		b.Fatalf("%d of %d clients were disconnected as slow consumers", clients-int(active), clients)
+	}
+}
+
+func BenchmarkHubBroadcastToRoom(b *testing.B) {
+	for _, clients := range []int{10, 100, 1000} {
+		b.Run(fmt.Sprintf("clients=%d", clients), func(b *testing.B) {
+			benchmarkFanOut(b, clients, "stream_update")
+		})
+	}
+}
+
+// BenchmarkHubBroadcastChat includes the chat sampling large rooms go through
+func BenchmarkHubBroadcastChat(b *testing.B) {
+	for _, clients := range []int{100, 1000} {
+		b.Run(fmt.Sprintf("clients=%d", clients), func(b *testing.B) {
+			benchmarkFanOut(b, clients, "chat_message")
+		})
+	}
+}
@@ -0,0 +1,271 @@
+package websocket
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// These tests exercise the hub from many goroutines at once. They assert
+// little on their own; run them with -race, which fails them on any data
+// race they expose.
+
+const (
+	raceClients = 40
+	raceRooms   = 4
+)
+
+// startRaceHub runs a hub until the test ends. Shutdown does not wait on
+// clients without write pumps.
+func startRaceHub(t testing.TB) *Hub {
+	t.Helper()
+	hub := NewHub()
+	hub.SetDrainOptions(DrainOptions{FlushTimeout: 50 * time.Millisecond, CloseTimeout: 50 * time.Millisecond})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go hub.Run(ctx)
+	t.Cleanup(func() {
+		cancel()
+		<-hub.done
+	})
+	return hub
+}
+
+// newDrainedClient creates a client without a connection whose queued
+// messages are discarded, standing in for its write pump
+func newDrainedClient(hub *Hub, userID string) *Client {
+	client := NewClient(hub, nil, userID)
+	go func() {
+		for range client.send {
+		}
+	}()
+	return client
+}
+
+// waitForHub returns once the Run loop has handled everything sent to its
+// unbuffered Register and Unregister channels
+func waitForHub(t testing.TB, hub *Hub) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := hub.Ready(ctx); err != nil {
+		t.Fatalf("hub not ready: %v", err)
+	}
+}
+
+// readMetrics polls the hub's metrics until stop is closed
+func readMetrics(hub *Hub, stop <-chan struct{}, wg *sync.WaitGroup) {
+	defer wg.Done()
+	for {
+		select {
+		case <-stop:
+			return
+		default:
+		}
+		metrics := hub.GetMetrics()
+		_ = metrics.ActiveConnections + int32(len(metrics.RoomCounts))
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestHubConcurrentRegisterJoinBroadcastUnregister(t *testing.T) {
+	hub := startRaceHub(t)
+
+	stop := make(chan struct{})
+	var readers sync.WaitGroup
+	readers.Add(1)
+	go readMetrics(hub, stop, &readers)
+
+	var wg sync.WaitGroup
+	for i := 0; i < raceClients; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			client := newDrainedClient(hub, fmt.Sprintf("user-%d", i))
+			if !hub.AddClient(client) {
+				t.Errorf("client %d was refused", i)
+				return
+			}
+
+			for r := 0; r < raceRooms; r++ {
+				room := fmt.Sprintf("room-%d", (i+r)%raceRooms)
+				hub.JoinRoom(room, client)
+				hub.BroadcastToRoom(room, "race_test", map[string]interface{}{"from": i})
+				client.IsInRoom(room)
+				client.GetRooms()
+			}
+			hub.BroadcastToAll("race_test", map[string]interface{}{"from": i})
+			hub.Sessions(client.GetUserID())
+
+			for r := 0; r < raceRooms/2; r++ {
+				hub.LeaveRoom(fmt.Sprintf("room-%d", (i+r)%raceRooms), client)
+			}
+			hub.unregister(client)
+		}(i)
+	}
+	wg.Wait()
+	waitForHub(t, hub)
+	close(stop)
+	readers.Wait()
+
+	metrics := hub.GetMetrics()
+	if metrics.TotalConnections != raceClients {
+		t.Errorf("TotalConnections = %d, want %d", metrics.TotalConnections, raceClients)
+	}
+	if metrics.ActiveConnections != 0 {
+		t.Errorf("ActiveConnections = %d, want 0", metrics.ActiveConnections)
+	}
+	if len(metrics.RoomCounts) != 0 {
+		t.Errorf("RoomCounts = %v, want none", metrics.RoomCounts)
+	}
+}
+
+func TestHubConcurrentJoinLeaveSameRoom(t *testing.T) {
+	hub := startRaceHub(t)
+
+	clients := make([]*Client, raceClients)
+	for i := range clients {
+		clients[i] = newDrainedClient(hub, fmt.Sprintf("user-%d", i))
+		hub.AddClient(clients[i])
+	}
+
+	var wg sync.WaitGroup
+	for _, client := range clients {
+		wg.Add(2)
+		go func(client *Client) {
+			defer wg.Done()
+			for n := 0; n < 20; n++ {
+				hub.JoinRoom("shared", client)
+				hub.LeaveRoom("shared", client)
+			}
+			hub.JoinRoom("shared", client)
+		}(client)
+		go func(client *Client) {
+			defer wg.Done()
+			for n := 0; n < 20; n++ {
+				client.IsInRoom("shared")
+				client.GetRooms()
+				// Few enough broadcasts that no send buffer fills, which
+				// would disconnect the client as a slow consumer
+				if n%4 == 0 {
+					hub.BroadcastToRoom("shared", "race_test", nil)
+				}
+			}
+		}(client)
+	}
+	wg.Wait()
+	waitForHub(t, hub)
+
+	if got := hub.GetMetrics().RoomCounts["shared"]; got != raceClients {
+		t.Errorf("RoomCounts[shared] = %d, want %d", got, raceClients)
+	}
+
+	for _, client := range clients {
+		hub.unregister(client)
+	}
+	waitForHub(t, hub)
+	if got := hub.GetMetrics().ActiveConnections; got != 0 {
+		t.Errorf("ActiveConnections = %d, want 0", got)
+	}
+}
+
+// TestHubConcurrentConnections drives the hub through real connections, so
+// read pumps count received messages while the hub delivers and metrics
+// are read
+func TestHubConcurrentConnections(t *testing.T) {
+	hub := startRaceHub(t)
+	hub.SetMessageRateLimits(MessageRateLimits{})
+
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		client := NewClient(hub, conn, r.URL.Query().Get("user"))
+		if !hub.AddClient(client) {
+			return
+		}
+		go client.WritePump()
+		go client.ReadPump()
+	}))
+	defer server.Close()
+
+	stop := make(chan struct{})
+	var readers sync.WaitGroup
+	readers.Add(1)
+	go readMetrics(hub, stop, &readers)
+
+	const sent = 3
+	var wg sync.WaitGroup
+	for i := 0; i < raceClients; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			endpoint := "ws" + strings.TrimPrefix(server.URL, "http") + fmt.Sprintf("/?user=user-%d", i)
+			conn, _, err := websocket.DefaultDialer.Dial(endpoint, nil)
+			if err != nil {
+				t.Errorf("dial: %v", err)
+				return
+			}
+			defer conn.Close()
+
+			room := fmt.Sprintf("room-%d", i%raceRooms)
+			messages := []map[string]interface{}{
+				{"type": "subscribe", "data": map[string]interface{}{"room": room}},
+				{"type": "message", "room": room, "data": map[string]interface{}{"message": "hello"}},
+				{"type": "unsubscribe", "data": map[string]interface{}{"room": room}},
+			}
+			for _, msg := range messages {
+				if err := conn.WriteJSON(msg); err != nil {
+					t.Errorf("write: %v", err)
+					return
+				}
+			}
+
+			// The unsubscribe ack is the last reply; wait for it so every
+			// message was handled before closing
+			conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+			for acked := false; !acked; {
+				_, frame, err := conn.ReadMessage()
+				if err != nil {
+					t.Errorf("read: %v", err)
+					return
+				}
+				// The write pump batches queued messages, one per line
+				for _, line := range bytes.Split(frame, []byte{'\n'}) {
+					var reply Message
+					if json.Unmarshal(line, &reply) == nil && reply.Type == "ack" && reply.Data["action"] == "unsubscribed" {
+						acked = true
+					}
+				}
+			}
+			conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+		}(i)
+	}
+	wg.Wait()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for hub.GetMetrics().ActiveConnections != 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	close(stop)
+	readers.Wait()
+
+	metrics := hub.GetMetrics()
+	if metrics.ActiveConnections != 0 {
+		t.Errorf("ActiveConnections = %d, want 0", metrics.ActiveConnections)
+	}
+	if metrics.TotalMessagesRecv != raceClients*sent {
+		t.Errorf("TotalMessagesRecv = %d, want %d", metrics.TotalMessagesRecv, raceClients*sent)
+	}
+}
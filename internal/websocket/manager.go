@@ -0,0 +1,258 @@
+package websocket
+
+import (
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Stream manager mode gives the broadcasting user one prioritized feed of
+// everything that needs their attention in their channel. Items are queued
+// per kind and flushed in priority order, so a chat flood never delays a
+// raid or an automod hold.
+const (
+	// How often manager feeds are flushed to their clients
+	managerFlushInterval = 250 * time.Millisecond
+
+	// Most items sent in one manager_feed message
+	managerMaxItemsPerFlush = 100
+
+	// Most items queued per kind; the oldest are dropped beyond this
+	managerMaxQueuedPerKind = 500
+)
+
+// Manager feed item kinds, highest priority first
+const (
+	ManagerKindRaid     = "raid"
+	ManagerKindAutoMod  = "automod"
+	ManagerKindModQueue = "mod_queue"
+	ManagerKindChat     = "chat"
+)
+
+// managerKinds lists the kinds in priority order
+var managerKinds = []string{ManagerKindRaid, ManagerKindAutoMod, ManagerKindModQueue, ManagerKindChat}
+
+// StreamerChecker reports whether a user is the broadcaster of a room
+type StreamerChecker interface {
+	IsStreamer(room, userID string) bool
+}
+
+// ManagerItem is one entry of a manager feed
+type ManagerItem struct {
+	Kind      string                 `json:"kind"`
+	Room      string                 `json:"room"`
+	Type      string                 `json:"type"`
+	Data      map[string]interface{} `json:"data"`
+	Timestamp time.Time              `json:"timestamp"`
+}
+
+// managerFeed queues items for one manager-mode client
+type managerFeed struct {
+	mu      sync.Mutex
+	queues  map[string][]ManagerItem
+	dropped map[string]int
+}
+
+// newManagerFeed creates an empty feed
+func newManagerFeed() *managerFeed {
+	return &managerFeed{
+		queues:  make(map[string][]ManagerItem),
+		dropped: make(map[string]int),
+	}
+}
+
+// push queues an item, dropping the oldest of its kind when full
+func (f *managerFeed) push(item ManagerItem) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	queue := append(f.queues[item.Kind], item)
+	if len(queue) > managerMaxQueuedPerKind {
+		f.dropped[item.Kind] += len(queue) - managerMaxQueuedPerKind
+		queue = queue[len(queue)-managerMaxQueuedPerKind:]
+	}
+	f.queues[item.Kind] = queue
+}
+
+// take removes up to max items in priority order, plus the per-kind counts
+// of items dropped since the last take
+func (f *managerFeed) take(max int) ([]ManagerItem, map[string]int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var items []ManagerItem
+	for _, kind := range managerKinds {
+		queue := f.queues[kind]
+		n := len(queue)
+		if n > max-len(items) {
+			n = max - len(items)
+		}
+		items = append(items, queue[:n]...)
+		f.queues[kind] = queue[n:]
+	}
+
+	var dropped map[string]int
+	if len(f.dropped) > 0 {
+		dropped = f.dropped
+		f.dropped = make(map[string]int)
+	}
+	return items, dropped
+}
+
+// SetStreamerChecker configures who may use stream manager mode
+func (h *Hub) SetStreamerChecker(checker StreamerChecker) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.streamers = checker
+}
+
+// managerKind classifies a room message for manager feeds; ok is false for
+// messages that don't belong in one
+func managerKind(messageType string) (kind string, ok bool) {
+	switch {
+	case messageType == "chat_message":
+		return ManagerKindChat, true
+	case messageType == "raid.incoming":
+		return ManagerKindRaid, true
+	case strings.HasPrefix(messageType, "automod."):
+		return ManagerKindAutoMod, true
+	case strings.HasPrefix(messageType, "moderation."):
+		return ManagerKindModQueue, true
+	default:
+		return "", false
+	}
+}
+
+// managersOnly reports whether a message type is for manager feeds only and
+// must never reach viewers in the room
+func managersOnly(messageType string) bool {
+	kind, ok := managerKind(messageType)
+	return ok && (kind == ManagerKindAutoMod || kind == ManagerKindModQueue)
+}
+
+// handleManagerSubscribe switches the client to manager mode for a room it
+// broadcasts. Room messages then arrive through manager_feed instead.
+func (c *Client) handleManagerSubscribe(msg *Message) {
+	room, _ := msg.Data["room"].(string)
+	if room == "" {
+		c.sendError(ErrorCodeInvalidMessage, "room is required", msg)
+		return
+	}
+	if !c.hub.isStreamer(room, c.GetUserID()) {
+		c.sendError(ErrorCodeForbidden, "only the streamer can manage this room", msg)
+		return
+	}
+
+	c.hub.LeaveRoom(room, c)
+	c.hub.addManager(room, c)
+	c.sendAck("manager_subscribed", room)
+}
+
+// handleManagerUnsubscribe leaves manager mode for a room
+func (c *Client) handleManagerUnsubscribe(msg *Message) {
+	room, _ := msg.Data["room"].(string)
+	if room == "" {
+		c.sendError(ErrorCodeInvalidMessage, "room is required", msg)
+		return
+	}
+	c.hub.removeManager(room, c)
+	c.sendAck("manager_unsubscribed", room)
+}
+
+// isStreamer reports whether userID broadcasts room
+func (h *Hub) isStreamer(room, userID string) bool {
+	h.mu.RLock()
+	checker := h.streamers
+	h.mu.RUnlock()
+
+	return checker != nil && checker.IsStreamer(room, userID)
+}
+
+// addManager registers client as a manager of room
+func (h *Hub) addManager(room string, client *Client) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	client.mu.Lock()
+	if client.managerFeed == nil {
+		client.managerFeed = newManagerFeed()
+	}
+	client.mu.Unlock()
+
+	if h.managers[room] == nil {
+		h.managers[room] = make(map[*Client]bool)
+	}
+	h.managers[room][client] = true
+
+	log.Printf("Stream manager attached: userID=%s, room=%s", client.userID, h.cardinality.Label(room))
+}
+
+// isManager reports whether client is in manager mode for room
+func (h *Hub) isManager(room string, client *Client) bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.managers[room][client]
+}
+
+// removeManager unregisters client as a manager of room
+func (h *Hub) removeManager(room string, client *Client) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.removeManagerLocked(room, client)
+}
+
+// removeManagerLocked unregisters client as a manager of room (caller must
+// hold h.mu)
+func (h *Hub) removeManagerLocked(room string, client *Client) {
+	delete(h.managers[room], client)
+	if len(h.managers[room]) == 0 {
+		delete(h.managers, room)
+	}
+}
+
+// feedManagers queues a room message for the room's managers (caller must
+// hold h.mu)
+func (h *Hub) feedManagers(message *Message) {
+	kind, ok := managerKind(message.Type)
+	if !ok {
+		return
+	}
+	for client := range h.managers[message.Room] {
+		client.managerFeed.push(ManagerItem{
+			Kind:      kind,
+			Room:      message.Room,
+			Type:      message.Type,
+			Data:      message.Data,
+			Timestamp: message.Timestamp,
+		})
+	}
+}
+
+// flushManagerFeeds sends each manager its queued items, highest priority
+// first. Must be called from the Run goroutine.
+func (h *Hub) flushManagerFeeds() {
+	h.mu.RLock()
+	clients := make(map[*Client]bool)
+	for _, managers := range h.managers {
+		for client := range managers {
+			clients[client] = true
+		}
+	}
+	h.mu.RUnlock()
+
+	for client := range clients {
+		items, dropped := client.managerFeed.take(managerMaxItemsPerFlush)
+		if len(items) == 0 && dropped == nil {
+			continue
+		}
+		data := map[string]interface{}{
+			"items": items,
+		}
+		if dropped != nil {
+			data["dropped"] = dropped
+		}
+		client.sendMessage("manager_feed", data)
+	}
+}
@@ -0,0 +1,75 @@
+package websocket
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/tinle0301/streaming-platform-api/internal/metrics"
+)
+
+var roomMessagesSent = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "streamhub_ws_room_messages_total",
+	Help: "Messages broadcast to rooms; rooms past the label limit are counted as \"other\"",
+}, []string{"room"})
+
+var (
+	activeConnectionsDesc = prometheus.NewDesc(
+		"streamhub_ws_active_connections", "Currently connected WebSocket clients", nil, nil)
+	totalConnectionsDesc = prometheus.NewDesc(
+		"streamhub_ws_connections_total", "WebSocket clients connected since start", nil, nil)
+	messagesSentDesc = prometheus.NewDesc(
+		"streamhub_ws_messages_sent_total", "Messages queued to WebSocket clients", nil, nil)
+	roomsDesc = prometheus.NewDesc(
+		"streamhub_ws_rooms", "Rooms with at least one client", nil, nil)
+	roomClientsDesc = prometheus.NewDesc(
+		"streamhub_ws_room_clients", "Clients in the largest rooms; the rest are summed under room=\"other\"", []string{"room"}, nil)
+)
+
+// SetCardinality bounds the room labels exported in metrics. Call before Run.
+func (h *Hub) SetCardinality(config metrics.CardinalityConfig) {
+	h.cardinality = config
+	h.roomLabels = metrics.NewLabelLimiter(config)
+}
+
+// Collector exports hub metrics to Prometheus, reporting per-room client
+// counts only for the top rooms so label cardinality stays bounded
+func (h *Hub) Collector() prometheus.Collector {
+	return &hubCollector{hub: h}
+}
+
+// hubCollector computes hub metrics at scrape time
+type hubCollector struct {
+	hub *Hub
+}
+
+// Describe sends the metric descriptors
+func (c *hubCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- activeConnectionsDesc
+	ch <- totalConnectionsDesc
+	ch <- messagesSentDesc
+	ch <- roomsDesc
+	ch <- roomClientsDesc
+}
+
+// Collect sends the current metric values
+func (c *hubCollector) Collect(ch chan<- prometheus.Metric) {
+	snapshot := c.hub.GetMetrics()
+
+	ch <- prometheus.MustNewConstMetric(activeConnectionsDesc, prometheus.GaugeValue, float64(snapshot.ActiveConnections))
+	ch <- prometheus.MustNewConstMetric(totalConnectionsDesc, prometheus.CounterValue, float64(snapshot.TotalConnections))
+	ch <- prometheus.MustNewConstMetric(messagesSentDesc, prometheus.CounterValue, float64(snapshot.TotalMessagesSent))
+	ch <- prometheus.MustNewConstMetric(roomsDesc, prometheus.GaugeValue, float64(len(snapshot.RoomCounts)))
+
+	top, other := metrics.TopN(snapshot.RoomCounts, c.hub.cardinality.TopN)
+	for _, entry := range top {
+		ch <- prometheus.MustNewConstMetric(roomClientsDesc, prometheus.GaugeValue, float64(entry.Count),
+			c.hub.cardinality.Label(entry.Label))
+	}
+	if other > 0 {
+		ch <- prometheus.MustNewConstMetric(roomClientsDesc, prometheus.GaugeValue, float64(other), metrics.OtherLabel)
+	}
+}
+
+// countRoomMessage records a broadcast to room under a bounded label
+func (h *Hub) countRoomMessage(room string) {
+	roomMessagesSent.WithLabelValues(h.roomLabels.Label(room)).Inc()
+}
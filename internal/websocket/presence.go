@@ -0,0 +1,30 @@
+package websocket
+
+// RoomObserver is told when connections join and leave rooms, e.g. to track
+// presence across nodes. Calls are made with the hub's lock held and must
+// not block.
+type RoomObserver interface {
+	RoomJoined(room, userID string, guest bool)
+	RoomLeft(room, userID string, guest bool)
+}
+
+// ViewerCountSource supplies cluster-wide viewer counts for viewer_count
+// broadcasts; ok is false for rooms it has not counted yet
+type ViewerCountSource interface {
+	ViewerCount(room string) (count int, ok bool)
+}
+
+// SetRoomObserver registers an observer for room joins and leaves
+func (h *Hub) SetRoomObserver(observer RoomObserver) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.roomObserver = observer
+}
+
+// SetViewerCountSource makes viewer_count broadcasts report counts from
+// source instead of this node's connections
+func (h *Hub) SetViewerCountSource(source ViewerCountSource) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.viewerCountSource = source
+}
@@ -0,0 +1,134 @@
+package websocket
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"testing"
+	"time"
+
+	"pgregory.net/rapid"
+)
+
+// settleHub waits until the Run loop has handled every queued register and
+// unregister
+func settleHub(t *rapid.T, hub *Hub) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := hub.Ready(ctx); err != nil {
+		t.Fatalf("hub not ready: %v", err)
+	}
+}
+
+// TestHubCountersNeverNegative connects, disconnects, joins and leaves in
+// any order, including leaving rooms never joined and disconnecting twice,
+// and checks the connection and room counters always match the hub
+func TestHubCountersNeverNegative(t *testing.T) {
+	rooms := []string{"room-1", "room-2", "room-3"}
+
+	rapid.Check(t, func(t *rapid.T) {
+		hub := NewHub()
+		ctx, cancel := context.WithCancel(context.Background())
+		go hub.Run(ctx)
+
+		var clients []*Client
+		connected := make(map[*Client]bool)
+		defer func() {
+			for client := range connected {
+				hub.unregister(client)
+			}
+			cancel()
+			<-hub.done
+		}()
+
+		pick := func(t *rapid.T) *Client {
+			if len(clients) == 0 {
+				t.Skip("no clients yet")
+			}
+			return clients[rapid.IntRange(0, len(clients)-1).Draw(t, "client")]
+		}
+
+		t.Repeat(map[string]func(*rapid.T){
+			"connect": func(t *rapid.T) {
+				client := newDrainedClient(hub, fmt.Sprintf("user-%d", len(clients)))
+				if !hub.AddClient(client) {
+					t.Fatalf("client was refused")
+				}
+				settleHub(t, hub)
+				clients = append(clients, client)
+				connected[client] = true
+			},
+			"disconnect": func(t *rapid.T) {
+				client := pick(t)
+				hub.unregister(client)
+				settleHub(t, hub)
+				delete(connected, client)
+			},
+			"join": func(t *rapid.T) {
+				client := pick(t)
+				if !connected[client] {
+					t.Skip("client disconnected")
+				}
+				hub.JoinRoom(rapid.SampledFrom(rooms).Draw(t, "room"), client)
+			},
+			"leave": func(t *rapid.T) {
+				hub.LeaveRoom(rapid.SampledFrom(rooms).Draw(t, "room"), pick(t))
+			},
+			"": func(t *rapid.T) {
+				metrics := hub.GetMetrics()
+				if metrics.ActiveConnections < 0 || int(metrics.ActiveConnections) != len(connected) {
+					t.Fatalf("ActiveConnections = %d, want %d", metrics.ActiveConnections, len(connected))
+				}
+				for _, room := range rooms {
+					count := metrics.RoomCounts[room]
+					if count < 0 || count != hub.GetRoomCount(room) {
+						t.Fatalf("RoomCounts[%s] = %d, want %d", room, count, hub.GetRoomCount(room))
+					}
+				}
+			},
+		})
+	})
+}
+
+// TestDetachedSessionBuffersWithoutDuplicatesOrGaps adds numbered messages
+// to a dropped session's buffer and flushes it at random, and checks each
+// flush returns exactly the newest messages since the last one, up to the
+// buffer size, in order
+func TestDetachedSessionBuffersWithoutDuplicatesOrGaps(t *testing.T) {
+	rapid.Check(t, func(t *rapid.T) {
+		session := &detachedSession{limit: rapid.IntRange(1, 20).Draw(t, "limit")}
+		next := 0
+		var pending []int
+		last := -1
+
+		t.Repeat(map[string]func(*rapid.T){
+			"add": func(t *rapid.T) {
+				session.add([]byte(strconv.Itoa(next)))
+				pending = append(pending, next)
+				next++
+			},
+			"flush": func(t *rapid.T) {
+				want := pending
+				if len(want) > session.limit {
+					want = want[len(want)-session.limit:]
+				}
+				pending = nil
+
+				got := session.take()
+				if len(got) != len(want) {
+					t.Fatalf("take() returned %d messages, want %d", len(got), len(want))
+				}
+				for i, message := range got {
+					seq, err := strconv.Atoi(string(message))
+					if err != nil || seq != want[i] {
+						t.Fatalf("take()[%d] = %s, want %d", i, message, want[i])
+					}
+					if seq <= last {
+						t.Fatalf("message %d delivered after %d", seq, last)
+					}
+					last = seq
+				}
+			},
+		})
+	})
+}
@@ -0,0 +1,252 @@
+package websocket
+
+import (
+	"github.com/tinle0301/streaming-platform-api/internal/events"
+)
+
+// ProtocolVersion is the version of the WebSocket protocol the registry
+// describes; it changes when a message type changes incompatibly
+const ProtocolVersion = "1.0.0"
+
+// Direction is which side sends a message type
+type Direction string
+
+// Message directions
+const (
+	FromClient Direction = "client"
+	FromServer Direction = "server"
+)
+
+// Field types, as JSON Schema names them
+const (
+	FieldString  = "string"
+	FieldInteger = "integer"
+	FieldNumber  = "number"
+	FieldBoolean = "boolean"
+	FieldObject  = "object"
+	FieldArray   = "array"
+)
+
+// Field is one key of a message's data
+type Field struct {
+	Name        string
+	Type        string
+	Required    bool
+	Description string
+
+	// Items is the element type of array fields
+	Items string
+}
+
+// MessageSpec describes one message type of the WebSocket protocol
+type MessageSpec struct {
+	Type      string
+	Direction Direction
+	Summary   string
+
+	// Room is the kind of room the message is scoped to, from Rooms; empty
+	// for messages about the connection itself
+	Room string
+
+	// Fields are the keys of the message's data
+	Fields []Field
+
+	// Open messages may carry more data than Fields lists, e.g. domain
+	// events relayed with their own data
+	Open bool
+}
+
+// RoomSpec describes a kind of room clients subscribe to
+type RoomSpec struct {
+	Kind        string
+	Pattern     string
+	Description string
+}
+
+// Room kinds
+const (
+	RoomKindStream    = "stream"
+	RoomKindCaptions  = "captions"
+	RoomKindPremiere  = "premiere"
+	RoomKindOverlay   = "overlay"
+	RoomKindParty     = "party"
+	RoomKindCommunity = "community"
+	RoomKindAny       = "any"
+)
+
+// Rooms lists the kinds of rooms. In a white-label tenant's namespace each
+// room is prefixed with tenant:<tenant_id>:, which the server adds itself.
+var Rooms = []RoomSpec{
+	{RoomKindStream, "<stream_id>", "A live stream's viewers and chat"},
+	{RoomKindCaptions, CaptionRoom("<stream_id>", "<language>"), "Live captions for a stream in one language"},
+	{RoomKindPremiere, PremiereRoom("<vod_id>"), "A VOD premiere's countdown and shared playback"},
+	{RoomKindOverlay, OverlayRoom("<channel_id>"), "A channel's stream overlays, for goal progress"},
+	{RoomKindParty, WatchPartyRoom("<party_id>"), "A watch party's synced playback and chat"},
+	{RoomKindCommunity, CommunityRoom("<channel_id>"), "A channel's community chat while it is offline"},
+	{RoomKindAny, "<room>", "Any of the rooms above"},
+}
+
+// Common fields
+var (
+	roomField         = Field{Name: "room", Type: FieldString, Required: true, Description: "Room name"}
+	optionalRoomField = Field{Name: "room", Type: FieldString, Description: "Room name, if not given in the envelope's room"}
+	messageIDField    = Field{Name: "message_id", Type: FieldString, Required: true, Description: "Message ID"}
+	eventIDField      = Field{Name: "event_id", Type: FieldString, Required: true, Description: "ID of the domain event, for deduplication"}
+	userIDField       = Field{Name: "user_id", Type: FieldString, Required: true, Description: "User ID"}
+)
+
+// Messages is the registry of every message type the server accepts and
+// sends. The AsyncAPI document client teams generate clients from is built
+// from it, so a new message type belongs here as well as in its handler.
+var Messages = []MessageSpec{
+	// Client to server
+	{Type: "hello", Direction: FromClient, Summary: "Negotiate optional capabilities; answered with welcome",
+		Fields: []Field{
+			{Name: "capabilities", Type: FieldArray, Items: FieldString, Description: "Capabilities the client supports, e.g. delta"},
+			{Name: "encodings", Type: FieldArray, Items: FieldString, Description: "Message encodings the client supports, most preferred first: json, msgpack, or protobuf"},
+		}},
+	{Type: "subscribe", Direction: FromClient, Summary: "Join a room; answered with an ack and the room's room_state", Room: RoomKindAny,
+		Fields: []Field{roomField}},
+	{Type: "unsubscribe", Direction: FromClient, Summary: "Leave a room", Room: RoomKindAny,
+		Fields: []Field{roomField}},
+	{Type: "ping", Direction: FromClient, Summary: "Application-level ping; answered with pong"},
+	{Type: "message", Direction: FromClient, Summary: "Send a chat message to a room you are in", Room: RoomKindAny,
+		Fields: []Field{optionalRoomField, {Name: "message", Type: FieldString, Required: true, Description: "Message text"}}},
+	{Type: "delete_message", Direction: FromClient, Summary: "Delete a chat message; streamers, moderators, and managers only", Room: RoomKindAny,
+		Fields: []Field{optionalRoomField, messageIDField}},
+	{Type: "whisper", Direction: FromClient, Summary: "Older name of direct, handled the same way",
+		Fields: []Field{{Name: "to", Type: FieldString, Required: true, Description: "Recipient user ID"}, {Name: "message", Type: FieldString, Required: true, Description: "Message text"}}},
+	{Type: "direct", Direction: FromClient, Summary: "Direct message checked against blocks and delivered cluster-wide",
+		Fields: []Field{{Name: "to", Type: FieldString, Required: true, Description: "Recipient user ID"}, {Name: "message", Type: FieldString, Required: true, Description: "Message text"}}},
+	{Type: "heartbeat", Direction: FromClient, Summary: "Answer a heartbeat_challenge to count watch time",
+		Fields: []Field{{Name: "nonce", Type: FieldString, Required: true, Description: "Nonce from the challenge"}}},
+	{Type: "resync", Direction: FromClient, Summary: "Ask for the full value of the envelope's room data key after a gap in patch versions", Room: RoomKindAny,
+		Fields: []Field{{Name: "key", Type: FieldString, Required: true, Description: "Room data key"}}},
+	{Type: "app_state", Direction: FromClient, Summary: "Mobile app moving to the background or foreground",
+		Fields: []Field{{Name: "state", Type: FieldString, Required: true, Description: "background or foreground"}}},
+	{Type: "manager_subscribe", Direction: FromClient, Summary: "Switch a room you broadcast to stream manager mode", Room: RoomKindStream,
+		Fields: []Field{roomField}},
+	{Type: "manager_unsubscribe", Direction: FromClient, Summary: "Leave stream manager mode", Room: RoomKindStream,
+		Fields: []Field{roomField}},
+	{Type: "party_sync", Direction: FromClient, Summary: "Watch party host moving everyone's playback", Room: RoomKindParty,
+		Fields: []Field{
+			optionalRoomField,
+			{Name: "position_ms", Type: FieldInteger, Required: true, Description: "Playback position"},
+			{Name: "playing", Type: FieldBoolean, Description: "Whether playback is running"},
+			{Name: "rate", Type: FieldNumber, Description: "Playback rate, 0.25 to 4; default 1"},
+		}},
+	{Type: "auth_refresh", Direction: FromClient, Summary: "Present a new access token before the current one expires; guests sign in with it",
+		Fields: []Field{{Name: "token", Type: FieldString, Required: true, Description: "Access token"}}},
+
+	// Server to client: connection
+	{Type: "welcome", Direction: FromServer, Summary: "Capabilities granted in reply to hello",
+		Fields: []Field{
+			{Name: "capabilities", Type: FieldArray, Items: FieldString, Required: true, Description: "Granted capabilities"},
+			{Name: "encoding", Type: FieldString, Description: "Encoding of every message after this one, when hello listed encodings"},
+		}},
+	{Type: "ack", Direction: FromServer, Summary: "Acknowledges an action",
+		Fields: []Field{
+			{Name: "action", Type: FieldString, Required: true, Description: "subscribed, unsubscribed, revoked, delete_message, manager_subscribed, manager_unsubscribed, direct, app_state, or the acknowledged message type"},
+			{Name: "room", Type: FieldString, Description: "Room the action applied to"},
+			{Name: "message_id", Type: FieldString, Description: "ID of an accepted direct message"},
+			{Name: "state", Type: FieldString, Description: "Acknowledged app state"},
+		}, Open: true},
+	{Type: "error", Direction: FromServer, Summary: "A message was rejected",
+		Fields: []Field{
+			{Name: "code", Type: FieldString, Required: true, Description: "invalid_message, unknown_type, forbidden, rate_limited, auth_required, auth_invalid, invalid_signature, or unavailable"},
+			{Name: "message", Type: FieldString, Required: true, Description: "Human-readable reason; may change"},
+			{Name: "ref", Type: FieldObject, Description: "The offending message's type, id, and room"},
+		}},
+	{Type: "pong", Direction: FromServer, Summary: "Reply to ping",
+		Fields: []Field{{Name: "timestamp", Type: FieldInteger, Required: true, Description: "Server time, Unix seconds"}}},
+	{Type: "guest_session", Direction: FromServer, Summary: "A guest's identity and the token to reconnect with",
+		Fields: []Field{userIDField, {Name: "token", Type: FieldString, Description: "Guest token"}, {Name: "expires_at", Type: FieldInteger, Description: "Token expiry, Unix seconds"}}},
+	{Type: "session", Direction: FromServer, Summary: "Token to resume this session with if the connection drops",
+		Fields: []Field{{Name: "resume_token", Type: FieldString, Required: true, Description: "Pass as resume_token when reconnecting"}, {Name: "resume_window_ms", Type: FieldInteger, Required: true, Description: "How long the session is held"}}},
+	{Type: "resumed", Direction: FromServer, Summary: "Rooms restored after a resume or handoff, with missed messages",
+		Fields: []Field{{Name: "rooms", Type: FieldArray, Items: FieldString, Required: true, Description: "Rooms rejoined"}, {Name: "missed", Type: FieldArray, Items: FieldObject, Description: "Missed messages, each with seq and message"}}},
+	{Type: "reconnect", Direction: FromServer, Summary: "This node is draining; reconnect after the delay",
+		Fields: []Field{{Name: "delay_ms", Type: FieldInteger, Required: true, Description: "Wait before reconnecting"}, {Name: "url", Type: FieldString, Description: "Node to reconnect to"}, {Name: "handoff_token", Type: FieldString, Description: "Pass as handoff_token to keep your rooms"}}},
+	{Type: "server_shutdown", Direction: FromServer, Summary: "The server is closing the connection",
+		Fields: []Field{{Name: "reason", Type: FieldString, Required: true}, {Name: "reconnect", Type: FieldBoolean, Required: true}, {Name: "retry_ms", Type: FieldInteger, Required: true}, {Name: "close_at", Type: FieldInteger, Required: true, Description: "Unix seconds"}}},
+	{Type: "auth_expiring", Direction: FromServer, Summary: "The access token expires soon; send auth_refresh",
+		Fields: []Field{{Name: "expires_at", Type: FieldInteger, Required: true, Description: "Unix seconds"}}},
+	{Type: "auth_refreshed", Direction: FromServer, Summary: "A new access token was accepted",
+		Fields: []Field{userIDField, {Name: "guest", Type: FieldBoolean, Required: true}, {Name: "expires_at", Type: FieldInteger, Required: true, Description: "Unix seconds"}}},
+	{Type: "heartbeat_challenge", Direction: FromServer, Summary: "Answer with heartbeat to count watch time",
+		Fields: []Field{{Name: "nonce", Type: FieldString, Required: true}}},
+	{Type: "direct", Direction: FromServer, Summary: "Direct message, sent to both users' connections",
+		Fields: []Field{messageIDField, {Name: "from", Type: FieldString, Required: true}, {Name: "to", Type: FieldString, Required: true}, {Name: "message", Type: FieldString, Required: true}, {Name: "sent_at", Type: FieldInteger, Required: true, Description: "Unix milliseconds"}}, Open: true},
+	{Type: "direct_rejected", Direction: FromServer, Summary: "A direct message was refused",
+		Fields: []Field{messageIDField, {Name: "reason", Type: FieldString, Required: true}}, Open: true},
+	{Type: "notification", Direction: FromServer, Summary: "A stored notification; dropped while backgrounded",
+		Fields: []Field{{Name: "type", Type: FieldString, Required: true}, {Name: "data", Type: FieldObject, Required: true}}},
+	{Type: "critical_notification", Direction: FromServer, Summary: "A notification delivered even while backgrounded",
+		Fields: []Field{{Name: "type", Type: FieldString, Required: true}, {Name: "data", Type: FieldObject, Required: true}}},
+	{Type: "manager_feed", Direction: FromServer, Summary: "Stream manager items, highest priority first",
+		Fields: []Field{{Name: "items", Type: FieldArray, Items: FieldObject, Required: true, Description: "Items with kind, room, type, data, and timestamp"}, {Name: "dropped", Type: FieldObject, Description: "Items dropped per kind"}}},
+	{Type: "raid_migrate", Direction: FromServer, Summary: "A raid landed; you were moved to the raided room",
+		Fields: []Field{{Name: "raid_id", Type: FieldString, Required: true}, {Name: "from_stream_id", Type: FieldString, Required: true}, {Name: "to_stream_id", Type: FieldString, Required: true}, {Name: "room", Type: FieldString, Required: true, Description: "Room you were moved to"}}},
+
+	// Server to client: rooms
+	{Type: "room_state", Direction: FromServer, Summary: "Snapshot of a room sent on joining it", Room: RoomKindAny,
+		Fields: []Field{{Name: "room", Type: FieldString, Required: true}, {Name: "viewer_count", Type: FieldInteger, Required: true}}, Open: true},
+	{Type: "viewer_count", Direction: FromServer, Summary: "The room's viewer count changed", Room: RoomKindAny,
+		Fields: []Field{{Name: "count", Type: FieldInteger, Required: true}}},
+	{Type: "chat_message", Direction: FromServer, Summary: "A chat message", Room: RoomKindAny,
+		Fields: []Field{userIDField, {Name: "message", Type: FieldString, Required: true}, {Name: "message_id", Type: FieldString}, {Name: "sent_at", Type: FieldInteger, Description: "Unix milliseconds"}, {Name: "subscriber_tier", Type: FieldString}, {Name: "event_id", Type: FieldString, Description: "Set on watch party chat"}}},
+	{Type: "chat_message_deleted", Direction: FromServer, Summary: "A chat message was deleted", Room: RoomKindAny,
+		Fields: []Field{messageIDField, {Name: "deleted_by", Type: FieldString, Required: true}}},
+	{Type: "chat_summary", Direction: FromServer, Summary: "Chat messages not relayed to you in a busy room", Room: RoomKindAny,
+		Fields: []Field{{Name: "suppressed", Type: FieldInteger, Required: true}}},
+	{Type: "data_update", Direction: FromServer, Summary: "Full value of a room data key, such as a goal's progress (goal:<goal_id>) in overlay rooms", Room: RoomKindAny,
+		Fields: []Field{{Name: "key", Type: FieldString, Required: true}, {Name: "version", Type: FieldInteger, Required: true}, {Name: "value", Type: FieldObject, Required: true}}},
+	{Type: "data_patch", Direction: FromServer, Summary: "Change to a room data key, for clients granted delta", Room: RoomKindAny,
+		Fields: []Field{{Name: "key", Type: FieldString, Required: true}, {Name: "version", Type: FieldInteger, Required: true}, {Name: "base_version", Type: FieldInteger, Required: true}, {Name: "ops", Type: FieldArray, Items: FieldObject, Required: true, Description: "Operations with op, path, and value"}}},
+	{Type: "raid_countdown", Direction: FromServer, Summary: "Seconds until a raid lands", Room: RoomKindStream,
+		Fields: []Field{{Name: "raid_id", Type: FieldString, Required: true}, {Name: "to_stream_id", Type: FieldString, Required: true}, {Name: "seconds_remaining", Type: FieldInteger, Required: true}}},
+	{Type: "party_sync", Direction: FromServer, Summary: "Watch party playback moved", Room: RoomKindParty,
+		Fields: []Field{{Name: "host_id", Type: FieldString, Required: true}, {Name: "position_ms", Type: FieldInteger, Required: true}, {Name: "playing", Type: FieldBoolean, Required: true}, {Name: "rate", Type: FieldNumber, Required: true}, {Name: "updated_at", Type: FieldInteger, Required: true, Description: "Unix milliseconds, to extrapolate the position from"}}, Open: true},
+	{Type: "party_ended", Direction: FromServer, Summary: "The watch party ended", Room: RoomKindParty,
+		Fields: []Field{{Name: "party_id", Type: FieldString, Required: true}, eventIDField}, Open: true},
+	{Type: "community_chat_opened", Direction: FromServer, Summary: "The channel's community chat opened", Room: RoomKindCommunity,
+		Fields: []Field{{Name: "channel_id", Type: FieldString, Required: true}, eventIDField}, Open: true},
+	{Type: "community_chat_closed", Direction: FromServer, Summary: "The channel's community chat closed; it may name the live stream to move to", Room: RoomKindCommunity,
+		Fields: []Field{{Name: "channel_id", Type: FieldString, Required: true}, eventIDField}, Open: true},
+
+	// Server to client: domain events relayed with their data
+	relayedEvent(events.EventTypeStreamLive, "The stream went live", RoomKindStream),
+	relayedEvent(events.EventTypeStreamOffline, "The stream ended", RoomKindStream),
+	relayedEvent(events.EventTypeStreamUpdated, "The stream's title or category changed", RoomKindStream),
+	relayedEvent(events.EventTypeStreamMilestone, "The stream reached a viewer, follower, or duration milestone; sent to the channel's connections when offline", RoomKindStream),
+	relayedEvent(events.EventTypeChatMessage, "A chat message published by the API", RoomKindStream),
+	relayedEvent(events.EventTypeSubscription, "A viewer subscribed; sent to the channel's connections", ""),
+	relayedEvent(events.EventTypeGiftSubscription, "A viewer gifted subscriptions; sent to the channel's connections", ""),
+	relayedEvent(events.EventTypeBitsCheered, "A viewer cheered bits", RoomKindStream),
+	relayedEvent(events.EventTypeRaidOutgoing, "The stream is raiding another; sent to the raiding room", RoomKindStream),
+	relayedEvent(events.EventTypeRaidIncoming, "A raid landed; sent to the raided room", RoomKindStream),
+	relayedEvent(events.EventTypeModerationQueued, "An item entered the moderation queue; manager feeds only", RoomKindStream),
+	relayedEvent(events.EventTypeAutoModHeld, "AutoMod held a message; manager feeds only", RoomKindStream),
+	relayedEvent(events.EventTypeCaptionSegment, "A caption segment", RoomKindCaptions),
+	relayedEvent(events.EventTypePremiereCountdown, "Time until the premiere starts", RoomKindPremiere),
+	relayedEvent(events.EventTypePremiereStarted, "The premiere started", RoomKindPremiere),
+	relayedEvent(events.EventTypePremiereSync, "The premiere's playback position", RoomKindPremiere),
+	relayedEvent(events.EventTypePremiereEnded, "The premiere ended", RoomKindPremiere),
+	relayedEvent(events.EventTypeGoalCompleted, "A channel goal was completed", RoomKindOverlay),
+	relayedEvent(events.EventTypeNewFollower, "You have a new follower; sent to your connections", ""),
+}
+
+// relayedEvent describes a domain event relayed to clients with its data
+func relayedEvent(eventType, summary, room string) MessageSpec {
+	return MessageSpec{Type: eventType, Direction: FromServer, Summary: summary, Room: room, Fields: []Field{eventIDField}, Open: true}
+}
+
+// LookupMessage returns the registered message type sent in direction
+func LookupMessage(direction Direction, messageType string) (MessageSpec, bool) {
+	for _, spec := range Messages {
+		if spec.Direction == direction && spec.Type == messageType {
+			return spec, true
+		}
+	}
+	return MessageSpec{}, false
+}
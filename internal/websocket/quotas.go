@@ -0,0 +1,81 @@
+package websocket
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrTenantQuotaExceeded is returned when a tenant is at its connection quota
+var ErrTenantQuotaExceeded = errors.New("tenant is at its connection quota")
+
+// TenantQuotas bounds tenants' concurrent connections across nodes
+type TenantQuotas interface {
+	// AdmitConnection reports whether a tenant with local connections on
+	// this node may open another
+	AdmitConnection(ctx context.Context, tenantID string, local int) bool
+
+	// ReportConnections shares this node's connections per tenant with the
+	// other nodes
+	ReportConnections(ctx context.Context, counts map[string]int, interval time.Duration)
+}
+
+// SetTenantQuotas enforces tenants' connection quotas
+func (h *Hub) SetTenantQuotas(quotas TenantQuotas) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.tenantQuotas = quotas
+}
+
+// AdmitTenant reports whether tenantID may open another connection
+func (h *Hub) AdmitTenant(ctx context.Context, tenantID string) error {
+	h.mu.RLock()
+	quotas := h.tenantQuotas
+	h.mu.RUnlock()
+	if quotas == nil {
+		return nil
+	}
+
+	if !quotas.AdmitConnection(ctx, tenantID, h.tenantConnections()[tenantID]) {
+		return ErrTenantQuotaExceeded
+	}
+	return nil
+}
+
+// ReportTenantConnections shares this node's connections per tenant every
+// interval until ctx is cancelled
+func (h *Hub) ReportTenantConnections(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-h.done:
+			return
+		case <-ticker.C:
+			h.mu.RLock()
+			quotas := h.tenantQuotas
+			h.mu.RUnlock()
+			if quotas != nil {
+				quotas.ReportConnections(ctx, h.tenantConnections(), interval)
+			}
+		}
+	}
+}
+
+// tenantConnections counts each tenant's connections on this node.
+// Connections held for resume don't count.
+func (h *Hub) tenantConnections() map[string]int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	counts := make(map[string]int)
+	for client := range h.clients {
+		if client.detachedSession() == nil {
+			counts[client.Tenant()]++
+		}
+	}
+	return counts
+}
@@ -0,0 +1,122 @@
+package websocket
+
+import (
+	"log"
+	"time"
+
+	"github.com/tinle0301/streaming-platform-api/internal/events"
+	"github.com/tinle0301/streaming-platform-api/internal/tenancy"
+)
+
+// raidCountdownInterval is how often a raiding room is told how long is left
+const raidCountdownInterval = time.Second
+
+// dispatchRaidEvent runs a raid on this node. The raiding stream's room is
+// told the raid started and counts down; when it lands, its viewers are
+// moved to the raided stream's room, which is told they arrived. Every node
+// gets both events and handles its own clients; the events carry when the
+// raid lands so the nodes agree.
+func (h *Hub) dispatchRaidEvent(event events.Event, data map[string]interface{}) {
+	fromStreamID, _ := data["from_stream_id"].(string)
+	toStreamID, _ := data["to_stream_id"].(string)
+	if fromStreamID == "" || toStreamID == "" {
+		return
+	}
+	fromRoom := tenancy.Room(event.TenantID, fromStreamID)
+	toRoom := tenancy.Room(event.TenantID, toStreamID)
+	landsAt := time.UnixMilli(raidLandsAt(data))
+
+	switch event.Type {
+	case events.EventTypeRaidOutgoing:
+		h.BroadcastToRoom(fromRoom, event.Type, data)
+		go h.countDownRaid(fromRoom, toRoom, landsAt, data)
+	case events.EventTypeRaidIncoming:
+		go func() {
+			timer := time.NewTimer(time.Until(landsAt))
+			defer timer.Stop()
+			select {
+			case <-h.done:
+			case <-timer.C:
+				h.BroadcastToRoom(toRoom, event.Type, data)
+			}
+		}()
+	}
+}
+
+// countDownRaid sends the raiding room the seconds left every
+// raidCountdownInterval, then moves its viewers
+func (h *Hub) countDownRaid(fromRoom, toRoom string, landsAt time.Time, data map[string]interface{}) {
+	ticker := time.NewTicker(raidCountdownInterval)
+	defer ticker.Stop()
+	timer := time.NewTimer(time.Until(landsAt))
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-h.done:
+			return
+		case <-timer.C:
+			h.migrateRaiders(fromRoom, toRoom, data)
+			return
+		case <-ticker.C:
+			remaining := time.Until(landsAt).Round(time.Second)
+			if remaining <= 0 {
+				continue
+			}
+			h.BroadcastToRoom(fromRoom, "raid_countdown", map[string]interface{}{
+				"raid_id":           data["raid_id"],
+				"to_stream_id":      data["to_stream_id"],
+				"seconds_remaining": int64(remaining / time.Second),
+			})
+		}
+	}
+}
+
+// migrateRaiders moves the raiding room's viewers on this node to the
+// raided room and tells each to switch streams. The raiding streamer stays,
+// as do viewers who may not join the raided room.
+func (h *Hub) migrateRaiders(fromRoom, toRoom string, data map[string]interface{}) {
+	streamerID, _ := data["from_streamer_id"].(string)
+
+	h.mu.RLock()
+	raiders := make([]*Client, 0, len(h.rooms[fromRoom]))
+	for client := range h.rooms[fromRoom] {
+		if client.GetUserID() != streamerID {
+			raiders = append(raiders, client)
+		}
+	}
+	h.mu.RUnlock()
+
+	migrate := map[string]interface{}{
+		"raid_id":        data["raid_id"],
+		"from_stream_id": data["from_stream_id"],
+		"to_stream_id":   data["to_stream_id"],
+		"room":           toRoom,
+	}
+	moved := 0
+	for _, client := range raiders {
+		// Leave first so guests at their room limit can still move
+		h.LeaveRoom(fromRoom, client)
+		if !client.Subscribe(toRoom) {
+			h.JoinRoom(fromRoom, client)
+			continue
+		}
+		client.sendMessage("raid_migrate", migrate)
+		moved++
+	}
+
+	log.Printf("Raid landed: raidID=%v, from=%s, to=%s, moved=%d", data["raid_id"], fromRoom, toRoom, moved)
+}
+
+// raidLandsAt returns when a raid lands, in Unix milliseconds. Events
+// decoded from JSON carry numbers as float64.
+func raidLandsAt(data map[string]interface{}) int64 {
+	switch v := data["lands_at"].(type) {
+	case int64:
+		return v
+	case float64:
+		return int64(v)
+	default:
+		return time.Now().UnixMilli()
+	}
+}
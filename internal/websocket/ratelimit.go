@@ -0,0 +1,220 @@
+package websocket
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/tinle0301/streaming-platform-api/internal/ratelimit"
+	"github.com/tinle0301/streaming-platform-api/internal/websocket/closecode"
+)
+
+const (
+	// ipBucketIdle is how long an unused per-IP bucket is kept
+	ipBucketIdle = 5 * time.Minute
+
+	// chatSettingsStateTTL is how long a room's chat settings are cached;
+	// they only change with the configuration
+	chatSettingsStateTTL = time.Minute
+)
+
+// MessageLimit is a token bucket rate: Rate messages/sec with bursts of Burst
+type MessageLimit struct {
+	Rate  float64
+	Burst int
+}
+
+// enabled reports whether the limit applies
+func (l MessageLimit) enabled() bool {
+	return l.Rate > 0 && l.Burst > 0
+}
+
+// MessageRateLimits bounds how fast clients may send inbound messages
+type MessageRateLimits struct {
+	// PerType limits each message type per connection; types without an
+	// entry use Default
+	PerType map[string]MessageLimit
+	Default MessageLimit
+
+	// PerIP limits all messages from one IP across its connections
+	PerIP MessageLimit
+
+	// A connection exceeding its limits more than MaxViolations times within
+	// ViolationWindow is closed with closecode.RateLimited (0 = never)
+	MaxViolations   int
+	ViolationWindow time.Duration
+}
+
+// DefaultMessageRateLimits returns the limits used when none are configured
+func DefaultMessageRateLimits() MessageRateLimits {
+	return MessageRateLimits{
+		PerType: map[string]MessageLimit{
+			"message":     {Rate: 5, Burst: 10},
+			"direct":      {Rate: 1, Burst: 3},
+			"subscribe":   {Rate: 1, Burst: 5},
+			"unsubscribe": {Rate: 1, Burst: 5},
+		},
+		Default:         MessageLimit{Rate: 10, Burst: 20},
+		PerIP:           MessageLimit{Rate: 50, Burst: 100},
+		MaxViolations:   20,
+		ViolationWindow: time.Minute,
+	}
+}
+
+// defaultBucket is the bucket shared by message types without their own limit
+const defaultBucket = "default"
+
+// bucketFor returns the per-connection bucket a message type draws from and
+// its limit. Types without their own limit share one bucket, so a client
+// can't get a fresh allowance by inventing types.
+func (l MessageRateLimits) bucketFor(messageType string) (string, MessageLimit) {
+	if messageType == "whisper" {
+		// An alias of direct; it must not get a second allowance
+		messageType = "direct"
+	}
+	if limit, ok := l.PerType[messageType]; ok {
+		return messageType, limit
+	}
+	return defaultBucket, l.Default
+}
+
+// clientRateLimiter holds a connection's buckets and recent violations
+type clientRateLimiter struct {
+	buckets         map[string]*ratelimit.TokenBucket
+	violations      int
+	violationsSince time.Time
+}
+
+// ipRateLimiter shares one bucket between all connections from an IP
+type ipRateLimiter struct {
+	mu       sync.Mutex
+	buckets  map[string]*ratelimit.TokenBucket
+	lastUsed map[string]time.Time
+}
+
+// newIPRateLimiter creates an empty per-IP limiter
+func newIPRateLimiter() *ipRateLimiter {
+	return &ipRateLimiter{
+		buckets:  make(map[string]*ratelimit.TokenBucket),
+		lastUsed: make(map[string]time.Time),
+	}
+}
+
+// allow takes a token from ip's bucket
+func (l *ipRateLimiter) allow(ip string, limit MessageLimit, now time.Time) bool {
+	l.mu.Lock()
+	bucket, ok := l.buckets[ip]
+	if !ok {
+		bucket = ratelimit.NewTokenBucket(limit.Rate, limit.Burst)
+		l.buckets[ip] = bucket
+	}
+	l.lastUsed[ip] = now
+	l.mu.Unlock()
+
+	return bucket.AllowAt(now)
+}
+
+// prune forgets IPs that have been idle long enough for their bucket to refill
+func (l *ipRateLimiter) prune(now time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for ip, lastUsed := range l.lastUsed {
+		if now.Sub(lastUsed) > ipBucketIdle {
+			delete(l.buckets, ip)
+			delete(l.lastUsed, ip)
+		}
+	}
+}
+
+// SetMessageRateLimits configures inbound message rate limits, and reports
+// the chat limit to joining clients in room_state's chat_settings
+func (h *Hub) SetMessageRateLimits(limits MessageRateLimits) {
+	h.rateLimits = limits
+
+	chat, ok := limits.PerType["message"]
+	if !ok {
+		chat = limits.Default
+	}
+	settings := map[string]interface{}{
+		"rate_limited":        chat.enabled(),
+		"messages_per_second": chat.Rate,
+		"burst":               chat.Burst,
+	}
+	h.AddRoomStateSection(RoomStateChatSettings, func(ctx context.Context, room string) (map[string]interface{}, error) {
+		return settings, nil
+	}, chatSettingsStateTTL)
+}
+
+// SetRemoteIP records the client's IP for per-IP rate limiting
+func (c *Client) SetRemoteIP(ip string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.remoteIP = ip
+}
+
+// RemoteIP returns the client's IP
+func (c *Client) RemoteIP() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.remoteIP
+}
+
+// allowMessage applies the per-IP and per-type limits to an inbound message.
+// Rejected messages are answered with a rate_limited error, and connections
+// that keep exceeding their limits are closed.
+func (c *Client) allowMessage(msg *Message, now time.Time) bool {
+	limits := c.hub.rateLimits
+
+	c.mu.Lock()
+	if c.closeCode != 0 {
+		// Already disconnecting; ignore anything still in flight
+		c.mu.Unlock()
+		return false
+	}
+	ip := c.remoteIP
+
+	allowed := true
+	if key, limit := limits.bucketFor(msg.Type); limit.enabled() {
+		if c.rateLimiter.buckets == nil {
+			c.rateLimiter.buckets = make(map[string]*ratelimit.TokenBucket)
+		}
+		bucket, ok := c.rateLimiter.buckets[key]
+		if !ok {
+			bucket = ratelimit.NewTokenBucket(limit.Rate, limit.Burst)
+			c.rateLimiter.buckets[key] = bucket
+		}
+		allowed = bucket.AllowAt(now)
+	}
+	c.mu.Unlock()
+
+	if allowed && ip != "" && limits.PerIP.enabled() {
+		allowed = c.hub.ipLimiter.allow(ip, limits.PerIP, now)
+	}
+	if allowed {
+		return true
+	}
+
+	c.mu.Lock()
+	if now.Sub(c.rateLimiter.violationsSince) > limits.ViolationWindow {
+		c.rateLimiter.violations = 0
+		c.rateLimiter.violationsSince = now
+	}
+	c.rateLimiter.violations++
+	violations := c.rateLimiter.violations
+	c.mu.Unlock()
+
+	c.hub.metrics.mu.Lock()
+	c.hub.metrics.RateLimitedMessages++
+	c.hub.metrics.mu.Unlock()
+
+	if limits.MaxViolations > 0 && violations > limits.MaxViolations {
+		log.Printf("Closing rate-limited connection: userID=%s, ip=%s, violations=%d", c.userID, ip, violations)
+		c.Disconnect(closecode.RateLimited)
+		return false
+	}
+
+	c.sendError(ErrorCodeRateLimited, "slow down", msg)
+	return false
+}
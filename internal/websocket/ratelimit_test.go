@@ -0,0 +1,37 @@
+package websocket
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestUnknownMessageTypesShareTheDefaultBucket(t *testing.T) {
+	hub := NewHub()
+	hub.SetMessageRateLimits(MessageRateLimits{
+		PerType:         map[string]MessageLimit{"direct": {Rate: 1, Burst: 1}},
+		Default:         MessageLimit{Rate: 1, Burst: 3},
+		ViolationWindow: time.Minute,
+	})
+	client := newDrainedClient(hub, "alice")
+
+	now := time.Now()
+	for i := 0; i < 3; i++ {
+		if !client.allowMessage(&Message{Type: fmt.Sprintf("made_up_%d", i)}, now) {
+			t.Fatalf("message %d was limited within the default burst", i)
+		}
+	}
+	if client.allowMessage(&Message{Type: "another_made_up_type"}, now) {
+		t.Error("a new unknown type got its own allowance")
+	}
+	if len(client.rateLimiter.buckets) != 1 {
+		t.Errorf("buckets = %d, want one shared bucket", len(client.rateLimiter.buckets))
+	}
+
+	if !client.allowMessage(&Message{Type: "direct"}, now) {
+		t.Fatal("direct was limited by the default bucket")
+	}
+	if client.allowMessage(&Message{Type: "whisper"}, now) {
+		t.Error("whisper got an allowance separate from direct")
+	}
+}
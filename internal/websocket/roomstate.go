@@ -0,0 +1,89 @@
+package websocket
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// Standard room state sections
+const (
+	RoomStateStream       = "stream"        // title, category, live status
+	RoomStatePresence     = "presence"      // who is in the room across nodes
+	RoomStateChatSettings = "chat_settings" // how fast each viewer may chat
+	RoomStateSlowMode     = "slow_mode"     // least time between one viewer's messages
+)
+
+// roomStateComposer assembles room_state snapshots from cached sections
+type roomStateComposer struct {
+	sections map[string]*StateCache
+	mu       sync.RWMutex
+}
+
+// newRoomStateComposer creates an empty composer
+func newRoomStateComposer() *roomStateComposer {
+	return &roomStateComposer{
+		sections: make(map[string]*StateCache),
+	}
+}
+
+// AddRoomStateSection registers a section of the room_state snapshot sent to
+// clients when they join a room. Each section is cached per room for ttl, so
+// a wave of joins costs one backend load per room and section.
+func (h *Hub) AddRoomStateSection(name string, loader StateLoader, ttl time.Duration) {
+	h.roomState.mu.Lock()
+	defer h.roomState.mu.Unlock()
+	h.roomState.sections[name] = NewStateCache(loader, ttl)
+}
+
+// evictRoomState drops a closed room's cached sections
+func (h *Hub) evictRoomState(room string) {
+	h.roomState.mu.RLock()
+	defer h.roomState.mu.RUnlock()
+	for _, cache := range h.roomState.sections {
+		cache.Evict(room)
+	}
+}
+
+// composeRoomState builds the snapshot for a room. Sections load in parallel;
+// a failing section is omitted rather than failing the whole snapshot.
+func (h *Hub) composeRoomState(ctx context.Context, room string) map[string]interface{} {
+	h.roomState.mu.RLock()
+	sections := make(map[string]*StateCache, len(h.roomState.sections))
+	for name, cache := range h.roomState.sections {
+		sections[name] = cache
+	}
+	h.roomState.mu.RUnlock()
+
+	state := map[string]interface{}{
+		"room":         room,
+		"viewer_count": h.GetRoomCount(room),
+	}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	for name, cache := range sections {
+		wg.Add(1)
+		go func(name string, cache *StateCache) {
+			defer wg.Done()
+
+			section, err := cache.Get(ctx, room)
+			if err != nil {
+				log.Printf("Error loading room state section: room=%s, section=%s, err=%v", room, name, err)
+				return
+			}
+			if section == nil {
+				// The section doesn't apply to this room
+				return
+			}
+
+			mu.Lock()
+			state[name] = section
+			mu.Unlock()
+		}(name, cache)
+	}
+	wg.Wait()
+
+	return state
+}
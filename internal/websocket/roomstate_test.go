@@ -0,0 +1,89 @@
+package websocket
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestRoomStateIncludesChatSettings(t *testing.T) {
+	hub := NewHub()
+	limits := DefaultMessageRateLimits()
+	limits.PerType["message"] = MessageLimit{Rate: 0.5, Burst: 1}
+	hub.SetMessageRateLimits(limits)
+	hub.AddRoomStateSection(RoomStateStream, func(ctx context.Context, room string) (map[string]interface{}, error) {
+		if room != "stream-1" {
+			return nil, nil
+		}
+		return map[string]interface{}{"title": "Speedrun"}, nil
+	}, 0)
+
+	state := hub.composeRoomState(context.Background(), "stream-1")
+	settings, _ := state[RoomStateChatSettings].(map[string]interface{})
+	if settings["messages_per_second"] != 0.5 || settings["burst"] != 1 || settings["rate_limited"] != true {
+		t.Errorf("chat_settings = %v, want the message rate limit", state[RoomStateChatSettings])
+	}
+	if stream, _ := state[RoomStateStream].(map[string]interface{}); stream["title"] != "Speedrun" {
+		t.Errorf("stream section = %v, want the stream's title", state[RoomStateStream])
+	}
+
+	if _, ok := hub.composeRoomState(context.Background(), "party:1")[RoomStateStream]; ok {
+		t.Error("stream section was sent for a room that isn't a stream's")
+	}
+}
+
+func TestStateCacheEvictsRooms(t *testing.T) {
+	loads := 0
+	cache := NewStateCache(func(ctx context.Context, room string) (map[string]interface{}, error) {
+		loads++
+		return map[string]interface{}{"room": room}, nil
+	}, 50*time.Millisecond)
+	ctx := context.Background()
+
+	for i := 0; i < 10; i++ {
+		cache.Get(ctx, fmt.Sprintf("room-%d", i))
+	}
+	cache.Get(ctx, "room-0")
+	if loads != 10 {
+		t.Errorf("loads = %d, want 10: a cached room was loaded again", loads)
+	}
+
+	cache.Evict("room-0")
+	if _, ok := cache.entries["room-0"]; ok {
+		t.Error("evicted room is still cached")
+	}
+
+	// Expired rooms are swept when the next room loads
+	time.Sleep(60 * time.Millisecond)
+	cache.Get(ctx, "room-new")
+	if len(cache.entries) != 1 {
+		t.Errorf("cache holds %d rooms after they expired, want 1", len(cache.entries))
+	}
+}
+
+// slowModeStore is a CommunityChatStore whose rooms are all open
+type slowModeStore struct{ interval time.Duration }
+
+func (s slowModeStore) CommunityChat(ctx context.Context, channelID string) (bool, time.Duration, error) {
+	return true, s.interval, nil
+}
+
+func (s slowModeStore) ClaimChatTurn(ctx context.Context, channelID, userID string, interval time.Duration) (bool, error) {
+	return true, nil
+}
+
+func TestRoomStateIncludesSlowMode(t *testing.T) {
+	hub := NewHub()
+	hub.SetCommunityChat(slowModeStore{interval: 30 * time.Second})
+
+	state := hub.composeRoomState(context.Background(), CommunityRoom("channel-1"))
+	slowMode, _ := state[RoomStateSlowMode].(map[string]interface{})
+	if slowMode["enabled"] != true || slowMode["seconds"] != int64(30) {
+		t.Errorf("slow_mode = %v, want 30 seconds", state[RoomStateSlowMode])
+	}
+
+	if _, ok := hub.composeRoomState(context.Background(), "stream-1")[RoomStateSlowMode]; ok {
+		t.Error("slow_mode section was sent for a room without slow mode")
+	}
+}
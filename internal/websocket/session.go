@@ -0,0 +1,250 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"sync"
+	"time"
+)
+
+// sessionFlushInterval is how often a detached session's missed messages
+// are written to the session store
+const sessionFlushInterval = 250 * time.Millisecond
+
+// SessionOptions configures resumable sessions
+type SessionOptions struct {
+	// Window is how long a dropped connection's rooms are held, and the
+	// messages it misses buffered, for the client to resume
+	Window time.Duration
+
+	// BufferSize is how many missed messages are kept; older ones are dropped
+	BufferSize int
+}
+
+// DefaultSessionOptions returns the default resume window and buffer size
+func DefaultSessionOptions() SessionOptions {
+	return SessionOptions{
+		Window:     30 * time.Second,
+		BufferSize: 100,
+	}
+}
+
+// BufferedMessage is a message a dropped session missed, numbered in the
+// order it was sent
+type BufferedMessage struct {
+	Seq     int64           `json:"seq"`
+	Message json.RawMessage `json:"message"`
+}
+
+// SessionStore holds dropped sessions so the client can resume them on any
+// node
+type SessionStore interface {
+	// Detach stores a dropped session's state until ttl expires
+	Detach(ctx context.Context, token string, state ResumeState, ttl time.Duration) error
+
+	// Buffer appends messages the session missed, keeping the newest limit.
+	// It fails with ErrResumeNotFound once the session was resumed or expired.
+	Buffer(ctx context.Context, token string, messages [][]byte, limit int) error
+
+	// Resume loads and deletes a dropped session with its missed messages
+	Resume(ctx context.Context, token string) (ResumeState, []BufferedMessage, error)
+}
+
+// SetSessions makes connections resumable. Each connection is sent a
+// resume token; if it drops unexpectedly, its rooms are held for the
+// window and the messages it misses are buffered in store, so the client
+// can reconnect to any node with the token and pick up where it left off.
+func (h *Hub) SetSessions(store SessionStore, opts SessionOptions) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.sessions = store
+	h.sessionOptions = opts
+}
+
+// ResumeSession restores a dropped session for a reconnecting client: it
+// rejoins the session's rooms and replays the messages missed meanwhile in
+// "resumed". A gap before the first seq means older messages were dropped.
+func (h *Hub) ResumeSession(ctx context.Context, client *Client, token string) error {
+	h.mu.RLock()
+	store := h.sessions
+	h.mu.RUnlock()
+	if store == nil {
+		return ErrResumeNotFound
+	}
+
+	state, missed, err := store.Resume(ctx, token)
+	if err != nil {
+		return err
+	}
+	if state.UserID != client.userID {
+		return ErrResumeNotFound
+	}
+	if missed == nil {
+		missed = []BufferedMessage{}
+	}
+
+	client.sendMessage("resumed", map[string]interface{}{
+		"rooms":  h.rejoinRooms(client, state.Rooms),
+		"missed": missed,
+	})
+	return nil
+}
+
+// issueResumeToken sends a new connection the token it can resume with if
+// it drops (caller must hold h.mu)
+func (h *Hub) issueResumeToken(client *Client) {
+	if h.sessions == nil || client.spectator {
+		return
+	}
+
+	token, err := newHandoffToken()
+	if err != nil {
+		log.Printf("Error creating resume token: userID=%s, err=%v", client.userID, err)
+		return
+	}
+	client.mu.Lock()
+	client.resumeToken = token
+	client.mu.Unlock()
+
+	client.sendMessage("session", map[string]interface{}{
+		"resume_token":     token,
+		"resume_window_ms": h.sessionOptions.Window.Milliseconds(),
+	})
+}
+
+// detachClient holds a dropped connection's rooms for the resume window and
+// buffers what it misses (caller must hold h.mu). It reports false if the
+// connection was closed on purpose and should be removed.
+func (h *Hub) detachClient(client *Client) bool {
+	if h.sessions == nil || h.draining {
+		return false
+	}
+
+	client.mu.Lock()
+	resumable := client.resumeToken != "" && client.detached == nil && client.closeCode == 0 && !client.closedByPeer
+	if resumable {
+		client.detached = &detachedSession{token: client.resumeToken, limit: h.sessionOptions.BufferSize}
+	}
+	client.mu.Unlock()
+	if !resumable {
+		return false
+	}
+
+	// The write pump exits; queued messages now go to the buffer
+	client.closeSend()
+	h.metrics.connectionClosed()
+
+	state := ResumeState{UserID: client.userID, Rooms: client.GetRooms()}
+	go h.holdSession(client, state, h.sessionOptions.Window)
+
+	log.Printf("Client detached: userID=%s, window=%v", client.userID, h.sessionOptions.Window)
+	return true
+}
+
+// holdSession saves a detached client's session and flushes the messages
+// it misses until the client resumes it or the window passes, then removes
+// the client
+func (h *Hub) holdSession(client *Client, state ResumeState, window time.Duration) {
+	defer h.unregister(client)
+
+	session := client.detachedSession()
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	err := h.sessions.Detach(ctx, session.token, state, window)
+	cancel()
+	if err != nil {
+		log.Printf("Error saving detached session: userID=%s, err=%v", client.userID, err)
+		return
+	}
+
+	ticker := time.NewTicker(sessionFlushInterval)
+	defer ticker.Stop()
+	expired := time.NewTimer(window)
+	defer expired.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if !h.flushSession(client, session) {
+				return
+			}
+		case <-expired.C:
+			return
+		case <-h.done:
+			return
+		}
+	}
+}
+
+// flushSession writes a detached session's missed messages to the store. It
+// reports false once the session was resumed, so holding it can stop.
+func (h *Hub) flushSession(client *Client, session *detachedSession) bool {
+	messages := session.take()
+	if len(messages) == 0 {
+		return true
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	err := h.sessions.Buffer(ctx, session.token, messages, session.limit)
+	if errors.Is(err, ErrResumeNotFound) {
+		return false
+	}
+	if err != nil {
+		log.Printf("Error buffering missed messages: userID=%s, count=%d, err=%v", client.userID, len(messages), err)
+	}
+	return true
+}
+
+// rejoinRooms joins client to each restored room it may still join and
+// returns those rooms
+func (h *Hub) rejoinRooms(client *Client, rooms []string) []string {
+	joined := make([]string, 0, len(rooms))
+	for _, room := range rooms {
+		if h.canJoin(client, room) {
+			h.JoinRoom(room, client)
+			joined = append(joined, room)
+		}
+	}
+	return joined
+}
+
+// detachedSession returns the client's buffer if its connection dropped
+// and is held for resume
+func (c *Client) detachedSession() *detachedSession {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.detached
+}
+
+// detachedSession collects the messages a dropped connection misses until
+// they are flushed to the session store
+type detachedSession struct {
+	token string
+	limit int
+
+	mu      sync.Mutex
+	pending [][]byte
+}
+
+// add buffers a missed message, dropping the oldest beyond the limit
+func (s *detachedSession) add(message []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.pending) > 0 && len(s.pending) >= s.limit {
+		s.pending = s.pending[1:]
+	}
+	s.pending = append(s.pending, message)
+}
+
+// take removes and returns the buffered messages
+func (s *detachedSession) take() [][]byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	pending := s.pending
+	s.pending = nil
+	return pending
+}
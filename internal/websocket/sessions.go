@@ -0,0 +1,196 @@
+package websocket
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/tinle0301/streaming-platform-api/internal/websocket/closecode"
+)
+
+// maxDeviceLabelLength caps the device label a client may declare
+const maxDeviceLabelLength = 64
+
+// ErrTooManyConnections is returned when a user is at their connection
+// limit and the policy rejects new connections
+var ErrTooManyConnections = errors.New("too many connections for this user")
+
+// What happens when a user opens more connections than allowed
+const (
+	// ConnectionLimitReject refuses the new connection
+	ConnectionLimitReject = "reject"
+
+	// ConnectionLimitBumpOldest closes the user's oldest connection
+	ConnectionLimitBumpOldest = "bump_oldest"
+)
+
+// ConnectionLimitPolicy bounds simultaneous connections per user
+type ConnectionLimitPolicy struct {
+	// MaxPerUser is the most connections one user may hold (0 = unlimited)
+	MaxPerUser int
+
+	// OnExceeded is ConnectionLimitReject or ConnectionLimitBumpOldest
+	OnExceeded string
+}
+
+// DefaultConnectionLimitPolicy returns the limit used when none is configured
+func DefaultConnectionLimitPolicy() ConnectionLimitPolicy {
+	return ConnectionLimitPolicy{MaxPerUser: 5, OnExceeded: ConnectionLimitBumpOldest}
+}
+
+// Session describes one of a user's connections
+type Session struct {
+	ID          string    `json:"id"`
+	Device      string    `json:"device,omitempty"`
+	RemoteIP    string    `json:"remote_ip,omitempty"`
+	ConnectedAt time.Time `json:"connected_at"`
+	Rooms       int       `json:"rooms"`
+	Spectator   bool      `json:"spectator"`
+}
+
+// SetConnectionLimitPolicy configures per-user connection limits
+func (h *Hub) SetConnectionLimitPolicy(policy ConnectionLimitPolicy) {
+	h.connectionLimit = policy
+}
+
+// AdmitUser reports whether userID may open another connection. Under the
+// bump-oldest policy new connections are always admitted.
+func (h *Hub) AdmitUser(userID string) error {
+	policy := h.connectionLimit
+	if policy.MaxPerUser <= 0 || policy.OnExceeded == ConnectionLimitBumpOldest {
+		return nil
+	}
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	if len(h.sessionsByAge(userID)) >= policy.MaxPerUser {
+		return ErrTooManyConnections
+	}
+	return nil
+}
+
+// Sessions lists a user's connections on this node, oldest first
+func (h *Hub) Sessions(userID string) []Session {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	sessions := make([]Session, 0, len(h.userClients[userID]))
+	for _, client := range h.sessionsByAge(userID) {
+		client.mu.RLock()
+		sessions = append(sessions, Session{
+			ID:          client.sessionID,
+			Device:      client.device,
+			RemoteIP:    client.remoteIP,
+			ConnectedAt: client.connectedAt,
+			Rooms:       len(client.rooms),
+			Spectator:   client.spectator,
+		})
+		client.mu.RUnlock()
+	}
+	return sessions
+}
+
+// EndSession closes one of a user's connections and reports whether it existed
+func (h *Hub) EndSession(userID, sessionID string) bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for client := range h.userClients[userID] {
+		if client.sessionID == sessionID {
+			client.Disconnect(closecode.SessionReplaced)
+			return true
+		}
+	}
+	return false
+}
+
+// SetDevice records the device label the client declared at handshake
+func (c *Client) SetDevice(label string) {
+	label = strings.TrimSpace(label)
+	if len(label) > maxDeviceLabelLength {
+		label = label[:maxDeviceLabelLength]
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.device = label
+}
+
+// SessionID returns the ID identifying this connection in the sessions API
+func (c *Client) SessionID() string {
+	return c.sessionID
+}
+
+// trackSession indexes client under its user and enforces the connection
+// limit (caller must hold h.mu)
+func (h *Hub) trackSession(client *Client) {
+	userID := client.userID
+	if h.userClients[userID] == nil {
+		h.userClients[userID] = make(map[*Client]bool)
+	}
+	h.userClients[userID][client] = true
+	h.observeUserOnline(client)
+
+	policy := h.connectionLimit
+	if policy.MaxPerUser <= 0 {
+		return
+	}
+	sessions := h.sessionsByAge(userID)
+	excess := len(sessions) - policy.MaxPerUser
+	if excess <= 0 {
+		return
+	}
+
+	// Under the reject policy the check before upgrade can race; close the
+	// newcomer. Otherwise the oldest connections make room for it.
+	if policy.OnExceeded != ConnectionLimitBumpOldest {
+		client.Disconnect(closecode.SessionReplaced)
+		return
+	}
+	for _, oldest := range sessions[:excess] {
+		log.Printf("Connection limit reached, closing oldest session: userID=%s, session=%s, device=%s",
+			userID, oldest.sessionID, oldest.device)
+		oldest.Disconnect(closecode.SessionReplaced)
+	}
+}
+
+// untrackSession removes client from the user index (caller must hold h.mu)
+func (h *Hub) untrackSession(client *Client) {
+	clients := h.userClients[client.userID]
+	delete(clients, client)
+	if len(clients) == 0 {
+		delete(h.userClients, client.userID)
+	}
+	h.observeUserOffline(client)
+}
+
+// sessionsByAge returns a user's live connections, oldest first. Clients
+// already disconnecting are skipped. Caller must hold h.mu.
+func (h *Hub) sessionsByAge(userID string) []*Client {
+	clients := make([]*Client, 0, len(h.userClients[userID]))
+	for client := range h.userClients[userID] {
+		client.mu.RLock()
+		closing := client.closeCode != 0
+		client.mu.RUnlock()
+		if !closing {
+			clients = append(clients, client)
+		}
+	}
+
+	sort.Slice(clients, func(i, j int) bool {
+		return clients[i].connectedAt.Before(clients[j].connectedAt)
+	})
+	return clients
+}
+
+// newSessionID returns a random connection ID
+func newSessionID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,224 @@
+package websocket
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/tinle0301/streaming-platform-api/internal/websocket/closecode"
+)
+
+// Hub readiness errors
+var (
+	ErrHubDraining   = errors.New("hub is draining")
+	ErrHubNotRunning = errors.New("hub event loop is not responding")
+)
+
+// drainPollInterval is how often draining checks whether send buffers are empty
+const drainPollInterval = 50 * time.Millisecond
+
+// DrainOptions bounds the phases of a graceful hub shutdown
+type DrainOptions struct {
+	// FlushTimeout is how long queued messages, including server_shutdown,
+	// get to reach clients before connections are closed
+	FlushTimeout time.Duration
+
+	// CloseTimeout is how long write pumps get to send their close frame
+	// before connections are closed outright
+	CloseTimeout time.Duration
+}
+
+// DefaultDrainOptions returns the drain bounds used when none are configured
+func DefaultDrainOptions() DrainOptions {
+	return DrainOptions{
+		FlushTimeout: 5 * time.Second,
+		CloseTimeout: 2 * time.Second,
+	}
+}
+
+// SetDrainOptions configures the shutdown drain bounds
+func (h *Hub) SetDrainOptions(opts DrainOptions) {
+	h.drainOptions = opts
+}
+
+// Draining reports whether the hub is shutting down and refusing new clients
+func (h *Hub) Draining() bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.draining
+}
+
+// Ready reports whether the hub's event loop is running and accepting
+// clients. A loop that is stuck or was never started fails the check when
+// ctx is done.
+func (h *Hub) Ready(ctx context.Context) error {
+	if h.Draining() {
+		return ErrHubDraining
+	}
+
+	reply := make(chan struct{})
+	select {
+	case h.probes <- reply:
+	case <-h.done:
+		return ErrHubDraining
+	case <-ctx.Done():
+		return ErrHubNotRunning
+	}
+	select {
+	case <-reply:
+		return nil
+	case <-ctx.Done():
+		return ErrHubNotRunning
+	}
+}
+
+// Done is closed once the hub has shut down and closed every connection
+func (h *Hub) Done() <-chan struct{} {
+	return h.done
+}
+
+// AddClient registers a new connection. It reports false, after closing the
+// connection with a server_drain close frame, if the hub is shutting down.
+func (h *Hub) AddClient(client *Client) bool {
+	if !h.Draining() {
+		select {
+		case h.Register <- client:
+			return true
+		case <-h.done:
+		}
+	}
+
+	client.conn.WriteControl(websocket.CloseMessage, client.closeMessage(), time.Now().Add(writeWait))
+	client.conn.Close()
+	return false
+}
+
+// unregister removes client from the hub unless the hub has already stopped
+func (h *Hub) unregister(client *Client) {
+	select {
+	case h.Unregister <- client:
+	case <-h.done:
+	}
+}
+
+// enqueue queues a broadcast unless the hub has already stopped
+func (h *Hub) enqueue(message *Message) {
+	select {
+	case h.Broadcast <- message:
+	case <-h.done:
+	}
+}
+
+// shutdown drains the hub: it stops accepting clients, delivers broadcasts
+// already queued, tells every client the server is going away with a
+// reconnect hint, gives send buffers until the flush deadline to empty, and
+// then closes each connection with a server_drain close frame
+func (h *Hub) shutdown() {
+	defer close(h.done)
+
+	h.mu.Lock()
+	h.draining = true
+	h.mu.Unlock()
+
+	h.flushBroadcasts()
+
+	h.mu.RLock()
+	clients := make([]*Client, 0, len(h.clients))
+	for client := range h.clients {
+		clients = append(clients, client)
+	}
+	h.mu.RUnlock()
+
+	log.Printf("Draining %d client connections...", len(clients))
+
+	// Each client gets its own jittered retry delay so they don't all return
+	// at the same instant; the close frame repeats the same hint
+	deadline := time.Now().Add(h.drainOptions.FlushTimeout)
+	for _, client := range clients {
+		retryAfter := retryDelay(closecode.ServerDrain)
+		client.mu.Lock()
+		if client.closeCode == 0 {
+			client.closeCode = closecode.ServerDrain
+		}
+		client.retryAfter = retryAfter
+		client.mu.Unlock()
+
+		client.sendMessage("server_shutdown", map[string]interface{}{
+			"reason":    closecode.Describe(closecode.ServerDrain).Reason,
+			"reconnect": true,
+			"retry_ms":  retryAfter.Milliseconds(),
+			"close_at":  deadline.Unix(),
+		})
+	}
+	h.waitForFlush(clients, deadline)
+
+	// Closing the send channels makes each write pump send its close frame
+	h.mu.Lock()
+	for _, client := range clients {
+		client.closeSend()
+	}
+	h.clients = make(map[*Client]bool)
+	h.userClients = make(map[string]map[*Client]bool)
+	h.managers = make(map[string]map[*Client]bool)
+	h.rooms = make(map[string]map[*Client]bool)
+	h.metrics.connectionsCleared()
+	h.mu.Unlock()
+
+	forced := h.waitForClose(clients, time.Now().Add(h.drainOptions.CloseTimeout))
+	log.Printf("Hub shutdown complete: closed=%d, forced=%d", len(clients), forced)
+}
+
+// flushBroadcasts delivers broadcasts queued before the drain began
+func (h *Hub) flushBroadcasts() {
+	for i := cap(h.Broadcast); i > 0; i-- {
+		select {
+		case message := <-h.Broadcast:
+			h.broadcastMessage(message)
+		default:
+			return
+		}
+	}
+}
+
+// waitForFlush waits until every client's send buffer is empty or deadline passes
+func (h *Hub) waitForFlush(clients []*Client, deadline time.Time) {
+	ticker := time.NewTicker(drainPollInterval)
+	defer ticker.Stop()
+
+	for time.Now().Before(deadline) {
+		pending := 0
+		for _, client := range clients {
+			pending += len(client.send)
+		}
+		if pending == 0 {
+			return
+		}
+		<-ticker.C
+	}
+	log.Printf("Drain flush deadline passed with messages still queued")
+}
+
+// waitForClose waits for each client's write pump to exit, closing the
+// connections of those still running at deadline. It returns how many
+// connections had to be closed outright.
+func (h *Hub) waitForClose(clients []*Client, deadline time.Time) int {
+	ctx, cancel := context.WithDeadline(context.Background(), deadline)
+	defer cancel()
+
+	forced := 0
+	for _, client := range clients {
+		select {
+		case <-client.writeDone:
+		case <-ctx.Done():
+			select {
+			case <-client.writeDone:
+			default:
+				client.conn.Close()
+				forced++
+			}
+		}
+	}
+	return forced
+}
@@ -0,0 +1,222 @@
+package websocket
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/vmihailenco/msgpack/v5"
+	"github.com/vmihailenco/msgpack/v5/msgpcode"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Errors returned when verifying signed messages
+var (
+	ErrMissingSignature = errors.New("missing message signature")
+	ErrInvalidSignature = errors.New("invalid message signature")
+	ErrStaleSignature   = errors.New("message signature timestamp outside allowed skew")
+)
+
+// maxSignatureSkew bounds how far a signed message timestamp may drift from
+// server time, limiting replay of captured messages
+const maxSignatureSkew = 30 * time.Second
+
+// BotKeyStore looks up the HMAC key registered for a bot or extension user
+type BotKeyStore interface {
+	KeyFor(userID string) ([]byte, bool)
+}
+
+// StaticBotKeys implements BotKeyStore with an in-memory map
+type StaticBotKeys struct {
+	keys map[string][]byte
+	mu   sync.RWMutex
+}
+
+// NewStaticBotKeys creates an empty bot key store
+func NewStaticBotKeys() *StaticBotKeys {
+	return &StaticBotKeys{
+		keys: make(map[string][]byte),
+	}
+}
+
+// Register sets the signing key for a bot user
+func (s *StaticBotKeys) Register(userID string, key []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys[userID] = key
+}
+
+// Revoke removes the signing key for a bot user
+func (s *StaticBotKeys) Revoke(userID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.keys, userID)
+}
+
+// KeyFor returns the signing key for a bot user
+func (s *StaticBotKeys) KeyFor(userID string) ([]byte, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	key, ok := s.keys[userID]
+	return key, ok
+}
+
+// SignFrame signs an encoded frame for a bot. frame is the message in
+// encoding without a signature; the result is the same frame with the hex
+// HMAC-SHA256 of frame appended as its last field, sig.
+func SignFrame(key []byte, encoding string, frame []byte) ([]byte, error) {
+	switch encoding {
+	case "", EncodingJSON:
+		end := bytes.LastIndexByte(frame, '}')
+		if end < 0 {
+			return nil, fmt.Errorf("frame is not a JSON object")
+		}
+		// Whitespace after the object isn't part of the frame
+		signature := frameSignature(key, frame[:end+1])
+		signed := append([]byte{}, frame[:end]...)
+		if len(bytes.TrimSpace(signed)) > 1 {
+			signed = append(signed, ',')
+		}
+		signed = append(signed, `"sig":"`...)
+		signed = append(signed, signature...)
+		return append(signed, `"}`...), nil
+	case EncodingMsgpack:
+		dec := msgpack.NewDecoder(bytes.NewReader(frame))
+		n, err := dec.DecodeMapLen()
+		if err != nil {
+			return nil, err
+		}
+		signature := frameSignature(key, frame)
+		var buf bytes.Buffer
+		enc := msgpack.NewEncoder(&buf)
+		if err := enc.EncodeMapLen(n + 1); err != nil {
+			return nil, err
+		}
+		buf.Write(frame[msgpackMapHeaderLen(frame):])
+		if err := enc.EncodeString("sig"); err != nil {
+			return nil, err
+		}
+		if err := enc.EncodeString(signature); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	case EncodingProtobuf:
+		signature := frameSignature(key, frame)
+		return appendProtoString(append([]byte{}, frame...), protoFieldSignature, signature), nil
+	default:
+		return nil, fmt.Errorf("unknown encoding %q", encoding)
+	}
+}
+
+// VerifyFrame checks the signature of a frame received in encoding. msg
+// is the frame decoded; its timestamp must be within maxSignatureSkew of now.
+func VerifyFrame(key []byte, encoding string, frame []byte, msg *Message, now time.Time) error {
+	unsigned, signature, ok := splitSignature(encoding, frame)
+	if !ok {
+		return ErrMissingSignature
+	}
+
+	skew := now.Sub(msg.Timestamp)
+	if skew > maxSignatureSkew || skew < -maxSignatureSkew {
+		return ErrStaleSignature
+	}
+
+	if !hmac.Equal([]byte(frameSignature(key, unsigned)), []byte(signature)) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// frameSignature returns the hex HMAC-SHA256 of frame
+func frameSignature(key, frame []byte) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(frame)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// jsonSignature matches a sig member closing a JSON frame
+var jsonSignature = regexp.MustCompile(`,\s*"sig"\s*:\s*"([0-9a-f]*)"\s*}\s*$`)
+
+// splitSignature undoes SignFrame: it returns the frame as it was before
+// its signature was appended, and the signature. ok is false unless sig is
+// the frame's last field.
+func splitSignature(encoding string, frame []byte) (unsigned []byte, signature string, ok bool) {
+	switch encoding {
+	case "", EncodingJSON:
+		match := jsonSignature.FindSubmatchIndex(frame)
+		if match == nil {
+			return nil, "", false
+		}
+		unsigned = append(append([]byte{}, frame[:match[0]]...), '}')
+		return unsigned, string(frame[match[2]:match[3]]), true
+	case EncodingMsgpack:
+		r := bytes.NewReader(frame)
+		dec := msgpack.NewDecoder(r)
+		n, err := dec.DecodeMapLen()
+		if err != nil || n < 1 {
+			return nil, "", false
+		}
+		// Skip to the last key, which must be sig
+		for i := 0; i < 2*(n-1); i++ {
+			if err := dec.Skip(); err != nil {
+				return nil, "", false
+			}
+		}
+		last := len(frame) - r.Len()
+		if key, err := dec.DecodeString(); err != nil || key != "sig" {
+			return nil, "", false
+		}
+		if signature, err = dec.DecodeString(); err != nil || r.Len() != 0 {
+			return nil, "", false
+		}
+		var buf bytes.Buffer
+		if err := msgpack.NewEncoder(&buf).EncodeMapLen(n - 1); err != nil {
+			return nil, "", false
+		}
+		buf.Write(frame[msgpackMapHeaderLen(frame):last])
+		return buf.Bytes(), signature, true
+	case EncodingProtobuf:
+		// Find the start of the last field, which must be sig
+		last := -1
+		for offset := 0; offset < len(frame); {
+			num, typ, n := protowire.ConsumeTag(frame[offset:])
+			if n < 0 {
+				return nil, "", false
+			}
+			m := protowire.ConsumeFieldValue(num, typ, frame[offset+n:])
+			if m < 0 {
+				return nil, "", false
+			}
+			if num == protoFieldSignature && typ == protowire.BytesType && offset+n+m == len(frame) {
+				last = offset
+			}
+			offset += n + m
+		}
+		if last < 0 {
+			return nil, "", false
+		}
+		_, _, n := protowire.ConsumeTag(frame[last:])
+		value, _ := protowire.ConsumeBytes(frame[last+n:])
+		return frame[:last], string(value), true
+	default:
+		return nil, "", false
+	}
+}
+
+// msgpackMapHeaderLen returns the length of the map header frame starts with
+func msgpackMapHeaderLen(frame []byte) int {
+	switch frame[0] {
+	case msgpcode.Map16:
+		return 3
+	case msgpcode.Map32:
+		return 5
+	default:
+		return 1
+	}
+}
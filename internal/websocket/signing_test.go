@@ -0,0 +1,74 @@
+package websocket
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestSignedFramesVerifyInEveryEncoding(t *testing.T) {
+	key := []byte("bot-key")
+	now := time.Now()
+	message := &Message{
+		Type:      "timeout",
+		Room:      "stream:1",
+		Data:      map[string]interface{}{"user_id": "viewer-1", "duration": 600},
+		Timestamp: now,
+	}
+
+	for _, encoding := range []string{EncodingJSON, EncodingMsgpack, EncodingProtobuf} {
+		encoded, err := newEncodedMessage(message)
+		if err != nil {
+			t.Fatal(err)
+		}
+		unsigned, err := encoded.encode(encoding)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if encoding == EncodingJSON {
+			// Whitespace a bot's encoder adds after the object is not signed
+			unsigned = append(unsigned, '\n')
+		}
+		signed, err := SignFrame(key, encoding, unsigned)
+		if err != nil {
+			t.Fatalf("%s: SignFrame: %v", encoding, err)
+		}
+
+		decode := func(frame []byte) *Message {
+			var decoded Message
+			if encoding == EncodingJSON {
+				err = json.Unmarshal(frame, &decoded)
+			} else {
+				err = decodeMessage(encoding, frame, &decoded)
+			}
+			if err != nil {
+				t.Fatalf("%s: decode signed frame: %v", encoding, err)
+			}
+			return &decoded
+		}
+
+		decoded := decode(signed)
+		if decoded.Signature == "" || decoded.Type != "timeout" || decoded.Data["user_id"] != "viewer-1" {
+			t.Errorf("%s: signed frame decoded to %+v", encoding, decoded)
+		}
+		if err := VerifyFrame(key, encoding, signed, decoded, now); err != nil {
+			t.Errorf("%s: VerifyFrame of a signed frame = %v", encoding, err)
+		}
+		if err := VerifyFrame([]byte("other-key"), encoding, signed, decoded, now); err != ErrInvalidSignature {
+			t.Errorf("%s: VerifyFrame with another key = %v, want ErrInvalidSignature", encoding, err)
+		}
+		if err := VerifyFrame(key, encoding, signed, decoded, now.Add(time.Minute)); err != ErrStaleSignature {
+			t.Errorf("%s: VerifyFrame a minute later = %v, want ErrStaleSignature", encoding, err)
+		}
+		if err := VerifyFrame(key, encoding, unsigned, decode(unsigned), now); err != ErrMissingSignature {
+			t.Errorf("%s: VerifyFrame of an unsigned frame = %v, want ErrMissingSignature", encoding, err)
+		}
+
+		// Changing any signed byte breaks the signature
+		tampered := bytes.Replace(signed, []byte("viewer-1"), []byte("viewer-2"), 1)
+		if err := VerifyFrame(key, encoding, tampered, decode(tampered), now); err != ErrInvalidSignature {
+			t.Errorf("%s: VerifyFrame of a tampered frame = %v, want ErrInvalidSignature", encoding, err)
+		}
+	}
+}
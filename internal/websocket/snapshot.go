@@ -0,0 +1,283 @@
+package websocket
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/netip"
+	"sort"
+	"strings"
+	"time"
+)
+
+// SnapshotRedaction selects the personal data a hub snapshot hides
+type SnapshotRedaction struct {
+	// UserIDs are replaced with hashes that are stable within one snapshot,
+	// so a user's connections still correlate
+	UserIDs bool `json:"user_ids"`
+
+	// IPs are truncated to their /24 (IPv4) or /48 (IPv6) network
+	IPs bool `json:"ips"`
+
+	// Metadata values are dropped; their keys are kept
+	Metadata bool `json:"metadata"`
+}
+
+// RedactAll hides all personal data in a snapshot
+var RedactAll = SnapshotRedaction{UserIDs: true, IPs: true, Metadata: true}
+
+// ParseSnapshotRedaction parses "all", "none", or a comma-separated list of
+// user_ids, ips, and metadata
+func ParseSnapshotRedaction(value string) (SnapshotRedaction, error) {
+	switch value {
+	case "all":
+		return RedactAll, nil
+	case "none":
+		return SnapshotRedaction{}, nil
+	}
+
+	var redaction SnapshotRedaction
+	for _, item := range strings.Split(value, ",") {
+		switch strings.TrimSpace(item) {
+		case "user_ids":
+			redaction.UserIDs = true
+		case "ips":
+			redaction.IPs = true
+		case "metadata":
+			redaction.Metadata = true
+		default:
+			return SnapshotRedaction{}, fmt.Errorf("unknown redaction %q, want all, none, or user_ids, ips, metadata", item)
+		}
+	}
+	return redaction, nil
+}
+
+// HubSnapshot is a node's state at one instant, for offline analysis of
+// incidents
+type HubSnapshot struct {
+	TakenAt      time.Time             `json:"taken_at"`
+	Redaction    SnapshotRedaction     `json:"redaction"`
+	Status       HubStatus             `json:"status"`
+	Rooms        []RoomSummary         `json:"rooms"`
+	Clients      []ClientSnapshot      `json:"clients"`
+	IPRateLimits []IPRateLimitStanding `json:"ip_rate_limits"`
+}
+
+// ClientSnapshot is a connection's state in a hub snapshot
+type ClientSnapshot struct {
+	UserID       string            `json:"user_id"`
+	SessionID    string            `json:"session_id"`
+	Tenant       string            `json:"tenant,omitempty"`
+	Device       string            `json:"device,omitempty"`
+	RemoteIP     string            `json:"remote_ip,omitempty"`
+	ConnectedAt  time.Time         `json:"connected_at"`
+	Rooms        []string          `json:"rooms"`
+	Spectator    bool              `json:"spectator,omitempty"`
+	Guest        bool              `json:"guest,omitempty"`
+	Background   bool              `json:"background,omitempty"`
+	Capabilities []string          `json:"capabilities,omitempty"`
+	Encoding     string            `json:"encoding"`
+	Metadata     map[string]string `json:"metadata,omitempty"`
+	Closing      bool              `json:"closing,omitempty"`
+
+	// Buffer depths: the send channel, the stream manager feed, and the
+	// messages held for resume after the connection dropped
+	SendBuffered   int `json:"send_buffered"`
+	SendCapacity   int `json:"send_capacity"`
+	ManagerQueued  int `json:"manager_queued,omitempty"`
+	ResumeBuffered int `json:"resume_buffered,omitempty"`
+
+	RateLimit ClientRateLimitStanding `json:"rate_limit"`
+}
+
+// ClientRateLimitStanding is a connection's inbound rate limit state
+type ClientRateLimitStanding struct {
+	// Tokens left in each message type's bucket
+	Tokens          map[string]float64 `json:"tokens,omitempty"`
+	Violations      int                `json:"violations"`
+	ViolationsSince time.Time          `json:"violations_since,omitempty"`
+}
+
+// IPRateLimitStanding is the tokens left in an IP's shared bucket
+type IPRateLimitStanding struct {
+	IP       string    `json:"ip"`
+	Tokens   float64   `json:"tokens"`
+	LastUsed time.Time `json:"last_used"`
+}
+
+// Snapshot captures the hub's clients, rooms, buffer depths, and rate
+// limit standings. The hub lock is held throughout, so rooms and clients
+// agree with each other; it should not be taken often.
+func (h *Hub) Snapshot(redaction SnapshotRedaction) HubSnapshot {
+	now := time.Now()
+	redactor := newSnapshotRedactor(redaction)
+	metrics := h.GetMetrics()
+	features := h.Features()
+
+	h.mu.RLock()
+	snapshot := HubSnapshot{
+		TakenAt:   now,
+		Redaction: redaction,
+		Status: HubStatus{
+			ActiveConnections:   metrics.ActiveConnections,
+			TotalConnections:    metrics.TotalConnections,
+			MessagesSent:        metrics.TotalMessagesSent,
+			MessagesReceived:    metrics.TotalMessagesRecv,
+			RateLimitedMessages: metrics.RateLimitedMessages,
+			LastMessageAt:       metrics.LastMessageTime,
+			Rooms:               len(h.rooms),
+			Draining:            h.draining,
+			Features:            features,
+		},
+		Rooms:   make([]RoomSummary, 0, len(h.rooms)),
+		Clients: make([]ClientSnapshot, 0, len(h.clients)),
+	}
+	for room, clients := range h.rooms {
+		snapshot.Rooms = append(snapshot.Rooms, RoomSummary{Room: room, Clients: len(clients)})
+	}
+	for client := range h.clients {
+		snapshot.Clients = append(snapshot.Clients, client.snapshot(now, redactor))
+	}
+	h.mu.RUnlock()
+
+	snapshot.IPRateLimits = h.ipLimiter.standings(now, redactor)
+
+	sort.Slice(snapshot.Rooms, func(i, j int) bool {
+		return snapshot.Rooms[i].Room < snapshot.Rooms[j].Room
+	})
+	sort.Slice(snapshot.Clients, func(i, j int) bool {
+		return snapshot.Clients[i].ConnectedAt.Before(snapshot.Clients[j].ConnectedAt)
+	})
+	return snapshot
+}
+
+// snapshot captures the client's state
+func (c *Client) snapshot(now time.Time, redactor *snapshotRedactor) ClientSnapshot {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	snapshot := ClientSnapshot{
+		UserID:       redactor.userID(c.userID),
+		SessionID:    c.sessionID,
+		Tenant:       c.tenant,
+		Device:       c.device,
+		RemoteIP:     redactor.ip(c.remoteIP),
+		ConnectedAt:  c.connectedAt,
+		Rooms:        make([]string, 0, len(c.rooms)),
+		Spectator:    c.spectator,
+		Guest:        c.guest,
+		Background:   c.background,
+		Closing:      c.closeCode != 0,
+		Encoding:     c.encoding,
+		SendBuffered: len(c.send),
+		SendCapacity: cap(c.send),
+		RateLimit: ClientRateLimitStanding{
+			Violations:      c.rateLimiter.violations,
+			ViolationsSince: c.rateLimiter.violationsSince,
+		},
+	}
+	for room := range c.rooms {
+		snapshot.Rooms = append(snapshot.Rooms, room)
+	}
+	sort.Strings(snapshot.Rooms)
+	for capability, granted := range c.capabilities {
+		if granted {
+			snapshot.Capabilities = append(snapshot.Capabilities, capability)
+		}
+	}
+	sort.Strings(snapshot.Capabilities)
+	if len(c.metadata) > 0 {
+		snapshot.Metadata = make(map[string]string, len(c.metadata))
+		for key, value := range c.metadata {
+			snapshot.Metadata[key] = redactor.metadata(value)
+		}
+	}
+	if len(c.rateLimiter.buckets) > 0 {
+		snapshot.RateLimit.Tokens = make(map[string]float64, len(c.rateLimiter.buckets))
+		for messageType, bucket := range c.rateLimiter.buckets {
+			snapshot.RateLimit.Tokens[messageType] = bucket.TokensAt(now)
+		}
+	}
+
+	if c.managerFeed != nil {
+		c.managerFeed.mu.Lock()
+		for _, queue := range c.managerFeed.queues {
+			snapshot.ManagerQueued += len(queue)
+		}
+		c.managerFeed.mu.Unlock()
+	}
+	if c.detached != nil {
+		c.detached.mu.Lock()
+		snapshot.ResumeBuffered = len(c.detached.pending)
+		c.detached.mu.Unlock()
+	}
+	return snapshot
+}
+
+// standings returns the tokens left in each IP's bucket, most used first
+func (l *ipRateLimiter) standings(now time.Time, redactor *snapshotRedactor) []IPRateLimitStanding {
+	l.mu.Lock()
+	standings := make([]IPRateLimitStanding, 0, len(l.buckets))
+	for ip, bucket := range l.buckets {
+		standings = append(standings, IPRateLimitStanding{
+			IP:       redactor.ip(ip),
+			Tokens:   bucket.TokensAt(now),
+			LastUsed: l.lastUsed[ip],
+		})
+	}
+	l.mu.Unlock()
+
+	sort.Slice(standings, func(i, j int) bool {
+		return standings[i].Tokens < standings[j].Tokens
+	})
+	return standings
+}
+
+// snapshotRedactor applies a snapshot's redaction
+type snapshotRedactor struct {
+	redaction SnapshotRedaction
+	salt      []byte
+}
+
+// newSnapshotRedactor creates a redactor with a salt of its own, so hashed
+// user IDs cannot be matched across snapshots
+func newSnapshotRedactor(redaction SnapshotRedaction) *snapshotRedactor {
+	salt := make([]byte, 16)
+	rand.Read(salt)
+	return &snapshotRedactor{redaction: redaction, salt: salt}
+}
+
+// userID returns the user ID or its salted hash
+func (r *snapshotRedactor) userID(id string) string {
+	if !r.redaction.UserIDs || id == "" {
+		return id
+	}
+	sum := sha256.Sum256(append(append([]byte(nil), r.salt...), id...))
+	return "user-" + hex.EncodeToString(sum[:8])
+}
+
+// ip returns the IP or its network
+func (r *snapshotRedactor) ip(ip string) string {
+	if !r.redaction.IPs || ip == "" {
+		return ip
+	}
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return "redacted"
+	}
+	bits := 48
+	if addr = addr.Unmap(); addr.Is4() {
+		bits = 24
+	}
+	prefix, _ := addr.Prefix(bits)
+	return prefix.String()
+}
+
+// metadata returns a metadata value or a placeholder
+func (r *snapshotRedactor) metadata(value string) string {
+	if !r.redaction.Metadata {
+		return value
+	}
+	return "redacted"
+}
@@ -0,0 +1,75 @@
+package websocket
+
+import (
+	"context"
+	"time"
+
+	"github.com/tinle0301/streaming-platform-api/internal/metering"
+)
+
+// UsageMeter records tenants' usage for billing
+type UsageMeter interface {
+	Add(tenantID, metric string, quantity float64)
+}
+
+// MeterUsage reports each tenant's connection-minutes and delivered
+// messages on this node to meter every interval until ctx is cancelled.
+// Connections count for the whole interval they are open at its end.
+func (h *Hub) MeterUsage(ctx context.Context, meter UsageMeter, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-h.done:
+			return
+		case <-ticker.C:
+			connections, delivered := h.takeUsage()
+			for tenantID, count := range connections {
+				meter.Add(tenantID, metering.ConnectionMinutes, float64(count)*interval.Minutes())
+			}
+			for tenantID, count := range delivered {
+				meter.Add(tenantID, metering.MessagesDelivered, float64(count))
+			}
+		}
+	}
+}
+
+// takeUsage counts each tenant's open connections and the messages
+// delivered since the last call. Connections held for resume don't count.
+func (h *Hub) takeUsage() (connections map[string]int, delivered map[string]int64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	connections = make(map[string]int)
+	delivered = h.usageCarry
+	h.usageCarry = make(map[string]int64)
+	for client := range h.clients {
+		tenantID := client.Tenant()
+		if client.detachedSession() == nil {
+			connections[tenantID]++
+		}
+		if count := client.takeDelivered(); count > 0 {
+			delivered[tenantID] += count
+		}
+	}
+	return connections, delivered
+}
+
+// countDelivered records messages written to the connection
+func (c *Client) countDelivered(count int64) {
+	c.usageMu.Lock()
+	defer c.usageMu.Unlock()
+	c.delivered += count
+}
+
+// takeDelivered returns and resets the messages written since the last call
+func (c *Client) takeDelivered() int64 {
+	c.usageMu.Lock()
+	defer c.usageMu.Unlock()
+	count := c.delivered
+	c.delivered = 0
+	return count
+}
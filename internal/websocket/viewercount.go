@@ -0,0 +1,122 @@
+package websocket
+
+import (
+	"math"
+	"time"
+)
+
+// ViewerCountPolicy controls how often viewer counts are broadcast to rooms.
+// Small rooms update quickly; large rooms update slowly, since a precise
+// count matters less and every broadcast fans out to every viewer.
+type ViewerCountPolicy struct {
+	// MinInterval applies to rooms at or below SmallRoomSize
+	MinInterval   time.Duration
+	SmallRoomSize int
+
+	// MaxInterval applies to rooms at or above LargeRoomSize
+	MaxInterval   time.Duration
+	LargeRoomSize int
+
+	// RelativeThreshold is the fractional change required to broadcast
+	// (e.g. 0.01 = 1%); any change is broadcast for tiny rooms
+	RelativeThreshold float64
+}
+
+// DefaultViewerCountPolicy returns the default broadcast policy
+func DefaultViewerCountPolicy() ViewerCountPolicy {
+	return ViewerCountPolicy{
+		MinInterval:       2 * time.Second,
+		SmallRoomSize:     100,
+		MaxInterval:       30 * time.Second,
+		LargeRoomSize:     100000,
+		RelativeThreshold: 0.01,
+	}
+}
+
+// Interval returns the broadcast interval for a room of the given size,
+// interpolated on a log scale between MinInterval and MaxInterval
+func (p ViewerCountPolicy) Interval(size int) time.Duration {
+	if size <= p.SmallRoomSize || p.LargeRoomSize <= p.SmallRoomSize {
+		return p.MinInterval
+	}
+	if size >= p.LargeRoomSize {
+		return p.MaxInterval
+	}
+
+	t := (math.Log(float64(size)) - math.Log(float64(p.SmallRoomSize))) /
+		(math.Log(float64(p.LargeRoomSize)) - math.Log(float64(p.SmallRoomSize)))
+	return p.MinInterval + time.Duration(t*float64(p.MaxInterval-p.MinInterval))
+}
+
+// ShouldBroadcast reports whether the change from last to current is large enough
+func (p ViewerCountPolicy) ShouldBroadcast(last, current int) bool {
+	if last == current {
+		return false
+	}
+	if last == 0 {
+		return true
+	}
+	change := math.Abs(float64(current-last)) / float64(last)
+	return change >= p.RelativeThreshold
+}
+
+// viewerCountState tracks the last broadcast for a room
+type viewerCountState struct {
+	count  int
+	sentAt time.Time
+}
+
+// SetViewerCountPolicy replaces the viewer count broadcast policy
+func (h *Hub) SetViewerCountPolicy(policy ViewerCountPolicy) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.viewerCountPolicy = policy
+}
+
+// broadcastViewerCounts sends viewer_count updates to rooms that are due.
+// Must be called from the Run goroutine.
+func (h *Hub) broadcastViewerCounts(now time.Time) {
+	if !h.featureEnabled(FeatureViewerCounts) {
+		return
+	}
+
+	h.mu.RLock()
+	policy := h.viewerCountPolicy
+	counts := make(map[string]int, len(h.rooms))
+	for room, clients := range h.rooms {
+		counts[room] = len(clients)
+		if h.viewerCountSource != nil {
+			if count, ok := h.viewerCountSource.ViewerCount(room); ok {
+				counts[room] = count
+			}
+		}
+	}
+	h.mu.RUnlock()
+
+	// Forget rooms that have emptied
+	for room := range h.viewerCounts {
+		if _, ok := counts[room]; !ok {
+			delete(h.viewerCounts, room)
+		}
+	}
+
+	for room, count := range counts {
+		last := h.viewerCounts[room]
+		if now.Sub(last.sentAt) < policy.Interval(count) {
+			continue
+		}
+		if !policy.ShouldBroadcast(last.count, count) {
+			continue
+		}
+
+		h.viewerCounts[room] = viewerCountState{count: count, sentAt: now}
+		h.broadcastMessage(&Message{
+			Type: "viewer_count",
+			Room: room,
+			Data: map[string]interface{}{
+				"count": count,
+			},
+			Timestamp: now,
+		})
+	}
+}
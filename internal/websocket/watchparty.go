@@ -0,0 +1,201 @@
+package websocket
+
+import (
+	"context"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/tinle0301/streaming-platform-api/internal/events"
+	"github.com/tinle0301/streaming-platform-api/internal/tenancy"
+)
+
+const (
+	// watchPartyRoomPrefix marks watch party rooms
+	watchPartyRoomPrefix = "party:"
+
+	// RoomStateWatchParty is the room_state section with a party's playback
+	RoomStateWatchParty = "watch_party"
+
+	// watchPartyStateTTL bounds how stale the playback sent to joiners is;
+	// clients extrapolate from updated_at while playing
+	watchPartyStateTTL = time.Second
+
+	// Playback rates a host may set
+	minPlaybackRate = 0.25
+	maxPlaybackRate = 4
+)
+
+// WatchPartyStore knows each running watch party's host and keeps its
+// latest playback state for viewers who join late
+type WatchPartyStore interface {
+	// PartyHost returns the host of a running party, or "" if it isn't running
+	PartyHost(ctx context.Context, partyID string) (string, error)
+
+	SavePlayback(ctx context.Context, partyID string, playback map[string]interface{}) error
+
+	// Playback returns the latest playback state, or nil if there is none
+	Playback(ctx context.Context, partyID string) (map[string]interface{}, error)
+}
+
+// WatchPartyRoom names the room of a watch party
+func WatchPartyRoom(partyID string) string {
+	return watchPartyRoomPrefix + partyID
+}
+
+// watchPartyID returns the party a room belongs to, if it is a party room in
+// any tenant's namespace
+func watchPartyID(room string) (string, bool) {
+	_, name := tenancy.SplitRoom(room)
+	partyID := strings.TrimPrefix(name, watchPartyRoomPrefix)
+	return partyID, partyID != name && partyID != ""
+}
+
+// roomContext is scoped to the tenant whose namespace room is in, so events
+// published for it are delivered within that tenant
+func roomContext(ctx context.Context, room string) context.Context {
+	tenantID, _ := tenancy.SplitRoom(room)
+	return tenancy.WithTenant(ctx, tenantID)
+}
+
+// SetWatchParties enables watch parties. Playback changes and party chat are
+// relayed through publisher so participants on every node receive them, and
+// party chat is stored from the published events; without a publisher they
+// only reach this node.
+func (h *Hub) SetWatchParties(store WatchPartyStore, publisher events.Publisher) {
+	h.mu.Lock()
+	h.watchParties = store
+	h.partyPublisher = publisher
+	h.mu.Unlock()
+
+	h.AddRoomStateSection(RoomStateWatchParty, func(ctx context.Context, room string) (map[string]interface{}, error) {
+		partyID, ok := watchPartyID(room)
+		if !ok {
+			return nil, nil
+		}
+		return store.Playback(ctx, partyID)
+	}, watchPartyStateTTL)
+}
+
+// handlePartySync relays the host's playback position to the party:
+//
+//	{"type":"party_sync","room":"party:<id>","data":{"position_ms":61500,"playing":true,"rate":1}}
+//
+// Participants receive party_sync with the same fields plus host_id and
+// updated_at (Unix milliseconds) to extrapolate the position from.
+func (c *Client) handlePartySync(ctx context.Context, msg *Message) {
+	if c.rejectGuest(msg) {
+		return
+	}
+
+	room := msg.Room
+	if room == "" {
+		room, _ = msg.Data["room"].(string)
+	}
+	partyID, ok := watchPartyID(room)
+	if !ok || !c.IsInRoom(room) {
+		c.sendError(ErrorCodeInvalidMessage, "party_sync needs a watch party room you are in", msg)
+		return
+	}
+
+	c.hub.mu.RLock()
+	store := c.hub.watchParties
+	c.hub.mu.RUnlock()
+	if store == nil {
+		c.sendError(ErrorCodeUnknownType, "watch parties are not enabled", msg)
+		return
+	}
+
+	position, _ := msg.Data["position_ms"].(float64)
+	playing, _ := msg.Data["playing"].(bool)
+	rate, ok := msg.Data["rate"].(float64)
+	if !ok {
+		rate = 1
+	}
+	if position < 0 || rate < minPlaybackRate || rate > maxPlaybackRate {
+		c.sendError(ErrorCodeInvalidMessage, "position_ms must not be negative and rate must be between 0.25 and 4", msg)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(roomContext(ctx, room), 2*time.Second)
+	defer cancel()
+
+	host, err := store.PartyHost(ctx, partyID)
+	if err != nil {
+		log.Printf("Error checking watch party host: partyID=%s, err=%v", partyID, err)
+		return
+	}
+	if host == "" || host != c.GetUserID() {
+		c.sendError(ErrorCodeForbidden, "only the host of a running party controls playback", msg)
+		return
+	}
+
+	playback := map[string]interface{}{
+		"host_id":     host,
+		"position_ms": int64(position),
+		"playing":     playing,
+		"rate":        rate,
+		"updated_at":  time.Now().UnixMilli(),
+	}
+	if err := store.SavePlayback(ctx, partyID, playback); err != nil {
+		log.Printf("Error saving watch party playback: partyID=%s, err=%v", partyID, err)
+	}
+	if !c.hub.publishPartyEvent(ctx, events.NewPartySyncEvent(partyID, host, playback)) {
+		c.hub.BroadcastToRoom(room, "party_sync", playback)
+	}
+}
+
+// relayPartyChat publishes a chat message sent in a watch party room, to be
+// relayed by every node and stored. It reports false for other rooms or when
+// the message could not be published, leaving it to the local broadcast.
+func (h *Hub) relayPartyChat(ctx context.Context, room, userID, text string) bool {
+	partyID, ok := watchPartyID(room)
+	if !ok {
+		return false
+	}
+
+	ctx, cancel := context.WithTimeout(roomContext(ctx, room), 2*time.Second)
+	defer cancel()
+	return h.publishPartyEvent(ctx, events.NewPartyChatMessageEvent(partyID, userID, text))
+}
+
+// publishPartyEvent publishes a watch party event, reporting whether it was
+// published
+func (h *Hub) publishPartyEvent(ctx context.Context, event events.Event) bool {
+	h.mu.RLock()
+	publisher := h.partyPublisher
+	h.mu.RUnlock()
+
+	if publisher == nil {
+		return false
+	}
+	if err := publisher.Publish(ctx, event); err != nil {
+		log.Printf("Error publishing watch party event: type=%s, err=%v", event.Type, err)
+		return false
+	}
+	return true
+}
+
+// dispatchPartyEvent delivers a published watch party event to the party's
+// room on this node
+func (h *Hub) dispatchPartyEvent(event events.Event, data map[string]interface{}) {
+	partyID, _ := data["party_id"].(string)
+	if partyID == "" {
+		return
+	}
+	room := tenancy.Room(event.TenantID, WatchPartyRoom(partyID))
+
+	switch event.Type {
+	case events.EventTypePartySync:
+		h.BroadcastToRoom(room, "party_sync", data)
+	case events.EventTypePartyChatMessage:
+		// Party chat arrives like any other room's chat
+		h.BroadcastToRoom(room, "chat_message", map[string]interface{}{
+			"user_id":  event.UserID,
+			"message":  data["message"],
+			"event_id": event.ID,
+		})
+	case events.EventTypePartyEnded:
+		h.BroadcastToRoom(room, "party_ended", data)
+	}
+}
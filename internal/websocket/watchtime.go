@@ -0,0 +1,255 @@
+package websocket
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+// watchTimeTick is how often the tracker issues challenges and accrues
+const watchTimeTick = 5 * time.Second
+
+// WatchTimeSink records watch time and channel points earned by a viewer
+type WatchTimeSink interface {
+	Accrue(ctx context.Context, userID, channel string, watched time.Duration, points int) error
+}
+
+// WatchTimePolicy controls watch-time accrual for a channel
+type WatchTimePolicy struct {
+	// Disabled turns off accrual for the channel
+	Disabled bool
+
+	// Grace is how long past a missed challenge a viewer still accrues
+	Grace time.Duration
+
+	// AccrualInterval is how often watch time and points are credited
+	AccrualInterval   time.Duration
+	PointsPerInterval int
+}
+
+// DefaultWatchTimePolicy returns the default accrual policy
+func DefaultWatchTimePolicy() WatchTimePolicy {
+	return WatchTimePolicy{
+		Grace:             time.Minute,
+		AccrualInterval:   5 * time.Minute,
+		PointsPerInterval: 10,
+	}
+}
+
+// heartbeatState tracks a client's outstanding heartbeat challenge
+type heartbeatState struct {
+	nonce      string
+	issuedAt   time.Time
+	verifiedAt time.Time
+}
+
+// WatchTimeTracker credits watch time only to viewers who keep answering
+// heartbeat challenges. Each client is periodically sent a random nonce it
+// must echo back; an idle tab that stops answering, a backgrounded app, or a
+// spectator connection stops accruing once its grace period runs out.
+type WatchTimeTracker struct {
+	hub               *Hub
+	sink              WatchTimeSink
+	challengeInterval time.Duration
+
+	defaultPolicy   WatchTimePolicy
+	channelPolicies map[string]WatchTimePolicy
+	lastAccrual     map[string]time.Time // only touched from Run
+	lastCredit      map[string]time.Time // by user; only touched from Run
+	mu              sync.RWMutex
+}
+
+// NewWatchTimeTracker creates a tracker that challenges clients every
+// challengeInterval and credits sink according to policy
+func NewWatchTimeTracker(hub *Hub, sink WatchTimeSink, challengeInterval time.Duration, policy WatchTimePolicy) *WatchTimeTracker {
+	return &WatchTimeTracker{
+		hub:               hub,
+		sink:              sink,
+		challengeInterval: challengeInterval,
+		defaultPolicy:     policy,
+		channelPolicies:   make(map[string]WatchTimePolicy),
+		lastAccrual:       make(map[string]time.Time),
+		lastCredit:        make(map[string]time.Time),
+	}
+}
+
+// SetChannelPolicy overrides the accrual policy for one channel
+func (t *WatchTimeTracker) SetChannelPolicy(channel string, policy WatchTimePolicy) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.channelPolicies[channel] = policy
+}
+
+// policyFor returns the accrual policy for a channel
+func (t *WatchTimeTracker) policyFor(channel string) WatchTimePolicy {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	if policy, ok := t.channelPolicies[channel]; ok {
+		return policy
+	}
+	return t.defaultPolicy
+}
+
+// Run issues challenges and accrues watch time until ctx is cancelled
+func (t *WatchTimeTracker) Run(ctx context.Context) {
+	ticker := time.NewTicker(watchTimeTick)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			t.tick(ctx, now)
+		}
+	}
+}
+
+// tick challenges clients that are due and credits rooms whose interval
+// elapsed. A viewer watching several rooms is credited in only one of them
+// per accrual interval, so opening more rooms doesn't multiply their points.
+func (t *WatchTimeTracker) tick(ctx context.Context, now time.Time) {
+	t.hub.mu.RLock()
+	clients := make([]*Client, 0, len(t.hub.clients))
+	for client := range t.hub.clients {
+		clients = append(clients, client)
+	}
+	rooms := make(map[string][]*Client, len(t.hub.rooms))
+	for room, roomClients := range t.hub.rooms {
+		for client := range roomClients {
+			rooms[room] = append(rooms[room], client)
+		}
+	}
+	t.hub.mu.RUnlock()
+
+	for _, client := range clients {
+		if !client.spectator && client.challengeDue(now, t.challengeInterval) {
+			t.challenge(client, now)
+		}
+	}
+
+	// Rooms are visited in order so the room a viewer is credited in doesn't
+	// depend on map order
+	names := make([]string, 0, len(rooms))
+	for room := range rooms {
+		names = append(names, room)
+	}
+	sort.Strings(names)
+
+	connected := make(map[string]bool, len(clients))
+	for _, client := range clients {
+		connected[client.userID] = true
+	}
+
+	for _, room := range names {
+		roomClients := rooms[room]
+		policy := t.policyFor(room)
+		if policy.Disabled {
+			continue
+		}
+
+		last, ok := t.lastAccrual[room]
+		if !ok {
+			// Start the clock when the room is first seen
+			t.lastAccrual[room] = now
+			continue
+		}
+		if now.Sub(last) < policy.AccrualInterval {
+			continue
+		}
+		t.lastAccrual[room] = now
+
+		// Credit each user once, however many connections and rooms they have open
+		for _, client := range roomClients {
+			if credited, ok := t.lastCredit[client.userID]; ok && now.Sub(credited) < policy.AccrualInterval {
+				continue
+			}
+			if !t.eligible(client, now, policy) {
+				continue
+			}
+			t.lastCredit[client.userID] = now
+
+			if err := t.sink.Accrue(ctx, client.userID, room, now.Sub(last), policy.PointsPerInterval); err != nil {
+				log.Printf("Error accruing watch time: userID=%s, room=%s, err=%v", client.userID, room, err)
+			}
+		}
+	}
+
+	// Forget rooms that have emptied and users who have left
+	for room := range t.lastAccrual {
+		if _, ok := rooms[room]; !ok {
+			delete(t.lastAccrual, room)
+		}
+	}
+	for userID := range t.lastCredit {
+		if !connected[userID] {
+			delete(t.lastCredit, userID)
+		}
+	}
+}
+
+// eligible reports whether a client has recently proven it is attended
+func (t *WatchTimeTracker) eligible(client *Client, now time.Time, policy WatchTimePolicy) bool {
+	if client.userID == "" || client.IsGuest() || client.IsBackground() {
+		return false
+	}
+
+	client.mu.RLock()
+	verifiedAt := client.heartbeat.verifiedAt
+	client.mu.RUnlock()
+
+	return !verifiedAt.IsZero() && now.Sub(verifiedAt) <= t.challengeInterval+policy.Grace
+}
+
+// challenge sends a fresh heartbeat nonce to a client
+func (t *WatchTimeTracker) challenge(client *Client, now time.Time) {
+	nonce, err := newHeartbeatNonce()
+	if err != nil {
+		log.Printf("Error generating heartbeat nonce: %v", err)
+		return
+	}
+
+	client.mu.Lock()
+	client.heartbeat.nonce = nonce
+	client.heartbeat.issuedAt = now
+	client.mu.Unlock()
+
+	client.sendMessage("heartbeat_challenge", map[string]interface{}{
+		"nonce": nonce,
+	})
+}
+
+// challengeDue reports whether the client should be sent a new challenge
+func (c *Client) challengeDue(now time.Time, interval time.Duration) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return now.Sub(c.heartbeat.issuedAt) >= interval
+}
+
+// handleHeartbeat verifies a client's answer to its outstanding challenge
+func (c *Client) handleHeartbeat(msg *Message) {
+	nonce, _ := msg.Data["nonce"].(string)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if nonce == "" || nonce != c.heartbeat.nonce {
+		return
+	}
+	c.heartbeat.nonce = ""
+	c.heartbeat.verifiedAt = time.Now()
+}
+
+// newHeartbeatNonce generates a random challenge nonce
+func newHeartbeatNonce() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,56 @@
+package websocket
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingSink records accruals
+type recordingSink struct {
+	mu       sync.Mutex
+	accruals map[string]int // userID -> number of accruals
+}
+
+func (s *recordingSink) Accrue(ctx context.Context, userID, channel string, watched time.Duration, points int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.accruals[userID]++
+	return nil
+}
+
+func TestWatchTimeCreditsViewerOncePerInterval(t *testing.T) {
+	hub := startRaceHub(t)
+	alice := newDrainedClient(hub, "alice")
+	hub.AddClient(alice)
+	waitForHub(t, hub)
+	t.Cleanup(func() { hub.unregister(alice) })
+	for _, room := range []string{"stream-1", "stream-2", "stream-3"} {
+		hub.JoinRoom(room, alice)
+	}
+
+	sink := &recordingSink{accruals: make(map[string]int)}
+	policy := DefaultWatchTimePolicy()
+	tracker := NewWatchTimeTracker(hub, sink, time.Hour, policy)
+
+	start := time.Now()
+	alice.mu.Lock()
+	alice.heartbeat.verifiedAt = start
+	alice.heartbeat.issuedAt = start
+	alice.mu.Unlock()
+
+	tracker.tick(context.Background(), start)
+	tracker.tick(context.Background(), start.Add(policy.AccrualInterval))
+	if got := sink.accruals["alice"]; got != 1 {
+		t.Fatalf("accruals after one interval in 3 rooms = %d, want 1", got)
+	}
+
+	alice.mu.Lock()
+	alice.heartbeat.verifiedAt = start.Add(2 * policy.AccrualInterval)
+	alice.mu.Unlock()
+	tracker.tick(context.Background(), start.Add(2*policy.AccrualInterval))
+	if got := sink.accruals["alice"]; got != 2 {
+		t.Fatalf("accruals after two intervals = %d, want 2", got)
+	}
+}
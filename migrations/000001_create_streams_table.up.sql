@@ -0,0 +1,22 @@
+CREATE TABLE IF NOT EXISTS streams (
+    id             UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    streamer_id    TEXT NOT NULL,
+    title          TEXT NOT NULL,
+    description    TEXT,
+    status         TEXT NOT NULL DEFAULT 'OFFLINE',
+    category       TEXT,
+    language       TEXT NOT NULL DEFAULT 'en',
+    tags           TEXT[] NOT NULL DEFAULT '{}',
+    is_mature      BOOLEAN NOT NULL DEFAULT FALSE,
+    chat_enabled   BOOLEAN NOT NULL DEFAULT TRUE,
+    viewer_count   INTEGER NOT NULL DEFAULT 0,
+    thumbnail_url  TEXT,
+    started_at     TIMESTAMPTZ,
+    ended_at       TIMESTAMPTZ,
+    created_at     TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at     TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_streams_streamer_id ON streams (streamer_id);
+CREATE INDEX IF NOT EXISTS idx_streams_status_viewers ON streams (status, viewer_count DESC);
+CREATE INDEX IF NOT EXISTS idx_streams_tags ON streams USING GIN (tags);
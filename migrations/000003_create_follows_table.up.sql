@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS follows (
+    follower_id  UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    followed_id  UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (follower_id, followed_id),
+    CHECK (follower_id <> followed_id)
+);
+
+-- Followers and following lists page newest first
+CREATE INDEX IF NOT EXISTS idx_follows_followed_created ON follows (followed_id, created_at DESC, follower_id);
+CREATE INDEX IF NOT EXISTS idx_follows_follower_created ON follows (follower_id, created_at DESC, followed_id);
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS raids (
+    id               UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    from_stream_id   UUID NOT NULL REFERENCES streams (id) ON DELETE CASCADE,
+    to_stream_id     UUID NOT NULL REFERENCES streams (id) ON DELETE CASCADE,
+    from_streamer_id TEXT NOT NULL,
+    to_streamer_id   TEXT NOT NULL,
+    viewer_count     INTEGER NOT NULL DEFAULT 0,
+    created_at       TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Raid history between streamers, newest first
+CREATE INDEX IF NOT EXISTS idx_raids_from_streamer ON raids (from_streamer_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_raids_to_streamer ON raids (to_streamer_id, created_at DESC);
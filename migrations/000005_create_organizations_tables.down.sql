@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS organization_invitations;
+DROP TABLE IF EXISTS organization_channels;
+DROP TABLE IF EXISTS organization_members;
+DROP TABLE IF EXISTS organizations;
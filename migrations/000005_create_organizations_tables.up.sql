@@ -0,0 +1,43 @@
+CREATE TABLE IF NOT EXISTS organizations (
+    id          UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    slug        TEXT NOT NULL UNIQUE,
+    name        TEXT NOT NULL,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS organization_members (
+    organization_id  UUID NOT NULL REFERENCES organizations (id) ON DELETE CASCADE,
+    user_id          UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    role             TEXT NOT NULL CHECK (role IN ('OWNER', 'ADMIN', 'MANAGER', 'MODERATOR', 'STREAMER')),
+    created_at       TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (organization_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_organization_members_user ON organization_members (user_id);
+
+-- A channel is a streamer's user ID and belongs to at most one organization
+CREATE TABLE IF NOT EXISTS organization_channels (
+    channel_id       UUID PRIMARY KEY REFERENCES users (id) ON DELETE CASCADE,
+    organization_id  UUID NOT NULL REFERENCES organizations (id) ON DELETE CASCADE,
+    created_at       TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_organization_channels_org ON organization_channels (organization_id);
+
+CREATE TABLE IF NOT EXISTS organization_invitations (
+    id               UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id  UUID NOT NULL REFERENCES organizations (id) ON DELETE CASCADE,
+    invitee_id       UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    invited_by       UUID REFERENCES users (id) ON DELETE SET NULL,
+    role             TEXT NOT NULL CHECK (role IN ('ADMIN', 'MANAGER', 'MODERATOR', 'STREAMER')),
+    status           TEXT NOT NULL DEFAULT 'PENDING' CHECK (status IN ('PENDING', 'ACCEPTED', 'DECLINED', 'REVOKED')),
+    created_at       TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    expires_at       TIMESTAMPTZ NOT NULL,
+    responded_at     TIMESTAMPTZ
+);
+
+-- One open invitation per user and organization
+CREATE UNIQUE INDEX IF NOT EXISTS idx_organization_invitations_pending
+    ON organization_invitations (organization_id, invitee_id) WHERE status = 'PENDING';
+CREATE INDEX IF NOT EXISTS idx_organization_invitations_invitee ON organization_invitations (invitee_id, status);
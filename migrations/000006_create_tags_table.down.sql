@@ -0,0 +1,2 @@
+ALTER TABLE streams DROP COLUMN IF EXISTS suggested_tags;
+DROP TABLE IF EXISTS tags;
@@ -0,0 +1,24 @@
+-- Managed tags; streams may also carry freeform tags outside this list
+CREATE TABLE IF NOT EXISTS tags (
+    slug        TEXT PRIMARY KEY,
+    name        TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    aliases     TEXT[] NOT NULL DEFAULT '{}',
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+INSERT INTO tags (slug, name, description, aliases) VALUES
+    ('english', 'English', 'Broadcast in English', '{en}'),
+    ('speedrun', 'Speedrun', 'Finishing a game as fast as possible', '{speedrunning,speedruns}'),
+    ('competitive', 'Competitive', 'Ranked or tournament play', '{ranked,esports}'),
+    ('casual', 'Casual', 'Laid-back play', '{chill,relaxed}'),
+    ('first-playthrough', 'First Playthrough', 'Playing a game for the first time', '{blind,first-time}'),
+    ('educational', 'Educational', 'Teaching or explaining as you go', '{tutorial,learning}'),
+    ('creative', 'Creative', 'Art, music, and making things', '{art,music}'),
+    ('co-op', 'Co-op', 'Playing together with others', '{coop,multiplayer}'),
+    ('challenge', 'Challenge', 'Self-imposed rules or handicaps', '{nohit,no-hit}'),
+    ('irl', 'IRL', 'Just chatting and real-life streams', '{just-chatting}')
+ON CONFLICT (slug) DO NOTHING;
+
+-- Tags the auto-tagger suggests, kept apart from the streamer's own tags
+ALTER TABLE streams ADD COLUMN IF NOT EXISTS suggested_tags TEXT[] NOT NULL DEFAULT '{}';
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS campaign_claims;
+DROP TABLE IF EXISTS campaign_progress;
+DROP TABLE IF EXISTS campaign_rewards;
+DROP TABLE IF EXISTS campaigns;
@@ -0,0 +1,52 @@
+-- Watch-time reward campaigns run by game publishers
+CREATE TABLE IF NOT EXISTS campaigns (
+    id              UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    publisher_id    UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    name            TEXT NOT NULL,
+    description     TEXT NOT NULL DEFAULT '',
+    categories      TEXT[] NOT NULL,
+    starts_at       TIMESTAMPTZ NOT NULL,
+    ends_at         TIMESTAMPTZ NOT NULL,
+    webhook_url     TEXT NOT NULL DEFAULT '',
+    webhook_secret  TEXT NOT NULL DEFAULT '',
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CHECK (ends_at > starts_at)
+);
+
+CREATE INDEX IF NOT EXISTS idx_campaigns_publisher ON campaigns (publisher_id);
+CREATE INDEX IF NOT EXISTS idx_campaigns_categories ON campaigns USING GIN (categories);
+
+CREATE TABLE IF NOT EXISTS campaign_rewards (
+    id                UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    campaign_id       UUID NOT NULL REFERENCES campaigns (id) ON DELETE CASCADE,
+    name              TEXT NOT NULL,
+    description       TEXT NOT NULL DEFAULT '',
+    image_url         TEXT NOT NULL DEFAULT '',
+    required_minutes  INTEGER NOT NULL CHECK (required_minutes > 0),
+    created_at        TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_campaign_rewards_campaign ON campaign_rewards (campaign_id, required_minutes);
+
+-- Watch time a viewer has accrued towards a campaign
+CREATE TABLE IF NOT EXISTS campaign_progress (
+    campaign_id      UUID NOT NULL REFERENCES campaigns (id) ON DELETE CASCADE,
+    user_id          UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    watched_seconds  BIGINT NOT NULL DEFAULT 0,
+    updated_at       TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (campaign_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_campaign_progress_user ON campaign_progress (user_id);
+
+-- Each reward can be claimed once per viewer
+CREATE TABLE IF NOT EXISTS campaign_claims (
+    id           UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    reward_id    UUID NOT NULL REFERENCES campaign_rewards (id) ON DELETE CASCADE,
+    campaign_id  UUID NOT NULL REFERENCES campaigns (id) ON DELETE CASCADE,
+    user_id      UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    claimed_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (reward_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_campaign_claims_user ON campaign_claims (user_id);
@@ -0,0 +1,3 @@
+ALTER TABLE streams DROP COLUMN IF EXISTS disclosure_type;
+ALTER TABLE streams DROP COLUMN IF EXISTS sponsor_name;
+ALTER TABLE streams DROP COLUMN IF EXISTS branded_content;
@@ -0,0 +1,4 @@
+-- Sponsorship disclosure for branded content
+ALTER TABLE streams ADD COLUMN IF NOT EXISTS branded_content BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE streams ADD COLUMN IF NOT EXISTS sponsor_name TEXT;
+ALTER TABLE streams ADD COLUMN IF NOT EXISTS disclosure_type TEXT;
@@ -0,0 +1,23 @@
+-- Clips cut from streams. Drafts can be trimmed and retitled by their
+-- creator until they are published.
+CREATE TABLE IF NOT EXISTS clips (
+    id               UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    stream_id        UUID NOT NULL REFERENCES streams (id) ON DELETE CASCADE,
+    creator_id       UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    title            TEXT NOT NULL DEFAULT '',
+    status           TEXT NOT NULL DEFAULT 'DRAFT' CHECK (status IN ('DRAFT', 'PUBLIC')),
+    -- Offsets into the stream, in milliseconds. The source window is the
+    -- footage captured for the draft; the trim range must stay inside it.
+    source_start_ms  BIGINT NOT NULL,
+    source_end_ms    BIGINT NOT NULL,
+    start_ms         BIGINT NOT NULL,
+    end_ms           BIGINT NOT NULL,
+    created_at       TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at       TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    published_at     TIMESTAMPTZ,
+    CHECK (source_start_ms >= 0 AND source_end_ms > source_start_ms),
+    CHECK (start_ms >= source_start_ms AND end_ms <= source_end_ms AND end_ms > start_ms)
+);
+
+CREATE INDEX IF NOT EXISTS idx_clips_stream ON clips (stream_id, published_at DESC) WHERE status = 'PUBLIC';
+CREATE INDEX IF NOT EXISTS idx_clips_creator ON clips (creator_id, created_at DESC);
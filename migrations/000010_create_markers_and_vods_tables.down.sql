@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS vods;
+DROP TABLE IF EXISTS stream_markers;
@@ -0,0 +1,29 @@
+-- Moments in a broadcast flagged by the streamer or detected automatically
+CREATE TABLE IF NOT EXISTS stream_markers (
+    id           UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    stream_id    UUID NOT NULL REFERENCES streams (id) ON DELETE CASCADE,
+    created_by   UUID REFERENCES users (id) ON DELETE SET NULL,
+    offset_ms    BIGINT NOT NULL CHECK (offset_ms >= 0),
+    description  TEXT NOT NULL DEFAULT '',
+    source       TEXT NOT NULL DEFAULT 'MANUAL' CHECK (source IN ('MANUAL', 'AUTO')),
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_stream_markers_stream ON stream_markers (stream_id, offset_ms);
+
+-- Videos on demand derived from broadcasts. Highlight reels are stored as
+-- segment lists; transcoding workers render them.
+CREATE TABLE IF NOT EXISTS vods (
+    id           UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    stream_id    UUID NOT NULL REFERENCES streams (id) ON DELETE CASCADE,
+    streamer_id  TEXT NOT NULL,
+    kind         TEXT NOT NULL CHECK (kind IN ('HIGHLIGHT')),
+    status       TEXT NOT NULL DEFAULT 'DRAFT' CHECK (status IN ('DRAFT', 'PUBLIC')),
+    title        TEXT NOT NULL,
+    segments     JSONB NOT NULL DEFAULT '[]',
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (stream_id, kind)
+);
+
+CREATE INDEX IF NOT EXISTS idx_vods_streamer ON vods (streamer_id, created_at DESC);
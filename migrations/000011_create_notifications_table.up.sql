@@ -0,0 +1,21 @@
+-- Notifications delivered to users, kept so they can be listed and marked
+-- read after the real-time push
+CREATE TABLE IF NOT EXISTS notifications (
+    id               UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id          UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    type             TEXT NOT NULL,
+    title            TEXT NOT NULL,
+    message          TEXT NOT NULL DEFAULT '',
+    data             JSONB NOT NULL DEFAULT '{}',
+    from_user_id     UUID REFERENCES users (id) ON DELETE SET NULL,
+    stream_id        UUID REFERENCES streams (id) ON DELETE SET NULL,
+    -- Event the notification was created from, so redelivered events don't
+    -- notify twice
+    source_event_id  TEXT,
+    created_at       TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    read_at          TIMESTAMPTZ,
+    UNIQUE (user_id, source_event_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_notifications_user ON notifications (user_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_notifications_unread ON notifications (user_id, created_at DESC) WHERE read_at IS NULL;
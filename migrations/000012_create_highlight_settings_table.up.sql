@@ -0,0 +1,7 @@
+-- Per-channel highlight settings. Channels without a row use the defaults.
+CREATE TABLE IF NOT EXISTS highlight_settings (
+    channel_id         TEXT PRIMARY KEY,
+    -- How readily chat spikes become automatic stream markers
+    spike_sensitivity  TEXT NOT NULL DEFAULT 'MEDIUM' CHECK (spike_sensitivity IN ('OFF', 'LOW', 'MEDIUM', 'HIGH')),
+    updated_at         TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,14 @@
+-- Per-minute audience aggregates for each stream. Viewer counts are sampled
+-- several times a minute; chat and views are collected once the minute ends.
+CREATE TABLE IF NOT EXISTS stream_analytics (
+    stream_id       UUID NOT NULL REFERENCES streams (id) ON DELETE CASCADE,
+    bucket_start    TIMESTAMPTZ NOT NULL,
+    peak_viewers    INT NOT NULL DEFAULT 0,
+    -- Sum and number of viewer samples, for the minute's average
+    viewer_total    BIGINT NOT NULL DEFAULT 0,
+    viewer_samples  INT NOT NULL DEFAULT 0,
+    views           INT NOT NULL DEFAULT 0,
+    chat_messages   INT NOT NULL DEFAULT 0,
+    new_followers   INT NOT NULL DEFAULT 0,
+    PRIMARY KEY (stream_id, bucket_start)
+);
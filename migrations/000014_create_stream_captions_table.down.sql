@@ -0,0 +1 @@
+DROP TABLE IF EXISTS stream_captions;
@@ -0,0 +1,12 @@
+-- Closed captions from the speech-to-text service, timed from the start of
+-- the broadcast. A revised segment replaces the one starting at the same time.
+CREATE TABLE IF NOT EXISTS stream_captions (
+    id          UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    stream_id   UUID NOT NULL REFERENCES streams (id) ON DELETE CASCADE,
+    language    TEXT NOT NULL,
+    start_ms    BIGINT NOT NULL CHECK (start_ms >= 0),
+    end_ms      BIGINT NOT NULL CHECK (end_ms > start_ms),
+    text        TEXT NOT NULL,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (stream_id, language, start_ms)
+);
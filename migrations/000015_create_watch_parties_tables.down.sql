@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS watch_party_messages;
+DROP TABLE IF EXISTS watch_parties;
@@ -0,0 +1,24 @@
+-- Co-watching parties: a host plays a VOD in sync for everyone in the
+-- party's room. Live playback state is kept in Redis.
+CREATE TABLE IF NOT EXISTS watch_parties (
+    id          UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    vod_id      UUID NOT NULL REFERENCES vods (id) ON DELETE CASCADE,
+    host_id     UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    ended_at    TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_watch_parties_host ON watch_parties (host_id, created_at DESC);
+
+-- Party chat, stored from the WebSocket servers' party.chat_message events
+CREATE TABLE IF NOT EXISTS watch_party_messages (
+    id               UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    party_id         UUID NOT NULL REFERENCES watch_parties (id) ON DELETE CASCADE,
+    user_id          TEXT NOT NULL,
+    message          TEXT NOT NULL,
+    -- Event the message was stored from, so redelivered events don't repeat it
+    source_event_id  TEXT NOT NULL UNIQUE,
+    created_at       TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_watch_party_messages_party ON watch_party_messages (party_id, created_at DESC);
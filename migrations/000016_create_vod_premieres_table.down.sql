@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS vod_premieres;
+
+UPDATE vods SET status = 'DRAFT' WHERE status = 'PREMIERE';
+ALTER TABLE vods DROP CONSTRAINT IF EXISTS vods_status_check;
+ALTER TABLE vods ADD CONSTRAINT vods_status_check CHECK (status IN ('DRAFT', 'PUBLIC'));
@@ -0,0 +1,19 @@
+-- VODs can premiere: air once at a scheduled time in a live room, then
+-- become ordinary public VODs
+ALTER TABLE vods DROP CONSTRAINT IF EXISTS vods_status_check;
+ALTER TABLE vods ADD CONSTRAINT vods_status_check CHECK (status IN ('DRAFT', 'PREMIERE', 'PUBLIC'));
+
+CREATE TABLE IF NOT EXISTS vod_premieres (
+    vod_id             UUID PRIMARY KEY REFERENCES vods (id) ON DELETE CASCADE,
+    scheduled_at       TIMESTAMPTZ NOT NULL,
+    status             TEXT NOT NULL DEFAULT 'SCHEDULED' CHECK (status IN ('SCHEDULED', 'LIVE', 'ENDED', 'CANCELLED')),
+    -- Smallest countdown mark announced so far, so each is sent once
+    countdown_sent_ms  BIGINT,
+    started_at         TIMESTAMPTZ,
+    -- Last playback position broadcast, so replicas take turns sending them
+    last_sync_at       TIMESTAMPTZ,
+    ended_at           TIMESTAMPTZ,
+    created_at         TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_vod_premieres_pending ON vod_premieres (scheduled_at) WHERE status IN ('SCHEDULED', 'LIVE');
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS community_chat_analytics;
+DROP TABLE IF EXISTS community_chat_settings;
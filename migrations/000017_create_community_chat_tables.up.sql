@@ -0,0 +1,22 @@
+-- Per-channel community chat settings. Channels without a row keep their
+-- chat closed while offline.
+CREATE TABLE IF NOT EXISTS community_chat_settings (
+    channel_id         TEXT PRIMARY KEY,
+    enabled            BOOLEAN NOT NULL DEFAULT FALSE,
+    -- Least time between one chatter's messages; 0 turns slow mode off
+    slow_mode_seconds  INT NOT NULL DEFAULT 0 CHECK (slow_mode_seconds BETWEEN 0 AND 300),
+    updated_at         TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Per-minute aggregates of each channel's community chat, kept apart from
+-- the live viewers in stream_analytics
+CREATE TABLE IF NOT EXISTS community_chat_analytics (
+    channel_id       TEXT NOT NULL,
+    bucket_start     TIMESTAMPTZ NOT NULL,
+    peak_chatters    INT NOT NULL DEFAULT 0,
+    -- Sum and number of presence samples, for the minute's average
+    chatter_total    BIGINT NOT NULL DEFAULT 0,
+    chatter_samples  INT NOT NULL DEFAULT 0,
+    chat_messages    INT NOT NULL DEFAULT 0,
+    PRIMARY KEY (channel_id, bucket_start)
+);
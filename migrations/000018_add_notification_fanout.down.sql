@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS notification_announcement_cursors;
+DROP TABLE IF EXISTS channel_announcements;
+
+ALTER TABLE notifications
+    DROP COLUMN IF EXISTS digested_event_ids,
+    DROP COLUMN IF EXISTS digest_count;
@@ -0,0 +1,27 @@
+-- Rapid repeats of a notification are merged into the user's latest unread
+-- one; the merged events are kept so redelivered events don't count twice
+ALTER TABLE notifications
+    ADD COLUMN IF NOT EXISTS digest_count INT NOT NULL DEFAULT 1,
+    ADD COLUMN IF NOT EXISTS digested_event_ids TEXT[] NOT NULL DEFAULT '{}';
+
+-- Notifications for every follower of a large channel, stored once and
+-- copied into each follower's notifications when they next read them
+CREATE TABLE IF NOT EXISTS channel_announcements (
+    id               UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    channel_id       UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    type             TEXT NOT NULL,
+    title            TEXT NOT NULL,
+    message          TEXT NOT NULL DEFAULT '',
+    data             JSONB NOT NULL DEFAULT '{}',
+    stream_id        UUID REFERENCES streams (id) ON DELETE SET NULL,
+    source_event_id  TEXT UNIQUE,
+    created_at       TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_channel_announcements_channel ON channel_announcements (channel_id, created_at DESC);
+
+-- How far each user's notifications have caught up with announcements
+CREATE TABLE IF NOT EXISTS notification_announcement_cursors (
+    user_id    UUID PRIMARY KEY REFERENCES users (id) ON DELETE CASCADE,
+    synced_at  TIMESTAMPTZ NOT NULL
+);
@@ -0,0 +1,11 @@
+-- Users a user has blocked. Blocks stop direct messages in both directions.
+CREATE TABLE IF NOT EXISTS user_blocks (
+    blocker_id  UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    blocked_id  UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (blocker_id, blocked_id),
+    CHECK (blocker_id <> blocked_id)
+);
+
+-- Blocks are checked from either side
+CREATE INDEX IF NOT EXISTS idx_user_blocks_blocked ON user_blocks (blocked_id, blocker_id);
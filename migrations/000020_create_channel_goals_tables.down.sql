@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS channel_goal_contributions;
+DROP TABLE IF EXISTS channel_goals;
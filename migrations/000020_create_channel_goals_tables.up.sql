@@ -0,0 +1,30 @@
+-- Follower, subscription, and cheer goals channels show on their overlays
+CREATE TABLE IF NOT EXISTS channel_goals (
+    id            UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    channel_id    UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    kind          TEXT NOT NULL CHECK (kind IN ('FOLLOWERS', 'SUBSCRIPTIONS', 'CHEERS')),
+    description   TEXT NOT NULL DEFAULT '',
+    target        BIGINT NOT NULL CHECK (target > 0),
+    status        TEXT NOT NULL DEFAULT 'ACTIVE' CHECK (status IN ('ACTIVE', 'COMPLETED', 'CANCELLED')),
+    -- Progress frozen when the goal ended; active goals compute it
+    final_value   BIGINT,
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    ended_at      TIMESTAMPTZ
+);
+
+-- A channel runs at most one goal of each kind at a time
+CREATE UNIQUE INDEX IF NOT EXISTS idx_channel_goals_active ON channel_goals (channel_id, kind) WHERE status = 'ACTIVE';
+CREATE INDEX IF NOT EXISTS idx_channel_goals_channel ON channel_goals (channel_id, created_at DESC);
+
+-- Subscriptions and cheers counted toward goals, one row per source event
+-- so redelivered events count once
+CREATE TABLE IF NOT EXISTS channel_goal_contributions (
+    channel_id    UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    event_id      TEXT NOT NULL,
+    kind          TEXT NOT NULL CHECK (kind IN ('SUBSCRIPTIONS', 'CHEERS')),
+    amount        BIGINT NOT NULL CHECK (amount > 0),
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (channel_id, event_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_channel_goal_contributions_kind ON channel_goal_contributions (channel_id, kind, created_at);
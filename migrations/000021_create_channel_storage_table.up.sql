@@ -0,0 +1,15 @@
+-- Enterprise channels' own S3 buckets for VODs and clips. The secret key is
+-- encrypted by the API server (STORAGE_ENCRYPTION_KEY) before it is stored.
+CREATE TABLE IF NOT EXISTS channel_storage (
+    channel_id            UUID PRIMARY KEY REFERENCES users (id) ON DELETE CASCADE,
+    bucket                TEXT NOT NULL,
+    region                TEXT NOT NULL,
+    prefix                TEXT NOT NULL DEFAULT '',
+    access_key_id         TEXT NOT NULL,
+    secret_key_encrypted  BYTEA NOT NULL,
+    status                TEXT NOT NULL CHECK (status IN ('VERIFIED', 'FAILED')),
+    last_error            TEXT,
+    last_checked_at       TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    created_at            TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at            TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
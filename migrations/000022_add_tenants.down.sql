@@ -0,0 +1,12 @@
+DROP INDEX IF EXISTS idx_streams_tenant_status_viewers;
+DROP INDEX IF EXISTS idx_users_tenant_email_lower;
+DROP INDEX IF EXISTS idx_users_tenant_username_lower;
+
+-- Fails if another tenant reused a username or email
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_username_lower ON users (LOWER(username));
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email_lower ON users (LOWER(email));
+
+ALTER TABLE streams DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE users DROP COLUMN IF EXISTS tenant_id;
+
+DROP TABLE IF EXISTS tenants;
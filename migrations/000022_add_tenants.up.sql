@@ -0,0 +1,25 @@
+-- White-label tenants. Every user (and so every channel) and stream belongs
+-- to one; records created before multi-tenancy belong to 'default'.
+-- settings holds string overrides of platform configuration.
+CREATE TABLE IF NOT EXISTS tenants (
+    id          TEXT PRIMARY KEY,
+    name        TEXT NOT NULL,
+    domain      TEXT,
+    settings    JSONB NOT NULL DEFAULT '{}',
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_tenants_domain_lower ON tenants (LOWER(domain));
+
+INSERT INTO tenants (id, name) VALUES ('default', 'StreamHub') ON CONFLICT (id) DO NOTHING;
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS tenant_id TEXT NOT NULL DEFAULT 'default' REFERENCES tenants (id);
+ALTER TABLE streams ADD COLUMN IF NOT EXISTS tenant_id TEXT NOT NULL DEFAULT 'default' REFERENCES tenants (id);
+
+-- Usernames and emails are unique within a tenant, regardless of case
+DROP INDEX IF EXISTS idx_users_username_lower;
+DROP INDEX IF EXISTS idx_users_email_lower;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_tenant_username_lower ON users (tenant_id, LOWER(username));
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_tenant_email_lower ON users (tenant_id, LOWER(email));
+
+CREATE INDEX IF NOT EXISTS idx_streams_tenant_status_viewers ON streams (tenant_id, status, viewer_count DESC);
@@ -0,0 +1,14 @@
+-- Daily usage per tenant, rolled up from the counters in Redis. Rows are
+-- overwritten by each rollup, so a day's quantity is final once the day has
+-- passed. tenant_id isn't a foreign key: guests may declare tenants that
+-- don't exist, and their usage is still recorded.
+CREATE TABLE IF NOT EXISTS usage_daily (
+    tenant_id   TEXT NOT NULL,
+    day         DATE NOT NULL,
+    metric      TEXT NOT NULL,
+    quantity    DOUBLE PRECISION NOT NULL DEFAULT 0,
+    updated_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (tenant_id, day, metric)
+);
+
+CREATE INDEX IF NOT EXISTS idx_usage_daily_day ON usage_daily (day);
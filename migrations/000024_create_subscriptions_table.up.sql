@@ -0,0 +1,22 @@
+-- Paid channel subscriptions. One row per channel and subscriber: renewals
+-- and gifts extend expires_at, and a lapsed subscription is reused when the
+-- viewer subscribes again. Gifted subscriptions don't renew.
+CREATE TABLE IF NOT EXISTS subscriptions (
+    id             UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    channel_id     UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    subscriber_id  UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    tier           TEXT NOT NULL CHECK (tier IN ('TIER_1', 'TIER_2', 'TIER_3')),
+    gifted_by      UUID REFERENCES users (id) ON DELETE SET NULL,
+    auto_renew     BOOLEAN NOT NULL DEFAULT TRUE,
+    months         INTEGER NOT NULL DEFAULT 1,
+    started_at     TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    expires_at     TIMESTAMPTZ NOT NULL,
+    created_at     TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at     TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (channel_id, subscriber_id),
+    CHECK (channel_id <> subscriber_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_subscriptions_channel_active ON subscriptions (channel_id, expires_at);
+CREATE INDEX IF NOT EXISTS idx_subscriptions_subscriber ON subscriptions (subscriber_id);
+CREATE INDEX IF NOT EXISTS idx_subscriptions_renewal ON subscriptions (expires_at) WHERE auto_renew;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS channel_cheer_totals;
+DROP TABLE IF EXISTS cheers;
@@ -0,0 +1,24 @@
+-- Bits cheered in streams. channel_cheer_totals keeps each viewer's
+-- all-time bits per channel, updated with every cheer, for the all-time
+-- leaderboard; per-stream leaderboards sum the stream's cheers.
+CREATE TABLE IF NOT EXISTS cheers (
+    id          UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    stream_id   UUID NOT NULL REFERENCES streams (id) ON DELETE CASCADE,
+    channel_id  TEXT NOT NULL,
+    user_id     UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    bits        INTEGER NOT NULL CHECK (bits > 0),
+    message     TEXT NOT NULL DEFAULT '',
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_cheers_stream_user ON cheers (stream_id, user_id);
+
+CREATE TABLE IF NOT EXISTS channel_cheer_totals (
+    channel_id  TEXT NOT NULL,
+    user_id     UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    bits        BIGINT NOT NULL DEFAULT 0,
+    updated_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (channel_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_channel_cheer_totals_leaderboard ON channel_cheer_totals (channel_id, bits DESC);
@@ -0,0 +1,5 @@
+ALTER TABLE users DROP COLUMN IF EXISTS deactivated_at;
+DROP TABLE IF EXISTS scim_group_members;
+DROP TABLE IF EXISTS scim_groups;
+DROP TABLE IF EXISTS scim_users;
+DROP TABLE IF EXISTS scim_directories;
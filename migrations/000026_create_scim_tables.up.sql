@@ -0,0 +1,54 @@
+-- SCIM 2.0 provisioning for enterprise organizations. A directory lets the
+-- organization's identity provider create, update, and deactivate accounts
+-- in its tenant and sync group memberships, which grant organization roles
+-- through group_roles (group display name -> role).
+CREATE TABLE IF NOT EXISTS scim_directories (
+    organization_id  UUID PRIMARY KEY REFERENCES organizations (id) ON DELETE CASCADE,
+    tenant_id        TEXT NOT NULL REFERENCES tenants (id),
+    token_hash       TEXT NOT NULL UNIQUE,
+    group_roles      JSONB NOT NULL DEFAULT '{}',
+    created_at       TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at       TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Accounts a directory provisioned, with the SCIM attributes the platform
+-- doesn't keep. managed_role is the organization role the directory last
+-- granted, so roles it didn't grant are left alone.
+CREATE TABLE IF NOT EXISTS scim_users (
+    organization_id  UUID NOT NULL REFERENCES scim_directories (organization_id) ON DELETE CASCADE,
+    user_id          UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    user_name        TEXT NOT NULL,
+    external_id      TEXT,
+    given_name       TEXT NOT NULL DEFAULT '',
+    family_name      TEXT NOT NULL DEFAULT '',
+    active           BOOLEAN NOT NULL DEFAULT TRUE,
+    managed_role     TEXT NOT NULL DEFAULT '',
+    created_at       TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at       TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (organization_id, user_id)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_scim_users_user_name ON scim_users (organization_id, LOWER(user_name));
+CREATE UNIQUE INDEX IF NOT EXISTS idx_scim_users_external_id ON scim_users (organization_id, external_id) WHERE external_id IS NOT NULL;
+
+CREATE TABLE IF NOT EXISTS scim_groups (
+    id               UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id  UUID NOT NULL REFERENCES scim_directories (organization_id) ON DELETE CASCADE,
+    display_name     TEXT NOT NULL,
+    external_id      TEXT,
+    created_at       TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at       TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_scim_groups_display_name ON scim_groups (organization_id, LOWER(display_name));
+
+CREATE TABLE IF NOT EXISTS scim_group_members (
+    group_id  UUID NOT NULL REFERENCES scim_groups (id) ON DELETE CASCADE,
+    user_id   UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    PRIMARY KEY (group_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_scim_group_members_user ON scim_group_members (user_id);
+
+-- Deactivated accounts can't sign in
+ALTER TABLE users ADD COLUMN IF NOT EXISTS deactivated_at TIMESTAMPTZ;
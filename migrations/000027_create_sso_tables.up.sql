@@ -0,0 +1,32 @@
+-- OpenID Connect single sign-on, one identity provider per tenant. The
+-- client secret is encrypted with SSO_ENCRYPTION_KEY. Members of the
+-- provider's groups get organization roles through role_mappings (claim
+-- value -> role) in organization_id.
+CREATE TABLE IF NOT EXISTS sso_providers (
+    tenant_id        TEXT PRIMARY KEY REFERENCES tenants (id) ON DELETE CASCADE,
+    issuer           TEXT NOT NULL,
+    client_id        TEXT NOT NULL,
+    client_secret    BYTEA NOT NULL,
+    organization_id  UUID REFERENCES organizations (id) ON DELETE SET NULL,
+    role_claim       TEXT NOT NULL DEFAULT 'groups',
+    role_mappings    JSONB NOT NULL DEFAULT '{}',
+    return_urls      TEXT[] NOT NULL DEFAULT '{}',
+    created_at       TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at       TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Accounts signed in through a provider, by the id_token's iss and sub.
+-- managed_role is the organization role single sign-on last granted, so
+-- roles it didn't grant are left alone.
+CREATE TABLE IF NOT EXISTS sso_identities (
+    tenant_id      TEXT NOT NULL REFERENCES tenants (id) ON DELETE CASCADE,
+    issuer         TEXT NOT NULL,
+    subject        TEXT NOT NULL,
+    user_id        UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    managed_role   TEXT NOT NULL DEFAULT '',
+    created_at     TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_login_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (tenant_id, issuer, subject)
+);
+
+CREATE INDEX IF NOT EXISTS idx_sso_identities_user ON sso_identities (user_id);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS stream_milestones;
+DROP TABLE IF EXISTS milestone_settings;
@@ -0,0 +1,25 @@
+-- Thresholds a channel celebrates; channels without a row use the defaults
+CREATE TABLE IF NOT EXISTS milestone_settings (
+    channel_id           UUID PRIMARY KEY REFERENCES users (id) ON DELETE CASCADE,
+    enabled              BOOLEAN NOT NULL DEFAULT TRUE,
+    viewer_thresholds    BIGINT[] NOT NULL DEFAULT '{}',
+    follower_thresholds  BIGINT[] NOT NULL DEFAULT '{}',
+    duration_minutes     BIGINT[] NOT NULL DEFAULT '{}',
+    updated_at           TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Milestones reached. Viewer and duration milestones are reached once per
+-- stream; follower milestones once per channel. The unique indexes let
+-- only one API replica claim and celebrate each.
+CREATE TABLE IF NOT EXISTS stream_milestones (
+    id           UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    channel_id   UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    stream_id    UUID REFERENCES streams (id) ON DELETE SET NULL,
+    kind         TEXT NOT NULL CHECK (kind IN ('VIEWERS', 'FOLLOWERS', 'DURATION')),
+    threshold    BIGINT NOT NULL CHECK (threshold > 0),
+    value        BIGINT NOT NULL,
+    reached_at   TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_stream_milestones_stream ON stream_milestones (stream_id, kind, threshold) WHERE kind <> 'FOLLOWERS';
+CREATE UNIQUE INDEX IF NOT EXISTS idx_stream_milestones_channel ON stream_milestones (channel_id, threshold) WHERE kind = 'FOLLOWERS';
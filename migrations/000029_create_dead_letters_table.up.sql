@@ -0,0 +1,14 @@
+-- Events consumers gave up on after retrying, when EVENT_DLQ is postgres.
+-- Operators inspect and requeue them through Query.deadLetters.
+CREATE TABLE IF NOT EXISTS dead_letters (
+    id          BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    consumer    TEXT NOT NULL,
+    event_id    TEXT NOT NULL,
+    event_type  TEXT NOT NULL,
+    event       JSONB NOT NULL,
+    error       TEXT NOT NULL,
+    attempts    INTEGER NOT NULL CHECK (attempts > 0),
+    failed_at   TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_dead_letters_event ON dead_letters (event_id);
@@ -0,0 +1,21 @@
+DROP INDEX IF EXISTS idx_campaigns_tenant;
+DROP INDEX IF EXISTS idx_notifications_tenant;
+DROP INDEX IF EXISTS idx_organizations_tenant_slug;
+
+-- Fails if another tenant reused an organization slug
+ALTER TABLE organizations ADD CONSTRAINT organizations_slug_key UNIQUE (slug);
+
+ALTER TABLE stream_captions DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE campaigns DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE subscriptions DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE channel_cheer_totals DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE cheers DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE highlight_settings DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE stream_markers DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE vods DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE vod_premieres DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE watch_parties DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE channel_announcements DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE notifications DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE organizations DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE clips DROP COLUMN IF EXISTS tenant_id;
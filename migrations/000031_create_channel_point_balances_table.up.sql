@@ -0,0 +1,12 @@
+-- Watch time and channel points viewers have earned in each channel, kept
+-- by the WebSocket servers' watch-time trackers. channel is the room name
+-- without its tenant prefix.
+CREATE TABLE IF NOT EXISTS channel_point_balances (
+    tenant_id      TEXT NOT NULL DEFAULT 'default' REFERENCES tenants (id),
+    user_id        UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    channel        TEXT NOT NULL,
+    watch_time_ms  BIGINT NOT NULL DEFAULT 0 CHECK (watch_time_ms >= 0),
+    points         BIGINT NOT NULL DEFAULT 0 CHECK (points >= 0),
+    updated_at     TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (tenant_id, user_id, channel)
+);
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS viewer_profiles;
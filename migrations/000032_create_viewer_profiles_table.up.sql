@@ -0,0 +1,8 @@
+-- Viewers' verified birth dates and mature content consent, checked by the
+-- age gate before mature streams and VODs are played or their rooms joined
+CREATE TABLE IF NOT EXISTS viewer_profiles (
+    user_id            UUID PRIMARY KEY REFERENCES users (id) ON DELETE CASCADE,
+    birth_date         DATE,
+    mature_consent_at  TIMESTAMPTZ,
+    updated_at         TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
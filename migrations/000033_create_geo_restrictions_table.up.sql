@@ -0,0 +1,11 @@
+-- Regional restrictions on streams and VODs, set by platform admins and
+-- enforced when content is loaded and when its WebSocket rooms are joined
+CREATE TABLE IF NOT EXISTS geo_restrictions (
+    content_id  TEXT PRIMARY KEY,
+    kind        TEXT NOT NULL CHECK (kind IN ('stream', 'vod')),
+    countries   TEXT[] NOT NULL,
+    allow_list  BOOLEAN NOT NULL DEFAULT FALSE,
+    reason      TEXT NOT NULL,
+    created_by  TEXT NOT NULL,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
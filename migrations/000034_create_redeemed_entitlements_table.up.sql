@@ -0,0 +1,8 @@
+-- Payments redeemed for subscriptions and gifts; a payment's entitlement
+-- token can be redeemed once
+CREATE TABLE IF NOT EXISTS redeemed_entitlements (
+    id          TEXT PRIMARY KEY,
+    buyer_id    UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    tenant_id   TEXT NOT NULL DEFAULT 'default' REFERENCES tenants (id),
+    redeemed_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
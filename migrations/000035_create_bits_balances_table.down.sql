@@ -0,0 +1 @@
+DROP TABLE IF EXISTS bits_balances;
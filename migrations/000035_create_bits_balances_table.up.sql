@@ -0,0 +1,9 @@
+-- Bits each viewer can cheer, credited when bits are bought and debited in
+-- the same transaction as every cheer
+CREATE TABLE IF NOT EXISTS bits_balances (
+    tenant_id   TEXT NOT NULL DEFAULT 'default' REFERENCES tenants (id),
+    user_id     UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    bits        BIGINT NOT NULL DEFAULT 0 CHECK (bits >= 0),
+    updated_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (tenant_id, user_id)
+);
@@ -0,0 +1,11 @@
+ALTER TABLE geo_restrictions DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE viewer_profiles DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE channel_storage DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE stream_analytics DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE raids DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE community_chat_analytics DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE community_chat_settings DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE stream_milestones DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE milestone_settings DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE channel_goal_contributions DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE channel_goals DROP COLUMN IF EXISTS tenant_id;
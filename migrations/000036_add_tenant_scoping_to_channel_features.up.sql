@@ -0,0 +1,26 @@
+-- Goals, milestones, community chat, raids, stream analytics, channel
+-- storage, viewer profiles, and geo restrictions carry their tenant too.
+-- Existing rows take the tenant of their channel, stream, or viewer.
+ALTER TABLE channel_goals ADD COLUMN IF NOT EXISTS tenant_id TEXT NOT NULL DEFAULT 'default' REFERENCES tenants (id);
+ALTER TABLE channel_goal_contributions ADD COLUMN IF NOT EXISTS tenant_id TEXT NOT NULL DEFAULT 'default' REFERENCES tenants (id);
+ALTER TABLE milestone_settings ADD COLUMN IF NOT EXISTS tenant_id TEXT NOT NULL DEFAULT 'default' REFERENCES tenants (id);
+ALTER TABLE stream_milestones ADD COLUMN IF NOT EXISTS tenant_id TEXT NOT NULL DEFAULT 'default' REFERENCES tenants (id);
+ALTER TABLE community_chat_settings ADD COLUMN IF NOT EXISTS tenant_id TEXT NOT NULL DEFAULT 'default' REFERENCES tenants (id);
+ALTER TABLE community_chat_analytics ADD COLUMN IF NOT EXISTS tenant_id TEXT NOT NULL DEFAULT 'default' REFERENCES tenants (id);
+ALTER TABLE raids ADD COLUMN IF NOT EXISTS tenant_id TEXT NOT NULL DEFAULT 'default' REFERENCES tenants (id);
+ALTER TABLE stream_analytics ADD COLUMN IF NOT EXISTS tenant_id TEXT NOT NULL DEFAULT 'default' REFERENCES tenants (id);
+ALTER TABLE channel_storage ADD COLUMN IF NOT EXISTS tenant_id TEXT NOT NULL DEFAULT 'default' REFERENCES tenants (id);
+ALTER TABLE viewer_profiles ADD COLUMN IF NOT EXISTS tenant_id TEXT NOT NULL DEFAULT 'default' REFERENCES tenants (id);
+ALTER TABLE geo_restrictions ADD COLUMN IF NOT EXISTS tenant_id TEXT NOT NULL DEFAULT 'default' REFERENCES tenants (id);
+
+UPDATE channel_goals t SET tenant_id = u.tenant_id FROM users u WHERE u.id = t.channel_id;
+UPDATE channel_goal_contributions t SET tenant_id = u.tenant_id FROM users u WHERE u.id = t.channel_id;
+UPDATE milestone_settings t SET tenant_id = u.tenant_id FROM users u WHERE u.id = t.channel_id;
+UPDATE stream_milestones t SET tenant_id = u.tenant_id FROM users u WHERE u.id = t.channel_id;
+UPDATE community_chat_settings t SET tenant_id = u.tenant_id FROM users u WHERE u.id::text = t.channel_id;
+UPDATE community_chat_analytics t SET tenant_id = u.tenant_id FROM users u WHERE u.id::text = t.channel_id;
+UPDATE raids t SET tenant_id = s.tenant_id FROM streams s WHERE s.id = t.from_stream_id;
+UPDATE stream_analytics t SET tenant_id = s.tenant_id FROM streams s WHERE s.id = t.stream_id;
+UPDATE channel_storage t SET tenant_id = u.tenant_id FROM users u WHERE u.id = t.channel_id;
+UPDATE viewer_profiles t SET tenant_id = u.tenant_id FROM users u WHERE u.id = t.user_id;
+UPDATE geo_restrictions t SET tenant_id = s.tenant_id FROM streams s WHERE t.kind = 'stream' AND s.id::text = t.content_id;
@@ -0,0 +1,20 @@
+-- Copyright claims filed by rights holders against VODs, with the
+-- uploader's counter-notice; accepted claims mute their segments
+CREATE TABLE IF NOT EXISTS copyright_claims (
+    id                    UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    vod_id                UUID NOT NULL REFERENCES vods (id) ON DELETE CASCADE,
+    claimant_name         TEXT NOT NULL,
+    claimant_email        TEXT NOT NULL,
+    work_title            TEXT NOT NULL,
+    segments              JSONB NOT NULL,
+    status                TEXT NOT NULL DEFAULT 'PENDING'
+                          CHECK (status IN ('PENDING', 'ACCEPTED', 'REJECTED', 'COUNTER_NOTICE', 'REINSTATED', 'WITHDRAWN')),
+    counter_user_id       TEXT,
+    counter_statement     TEXT,
+    counter_submitted_at  TIMESTAMPTZ,
+    tenant_id             TEXT NOT NULL DEFAULT 'default' REFERENCES tenants (id),
+    created_at            TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at            TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_copyright_claims_vod ON copyright_claims (vod_id, status);
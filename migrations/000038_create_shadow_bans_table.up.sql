@@ -0,0 +1,11 @@
+-- Shadow bans hide a user's chat from everyone but themselves, in one
+-- channel's room or platform-wide (channel ''); every WebSocket node
+-- reloads them
+CREATE TABLE IF NOT EXISTS shadow_bans (
+    channel     TEXT NOT NULL,
+    user_id     TEXT NOT NULL,
+    actor_id    TEXT NOT NULL,
+    reason      TEXT NOT NULL DEFAULT '',
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (channel, user_id)
+);
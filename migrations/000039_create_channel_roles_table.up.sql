@@ -0,0 +1,10 @@
+-- Streamer and moderator roles granted by platform admins, in a channel
+-- (the channel's user ID, covering all its streams' rooms) or one room;
+-- every WebSocket node reloads them
+CREATE TABLE IF NOT EXISTS channel_roles (
+    channel     TEXT NOT NULL,
+    user_id     TEXT NOT NULL,
+    role        TEXT NOT NULL CHECK (role IN ('streamer', 'moderator')),
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (channel, user_id)
+);
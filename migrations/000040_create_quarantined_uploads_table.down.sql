@@ -0,0 +1,7 @@
+-- Quarantined clips can't be represented without the status, and must not
+-- become drafts again
+DELETE FROM clips WHERE status = 'QUARANTINED';
+ALTER TABLE clips DROP CONSTRAINT IF EXISTS clips_status_check;
+ALTER TABLE clips ADD CONSTRAINT clips_status_check CHECK (status IN ('DRAFT', 'PUBLIC'));
+
+DROP TABLE IF EXISTS quarantined_uploads;
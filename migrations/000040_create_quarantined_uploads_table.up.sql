@@ -0,0 +1,18 @@
+-- Uploads that matched a known-illegal hash; matched clips are withdrawn
+-- with the QUARANTINED status
+CREATE TABLE IF NOT EXISTS quarantined_uploads (
+    kind            TEXT NOT NULL,
+    upload_id       TEXT NOT NULL,
+    uploader_id     TEXT NOT NULL,
+    stream_id       TEXT NOT NULL DEFAULT '',
+    sha256          TEXT NOT NULL,
+    list_name       TEXT NOT NULL DEFAULT '',
+    match_id        TEXT NOT NULL DEFAULT '',
+    signature       TEXT NOT NULL DEFAULT '',
+    tenant_id       TEXT NOT NULL DEFAULT 'default' REFERENCES tenants (id),
+    quarantined_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (kind, upload_id)
+);
+
+ALTER TABLE clips DROP CONSTRAINT IF EXISTS clips_status_check;
+ALTER TABLE clips ADD CONSTRAINT clips_status_check CHECK (status IN ('DRAFT', 'PUBLIC', 'QUARANTINED'));
@@ -0,0 +1,82 @@
+#!/bin/bash
+
+# Compares the hot-path benchmarks of the working tree against a base ref
+# with benchstat, and fails if any benchmark regressed significantly.
+#
+# Usage: scripts/bench-compare.sh [base-ref]
+#
+#   BASE       ref to compare against (default: main, or the first argument)
+#   COUNT      runs per benchmark; benchstat needs 6+ for confidence (default: 10)
+#   BENCH      benchmark regexp (default: .)
+#   BENCHTIME  go test -benchtime (default: 1s)
+#   PACKAGES   packages to benchmark
+#   THRESHOLD  regression percentage that fails the comparison (default: 10)
+#
+# Only changes benchstat finds significant (p < 0.05) count. Costs per
+# operation (sec/op, B/op, allocs/op) regress when they grow; rates such as
+# events/s regress when they shrink.
+#
+# Needs benchstat: go install golang.org/x/perf/cmd/benchstat@latest
+
+set -euo pipefail
+
+BASE=${1:-${BASE:-main}}
+COUNT=${COUNT:-10}
+BENCH=${BENCH:-.}
+BENCHTIME=${BENCHTIME:-1s}
+PACKAGES=${PACKAGES:-./internal/websocket/ ./internal/events/ ./internal/graphql/}
+THRESHOLD=${THRESHOLD:-10}
+BENCHSTAT=${BENCHSTAT:-benchstat}
+
+if ! command -v "$BENCHSTAT" > /dev/null; then
+    echo "benchstat not found; install it with: go install golang.org/x/perf/cmd/benchstat@latest" >&2
+    exit 2
+fi
+
+root=$(git rev-parse --show-toplevel)
+out=$(mktemp -d -t streamhub-bench-XXXXXX)
+worktree="$out/base"
+
+cleanup() {
+    git -C "$root" worktree remove --force "$worktree" > /dev/null 2>&1 || true
+}
+trap cleanup EXIT
+
+# run_benchmarks DIR FILE runs the benchmarks in DIR, writing results to FILE
+run_benchmarks() {
+    # A package the base does not have yet only fails its own line
+    (cd "$1" && go test -run='^$' -bench="$BENCH" -benchmem -benchtime="$BENCHTIME" -count="$COUNT" $PACKAGES) > "$2" || true
+}
+
+echo "Benchmarking $BASE ($(git -C "$root" rev-parse --short "$BASE"))..."
+git -C "$root" worktree add --detach "$worktree" "$BASE" > /dev/null
+run_benchmarks "$worktree" "$out/base.txt"
+
+echo "Benchmarking working tree..."
+run_benchmarks "$root" "$out/head.txt"
+
+"$BENCHSTAT" base="$out/base.txt" head="$out/head.txt"
+
+echo
+echo "Results kept in $out"
+
+# Each unit's CSV table starts with a ",<unit>,CI,<unit>,CI,vs base,P" row;
+# benchmark rows carry the change in column 6, or "~" if not significant
+"$BENCHSTAT" -format csv base="$out/base.txt" head="$out/head.txt" 2> /dev/null | awk -F, -v threshold="$THRESHOLD" '
+    $3 == "CI" { unit = $2; next }
+    $1 == "" || $1 == "geomean" || $6 !~ /^[+-][0-9.]+%$/ { next }
+    {
+        change = $6 + 0
+        regressed = unit ~ /\/s$/ ? change < -threshold : change > threshold
+        if (regressed) {
+            printf "REGRESSION %s %s %s\n", $1, unit, $6
+            failed = 1
+        }
+    }
+    END { exit failed }
+' || {
+    echo "Benchmarks regressed by more than $THRESHOLD% against $BASE" >&2
+    exit 1
+}
+
+echo "No benchmark regressed by more than $THRESHOLD% against $BASE"
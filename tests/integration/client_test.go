@@ -0,0 +1,253 @@
+//go:build integration
+
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	gorilla "github.com/gorilla/websocket"
+
+	"github.com/tinle0301/streaming-platform-api/internal/websocket"
+)
+
+// waitTimeout bounds every wait for a server-side effect
+const waitTimeout = 20 * time.Second
+
+// account is a registered user and their access token
+type account struct {
+	ID       string
+	Username string
+	Password string
+	Token    string
+}
+
+// gqlError is an error in a GraphQL response
+type gqlError struct {
+	Message    string                 `json:"message"`
+	Extensions map[string]interface{} `json:"extensions"`
+}
+
+// graphql posts an operation as the holder of token and decodes its data
+// into out, failing the test on transport or GraphQL errors
+func (h *harness) graphql(t *testing.T, token, query string, variables map[string]interface{}, out interface{}) {
+	t.Helper()
+	if errs := h.tryGraphQL(t, token, query, variables, out); len(errs) > 0 {
+		t.Fatalf("graphql errors: %+v", errs)
+	}
+}
+
+// tryGraphQL is graphql returning GraphQL errors instead of failing on them
+func (h *harness) tryGraphQL(t *testing.T, token, query string, variables map[string]interface{}, out interface{}) []gqlError {
+	t.Helper()
+	body, err := json.Marshal(map[string]interface{}{"query": query, "variables": variables})
+	if err != nil {
+		t.Fatalf("failed to encode graphql request: %v", err)
+	}
+	req, err := http.NewRequest(http.MethodPost, h.apiURL+"/graphql", bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("failed to create graphql request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("graphql request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("graphql request returned %s", resp.Status)
+	}
+
+	var result struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []gqlError      `json:"errors"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		t.Fatalf("failed to decode graphql response: %v", err)
+	}
+	if len(result.Errors) > 0 {
+		return result.Errors
+	}
+	if out != nil {
+		if err := json.Unmarshal(result.Data, out); err != nil {
+			t.Fatalf("failed to decode graphql data: %v", err)
+		}
+	}
+	return nil
+}
+
+// register creates an account. Each run starts from an empty database, so
+// usernames only need to be unique within the run.
+func (h *harness) register(t *testing.T, username string) *account {
+	t.Helper()
+	a := &account{Username: username, Password: "integration-password"}
+
+	var out struct {
+		Register struct {
+			Token string `json:"token"`
+			User  struct {
+				ID string `json:"id"`
+			} `json:"user"`
+		} `json:"register"`
+	}
+	h.graphql(t, "", `mutation($input: RegisterInput!) {
+		register(input: $input) { token user { id } }
+	}`, map[string]interface{}{"input": map[string]interface{}{
+		"username":    username,
+		"email":       username + "@example.com",
+		"password":    a.Password,
+		"displayName": username,
+	}}, &out)
+
+	a.ID, a.Token = out.Register.User.ID, out.Register.Token
+	return a
+}
+
+// login signs in with the account's password and replaces its token
+func (h *harness) login(t *testing.T, a *account) {
+	t.Helper()
+	var out struct {
+		Login struct {
+			Token string `json:"token"`
+			User  struct {
+				ID string `json:"id"`
+			} `json:"user"`
+		} `json:"login"`
+	}
+	h.graphql(t, "", `mutation($input: LoginInput!) {
+		login(input: $input) { token user { id } }
+	}`, map[string]interface{}{"input": map[string]interface{}{
+		"login":    a.Username,
+		"password": a.Password,
+	}}, &out)
+
+	if out.Login.User.ID != a.ID {
+		t.Fatalf("login returned user %q, want %q", out.Login.User.ID, a.ID)
+	}
+	a.Token = out.Login.Token
+}
+
+// goLive starts a new stream for the account and returns its ID
+func (h *harness) goLive(t *testing.T, a *account, title string) string {
+	t.Helper()
+	var out struct {
+		GoLive struct {
+			ID     string `json:"id"`
+			Status string `json:"status"`
+		} `json:"goLive"`
+	}
+	h.graphql(t, a.Token, `mutation($input: GoLiveInput!) {
+		goLive(input: $input) { id status }
+	}`, map[string]interface{}{"input": map[string]interface{}{"title": title}}, &out)
+
+	if out.GoLive.Status != "LIVE" {
+		t.Fatalf("goLive returned status %q, want LIVE", out.GoLive.Status)
+	}
+	return out.GoLive.ID
+}
+
+// wsClient is a WebSocket connection that queues the messages it receives
+type wsClient struct {
+	t        *testing.T
+	conn     *gorilla.Conn
+	messages chan websocket.Message
+}
+
+// connect opens a WebSocket connection as the holder of token
+func (h *harness) connect(t *testing.T, token string) *wsClient {
+	t.Helper()
+	endpoint := "ws" + strings.TrimPrefix(h.wsURL, "http") + "/ws?token=" + url.QueryEscape(token)
+	conn, resp, err := gorilla.DefaultDialer.Dial(endpoint, nil)
+	if err != nil {
+		if resp != nil {
+			t.Fatalf("failed to connect: %v (%s)", err, resp.Status)
+		}
+		t.Fatalf("failed to connect: %v", err)
+	}
+
+	c := &wsClient{t: t, conn: conn, messages: make(chan websocket.Message, 256)}
+	go func() {
+		defer close(c.messages)
+		for {
+			_, frame, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			// The server batches queued messages into a frame, one per line
+			for _, line := range bytes.Split(frame, []byte{'\n'}) {
+				var msg websocket.Message
+				if err := json.Unmarshal(line, &msg); err == nil {
+					c.messages <- msg
+				}
+			}
+		}
+	}()
+	t.Cleanup(func() { conn.Close() })
+	return c
+}
+
+// send writes a message envelope
+func (c *wsClient) send(msgType, room string, data map[string]interface{}) {
+	c.t.Helper()
+	msg := map[string]interface{}{"type": msgType, "data": data}
+	if room != "" {
+		msg["room"] = room
+	}
+	if err := c.conn.WriteJSON(msg); err != nil {
+		c.t.Fatalf("failed to send %s: %v", msgType, err)
+	}
+}
+
+// subscribe joins a room and waits for the acknowledgement
+func (c *wsClient) subscribe(room string) {
+	c.t.Helper()
+	c.send("subscribe", "", map[string]interface{}{"room": room})
+	c.waitFor("ack", func(msg websocket.Message) bool {
+		return msg.Data["action"] == "subscribed" && msg.Data["room"] == room
+	})
+}
+
+// waitFor returns the first message of msgType that match accepts,
+// skipping others. A nil match accepts any message of the type.
+func (c *wsClient) waitFor(msgType string, match func(websocket.Message) bool) websocket.Message {
+	c.t.Helper()
+	timeout := time.After(waitTimeout)
+	for {
+		select {
+		case msg, ok := <-c.messages:
+			if !ok {
+				c.t.Fatalf("connection closed waiting for %s", msgType)
+			}
+			if msg.Type == msgType && (match == nil || match(msg)) {
+				return msg
+			}
+		case <-timeout:
+			c.t.Fatalf("timed out waiting for %s", msgType)
+		}
+	}
+}
+
+// eventually retries check until it returns nil or waitTimeout passes
+func eventually(t *testing.T, check func() error) {
+	t.Helper()
+	deadline := time.Now().Add(waitTimeout)
+	for {
+		err := check()
+		if err == nil {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("condition not met within %s: %v", waitTimeout, err)
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+}
@@ -0,0 +1,89 @@
+//go:build integration
+
+package integration
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/tinle0301/streaming-platform-api/internal/websocket"
+)
+
+// TestLiveStreamFlow follows a stream from login to analytics: a streamer
+// goes live, a viewer connects and chats, the streamer raids another
+// channel, and the raided stream's analytics count the arriving viewer
+func TestLiveStreamFlow(t *testing.T) {
+	streamer := env.register(t, "streamer")
+	raided := env.register(t, "raided_streamer")
+	viewer := env.register(t, "viewer")
+
+	// Login issues a fresh token for the registered account
+	env.login(t, streamer)
+
+	from := env.goLive(t, streamer, "Integration stream")
+	to := env.goLive(t, raided, "Raid target")
+
+	// The viewer and the streamer join the stream's room
+	viewerConn := env.connect(t, viewer.Token)
+	viewerConn.subscribe(from)
+	streamerConn := env.connect(t, streamer.Token)
+	streamerConn.subscribe(from)
+
+	// Chat from the viewer reaches the streamer
+	const text = "hello from the integration suite"
+	viewerConn.send("message", from, map[string]interface{}{"message": text})
+	chat := streamerConn.waitFor("chat_message", func(msg websocket.Message) bool {
+		return msg.Data["message"] == text
+	})
+	if chat.Data["user_id"] != viewer.ID {
+		t.Fatalf("chat_message user_id = %v, want %s", chat.Data["user_id"], viewer.ID)
+	}
+
+	// The raid counts down in the raiding room, then moves the viewer
+	var raid struct {
+		StartRaid struct {
+			Success    bool `json:"success"`
+			FromStream struct {
+				ID string `json:"id"`
+			} `json:"fromStream"`
+			ToStream struct {
+				ID string `json:"id"`
+			} `json:"toStream"`
+		} `json:"startRaid"`
+	}
+	env.graphql(t, streamer.Token, `mutation($from: ID!, $to: ID!) {
+		startRaid(fromStreamId: $from, toStreamId: $to) { success fromStream { id } toStream { id } }
+	}`, map[string]interface{}{"from": from, "to": to}, &raid)
+	if !raid.StartRaid.Success || raid.StartRaid.FromStream.ID != from || raid.StartRaid.ToStream.ID != to {
+		t.Fatalf("startRaid = %+v, want success from %s to %s", raid.StartRaid, from, to)
+	}
+
+	viewerConn.waitFor("raid.outgoing", nil)
+	viewerConn.waitFor("raid_countdown", nil)
+	migrate := viewerConn.waitFor("raid_migrate", nil)
+	if migrate.Data["to_stream_id"] != to {
+		t.Fatalf("raid_migrate to_stream_id = %v, want %s", migrate.Data["to_stream_id"], to)
+	}
+
+	// The raided stream's analytics sample the viewer now in its room
+	eventually(t, func() error {
+		var out struct {
+			StreamAnalytics *struct {
+				StreamID    string `json:"streamId"`
+				TotalViews  int    `json:"totalViews"`
+				PeakViewers int    `json:"peakViewers"`
+			} `json:"streamAnalytics"`
+		}
+		env.graphql(t, raided.Token, `query($id: ID!) {
+			streamAnalytics(streamId: $id, timeRange: HOUR) { streamId totalViews peakViewers }
+		}`, map[string]interface{}{"id": to}, &out)
+
+		switch analytics := out.StreamAnalytics; {
+		case analytics == nil:
+			return fmt.Errorf("no analytics for stream %s", to)
+		case analytics.PeakViewers < 1:
+			return fmt.Errorf("peakViewers = %d, want at least 1", analytics.PeakViewers)
+		}
+		return nil
+	})
+}
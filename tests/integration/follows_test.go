@@ -0,0 +1,84 @@
+//go:build integration
+
+package integration
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/tinle0301/streaming-platform-api/internal/users"
+)
+
+// TestFollowPagesReturnEachFollowOnce pages through a channel's followers
+// with the repository's keyset query, many of them followed at the same
+// instant, and checks each follower comes back once, newest first
+func TestFollowPagesReturnEachFollowOnce(t *testing.T) {
+	ctx := context.Background()
+	pool, err := pgxpool.New(ctx, env.databaseURL)
+	if err != nil {
+		t.Fatalf("connect to postgres: %v", err)
+	}
+	defer pool.Close()
+	repo := users.NewPostgresRepository(pool)
+
+	newUser := func(name string) *users.User {
+		user := &users.User{Username: name, Email: name + "@example.com", PasswordHash: "x", DisplayName: name}
+		if err := repo.Create(ctx, user); err != nil {
+			t.Fatalf("create %s: %v", name, err)
+		}
+		return user
+	}
+
+	channel := newUser("paged_channel")
+	const followers = 23
+	for i := 0; i < followers; i++ {
+		follower := newUser(fmt.Sprintf("paged_follower_%d", i))
+		if _, err := repo.Follow(ctx, follower.ID, channel.ID); err != nil {
+			t.Fatalf("follow: %v", err)
+		}
+	}
+	// Four distinct follow times, so most pages end inside a run of ties
+	_, err = pool.Exec(ctx, `
+		UPDATE follows SET created_at = '2024-01-01T00:00:00Z'::timestamptz
+			+ (hashtext(follower_id::text) & 3) * interval '1 microsecond'
+		WHERE followed_id = $1`, channel.ID)
+	if err != nil {
+		t.Fatalf("tie follow times: %v", err)
+	}
+
+	for _, first := range []int{1, 4, 7, followers} {
+		seen := make(map[string]bool, followers)
+		var last *users.Follow
+		after := ""
+		for {
+			page, err := repo.Followers(ctx, channel.ID, first, after)
+			if err != nil {
+				t.Fatalf("first %d: page after %q: %v", first, after, err)
+			}
+			if page.TotalCount != followers || len(page.Follows) > first {
+				t.Fatalf("first %d: page of %d follows with total %d", first, len(page.Follows), page.TotalCount)
+			}
+			for i := range page.Follows {
+				follow := page.Follows[i]
+				if seen[follow.User.ID] {
+					t.Fatalf("first %d: follower %s returned twice", first, follow.User.ID)
+				}
+				seen[follow.User.ID] = true
+				if last != nil && (follow.FollowedAt.After(last.FollowedAt) ||
+					follow.FollowedAt.Equal(last.FollowedAt) && follow.User.ID > last.User.ID) {
+					t.Fatalf("first %d: follower %s is out of order", first, follow.User.ID)
+				}
+				last = &follow
+			}
+			if !page.HasNext {
+				break
+			}
+			after = page.Follows[len(page.Follows)-1].Cursor()
+		}
+		if len(seen) != followers {
+			t.Errorf("first %d: paged through %d followers, want %d", first, len(seen), followers)
+		}
+	}
+}
@@ -0,0 +1,401 @@
+//go:build integration
+
+// Package integration runs the API and WebSocket servers against real
+// Postgres, Redis, and RabbitMQ containers started with dockertest.
+// Run it with make test-integration; it needs a running Docker daemon.
+package integration
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/ory/dockertest/v3"
+	"github.com/ory/dockertest/v3/docker"
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/redis/go-redis/v9"
+)
+
+// moduleRoot is the repository root relative to this package
+const moduleRoot = "../.."
+
+// containerExpiry is how long Docker keeps containers if the run dies
+// before purging them
+const containerExpiry = 10 * time.Minute
+
+// env is the running harness the tests share
+var env *harness
+
+// harness owns the containers and server processes of a test run
+type harness struct {
+	pool       *dockertest.Pool
+	containers []*dockertest.Resource
+	servers    []*server
+	dir        string
+
+	databaseURL string
+	redisURL    string
+	rabbitMQURL string
+
+	apiURL string
+	wsURL  string
+}
+
+// server is a running server binary and where its output goes
+type server struct {
+	name string
+	cmd  *exec.Cmd
+	log  string
+	done chan struct{}
+}
+
+func TestMain(m *testing.M) {
+	h := &harness{}
+	if err := h.start(); err != nil {
+		fmt.Fprintln(os.Stderr, "integration harness:", err)
+		h.dumpLogs()
+		h.stop()
+		os.Exit(1)
+	}
+	env = h
+
+	code := m.Run()
+	if code != 0 {
+		h.dumpLogs()
+	}
+	h.stop()
+	os.Exit(code)
+}
+
+// start brings up the dependencies and both servers
+func (h *harness) start() error {
+	dir, err := os.MkdirTemp("", "streamhub-integration-")
+	if err != nil {
+		return fmt.Errorf("failed to create temp dir: %w", err)
+	}
+	h.dir = dir
+
+	pool, err := dockertest.NewPool("")
+	if err != nil {
+		return fmt.Errorf("failed to connect to docker: %w", err)
+	}
+	if err := pool.Client.Ping(); err != nil {
+		return fmt.Errorf("failed to reach docker: %w", err)
+	}
+	pool.MaxWait = 2 * time.Minute
+	h.pool = pool
+
+	if err := h.startPostgres(); err != nil {
+		return err
+	}
+	if err := h.startRedis(); err != nil {
+		return err
+	}
+	if err := h.startRabbitMQ(); err != nil {
+		return err
+	}
+	if err := migrate(h.databaseURL); err != nil {
+		return err
+	}
+	return h.startServers()
+}
+
+// run starts a container that Docker removes when it stops
+func (h *harness) run(options *dockertest.RunOptions) (*dockertest.Resource, error) {
+	resource, err := h.pool.RunWithOptions(options, func(config *docker.HostConfig) {
+		config.AutoRemove = true
+		config.RestartPolicy = docker.RestartPolicy{Name: "no"}
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to start %s: %w", options.Repository, err)
+	}
+	h.containers = append(h.containers, resource)
+	resource.Expire(uint(containerExpiry.Seconds()))
+	return resource, nil
+}
+
+// startPostgres starts Postgres and waits until it accepts connections
+func (h *harness) startPostgres() error {
+	resource, err := h.run(&dockertest.RunOptions{
+		Repository: "postgres",
+		Tag:        "16-alpine",
+		Env: []string{
+			"POSTGRES_USER=streamhub",
+			"POSTGRES_PASSWORD=streamhub",
+			"POSTGRES_DB=streamhub",
+		},
+	})
+	if err != nil {
+		return err
+	}
+	h.databaseURL = fmt.Sprintf("postgres://streamhub:streamhub@%s/streamhub?sslmode=disable", resource.GetHostPort("5432/tcp"))
+
+	err = h.pool.Retry(func() error {
+		conn, err := pgx.Connect(context.Background(), h.databaseURL)
+		if err != nil {
+			return err
+		}
+		defer conn.Close(context.Background())
+		return conn.Ping(context.Background())
+	})
+	if err != nil {
+		return fmt.Errorf("failed to wait for postgres: %w", err)
+	}
+	return nil
+}
+
+// startRedis starts Redis and waits until it answers PING
+func (h *harness) startRedis() error {
+	resource, err := h.run(&dockertest.RunOptions{Repository: "redis", Tag: "7-alpine"})
+	if err != nil {
+		return err
+	}
+	h.redisURL = "redis://" + resource.GetHostPort("6379/tcp")
+
+	err = h.pool.Retry(func() error {
+		opts, err := redis.ParseURL(h.redisURL)
+		if err != nil {
+			return err
+		}
+		client := redis.NewClient(opts)
+		defer client.Close()
+		return client.Ping(context.Background()).Err()
+	})
+	if err != nil {
+		return fmt.Errorf("failed to wait for redis: %w", err)
+	}
+	return nil
+}
+
+// startRabbitMQ starts RabbitMQ and waits until it accepts connections
+func (h *harness) startRabbitMQ() error {
+	resource, err := h.run(&dockertest.RunOptions{Repository: "rabbitmq", Tag: "3-alpine"})
+	if err != nil {
+		return err
+	}
+	h.rabbitMQURL = fmt.Sprintf("amqp://guest:guest@%s/", resource.GetHostPort("5672/tcp"))
+
+	err = h.pool.Retry(func() error {
+		conn, err := amqp.Dial(h.rabbitMQURL)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	})
+	if err != nil {
+		return fmt.Errorf("failed to wait for rabbitmq: %w", err)
+	}
+	return nil
+}
+
+// migrate applies every up migration in order and records the schema
+// version the way golang-migrate does, so the servers' schema check passes
+func migrate(databaseURL string) error {
+	files, err := filepath.Glob(filepath.Join(moduleRoot, "migrations", "*.up.sql"))
+	if err != nil {
+		return fmt.Errorf("failed to list migrations: %w", err)
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("no migrations found")
+	}
+	sort.Strings(files)
+
+	ctx := context.Background()
+	conn, err := pgx.Connect(ctx, databaseURL)
+	if err != nil {
+		return fmt.Errorf("failed to connect to postgres: %w", err)
+	}
+	defer conn.Close(ctx)
+
+	var version int
+	for _, file := range files {
+		sql, err := os.ReadFile(file)
+		if err != nil {
+			return fmt.Errorf("failed to read migration: %w", err)
+		}
+		if _, err := conn.Exec(ctx, string(sql)); err != nil {
+			return fmt.Errorf("failed to apply %s: %w", filepath.Base(file), err)
+		}
+		prefix, _, _ := strings.Cut(filepath.Base(file), "_")
+		if version, err = strconv.Atoi(prefix); err != nil {
+			return fmt.Errorf("failed to parse migration version of %s: %w", filepath.Base(file), err)
+		}
+	}
+
+	_, err = conn.Exec(ctx, `CREATE TABLE schema_migrations (version bigint NOT NULL PRIMARY KEY, dirty boolean NOT NULL)`)
+	if err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+	if _, err := conn.Exec(ctx, `INSERT INTO schema_migrations (version, dirty) VALUES ($1, false)`, version); err != nil {
+		return fmt.Errorf("failed to record schema version: %w", err)
+	}
+	return nil
+}
+
+// startServers builds and starts the API and WebSocket servers on free
+// ports. Raids and analytics run on short timers so flows finish quickly.
+func (h *harness) startServers() error {
+	apiPort, err := freePort()
+	if err != nil {
+		return err
+	}
+	metricsPort, err := freePort()
+	if err != nil {
+		return err
+	}
+	wsPort, err := freePort()
+	if err != nil {
+		return err
+	}
+	h.apiURL = "http://127.0.0.1:" + apiPort
+	h.wsURL = "http://127.0.0.1:" + wsPort
+
+	shared := []string{
+		"ENVIRONMENT=development",
+		"CONFIG_FILE=",
+		"SECRETS_PROVIDER=env",
+		"LOG_LEVEL=debug",
+		"JWT_SECRET=integration-test-secret",
+		"DATABASE_URL=" + h.databaseURL,
+		"REDIS_URL=" + h.redisURL,
+		"RABBITMQ_URL=" + h.rabbitMQURL,
+		"EVENT_BACKEND=",
+		"RAID_COUNTDOWN=2s",
+		"ANALYTICS_SAMPLE_INTERVAL=1s",
+		"WS_PRESENCE_TTL=5s",
+		"WS_PRESENCE_HEARTBEAT_INTERVAL=1s",
+	}
+
+	if err := h.startServer("api-server", append(shared, "API_PORT="+apiPort, "METRICS_PORT="+metricsPort)); err != nil {
+		return err
+	}
+	if err := h.startServer("ws-server", append(shared, "WS_PORT="+wsPort)); err != nil {
+		return err
+	}
+
+	if err := h.waitReady(h.servers[0], h.apiURL+"/ready"); err != nil {
+		return err
+	}
+	return h.waitReady(h.servers[1], h.wsURL+"/ready")
+}
+
+// startServer builds cmd/<name> into the harness's temp dir and starts it
+// with env added to the test's environment
+func (h *harness) startServer(name string, env []string) error {
+	binary := filepath.Join(h.dir, name)
+	build := exec.Command("go", "build", "-o", binary, "./cmd/"+name)
+	build.Dir = moduleRoot
+	if out, err := build.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to build %s: %w\n%s", name, err, out)
+	}
+
+	logPath := filepath.Join(h.dir, name+".log")
+	logFile, err := os.Create(logPath)
+	if err != nil {
+		return fmt.Errorf("failed to create %s log: %w", name, err)
+	}
+
+	cmd := exec.Command(binary)
+	cmd.Dir = moduleRoot
+	cmd.Env = append(os.Environ(), env...)
+	cmd.Stdout = logFile
+	cmd.Stderr = logFile
+	if err := cmd.Start(); err != nil {
+		logFile.Close()
+		return fmt.Errorf("failed to start %s: %w", name, err)
+	}
+
+	s := &server{name: name, cmd: cmd, log: logPath, done: make(chan struct{})}
+	go func() {
+		cmd.Wait()
+		logFile.Close()
+		close(s.done)
+	}()
+	h.servers = append(h.servers, s)
+	return nil
+}
+
+// waitReady polls a readiness endpoint until it returns 200
+func (h *harness) waitReady(s *server, url string) error {
+	deadline := time.Now().Add(time.Minute)
+	for time.Now().Before(deadline) {
+		select {
+		case <-s.done:
+			return fmt.Errorf("%s exited during startup", s.name)
+		default:
+		}
+		resp, err := http.Get(url)
+		if err == nil {
+			resp.Body.Close()
+			if resp.StatusCode == http.StatusOK {
+				return nil
+			}
+		}
+		time.Sleep(250 * time.Millisecond)
+	}
+	return fmt.Errorf("%s was not ready within a minute", s.name)
+}
+
+// stop shuts down the servers, then removes the containers
+func (h *harness) stop() {
+	for _, s := range h.servers {
+		s.cmd.Process.Signal(syscall.SIGTERM)
+		select {
+		case <-s.done:
+		case <-time.After(10 * time.Second):
+			s.cmd.Process.Kill()
+			<-s.done
+		}
+	}
+	for _, resource := range h.containers {
+		if err := h.pool.Purge(resource); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to purge %s: %v\n", resource.Container.Name, err)
+		}
+	}
+	if h.dir != "" {
+		os.RemoveAll(h.dir)
+	}
+}
+
+// dumpLogs prints the tail of each server's output
+func (h *harness) dumpLogs() {
+	const tail = 50
+	for _, s := range h.servers {
+		f, err := os.Open(s.log)
+		if err != nil {
+			continue
+		}
+		var lines []string
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			lines = append(lines, scanner.Text())
+		}
+		f.Close()
+		if len(lines) > tail {
+			lines = lines[len(lines)-tail:]
+		}
+		fmt.Fprintf(os.Stderr, "--- %s (last %d lines) ---\n%s\n", s.name, len(lines), strings.Join(lines, "\n"))
+	}
+}
+
+// freePort returns a TCP port nothing is listening on
+func freePort() (string, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return "", fmt.Errorf("failed to find a free port: %w", err)
+	}
+	defer l.Close()
+	return strconv.Itoa(l.Addr().(*net.TCPAddr).Port), nil
+}
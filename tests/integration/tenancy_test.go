@@ -0,0 +1,96 @@
+//go:build integration
+
+package integration
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/tinle0301/streaming-platform-api/internal/communitychat"
+	"github.com/tinle0301/streaming-platform-api/internal/goals"
+	"github.com/tinle0301/streaming-platform-api/internal/milestones"
+	"github.com/tinle0301/streaming-platform-api/internal/tenancy"
+	"github.com/tinle0301/streaming-platform-api/internal/users"
+)
+
+// TestChannelRecordsAreHiddenFromOtherTenants saves a channel's goal,
+// milestone settings, and community chat settings in one tenant and checks
+// another tenant can neither read nor overwrite them
+func TestChannelRecordsAreHiddenFromOtherTenants(t *testing.T) {
+	ctx := context.Background()
+	pool, err := pgxpool.New(ctx, env.databaseURL)
+	if err != nil {
+		t.Fatalf("connect to postgres: %v", err)
+	}
+	defer pool.Close()
+
+	if _, err := pool.Exec(ctx, `
+		INSERT INTO tenants (id, name) VALUES ('tenant_a', 'Tenant A'), ('tenant_b', 'Tenant B')
+		ON CONFLICT (id) DO NOTHING`); err != nil {
+		t.Fatalf("create tenants: %v", err)
+	}
+	ctxA := tenancy.WithTenant(ctx, "tenant_a")
+	ctxB := tenancy.WithTenant(ctx, "tenant_b")
+
+	channel := &users.User{Username: "tenant_channel", Email: "tenant_channel@example.com", PasswordHash: "x",
+		DisplayName: "tenant_channel"}
+	if err := users.NewPostgresRepository(pool).Create(ctxA, channel); err != nil {
+		t.Fatalf("create channel: %v", err)
+	}
+
+	goalRepo := goals.NewPostgresRepository(pool)
+	goal := &goals.Goal{ChannelID: channel.ID, Kind: goals.KindCheers, Description: "bits", Target: 100}
+	if err := goalRepo.Create(ctxA, goal); err != nil {
+		t.Fatalf("create goal: %v", err)
+	}
+	if _, err := goalRepo.Get(ctxA, goal.ID); err != nil {
+		t.Fatalf("get goal in its tenant: %v", err)
+	}
+	if _, err := goalRepo.Get(ctxB, goal.ID); !errors.Is(err, goals.ErrNotFound) {
+		t.Errorf("get goal from another tenant: err = %v, want ErrNotFound", err)
+	}
+	if list, err := goalRepo.List(ctxB, channel.ID, true); err != nil || len(list) != 0 {
+		t.Errorf("list goals from another tenant = %d goals, %v; want none", len(list), err)
+	}
+	if _, _, err := goalRepo.End(ctxB, goal.ID, goals.StatusCancelled); !errors.Is(err, goals.ErrNotFound) {
+		t.Errorf("end goal from another tenant: err = %v, want ErrNotFound", err)
+	}
+
+	milestoneRepo := milestones.NewPostgresRepository(pool)
+	saved := milestones.DefaultSettings(channel.ID)
+	saved.Enabled = false
+	if err := milestoneRepo.SaveSettings(ctxA, saved); err != nil {
+		t.Fatalf("save milestone settings: %v", err)
+	}
+	if got, err := milestoneRepo.Settings(ctxB, []string{channel.ID}); err != nil || len(got) != 0 {
+		t.Errorf("milestone settings from another tenant = %v, %v; want none", got, err)
+	}
+	overwrite := milestones.DefaultSettings(channel.ID)
+	if err := milestoneRepo.SaveSettings(ctxB, overwrite); !errors.Is(err, milestones.ErrNotFound) {
+		t.Errorf("save milestone settings from another tenant: err = %v, want ErrNotFound", err)
+	}
+	if got, err := milestoneRepo.Settings(ctxA, []string{channel.ID}); err != nil || got[channel.ID] == nil ||
+		got[channel.ID].Enabled {
+		t.Errorf("milestone settings in their tenant = %v, %v; want the saved, disabled settings", got, err)
+	}
+
+	chatRepo := communitychat.NewPostgresRepository(pool)
+	if err := chatRepo.SaveSettings(ctxA, &communitychat.Settings{ChannelID: channel.ID, Enabled: true,
+		SlowMode: 30 * time.Second}); err != nil {
+		t.Fatalf("save community chat settings: %v", err)
+	}
+	got, err := chatRepo.Settings(ctxB, channel.ID)
+	if err != nil {
+		t.Fatalf("community chat settings from another tenant: %v", err)
+	}
+	if got.Enabled || got.SlowMode != 0 {
+		t.Errorf("community chat settings from another tenant = %+v, want the defaults", got)
+	}
+	if err := chatRepo.SaveSettings(ctxB, &communitychat.Settings{ChannelID: channel.ID}); !errors.Is(err,
+		communitychat.ErrNotFound) {
+		t.Errorf("save community chat settings from another tenant: err = %v, want ErrNotFound", err)
+	}
+}